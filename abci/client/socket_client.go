@@ -19,6 +19,10 @@ const reqQueueSize = 256 // TODO make configurable
 // const maxResponseSize = 1048576 // 1MB TODO make configurable
 const flushThrottleMS = 20 // Don't wait longer than...
 
+// unknownRequestError is the exception of the socket server of an app for
+// the requests its version of ABCI doesn't know.
+const unknownRequestError = "Unknown request"
+
 var _ Client = (*socketClient)(nil)
 
 // This is goroutine-safe, but users should beware that
@@ -164,6 +168,14 @@ func (cli *socketClient) recvResponseRoutine(conn io.Reader) {
 		}
 		switch r := res.Value.(type) {
 		case *types.Response_Exception:
+			if noop := cli.unknownRequestResponse(r.Exception); noop != nil {
+				// an older app, which doesn't implement the request
+				if err := cli.didRecvResponse(noop); err != nil {
+					cli.StopForError(err)
+					return
+				}
+				continue
+			}
 			// XXX After setting cli.err, release waiters (e.g. reqres.Done())
 			cli.StopForError(errors.New(r.Exception.Error))
 			return
@@ -184,6 +196,22 @@ func (cli *socketClient) willSendReq(reqres *ReqRes) {
 	cli.reqSent.PushBack(reqres)
 }
 
+// unknownRequestResponse returns the no-op response of the first request
+// sent, if the exception is the app not knowing it and the request is one an
+// app may not implement (see noopResponse). Otherwise it returns nil.
+func (cli *socketClient) unknownRequestResponse(exception *types.ResponseException) *types.Response {
+	if exception.Error != unknownRequestError {
+		return nil
+	}
+	cli.mtx.Lock()
+	defer cli.mtx.Unlock()
+	next := cli.reqSent.Front()
+	if next == nil {
+		return nil
+	}
+	return noopResponse(next.Value.(*ReqRes).Request)
+}
+
 func (cli *socketClient) didRecvResponse(res *types.Response) error {
 	cli.mtx.Lock()
 	defer cli.mtx.Unlock()
@@ -244,6 +272,10 @@ func (cli *socketClient) CheckTxAsync(req types.RequestCheckTx) *ReqRes {
 	return cli.queueRequest(types.ToRequestCheckTx(req))
 }
 
+func (cli *socketClient) RecheckBatchAsync(req types.RequestRecheckBatch) *ReqRes {
+	return cli.queueRequest(types.ToRequestRecheckBatch(req))
+}
+
 func (cli *socketClient) QueryAsync(req types.RequestQuery) *ReqRes {
 	return cli.queueRequest(types.ToRequestQuery(req))
 }
@@ -264,6 +296,22 @@ func (cli *socketClient) EndBlockAsync(req types.RequestEndBlock) *ReqRes {
 	return cli.queueRequest(types.ToRequestEndBlock(req))
 }
 
+func (cli *socketClient) ExtendVoteAsync(req types.RequestExtendVote) *ReqRes {
+	return cli.queueRequest(types.ToRequestExtendVote(req))
+}
+
+func (cli *socketClient) DeliverVoteExtensionsAsync(req types.RequestDeliverVoteExtensions) *ReqRes {
+	return cli.queueRequest(types.ToRequestDeliverVoteExtensions(req))
+}
+
+func (cli *socketClient) PrepareProposalAsync(req types.RequestPrepareProposal) *ReqRes {
+	return cli.queueRequest(types.ToRequestPrepareProposal(req))
+}
+
+func (cli *socketClient) ProcessProposalAsync(req types.RequestProcessProposal) *ReqRes {
+	return cli.queueRequest(types.ToRequestProcessProposal(req))
+}
+
 //----------------------------------------
 
 func (cli *socketClient) FlushSync() error {
@@ -305,6 +353,12 @@ func (cli *socketClient) CheckTxSync(req types.RequestCheckTx) (*types.ResponseC
 	return reqres.Response.GetCheckTx(), cli.Error()
 }
 
+func (cli *socketClient) RecheckBatchSync(req types.RequestRecheckBatch) (*types.ResponseRecheckBatch, error) {
+	reqres := cli.queueRequest(types.ToRequestRecheckBatch(req))
+	cli.FlushSync()
+	return reqres.Response.GetRecheckBatch(), cli.Error()
+}
+
 func (cli *socketClient) QuerySync(req types.RequestQuery) (*types.ResponseQuery, error) {
 	reqres := cli.queueRequest(types.ToRequestQuery(req))
 	cli.FlushSync()
@@ -335,6 +389,33 @@ func (cli *socketClient) EndBlockSync(req types.RequestEndBlock) (*types.Respons
 	return reqres.Response.GetEndBlock(), cli.Error()
 }
 
+func (cli *socketClient) ExtendVoteSync(req types.RequestExtendVote) (*types.ResponseExtendVote, error) {
+	reqres := cli.queueRequest(types.ToRequestExtendVote(req))
+	cli.FlushSync()
+	return reqres.Response.GetExtendVote(), cli.Error()
+}
+
+func (cli *socketClient) DeliverVoteExtensionsSync(
+	req types.RequestDeliverVoteExtensions) (*types.ResponseDeliverVoteExtensions, error) {
+	reqres := cli.queueRequest(types.ToRequestDeliverVoteExtensions(req))
+	cli.FlushSync()
+	return reqres.Response.GetDeliverVoteExtensions(), cli.Error()
+}
+
+func (cli *socketClient) PrepareProposalSync(
+	req types.RequestPrepareProposal) (*types.ResponsePrepareProposal, error) {
+	reqres := cli.queueRequest(types.ToRequestPrepareProposal(req))
+	cli.FlushSync()
+	return reqres.Response.GetPrepareProposal(), cli.Error()
+}
+
+func (cli *socketClient) ProcessProposalSync(
+	req types.RequestProcessProposal) (*types.ResponseProcessProposal, error) {
+	reqres := cli.queueRequest(types.ToRequestProcessProposal(req))
+	cli.FlushSync()
+	return reqres.Response.GetProcessProposal(), cli.Error()
+}
+
 //----------------------------------------
 
 func (cli *socketClient) queueRequest(req *types.Request) *ReqRes {
@@ -375,6 +456,23 @@ LOOP:
 
 //----------------------------------------
 
+// noopResponse returns the response of an app leaving the request to
+// Tendermint, for the requests added to ABCI after the apps built with an
+// older version, or nil if the app must implement the request.
+func noopResponse(req *types.Request) *types.Response {
+	switch r := req.Value.(type) {
+	case *types.Request_PrepareProposal:
+		return types.ToResponsePrepareProposal(types.ResponsePrepareProposal{Txs: r.PrepareProposal.Txs})
+	case *types.Request_ProcessProposal:
+		return types.ToResponseProcessProposal(types.ResponseProcessProposal{Accept: true})
+	case *types.Request_ExtendVote:
+		return types.ToResponseExtendVote(types.ResponseExtendVote{})
+	case *types.Request_DeliverVoteExtensions:
+		return types.ToResponseDeliverVoteExtensions(types.ResponseDeliverVoteExtensions{})
+	}
+	return nil
+}
+
 func resMatchesReq(req *types.Request, res *types.Response) (ok bool) {
 	switch req.Value.(type) {
 	case *types.Request_Echo:
@@ -389,6 +487,12 @@ func resMatchesReq(req *types.Request, res *types.Response) (ok bool) {
 		_, ok = res.Value.(*types.Response_DeliverTx)
 	case *types.Request_CheckTx:
 		_, ok = res.Value.(*types.Response_CheckTx)
+	case *types.Request_RecheckBatch:
+		_, ok = res.Value.(*types.Response_RecheckBatch)
+	case *types.Request_PrepareProposal:
+		_, ok = res.Value.(*types.Response_PrepareProposal)
+	case *types.Request_ProcessProposal:
+		_, ok = res.Value.(*types.Response_ProcessProposal)
 	case *types.Request_Commit:
 		_, ok = res.Value.(*types.Response_Commit)
 	case *types.Request_Query:
@@ -399,6 +503,10 @@ func resMatchesReq(req *types.Request, res *types.Response) (ok bool) {
 		_, ok = res.Value.(*types.Response_BeginBlock)
 	case *types.Request_EndBlock:
 		_, ok = res.Value.(*types.Response_EndBlock)
+	case *types.Request_ExtendVote:
+		_, ok = res.Value.(*types.Response_ExtendVote)
+	case *types.Request_DeliverVoteExtensions:
+		_, ok = res.Value.(*types.Response_DeliverVoteExtensions)
 	}
 	return ok
 }
@@ -1,8 +1,10 @@
 package abcicli_test
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"net"
 	"testing"
 	"time"
 
@@ -93,6 +95,77 @@ func TestHangingSyncCalls(t *testing.T) {
 	}
 }
 
+func TestSocketClientUnknownRequest(t *testing.T) {
+	addr := startOldAppServer(t)
+	c := abcicli.NewSocketClient(addr, true)
+	require.NoError(t, c.Start())
+	defer c.Stop()
+
+	// an app which doesn't know PrepareProposal proposes the txs as they are
+	txs := [][]byte{[]byte("foo"), []byte("bar")}
+	res, err := c.PrepareProposalSync(types.RequestPrepareProposal{Txs: txs})
+	require.NoError(t, err)
+	assert.Equal(t, txs, res.Txs)
+	assert.True(t, c.IsRunning())
+
+	// and accepts the proposals if it doesn't know ProcessProposal
+	resProcess, err := c.ProcessProposalSync(types.RequestProcessProposal{Txs: txs})
+	require.NoError(t, err)
+	assert.True(t, resProcess.Accept)
+	assert.True(t, c.IsRunning())
+
+	// doesn't extend its votes if it doesn't know ExtendVote
+	resExtend, err := c.ExtendVoteSync(types.RequestExtendVote{Height: 1})
+	require.NoError(t, err)
+	assert.Empty(t, resExtend.VoteExtension)
+	assert.True(t, c.IsRunning())
+
+	// and ignores the vote extensions if it doesn't know DeliverVoteExtensions
+	_, err = c.DeliverVoteExtensionsSync(types.RequestDeliverVoteExtensions{Height: 1})
+	require.NoError(t, err)
+	assert.True(t, c.IsRunning())
+
+	// but it must know the other requests
+	_, err = c.BeginBlockSync(types.RequestBeginBlock{})
+	assert.Error(t, err)
+	assert.False(t, c.IsRunning())
+}
+
+// startOldAppServer starts a socket server answering the requests other than
+// Flush as the app of an older version of ABCI answers the ones it doesn't
+// know, and returns its address.
+func startOldAppServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+		for {
+			req := &types.Request{}
+			if err := types.ReadMessage(r, req); err != nil {
+				return
+			}
+			res := types.ToResponseException("Unknown request")
+			if _, ok := req.Value.(*types.Request_Flush); ok {
+				res = types.ToResponseFlush()
+			}
+			if err := types.WriteMessage(res, w); err != nil {
+				return
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
 func setupClientServer(t *testing.T, app types.Application) (
 	cmn.Service, abcicli.Client) {
 	// some port between 20k and 30k
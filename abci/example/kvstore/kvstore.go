@@ -105,6 +105,14 @@ func (app *KVStoreApplication) CheckTx(req types.RequestCheckTx) types.ResponseC
 	return types.ResponseCheckTx{Code: code.CodeTypeOK, GasWanted: 1}
 }
 
+func (app *KVStoreApplication) RecheckBatch(req types.RequestRecheckBatch) types.ResponseRecheckBatch {
+	responses := make([]types.ResponseCheckTx, len(req.Txs))
+	for i, tx := range req.Txs {
+		responses[i] = app.CheckTx(types.RequestCheckTx{Tx: tx, Type: types.CheckTxType_Recheck})
+	}
+	return types.ResponseRecheckBatch{Responses: responses}
+}
+
 func (app *KVStoreApplication) Commit() types.ResponseCommit {
 	// Using a memdb - just return the big endian size of the db
 	appHash := make([]byte, 8)
@@ -319,4 +319,20 @@ func testClient(t *testing.T, app abcicli.Client, tx []byte, key, value string)
 	require.Nil(t, err)
 	require.Equal(t, code.CodeTypeOK, resQuery.Code)
 	require.Equal(t, value, string(resQuery.Value))
+
+	// the txs are proposed as they are
+	resPrepare, err := app.PrepareProposalSync(types.RequestPrepareProposal{
+		Height: 1,
+		Txs:    [][]byte{tx},
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{tx}, resPrepare.Txs)
+
+	// and accepted
+	resProcess, err := app.ProcessProposalSync(types.RequestProcessProposal{
+		Height: 1,
+		Txs:    [][]byte{tx},
+	})
+	require.NoError(t, err)
+	require.True(t, resProcess.Accept)
 }
@@ -15,7 +15,8 @@ type Application interface {
 	Query(RequestQuery) ResponseQuery             // Query for state
 
 	// Mempool Connection
-	CheckTx(RequestCheckTx) ResponseCheckTx // Validate a tx for the mempool
+	CheckTx(RequestCheckTx) ResponseCheckTx                // Validate a tx for the mempool
+	RecheckBatch(RequestRecheckBatch) ResponseRecheckBatch // Revalidate the mempool txs after a block
 
 	// Consensus Connection
 	InitChain(RequestInitChain) ResponseInitChain    // Initialize blockchain w validators/other info from TendermintCore
@@ -23,6 +24,14 @@ type Application interface {
 	DeliverTx(RequestDeliverTx) ResponseDeliverTx    // Deliver a tx for full processing
 	EndBlock(RequestEndBlock) ResponseEndBlock       // Signals the end of a block, returns changes to the validator set
 	Commit() ResponseCommit                          // Commit the state and return the application Merkle root hash
+
+	// Vote extensions (Consensus Connection)
+	ExtendVote(RequestExtendVote) ResponseExtendVote                                  // Extend our precommit
+	DeliverVoteExtensions(RequestDeliverVoteExtensions) ResponseDeliverVoteExtensions // Deliver the commit's extensions
+
+	// Proposals (Consensus Connection)
+	PrepareProposal(RequestPrepareProposal) ResponsePrepareProposal // Reorder or replace the txs of our proposal
+	ProcessProposal(RequestProcessProposal) ResponseProcessProposal // Accept or reject the txs of a proposal
 }
 
 //-------------------------------------------------------
@@ -53,6 +62,14 @@ func (BaseApplication) CheckTx(req RequestCheckTx) ResponseCheckTx {
 	return ResponseCheckTx{Code: CodeTypeOK}
 }
 
+func (BaseApplication) RecheckBatch(req RequestRecheckBatch) ResponseRecheckBatch {
+	responses := make([]ResponseCheckTx, len(req.Txs))
+	for i := range responses {
+		responses[i] = ResponseCheckTx{Code: CodeTypeOK}
+	}
+	return ResponseRecheckBatch{Responses: responses}
+}
+
 func (BaseApplication) Commit() ResponseCommit {
 	return ResponseCommit{}
 }
@@ -73,6 +90,24 @@ func (BaseApplication) EndBlock(req RequestEndBlock) ResponseEndBlock {
 	return ResponseEndBlock{}
 }
 
+func (BaseApplication) ExtendVote(req RequestExtendVote) ResponseExtendVote {
+	return ResponseExtendVote{}
+}
+
+func (BaseApplication) DeliverVoteExtensions(req RequestDeliverVoteExtensions) ResponseDeliverVoteExtensions {
+	return ResponseDeliverVoteExtensions{}
+}
+
+// PrepareProposal proposes the reaped txs as they are.
+func (BaseApplication) PrepareProposal(req RequestPrepareProposal) ResponsePrepareProposal {
+	return ResponsePrepareProposal{Txs: req.Txs}
+}
+
+// ProcessProposal accepts all the proposals.
+func (BaseApplication) ProcessProposal(req RequestProcessProposal) ResponseProcessProposal {
+	return ResponseProcessProposal{Accept: true}
+}
+
 //-------------------------------------------------------
 
 // GRPCApplication is a GRPC wrapper for Application
@@ -112,6 +147,11 @@ func (app *GRPCApplication) CheckTx(ctx context.Context, req *RequestCheckTx) (*
 	return &res, nil
 }
 
+func (app *GRPCApplication) RecheckBatch(ctx context.Context, req *RequestRecheckBatch) (*ResponseRecheckBatch, error) {
+	res := app.app.RecheckBatch(*req)
+	return &res, nil
+}
+
 func (app *GRPCApplication) Query(ctx context.Context, req *RequestQuery) (*ResponseQuery, error) {
 	res := app.app.Query(*req)
 	return &res, nil
@@ -136,3 +176,26 @@ func (app *GRPCApplication) EndBlock(ctx context.Context, req *RequestEndBlock)
 	res := app.app.EndBlock(*req)
 	return &res, nil
 }
+
+func (app *GRPCApplication) ExtendVote(ctx context.Context, req *RequestExtendVote) (*ResponseExtendVote, error) {
+	res := app.app.ExtendVote(*req)
+	return &res, nil
+}
+
+func (app *GRPCApplication) DeliverVoteExtensions(
+	ctx context.Context, req *RequestDeliverVoteExtensions) (*ResponseDeliverVoteExtensions, error) {
+	res := app.app.DeliverVoteExtensions(*req)
+	return &res, nil
+}
+
+func (app *GRPCApplication) PrepareProposal(
+	ctx context.Context, req *RequestPrepareProposal) (*ResponsePrepareProposal, error) {
+	res := app.app.PrepareProposal(*req)
+	return &res, nil
+}
+
+func (app *GRPCApplication) ProcessProposal(
+	ctx context.Context, req *RequestProcessProposal) (*ResponseProcessProposal, error) {
+	res := app.app.ProcessProposal(*req)
+	return &res, nil
+}
@@ -135,6 +135,36 @@ func ToRequestEndBlock(req RequestEndBlock) *Request {
 	}
 }
 
+func ToRequestExtendVote(req RequestExtendVote) *Request {
+	return &Request{
+		Value: &Request_ExtendVote{&req},
+	}
+}
+
+func ToRequestDeliverVoteExtensions(req RequestDeliverVoteExtensions) *Request {
+	return &Request{
+		Value: &Request_DeliverVoteExtensions{&req},
+	}
+}
+
+func ToRequestRecheckBatch(req RequestRecheckBatch) *Request {
+	return &Request{
+		Value: &Request_RecheckBatch{&req},
+	}
+}
+
+func ToRequestPrepareProposal(req RequestPrepareProposal) *Request {
+	return &Request{
+		Value: &Request_PrepareProposal{&req},
+	}
+}
+
+func ToRequestProcessProposal(req RequestProcessProposal) *Request {
+	return &Request{
+		Value: &Request_ProcessProposal{&req},
+	}
+}
+
 //----------------------------------------
 
 func ToResponseException(errStr string) *Response {
@@ -208,3 +238,33 @@ func ToResponseEndBlock(res ResponseEndBlock) *Response {
 		Value: &Response_EndBlock{&res},
 	}
 }
+
+func ToResponseExtendVote(res ResponseExtendVote) *Response {
+	return &Response{
+		Value: &Response_ExtendVote{&res},
+	}
+}
+
+func ToResponseDeliverVoteExtensions(res ResponseDeliverVoteExtensions) *Response {
+	return &Response{
+		Value: &Response_DeliverVoteExtensions{&res},
+	}
+}
+
+func ToResponseRecheckBatch(res ResponseRecheckBatch) *Response {
+	return &Response{
+		Value: &Response_RecheckBatch{&res},
+	}
+}
+
+func ToResponsePrepareProposal(res ResponsePrepareProposal) *Response {
+	return &Response{
+		Value: &Response_PrepareProposal{&res},
+	}
+}
+
+func ToResponseProcessProposal(res ResponseProcessProposal) *Response {
+	return &Response{
+		Value: &Response_ProcessProposal{&res},
+	}
+}
@@ -0,0 +1,95 @@
+// Package inflight sizes the block requests of the fast sync block pools, so
+// that the blocks requested or received but not applied yet fit in a memory
+// budget, and each peer has enough requests pending to send blocks at its
+// throughput.
+package inflight
+
+import (
+	"time"
+
+	flow "github.com/tendermint/tendermint/libs/flowrate"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// Bounds of the number of blocks requested or received but not applied
+	// yet. Within them, it follows the memory budget and the average size of
+	// the blocks.
+	MinRequests = 10
+	MaxRequests = 600
+
+	// Bounds of the number of requests pending per peer. Within them, it
+	// follows the throughput of the peer, so that it has RequestWindow worth
+	// of blocks to send.
+	MinRequestsPerPeer = 2
+	MaxRequestsPerPeer = 50
+	RequestWindow      = 2 * time.Second
+
+	// DefaultMemoryBudget is the default memory budget of the block pools.
+	DefaultMemoryBudget = 128 * 1024 * 1024
+
+	// initialBlockSize is the size of blocks assumed before any is received.
+	initialBlockSize = types.BlockPartSizeBytes
+)
+
+// Limits tracks the average size of the received blocks to derive the number
+// of block requests from the memory budget and the throughput of the peers.
+// It isn't safe for concurrent use.
+type Limits struct {
+	memoryBudget int64
+	avgBlockSize float64 // moving average of the received blocks' size
+}
+
+// NewLimits returns the limits of the given memory budget.
+func NewLimits(memoryBudget int64) *Limits {
+	return &Limits{
+		memoryBudget: memoryBudget,
+		avgBlockSize: initialBlockSize,
+	}
+}
+
+// SetMemoryBudget sets the approximate size of the blocks requested or
+// received but not applied yet.
+func (l *Limits) SetMemoryBudget(budget int64) {
+	l.memoryBudget = budget
+}
+
+// BlockReceived accounts for the size of a received block in the average.
+func (l *Limits) BlockReceived(size int) {
+	l.avgBlockSize = 0.9*l.avgBlockSize + 0.1*float64(size)
+}
+
+// MaxRequests returns the number of blocks which fit in the memory budget,
+// between MinRequests and MaxRequests.
+func (l *Limits) MaxRequests() int {
+	n := int(float64(l.memoryBudget) / l.avgBlockSize)
+	if n < MinRequests {
+		return MinRequests
+	}
+	if n > MaxRequests {
+		return MaxRequests
+	}
+	return n
+}
+
+// MaxRequestsPerPeer returns the number of requests a peer sending blocks at
+// rate (bytes per second, see NewThroughputMonitor) should have pending to
+// send blocks for RequestWindow, between MinRequestsPerPeer and
+// MaxRequestsPerPeer.
+func (l *Limits) MaxRequestsPerPeer(rate int64) int {
+	n := int(float64(rate) * RequestWindow.Seconds() / l.avgBlockSize)
+	if n < MinRequestsPerPeer {
+		return MinRequestsPerPeer
+	}
+	if n > MaxRequestsPerPeer {
+		return MaxRequestsPerPeer
+	}
+	return n
+}
+
+// NewThroughputMonitor returns a monitor of the rate of the blocks received
+// from a peer, to be updated with their sizes. Unlike the monitors detecting
+// slow peers, it must not be reset while the peer is idle.
+func NewThroughputMonitor() *flow.Monitor {
+	return flow.New(time.Second, 5*time.Second)
+}
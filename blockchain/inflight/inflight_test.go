@@ -0,0 +1,50 @@
+package inflight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsMaxRequests(t *testing.T) {
+	l := NewLimits(10 * 1024 * 1024)
+
+	// the number of requests follows the size of the blocks
+	l.avgBlockSize = 64 * 1024
+	assert.Equal(t, 160, l.MaxRequests())
+	l.avgBlockSize = 10 * 1024 * 1024
+	assert.Equal(t, MinRequests, l.MaxRequests())
+	l.avgBlockSize = 1024
+	assert.Equal(t, MaxRequests, l.MaxRequests())
+
+	// and the memory budget
+	l.avgBlockSize = 64 * 1024
+	l.SetMemoryBudget(20 * 1024 * 1024)
+	assert.Equal(t, 320, l.MaxRequests())
+
+	// the average moves towards the size of the received blocks
+	for i := 0; i < 100; i++ {
+		l.BlockReceived(1024 * 1024)
+	}
+	assert.Equal(t, 20, l.MaxRequests())
+}
+
+func TestLimitsMaxRequestsPerPeer(t *testing.T) {
+	l := NewLimits(DefaultMemoryBudget)
+	l.avgBlockSize = 1024
+
+	assert.Equal(t, MinRequestsPerPeer, l.MaxRequestsPerPeer(0), "unknown throughput")
+	assert.Equal(t, 10, l.MaxRequestsPerPeer(5*1024))
+	assert.Equal(t, MaxRequestsPerPeer, l.MaxRequestsPerPeer(10*1024*1024))
+	l.avgBlockSize = 100 * 1024 * 1024
+	assert.Equal(t, MinRequestsPerPeer, l.MaxRequestsPerPeer(10*1024*1024))
+}
+
+func TestThroughputMonitor(t *testing.T) {
+	m := NewThroughputMonitor()
+	assert.Zero(t, m.Status().CurRate)
+	m.Update(10 * 1024 * 1024)
+	time.Sleep(1100 * time.Millisecond) // wait for a sample
+	assert.True(t, m.Status().CurRate > 0)
+}
@@ -0,0 +1,210 @@
+package blockchain
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	bcv0 "github.com/tendermint/tendermint/blockchain/v0"
+	bcv1 "github.com/tendermint/tendermint/blockchain/v1"
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// The fast sync reactors v0 (the original one) and v1 (the FSM) speak the
+// same protocol on the blockchain channel: these tests sync nodes running one
+// from nodes running the other, in one binary, so that a change to either
+// can't break syncing from the deployed network.
+
+const interopMaxBlockHeight = 65
+
+func TestFastSyncInterop(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string // versions of the nodes with the blocks
+		syncing string   // version of the node syncing from them
+	}{
+		{"v1 syncs from v0", []string{"v0"}, "v1"},
+		{"v0 syncs from v1", []string{"v1"}, "v0"},
+		{"v1 syncs from v0 and v1", []string{"v0", "v1"}, "v1"},
+		{"v0 syncs from v0 and v1", []string{"v0", "v1"}, "v0"},
+	}
+
+	config := cfg.ResetTestRoot("blockchain_interop_test")
+	defer os.RemoveAll(config.RootDir)
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			genDoc, privVal := interopGenesisDoc(config.ChainID())
+			chain := makeInteropChain(t, genDoc, privVal, interopMaxBlockHeight)
+			logger := log.TestingLogger()
+
+			var (
+				nodes    []*interopNode
+				reactors []p2p.Reactor
+			)
+			for _, version := range tc.sources {
+				node := newInteropNode(t, genDoc, chain)
+				nodes = append(nodes, node)
+				reactors = append(reactors, node.reactor(t, version, false))
+			}
+			syncing := newInteropNode(t, genDoc, nil)
+			nodes = append(nodes, syncing)
+			reactors = append(reactors, syncing.reactor(t, tc.syncing, true))
+
+			switches := p2p.MakeConnectedSwitches(config.P2P, len(nodes), func(i int, s *p2p.Switch) *p2p.Switch {
+				reactors[i].SetLogger(logger.With("module", fmt.Sprintf("blockchain-%v", i)))
+				s.AddReactor("BLOCKCHAIN", reactors[i])
+				s.AddReactor("CONSENSUS", nodes[i].conR)
+				return s
+			}, p2p.Connect2Switches)
+			defer func() {
+				for i, sw := range switches {
+					_ = sw.Stop()
+					_ = nodes[i].app.Stop()
+				}
+			}()
+
+			// Fast sync applies a block with the commit of the next one: the
+			// last block of the sources is left to consensus.
+			state, ok := syncing.conR.waitForSwitch(30 * time.Second)
+			require.True(t, ok, "the %v node didn't switch to consensus", tc.syncing)
+			assert.EqualValues(t, interopMaxBlockHeight-1, state.LastBlockHeight)
+			require.EqualValues(t, interopMaxBlockHeight-1, syncing.store.Height())
+			for height := int64(1); height < interopMaxBlockHeight; height++ {
+				want := chain[height-1].Hash()
+				got := syncing.store.LoadBlockMeta(height).BlockID.Hash
+				require.Equal(t, want, got, "block %d", height)
+			}
+		})
+	}
+}
+
+func interopGenesisDoc(chainID string) (*types.GenesisDoc, types.PrivValidator) {
+	val, privVal := types.RandValidator(false, 30)
+	return &types.GenesisDoc{
+		GenesisTime: tmtime.Now(),
+		ChainID:     chainID,
+		Validators: []types.GenesisValidator{
+			{PubKey: val.PubKey, Power: val.VotingPower},
+		},
+	}, privVal
+}
+
+// makeInteropChain returns the blocks of a chain of maxBlockHeight blocks
+// signed by the only validator, followed by one more block, whose last commit
+// is the seen commit of the last one.
+func makeInteropChain(t *testing.T, genDoc *types.GenesisDoc, privVal types.PrivValidator,
+	maxBlockHeight int64) []*types.Block {
+
+	node := newInteropNode(t, genDoc, nil)
+	defer node.app.Stop() // nolint: errcheck
+
+	state := node.state
+	blocks := make([]*types.Block, 0, maxBlockHeight+1)
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+	for height := int64(1); height <= maxBlockHeight+1; height++ {
+		block, parts := state.MakeBlock(height, nil, lastCommit, nil, state.Validators.GetProposer().Address)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		blocks = append(blocks, block)
+
+		var err error
+		state, err = node.blockExec.ApplyBlock(state, blockID, block)
+		require.NoError(t, err)
+		vote, err := types.MakeVote(height, blockID, state.LastValidators, privVal, genDoc.ChainID)
+		require.NoError(t, err)
+		lastCommit = types.NewCommit(blockID, []*types.CommitSig{vote.CommitSig()})
+	}
+	return blocks
+}
+
+// interopNode is a node with the blocks of a chain but the last one (see
+// makeInteropChain), and the state after them.
+type interopNode struct {
+	app       proxy.AppConns
+	state     sm.State
+	store     *store.BlockStore
+	blockExec *sm.BlockExecutor
+	conR      *interopConsensusReactor
+}
+
+func newInteropNode(t *testing.T, genDoc *types.GenesisDoc, chain []*types.Block) *interopNode {
+	app := proxy.NewAppConns(proxy.NewLocalClientCreator(abci.NewBaseApplication()))
+	require.NoError(t, app.Start())
+
+	stateDB := dbm.NewMemDB()
+	state, err := sm.LoadStateFromDBOrGenesisDoc(stateDB, genDoc)
+	require.NoError(t, err)
+	sm.SaveState(stateDB, state)
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), app.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{})
+
+	for i := 0; i+1 < len(chain); i++ {
+		block := chain[i]
+		parts := block.MakePartSet(types.BlockPartSizeBytes)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		state, err = blockExec.ApplyBlock(state, blockID, block)
+		require.NoError(t, err)
+		blockStore.SaveBlock(block, parts, chain[i+1].LastCommit)
+	}
+
+	conR := &interopConsensusReactor{switched: make(chan sm.State, 1)}
+	conR.BaseReactor = *p2p.NewBaseReactor("InteropConsensusReactor", conR)
+	return &interopNode{
+		app:       app,
+		state:     state,
+		store:     blockStore,
+		blockExec: blockExec,
+		conR:      conR,
+	}
+}
+
+// reactor returns a fast sync reactor of the given version for the node.
+func (node *interopNode) reactor(t *testing.T, version string, fastSync bool) p2p.Reactor {
+	switch version {
+	case "v0":
+		return bcv0.NewBlockchainReactor(node.state.Copy(), node.blockExec, node.store, fastSync)
+	case "v1":
+		return bcv1.NewBlockchainReactor(node.state.Copy(), node.blockExec, node.store, fastSync)
+	}
+	t.Fatalf("unknown fast sync version %s", version)
+	return nil
+}
+
+// interopConsensusReactor records the state fast sync switched to consensus
+// with.
+type interopConsensusReactor struct {
+	p2p.BaseReactor
+
+	once     sync.Once
+	switched chan sm.State
+}
+
+func (conR *interopConsensusReactor) SwitchToConsensus(state sm.State, blocksSynced int) {
+	conR.once.Do(func() { conR.switched <- state })
+}
+
+func (conR *interopConsensusReactor) waitForSwitch(timeout time.Duration) (sm.State, bool) {
+	select {
+	case state := <-conR.switched:
+		return state, true
+	case <-time.After(timeout):
+		return sm.State{}, false
+	}
+}
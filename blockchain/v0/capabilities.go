@@ -0,0 +1,91 @@
+package v0
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tendermint/tendermint/libs/compress"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Capabilities is a set of extensions of the blockchain channel protocol.
+//
+// Nodes advertise the extensions they support in their status messages, and
+// only use an extension with peers supporting it: a request using an
+// extension is only sent to such peers, and the response to it is the only
+// message using it. Peers not advertising any capability (e.g. running an
+// older version) keep talking the base protocol, so that networks can be
+// upgraded node by node.
+type Capabilities uint64
+
+const (
+	// CapCompression is the support of compressed block requests and
+	// responses (bcCompressedBlockRequestMessage).
+	CapCompression Capabilities = 1 << iota
+
+	// CapBatchedResponses is the support of requests for several blocks,
+	// answered with several blocks per message (bcBlockBatchRequestMessage).
+	CapBatchedResponses
+
+	// CapBlockHashes is the support of the hash of the block at the requested
+	// height in status responses (bcStatusResponseMessage.RequestedHash), used
+	// to check the peers agree on the tip of the chain at the end of fast sync.
+	CapBlockHashes
+
+	// NOTE: append new capabilities; never reuse or reorder the bits.
+)
+
+// localCapabilities are the extensions supported by this node.
+const localCapabilities = CapCompression | CapBatchedResponses | CapBlockHashes
+
+var capabilityNames = []struct {
+	cap  Capabilities
+	name string
+}{
+	{CapCompression, "compression"},
+	{CapBatchedResponses, "batched_responses"},
+	{CapBlockHashes, "block_hashes"},
+}
+
+// Has returns true if c includes all of the given capabilities.
+func (c Capabilities) Has(caps Capabilities) bool {
+	return c&caps == caps
+}
+
+func (c Capabilities) String() string {
+	var names []string
+	for _, cn := range capabilityNames {
+		if c.Has(cn.cap) {
+			names = append(names, cn.name)
+			c &^= cn.cap
+		}
+	}
+	if c != 0 {
+		// capabilities of a newer version
+		names = append(names, fmt.Sprintf("%#x", uint64(c)))
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+//-------------------------------------
+
+// compressBlock returns the encoding of the block compressed by codecs. The
+// blocks are compressed with snappy, which is fast, and doesn't need a
+// dictionary the peer may not have.
+func compressBlock(codecs *compress.Registry, block *types.Block) ([]byte, error) {
+	bz, err := cdc.MarshalBinaryBare(block)
+	if err != nil {
+		return nil, err
+	}
+	return codecs.Encode(compress.Snappy(), bz)
+}
+
+// decompressBlock decodes a block compressed by compressBlock, with any codec
+// of codecs. The decompressed encoding is bounded by maxMsgSize.
+func decompressBlock(codecs *compress.Registry, data []byte) (*types.Block, error) {
+	bz, err := codecs.DecodeLimit(data, maxMsgSize)
+	if err != nil {
+		return nil, err
+	}
+	return types.DecodeBlock(bz, types.MaxDecodeLimits())
+}
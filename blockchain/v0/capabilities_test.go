@@ -0,0 +1,41 @@
+package v0
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/compress"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestCapabilitiesString(t *testing.T) {
+	assert.Equal(t, "[]", Capabilities(0).String())
+	assert.Equal(t, "[compression]", CapCompression.String())
+	assert.Equal(t, "[compression,batched_responses,block_hashes]", localCapabilities.String())
+	assert.Equal(t, "[compression,0x100]", (CapCompression | 1<<8).String())
+}
+
+func TestCapabilitiesHas(t *testing.T) {
+	caps := CapCompression | 1<<8
+	assert.True(t, caps.Has(CapCompression))
+	assert.True(t, caps.Has(0))
+	assert.False(t, Capabilities(0).Has(CapCompression))
+}
+
+func TestCompressBlock(t *testing.T) {
+	block := types.MakeBlock(3, makeTxs(3), new(types.Commit), nil)
+
+	codecs := compress.NewRegistry()
+
+	data, err := compressBlock(codecs, block)
+	require.NoError(t, err)
+
+	decompressed, err := decompressBlock(codecs, data)
+	require.NoError(t, err)
+	assert.Equal(t, block.Hash(), decompressed.Hash())
+
+	_, err = decompressBlock(codecs, []byte("not compressed"))
+	assert.Error(t, err)
+}
@@ -10,4 +10,7 @@ var cdc = amino.NewCodec()
 func init() {
 	RegisterBlockchainMessages(cdc)
 	types.RegisterBlockAmino(cdc)
+
+	bcBlockResponseMessagePrefix = cdc.MustMarshalBinaryBare(&bcBlockResponseMessage{})
+	bcBlockBatchResponseMessagePrefix = cdc.MustMarshalBinaryBare(&bcBlockBatchResponseMessage{})
 }
@@ -1,13 +1,16 @@
 package v0
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/tendermint/tendermint/blockchain/inflight"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	flow "github.com/tendermint/tendermint/libs/flowrate"
 	"github.com/tendermint/tendermint/libs/log"
@@ -29,10 +32,7 @@ eg, L = latency = 0.1s
 */
 
 const (
-	requestIntervalMS         = 2
-	maxTotalRequesters        = 600
-	maxPendingRequests        = maxTotalRequesters
-	maxPendingRequestsPerPeer = 20
+	requestIntervalMS = 2
 
 	// Minimum recv rate to ensure we're receiving blocks from a peer fast
 	// enough. If a peer is not sending us data at at least that rate, we
@@ -70,7 +70,21 @@ type BlockPool struct {
 	height     int64 // the lowest key in requesters.
 	// peers
 	peers         map[p2p.ID]*bpPeer
-	maxPeerHeight int64 // the biggest reported height
+	maxPeerHeight int64     // the biggest reported height
+	firstPeerTime time.Time // when the first of the current peers was added
+
+	// see SetPeerRequirements
+	minPeers           int
+	stabilizationDelay time.Duration
+
+	// see SetMemoryBudget
+	limits *inflight.Limits
+
+	// see SetExpectedBlockHash
+	expectedHashes map[int64][]byte
+
+	// see SetPaused
+	paused bool
 
 	// atomic
 	numPending int32 // number of requests pending assignment or block response
@@ -88,6 +102,11 @@ func NewBlockPool(start int64, requestsCh chan<- BlockRequest, errorsCh chan<- p
 		requesters: make(map[int64]*bpRequester),
 		height:     start,
 		numPending: 0,
+		minPeers:   1,
+
+		limits: inflight.NewLimits(inflight.DefaultMemoryBudget),
+
+		expectedHashes: make(map[int64][]byte),
 
 		requestsCh: requestsCh,
 		errorsCh:   errorsCh,
@@ -96,10 +115,91 @@ func NewBlockPool(start int64, requestsCh chan<- BlockRequest, errorsCh chan<- p
 	return bp
 }
 
+// SetPeerRequirements makes the pool consider itself caught up only once it
+// has at least minPeers peers, and stabilizationDelay passed since the first
+// one was added. This prevents the sync from targeting the height of the first
+// (possibly lagging or lying) peer and finishing below the real tip. It must
+// be called before the pool is started.
+func (pool *BlockPool) SetPeerRequirements(minPeers int, stabilizationDelay time.Duration) {
+	if minPeers < 1 {
+		minPeers = 1
+	}
+	pool.minPeers = minPeers
+	pool.stabilizationDelay = stabilizationDelay
+}
+
+// SetMemoryBudget sets the approximate size of the blocks the pool requests
+// or holds at a time. The number of requests follows it, based on the average
+// size of the received blocks. It must be called before the pool is started.
+func (pool *BlockPool) SetMemoryBudget(budget int64) {
+	pool.limits.SetMemoryBudget(budget)
+}
+
+// SetExpectedBlockHash sets the hash the block at height must have, when it
+// is known ahead of the block from a verified source (e.g. a verified header
+// chain, or the header following a trust anchor). The blocks received with
+// another hash are rejected by CheckBlockHash before they are validated or
+// applied, blaming the peer which sent them alone.
+func (pool *BlockPool) SetExpectedBlockHash(height int64, hash []byte) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	if height >= pool.height {
+		pool.expectedHashes[height] = hash
+	}
+}
+
+// CheckBlockHash returns an error if the hash of the block isn't the one
+// expected at its height (see SetExpectedBlockHash). Only the header is
+// hashed, so it's cheap enough to run before validating the block.
+func (pool *BlockPool) CheckBlockHash(block *types.Block) error {
+	pool.mtx.Lock()
+	expected := pool.expectedHashes[block.Height]
+	pool.mtx.Unlock()
+
+	if expected == nil {
+		return nil
+	}
+	if hash := block.Hash(); !bytes.Equal(hash, expected) {
+		return fmt.Errorf("block %d has hash %X, expected %X", block.Height, hash, expected)
+	}
+	return nil
+}
+
+// hasExpectedBlockHash returns true if the hash of the block at height was
+// checked against an expected one when the block was received.
+func (pool *BlockPool) hasExpectedBlockHash(height int64) bool {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	return pool.expectedHashes[height] != nil
+}
+
+// maxRequesters returns the number of blocks which fit in the memory budget.
+// CONTRACT: pool.mtx must be locked.
+func (pool *BlockPool) maxRequesters() int {
+	return pool.limits.MaxRequests()
+}
+
+// SetPaused pauses or resumes the pool: while paused, no new block is
+// requested, the requests in flight still being completed, and the pool isn't
+// caught up.
+func (pool *BlockPool) SetPaused(paused bool) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	pool.paused = paused
+}
+
+// IsPaused returns true if the pool is paused.
+func (pool *BlockPool) IsPaused() bool {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	return pool.paused
+}
+
 // OnStart implements cmn.Service by spawning requesters routine and recording
 // pool's start time.
 func (pool *BlockPool) OnStart() error {
-	go pool.makeRequestersRoutine()
+	pool.Go("makeRequestersRoutine", func() { pool.makeRequestersRoutine() })
 	pool.startTime = time.Now()
 	return nil
 }
@@ -111,14 +211,22 @@ func (pool *BlockPool) makeRequestersRoutine() {
 			break
 		}
 
+		if pool.IsPaused() {
+			time.Sleep(requestIntervalMS * time.Millisecond)
+			continue
+		}
+
 		_, numPending, lenRequesters := pool.GetStatus()
+		pool.mtx.Lock()
+		maxRequesters := pool.maxRequesters()
+		pool.mtx.Unlock()
 		switch {
-		case numPending >= maxPendingRequests:
+		case int(numPending) >= maxRequesters:
 			// sleep for a bit.
 			time.Sleep(requestIntervalMS * time.Millisecond)
 			// check for timed out peers
 			pool.removeTimedoutPeers()
-		case lenRequesters >= maxTotalRequesters:
+		case lenRequesters >= maxRequesters:
 			// sleep for a bit.
 			time.Sleep(requestIntervalMS * time.Millisecond)
 			// check for timed out peers
@@ -169,12 +277,25 @@ func (pool *BlockPool) IsCaughtUp() bool {
 	pool.mtx.Lock()
 	defer pool.mtx.Unlock()
 
+	if pool.paused {
+		pool.Logger.Debug("Blockpool is paused")
+		return false
+	}
+
 	// Need at least 1 peer to be considered caught up.
 	if len(pool.peers) == 0 {
 		pool.Logger.Debug("Blockpool has no peers")
 		return false
 	}
 
+	// Don't trust the max height of the first peers before we know enough of
+	// them for long enough.
+	if len(pool.peers) < pool.minPeers || time.Since(pool.firstPeerTime) < pool.stabilizationDelay {
+		pool.Logger.Debug("Waiting for the peers to stabilize", "peers", len(pool.peers),
+			"min_peers", pool.minPeers, "since", pool.firstPeerTime)
+		return false
+	}
+
 	// Some conditions to determine if we're caught up.
 	// Ensures we've either received a block or waited some amount of time,
 	// and that we're synced to the highest known height.
@@ -217,6 +338,7 @@ func (pool *BlockPool) PopRequest() {
 		*/
 		r.Stop()
 		delete(pool.requesters, pool.height)
+		delete(pool.expectedHashes, pool.height)
 		pool.height++
 	} else {
 		panic(fmt.Sprintf("Expected requester to pop, got nothing at height %v", pool.height))
@@ -267,9 +389,11 @@ func (pool *BlockPool) AddBlock(peerID p2p.ID, block *types.Block, blockSize int
 
 	if requester.setBlock(block, peerID) {
 		atomic.AddInt32(&pool.numPending, -1)
+		pool.limits.BlockReceived(blockSize)
 		peer := pool.peers[peerID]
 		if peer != nil {
 			peer.decrPending(blockSize)
+			peer.numReceived++
 		}
 	} else {
 		pool.Logger.Info("invalid peer", "peer", peerID, "blockHeight", block.Height)
@@ -277,6 +401,44 @@ func (pool *BlockPool) AddBlock(peerID p2p.ID, block *types.Block, blockSize int
 	}
 }
 
+// PeerStats returns the statistics of the pool's peers, the least useful
+// first: the ones that timed out, then the ones which don't have the next
+// block, then the ones which sent us the fewest blocks.
+func (pool *BlockPool) PeerStats() []PeerStats {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	stats := make([]PeerStats, 0, len(pool.peers))
+	for _, peer := range pool.peers {
+		stats = append(stats, PeerStats{
+			ID:          peer.id,
+			Height:      peer.height,
+			NumPending:  peer.numPending,
+			NumReceived: peer.numReceived,
+			DidTimeout:  peer.didTimeout,
+		})
+	}
+	hasNext := func(ps PeerStats) bool { return ps.Height >= pool.height }
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.DidTimeout != b.DidTimeout {
+			return a.DidTimeout
+		}
+		if hasNext(a) != hasNext(b) {
+			return !hasNext(a)
+		}
+		return a.NumReceived < b.NumReceived
+	})
+	return stats
+}
+
+// NumPeers returns the number of peers which reported their height.
+func (pool *BlockPool) NumPeers() int {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+	return len(pool.peers)
+}
+
 // MaxPeerHeight returns the highest reported height.
 func (pool *BlockPool) MaxPeerHeight() int64 {
 	pool.mtx.Lock()
@@ -284,8 +446,9 @@ func (pool *BlockPool) MaxPeerHeight() int64 {
 	return pool.maxPeerHeight
 }
 
-// SetPeerHeight sets the peer's alleged blockchain height.
-func (pool *BlockPool) SetPeerHeight(peerID p2p.ID, height int64) {
+// SetPeerStatus sets the peer's alleged blockchain height and the
+// capabilities it advertises.
+func (pool *BlockPool) SetPeerStatus(peerID p2p.ID, height int64, caps Capabilities) {
 	pool.mtx.Lock()
 	defer pool.mtx.Unlock()
 
@@ -295,14 +458,70 @@ func (pool *BlockPool) SetPeerHeight(peerID p2p.ID, height int64) {
 	} else {
 		peer = newBPPeer(pool, peerID, height)
 		peer.setLogger(pool.Logger.With("peer", peerID))
+		if len(pool.peers) == 0 {
+			pool.firstPeerTime = time.Now()
+		}
 		pool.peers[peerID] = peer
 	}
+	if peer.caps != caps {
+		peer.logger.Debug("Peer capabilities", "caps", caps)
+		peer.caps = caps
+	}
 
 	if height > pool.maxPeerHeight {
 		pool.maxPeerHeight = height
 	}
 }
 
+// SetPeerSyncPhase sets the sync phase reported by the peer. The peers which
+// are still syncing are only requested blocks when the others can't serve
+// them (see pickIncrAvailablePeer).
+func (pool *BlockPool) SetPeerSyncPhase(peerID p2p.ID, phase types.SyncPhase) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	if peer := pool.peers[peerID]; peer != nil && peer.phase != phase {
+		peer.logger.Debug("Peer sync phase", "phase", phase)
+		peer.phase = phase
+	}
+}
+
+// SetPeerBlockHash sets the hash of the block at height reported by the
+// peer. Only the latest reported hash is kept.
+func (pool *BlockPool) SetPeerBlockHash(peerID p2p.ID, height int64, hash []byte) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	if peer := pool.peers[peerID]; peer != nil {
+		peer.hashHeight, peer.hash = height, hash
+	}
+}
+
+// TipAgreement compares the hash of the block at height with the ones the
+// peers reported. It returns the number of peers which reported the same
+// hash, the peers which reported another one, and the number of peers which
+// could report it: the ones supporting CapBlockHashes which have the block.
+func (pool *BlockPool) TipAgreement(height int64, hash []byte) (agreeing int, disagreeing []p2p.ID, total int) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	for _, peer := range pool.peers {
+		if !peer.caps.Has(CapBlockHashes) || peer.height < height {
+			continue
+		}
+		total++
+		if peer.hashHeight != height {
+			continue
+		}
+		if bytes.Equal(peer.hash, hash) {
+			agreeing++
+		} else {
+			disagreeing = append(disagreeing, peer.id)
+		}
+	}
+	return agreeing, disagreeing, total
+}
+
 // RemovePeer removes the peer with peerID from the pool. If there's no peer
 // with peerID, function is a no-op.
 func (pool *BlockPool) RemovePeer(peerID p2p.ID) {
@@ -352,21 +571,33 @@ func (pool *BlockPool) pickIncrAvailablePeer(minHeight int64) *bpPeer {
 	pool.mtx.Lock()
 	defer pool.mtx.Unlock()
 
+	var syncing *bpPeer
 	for _, peer := range pool.peers {
 		if peer.didTimeout {
 			pool.removePeer(peer.id)
 			continue
 		}
-		if peer.numPending >= maxPendingRequestsPerPeer {
+		if peer.numPending >= peer.maxPending() {
 			continue
 		}
 		if peer.height < minHeight {
 			continue
 		}
+		// prefer the peers which are caught up: the syncing ones are busy
+		// applying blocks, and their height moves fast
+		if peer.phase.IsSyncing() {
+			if syncing == nil {
+				syncing = peer
+			}
+			continue
+		}
 		peer.incrPending()
 		return peer
 	}
-	return nil
+	if syncing != nil {
+		syncing.incrPending()
+	}
+	return syncing
 }
 
 func (pool *BlockPool) makeNextRequester() {
@@ -397,7 +628,20 @@ func (pool *BlockPool) sendRequest(height int64, peerID p2p.ID) {
 	if !pool.IsRunning() {
 		return
 	}
-	pool.requestsCh <- BlockRequest{height, peerID}
+	pool.requestsCh <- BlockRequest{height, peerID, pool.peerCapabilities(peerID)}
+}
+
+// peerCapabilities returns the capabilities supported by both the peer and
+// this node.
+func (pool *BlockPool) peerCapabilities(peerID p2p.ID) Capabilities {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	peer := pool.peers[peerID]
+	if peer == nil {
+		return 0
+	}
+	return peer.caps & localCapabilities
 }
 
 func (pool *BlockPool) sendError(err error, peerID p2p.ID) {
@@ -428,10 +672,30 @@ func (pool *BlockPool) debug() string {
 
 //-------------------------------------
 
+// PeerStats are statistics about a peer of the pool, for diagnostics.
+type PeerStats struct {
+	ID          p2p.ID `json:"id"`
+	Height      int64  `json:"height"`
+	NumPending  int32  `json:"num_pending"`
+	NumReceived int64  `json:"num_received"` // blocks received from the peer
+	DidTimeout  bool   `json:"did_timeout"`
+}
+
+func (ps PeerStats) String() string {
+	return fmt.Sprintf("%v{height:%d pending:%d received:%d timeout:%v}",
+		ps.ID, ps.Height, ps.NumPending, ps.NumReceived, ps.DidTimeout)
+}
+
 type bpPeer struct {
 	didTimeout  bool
 	numPending  int32
+	numReceived int64
 	height      int64
+	caps        Capabilities
+	phase       types.SyncPhase
+	throughput  *flow.Monitor // rate of the blocks received, unlike recvMonitor not reset when idle
+	hashHeight  int64         // height of the latest reported block hash
+	hash        []byte        // see SetPeerBlockHash
 	pool        *BlockPool
 	id          p2p.ID
 	recvMonitor *flow.Monitor
@@ -447,11 +711,19 @@ func newBPPeer(pool *BlockPool, peerID p2p.ID, height int64) *bpPeer {
 		id:         peerID,
 		height:     height,
 		numPending: 0,
+		throughput: inflight.NewThroughputMonitor(),
 		logger:     log.NewNopLogger(),
 	}
 	return peer
 }
 
+// maxPending returns the number of requests the peer should have pending to
+// send blocks at its throughput.
+// CONTRACT: pool.mtx must be locked.
+func (peer *bpPeer) maxPending() int32 {
+	return int32(peer.pool.limits.MaxRequestsPerPeer(peer.throughput.Status().CurRate))
+}
+
 func (peer *bpPeer) setLogger(l log.Logger) {
 	peer.logger = l
 }
@@ -479,6 +751,7 @@ func (peer *bpPeer) incrPending() {
 }
 
 func (peer *bpPeer) decrPending(recvSize int) {
+	peer.throughput.Update(recvSize)
 	peer.numPending--
 	if peer.numPending == 0 {
 		peer.timeout.Stop()
@@ -527,7 +800,7 @@ func newBPRequester(pool *BlockPool, height int64) *bpRequester {
 }
 
 func (bpr *bpRequester) OnStart() error {
-	go bpr.requestRoutine()
+	bpr.Go("requestRoutine", func() { bpr.requestRoutine() })
 	return nil
 }
 
@@ -634,8 +907,9 @@ OUTER_LOOP:
 }
 
 // BlockRequest stores a block request identified by the block Height and the PeerID responsible for
-// delivering the block
+// delivering the block, and the Capabilities to request it with
 type BlockRequest struct {
-	Height int64
-	PeerID p2p.ID
+	Height       int64
+	PeerID       p2p.ID
+	Capabilities Capabilities
 }
@@ -8,6 +8,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/tendermint/tendermint/blockchain/inflight"
+	"github.com/tendermint/tendermint/crypto/tmhash"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
@@ -93,7 +95,7 @@ func TestBlockPoolBasic(t *testing.T) {
 	// Introduce each peer.
 	go func() {
 		for _, peer := range peers {
-			pool.SetPeerHeight(peer.id, peer.height)
+			pool.SetPeerStatus(peer.id, peer.height, 0)
 		}
 	}()
 
@@ -148,7 +150,7 @@ func TestBlockPoolTimeout(t *testing.T) {
 	// Introduce each peer.
 	go func() {
 		for _, peer := range peers {
-			pool.SetPeerHeight(peer.id, peer.height)
+			pool.SetPeerStatus(peer.id, peer.height, 0)
 		}
 	}()
 
@@ -187,6 +189,188 @@ func TestBlockPoolTimeout(t *testing.T) {
 	}
 }
 
+func TestBlockPoolRequestCapabilities(t *testing.T) {
+	requestsCh := make(chan BlockRequest, 10)
+	errorsCh := make(chan peerError, 10)
+
+	pool := NewBlockPool(1, requestsCh, errorsCh)
+	pool.SetLogger(log.TestingLogger())
+
+	// an older peer, a peer like us and a newer peer
+	expected := map[p2p.ID]Capabilities{
+		"old":  0,
+		"same": CapCompression,
+		"new":  CapCompression,
+	}
+	pool.SetPeerStatus("old", 5, 0)
+	pool.SetPeerStatus("same", 5, CapCompression)
+	pool.SetPeerStatus("new", 5, CapCompression|1<<8)
+
+	err := pool.Start()
+	require.NoError(t, err)
+	defer pool.Stop()
+
+	for i := 0; i < 5; i++ {
+		request := <-requestsCh
+		assert.Equal(t, expected[request.PeerID], request.Capabilities, "peer %v", request.PeerID)
+	}
+}
+
+func TestBlockPoolPreferCaughtUpPeers(t *testing.T) {
+	pool := NewBlockPool(10, make(chan BlockRequest), make(chan peerError))
+	pool.SetLogger(log.TestingLogger())
+
+	pool.SetPeerStatus("syncing", 20, 0)
+	pool.SetPeerSyncPhase("syncing", types.SyncPhaseFastSync)
+	pool.SetPeerStatus("caught_up", 20, 0)
+	pool.SetPeerSyncPhase("caught_up", types.SyncPhaseCaughtUp)
+	pool.SetPeerStatus("old", 20, 0)
+
+	for i := 0; i < 10; i++ {
+		peer := pool.pickIncrAvailablePeer(10)
+		require.NotNil(t, peer)
+		assert.NotEqual(t, p2p.ID("syncing"), peer.id)
+		peer.numPending = 0
+	}
+
+	// the syncing peers serve the blocks nobody else can
+	pool.RemovePeer("caught_up")
+	pool.RemovePeer("old")
+	peer := pool.pickIncrAvailablePeer(10)
+	require.NotNil(t, peer)
+	assert.Equal(t, p2p.ID("syncing"), peer.id)
+}
+
+func TestBlockPoolPeerStats(t *testing.T) {
+	pool := NewBlockPool(10, make(chan BlockRequest), make(chan peerError))
+	pool.SetLogger(log.TestingLogger())
+
+	pool.SetPeerStatus("behind", 5, 0)
+	pool.SetPeerStatus("timedout", 20, 0)
+	pool.SetPeerStatus("slow", 20, 0)
+	pool.SetPeerStatus("fast", 20, 0)
+	pool.peers["timedout"].didTimeout = true
+	pool.peers["slow"].numReceived = 1
+	pool.peers["fast"].numReceived = 10
+
+	var ids []p2p.ID
+	for _, ps := range pool.PeerStats() {
+		ids = append(ids, ps.ID)
+	}
+	assert.Equal(t, []p2p.ID{"timedout", "behind", "slow", "fast"}, ids)
+}
+
+func TestBlockPoolMinPeers(t *testing.T) {
+	pool := NewBlockPool(10, make(chan BlockRequest), make(chan peerError))
+	pool.SetLogger(log.TestingLogger())
+	pool.SetPeerRequirements(2, time.Hour)
+
+	pool.SetPeerStatus("lagging", 5, 0)
+	assert.False(t, pool.IsCaughtUp(), "one peer is not enough")
+
+	pool.SetPeerStatus("other", 9, 0)
+	assert.False(t, pool.IsCaughtUp(), "the peers are not stable yet")
+
+	pool.firstPeerTime = pool.firstPeerTime.Add(-time.Hour)
+	assert.True(t, pool.IsCaughtUp())
+}
+
+func TestBlockPoolPause(t *testing.T) {
+	requestsCh := make(chan BlockRequest, 10)
+	pool := NewBlockPool(1, requestsCh, make(chan peerError, 10))
+	pool.SetLogger(log.TestingLogger())
+	pool.SetPaused(true)
+	pool.SetPeerStatus("peer", 2, 0)
+
+	err := pool.Start()
+	require.NoError(t, err)
+	defer pool.Stop()
+
+	// nothing is requested while paused
+	select {
+	case request := <-requestsCh:
+		t.Fatalf("unexpected request while paused: %v", request)
+	case <-time.After(3 * requestIntervalMS * time.Millisecond):
+	}
+	// the peer is 1 block ahead
+	assert.False(t, pool.IsCaughtUp(), "a paused pool is not caught up")
+
+	pool.SetPaused(false)
+	select {
+	case request := <-requestsCh:
+		assert.Equal(t, p2p.ID("peer"), request.PeerID)
+	case <-time.After(time.Second):
+		t.Fatal("expected a request once resumed")
+	}
+}
+
+func TestBlockPoolAdaptiveRequests(t *testing.T) {
+	pool := NewBlockPool(10, make(chan BlockRequest), make(chan peerError))
+	pool.SetLogger(log.TestingLogger())
+	pool.SetMemoryBudget(10 * 1024 * 1024)
+
+	// the number of requesters follows the size of the received blocks
+	for i := 0; i < 200; i++ {
+		pool.limits.BlockReceived(1024 * 1024)
+	}
+	assert.InDelta(t, 10, pool.maxRequesters(), 1)
+	for i := 0; i < 200; i++ {
+		pool.limits.BlockReceived(64 * 1024)
+	}
+	assert.InDelta(t, 160, pool.maxRequesters(), 1)
+
+	// the requests pending per peer follow its throughput
+	pool.SetPeerStatus("peer", 20, 0)
+	peer := pool.peers["peer"]
+	assert.EqualValues(t, inflight.MinRequestsPerPeer, peer.maxPending(), "unknown throughput")
+	peer.throughput.Update(10 * 1024 * 1024)
+	time.Sleep(1100 * time.Millisecond) // wait for a sample
+	n := peer.maxPending()
+	assert.True(t, n > inflight.MinRequestsPerPeer, "%d pending", n)
+}
+
+func TestBlockPoolTipAgreement(t *testing.T) {
+	pool := NewBlockPool(10, make(chan BlockRequest), make(chan peerError))
+	pool.SetLogger(log.TestingLogger())
+
+	hash, otherHash := tmhash.Sum([]byte("tip")), tmhash.Sum([]byte("fork"))
+	pool.SetPeerStatus("agreeing", 10, CapBlockHashes)
+	pool.SetPeerStatus("disagreeing", 11, CapBlockHashes)
+	pool.SetPeerStatus("silent", 10, CapBlockHashes)
+	pool.SetPeerStatus("behind", 9, CapBlockHashes)
+	pool.SetPeerStatus("legacy", 10, 0)
+	pool.SetPeerBlockHash("agreeing", 10, hash)
+	pool.SetPeerBlockHash("disagreeing", 10, otherHash)
+	pool.SetPeerBlockHash("silent", 9, hash)
+	pool.SetPeerBlockHash("unknown", 10, hash)
+
+	agreeing, disagreeing, total := pool.TipAgreement(10, hash)
+	assert.Equal(t, 1, agreeing)
+	assert.Equal(t, []p2p.ID{"disagreeing"}, disagreeing)
+	assert.Equal(t, 3, total, "peers behind or not supporting CapBlockHashes can't tell")
+}
+
+func TestBlockPoolExpectedBlockHash(t *testing.T) {
+	pool := NewBlockPool(10, make(chan BlockRequest), make(chan peerError))
+	pool.SetLogger(log.TestingLogger())
+
+	valsHash := tmhash.Sum([]byte("vals"))
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+	block := types.MakeBlock(11, nil, lastCommit, nil)
+	block.ChainID, block.ValidatorsHash = "chain", valsHash
+	other := types.MakeBlock(11, nil, lastCommit, nil)
+	other.ChainID, other.ValidatorsHash = "fork", valsHash
+	require.NotEqual(t, block.Hash(), other.Hash())
+	assert.NoError(t, pool.CheckBlockHash(other), "no expected hash")
+
+	pool.SetExpectedBlockHash(11, block.Hash())
+	pool.SetExpectedBlockHash(9, other.Hash()) // already synced, ignored
+	assert.NoError(t, pool.CheckBlockHash(block))
+	assert.Error(t, pool.CheckBlockHash(other))
+	assert.True(t, pool.hasExpectedBlockHash(11))
+	assert.False(t, pool.hasExpectedBlockHash(9))
+}
+
 func TestBlockPoolRemovePeer(t *testing.T) {
 	peers := make(testPeers, 10)
 	for i := 0; i < 10; i++ {
@@ -205,7 +389,7 @@ func TestBlockPoolRemovePeer(t *testing.T) {
 
 	// add peers
 	for peerID, peer := range peers {
-		pool.SetPeerHeight(peerID, peer.height)
+		pool.SetPeerStatus(peerID, peer.height, 0)
 	}
 	assert.EqualValues(t, 10, pool.MaxPeerHeight())
 
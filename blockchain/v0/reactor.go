@@ -1,17 +1,21 @@
 package v0
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"reflect"
+	"sync/atomic"
 	"time"
 
 	amino "github.com/tendermint/go-amino"
 
+	"github.com/tendermint/tendermint/blockchain/inflight"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"github.com/tendermint/tendermint/libs/compress"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
 	sm "github.com/tendermint/tendermint/state"
-	"github.com/tendermint/tendermint/store"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -29,6 +33,8 @@ const (
 	statusUpdateIntervalSeconds = 10
 	// check if we should switch to consensus reactor
 	switchToConsensusIntervalSeconds = 1
+	// check if a peer is ahead of us during the tip grace period
+	tipCheckIntervalMS = 100
 
 	// NOTE: keep up to date with bcBlockResponseMessage
 	bcBlockResponseMessagePrefixSize   = 4
@@ -36,8 +42,16 @@ const (
 	maxMsgSize                         = types.MaxBlockSizeBytes +
 		bcBlockResponseMessagePrefixSize +
 		bcBlockResponseMessageFieldKeySize
+
+	// maximum number of heights in a bcBlockBatchRequestMessage
+	maxBatchRequestHeights = 100
+	// maximum size of the blocks in a bcBlockBatchResponseMessage; bigger
+	// blocks are sent alone in a bcBlockResponseMessage
+	maxBatchResponseBytes = 1024 * 1024
 )
 
+var errNoProgress = errors.New("fast sync made no progress")
+
 type consensusReactor interface {
 	// for when we switch from blockchain reactor and fast sync to
 	// the consensus machine
@@ -53,6 +67,11 @@ func (e peerError) Error() string {
 	return fmt.Sprintf("error with peer %v: %s", e.peerID, e.err.Error())
 }
 
+// peerSolicitor is implemented by the PEX reactor.
+type peerSolicitor interface {
+	EnsurePeers()
+}
+
 // BlockchainReactor handles long-term catchup syncing.
 type BlockchainReactor struct {
 	p2p.BaseReactor
@@ -61,24 +80,103 @@ type BlockchainReactor struct {
 	initialState sm.State
 
 	blockExec *sm.BlockExecutor
-	store     *store.BlockStore
+	store     sm.BlockStore
 	pool      *BlockPool
 	fastSync  bool
+	syncing   int32 // 1 until switched to consensus; atomic, see syncPhase
 
 	requestsCh <-chan BlockRequest
 	errorsCh   <-chan peerError
+
+	// see ReactorNoProgressTimeout
+	noProgressTimeout time.Duration
+	// see ReactorTipGracePeriod
+	tipGracePeriod time.Duration
+	// see ReactorBandwidthLimits; nil if unlimited
+	uploadLimiter   *bandwidthLimiter
+	downloadLimiter *bandwidthLimiter
+
+	// compress the blocks of bcCompressedBlockResponseMessages
+	codecs *compress.Registry
+}
+
+// ReactorOption sets an optional parameter on the BlockchainReactor.
+type ReactorOption func(*BlockchainReactor)
+
+// ReactorNoProgressTimeout makes the reactor recover from a stuck fast sync:
+// when the height didn't move for timeout, it logs the state of the pool,
+// disconnects the least useful half of its peers and makes the PEX reactor
+// look for new ones right away. 0 disables it.
+func ReactorNoProgressTimeout(timeout time.Duration) ReactorOption {
+	return func(bcR *BlockchainReactor) { bcR.noProgressTimeout = timeout }
+}
+
+// ReactorTipGracePeriod makes the reactor wait up to period for the heights
+// of its first peers before fast syncing: if none of them is ahead of the
+// block store, fast sync is skipped and the reactor switches to consensus
+// right away. Fast sync starts as soon as a peer ahead of us shows up. 0
+// disables it.
+func ReactorTipGracePeriod(period time.Duration) ReactorOption {
+	return func(bcR *BlockchainReactor) { bcR.tipGracePeriod = period }
+}
+
+// ReactorMinPeers makes the reactor wait for at least minPeers peers, and for
+// stabilizationDelay after the first one reported its height, before it
+// considers fast sync done (see BlockPool#SetPeerRequirements). The tip grace
+// period also requires minPeers peers.
+func ReactorMinPeers(minPeers int, stabilizationDelay time.Duration) ReactorOption {
+	return func(bcR *BlockchainReactor) { bcR.pool.SetPeerRequirements(minPeers, stabilizationDelay) }
+}
+
+// ReactorMemoryBudget sets the approximate size of the blocks requested or
+// received but not applied yet (see BlockPool#SetMemoryBudget).
+func ReactorMemoryBudget(budget int64) ReactorOption {
+	return func(bcR *BlockchainReactor) { bcR.pool.SetMemoryBudget(budget) }
+}
+
+// ReactorExpectedBlockHashes sets the hashes of the blocks known ahead of
+// them from a verified source, by height (see BlockPool#SetExpectedBlockHash).
+// The blocks received with another hash are rejected before being validated.
+func ReactorExpectedBlockHashes(hashes map[int64][]byte) ReactorOption {
+	return func(bcR *BlockchainReactor) {
+		for height, hash := range hashes {
+			bcR.pool.SetExpectedBlockHash(height, hash)
+		}
+	}
+}
+
+// ReactorBandwidthLimits limits the rates of the blocks the reactor sends to
+// and receives from peers, so that serving or fast syncing blocks doesn't
+// saturate the link of the node and delay consensus gossip.
+//
+// Block responses are delayed to honour the upload limits (and dropped past
+// maxUploadDelay), and block requests are delayed until the blocks already
+// received fit in the download limits. Peers sending blocks slower than
+// minRecvRate are dropped by the pool, so the limits should leave more than
+// that to each peer.
+func ReactorBandwidthLimits(limits BandwidthLimits) ReactorOption {
+	return func(bcR *BlockchainReactor) {
+		bcR.uploadLimiter = newBandwidthLimiter(limits.UploadRate, limits.UploadRatePerPeer)
+		bcR.downloadLimiter = newBandwidthLimiter(limits.DownloadRate, limits.DownloadRatePerPeer)
+	}
+}
+
+// ReactorCodecs sets the codecs compressing the blocks sent to and received
+// from peers, e.g. to share the ones of the block store.
+func ReactorCodecs(codecs *compress.Registry) ReactorOption {
+	return func(bcR *BlockchainReactor) { bcR.codecs = codecs }
 }
 
 // NewBlockchainReactor returns new reactor instance.
-func NewBlockchainReactor(state sm.State, blockExec *sm.BlockExecutor, store *store.BlockStore,
-	fastSync bool) *BlockchainReactor {
+func NewBlockchainReactor(state sm.State, blockExec *sm.BlockExecutor, store sm.BlockStore,
+	fastSync bool, options ...ReactorOption) *BlockchainReactor {
 
 	if state.LastBlockHeight != store.Height() {
 		panic(fmt.Sprintf("state (%v) and store (%v) height mismatch", state.LastBlockHeight,
 			store.Height()))
 	}
 
-	requestsCh := make(chan BlockRequest, maxTotalRequesters)
+	requestsCh := make(chan BlockRequest, inflight.MaxRequests)
 
 	const capacity = 1000                      // must be bigger than peers count
 	errorsCh := make(chan peerError, capacity) // so we don't block in #Receive#pool.AddBlock
@@ -97,8 +195,15 @@ func NewBlockchainReactor(state sm.State, blockExec *sm.BlockExecutor, store *st
 		fastSync:     fastSync,
 		requestsCh:   requestsCh,
 		errorsCh:     errorsCh,
+		codecs:       compress.NewRegistry(),
+	}
+	if fastSync {
+		bcR.syncing = 1
 	}
 	bcR.BaseReactor = *p2p.NewBaseReactor("BlockchainReactor", bcR)
+	for _, option := range options {
+		option(bcR)
+	}
 	return bcR
 }
 
@@ -111,15 +216,85 @@ func (bcR *BlockchainReactor) SetLogger(l log.Logger) {
 // OnStart implements cmn.Service.
 func (bcR *BlockchainReactor) OnStart() error {
 	if bcR.fastSync {
-		err := bcR.pool.Start()
-		if err != nil {
-			return err
+		if bcR.tipGracePeriod > 0 {
+			bcR.Go("tipCheckRoutine", bcR.tipCheckRoutine)
+			return nil
 		}
-		go bcR.poolRoutine()
+		return bcR.startFastSync()
+	}
+	return nil
+}
+
+func (bcR *BlockchainReactor) startFastSync() error {
+	err := bcR.pool.Start()
+	if err != nil {
+		return err
 	}
+	bcR.Go("poolRoutine", func() { bcR.poolRoutine() })
 	return nil
 }
 
+// tipCheckRoutine collects the heights of the peers for tipGracePeriod. If
+// no peer is ahead of the block store by then, the node is already at the tip
+// of the network (e.g. a restarted validator) and switches to consensus
+// without fast syncing. Otherwise, fast sync starts as usual.
+func (bcR *BlockchainReactor) tipCheckRoutine() {
+	height := bcR.store.Height()
+	deadline := time.NewTimer(bcR.tipGracePeriod)
+	defer deadline.Stop()
+	ticker := time.NewTicker(tipCheckIntervalMS * time.Millisecond)
+	defer ticker.Stop()
+
+FOR_LOOP:
+	for {
+		select {
+		case <-bcR.Quit():
+			return
+		case <-ticker.C:
+			if bcR.pool.MaxPeerHeight() > height {
+				break FOR_LOOP
+			}
+		case <-deadline.C:
+			numPeers := bcR.pool.NumPeers()
+			if numPeers < bcR.pool.minPeers || bcR.pool.MaxPeerHeight() > height {
+				break FOR_LOOP
+			}
+			bcR.Logger.Info("Already at the tip of the network, skipping fast sync",
+				"height", height, "peers", numPeers)
+			bcR.switchToConsensus(bcR.initialState, 0)
+			return
+		}
+	}
+
+	if err := bcR.startFastSync(); err != nil {
+		bcR.Logger.Error("Failed to start fast sync", "err", err)
+	}
+}
+
+// switchToConsensus hands over to the consensus reactor, and tells the peers
+// we're caught up, so they use us as a source of blocks and gossip consensus
+// messages to us.
+func (bcR *BlockchainReactor) switchToConsensus(state sm.State, blocksSynced int) {
+	bcR.blockExec.StopBatchedWrites()
+	conR, ok := bcR.Switch.Reactor("CONSENSUS").(consensusReactor)
+	if ok {
+		conR.SwitchToConsensus(state, blocksSynced)
+	}
+	// else {
+	// should only happen during testing
+	// }
+	atomic.StoreInt32(&bcR.syncing, 0)
+	bcR.Switch.Broadcast(BlockchainChannel, bcR.statusResponse(0))
+}
+
+// syncPhase returns the sync phase we advertise to peers.
+func (bcR *BlockchainReactor) syncPhase() types.SyncPhase {
+	if atomic.LoadInt32(&bcR.syncing) == 1 {
+		return types.SyncPhaseFastSync
+	}
+	return types.SyncPhaseCaughtUp
+}
+
 // OnStop implements cmn.Service.
 func (bcR *BlockchainReactor) OnStop() {
 	bcR.pool.Stop()
@@ -140,39 +315,128 @@ func (bcR *BlockchainReactor) GetChannels() []*p2p.ChannelDescriptor {
 
 // AddPeer implements Reactor by sending our state to peer.
 func (bcR *BlockchainReactor) AddPeer(peer p2p.Peer) {
-	msgBytes := cdc.MustMarshalBinaryBare(&bcStatusResponseMessage{bcR.store.Height()})
-	peer.Send(BlockchainChannel, msgBytes)
+	peer.Send(BlockchainChannel, bcR.statusResponse(0))
 	// it's OK if send fails. will try later in poolRoutine
 
 	// peer is added to the pool once we receive the first
-	// bcStatusResponseMessage from the peer and call pool.SetPeerHeight
+	// bcStatusResponseMessage from the peer and call pool.SetPeerStatus
 }
 
 // RemovePeer implements Reactor by removing peer from the pool.
 func (bcR *BlockchainReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
 	bcR.pool.RemovePeer(peer.ID())
+	if bcR.uploadLimiter != nil {
+		bcR.uploadLimiter.removePeer(peer.ID())
+	}
+	if bcR.downloadLimiter != nil {
+		bcR.downloadLimiter.removePeer(peer.ID())
+	}
 }
 
 // respondToPeer loads a block and sends it to the requesting peer,
 // if we have it. Otherwise, we'll respond saying we don't have it.
 // According to the Tendermint spec, if all nodes are honest,
 // no node should be requesting for a block that's non-existent.
-func (bcR *BlockchainReactor) respondToPeer(msg *bcBlockRequestMessage,
+// The block is compressed if the peer asked for it with compress.
+func (bcR *BlockchainReactor) respondToPeer(height int64, compress bool,
 	src p2p.Peer) (queued bool) {
 
-	block := bcR.store.LoadBlock(msg.Height)
+	block := bcR.store.LoadBlock(height)
 	if block != nil {
-		msgBytes := cdc.MustMarshalBinaryBare(&bcBlockResponseMessage{Block: block})
+		var msg BlockchainMessage = &bcBlockResponseMessage{Block: block}
+		if compress {
+			data, err := compressBlock(bcR.codecs, block)
+			if err != nil {
+				bcR.Logger.Error("Failed to compress block", "height", height, "err", err)
+				return false
+			}
+			msg = &bcCompressedBlockResponseMessage{Height: height, Data: data}
+		}
+		return bcR.sendResponse(src, cdc.MustMarshalBinaryBare(msg))
+	}
+
+	bcR.Logger.Info("Peer asking for a block we don't have", "src", src, "height", height)
+
+	msgBytes := cdc.MustMarshalBinaryBare(&bcNoBlockResponseMessage{Height: height})
+	return bcR.sendResponse(src, msgBytes)
+}
+
+// sendResponse sends a response to a block request, after the delay required
+// by the upload limits. It returns false if the response was dropped.
+func (bcR *BlockchainReactor) sendResponse(src p2p.Peer, msgBytes []byte) (queued bool) {
+	if bcR.uploadLimiter == nil {
 		return src.TrySend(BlockchainChannel, msgBytes)
 	}
+	if delay := bcR.uploadLimiter.delay(src.ID()); delay > maxUploadDelay {
+		bcR.Logger.Debug("Upload limit reached, drop block response", "peer", src, "delay", delay)
+		return false
+	}
+	delay := bcR.uploadLimiter.reserve(src.ID(), len(msgBytes))
+	if delay == 0 {
+		return src.TrySend(BlockchainChannel, msgBytes)
+	}
+	time.AfterFunc(delay, func() {
+		if bcR.IsRunning() {
+			src.TrySend(BlockchainChannel, msgBytes)
+		}
+	})
+	return true
+}
 
-	bcR.Logger.Info("Peer asking for a block we don't have", "src", src, "height", msg.Height)
+// respondWithBatch sends the blocks at the given heights to the requesting
+// peer, in as few bcBlockBatchResponseMessages as maxBatchResponseBytes
+// allows. Missing blocks are answered with bcNoBlockResponseMessages.
+func (bcR *BlockchainReactor) respondWithBatch(heights []int64, src p2p.Peer) {
+	var (
+		batch []*types.Block
+		size  int
+	)
+	flush := func() {
+		if len(batch) > 0 {
+			bcR.sendResponse(src, cdc.MustMarshalBinaryBare(&bcBlockBatchResponseMessage{Blocks: batch}))
+			batch, size = nil, 0
+		}
+	}
+
+	for _, height := range heights {
+		block := bcR.store.LoadBlock(height)
+		if block == nil {
+			bcR.respondToPeer(height, false, src)
+			continue
+		}
+		blockSize := block.Size()
+		if blockSize > maxBatchResponseBytes {
+			bcR.sendResponse(src, cdc.MustMarshalBinaryBare(&bcBlockResponseMessage{Block: block}))
+			continue
+		}
+		if size+blockSize > maxBatchResponseBytes {
+			flush()
+		}
+		batch = append(batch, block)
+		size += blockSize
+	}
+	flush()
+}
 
-	msgBytes := cdc.MustMarshalBinaryBare(&bcNoBlockResponseMessage{Height: msg.Height})
-	return src.TrySend(BlockchainChannel, msgBytes)
+// statusResponse returns our status, to send to peers, with the hash of the
+// block at requestedHeight if we have it (0 for none).
+func (bcR *BlockchainReactor) statusResponse(requestedHeight int64) []byte {
+	msg := &bcStatusResponseMessage{
+		Height:       bcR.store.Height(),
+		Base:         bcR.store.Base(),
+		Capabilities: localCapabilities,
+		SyncPhase:    bcR.syncPhase(),
+	}
+	if requestedHeight > 0 && requestedHeight <= msg.Height {
+		if meta := bcR.store.LoadBlockMeta(requestedHeight); meta != nil {
+			msg.RequestedHeight = requestedHeight
+			msg.RequestedHash = meta.BlockID.Hash
+		}
+	}
+	return cdc.MustMarshalBinaryBare(msg)
 }
 
-// Receive implements Reactor by handling 4 types of messages (look below).
+// Receive implements Reactor by handling 8 types of messages (look below).
 func (bcR *BlockchainReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
 	msg, err := decodeMsg(msgBytes)
 	if err != nil {
@@ -181,6 +445,14 @@ func (bcR *BlockchainReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 		return
 	}
 
+	// reject the blocks which don't have the expected hash before validating
+	// them, so that only their sender is blamed
+	if err = bcR.checkBlockHashes(msg); err != nil {
+		bcR.Logger.Error("Peer sent us an unexpected block", "peer", src, "msg", msg, "err", err)
+		bcR.Switch.StopPeerForError(src, err)
+		return
+	}
+
 	if err = msg.ValidateBasic(); err != nil {
 		bcR.Logger.Error("Peer sent us invalid msg", "peer", src, "msg", msg, "err", err)
 		bcR.Switch.StopPeerForError(src, err)
@@ -189,23 +461,82 @@ func (bcR *BlockchainReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 
 	bcR.Logger.Debug("Receive", "src", src, "chID", chID, "msg", msg)
 
+	if bcR.downloadLimiter != nil {
+		switch msg.(type) {
+		case *bcBlockResponseMessage, *bcCompressedBlockResponseMessage, *bcBlockBatchResponseMessage:
+			// the next requests to the peer wait for the blocks to fit in
+			// the limits (see sendBlockRequest)
+			bcR.downloadLimiter.reserve(src.ID(), len(msgBytes))
+		}
+	}
+
 	switch msg := msg.(type) {
 	case *bcBlockRequestMessage:
-		bcR.respondToPeer(msg, src)
+		bcR.respondToPeer(msg.Height, false, src)
+	case *bcCompressedBlockRequestMessage:
+		bcR.respondToPeer(msg.Height, true, src)
+	case *bcBlockBatchRequestMessage:
+		bcR.respondWithBatch(msg.Heights, src)
 	case *bcBlockResponseMessage:
 		bcR.pool.AddBlock(src.ID(), msg.Block, len(msgBytes))
+	case *bcCompressedBlockResponseMessage:
+		block, err := decompressBlock(bcR.codecs, msg.Data)
+		if err == nil && block.Height != msg.Height {
+			err = fmt.Errorf("expected block %d, got %d", msg.Height, block.Height)
+		}
+		if err == nil {
+			err = bcR.pool.CheckBlockHash(block)
+		}
+		if err == nil {
+			err = block.ValidateBasic()
+		}
+		if err != nil {
+			bcR.Logger.Error("Peer sent us an invalid compressed block", "peer", src, "msg", msg, "err", err)
+			bcR.Switch.StopPeerForError(src, err)
+			return
+		}
+		bcR.pool.AddBlock(src.ID(), block, len(msgBytes))
+	case *bcBlockBatchResponseMessage:
+		for _, block := range msg.Blocks {
+			bcR.pool.AddBlock(src.ID(), block, len(msgBytes)/len(msg.Blocks))
+		}
 	case *bcStatusRequestMessage:
 		// Send peer our state.
-		msgBytes := cdc.MustMarshalBinaryBare(&bcStatusResponseMessage{bcR.store.Height()})
-		src.TrySend(BlockchainChannel, msgBytes)
+		src.TrySend(BlockchainChannel, bcR.statusResponse(msg.Height))
 	case *bcStatusResponseMessage:
 		// Got a peer status. Unverified.
-		bcR.pool.SetPeerHeight(src.ID(), msg.Height)
+		bcR.pool.SetPeerStatus(src.ID(), msg.Height, msg.Capabilities)
+		bcR.pool.SetPeerSyncPhase(src.ID(), msg.SyncPhase)
+		src.Set(types.PeerSyncPhaseKey, msg.SyncPhase)
+		if len(msg.RequestedHash) > 0 {
+			bcR.pool.SetPeerBlockHash(src.ID(), msg.RequestedHeight, msg.RequestedHash)
+		}
 	default:
 		bcR.Logger.Error(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
 	}
 }
 
+// checkBlockHashes checks the hashes of the blocks of a block response
+// against the expected ones (see BlockPool#SetExpectedBlockHash).
+func (bcR *BlockchainReactor) checkBlockHashes(msg BlockchainMessage) error {
+	var blocks []*types.Block
+	switch msg := msg.(type) {
+	case *bcBlockResponseMessage:
+		blocks = []*types.Block{msg.Block}
+	case *bcBlockBatchResponseMessage:
+		blocks = msg.Blocks
+	}
+	for _, block := range blocks {
+		if block == nil {
+			continue // rejected by ValidateBasic
+		}
+		if err := bcR.pool.CheckBlockHash(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Handle messages from the poolReactor telling the reactor what to do.
 // NOTE: Don't sleep in the FOR_LOOP or otherwise slow it down!
 func (bcR *BlockchainReactor) poolRoutine() {
@@ -224,7 +555,15 @@ func (bcR *BlockchainReactor) poolRoutine() {
 
 	didProcessCh := make(chan struct{}, 1)
 
-	go func() {
+	var watchdogCh <-chan time.Time
+	lastProgress, lastProgressHeight := time.Now(), state.LastBlockHeight
+	if bcR.noProgressTimeout > 0 {
+		watchdogTicker := time.NewTicker(bcR.noProgressTimeout / 4)
+		defer watchdogTicker.Stop()
+		watchdogCh = watchdogTicker.C
+	}
+
+	bcR.Go("sendRoutine", func() {
 		for {
 			select {
 			case <-bcR.Quit():
@@ -232,15 +571,7 @@ func (bcR *BlockchainReactor) poolRoutine() {
 			case <-bcR.pool.Quit():
 				return
 			case request := <-bcR.requestsCh:
-				peer := bcR.Switch.Peers().Get(request.PeerID)
-				if peer == nil {
-					continue
-				}
-				msgBytes := cdc.MustMarshalBinaryBare(&bcBlockRequestMessage{request.Height})
-				queued := peer.TrySend(BlockchainChannel, msgBytes)
-				if !queued {
-					bcR.Logger.Debug("Send queue is full, drop block request", "peer", peer.ID(), "height", request.Height)
-				}
+				bcR.sendBlockRequests(request)
 			case err := <-bcR.errorsCh:
 				peer := bcR.Switch.Peers().Get(err.peerID)
 				if peer != nil {
@@ -249,11 +580,11 @@ func (bcR *BlockchainReactor) poolRoutine() {
 
 			case <-statusUpdateTicker.C:
 				// ask for status updates
-				go bcR.BroadcastStatusRequest() // nolint: errcheck
+				bcR.Go("BroadcastStatusRequest", func() { bcR.BroadcastStatusRequest() }) // nolint: errcheck
 
 			}
 		}
-	}()
+	})
 
 FOR_LOOP:
 	for {
@@ -263,20 +594,21 @@ FOR_LOOP:
 			outbound, inbound, _ := bcR.Switch.NumPeers()
 			bcR.Logger.Debug("Consensus ticker", "numPending", numPending, "total", lenRequesters,
 				"outbound", outbound, "inbound", inbound)
-			if bcR.pool.IsCaughtUp() {
+			if bcR.pool.IsCaughtUp() && bcR.isTipConfirmed(state) {
 				bcR.Logger.Info("Time to switch to consensus reactor!", "height", height)
 				bcR.pool.Stop()
-				conR, ok := bcR.Switch.Reactor("CONSENSUS").(consensusReactor)
-				if ok {
-					conR.SwitchToConsensus(state, blocksSynced)
-				}
-				// else {
-				// should only happen during testing
-				// }
-
+				bcR.switchToConsensus(state, blocksSynced)
 				break FOR_LOOP
 			}
 
+		case <-watchdogCh:
+			if state.LastBlockHeight != lastProgressHeight || bcR.pool.IsPaused() {
+				lastProgress, lastProgressHeight = time.Now(), state.LastBlockHeight
+			} else if time.Since(lastProgress) >= bcR.noProgressTimeout {
+				bcR.recoverFromNoProgress(time.Since(lastProgress))
+				lastProgress = time.Now()
+			}
+
 		case <-trySyncTicker.C: // chan time
 			select {
 			case didProcessCh <- struct{}{}:
@@ -284,6 +616,10 @@ FOR_LOOP:
 			}
 
 		case <-didProcessCh:
+			if bcR.pool.IsPaused() {
+				continue FOR_LOOP
+			}
+
 			// NOTE: It is a subtle mistake to process more than a single block
 			// at a time (e.g. 10) here, because we only TrySend 1 request per
 			// loop.  The ratio mismatch can result in starving of blocks, a
@@ -310,16 +646,27 @@ FOR_LOOP:
 			// NOTE: we can probably make this more efficient, but note that calling
 			// first.Hash() doesn't verify the tx contents, so MakePartSet() is
 			// currently necessary.
-			err := state.Validators.VerifyCommit(
-				chainID, firstID, first.Height, second.LastCommit)
+			err := checkChainLinks(state, first)
+			badCommitOnly := false
+			if err == nil {
+				err = state.Validators.VerifyCommit(
+					chainID, firstID, first.Height, second.LastCommit)
+				// the first block is the right one if its hash was checked
+				// against an expected one: only the commit of the second
+				// block can be wrong
+				badCommitOnly = err != nil && bcR.pool.hasExpectedBlockHash(first.Height)
+			}
 			if err != nil {
 				bcR.Logger.Error("Error in validation", "err", err)
-				peerID := bcR.pool.RedoRequest(first.Height)
-				peer := bcR.Switch.Peers().Get(peerID)
-				if peer != nil {
-					// NOTE: we've already removed the peer's request, but we
-					// still need to clean up the rest.
-					bcR.Switch.StopPeerForError(peer, fmt.Errorf("BlockchainReactor validation error: %v", err))
+				var peer p2p.Peer
+				if !badCommitOnly {
+					peerID := bcR.pool.RedoRequest(first.Height)
+					peer = bcR.Switch.Peers().Get(peerID)
+					if peer != nil {
+						// NOTE: we've already removed the peer's request, but we
+						// still need to clean up the rest.
+						bcR.Switch.StopPeerForError(peer, fmt.Errorf("BlockchainReactor validation error: %v", err))
+					}
 				}
 				peerID2 := bcR.pool.RedoRequest(second.Height)
 				peer2 := bcR.Switch.Peers().Get(peerID2)
@@ -358,6 +705,186 @@ FOR_LOOP:
 			break FOR_LOOP
 		}
 	}
+
+	// write the state of the last synced blocks
+	bcR.blockExec.StopBatchedWrites()
+}
+
+// checkChainLinks checks that the block follows the last block of the state,
+// and has its validators. The commit only proves the validators signed the
+// block, this proves it is the next block of our chain. The last block may be
+// a trust anchor (see FastSyncConfig#TrustHeight) rather than a block the node
+// verified, in which case the first block is verified against it.
+func checkChainLinks(state sm.State, block *types.Block) error {
+	if !block.LastBlockID.Equals(state.LastBlockID) {
+		return fmt.Errorf("block %d doesn't follow our last block: expected LastBlockID %v, got %v",
+			block.Height, state.LastBlockID, block.LastBlockID)
+	}
+	if !bytes.Equal(block.ValidatorsHash, state.Validators.Hash()) {
+		return fmt.Errorf("block %d has unexpected ValidatorsHash %X", block.Height, block.ValidatorsHash)
+	}
+	if !bytes.Equal(block.NextValidatorsHash, state.NextValidators.Hash()) {
+		return fmt.Errorf("block %d has unexpected NextValidatorsHash %X", block.Height, block.NextValidatorsHash)
+	}
+	return nil
+}
+
+// isTipConfirmed returns true if more than 2/3 of the peers which can tell
+// (see BlockPool#TipAgreement) reported the hash of our last block. Otherwise,
+// it asks the peers for the hash again, and fast sync goes on, so that the
+// node doesn't switch to consensus on a minority fork served by a single
+// peer. Fast sync isn't held back by peers not supporting CapBlockHashes.
+func (bcR *BlockchainReactor) isTipConfirmed(state sm.State) bool {
+	height, hash := state.LastBlockHeight, state.LastBlockID.Hash
+	if height == 0 {
+		return true
+	}
+	agreeing, disagreeing, total := bcR.pool.TipAgreement(height, hash)
+	if total == 0 || agreeing*3 > total*2 {
+		return true
+	}
+
+	if len(disagreeing) > 0 {
+		bcR.Logger.Error("Peers disagree on the hash of the last synced block, syncing on",
+			"height", height, "hash", hash, "agreeing", agreeing, "disagreeing", disagreeing, "total", total)
+	} else {
+		bcR.Logger.Info("Waiting for peers to confirm the hash of the last synced block",
+			"height", height, "agreeing", agreeing, "total", total)
+	}
+	bcR.Go("BroadcastStatusRequest", func() { bcR.BroadcastStatusRequest() }) // nolint: errcheck
+	return false
+}
+
+// recoverFromNoProgress logs the state of the pool, disconnects the least
+// useful half of the peers and looks for new ones.
+func (bcR *BlockchainReactor) recoverFromNoProgress(stalled time.Duration) {
+	height, numPending, lenRequesters := bcR.pool.GetStatus()
+	stats := bcR.pool.PeerStats()
+	bcR.Logger.Error("Fast sync made no progress, rotating peers", "height", height, "stalled", stalled,
+		"max_peer_height", bcR.pool.MaxPeerHeight(), "num_pending", numPending,
+		"requesters", lenRequesters, "peers", stats)
+
+	for _, ps := range stats[:(len(stats)+1)/2] {
+		if peer := bcR.Switch.Peers().Get(ps.ID); peer != nil {
+			bcR.Switch.StopPeerForError(peer, errNoProgress)
+		}
+	}
+	if pex, ok := bcR.Switch.Reactor("PEX").(peerSolicitor); ok {
+		pex.EnsurePeers()
+	}
+}
+
+// sendBlockRequests sends the request, and the other ones already made by the
+// pool. The requests to a peer supporting CapBatchedResponses are sent in a
+// single bcBlockBatchRequestMessage.
+func (bcR *BlockchainReactor) sendBlockRequests(request BlockRequest) {
+	requests := []BlockRequest{request}
+DRAIN_LOOP:
+	for len(requests) < maxBatchRequestHeights {
+		select {
+		case request := <-bcR.requestsCh:
+			requests = append(requests, request)
+		default:
+			break DRAIN_LOOP
+		}
+	}
+
+	var (
+		batches    = make(map[p2p.ID][]int64)
+		batchPeers []p2p.ID // in the order of their first request
+	)
+	for _, request := range requests {
+		if !request.Capabilities.Has(CapBatchedResponses) {
+			// use the other extensions the peer supports
+			var msg BlockchainMessage = &bcBlockRequestMessage{request.Height}
+			if request.Capabilities.Has(CapCompression) {
+				msg = &bcCompressedBlockRequestMessage{request.Height}
+			}
+			bcR.sendBlockRequest(request.PeerID, msg)
+			continue
+		}
+		if _, ok := batches[request.PeerID]; !ok {
+			batchPeers = append(batchPeers, request.PeerID)
+		}
+		batches[request.PeerID] = append(batches[request.PeerID], request.Height)
+	}
+	for _, peerID := range batchPeers {
+		bcR.sendBlockRequest(peerID, &bcBlockBatchRequestMessage{Heights: batches[peerID]})
+	}
+}
+
+// sendBlockRequest sends the request once the blocks received from the peer
+// fit in the download limits.
+func (bcR *BlockchainReactor) sendBlockRequest(peerID p2p.ID, msg BlockchainMessage) {
+	if bcR.downloadLimiter != nil {
+		if delay := bcR.downloadLimiter.delay(peerID); delay > 0 {
+			time.AfterFunc(delay, func() {
+				if bcR.IsRunning() {
+					bcR.trySendBlockRequest(peerID, msg)
+				}
+			})
+			return
+		}
+	}
+	bcR.trySendBlockRequest(peerID, msg)
+}
+
+func (bcR *BlockchainReactor) trySendBlockRequest(peerID p2p.ID, msg BlockchainMessage) {
+	peer := bcR.Switch.Peers().Get(peerID)
+	if peer == nil {
+		return
+	}
+	queued := peer.TrySend(BlockchainChannel, cdc.MustMarshalBinaryBare(msg))
+	if !queued {
+		bcR.Logger.Debug("Send queue is full, drop block request", "peer", peerID, "msg", msg)
+	}
+}
+
+// SyncStatus is the state of the fast sync, as reported by SyncStatusJSON.
+type SyncStatus struct {
+	Syncing       bool        `json:"syncing"`
+	Paused        bool        `json:"paused"`
+	Height        int64       `json:"height"`
+	MaxPeerHeight int64       `json:"max_peer_height"`
+	NumPending    int32       `json:"num_pending"`
+	NumRequesters int         `json:"num_requesters"`
+	Peers         []PeerStats `json:"peers"` // the least useful first
+}
+
+// SyncStatusJSON returns the JSON encoded SyncStatus of the fast sync.
+func (bcR *BlockchainReactor) SyncStatusJSON() ([]byte, error) {
+	height, numPending, numRequesters := bcR.pool.GetStatus()
+	return cdc.MarshalJSON(SyncStatus{
+		Syncing:       bcR.pool.IsRunning(),
+		Paused:        bcR.pool.IsPaused(),
+		Height:        height,
+		MaxPeerHeight: bcR.pool.MaxPeerHeight(),
+		NumPending:    numPending,
+		NumRequesters: numRequesters,
+		Peers:         bcR.pool.PeerStats(),
+	})
+}
+
+// PauseSync stops requesting and applying blocks until ResumeSync, e.g. to
+// inspect the pool. The requests in flight are still completed. It returns
+// an error if the node isn't fast syncing.
+func (bcR *BlockchainReactor) PauseSync() error {
+	if !bcR.pool.IsRunning() {
+		return errors.New("not fast syncing")
+	}
+	bcR.pool.SetPaused(true)
+	bcR.Logger.Info("Paused fast sync")
+	return nil
+}
+
+// ResumeSync resumes the fast sync paused by PauseSync.
+func (bcR *BlockchainReactor) ResumeSync() error {
+	if !bcR.pool.IsRunning() {
+		return errors.New("not fast syncing")
+	}
+	bcR.pool.SetPaused(false)
+	bcR.Logger.Info("Resumed fast sync")
+	return nil
 }
 
 // BroadcastStatusRequest broadcasts `BlockStore` height.
@@ -383,16 +910,45 @@ func RegisterBlockchainMessages(cdc *amino.Codec) {
 	cdc.RegisterConcrete(&bcNoBlockResponseMessage{}, "tendermint/blockchain/NoBlockResponse", nil)
 	cdc.RegisterConcrete(&bcStatusResponseMessage{}, "tendermint/blockchain/StatusResponse", nil)
 	cdc.RegisterConcrete(&bcStatusRequestMessage{}, "tendermint/blockchain/StatusRequest", nil)
+	cdc.RegisterConcrete(&bcCompressedBlockRequestMessage{}, "tendermint/blockchain/CompressedBlockRequest", nil)
+	cdc.RegisterConcrete(&bcCompressedBlockResponseMessage{}, "tendermint/blockchain/CompressedBlockResponse", nil)
+	cdc.RegisterConcrete(&bcBlockBatchRequestMessage{}, "tendermint/blockchain/BlockBatchRequest", nil)
+	cdc.RegisterConcrete(&bcBlockBatchResponseMessage{}, "tendermint/blockchain/BlockBatchResponse", nil)
 }
 
+// The amino prefixes of the messages embedding blocks, set once they're
+// registered.
+var (
+	bcBlockResponseMessagePrefix      []byte
+	bcBlockBatchResponseMessagePrefix []byte
+)
+
 func decodeMsg(bz []byte) (msg BlockchainMessage, err error) {
 	if len(bz) > maxMsgSize {
 		return msg, fmt.Errorf("Msg exceeds max size (%d > %d)", len(bz), maxMsgSize)
 	}
+	if err = checkDecodeLimits(bz); err != nil {
+		return msg, err
+	}
 	err = cdc.UnmarshalBinaryBare(bz, &msg)
 	return
 }
 
+// checkDecodeLimits checks the blocks of the block responses against the
+// decode limits before they're decoded. The consensus params of the heights
+// being synced aren't known yet, so the blocks are checked against the
+// limits of the largest valid params.
+func checkDecodeLimits(bz []byte) error {
+	limits := types.MaxDecodeLimits()
+	switch {
+	case bytes.HasPrefix(bz, bcBlockResponseMessagePrefix):
+		return limits.CheckBlocks(bz[len(bcBlockResponseMessagePrefix):], 1, 1)
+	case bytes.HasPrefix(bz, bcBlockBatchResponseMessagePrefix):
+		return limits.CheckBlocks(bz[len(bcBlockBatchResponseMessagePrefix):], 1, maxBatchRequestHeights)
+	}
+	return nil
+}
+
 //-------------------------------------
 
 type bcBlockRequestMessage struct {
@@ -444,6 +1000,107 @@ func (m *bcBlockResponseMessage) String() string {
 
 //-------------------------------------
 
+// bcCompressedBlockRequestMessage asks for a bcCompressedBlockResponseMessage.
+// It's only sent to peers advertising CapCompression.
+type bcCompressedBlockRequestMessage struct {
+	Height int64
+}
+
+// ValidateBasic performs basic validation.
+func (m *bcCompressedBlockRequestMessage) ValidateBasic() error {
+	if m.Height < 0 {
+		return errors.New("Negative Height")
+	}
+	return nil
+}
+
+func (m *bcCompressedBlockRequestMessage) String() string {
+	return fmt.Sprintf("[bcCompressedBlockRequestMessage %v]", m.Height)
+}
+
+// bcCompressedBlockResponseMessage carries a block compressed by
+// compressBlock.
+type bcCompressedBlockResponseMessage struct {
+	Height int64
+	Data   []byte
+}
+
+// ValidateBasic performs basic validation. The block is validated once
+// decompressed.
+func (m *bcCompressedBlockResponseMessage) ValidateBasic() error {
+	if m.Height < 0 {
+		return errors.New("Negative Height")
+	}
+	if len(m.Data) == 0 {
+		return errors.New("Empty Data")
+	}
+	return nil
+}
+
+func (m *bcCompressedBlockResponseMessage) String() string {
+	return fmt.Sprintf("[bcCompressedBlockResponseMessage %v (%d bytes)]", m.Height, len(m.Data))
+}
+
+//-------------------------------------
+
+// bcBlockBatchRequestMessage asks for the blocks at the given heights, to be
+// sent in bcBlockBatchResponseMessages. It's only sent to peers advertising
+// CapBatchedResponses.
+type bcBlockBatchRequestMessage struct {
+	Heights []int64
+}
+
+// ValidateBasic performs basic validation.
+func (m *bcBlockBatchRequestMessage) ValidateBasic() error {
+	if len(m.Heights) == 0 {
+		return errors.New("No Heights")
+	}
+	if len(m.Heights) > maxBatchRequestHeights {
+		return fmt.Errorf("Too many Heights (%d > %d)", len(m.Heights), maxBatchRequestHeights)
+	}
+	for _, height := range m.Heights {
+		if height < 0 {
+			return errors.New("Negative Height")
+		}
+	}
+	return nil
+}
+
+func (m *bcBlockBatchRequestMessage) String() string {
+	return fmt.Sprintf("[bcBlockBatchRequestMessage %v]", m.Heights)
+}
+
+// bcBlockBatchResponseMessage carries several blocks, so that small blocks
+// don't pay the overhead of a message each.
+type bcBlockBatchResponseMessage struct {
+	Blocks []*types.Block
+}
+
+// ValidateBasic performs basic validation.
+func (m *bcBlockBatchResponseMessage) ValidateBasic() error {
+	if len(m.Blocks) == 0 {
+		return errors.New("No Blocks")
+	}
+	for _, block := range m.Blocks {
+		if block == nil {
+			return errors.New("Nil Block")
+		}
+		if err := block.ValidateBasic(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *bcBlockBatchResponseMessage) String() string {
+	if len(m.Blocks) == 0 {
+		return "[bcBlockBatchResponseMessage]"
+	}
+	return fmt.Sprintf("[bcBlockBatchResponseMessage %v-%v]", m.Blocks[0].Height, m.Blocks[len(m.Blocks)-1].Height)
+}
+
+//-------------------------------------
+
 type bcStatusRequestMessage struct {
 	Height int64
 }
@@ -462,8 +1119,25 @@ func (m *bcStatusRequestMessage) String() string {
 
 //-------------------------------------
 
+// bcStatusResponseMessage also advertises the extensions supported by the
+// node. Older nodes ignore the Capabilities, and don't send any.
+//
+// In response to a bcStatusRequestMessage, nodes supporting CapBlockHashes
+// send the hash of the block at the height of the request, if they have it.
+//
+// Nodes advertise their SyncPhase too, and broadcast a status response when
+// they switch to consensus. Older nodes don't send it (SyncPhaseUnknown).
+//
+// Base is the lowest block the node can serve, once it pruned the ones below.
+// Older nodes don't send it (0): they serve the blocks from 1. The v1 reactor
+// schedules its requests in [Base, Height].
 type bcStatusResponseMessage struct {
-	Height int64
+	Height          int64
+	Capabilities    Capabilities
+	RequestedHeight int64
+	RequestedHash   []byte
+	SyncPhase       types.SyncPhase
+	Base            int64
 }
 
 // ValidateBasic performs basic validation.
@@ -471,9 +1145,25 @@ func (m *bcStatusResponseMessage) ValidateBasic() error {
 	if m.Height < 0 {
 		return errors.New("Negative Height")
 	}
+	if m.RequestedHeight < 0 {
+		return errors.New("Negative RequestedHeight")
+	}
+	if m.Base < 0 {
+		return errors.New("Negative Base")
+	}
+	if m.Base > m.Height {
+		return errors.New("Base is above Height")
+	}
+	if m.RequestedHeight > m.Height {
+		return errors.New("RequestedHeight is above Height")
+	}
+	if len(m.RequestedHash) > 0 && len(m.RequestedHash) != tmhash.Size {
+		return fmt.Errorf("RequestedHash has the wrong size, expected %d, got %d",
+			tmhash.Size, len(m.RequestedHash))
+	}
 	return nil
 }
 
 func (m *bcStatusResponseMessage) String() string {
-	return fmt.Sprintf("[bcStatusResponseMessage %v]", m.Height)
+	return fmt.Sprintf("[bcStatusResponseMessage %v %v %v]", m.Height, m.Capabilities, m.SyncPhase)
 }
@@ -1,6 +1,7 @@
 package v0
 
 import (
+	"encoding/binary"
 	"os"
 	"sort"
 	"testing"
@@ -10,9 +11,12 @@ import (
 	"github.com/tendermint/tendermint/store"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	abci "github.com/tendermint/tendermint/abci/types"
 	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/mock"
 	"github.com/tendermint/tendermint/p2p"
@@ -179,6 +183,75 @@ func TestNoBlockResponse(t *testing.T) {
 	}
 }
 
+func TestTipGracePeriod(t *testing.T) {
+	config = cfg.ResetTestRoot("blockchain_reactor_test")
+	defer os.RemoveAll(config.RootDir)
+	genDoc, privVals := randGenesisDoc(1, false, 30)
+
+	reactorPairs := make([]BlockchainReactorPair, 3)
+	reactorPairs[0] = newBlockchainReactor(log.TestingLogger(), genDoc, privVals, 10)
+	reactorPairs[1] = newBlockchainReactor(log.TestingLogger(), genDoc, privVals, 10)
+	reactorPairs[2] = newBlockchainReactor(log.TestingLogger(), genDoc, privVals, 0)
+	// the first two are at the tip, the last one is behind and must not wait
+	// for the end of the grace period
+	reactorPairs[0].reactor.tipGracePeriod = 500 * time.Millisecond
+	reactorPairs[1].reactor.tipGracePeriod = 500 * time.Millisecond
+	reactorPairs[2].reactor.tipGracePeriod = time.Minute
+
+	p2p.MakeConnectedSwitches(config.P2P, 3, func(i int, s *p2p.Switch) *p2p.Switch {
+		s.AddReactor("BLOCKCHAIN", reactorPairs[i].reactor)
+		return s
+	}, func(switches []*p2p.Switch, i, j int) {
+		// the chains of the nodes at the tip differ (by the times of their
+		// votes): the node behind only syncs from the first one
+		if i == 1 && j == 2 {
+			return
+		}
+		p2p.Connect2Switches(switches, i, j)
+	})
+
+	defer func() {
+		for _, r := range reactorPairs {
+			r.reactor.Stop()
+			r.app.Stop()
+		}
+	}()
+
+	waitFor(t, func() bool {
+		return reactorPairs[2].reactor.pool.IsRunning()
+	}, 10*time.Second, "the node behind should fast sync right away")
+
+	time.Sleep(time.Second)
+	assert.False(t, reactorPairs[0].reactor.pool.IsRunning(), "the node at the tip should skip fast sync")
+	assert.False(t, reactorPairs[1].reactor.pool.IsRunning(), "the node at the tip should skip fast sync")
+
+	// the nodes at the tip told the others they're caught up
+	peerPhase := func(r *BlockchainReactor, i int) types.SyncPhase {
+		peer := r.Switch.Peers().Get(reactorPairs[i].reactor.Switch.NodeInfo().ID())
+		if peer == nil {
+			// not connected yet, or reconnecting
+			return types.SyncPhaseUnknown
+		}
+		phase, _ := peer.Get(types.PeerSyncPhaseKey).(types.SyncPhase)
+		return phase
+	}
+	waitFor(t, func() bool {
+		return peerPhase(reactorPairs[0].reactor, 1) == types.SyncPhaseCaughtUp &&
+			peerPhase(reactorPairs[1].reactor, 0) == types.SyncPhaseCaughtUp &&
+			peerPhase(reactorPairs[2].reactor, 0) == types.SyncPhaseCaughtUp
+	}, 5*time.Second, "the nodes at the tip should be caught up")
+}
+
+// waitFor polls the condition until it holds, and fails the test if it
+// doesn't within the timeout.
+func waitFor(t *testing.T, condition func() bool, timeout time.Duration, msg string) {
+	for start := time.Now(); !condition(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > timeout {
+			t.Fatal(msg)
+		}
+	}
+}
+
 // NOTE: This is too hard to test without
 // an easy way to add test peer to switch
 // or without significant refactoring of the module.
@@ -255,6 +328,29 @@ func TestBadBlockStopsPeer(t *testing.T) {
 	assert.True(t, lastReactorPair.reactor.Switch.Peers().Size() < len(reactorPairs)-1)
 }
 
+func TestCheckChainLinks(t *testing.T) {
+	config = cfg.ResetTestRoot("blockchain_reactor_test")
+	defer os.RemoveAll(config.RootDir)
+	genDoc, _ := randGenesisDoc(1, false, 30)
+	state, err := sm.MakeGenesisState(genDoc)
+	assert.NoError(t, err)
+
+	// e.g. a trust anchor
+	state.LastBlockHeight = 10
+	state.LastBlockID = types.BlockID{Hash: tmhash.Sum([]byte("anchor"))}
+
+	block := makeBlock(11, state, types.NewCommit(state.LastBlockID, nil))
+	assert.NoError(t, checkChainLinks(state, block))
+
+	forked := makeBlock(11, state, types.NewCommit(state.LastBlockID, nil))
+	forked.LastBlockID = types.BlockID{Hash: tmhash.Sum([]byte("fork"))}
+	assert.Error(t, checkChainLinks(state, forked))
+
+	otherVals := makeBlock(11, state, types.NewCommit(state.LastBlockID, nil))
+	otherVals.ValidatorsHash = tmhash.Sum([]byte("validators"))
+	assert.Error(t, checkChainLinks(state, otherVals))
+}
+
 func TestBcBlockRequestMessageValidateBasic(t *testing.T) {
 	testCases := []struct {
 		testName      string
@@ -316,28 +412,129 @@ func TestBcStatusRequestMessageValidateBasic(t *testing.T) {
 }
 
 func TestBcStatusResponseMessageValidateBasic(t *testing.T) {
+	hash := tmhash.Sum([]byte("block"))
 	testCases := []struct {
-		testName       string
-		responseHeight int64
-		expectErr      bool
+		testName        string
+		responseHeight  int64
+		requestedHeight int64
+		requestedHash   []byte
+		expectErr       bool
 	}{
-		{"Valid Response Message", 0, false},
-		{"Valid Response Message", 1, false},
-		{"Invalid Response Message", -1, true},
+		{"Valid Response Message", 0, 0, nil, false},
+		{"Valid Response Message", 1, 0, nil, false},
+		{"Invalid Response Message", -1, 0, nil, true},
+		{"Valid Requested Hash", 2, 1, hash, false},
+		{"Negative Requested Height", 2, -1, nil, true},
+		{"Requested Height Above Height", 2, 3, hash, true},
+		{"Invalid Requested Hash", 2, 1, hash[:10], true},
 	}
 
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.testName, func(t *testing.T) {
-			response := bcStatusResponseMessage{Height: tc.responseHeight}
+			response := bcStatusResponseMessage{
+				Height:          tc.responseHeight,
+				RequestedHeight: tc.requestedHeight,
+				RequestedHash:   tc.requestedHash,
+			}
 			assert.Equal(t, tc.expectErr, response.ValidateBasic() != nil, "Validate Basic had an unexpected result")
 		})
 	}
 }
 
+func TestBcBlockBatchRequestMessageValidateBasic(t *testing.T) {
+	testCases := []struct {
+		testName  string
+		heights   []int64
+		expectErr bool
+	}{
+		{"Valid Batch Request Message", []int64{0, 1, 5}, false},
+		{"No Heights", nil, true},
+		{"Negative Height", []int64{1, -1}, true},
+		{"Too Many Heights", make([]int64, maxBatchRequestHeights+1), true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			request := bcBlockBatchRequestMessage{Heights: tc.heights}
+			assert.Equal(t, tc.expectErr, request.ValidateBasic() != nil, "Validate Basic had an unexpected result")
+		})
+	}
+}
+
+func TestBcBlockBatchResponseMessageValidateBasic(t *testing.T) {
+	block := types.MakeBlock(1, makeTxs(1), new(types.Commit), nil)
+	block.ProposerAddress = crypto.AddressHash([]byte("proposer"))
+	invalidBlock := types.MakeBlock(1, makeTxs(1), new(types.Commit), nil)
+	invalidBlock.ProposerAddress = block.ProposerAddress
+	invalidBlock.NumTxs++
+
+	testCases := []struct {
+		testName  string
+		blocks    []*types.Block
+		expectErr bool
+	}{
+		{"Valid Batch Response Message", []*types.Block{block}, false},
+		{"No Blocks", nil, true},
+		{"Nil Block", []*types.Block{block, nil}, true},
+		{"Invalid Block", []*types.Block{block, invalidBlock}, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			response := bcBlockBatchResponseMessage{Blocks: tc.blocks}
+			assert.Equal(t, tc.expectErr, response.ValidateBasic() != nil, "Validate Basic had an unexpected result")
+		})
+	}
+}
+
+func TestDecodeMsgDecodeLimits(t *testing.T) {
+	block := types.MakeBlock(1, makeTxs(1), new(types.Commit), nil)
+
+	bz := cdc.MustMarshalBinaryBare(&bcBlockResponseMessage{Block: block})
+	msg, err := decodeMsg(bz)
+	require.NoError(t, err)
+	assert.Equal(t, block.Hash(), msg.(*bcBlockResponseMessage).Block.Hash())
+
+	blocks := make([]*types.Block, maxBatchRequestHeights)
+	for i := range blocks {
+		blocks[i] = block
+	}
+	bz = cdc.MustMarshalBinaryBare(&bcBlockBatchResponseMessage{Blocks: blocks})
+	_, err = decodeMsg(bz)
+	require.NoError(t, err)
+
+	// a batch of too many (empty) blocks
+	bz = append([]byte{}, bcBlockBatchResponseMessagePrefix...)
+	for i := 0; i <= maxBatchRequestHeights; i++ {
+		bz = append(bz, 1<<3|2, 0)
+	}
+	_, err = decodeMsg(bz)
+	assert.Error(t, err)
+
+	// a block whose last commit has too many (empty) precommits
+	precommits := make([]byte, 0, 2*(types.MaxVotesCount+1))
+	for i := 0; i <= types.MaxVotesCount; i++ {
+		precommits = append(precommits, 2<<3|2, 0)
+	}
+	blockBz := appendBytesField(nil, 4, precommits)
+	bz = appendBytesField(append([]byte{}, bcBlockResponseMessagePrefix...), 1, blockBz)
+	_, err = decodeMsg(bz)
+	assert.Error(t, err)
+}
+
 //----------------------------------------------
 // utility funcs
 
+func appendBytesField(bz []byte, num uint64, value []byte) []byte {
+	buf := make([]byte, 2*binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, num<<3|2)
+	n += binary.PutUvarint(buf[n:], uint64(len(value)))
+	return append(append(bz, buf[:n]...), value...)
+}
+
 func makeTxs(height int64) (txs []types.Tx) {
 	for i := 0; i < 10; i++ {
 		txs = append(txs, types.Tx([]byte{byte(height), byte(i)}))
@@ -0,0 +1,142 @@
+package v0
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// BandwidthLimits are the maximum rates, in bytes per second, of the blocks
+// sent to (upload) and received from (download) peers on the blockchain
+// channel, for all peers and per peer. 0 means unlimited.
+type BandwidthLimits struct {
+	UploadRate          int64
+	UploadRatePerPeer   int64
+	DownloadRate        int64
+	DownloadRatePerPeer int64
+}
+
+// maxUploadDelay bounds how long a block response may be delayed by the
+// upload limits. Past it, requests are dropped: the peer would time out
+// anyway, and the delayed blocks are kept in memory.
+const maxUploadDelay = 10 * time.Second
+
+// rateLimiter is a token bucket refilled at rate bytes per second, holding at
+// most one second of traffic. A transfer is always allowed, and puts the
+// bucket in debt: the transfers made while it is in debt must wait for it to
+// be paid off.
+type rateLimiter struct {
+	mtx    sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate int64) *rateLimiter {
+	return &rateLimiter{rate: float64(rate), tokens: float64(rate), last: time.Now()}
+}
+
+func (rl *rateLimiter) refill(now time.Time) {
+	rl.tokens += now.Sub(rl.last).Seconds() * rl.rate
+	if rl.tokens > rl.rate {
+		rl.tokens = rl.rate
+	}
+	rl.last = now
+}
+
+// delay returns how long to wait for the bucket to be out of debt.
+func (rl *rateLimiter) delay() time.Duration {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	rl.refill(time.Now())
+	if rl.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+}
+
+// reserve takes n bytes from the bucket, and returns how long to wait before
+// transferring them.
+func (rl *rateLimiter) reserve(n int) time.Duration {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	rl.refill(time.Now())
+	wait := time.Duration(0)
+	if rl.tokens < 0 {
+		wait = time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+	}
+	rl.tokens -= float64(n)
+	return wait
+}
+
+// bandwidthLimiter limits the traffic in one direction, for all peers and per
+// peer. Its methods return the longest delay of the two limits.
+type bandwidthLimiter struct {
+	global   *rateLimiter // nil if unlimited
+	peerRate int64        // 0 if unlimited
+
+	mtx   sync.Mutex
+	peers map[p2p.ID]*rateLimiter
+}
+
+// newBandwidthLimiter returns nil if both rates are unlimited.
+func newBandwidthLimiter(rate, peerRate int64) *bandwidthLimiter {
+	if rate <= 0 && peerRate <= 0 {
+		return nil
+	}
+	bl := &bandwidthLimiter{peerRate: peerRate, peers: make(map[p2p.ID]*rateLimiter)}
+	if rate > 0 {
+		bl.global = newRateLimiter(rate)
+	}
+	return bl
+}
+
+func (bl *bandwidthLimiter) peer(peerID p2p.ID) *rateLimiter {
+	if bl.peerRate <= 0 {
+		return nil
+	}
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	rl, ok := bl.peers[peerID]
+	if !ok {
+		rl = newRateLimiter(bl.peerRate)
+		bl.peers[peerID] = rl
+	}
+	return rl
+}
+
+// delay returns how long to wait before transferring anything with the peer.
+func (bl *bandwidthLimiter) delay(peerID p2p.ID) time.Duration {
+	var d time.Duration
+	if bl.global != nil {
+		d = bl.global.delay()
+	}
+	if rl := bl.peer(peerID); rl != nil {
+		if pd := rl.delay(); pd > d {
+			d = pd
+		}
+	}
+	return d
+}
+
+// reserve accounts for the transfer of n bytes with the peer, and returns how
+// long to wait before making it.
+func (bl *bandwidthLimiter) reserve(peerID p2p.ID, n int) time.Duration {
+	var d time.Duration
+	if bl.global != nil {
+		d = bl.global.reserve(n)
+	}
+	if rl := bl.peer(peerID); rl != nil {
+		if pd := rl.reserve(n); pd > d {
+			d = pd
+		}
+	}
+	return d
+}
+
+func (bl *bandwidthLimiter) removePeer(peerID p2p.ID) {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	delete(bl.peers, peerID)
+}
@@ -0,0 +1,37 @@
+package v0
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	rl := newRateLimiter(1000)
+
+	// a second of traffic is allowed right away
+	assert.Zero(t, rl.reserve(1000))
+
+	// the next transfers wait for the debt to be paid off
+	rl.reserve(1000)
+	delay := rl.reserve(100)
+	assert.True(t, delay > 900*time.Millisecond && delay <= time.Second, "delay %v", delay)
+	assert.True(t, rl.delay() > time.Second, "the transfer adds to the debt")
+}
+
+func TestBandwidthLimiter(t *testing.T) {
+	assert.Nil(t, newBandwidthLimiter(0, 0), "no limits")
+
+	bl := newBandwidthLimiter(0, 1000)
+	bl.reserve("busy", 2000)
+	assert.True(t, bl.delay("busy") > 900*time.Millisecond)
+	assert.Zero(t, bl.delay("idle"), "the limit applies per peer")
+
+	bl.removePeer("busy")
+	assert.Zero(t, bl.delay("busy"))
+
+	bl = newBandwidthLimiter(1000, 10000)
+	bl.reserve("busy", 2000)
+	assert.True(t, bl.delay("idle") > 900*time.Millisecond, "the global limit applies to all peers")
+}
@@ -0,0 +1,31 @@
+package v1
+
+import (
+	"time"
+)
+
+// clockTimer is the subset of *time.Timer used by the FSM and the peers. It
+// allows the timers to be driven by a simulated clock in tests.
+type clockTimer interface {
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// clock is the source of time and timers for the fast sync peers.
+type clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) clockTimer
+}
+
+// systemClock is the clock backed by the time package.
+type systemClock struct{}
+
+var _ clock = systemClock{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) clockTimer {
+	return time.AfterFunc(d, f)
+}
@@ -10,4 +10,6 @@ var cdc = amino.NewCodec()
 func init() {
 	RegisterBlockchainMessages(cdc)
 	types.RegisterBlockAmino(cdc)
+
+	bcBlockResponseMessagePrefix = cdc.MustMarshalBinaryBare(&bcBlockResponseMessage{})
 }
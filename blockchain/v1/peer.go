@@ -5,6 +5,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/tendermint/tendermint/blockchain/inflight"
 	flow "github.com/tendermint/tendermint/libs/flowrate"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
@@ -20,6 +21,7 @@ type BpPeerParams struct {
 	minRecvRate int64
 	sampleRate  time.Duration
 	windowSize  time.Duration
+	clock       clock // source of the block response timers, defaults to the system clock
 }
 
 // BpPeer is the datastructure associated with a fast sync peer.
@@ -27,11 +29,13 @@ type BpPeer struct {
 	logger log.Logger
 	ID     p2p.ID
 
+	Base                    int64                  // the peer reported base, the lowest height it can serve
 	Height                  int64                  // the peer reported height
 	NumPendingBlockRequests int                    // number of requests still waiting for block responses
 	blocks                  map[int64]*types.Block // blocks received or expected to be received from this peer
-	blockResponseTimer      *time.Timer
+	blockResponseTimer      clockTimer
 	recvMonitor             *flow.Monitor
+	throughput              *flow.Monitor // rate of the blocks received, unlike recvMonitor not reset when idle
 	params                  *BpPeerParams // parameters for timer and monitor
 
 	onErr func(err error, peerID p2p.ID) // function to call on error
@@ -44,19 +48,24 @@ func NewBpPeer(
 	if params == nil {
 		params = BpPeerDefaultParams()
 	}
+	if params.clock == nil {
+		params.clock = systemClock{}
+	}
 	return &BpPeer{
-		ID:     peerID,
-		Height: height,
-		blocks: make(map[int64]*types.Block, maxRequestsPerPeer),
-		logger: log.NewNopLogger(),
-		onErr:  onErr,
-		params: params,
+		ID:         peerID,
+		Height:     height,
+		blocks:     make(map[int64]*types.Block, inflight.MinRequestsPerPeer),
+		throughput: inflight.NewThroughputMonitor(),
+		logger:     log.NewNopLogger(),
+		onErr:      onErr,
+		params:     params,
 	}
 }
 
 // String returns a string representation of a peer.
 func (peer *BpPeer) String() string {
-	return fmt.Sprintf("peer: %v height: %v pending: %v", peer.ID, peer.Height, peer.NumPendingBlockRequests)
+	return fmt.Sprintf("peer: %v base: %v height: %v pending: %v", peer.ID, peer.Base, peer.Height,
+		peer.NumPendingBlockRequests)
 }
 
 // SetLogger sets the logger of the peer.
@@ -113,6 +122,7 @@ func (peer *BpPeer) AddBlock(block *types.Block, recvSize int) error {
 		panic("peer does not have pending requests")
 	}
 	peer.blocks[block.Height] = block
+	peer.throughput.Update(recvSize)
 	peer.NumPendingBlockRequests--
 	if peer.NumPendingBlockRequests == 0 {
 		peer.stopMonitor()
@@ -175,7 +185,7 @@ func (peer *BpPeer) startMonitor() {
 
 func (peer *BpPeer) resetBlockResponseTimer() {
 	if peer.blockResponseTimer == nil {
-		peer.blockResponseTimer = time.AfterFunc(peer.params.timeout, peer.onTimeout)
+		peer.blockResponseTimer = peer.params.clock.AfterFunc(peer.params.timeout, peer.onTimeout)
 	} else {
 		peer.blockResponseTimer.Reset(peer.params.timeout)
 	}
@@ -205,5 +215,7 @@ func BpPeerDefaultParams() *BpPeerParams {
 		// Monitor parameters
 		sampleRate: time.Second,
 		windowSize: 40 * time.Second,
+
+		clock: systemClock{},
 	}
 }
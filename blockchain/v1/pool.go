@@ -3,6 +3,8 @@ package v1
 import (
 	"sort"
 
+	"github.com/tendermint/tendermint/blockchain/inflight"
+	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/types"
@@ -22,6 +24,11 @@ type BlockPool struct {
 	Height        int64 // height of next block to execute
 	MaxPeerHeight int64 // maximum height of all peers
 	toBcR         bcReactor
+
+	peerParams *BpPeerParams // parameters for new peers, nil for defaults
+
+	// see SetMemoryBudget
+	limits *inflight.Limits
 }
 
 // NewBlockPool creates a new BlockPool.
@@ -34,9 +41,18 @@ func NewBlockPool(height int64, toBcR bcReactor) *BlockPool {
 		plannedRequests:   make(map[int64]struct{}),
 		nextRequestHeight: height,
 		toBcR:             toBcR,
+		limits:            inflight.NewLimits(inflight.DefaultMemoryBudget),
 	}
 }
 
+// SetMemoryBudget sets the approximate size of the blocks the pool requests
+// or holds at a time. The number of requests follows it, based on the average
+// size of the received blocks, and the number of requests pending per peer
+// follows the throughput of the peer.
+func (pool *BlockPool) SetMemoryBudget(budget int64) {
+	pool.limits.SetMemoryBudget(budget)
+}
+
 // SetLogger sets the logger of the pool.
 func (pool *BlockPool) SetLogger(l log.Logger) {
 	pool.logger = l
@@ -66,9 +82,10 @@ func (pool *BlockPool) updateMaxPeerHeight() {
 	pool.MaxPeerHeight = newMax
 }
 
-// UpdatePeer adds a new peer or updates an existing peer with a new height.
-// If a peer is short it is not added.
-func (pool *BlockPool) UpdatePeer(peerID p2p.ID, height int64) error {
+// UpdatePeer adds a new peer or updates an existing peer with a new base and
+// height. If a peer is short it is not added. A peer which pruned its blocks
+// below the pool height is added: it serves the blocks from its base on.
+func (pool *BlockPool) UpdatePeer(peerID p2p.ID, base int64, height int64) error {
 
 	peer := pool.peers[peerID]
 
@@ -79,17 +96,20 @@ func (pool *BlockPool) UpdatePeer(peerID p2p.ID, height int64) error {
 			return errPeerTooShort
 		}
 		// Add new peer.
-		peer = NewBpPeer(peerID, height, pool.toBcR.sendPeerError, nil)
+		peer = NewBpPeer(peerID, height, pool.toBcR.sendPeerError, pool.peerParams)
+		peer.Base = base
 		peer.SetLogger(pool.logger.With("peer", peerID))
 		pool.peers[peerID] = peer
-		pool.logger.Info("added peer", "peerID", peerID, "height", height, "num_peers", len(pool.peers))
+		pool.logger.Info("added peer", "peerID", peerID, "base", base, "height", height,
+			"num_peers", len(pool.peers))
 	} else {
 		// Check if peer is lowering its height. This is not allowed.
 		if height < peer.Height {
 			pool.RemovePeer(peerID, errPeerLowersItsHeight)
 			return errPeerLowersItsHeight
 		}
-		// Update existing peer.
+		// Update existing peer. Its base may go up as it prunes its blocks.
+		peer.Base = base
 		peer.Height = height
 	}
 
@@ -139,9 +159,10 @@ func (pool *BlockPool) RemovePeer(peerID p2p.ID, err error) {
 				delete(pool.plannedRequests, h)
 			}
 		}
-		// Adjust the nextRequestHeight to the new max plus one.
+		// Adjust the nextRequestHeight to the new max plus one, but never
+		// below the height to sync: the blocks below it are processed already.
 		if pool.nextRequestHeight > pool.MaxPeerHeight {
-			pool.nextRequestHeight = pool.MaxPeerHeight + 1
+			pool.nextRequestHeight = cmn.MaxInt64(pool.Height, pool.MaxPeerHeight+1)
 		}
 	}
 }
@@ -165,8 +186,8 @@ func (pool *BlockPool) removeBadPeers() {
 }
 
 // MakeNextRequests creates more requests if the block pool is running low.
-func (pool *BlockPool) MakeNextRequests(maxNumRequests int) {
-	heights := pool.makeRequestBatch(maxNumRequests)
+func (pool *BlockPool) MakeNextRequests() {
+	heights := pool.makeRequestBatch()
 	if len(heights) != 0 {
 		pool.logger.Info("makeNextRequests will make following requests",
 			"number", len(heights), "heights", heights)
@@ -183,15 +204,16 @@ func (pool *BlockPool) MakeNextRequests(maxNumRequests int) {
 	}
 }
 
-// Makes a batch of requests sorted by height such that the block pool has up to maxNumRequests entries.
-func (pool *BlockPool) makeRequestBatch(maxNumRequests int) []int {
+// Makes a batch of requests sorted by height such that the block pool has up to the number of entries which fit
+// in the memory budget.
+func (pool *BlockPool) makeRequestBatch() []int {
 	pool.removeBadPeers()
 	// At this point pool.requests may include heights for requests to be redone due to removal of peers:
 	// - peers timed out or were removed by switch
 	// - FSM timed out on waiting to advance the block execution due to missing blocks at h or h+1
 	// Determine the number of requests needed by subtracting the number of requests already made from the maximum
 	// allowed
-	numNeeded := maxNumRequests - len(pool.blocks)
+	numNeeded := pool.limits.MaxRequests() - len(pool.blocks)
 	for len(pool.plannedRequests) < numNeeded {
 		if pool.nextRequestHeight > pool.MaxPeerHeight {
 			break
@@ -208,12 +230,24 @@ func (pool *BlockPool) makeRequestBatch(maxNumRequests int) []int {
 	return heights
 }
 
+// sortedPeerIDs returns the IDs of the pool peers in ascending order so that request
+// assignment does not depend on the map iteration order.
+func (pool *BlockPool) sortedPeerIDs() []p2p.ID {
+	ids := make([]p2p.ID, 0, len(pool.peers))
+	for id := range pool.peers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
 func (pool *BlockPool) sendRequest(height int64) bool {
-	for _, peer := range pool.peers {
-		if peer.NumPendingBlockRequests >= maxRequestsPerPeer {
+	for _, peerID := range pool.sortedPeerIDs() {
+		peer := pool.peers[peerID]
+		if peer.NumPendingBlockRequests >= pool.limits.MaxRequestsPerPeer(peer.throughput.Status().CurRate) {
 			continue
 		}
-		if peer.Height < height {
+		if peer.Base > height || peer.Height < height {
 			continue
 		}
 
@@ -231,6 +265,11 @@ func (pool *BlockPool) sendRequest(height int64) bool {
 			continue
 		}
 
+		if err == errSendQueueCongested {
+			pool.logger.Debug("peer queue is congested", "peer", peer.ID, "height", height)
+			continue
+		}
+
 		pool.logger.Info("assigned request to peer", "peer", peer.ID, "height", height)
 
 		pool.blocks[height] = peer.ID
@@ -255,7 +294,11 @@ func (pool *BlockPool) AddBlock(peerID p2p.ID, block *types.Block, blockSize int
 		return errBadDataFromPeer
 	}
 
-	return peer.AddBlock(block, blockSize)
+	if err := peer.AddBlock(block, blockSize); err != nil {
+		return err
+	}
+	pool.limits.BlockReceived(blockSize)
+	return nil
 }
 
 // BlockData stores the peer responsible to deliver a block and the actual block if delivered.
@@ -264,6 +307,24 @@ type BlockData struct {
 	peer  *BpPeer
 }
 
+// PrunedHeight returns true if the pool has peers, and all of them pruned the
+// block at height, i.e. their base is above it, along with their lowest base.
+// Fast sync can't go on from height with these peers.
+func (pool *BlockPool) PrunedHeight(height int64) (pruned bool, lowestBase int64) {
+	if len(pool.peers) == 0 {
+		return false, 0
+	}
+	for _, peer := range pool.peers {
+		if peer.Base <= height {
+			return false, 0
+		}
+		if lowestBase == 0 || peer.Base < lowestBase {
+			lowestBase = peer.Base
+		}
+	}
+	return true, lowestBase
+}
+
 // BlockAndPeerAtHeight retrieves the block and delivery peer at specified height.
 // Returns errMissingBlock if a block was not found
 func (pool *BlockPool) BlockAndPeerAtHeight(height int64) (bData *BlockData, err error) {
@@ -365,5 +426,5 @@ func (pool *BlockPool) NumPeers() int {
 
 // NeedsBlocks returns true if more blocks are required.
 func (pool *BlockPool) NeedsBlocks() bool {
-	return len(pool.blocks) < maxNumRequests
+	return len(pool.blocks) < pool.limits.MaxRequests()
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/tendermint/tendermint/blockchain/inflight"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/types"
@@ -41,13 +42,19 @@ func (testR *testBcR) sendBlockRequest(peerID p2p.ID, height int64) error {
 	return nil
 }
 
-func (testR *testBcR) resetStateTimer(name string, timer **time.Timer, timeout time.Duration) {
+func (testR *testBcR) resetStateTimer(name string, timer *clockTimer, timeout time.Duration) {
 }
 
 func (testR *testBcR) switchToConsensus() {
 
 }
 
+func (testR *testBcR) abortSync(err error) {
+}
+
+func (testR *testBcR) reportSyncStatus(status SyncStatus) {
+}
+
 func newTestBcR() *testBcR {
 	testBcR := &testBcR{logger: log.TestingLogger()}
 	return testBcR
@@ -71,6 +78,7 @@ func makeBlockPool(bcr *testBcR, height int64, peers []BpPeer, blocks map[int64]
 			maxH = p.Height
 		}
 		bPool.peers[p.ID] = NewBpPeer(p.ID, p.Height, bcr.sendPeerError, nil)
+		bPool.peers[p.ID].Base = p.Base
 		bPool.peers[p.ID].SetLogger(bcr.logger)
 
 	}
@@ -92,6 +100,7 @@ func assertPeerSetsEquivalent(t *testing.T, set1 map[p2p.ID]*BpPeer, set2 map[p2
 		peer2 := set2[peerID]
 		assert.NotNil(t, peer2)
 		assert.Equal(t, peer1.NumPendingBlockRequests, peer2.NumPendingBlockRequests)
+		assert.Equal(t, peer1.Base, peer2.Base)
 		assert.Equal(t, peer1.Height, peer2.Height)
 		assert.Equal(t, len(peer1.blocks), len(peer2.blocks))
 		for h, block1 := range peer1.blocks {
@@ -162,7 +171,7 @@ func TestBlockPoolUpdatePeer(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			pool := tt.pool
-			err := pool.UpdatePeer(tt.args.id, tt.args.height)
+			err := pool.UpdatePeer(tt.args.id, 0, tt.args.height)
 			assert.Equal(t, tt.errWanted, err)
 			assert.Equal(t, tt.poolWanted.blocks, tt.pool.blocks)
 			assertPeerSetsEquivalent(t, tt.poolWanted.peers, tt.pool.peers)
@@ -171,6 +180,24 @@ func TestBlockPoolUpdatePeer(t *testing.T) {
 	}
 }
 
+func TestBlockPoolUpdatePrunedPeer(t *testing.T) {
+	testBcR := newTestBcR()
+
+	// a peer which pruned the blocks at the pool height is added
+	pool := makeBlockPool(testBcR, 100, []BpPeer{}, map[int64]tPBlocks{})
+	assert.NoError(t, pool.UpdatePeer("P1", 110, 120))
+	assertPeerSetsEquivalent(t,
+		makeBlockPool(testBcR, 100, []BpPeer{{ID: "P1", Base: 110, Height: 120}}, map[int64]tPBlocks{}).peers,
+		pool.peers)
+
+	// and its base goes up as it prunes more blocks
+	assert.NoError(t, pool.UpdatePeer("P1", 115, 125))
+	assertPeerSetsEquivalent(t,
+		makeBlockPool(testBcR, 100, []BpPeer{{ID: "P1", Base: 115, Height: 125}}, map[int64]tPBlocks{}).peers,
+		pool.peers)
+	assert.EqualValues(t, 125, pool.MaxPeerHeight)
+}
+
 func TestBlockPoolRemovePeer(t *testing.T) {
 	testBcR := newTestBcR()
 
@@ -302,7 +329,6 @@ func TestBlockPoolSendRequestBatch(t *testing.T) {
 	tests := []struct {
 		name                       string
 		pool                       *BlockPool
-		maxRequestsPerPeer         int
 		expRequests                map[int64]bool
 		expPeerResults             []testPeerResult
 		expnumPendingBlockRequests int
@@ -310,7 +336,6 @@ func TestBlockPoolSendRequestBatch(t *testing.T) {
 		{
 			name:                       "one peer - send up to maxRequestsPerPeer block requests",
 			pool:                       makeBlockPool(testBcR, 10, []BpPeer{{ID: "P1", Height: 100}}, map[int64]tPBlocks{}),
-			maxRequestsPerPeer:         2,
 			expRequests:                map[int64]bool{10: true, 11: true},
 			expPeerResults:             []testPeerResult{{id: "P1", numPendingBlockRequests: 2}},
 			expnumPendingBlockRequests: 2,
@@ -322,8 +347,7 @@ func TestBlockPoolSendRequestBatch(t *testing.T) {
 				10,
 				[]BpPeer{{ID: "P1", Height: 100}, {ID: "P2", Height: 100}},
 				map[int64]tPBlocks{}),
-			maxRequestsPerPeer: 2,
-			expRequests:        map[int64]bool{10: true, 11: true},
+			expRequests: map[int64]bool{10: true, 11: true},
 			expPeerResults: []testPeerResult{
 				{id: "P1", numPendingBlockRequests: 2},
 				{id: "P2", numPendingBlockRequests: 2}},
@@ -337,17 +361,58 @@ func TestBlockPoolSendRequestBatch(t *testing.T) {
 			resetPoolTestResults()
 
 			var pool = tt.pool
-			maxRequestsPerPeer = tt.maxRequestsPerPeer
-			pool.MakeNextRequests(10)
-			assert.Equal(t, testResults.numRequestsSent, maxRequestsPerPeer*len(pool.peers))
+			pool.SetMemoryBudget(10 * types.BlockPartSizeBytes)
+			pool.MakeNextRequests()
+			assert.Equal(t, testResults.numRequestsSent, inflight.MinRequestsPerPeer*len(pool.peers))
 
 			for _, tPeer := range tt.expPeerResults {
 				var peer = pool.peers[tPeer.id]
 				assert.NotNil(t, peer)
 				assert.Equal(t, tPeer.numPendingBlockRequests, peer.NumPendingBlockRequests)
 			}
-			assert.Equal(t, testResults.numRequestsSent, maxRequestsPerPeer*len(pool.peers))
+			assert.Equal(t, testResults.numRequestsSent, inflight.MinRequestsPerPeer*len(pool.peers))
+
+		})
+	}
+}
+
+func TestBlockPoolSendRequestPrunedPeers(t *testing.T) {
+	testBcR := newTestBcR()
+
+	// P0 pruned the blocks below 12, P1 has all of them
+	pool := makeBlockPool(testBcR, 10,
+		[]BpPeer{{ID: "P0", Base: 12, Height: 100}, {ID: "P1", Height: 100}},
+		map[int64]tPBlocks{})
+	pool.SetMemoryBudget(10 * types.BlockPartSizeBytes)
+	pool.MakeNextRequests()
+	assert.Equal(t, map[int64]p2p.ID{10: "P1", 11: "P1", 12: "P0", 13: "P0"}, pool.blocks)
+}
 
+func TestBlockPoolPrunedHeight(t *testing.T) {
+	testBcR := newTestBcR()
+
+	tests := []struct {
+		name       string
+		peers      []BpPeer
+		wantPruned bool
+		wantBase   int64
+	}{
+		{"no peers", []BpPeer{}, false, 0},
+		{"peer without base", []BpPeer{{ID: "P1", Height: 100}}, false, 0},
+		{"peer with the block", []BpPeer{{ID: "P1", Base: 10, Height: 100}}, false, 0},
+		{"one peer with the block", []BpPeer{{ID: "P1", Base: 12, Height: 100}, {ID: "P2", Base: 5, Height: 100}},
+			false, 0},
+		{"all peers pruned the block", []BpPeer{{ID: "P1", Base: 15, Height: 100}, {ID: "P2", Base: 12, Height: 100}},
+			true, 12},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pool := makeBlockPool(testBcR, 10, tt.peers, map[int64]tPBlocks{})
+			pruned, base := pool.PrunedHeight(10)
+			assert.Equal(t, tt.wantPruned, pruned)
+			assert.Equal(t, tt.wantBase, base)
 		})
 	}
 }
@@ -1,6 +1,7 @@
 package v1
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"reflect"
@@ -11,7 +12,6 @@ import (
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
 	sm "github.com/tendermint/tendermint/state"
-	"github.com/tendermint/tendermint/store"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -24,6 +24,9 @@ const (
 	// ask for best height every 10s
 	statusUpdateIntervalSeconds = 10
 
+	// peer data key set while the channel to the peer is congested
+	peerCongestedKey = "BlockchainReactor.congested"
+
 	// NOTE: keep up to date with bcBlockResponseMessage
 	bcBlockResponseMessagePrefixSize   = 4
 	bcBlockResponseMessageFieldKeySize = 1
@@ -32,14 +35,6 @@ const (
 		bcBlockResponseMessageFieldKeySize
 )
 
-var (
-	// Maximum number of requests that can be pending per peer, i.e. for which requests have been sent but blocks
-	// have not been received.
-	maxRequestsPerPeer = 20
-	// Maximum number of block requests for the reactor, pending or for which blocks have been received.
-	maxNumRequests = 64
-)
-
 type consensusReactor interface {
 	// for when we switch from blockchain reactor and fast sync to
 	// the consensus machine
@@ -54,13 +49,20 @@ type BlockchainReactor struct {
 	state        sm.State
 
 	blockExec *sm.BlockExecutor
-	store     *store.BlockStore
+	store     sm.BlockStore
 
 	fastSync bool
 
 	fsm          *BcReactorFSM
 	blocksSynced int
 
+	// The commit of the next block is verified by the verifyRoutine while the
+	// current one is applied (see processBlock): verifyCh feeds it, and
+	// nextVerification is the one of the next block, if any. Both are only
+	// used by the processBlocksRoutine.
+	verifyCh         chan *blockVerification
+	nextVerification *blockVerification
+
 	// Receive goroutine forwards messages to this channel to be processed in the context of the poolRoutine.
 	messagesForFSMCh chan bcReactorMessage
 
@@ -73,11 +75,34 @@ type BlockchainReactor struct {
 	eventsFromFSMCh chan bcFsmMessage
 
 	swReporter *behaviour.SwitchReporter
+	eventBus   *types.EventBus // nil if the fast sync status isn't published
+}
+
+// ReactorOption sets an optional parameter on the BlockchainReactor.
+type ReactorOption func(*BlockchainReactor)
+
+// ReactorMinPeers makes the reactor wait for at least minPeers peers, and for
+// stabilizationDelay after the first one reported its height, before it
+// targets their max height (see BcReactorFSM#SetPeerRequirements).
+func ReactorMinPeers(minPeers int, stabilizationDelay time.Duration) ReactorOption {
+	return func(bcR *BlockchainReactor) { bcR.fsm.SetPeerRequirements(minPeers, stabilizationDelay) }
+}
+
+// ReactorMemoryBudget sets the approximate size of the blocks requested or
+// received but not applied yet (see BlockPool#SetMemoryBudget).
+func ReactorMemoryBudget(budget int64) ReactorOption {
+	return func(bcR *BlockchainReactor) { bcR.fsm.SetMemoryBudget(budget) }
+}
+
+// ReactorEventBus makes the reactor publish the fast sync status on the event
+// bus on each change of state of the FSM (see types.EventDataFastSyncStatus).
+func ReactorEventBus(eventBus *types.EventBus) ReactorOption {
+	return func(bcR *BlockchainReactor) { bcR.eventBus = eventBus }
 }
 
 // NewBlockchainReactor returns new reactor instance.
-func NewBlockchainReactor(state sm.State, blockExec *sm.BlockExecutor, store *store.BlockStore,
-	fastSync bool) *BlockchainReactor {
+func NewBlockchainReactor(state sm.State, blockExec *sm.BlockExecutor, store sm.BlockStore,
+	fastSync bool, options ...ReactorOption) *BlockchainReactor {
 
 	if state.LastBlockHeight != store.Height() {
 		panic(fmt.Sprintf("state (%v) and store (%v) height mismatch", state.LastBlockHeight,
@@ -99,11 +124,15 @@ func NewBlockchainReactor(state sm.State, blockExec *sm.BlockExecutor, store *st
 		messagesForFSMCh: messagesForFSMCh,
 		eventsFromFSMCh:  eventsFromFSMCh,
 		errorsForFSMCh:   errorsForFSMCh,
+		verifyCh:         make(chan *blockVerification, 1),
 	}
 	fsm := NewFSM(startHeight, bcR)
 	bcR.fsm = fsm
 	bcR.BaseReactor = *p2p.NewBaseReactor("BlockchainReactor", bcR)
 	//bcR.swReporter = behaviour.NewSwitcReporter(bcR.BaseReactor.Switch)
+	for _, option := range options {
+		option(bcR)
+	}
 
 	return bcR
 }
@@ -120,6 +149,7 @@ const (
 	// message type events
 	peerErrorEv = iota + 1
 	syncFinishedEv
+	syncAbortedEv
 )
 
 type bFsmEventData struct {
@@ -143,7 +173,7 @@ func (bcR *BlockchainReactor) SetLogger(l log.Logger) {
 func (bcR *BlockchainReactor) OnStart() error {
 	bcR.swReporter = behaviour.NewSwitcReporter(bcR.BaseReactor.Switch)
 	if bcR.fastSync {
-		go bcR.poolRoutine()
+		bcR.Go("poolRoutine", func() { bcR.poolRoutine() })
 	}
 	return nil
 }
@@ -168,7 +198,7 @@ func (bcR *BlockchainReactor) GetChannels() []*p2p.ChannelDescriptor {
 
 // AddPeer implements Reactor by sending our state to peer.
 func (bcR *BlockchainReactor) AddPeer(peer p2p.Peer) {
-	msgBytes := cdc.MustMarshalBinaryBare(&bcStatusResponseMessage{bcR.store.Height()})
+	msgBytes := cdc.MustMarshalBinaryBare(&bcStatusResponseMessage{Base: bcR.store.Base(), Height: bcR.store.Height()})
 	peer.Send(BlockchainChannel, msgBytes)
 	// it's OK if send fails. will try later in poolRoutine
 
@@ -176,6 +206,12 @@ func (bcR *BlockchainReactor) AddPeer(peer p2p.Peer) {
 	// bcStatusResponseMessage from the peer and call pool.updatePeer()
 }
 
+// ChannelCongestion implements p2p.CongestionAwareReactor. No block requests
+// are sent to a peer while the channel to it is congested.
+func (bcR *BlockchainReactor) ChannelCongestion(chID byte, peer p2p.Peer, congested bool) {
+	peer.Set(peerCongestedKey, congested)
+}
+
 // sendBlockToPeer loads a block and sends it to the requesting peer.
 // If the block doesn't exist a bcNoBlockResponseMessage is sent.
 // If all nodes are honest, no node should be requesting for a block that doesn't exist.
@@ -195,7 +231,7 @@ func (bcR *BlockchainReactor) sendBlockToPeer(msg *bcBlockRequestMessage,
 }
 
 func (bcR *BlockchainReactor) sendStatusResponseToPeer(msg *bcStatusRequestMessage, src p2p.Peer) (queued bool) {
-	msgBytes := cdc.MustMarshalBinaryBare(&bcStatusResponseMessage{bcR.store.Height()})
+	msgBytes := cdc.MustMarshalBinaryBare(&bcStatusResponseMessage{Base: bcR.store.Base(), Height: bcR.store.Height()})
 	return src.TrySend(BlockchainChannel, msgBytes)
 }
 
@@ -262,6 +298,7 @@ func (bcR *BlockchainReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 			event: statusResponseEv,
 			data: bReactorEventData{
 				peerID: src.ID(),
+				base:   msg.Base,
 				height: msg.Height,
 				length: len(msgBytes),
 			},
@@ -288,6 +325,8 @@ ForLoop:
 		case <-stopProcessing:
 			bcR.Logger.Info("finishing block execution")
 			break ForLoop
+		case <-bcR.Quit():
+			break ForLoop
 		case <-processReceivedBlockTicker.C: // try to execute blocks
 			select {
 			case doProcessBlockCh <- struct{}{}:
@@ -323,6 +362,9 @@ ForLoop:
 			}
 		}
 	}
+
+	// write the state of the last synced blocks
+	bcR.blockExec.StopBatchedWrites()
 }
 
 // poolRoutine receives and handles messages from the Receive() routine and from the FSM.
@@ -334,7 +376,8 @@ func (bcR *BlockchainReactor) poolRoutine() {
 	statusUpdateTicker := time.NewTicker(statusUpdateIntervalSeconds * time.Second)
 
 	stopProcessing := make(chan struct{}, 1)
-	go bcR.processBlocksRoutine(stopProcessing)
+	bcR.Go("processBlocksRoutine", func() { bcR.processBlocksRoutine(stopProcessing) })
+	bcR.Go("verifyRoutine", bcR.verifyRoutine)
 
 ForLoop:
 	for {
@@ -344,14 +387,11 @@ ForLoop:
 			if !bcR.fsm.NeedsBlocks() {
 				continue
 			}
-			_ = bcR.fsm.Handle(&bcReactorMessage{
-				event: makeRequestsEv,
-				data: bReactorEventData{
-					maxNumRequests: maxNumRequests}})
+			_ = bcR.fsm.Handle(&bcReactorMessage{event: makeRequestsEv})
 
 		case <-statusUpdateTicker.C:
 			// Ask for status updates.
-			go bcR.sendStatusRequest()
+			bcR.Go("sendStatusRequest", func() { bcR.sendStatusRequest() })
 
 		case msg := <-bcR.messagesForFSMCh:
 			// Sent from the Receive() routine when status (statusResponseEv) and
@@ -369,6 +409,11 @@ ForLoop:
 				stopProcessing <- struct{}{}
 				// Sent from the FSM when it enters finished state.
 				break ForLoop
+			case syncAbortedEv:
+				// Sent from the FSM when it enters aborted state. The node
+				// stays out of consensus, reporting the error in /status.
+				stopProcessing <- struct{}{}
+				break ForLoop
 			case peerErrorEv:
 				// Sent from the FSM when it detects peer error
 				bcR.reportPeerErrorToSwitch(msg.data.err, msg.data.peerID)
@@ -413,30 +458,63 @@ func (bcR *BlockchainReactor) processBlock() error {
 
 	chainID := bcR.initialState.ChainID
 
-	firstParts := first.MakePartSet(types.BlockPartSizeBytes)
-	firstPartsHeader := firstParts.Header()
-	firstID := types.BlockID{Hash: first.Hash(), PartsHeader: firstPartsHeader}
-	// Finally, verify the first block using the second's commit
-	// NOTE: we can probably make this more efficient, but note that calling
-	// first.Hash() doesn't verify the tx contents, so MakePartSet() is
-	// currently necessary.
-	err = bcR.state.Validators.VerifyCommit(chainID, firstID, first.Height, second.LastCommit)
-	if err != nil {
-		bcR.Logger.Error("error during commit verification", "err", err,
+	// Verify the first block using the second's commit, unless the
+	// verifyRoutine did it already while the previous block was applied.
+	// NOTE: calling first.Hash() doesn't verify the tx contents, so
+	// MakePartSet() is necessary.
+	v := bcR.nextVerification
+	bcR.nextVerification = nil
+	if v == nil || !v.matches(first, second.LastCommit) {
+		v = newBlockVerification(first, second.LastCommit, bcR.state.Validators)
+		v.run(chainID)
+	} else if !v.wait(bcR.Quit()) {
+		// stopping, nothing to process
+		return errMissingBlock
+	}
+	if v.err != nil {
+		bcR.Logger.Error("error during commit verification", "err", v.err,
 			"first", first.Height, "second", second.Height)
 		return errBlockVerificationFailure
 	}
+	firstParts, firstID := v.parts, v.id
+
+	// Verify the second block while the first one is applied, if the third
+	// one, with its commit, was received.
+	if third := bcR.fsm.BlockAtHeight(second.Height + 1); third != nil {
+		next := newBlockVerification(second, third.LastCommit, bcR.state.NextValidators.Copy())
+		select {
+		case bcR.verifyCh <- next:
+			bcR.nextVerification = next
+		case <-bcR.Quit():
+		}
+	}
 
 	bcR.store.SaveBlock(first, firstParts, second.LastCommit)
 
 	bcR.state, err = bcR.blockExec.ApplyBlock(bcR.state, firstID, first)
 	if err != nil {
-		panic(fmt.Sprintf("failed to process committed block (%d:%X): %v", first.Height, first.Hash(), err))
+		// The block is committed, fetching it again won't help: e.g. the app
+		// hash differs from the one of the network.
+		return terminalError{fmt.Errorf("failed to process committed block (%d:%X): %v",
+			first.Height, first.Hash(), err)}
 	}
 
 	return nil
 }
 
+// verifyRoutine runs the verifications of the next blocks (see processBlock).
+func (bcR *BlockchainReactor) verifyRoutine() {
+	chainID := bcR.initialState.ChainID
+	for {
+		select {
+		case v := <-bcR.verifyCh:
+			v.run(chainID)
+		case <-bcR.Quit():
+			return
+		}
+	}
+}
+
 // Implements bcRNotifier
 // sendStatusRequest broadcasts `BlockStore` height.
 func (bcR *BlockchainReactor) sendStatusRequest() {
@@ -452,6 +530,10 @@ func (bcR *BlockchainReactor) sendBlockRequest(peerID p2p.ID, height int64) erro
 		return errNilPeerForBlockRequest
 	}
 
+	if congested, _ := peer.Get(peerCongestedKey).(bool); congested {
+		return errSendQueueCongested
+	}
+
 	msgBytes := cdc.MustMarshalBinaryBare(&bcBlockRequestMessage{height})
 	queued := peer.TrySend(BlockchainChannel, msgBytes)
 	if !queued {
@@ -462,6 +544,7 @@ func (bcR *BlockchainReactor) sendBlockRequest(peerID p2p.ID, height int64) erro
 
 // Implements bcRNotifier
 func (bcR *BlockchainReactor) switchToConsensus() {
+	bcR.blockExec.StopBatchedWrites()
 	conR, ok := bcR.Switch.Reactor("CONSENSUS").(consensusReactor)
 	if ok {
 		conR.SwitchToConsensus(bcR.state, bcR.blocksSynced)
@@ -472,6 +555,39 @@ func (bcR *BlockchainReactor) switchToConsensus() {
 	// }
 }
 
+// Implements bcRNotifier
+func (bcR *BlockchainReactor) abortSync(err error) {
+	bcR.blockExec.StopBatchedWrites()
+	bcR.eventsFromFSMCh <- bcFsmMessage{event: syncAbortedEv, data: bFsmEventData{err: err}}
+}
+
+// Implements bcRNotifier
+func (bcR *BlockchainReactor) reportSyncStatus(status SyncStatus) {
+	if bcR.eventBus == nil {
+		return
+	}
+	data := types.EventDataFastSyncStatus{
+		State:         status.State,
+		Height:        status.Height,
+		MaxPeerHeight: status.MaxPeerHeight,
+		Retries:       status.Retries,
+		RetryTime:     status.RetryTime,
+	}
+	if status.Err != nil {
+		data.Error = status.Err.Error()
+	}
+	if err := bcR.eventBus.PublishEventFastSyncStatus(data); err != nil {
+		bcR.Logger.Error("Failed to publish the fast sync status", "err", err)
+	}
+}
+
+// SyncState returns the state of the FSM, the number of restarts since the
+// last block synced, and the error of the last restart or of the abort.
+func (bcR *BlockchainReactor) SyncState() (state string, retries int, err error) {
+	status := bcR.fsm.SyncStatus()
+	return status.State, status.Retries, status.Err
+}
+
 // Implements bcRNotifier
 // Called by FSM and pool:
 // - pool calls when it detects slow peer or when peer times out
@@ -491,7 +607,7 @@ func (bcR *BlockchainReactor) sendPeerError(err error, peerID p2p.ID) {
 }
 
 // Implements bcRNotifier
-func (bcR *BlockchainReactor) resetStateTimer(name string, timer **time.Timer, timeout time.Duration) {
+func (bcR *BlockchainReactor) resetStateTimer(name string, timer *clockTimer, timeout time.Duration) {
 	if timer == nil {
 		panic("nil timer pointer parameter")
 	}
@@ -528,10 +644,23 @@ func RegisterBlockchainMessages(cdc *amino.Codec) {
 	cdc.RegisterConcrete(&bcStatusRequestMessage{}, "tendermint/blockchain/StatusRequest", nil)
 }
 
+// bcBlockResponseMessagePrefix is the amino prefix of bcBlockResponseMessage,
+// set once it's registered.
+var bcBlockResponseMessagePrefix []byte
+
 func decodeMsg(bz []byte) (msg BlockchainMessage, err error) {
 	if len(bz) > maxMsgSize {
 		return msg, fmt.Errorf("msg exceeds max size (%d > %d)", len(bz), maxMsgSize)
 	}
+	// Check the block of the block responses before decoding it, against the
+	// limits of the largest valid consensus params as the params of the
+	// heights being synced aren't known yet.
+	if bytes.HasPrefix(bz, bcBlockResponseMessagePrefix) {
+		err = types.MaxDecodeLimits().CheckBlocks(bz[len(bcBlockResponseMessagePrefix):], 1, 1)
+		if err != nil {
+			return msg, err
+		}
+	}
 	err = cdc.UnmarshalBinaryBare(bz, &msg)
 	return
 }
@@ -605,8 +734,20 @@ func (m *bcStatusRequestMessage) String() string {
 
 //-------------------------------------
 
+// bcStatusResponseMessage advertises the range of blocks the node can serve,
+// from Base, its lowest block after pruning, to Height. Older nodes don't
+// send the Base (0): they serve the blocks from 1.
+//
+// NOTE: amino numbers the fields in order. The fields between Height and Base
+// are the ones of the v0 status response, which v1 doesn't use: they keep the
+// Base at the same field number as in v0.
 type bcStatusResponseMessage struct {
-	Height int64
+	Height          int64
+	Capabilities    uint64
+	RequestedHeight int64
+	RequestedHash   []byte
+	SyncPhase       uint8
+	Base            int64
 }
 
 // ValidateBasic performs basic validation.
@@ -614,9 +755,15 @@ func (m *bcStatusResponseMessage) ValidateBasic() error {
 	if m.Height < 0 {
 		return errors.New("negative Height")
 	}
+	if m.Base < 0 {
+		return errors.New("negative Base")
+	}
+	if m.Base > m.Height {
+		return fmt.Errorf("base %v cannot be greater than height %v", m.Base, m.Height)
+	}
 	return nil
 }
 
 func (m *bcStatusResponseMessage) String() string {
-	return fmt.Sprintf("[bcStatusResponseMessage %v]", m.Height)
+	return fmt.Sprintf("[bcStatusResponseMessage %v-%v]", m.Base, m.Height)
 }
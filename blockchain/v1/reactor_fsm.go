@@ -37,9 +37,21 @@ type BcReactorFSM struct {
 	startTime time.Time
 
 	state      *bcReactorFSMState
-	stateTimer *time.Timer
+	stateTimer clockTimer
 	pool       *BlockPool
 
+	// see SetPeerRequirements
+	minPeers           int
+	stabilizationDelay time.Duration
+	firstPeerTime      time.Time // when the first peer was added in waitForPeer
+
+	// see waitForRetry
+	hadPeers   bool       // the FSM got to waitForBlock, so it lost its peers if it has none
+	retries    int        // restarts since the last block processed
+	retryTime  time.Time  // when the FSM restarts, in waitForRetry
+	retryTimer clockTimer // runs in waitForRetry only
+	lastErr    error      // the error of the last retry or of the abort
+
 	// interface used to call the Blockchain reactor to send StatusRequest, BlockRequest, reporting errors, etc.
 	toBcR bcReactor
 }
@@ -51,18 +63,39 @@ func NewFSM(height int64, toBcR bcReactor) *BcReactorFSM {
 		startTime: time.Now(),
 		pool:      NewBlockPool(height, toBcR),
 		toBcR:     toBcR,
+		minPeers:  1,
+	}
+}
+
+// SetPeerRequirements makes the FSM wait in waitForPeer until it has at least
+// minPeers peers, and stabilizationDelay passed since the first one reported
+// its height, before it targets their max height. This prevents the sync from
+// targeting the height of the first (possibly lagging or lying) peer and
+// finishing below the real tip. It must be called before the FSM is started.
+func (fsm *BcReactorFSM) SetPeerRequirements(minPeers int, stabilizationDelay time.Duration) {
+	if minPeers < 1 {
+		minPeers = 1
 	}
+	fsm.minPeers = minPeers
+	fsm.stabilizationDelay = stabilizationDelay
+}
+
+// SetMemoryBudget sets the approximate size of the blocks requested or
+// received but not applied yet (see BlockPool#SetMemoryBudget). It must be
+// called before the FSM is started.
+func (fsm *BcReactorFSM) SetMemoryBudget(budget int64) {
+	fsm.pool.SetMemoryBudget(budget)
 }
 
 // bReactorEventData is part of the message sent by the reactor to the FSM and used by the state handlers.
 type bReactorEventData struct {
-	peerID         p2p.ID
-	err            error        // for peer error: timeout, slow; for processed block event if error occurred
-	height         int64        // for status response; for processed block event
-	block          *types.Block // for block response
-	stateName      string       // for state timeout events
-	length         int          // for block response event, length of received block, used to detect slow peers
-	maxNumRequests int          // for request needed event, maximum number of pending requests
+	peerID    p2p.ID
+	err       error        // for peer error: timeout, slow; for processed block event if error occurred
+	base      int64        // for status response
+	height    int64        // for status response; for processed block event
+	block     *types.Block // for block response
+	stateName string       // for state timeout events
+	length    int          // for block response event, length of received block, used to detect slow peers
 }
 
 // Blockchain Reactor Events (the input to the state machine)
@@ -89,7 +122,7 @@ func (msg *bcReactorMessage) String() string {
 	case startFSMEv:
 		dataStr = ""
 	case statusResponseEv:
-		dataStr = fmt.Sprintf("peer=%v height=%v", msg.data.peerID, msg.data.height)
+		dataStr = fmt.Sprintf("peer=%v base=%v height=%v", msg.data.peerID, msg.data.base, msg.data.height)
 	case blockResponseEv:
 		dataStr = fmt.Sprintf("peer=%v block.height=%v length=%v",
 			msg.data.peerID, msg.data.block.Height, msg.data.length)
@@ -139,13 +172,20 @@ var (
 	unknown      *bcReactorFSMState
 	waitForPeer  *bcReactorFSMState
 	waitForBlock *bcReactorFSMState
+	waitForRetry *bcReactorFSMState
 	finished     *bcReactorFSMState
+	aborted      *bcReactorFSMState
 )
 
 // timeouts for state timers
 const (
 	waitForPeerTimeout                 = 3 * time.Second
 	waitForBlockAtCurrentHeightTimeout = 10 * time.Second
+
+	// the FSM waits minRetryBackoff in waitForRetry the first time, and twice
+	// as long each time after, up to maxRetryBackoff
+	minRetryBackoff = 1 * time.Second
+	maxRetryBackoff = 1 * time.Minute
 )
 
 // errors
@@ -156,10 +196,13 @@ var (
 	errMissingBlock           = errors.New("missing blocks")
 	errNilPeerForBlockRequest = errors.New("peer for block request does not exist in the switch")
 	errSendQueueFull          = errors.New("block request not made, send-queue is full")
+	errSendQueueCongested     = errors.New("block request not made, send-queue is congested")
 	errPeerTooShort           = errors.New("peer height too low, old peer removed/ new peer not added")
 	errSwitchRemovesPeer      = errors.New("switch is removing peer")
 	errTimeoutEventWrongState = errors.New("timeout event for a state different than the current one")
 	errNoTallerPeer           = errors.New("fast sync timed out on waiting for a peer taller than this node")
+	errAllPeersLost           = errors.New("fast sync lost all its peers")
+	errPeersPrunedBlocks      = errors.New("fast sync peers pruned the blocks needed")
 
 	// reported eventually to the switch
 	// handle return
@@ -174,6 +217,16 @@ var (
 
 )
 
+// terminalError is an error processing a block which fast sync can't recover
+// from by fetching the block again, e.g. an app hash mismatch: the FSM aborts.
+type terminalError struct {
+	err error
+}
+
+func (e terminalError) Error() string {
+	return fmt.Sprintf("fast sync aborted: %v", e.err)
+}
+
 func init() {
 	unknown = &bcReactorFSMState{
 		name: "unknown",
@@ -199,6 +252,9 @@ func init() {
 		enter: func(fsm *BcReactorFSM) {
 			// Stop when leaving the state.
 			fsm.resetStateTimer()
+			if fsm.pool.NumPeers() == 0 {
+				fsm.firstPeerTime = time.Time{}
+			}
 		},
 		handle: func(fsm *BcReactorFSM, ev bReactorEvent, data bReactorEventData) (*bcReactorFSMState, error) {
 			switch ev {
@@ -208,16 +264,36 @@ func init() {
 						"state", data.stateName)
 					return waitForPeer, errTimeoutEventWrongState
 				}
-				// There was no statusResponse received from any peer.
-				// Should we send status request again?
-				return finished, errNoTallerPeer
+				if fsm.pool.NumPeers() == 0 {
+					if fsm.hadPeers {
+						// All the peers were lost while syncing, restart later.
+						return waitForRetry, errAllPeersLost
+					}
+					// There was no statusResponse received from any peer.
+					// Should we send status request again?
+					return finished, errNoTallerPeer
+				}
+				if fsm.peersStable() {
+					return waitForBlock, nil
+				}
+				// Not enough peers yet, ask again.
+				fsm.toBcR.sendStatusRequest()
+				fsm.resetPeerStabilizationTimer()
+				return waitForPeer, nil
 
 			case statusResponseEv:
-				if err := fsm.pool.UpdatePeer(data.peerID, data.height); err != nil {
+				if err := fsm.pool.UpdatePeer(data.peerID, data.base, data.height); err != nil {
 					if fsm.pool.NumPeers() == 0 {
 						return waitForPeer, err
 					}
 				}
+				if fsm.firstPeerTime.IsZero() {
+					fsm.firstPeerTime = time.Now()
+				}
+				if !fsm.peersStable() {
+					fsm.resetPeerStabilizationTimer()
+					return waitForPeer, nil
+				}
 				if fsm.stateTimer != nil {
 					fsm.stateTimer.Stop()
 				}
@@ -239,6 +315,7 @@ func init() {
 		name:    "waitForBlock",
 		timeout: waitForBlockAtCurrentHeightTimeout,
 		enter: func(fsm *BcReactorFSM) {
+			fsm.hadPeers = true
 			// Stop when leaving the state.
 			fsm.resetStateTimer()
 		},
@@ -246,7 +323,7 @@ func init() {
 			switch ev {
 
 			case statusResponseEv:
-				err := fsm.pool.UpdatePeer(data.peerID, data.height)
+				err := fsm.pool.UpdatePeer(data.peerID, data.base, data.height)
 				if fsm.pool.NumPeers() == 0 {
 					return waitForPeer, err
 				}
@@ -270,6 +347,10 @@ func init() {
 				return waitForBlock, err
 
 			case processedBlockEv:
+				if _, ok := data.err.(terminalError); ok {
+					fsm.lastErr = data.err
+					return aborted, data.err
+				}
 				if data.err != nil {
 					first, second, _ := fsm.pool.FirstTwoBlocksAndPeers()
 					fsm.logger.Error("error processing block", "err", data.err,
@@ -282,6 +363,8 @@ func init() {
 					fsm.pool.InvalidateFirstTwoBlocks(data.err)
 				} else {
 					fsm.pool.ProcessedCurrentHeightBlock()
+					fsm.retries = 0
+					fsm.lastErr = nil
 					// Since we advanced one block reset the state timer
 					fsm.resetStateTimer()
 				}
@@ -305,7 +388,7 @@ func init() {
 				return waitForBlock, nil
 
 			case makeRequestsEv:
-				fsm.makeNextRequests(data.maxNumRequests)
+				fsm.makeNextRequests()
 				return waitForBlock, nil
 
 			case stateTimeoutEv:
@@ -320,6 +403,14 @@ func init() {
 				if fsm.pool.NumPeers() == 0 {
 					return waitForPeer, errNoPeerResponseForCurrentHeights
 				}
+				// No block could be requested at all if the peers pruned it:
+				// the node can't catch up with them.
+				if pruned, base := fsm.pool.PrunedHeight(fsm.pool.Height); pruned {
+					fsm.lastErr = terminalError{fmt.Errorf(
+						"%v: no peer has the block at height %d, the lowest base of the %d peers is %d",
+						errPeersPrunedBlocks, fsm.pool.Height, fsm.pool.NumPeers(), base)}
+					return aborted, fsm.lastErr
+				}
 				if fsm.pool.ReachedMaxHeight() {
 					return finished, nil
 				}
@@ -337,6 +428,68 @@ func init() {
 		},
 	}
 
+	// The FSM waits in waitForRetry, with the blocks synced so far, after it
+	// lost all its peers while syncing, and restarts in waitForPeer after a
+	// backoff, or as soon as a peer reports its height.
+	waitForRetry = &bcReactorFSMState{
+		name: "waitForRetry",
+		enter: func(fsm *BcReactorFSM) {
+			if fsm.stateTimer != nil {
+				fsm.stateTimer.Stop()
+			}
+			fsm.retries++
+			fsm.lastErr = errAllPeersLost
+			backoff := maxRetryBackoff
+			if fsm.retries <= 6 { // 2^6s > 1min
+				backoff = minRetryBackoff << uint(fsm.retries-1)
+			}
+			fsm.retryTime = time.Now().Add(backoff)
+			fsm.logger.Info("Lost all the peers, retrying fast sync later", "height", fsm.pool.Height,
+				"retries", fsm.retries, "backoff", backoff)
+			fsm.toBcR.resetStateTimer("waitForRetry", &fsm.retryTimer, backoff)
+		},
+		handle: func(fsm *BcReactorFSM, ev bReactorEvent, data bReactorEventData) (*bcReactorFSMState, error) {
+			switch ev {
+			case stateTimeoutEv:
+				if data.stateName != "waitForRetry" {
+					fsm.logger.Error("received a state timeout event for different state",
+						"state", data.stateName)
+					return waitForRetry, errTimeoutEventWrongState
+				}
+				fsm.toBcR.sendStatusRequest()
+				return waitForPeer, nil
+
+			case statusResponseEv:
+				if err := fsm.pool.UpdatePeer(data.peerID, data.base, data.height); err != nil {
+					if fsm.pool.NumPeers() == 0 {
+						return waitForRetry, err
+					}
+				}
+				fsm.firstPeerTime = time.Now()
+				if fsm.retryTimer != nil {
+					fsm.retryTimer.Stop()
+				}
+				if fsm.peersStable() {
+					return waitForBlock, nil
+				}
+				return waitForPeer, nil
+
+			case peerRemoveEv:
+				fsm.pool.RemovePeer(data.peerID, data.err)
+				return waitForRetry, nil
+
+			case stopFSMEv:
+				if fsm.retryTimer != nil {
+					fsm.retryTimer.Stop()
+				}
+				return finished, errNoErrorFinished
+
+			default:
+				return waitForRetry, errInvalidEvent
+			}
+		},
+	}
+
 	finished = &bcReactorFSMState{
 		name: "finished",
 		enter: func(fsm *BcReactorFSM) {
@@ -348,6 +501,23 @@ func init() {
 			return finished, nil
 		},
 	}
+
+	// The FSM stops in aborted after a terminal error, without switching to
+	// consensus: the node has to be fixed (e.g. the app) and restarted.
+	aborted = &bcReactorFSMState{
+		name: "aborted",
+		enter: func(fsm *BcReactorFSM) {
+			if fsm.stateTimer != nil {
+				fsm.stateTimer.Stop()
+			}
+			fsm.logger.Error("Fast sync aborted", "height", fsm.pool.Height, "err", fsm.lastErr)
+			fsm.toBcR.abortSync(fsm.lastErr)
+			fsm.cleanup()
+		},
+		handle: func(fsm *BcReactorFSM, ev bReactorEvent, data bReactorEventData) (*bcReactorFSMState, error) {
+			return aborted, nil
+		},
+	}
 }
 
 // Interface used by FSM for sending Block and Status requests,
@@ -357,8 +527,21 @@ type bcReactor interface {
 	sendStatusRequest()
 	sendBlockRequest(peerID p2p.ID, height int64) error
 	sendPeerError(err error, peerID p2p.ID)
-	resetStateTimer(name string, timer **time.Timer, timeout time.Duration)
+	resetStateTimer(name string, timer *clockTimer, timeout time.Duration)
 	switchToConsensus()
+	abortSync(err error)
+	reportSyncStatus(status SyncStatus)
+}
+
+// SyncStatus is the state of fast sync, reported on each change of state of
+// the FSM.
+type SyncStatus struct {
+	State         string    // state of the FSM, e.g. waitForRetry or aborted
+	Height        int64     // height of the next block to sync
+	MaxPeerHeight int64     // max height of the peers
+	Retries       int       // restarts since the last block synced
+	RetryTime     time.Time // when the FSM restarts, in waitForRetry
+	Err           error     // error of the last retry, or of the abort
 }
 
 // SetLogger sets the FSM logger.
@@ -391,6 +574,7 @@ func (fsm *BcReactorFSM) Handle(msg *bcReactorMessage) error {
 	fsm.transition(next)
 	if oldState != fsm.state.name {
 		fsm.logger.Info("FSM changed state", "new_state", fsm.state)
+		fsm.toBcR.reportSyncStatus(fsm.syncStatus())
 	}
 	return err
 }
@@ -413,12 +597,43 @@ func (fsm *BcReactorFSM) resetStateTimer() {
 	fsm.toBcR.resetStateTimer(fsm.state.name, &fsm.stateTimer, fsm.state.timeout)
 }
 
+// peersStable returns true if the FSM has enough peers, known for long
+// enough, to target their max height.
+func (fsm *BcReactorFSM) peersStable() bool {
+	return fsm.pool.NumPeers() >= fsm.minPeers &&
+		time.Since(fsm.firstPeerTime) >= fsm.stabilizationDelay
+}
+
+// resetPeerStabilizationTimer sets the waitForPeer timer to fire when the
+// peers are stable, or after the waitForPeer timeout if more peers are needed.
+func (fsm *BcReactorFSM) resetPeerStabilizationTimer() {
+	timeout := waitForPeerTimeout
+	if fsm.pool.NumPeers() >= fsm.minPeers {
+		timeout = fsm.stabilizationDelay - time.Since(fsm.firstPeerTime)
+	}
+	fsm.toBcR.resetStateTimer(fsm.state.name, &fsm.stateTimer, timeout)
+}
+
+func (fsm *BcReactorFSM) syncStatus() SyncStatus {
+	status := SyncStatus{
+		State:         fsm.state.name,
+		Height:        fsm.pool.Height,
+		MaxPeerHeight: fsm.pool.MaxPeerHeight,
+		Retries:       fsm.retries,
+		Err:           fsm.lastErr,
+	}
+	if fsm.state == waitForRetry {
+		status.RetryTime = fsm.retryTime
+	}
+	return status
+}
+
 func (fsm *BcReactorFSM) isCaughtUp() bool {
 	return fsm.state == finished
 }
 
-func (fsm *BcReactorFSM) makeNextRequests(maxNumRequests int) {
-	fsm.pool.MakeNextRequests(maxNumRequests)
+func (fsm *BcReactorFSM) makeNextRequests() {
+	fsm.pool.MakeNextRequests()
 }
 
 func (fsm *BcReactorFSM) cleanup() {
@@ -444,6 +659,24 @@ func (fsm *BcReactorFSM) FirstTwoBlocks() (first, second *types.Block, err error
 	return
 }
 
+// BlockAtHeight returns the block received for the height, or nil.
+func (fsm *BcReactorFSM) BlockAtHeight(height int64) *types.Block {
+	fsm.mtx.Lock()
+	defer fsm.mtx.Unlock()
+	bData, err := fsm.pool.BlockAndPeerAtHeight(height)
+	if err != nil {
+		return nil
+	}
+	return bData.block
+}
+
+// SyncStatus returns the state of fast sync.
+func (fsm *BcReactorFSM) SyncStatus() SyncStatus {
+	fsm.mtx.Lock()
+	defer fsm.mtx.Unlock()
+	return fsm.syncStatus()
+}
+
 // Status returns the pool's height and the maximum peer height.
 func (fsm *BcReactorFSM) Status() (height, maxPeerHeight int64) {
 	fsm.mtx.Lock()
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/tendermint/tendermint/blockchain/inflight"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
@@ -31,6 +32,8 @@ type testReactor struct {
 	lastBlockRequest  lastBlockRequestT
 	lastPeerError     lastPeerErrorT
 	stateTimerStarts  map[string]int
+	lastSyncStatus    SyncStatus
+	abortErr          error
 }
 
 func sendEventToFSM(fsm *BcReactorFSM, ev bReactorEvent, data bReactorEventData) error {
@@ -109,22 +112,19 @@ func sStatusEv(current, expected string, peerID p2p.ID, height int64, err error)
 		wantErr:      err}
 }
 
-func sMakeRequestsEv(current, expected string, maxPendingRequests int) fsmStepTestValues {
+func sMakeRequestsEv(current, expected string) fsmStepTestValues {
 	return fsmStepTestValues{
 		currentState:     current,
 		event:            makeRequestsEv,
-		data:             bReactorEventData{maxNumRequests: maxPendingRequests},
 		wantState:        expected,
 		wantReqIncreased: true,
 	}
 }
 
-func sMakeRequestsEvErrored(current, expected string,
-	maxPendingRequests int, err error, peersRemoved []p2p.ID) fsmStepTestValues {
+func sMakeRequestsEvErrored(current, expected string, err error, peersRemoved []p2p.ID) fsmStepTestValues {
 	return fsmStepTestValues{
 		currentState:     current,
 		event:            makeRequestsEv,
-		data:             bReactorEventData{maxNumRequests: maxPendingRequests},
 		wantState:        expected,
 		wantErr:          err,
 		wantRemovedPeers: peersRemoved,
@@ -204,8 +204,7 @@ func fixBlockResponseEvStep(step *fsmStepTestValues, testBcR *testReactor) {
 type testFields struct {
 	name               string
 	startingHeight     int64
-	maxRequestsPerPeer int
-	maxPendingRequests int
+	maxPendingRequests int // sets the memory budget to fit as many blocks of the initial average size, if not 0
 	steps              []fsmStepTestValues
 }
 
@@ -216,8 +215,8 @@ func executeFSMTests(t *testing.T, tests []testFields, matchRespToReq bool) {
 			// Create test reactor
 			testBcR := newTestReactor(tt.startingHeight)
 
-			if tt.maxRequestsPerPeer != 0 {
-				maxRequestsPerPeer = tt.maxRequestsPerPeer
+			if tt.maxPendingRequests != 0 {
+				testBcR.fsm.SetMemoryBudget(int64(tt.maxPendingRequests) * types.BlockPartSizeBytes)
 			}
 
 			for _, step := range tt.steps {
@@ -277,27 +276,25 @@ func executeFSMTests(t *testing.T, tests []testFields, matchRespToReq bool) {
 func TestFSMBasic(t *testing.T) {
 	tests := []testFields{
 		{
-			name:               "one block, one peer - TS2",
-			startingHeight:     1,
-			maxRequestsPerPeer: 2,
+			name:           "one block, one peer - TS2",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 2, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 1, []int64{}),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P2", 2, []int64{1}),
 				sProcessedBlockEv("waitForBlock", "finished", nil),
 			},
 		},
 		{
-			name:               "multi block, multi peer - TS2",
-			startingHeight:     1,
-			maxRequestsPerPeer: 2,
+			name:           "multi block, multi peer - TS2",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 4, nil),
 				sStatusEv("waitForBlock", "waitForBlock", "P2", 4, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 
 				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 1, []int64{}),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 2, []int64{1}),
@@ -317,33 +314,29 @@ func TestFSMBasic(t *testing.T) {
 func TestFSMBlockVerificationFailure(t *testing.T) {
 	tests := []testFields{
 		{
-			name:               "block verification failure - TS2 variant",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "block verification failure - TS2 variant",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 
-				// add P1 and get blocks 1-3 from it
-				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				// add P1 and get blocks 1-2 from it
+				sStatusEv("waitForPeer", "waitForBlock", "P1", 2, nil),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 1, []int64{}),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 2, []int64{1}),
-				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 3, []int64{1, 2}),
 
 				// add P2
-				sStatusEv("waitForBlock", "waitForBlock", "P2", 3, nil),
+				sStatusEv("waitForBlock", "waitForBlock", "P2", 2, nil),
 
 				// process block failure, should remove P1 and all blocks
 				sProcessedBlockEv("waitForBlock", "waitForBlock", errBlockVerificationFailure),
 
-				// get blocks 1-3 from P2
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				// get blocks 1-2 from P2
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P2", 1, []int64{}),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P2", 2, []int64{1}),
-				sBlockRespEv("waitForBlock", "waitForBlock", "P2", 3, []int64{1, 2}),
 
-				// finish after processing blocks 1 and 2
-				sProcessedBlockEv("waitForBlock", "waitForBlock", nil),
+				// finish after processing block 1
 				sProcessedBlockEv("waitForBlock", "finished", nil),
 			},
 		},
@@ -352,17 +345,78 @@ func TestFSMBlockVerificationFailure(t *testing.T) {
 	executeFSMTests(t, tests, false)
 }
 
+func TestFSMTerminalError(t *testing.T) {
+	errAppHash := terminalError{fmt.Errorf("wrong Block.Header.AppHash")}
+	tests := []testFields{
+		{
+			name:           "terminal error processing a block",
+			startingHeight: 1,
+			steps: []fsmStepTestValues{
+				sStartFSMEv(),
+				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
+				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 1, []int64{}),
+				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 2, []int64{1}),
+
+				// abort, without blaming the peer
+				sProcessedBlockEv("waitForBlock", "aborted", errAppHash),
+				sStatusEv("aborted", "aborted", "P2", 3, nil),
+			},
+		},
+	}
+
+	executeFSMTests(t, tests, false)
+
+	testBcR := newTestReactor(1)
+	for _, step := range tests[0].steps {
+		_ = sendEventToFSM(testBcR.fsm, step.event, step.data)
+	}
+	assert.Equal(t, errAppHash, testBcR.abortErr)
+	assert.Equal(t, lastPeerErrorT{}, testBcR.lastPeerError)
+	assert.False(t, testBcR.fsm.isCaughtUp())
+	assert.Equal(t, "aborted", testBcR.lastSyncStatus.State)
+	assert.Equal(t, errAppHash, testBcR.lastSyncStatus.Err)
+}
+
+func TestFSMPeersPrunedBlocks(t *testing.T) {
+	testBcR := newTestReactor(1)
+	steps := []fsmStepTestValues{
+		sStartFSMEv(),
+		{
+			currentState: "waitForPeer",
+			event:        statusResponseEv,
+			data:         bReactorEventData{peerID: "P1", base: 5, height: 20},
+			wantState:    "waitForBlock",
+		},
+		sMakeRequestsEv("waitForBlock", "waitForBlock"),
+	}
+	for _, step := range steps {
+		_ = sendEventToFSM(testBcR.fsm, step.event, step.data)
+		assert.Equal(t, step.wantState, testBcR.fsm.state.name)
+	}
+	// the peer can't serve the block at height 1
+	assert.Equal(t, 0, testBcR.numBlockRequests)
+
+	// abort, without blaming the peer
+	err := sendEventToFSM(testBcR.fsm, stateTimeoutEv, bReactorEventData{stateName: "waitForBlock"})
+	assert.IsType(t, terminalError{}, err)
+	assert.Contains(t, err.Error(), errPeersPrunedBlocks.Error())
+	assert.Equal(t, "aborted", testBcR.fsm.state.name)
+	assert.Equal(t, err, testBcR.abortErr)
+	assert.Equal(t, lastPeerErrorT{}, testBcR.lastPeerError)
+	assert.False(t, testBcR.fsm.isCaughtUp())
+}
+
 func TestFSMBadBlockFromPeer(t *testing.T) {
 	tests := []testFields{
 		{
-			name:               "block we haven't asked for",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "block we haven't asked for",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1 and ask for blocks 1-3
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 300, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 
 				// blockResponseEv for height 100 should cause an error
 				sBlockRespEvErrored("waitForBlock", "waitForPeer",
@@ -370,14 +424,13 @@ func TestFSMBadBlockFromPeer(t *testing.T) {
 			},
 		},
 		{
-			name:               "block we already have",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "block we already have",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1 and get block 1
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 100, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sBlockRespEv("waitForBlock", "waitForBlock",
 					"P1", 1, []int64{}),
 
@@ -388,29 +441,27 @@ func TestFSMBadBlockFromPeer(t *testing.T) {
 			},
 		},
 		{
-			name:               "block from unknown peer",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "block from unknown peer",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1 and get block 1
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
 
 				// get block 1 from unknown peer P2
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sBlockRespEvErrored("waitForBlock", "waitForBlock",
 					"P2", 1, []int64{}, errBadDataFromPeer, []p2p.ID{"P2"}),
 			},
 		},
 		{
-			name:               "block from wrong peer",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "block from wrong peer",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1, make requests for blocks 1-3 to P1
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 
 				// add P2
 				sStatusEv("waitForBlock", "waitForBlock", "P2", 3, nil),
@@ -428,20 +479,22 @@ func TestFSMBadBlockFromPeer(t *testing.T) {
 func TestFSMBlockAtCurrentHeightDoesNotArriveInTime(t *testing.T) {
 	tests := []testFields{
 		{
-			name:               "block at current height undelivered - TS5",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "block at current height undelivered - TS5",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1, get blocks 1 and 2, process block 1
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sBlockRespEv("waitForBlock", "waitForBlock",
 					"P1", 1, []int64{}),
 				sBlockRespEv("waitForBlock", "waitForBlock",
 					"P1", 2, []int64{1}),
 				sProcessedBlockEv("waitForBlock", "waitForBlock", nil),
 
+				// request block 3 from P1
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
+
 				// add P2
 				sStatusEv("waitForBlock", "waitForBlock", "P2", 3, nil),
 
@@ -449,25 +502,24 @@ func TestFSMBlockAtCurrentHeightDoesNotArriveInTime(t *testing.T) {
 				sStateTimeoutEv("waitForBlock", "waitForBlock", "waitForBlock", errNoPeerResponseForCurrentHeights),
 
 				// make requests and finish by receiving blocks 2 and 3 from P2
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P2", 2, []int64{}),
 				sBlockRespEv("waitForBlock", "waitForBlock", "P2", 3, []int64{2}),
 				sProcessedBlockEv("waitForBlock", "finished", nil),
 			},
 		},
 		{
-			name:               "block at current height undelivered, at maxPeerHeight after peer removal - TS3",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "block at current height undelivered, at maxPeerHeight after peer removal - TS3",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1, request blocks 1-3 from P1
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 
 				// add P2 (tallest)
 				sStatusEv("waitForBlock", "waitForBlock", "P2", 30, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 
 				// receive blocks 1-3 from P1
 				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 1, []int64{}),
@@ -516,14 +568,13 @@ func TestFSMPeerRelatedEvents(t *testing.T) {
 			},
 		},
 		{
-			name:               "highest peer removed while in waitForBlock state, node reaches maxPeerHeight - TS4 ",
-			startingHeight:     100,
-			maxRequestsPerPeer: 3,
+			name:           "highest peer removed while in waitForBlock state, node reaches maxPeerHeight - TS4 ",
+			startingHeight: 100,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1 and make requests
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 101, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				// add P2
 				sStatusEv("waitForBlock", "waitForBlock", "P2", 200, nil),
 
@@ -537,14 +588,13 @@ func TestFSMPeerRelatedEvents(t *testing.T) {
 			},
 		},
 		{
-			name:               "highest peer lowers its height in waitForBlock state, node reaches maxPeerHeight - TS4",
-			startingHeight:     100,
-			maxRequestsPerPeer: 3,
+			name:           "highest peer lowers its height in waitForBlock state, node reaches maxPeerHeight - TS4",
+			startingHeight: 100,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1 and make requests
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 101, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 
 				// add P2
 				sStatusEv("waitForBlock", "waitForBlock", "P2", 200, nil),
@@ -587,9 +637,8 @@ func TestFSMPeerRelatedEvents(t *testing.T) {
 			},
 		},
 		{
-			name:               "peer does not exist in the switch",
-			startingHeight:     9999999,
-			maxRequestsPerPeer: 3,
+			name:           "peer does not exist in the switch",
+			startingHeight: 9999999,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				// add P1
@@ -597,8 +646,7 @@ func TestFSMPeerRelatedEvents(t *testing.T) {
 				// send request for block 9999999
 				// Note: For this block request the "switch missing the peer" error is simulated,
 				// see implementation of bcReactor interface, sendBlockRequest(), in this file.
-				sMakeRequestsEvErrored("waitForBlock", "waitForBlock",
-					maxNumRequests, nil, []p2p.ID{"P1"}),
+				sMakeRequestsEvErrored("waitForBlock", "waitForBlock", nil, []p2p.ID{"P1"}),
 			},
 		},
 	}
@@ -606,6 +654,48 @@ func TestFSMPeerRelatedEvents(t *testing.T) {
 	executeFSMTests(t, tests, true)
 }
 
+func TestFSMMinPeers(t *testing.T) {
+	testBcR := newTestReactor(1)
+	testBcR.fsm.SetPeerRequirements(2, 0)
+	fsm := testBcR.fsm
+
+	_ = sendEventToFSM(fsm, startFSMEv, bReactorEventData{})
+	assert.Equal(t, 1, testBcR.numStatusRequests)
+
+	// the height of the first peer is not a target yet
+	err := sendEventToFSM(fsm, statusResponseEv, bReactorEventData{peerID: "P1", height: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, "waitForPeer", fsm.state.name)
+
+	// on timeout, the FSM asks for more peers instead of finishing
+	err = sendEventToFSM(fsm, stateTimeoutEv, bReactorEventData{stateName: "waitForPeer"})
+	assert.NoError(t, err)
+	assert.Equal(t, "waitForPeer", fsm.state.name)
+	assert.Equal(t, 2, testBcR.numStatusRequests)
+
+	err = sendEventToFSM(fsm, statusResponseEv, bReactorEventData{peerID: "P2", height: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, "waitForBlock", fsm.state.name)
+	assert.EqualValues(t, 10, fsm.pool.MaxPeerHeight)
+}
+
+func TestFSMPeerStabilizationDelay(t *testing.T) {
+	testBcR := newTestReactor(1)
+	testBcR.fsm.SetPeerRequirements(1, time.Hour)
+	fsm := testBcR.fsm
+
+	_ = sendEventToFSM(fsm, startFSMEv, bReactorEventData{})
+	err := sendEventToFSM(fsm, statusResponseEv, bReactorEventData{peerID: "P1", height: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, "waitForPeer", fsm.state.name)
+
+	// the timer fires once the delay passed
+	fsm.firstPeerTime = fsm.firstPeerTime.Add(-time.Hour)
+	err = sendEventToFSM(fsm, stateTimeoutEv, bReactorEventData{stateName: "waitForPeer"})
+	assert.NoError(t, err)
+	assert.Equal(t, "waitForBlock", fsm.state.name)
+}
+
 func TestFSMStopFSM(t *testing.T) {
 	tests := []testFields{
 		{
@@ -668,53 +758,48 @@ func TestFSMUnknownElements(t *testing.T) {
 func TestFSMPeerStateTimeoutEvent(t *testing.T) {
 	tests := []testFields{
 		{
-			name:               "timeout event for state waitForPeer while in state waitForPeer - TS1",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "timeout event for state waitForPeer while in state waitForPeer - TS1",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				sStateTimeoutEv("waitForPeer", "finished", "waitForPeer", errNoTallerPeer),
 			},
 		},
 		{
-			name:               "timeout event for state waitForPeer while in a state != waitForPeer",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "timeout event for state waitForPeer while in a state != waitForPeer",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				sStateTimeoutEv("waitForPeer", "waitForPeer", "waitForBlock", errTimeoutEventWrongState),
 			},
 		},
 		{
-			name:               "timeout event for state waitForBlock while in state waitForBlock ",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "timeout event for state waitForBlock while in state waitForBlock ",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sStateTimeoutEv("waitForBlock", "waitForPeer", "waitForBlock", errNoPeerResponseForCurrentHeights),
 			},
 		},
 		{
-			name:               "timeout event for state waitForBlock while in a state != waitForBlock",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "timeout event for state waitForBlock while in a state != waitForBlock",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sStateTimeoutEv("waitForBlock", "waitForBlock", "waitForPeer", errTimeoutEventWrongState),
 			},
 		},
 		{
-			name:               "timeout event for state waitForBlock with multiple peers",
-			startingHeight:     1,
-			maxRequestsPerPeer: 3,
+			name:           "timeout event for state waitForBlock with multiple peers",
+			startingHeight: 1,
 			steps: []fsmStepTestValues{
 				sStartFSMEv(),
 				sStatusEv("waitForPeer", "waitForBlock", "P1", 3, nil),
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 				sStatusEv("waitForBlock", "waitForBlock", "P2", 3, nil),
 				sStateTimeoutEv("waitForBlock", "waitForBlock", "waitForBlock", errNoPeerResponseForCurrentHeights),
 			},
@@ -724,8 +809,67 @@ func TestFSMPeerStateTimeoutEvent(t *testing.T) {
 	executeFSMTests(t, tests, false)
 }
 
+func TestFSMRetryAfterLosingAllPeers(t *testing.T) {
+	tests := []testFields{
+		{
+			name:           "all peers lost while syncing",
+			startingHeight: 1,
+			steps: []fsmStepTestValues{
+				sStartFSMEv(),
+				sStatusEv("waitForPeer", "waitForBlock", "P1", 4, nil),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
+				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 1, []int64{}),
+				sBlockRespEv("waitForBlock", "waitForBlock", "P1", 2, []int64{1}),
+				sProcessedBlockEv("waitForBlock", "waitForBlock", nil),
+				sPeerRemoveEv("waitForBlock", "waitForPeer", "P1", errSwitchRemovesPeer, []p2p.ID{"P1"}),
+
+				// back off instead of finishing
+				sStateTimeoutEv("waitForPeer", "waitForRetry", "waitForPeer", errAllPeersLost),
+				sStateTimeoutEv("waitForRetry", "waitForRetry", "waitForPeer", errTimeoutEventWrongState),
+				sUnknownFSMEv("waitForRetry"),
+			},
+		},
+	}
+
+	executeFSMTests(t, tests, false)
+
+	testBcR := newTestReactor(1)
+	for _, step := range tests[0].steps {
+		_ = sendEventToFSM(testBcR.fsm, step.event, step.data)
+	}
+	status := testBcR.lastSyncStatus
+	assert.Equal(t, "waitForRetry", status.State)
+	assert.Equal(t, int64(2), status.Height, "the blocks synced are kept")
+	assert.Equal(t, 1, status.Retries)
+	assert.Equal(t, errAllPeersLost, status.Err)
+	assert.Equal(t, 1, testBcR.stateTimerStarts["waitForRetry"])
+
+	// restart after the backoff
+	numStatusRequests := testBcR.numStatusRequests
+	err := sendEventToFSM(testBcR.fsm, stateTimeoutEv, bReactorEventData{stateName: "waitForRetry"})
+	assert.NoError(t, err)
+	assert.Equal(t, "waitForPeer", testBcR.fsm.state.name)
+	assert.Equal(t, numStatusRequests+1, testBcR.numStatusRequests)
+
+	// no peer yet, back off again
+	err = sendEventToFSM(testBcR.fsm, stateTimeoutEv, bReactorEventData{stateName: "waitForPeer"})
+	assert.Equal(t, errAllPeersLost, err)
+	assert.Equal(t, 2, testBcR.lastSyncStatus.Retries)
+	assert.Equal(t, 2, testBcR.stateTimerStarts["waitForRetry"])
+
+	// a peer shows up during the backoff: resume from block 2
+	err = sendEventToFSM(testBcR.fsm, statusResponseEv, bReactorEventData{peerID: "P2", height: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, "waitForBlock", testBcR.fsm.state.name)
+	assert.Equal(t, 2, testBcR.lastSyncStatus.Retries)
+	assert.Equal(t, int64(2), testBcR.fsm.pool.Height)
+}
+
 func makeCorrectTransitionSequence(startingHeight int64, numBlocks int64, numPeers int, randomPeerHeights bool,
-	maxRequestsPerPeer int, maxPendingRequests int) testFields {
+	maxPendingRequests int) testFields {
+
+	// The peers have at least this many requests pending, more once their throughput is measured.
+	maxRequestsPerPeer := inflight.MinRequestsPerPeer
 
 	// Generate numPeers peers with random or numBlocks heights according to the randomPeerHeights flag.
 	peerHeights := make([]int64, numPeers)
@@ -744,8 +888,8 @@ func makeCorrectTransitionSequence(startingHeight int64, numBlocks int64, numPee
 	// Approximate the slice capacity to save time for appends.
 	testSteps := make([]fsmStepTestValues, 0, 3*numBlocks+int64(numPeers))
 
-	testName := fmt.Sprintf("%v-blocks %v-startingHeight %v-peers %v-maxRequestsPerPeer %v-maxNumRequests",
-		numBlocks, startingHeight, numPeers, maxRequestsPerPeer, maxPendingRequests)
+	testName := fmt.Sprintf("%v-blocks %v-startingHeight %v-peers %v-maxNumRequests",
+		numBlocks, startingHeight, numPeers, maxPendingRequests)
 
 	// Add startFSMEv step.
 	testSteps = append(testSteps, sStartFSMEv())
@@ -774,7 +918,7 @@ forLoop:
 		if i%maxRequestsPerPeer == 0 {
 			testSteps = append(
 				testSteps,
-				sMakeRequestsEv("waitForBlock", "waitForBlock", maxNumRequests),
+				sMakeRequestsEv("waitForBlock", "waitForBlock"),
 			)
 		}
 
@@ -809,7 +953,6 @@ forLoop:
 	return testFields{
 		name:               testName,
 		startingHeight:     startingHeight,
-		maxRequestsPerPeer: maxRequestsPerPeer,
 		maxPendingRequests: maxPendingRequests,
 		steps:              testSteps,
 	}
@@ -817,7 +960,6 @@ forLoop:
 
 const (
 	maxStartingHeightTest       = 100
-	maxRequestsPerPeerTest      = 20
 	maxTotalPendingRequestsTest = 600
 	maxNumPeersTest             = 1000
 	maxNumBlocksInChainTest     = 10000 //should be smaller than 9999999
@@ -827,11 +969,8 @@ func makeCorrectTransitionSequenceWithRandomParameters() testFields {
 	// Generate a starting height for fast sync.
 	startingHeight := int64(cmn.RandIntn(maxStartingHeightTest) + 1)
 
-	// Generate the number of requests per peer.
-	maxRequestsPerPeer := cmn.RandIntn(maxRequestsPerPeerTest) + 1
-
-	// Generate the maximum number of total pending requests, >= maxRequestsPerPeer.
-	maxPendingRequests := cmn.RandIntn(maxTotalPendingRequestsTest-maxRequestsPerPeer) + maxRequestsPerPeer
+	// Generate the maximum number of total pending requests.
+	maxPendingRequests := cmn.RandIntn(maxTotalPendingRequestsTest-inflight.MinRequests) + inflight.MinRequests
 
 	// Generate the number of blocks to be synced.
 	numBlocks := int64(cmn.RandIntn(maxNumBlocksInChainTest)) + startingHeight
@@ -839,7 +978,7 @@ func makeCorrectTransitionSequenceWithRandomParameters() testFields {
 	// Generate a number of peers.
 	numPeers := cmn.RandIntn(maxNumPeersTest) + 1
 
-	return makeCorrectTransitionSequence(startingHeight, numBlocks, numPeers, true, maxRequestsPerPeer, maxPendingRequests)
+	return makeCorrectTransitionSequence(startingHeight, numBlocks, numPeers, true, maxPendingRequests)
 }
 
 func shouldApplyProcessedBlockEvStep(step *fsmStepTestValues, testBcR *testReactor) bool {
@@ -859,7 +998,7 @@ func shouldApplyProcessedBlockEvStep(step *fsmStepTestValues, testBcR *testReact
 func TestFSMCorrectTransitionSequences(t *testing.T) {
 
 	tests := []testFields{
-		makeCorrectTransitionSequence(1, 100, 10, true, 10, 40),
+		makeCorrectTransitionSequence(1, 100, 10, true, 40),
 		makeCorrectTransitionSequenceWithRandomParameters(),
 	}
 
@@ -869,8 +1008,8 @@ func TestFSMCorrectTransitionSequences(t *testing.T) {
 			// Create test reactor
 			testBcR := newTestReactor(tt.startingHeight)
 
-			if tt.maxRequestsPerPeer != 0 {
-				maxRequestsPerPeer = tt.maxRequestsPerPeer
+			if tt.maxPendingRequests != 0 {
+				testBcR.fsm.SetMemoryBudget(int64(tt.maxPendingRequests) * types.BlockPartSizeBytes)
 			}
 
 			for _, step := range tt.steps {
@@ -927,7 +1066,7 @@ func (testR *testReactor) sendBlockRequest(peerID p2p.ID, height int64) error {
 	return nil
 }
 
-func (testR *testReactor) resetStateTimer(name string, timer **time.Timer, timeout time.Duration) {
+func (testR *testReactor) resetStateTimer(name string, timer *clockTimer, timeout time.Duration) {
 	testR.logger.Info("Reactor received resetStateTimer call from FSM", "state", name, "timeout", timeout)
 	if _, ok := testR.stateTimerStarts[name]; !ok {
 		testR.stateTimerStarts[name] = 1
@@ -939,4 +1078,13 @@ func (testR *testReactor) resetStateTimer(name string, timer **time.Timer, timeo
 func (testR *testReactor) switchToConsensus() {
 }
 
+func (testR *testReactor) abortSync(err error) {
+	testR.logger.Info("Reactor received abortSync call from FSM", "err", err)
+	testR.abortErr = err
+}
+
+func (testR *testReactor) reportSyncStatus(status SyncStatus) {
+	testR.lastSyncStatus = status
+}
+
 // ----------------------------------------
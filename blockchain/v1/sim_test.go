@@ -0,0 +1,546 @@
+package v1
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// ----------------------------------------
+// Simulated clock
+
+// mockClock is a simulated clock. Time only moves when the clock is advanced and
+// the expired timers are fired synchronously, in deadline order.
+type mockClock struct {
+	now    time.Time
+	seq    int
+	timers []*mockTimer
+}
+
+type mockTimer struct {
+	clock    *mockClock
+	deadline time.Time
+	seq      int // order of the last (re)start, breaks ties between equal deadlines
+	f        func()
+	active   bool
+}
+
+var _ clock = (*mockClock)(nil)
+
+func newMockClock() *mockClock {
+	return &mockClock{now: time.Unix(0, 0)}
+}
+
+func (c *mockClock) Now() time.Time {
+	return c.now
+}
+
+func (c *mockClock) AfterFunc(d time.Duration, f func()) clockTimer {
+	t := &mockTimer{clock: c, f: f}
+	t.Reset(d)
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// fireNext fires the earliest active timer expiring no later than end and
+// moves the clock to its deadline. It returns false if there is no such timer.
+func (c *mockClock) fireNext(end time.Time) bool {
+	var next *mockTimer
+	for _, t := range c.timers {
+		if !t.active || t.deadline.After(end) {
+			continue
+		}
+		if next == nil || t.deadline.Before(next.deadline) ||
+			(t.deadline.Equal(next.deadline) && t.seq < next.seq) {
+			next = t
+		}
+	}
+	if next == nil {
+		return false
+	}
+	c.now = next.deadline
+	next.active = false
+	next.f()
+	return true
+}
+
+func (t *mockTimer) Stop() bool {
+	wasActive := t.active
+	t.active = false
+	return wasActive
+}
+
+func (t *mockTimer) Reset(d time.Duration) bool {
+	wasActive := t.active
+	t.clock.seq++
+	t.seq = t.clock.seq
+	t.deadline = t.clock.now.Add(d)
+	t.active = true
+	return wasActive
+}
+
+// ----------------------------------------
+// Simulated reactor
+
+type simBlockRequest struct {
+	peerID p2p.ID
+	height int64
+}
+
+// simReactor implements the bcReactor interface on top of a simulated clock and
+// switch. Instead of being handled concurrently, the events generated for the
+// FSM (peer removals, state timeouts) are queued and processed by the simulator.
+type simReactor struct {
+	clock *mockClock
+
+	connected      map[p2p.ID]bool    // peers known to the simulated switch
+	queue          []bcReactorMessage // events for the FSM, in the order they were generated
+	blockRequests  []simBlockRequest  // requests without a response, in the order they were made
+	peerErrors     []lastPeerErrorT
+	statusRequests int
+	switched       bool
+	abortErr       error
+	statuses       []SyncStatus // reported on each change of state
+}
+
+func (bcR *simReactor) sendStatusRequest() {
+	bcR.statusRequests++
+}
+
+func (bcR *simReactor) sendBlockRequest(peerID p2p.ID, height int64) error {
+	if !bcR.connected[peerID] {
+		return errNilPeerForBlockRequest
+	}
+	bcR.blockRequests = append(bcR.blockRequests, simBlockRequest{peerID: peerID, height: height})
+	return nil
+}
+
+// sendPeerError mimics the switch stopping the peer, which results in a peer removal event.
+func (bcR *simReactor) sendPeerError(err error, peerID p2p.ID) {
+	bcR.peerErrors = append(bcR.peerErrors, lastPeerErrorT{peerID: peerID, err: err})
+	if !bcR.connected[peerID] {
+		return
+	}
+	delete(bcR.connected, peerID)
+	bcR.queue = append(bcR.queue, bcReactorMessage{
+		event: peerRemoveEv,
+		data:  bReactorEventData{peerID: peerID, err: err},
+	})
+}
+
+func (bcR *simReactor) resetStateTimer(name string, timer *clockTimer, timeout time.Duration) {
+	if *timer == nil {
+		*timer = bcR.clock.AfterFunc(timeout, func() {
+			bcR.queue = append(bcR.queue, bcReactorMessage{
+				event: stateTimeoutEv,
+				data:  bReactorEventData{stateName: name},
+			})
+		})
+	} else {
+		(*timer).Reset(timeout)
+	}
+}
+
+func (bcR *simReactor) switchToConsensus() {
+	bcR.switched = true
+}
+
+func (bcR *simReactor) abortSync(err error) {
+	bcR.abortErr = err
+}
+
+func (bcR *simReactor) reportSyncStatus(status SyncStatus) {
+	bcR.statuses = append(bcR.statuses, status)
+}
+
+// ----------------------------------------
+// Simulator
+
+// fsmSimulator drives a BcReactorFSM with injected events on a single goroutine.
+// Peer and state timers run on a mockClock, so timeouts, out of order responses
+// and peer churn can be simulated deterministically and without sleeping.
+type fsmSimulator struct {
+	t      *testing.T
+	clock  *mockClock
+	bcR    *simReactor
+	fsm    *BcReactorFSM
+	silent map[p2p.ID]bool // connected peers that never respond to block requests
+
+	processedHeight int64    // height of the last block processed
+	trace           []string // handled events and resulting states
+}
+
+func newFSMSimulator(t *testing.T, height int64, peerTimeout time.Duration) *fsmSimulator {
+	clock := newMockClock()
+	bcR := &simReactor{clock: clock, connected: make(map[p2p.ID]bool)}
+	fsm := NewFSM(height, bcR)
+	fsm.SetLogger(log.TestingLogger())
+
+	params := BpPeerDefaultParams()
+	params.timeout = peerTimeout
+	// The receive rate monitor runs on the wall clock, disable the slow peer check.
+	params.minRecvRate = 0
+	params.clock = clock
+	fsm.pool.peerParams = params
+
+	return &fsmSimulator{
+		t:               t,
+		clock:           clock,
+		bcR:             bcR,
+		fsm:             fsm,
+		silent:          make(map[p2p.ID]bool),
+		processedHeight: height - 1,
+	}
+}
+
+func (s *fsmSimulator) handle(msg bcReactorMessage) error {
+	err := s.fsm.Handle(&msg)
+	s.trace = append(s.trace, fmt.Sprintf("%v -> %v (%v)", msg.String(), s.fsm.state, err))
+	return err
+}
+
+// drain processes the queued events generated by the simulated reactor.
+func (s *fsmSimulator) drain() {
+	for len(s.bcR.queue) > 0 {
+		msg := s.bcR.queue[0]
+		s.bcR.queue = s.bcR.queue[1:]
+		_ = s.handle(msg)
+	}
+}
+
+// send delivers an event to the FSM and then processes any events it caused.
+func (s *fsmSimulator) send(ev bReactorEvent, data bReactorEventData) error {
+	err := s.handle(bcReactorMessage{event: ev, data: data})
+	s.drain()
+	return err
+}
+
+func (s *fsmSimulator) start() {
+	_ = s.send(startFSMEv, bReactorEventData{})
+}
+
+// advance moves the simulated time forward, firing expired timers one at a time.
+func (s *fsmSimulator) advance(d time.Duration) {
+	end := s.clock.Now().Add(d)
+	for s.clock.fireNext(end) {
+		s.drain()
+	}
+	s.clock.now = end
+}
+
+func (s *fsmSimulator) connectPeer(peerID p2p.ID, height int64, silent bool) {
+	s.bcR.connected[peerID] = true
+	s.silent[peerID] = silent
+	_ = s.send(statusResponseEv, bReactorEventData{peerID: peerID, height: height})
+}
+
+func (s *fsmSimulator) disconnectPeer(peerID p2p.ID) {
+	if !s.bcR.connected[peerID] {
+		return
+	}
+	delete(s.bcR.connected, peerID)
+	_ = s.send(peerRemoveEv, bReactorEventData{peerID: peerID, err: errSwitchRemovesPeer})
+}
+
+func (s *fsmSimulator) connectedPeers() []p2p.ID {
+	ids := make([]p2p.ID, 0, len(s.bcR.connected))
+	for id := range s.bcR.connected {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func (s *fsmSimulator) makeRequests() {
+	if s.fsm.NeedsBlocks() {
+		_ = s.send(makeRequestsEv, bReactorEventData{})
+	}
+}
+
+// respond delivers the responses to the outstanding requests for which pick
+// returns true, in the order given by the requests slice. Requests made to
+// disconnected peers are dropped.
+func (s *fsmSimulator) respond(pick func(req simBlockRequest) bool) {
+	var pending []simBlockRequest
+	var responses []simBlockRequest
+	for _, req := range s.bcR.blockRequests {
+		switch {
+		case !s.bcR.connected[req.peerID]:
+		case s.silent[req.peerID] || !pick(req):
+			pending = append(pending, req)
+		default:
+			responses = append(responses, req)
+		}
+	}
+	s.bcR.blockRequests = pending
+
+	for _, req := range responses {
+		if !s.bcR.connected[req.peerID] {
+			continue
+		}
+		_ = s.send(blockResponseEv, bReactorEventData{
+			peerID: req.peerID,
+			height: req.height,
+			block:  makeSmallBlock(int(req.height)),
+			length: 100,
+		})
+	}
+}
+
+func (s *fsmSimulator) respondAll() {
+	s.respond(func(simBlockRequest) bool { return true })
+}
+
+// shuffleRequests permutes the outstanding requests so that responses arrive out of order.
+func (s *fsmSimulator) shuffleRequests(r *rand.Rand) {
+	r.Shuffle(len(s.bcR.blockRequests), func(i, j int) {
+		s.bcR.blockRequests[i], s.bcR.blockRequests[j] = s.bcR.blockRequests[j], s.bcR.blockRequests[i]
+	})
+}
+
+// processBlocks executes the blocks available at the pool height, as the reactor does.
+func (s *fsmSimulator) processBlocks() {
+	for !s.fsm.isCaughtUp() {
+		first, _, err := s.fsm.FirstTwoBlocks()
+		if err != nil {
+			return
+		}
+		height := first.Height
+		_ = s.send(processedBlockEv, bReactorEventData{})
+		s.processedHeight = height
+	}
+}
+
+// checkPoolInvariants verifies that every pending request or received block belongs to a pool peer.
+func (s *fsmSimulator) checkPoolInvariants() {
+	pool := s.fsm.pool
+	for h, peerID := range pool.blocks {
+		peer, ok := pool.peers[peerID]
+		require.True(s.t, ok, "block %d assigned to unknown peer %v", h, peerID)
+		_, ok = peer.blocks[h]
+		require.True(s.t, ok, "peer %v does not know about block %d", peerID, h)
+		require.True(s.t, h >= pool.Height, "block %d below pool height %d", h, pool.Height)
+	}
+	for _, peer := range pool.peers {
+		require.True(s.t, peer.NumPendingBlockRequests <= len(peer.blocks))
+	}
+}
+
+// ----------------------------------------
+// Tests
+
+func TestFSMSimNoPeers(t *testing.T) {
+	s := newFSMSimulator(t, 1, 15*time.Second)
+	s.start()
+	assert.Equal(t, 1, s.bcR.statusRequests)
+
+	s.advance(waitForPeerTimeout - time.Millisecond)
+	assert.Equal(t, "waitForPeer", s.fsm.state.name)
+	assert.False(t, s.bcR.switched)
+
+	s.advance(time.Millisecond)
+	assert.Equal(t, "finished", s.fsm.state.name)
+	assert.True(t, s.bcR.switched)
+}
+
+func TestFSMSimPeerTimeout(t *testing.T) {
+	const maxHeight = 30
+	s := newFSMSimulator(t, 1, 2*time.Second)
+	s.start()
+
+	// P1 is assigned the first requests but never responds.
+	s.connectPeer("P1", maxHeight, true)
+	s.connectPeer("P2", maxHeight, false)
+	s.makeRequests()
+	s.respondAll()
+	s.processBlocks()
+	assert.Equal(t, int64(0), s.processedHeight)
+
+	s.advance(2*time.Second - time.Millisecond)
+	assert.Empty(t, s.bcR.peerErrors)
+
+	// The peer timer expires before the state timer and P1 is removed.
+	s.advance(time.Millisecond)
+	require.Equal(t, []lastPeerErrorT{{peerID: "P1", err: errNoPeerResponse}}, s.bcR.peerErrors)
+	assert.Equal(t, 1, s.fsm.pool.NumPeers())
+
+	// The requests made to P1 are rescheduled to P2.
+	for i := 0; i < 10*maxHeight && !s.fsm.isCaughtUp(); i++ {
+		s.makeRequests()
+		s.respondAll()
+		s.processBlocks()
+	}
+	assert.Equal(t, "finished", s.fsm.state.name)
+	assert.Equal(t, int64(maxHeight-1), s.processedHeight)
+}
+
+func TestFSMSimStateTimeout(t *testing.T) {
+	const maxHeight = 10
+	// The peer timeout is larger than the state timeout.
+	s := newFSMSimulator(t, 1, time.Minute)
+	s.start()
+	s.connectPeer("P1", maxHeight, true)
+	s.connectPeer("P2", maxHeight, false)
+	s.makeRequests()
+	s.respondAll()
+
+	// The state timer is created when entering waitForPeer and only reset
+	// afterwards, so its timeout event still carries the waitForPeer name and
+	// is ignored in waitForBlock.
+	s.advance(waitForBlockAtCurrentHeightTimeout)
+	assert.Empty(t, s.bcR.peerErrors)
+	assert.Equal(t, 2, s.fsm.pool.NumPeers())
+	assert.Equal(t, "waitForBlock", s.fsm.state.name)
+	assert.Contains(t, s.trace[len(s.trace)-1], errTimeoutEventWrongState.Error())
+
+	// P1 is removed by its peer timeout instead.
+	s.advance(time.Minute)
+	require.Equal(t, []lastPeerErrorT{{peerID: "P1", err: errNoPeerResponse}}, s.bcR.peerErrors)
+	assert.Equal(t, 1, s.fsm.pool.NumPeers())
+
+	for i := 0; i < 10*maxHeight && !s.fsm.isCaughtUp(); i++ {
+		s.makeRequests()
+		s.respondAll()
+		s.processBlocks()
+	}
+	assert.Equal(t, "finished", s.fsm.state.name)
+}
+
+func TestFSMSimAllPeersLost(t *testing.T) {
+	const maxHeight = 20
+	s := newFSMSimulator(t, 1, 15*time.Second)
+	s.start()
+	s.connectPeer("P1", maxHeight, false)
+	for i := 0; i < 10 && s.processedHeight < 9; i++ {
+		s.makeRequests()
+		s.respond(func(req simBlockRequest) bool { return req.height <= 10 })
+		s.processBlocks()
+	}
+	require.Equal(t, int64(9), s.processedHeight)
+
+	s.disconnectPeer("P1")
+	assert.Equal(t, "waitForPeer", s.fsm.state.name)
+	s.advance(waitForPeerTimeout)
+	require.Equal(t, "waitForRetry", s.fsm.state.name)
+	assert.False(t, s.bcR.switched)
+	status := s.bcR.statuses[len(s.bcR.statuses)-1]
+	assert.Equal(t, "waitForRetry", status.State)
+	assert.Equal(t, int64(10), status.Height)
+	assert.Equal(t, 1, status.Retries)
+	assert.Equal(t, errAllPeersLost, status.Err)
+
+	// restart after the backoff, and back off twice as long without peers
+	statusRequests := s.bcR.statusRequests
+	s.advance(minRetryBackoff)
+	assert.Equal(t, "waitForPeer", s.fsm.state.name)
+	assert.Equal(t, statusRequests+1, s.bcR.statusRequests)
+	s.advance(waitForPeerTimeout)
+	assert.Equal(t, "waitForRetry", s.fsm.state.name)
+	s.advance(2*minRetryBackoff - time.Millisecond)
+	assert.Equal(t, "waitForRetry", s.fsm.state.name)
+	assert.Equal(t, 2, s.fsm.SyncStatus().Retries)
+
+	// a peer shows up, the sync resumes where it stopped
+	s.connectPeer("P2", maxHeight, false)
+	require.Equal(t, "waitForBlock", s.fsm.state.name)
+	s.makeRequests()
+	for _, req := range s.bcR.blockRequests {
+		assert.True(t, req.height >= 10, "block %d synced already", req.height)
+	}
+	for i := 0; i < 10*maxHeight && !s.fsm.isCaughtUp(); i++ {
+		s.makeRequests()
+		s.respondAll()
+		s.processBlocks()
+	}
+	assert.Equal(t, "finished", s.fsm.state.name)
+	assert.True(t, s.bcR.switched)
+	assert.Equal(t, int64(maxHeight-1), s.processedHeight)
+	assert.Equal(t, 0, s.fsm.SyncStatus().Retries)
+}
+
+func TestFSMSimOutOfOrderResponses(t *testing.T) {
+	const maxHeight = 200
+	for seed := int64(0); seed < 20; seed++ {
+		r := rand.New(rand.NewSource(seed))
+		s := newFSMSimulator(t, 1, 15*time.Second)
+		s.start()
+		for i := 0; i < 4; i++ {
+			s.connectPeer(p2p.ID(fmt.Sprintf("P%d", i)), maxHeight, false)
+		}
+
+		for step := 0; step < 10*maxHeight && !s.fsm.isCaughtUp(); step++ {
+			s.makeRequests()
+			s.shuffleRequests(r)
+			// Respond to a random subset of the requests, in random order.
+			s.respond(func(simBlockRequest) bool { return r.Intn(3) == 0 })
+			s.processBlocks()
+			s.checkPoolInvariants()
+			s.advance(time.Duration(r.Intn(100)) * time.Millisecond)
+		}
+
+		require.Equal(t, "finished", s.fsm.state.name, "seed %d", seed)
+		assert.Equal(t, int64(maxHeight-1), s.processedHeight, "seed %d", seed)
+		assert.Empty(t, s.bcR.peerErrors, "seed %d", seed)
+	}
+}
+
+// runChurn runs a simulation where peers randomly connect, disconnect, stay silent
+// and respond out of order. The anchor peer stays connected and always responds.
+func runChurn(t *testing.T, seed int64, maxHeight int64) *fsmSimulator {
+	r := rand.New(rand.NewSource(seed))
+	s := newFSMSimulator(t, 1, 2*time.Second)
+	s.start()
+	s.connectPeer("anchor", maxHeight, false)
+
+	nextPeer := 0
+	for step := 0; step < 100*int(maxHeight) && !s.fsm.isCaughtUp(); step++ {
+		switch r.Intn(10) {
+		case 0, 1:
+			nextPeer++
+			height := 1 + r.Int63n(maxHeight)
+			s.connectPeer(p2p.ID(fmt.Sprintf("P%03d", nextPeer)), height, r.Intn(4) == 0)
+		case 2:
+			peers := s.connectedPeers()
+			if peerID := peers[r.Intn(len(peers))]; peerID != "anchor" {
+				s.disconnectPeer(peerID)
+			}
+		}
+
+		s.makeRequests()
+		s.respond(func(req simBlockRequest) bool { return req.peerID == "anchor" })
+		s.shuffleRequests(r)
+		s.respond(func(simBlockRequest) bool { return r.Intn(2) == 0 })
+		s.processBlocks()
+		s.checkPoolInvariants()
+		s.advance(time.Duration(r.Intn(1000)) * time.Millisecond)
+	}
+	return s
+}
+
+func TestFSMSimPeerChurn(t *testing.T) {
+	const maxHeight = 100
+	for seed := int64(0); seed < 20; seed++ {
+		s := runChurn(t, seed, maxHeight)
+		require.Equal(t, "finished", s.fsm.state.name, "seed %d", seed)
+		assert.True(t, s.bcR.switched, "seed %d", seed)
+		assert.Equal(t, int64(maxHeight-1), s.processedHeight, "seed %d", seed)
+		for _, pErr := range s.bcR.peerErrors {
+			assert.NotEqual(t, p2p.ID("anchor"), pErr.peerID, "seed %d", seed)
+		}
+	}
+}
+
+func TestFSMSimDeterministic(t *testing.T) {
+	first := runChurn(t, 42, 50)
+	second := runChurn(t, 42, 50)
+	assert.Equal(t, first.trace, second.trace)
+	assert.Equal(t, first.bcR.peerErrors, second.bcR.peerErrors)
+}
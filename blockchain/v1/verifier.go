@@ -0,0 +1,57 @@
+package v1
+
+import (
+	"github.com/tendermint/tendermint/types"
+)
+
+// blockVerification is the verification of the commit of a block, done by
+// the verifyRoutine of the reactor while the previous block is applied, so
+// that the two run on different cores (see processBlock). The verification
+// of the next block only needs the validator set of its height, which is the
+// NextValidators of the state before the previous block is applied.
+//
+// It also makes the part set of the block, to hash its txs and to save it.
+type blockVerification struct {
+	block  *types.Block
+	commit *types.Commit // LastCommit of the following block
+	vals   *types.ValidatorSet
+
+	done  chan struct{} // closed once verified
+	parts *types.PartSet
+	id    types.BlockID
+	err   error
+}
+
+func newBlockVerification(block *types.Block, commit *types.Commit, vals *types.ValidatorSet) *blockVerification {
+	return &blockVerification{
+		block:  block,
+		commit: commit,
+		vals:   vals,
+		done:   make(chan struct{}),
+	}
+}
+
+// run verifies the commit of the block, and closes done.
+func (v *blockVerification) run(chainID string) {
+	v.parts = v.block.MakePartSet(types.BlockPartSizeBytes)
+	v.id = types.BlockID{Hash: v.block.Hash(), PartsHeader: v.parts.Header()}
+	v.err = v.vals.VerifyCommit(chainID, v.id, v.block.Height, v.commit)
+	close(v.done)
+}
+
+// matches returns true if the verification is the one of the block with the
+// commit: the blocks of the pool were not replaced since it started.
+func (v *blockVerification) matches(block *types.Block, commit *types.Commit) bool {
+	return v.block == block && v.commit == commit
+}
+
+// wait waits for the verification to be done, and returns false if quit is
+// closed first.
+func (v *blockVerification) wait(quit <-chan struct{}) bool {
+	select {
+	case <-v.done:
+		return true
+	case <-quit:
+		return false
+	}
+}
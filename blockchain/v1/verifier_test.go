@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/tendermint/tendermint/config"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestBlockVerification(t *testing.T) {
+	config = cfg.ResetTestRoot("blockchain_verifier_test")
+	defer os.RemoveAll(config.RootDir)
+	genDoc, privVals := randGenesisDoc(1, false, 30)
+	state, err := sm.MakeGenesisState(genDoc)
+	require.NoError(t, err)
+
+	block := makeBlock(1, state, types.NewCommit(types.BlockID{}, nil))
+	parts := block.MakePartSet(types.BlockPartSizeBytes)
+	blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+	vote := makeVote(&block.Header, blockID, state.Validators, privVals[0]).CommitSig()
+	commit := types.NewCommit(blockID, []*types.CommitSig{vote})
+
+	// verified in the background
+	v := newBlockVerification(block, commit, state.Validators.Copy())
+	go v.run(genDoc.ChainID)
+	require.True(t, v.wait(nil))
+	require.NoError(t, v.err)
+	assert.Equal(t, blockID, v.id)
+	assert.Equal(t, parts.Header(), v.parts.Header())
+	assert.True(t, v.matches(block, commit))
+	assert.False(t, v.matches(block, types.NewCommit(blockID, []*types.CommitSig{vote})))
+
+	// a commit for another block
+	otherID := types.BlockID{Hash: []byte("other block hash, 32 bytes long!"), PartsHeader: parts.Header()}
+	vote = makeVote(&block.Header, otherID, state.Validators, privVals[0]).CommitSig()
+	v = newBlockVerification(block, types.NewCommit(otherID, []*types.CommitSig{vote}), state.Validators.Copy())
+	v.run(genDoc.ChainID)
+	assert.Error(t, v.err)
+
+	// the reactor stops first
+	v = newBlockVerification(block, commit, state.Validators.Copy())
+	quit := make(chan struct{})
+	close(quit)
+	assert.False(t, v.wait(quit))
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/tendermint/tendermint/state"
-	"github.com/tendermint/tendermint/store"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -16,13 +15,13 @@ type processorContext interface {
 
 // nolint:unused
 type pContext struct {
-	store    *store.BlockStore
+	store    state.BlockStore
 	executor *state.BlockExecutor
 	state    *state.State
 }
 
 // nolint:unused,deadcode
-func newProcessorContext(st *store.BlockStore, ex *state.BlockExecutor, s *state.State) *pContext {
+func newProcessorContext(st state.BlockStore, ex *state.BlockExecutor, s *state.State) *pContext {
 	return &pContext{
 		store:    st,
 		executor: ex,
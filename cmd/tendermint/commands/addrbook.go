@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/p2p/pex"
+)
+
+// AddrBookCmd groups the commands managing the address book.
+var AddrBookCmd = &cobra.Command{
+	Use:   "addrbook",
+	Short: "Export and import the addresses of the address book",
+}
+
+// AddrBookExportCmd exports the addresses of the address book.
+var AddrBookExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the addresses of the address book",
+	Long: `Write the addresses of the address book to stdout (or --output) as JSON, with
+their connection history, last seen time, score and hints, to seed the address
+book of another node with 'tendermint addrbook import', or to debug
+connectivity issues.`,
+	RunE: addrBookExport,
+}
+
+// AddrBookImportCmd imports exported addresses into the address book.
+var AddrBookImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import exported addresses into the address book",
+	Long: `Add the addresses exported with 'tendermint addrbook export' to the address
+book, with their history and hints. The addresses known to be good are placed
+in the old buckets, so that they are dialed first. The addresses already in
+the book are skipped. The node must be stopped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: addrBookImport,
+}
+
+var (
+	addrBookMinScore float64
+	addrBookOnlyGood bool
+	addrBookOutput   string
+)
+
+func init() {
+	AddrBookExportCmd.Flags().Float64Var(&addrBookMinScore, "min_score", 0, "Minimum score of the exported addresses (0 to 1)")
+	AddrBookExportCmd.Flags().BoolVar(&addrBookOnlyGood, "only_good", false,
+		"Export only the addresses known to be good (in the old buckets)")
+	AddrBookExportCmd.Flags().StringVar(&addrBookOutput, "output", "", "Path of the export (stdout if empty)")
+	AddrBookCmd.AddCommand(AddrBookExportCmd, AddrBookImportCmd)
+}
+
+func addrBookExport(cmd *cobra.Command, args []string) error {
+	bz, err := pex.ExportAddrBook(config.P2P.AddrBookFile(), addrBookMinScore, addrBookOnlyGood)
+	if err != nil {
+		return err
+	}
+	if addrBookOutput == "" {
+		_, err = os.Stdout.Write(append(bz, '\n'))
+		return err
+	}
+	return ioutil.WriteFile(addrBookOutput, bz, 0644)
+}
+
+func addrBookImport(cmd *cobra.Command, args []string) error {
+	bz, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	n, err := pex.ImportAddrBook(config.P2P.AddrBookFile(), config.P2P.AddrBookStrict, bz)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d addresses into %s\n", n, config.P2P.AddrBookFile())
+	return nil
+}
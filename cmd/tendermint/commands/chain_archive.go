@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/consensus"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	nm "github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store/chainfile"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// ExportCmd exports a range of heights of the local chain to a chain archive.
+var ExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export blocks, commits and validator sets to a chain archive",
+	Long: `Export the headers, data, commits and validator sets of --heights to a chain
+archive in --dir (see docs/spec/blockchain/chain-archive.md), which other
+nodes import with "tendermint import", and third-party tools read without the
+Go types. The node must be stopped, as it holds the lock of the block store.`,
+	RunE: exportChain,
+}
+
+// ImportCmd applies the blocks of a chain archive, like fast sync does with
+// the blocks of peers.
+var ImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Apply the blocks of a chain archive, instead of fast syncing them",
+	Long: `Verify the blocks of the chain archive in --dir and apply them against the
+app, from the height following the one of the local block store, so that
+the node syncs from disk instead of from its peers. Each block is verified
+against the validators of the local state, not the ones of the archive. The
+node must be stopped, and the app must be running.`,
+	RunE: importChain,
+}
+
+var (
+	chainArchiveDir     string
+	chainArchiveHeights string
+)
+
+func init() {
+	ExportCmd.Flags().StringVar(&chainArchiveDir, "dir", "", "Directory of the chain archive to create")
+	ExportCmd.Flags().StringVar(&chainArchiveHeights, "heights", "",
+		"Heights to export, as H1..H2 or H1.. (all the stored heights if empty)")
+	ImportCmd.Flags().StringVar(&chainArchiveDir, "dir", "", "Directory of the chain archive to import")
+}
+
+func exportChain(cmd *cobra.Command, args []string) error {
+	if chainArchiveDir == "" {
+		return errors.New("--dir is required")
+	}
+	from, to, err := parseHeightRange(chainArchiveHeights)
+	if err != nil {
+		return err
+	}
+
+	genDoc, err := types.GenesisDocFromFile(config.GenesisFile())
+	if err != nil {
+		return err
+	}
+	blockStore, closeBlockStore, err := openBlockStore()
+	if err != nil {
+		return err
+	}
+	defer closeBlockStore()
+	stateDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: "state", Config: config})
+	if err != nil {
+		return errors.Wrap(err, "failed to open the state")
+	}
+	defer stateDB.Close()
+
+	from, to, err = writeChainArchive(chainArchiveDir, genDoc.ChainID, blockStore, stateDB, from, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported heights %d to %d to %s\n", from, to, chainArchiveDir)
+	return nil
+}
+
+// writeChainArchive writes the heights in [from, to] of the block store to a
+// chain archive in dir. 0 stands for the first or last stored height. The
+// commit for the last stored block is its seen commit.
+func writeChainArchive(
+	dir, chainID string,
+	blockStore sm.BlockStoreRPC,
+	stateDB dbm.DB,
+	from, to int64,
+) (int64, int64, error) {
+	if from == 0 || from < blockStore.Base() {
+		from = blockStore.Base()
+	}
+	if to == 0 || to > blockStore.Height() {
+		to = blockStore.Height()
+	}
+	if from == 0 || from > to {
+		return 0, 0, fmt.Errorf("no height to export in [%d, %d], the block store holds [%d, %d]",
+			from, to, blockStore.Base(), blockStore.Height())
+	}
+
+	w, err := chainfile.NewWriter(dir, chainID)
+	if err != nil {
+		return 0, 0, err
+	}
+	for height := from; height <= to; height++ {
+		block := blockStore.LoadBlock(height)
+		if block == nil {
+			w.Close()
+			return 0, 0, fmt.Errorf("block %d is missing from the block store", height)
+		}
+		commit := blockStore.LoadBlockCommit(height)
+		if commit == nil {
+			commit = blockStore.LoadSeenCommit(height)
+		}
+		vals, err := sm.LoadValidators(stateDB, height)
+		if err != nil {
+			w.Close()
+			return 0, 0, errors.Wrapf(err, "failed to load the validators of height %d", height)
+		}
+		if err := w.Append(chainfile.NewRecord(block, commit, vals)); err != nil {
+			w.Close()
+			return 0, 0, errors.Wrapf(err, "failed to export height %d", height)
+		}
+	}
+	return from, to, w.Close()
+}
+
+func importChain(cmd *cobra.Command, args []string) error {
+	if chainArchiveDir == "" {
+		return errors.New("--dir is required")
+	}
+	archive, err := chainfile.Open(chainArchiveDir)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	genDoc, err := types.GenesisDocFromFile(config.GenesisFile())
+	if err != nil {
+		return err
+	}
+	blockStore, closeBlockStore, err := openBlockStore()
+	if err != nil {
+		return err
+	}
+	defer closeBlockStore()
+	stateDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: "state", Config: config})
+	if err != nil {
+		return errors.Wrap(err, "failed to open the state")
+	}
+	defer stateDB.Close()
+	state, err := sm.LoadStateFromDBOrGenesisDoc(stateDB, genDoc)
+	if err != nil {
+		return err
+	}
+
+	proxyApp := proxy.NewAppConns(proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()))
+	if err := proxyApp.Start(); err != nil {
+		return errors.Wrap(err, "failed to connect to the app")
+	}
+	defer proxyApp.Stop()
+	handshaker := consensus.NewHandshaker(stateDB, state, blockStore, genDoc)
+	handshaker.SetLogger(logger)
+	if err := handshaker.Handshake(proxyApp); err != nil {
+		return errors.Wrap(err, "error during handshake")
+	}
+
+	blockExec := sm.NewBlockExecutor(stateDB, logger, proxyApp.Consensus(), mock.Mempool{}, sm.MockEvidencePool{})
+	from, to, err := applyChainArchive(archive, sm.LoadState(stateDB), blockExec, blockStore, logger)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported heights %d to %d\n", from, to)
+	return nil
+}
+
+// applyChainArchive saves and applies the blocks of the archive following
+// the state, like fast sync: each block is verified with the commit for it,
+// signed by the validators of the state.
+func applyChainArchive(
+	archive *chainfile.Reader,
+	state sm.State,
+	blockExec *sm.BlockExecutor,
+	blockStore sm.BlockStore,
+	logger log.Logger,
+) (int64, int64, error) {
+	m := archive.Manifest()
+	from := state.LastBlockHeight + 1
+	switch {
+	case m.ChainID != state.ChainID:
+		return 0, 0, fmt.Errorf("the archive is of chain %q, not %q", m.ChainID, state.ChainID)
+	case blockStore.Height() != state.LastBlockHeight:
+		return 0, 0, fmt.Errorf("the block store is at height %d, but the state at height %d",
+			blockStore.Height(), state.LastBlockHeight)
+	case from < m.FirstHeight || from > m.LastHeight:
+		return 0, 0, fmt.Errorf("the archive holds [%d, %d], not the next height %d",
+			m.FirstHeight, m.LastHeight, from)
+	}
+
+	for height := from; height <= m.LastHeight; height++ {
+		rec, err := archive.Read(height)
+		if err != nil {
+			return 0, 0, err
+		}
+		if rec.Commit == nil {
+			return 0, 0, fmt.Errorf("the commit for block %d is missing", height)
+		}
+		block := rec.Block()
+		parts := block.MakePartSet(types.BlockPartSizeBytes)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		if err := state.Validators.VerifyCommit(state.ChainID, blockID, height, rec.Commit); err != nil {
+			return 0, 0, errors.Wrapf(err, "invalid commit for block %d", height)
+		}
+
+		blockStore.SaveBlock(block, parts, rec.Commit)
+		state, err = blockExec.ApplyBlock(state, blockID, block)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "failed to apply block %d", height)
+		}
+		logger.Info("Imported block", "height", height, "appHash", state.AppHash)
+	}
+	return from, m.LastHeight, nil
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/store/chainfile"
+	"github.com/tendermint/tendermint/types"
+)
+
+// testNode is the state, block store and app of a node.
+type testNode struct {
+	state      sm.State
+	stateDB    dbm.DB
+	blockStore *store.BlockStore
+	blockExec  *sm.BlockExecutor
+}
+
+func newTestNode(t *testing.T, genDoc *types.GenesisDoc) *testNode {
+	state, err := sm.MakeGenesisState(genDoc)
+	require.NoError(t, err)
+	stateDB := dbm.NewMemDB()
+	sm.SaveState(stateDB, state)
+	app := startApp(t, kvstore.NewKVStoreApplication())
+	req, err := sm.InitChainRequest(genDoc)
+	require.NoError(t, err)
+	_, err = app.Consensus().InitChainSync(req)
+	require.NoError(t, err)
+	return &testNode{
+		state:      state,
+		stateDB:    stateDB,
+		blockStore: store.NewBlockStore(dbm.NewMemDB()),
+		blockExec: sm.NewBlockExecutor(stateDB, log.TestingLogger(), app.Consensus(),
+			mock.Mempool{}, sm.MockEvidencePool{}),
+	}
+}
+
+func TestExportImportChainArchive(t *testing.T) {
+	pv := types.NewMockPV()
+	genDoc := &types.GenesisDoc{
+		ChainID:     "chain-archive",
+		GenesisTime: time.Now(),
+		Validators:  []types.GenesisValidator{{PubKey: pv.GetPubKey(), Power: 10}},
+	}
+	require.NoError(t, genDoc.ValidateAndComplete())
+
+	// build a chain of 5 blocks
+	src := newTestNode(t, genDoc)
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+	for h := int64(1); h <= 5; h++ {
+		txs := []types.Tx{types.Tx(fmt.Sprintf("key%d=value", h))}
+		block, parts := src.state.MakeBlock(h, txs, lastCommit, nil, src.state.Validators.GetProposer().Address)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		voteSet := types.NewVoteSet(genDoc.ChainID, h, 0, types.PrecommitType, src.state.Validators)
+		commit, err := types.MakeCommit(blockID, h, 0, voteSet, []types.PrivValidator{pv})
+		require.NoError(t, err)
+		src.blockStore.SaveBlock(block, parts, commit)
+		src.state, err = src.blockExec.ApplyBlock(src.state, blockID, block)
+		require.NoError(t, err)
+		lastCommit = commit
+	}
+
+	dir, err := ioutil.TempDir("", "chain_archive_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	from, to, err := writeChainArchive(dir, genDoc.ChainID, src.blockStore, src.stateDB, 0, 4)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, from)
+	assert.EqualValues(t, 4, to)
+	archive, err := chainfile.Open(dir)
+	require.NoError(t, err)
+	defer archive.Close()
+
+	// a fresh node syncs from the archive
+	dst := newTestNode(t, genDoc)
+	from, to, err = applyChainArchive(archive, dst.state, dst.blockExec, dst.blockStore, log.TestingLogger())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, from)
+	assert.EqualValues(t, 4, to)
+	assert.EqualValues(t, 4, dst.blockStore.Height())
+	dst.state = sm.LoadState(dst.stateDB)
+	assert.Equal(t, []byte(src.blockStore.LoadBlockMeta(5).Header.AppHash), dst.state.AppHash)
+
+	// the archive must hold the next height
+	_, _, err = applyChainArchive(archive, dst.state, dst.blockExec, dst.blockStore, log.TestingLogger())
+	assert.Error(t, err)
+
+	// and the blocks must be signed by the validators of the state
+	other := newTestNode(t, genDoc)
+	other.state.Validators, _ = types.RandValidatorSet(1, 10)
+	_, _, err = applyChainArchive(archive, other.state, other.blockExec, other.blockStore, log.TestingLogger())
+	assert.Error(t, err)
+	assert.EqualValues(t, 0, other.blockStore.Height())
+}
@@ -0,0 +1,274 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	rpcclient "github.com/tendermint/tendermint/rpc/lib/client"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+)
+
+// ConsoleCmd inspects and operates a running node over its admin API.
+var ConsoleCmd = &cobra.Command{
+	Use:   "console [command [args...]]",
+	Short: "Inspect and operate a running node over its admin API",
+	Long: `Connect to the admin socket of a running node (admin_socket in the config), or
+to its admin API (rpc.admin_laddr) with --addr, to inspect its fast sync, peers
+and consensus, change its log level, pause and resume the fast sync, evict
+transactions, prune blocks or compact its databases. Commands are read from
+the standard input, one per line, or the single command given as arguments is
+run. Type "help" for the list of commands.`,
+	RunE: console,
+}
+
+var (
+	consoleSocket    string
+	consoleAddr      string
+	consoleTokenFile string
+	consoleCertFile  string
+	consoleKeyFile   string
+	consoleCAFile    string
+)
+
+func init() {
+	ConsoleCmd.Flags().StringVar(&consoleSocket, "socket", "",
+		"Path of the admin socket of the node (default: admin_socket of the config)")
+	ConsoleCmd.Flags().StringVar(&consoleAddr, "addr", "",
+		"Address of the admin API of the node (rpc.admin_laddr), e.g. https://host:26659, instead of its admin socket")
+	ConsoleCmd.Flags().StringVar(&consoleTokenFile, "token_file", "",
+		"File holding the token authenticating with the admin API")
+	ConsoleCmd.Flags().StringVar(&consoleCertFile, "tls_cert_file", "",
+		"Certificate authenticating with the admin API over HTTPS")
+	ConsoleCmd.Flags().StringVar(&consoleKeyFile, "tls_key_file", "",
+		"Private key of --tls_cert_file")
+	ConsoleCmd.Flags().StringVar(&consoleCAFile, "tls_ca_file", "",
+		"Certificates of the authorities signing the certificate of the admin API (default: the system ones)")
+}
+
+// consoleClient calls the routes of the admin API of a node.
+type consoleClient struct {
+	client  *http.Client
+	baseURL string
+	token   string // sent as a bearer token, if not empty
+}
+
+func newConsoleClient() (*consoleClient, error) {
+	c := new(consoleClient)
+	if consoleTokenFile != "" {
+		token, err := ioutil.ReadFile(consoleTokenFile)
+		if err != nil {
+			return nil, err
+		}
+		c.token = strings.TrimSpace(string(token))
+	}
+
+	if consoleAddr == "" {
+		socket := consoleSocket
+		if socket == "" {
+			if config.AdminSocket == "" {
+				return nil, errors.New("admin_socket is not set in the config (or use --socket or --addr)")
+			}
+			socket = config.AdminSocketFile()
+		}
+		c.client = rpcclient.DefaultHTTPClient("unix://" + socket)
+		c.baseURL = "http://admin"
+		return c, nil
+	}
+
+	u, err := url.Parse(consoleAddr)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "tcp":
+		c.client = http.DefaultClient
+	case "https":
+		tlsConfig := new(tls.Config)
+		if consoleCertFile != "" {
+			cert, err := tls.LoadX509KeyPair(consoleCertFile, consoleKeyFile)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to load the client certificate")
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		if consoleCAFile != "" {
+			pem, err := ioutil.ReadFile(consoleCAFile)
+			if err != nil {
+				return nil, err
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in %s", consoleCAFile)
+			}
+		}
+		c.client = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q of --addr (want http, tcp or https)", u.Scheme)
+	}
+	c.baseURL = (&url.URL{Scheme: strings.Replace(u.Scheme, "tcp", "http", 1), Host: u.Host}).String()
+	return c, nil
+}
+
+// consoleCommand is a shortcut for an admin route, taking its parameters in
+// order.
+type consoleCommand struct {
+	name   string
+	route  string
+	params []string
+	help   string
+}
+
+var consoleCommands = []consoleCommand{
+	{"status", "status", nil, "status of the node"},
+	{"peers", "net_info", nil, "peers and their connections"},
+	{"consensus", "dump_consensus_state", nil, "round state of the consensus and of the peers"},
+	{"sync", "sync_status", nil, "state of the fast sync and of its peers"},
+	{"pause", "pause_sync", nil, "stop requesting and applying blocks"},
+	{"resume", "resume_sync", nil, "resume the paused fast sync"},
+	{"evict", "evict_tx", []string{"hash"}, "evict the tx of the hash (0x...) from the mempool"},
+	{"log_level", "set_log_level", []string{"level"}, "set the log level (e.g. consensus:debug,*:info)"},
+	{"prune", "prune_blocks", []string{"height"}, "prune the blocks below height"},
+	{"compact", "compact_db", nil, "compact all the databases"},
+	{"backfill", "backfill_results", []string{"height"}, "backfill the missing block results up to height"},
+	{"backfill_status", "backfill_status", nil, "progress of the backfill of the block results"},
+}
+
+func console(cmd *cobra.Command, args []string) error {
+	client, err := newConsoleClient()
+	if err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		return runConsoleCommand(client, args, os.Stdout)
+	}
+
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("> ")
+		if !in.Scan() {
+			fmt.Println()
+			return in.Err()
+		}
+		fields := strings.Fields(in.Text())
+		switch {
+		case len(fields) == 0:
+			continue
+		case fields[0] == "exit" || fields[0] == "quit":
+			return nil
+		}
+		if err := runConsoleCommand(client, fields, os.Stdout); err != nil {
+			fmt.Println("Error:", err)
+		}
+	}
+}
+
+// runConsoleCommand runs the command of the words of args: either a shortcut
+// followed by its parameters, or an admin route followed by key=value
+// parameters. The result is written to w.
+func runConsoleCommand(c *consoleClient, args []string, w io.Writer) error {
+	if args[0] == "help" {
+		printConsoleHelp(w)
+		return nil
+	}
+
+	route, params := args[0], url.Values{}
+	if c, ok := findConsoleCommand(args[0]); ok {
+		if len(args)-1 != len(c.params) {
+			return fmt.Errorf("usage: %s %s", c.name, strings.Join(c.params, " "))
+		}
+		route = c.route
+		for i, param := range c.params {
+			params.Set(param, consoleParamValue(args[i+1]))
+		}
+	} else {
+		for _, arg := range args[1:] {
+			kv := strings.SplitN(arg, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("invalid parameter %q (want key=value)", arg)
+			}
+			params.Set(kv[0], consoleParamValue(kv[1]))
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/"+route+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusUnauthorized {
+		return errors.New("authentication required (see --token_file and --tls_cert_file)")
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	var response rpctypes.RPCResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return errors.Wrapf(err, "unexpected response %q", body)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, response.Result, "", "  "); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, out.String())
+	return err
+}
+
+func findConsoleCommand(name string) (consoleCommand, bool) {
+	for _, c := range consoleCommands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return consoleCommand{}, false
+}
+
+var consoleIntRegexp = regexp.MustCompile(`^-?[0-9]+$`)
+
+// consoleParamValue returns the URI parameter of value: numbers, hex bytes
+// (0x...) and arrays as is, and other values quoted, as the RPC server
+// expects strings.
+func consoleParamValue(value string) string {
+	if consoleIntRegexp.MatchString(value) || strings.HasPrefix(value, `"`) ||
+		strings.HasPrefix(value, "0x") || strings.HasPrefix(value, "[") {
+		return value
+	}
+	return `"` + value + `"`
+}
+
+func printConsoleHelp(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, c := range consoleCommands {
+		fmt.Fprintf(tw, "%s %s\t%s\n", c.name, strings.Join(c.params, " "), c.help)
+	}
+	fmt.Fprintf(tw, "<route> [key=value...]\tcall any admin route\n")
+	fmt.Fprintf(tw, "exit\tleave the console\n")
+	tw.Flush()
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rpcclient "github.com/tendermint/tendermint/rpc/lib/client"
+)
+
+func TestRunConsoleCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "console_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "admin.sock")
+
+	// a fake admin server echoing the requests
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	defer listener.Close()
+	requests := make(chan string, 10)
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- strings.TrimSpace(r.URL.Path + "?" + r.URL.RawQuery + " " + r.Header.Get("Authorization"))
+		if r.URL.Path == "/pause_sync" {
+			w.Write([]byte(`{"jsonrpc":"2.0","id":"","error":{"code":-32603,"message":"Internal error","data":"not fast syncing"}}`))
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":"","result":{"pruned":"5"}}`))
+	}))
+
+	client := &consoleClient{
+		client:  rpcclient.DefaultHTTPClient("unix://" + socket),
+		baseURL: "http://admin",
+	}
+	out := new(bytes.Buffer)
+
+	err = runConsoleCommand(client, []string{"prune", "5"}, out)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"pruned\": \"5\"\n}\n", out.String())
+
+	err = runConsoleCommand(client, []string{"log_level", "*:debug"}, out)
+	require.NoError(t, err)
+
+	err = runConsoleCommand(client, []string{"pause"}, out)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "not fast syncing")
+	}
+
+	// wrong number of parameters
+	err = runConsoleCommand(client, []string{"prune"}, out)
+	assert.Error(t, err)
+
+	client.token = "s3cret"
+	err = runConsoleCommand(client, []string{"evict", "0xAB"}, out)
+	require.NoError(t, err)
+	err = runConsoleCommand(client, []string{"dial_peers", `peers=["id@1.2.3.4:26656"]`}, out)
+	require.NoError(t, err)
+
+	for _, request := range []string{
+		"/prune_blocks?height=5",
+		"/set_log_level?level=%22%2A%3Adebug%22",
+		"/pause_sync?",
+		"/evict_tx?hash=0xAB Bearer s3cret",
+		"/dial_peers?peers=%5B%22id%401.2.3.4%3A26656%22%5D Bearer s3cret",
+	} {
+		assert.Equal(t, request, <-requests)
+	}
+}
@@ -0,0 +1,187 @@
+package commands
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+)
+
+// DebugCmd groups the commands helping to debug a node.
+var DebugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "Debug a running node",
+}
+
+// DebugDumpCmd collects the state of a running node into a tarball.
+var DebugDumpCmd = &cobra.Command{
+	Use:   "dump",
+	Short: "Collect debugging data of a running node into a tarball",
+	Long: `Collect the status, net_info and consensus state of a running node through its
+RPC, its goroutines through its profiling server (prof_laddr), the tail of
+its consensus WAL and its config into a gzipped tarball, to attach to bug
+reports (e.g. when consensus is stuck). The data which can't be collected is
+skipped, its error is written to errors.txt in the tarball.`,
+	RunE: debugDump,
+}
+
+var (
+	debugRPCAddr  string
+	debugProfAddr string
+	debugWALTail  int64
+	debugOutput   string
+)
+
+func init() {
+	DebugDumpCmd.Flags().StringVar(&debugRPCAddr, "rpc_laddr", "",
+		"RPC address of the node (rpc.laddr of the config if empty)")
+	DebugDumpCmd.Flags().StringVar(&debugProfAddr, "prof_laddr", "",
+		"Address of the profiling server of the node (prof_laddr of the config if empty)")
+	DebugDumpCmd.Flags().Int64Var(&debugWALTail, "wal_tail", 1<<20, "Number of bytes of the end of the consensus WAL to collect")
+	DebugDumpCmd.Flags().StringVar(&debugOutput, "output", "",
+		"Path of the tarball (debug-<time>.tar.gz in the current directory if empty)")
+	DebugCmd.AddCommand(DebugDumpCmd)
+}
+
+func debugDump(cmd *cobra.Command, args []string) error {
+	rpcAddr, profAddr, output := debugRPCAddr, debugProfAddr, debugOutput
+	if rpcAddr == "" {
+		rpcAddr = config.RPC.ListenAddress
+	}
+	if profAddr == "" {
+		profAddr = config.ProfListenAddress
+	}
+	if output == "" {
+		output = fmt.Sprintf("debug-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+
+	var collectErrs []byte
+	for _, c := range debugCollectors(rpcclient.NewHTTP(rpcAddr, "/websocket"), profAddr) {
+		bz, err := c.collect()
+		if err == nil {
+			err = addTarFile(tw, c.name, bz)
+		}
+		if err != nil {
+			logger.Error("Failed to collect debugging data", "file", c.name, "err", err)
+			collectErrs = append(collectErrs, fmt.Sprintf("%s: %v\n", c.name, err)...)
+		}
+	}
+	if len(collectErrs) > 0 {
+		if err := addTarFile(tw, "errors.txt", collectErrs); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gzw.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the debugging data to %s\n", output)
+	return nil
+}
+
+// debugCollector collects a file of the tarball.
+type debugCollector struct {
+	name    string
+	collect func() ([]byte, error)
+}
+
+func debugCollectors(rpc *rpcclient.HTTP, profAddr string) []debugCollector {
+	rpcJSON := func(call func() (interface{}, error)) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			res, err := call()
+			if err != nil {
+				return nil, err
+			}
+			return cdc.MarshalJSONIndent(res, "", "  ")
+		}
+	}
+	return []debugCollector{
+		{"status.json", rpcJSON(func() (interface{}, error) { return rpc.Status() })},
+		{"net_info.json", rpcJSON(func() (interface{}, error) { return rpc.NetInfo() })},
+		{"consensus_state.json", rpcJSON(func() (interface{}, error) { return rpc.DumpConsensusState() })},
+		{"goroutines.txt", func() ([]byte, error) {
+			return httpGet(profAddr, "/debug/pprof/goroutine?debug=2")
+		}},
+		{"service_goroutines.json", func() ([]byte, error) {
+			return httpGet(profAddr, "/debug/services/goroutines")
+		}},
+		{"cs.wal.tail", func() ([]byte, error) {
+			return readFileTail(config.Consensus.WalFile(), debugWALTail)
+		}},
+		{"config.toml", func() ([]byte, error) {
+			return ioutil.ReadFile(filepath.Join(config.RootDir, "config", "config.toml"))
+		}},
+	}
+}
+
+// httpGet returns the body of the response to a GET of path on the server at
+// addr (host:port).
+func httpGet(addr, path string) ([]byte, error) {
+	if addr == "" {
+		return nil, errors.New("the profiling server is disabled (empty prof_laddr)")
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get("http://" + addr + path)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", path, res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// readFileTail returns the last n bytes of the file.
+func readFileTail(path string, n int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if size := info.Size(); size > n {
+		if _, err := f.Seek(size-n, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
+	return ioutil.ReadAll(f)
+}
+
+func addTarFile(tw *tar.Writer, name string, bz []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(bz)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(bz)
+	return err
+}
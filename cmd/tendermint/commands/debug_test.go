@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFileTail(t *testing.T) {
+	f, err := ioutil.TempFile("", "debug_test")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("0123456789")
+	require.NoError(t, err)
+	f.Close()
+
+	bz, err := readFileTail(f.Name(), 4)
+	require.NoError(t, err)
+	assert.Equal(t, "6789", string(bz))
+	bz, err = readFileTail(f.Name(), 100)
+	require.NoError(t, err)
+	assert.Equal(t, "0123456789", string(bz))
+}
+
+func TestHTTPGet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/goroutine" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("goroutine 1 [running]"))
+	}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	bz, err := httpGet(addr, "/debug/pprof/goroutine?debug=2")
+	require.NoError(t, err)
+	assert.Equal(t, "goroutine 1 [running]", string(bz))
+	_, err = httpGet(addr, "/other")
+	assert.Error(t, err)
+	_, err = httpGet("", "/debug/pprof/goroutine?debug=2")
+	assert.Error(t, err, "disabled profiling server")
+}
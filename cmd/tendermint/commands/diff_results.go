@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+// DiffResultsCmd compares the results of a block on two nodes.
+var DiffResultsCmd = &cobra.Command{
+	Use:   "diff-results",
+	Short: "Compare the results of executing a block on two nodes",
+	Long: `Fetch the results of the block at --height from the RPC of two nodes and
+report their differences: the DeliverTx codes, data, gas and events, the
+BeginBlock and EndBlock events, and the validator and consensus params
+updates. The logs and infos, which don't affect consensus, are ignored.
+
+It's meant to triage the divergence of nodes on the app hash: the first
+block whose results differ points at the non-deterministic txs. The command
+fails if the results differ.`,
+	RunE: diffResults,
+}
+
+var (
+	diffResultsRPCA   string
+	diffResultsRPCB   string
+	diffResultsHeight int64
+	diffResultsOutput string
+)
+
+func init() {
+	DiffResultsCmd.Flags().StringVar(&diffResultsRPCA, "rpc-a", "", "RPC address of the first node (e.g. tcp://10.0.0.1:26657)")
+	DiffResultsCmd.Flags().StringVar(&diffResultsRPCB, "rpc-b", "", "RPC address of the second node")
+	DiffResultsCmd.Flags().Int64Var(&diffResultsHeight, "height", 0, "Height of the block to compare")
+	DiffResultsCmd.Flags().StringVar(&diffResultsOutput, "output", "text", "Output format (text | json)")
+}
+
+func diffResults(cmd *cobra.Command, args []string) error {
+	if diffResultsRPCA == "" || diffResultsRPCB == "" {
+		return errors.New("both --rpc-a and --rpc-b must be set")
+	}
+	if diffResultsHeight <= 0 {
+		return errors.New("--height must be positive")
+	}
+	switch diffResultsOutput {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown output format %q (want text or json)", diffResultsOutput)
+	}
+
+	resultsA, err := fetchBlockResults(diffResultsRPCA, diffResultsHeight)
+	if err != nil {
+		return err
+	}
+	resultsB, err := fetchBlockResults(diffResultsRPCB, diffResultsHeight)
+	if err != nil {
+		return err
+	}
+
+	diffs := diffBlockResults(resultsA, resultsB)
+	if err := writeResultsDiffs(os.Stdout, diffResultsOutput, diffResultsHeight, diffs); err != nil {
+		return err
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("the results of block %d differ in %d places", diffResultsHeight, len(diffs))
+	}
+	return nil
+}
+
+func fetchBlockResults(addr string, height int64) (*sm.ABCIResponses, error) {
+	res, err := rpcclient.NewHTTP(addr, "/websocket").BlockResults(&height)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch the results of block %d from %s", height, addr)
+	}
+	if res.Results == nil {
+		return nil, fmt.Errorf("%s returned no results for block %d", addr, height)
+	}
+	return res.Results, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// resultsDiff is a difference between the results of a block on two nodes.
+// Path locates the field, e.g. "deliver_tx[3].gas_used"; A and B are its
+// values on each node, "" if missing.
+type resultsDiff struct {
+	Path string `json:"path"`
+	A    string `json:"a"`
+	B    string `json:"b"`
+}
+
+// diffBlockResults returns the differences between the results of a block on
+// two nodes, in the order of the results.
+func diffBlockResults(a, b *sm.ABCIResponses) []resultsDiff {
+	var diffs []resultsDiff
+	add := func(path string, va, vb interface{}) {
+		sa, sb := fmt.Sprint(va), fmt.Sprint(vb)
+		if sa != sb {
+			diffs = append(diffs, resultsDiff{Path: path, A: sa, B: sb})
+		}
+	}
+
+	var (
+		beginA, beginB abci.ResponseBeginBlock
+		endA, endB     abci.ResponseEndBlock
+	)
+	if a.BeginBlock != nil {
+		beginA = *a.BeginBlock
+	}
+	if b.BeginBlock != nil {
+		beginB = *b.BeginBlock
+	}
+	if a.EndBlock != nil {
+		endA = *a.EndBlock
+	}
+	if b.EndBlock != nil {
+		endB = *b.EndBlock
+	}
+
+	diffEvents(add, "begin_block", beginA.Events, beginB.Events)
+
+	add("deliver_tx.count", len(a.DeliverTx), len(b.DeliverTx))
+	for i := 0; i < len(a.DeliverTx) && i < len(b.DeliverTx); i++ {
+		txA, txB := a.DeliverTx[i], b.DeliverTx[i]
+		if txA == nil {
+			txA = &abci.ResponseDeliverTx{}
+		}
+		if txB == nil {
+			txB = &abci.ResponseDeliverTx{}
+		}
+		path := fmt.Sprintf("deliver_tx[%d]", i)
+		add(path+".code", txA.Code, txB.Code)
+		add(path+".codespace", txA.Codespace, txB.Codespace)
+		add(path+".data", fmt.Sprintf("%X", txA.Data), fmt.Sprintf("%X", txB.Data))
+		add(path+".gas_wanted", txA.GasWanted, txB.GasWanted)
+		add(path+".gas_used", txA.GasUsed, txB.GasUsed)
+		diffEvents(add, path, txA.Events, txB.Events)
+	}
+
+	diffEvents(add, "end_block", endA.Events, endB.Events)
+
+	// validator updates, by public key
+	powersA, powersB := validatorUpdatePowers(endA.ValidatorUpdates), validatorUpdatePowers(endB.ValidatorUpdates)
+	keys := make([]string, 0, len(powersA)+len(powersB))
+	for key := range powersA {
+		keys = append(keys, key)
+	}
+	for key := range powersB {
+		if _, ok := powersA[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add(fmt.Sprintf("end_block.validator_updates[%s]", key), powersA[key], powersB[key])
+	}
+
+	add("end_block.consensus_param_updates", paramsString(endA.ConsensusParamUpdates),
+		paramsString(endB.ConsensusParamUpdates))
+
+	return diffs
+}
+
+func diffEvents(add func(path string, va, vb interface{}), path string, a, b []abci.Event) {
+	add(path+".events.count", len(a), len(b))
+	for i := 0; i < len(a) && i < len(b); i++ {
+		add(fmt.Sprintf("%s.events[%d]", path, i), eventString(a[i]), eventString(b[i]))
+	}
+}
+
+// eventString formats the event as type{key=value,...}.
+func eventString(e abci.Event) string {
+	attrs := make([]string, len(e.Attributes))
+	for i, attr := range e.Attributes {
+		attrs[i] = fmt.Sprintf("%s=%s", attr.Key, attr.Value)
+	}
+	return fmt.Sprintf("%s{%s}", e.Type, strings.Join(attrs, ","))
+}
+
+// validatorUpdatePowers returns the updated powers, formatted, by public key.
+func validatorUpdatePowers(updates []abci.ValidatorUpdate) map[string]string {
+	powers := make(map[string]string, len(updates))
+	for _, u := range updates {
+		powers[fmt.Sprintf("%s:%X", u.PubKey.Type, u.PubKey.Data)] = fmt.Sprint(u.Power)
+	}
+	return powers
+}
+
+func paramsString(params *abci.ConsensusParams) string {
+	if params == nil {
+		return ""
+	}
+	return params.String()
+}
+
+func writeResultsDiffs(w io.Writer, output string, height int64, diffs []resultsDiff) error {
+	if output == "json" {
+		if diffs == nil {
+			diffs = []resultsDiff{}
+		}
+		bz, err := json.MarshalIndent(struct {
+			Height      int64         `json:"height"`
+			Differences []resultsDiff `json:"differences"`
+		}{height, diffs}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(bz))
+		return err
+	}
+
+	if len(diffs) == 0 {
+		_, err := fmt.Fprintf(w, "The results of block %d are identical\n", height)
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tA\tB")
+	for _, d := range diffs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", d.Path, d.A, d.B)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+func makeResults(code uint32, gasUsed int64, amount string, power int64) *sm.ABCIResponses {
+	transfer := abci.Event{Type: "transfer", Attributes: []cmn.KVPair{{Key: []byte("amount"), Value: []byte(amount)}}}
+	return &sm.ABCIResponses{
+		BeginBlock: &abci.ResponseBeginBlock{},
+		DeliverTx: []*abci.ResponseDeliverTx{
+			{Code: 0, GasUsed: 10, Log: "ok"},
+			{Code: code, GasUsed: gasUsed, Events: []abci.Event{transfer}},
+		},
+		EndBlock: &abci.ResponseEndBlock{
+			ValidatorUpdates: []abci.ValidatorUpdate{
+				{PubKey: abci.PubKey{Type: "ed25519", Data: []byte{1}}, Power: power},
+			},
+		},
+	}
+}
+
+func TestDiffBlockResults(t *testing.T) {
+	a := makeResults(0, 20, "5", 10)
+	assert.Empty(t, diffBlockResults(a, makeResults(0, 20, "5", 10)))
+
+	// the logs are ignored
+	b := makeResults(1, 25, "6", 0)
+	b.DeliverTx[0].Log = "different"
+	b.EndBlock.ValidatorUpdates = append(b.EndBlock.ValidatorUpdates,
+		abci.ValidatorUpdate{PubKey: abci.PubKey{Type: "ed25519", Data: []byte{2}}, Power: 3})
+	assert.Equal(t, []resultsDiff{
+		{Path: "deliver_tx[1].code", A: "0", B: "1"},
+		{Path: "deliver_tx[1].gas_used", A: "20", B: "25"},
+		{Path: "deliver_tx[1].events[0]", A: "transfer{amount=5}", B: "transfer{amount=6}"},
+		{Path: "end_block.validator_updates[ed25519:01]", A: "10", B: "0"},
+		{Path: "end_block.validator_updates[ed25519:02]", A: "", B: "3"},
+	}, diffBlockResults(a, b))
+
+	b = makeResults(0, 20, "5", 10)
+	b.DeliverTx = b.DeliverTx[:1]
+	b.EndBlock = nil
+	assert.Equal(t, []resultsDiff{
+		{Path: "deliver_tx.count", A: "2", B: "1"},
+		{Path: "end_block.validator_updates[ed25519:01]", A: "10", B: ""},
+	}, diffBlockResults(a, b))
+}
+
+func TestWriteResultsDiffs(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeResultsDiffs(&buf, "text", 5, nil))
+	assert.Equal(t, "The results of block 5 are identical\n", buf.String())
+
+	buf.Reset()
+	diffs := []resultsDiff{{Path: "deliver_tx[0].code", A: "0", B: "1"}}
+	require.NoError(t, writeResultsDiffs(&buf, "text", 5, diffs))
+	assert.Contains(t, buf.String(), "deliver_tx[0].code")
+
+	buf.Reset()
+	require.NoError(t, writeResultsDiffs(&buf, "json", 5, diffs))
+	assert.Contains(t, buf.String(), `"path": "deliver_tx[0].code"`)
+}
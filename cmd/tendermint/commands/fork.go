@@ -0,0 +1,232 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	cfg "github.com/tendermint/tendermint/config"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	nm "github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/privval"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ForkCmd copies the data of the node to a sandbox home, where a single
+// validator continues the chain.
+var ForkCmd = &cobra.Command{
+	Use:   "fork",
+	Short: "Copy the chain to a sandbox home where a single local validator continues it",
+	Long: `Copy the block store and the state of the node at its last height to the
+sandbox home given by --output, with a new validator key and node key, and
+replace the validators of the next heights with the new validator only. A node
+started from the sandbox home continues the chain alone, isolated from the
+network, e.g. to replay mainnet txs or to test an upgrade against the mainnet
+data. The node must be stopped.
+
+The app data isn't copied: copy it at the same height, and point proxy_app of
+the sandbox config to the sandbox app. The tx index isn't copied either.`,
+	RunE: forkChain,
+}
+
+var (
+	forkOutput string
+	forkPower  int64
+)
+
+func init() {
+	ForkCmd.Flags().StringVar(&forkOutput, "output", "", "Home directory of the sandbox (must not exist)")
+	ForkCmd.Flags().Int64Var(&forkPower, "power", 10, "Voting power of the sandbox validator")
+}
+
+func forkChain(cmd *cobra.Command, args []string) error {
+	if forkOutput == "" {
+		return errors.New("--output is required")
+	}
+	if forkPower <= 0 {
+		return errors.New("--power must be positive")
+	}
+	if _, err := os.Stat(forkOutput); err == nil {
+		return fmt.Errorf("%s already exists", forkOutput)
+	}
+
+	blockStore, closeBlockStore, err := openBlockStore()
+	if err != nil {
+		return err
+	}
+	height := blockStore.Height()
+	closeBlockStore()
+	stateDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: "state", Config: config})
+	if err != nil {
+		return errors.Wrap(err, "failed to open the state")
+	}
+	state := sm.LoadState(stateDB)
+	stateDB.Close()
+	if state.LastBlockHeight != height {
+		return fmt.Errorf("the state is at height %d, not at the height of the block store (%d): start and stop the node first",
+			state.LastBlockHeight, height)
+	}
+
+	sandbox := sandboxConfig(config, forkOutput)
+	cfg.EnsureRoot(sandbox.RootDir)
+	cfg.WriteConfigFile(filepath.Join(sandbox.RootDir, "config", "config.toml"), sandbox)
+	if err := copyFile(config.GenesisFile(), sandbox.GenesisFile()); err != nil {
+		return errors.Wrap(err, "failed to copy the genesis file")
+	}
+	pv := privval.GenFilePV(sandbox.PrivValidatorKeyFile(), sandbox.PrivValidatorStateFile())
+	pv.Save()
+	if _, err := p2p.LoadOrGenNodeKey(sandbox.NodeKeyFile()); err != nil {
+		return err
+	}
+
+	for _, id := range []string{"blockstore", "state"} {
+		if err := copyDB(id, config, sandbox); err != nil {
+			return errors.Wrapf(err, "failed to copy the %s", id)
+		}
+	}
+	if config.BlockStoreBackend == cfg.BlockStoreBackendFlatFile {
+		if err := copyDir(config.BlockStoreFilesDir(), sandbox.BlockStoreFilesDir()); err != nil {
+			return errors.Wrap(err, "failed to copy the block store files")
+		}
+	}
+
+	sandboxStateDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: "state", Config: sandbox})
+	if err != nil {
+		return errors.Wrap(err, "failed to open the sandbox state")
+	}
+	defer sandboxStateDB.Close()
+	vals := types.NewValidatorSet([]*types.Validator{types.NewValidator(pv.GetPubKey(), forkPower)})
+	if _, err := sm.Fork(sandboxStateDB, vals); err != nil {
+		return errors.Wrap(err, "failed to fork the state")
+	}
+
+	fmt.Printf("Forked the chain at height %d into %s, with validator %v\n", height, sandbox.RootDir, pv.GetAddress())
+	fmt.Printf("Copy the app data at height %d, then run: tendermint node --home %s\n", height, sandbox.RootDir)
+	return nil
+}
+
+// sandboxConfig returns a copy of config with its home set to root, which
+// doesn't share files, peers or external services with the live node.
+func sandboxConfig(config *cfg.Config, root string) *cfg.Config {
+	var (
+		rpc       = *config.RPC
+		p2pConfig = *config.P2P
+		mempool   = *config.Mempool
+		fastSync  = *config.FastSync
+		consensus = *config.Consensus
+		instr     = *config.Instrumentation
+	)
+	sandbox := &cfg.Config{
+		BaseConfig:      config.BaseConfig,
+		RPC:             &rpc,
+		P2P:             &p2pConfig,
+		Mempool:         &mempool,
+		FastSync:        &fastSync,
+		Consensus:       &consensus,
+		TxIndex:         cfg.DefaultTxIndexConfig(),
+		Instrumentation: &instr,
+	}
+	sandbox.TxIndex.IndexTags = config.TxIndex.IndexTags
+	sandbox.TxIndex.IndexAllTags = config.TxIndex.IndexAllTags
+
+	// the paths may be absolute
+	defaults := cfg.DefaultConfig()
+	sandbox.Genesis = defaults.Genesis
+	sandbox.PrivValidatorKey = defaults.PrivValidatorKey
+	sandbox.PrivValidatorState = defaults.PrivValidatorState
+	sandbox.NodeKey = defaults.NodeKey
+	sandbox.DBPath = defaults.DBPath
+	sandbox.Mempool.WalPath = defaults.Mempool.WalPath
+	sandbox.Mempool.RejectionsLogPath = defaults.Mempool.RejectionsLogPath
+	sandbox.Consensus.WalPath = defaults.Consensus.WalPath
+	sandbox.P2P.AddrBook = defaults.P2P.AddrBook
+	sandbox.SetRoot(root)
+
+	// the local validator signs alone
+	sandbox.PrivValidatorListenAddr = ""
+	sandbox.PrivValidatorGRPCAddr = ""
+	sandbox.P2P.Seeds = ""
+	sandbox.P2P.PersistentPeers = ""
+	sandbox.P2P.PexReactor = false
+
+	sandbox.ArchiveURL = ""
+	sandbox.RetainBlocks = 0
+	sandbox.ShadowProxyApp = ""
+	return sandbox
+}
+
+// copyDB copies the database id of src to dst, key by key.
+func copyDB(id string, src, dst *cfg.Config) error {
+	srcDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: id, Config: src})
+	if err != nil {
+		return err
+	}
+	defer srcDB.Close()
+	dstDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: id, Config: dst})
+	if err != nil {
+		return err
+	}
+	defer dstDB.Close()
+
+	const batchSize = 1000
+	batch := dstDB.NewBatch()
+	n := 0
+	it := srcDB.Iterator(nil, nil)
+	defer it.Close()
+	for ; it.Valid(); it.Next() {
+		batch.Set(it.Key(), it.Value())
+		n++
+		if n%batchSize == 0 {
+			batch.Write()
+			batch.Close()
+			batch = dstDB.NewBatch()
+		}
+	}
+	batch.WriteSync()
+	batch.Close()
+	return nil
+}
+
+// copyDir copies the regular files of the directory src to dst.
+func copyDir(src, dst string) error {
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := cmn.EnsureDir(dst, 0700); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		if err := copyFile(filepath.Join(src, f.Name()), filepath.Join(dst, f.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/tendermint/tendermint/crypto/mnemonic"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/p2p"
 )
@@ -17,13 +18,28 @@ var GenNodeKeyCmd = &cobra.Command{
 	RunE:  genNodeKey,
 }
 
+func init() {
+	addMnemonicFlags(GenNodeKeyCmd)
+}
+
 func genNodeKey(cmd *cobra.Command, args []string) error {
 	nodeKeyFile := config.NodeKeyFile()
 	if cmn.FileExists(nodeKeyFile) {
 		return fmt.Errorf("node key at %s already exists", nodeKeyFile)
 	}
 
-	nodeKey, err := p2p.LoadOrGenNodeKey(nodeKeyFile)
+	// the secret connection only accepts ed25519 keys
+	privKey, err := mnemonicPrivKey(mnemonic.KeyTypeEd25519, mnemonic.PurposeNodeKey)
+	if err != nil {
+		return err
+	}
+	var nodeKey *p2p.NodeKey
+	if privKey != nil {
+		nodeKey = &p2p.NodeKey{PrivKey: privKey}
+		err = nodeKey.SaveAs(nodeKeyFile)
+	} else {
+		nodeKey, err = p2p.LoadOrGenNodeKey(nodeKeyFile)
+	}
 	if err != nil {
 		return err
 	}
@@ -5,6 +5,12 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/bls"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/mnemonic"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/sr25519"
 	"github.com/tendermint/tendermint/privval"
 )
 
@@ -13,15 +19,52 @@ import (
 var GenValidatorCmd = &cobra.Command{
 	Use:   "gen_validator",
 	Short: "Generate new validator keypair",
-	Run:   genValidator,
+	RunE:  genValidator,
 }
 
-func genValidator(cmd *cobra.Command, args []string) {
-	pv := privval.GenFilePV("", "")
+var validatorKeyType string
+
+func init() {
+	addMnemonicFlags(GenValidatorCmd)
+	GenValidatorCmd.Flags().StringVar(&validatorKeyType, "key_type", mnemonic.KeyTypeEd25519,
+		"Type of the key ("+mnemonic.KeyTypeEd25519+"|"+mnemonic.KeyTypeSecp256k1+"|"+mnemonic.KeyTypeSr25519+
+			"|"+mnemonic.KeyTypeBLS+"); the consensus params of the chain must allow it")
+}
+
+func genValidator(cmd *cobra.Command, args []string) error {
+	privKey, err := mnemonicPrivKey(validatorKeyType, mnemonic.PurposeValidatorKey)
+	if err != nil {
+		return err
+	}
+	if privKey == nil {
+		if privKey, err = genPrivKey(validatorKeyType); err != nil {
+			return err
+		}
+	}
+
+	pv := privval.NewFilePV(privKey, "", "")
 	jsbz, err := cdc.MarshalJSON(pv)
 	if err != nil {
-		panic(err)
+		return err
 	}
 	fmt.Printf(`%v
 `, string(jsbz))
+	return nil
+}
+
+// genPrivKey generates a random private key of keyType.
+func genPrivKey(keyType string) (crypto.PrivKey, error) {
+	switch keyType {
+	case mnemonic.KeyTypeEd25519:
+		return ed25519.GenPrivKey(), nil
+	case mnemonic.KeyTypeSecp256k1:
+		return secp256k1.GenPrivKey(), nil
+	case mnemonic.KeyTypeSr25519:
+		return sr25519.GenPrivKey(), nil
+	case mnemonic.KeyTypeBLS:
+		return bls.GenPrivKey(), nil
+	default:
+		return nil, fmt.Errorf("unknown key type %q, want %s, %s, %s or %s", keyType, mnemonic.KeyTypeEd25519,
+			mnemonic.KeyTypeSecp256k1, mnemonic.KeyTypeSr25519, mnemonic.KeyTypeBLS)
+	}
 }
@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// GenesisCmd groups the commands inspecting the genesis file.
+var GenesisCmd = &cobra.Command{
+	Use:   "genesis",
+	Short: "Inspect the genesis file",
+}
+
+// GenesisHashCmd prints the hash of the genesis file.
+var GenesisHashCmd = &cobra.Command{
+	Use:   "hash [file]",
+	Short: "Validate the genesis file and print its hash",
+	Long: `Validate the genesis file (the one of the config, unless given) and print
+its SHA-256 hash, to compare with other operators or to set as genesis_hash in
+the config. The file is streamed, so genesis files bigger than the memory are
+supported.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: genesisHash,
+}
+
+func init() {
+	GenesisCmd.AddCommand(GenesisHashCmd)
+}
+
+func genesisHash(cmd *cobra.Command, args []string) error {
+	file := config.GenesisFile()
+	if len(args) > 0 {
+		file = args[0]
+	}
+	hash, err := types.GenesisFileHash(file)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%X\n", hash)
+	return nil
+}
@@ -2,8 +2,11 @@ package commands
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	cfg "github.com/tendermint/tendermint/config"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/p2p"
@@ -19,10 +22,52 @@ var InitFilesCmd = &cobra.Command{
 	RunE:  initFiles,
 }
 
+var (
+	profile string
+	force   bool
+)
+
+func init() {
+	InitFilesCmd.Flags().StringVar(&profile, "profile", "",
+		"Tune the config for the node role ("+strings.Join(cfg.Profiles(), "|")+")")
+	InitFilesCmd.Flags().BoolVar(&force, "force", false,
+		"Overwrite an existing config file with the profile")
+}
+
 func initFiles(cmd *cobra.Command, args []string) error {
+	if profile != "" {
+		// the config file was read before the command ran, so it is only
+		// the user's one if it existed then: otherwise it is the default
+		// one written by ParseConfig
+		overwrite := force || viper.ConfigFileUsed() == ""
+		if err := initConfigWithProfile(config, profile, overwrite); err != nil {
+			return err
+		}
+	}
 	return initFilesWithConfig(config)
 }
 
+// initConfigWithProfile applies the profile to the config and writes it to
+// the config file. An existing config file is left untouched unless
+// overwrite is true.
+func initConfigWithProfile(config *cfg.Config, profile string, overwrite bool) error {
+	configFilePath := filepath.Join(config.RootDir, "config", "config.toml")
+	if !overwrite && cmn.FileExists(configFilePath) {
+		logger.Info("Found config file, not applying the profile (use --force to overwrite it)",
+			"path", configFilePath, "profile", profile)
+		return nil
+	}
+	if err := config.ApplyProfile(profile); err != nil {
+		return err
+	}
+	if err := config.ValidateBasic(); err != nil {
+		return fmt.Errorf("error in config with profile %s: %v", profile, err)
+	}
+	cfg.WriteConfigFile(configFilePath, config)
+	logger.Info("Generated config file", "path", configFilePath, "profile", profile)
+	return nil
+}
+
 func initFilesWithConfig(config *cfg.Config) error {
 	// private validator
 	privValKeyFile := config.PrivValidatorKeyFile()
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	nm "github.com/tendermint/tendermint/node"
+)
+
+// InspectCmd serves the RPC of a stopped node from its databases.
+var InspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Serve the RPC of a stopped node from its block store and state",
+	Long: `Start only the RPC server of the node, backed by its block store, state DB and
+tx index, without p2p, consensus or the app, to query the blocks, results,
+txs, validators and consensus params of a halted or crashed node without
+restarting consensus. The node must be stopped. Only the read-only endpoints
+backed by the databases are served: health, blockchain, genesis, block,
+block_results, commit, tx, tx_search, block_search, validators,
+validator_changes and consensus_params.`,
+	RunE: inspect,
+}
+
+func init() {
+	InspectCmd.Flags().String("rpc.laddr", config.RPC.ListenAddress, "RPC listen address. Port required")
+}
+
+func inspect(cmd *cobra.Command, args []string) error {
+	ins, err := nm.NewInspector(config, nm.DefaultDBProvider, logger)
+	if err != nil {
+		return fmt.Errorf("failed to open the node data: %v", err)
+	}
+
+	// Stop upon receiving SIGTERM or CTRL-C.
+	cmn.TrapSignal(logger, func() {
+		if ins.IsRunning() {
+			ins.Stop()
+		}
+	})
+
+	if err := ins.Start(); err != nil {
+		return fmt.Errorf("failed to start the RPC server: %v", err)
+	}
+	logger.Info("Inspecting the node", "listeners", ins.Listeners())
+
+	// Run forever.
+	select {}
+}
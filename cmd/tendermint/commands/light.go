@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/lite/proxy"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+)
+
+// LightCmd runs an RPC proxy in front of an untrusted full node, which
+// verifies the responses of the node with the lite client, starting from a
+// trusted header.
+var LightCmd = &cobra.Command{
+	Use:   "light",
+	Short: "Run a light client proxy, verifying the RPC responses of an untrusted node",
+	Long: `Run an RPC proxy in front of an untrusted full node, so that wallets and
+other clients can use the node safely.
+
+The proxy verifies the headers and commits of the node with the lite client,
+from a trusted header: pass its height and hash (e.g. from a block explorer,
+or the operator of another node) with --trusted-height and --trusted-hash the
+first time. The headers verified are saved in --home-dir, and the proxy starts
+from the latest one afterwards.
+
+The blocks, block results, validators, txs (with prove=true) and ABCI queries
+(with merkle proofs) are verified against the verified headers; the responses
+which can't be verified are errors. The txs are broadcast as is.`,
+	RunE:         runLightProxy,
+	SilenceUsage: true,
+}
+
+var (
+	lightListenAddr         string
+	lightNodeAddr           string
+	lightChainID            string
+	lightHome               string
+	lightMaxOpenConnections int
+	lightCacheSize          int
+	lightTrustedHeight      int64
+	lightTrustedHash        string
+)
+
+func init() {
+	LightCmd.Flags().StringVar(&lightListenAddr, "laddr", "tcp://localhost:8888", "Serve the proxy on the given address")
+	LightCmd.Flags().StringVar(&lightNodeAddr, "node", "tcp://localhost:26657",
+		"Connect to the (untrusted) Tendermint node at this address")
+	LightCmd.Flags().StringVar(&lightChainID, "chain-id", "tendermint", "Specify the Tendermint chain ID")
+	LightCmd.Flags().StringVar(&lightHome, "home-dir", ".tendermint-light", "Specify the home directory")
+	LightCmd.Flags().IntVar(
+		&lightMaxOpenConnections,
+		"max-open-connections",
+		900,
+		"Maximum number of simultaneous connections (including WebSocket).")
+	LightCmd.Flags().IntVar(&lightCacheSize, "cache-size", 10, "Specify the memory trust store cache size")
+	LightCmd.Flags().Int64Var(&lightTrustedHeight, "trusted-height", 0,
+		"Height of the trusted header (only needed while the trust store is empty)")
+	LightCmd.Flags().StringVar(&lightTrustedHash, "trusted-hash", "",
+		"Hash of the trusted header, in hex (only needed while the trust store is empty)")
+}
+
+func runLightProxy(cmd *cobra.Command, args []string) error {
+	// Stop upon receiving SIGTERM or CTRL-C.
+	cmn.TrapSignal(logger, func() {})
+
+	nodeAddr, err := EnsureAddrHasSchemeOrDefaultToTCP(lightNodeAddr)
+	if err != nil {
+		return err
+	}
+	listenAddr, err := EnsureAddrHasSchemeOrDefaultToTCP(lightListenAddr)
+	if err != nil {
+		return err
+	}
+	trustedHash, err := hex.DecodeString(lightTrustedHash)
+	if err != nil {
+		return errors.Wrap(err, "invalid --trusted-hash")
+	}
+
+	logger.Info("Connecting to the node", "node", nodeAddr)
+	node := rpcclient.NewHTTP(nodeAddr, "/websocket")
+
+	cert, err := proxy.NewVerifierFromTrustedRoot(lightChainID, lightHome, node, logger, lightCacheSize,
+		lightTrustedHeight, trustedHash)
+	if err != nil {
+		return errors.Wrap(err, "constructing verifier")
+	}
+	sc := proxy.SecureClient(node, cert)
+
+	logger.Info("Starting the light client proxy", "laddr", listenAddr)
+	err = proxy.StartProxy(sc, listenAddr, logger, lightMaxOpenConnections)
+	if err != nil {
+		return errors.Wrap(err, "starting proxy")
+	}
+
+	// Run forever
+	select {}
+}
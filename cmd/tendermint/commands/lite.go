@@ -24,6 +24,7 @@ that it will present the same interface as a full tendermint node,
 just with added trust and running locally.`,
 	RunE:         runProxy,
 	SilenceUsage: true,
+	Deprecated:   "use light, which starts from a trusted header rather than the first header of the node",
 }
 
 var (
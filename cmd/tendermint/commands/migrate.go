@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/libs/dbschema"
+	nm "github.com/tendermint/tendermint/node"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+)
+
+// MigrateCmd migrates the databases of the node to the current layout.
+var MigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the block store and the state to the layout of this version",
+	Long: `Migrate the block store and the state DB written by an older version of
+Tendermint to the layout of this version, as the node does when it starts. With
+--dry_run, only list the pending migrations. Databases written by a newer
+version can't be migrated back. The node must be stopped.`,
+	RunE: migrate,
+}
+
+var migrateDryRun bool
+
+func init() {
+	MigrateCmd.Flags().BoolVar(&migrateDryRun, "dry_run", false, "Only list the pending migrations")
+}
+
+func migrate(cmd *cobra.Command, args []string) error {
+	for _, schema := range []dbschema.Schema{store.Schema, sm.Schema} {
+		db, err := nm.DefaultDBProvider(&nm.DBContext{ID: schema.Name, Config: config})
+		if err != nil {
+			return errors.Wrapf(err, "failed to open the %s database", schema.Name)
+		}
+		version, err := dbschema.LoadVersion(db)
+		if err != nil {
+			db.Close()
+			return errors.Wrapf(err, "failed to load the version of the %s database", schema.Name)
+		}
+		pending, err := schema.Migrate(db, migrateDryRun, logger)
+		db.Close()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(pending) == 0:
+			fmt.Printf("%s: version %d, up to date\n", schema.Name, schema.Version())
+		case migrateDryRun:
+			fmt.Printf("%s: version %d, %d migrations pending to version %d\n",
+				schema.Name, version, len(pending), schema.Version())
+		default:
+			fmt.Printf("%s: migrated from version %d to version %d\n", schema.Name, version, schema.Version())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/mnemonic"
+)
+
+// Flags of the key generation commands (gen_node_key and gen_validator).
+var (
+	keyFromMnemonic bool
+	keyRecover      bool
+)
+
+func addMnemonicFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&keyFromMnemonic, "mnemonic", false,
+		"Derive the key from a new BIP39 mnemonic, printed once to stderr for backup")
+	cmd.Flags().BoolVar(&keyRecover, "recover", false,
+		"Derive the key from the BIP39 mnemonic read from stdin")
+}
+
+// mnemonicPrivKey returns the key for purpose derived from a new mnemonic with
+// --mnemonic, or from the mnemonic read from stdin with --recover, or nil
+// without either flag.
+func mnemonicPrivKey(keyType, purpose string) (crypto.PrivKey, error) {
+	var (
+		words string
+		err   error
+	)
+	switch {
+	case keyFromMnemonic && keyRecover:
+		return nil, fmt.Errorf("--mnemonic and --recover are mutually exclusive")
+	case keyFromMnemonic:
+		if words, err = mnemonic.New(); err != nil {
+			return nil, err
+		}
+	case keyRecover:
+		fmt.Fprintln(os.Stderr, "Enter the mnemonic:")
+		if words, err = readMnemonic(os.Stdin); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	privKey, err := mnemonic.PrivKey(words, keyType, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if keyFromMnemonic {
+		fmt.Fprintf(os.Stderr, "\n%s\n\nWrite down the mnemonic above and keep it safe: it's the only way to "+
+			"recover the key without a backup of the key file, and it won't be shown again.\n\n", words)
+	}
+	return privKey, nil
+}
+
+// readMnemonic reads a mnemonic on a single line.
+func readMnemonic(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("failed to read the mnemonic: %v", err)
+	}
+	return mnemonic.Normalize(line), nil
+}
@@ -1,13 +1,10 @@
 package commands
 
 import (
-	"os"
-
 	"github.com/spf13/cobra"
 
-	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
-	"github.com/tendermint/tendermint/privval"
+	"github.com/tendermint/tendermint/node/reset"
 )
 
 // ResetAllCmd removes the database of this Tendermint core
@@ -15,7 +12,10 @@ import (
 var ResetAllCmd = &cobra.Command{
 	Use:   "unsafe_reset_all",
 	Short: "(unsafe) Remove all the data and WAL, reset this node's validator to genesis state",
-	Run:   resetAll,
+	Long: `Remove all the data and WAL, and reset this node's validator to genesis state.
+To reset a single component, use reset-blockstore, reset-state, reset-addrbook
+or reset-privval-state instead.`,
+	Run: resetAll,
 }
 
 var keepAddrBook bool
@@ -31,6 +31,54 @@ var ResetPrivValidatorCmd = &cobra.Command{
 	Run:   resetPrivValidator,
 }
 
+// ResetBlockStoreCmd removes the block store.
+var ResetBlockStoreCmd = &cobra.Command{
+	Use:   "reset-blockstore",
+	Short: "(unsafe) Remove the block store",
+	Long: `Remove the block store. The node refuses to start from a state ahead of its
+block store, so the state must be reset too (reset-state), unless the block
+store is restored by other means. The node must be stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reset.BlockStore(config.DBDir(), logger)
+	},
+}
+
+// ResetStateCmd removes the state.
+var ResetStateCmd = &cobra.Command{
+	Use:   "reset-state",
+	Short: "(unsafe) Remove the state, tx index, evidence and consensus WAL",
+	Long: `Remove the state, the tx index, the evidence and the consensus WAL. The node
+starts from genesis again, so the block store (reset-blockstore) and the data
+of the app must be reset too. The node must be stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reset.State(config.DBDir(), config.Consensus.WalFile(), logger)
+	},
+}
+
+// ResetAddrBookCmd removes the address book.
+var ResetAddrBookCmd = &cobra.Command{
+	Use:   "reset-addrbook",
+	Short: "Remove the address book",
+	Long: `Remove the address book, so the node only dials its seeds and persistent
+peers on start. The node must be stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reset.AddrBook(config.P2P.AddrBookFile(), logger)
+	},
+}
+
+// ResetPrivValidatorStateCmd resets the sign state of the private validator.
+var ResetPrivValidatorStateCmd = &cobra.Command{
+	Use:   "reset-privval-state",
+	Short: "(unsafe) Reset the sign state of this node's validator to genesis",
+	Long: `Reset the last signed height, round and step of this node's validator to
+genesis, keeping its key. The validator signs at any height again: if it
+already signed at the current height of the chain, it will double sign. The
+node must be stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return reset.PrivValidatorState(config.PrivValidatorKeyFile(), config.PrivValidatorStateFile(), logger)
+	},
+}
+
 // XXX: this is totally unsafe.
 // it's only suitable for testnets.
 func resetAll(cmd *cobra.Command, args []string) {
@@ -41,45 +89,16 @@ func resetAll(cmd *cobra.Command, args []string) {
 // XXX: this is totally unsafe.
 // it's only suitable for testnets.
 func resetPrivValidator(cmd *cobra.Command, args []string) {
-	resetFilePV(config.PrivValidatorKeyFile(), config.PrivValidatorStateFile(), logger)
+	if err := reset.PrivValidator(config.PrivValidatorKeyFile(), config.PrivValidatorStateFile(), logger); err != nil {
+		logger.Error("Error resetting the private validator", "err", err)
+	}
 }
 
 // ResetAll removes address book files plus all data, and resets the privValdiator data.
-// Exported so other CLI tools can use it.
+// Exported so other CLI tools can use it; see the node/reset package to reset
+// a single component.
 func ResetAll(dbDir, addrBookFile, privValKeyFile, privValStateFile string, logger log.Logger) {
-	if keepAddrBook {
-		logger.Info("The address book remains intact")
-	} else {
-		removeAddrBook(addrBookFile, logger)
-	}
-	if err := os.RemoveAll(dbDir); err == nil {
-		logger.Info("Removed all blockchain history", "dir", dbDir)
-	} else {
-		logger.Error("Error removing all blockchain history", "dir", dbDir, "err", err)
-	}
-	// recreate the dbDir since the privVal state needs to live there
-	cmn.EnsureDir(dbDir, 0700)
-	resetFilePV(privValKeyFile, privValStateFile, logger)
-}
-
-func resetFilePV(privValKeyFile, privValStateFile string, logger log.Logger) {
-	if _, err := os.Stat(privValKeyFile); err == nil {
-		pv := privval.LoadFilePVEmptyState(privValKeyFile, privValStateFile)
-		pv.Reset()
-		logger.Info("Reset private validator file to genesis state", "keyFile", privValKeyFile,
-			"stateFile", privValStateFile)
-	} else {
-		pv := privval.GenFilePV(privValKeyFile, privValStateFile)
-		pv.Save()
-		logger.Info("Generated private validator file", "keyFile", privValKeyFile,
-			"stateFile", privValStateFile)
-	}
-}
-
-func removeAddrBook(addrBookFile string, logger log.Logger) {
-	if err := os.Remove(addrBookFile); err == nil {
-		logger.Info("Removed existing address book", "file", addrBookFile)
-	} else if !os.IsNotExist(err) {
-		logger.Info("Error removing address book", "file", addrBookFile, "err", err)
+	if err := reset.All(dbDir, addrBookFile, privValKeyFile, privValStateFile, keepAddrBook, logger); err != nil {
+		logger.Error("Error resetting all the data", "err", err)
 	}
 }
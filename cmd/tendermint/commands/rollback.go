@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	nm "github.com/tendermint/tendermint/node"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+// RollbackStateCmd rewinds the state of the node by one height.
+var RollbackStateCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Rollback the state of the node by one height",
+	Long: `Overwrite the state of the node at height n with the state of height n - 1.
+The block store and the app are left as they are: on restart, the node
+executes block n again against the app. This recovers a node which halted on
+an app hash mismatch after an upgrade to a non-deterministic version of the
+app, once the app is fixed. The node must be stopped.`,
+	RunE: rollbackState,
+}
+
+func rollbackState(cmd *cobra.Command, args []string) error {
+	blockStore, closeBlockStore, err := openBlockStore()
+	if err != nil {
+		return err
+	}
+	defer closeBlockStore()
+	stateDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: "state", Config: config})
+	if err != nil {
+		return errors.Wrap(err, "failed to open the state")
+	}
+	defer stateDB.Close()
+
+	height, appHash, err := sm.Rollback(blockStore, stateDB)
+	if err != nil {
+		return errors.Wrap(err, "failed to rollback the state")
+	}
+	fmt.Printf("Rolled back the state to height %d and app hash %X\n", height, appHash)
+	return nil
+}
@@ -16,6 +16,9 @@ import (
 var (
 	config = cfg.DefaultConfig()
 	logger = log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+
+	// setLogOptions changes the log level of logger
+	setLogOptions = func(...log.Option) {}
 )
 
 func init() {
@@ -57,10 +60,11 @@ var RootCmd = &cobra.Command{
 		if config.LogFormat == cfg.LogFormatJSON {
 			logger = log.NewTMJSONLogger(log.NewSyncWriter(os.Stdout))
 		}
-		logger, err = tmflags.ParseLogLevel(config.LogLevel, logger, cfg.DefaultLogLevel())
+		logOptions, err := tmflags.ParseLogLevelOptions(config.LogLevel, cfg.DefaultLogLevel())
 		if err != nil {
 			return err
 		}
+		logger, setLogOptions = log.NewReloadableFilter(logger, logOptions...)
 		if viper.GetBool(cli.TraceFlag) {
 			logger = log.NewTracingLogger(logger)
 		}
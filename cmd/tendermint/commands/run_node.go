@@ -2,9 +2,15 @@ package commands
 
 import (
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
+	cfg "github.com/tendermint/tendermint/config"
+	tmflags "github.com/tendermint/tendermint/libs/cli/flags"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	nm "github.com/tendermint/tendermint/node"
 )
@@ -20,6 +26,10 @@ func AddNodeFlags(cmd *cobra.Command) {
 		"priv_validator_laddr",
 		config.PrivValidatorListenAddr,
 		"Socket address to listen on for connections from external priv_validator process")
+	cmd.Flags().String(
+		"priv_validator_grpc_addr",
+		config.PrivValidatorGRPCAddr,
+		"Socket address of an external priv_validator process serving gRPC")
 
 	// node flags
 	cmd.Flags().Bool("fast_sync", config.FastSyncMode, "Fast blockchain syncing")
@@ -53,6 +63,8 @@ func AddNodeFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("p2p.pex", config.P2P.PexReactor, "Enable/disable Peer-Exchange")
 	cmd.Flags().Bool("p2p.seed_mode", config.P2P.SeedMode, "Enable/disable seed mode")
 	cmd.Flags().String("p2p.private_peer_ids", config.P2P.PrivatePeerIDs, "Comma-delimited private peer IDs")
+	cmd.Flags().String("p2p.unconditional_peer_ids", config.P2P.UnconditionalPeerIDs,
+		"Comma-delimited IDs of the peers accepted beyond max_num_inbound_peers")
 
 	// consensus flags
 	cmd.Flags().Bool(
@@ -73,6 +85,9 @@ func NewRunNodeCmd(nodeProvider nm.NodeProvider) *cobra.Command {
 				return fmt.Errorf("Failed to create node: %v", err)
 			}
 
+			// The admin console can change the log level.
+			n.SetLogLevelFunc(setLogLevel)
+
 			// Stop upon receiving SIGTERM or CTRL-C.
 			cmn.TrapSignal(logger, func() {
 				if n.IsRunning() {
@@ -85,6 +100,9 @@ func NewRunNodeCmd(nodeProvider nm.NodeProvider) *cobra.Command {
 			}
 			logger.Info("Started node", "nodeInfo", n.Switch().NodeInfo())
 
+			// Reload the config file upon receiving SIGHUP.
+			trapReload(n)
+
 			// Run forever.
 			select {}
 		},
@@ -93,3 +111,52 @@ func NewRunNodeCmd(nodeProvider nm.NodeProvider) *cobra.Command {
 	AddNodeFlags(cmd)
 	return cmd
 }
+
+// trapReload reloads the config file upon receiving SIGHUP, and applies its
+// hot reloadable fields to the running node n.
+func trapReload(n *nm.Node) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		for range c {
+			if err := reloadConfig(n); err != nil {
+				logger.Error("Failed to reload the config", "err", err)
+			}
+		}
+	}()
+}
+
+func setLogLevel(logLevel string) error {
+	logOptions, err := tmflags.ParseLogLevelOptions(logLevel, cfg.DefaultLogLevel())
+	if err != nil {
+		return err
+	}
+	setLogOptions(logOptions...)
+	return nil
+}
+
+func reloadConfig(n *nm.Node) error {
+	if err := viper.ReadInConfig(); err != nil {
+		return err
+	}
+	newConfig := cfg.DefaultConfig()
+	if err := viper.Unmarshal(newConfig); err != nil {
+		return err
+	}
+	newConfig.SetRoot(newConfig.RootDir)
+	logOptions, err := tmflags.ParseLogLevelOptions(newConfig.LogLevel, cfg.DefaultLogLevel())
+	if err != nil {
+		return err
+	}
+
+	restartRequired, err := n.ReloadConfig(newConfig)
+	if err != nil {
+		return err
+	}
+	setLogOptions(logOptions...)
+	logger.Info("Reloaded the config", "hot_reloadable", cfg.HotReloadableFields())
+	if len(restartRequired) > 0 {
+		logger.Error("Restart the node to apply the other changes of the config", "fields", restartRequired)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/libs/log"
+	nm "github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// SelfTestCmd groups the commands checking the node and its app.
+var SelfTestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Check the node and its app",
+}
+
+// SelfTestReplayCmd replays the stored blocks against a fresh instance of the
+// app, to check the app is deterministic.
+var SelfTestReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay the stored blocks against a fresh app and compare the app hashes",
+	Long: `Replay the blocks of the local block store against a fresh instance of the
+app, listening on --proxy_app, and compare the resulting app hashes with the
+ones of the stored headers. The first height whose app hash diverges is
+reported.
+
+The app must be behind the first height of --heights: the blocks it lacks
+are replayed from its height (from genesis if it's empty), and the app hashes
+are compared for the heights of --heights. The node must be stopped, as it
+holds the lock of the block store.`,
+	RunE: selfTestReplay,
+}
+
+var (
+	selfTestHeights  string
+	selfTestProxyApp string
+)
+
+func init() {
+	SelfTestReplayCmd.Flags().StringVar(&selfTestHeights, "heights", "",
+		"Heights to check, as H1..H2 or H1.. (all the stored heights if empty)")
+	SelfTestReplayCmd.Flags().StringVar(&selfTestProxyApp, "proxy_app", "",
+		"Address of the fresh app instance, or the name of a builtin app (the proxy_app of the config if empty)")
+	SelfTestCmd.AddCommand(SelfTestReplayCmd)
+}
+
+func selfTestReplay(cmd *cobra.Command, args []string) error {
+	from, to, err := parseHeightRange(selfTestHeights)
+	if err != nil {
+		return err
+	}
+
+	genDoc, err := types.GenesisDocFromFile(config.GenesisFile())
+	if err != nil {
+		return err
+	}
+	blockStore, closeBlockStore, err := openBlockStore()
+	if err != nil {
+		return err
+	}
+	defer closeBlockStore()
+	stateDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: "state", Config: config})
+	if err != nil {
+		return errors.Wrap(err, "failed to open the state")
+	}
+	defer stateDB.Close()
+
+	addr := selfTestProxyApp
+	if addr == "" {
+		addr = config.ProxyApp
+	}
+	proxyApp := proxy.NewAppConns(proxy.DefaultClientCreator(addr, config.ABCI, config.DBDir()))
+	if err := proxyApp.Start(); err != nil {
+		return errors.Wrap(err, "failed to connect to the app")
+	}
+	defer proxyApp.Stop()
+
+	from, to, err = replayAppHashes(proxyApp, genDoc, blockStore, stateDB, from, to, logger)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("The app hashes of heights %d to %d match\n", from, to)
+	return nil
+}
+
+// parseHeightRange parses a range of heights, H1..H2 or H1... It returns 0
+// for the bounds which aren't given.
+func parseHeightRange(s string) (from, to int64, err error) {
+	if s == "" {
+		return 0, 0, nil
+	}
+	parts := strings.Split(s, "..")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid heights %q (want H1..H2 or H1..)", s)
+	}
+	if from, err = strconv.ParseInt(parts[0], 10, 64); err != nil || from <= 0 {
+		return 0, 0, fmt.Errorf("invalid first height %q", parts[0])
+	}
+	if parts[1] != "" {
+		if to, err = strconv.ParseInt(parts[1], 10, 64); err != nil || to < from {
+			return 0, 0, fmt.Errorf("invalid last height %q", parts[1])
+		}
+	}
+	return from, to, nil
+}
+
+// appHashMismatchError is returned when the app hash of the replayed app
+// diverges from the one of the chain.
+type appHashMismatchError struct {
+	Height   int64
+	Expected []byte
+	Actual   []byte
+}
+
+func (e appHashMismatchError) Error() string {
+	return fmt.Sprintf("the app hash diverges at height %d: expected %X, got %X", e.Height, e.Expected, e.Actual)
+}
+
+// replayAppHashes replays the stored blocks against the app, from its height
+// up to the last height to check, and compares its app hashes with the ones
+// of the chain for the heights in [from, to]. The app hash after block H is
+// the one of header H+1, or the one of the state for the last block.
+//
+// It returns the heights checked, 0 meaning the first or last height
+// available, or an appHashMismatchError for the first divergent height.
+func replayAppHashes(
+	proxyApp proxy.AppConns,
+	genDoc *types.GenesisDoc,
+	blockStore sm.BlockStore,
+	stateDB dbm.DB,
+	from, to int64,
+	logger log.Logger,
+) (int64, int64, error) {
+	state := sm.LoadState(stateDB)
+	last := blockStore.Height() - 1
+	if state.LastBlockHeight == blockStore.Height() {
+		last = blockStore.Height()
+	}
+	if to == 0 || to > last {
+		to = last
+	}
+
+	res, err := proxyApp.Query().InfoSync(proxy.RequestInfo)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "error calling Info")
+	}
+	appHeight := res.LastBlockHeight
+	if from == 0 {
+		from = appHeight + 1
+	}
+	switch {
+	case from > to:
+		return 0, 0, fmt.Errorf("no app hash to check in [%d, %d], the block store is at height %d",
+			from, to, blockStore.Height())
+	case appHeight >= from:
+		return 0, 0, fmt.Errorf("the app is at height %d, it must be fresh (or behind height %d)",
+			appHeight, from)
+	case appHeight+1 < blockStore.Base():
+		return 0, 0, fmt.Errorf("the app is at height %d, but the block store starts at height %d",
+			appHeight, blockStore.Base())
+	}
+
+	if appHeight == 0 {
+		req, err := sm.InitChainRequest(genDoc)
+		if err != nil {
+			return 0, 0, err
+		}
+		if _, err := proxyApp.Consensus().InitChainSync(req); err != nil {
+			return 0, 0, errors.Wrap(err, "error calling InitChain")
+		}
+	}
+
+	for height := appHeight + 1; height <= to; height++ {
+		block := blockStore.LoadBlock(height)
+		if block == nil {
+			return 0, 0, fmt.Errorf("block %d is missing from the block store", height)
+		}
+		lastBlockTime, err := sm.LastBlockTime(blockStore, height)
+		if err != nil {
+			return 0, 0, err
+		}
+		appHash, err := sm.ExecCommitBlock(proxyApp.Consensus(), block, lastBlockTime, logger, stateDB)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "failed to replay block %d", height)
+		}
+		if height < from {
+			continue
+		}
+
+		expected := state.AppHash
+		if height < blockStore.Height() {
+			expected = blockStore.LoadBlockMeta(height + 1).Header.AppHash
+		}
+		if !bytes.Equal(appHash, expected) {
+			return 0, 0, appHashMismatchError{Height: height, Expected: expected, Actual: appHash}
+		}
+		logger.Info("Replayed block", "height", height, "appHash", appHash)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/types"
+)
+
+// divergentApp is a kvstore whose app hash diverges from the height
+// divergeAt.
+type divergentApp struct {
+	*kvstore.KVStoreApplication
+	height    int64
+	divergeAt int64
+}
+
+func (app *divergentApp) Commit() abci.ResponseCommit {
+	res := app.KVStoreApplication.Commit()
+	app.height++
+	if app.height >= app.divergeAt {
+		res.Data = []byte("divergent")
+	}
+	return res
+}
+
+func startApp(t *testing.T, app abci.Application) proxy.AppConns {
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(app))
+	require.NoError(t, proxyApp.Start())
+	return proxyApp
+}
+
+func TestReplayAppHashes(t *testing.T) {
+	genDoc := &types.GenesisDoc{
+		ChainID:     "selftest",
+		GenesisTime: time.Now(),
+		Validators:  []types.GenesisValidator{{PubKey: ed25519.GenPrivKey().PubKey(), Power: 10}},
+	}
+	require.NoError(t, genDoc.ValidateAndComplete())
+	state, err := sm.MakeGenesisState(genDoc)
+	require.NoError(t, err)
+	stateDB := dbm.NewMemDB()
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	logger := log.TestingLogger()
+
+	// build a chain of 4 blocks of h txs with the app hashes of a kvstore
+	app := startApp(t, kvstore.NewKVStoreApplication())
+	defer app.Stop()
+	req, err := sm.InitChainRequest(genDoc)
+	require.NoError(t, err)
+	_, err = app.Consensus().InitChainSync(req)
+	require.NoError(t, err)
+	sm.SaveState(stateDB, state)
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+	for h := int64(1); h <= 4; h++ {
+		txs := make([]types.Tx, h)
+		for i := range txs {
+			txs[i] = types.Tx(fmt.Sprintf("key%d-%d=value", h, i))
+		}
+		block := types.MakeBlock(h, txs, lastCommit, nil)
+		block.AppHash = state.AppHash
+		parts := block.MakePartSet(types.BlockPartSizeBytes)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		vote := &types.Vote{Height: h, BlockID: blockID, Type: types.PrecommitType, Timestamp: block.Time}
+		lastCommit = types.NewCommit(blockID, []*types.CommitSig{vote.CommitSig()})
+		blockStore.SaveBlock(block, parts, lastCommit)
+
+		lastBlockTime, err := sm.LastBlockTime(blockStore, h)
+		require.NoError(t, err)
+		state.AppHash, err = sm.ExecCommitBlock(app.Consensus(), block, lastBlockTime, logger, stateDB)
+		require.NoError(t, err)
+		state.LastBlockHeight = h
+		sm.SaveState(stateDB, state)
+	}
+
+	// a fresh deterministic app matches the chain
+	app = startApp(t, kvstore.NewKVStoreApplication())
+	defer app.Stop()
+	from, to, err := replayAppHashes(app, genDoc, blockStore, stateDB, 0, 0, logger)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, from)
+	assert.EqualValues(t, 4, to)
+
+	app = startApp(t, kvstore.NewKVStoreApplication())
+	defer app.Stop()
+	from, to, err = replayAppHashes(app, genDoc, blockStore, stateDB, 2, 3, logger)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, from)
+	assert.EqualValues(t, 3, to)
+
+	// the app must be behind the first height
+	_, _, err = replayAppHashes(app, genDoc, blockStore, stateDB, 2, 0, logger)
+	assert.Error(t, err)
+
+	// the first divergent height is reported
+	app = startApp(t, &divergentApp{KVStoreApplication: kvstore.NewKVStoreApplication(), divergeAt: 3})
+	defer app.Stop()
+	_, _, err = replayAppHashes(app, genDoc, blockStore, stateDB, 0, 0, logger)
+	if assert.IsType(t, appHashMismatchError{}, err) {
+		assert.EqualValues(t, 3, err.(appHashMismatchError).Height)
+		assert.Equal(t, []byte("divergent"), err.(appHashMismatchError).Actual)
+	}
+}
+
+func TestParseHeightRange(t *testing.T) {
+	testCases := []struct {
+		s        string
+		from, to int64
+		wantErr  bool
+	}{
+		{"", 0, 0, false},
+		{"3..7", 3, 7, false},
+		{"3..", 3, 0, false},
+		{"3", 0, 0, true},
+		{"..7", 0, 0, true},
+		{"7..3", 0, 0, true},
+		{"0..3", 0, 0, true},
+		{"a..b", 0, 0, true},
+	}
+	for _, tc := range testCases {
+		from, to, err := parseHeightRange(tc.s)
+		if tc.wantErr {
+			assert.Error(t, err, tc.s)
+			continue
+		}
+		require.NoError(t, err, tc.s)
+		assert.Equal(t, tc.from, from, tc.s)
+		assert.Equal(t, tc.to, to, tc.s)
+	}
+}
@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/compress"
+	nm "github.com/tendermint/tendermint/node"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+)
+
+// StatsCmd reports statistics about the blocks in the local block store.
+var StatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report block size, tx count, commit size and block interval statistics of the local block store",
+	Long: `Scan the blocks in [--from, --to] of the local block store and report the
+distribution of block sizes, tx counts, commit sizes, signature counts and
+block intervals. The node must be stopped, as it holds the lock of the
+block store.`,
+	RunE: stats,
+}
+
+var (
+	statsFrom   int64
+	statsTo     int64
+	statsOutput string
+)
+
+func init() {
+	StatsCmd.Flags().Int64Var(&statsFrom, "from", 1, "First height to scan")
+	StatsCmd.Flags().Int64Var(&statsTo, "to", 0, "Last height to scan (0 for the latest height)")
+	StatsCmd.Flags().StringVar(&statsOutput, "output", "text", "Output format (text | csv | json)")
+}
+
+func stats(cmd *cobra.Command, args []string) error {
+	switch statsOutput {
+	case "text", "csv", "json":
+	default:
+		return fmt.Errorf("unknown output format %q (want text, csv or json)", statsOutput)
+	}
+
+	blockStore, closeBlockStore, err := openBlockStore()
+	if err != nil {
+		return err
+	}
+	defer closeBlockStore()
+
+	chainStats, err := collectChainStats(blockStore, statsFrom, statsTo)
+	if err != nil {
+		return err
+	}
+	return writeChainStats(os.Stdout, statsOutput, chainStats)
+}
+
+// openBlockStore opens the block store of the node, with the backend and the
+// compression of the config. The node must be stopped, as it holds the lock
+// of the DB.
+func openBlockStore() (blockStore sm.BlockStore, close func(), err error) {
+	codecs, codec, err := store.LoadCompression(config.BlockStoreCompression,
+		config.BlockStoreCompressionDictFile(), compress.NopMetrics())
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := nm.DefaultDBProvider(&nm.DBContext{ID: "blockstore", Config: config})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to open the block store")
+	}
+	if config.BlockStoreBackend != cfg.BlockStoreBackendFlatFile {
+		bs := store.NewBlockStore(db)
+		bs.SetCompression(codecs, codec)
+		return bs, db.Close, nil
+	}
+	fbs, err := store.NewFileBlockStore(db, config.BlockStoreFilesDir(), store.DefaultSegmentSize)
+	if err != nil {
+		db.Close()
+		return nil, nil, errors.Wrap(err, "failed to open the block store")
+	}
+	fbs.SetCompression(codecs, codec)
+	return fbs, func() { fbs.Close(); db.Close() }, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// chainStats holds the distributions of the blocks in [From, To].
+type chainStats struct {
+	From    int64          `json:"from"`
+	To      int64          `json:"to"`
+	Metrics []distribution `json:"metrics"`
+}
+
+// distribution summarizes the samples of a metric.
+type distribution struct {
+	Metric string  `json:"metric"`
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	P50    float64 `json:"p50"`
+	P90    float64 `json:"p90"`
+	P99    float64 `json:"p99"`
+}
+
+// collectChainStats scans the blocks in [from, to] of the given store. to <= 0
+// stands for the latest height.
+func collectChainStats(blockStore sm.BlockStoreRPC, from, to int64) (chainStats, error) {
+	height := blockStore.Height()
+	if to <= 0 || to > height {
+		to = height
+	}
+	if from < 1 {
+		from = 1
+	}
+	if from > to {
+		return chainStats{}, fmt.Errorf("empty range [%d, %d] (the block store is at height %d)", from, to, height)
+	}
+
+	var (
+		blockSizes  = make([]float64, 0, to-from+1)
+		txCounts    = make([]float64, 0, to-from+1)
+		commitSizes = make([]float64, 0, to-from+1)
+		sigCounts   = make([]float64, 0, to-from+1)
+		intervals   = make([]float64, 0, to-from+1)
+		prevTime    time.Time
+	)
+	if blockMeta := blockStore.LoadBlockMeta(from - 1); blockMeta != nil {
+		prevTime = blockMeta.Header.Time
+	}
+	for h := from; h <= to; h++ {
+		block := blockStore.LoadBlock(h)
+		if block == nil {
+			return chainStats{}, fmt.Errorf("block %d is missing from the block store", h)
+		}
+		blockSizes = append(blockSizes, float64(block.Size()))
+		txCounts = append(txCounts, float64(len(block.Txs)))
+
+		// The commit of the last height is only available as the seen commit.
+		commit := blockStore.LoadBlockCommit(h)
+		if commit == nil {
+			commit = blockStore.LoadSeenCommit(h)
+		}
+		if commit != nil {
+			commitSizes = append(commitSizes, float64(len(cdc.MustMarshalBinaryBare(commit))))
+			sigCount := 0
+			signers := commit.BitArray()
+			for i := 0; i < signers.Size(); i++ {
+				if signers.GetIndex(i) {
+					sigCount++
+				}
+			}
+			sigCounts = append(sigCounts, float64(sigCount))
+		}
+
+		if !prevTime.IsZero() {
+			intervals = append(intervals, float64(block.Time.Sub(prevTime)/time.Millisecond))
+		}
+		prevTime = block.Time
+	}
+
+	return chainStats{
+		From: from,
+		To:   to,
+		Metrics: []distribution{
+			newDistribution("block_size_bytes", blockSizes),
+			newDistribution("tx_count", txCounts),
+			newDistribution("commit_size_bytes", commitSizes),
+			newDistribution("signature_count", sigCounts),
+			newDistribution("block_interval_ms", intervals),
+		},
+	}, nil
+}
+
+func newDistribution(metric string, samples []float64) distribution {
+	d := distribution{Metric: metric, Count: len(samples)}
+	if len(samples) == 0 {
+		return d
+	}
+	sort.Float64s(samples)
+
+	var sum float64
+	for _, s := range samples {
+		sum += s
+	}
+	d.Min = samples[0]
+	d.Max = samples[len(samples)-1]
+	d.Mean = sum / float64(len(samples))
+	d.P50 = percentile(samples, 50)
+	d.P90 = percentile(samples, 90)
+	d.P99 = percentile(samples, 99)
+	return d
+}
+
+// percentile returns the nearest-rank percentile p of the sorted samples.
+func percentile(sorted []float64, p int) float64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func writeChainStats(w io.Writer, format string, s chainStats) error {
+	switch format {
+	case "json":
+		bz, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(bz))
+		return err
+	case "csv":
+		cw := csv.NewWriter(w)
+		header := []string{"metric", "count", "min", "max", "mean", "p50", "p90", "p99"}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for _, d := range s.Metrics {
+			if err := cw.Write(append([]string{d.Metric, strconv.Itoa(d.Count)}, d.values()...)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		fmt.Fprintf(w, "Blocks %d-%d\n\n", s.From, s.To)
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "METRIC\tCOUNT\tMIN\tMAX\tMEAN\tP50\tP90\tP99")
+		for _, d := range s.Metrics {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", d.Metric, d.Count, strings.Join(d.values(), "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+func (d distribution) values() []string {
+	values := []float64{d.Min, d.Max, d.Mean, d.P50, d.P90, d.P99}
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+	}
+	return strs
+}
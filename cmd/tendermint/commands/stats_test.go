@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestCollectChainStats(t *testing.T) {
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	start := time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// block h holds h txs and is committed h seconds after the previous one
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+	blockTime := start
+	for h := int64(1); h <= 4; h++ {
+		txs := make([]types.Tx, h)
+		for i := range txs {
+			txs[i] = types.Tx("tx")
+		}
+		block := types.MakeBlock(h, txs, lastCommit, nil)
+		blockTime = blockTime.Add(time.Duration(h) * time.Second)
+		block.Time = blockTime
+		parts := block.MakePartSet(types.BlockPartSizeBytes)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		vote := &types.Vote{Height: h, BlockID: blockID, Type: types.PrecommitType, Timestamp: blockTime}
+		lastCommit = types.NewCommit(blockID, []*types.CommitSig{vote.CommitSig(), nil})
+		blockStore.SaveBlock(block, parts, lastCommit)
+	}
+
+	s, err := collectChainStats(blockStore, 2, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, s.From)
+	assert.EqualValues(t, 4, s.To)
+
+	metrics := make(map[string]distribution)
+	for _, d := range s.Metrics {
+		metrics[d.Metric] = d
+	}
+	assert.Equal(t, distribution{Metric: "tx_count", Count: 3, Min: 2, Max: 4, Mean: 3, P50: 3, P90: 4, P99: 4},
+		metrics["tx_count"])
+	assert.Equal(t, distribution{Metric: "signature_count", Count: 3, Min: 1, Max: 1, Mean: 1, P50: 1, P90: 1, P99: 1},
+		metrics["signature_count"])
+	assert.Equal(t, distribution{Metric: "block_interval_ms", Count: 3, Min: 2000, Max: 4000, Mean: 3000,
+		P50: 3000, P90: 4000, P99: 4000}, metrics["block_interval_ms"])
+	assert.Equal(t, 3, metrics["block_size_bytes"].Count)
+	assert.Equal(t, 3, metrics["commit_size_bytes"].Count)
+
+	_, err = collectChainStats(blockStore, 5, 0)
+	assert.Error(t, err)
+
+	// csv and json
+	buf := new(bytes.Buffer)
+	require.NoError(t, writeChainStats(buf, "csv", s))
+	assert.Contains(t, buf.String(), "metric,count,min,max,mean,p50,p90,p99\n")
+	assert.Contains(t, buf.String(), "tx_count,3,2,4,3,3,4,4\n")
+
+	buf.Reset()
+	require.NoError(t, writeChainStats(buf, "json", s))
+	var decoded chainStats
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, s, decoded)
+}
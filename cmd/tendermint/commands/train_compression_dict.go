@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/libs/compress"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+// TrainCompressionDictCmd trains a zstd dictionary on the blocks of the local
+// block store.
+var TrainCompressionDictCmd = &cobra.Command{
+	Use:   "train_compression_dict",
+	Short: "Train a zstd compression dictionary on the blocks of the local block store",
+	Long: `Sample the block parts and commits of the blocks in [--from, --to] of the
+local block store, and write a zstd dictionary trained on them to --output.
+A dictionary improves the compression ratio of small values, which don't
+have enough context of their own. The node must be stopped, as it holds the
+lock of the block store.`,
+	RunE: trainCompressionDict,
+}
+
+var (
+	trainDictFrom   int64
+	trainDictTo     int64
+	trainDictSize   int
+	trainDictOutput string
+)
+
+func init() {
+	TrainCompressionDictCmd.Flags().Int64Var(&trainDictFrom, "from", 1, "First height to sample")
+	TrainCompressionDictCmd.Flags().Int64Var(&trainDictTo, "to", 0, "Last height to sample (0 for the latest height)")
+	TrainCompressionDictCmd.Flags().IntVar(&trainDictSize, "size", 112640, "Maximum size of the dictionary, in bytes")
+	TrainCompressionDictCmd.Flags().StringVar(&trainDictOutput, "output", "compression.dict",
+		"File to write the dictionary to")
+}
+
+func trainCompressionDict(cmd *cobra.Command, args []string) error {
+	blockStore, closeBlockStore, err := openBlockStore()
+	if err != nil {
+		return err
+	}
+	defer closeBlockStore()
+
+	samples, err := blockSamples(blockStore, trainDictFrom, trainDictTo)
+	if err != nil {
+		return err
+	}
+	dict, err := compress.TrainZstdDict(samples, trainDictSize)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(trainDictOutput, dict, 0644); err != nil {
+		return errors.Wrap(err, "failed to write the dictionary")
+	}
+	fmt.Printf("Wrote a %d bytes dictionary trained on %d samples to %s\n", len(dict), len(samples), trainDictOutput)
+	return nil
+}
+
+// blockSamples returns the bytes of the data parts and the commits of the
+// blocks in [from, to] of the given store. to <= 0 stands for the latest
+// height.
+func blockSamples(blockStore sm.BlockStoreRPC, from, to int64) ([][]byte, error) {
+	if to <= 0 || to > blockStore.Height() {
+		to = blockStore.Height()
+	}
+	if base := blockStore.Base(); from < base {
+		from = base
+	}
+	if from > to || from < 1 {
+		return nil, fmt.Errorf("empty range [%d, %d] (the block store is at height %d)",
+			from, to, blockStore.Height())
+	}
+
+	var samples [][]byte
+	for height := from; height <= to; height++ {
+		meta := blockStore.LoadBlockMeta(height)
+		if meta == nil {
+			return nil, fmt.Errorf("no block at height %d", height)
+		}
+		// the parity parts don't compress
+		for i := 0; i < meta.BlockID.PartsHeader.DataTotal(meta.Header.Version.Block); i++ {
+			if part := blockStore.LoadBlockPart(height, i); part != nil {
+				samples = append(samples, part.Bytes)
+			}
+		}
+		if commit := blockStore.LoadSeenCommit(height); commit != nil {
+			samples = append(samples, cdc.MustMarshalBinaryBare(commit))
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/consensus"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	nm "github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/privval"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+// WALCmd groups the commands working on the consensus WAL.
+var WALCmd = &cobra.Command{
+	Use:   "wal",
+	Short: "Replay and check the consensus WAL",
+}
+
+// WALReplayCmd replays the consensus WAL, or checks it with --check.
+var WALReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay the messages of the consensus WAL, or check them with --check",
+	Long: `Replay the messages of the consensus WAL (like 'tendermint replay').
+
+With --check, the messages of the whole WAL are replayed in a sandbox instead:
+nothing is written and the app isn't involved. The proposals, block parts and
+votes are checked against the validators of the state, and against each
+other, and the double-sign hazards of the validator of the node are reported
+before restarting it: its conflicting signatures, its signatures received from
+peers (another node signing with its key), and its signatures in the WAL
+beyond the last signed height/round/step of priv_validator_state.json (e.g.
+restored from a backup). The command fails if any problem is found. The node
+must be stopped.`,
+	RunE: walReplay,
+}
+
+var walReplayCheck bool
+
+func init() {
+	WALReplayCmd.Flags().BoolVar(&walReplayCheck, "check", false,
+		"Check the messages of the WAL and the double-sign hazards, without applying them")
+	WALCmd.AddCommand(WALReplayCmd)
+}
+
+func walReplay(cmd *cobra.Command, args []string) error {
+	if !walReplayCheck {
+		consensus.RunReplayFile(config.BaseConfig, config.Consensus, false)
+		return nil
+	}
+
+	stateDB, err := nm.DefaultDBProvider(&nm.DBContext{ID: "state", Config: config})
+	if err != nil {
+		return errors.Wrap(err, "failed to open the state")
+	}
+	defer stateDB.Close()
+	state := sm.LoadState(stateDB)
+	if state.IsEmpty() {
+		return errors.New("the state is empty: there is nothing to check the WAL against")
+	}
+
+	checker := consensus.NewWALChecker(state, stateDB)
+	if cmn.FileExists(config.PrivValidatorKeyFile()) {
+		pv := privval.LoadFilePV(config.PrivValidatorKeyFile(), config.PrivValidatorStateFile())
+		lss := pv.LastSignState
+		checker.SetValidator(pv.GetAddress(), lss.Height, lss.Round, lss.Step)
+	}
+	if err := checker.CheckFile(config.Consensus.WalFile()); err != nil {
+		return errors.Wrap(err, "failed to read the WAL")
+	}
+
+	problems := checker.Finish(state)
+	hazards := 0
+	for _, p := range problems {
+		fmt.Println(p)
+		if p.Hazard {
+			hazards++
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problems in the WAL, including %d double-sign hazards", len(problems), hazards)
+	}
+	fmt.Printf("No problems found in the WAL up to height %d\n", state.LastBlockHeight)
+	return nil
+}
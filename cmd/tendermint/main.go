@@ -18,13 +18,33 @@ func main() {
 		cmd.InitFilesCmd,
 		cmd.ProbeUpnpCmd,
 		cmd.LiteCmd,
+		cmd.LightCmd,
 		cmd.ReplayCmd,
 		cmd.ReplayConsoleCmd,
+		cmd.WALCmd,
 		cmd.ResetAllCmd,
 		cmd.ResetPrivValidatorCmd,
+		cmd.ResetBlockStoreCmd,
+		cmd.ResetStateCmd,
+		cmd.ResetAddrBookCmd,
+		cmd.ResetPrivValidatorStateCmd,
+		cmd.RollbackStateCmd,
+		cmd.MigrateCmd,
 		cmd.ShowValidatorCmd,
 		cmd.TestnetFilesCmd,
 		cmd.ShowNodeIDCmd,
+		cmd.StatsCmd,
+		cmd.TrainCompressionDictCmd,
+		cmd.DiffResultsCmd,
+		cmd.SelfTestCmd,
+		cmd.DebugCmd,
+		cmd.ExportCmd,
+		cmd.ImportCmd,
+		cmd.InspectCmd,
+		cmd.ConsoleCmd,
+		cmd.ForkCmd,
+		cmd.AddrBookCmd,
+		cmd.GenesisCmd,
 		cmd.GenNodeKeyCmd,
 		cmd.VersionCmd)
 
@@ -1,13 +1,19 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"github.com/tendermint/tendermint/libs/cgroup"
+	"github.com/tendermint/tendermint/libs/compress"
 )
 
 const (
@@ -20,6 +26,21 @@ const (
 	LogFormatPlain = "plain"
 	// LogFormatJSON is a format for json output
 	LogFormatJSON = "json"
+
+	// BlockStoreBackendKV keeps blocks in the DB
+	BlockStoreBackendKV = "kv"
+	// BlockStoreBackendFlatFile keeps block parts in flat append-only files
+	BlockStoreBackendFlatFile = "flatfile"
+
+	// WALCompressionNone writes the consensus WAL messages as is
+	WALCompressionNone = compress.CodecNone
+	// WALCompressionSnappy snappy compresses the consensus WAL messages
+	WALCompressionSnappy = compress.CodecSnappy
+
+	// MempoolCacheLRU keeps the hashes of the recent txs in memory
+	MempoolCacheLRU = "lru"
+	// MempoolCacheCuckoo keeps the recent txs in cuckoo filters saved to a file
+	MempoolCacheCuckoo = "cuckoo"
 )
 
 // NOTE: Most of the structs & relevant comments + the
@@ -130,6 +151,9 @@ func (cfg *Config) ValidateBasic() error {
 	if err := cfg.Consensus.ValidateBasic(); err != nil {
 		return errors.Wrap(err, "Error in [consensus] section")
 	}
+	if err := cfg.TxIndex.ValidateBasic(); err != nil {
+		return errors.Wrap(err, "Error in [tx_index] section")
+	}
 	return errors.Wrap(
 		cfg.Instrumentation.ValidateBasic(),
 		"Error in [instrumentation] section",
@@ -152,6 +176,21 @@ type BaseConfig struct { //nolint: maligned
 	// or the name of an ABCI application compiled in with the Tendermint binary
 	ProxyApp string `mapstructure:"proxy_app"`
 
+	// TCP or UNIX socket address of a shadow ABCI application (e.g. the next
+	// version of the app), or the name of a compiled in one. The committed
+	// blocks are executed against it too, in the background, and its app
+	// hashes are compared with the ones of the application, without affecting
+	// consensus. Empty - no shadow application
+	ShadowProxyApp string `mapstructure:"shadow_proxy_app"`
+
+	// TCP or UNIX socket address of a backfill ABCI application: an instance
+	// of the application restored to a past state (e.g. from a snapshot of
+	// its data), or the name of a compiled in one. The block results missing
+	// from the node (e.g. removed by a pruning tool) are recomputed by
+	// re-executing the blocks against it, from its height, when started with
+	// the admin backfill_results. Empty - no backfill application
+	BackfillProxyApp string `mapstructure:"backfill_proxy_app"`
+
 	// A custom human readable name for this node
 	Moniker string `mapstructure:"moniker"`
 
@@ -177,6 +216,66 @@ type BaseConfig struct { //nolint: maligned
 	// Database directory
 	DBPath string `mapstructure:"db_dir"`
 
+	// Block store backend: kv | flatfile
+	// * kv - blocks are kept in the DB, split into parts
+	// * flatfile - block parts are appended to flat files under
+	//   db_dir/blockstore.files, with an index in the DB
+	//   - much faster sequential reads (e.g. serving fast sync)
+	//   - EXPERIMENTAL
+	BlockStoreBackend string `mapstructure:"block_store_backend"`
+
+	// Compression of the block parts on disk: none | snappy | zstd
+	// * snappy - fast, about half the ratio of zstd
+	// * zstd - requires a build with cgo
+	// The parts saved with another codec are still read
+	BlockStoreCompression string `mapstructure:"block_store_compression"`
+
+	// zstd dictionary trained on the blocks of the chain (see
+	// tendermint train_compression_dict), which improves the ratio of the
+	// small blocks. The parts compressed with a dictionary can't be read
+	// without it, so it must not be changed or removed afterwards.
+	// Empty - no dictionary
+	BlockStoreCompressionDict string `mapstructure:"block_store_compression_dict"`
+
+	// Save the existing block parts again with the codec of
+	// block_store_compression, in the background (kv backend only)
+	BlockStoreRecompress bool `mapstructure:"block_store_recompress"`
+
+	// Object store to archive old blocks to, before pruning them from the
+	// block store: file:///path, s3://bucket/prefix or gs://bucket/prefix.
+	// S3 and GCS credentials are read from the AWS_ACCESS_KEY_ID and
+	// AWS_SECRET_ACCESS_KEY environment variables. Archived blocks are still
+	// served by /block. Empty - no archiving
+	ArchiveURL string `mapstructure:"archive_url"`
+
+	// Endpoint of the S3 compatible service, if not the default one of the
+	// archive_url scheme (e.g. for MinIO)
+	ArchiveEndpoint string `mapstructure:"archive_endpoint"`
+
+	// Number of latest blocks to keep in the block store when archiving.
+	// 0 - keep all blocks, and archive none
+	RetainBlocks int64 `mapstructure:"retain_blocks"`
+
+	// Soft memory ceiling, in bytes. Above it, the node halves the mempool
+	// and evicts caches, hopefully before the OOM killer intervenes.
+	// 0 - 90% of the container memory limit, if any; -1 - disabled
+	MemorySoftLimit int64 `mapstructure:"memory_soft_limit"`
+
+	// Comma separated list of <category>=<bytes> soft quotas of the disk
+	// space used by the node data (blocks, state, wal, tx_index, evidence).
+	// Above its quota, the oldest blocks are pruned; the other categories are
+	// only reported. Empty - no quotas
+	DiskSoftQuotas string `mapstructure:"disk_soft_quotas"`
+
+	// Number of workers verifying commit and evidence signatures, apart from
+	// the p2p and consensus goroutines.
+	// 0 - GOMAXPROCS - 1 (at least 1); -1 - verify inline
+	SigVerifyWorkers int `mapstructure:"sig_verify_workers"`
+
+	// CPUs the signature verification workers are pinned to (Linux only),
+	// in the cpuset format (e.g. "2,3" or "2-5"). Empty - no pinning
+	SigVerifyCPUs string `mapstructure:"sig_verify_cpus"`
+
 	// Output level for logging
 	LogLevel string `mapstructure:"log_level"`
 
@@ -186,6 +285,10 @@ type BaseConfig struct { //nolint: maligned
 	// Path to the JSON file containing the initial validator set and other meta data
 	Genesis string `mapstructure:"genesis_file"`
 
+	// Hex encoded SHA-256 hash of the genesis file (see "tendermint genesis
+	// hash"). If set, the node refuses to start from a different genesis file.
+	GenesisHash string `mapstructure:"genesis_hash"`
+
 	// Path to the JSON file containing the private key to use as a validator in the consensus protocol
 	PrivValidatorKey string `mapstructure:"priv_validator_key_file"`
 
@@ -196,6 +299,16 @@ type BaseConfig struct { //nolint: maligned
 	// connections from an external PrivValidator process
 	PrivValidatorListenAddr string `mapstructure:"priv_validator_laddr"`
 
+	// TCP or UNIX socket address of an external PrivValidator process
+	// serving gRPC, for Tendermint to connect to
+	PrivValidatorGRPCAddr string `mapstructure:"priv_validator_grpc_addr"`
+
+	// Client certificate and key, and the CA certificates to verify the
+	// external PrivValidator with, for mutual TLS over gRPC
+	PrivValidatorGRPCCert   string `mapstructure:"priv_validator_grpc_cert_file"`
+	PrivValidatorGRPCKey    string `mapstructure:"priv_validator_grpc_key_file"`
+	PrivValidatorGRPCRootCA string `mapstructure:"priv_validator_grpc_root_ca_file"`
+
 	// A JSON file containing the private key to use for p2p authenticated encryption
 	NodeKey string `mapstructure:"node_key_file"`
 
@@ -205,6 +318,11 @@ type BaseConfig struct { //nolint: maligned
 	// TCP or UNIX socket address for the profiling server to listen on
 	ProfListenAddress string `mapstructure:"prof_laddr"`
 
+	// Path of the UNIX socket of the admin API, which `tendermint console`
+	// connects to. Only the user running the node can connect to it.
+	// Empty - disabled
+	AdminSocket string `mapstructure:"admin_socket"`
+
 	// If true, query the ABCI app on connecting to a new peer
 	// so the app can decide if we should keep the connection or not
 	FilterPeers bool `mapstructure:"filter_peers"` // false
@@ -213,20 +331,31 @@ type BaseConfig struct { //nolint: maligned
 // DefaultBaseConfig returns a default base configuration for a Tendermint node
 func DefaultBaseConfig() BaseConfig {
 	return BaseConfig{
-		Genesis:            defaultGenesisJSONPath,
-		PrivValidatorKey:   defaultPrivValKeyPath,
-		PrivValidatorState: defaultPrivValStatePath,
-		NodeKey:            defaultNodeKeyPath,
-		Moniker:            defaultMoniker,
-		ProxyApp:           "tcp://127.0.0.1:26658",
-		ABCI:               "socket",
-		LogLevel:           DefaultPackageLogLevels(),
-		LogFormat:          LogFormatPlain,
-		ProfListenAddress:  "",
-		FastSyncMode:       true,
-		FilterPeers:        false,
-		DBBackend:          "goleveldb",
-		DBPath:             "data",
+		Genesis:                   defaultGenesisJSONPath,
+		PrivValidatorKey:          defaultPrivValKeyPath,
+		PrivValidatorState:        defaultPrivValStatePath,
+		NodeKey:                   defaultNodeKeyPath,
+		Moniker:                   defaultMoniker,
+		ProxyApp:                  "tcp://127.0.0.1:26658",
+		ABCI:                      "socket",
+		LogLevel:                  DefaultPackageLogLevels(),
+		LogFormat:                 LogFormatPlain,
+		ProfListenAddress:         "",
+		FastSyncMode:              true,
+		FilterPeers:               false,
+		DBBackend:                 "goleveldb",
+		DBPath:                    "data",
+		BlockStoreBackend:         BlockStoreBackendKV,
+		BlockStoreCompression:     compress.CodecNone,
+		BlockStoreCompressionDict: "",
+		BlockStoreRecompress:      false,
+		ArchiveURL:                "",
+		ArchiveEndpoint:           "",
+		RetainBlocks:              0,
+		MemorySoftLimit:           0,
+		DiskSoftQuotas:            "",
+		SigVerifyWorkers:          0,
+		SigVerifyCPUs:             "",
 	}
 }
 
@@ -259,6 +388,21 @@ func (cfg BaseConfig) PrivValidatorStateFile() string {
 	return rootify(cfg.PrivValidatorState, cfg.RootDir)
 }
 
+// PrivValidatorGRPCCertFile returns the full path to the gRPC client certificate
+func (cfg BaseConfig) PrivValidatorGRPCCertFile() string {
+	return rootify(cfg.PrivValidatorGRPCCert, cfg.RootDir)
+}
+
+// PrivValidatorGRPCKeyFile returns the full path to the gRPC client key
+func (cfg BaseConfig) PrivValidatorGRPCKeyFile() string {
+	return rootify(cfg.PrivValidatorGRPCKey, cfg.RootDir)
+}
+
+// PrivValidatorGRPCRootCAFile returns the full path to the CA certificates of the gRPC signer
+func (cfg BaseConfig) PrivValidatorGRPCRootCAFile() string {
+	return rootify(cfg.PrivValidatorGRPCRootCA, cfg.RootDir)
+}
+
 // OldPrivValidatorFile returns the full path of the priv_validator.json from pre v0.28.0.
 // TODO: eventually remove.
 func (cfg BaseConfig) OldPrivValidatorFile() string {
@@ -270,11 +414,31 @@ func (cfg BaseConfig) NodeKeyFile() string {
 	return rootify(cfg.NodeKey, cfg.RootDir)
 }
 
+// AdminSocketFile returns the full path to the admin API socket
+func (cfg BaseConfig) AdminSocketFile() string {
+	return rootify(cfg.AdminSocket, cfg.RootDir)
+}
+
 // DBDir returns the full path to the database directory
 func (cfg BaseConfig) DBDir() string {
 	return rootify(cfg.DBPath, cfg.RootDir)
 }
 
+// BlockStoreFilesDir returns the full path to the directory of the flatfile
+// block store segments
+func (cfg BaseConfig) BlockStoreFilesDir() string {
+	return filepath.Join(cfg.DBDir(), "blockstore.files")
+}
+
+// BlockStoreCompressionDictFile returns the full path to the zstd dictionary
+// of the block store, or "" if there is none.
+func (cfg BaseConfig) BlockStoreCompressionDictFile() string {
+	if cfg.BlockStoreCompressionDict == "" {
+		return ""
+	}
+	return rootify(cfg.BlockStoreCompressionDict, cfg.RootDir)
+}
+
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg BaseConfig) ValidateBasic() error {
@@ -283,6 +447,60 @@ func (cfg BaseConfig) ValidateBasic() error {
 	default:
 		return errors.New("unknown log_format (must be 'plain' or 'json')")
 	}
+	switch cfg.BlockStoreBackend {
+	case BlockStoreBackendKV, BlockStoreBackendFlatFile:
+	default:
+		return errors.New("unknown block_store_backend (must be 'kv' or 'flatfile')")
+	}
+	switch cfg.BlockStoreCompression {
+	case compress.CodecNone, compress.CodecSnappy:
+	case compress.CodecZstd:
+		if !compress.ZstdAvailable {
+			return errors.New("block_store_compression 'zstd' requires a build with cgo")
+		}
+	default:
+		return errors.New("unknown block_store_compression (must be 'none', 'snappy' or 'zstd')")
+	}
+	if cfg.BlockStoreCompressionDict != "" && cfg.BlockStoreCompression != compress.CodecZstd {
+		return errors.New("block_store_compression_dict requires block_store_compression 'zstd'")
+	}
+	if cfg.BlockStoreRecompress && cfg.BlockStoreBackend != BlockStoreBackendKV {
+		return errors.New("block_store_recompress requires block_store_backend 'kv'")
+	}
+	if cfg.GenesisHash != "" {
+		if hash, err := hex.DecodeString(cfg.GenesisHash); err != nil || len(hash) != tmhash.Size {
+			return fmt.Errorf("genesis_hash must be the hex encoded SHA-256 hash (%d bytes) of the genesis file",
+				tmhash.Size)
+		}
+	}
+	if cfg.RetainBlocks < 0 {
+		return errors.New("retain_blocks can't be negative")
+	}
+	if cfg.RetainBlocks > 0 && cfg.ArchiveURL == "" {
+		return errors.New("retain_blocks requires archive_url")
+	}
+	if cfg.MemorySoftLimit < -1 {
+		return errors.New("memory_soft_limit can't be less than -1")
+	}
+	if cfg.SigVerifyWorkers < -1 {
+		return errors.New("sig_verify_workers can't be less than -1")
+	}
+	if _, err := cgroup.ParseCPUList(cfg.SigVerifyCPUs); err != nil {
+		return errors.Wrap(err, "wrong sig_verify_cpus")
+	}
+	if cfg.PrivValidatorListenAddr != "" && cfg.PrivValidatorGRPCAddr != "" {
+		return errors.New("priv_validator_laddr and priv_validator_grpc_addr can't both be set")
+	}
+	tlsFiles := 0
+	for _, f := range []string{cfg.PrivValidatorGRPCCert, cfg.PrivValidatorGRPCKey, cfg.PrivValidatorGRPCRootCA} {
+		if f != "" {
+			tlsFiles++
+		}
+	}
+	if tlsFiles != 0 && tlsFiles != 3 {
+		return errors.New("priv_validator_grpc_cert_file, priv_validator_grpc_key_file and " +
+			"priv_validator_grpc_root_ca_file must be set together")
+	}
 	return nil
 }
 
@@ -304,7 +522,8 @@ func DefaultPackageLogLevels() string {
 type RPCConfig struct {
 	RootDir string `mapstructure:"home"`
 
-	// TCP or UNIX socket address for the RPC server to listen on
+	// TCP or UNIX socket address for the RPC server to listen on, or a comma
+	// separated list of them. tcp4:// and tcp6:// listen on IPv4 or IPv6 only
 	ListenAddress string `mapstructure:"laddr"`
 
 	// A list of origins a cross-domain request can be executed from.
@@ -320,7 +539,8 @@ type RPCConfig struct {
 	CORSAllowedHeaders []string `mapstructure:"cors_allowed_headers"`
 
 	// TCP or UNIX socket address for the gRPC server to listen on
-	// NOTE: This server only supports /broadcast_tx_commit
+	// It serves /broadcast_tx_commit (BroadcastAPI), and the status, blocks,
+	// block results, validators and broadcast_tx_sync (CoreAPI, see rpc/grpc/types.proto)
 	GRPCListenAddress string `mapstructure:"grpc_laddr"`
 
 	// Maximum number of simultaneous connections.
@@ -331,8 +551,47 @@ type RPCConfig struct {
 	GRPCMaxOpenConnections int `mapstructure:"grpc_max_open_connections"`
 
 	// Activate unsafe RPC commands like /dial_persistent_peers and /unsafe_flush_mempool
+	//
+	// Deprecated: use the admin API (admin_laddr), which serves the same
+	// routes to authenticated clients only.
 	Unsafe bool `mapstructure:"unsafe"`
 
+	// Serve only the methods which don't change the state of the node or of
+	// the network, on laddr and grpc_laddr: the broadcast methods (txs and
+	// evidence) and the unsafe ones are disabled, so that the RPC can be
+	// exposed publicly. The admin API is not affected.
+	ReadOnly bool `mapstructure:"read_only"`
+
+	// If not empty, only these methods are served on laddr and grpc_laddr,
+	// e.g. ["status", "block", "block_results", "tx"]; read_only still
+	// applies to them. The admin API is not affected.
+	AllowedMethods []string `mapstructure:"allowed_methods"`
+
+	// TCP or UNIX socket address for the admin API to listen on, separate
+	// from laddr. It serves the operator endpoints (dialing peers, evicting
+	// and flushing txs, pruning blocks, compacting the databases, changing
+	// the log level...) to the clients authenticated with admin_token_file
+	// or admin_tls_client_ca_file, and logs their actions.
+	// "" - disabled.
+	AdminListenAddress string `mapstructure:"admin_laddr"`
+
+	// File holding the token of the admin API clients, sent in the
+	// "Authorization: Bearer <token>" header.
+	// Might be either absolute path or path related to tendermint's config directory.
+	AdminTokenFile string `mapstructure:"admin_token_file"`
+
+	// Certificate and private key of the admin API server. Both must be set
+	// for the admin API to be served over HTTPS.
+	// Might be either absolute path or path related to tendermint's config directory.
+	AdminTLSCertFile string `mapstructure:"admin_tls_cert_file"`
+	AdminTLSKeyFile  string `mapstructure:"admin_tls_key_file"`
+
+	// Certificates of the authorities signing the certificates of the admin
+	// API clients (mutual TLS). Requires admin_tls_cert_file and
+	// admin_tls_key_file.
+	// Might be either absolute path or path related to tendermint's config directory.
+	AdminTLSClientCAFile string `mapstructure:"admin_tls_client_ca_file"`
+
 	// Maximum number of simultaneous connections (including WebSocket).
 	// Does not include gRPC connections. See grpc_max_open_connections
 	// If you want to accept a larger number than the default, make sure
@@ -352,6 +611,19 @@ type RPCConfig struct {
 	// to the estimated maximum number of broadcast_tx_commit calls per block.
 	MaxSubscriptionsPerClient int `mapstructure:"max_subscriptions_per_client"`
 
+	// Number of events buffered for each /subscribe-d query of a WebSocket
+	// client which is not reading them fast enough, unless the client asks
+	// for another size (up to MaxSubscriptionBufferSize).
+	SubscriptionBufferSize    int `mapstructure:"subscription_buffer_size"`
+	MaxSubscriptionBufferSize int `mapstructure:"max_subscription_buffer_size"`
+
+	// What to do when the buffer of a subscription is full, unless the client
+	// asks for another policy:
+	//   1) "disconnect" - cancel the subscription
+	//   2) "drop_oldest" - drop the oldest buffered event
+	//   3) "drop_newest" - drop the new event
+	SubscriptionBufferPolicy string `mapstructure:"subscription_buffer_policy"`
+
 	// How long to wait for a tx to be committed during /broadcast_tx_commit
 	// WARNING: Using a value larger than 10s will result in increasing the
 	// global HTTP write timeout, which applies to all connections and endpoints.
@@ -364,6 +636,15 @@ type RPCConfig struct {
 	// Maximum size of request header, in bytes
 	MaxHeaderBytes int `mapstructure:"max_header_bytes"`
 
+	// Maximum number of requests from a single JSON-RPC batch executed
+	// concurrently. 0 - process batches sequentially.
+	MaxBatchConcurrency int `mapstructure:"max_batch_concurrency"`
+
+	// Attest the /status responses with a signature of the node ID, chain
+	// ID, latest height and current time by the node key, so that monitoring
+	// systems can check they talk to the node itself.
+	StatusAttestation bool `mapstructure:"status_attestation"`
+
 	// The path to a file containing certificate that is used to create the HTTPS server.
 	// Migth be either absolute path or path related to tendermint's config directory.
 	//
@@ -394,15 +675,22 @@ func DefaultRPCConfig() *RPCConfig {
 		GRPCMaxOpenConnections: 900,
 
 		Unsafe:             false,
+		ReadOnly:           false,
+		AllowedMethods:     []string{},
 		MaxOpenConnections: 900,
 
 		MaxSubscriptionClients:    100,
 		MaxSubscriptionsPerClient: 5,
+		SubscriptionBufferSize:    100,
+		MaxSubscriptionBufferSize: 1000,
+		SubscriptionBufferPolicy:  "disconnect",
 		TimeoutBroadcastTxCommit:  10 * time.Second,
 
 		MaxBodyBytes:   int64(1000000), // 1MB
 		MaxHeaderBytes: 1 << 20,        // same as the net/http default
 
+		MaxBatchConcurrency: 8,
+
 		TLSCertFile: "",
 		TLSKeyFile:  "",
 	}
@@ -432,6 +720,18 @@ func (cfg *RPCConfig) ValidateBasic() error {
 	if cfg.MaxSubscriptionsPerClient < 0 {
 		return errors.New("max_subscriptions_per_client can't be negative")
 	}
+	if cfg.SubscriptionBufferSize <= 0 {
+		return errors.New("subscription_buffer_size must be positive")
+	}
+	if cfg.MaxSubscriptionBufferSize < cfg.SubscriptionBufferSize {
+		return errors.New("max_subscription_buffer_size can't be less than subscription_buffer_size")
+	}
+	switch cfg.SubscriptionBufferPolicy {
+	case "disconnect", "drop_oldest", "drop_newest":
+	default:
+		return fmt.Errorf("unknown subscription_buffer_policy %q (want disconnect, drop_oldest or drop_newest)",
+			cfg.SubscriptionBufferPolicy)
+	}
 	if cfg.TimeoutBroadcastTxCommit < 0 {
 		return errors.New("timeout_broadcast_tx_commit can't be negative")
 	}
@@ -441,6 +741,21 @@ func (cfg *RPCConfig) ValidateBasic() error {
 	if cfg.MaxHeaderBytes < 0 {
 		return errors.New("max_header_bytes can't be negative")
 	}
+	if cfg.MaxBatchConcurrency < 0 {
+		return errors.New("max_batch_concurrency can't be negative")
+	}
+	if (cfg.AdminTLSCertFile == "") != (cfg.AdminTLSKeyFile == "") {
+		return errors.New("admin_tls_cert_file and admin_tls_key_file must be set together")
+	}
+	if cfg.AdminTLSClientCAFile != "" && !cfg.IsAdminTLSEnabled() {
+		return errors.New("admin_tls_client_ca_file requires admin_tls_cert_file and admin_tls_key_file")
+	}
+	if cfg.ReadOnly && cfg.Unsafe {
+		return errors.New("unsafe and read_only can't be set together")
+	}
+	if cfg.AdminListenAddress != "" && cfg.AdminTokenFile == "" && cfg.AdminTLSClientCAFile == "" {
+		return errors.New("admin_laddr requires admin_token_file or admin_tls_client_ca_file to authenticate the clients")
+	}
 	return nil
 }
 
@@ -469,6 +784,38 @@ func (cfg RPCConfig) IsTLSEnabled() bool {
 	return cfg.TLSCertFile != "" && cfg.TLSKeyFile != ""
 }
 
+// AdminTokenFilePath returns the full path of AdminTokenFile.
+func (cfg RPCConfig) AdminTokenFilePath() string {
+	return cfg.configFile(cfg.AdminTokenFile)
+}
+
+// AdminCertFile returns the full path of AdminTLSCertFile.
+func (cfg RPCConfig) AdminCertFile() string {
+	return cfg.configFile(cfg.AdminTLSCertFile)
+}
+
+// AdminKeyFile returns the full path of AdminTLSKeyFile.
+func (cfg RPCConfig) AdminKeyFile() string {
+	return cfg.configFile(cfg.AdminTLSKeyFile)
+}
+
+// AdminClientCAFile returns the full path of AdminTLSClientCAFile.
+func (cfg RPCConfig) AdminClientCAFile() string {
+	return cfg.configFile(cfg.AdminTLSClientCAFile)
+}
+
+// IsAdminTLSEnabled returns whether the admin API is served over HTTPS.
+func (cfg RPCConfig) IsAdminTLSEnabled() bool {
+	return cfg.AdminTLSCertFile != "" && cfg.AdminTLSKeyFile != ""
+}
+
+func (cfg RPCConfig) configFile(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return rootify(filepath.Join(defaultConfigDir, path), cfg.RootDir)
+}
+
 //-----------------------------------------------------------------------------
 // P2PConfig
 
@@ -476,10 +823,21 @@ func (cfg RPCConfig) IsTLSEnabled() bool {
 type P2PConfig struct { //nolint: maligned
 	RootDir string `mapstructure:"home"`
 
-	// Address to listen for incoming connections
+	// Comma separated list of addresses to listen for incoming connections.
+	// The tcp:// prefix listens on both IPv4 and IPv6 for unspecified IPs
+	// (0.0.0.0 or [::]), tcp4:// on IPv4 only and tcp6:// on IPv6 only
 	ListenAddress string `mapstructure:"laddr"`
 
-	// Address to advertise to peers for them to dial
+	// Transport of the peer connections: "tcp" multiplexes the channels on a
+	// single connection; "quic" opens a stream per channel, so that a lost
+	// packet only delays the messages of its channel. QUIC listens on the UDP
+	// ports of laddr and needs an Ed25519 node key.
+	Transport string `mapstructure:"transport"`
+
+	// Comma separated list of addresses to advertise to peers for them to
+	// dial, one per listen address: the peers connected over IPv4 (or IPv6)
+	// are told the first advertised IPv4 (or IPv6) address.
+	// "" - the listen address; "none" - not advertised
 	ExternalAddress string `mapstructure:"external_address"`
 
 	// Comma separated list of seed nodes to connect to
@@ -530,6 +888,14 @@ type P2PConfig struct { //nolint: maligned
 	// other peers)
 	PrivatePeerIDs string `mapstructure:"private_peer_ids"`
 
+	// Comma separated list of peer IDs accepted even when the node already
+	// has MaxNumInboundPeers inbound peers
+	UnconditionalPeerIDs string `mapstructure:"unconditional_peer_ids"`
+
+	// Comma separated list of <ID>=<label> pairs naming peers in logs,
+	// metrics and /net_info
+	PeerLabels string `mapstructure:"peer_labels"`
+
 	// Toggle to disable guard against peers connecting from the same ip.
 	AllowDuplicateIP bool `mapstructure:"allow_duplicate_ip"`
 
@@ -537,6 +903,31 @@ type P2PConfig struct { //nolint: maligned
 	HandshakeTimeout time.Duration `mapstructure:"handshake_timeout"`
 	DialTimeout      time.Duration `mapstructure:"dial_timeout"`
 
+	// SOCKS5 proxy the peers are dialed through, except the persistent ones:
+	// socks5://[user:password@]host:port (e.g. socks5://127.0.0.1:9050 for
+	// Tor). Required to dial .onion addresses. Empty to dial them directly.
+	Proxy string `mapstructure:"proxy"`
+
+	// SOCKS5 proxy the persistent peers are dialed through. Empty to dial
+	// them directly.
+	PersistentPeersProxy string `mapstructure:"persistent_peers_proxy"`
+
+	// External admission control: an HTTP(S) endpoint (http://host:port/path)
+	// or a gRPC service (grpc://host:port) asked whether to admit each peer.
+	// Peers are rejected if the check fails or times out.
+	AdmissionCheckAddr string `mapstructure:"admission_check_addr"`
+
+	// Timeout of an admission check
+	AdmissionCheckTimeout time.Duration `mapstructure:"admission_check_timeout"`
+
+	// How long the decision of an admission check is cached for a peer
+	AdmissionCheckCacheTTL time.Duration `mapstructure:"admission_check_cache_ttl"`
+
+	// Comma separated list of the home directories of other chains run by the
+	// node, sharing its connections (see node.NewNetworkNode). Their p2p
+	// config is ignored, and their RPC is served under /networks/<chain_id>/.
+	Networks string `mapstructure:"networks"`
+
 	// Testing params.
 	// Force dial to fail
 	TestDialFail bool `mapstructure:"test_dial_fail"`
@@ -549,6 +940,7 @@ type P2PConfig struct { //nolint: maligned
 func DefaultP2PConfig() *P2PConfig {
 	return &P2PConfig{
 		ListenAddress:           "tcp://0.0.0.0:26656",
+		Transport:               "tcp",
 		ExternalAddress:         "",
 		UPNP:                    false,
 		AddrBook:                defaultAddrBookPath,
@@ -564,6 +956,12 @@ func DefaultP2PConfig() *P2PConfig {
 		AllowDuplicateIP:        false,
 		HandshakeTimeout:        20 * time.Second,
 		DialTimeout:             3 * time.Second,
+		Proxy:                   "",
+		PersistentPeersProxy:    "",
+		AdmissionCheckAddr:      "",
+		AdmissionCheckTimeout:   3 * time.Second,
+		AdmissionCheckCacheTTL:  time.Minute,
+		Networks:                "",
 		TestDialFail:            false,
 		TestFuzz:                false,
 		TestFuzzConfig:          DefaultFuzzConnConfig(),
@@ -584,6 +982,53 @@ func (cfg *P2PConfig) AddrBookFile() string {
 	return rootify(cfg.AddrBook, cfg.RootDir)
 }
 
+// NetworkHomes returns the home directories of Networks.
+func (cfg *P2PConfig) NetworkHomes() []string {
+	var homes []string
+	for _, home := range splitAndTrim(cfg.Networks) {
+		if home != "" {
+			homes = append(homes, rootify(home, cfg.RootDir))
+		}
+	}
+	return homes
+}
+
+// ExternalAddressNone is the external address of the listen addresses which
+// aren't advertised to peers.
+const ExternalAddressNone = "none"
+
+// ListenAddresses returns the addresses of ListenAddress.
+func (cfg *P2PConfig) ListenAddresses() []string {
+	var laddrs []string
+	for _, laddr := range splitAndTrim(cfg.ListenAddress) {
+		if laddr != "" {
+			laddrs = append(laddrs, laddr)
+		}
+	}
+	return laddrs
+}
+
+// ExternalAddresses returns the addresses of ExternalAddress, one per listen
+// address: empty for the listen address itself, or ExternalAddressNone.
+func (cfg *P2PConfig) ExternalAddresses() []string {
+	addrs := make([]string, len(cfg.ListenAddresses()))
+	copy(addrs, splitAndTrim(cfg.ExternalAddress))
+	return addrs
+}
+
+// splitAndTrim splits s by commas and trims the spaces around the parts,
+// keeping the empty ones. It returns nil if s is empty.
+func splitAndTrim(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg *P2PConfig) ValidateBasic() error {
@@ -605,6 +1050,48 @@ func (cfg *P2PConfig) ValidateBasic() error {
 	if cfg.RecvRate < 0 {
 		return errors.New("recv_rate can't be negative")
 	}
+	laddrs := cfg.ListenAddresses()
+	for _, laddr := range laddrs {
+		if i := strings.Index(laddr, "://"); i >= 0 {
+			switch laddr[:i] {
+			case "tcp", "tcp4", "tcp6":
+			default:
+				return fmt.Errorf("laddr %s must start with tcp://, tcp4:// or tcp6://", laddr)
+			}
+		}
+	}
+	switch cfg.Transport {
+	case "tcp":
+	case "quic":
+		// the peers are dialed over UDP
+		if cfg.Proxy != "" || cfg.PersistentPeersProxy != "" {
+			return errors.New("the quic transport can't dial through proxy or persistent_peers_proxy")
+		}
+	default:
+		return fmt.Errorf("unknown transport %q, must be tcp or quic", cfg.Transport)
+	}
+	if n := len(splitAndTrim(cfg.ExternalAddress)); n > len(laddrs) && n > 1 {
+		return errors.New("external_address can't have more addresses than laddr")
+	}
+	if cfg.Proxy != "" && !strings.HasPrefix(cfg.Proxy, "socks5://") {
+		return errors.New("proxy must start with socks5://")
+	}
+	if cfg.PersistentPeersProxy != "" && !strings.HasPrefix(cfg.PersistentPeersProxy, "socks5://") {
+		return errors.New("persistent_peers_proxy must start with socks5://")
+	}
+	if cfg.AdmissionCheckAddr != "" {
+		if !strings.HasPrefix(cfg.AdmissionCheckAddr, "http://") &&
+			!strings.HasPrefix(cfg.AdmissionCheckAddr, "https://") &&
+			!strings.HasPrefix(cfg.AdmissionCheckAddr, "grpc://") {
+			return errors.New("admission_check_addr must start with http://, https:// or grpc://")
+		}
+		if cfg.AdmissionCheckTimeout <= 0 {
+			return errors.New("admission_check_timeout must be positive")
+		}
+	}
+	if cfg.AdmissionCheckCacheTTL < 0 {
+		return errors.New("admission_check_cache_ttl can't be negative")
+	}
 	return nil
 }
 
@@ -641,6 +1128,29 @@ type MempoolConfig struct {
 	MaxTxsBytes int64  `mapstructure:"max_txs_bytes"`
 	CacheSize   int    `mapstructure:"cache_size"`
 	MaxTxBytes  int    `mapstructure:"max_tx_bytes"`
+
+	// Type of the cache of the seen txs: "lru" keeps the hashes of the
+	// CacheSize most recent txs in memory, "cuckoo" keeps them in cuckoo
+	// filters saved to CacheFilePath after each block, so the txs committed
+	// before a restart aren't admitted again after it.
+	CacheType string `mapstructure:"cache_type"`
+
+	// File of the "cuckoo" cache. Empty keeps it in memory only.
+	CacheFilePath string `mapstructure:"cache_file"`
+
+	// Number of txs rechecked per RecheckBatch ABCI call after a block.
+	// 0 rechecks the txs one by one with CheckTx.
+	RecheckBatchSize int `mapstructure:"recheck_batch_size"`
+
+	// Reap the txs for a proposal without waiting for the recheck to complete.
+	// The txs which aren't rechecked yet are left out of the proposal.
+	RecheckAsync bool `mapstructure:"recheck_async"`
+
+	// Directory of the log of the rejected txs. Empty disables the log.
+	RejectionsLogPath string `mapstructure:"rejections_log_dir"`
+
+	// Maximum total size of the log of the rejected txs, in bytes.
+	RejectionsLogMaxSize int64 `mapstructure:"rejections_log_max_size"`
 }
 
 // DefaultMempoolConfig returns a default configuration for the Tendermint mempool
@@ -655,6 +1165,15 @@ func DefaultMempoolConfig() *MempoolConfig {
 		MaxTxsBytes: 1024 * 1024 * 1024, // 1GB
 		CacheSize:   10000,
 		MaxTxBytes:  1024 * 1024, // 1MB
+
+		CacheType:     MempoolCacheLRU,
+		CacheFilePath: filepath.Join(defaultDataDir, "mempool.cache"),
+
+		RecheckBatchSize: 0,
+		RecheckAsync:     false,
+
+		RejectionsLogPath:    "",
+		RejectionsLogMaxSize: 10 * 1024 * 1024, // 10MB
 	}
 }
 
@@ -675,6 +1194,27 @@ func (cfg *MempoolConfig) WalEnabled() bool {
 	return cfg.WalPath != ""
 }
 
+// CacheFile returns the full path to the file of the cache of the seen txs.
+func (cfg *MempoolConfig) CacheFile() string {
+	return rootify(cfg.CacheFilePath, cfg.RootDir)
+}
+
+// CacheFileEnabled returns true if the cache of the seen txs is saved to a
+// file.
+func (cfg *MempoolConfig) CacheFileEnabled() bool {
+	return cfg.CacheType == MempoolCacheCuckoo && cfg.CacheFilePath != ""
+}
+
+// RejectionsLogDir returns the full path to the log of the rejected txs.
+func (cfg *MempoolConfig) RejectionsLogDir() string {
+	return rootify(cfg.RejectionsLogPath, cfg.RootDir)
+}
+
+// RejectionsLogEnabled returns true if the log of the rejected txs is enabled.
+func (cfg *MempoolConfig) RejectionsLogEnabled() bool {
+	return cfg.RejectionsLogPath != ""
+}
+
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg *MempoolConfig) ValidateBasic() error {
@@ -690,6 +1230,17 @@ func (cfg *MempoolConfig) ValidateBasic() error {
 	if cfg.MaxTxBytes < 0 {
 		return errors.New("max_tx_bytes can't be negative")
 	}
+	switch cfg.CacheType {
+	case MempoolCacheLRU, MempoolCacheCuckoo:
+	default:
+		return fmt.Errorf("unknown cache_type %q (want %q or %q)", cfg.CacheType, MempoolCacheLRU, MempoolCacheCuckoo)
+	}
+	if cfg.RecheckBatchSize < 0 {
+		return errors.New("recheck_batch_size can't be negative")
+	}
+	if cfg.RejectionsLogMaxSize < 0 {
+		return errors.New("rejections_log_max_size can't be negative")
+	}
 	return nil
 }
 
@@ -699,12 +1250,63 @@ func (cfg *MempoolConfig) ValidateBasic() error {
 // FastSyncConfig defines the configuration for the Tendermint fast sync service
 type FastSyncConfig struct {
 	Version string `mapstructure:"version"`
+
+	// Number of blocks whose state is written to disk in a single batch
+	// while fast syncing, instead of block by block. 0 disables batching.
+	BatchedWrites int `mapstructure:"batched_writes"`
+
+	// If the height doesn't move for that long (v0 only), log the state of
+	// the sync, disconnect the least useful half of the peers and look for
+	// new ones. 0 disables it.
+	NoProgressTimeout time.Duration `mapstructure:"no_progress_timeout"`
+
+	// How long to wait for the heights of the first peers on startup (v0
+	// only). If none of them is ahead of us, fast sync is skipped and the
+	// node switches to consensus right away. 0 disables it.
+	TipGracePeriod time.Duration `mapstructure:"tip_grace_period"`
+
+	// Fast sync waits for at least MinPeers peers, and for
+	// PeerStabilizationDelay after the first one reported its height, before
+	// it targets the max height of the peers and may consider itself done.
+	MinPeers               int           `mapstructure:"min_peers"`
+	PeerStabilizationDelay time.Duration `mapstructure:"peer_stabilization_delay"`
+
+	// Maximum rates (bytes/s) of the blocks sent to and received from peers
+	// on the blockchain channel, for all peers and per peer (v0 only), so
+	// that fast syncing nodes don't saturate the link of the node and delay
+	// consensus gossip. 0 means unlimited.
+	UploadRate          int64 `mapstructure:"upload_rate"`
+	UploadRatePerPeer   int64 `mapstructure:"upload_rate_per_peer"`
+	DownloadRate        int64 `mapstructure:"download_rate"`
+	DownloadRatePerPeer int64 `mapstructure:"download_rate_per_peer"`
+
+	// Approximate size (bytes) of the blocks requested or received but not
+	// applied yet. The number of requests follows it, based on the average
+	// size of the blocks, and the throughput of each peer.
+	MemoryBudget int64 `mapstructure:"memory_budget"`
+
+	// Trust anchor to fast sync from instead of genesis, for chains whose
+	// early blocks are no longer available: the hash of the block at
+	// TrustHeight, which the app must have committed already (e.g. restored
+	// from a snapshot). The state at TrustHeight is fetched from the
+	// comma-separated TrustRPCServers, and verified against the hash. Only
+	// used when the node has no blocks yet. 0 disables it.
+	TrustHeight     int64  `mapstructure:"trust_height"`
+	TrustHash       string `mapstructure:"trust_hash"`
+	TrustRPCServers string `mapstructure:"trust_rpc_servers"`
 }
 
 // DefaultFastSyncConfig returns a default configuration for the fast sync service
 func DefaultFastSyncConfig() *FastSyncConfig {
 	return &FastSyncConfig{
-		Version: "v0",
+		Version:           "v0",
+		NoProgressTimeout: 2 * time.Minute,
+		TipGracePeriod:    5 * time.Second,
+
+		MinPeers:               1,
+		PeerStabilizationDelay: 5 * time.Second,
+
+		MemoryBudget: 128 * 1024 * 1024, // 128MB
 	}
 }
 
@@ -715,6 +1317,47 @@ func TestFastSyncConfig() *FastSyncConfig {
 
 // ValidateBasic performs basic validation.
 func (cfg *FastSyncConfig) ValidateBasic() error {
+	if cfg.BatchedWrites < 0 {
+		return errors.New("batched_writes can't be negative")
+	}
+	if cfg.NoProgressTimeout < 0 {
+		return errors.New("no_progress_timeout can't be negative")
+	}
+	if cfg.TipGracePeriod < 0 {
+		return errors.New("tip_grace_period can't be negative")
+	}
+	if cfg.MinPeers < 1 {
+		return errors.New("min_peers must be at least 1")
+	}
+	if cfg.PeerStabilizationDelay < 0 {
+		return errors.New("peer_stabilization_delay can't be negative")
+	}
+	if cfg.UploadRate < 0 {
+		return errors.New("upload_rate can't be negative")
+	}
+	if cfg.UploadRatePerPeer < 0 {
+		return errors.New("upload_rate_per_peer can't be negative")
+	}
+	if cfg.DownloadRate < 0 {
+		return errors.New("download_rate can't be negative")
+	}
+	if cfg.DownloadRatePerPeer < 0 {
+		return errors.New("download_rate_per_peer can't be negative")
+	}
+	if cfg.MemoryBudget <= 0 {
+		return errors.New("memory_budget must be positive")
+	}
+	if cfg.TrustHeight < 0 {
+		return errors.New("trust_height can't be negative")
+	}
+	if cfg.TrustHeight > 0 {
+		if hash, err := hex.DecodeString(cfg.TrustHash); err != nil || len(hash) != tmhash.Size {
+			return fmt.Errorf("trust_hash must be the hex encoded hash (%d bytes) of the block at trust_height", tmhash.Size)
+		}
+		if cfg.TrustRPCServers == "" {
+			return errors.New("trust_rpc_servers can't be empty with a trust_height")
+		}
+	}
 	switch cfg.Version {
 	case "v0":
 		return nil
@@ -735,6 +1378,16 @@ type ConsensusConfig struct {
 	WalPath string `mapstructure:"wal_file"`
 	walFile string // overrides WalPath if set
 
+	// Size of a WAL segment: once the current segment reaches it, it is
+	// rotated and a new one is started.
+	WalSegmentSize int64 `mapstructure:"wal_segment_size"`
+	// Number of rotated WAL segments kept, the oldest ones being removed.
+	// 0 - all of them, up to 1GB in total
+	WalRetainSegments int `mapstructure:"wal_retain_segments"`
+	// Compression of the WAL messages: none | snappy
+	WalCompression string `mapstructure:"wal_compression"`
+
+	// Overridden by the timeouts set in the consensus params of the chain.
 	TimeoutPropose        time.Duration `mapstructure:"timeout_propose"`
 	TimeoutProposeDelta   time.Duration `mapstructure:"timeout_propose_delta"`
 	TimeoutPrevote        time.Duration `mapstructure:"timeout_prevote"`
@@ -743,6 +1396,12 @@ type ConsensusConfig struct {
 	TimeoutPrecommitDelta time.Duration `mapstructure:"timeout_precommit_delta"`
 	TimeoutCommit         time.Duration `mapstructure:"timeout_commit"`
 
+	// Maximum time the proposer spends reaping txs from the mempool and
+	// waiting for PrepareProposal. Past it, the block is proposed with the txs
+	// reaped so far rather than missing the proposal.
+	// 0 - no deadline
+	CreateProposalDeadline time.Duration `mapstructure:"create_proposal_deadline"`
+
 	// Make progress as soon as we have all the precommits (as if TimeoutCommit = 0)
 	SkipTimeoutCommit bool `mapstructure:"skip_timeout_commit"`
 
@@ -759,6 +1418,9 @@ type ConsensusConfig struct {
 func DefaultConsensusConfig() *ConsensusConfig {
 	return &ConsensusConfig{
 		WalPath:                     filepath.Join(defaultDataDir, "cs.wal", "wal"),
+		WalSegmentSize:              10 * 1024 * 1024, // 10MB
+		WalRetainSegments:           0,
+		WalCompression:              WALCompressionNone,
 		TimeoutPropose:              3000 * time.Millisecond,
 		TimeoutProposeDelta:         500 * time.Millisecond,
 		TimeoutPrevote:              1000 * time.Millisecond,
@@ -766,6 +1428,7 @@ func DefaultConsensusConfig() *ConsensusConfig {
 		TimeoutPrecommit:            1000 * time.Millisecond,
 		TimeoutPrecommitDelta:       500 * time.Millisecond,
 		TimeoutCommit:               1000 * time.Millisecond,
+		CreateProposalDeadline:      0,
 		SkipTimeoutCommit:           false,
 		CreateEmptyBlocks:           true,
 		CreateEmptyBlocksInterval:   0 * time.Second,
@@ -838,6 +1501,17 @@ func (cfg *ConsensusConfig) SetWalFile(walFile string) {
 // ValidateBasic performs basic validation (checking param bounds, etc.) and
 // returns an error if any check fails.
 func (cfg *ConsensusConfig) ValidateBasic() error {
+	if cfg.WalSegmentSize <= 0 {
+		return errors.New("wal_segment_size must be positive")
+	}
+	if cfg.WalRetainSegments < 0 {
+		return errors.New("wal_retain_segments can't be negative")
+	}
+	switch cfg.WalCompression {
+	case WALCompressionNone, WALCompressionSnappy:
+	default:
+		return errors.New("unknown wal_compression (must be 'none' or 'snappy')")
+	}
 	if cfg.TimeoutPropose < 0 {
 		return errors.New("timeout_propose can't be negative")
 	}
@@ -859,6 +1533,9 @@ func (cfg *ConsensusConfig) ValidateBasic() error {
 	if cfg.TimeoutCommit < 0 {
 		return errors.New("timeout_commit can't be negative")
 	}
+	if cfg.CreateProposalDeadline < 0 {
+		return errors.New("create_proposal_deadline can't be negative")
+	}
 	if cfg.CreateEmptyBlocksInterval < 0 {
 		return errors.New("create_empty_blocks_interval can't be negative")
 	}
@@ -877,13 +1554,22 @@ func (cfg *ConsensusConfig) ValidateBasic() error {
 // TxIndexConfig defines the configuration for the transaction indexer,
 // including tags to index.
 type TxIndexConfig struct {
-	// What indexer to use for transactions
+	// The backend database list to back the indexer.
+	// If list contains "null", meaning no indexer service will be used.
 	//
 	// Options:
 	//   1) "null"
 	//   2) "kv" (default) - the simplest possible indexer,
 	//      backed by key-value storage (defaults to levelDB; see DBBackend).
-	Indexer string `mapstructure:"indexer"`
+	//   3) "psql" - the indexer services backed by PostgreSQL.
+	// When "kv" or "psql" is chosen, both block and transaction events are
+	// indexed. The "kv" indexer serves the RPC queries, else the "psql"
+	// indexer serves /tx and /tx_search.
+	Indexer []string `mapstructure:"indexer"`
+
+	// The PostgreSQL connection configuration, the connection format:
+	// postgresql://<user>:<password>@<host>:<port>/<db>?<opts>
+	PsqlConn string `mapstructure:"psql_conn"`
 
 	// Comma-separated list of tags to index (by default the only tag is "tx.hash")
 	//
@@ -901,14 +1587,49 @@ type TxIndexConfig struct {
 	// precedence over IndexAllTags (i.e. when given both, IndexTags will be
 	// indexed).
 	IndexAllTags bool `mapstructure:"index_all_tags"`
+
+	// The PostgreSQL connection configuration of a read replica of the
+	// psql_conn database (e.g. a streaming replica), in the psql_conn format.
+	// When set, the "psql" indexer reads the transactions (/tx, /tx_search)
+	// from the replica, while the node keeps writing to psql_conn.
+	PsqlReadReplicaConn string `mapstructure:"psql_read_replica_conn"`
+
+	// Maximum number of blocks the read replica may lag behind before reads
+	// go back to psql_conn. 0 - no limit.
+	ReadReplicaMaxLag int64 `mapstructure:"read_replica_max_lag"`
+
+	// Directory to stream the applied blocks to, with their results
+	// (BeginBlock, DeliverTx and EndBlock responses, validator updates and
+	// app hash), one JSON record per line, for external indexers. Relative
+	// paths are resolved against the node's home directory. "" - disabled.
+	StreamFileDir string `mapstructure:"stream_file_dir"`
+
+	// Maximum size in bytes of the stream files. The oldest files are removed
+	// past it.
+	StreamFileMaxSize int64 `mapstructure:"stream_file_max_size"`
+
+	// Address of a gRPC service to push the applied blocks to, with their
+	// results (see the state/streaming package). "" - disabled.
+	StreamGRPCAddr string `mapstructure:"stream_grpc_addr"`
+
+	// Maximum number of blocks buffered for the gRPC service. Past it, the
+	// blocks are dropped.
+	StreamBufferSize int `mapstructure:"stream_buffer_size"`
 }
 
 // DefaultTxIndexConfig returns a default configuration for the transaction indexer.
 func DefaultTxIndexConfig() *TxIndexConfig {
 	return &TxIndexConfig{
-		Indexer:      "kv",
-		IndexTags:    "",
-		IndexAllTags: false,
+		Indexer:             []string{"kv"},
+		PsqlConn:            "",
+		IndexTags:           "",
+		IndexAllTags:        false,
+		PsqlReadReplicaConn: "",
+		ReadReplicaMaxLag:   10,
+		StreamFileDir:       "",
+		StreamFileMaxSize:   1024 * 1024 * 1024, // 1GB
+		StreamGRPCAddr:      "",
+		StreamBufferSize:    1000,
 	}
 }
 
@@ -917,6 +1638,47 @@ func TestTxIndexConfig() *TxIndexConfig {
 	return DefaultTxIndexConfig()
 }
 
+// ValidateBasic performs basic validation (checking param bounds, etc.) and
+// returns an error if any check fails.
+func (cfg *TxIndexConfig) ValidateBasic() error {
+	if len(cfg.Indexer) == 0 {
+		return errors.New("indexer can't be empty (use \"null\" to disable indexing)")
+	}
+	seen := make(map[string]bool)
+	for _, indexer := range cfg.Indexer {
+		switch indexer {
+		case "null":
+			if len(cfg.Indexer) > 1 {
+				return errors.New("the \"null\" indexer can't be combined with other indexers")
+			}
+		case "kv":
+		case "psql":
+			if cfg.PsqlConn == "" {
+				return errors.New("psql_conn must be set when using the \"psql\" indexer")
+			}
+		default:
+			return fmt.Errorf("unknown indexer %q", indexer)
+		}
+		if seen[indexer] {
+			return fmt.Errorf("duplicate indexer %q", indexer)
+		}
+		seen[indexer] = true
+	}
+	if cfg.PsqlReadReplicaConn != "" && !seen["psql"] {
+		return errors.New("psql_read_replica_conn needs the \"psql\" indexer")
+	}
+	if cfg.ReadReplicaMaxLag < 0 {
+		return errors.New("read_replica_max_lag can't be negative")
+	}
+	if cfg.StreamFileMaxSize <= 0 {
+		return errors.New("stream_file_max_size must be positive")
+	}
+	if cfg.StreamBufferSize <= 0 {
+		return errors.New("stream_buffer_size must be positive")
+	}
+	return nil
+}
+
 //-----------------------------------------------------------------------------
 // InstrumentationConfig
 
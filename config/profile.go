@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Profiles tune the configuration for the role of the node in the network.
+const (
+	// ProfileValidator is a validator behind sentry nodes: it only connects to
+	// its persistent peers and doesn't index transactions.
+	ProfileValidator = "validator"
+	// ProfileSentry is a full node shielding validators from the network.
+	ProfileSentry = "sentry"
+	// ProfileArchive is a full node keeping and indexing the whole history
+	// to serve RPC queries.
+	ProfileArchive = "archive"
+	// ProfileSeed is a node crawling the network to share peer addresses.
+	ProfileSeed = "seed"
+)
+
+// Profiles returns the names of the available profiles.
+func Profiles() []string {
+	return []string{ProfileValidator, ProfileSentry, ProfileArchive, ProfileSeed}
+}
+
+// ApplyProfile tunes the config for the given profile. All profiles disable
+// the unsafe RPC endpoints. Note Tendermint keeps all blocks; pruning old
+// state is up to the application.
+func (cfg *Config) ApplyProfile(profile string) error {
+	switch profile {
+	case ProfileValidator:
+		cfg.P2P.PexReactor = false
+		cfg.P2P.MaxNumInboundPeers = 10
+		cfg.P2P.MaxNumOutboundPeers = 10
+		cfg.TxIndex.Indexer = []string{"null"}
+	case ProfileSentry:
+		cfg.P2P.PexReactor = true
+		cfg.P2P.MaxNumInboundPeers = 100
+		cfg.P2P.MaxNumOutboundPeers = 20
+		cfg.TxIndex.Indexer = []string{"null"}
+	case ProfileArchive:
+		cfg.TxIndex.Indexer = []string{"kv"}
+		cfg.TxIndex.IndexTags = ""
+		cfg.TxIndex.IndexAllTags = true
+	case ProfileSeed:
+		cfg.P2P.PexReactor = true
+		cfg.P2P.SeedMode = true
+		cfg.P2P.MaxNumInboundPeers = 1000
+		cfg.P2P.MaxNumOutboundPeers = 100
+		cfg.Mempool.Broadcast = false
+		cfg.TxIndex.Indexer = []string{"null"}
+	default:
+		return fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(Profiles(), ", "))
+	}
+	cfg.RPC.Unsafe = false
+	return nil
+}
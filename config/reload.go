@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// hotReloadable are the keys of the config fields applied to a running node
+// when it reloads its config file (on SIGHUP), without a restart. The other
+// fields are only read on startup.
+var hotReloadable = map[string]bool{
+	"log_level":                true,
+	"rpc.cors_allowed_origins": true,
+	"rpc.cors_allowed_methods": true,
+	"rpc.cors_allowed_headers": true,
+	"mempool.size":             true,
+	"mempool.max_txs_bytes":    true,
+	"p2p.send_rate":            true,
+	"p2p.recv_rate":            true,
+}
+
+// IsHotReloadable returns true if the config field with the given key (e.g.
+// "mempool.size") is applied to a running node when it reloads its config.
+func IsHotReloadable(key string) bool {
+	return hotReloadable[key]
+}
+
+// HotReloadableFields returns the sorted keys of the hot reloadable fields.
+func HotReloadableFields() []string {
+	keys := make([]string, 0, len(hotReloadable))
+	for key := range hotReloadable {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Diff returns the keys of the fields (e.g. "rpc.laddr") whose values differ
+// between cfg and other, in the order of the config file.
+func (cfg *Config) Diff(other *Config) []string {
+	return diffFields("", reflect.ValueOf(cfg).Elem(), reflect.ValueOf(other).Elem())
+}
+
+func diffFields(prefix string, a, b reflect.Value) []string {
+	var keys []string
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" {
+			continue // unexported or not in the config file
+		}
+		fa, fb := a.Field(i), b.Field(i)
+		switch {
+		case tag == ",squash":
+			keys = append(keys, diffFields(prefix, fa, fb)...)
+		case prefix == "" && field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct:
+			// a section of the config file
+			keys = append(keys, diffFields(tag+".", fa.Elem(), fb.Elem())...)
+		case !reflect.DeepEqual(fa.Interface(), fb.Interface()):
+			keys = append(keys, prefix+strings.TrimSuffix(tag, ",omitempty"))
+		}
+	}
+	return keys
+}
@@ -78,6 +78,21 @@ const defaultConfigTemplate = `# This is a TOML config file.
 # or the name of an ABCI application compiled in with the Tendermint binary
 proxy_app = "{{ .BaseConfig.ProxyApp }}"
 
+# TCP or UNIX socket address of a shadow ABCI application (e.g. the next
+# version of the app), or the name of a compiled in one. The committed
+# blocks are executed against it too, in the background, and its app
+# hashes are compared with the ones of the application, without affecting
+# consensus. Empty - no shadow application
+shadow_proxy_app = "{{ .BaseConfig.ShadowProxyApp }}"
+
+# TCP or UNIX socket address of a backfill ABCI application: an instance of
+# the application restored to a past state (e.g. from a snapshot of its data),
+# or the name of a compiled in one. The block results missing from the node
+# (e.g. removed by a pruning tool) are recomputed by re-executing the blocks
+# against it, from its height, when started with the admin backfill_results.
+# Empty - no backfill application
+backfill_proxy_app = "{{ .BaseConfig.BackfillProxyApp }}"
+
 # A custom human readable name for this node
 moniker = "{{ .BaseConfig.Moniker }}"
 
@@ -103,6 +118,68 @@ db_backend = "{{ .BaseConfig.DBBackend }}"
 # Database directory
 db_dir = "{{ js .BaseConfig.DBPath }}"
 
+# Block store backend: kv | flatfile
+# * kv - blocks are kept in the DB, split into parts
+# * flatfile - block parts are appended to flat files under
+#   db_dir/blockstore.files, with an index in the DB
+#   - much faster sequential reads (e.g. serving fast sync)
+#   - EXPERIMENTAL
+block_store_backend = "{{ .BaseConfig.BlockStoreBackend }}"
+
+# Compression of the block parts on disk: none | snappy | zstd
+# * snappy - fast, about half the ratio of zstd
+# * zstd - requires a build with cgo
+# The parts saved with another codec are still read
+block_store_compression = "{{ .BaseConfig.BlockStoreCompression }}"
+
+# zstd dictionary trained on the blocks of the chain (see
+# tendermint train_compression_dict), which improves the ratio of the
+# small blocks. The parts compressed with a dictionary can't be read
+# without it, so it must not be changed or removed afterwards.
+# Empty - no dictionary
+block_store_compression_dict = "{{ .BaseConfig.BlockStoreCompressionDict }}"
+
+# Save the existing block parts again with the codec of
+# block_store_compression, in the background (kv backend only)
+block_store_recompress = {{ .BaseConfig.BlockStoreRecompress }}
+
+# Object store to archive old blocks to, before pruning them from the
+# block store: file:///path, s3://bucket/prefix or gs://bucket/prefix.
+# S3 and GCS credentials are read from the AWS_ACCESS_KEY_ID and
+# AWS_SECRET_ACCESS_KEY environment variables. Archived blocks are still
+# served by /block. Empty - no archiving
+archive_url = "{{ .BaseConfig.ArchiveURL }}"
+
+# Endpoint of the S3 compatible service, if not the default one of the
+# archive_url scheme (e.g. for MinIO)
+archive_endpoint = "{{ .BaseConfig.ArchiveEndpoint }}"
+
+# Number of latest blocks to keep in the block store when archiving.
+# 0 - keep all blocks, and archive none
+retain_blocks = {{ .BaseConfig.RetainBlocks }}
+
+# Soft memory ceiling, in bytes. Above it, the node halves the mempool
+# and evicts caches, hopefully before the OOM killer intervenes.
+# 0 - 90% of the container memory limit, if any; -1 - disabled
+memory_soft_limit = {{ .BaseConfig.MemorySoftLimit }}
+
+# Comma separated list of <category>=<bytes> soft quotas of the disk space
+# used by the node data, reported by /status and the metrics. Categories:
+# blocks, state, wal, tx_index and evidence. Above its quota, the oldest
+# blocks are pruned (keeping the latest 1000, can't be combined with
+# retain_blocks); the other categories are only reported.
+# Empty - no quotas
+disk_soft_quotas = "{{ .BaseConfig.DiskSoftQuotas }}"
+
+# Number of workers verifying commit and evidence signatures, apart from
+# the p2p and consensus goroutines.
+# 0 - GOMAXPROCS - 1 (at least 1); -1 - verify inline
+sig_verify_workers = {{ .BaseConfig.SigVerifyWorkers }}
+
+# CPUs the signature verification workers are pinned to (Linux only),
+# in the cpuset format (e.g. "2,3" or "2-5"). Empty - no pinning
+sig_verify_cpus = "{{ .BaseConfig.SigVerifyCPUs }}"
+
 # Output level for logging, including package level options
 log_level = "{{ .BaseConfig.LogLevel }}"
 
@@ -114,6 +191,10 @@ log_format = "{{ .BaseConfig.LogFormat }}"
 # Path to the JSON file containing the initial validator set and other meta data
 genesis_file = "{{ js .BaseConfig.Genesis }}"
 
+# Hex encoded SHA-256 hash of the genesis file, as printed by "tendermint genesis hash".
+# If set, the node refuses to start from a different genesis file.
+genesis_hash = "{{ .BaseConfig.GenesisHash }}"
+
 # Path to the JSON file containing the private key to use as a validator in the consensus protocol
 priv_validator_key_file = "{{ js .BaseConfig.PrivValidatorKey }}"
 
@@ -124,6 +205,16 @@ priv_validator_state_file = "{{ js .BaseConfig.PrivValidatorState }}"
 # connections from an external PrivValidator process
 priv_validator_laddr = "{{ .BaseConfig.PrivValidatorListenAddr }}"
 
+# TCP or UNIX socket address of an external PrivValidator process
+# serving gRPC, for Tendermint to connect to
+priv_validator_grpc_addr = "{{ .BaseConfig.PrivValidatorGRPCAddr }}"
+
+# Client certificate and key, and the CA certificates to verify the
+# external PrivValidator with, for mutual TLS over gRPC
+priv_validator_grpc_cert_file = "{{ js .BaseConfig.PrivValidatorGRPCCert }}"
+priv_validator_grpc_key_file = "{{ js .BaseConfig.PrivValidatorGRPCKey }}"
+priv_validator_grpc_root_ca_file = "{{ js .BaseConfig.PrivValidatorGRPCRootCA }}"
+
 # Path to the JSON file containing the private key to use for node authentication in the p2p protocol
 node_key_file = "{{ js .BaseConfig.NodeKey }}"
 
@@ -133,6 +224,13 @@ abci = "{{ .BaseConfig.ABCI }}"
 # TCP or UNIX socket address for the profiling server to listen on
 prof_laddr = "{{ .BaseConfig.ProfListenAddress }}"
 
+# Path of the UNIX socket of the admin API, which "tendermint console"
+# connects to, to inspect the fast sync, peers and consensus, change the log
+# level, pause the fast sync or prune blocks of the running node. Only the
+# user running the node can connect to it.
+# Empty - disabled
+admin_socket = "{{ js .BaseConfig.AdminSocket }}"
+
 # If true, query the ABCI app on connecting to a new peer
 # so the app can decide if we should keep the connection or not
 filter_peers = {{ .BaseConfig.FilterPeers }}
@@ -142,7 +240,8 @@ filter_peers = {{ .BaseConfig.FilterPeers }}
 ##### rpc server configuration options #####
 [rpc]
 
-# TCP or UNIX socket address for the RPC server to listen on
+# TCP or UNIX socket address for the RPC server to listen on, or a comma
+# separated list of them. tcp4:// and tcp6:// listen on IPv4 or IPv6 only
 laddr = "{{ .RPC.ListenAddress }}"
 
 # A list of origins a cross-domain request can be executed from
@@ -157,7 +256,8 @@ cors_allowed_methods = [{{ range .RPC.CORSAllowedMethods }}{{ printf "%q, " . }}
 cors_allowed_headers = [{{ range .RPC.CORSAllowedHeaders }}{{ printf "%q, " . }}{{end}}]
 
 # TCP or UNIX socket address for the gRPC server to listen on
-# NOTE: This server only supports /broadcast_tx_commit
+# It serves /broadcast_tx_commit (BroadcastAPI), and the status, blocks,
+# block results, validators and broadcast_tx_sync (CoreAPI, see rpc/grpc/types.proto)
 grpc_laddr = "{{ .RPC.GRPCListenAddress }}"
 
 # Maximum number of simultaneous connections.
@@ -172,6 +272,41 @@ grpc_max_open_connections = {{ .RPC.GRPCMaxOpenConnections }}
 # Activate unsafe RPC commands like /dial_seeds and /unsafe_flush_mempool
 unsafe = {{ .RPC.Unsafe }}
 
+# Serve only the methods which don't change the state of the node or of the
+# network, on laddr and grpc_laddr: the broadcast methods (txs and evidence)
+# and the unsafe ones are disabled, so that the RPC can be exposed publicly.
+# The admin API is not affected.
+read_only = {{ .RPC.ReadOnly }}
+
+# If not empty, only these methods are served on laddr and grpc_laddr, e.g.
+# ["status", "block", "block_results", "tx"]; read_only still applies to them.
+# The admin API is not affected.
+allowed_methods = [{{ range .RPC.AllowedMethods }}{{ printf "%q, " . }}{{end}}]
+
+# TCP or UNIX socket address for the admin API to listen on, separate from laddr.
+# It serves the operator endpoints (dialing peers, evicting and flushing txs,
+# pruning blocks, compacting the databases, changing the log level...) to the
+# clients authenticated with admin_token_file or admin_tls_client_ca_file, and
+# logs their actions. Supersedes unsafe, which is deprecated.
+# "" - disabled.
+admin_laddr = "{{ .RPC.AdminListenAddress }}"
+
+# File holding the token of the admin API clients, sent in the
+# "Authorization: Bearer <token>" header.
+# Might be either absolute path or path related to tendermint's config directory.
+admin_token_file = "{{ .RPC.AdminTokenFile }}"
+
+# Certificate and private key of the admin API server. Both must be set for
+# the admin API to be served over HTTPS.
+# Might be either absolute path or path related to tendermint's config directory.
+admin_tls_cert_file = "{{ .RPC.AdminTLSCertFile }}"
+admin_tls_key_file = "{{ .RPC.AdminTLSKeyFile }}"
+
+# Certificates of the authorities signing the certificates of the admin API
+# clients (mutual TLS). Requires admin_tls_cert_file and admin_tls_key_file.
+# Might be either absolute path or path related to tendermint's config directory.
+admin_tls_client_ca_file = "{{ .RPC.AdminTLSClientCAFile }}"
+
 # Maximum number of simultaneous connections (including WebSocket).
 # Does not include gRPC connections. See grpc_max_open_connections
 # If you want to accept a larger number than the default, make sure
@@ -191,6 +326,19 @@ max_subscription_clients = {{ .RPC.MaxSubscriptionClients }}
 # the estimated # maximum number of broadcast_tx_commit calls per block.
 max_subscriptions_per_client = {{ .RPC.MaxSubscriptionsPerClient }}
 
+# Number of events buffered for each query a WebSocket client /subscribe-s to,
+# when the client isn't reading them fast enough. Clients may ask for another
+# size with the buffer_size parameter, up to max_subscription_buffer_size.
+subscription_buffer_size = {{ .RPC.SubscriptionBufferSize }}
+max_subscription_buffer_size = {{ .RPC.MaxSubscriptionBufferSize }}
+
+# What to do when the buffer of a subscription is full, unless the client asks
+# for another policy with the buffer_policy parameter:
+#   1) "disconnect" - cancel the subscription
+#   2) "drop_oldest" - drop the oldest buffered event
+#   3) "drop_newest" - drop the new event
+subscription_buffer_policy = "{{ .RPC.SubscriptionBufferPolicy }}"
+
 # How long to wait for a tx to be committed during /broadcast_tx_commit.
 # WARNING: Using a value larger than 10s will result in increasing the
 # global HTTP write timeout, which applies to all connections and endpoints.
@@ -203,6 +351,16 @@ max_body_bytes = {{ .RPC.MaxBodyBytes }}
 # Maximum size of request header, in bytes
 max_header_bytes = {{ .RPC.MaxHeaderBytes }}
 
+# Maximum number of requests from a single JSON-RPC batch executed concurrently.
+# 0 - process batches sequentially.
+max_batch_concurrency = {{ .RPC.MaxBatchConcurrency }}
+
+# Attest the /status responses with a signature of the node ID, chain ID,
+# latest height and current time by the node key, so that monitoring systems
+# can check they talk to the node itself rather than to a stale cache or an
+# imposter behind a load balancer.
+status_attestation = {{ .RPC.StatusAttestation }}
+
 # The path to a file containing certificate that is used to create the HTTPS server.
 # Migth be either absolute path or path related to tendermint's config directory.
 # If the certificate is signed by a certificate authority,
@@ -221,13 +379,28 @@ tls_key_file = "{{ .RPC.TLSKeyFile }}"
 ##### peer to peer configuration options #####
 [p2p]
 
-# Address to listen for incoming connections
+# Comma separated list of addresses to listen for incoming connections.
+# The tcp:// prefix listens on both IPv4 and IPv6 for unspecified IPs
+# (0.0.0.0 or [::]), tcp4:// on IPv4 only and tcp6:// on IPv6 only,
+# e.g. "tcp4://0.0.0.0:26656,tcp6://[::]:26656"
 laddr = "{{ .P2P.ListenAddress }}"
 
+# Transport of the peer connections:
+# 1) "tcp" (default) - the channels are multiplexed on a single TCP connection
+# 2) "quic" - a QUIC stream per channel, so that a lost packet only delays the
+#   messages of its channel. Listens on the UDP ports of laddr, and needs an
+#   Ed25519 node key. The peers must use the same transport
+transport = "{{ .P2P.Transport }}"
+
 # Address to advertise to peers for them to dial
 # If empty, will use the same port as the laddr,
 # and will introspect on the listener or use UPnP
 # to figure out the address.
+# With several listen addresses, a comma separated list of the addresses to
+# advertise, one per listen address ("" - the listen address, "none" - not
+# advertised): the peers connected over IPv4 (or IPv6) are told the first
+# advertised IPv4 (or IPv6) address, e.g. ",[2001:db8::1]:26656" or
+# "1.2.3.4:26656,none"
 external_address = "{{ .P2P.ExternalAddress }}"
 
 # Comma separated list of seed nodes to connect to
@@ -276,6 +449,14 @@ seed_mode = {{ .P2P.SeedMode }}
 # Comma separated list of peer IDs to keep private (will not be gossiped to other peers)
 private_peer_ids = "{{ .P2P.PrivatePeerIDs }}"
 
+# Comma separated list of peer IDs accepted even when the node already has
+# max_num_inbound_peers inbound peers (e.g. the validator and the other
+# sentries of a sentry node)
+unconditional_peer_ids = "{{ .P2P.UnconditionalPeerIDs }}"
+
+# Comma separated list of <ID>=<label> pairs naming peers in logs, metrics and /net_info
+peer_labels = "{{ .P2P.PeerLabels }}"
+
 # Toggle to disable guard against peers connecting from the same ip.
 allow_duplicate_ip = {{ .P2P.AllowDuplicateIP }}
 
@@ -283,6 +464,35 @@ allow_duplicate_ip = {{ .P2P.AllowDuplicateIP }}
 handshake_timeout = "{{ .P2P.HandshakeTimeout }}"
 dial_timeout = "{{ .P2P.DialTimeout }}"
 
+# SOCKS5 proxy the peers are dialed through, except the persistent ones:
+# "socks5://[user:password@]host:port" (e.g. "socks5://127.0.0.1:9050" for Tor).
+# Required to dial .onion addresses. Empty to dial them directly.
+proxy = "{{ .P2P.Proxy }}"
+
+# SOCKS5 proxy the persistent peers are dialed through. Empty to dial them directly.
+persistent_peers_proxy = "{{ .P2P.PersistentPeersProxy }}"
+
+# External admission control: an HTTP(S) endpoint ("http://host:port/path")
+# or a gRPC service ("grpc://host:port") asked whether to admit each peer.
+# The HTTP endpoint receives a POST with {"id": "<node id>", "addr": "<ip:port>"},
+# and admits the peer with 200 OK, or rejects it with 403 Forbidden (the body
+# being the reason).
+# Peers are rejected if the check fails or times out.
+admission_check_addr = "{{ .P2P.AdmissionCheckAddr }}"
+admission_check_timeout = "{{ .P2P.AdmissionCheckTimeout }}"
+
+# How long the decision of an admission check is cached for a peer
+admission_check_cache_ttl = "{{ .P2P.AdmissionCheckCacheTTL }}"
+
+# Comma separated list of the home directories of other chains run by this
+# node, sharing its connections: their messages are multiplexed on the
+# channels of their chain ID. Each home has its own config, genesis and data,
+# but its p2p config is ignored: its RPC is served by the RPC server of this
+# node under /networks/<chain_id>/, and its gRPC, admin, Prometheus and
+# profile servers are disabled. Relative paths are relative to the home of
+# this node.
+networks = "{{ .P2P.Networks }}"
+
 ##### mempool configuration options #####
 [mempool]
 
@@ -301,10 +511,42 @@ max_txs_bytes = {{ .Mempool.MaxTxsBytes }}
 # Size of the cache (used to filter transactions we saw earlier) in transactions
 cache_size = {{ .Mempool.CacheSize }}
 
+# Type of the cache:
+#   1) "lru" (default) - the hashes of the last cache_size transactions, in memory
+#   2) "cuckoo" - the last cache_size to 2*cache_size transactions, in cuckoo
+#   filters of about 4 bytes per transaction saved to cache_file after each
+#   block, so the transactions committed before a restart aren't admitted
+#   again after it. Transactions never seen are taken for seen ones with a
+#   probability under 4*10^-9.
+cache_type = "{{ .Mempool.CacheType }}"
+
+# File of the "cuckoo" cache, relative to the home directory
+# "" - the cache isn't saved
+cache_file = "{{ js .Mempool.CacheFilePath }}"
+
 # Maximum size of a single transaction.
 # NOTE: the max size of a tx transmitted over the network is {max_tx_bytes} + {amino overhead}.
 max_tx_bytes = {{ .Mempool.MaxTxBytes }}
 
+# Number of txs rechecked per RecheckBatch ABCI call after a block, which
+# saves a round trip to the app per tx. The app must implement RecheckBatch.
+# 0 - the txs are rechecked one by one with CheckTx
+recheck_batch_size = {{ .Mempool.RecheckBatchSize }}
+
+# If true, the txs are reaped for a proposal without waiting for the recheck
+# to complete: the txs which aren't rechecked yet are left out of the proposal.
+recheck_async = {{ .Mempool.RecheckAsync }}
+
+# Directory of the log of the rejected txs (hash, size, reason, CheckTx code
+# and sending peer), one JSON object per line. The recent rejections can be
+# queried with /rejected_txs.
+# "" - the rejected txs aren't logged
+rejections_log_dir = "{{ js .Mempool.RejectionsLogPath }}"
+
+# Maximum total size of the log of the rejected txs, in bytes. The oldest
+# rejections are deleted beyond it.
+rejections_log_max_size = {{ .Mempool.RejectionsLogMaxSize }}
+
 ##### fast sync configuration options #####
 [fastsync]
 
@@ -313,11 +555,75 @@ max_tx_bytes = {{ .Mempool.MaxTxBytes }}
 #   2) "v1" - refactor of v0 version for better testability
 version = "{{ .FastSync.Version }}"
 
+# Number of blocks whose state is written to disk in a single batch while
+# fast syncing, instead of block by block, which speeds it up. After a crash,
+# the state is recovered from the app on restart. 0 disables batching.
+batched_writes = {{ .FastSync.BatchedWrites }}
+
+# If the height doesn't move for that long while fast syncing (v0 only), log
+# the state of the sync, disconnect the least useful half of the peers and
+# look for new ones. 0 disables it.
+no_progress_timeout = "{{ .FastSync.NoProgressTimeout }}"
+
+# How long to wait on startup for the heights of the first peers (v0 only). If
+# none of them is ahead of us, e.g. when a validator restarts, fast sync is
+# skipped and the node switches to consensus right away. 0 disables it.
+tip_grace_period = "{{ .FastSync.TipGracePeriod }}"
+
+# Fast sync waits for at least min_peers peers, and for
+# peer_stabilization_delay after the first one reported its height, before it
+# targets the max height of the peers and may consider itself done. This keeps
+# a single lagging or lying peer from ending the sync below the real tip.
+min_peers = {{ .FastSync.MinPeers }}
+peer_stabilization_delay = "{{ .FastSync.PeerStabilizationDelay }}"
+
+# Maximum rates (bytes/s) of the blocks sent to (upload) and received from
+# (download) peers while fast syncing or serving fast syncing peers (v0 only),
+# for all peers and per peer, so that fast sync doesn't saturate the link of
+# the node and delay consensus gossip. Peers sending blocks slower than 7.5
+# kB/s are dropped, so leave more than that to each peer. 0 means unlimited.
+upload_rate = {{ .FastSync.UploadRate }}
+upload_rate_per_peer = {{ .FastSync.UploadRatePerPeer }}
+download_rate = {{ .FastSync.DownloadRate }}
+download_rate_per_peer = {{ .FastSync.DownloadRatePerPeer }}
+
+# Approximate size (bytes) of the blocks requested or received but not applied
+# yet while fast syncing. The number of requests follows it, based on the
+# average size of the blocks, and the number of requests sent to each peer
+# follows the throughput of the peer.
+memory_budget = {{ .FastSync.MemoryBudget }}
+
+# Fast sync from the block at trust_height, whose hash is trust_hash, instead
+# of genesis, e.g. when the early blocks of the chain are no longer available.
+# The app must have committed the block already (e.g. restored from a
+# snapshot). The state at trust_height is fetched from trust_rpc_servers
+# (comma-separated "host:port" list) and verified against trust_hash. Only
+# used when the node has no blocks yet. 0 disables it.
+trust_height = {{ .FastSync.TrustHeight }}
+trust_hash = "{{ .FastSync.TrustHash }}"
+trust_rpc_servers = "{{ .FastSync.TrustRPCServers }}"
+
 ##### consensus configuration options #####
 [consensus]
 
 wal_file = "{{ js .Consensus.WalPath }}"
 
+# The WAL is split into segments of wal_segment_size bytes. Once the current
+# segment reaches it, it is rotated and a new one is started.
+wal_segment_size = {{ .Consensus.WalSegmentSize }}
+
+# Number of rotated WAL segments kept, the oldest ones being removed. Only the
+# messages of the current height are needed to recover from a crash, but the
+# older ones help debugging.
+# 0 - all of them, up to 1GB in total
+wal_retain_segments = {{ .Consensus.WalRetainSegments }}
+
+# Compression of the WAL messages: none | snappy
+# WALs with snappy compressed messages can't be read by older versions.
+wal_compression = "{{ .Consensus.WalCompression }}"
+
+# The timeouts set by the consensus params of the chain (Timeout) override
+# these ones.
 timeout_propose = "{{ .Consensus.TimeoutPropose }}"
 timeout_propose_delta = "{{ .Consensus.TimeoutProposeDelta }}"
 timeout_prevote = "{{ .Consensus.TimeoutPrevote }}"
@@ -326,6 +632,12 @@ timeout_precommit = "{{ .Consensus.TimeoutPrecommit }}"
 timeout_precommit_delta = "{{ .Consensus.TimeoutPrecommitDelta }}"
 timeout_commit = "{{ .Consensus.TimeoutCommit }}"
 
+# Maximum time the proposer spends reaping txs from the mempool and waiting
+# for PrepareProposal. Past it, the block is proposed with the txs reaped so
+# far rather than missing the proposal.
+# 0 - no deadline
+create_proposal_deadline = "{{ .Consensus.CreateProposalDeadline }}"
+
 # Make progress as soon as we have all the precommits (as if TimeoutCommit = 0)
 skip_timeout_commit = {{ .Consensus.SkipTimeoutCommit }}
 
@@ -340,12 +652,22 @@ peer_query_maj23_sleep_duration = "{{ .Consensus.PeerQueryMaj23SleepDuration }}"
 ##### transactions indexer configuration options #####
 [tx_index]
 
-# What indexer to use for transactions
+# The backend database list to back the indexer.
+# If list contains "null", meaning no indexer service will be used.
 #
 # Options:
 #   1) "null"
 #   2) "kv" (default) - the simplest possible indexer, backed by key-value storage (defaults to levelDB; see DBBackend).
-indexer = "{{ .TxIndex.Indexer }}"
+#   3) "psql" - the indexer services backed by PostgreSQL.
+# When "kv" or "psql" is chosen, both block (BeginBlock/EndBlock) and
+# transaction events are indexed. The "kv" indexer serves the RPC queries,
+# else the "psql" indexer serves /tx and /tx_search.
+indexer = [{{ range $i, $e := .TxIndex.Indexer }}{{ if $i }}, {{ end }}{{ printf "%q" $e }}{{ end }}]
+
+# The PostgreSQL connection configuration, the connection format:
+#   postgresql://<user>:<password>@<host>:<port>/<db>?<opts>
+# The database must be initialized with state/indexer/sink/psql/schema.sql.
+psql_conn = "{{ .TxIndex.PsqlConn }}"
 
 # Comma-separated list of tags to index (by default the only tag is "tx.hash")
 #
@@ -364,6 +686,34 @@ index_tags = "{{ .TxIndex.IndexTags }}"
 # indexed).
 index_all_tags = {{ .TxIndex.IndexAllTags }}
 
+# The PostgreSQL connection configuration of a read replica of the psql_conn
+# database (e.g. a streaming replica), in the psql_conn format. When set, the
+# "psql" indexer reads the transactions (/tx, /tx_search) from the replica,
+# while the node keeps writing to psql_conn.
+psql_read_replica_conn = "{{ .TxIndex.PsqlReadReplicaConn }}"
+
+# Maximum number of blocks the read replica may lag behind before reads go
+# back to psql_conn. 0 - no limit.
+read_replica_max_lag = {{ .TxIndex.ReadReplicaMaxLag }}
+
+# Directory to stream the applied blocks to, with their results (BeginBlock,
+# DeliverTx and EndBlock responses, validator updates and app hash), one JSON
+# record per line, for external indexers. Relative paths are resolved against
+# the node's home directory. "" - disabled.
+stream_file_dir = "{{ .TxIndex.StreamFileDir }}"
+
+# Maximum size in bytes of the stream files. The oldest files are removed
+# past it.
+stream_file_max_size = {{ .TxIndex.StreamFileMaxSize }}
+
+# Address of a gRPC service to push the applied blocks to, with their results
+# (see the state/streaming package). "" - disabled.
+stream_grpc_addr = "{{ .TxIndex.StreamGRPCAddr }}"
+
+# Maximum number of blocks buffered for the gRPC service. Past it, the blocks
+# are dropped.
+stream_buffer_size = {{ .TxIndex.StreamBufferSize }}
+
 ##### instrumentation configuration options #####
 [instrumentation]
 
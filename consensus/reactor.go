@@ -72,7 +72,7 @@ func (conR *ConsensusReactor) OnStart() error {
 	conR.Logger.Info("ConsensusReactor ", "fastSync", conR.FastSync())
 
 	// start routine that computes peer statistics for evaluating peer quality
-	go conR.peerStatsRoutine()
+	conR.Go("peerStatsRoutine", func() { conR.peerStatsRoutine() })
 
 	conR.subscribeToBroadcastEvents()
 
@@ -133,6 +133,7 @@ func (conR *ConsensusReactor) GetChannels() []*p2p.ChannelDescriptor {
 		{
 			ID:                  StateChannel,
 			Priority:            5,
+			Preemptive:          true,
 			SendQueueCapacity:   100,
 			RecvMessageCapacity: maxMsgSize,
 		},
@@ -147,6 +148,7 @@ func (conR *ConsensusReactor) GetChannels() []*p2p.ChannelDescriptor {
 		{
 			ID:                  VoteChannel,
 			Priority:            5,
+			Preemptive:          true,
 			SendQueueCapacity:   100,
 			RecvBufferCapacity:  100 * 100,
 			RecvMessageCapacity: maxMsgSize,
@@ -180,9 +182,9 @@ func (conR *ConsensusReactor) AddPeer(peer p2p.Peer) {
 		panic(fmt.Sprintf("peer %v has no state", peer))
 	}
 	// Begin routines for this peer.
-	go conR.gossipDataRoutine(peer, peerState)
-	go conR.gossipVotesRoutine(peer, peerState)
-	go conR.queryMaj23Routine(peer, peerState)
+	conR.Go("gossipDataRoutine", func() { conR.gossipDataRoutine(peer, peerState) })
+	conR.Go("gossipVotesRoutine", func() { conR.gossipVotesRoutine(peer, peerState) })
+	conR.Go("queryMaj23Routine", func() { conR.queryMaj23Routine(peer, peerState) })
 
 	// Send our state to peer.
 	// If we're fast_syncing, broadcast a RoundStepMessage later upon SwitchToConsensus().
@@ -372,6 +374,12 @@ func (conR *ConsensusReactor) FastSync() bool {
 	return conR.fastSync
 }
 
+// ReplayingWAL returns whether the consensus state is replaying its WAL
+// before it starts participating in consensus.
+func (conR *ConsensusReactor) ReplayingWAL() bool {
+	return conR.conS.IsReplayingWAL()
+}
+
 //--------------------------------------
 
 // subscribeToBroadcastEvents subscribes for new round steps and votes
@@ -476,8 +484,39 @@ OUTER_LOOP:
 		rs := conR.conS.GetRoundState()
 		prs := ps.GetRoundState()
 
-		// Send proposal Block parts?
-		if rs.ProposalBlockParts.HasHeader(prs.ProposalBlockPartsHeader) {
+		// Send Proposal && ProposalPOL BitArray?
+		// These go before the block parts, so the peer doesn't wait for the
+		// whole block to learn about the proposal.
+		if rs.Height == prs.Height && rs.Round == prs.Round && rs.Proposal != nil && !prs.Proposal {
+			// Proposal: share the proposal metadata with peer.
+			{
+				msg := &ProposalMessage{Proposal: rs.Proposal}
+				logger.Debug("Sending proposal", "height", prs.Height, "round", prs.Round)
+				if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
+					// NOTE[ZM]: A peer might have received different proposal msg so this Proposal msg will be rejected!
+					ps.SetHasProposal(rs.Proposal)
+				}
+			}
+			// ProposalPOL: lets peer know which POL votes we have so far.
+			// Peer must receive ProposalMessage first.
+			// rs.Proposal was validated, so rs.Proposal.POLRound <= rs.Round,
+			// so we definitely have rs.Votes.Prevotes(rs.Proposal.POLRound).
+			if 0 <= rs.Proposal.POLRound {
+				msg := &ProposalPOLMessage{
+					Height:           rs.Height,
+					ProposalPOLRound: rs.Proposal.POLRound,
+					ProposalPOL:      rs.Votes.Prevotes(rs.Proposal.POLRound).BitArray(),
+				}
+				logger.Debug("Sending POL", "height", prs.Height, "round", prs.Round)
+				peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg))
+			}
+			continue OUTER_LOOP
+		}
+
+		// Send proposal Block parts? The peer needs only enough of them to
+		// reconstruct the others.
+		if rs.ProposalBlockParts.HasHeader(prs.ProposalBlockPartsHeader) &&
+			!hasEnoughBlockParts(rs.ProposalBlockParts.DataTotal(), prs.ProposalBlockParts) {
 			if index, ok := rs.ProposalBlockParts.BitArray().Sub(prs.ProposalBlockParts.Copy()).PickRandom(); ok {
 				part := rs.ProposalBlockParts.GetPart(index)
 				msg := &BlockPartMessage{
@@ -520,36 +559,8 @@ OUTER_LOOP:
 		}
 
 		// By here, height and round match.
-		// Proposal block parts were already matched and sent if any were wanted.
-		// (These can match on hash so the round doesn't matter)
-		// Now consider sending other things, like the Proposal itself.
-
-		// Send Proposal && ProposalPOL BitArray?
-		if rs.Proposal != nil && !prs.Proposal {
-			// Proposal: share the proposal metadata with peer.
-			{
-				msg := &ProposalMessage{Proposal: rs.Proposal}
-				logger.Debug("Sending proposal", "height", prs.Height, "round", prs.Round)
-				if peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg)) {
-					// NOTE[ZM]: A peer might have received different proposal msg so this Proposal msg will be rejected!
-					ps.SetHasProposal(rs.Proposal)
-				}
-			}
-			// ProposalPOL: lets peer know which POL votes we have so far.
-			// Peer must receive ProposalMessage first.
-			// rs.Proposal was validated, so rs.Proposal.POLRound <= rs.Round,
-			// so we definitely have rs.Votes.Prevotes(rs.Proposal.POLRound).
-			if 0 <= rs.Proposal.POLRound {
-				msg := &ProposalPOLMessage{
-					Height:           rs.Height,
-					ProposalPOLRound: rs.Proposal.POLRound,
-					ProposalPOL:      rs.Votes.Prevotes(rs.Proposal.POLRound).BitArray(),
-				}
-				logger.Debug("Sending POL", "height", prs.Height, "round", prs.Round)
-				peer.Send(DataChannel, cdc.MustMarshalBinaryBare(msg))
-			}
-			continue OUTER_LOOP
-		}
+		// The Proposal and the proposal block parts were already sent if any
+		// were wanted.
 
 		// Nothing to do. Sleep.
 		time.Sleep(conR.conS.config.PeerGossipSleepDuration)
@@ -574,6 +585,11 @@ func (conR *ConsensusReactor) gossipDataForCatchup(logger log.Logger, rs *cstype
 			time.Sleep(conR.conS.config.PeerGossipSleepDuration)
 			return
 		}
+		if hasEnoughBlockParts(blockMeta.BlockID.PartsHeader.DataTotal(blockMeta.Header.Version.Block),
+			prs.ProposalBlockParts) {
+			time.Sleep(conR.conS.config.PeerGossipSleepDuration)
+			return
+		}
 		// Load the part
 		part := conR.conS.blockStore.LoadBlockPart(prs.Height, index)
 		if part == nil {
@@ -600,6 +616,21 @@ func (conR *ConsensusReactor) gossipDataForCatchup(logger log.Logger, rs *cstype
 	time.Sleep(conR.conS.config.PeerGossipSleepDuration)
 }
 
+// hasEnoughBlockParts returns true if the peer has enough parts to reconstruct
+// the others, the count of data parts.
+func hasEnoughBlockParts(dataTotal int, parts *cmn.BitArray) bool {
+	if parts == nil {
+		return false
+	}
+	count := 0
+	for i := 0; i < parts.Size(); i++ {
+		if parts.GetIndex(i) {
+			count++
+		}
+	}
+	return count >= dataTotal
+}
+
 func (conR *ConsensusReactor) gossipVotesRoutine(peer p2p.Peer, ps *PeerState) {
 	logger := conR.Logger.With("peer", peer)
 
@@ -626,6 +657,13 @@ OUTER_LOOP:
 		//logger.Debug("gossipVotesRoutine", "rsHeight", rs.Height, "rsRound", rs.Round,
 		//	"prsHeight", prs.Height, "prsRound", prs.Round, "prsStep", prs.Step)
 
+		// A peer still syncing drops the votes: wait for it to advertise
+		// it's caught up (see types.PeerSyncPhaseKey).
+		if phase, ok := peer.Get(types.PeerSyncPhaseKey).(types.SyncPhase); ok && phase.IsSyncing() {
+			time.Sleep(conR.conS.config.PeerGossipSleepDuration)
+			continue OUTER_LOOP
+		}
+
 		// If height matches, then send LastCommit, Prevotes, Precommits.
 		if rs.Height == prs.Height {
 			heightLogger := logger.With("height", prs.Height)
@@ -649,6 +687,11 @@ OUTER_LOOP:
 			// Load the block commit for prs.Height,
 			// which contains precommit signatures for prs.Height.
 			commit := conR.conS.blockStore.LoadBlockCommit(prs.Height)
+			if commit != nil && commit.Aggregate != nil {
+				// The votes of an aggregate commit can't be sent: send the
+				// ones we've seen.
+				commit = conR.conS.blockStore.LoadSeenCommit(prs.Height)
+			}
 			if ps.PickSendVote(commit) {
 				logger.Debug("Picked Catchup commit to send", "height", prs.Height)
 				continue OUTER_LOOP
@@ -1064,7 +1107,10 @@ func (ps *PeerState) PickVoteToSend(votes types.VoteSetReader) (vote *types.Vote
 		return nil, false // Not something worth sending
 	}
 	if index, ok := votes.BitArray().Sub(psVotes).PickRandom(); ok {
-		return votes.GetByIndex(index), true
+		// nil for the votes of an aggregate commit, which can't be sent
+		if vote := votes.GetByIndex(index); vote != nil {
+			return vote, true
+		}
 	}
 	return nil, false
 }
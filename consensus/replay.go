@@ -6,6 +6,7 @@ import (
 	"hash/crc32"
 	"io"
 	"reflect"
+	"sync/atomic"
 
 	//"strconv"
 	//"strings"
@@ -101,7 +102,11 @@ func (cs *ConsensusState) catchupReplay(csHeight int64) error {
 
 	// Set replayMode to true so we don't log signing errors.
 	cs.replayMode = true
-	defer func() { cs.replayMode = false }()
+	atomic.StoreInt32(&cs.walReplaying, 1)
+	defer func() {
+		cs.replayMode = false
+		atomic.StoreInt32(&cs.walReplaying, 0)
+	}()
 
 	// Ensure that #ENDHEIGHT for this height doesn't exist.
 	// NOTE: This is just a sanity check. As far as we know things work fine
@@ -139,7 +144,7 @@ func (cs *ConsensusState) catchupReplay(csHeight int64) error {
 	cs.Logger.Info("Catchup by replaying consensus messages", "height", csHeight)
 
 	var msg *TimedWALMessage
-	dec := WALDecoder{gr}
+	dec := NewWALDecoder(gr)
 
 LOOP:
 	for {
@@ -301,19 +306,9 @@ func (h *Handshaker) ReplayBlocks(
 
 	// If appBlockHeight == 0 it means that we are at genesis and hence should send InitChain.
 	if appBlockHeight == 0 {
-		validators := make([]*types.Validator, len(h.genDoc.Validators))
-		for i, val := range h.genDoc.Validators {
-			validators[i] = types.NewValidator(val.PubKey, val.Power)
-		}
-		validatorSet := types.NewValidatorSet(validators)
-		nextVals := types.TM2PB.ValidatorUpdates(validatorSet)
-		csParams := types.TM2PB.ConsensusParams(h.genDoc.ConsensusParams)
-		req := abci.RequestInitChain{
-			Time:            h.genDoc.GenesisTime,
-			ChainId:         h.genDoc.ChainID,
-			ConsensusParams: csParams,
-			Validators:      nextVals,
-			AppStateBytes:   h.genDoc.AppState,
+		req, err := sm.InitChainRequest(h.genDoc)
+		if err != nil {
+			return nil, err
 		}
 		res, err := proxyApp.Consensus().InitChainSync(req)
 		if err != nil {
@@ -337,10 +332,27 @@ func (h *Handshaker) ReplayBlocks(
 			if res.ConsensusParams != nil {
 				state.ConsensusParams = state.ConsensusParams.Update(res.ConsensusParams)
 			}
+			for _, val := range state.Validators.Validators {
+				if err := state.ConsensusParams.Validator.ValidatePubKey(val.PubKey); err != nil {
+					return nil, fmt.Errorf("invalid validator after InitChain: %v", err)
+				}
+			}
 			sm.SaveState(h.stateDB, state)
 		}
 	}
 
+	// With batched writes (see sm.BlockExecutorWithBatchedWrites), the state
+	// may be several blocks behind the app after a crash. Catch it up with the
+	// ABCI responses of the blocks the app committed.
+	if storeBlockHeight > stateBlockHeight+1 && appBlockHeight > stateBlockHeight {
+		var err error
+		state, err = h.catchUpState(state, appHash, appBlockHeight)
+		if err != nil {
+			return nil, err
+		}
+		stateBlockHeight = state.LastBlockHeight
+	}
+
 	// First handle edge cases and constraints on the storeBlockHeight.
 	switch {
 	case storeBlockHeight == 0:
@@ -442,7 +454,11 @@ func (h *Handshaker) replayBlocks(
 			assertAppHashEqualsOneFromBlock(appHash, block)
 		}
 
-		appHash, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, h.logger, h.stateDB)
+		lastBlockTime, err := sm.LastBlockTime(h.store, i)
+		if err != nil {
+			return nil, err
+		}
+		appHash, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, lastBlockTime, h.logger, h.stateDB)
 		if err != nil {
 			return nil, err
 		}
@@ -464,6 +480,29 @@ func (h *Handshaker) replayBlocks(
 }
 
 // ApplyBlock on the proxyApp with the last block.
+// catchUpState applies the blocks after the state up to appBlockHeight with
+// a mock app returning their saved ABCI responses, as the app already
+// committed them.
+func (h *Handshaker) catchUpState(state sm.State, appHash []byte, appBlockHeight int64) (sm.State, error) {
+	for height := state.LastBlockHeight + 1; height <= appBlockHeight; height++ {
+		abciResponses, err := sm.LoadABCIResponses(h.stateDB, height)
+		if err != nil {
+			return state, err
+		}
+		// the app hash after a block is in the header of the next one
+		hash := appHash
+		if height < appBlockHeight {
+			hash = h.store.LoadBlock(height + 1).AppHash
+		}
+		h.logger.Info("Catch up state using mock app", "height", height)
+		state, err = h.replayBlock(state, height, newMockProxyApp(hash, abciResponses))
+		if err != nil {
+			return state, err
+		}
+	}
+	return state, nil
+}
+
 func (h *Handshaker) replayBlock(state sm.State, height int64, proxyApp proxy.AppConnConsensus) (sm.State, error) {
 	block := h.store.LoadBlock(height)
 	meta := h.store.LoadBlockMeta(height)
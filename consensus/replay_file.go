@@ -14,6 +14,7 @@ import (
 
 	cfg "github.com/tendermint/tendermint/config"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/compress"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/mock"
 	"github.com/tendermint/tendermint/proxy"
@@ -279,6 +280,12 @@ func newConsensusStateForReplay(config cfg.BaseConfig, csConfig *cfg.ConsensusCo
 	// Get BlockStore
 	blockStoreDB := dbm.NewDB("blockstore", dbType, config.DBDir())
 	blockStore := store.NewBlockStore(blockStoreDB)
+	codecs, codec, err := store.LoadCompression(config.BlockStoreCompression,
+		config.BlockStoreCompressionDictFile(), compress.NopMetrics())
+	if err != nil {
+		cmn.Exit(err.Error())
+	}
+	blockStore.SetCompression(codecs, codec)
 
 	// Get State
 	stateDB := dbm.NewDB("state", dbType, config.DBDir())
@@ -3,14 +3,18 @@ package consensus
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"reflect"
 	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 
+	auto "github.com/tendermint/tendermint/libs/autofile"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/compress"
 	"github.com/tendermint/tendermint/libs/fail"
 	"github.com/tendermint/tendermint/libs/log"
 	tmtime "github.com/tendermint/tendermint/types/time"
@@ -31,6 +35,7 @@ var (
 	ErrInvalidProposalPOLRound  = errors.New("Error invalid proposal POL round")
 	ErrAddingVote               = errors.New("Error adding vote")
 	ErrVoteHeightMismatch       = errors.New("Error vote height mismatch")
+	ErrVoteUnexpectedExtension  = errors.New("Error vote extension not enabled by the consensus params")
 )
 
 //-----------------------------------------------------------------------------
@@ -113,8 +118,9 @@ type ConsensusState struct {
 	// a Write-Ahead Log ensures we can recover from any kind of crash
 	// and helps us avoid signing conflicting votes
 	wal          WAL
-	replayMode   bool // so we don't log signing errors during replay
-	doWALCatchup bool // determines if we even try to do the catchup
+	replayMode   bool  // so we don't log signing errors during replay
+	doWALCatchup bool  // determines if we even try to do the catchup
+	walReplaying int32 // 1 while catching up from the WAL, read concurrently by IsReplayingWAL
 
 	// for tests where we want to limit the number of transitions the state makes
 	nSteps int
@@ -133,6 +139,15 @@ type ConsensusState struct {
 
 	// for reporting metrics
 	metrics *Metrics
+
+	// timestamps the proposals, votes and WAL messages of this node
+	clock *tmtime.MonotonicClock
+
+	// compress the WAL messages (see ConsensusConfig.WalCompression)
+	codecs *compress.Registry
+
+	// traces of the latest heights, for GetHeightTrace
+	traces *traceRecorder
 }
 
 // StateOption sets an optional parameter on the ConsensusState.
@@ -163,6 +178,9 @@ func NewConsensusState(
 		evpool:           evpool,
 		evsw:             tmevents.NewEventSwitch(),
 		metrics:          NopMetrics(),
+		clock:            tmtime.NewMonotonicClock(),
+		codecs:           compress.NewRegistry(),
+		traces:           newTraceRecorder(),
 	}
 	// set function defaults (may be overwritten before calling Start)
 	cs.decideProposal = cs.defaultDecideProposal
@@ -201,6 +219,18 @@ func StateMetrics(metrics *Metrics) StateOption {
 	return func(cs *ConsensusState) { cs.metrics = metrics }
 }
 
+// StateClock sets the clock timestamping the proposals, votes and WAL messages,
+// e.g. to report its steps (see tmtime.MonotonicClock#SetStepHandler).
+func StateClock(clock *tmtime.MonotonicClock) StateOption {
+	return func(cs *ConsensusState) { cs.clock = clock }
+}
+
+// StateCodecs sets the compression codecs of the WAL, e.g. to share the ones
+// of the block store.
+func StateCodecs(codecs *compress.Registry) StateOption {
+	return func(cs *ConsensusState) { cs.codecs = codecs }
+}
+
 // String returns a string.
 func (cs *ConsensusState) String() string {
 	// better not to access shared variables
@@ -222,6 +252,13 @@ func (cs *ConsensusState) GetLastHeight() int64 {
 	return cs.RoundState.Height - 1
 }
 
+// IsReplayingWAL returns true while the consensus state is catching up by
+// replaying the messages of its WAL on start.
+// It is safe to call concurrently.
+func (cs *ConsensusState) IsReplayingWAL() bool {
+	return atomic.LoadInt32(&cs.walReplaying) == 1
+}
+
 // GetRoundState returns a shallow copy of the internal consensus state.
 func (cs *ConsensusState) GetRoundState() *cstypes.RoundState {
 	cs.mtx.RLock()
@@ -251,6 +288,12 @@ func (cs *ConsensusState) GetValidators() (int64, []*types.Validator) {
 	return cs.state.LastBlockHeight, cs.state.Validators.Copy().Validators
 }
 
+// GetHeightTrace returns what the node saw of the rounds of height, if it is
+// one of the latest heights.
+func (cs *ConsensusState) GetHeightTrace(height int64) (*cstypes.HeightTrace, bool) {
+	return cs.traces.get(height)
+}
+
 // SetPrivValidator sets the private validator account for signing votes.
 func (cs *ConsensusState) SetPrivValidator(priv types.PrivValidator) {
 	cs.mtx.Lock()
@@ -272,7 +315,13 @@ func (cs *ConsensusState) LoadCommit(height int64) *types.Commit {
 	if height == cs.blockStore.Height() {
 		return cs.blockStore.LoadSeenCommit(height)
 	}
-	return cs.blockStore.LoadBlockCommit(height)
+	commit := cs.blockStore.LoadBlockCommit(height)
+	if commit != nil && commit.Aggregate != nil {
+		// The votes of an aggregate commit can't be gossiped: use the ones
+		// we've seen.
+		return cs.blockStore.LoadSeenCommit(height)
+	}
+	return commit
 }
 
 // OnStart implements cmn.Service.
@@ -367,12 +416,21 @@ func (cs *ConsensusState) Wait() {
 
 // OpenWAL opens a file to log all consensus messages and timeouts for deterministic accountability
 func (cs *ConsensusState) OpenWAL(walFile string) (WAL, error) {
-	wal, err := NewWAL(walFile)
+	codec, err := cs.codecs.Get(cs.config.WalCompression)
+	if err != nil {
+		return nil, err
+	}
+	wal, err := NewWAL(walFile,
+		auto.GroupHeadSizeLimit(cs.config.WalSegmentSize),
+		auto.GroupRetainFiles(cs.config.WalRetainSegments),
+	)
 	if err != nil {
 		cs.Logger.Error("Failed to open WAL for consensus state", "wal", walFile, "err", err)
 		return nil, err
 	}
 	wal.SetLogger(cs.Logger.With("wal", walFile))
+	wal.SetCompression(cs.codecs, codec)
+	wal.SetClock(cs.clock)
 	if err := wal.Start(); err != nil {
 		return nil, err
 	}
@@ -462,6 +520,33 @@ func (cs *ConsensusState) scheduleRound0(rs *cstypes.RoundState) {
 	cs.scheduleTimeout(sleepDuration, rs.Height, 0, cstypes.RoundStepNewHeight)
 }
 
+// timeouts returns the consensus config with the timeouts set by the consensus
+// params of state, which override the ones of the config.
+func (cs *ConsensusState) timeouts(state sm.State) *cfg.ConsensusConfig {
+	params := state.ConsensusParams.Timeout
+	if params == (types.TimeoutParams{}) {
+		return cs.config
+	}
+	config := *cs.config
+	for _, timeout := range []struct {
+		param  time.Duration
+		config *time.Duration
+	}{
+		{params.Propose, &config.TimeoutPropose},
+		{params.ProposeDelta, &config.TimeoutProposeDelta},
+		{params.Prevote, &config.TimeoutPrevote},
+		{params.PrevoteDelta, &config.TimeoutPrevoteDelta},
+		{params.Precommit, &config.TimeoutPrecommit},
+		{params.PrecommitDelta, &config.TimeoutPrecommitDelta},
+		{params.Commit, &config.TimeoutCommit},
+	} {
+		if timeout.param > 0 {
+			*timeout.config = timeout.param
+		}
+	}
+	return &config
+}
+
 // Attempt to schedule a timeout (by sending timeoutInfo on the tickChan)
 func (cs *ConsensusState) scheduleTimeout(duration time.Duration, height int64, round int, step cstypes.RoundStepType) {
 	cs.timeoutTicker.ScheduleTimeout(timeoutInfo{duration, height, round, step})
@@ -488,6 +573,13 @@ func (cs *ConsensusState) reconstructLastCommit(state sm.State) {
 		return
 	}
 	seenCommit := cs.blockStore.LoadSeenCommit(state.LastBlockHeight)
+	if seenCommit.Aggregate != nil {
+		// The votes of an aggregate commit (of a block synced from the peers)
+		// can't be reconstructed: they're gossiped again by the peers.
+		cs.LastCommit = types.NewVoteSet(state.ChainID, state.LastBlockHeight, seenCommit.Round(),
+			types.PrecommitType, state.LastValidators)
+		return
+	}
 	lastPrecommits := types.CommitToVoteSet(state.ChainID, seenCommit, state.LastValidators)
 	if !lastPrecommits.HasTwoThirdsMajority() {
 		panic("Failed to reconstruct LastCommit: Does not have +2/3 maj")
@@ -547,13 +639,14 @@ func (cs *ConsensusState) updateToState(state sm.State) {
 		// to be gathered for the first block.
 		// And alternative solution that relies on clocks:
 		// cs.StartTime = state.LastBlockTime.Add(timeoutCommit)
-		cs.StartTime = cs.config.Commit(tmtime.Now())
+		cs.StartTime = cs.timeouts(state).Commit(tmtime.Now())
 	} else {
-		cs.StartTime = cs.config.Commit(cs.CommitTime)
+		cs.StartTime = cs.timeouts(state).Commit(cs.CommitTime)
 	}
 
 	cs.Validators = validators
 	cs.Proposal = nil
+	cs.ProposalReceiveTime = time.Time{}
 	cs.ProposalBlock = nil
 	cs.ProposalBlockParts = nil
 	cs.LockedRound = -1
@@ -569,6 +662,7 @@ func (cs *ConsensusState) updateToState(state sm.State) {
 	cs.TriggeredTimeoutPrecommit = false
 
 	cs.state = state
+	cs.traces.newHeight(height, validators)
 
 	// Finally, broadcast RoundState
 	cs.newStep()
@@ -755,7 +849,7 @@ func (cs *ConsensusState) handleTimeout(ti timeoutInfo, rs cstypes.RoundState) {
 		// XXX: should we fire timeout here (for timeout commit)?
 		cs.enterNewRound(ti.Height, 0)
 	case cstypes.RoundStepNewRound:
-		cs.enterPropose(ti.Height, 0)
+		cs.enterPropose(ti.Height, ti.Round)
 	case cstypes.RoundStepPropose:
 		cs.eventBus.PublishEventTimeoutPropose(cs.RoundStateEvent())
 		cs.enterPrevote(ti.Height, ti.Round)
@@ -838,6 +932,7 @@ func (cs *ConsensusState) enterNewRound(height int64, round int) {
 	// but we fire an event, so update the round step first
 	cs.updateRoundStep(round, cstypes.RoundStepNewRound)
 	cs.Validators = validators
+	cs.traces.newRound(height, round, validators.GetProposer().Address, tmtime.Now())
 	if round == 0 {
 		// We've already reset these upon new height,
 		// and meanwhile we might have received a proposal
@@ -845,6 +940,7 @@ func (cs *ConsensusState) enterNewRound(height int64, round int) {
 	} else {
 		logger.Info("Resetting Proposal info")
 		cs.Proposal = nil
+		cs.ProposalReceiveTime = time.Time{}
 		cs.ProposalBlock = nil
 		cs.ProposalBlockParts = nil
 	}
@@ -896,6 +992,15 @@ func (cs *ConsensusState) enterPropose(height int64, round int) {
 			cs.Step))
 		return
 	}
+
+	// With proposer-based timestamps, the proposer waits for its clock to pass
+	// the earliest time of the block, rather than proposing a block from the
+	// future, which the validators wouldn't find timely.
+	if wait := cs.proposerWaitTime(height); wait > 0 {
+		logger.Info("enterPropose: Waiting for our clock to pass the earliest block time", "wait", wait)
+		cs.scheduleTimeout(wait, height, round, cstypes.RoundStepNewRound)
+		return
+	}
 	logger.Info(fmt.Sprintf("enterPropose(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step))
 
 	defer func() {
@@ -912,7 +1017,7 @@ func (cs *ConsensusState) enterPropose(height int64, round int) {
 	}()
 
 	// If we don't get the proposal and all block parts quick enough, enterPrevote
-	cs.scheduleTimeout(cs.config.Propose(round), height, round, cstypes.RoundStepPropose)
+	cs.scheduleTimeout(cs.timeouts(cs.state).Propose(round), height, round, cstypes.RoundStepPropose)
 
 	// Nothing more to do if we're not a validator
 	if cs.privValidator == nil {
@@ -948,6 +1053,20 @@ func (cs *ConsensusState) isProposer(address []byte) bool {
 	return bytes.Equal(cs.Validators.GetProposer().Address, address)
 }
 
+// proposerWaitTime returns how long the local node must wait before it
+// proposes the block at height, if it's the proposer and the consensus params
+// enable proposer-based timestamps: until its clock passes the earliest time
+// of the block (see sm.ProposerMinTime).
+func (cs *ConsensusState) proposerWaitTime(height int64) time.Duration {
+	if !cs.state.ConsensusParams.Synchrony.ProposerBasedTimestamps() || cs.privValidator == nil {
+		return 0
+	}
+	if !cs.isProposer(cs.privValidator.GetPubKey().Address()) {
+		return 0
+	}
+	return sm.ProposerMinTime(height, cs.state).Sub(cs.clock.Now())
+}
+
 func (cs *ConsensusState) defaultDecideProposal(height int64, round int) {
 	var block *types.Block
 	var blockParts *types.PartSet
@@ -971,6 +1090,7 @@ func (cs *ConsensusState) defaultDecideProposal(height int64, round int) {
 	// Make proposal
 	propBlockId := types.BlockID{Hash: block.Hash(), PartsHeader: blockParts.Header()}
 	proposal := types.NewProposal(height, round, cs.ValidRound, propBlockId)
+	proposal.Timestamp = cs.clock.Now()
 	if err := cs.privValidator.SignProposal(cs.state.ChainID, proposal); err == nil {
 
 		// send proposal and block parts on internal msg queue
@@ -1084,6 +1204,22 @@ func (cs *ConsensusState) defaultDoPrevote(height int64, round int) {
 		return
 	}
 
+	// The app must accept the txs of the proposal block.
+	if err := cs.blockExec.ProcessProposal(cs.ProposalBlock); err != nil {
+		logger.Error("enterPrevote: ProposalBlock is rejected by the app", "err", err)
+		cs.signAddVote(types.PrevoteType, nil, types.PartSetHeader{})
+		return
+	}
+
+	// With proposer-based timestamps, a new proposal must be timely.
+	// The block of a POL was timely for +2/3 of the validators already.
+	if cs.Proposal != nil && cs.Proposal.POLRound == -1 && !cs.isProposalTimely() {
+		logger.Error("enterPrevote: ProposalBlock is not timely",
+			"time", cs.ProposalBlock.Time, "received", cs.ProposalReceiveTime)
+		cs.signAddVote(types.PrevoteType, nil, types.PartSetHeader{})
+		return
+	}
+
 	// Prevote cs.ProposalBlock
 	// NOTE: the proposal signature is validated when it is received,
 	// and the proposal block parts are validated as they are received (against the merkle hash in the proposal)
@@ -1091,6 +1227,20 @@ func (cs *ConsensusState) defaultDoPrevote(height int64, round int) {
 	cs.signAddVote(types.PrevoteType, cs.ProposalBlock.Hash(), cs.ProposalBlockParts.Header())
 }
 
+// isProposalTimely returns true if the time of the proposal block is timely
+// given the local time the proposal was received, or if the consensus params
+// don't enable proposer-based timestamps.
+func (cs *ConsensusState) isProposalTimely() bool {
+	sp := cs.state.ConsensusParams.Synchrony
+	if !sp.ProposerBasedTimestamps() {
+		return true
+	}
+	timely := sp.IsTimely(cs.ProposalBlock.Time, cs.ProposalReceiveTime, cs.Proposal.Round)
+	cs.metrics.ProposalTimestampDifference.With("is_timely", fmt.Sprintf("%t", timely)).
+		Observe(cs.ProposalReceiveTime.Sub(cs.ProposalBlock.Time).Seconds())
+	return timely
+}
+
 // Enter: any +2/3 prevotes at next round.
 func (cs *ConsensusState) enterPrevoteWait(height int64, round int) {
 	logger := cs.Logger.With("height", height, "round", round)
@@ -1117,7 +1267,7 @@ func (cs *ConsensusState) enterPrevoteWait(height int64, round int) {
 	}()
 
 	// Wait for some more prevotes; enterPrecommit
-	cs.scheduleTimeout(cs.config.Prevote(round), height, round, cstypes.RoundStepPrevoteWait)
+	cs.scheduleTimeout(cs.timeouts(cs.state).Prevote(round), height, round, cstypes.RoundStepPrevoteWait)
 }
 
 // Enter: `timeoutPrevote` after any +2/3 prevotes.
@@ -1221,7 +1371,7 @@ func (cs *ConsensusState) enterPrecommit(height int64, round int) {
 	cs.LockedBlockParts = nil
 	if !cs.ProposalBlockParts.HasHeader(blockID.PartsHeader) {
 		cs.ProposalBlock = nil
-		cs.ProposalBlockParts = types.NewPartSetFromHeader(blockID.PartsHeader)
+		cs.ProposalBlockParts = types.NewBlockPartSetFromHeader(blockID.PartsHeader, cs.state.Version.Consensus.Block)
 	}
 	cs.eventBus.PublishEventUnlock(cs.RoundStateEvent())
 	cs.signAddVote(types.PrecommitType, nil, types.PartSetHeader{})
@@ -1251,7 +1401,7 @@ func (cs *ConsensusState) enterPrecommitWait(height int64, round int) {
 	}()
 
 	// Wait for some more precommits; enterNewRound
-	cs.scheduleTimeout(cs.config.Precommit(round), height, round, cstypes.RoundStepPrecommitWait)
+	cs.scheduleTimeout(cs.timeouts(cs.state).Precommit(round), height, round, cstypes.RoundStepPrecommitWait)
 
 }
 
@@ -1309,7 +1459,7 @@ func (cs *ConsensusState) enterCommit(height int64, commitRound int) {
 			// We're getting the wrong block.
 			// Set up ProposalBlockParts and keep waiting.
 			cs.ProposalBlock = nil
-			cs.ProposalBlockParts = types.NewPartSetFromHeader(blockID.PartsHeader)
+			cs.ProposalBlockParts = types.NewBlockPartSetFromHeader(blockID.PartsHeader, cs.state.Version.Consensus.Block)
 			cs.eventBus.PublishEventValidBlock(cs.RoundStateEvent())
 			cs.evsw.FireEvent(types.EventValidBlock, &cs.RoundState)
 		}
@@ -1460,12 +1610,9 @@ func (cs *ConsensusState) recordMetrics(height int64, block *types.Block) {
 	cs.metrics.ValidatorsPower.Set(float64(cs.Validators.TotalVotingPower()))
 	missingValidators := 0
 	missingValidatorsPower := int64(0)
+	signers := block.LastCommit.BitArray()
 	for i, val := range cs.Validators.Validators {
-		var vote *types.CommitSig
-		if i < len(block.LastCommit.Precommits) {
-			vote = block.LastCommit.Precommits[i]
-		}
-		if vote == nil {
+		if !signers.GetIndex(i) {
 			missingValidators++
 			missingValidatorsPower += val.VotingPower
 		}
@@ -1521,12 +1668,14 @@ func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
 	}
 
 	cs.Proposal = proposal
+	cs.ProposalReceiveTime = cs.clock.Now()
 	// We don't update cs.ProposalBlockParts if it is already set.
 	// This happens if we're already in cstypes.RoundStepCommit or if there is a valid block in the current round.
 	// TODO: We can check if Proposal is for a different block as this is a sign of misbehavior!
 	if cs.ProposalBlockParts == nil {
-		cs.ProposalBlockParts = types.NewPartSetFromHeader(proposal.BlockID.PartsHeader)
+		cs.ProposalBlockParts = types.NewBlockPartSetFromHeader(proposal.BlockID.PartsHeader, cs.state.Version.Consensus.Block)
 	}
+	cs.traces.proposal(proposal.Height, proposal.Round, tmtime.Now())
 	cs.Logger.Info("Received proposal", "proposal", proposal)
 	return nil
 }
@@ -1558,14 +1707,17 @@ func (cs *ConsensusState) addProposalBlockPart(msg *BlockPartMessage, peerID p2p
 	}
 	if added && cs.ProposalBlockParts.IsComplete() {
 		// Added and completed!
-		_, err = cdc.UnmarshalBinaryLengthPrefixedReader(
-			cs.ProposalBlockParts.GetReader(),
-			&cs.ProposalBlock,
-			cs.state.ConsensusParams.Block.MaxBytes,
-		)
+		bz, err := ioutil.ReadAll(cs.ProposalBlockParts.GetReader())
 		if err != nil {
 			return added, err
 		}
+		// Check the repeated fields of the block before decoding it.
+		limits := types.NewDecodeLimits(cs.state.ConsensusParams, cs.state.LastValidators.Size())
+		block, err := types.DecodeBlockLengthPrefixed(bz, cs.state.ConsensusParams.Block.MaxBytes, limits)
+		if err != nil {
+			return added, err
+		}
+		cs.ProposalBlock = block
 		// NOTE: it's possible to receive complete proposal blocks for future rounds without having the proposal
 		cs.Logger.Info("Received complete proposal block", "height", cs.ProposalBlock.Height, "hash", cs.ProposalBlock.Hash())
 		cs.eventBus.PublishEventCompleteProposal(cs.CompleteProposalEvent())
@@ -1656,6 +1808,12 @@ func (cs *ConsensusState) addVote(
 		"csHeight",
 		cs.Height)
 
+	// Only the validators extending their precommits sign the extensions,
+	// from the height the consensus params enable them at.
+	if len(vote.Extension) > 0 && !cs.state.ConsensusParams.Block.VoteExtensionsEnabled(vote.Height) {
+		return added, ErrVoteUnexpectedExtension
+	}
+
 	// A precommit for the previous height?
 	// These come in while we wait timeoutCommit
 	if vote.Height+1 == cs.Height {
@@ -1668,6 +1826,7 @@ func (cs *ConsensusState) addVote(
 		if !added {
 			return added, err
 		}
+		cs.traces.vote(vote, tmtime.Now())
 
 		cs.Logger.Info(fmt.Sprintf("Added to lastPrecommits: %v", cs.LastCommit.StringShort()))
 		cs.eventBus.PublishEventVote(types.EventDataVote{Vote: vote})
@@ -1697,6 +1856,7 @@ func (cs *ConsensusState) addVote(
 		// Either duplicate, or error upon cs.Votes.AddByIndex()
 		return
 	}
+	cs.traces.vote(vote, tmtime.Now())
 
 	cs.eventBus.PublishEventVote(types.EventDataVote{Vote: vote})
 	cs.evsw.FireEvent(types.EventVote, vote)
@@ -1745,7 +1905,7 @@ func (cs *ConsensusState) addVote(
 					cs.ProposalBlock = nil
 				}
 				if !cs.ProposalBlockParts.HasHeader(blockID.PartsHeader) {
-					cs.ProposalBlockParts = types.NewPartSetFromHeader(blockID.PartsHeader)
+					cs.ProposalBlockParts = types.NewBlockPartSetFromHeader(blockID.PartsHeader, cs.state.Version.Consensus.Block)
 				}
 				cs.evsw.FireEvent(types.EventValidBlock, &cs.RoundState)
 				cs.eventBus.PublishEventValidBlock(cs.RoundStateEvent())
@@ -1820,12 +1980,20 @@ func (cs *ConsensusState) signVote(
 		Type:             type_,
 		BlockID:          types.BlockID{Hash: hash, PartsHeader: header},
 	}
+	if type_ == types.PrecommitType && cs.state.ConsensusParams.Block.VoteExtensionsEnabled(cs.Height) {
+		extension, err := cs.blockExec.ExtendVote(cs.Height, cs.Round, hash)
+		if err != nil {
+			// Precommit anyway, liveness doesn't depend on the extension.
+			cs.Logger.Error("Error extending precommit", "height", cs.Height, "round", cs.Round, "err", err)
+		}
+		vote.Extension = extension
+	}
 	err := cs.privValidator.SignVote(cs.state.ChainID, vote)
 	return vote, err
 }
 
 func (cs *ConsensusState) voteTime() time.Time {
-	now := tmtime.Now()
+	now := cs.clock.Now()
 	minVoteTime := now
 	// TODO: We should remove next line in case we don't vote for v in case cs.ProposalBlock == nil,
 	// even if cs.LockedBlock != nil. See https://github.com/tendermint/spec.
@@ -16,6 +16,7 @@ import (
 	tmpubsub "github.com/tendermint/tendermint/libs/pubsub"
 	p2pmock "github.com/tendermint/tendermint/p2p/mock"
 	"github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
 )
 
 /*
@@ -171,6 +172,36 @@ func TestStateEnterProposeYesPrivValidator(t *testing.T) {
 	ensureNoNewTimeout(timeoutCh, cs.config.TimeoutPropose.Nanoseconds())
 }
 
+func TestStateEnterProposeWaitsForMinTime(t *testing.T) {
+	cs, _ := randConsensusState(1)
+	height, round := cs.Height, cs.Round
+
+	// with proposer-based timestamps, a genesis time in the future is the
+	// earliest time of the first block
+	cs.state.ConsensusParams.Synchrony = types.SynchronyParams{
+		Precision:    500 * time.Millisecond,
+		MessageDelay: 2 * time.Second,
+	}
+	minTime := tmtime.Now().Add(time.Second)
+	cs.state.LastBlockTime = minTime
+
+	proposalCh := subscribe(cs.eventBus, types.EventQueryCompleteProposal)
+
+	startTestRound(cs, height, round)
+
+	ensureNoNewEvent(proposalCh, 500*time.Millisecond, "proposed before the earliest block time")
+	select {
+	case <-proposalCh:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the proposal")
+	}
+
+	// the proposal is timely by our own clock
+	rs := cs.GetRoundState()
+	assert.False(t, rs.ProposalBlock.Time.Before(minTime))
+	assert.True(t, cs.state.ConsensusParams.Synchrony.IsTimely(rs.ProposalBlock.Time, rs.ProposalReceiveTime, round))
+}
+
 func TestStateBadProposal(t *testing.T) {
 	cs1, vss := randConsensusState(2)
 	height, round := cs1.Height, cs1.Round
@@ -1117,6 +1148,26 @@ func TestWaitingTimeoutOnNilPolka(t *testing.T) {
 	ensureNewRound(newRoundCh, height, round+1)
 }
 
+func TestStateTimeoutParams(t *testing.T) {
+	cs1, _ := randConsensusState(1)
+	state := cs1.state
+	assert.Equal(t, cs1.config, cs1.timeouts(state))
+
+	// the timeouts set in the consensus params override the config
+	state.ConsensusParams.Timeout = types.TimeoutParams{
+		Propose:      time.Second,
+		ProposeDelta: 100 * time.Millisecond,
+		Commit:       2 * time.Second,
+	}
+	timeouts := cs1.timeouts(state)
+	assert.Equal(t, 1200*time.Millisecond, timeouts.Propose(2))
+	assert.Equal(t, cs1.config.Prevote(2), timeouts.Prevote(2))
+	assert.Equal(t, cs1.config.Precommit(2), timeouts.Precommit(2))
+	now := time.Now()
+	assert.Equal(t, now.Add(2*time.Second), timeouts.Commit(now))
+	assert.Equal(t, 40*time.Millisecond, cs1.config.TimeoutPropose, "config unchanged")
+}
+
 // 4 vals, 3 Prevotes for nil from the higher round.
 // What we want:
 // P0 waits for timeoutPropose in the next round before entering prevote
@@ -0,0 +1,82 @@
+package consensus
+
+import (
+	"sync"
+	"time"
+
+	cstypes "github.com/tendermint/tendermint/consensus/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// numTracedHeights is the number of heights whose trace is kept.
+const numTracedHeights = 100
+
+// traceRecorder records the traces of the latest heights (see
+// cstypes.HeightTrace). It is safe for concurrent use.
+type traceRecorder struct {
+	mtx    sync.Mutex
+	traces map[int64]*cstypes.HeightTrace
+}
+
+func newTraceRecorder() *traceRecorder {
+	return &traceRecorder{traces: make(map[int64]*cstypes.HeightTrace)}
+}
+
+// newHeight starts the trace of height, and drops the traces which are too
+// old.
+func (tr *traceRecorder) newHeight(height int64, validators *types.ValidatorSet) {
+	tr.mtx.Lock()
+	defer tr.mtx.Unlock()
+	if _, ok := tr.traces[height]; !ok {
+		tr.traces[height] = cstypes.NewHeightTrace(height, validators)
+	}
+	for h := range tr.traces {
+		if h <= height-numTracedHeights {
+			delete(tr.traces, h)
+		}
+	}
+}
+
+func (tr *traceRecorder) newRound(height int64, round int, proposer types.Address, now time.Time) {
+	tr.mtx.Lock()
+	defer tr.mtx.Unlock()
+	if ht, ok := tr.traces[height]; ok {
+		rt := ht.Round(round)
+		rt.StartTime, rt.Proposer = now, proposer
+	}
+}
+
+func (tr *traceRecorder) proposal(height int64, round int, now time.Time) {
+	tr.mtx.Lock()
+	defer tr.mtx.Unlock()
+	if ht, ok := tr.traces[height]; ok {
+		ht.Round(round).ProposalTime = now
+	}
+}
+
+func (tr *traceRecorder) vote(vote *types.Vote, now time.Time) {
+	tr.mtx.Lock()
+	defer tr.mtx.Unlock()
+	ht, ok := tr.traces[vote.Height]
+	if !ok || vote.ValidatorIndex < 0 || vote.ValidatorIndex >= len(ht.Validators) {
+		return
+	}
+	rt := ht.Round(vote.Round)
+	switch vote.Type {
+	case types.PrevoteType:
+		rt.Prevotes[vote.ValidatorIndex] = now
+	case types.PrecommitType:
+		rt.Precommits[vote.ValidatorIndex] = now
+	}
+}
+
+// get returns a copy of the trace of height, if it is recorded.
+func (tr *traceRecorder) get(height int64) (*cstypes.HeightTrace, bool) {
+	tr.mtx.Lock()
+	defer tr.mtx.Unlock()
+	ht, ok := tr.traces[height]
+	if !ok {
+		return nil, false
+	}
+	return ht.Copy(), true
+}
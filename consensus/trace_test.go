@@ -0,0 +1,51 @@
+package consensus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestTraceRecorder(t *testing.T) {
+	vals, _ := types.RandValidatorSet(2, 10)
+	tr := newTraceRecorder()
+	now := time.Now()
+
+	tr.newHeight(1, vals)
+	tr.newRound(1, 1, vals.Validators[1].Address, now)
+	tr.proposal(1, 1, now.Add(time.Second))
+	tr.vote(&types.Vote{Type: types.PrevoteType, Height: 1, Round: 1, ValidatorIndex: 1}, now.Add(2*time.Second))
+	tr.vote(&types.Vote{Type: types.PrecommitType, Height: 1, Round: 0, ValidatorIndex: 0}, now.Add(3*time.Second))
+	tr.vote(&types.Vote{Type: types.PrevoteType, Height: 1, Round: 0, ValidatorIndex: 2}, now) // unknown validator
+	tr.vote(&types.Vote{Type: types.PrevoteType, Height: 2, Round: 0, ValidatorIndex: 0}, now) // height not started
+
+	ht, ok := tr.get(1)
+	require.True(t, ok)
+	assert.Equal(t, []types.Address{vals.Validators[0].Address, vals.Validators[1].Address}, ht.Validators)
+	require.Len(t, ht.Rounds, 2)
+	assert.True(t, ht.Rounds[0].StartTime.IsZero())
+	assert.True(t, ht.Rounds[0].Prevotes[0].IsZero())
+	assert.Equal(t, now.Add(3*time.Second), ht.Rounds[0].Precommits[0])
+	assert.Equal(t, vals.Validators[1].Address, ht.Rounds[1].Proposer)
+	assert.Equal(t, now.Add(time.Second), ht.Rounds[1].ProposalTime)
+	assert.Equal(t, now.Add(2*time.Second), ht.Rounds[1].Prevotes[1])
+	_, ok = tr.get(2)
+	assert.False(t, ok)
+
+	// the returned trace is a copy
+	ht.Rounds[1].Prevotes[1] = time.Time{}
+	ht, _ = tr.get(1)
+	assert.Equal(t, now.Add(2*time.Second), ht.Rounds[1].Prevotes[1])
+
+	// old heights are dropped
+	tr.newHeight(numTracedHeights, vals)
+	_, ok = tr.get(1)
+	assert.True(t, ok)
+	tr.newHeight(numTracedHeights+1, vals)
+	_, ok = tr.get(1)
+	assert.False(t, ok)
+}
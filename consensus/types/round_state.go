@@ -80,6 +80,10 @@ type RoundState struct {
 	LockedBlock        *types.Block        `json:"locked_block"`
 	LockedBlockParts   *types.PartSet      `json:"locked_block_parts"`
 
+	// Local time when the Proposal was received, to check that its block time
+	// is timely with proposer-based timestamps.
+	ProposalReceiveTime time.Time `json:"proposal_receive_time"`
+
 	// Last known round with POL for non-nil valid block.
 	ValidRound int          `json:"valid_round"`
 	ValidBlock *types.Block `json:"valid_block"` // Last known block of POL mentioned above.
@@ -0,0 +1,69 @@
+package types
+
+import (
+	"time"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// HeightTrace is what a node saw of the rounds of a height: when each round
+// started, who proposed, and when the proposal and the votes of each
+// validator were received. It is meant for visualizations and diagnostics.
+//
+// Times are local times, and are zero for what the node didn't see.
+type HeightTrace struct {
+	Height int64 `json:"height"`
+	// addresses of the validators of the height, in the order of the votes
+	Validators []types.Address `json:"validators"`
+	Rounds     []*RoundTrace   `json:"rounds"`
+}
+
+// RoundTrace is what a node saw of a round (see HeightTrace).
+type RoundTrace struct {
+	Round        int           `json:"round"`
+	StartTime    time.Time     `json:"start_time"`
+	Proposer     types.Address `json:"proposer"`
+	ProposalTime time.Time     `json:"proposal_time"`
+	Prevotes     []time.Time   `json:"prevotes"`   // by validator index
+	Precommits   []time.Time   `json:"precommits"` // by validator index
+}
+
+// NewHeightTrace returns an empty HeightTrace for the validators of height.
+func NewHeightTrace(height int64, validators *types.ValidatorSet) *HeightTrace {
+	ht := &HeightTrace{Height: height}
+	if validators != nil {
+		for _, val := range validators.Validators {
+			ht.Validators = append(ht.Validators, val.Address)
+		}
+	}
+	return ht
+}
+
+// Round returns the trace of the round, adding it (and the rounds before it)
+// if needed.
+func (ht *HeightTrace) Round(round int) *RoundTrace {
+	for len(ht.Rounds) <= round {
+		ht.Rounds = append(ht.Rounds, &RoundTrace{
+			Round:      len(ht.Rounds),
+			Prevotes:   make([]time.Time, len(ht.Validators)),
+			Precommits: make([]time.Time, len(ht.Validators)),
+		})
+	}
+	return ht.Rounds[round]
+}
+
+// Copy returns a deep copy of the trace.
+func (ht *HeightTrace) Copy() *HeightTrace {
+	cp := &HeightTrace{
+		Height:     ht.Height,
+		Validators: ht.Validators,
+		Rounds:     make([]*RoundTrace, len(ht.Rounds)),
+	}
+	for i, rt := range ht.Rounds {
+		rtCopy := *rt
+		rtCopy.Prevotes = append([]time.Time(nil), rt.Prevotes...)
+		rtCopy.Precommits = append([]time.Time(nil), rt.Precommits...)
+		cp.Rounds[i] = &rtCopy
+	}
+	return cp
+}
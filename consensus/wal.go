@@ -13,6 +13,7 @@ import (
 	amino "github.com/tendermint/go-amino"
 	auto "github.com/tendermint/tendermint/libs/autofile"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/compress"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/types"
 	tmtime "github.com/tendermint/tendermint/types/time"
@@ -24,6 +25,9 @@ const (
 
 	// how often the WAL should be sync'd during period sync'ing
 	walDefaultFlushInterval = 2 * time.Second
+
+	// set in the length of the compressed messages
+	walCompressedFlag = uint32(1) << 31
 )
 
 //--------------------------------------------------------
@@ -82,6 +86,9 @@ type baseWAL struct {
 
 	flushTicker   *time.Ticker
 	flushInterval time.Duration
+
+	// timestamps the messages
+	clock *tmtime.MonotonicClock
 }
 
 var _ WAL = &baseWAL{}
@@ -102,6 +109,7 @@ func NewWAL(walFile string, groupOptions ...func(*auto.Group)) (*baseWAL, error)
 		group:         group,
 		enc:           NewWALEncoder(group),
 		flushInterval: walDefaultFlushInterval,
+		clock:         tmtime.NewMonotonicClock(),
 	}
 	wal.BaseService = *cmn.NewBaseService(nil, "baseWAL", wal)
 	return wal, nil
@@ -112,6 +120,22 @@ func (wal *baseWAL) SetFlushInterval(i time.Duration) {
 	wal.flushInterval = i
 }
 
+// SetClock sets the clock timestamping the messages, e.g. to share the one of
+// the consensus state.
+func (wal *baseWAL) SetClock(clock *tmtime.MonotonicClock) {
+	wal.clock = clock
+}
+
+// SetCompression compresses the messages written to the WAL from now on with
+// codec of codecs, or not at all if codec is nil or none. Messages already
+// written are read either way.
+func (wal *baseWAL) SetCompression(codecs *compress.Registry, codec compress.Codec) {
+	if codec != nil && codec.ID() == compress.IDNone {
+		codec = nil
+	}
+	wal.enc.codecs, wal.enc.codec = codecs, codec
+}
+
 func (wal *baseWAL) Group() *auto.Group {
 	return wal.group
 }
@@ -180,7 +204,7 @@ func (wal *baseWAL) Write(msg WALMessage) error {
 		return nil
 	}
 
-	if err := wal.enc.Encode(&TimedWALMessage{tmtime.Now(), msg}); err != nil {
+	if err := wal.enc.Encode(&TimedWALMessage{wal.clock.Now(), msg}); err != nil {
 		wal.Logger.Error("Error writing msg to consensus wal. WARNING: recover may not be possible for the current height",
 			"err", err, "msg", msg)
 		return err
@@ -279,14 +303,25 @@ func (wal *baseWAL) SearchForEndHeight(
 
 // A WALEncoder writes custom-encoded WAL messages to an output stream.
 //
-// Format: 4 bytes CRC sum + 4 bytes length + arbitrary-length value (go-amino encoded)
+// Format: 4 bytes CRC sum + 4 bytes length + arbitrary-length value (go-amino
+// encoded). If the value is compressed, the highest bit of the length is set,
+// the value is encoded by a compress.Registry (i.e. starts with the ID of its
+// codec), and the CRC sum and the length are the ones of the compressed value.
 type WALEncoder struct {
-	wr io.Writer
+	wr     io.Writer
+	codecs *compress.Registry
+	codec  compress.Codec // nil if the messages aren't compressed
 }
 
 // NewWALEncoder returns a new encoder that writes to wr.
 func NewWALEncoder(wr io.Writer) *WALEncoder {
-	return &WALEncoder{wr}
+	return &WALEncoder{wr: wr}
+}
+
+// NewCompressedWALEncoder returns a new encoder that writes messages
+// compressed with codec of codecs to wr.
+func NewCompressedWALEncoder(wr io.Writer, codecs *compress.Registry, codec compress.Codec) *WALEncoder {
+	return &WALEncoder{wr: wr, codecs: codecs, codec: codec}
 }
 
 // Encode writes the custom encoding of v to the stream. It returns an error if
@@ -295,16 +330,30 @@ func NewWALEncoder(wr io.Writer) *WALEncoder {
 func (enc *WALEncoder) Encode(v *TimedWALMessage) error {
 	data := cdc.MustMarshalBinaryBare(v)
 
+	if len(data) > maxMsgSizeBytes {
+		return fmt.Errorf("msg is too big: %d bytes, max: %d bytes", len(data), maxMsgSizeBytes)
+	}
+
+	// incompressible messages are written uncompressed
+	lengthFlags := uint32(0)
+	if enc.codec != nil {
+		compressed, err := enc.codecs.Encode(enc.codec, data)
+		if err != nil {
+			return err
+		}
+		if compressed[0] != compress.IDNone {
+			data = compressed
+			lengthFlags = walCompressedFlag
+		}
+	}
+
 	crc := crc32.Checksum(data, crc32c)
 	length := uint32(len(data))
-	if length > maxMsgSizeBytes {
-		return fmt.Errorf("msg is too big: %d bytes, max: %d bytes", length, maxMsgSizeBytes)
-	}
 	totalLength := 8 + int(length)
 
 	msg := make([]byte, totalLength)
 	binary.BigEndian.PutUint32(msg[0:4], crc)
-	binary.BigEndian.PutUint32(msg[4:8], length)
+	binary.BigEndian.PutUint32(msg[4:8], length|lengthFlags)
 	copy(msg[8:], data)
 
 	_, err := enc.wr.Write(msg)
@@ -338,12 +387,13 @@ func (e DataCorruptionError) Cause() error {
 // It will also compare the checksums and make sure data size is equal to the
 // length from the header. If that is not the case, error will be returned.
 type WALDecoder struct {
-	rd io.Reader
+	rd     io.Reader
+	codecs *compress.Registry
 }
 
 // NewWALDecoder returns a new decoder that reads from rd.
 func NewWALDecoder(rd io.Reader) *WALDecoder {
-	return &WALDecoder{rd}
+	return &WALDecoder{rd: rd, codecs: compress.NewRegistry()}
 }
 
 // Decode reads the next custom-encoded value from its reader and returns it.
@@ -365,6 +415,8 @@ func (dec *WALDecoder) Decode() (*TimedWALMessage, error) {
 		return nil, DataCorruptionError{fmt.Errorf("failed to read length: %v", err)}
 	}
 	length := binary.BigEndian.Uint32(b)
+	compressed := length&walCompressedFlag != 0
+	length &^= walCompressedFlag
 
 	if length > maxMsgSizeBytes {
 		return nil, DataCorruptionError{fmt.Errorf(
@@ -385,6 +437,13 @@ func (dec *WALDecoder) Decode() (*TimedWALMessage, error) {
 		return nil, DataCorruptionError{fmt.Errorf("checksums do not match: read: %v, actual: %v", crc, actualCRC)}
 	}
 
+	if compressed {
+		data, err = dec.codecs.DecodeLimit(data, maxMsgSizeBytes)
+		if err != nil {
+			return nil, DataCorruptionError{fmt.Errorf("failed to decompress data: %v", err)}
+		}
+	}
+
 	var res = new(TimedWALMessage) // nolint: gosimple
 	err = cdc.UnmarshalBinaryBare(data, res)
 	if err != nil {
@@ -0,0 +1,381 @@
+package consensus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/crypto"
+	auto "github.com/tendermint/tendermint/libs/autofile"
+	"github.com/tendermint/tendermint/p2p"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+)
+
+// Steps of the signatures of a validator, ordered like those of the
+// privval.FilePVLastSignState.
+const (
+	walSignStepPropose   int8 = 1
+	walSignStepPrevote   int8 = 2
+	walSignStepPrecommit int8 = 3
+)
+
+// WALProblem is an inconsistency found in the WAL by the WALChecker.
+type WALProblem struct {
+	Index  int   // of the message in the WAL, -1 if not about a message
+	Height int64 // 0 if not about a height
+	Round  int
+	// Hazard is set when restarting the validator could make it double sign.
+	Hazard      bool
+	Description string
+}
+
+func (p WALProblem) String() string {
+	s := p.Description
+	if p.Height > 0 {
+		s = fmt.Sprintf("%d/%d: %s", p.Height, p.Round, s)
+	}
+	if p.Index >= 0 {
+		s = fmt.Sprintf("#%d %s", p.Index, s)
+	}
+	if p.Hazard {
+		s = "DOUBLE-SIGN HAZARD " + s
+	}
+	return s
+}
+
+type walHeightRound struct {
+	height int64
+	round  int
+}
+
+type walVoteSetKey struct {
+	height int64
+	round  int
+	typ    types.SignedMsgType
+}
+
+// WALChecker replays the messages of a consensus WAL into vote sets and part
+// sets, as the consensus state machine would, without applying anything, and
+// checks that they are consistent:
+//
+//   - the proposals and votes are signed by the validators of their height;
+//   - no validator signed conflicting proposals or votes;
+//   - the block parts match their proposal, and the blocks their hash;
+//   - the heights of the #ENDHEIGHT messages increase (with gaps where the
+//     node fast synced), up to the state.
+//
+// With the address of the validator of the node, it also detects the
+// double-sign hazards: its own conflicting signatures, its signatures
+// received from peers (another node signing with the same key), and the
+// signatures of the WAL beyond the last sign state of the private validator
+// (e.g. restored from a backup).
+type WALChecker struct {
+	chainID       string
+	stateDB       dbm.DB
+	maxBlockBytes int64
+	blockProtocol version.Protocol
+
+	address    crypto.Address // nil if the node isn't a validator
+	lastSigned walSignState   // of the private validator
+
+	index      int
+	endHeight  int64 // of the last #ENDHEIGHT, 0 if none
+	validators map[int64]*types.ValidatorSet
+	proposals  map[walHeightRound]*types.Proposal
+	parts      map[walHeightRound]*types.PartSet
+	voteSets   map[walVoteSetKey]*types.VoteSet
+	ownSigned  walSignState // highest signature of the validator in the WAL
+	problems   []WALProblem
+}
+
+type walSignState struct {
+	height int64
+	round  int
+	step   int8
+}
+
+func (s walSignState) less(o walSignState) bool {
+	if s.height != o.height {
+		return s.height < o.height
+	}
+	if s.round != o.round {
+		return s.round < o.round
+	}
+	return s.step < o.step
+}
+
+// NewWALChecker returns a WALChecker loading the validators of the heights
+// of the WAL from the state DB, which is only read.
+func NewWALChecker(state sm.State, stateDB dbm.DB) *WALChecker {
+	return &WALChecker{
+		chainID:       state.ChainID,
+		stateDB:       stateDB,
+		maxBlockBytes: state.ConsensusParams.Block.MaxBytes,
+		blockProtocol: state.Version.Consensus.Block,
+		validators:    make(map[int64]*types.ValidatorSet),
+		proposals:     make(map[walHeightRound]*types.Proposal),
+		parts:         make(map[walHeightRound]*types.PartSet),
+		voteSets:      make(map[walVoteSetKey]*types.VoteSet),
+	}
+}
+
+// SetValidator sets the address of the validator of the node and the last
+// height/round/step it signed, according to its private validator, to
+// detect the double-sign hazards.
+func (c *WALChecker) SetValidator(address crypto.Address, lastHeight int64, lastRound int, lastStep int8) {
+	c.address = address
+	c.lastSigned = walSignState{lastHeight, lastRound, lastStep}
+}
+
+// Check checks the next message of the WAL.
+func (c *WALChecker) Check(msg *TimedWALMessage) {
+	defer func() { c.index++ }()
+
+	switch m := msg.Msg.(type) {
+	case EndHeightMessage:
+		if c.endHeight > 0 && m.Height <= c.endHeight {
+			c.report(m.Height, 0, false, fmt.Sprintf("#ENDHEIGHT %d follows #ENDHEIGHT %d", m.Height, c.endHeight))
+		}
+		c.endHeight = m.Height
+		c.prune(m.Height)
+	case msgInfo:
+		switch cm := m.Msg.(type) {
+		case *ProposalMessage:
+			c.checkProposal(cm.Proposal, m.PeerID)
+		case *BlockPartMessage:
+			c.checkBlockPart(cm)
+		case *VoteMessage:
+			c.checkVote(cm.Vote, m.PeerID)
+		}
+	}
+}
+
+// CheckFile checks the messages of the WAL group of walFile, from its oldest
+// file. A corrupted message is reported, and ends the check.
+func (c *WALChecker) CheckFile(walFile string) error {
+	if _, err := os.Stat(walFile); err != nil {
+		return err
+	}
+	group, err := auto.OpenGroup(walFile)
+	if err != nil {
+		return err
+	}
+	defer group.Close()
+	gr, err := group.NewReader(group.MinIndex())
+	if err != nil {
+		return err
+	}
+	defer gr.Close() // nolint: errcheck
+
+	dec := NewWALDecoder(gr)
+	for {
+		msg, err := dec.Decode()
+		switch {
+		case err == io.EOF:
+			return nil
+		case IsDataCorruptionError(err):
+			c.report(0, 0, false, fmt.Sprintf("corrupted message, the rest of the WAL is unreadable: %v", err))
+			return nil
+		case err != nil:
+			return err
+		}
+		c.Check(msg)
+	}
+}
+
+// Finish checks the WAL against the state, once all its messages are
+// checked, and returns the problems found.
+func (c *WALChecker) Finish(state sm.State) []WALProblem {
+	c.index = -1
+	// The #ENDHEIGHT of a block is written after the block is saved, and
+	// before the state is, so the WAL may be one height ahead of the state.
+	if c.endHeight > state.LastBlockHeight+1 {
+		c.report(0, 0, false, fmt.Sprintf(
+			"the WAL ends height %d, beyond the last height %d of the state", c.endHeight, state.LastBlockHeight))
+	}
+	if c.address != nil && c.lastSigned.less(c.ownSigned) {
+		c.report(0, 0, true, fmt.Sprintf(
+			"the validator signed %d/%d/%d in the WAL, beyond the last signed %d/%d/%d of its private validator state "+
+				"(restored from a backup?)",
+			c.ownSigned.height, c.ownSigned.round, c.ownSigned.step,
+			c.lastSigned.height, c.lastSigned.round, c.lastSigned.step))
+	}
+	return c.problems
+}
+
+func (c *WALChecker) checkProposal(proposal *types.Proposal, peerID p2p.ID) {
+	h, r := proposal.Height, proposal.Round
+	if err := proposal.ValidateBasic(); err != nil {
+		c.report(h, r, false, fmt.Sprintf("invalid proposal: %v", err))
+		return
+	}
+	vals, ok := c.validatorsAt(h, r)
+	if !ok {
+		return
+	}
+	proposer := vals.GetProposer()
+	if r > 0 {
+		proposer = vals.CopyIncrementProposerPriority(r).GetProposer()
+	}
+	if !proposer.PubKey.VerifyBytes(proposal.SignBytes(c.chainID), proposal.Signature) {
+		c.report(h, r, false, fmt.Sprintf("proposal not signed by the proposer %v of the round", proposer.Address))
+		return
+	}
+
+	own := c.isOwn(proposer.Address)
+	if own {
+		c.checkOwnSignature(h, r, walSignStepPropose, peerID)
+	}
+	key := walHeightRound{h, r}
+	if prev, ok := c.proposals[key]; ok {
+		if !prev.BlockID.Equals(proposal.BlockID) {
+			c.report(h, r, own, fmt.Sprintf("conflicting proposals of %v and %v by the proposer %v",
+				prev.BlockID, proposal.BlockID, proposer.Address))
+		}
+		return
+	}
+	c.proposals[key] = proposal
+	c.parts[key] = types.NewBlockPartSetFromHeader(proposal.BlockID.PartsHeader, c.blockProtocol)
+}
+
+func (c *WALChecker) checkBlockPart(msg *BlockPartMessage) {
+	h, r := msg.Height, msg.Round
+	key := walHeightRound{h, r}
+	parts, ok := c.parts[key]
+	if !ok || parts.IsComplete() {
+		// parts of a proposal not in the WAL can't be checked
+		return
+	}
+	added, err := parts.AddPart(msg.Part)
+	if err != nil {
+		c.report(h, r, false, fmt.Sprintf("block part %d doesn't match the proposal: %v", msg.Part.Index, err))
+		return
+	}
+	if !added || !parts.IsComplete() {
+		return
+	}
+
+	var block *types.Block
+	_, err = cdc.UnmarshalBinaryLengthPrefixedReader(parts.GetReader(), &block, c.maxBlockBytes)
+	if err != nil {
+		c.report(h, r, false, fmt.Sprintf("invalid proposal block: %v", err))
+		return
+	}
+	if blockID := c.proposals[key].BlockID; !bytes.Equal(block.Hash(), blockID.Hash) || block.Height != h {
+		c.report(h, r, false, fmt.Sprintf("proposal block %d/%X doesn't match the proposal %v",
+			block.Height, block.Hash(), blockID))
+	}
+}
+
+func (c *WALChecker) checkVote(vote *types.Vote, peerID p2p.ID) {
+	h, r := vote.Height, vote.Round
+	if err := vote.ValidateBasic(); err != nil {
+		c.report(h, r, false, fmt.Sprintf("invalid vote: %v", err))
+		return
+	}
+	vals, ok := c.validatorsAt(h, r)
+	if !ok {
+		return
+	}
+
+	own := c.isOwn(vote.ValidatorAddress)
+	if own {
+		step := walSignStepPrevote
+		if vote.Type == types.PrecommitType {
+			step = walSignStepPrecommit
+		}
+		c.checkOwnSignature(h, r, step, peerID)
+	}
+
+	key := walVoteSetKey{h, r, vote.Type}
+	voteSet, ok := c.voteSets[key]
+	if !ok {
+		voteSet = types.NewVoteSet(c.chainID, h, r, vote.Type, vals)
+		c.voteSets[key] = voteSet
+	}
+	_, err := voteSet.AddVote(vote)
+	if conflict, ok := err.(*types.ErrVoteConflictingVotes); ok {
+		c.report(h, r, own, fmt.Sprintf("conflicting %s for %v and %v by %v (equivocation)",
+			walVoteType(vote.Type), conflict.VoteA.BlockID, conflict.VoteB.BlockID, vote.ValidatorAddress))
+	} else if errors.Cause(err) == types.ErrVoteNonDeterministicSignature {
+		c.report(h, r, own, fmt.Sprintf("%s for %v signed twice by %v: another node may sign with its key",
+			walVoteType(vote.Type), vote.BlockID, vote.ValidatorAddress))
+	} else if err != nil {
+		c.report(h, r, false, fmt.Sprintf("invalid %s by %v: %v", walVoteType(vote.Type), vote.ValidatorAddress, err))
+	}
+}
+
+func walVoteType(typ types.SignedMsgType) string {
+	if typ == types.PrecommitType {
+		return "precommit"
+	}
+	return "prevote"
+}
+
+// checkOwnSignature records a signature of the validator of the node, which
+// is a hazard if it was received from a peer.
+func (c *WALChecker) checkOwnSignature(height int64, round int, step int8, peerID p2p.ID) {
+	if s := (walSignState{height, round, step}); c.ownSigned.less(s) {
+		c.ownSigned = s
+	}
+	if peerID != "" {
+		c.report(height, round, true, fmt.Sprintf(
+			"signature of the validator received from peer %v: another node may sign with its key", peerID))
+	}
+}
+
+func (c *WALChecker) isOwn(address crypto.Address) bool {
+	return c.address != nil && bytes.Equal(address, c.address)
+}
+
+// validatorsAt returns the validators of the height, reporting a problem if
+// they aren't in the state DB.
+func (c *WALChecker) validatorsAt(height int64, round int) (*types.ValidatorSet, bool) {
+	if vals, ok := c.validators[height]; ok {
+		return vals, vals != nil
+	}
+	vals, err := sm.LoadValidators(c.stateDB, height)
+	if err != nil {
+		c.report(height, round, false, fmt.Sprintf("can't check the signatures: %v", err))
+		vals = nil
+	}
+	c.validators[height] = vals
+	return vals, vals != nil
+}
+
+// prune forgets the proposals and votes of the heights before the ended
+// height, whose precommits may still be received (LastCommit).
+func (c *WALChecker) prune(endHeight int64) {
+	for key := range c.proposals {
+		if key.height < endHeight {
+			delete(c.proposals, key)
+			delete(c.parts, key)
+		}
+	}
+	for key := range c.voteSets {
+		if key.height < endHeight {
+			delete(c.voteSets, key)
+		}
+	}
+	for height := range c.validators {
+		if height < endHeight {
+			delete(c.validators, height)
+		}
+	}
+}
+
+func (c *WALChecker) report(height int64, round int, hazard bool, description string) {
+	c.problems = append(c.problems, WALProblem{
+		Index:       c.index,
+		Height:      height,
+		Round:       round,
+		Hazard:      hazard,
+		Description: description,
+	})
+}
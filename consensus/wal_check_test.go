@@ -0,0 +1,79 @@
+package consensus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
+)
+
+func TestWALChecker(t *testing.T) {
+	state, privVals := randGenesisState(1, false, 10)
+	stateDB := dbm.NewMemDB()
+	sm.SaveState(stateDB, state)
+	vs := NewValidatorStub(privVals[0], 0)
+	vs.Height = 1
+	address := privVals[0].GetPubKey().Address()
+
+	block, parts := state.MakeBlock(1, nil, new(types.Commit), nil, address)
+	blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+	proposal := types.NewProposal(1, 0, -1, blockID)
+	require.NoError(t, privVals[0].SignProposal(state.ChainID, proposal))
+
+	msgs := []WALMessage{
+		EndHeightMessage{0},
+		msgInfo{&ProposalMessage{proposal}, ""},
+		msgInfo{&BlockPartMessage{1, 0, parts.GetPart(0)}, ""},
+		msgInfo{&VoteMessage{signVote(vs, types.PrevoteType, blockID.Hash, blockID.PartsHeader)}, ""},
+		EndHeightMessage{1},
+		// conflicting prevote
+		msgInfo{&VoteMessage{signVote(vs, types.PrevoteType, nil, types.PartSetHeader{})}, ""},
+		// signed with the key of the validator, by another node
+		msgInfo{&VoteMessage{signVote(vs, types.PrecommitType, blockID.Hash, blockID.PartsHeader)}, "peer"},
+		EndHeightMessage{1},
+	}
+
+	// the first messages are consistent
+	checker := NewWALChecker(state, stateDB)
+	for _, msg := range msgs[:5] {
+		checker.Check(&TimedWALMessage{Time: tmtime.Now(), Msg: msg})
+	}
+	assert.Empty(t, checker.Finish(state))
+
+	// write the WAL
+	dir, err := ioutil.TempDir("", "wal_check")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	walFile := filepath.Join(dir, "wal")
+	f, err := os.Create(walFile)
+	require.NoError(t, err)
+	enc := NewWALEncoder(f)
+	for _, msg := range msgs {
+		require.NoError(t, enc.Encode(&TimedWALMessage{Time: tmtime.Now(), Msg: msg}))
+	}
+	require.NoError(t, f.Close())
+
+	checker = NewWALChecker(state, stateDB)
+	// the private validator state was restored from before the prevote
+	checker.SetValidator(address, 0, 0, 0)
+	require.NoError(t, checker.CheckFile(walFile))
+	problems := checker.Finish(state)
+
+	require.Len(t, problems, 4)
+	assert.Equal(t, 5, problems[0].Index)
+	assert.True(t, problems[0].Hazard, "equivocation")
+	assert.Equal(t, 6, problems[1].Index)
+	assert.True(t, problems[1].Hazard, "signature received from a peer")
+	assert.Equal(t, 7, problems[2].Index)
+	assert.False(t, problems[2].Hazard, "#ENDHEIGHT repeated")
+	assert.Equal(t, -1, problems[3].Index)
+	assert.True(t, problems[3].Hazard, "private validator state behind the WAL")
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/tendermint/tendermint/consensus/types"
 	"github.com/tendermint/tendermint/crypto/merkle"
 	"github.com/tendermint/tendermint/libs/autofile"
+	"github.com/tendermint/tendermint/libs/compress"
 	"github.com/tendermint/tendermint/libs/log"
 	tmtypes "github.com/tendermint/tendermint/types"
 	tmtime "github.com/tendermint/tendermint/types/time"
@@ -104,6 +105,36 @@ func TestWALEncoderDecoder(t *testing.T) {
 	}
 }
 
+func TestWALEncoderDecoderCompressed(t *testing.T) {
+	now := tmtime.Now()
+	msgs := []TimedWALMessage{
+		{Time: now, Msg: EndHeightMessage{0}},
+		{Time: now, Msg: msgInfo{Msg: &BlockPartMessage{
+			Height: 1,
+			Round:  1,
+			Part:   &tmtypes.Part{Index: 1, Bytes: make([]byte, 10000)},
+		}}},
+	}
+
+	b := new(bytes.Buffer)
+
+	// compressed messages are read along with uncompressed ones
+	err := NewWALEncoder(b).Encode(&msgs[0])
+	require.NoError(t, err)
+	size := b.Len()
+	err = NewCompressedWALEncoder(b, compress.NewRegistry(), compress.Snappy()).Encode(&msgs[1])
+	require.NoError(t, err)
+	assert.True(t, b.Len()-size < 1000, "expected the block part to be compressed")
+
+	dec := NewWALDecoder(b)
+	for _, msg := range msgs {
+		decoded, err := dec.Decode()
+		require.NoError(t, err)
+		assert.Equal(t, msg.Time.UTC(), decoded.Time)
+		assert.Equal(t, msg.Msg, decoded.Msg)
+	}
+}
+
 func TestWALWrite(t *testing.T) {
 	walDir, err := ioutil.TempDir("", "wal")
 	require.NoError(t, err)
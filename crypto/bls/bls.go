@@ -0,0 +1,274 @@
+// Package bls implements BLS signatures on the bn256 pairing curve, whose
+// signatures can be aggregated: the precommits of the validators with BLS keys
+// can be compressed into a single signature in the commits (see
+// types.AggregateCommit).
+//
+// The signatures are points of G1, hashed to with the message, and the public
+// keys points of G2. The signatures are aggregated with coefficients derived
+// from all the public keys, as in the BDN scheme, so rogue public keys can't
+// forge an aggregate signature, whether the messages are distinct or not.
+//
+// EXPERIMENTAL: bn256 has about 100 bits of security.
+package bls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+
+	amino "github.com/tendermint/go-amino"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	kbls "go.dedis.ch/kyber/v3/sign/bls"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+//-------------------------------------
+
+var _ crypto.PrivKey = PrivKeyBLS{}
+
+const (
+	PrivKeyAminoName = "tendermint/PrivKeyBLS"
+	PubKeyAminoName  = "tendermint/PubKeyBLS"
+
+	// SignatureSize is the size of a BLS signature, aggregated or not: a
+	// point of G1.
+	SignatureSize = 64
+)
+
+var suite = bn256.NewSuite()
+
+var cdc = amino.NewCodec()
+
+func init() {
+	cdc.RegisterInterface((*crypto.PubKey)(nil), nil)
+	cdc.RegisterConcrete(PubKeyBLS{},
+		PubKeyAminoName, nil)
+
+	cdc.RegisterInterface((*crypto.PrivKey)(nil), nil)
+	cdc.RegisterConcrete(PrivKeyBLS{},
+		PrivKeyAminoName, nil)
+}
+
+// PrivKeyBLSSize is the number of bytes in a BLS private key: a scalar.
+const PrivKeyBLSSize = 32
+
+// PrivKeyBLS implements crypto.PrivKey.
+type PrivKeyBLS [PrivKeyBLSSize]byte
+
+// Bytes marshals the privkey using amino encoding.
+func (privKey PrivKeyBLS) Bytes() []byte {
+	return cdc.MustMarshalBinaryBare(privKey)
+}
+
+func (privKey PrivKeyBLS) scalar() kyber.Scalar {
+	return suite.G2().Scalar().SetBytes(privKey[:])
+}
+
+// Sign produces a signature on the provided message.
+func (privKey PrivKeyBLS) Sign(msg []byte) ([]byte, error) {
+	return kbls.Sign(suite, privKey.scalar(), msg)
+}
+
+// PubKey gets the corresponding public key from the private key.
+func (privKey PrivKeyBLS) PubKey() crypto.PubKey {
+	bz, err := suite.G2().Point().Mul(privKey.scalar(), nil).MarshalBinary()
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal the BLS public key: %v", err))
+	}
+	var pubKey PubKeyBLS
+	copy(pubKey[:], bz)
+	return pubKey
+}
+
+// Equals - you probably don't need to use this.
+// Runs in constant time based on length of the keys.
+func (privKey PrivKeyBLS) Equals(other crypto.PrivKey) bool {
+	if otherBLS, ok := other.(PrivKeyBLS); ok {
+		return subtle.ConstantTimeCompare(privKey[:], otherBLS[:]) == 1
+	}
+	return false
+}
+
+// GenPrivKey generates a new BLS private key.
+// It uses OS randomness to generate the private key.
+func GenPrivKey() PrivKeyBLS {
+	return genPrivKey(crypto.CReader())
+}
+
+// genPrivKey generates a new BLS private key using the provided reader.
+func genPrivKey(rand io.Reader) PrivKeyBLS {
+	return privKeyFromScalar(suite.G2().Scalar().Pick(random.New(rand)))
+}
+
+// GenPrivKeyFromSecret hashes the secret with SHA2, and uses
+// that 32 byte output to create the private key.
+// NOTE: secret should be the output of a KDF like bcrypt,
+// if it's derived from user input.
+func GenPrivKeyFromSecret(secret []byte) PrivKeyBLS {
+	return privKeyFromScalar(suite.G2().Scalar().SetBytes(crypto.Sha256(secret)))
+}
+
+func privKeyFromScalar(s kyber.Scalar) PrivKeyBLS {
+	bz, err := s.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+	var privKey PrivKeyBLS
+	copy(privKey[PrivKeyBLSSize-len(bz):], bz)
+	return privKey
+}
+
+//-------------------------------------
+
+var _ crypto.PubKey = PubKeyBLS{}
+
+// PubKeyBLSSize is the number of bytes in a BLS public key: a point of G2.
+const PubKeyBLSSize = 128
+
+// PubKeyBLS implements crypto.PubKey for the BLS signature scheme.
+type PubKeyBLS [PubKeyBLSSize]byte
+
+// Address is the SHA256-20 of the raw pubkey bytes.
+func (pubKey PubKeyBLS) Address() crypto.Address {
+	return crypto.Address(tmhash.SumTruncated(pubKey[:]))
+}
+
+// Bytes marshals the PubKey using amino encoding.
+func (pubKey PubKeyBLS) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(pubKey)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (pubKey PubKeyBLS) point() (kyber.Point, error) {
+	p := suite.G2().Point()
+	if err := p.UnmarshalBinary(pubKey[:]); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (pubKey PubKeyBLS) VerifyBytes(msg []byte, sig []byte) bool {
+	if len(sig) != SignatureSize {
+		return false
+	}
+	p, err := pubKey.point()
+	if err != nil {
+		return false
+	}
+	return kbls.Verify(suite, p, msg, sig) == nil
+}
+
+func (pubKey PubKeyBLS) String() string {
+	return fmt.Sprintf("PubKeyBLS{%X}", pubKey[:])
+}
+
+// nolint: golint
+func (pubKey PubKeyBLS) Equals(other crypto.PubKey) bool {
+	if otherBLS, ok := other.(PubKeyBLS); ok {
+		return bytes.Equal(pubKey[:], otherBLS[:])
+	}
+	return false
+}
+
+//-------------------------------------
+// Aggregation
+
+// AggregateSignatures aggregates the signatures sigs by pubKeys, in the same
+// order, into a single signature. The signed messages may differ.
+func AggregateSignatures(pubKeys []PubKeyBLS, sigs [][]byte) ([]byte, error) {
+	if len(pubKeys) == 0 || len(pubKeys) != len(sigs) {
+		return nil, fmt.Errorf("want a signature per key, got %d keys and %d signatures", len(pubKeys), len(sigs))
+	}
+	coefs := coefficients(pubKeys)
+	agg := suite.G1().Point().Null()
+	for i, sig := range sigs {
+		if len(sig) != SignatureSize {
+			return nil, errors.New("invalid signature size")
+		}
+		s := suite.G1().Point()
+		if err := s.UnmarshalBinary(sig); err != nil {
+			return nil, err
+		}
+		agg = agg.Add(agg, s.Mul(coefs[i], s))
+	}
+	return agg.MarshalBinary()
+}
+
+// VerifyAggregateSignature returns true if sig aggregates the signatures of
+// msgs by pubKeys, in the same order (see AggregateSignatures). It computes a
+// pairing per distinct message.
+func VerifyAggregateSignature(pubKeys []PubKeyBLS, msgs [][]byte, sig []byte) bool {
+	if len(pubKeys) == 0 || len(pubKeys) != len(msgs) || len(sig) != SignatureSize {
+		return false
+	}
+	s := suite.G1().Point()
+	if err := s.UnmarshalBinary(sig); err != nil {
+		return false
+	}
+
+	// e(sig, G2) = prod e(H(m_i), c_i * pk_i), summing the keys by message
+	coefs := coefficients(pubKeys)
+	keys := make(map[string]kyber.Point, len(msgs))
+	for i, pubKey := range pubKeys {
+		p, err := pubKey.point()
+		if err != nil {
+			return false
+		}
+		p = p.Mul(coefs[i], p)
+		if k, ok := keys[string(msgs[i])]; ok {
+			k.Add(k, p)
+		} else {
+			keys[string(msgs[i])] = p
+		}
+	}
+	left := suite.GT().Point().Null()
+	for msg, k := range keys {
+		hm, err := hashToPoint([]byte(msg))
+		if err != nil {
+			return false
+		}
+		left = left.Add(left, suite.Pair(hm, k))
+	}
+	right := suite.Pair(s, suite.G2().Point().Base())
+	return left.Equal(right)
+}
+
+// coefficients returns the coefficients of the signatures of pubKeys in an
+// aggregate signature: the first 128 bits of SHA256(pk_i || SHA256(pk_1 ||
+// ... || pk_n)).
+func coefficients(pubKeys []PubKeyBLS) []kyber.Scalar {
+	h := sha256.New()
+	for _, pubKey := range pubKeys {
+		h.Write(pubKey[:]) // nolint: errcheck
+	}
+	all := h.Sum(nil)
+	coefs := make([]kyber.Scalar, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		sum := sha256.Sum256(append(pubKey[:], all...))
+		coefs[i] = suite.G2().Scalar().SetBytes(sum[:16])
+	}
+	return coefs
+}
+
+type hashablePoint interface {
+	Hash([]byte) kyber.Point
+}
+
+// hashToPoint hashes msg to G1, as the signatures.
+func hashToPoint(msg []byte) (kyber.Point, error) {
+	hashable, ok := suite.G1().Point().(hashablePoint)
+	if !ok {
+		return nil, errors.New("point needs to implement hashablePoint")
+	}
+	return hashable.Hash(msg), nil
+}
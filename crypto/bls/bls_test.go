@@ -0,0 +1,78 @@
+package bls_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/bls"
+)
+
+func TestSignAndValidateBLS(t *testing.T) {
+
+	privKey := bls.GenPrivKey()
+	pubKey := privKey.PubKey()
+
+	msg := crypto.CRandBytes(128)
+	sig, err := privKey.Sign(msg)
+	require.Nil(t, err)
+	assert.Len(t, sig, bls.SignatureSize)
+
+	// Test the signature
+	assert.True(t, pubKey.VerifyBytes(msg, sig))
+	assert.False(t, pubKey.VerifyBytes(crypto.CRandBytes(128), sig))
+	assert.False(t, bls.GenPrivKey().PubKey().VerifyBytes(msg, sig))
+
+	// Mutate the signature, just one bit.
+	sig[7] ^= byte(0x01)
+
+	assert.False(t, pubKey.VerifyBytes(msg, sig))
+}
+
+func TestGenPrivKeyFromSecret(t *testing.T) {
+	privKey := bls.GenPrivKeyFromSecret([]byte("secret"))
+	assert.True(t, privKey.Equals(bls.GenPrivKeyFromSecret([]byte("secret"))))
+	assert.False(t, privKey.Equals(bls.GenPrivKeyFromSecret([]byte("other"))))
+}
+
+func TestAggregateSignatures(t *testing.T) {
+	const n = 5
+	var (
+		pubKeys = make([]bls.PubKeyBLS, n)
+		msgs    = make([][]byte, n)
+		sigs    = make([][]byte, n)
+	)
+	for i := 0; i < n; i++ {
+		privKey := bls.GenPrivKey()
+		pubKeys[i] = privKey.PubKey().(bls.PubKeyBLS)
+		// the last two sign the same message
+		msgs[i] = []byte{byte(i)}
+		if i == n-1 {
+			msgs[i] = msgs[i-1]
+		}
+		var err error
+		sigs[i], err = privKey.Sign(msgs[i])
+		require.NoError(t, err)
+	}
+
+	agg, err := bls.AggregateSignatures(pubKeys, sigs)
+	require.NoError(t, err)
+	assert.Len(t, agg, bls.SignatureSize)
+	assert.True(t, bls.VerifyAggregateSignature(pubKeys, msgs, agg))
+
+	// a missing signer
+	assert.False(t, bls.VerifyAggregateSignature(pubKeys[1:], msgs[1:], agg))
+	// another message
+	msgs[0] = []byte("other")
+	assert.False(t, bls.VerifyAggregateSignature(pubKeys, msgs, agg))
+	msgs[0] = []byte{0}
+	// the keys in another order
+	pubKeys[0], pubKeys[1] = pubKeys[1], pubKeys[0]
+	msgs[0], msgs[1] = msgs[1], msgs[0]
+	assert.False(t, bls.VerifyAggregateSignature(pubKeys, msgs, agg))
+
+	_, err = bls.AggregateSignatures(pubKeys, sigs[1:])
+	assert.Error(t, err)
+}
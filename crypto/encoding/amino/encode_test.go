@@ -7,9 +7,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/bls"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	"github.com/tendermint/tendermint/crypto/multisig"
 	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/sr25519"
 )
 
 type byter interface {
@@ -77,6 +79,18 @@ func TestKeyEncodings(t *testing.T) {
 			pubSize:  38,
 			sigSize:  65,
 		},
+		{
+			privKey:  sr25519.GenPrivKey(),
+			privSize: 37,
+			pubSize:  37,
+			sigSize:  65,
+		},
+		{
+			privKey:  bls.GenPrivKey(),
+			privSize: 37,
+			pubSize:  134,
+			sigSize:  65,
+		},
 	}
 
 	for tcIndex, tc := range cases {
@@ -137,6 +151,8 @@ func TestPubkeyAminoName(t *testing.T) {
 	}{
 		{ed25519.PubKeyEd25519{}, ed25519.PubKeyAminoName, true},
 		{secp256k1.PubKeySecp256k1{}, secp256k1.PubKeyAminoName, true},
+		{sr25519.PubKeySr25519{}, sr25519.PubKeyAminoName, true},
+		{bls.PubKeyBLS{}, bls.PubKeyAminoName, true},
 		{multisig.PubKeyMultisigThreshold{}, multisig.PubKeyMultisigThresholdAminoRoute, true},
 	}
 	for i, tc := range tests {
@@ -0,0 +1,619 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	proto "github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ripemd160"
+	"golang.org/x/crypto/sha3"
+)
+
+// The types below are the messages of ICS-23, the proof format of IBC
+// (https://github.com/confio/ics23, proofs.proto, package ics23), with the
+// same field numbers, so that their protobuf encoding is the one of the other
+// ICS-23 implementations. Only the existence and non-existence proofs are
+// supported, not the batches.
+
+// HashOp is the hash function applied by a LeafOp or an InnerOp.
+type HashOp int32
+
+// nolint: golint
+const (
+	HashOp_NO_HASH   HashOp = 0
+	HashOp_SHA256    HashOp = 1
+	HashOp_SHA512    HashOp = 2
+	HashOp_KECCAK    HashOp = 3
+	HashOp_RIPEMD160 HashOp = 4
+	HashOp_BITCOIN   HashOp = 5 // ripemd160(sha256(x))
+)
+
+var hashOpNames = map[int32]string{
+	0: "NO_HASH",
+	1: "SHA256",
+	2: "SHA512",
+	3: "KECCAK",
+	4: "RIPEMD160",
+	5: "BITCOIN",
+}
+
+func (op HashOp) String() string {
+	return proto.EnumName(hashOpNames, int32(op))
+}
+
+// LengthOp is the length prefix of the key and the value of a LeafOp.
+type LengthOp int32
+
+// nolint: golint
+const (
+	LengthOp_NO_PREFIX        LengthOp = 0
+	LengthOp_VAR_PROTO        LengthOp = 1 // protobuf varint
+	LengthOp_VAR_RLP          LengthOp = 2 // not supported
+	LengthOp_FIXED32_BIG      LengthOp = 3
+	LengthOp_FIXED32_LITTLE   LengthOp = 4
+	LengthOp_FIXED64_BIG      LengthOp = 5
+	LengthOp_FIXED64_LITTLE   LengthOp = 6
+	LengthOp_REQUIRE_32_BYTES LengthOp = 7 // no prefix, but the length must be 32
+	LengthOp_REQUIRE_64_BYTES LengthOp = 8 // no prefix, but the length must be 64
+)
+
+var lengthOpNames = map[int32]string{
+	0: "NO_PREFIX",
+	1: "VAR_PROTO",
+	2: "VAR_RLP",
+	3: "FIXED32_BIG",
+	4: "FIXED32_LITTLE",
+	5: "FIXED64_BIG",
+	6: "FIXED64_LITTLE",
+	7: "REQUIRE_32_BYTES",
+	8: "REQUIRE_64_BYTES",
+}
+
+func (op LengthOp) String() string {
+	return proto.EnumName(lengthOpNames, int32(op))
+}
+
+// ExistenceProof proves that the key has the value in the tree of the root it
+// computes: the leaf of the key and value, hashed with Leaf, and then with the
+// inner nodes of the Path, from the leaf up to the root.
+type ExistenceProof struct {
+	Key   []byte     `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte     `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Leaf  *LeafOp    `protobuf:"bytes,3,opt,name=leaf,proto3" json:"leaf,omitempty"`
+	Path  []*InnerOp `protobuf:"bytes,4,rep,name=path,proto3" json:"path,omitempty"`
+}
+
+func (m *ExistenceProof) Reset()         { *m = ExistenceProof{} }
+func (m *ExistenceProof) String() string { return proto.CompactTextString(m) }
+func (*ExistenceProof) ProtoMessage()    {}
+
+// NonExistenceProof proves that the key isn't in the tree: Left and Right
+// prove the keys right below and above it, which are neighbours in the tree.
+// Left is nil if the key is below all the keys, and Right if it's above.
+type NonExistenceProof struct {
+	Key   []byte          `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Left  *ExistenceProof `protobuf:"bytes,2,opt,name=left,proto3" json:"left,omitempty"`
+	Right *ExistenceProof `protobuf:"bytes,3,opt,name=right,proto3" json:"right,omitempty"`
+}
+
+func (m *NonExistenceProof) Reset()         { *m = NonExistenceProof{} }
+func (m *NonExistenceProof) String() string { return proto.CompactTextString(m) }
+func (*NonExistenceProof) ProtoMessage()    {}
+
+// CommitmentProof is either an ExistenceProof or a NonExistenceProof.
+type CommitmentProof struct {
+	// Types that are valid to be assigned to Proof:
+	//	*CommitmentProof_Exist
+	//	*CommitmentProof_Nonexist
+	Proof isCommitmentProof_Proof `protobuf_oneof:"proof"`
+}
+
+func (m *CommitmentProof) Reset()         { *m = CommitmentProof{} }
+func (m *CommitmentProof) String() string { return proto.CompactTextString(m) }
+func (*CommitmentProof) ProtoMessage()    {}
+
+type isCommitmentProof_Proof interface { // nolint: golint
+	isCommitmentProof_Proof()
+}
+
+type CommitmentProof_Exist struct { // nolint: golint
+	Exist *ExistenceProof `protobuf:"bytes,1,opt,name=exist,proto3,oneof"`
+}
+
+type CommitmentProof_Nonexist struct { // nolint: golint
+	Nonexist *NonExistenceProof `protobuf:"bytes,2,opt,name=nonexist,proto3,oneof"`
+}
+
+func (*CommitmentProof_Exist) isCommitmentProof_Proof()    {}
+func (*CommitmentProof_Nonexist) isCommitmentProof_Proof() {}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*CommitmentProof) XXX_OneofWrappers() []interface{} { // nolint: golint
+	return []interface{}{
+		(*CommitmentProof_Exist)(nil),
+		(*CommitmentProof_Nonexist)(nil),
+	}
+}
+
+// GetExist returns the existence proof, or nil.
+func (m *CommitmentProof) GetExist() *ExistenceProof {
+	if x, ok := m.GetProof().(*CommitmentProof_Exist); ok {
+		return x.Exist
+	}
+	return nil
+}
+
+// GetNonexist returns the non-existence proof, or nil.
+func (m *CommitmentProof) GetNonexist() *NonExistenceProof {
+	if x, ok := m.GetProof().(*CommitmentProof_Nonexist); ok {
+		return x.Nonexist
+	}
+	return nil
+}
+
+func (m *CommitmentProof) GetProof() isCommitmentProof_Proof {
+	if m != nil {
+		return m.Proof
+	}
+	return nil
+}
+
+// LeafOp hashes a key and a value into a leaf:
+// Hash(Prefix || Length(PrehashKey(key)) || Length(PrehashValue(value))).
+type LeafOp struct {
+	Hash         HashOp   `protobuf:"varint,1,opt,name=hash,proto3,enum=ics23.HashOp" json:"hash,omitempty"`
+	PrehashKey   HashOp   `protobuf:"varint,2,opt,name=prehash_key,json=prehashKey,proto3,enum=ics23.HashOp" json:"prehash_key,omitempty"`
+	PrehashValue HashOp   `protobuf:"varint,3,opt,name=prehash_value,json=prehashValue,proto3,enum=ics23.HashOp" json:"prehash_value,omitempty"`
+	Length       LengthOp `protobuf:"varint,4,opt,name=length,proto3,enum=ics23.LengthOp" json:"length,omitempty"`
+	Prefix       []byte   `protobuf:"bytes,5,opt,name=prefix,proto3" json:"prefix,omitempty"`
+}
+
+func (m *LeafOp) Reset()         { *m = LeafOp{} }
+func (m *LeafOp) String() string { return proto.CompactTextString(m) }
+func (*LeafOp) ProtoMessage()    {}
+
+// InnerOp hashes a child into its parent: Hash(Prefix || child || Suffix),
+// where the prefix and the suffix hold the hashes of the other children.
+type InnerOp struct {
+	Hash   HashOp `protobuf:"varint,1,opt,name=hash,proto3,enum=ics23.HashOp" json:"hash,omitempty"`
+	Prefix []byte `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Suffix []byte `protobuf:"bytes,3,opt,name=suffix,proto3" json:"suffix,omitempty"`
+}
+
+func (m *InnerOp) Reset()         { *m = InnerOp{} }
+func (m *InnerOp) String() string { return proto.CompactTextString(m) }
+func (*InnerOp) ProtoMessage()    {}
+
+// ProofSpec is the shape of the proofs of a kind of tree: the proofs checked
+// against it can't be forged by taking a leaf for an inner node, or the
+// reverse.
+type ProofSpec struct {
+	LeafSpec  *LeafOp    `protobuf:"bytes,1,opt,name=leaf_spec,json=leafSpec,proto3" json:"leaf_spec,omitempty"`
+	InnerSpec *InnerSpec `protobuf:"bytes,2,opt,name=inner_spec,json=innerSpec,proto3" json:"inner_spec,omitempty"`
+	MaxDepth  int32      `protobuf:"varint,3,opt,name=max_depth,json=maxDepth,proto3" json:"max_depth,omitempty"` // 0 for no limit
+	MinDepth  int32      `protobuf:"varint,4,opt,name=min_depth,json=minDepth,proto3" json:"min_depth,omitempty"`
+}
+
+func (m *ProofSpec) Reset()         { *m = ProofSpec{} }
+func (m *ProofSpec) String() string { return proto.CompactTextString(m) }
+func (*ProofSpec) ProtoMessage()    {}
+
+// InnerSpec is the shape of the inner nodes of a tree: ChildOrder lists the
+// children in the order they're hashed (e.g. [0, 1] for a binary tree), and
+// each of them is a hash of ChildSize bytes. The prefix of the nodes, before
+// the children, is between MinPrefixLength and MaxPrefixLength bytes long.
+type InnerSpec struct {
+	ChildOrder      []int32 `protobuf:"varint,1,rep,packed,name=child_order,json=childOrder,proto3" json:"child_order,omitempty"`
+	ChildSize       int32   `protobuf:"varint,2,opt,name=child_size,json=childSize,proto3" json:"child_size,omitempty"`
+	MinPrefixLength int32   `protobuf:"varint,3,opt,name=min_prefix_length,json=minPrefixLength,proto3" json:"min_prefix_length,omitempty"`
+	MaxPrefixLength int32   `protobuf:"varint,4,opt,name=max_prefix_length,json=maxPrefixLength,proto3" json:"max_prefix_length,omitempty"`
+	EmptyChild      []byte  `protobuf:"bytes,5,opt,name=empty_child,json=emptyChild,proto3" json:"empty_child,omitempty"` // not supported
+	Hash            HashOp  `protobuf:"varint,6,opt,name=hash,proto3,enum=ics23.HashOp" json:"hash,omitempty"`
+}
+
+func (m *InnerSpec) Reset()         { *m = InnerSpec{} }
+func (m *InnerSpec) String() string { return proto.CompactTextString(m) }
+func (*InnerSpec) ProtoMessage()    {}
+
+func init() {
+	proto.RegisterEnum("ics23.HashOp", hashOpNames, enumValues(hashOpNames))
+	proto.RegisterEnum("ics23.LengthOp", lengthOpNames, enumValues(lengthOpNames))
+	proto.RegisterType((*ExistenceProof)(nil), "ics23.ExistenceProof")
+	proto.RegisterType((*NonExistenceProof)(nil), "ics23.NonExistenceProof")
+	proto.RegisterType((*CommitmentProof)(nil), "ics23.CommitmentProof")
+	proto.RegisterType((*LeafOp)(nil), "ics23.LeafOp")
+	proto.RegisterType((*InnerOp)(nil), "ics23.InnerOp")
+	proto.RegisterType((*ProofSpec)(nil), "ics23.ProofSpec")
+	proto.RegisterType((*InnerSpec)(nil), "ics23.InnerSpec")
+}
+
+func enumValues(names map[int32]string) map[string]int32 {
+	values := make(map[string]int32, len(names))
+	for v, name := range names {
+		values[name] = v
+	}
+	return values
+}
+
+// TendermintSpec is the ProofSpec of the simple Merkle trees of maps (see
+// SimpleProofsFromMap): the leaves are 0x00 || key || tmhash(value), both
+// length prefixed, and the inner nodes 0x01 || left || right, hashed with
+// tmhash (SHA256).
+var TendermintSpec = &ProofSpec{
+	LeafSpec: &LeafOp{
+		Prefix:       leafPrefix,
+		PrehashKey:   HashOp_NO_HASH,
+		PrehashValue: HashOp_SHA256,
+		Hash:         HashOp_SHA256,
+		Length:       LengthOp_VAR_PROTO,
+	},
+	InnerSpec: &InnerSpec{
+		ChildOrder:      []int32{0, 1},
+		MinPrefixLength: 1,
+		MaxPrefixLength: 1,
+		ChildSize:       32,
+		Hash:            HashOp_SHA256,
+	},
+}
+
+//----------------------------------------
+// Verification
+
+// Verify checks that the proof is of the shape of the spec, computes the root
+// hash and compares it with root, and checks that the proof is for the key
+// and the value.
+func (p *ExistenceProof) Verify(spec *ProofSpec, root, key, value []byte) error {
+	if err := p.checkAgainstSpec(spec); err != nil {
+		return err
+	}
+	if !bytes.Equal(p.Key, key) {
+		return errors.Errorf("proof is for key %X, not %X", p.Key, key)
+	}
+	if !bytes.Equal(p.Value, value) {
+		return errors.Errorf("proof is for value %X, not %X", p.Value, value)
+	}
+	calc, err := p.Calculate()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(calc, root) {
+		return errors.Errorf("calculated root hash is invalid: expected %X but got %X", root, calc)
+	}
+	return nil
+}
+
+// Calculate returns the root hash of the proof.
+func (p *ExistenceProof) Calculate() ([]byte, error) {
+	if p.Leaf == nil {
+		return nil, errors.New("existence proof must start with a leaf operation")
+	}
+	res, err := p.Leaf.Apply(p.Key, p.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, "leaf")
+	}
+	for i, step := range p.Path {
+		res, err = step.Apply(res)
+		if err != nil {
+			return nil, errors.Wrapf(err, "inner #%d", i)
+		}
+	}
+	return res, nil
+}
+
+func (p *ExistenceProof) checkAgainstSpec(spec *ProofSpec) error {
+	if p.Leaf == nil {
+		return errors.New("existence proof must start with a leaf operation")
+	}
+	if err := p.Leaf.checkAgainstSpec(spec); err != nil {
+		return errors.Wrap(err, "leaf spec")
+	}
+	if spec.MinDepth > 0 && len(p.Path) < int(spec.MinDepth) {
+		return errors.Errorf("inner path depth too short: %d", len(p.Path))
+	}
+	if spec.MaxDepth > 0 && len(p.Path) > int(spec.MaxDepth) {
+		return errors.Errorf("inner path depth too long: %d", len(p.Path))
+	}
+	for i, inner := range p.Path {
+		if err := inner.checkAgainstSpec(spec); err != nil {
+			return errors.Wrapf(err, "inner #%d", i)
+		}
+	}
+	return nil
+}
+
+// Verify checks that the proof is of the shape of the spec, that its
+// neighbours are in the tree of root, and that the key is between them, with
+// no other key in between.
+func (p *NonExistenceProof) Verify(spec *ProofSpec, root, key []byte) error {
+	if !bytes.Equal(p.Key, key) {
+		return errors.Errorf("proof is for key %X, not %X", p.Key, key)
+	}
+	if p.Left == nil && p.Right == nil {
+		return errors.New("both left and right proofs missing")
+	}
+	if p.Left != nil {
+		if err := p.Left.Verify(spec, root, p.Left.Key, p.Left.Value); err != nil {
+			return errors.Wrap(err, "left proof")
+		}
+		if bytes.Compare(key, p.Left.Key) <= 0 {
+			return errors.New("key is not right of the left proof")
+		}
+	}
+	if p.Right != nil {
+		if err := p.Right.Verify(spec, root, p.Right.Key, p.Right.Value); err != nil {
+			return errors.Wrap(err, "right proof")
+		}
+		if bytes.Compare(key, p.Right.Key) >= 0 {
+			return errors.New("key is not left of the right proof")
+		}
+	}
+
+	switch {
+	case p.Left == nil:
+		if !isLeftMost(spec.InnerSpec, p.Right.Path) {
+			return errors.New("left proof missing, right proof must be left-most")
+		}
+	case p.Right == nil:
+		if !isRightMost(spec.InnerSpec, p.Left.Path) {
+			return errors.New("right proof missing, left proof must be right-most")
+		}
+	default:
+		if !isLeftNeighbor(spec.InnerSpec, p.Left.Path, p.Right.Path) {
+			return errors.New("left proof and right proof are not neighbors")
+		}
+	}
+	return nil
+}
+
+// Apply returns the leaf of the key and the value.
+func (op *LeafOp) Apply(key, value []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errors.New("leaf op needs key")
+	}
+	if len(value) == 0 {
+		return nil, errors.New("leaf op needs value")
+	}
+	pkey, err := prepareLeafData(op.PrehashKey, op.Length, key)
+	if err != nil {
+		return nil, errors.Wrap(err, "prehash key")
+	}
+	pvalue, err := prepareLeafData(op.PrehashValue, op.Length, value)
+	if err != nil {
+		return nil, errors.Wrap(err, "prehash value")
+	}
+	data := append(append(append([]byte{}, op.Prefix...), pkey...), pvalue...)
+	return doHash(op.Hash, data)
+}
+
+func (op *LeafOp) checkAgainstSpec(spec *ProofSpec) error {
+	lspec := spec.LeafSpec
+	if lspec == nil {
+		return errors.New("spec without leaf spec")
+	}
+	if op.Hash != lspec.Hash {
+		return errors.Errorf("unexpected hash op: %v", op.Hash)
+	}
+	if op.PrehashKey != lspec.PrehashKey {
+		return errors.Errorf("unexpected prehash key op: %v", op.PrehashKey)
+	}
+	if op.PrehashValue != lspec.PrehashValue {
+		return errors.Errorf("unexpected prehash value op: %v", op.PrehashValue)
+	}
+	if op.Length != lspec.Length {
+		return errors.Errorf("unexpected length op: %v", op.Length)
+	}
+	if !bytes.HasPrefix(op.Prefix, lspec.Prefix) {
+		return errors.Errorf("leaf prefix %X doesn't start with %X", op.Prefix, lspec.Prefix)
+	}
+	return nil
+}
+
+// Apply returns the hash of the parent of the child.
+func (op *InnerOp) Apply(child []byte) ([]byte, error) {
+	if len(child) == 0 {
+		return nil, errors.New("inner op needs a child value")
+	}
+	data := append(append(append([]byte{}, op.Prefix...), child...), op.Suffix...)
+	return doHash(op.Hash, data)
+}
+
+func (op *InnerOp) checkAgainstSpec(spec *ProofSpec) error {
+	ispec := spec.InnerSpec
+	if ispec == nil {
+		return errors.New("spec without inner spec")
+	}
+	if op.Hash != ispec.Hash {
+		return errors.Errorf("unexpected hash op: %v", op.Hash)
+	}
+	// an inner node can't be taken for a leaf
+	if spec.LeafSpec != nil && len(spec.LeafSpec.Prefix) > 0 && bytes.HasPrefix(op.Prefix, spec.LeafSpec.Prefix) {
+		return errors.Errorf("inner prefix %X starts with the leaf prefix", op.Prefix)
+	}
+	if len(op.Prefix) < int(ispec.MinPrefixLength) {
+		return errors.Errorf("inner prefix too short: %d", len(op.Prefix))
+	}
+	maxLeftChildBytes := (len(ispec.ChildOrder) - 1) * int(ispec.ChildSize)
+	if len(op.Prefix) > int(ispec.MaxPrefixLength)+maxLeftChildBytes {
+		return errors.Errorf("inner prefix too long: %d", len(op.Prefix))
+	}
+	if ispec.ChildSize <= 0 || len(op.Suffix)%int(ispec.ChildSize) != 0 {
+		return errors.Errorf("inner suffix of %d bytes not a multiple of the child size", len(op.Suffix))
+	}
+	return nil
+}
+
+// isLeftMost returns true if the path is the one of the leftmost leaf: each
+// step has the padding of the first child.
+func isLeftMost(spec *InnerSpec, path []*InnerOp) bool {
+	minPrefix, maxPrefix, suffix := getPadding(spec, 0)
+	for _, step := range path {
+		if !hasPadding(step, minPrefix, maxPrefix, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// isRightMost returns true if the path is the one of the rightmost leaf: each
+// step has the padding of the last child.
+func isRightMost(spec *InnerSpec, path []*InnerOp) bool {
+	minPrefix, maxPrefix, suffix := getPadding(spec, int32(len(spec.ChildOrder)-1))
+	for _, step := range path {
+		if !hasPadding(step, minPrefix, maxPrefix, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLeftNeighbor returns true if the leaves of the paths are next to each
+// other: below their common ancestor, the left one is the rightmost leaf of a
+// child, and the right one the leftmost leaf of the next child.
+func isLeftNeighbor(spec *InnerSpec, left, right []*InnerOp) bool {
+	// skip the common ancestors, from the root down
+	l, r := len(left)-1, len(right)-1
+	for l >= 0 && r >= 0 &&
+		bytes.Equal(left[l].Prefix, right[r].Prefix) && bytes.Equal(left[l].Suffix, right[r].Suffix) {
+		l--
+		r--
+	}
+	if l < 0 || r < 0 {
+		return false
+	}
+	// the divergent nodes are the ones of the common ancestor
+	if !isLeftStep(spec, left[l], right[r]) {
+		return false
+	}
+	return isRightMost(spec, left[:l]) && isLeftMost(spec, right[:r])
+}
+
+// isLeftStep returns true if the children of the steps are next to each
+// other, the left one before the right one.
+func isLeftStep(spec *InnerSpec, left, right *InnerOp) bool {
+	leftIdx, err := orderFromPadding(spec, left)
+	if err != nil {
+		return false
+	}
+	rightIdx, err := orderFromPadding(spec, right)
+	if err != nil {
+		return false
+	}
+	return rightIdx == leftIdx+1
+}
+
+func hasPadding(op *InnerOp, minPrefix, maxPrefix, suffix int) bool {
+	return len(op.Prefix) >= minPrefix && len(op.Prefix) <= maxPrefix && len(op.Suffix) == suffix
+}
+
+// getPadding returns the lengths of the prefix and the suffix of a node whose
+// child is the branch.
+func getPadding(spec *InnerSpec, branch int32) (minPrefix, maxPrefix, suffix int) {
+	idx := -1
+	for i, b := range spec.ChildOrder {
+		if b == branch {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		panic(fmt.Sprintf("branch %d not in the child order %v", branch, spec.ChildOrder))
+	}
+	prefix := idx * int(spec.ChildSize)
+	minPrefix = prefix + int(spec.MinPrefixLength)
+	maxPrefix = prefix + int(spec.MaxPrefixLength)
+	suffix = (len(spec.ChildOrder) - 1 - idx) * int(spec.ChildSize)
+	return
+}
+
+// orderFromPadding returns the branch of the child of the step.
+func orderFromPadding(spec *InnerSpec, step *InnerOp) (int32, error) {
+	for branch := int32(0); branch < int32(len(spec.ChildOrder)); branch++ {
+		minPrefix, maxPrefix, suffix := getPadding(spec, branch)
+		if hasPadding(step, minPrefix, maxPrefix, suffix) {
+			return branch, nil
+		}
+	}
+	return 0, errors.New("cannot find any valid spacing for this node")
+}
+
+func prepareLeafData(hashOp HashOp, lengthOp LengthOp, data []byte) ([]byte, error) {
+	hashed, err := doHashOrNoop(hashOp, data)
+	if err != nil {
+		return nil, err
+	}
+	return doLengthOp(lengthOp, hashed)
+}
+
+func doHashOrNoop(hashOp HashOp, data []byte) ([]byte, error) {
+	if hashOp == HashOp_NO_HASH {
+		return data, nil
+	}
+	return doHash(hashOp, data)
+}
+
+func doHash(hashOp HashOp, data []byte) ([]byte, error) {
+	switch hashOp {
+	case HashOp_SHA256:
+		sum := sha256.Sum256(data)
+		return sum[:], nil
+	case HashOp_SHA512:
+		sum := sha512.Sum512(data)
+		return sum[:], nil
+	case HashOp_KECCAK:
+		hasher := sha3.NewLegacyKeccak256()
+		hasher.Write(data) // does not error
+		return hasher.Sum(nil), nil
+	case HashOp_RIPEMD160:
+		hasher := ripemd160.New()
+		hasher.Write(data) // does not error
+		return hasher.Sum(nil), nil
+	case HashOp_BITCOIN:
+		sum := sha256.Sum256(data)
+		hasher := ripemd160.New()
+		hasher.Write(sum[:]) // does not error
+		return hasher.Sum(nil), nil
+	}
+	return nil, errors.Errorf("unsupported hash op %v", hashOp)
+}
+
+func doLengthOp(lengthOp LengthOp, data []byte) ([]byte, error) {
+	switch lengthOp {
+	case LengthOp_NO_PREFIX:
+		return data, nil
+	case LengthOp_VAR_PROTO:
+		buf := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(buf, uint64(len(data)))
+		return append(buf[:n], data...), nil
+	case LengthOp_FIXED32_BIG:
+		buf := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(buf, uint32(len(data)))
+		return append(buf, data...), nil
+	case LengthOp_FIXED32_LITTLE:
+		buf := make([]byte, 4, 4+len(data))
+		binary.LittleEndian.PutUint32(buf, uint32(len(data)))
+		return append(buf, data...), nil
+	case LengthOp_FIXED64_BIG:
+		buf := make([]byte, 8, 8+len(data))
+		binary.BigEndian.PutUint64(buf, uint64(len(data)))
+		return append(buf, data...), nil
+	case LengthOp_FIXED64_LITTLE:
+		buf := make([]byte, 8, 8+len(data))
+		binary.LittleEndian.PutUint64(buf, uint64(len(data)))
+		return append(buf, data...), nil
+	case LengthOp_REQUIRE_32_BYTES:
+		if len(data) != 32 {
+			return nil, errors.Errorf("data was %d bytes, not 32", len(data))
+		}
+		return data, nil
+	case LengthOp_REQUIRE_64_BYTES:
+		if len(data) != 64 {
+			return nil, errors.Errorf("data was %d bytes, not 64", len(data))
+		}
+		return data, nil
+	}
+	return nil, errors.Errorf("unsupported length op %v", lengthOp)
+}
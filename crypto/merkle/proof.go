@@ -89,6 +89,13 @@ func (prt *ProofRuntime) RegisterOpDecoder(typ string, dec OpDecoder) {
 	prt.decoders[typ] = dec
 }
 
+// RegisterICS23Spec registers the decoder of the ProofOps of the type as
+// ICS-23 proofs checked against the spec, e.g. the one of the trees of an app
+// (see ICS23Op).
+func (prt *ProofRuntime) RegisterICS23Spec(typ string, spec *ProofSpec) {
+	prt.RegisterOpDecoder(typ, ICS23OpDecoder(typ, spec))
+}
+
 func (prt *ProofRuntime) Decode(pop ProofOp) (ProofOperator, error) {
 	decoder := prt.decoders[pop.Type]
 	if decoder == nil {
@@ -128,11 +135,12 @@ func (prt *ProofRuntime) Verify(proof *Proof, root []byte, keypath string, args
 }
 
 // DefaultProofRuntime only knows about Simple value
-// proofs.
+// proofs, and their ICS-23 form.
 // To use e.g. IAVL proofs, register op-decoders as
-// defined in the IAVL package.
+// defined in the IAVL package, or the ICS-23 spec of the tree.
 func DefaultProofRuntime() (prt *ProofRuntime) {
 	prt = NewProofRuntime()
 	prt.RegisterOpDecoder(ProofOpSimpleValue, SimpleValueOpDecoder)
+	prt.RegisterICS23Spec(ProofOpICS23Simple, TendermintSpec)
 	return
 }
@@ -0,0 +1,207 @@
+package merkle
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	proto "github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// ProofOpICS23Simple is the type of the ICS23Ops of the simple Merkle trees of
+// maps, checked against TendermintSpec.
+const ProofOpICS23Simple = "ics23:simple"
+
+// ICS23Op is a ProofOperator of an ICS-23 proof, checked against a ProofSpec.
+// It takes the value of the key and produces the root hash for an existence
+// proof, and takes no value for a non-existence proof (see
+// ProofRuntime#VerifyAbsence).
+//
+// The proof is encoded in ProofOp.Data in protobuf, as by the other ICS-23
+// implementations, so that the proofs of ABCI queries can be checked by IBC.
+type ICS23Op struct {
+	// Encoded in ProofOp.Type and ProofOp.Key.
+	typ string
+	key []byte
+
+	spec *ProofSpec
+
+	// To encode in ProofOp.Data
+	Proof *CommitmentProof
+}
+
+var _ ProofOperator = ICS23Op{}
+
+func NewICS23Op(typ string, spec *ProofSpec, key []byte, proof *CommitmentProof) ICS23Op {
+	return ICS23Op{
+		typ:   typ,
+		key:   key,
+		spec:  spec,
+		Proof: proof,
+	}
+}
+
+// ICS23OpDecoder returns the OpDecoder of the ICS23Ops of the type, checked
+// against the spec. See ProofRuntime#RegisterICS23Spec.
+func ICS23OpDecoder(typ string, spec *ProofSpec) OpDecoder {
+	return func(pop ProofOp) (ProofOperator, error) {
+		if pop.Type != typ {
+			return nil, errors.Errorf("unexpected ProofOp.Type; got %v, want %v", pop.Type, typ)
+		}
+		proof := new(CommitmentProof)
+		if err := proto.Unmarshal(pop.Data, proof); err != nil {
+			return nil, errors.Wrap(err, "decoding ProofOp.Data into CommitmentProof")
+		}
+		return NewICS23Op(typ, spec, pop.Key, proof), nil
+	}
+}
+
+func (op ICS23Op) ProofOp() ProofOp {
+	bz, err := proto.Marshal(op.Proof)
+	if err != nil {
+		panic(err)
+	}
+	return ProofOp{
+		Type: op.typ,
+		Key:  op.key,
+		Data: bz,
+	}
+}
+
+func (op ICS23Op) String() string {
+	return fmt.Sprintf("ICS23Op{%v %v}", op.typ, op.GetKey())
+}
+
+func (op ICS23Op) Run(args [][]byte) ([][]byte, error) {
+	switch len(args) {
+	case 0:
+		nonexist := op.Proof.GetNonexist()
+		if nonexist == nil {
+			return nil, errors.New("expected a non-existence proof")
+		}
+		existing := nonexist.Left
+		if existing == nil {
+			existing = nonexist.Right
+		}
+		if existing == nil {
+			return nil, errors.New("both left and right proofs missing")
+		}
+		root, err := existing.Calculate()
+		if err != nil {
+			return nil, errors.Wrap(err, "computing the root hash")
+		}
+		if err := nonexist.Verify(op.spec, root, op.key); err != nil {
+			return nil, err
+		}
+		return [][]byte{root}, nil
+
+	case 1:
+		exist := op.Proof.GetExist()
+		if exist == nil {
+			return nil, errors.New("expected an existence proof")
+		}
+		root, err := exist.Calculate()
+		if err != nil {
+			return nil, errors.Wrap(err, "computing the root hash")
+		}
+		if err := exist.Verify(op.spec, root, op.key, args[0]); err != nil {
+			return nil, err
+		}
+		return [][]byte{root}, nil
+
+	default:
+		return nil, errors.Errorf("expected 0 or 1 arg, got %v", len(args))
+	}
+}
+
+func (op ICS23Op) GetKey() []byte {
+	return op.key
+}
+
+//----------------------------------------
+
+// ICS23ProofFromMap returns the root hash of the simple Merkle tree of the map
+// (see SimpleHashFromMap), and the ICS-23 proof of the key in it, checked
+// against TendermintSpec: an existence proof if the map has the key, and a
+// non-existence proof otherwise.
+//
+// NOTE: ICS-23 has no proofs of empty values: the values of the key and of
+// its neighbours must not be empty.
+func ICS23ProofFromMap(m map[string][]byte, key string) (rootHash []byte, proof *CommitmentProof, err error) {
+	if len(m) == 0 {
+		return nil, nil, errors.New("no proof in an empty map")
+	}
+	sm := newSimpleMap()
+	for k, v := range m {
+		sm.Set(k, v)
+	}
+	kvs := sm.KVPairs()
+	kvsBytes := make([][]byte, len(kvs))
+	for i, kvp := range kvs {
+		kvsBytes[i] = KVPair(kvp).Bytes()
+	}
+	rootHash, simpleProofs := SimpleProofsFromByteSlices(kvsBytes)
+
+	existenceProof := func(i int) (*ExistenceProof, error) {
+		k := kvs[i].Key
+		value := m[string(k)]
+		if len(value) == 0 {
+			return nil, errors.Errorf("empty value of key %X", k)
+		}
+		leaf := *TendermintSpec.LeafSpec
+		sp := simpleProofs[i]
+		return &ExistenceProof{
+			Key:   k,
+			Value: value,
+			Leaf:  &leaf,
+			Path:  innerOpsFromAunts(sp.Index, sp.Total, sp.Aunts),
+		}, nil
+	}
+
+	idx := sort.Search(len(kvs), func(i int) bool {
+		return bytes.Compare(kvs[i].Key, []byte(key)) >= 0
+	})
+	if idx < len(kvs) && bytes.Equal(kvs[idx].Key, []byte(key)) {
+		exist, err := existenceProof(idx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rootHash, &CommitmentProof{Proof: &CommitmentProof_Exist{Exist: exist}}, nil
+	}
+
+	nonexist := &NonExistenceProof{Key: []byte(key)}
+	if idx > 0 {
+		if nonexist.Left, err = existenceProof(idx - 1); err != nil {
+			return nil, nil, err
+		}
+	}
+	if idx < len(kvs) {
+		if nonexist.Right, err = existenceProof(idx); err != nil {
+			return nil, nil, err
+		}
+	}
+	return rootHash, &CommitmentProof{Proof: &CommitmentProof_Nonexist{Nonexist: nonexist}}, nil
+}
+
+// innerOpsFromAunts returns the path of the leaf at index in a simple Merkle
+// tree of total leaves, from the leaf up to the root (see
+// computeHashFromAunts).
+func innerOpsFromAunts(index int, total int, aunts [][]byte) []*InnerOp {
+	if total <= 1 || len(aunts) == 0 {
+		return nil
+	}
+	aunt := aunts[len(aunts)-1]
+	numLeft := getSplitPoint(total)
+	if index < numLeft {
+		return append(innerOpsFromAunts(index, numLeft, aunts[:len(aunts)-1]), &InnerOp{
+			Hash:   HashOp_SHA256,
+			Prefix: append([]byte{}, innerPrefix...),
+			Suffix: aunt,
+		})
+	}
+	return append(innerOpsFromAunts(index-numLeft, total-numLeft, aunts[:len(aunts)-1]), &InnerOp{
+		Hash:   HashOp_SHA256,
+		Prefix: append(append([]byte{}, innerPrefix...), aunt...),
+	})
+}
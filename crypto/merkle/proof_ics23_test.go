@@ -0,0 +1,177 @@
+package merkle
+
+import (
+	"fmt"
+	"testing"
+
+	proto "github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ics23TestMap(n int) map[string][]byte {
+	m := make(map[string][]byte, n)
+	for i := 0; i < n; i++ {
+		// even keys only, to prove the absence of the odd ones
+		m[fmt.Sprintf("key%02d", 2*i)] = []byte(fmt.Sprintf("value%d", i))
+	}
+	return m
+}
+
+func ics23KeyPath(key string) string {
+	return KeyPath{}.AppendKey([]byte(key), KeyEncodingURL).String()
+}
+
+func TestICS23ExistenceProof(t *testing.T) {
+	prt := DefaultProofRuntime()
+
+	// trees of 1 to 7 leaves, balanced or not
+	for n := 1; n <= 7; n++ {
+		m := ics23TestMap(n)
+		for key, value := range m {
+			root, proof, err := ICS23ProofFromMap(m, key)
+			require.NoError(t, err, key)
+			require.Equal(t, SimpleHashFromMap(m), root)
+			require.NotNil(t, proof.GetExist(), key)
+
+			// as is, and through the proof runtime
+			require.NoError(t, proof.GetExist().Verify(TendermintSpec, root, []byte(key), value), key)
+			op := NewICS23Op(ProofOpICS23Simple, TendermintSpec, []byte(key), proof)
+			merkleProof := &Proof{Ops: []ProofOp{op.ProofOp()}}
+			assert.NoError(t, prt.VerifyValue(merkleProof, root, ics23KeyPath(key), value), key)
+
+			assert.Error(t, prt.VerifyValue(merkleProof, root, ics23KeyPath(key), []byte("other")), key)
+			assert.Error(t, prt.VerifyValue(merkleProof, []byte("other root"), ics23KeyPath(key), value), key)
+			assert.Error(t, prt.VerifyAbsence(merkleProof, root, ics23KeyPath(key)), key)
+		}
+	}
+}
+
+func TestICS23NonExistenceProof(t *testing.T) {
+	prt := DefaultProofRuntime()
+
+	for n := 1; n <= 7; n++ {
+		m := ics23TestMap(n)
+		// below all the keys, between each of them, and above
+		for i := -1; i < 2*n; i += 2 {
+			key := fmt.Sprintf("key%02d", i)
+			if i < 0 {
+				key = "a"
+			}
+			root, proof, err := ICS23ProofFromMap(m, key)
+			require.NoError(t, err, key)
+			require.NotNil(t, proof.GetNonexist(), key)
+
+			require.NoError(t, proof.GetNonexist().Verify(TendermintSpec, root, []byte(key)), key)
+			op := NewICS23Op(ProofOpICS23Simple, TendermintSpec, []byte(key), proof)
+			merkleProof := &Proof{Ops: []ProofOp{op.ProofOp()}}
+			assert.NoError(t, prt.VerifyAbsence(merkleProof, root, ics23KeyPath(key)), key)
+
+			assert.Error(t, prt.VerifyAbsence(merkleProof, []byte("other root"), ics23KeyPath(key)), key)
+			assert.Error(t, prt.VerifyValue(merkleProof, root, ics23KeyPath(key), []byte("value")), key)
+		}
+	}
+}
+
+func TestICS23NonExistenceProofNotNeighbors(t *testing.T) {
+	m := ics23TestMap(5)
+	root, left, err := ICS23ProofFromMap(m, "key02")
+	require.NoError(t, err)
+	_, right, err := ICS23ProofFromMap(m, "key06")
+	require.NoError(t, err)
+
+	// key04 is between them
+	nonexist := &NonExistenceProof{
+		Key:   []byte("key05"),
+		Left:  left.GetExist(),
+		Right: right.GetExist(),
+	}
+	assert.Error(t, nonexist.Verify(TendermintSpec, root, []byte("key05")))
+
+	// and isn't the leftmost key
+	nonexist = &NonExistenceProof{
+		Key:   []byte("key01"),
+		Right: right.GetExist(),
+	}
+	assert.Error(t, nonexist.Verify(TendermintSpec, root, []byte("key01")))
+}
+
+func TestICS23ProofAgainstSpec(t *testing.T) {
+	m := ics23TestMap(4)
+	root, proof, err := ICS23ProofFromMap(m, "key02")
+	require.NoError(t, err)
+	exist := proof.GetExist()
+
+	// an inner node taken for a leaf
+	forged := &ExistenceProof{
+		Key:   exist.Key,
+		Value: exist.Value,
+		Leaf:  exist.Leaf,
+		Path:  exist.Path[:1],
+	}
+	forged.Leaf = &LeafOp{Hash: HashOp_SHA256, Length: LengthOp_VAR_PROTO, Prefix: innerPrefix}
+	assert.Error(t, forged.Verify(TendermintSpec, root, exist.Key, exist.Value))
+
+	// the same proof with another hash
+	spec := *TendermintSpec
+	spec.InnerSpec = &InnerSpec{ChildOrder: []int32{0, 1}, MinPrefixLength: 1, MaxPrefixLength: 1, ChildSize: 32,
+		Hash: HashOp_SHA512}
+	assert.Error(t, exist.Verify(&spec, root, exist.Key, exist.Value))
+
+	// too deep
+	spec = *TendermintSpec
+	spec.MaxDepth = 1
+	assert.Error(t, exist.Verify(&spec, root, exist.Key, exist.Value))
+}
+
+func TestICS23ProofEncoding(t *testing.T) {
+	m := ics23TestMap(3)
+	for _, key := range []string{"key02", "key03"} {
+		_, proof, err := ICS23ProofFromMap(m, key)
+		require.NoError(t, err)
+		bz, err := proto.Marshal(proof)
+		require.NoError(t, err)
+		decoded := new(CommitmentProof)
+		require.NoError(t, proto.Unmarshal(bz, decoded))
+		assert.True(t, proto.Equal(proof, decoded), key)
+	}
+
+	// the encoding of ICS-23: CommitmentProof.exist (1), with the key (1),
+	// the value (2) and the leaf (3)
+	proof := &CommitmentProof{Proof: &CommitmentProof_Exist{Exist: &ExistenceProof{
+		Key:   []byte("k"),
+		Value: []byte("v"),
+		Leaf:  TendermintSpec.LeafSpec,
+	}}}
+	bz, err := proto.Marshal(proof)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x0a, 0x11,
+		0x0a, 0x01, 'k',
+		0x12, 0x01, 'v',
+		0x1a, 0x09, 0x08, 0x01, 0x18, 0x01, 0x20, 0x01, 0x2a, 0x01, 0x00,
+	}, bz)
+}
+
+func TestICS23ProofFromMapErrors(t *testing.T) {
+	_, _, err := ICS23ProofFromMap(map[string][]byte{}, "key")
+	assert.Error(t, err)
+	_, _, err = ICS23ProofFromMap(map[string][]byte{"key": {}}, "key")
+	assert.Error(t, err)
+}
+
+func TestProofRuntimeRegisterICS23Spec(t *testing.T) {
+	prt := NewProofRuntime()
+	prt.RegisterICS23Spec("ics23:test", TendermintSpec)
+	assert.Panics(t, func() { prt.RegisterICS23Spec("ics23:test", TendermintSpec) })
+
+	m := ics23TestMap(3)
+	root, proof, err := ICS23ProofFromMap(m, "key00")
+	require.NoError(t, err)
+	op := NewICS23Op("ics23:test", TendermintSpec, []byte("key00"), proof)
+	assert.NoError(t, prt.VerifyValue(&Proof{Ops: []ProofOp{op.ProofOp()}}, root, ics23KeyPath("key00"), m["key00"]))
+
+	// the simple ICS-23 proofs aren't registered in this runtime
+	op = NewICS23Op(ProofOpICS23Simple, TendermintSpec, []byte("key00"), proof)
+	assert.Error(t, prt.VerifyValue(&Proof{Ops: []ProofOp{op.ProofOp()}}, root, ics23KeyPath("key00"), m["key00"]))
+}
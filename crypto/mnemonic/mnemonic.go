@@ -0,0 +1,91 @@
+// Package mnemonic derives the keys of a node from a BIP39 mnemonic, so an
+// operator can recover the node and validator keys from the words written
+// down, without a backup of the key files.
+//
+// The keys are derived from the BIP39 seed of the mnemonic (without
+// passphrase), separately for each purpose:
+//
+//	secret = HMAC-SHA256(key: "tendermint/" + purpose, msg: seed)
+//
+// and secret is passed to ed25519.GenPrivKeyFromSecret,
+// secp256k1.GenPrivKeySecp256k1, sr25519.GenPrivKeyFromSecret or
+// bls.GenPrivKeyFromSecret. It's not a BIP32/BIP44 derivation: the keys
+// don't match the ones of a wallet using the same mnemonic.
+package mnemonic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	bip39 "github.com/cosmos/go-bip39"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/bls"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/sr25519"
+)
+
+const (
+	// EntropyBits is the entropy of the generated mnemonics: 24 words.
+	EntropyBits = 256
+
+	// KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeSr25519 and KeyTypeBLS are the
+	// key types which can be derived.
+	KeyTypeEd25519   = "ed25519"
+	KeyTypeSecp256k1 = "secp256k1"
+	KeyTypeSr25519   = "sr25519"
+	KeyTypeBLS       = "bls"
+
+	// PurposeNodeKey and PurposeValidatorKey separate the keys derived from
+	// the same mnemonic.
+	PurposeNodeKey      = "node_key"
+	PurposeValidatorKey = "validator_key"
+)
+
+// New generates a new mnemonic from OS randomness.
+func New() (string, error) {
+	entropy, err := bip39.NewEntropy(EntropyBits)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// Normalize returns the mnemonic with its words separated by single spaces.
+func Normalize(mnemonic string) string {
+	return strings.Join(strings.Fields(mnemonic), " ")
+}
+
+// PrivKey derives the private key of the type keyType (KeyTypeEd25519,
+// KeyTypeSecp256k1, KeyTypeSr25519 or KeyTypeBLS) for purpose from the
+// mnemonic. The checksum of the mnemonic is verified, so a mistyped word is
+// detected.
+func PrivKey(mnemonic, keyType, purpose string) (crypto.PrivKey, error) {
+	if purpose == "" {
+		return nil, fmt.Errorf("empty purpose")
+	}
+	seed, err := bip39.NewSeedWithErrorChecking(Normalize(mnemonic), "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic: %v", err)
+	}
+	mac := hmac.New(sha256.New, []byte("tendermint/"+purpose))
+	mac.Write(seed) // nolint: errcheck
+	secret := mac.Sum(nil)
+
+	switch keyType {
+	case KeyTypeEd25519:
+		return ed25519.GenPrivKeyFromSecret(secret), nil
+	case KeyTypeSecp256k1:
+		return secp256k1.GenPrivKeySecp256k1(secret), nil
+	case KeyTypeSr25519:
+		return sr25519.GenPrivKeyFromSecret(secret), nil
+	case KeyTypeBLS:
+		return bls.GenPrivKeyFromSecret(secret), nil
+	default:
+		return nil, fmt.Errorf("unknown key type %q, want %s, %s, %s or %s",
+			keyType, KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeSr25519, KeyTypeBLS)
+	}
+}
@@ -0,0 +1,70 @@
+package mnemonic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/bls"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/sr25519"
+)
+
+func TestPrivKey(t *testing.T) {
+	mnemonic, err := New()
+	require.NoError(t, err)
+	assert.Len(t, strings.Fields(mnemonic), 24)
+
+	nodeKey, err := PrivKey(mnemonic, KeyTypeEd25519, PurposeNodeKey)
+	require.NoError(t, err)
+	assert.IsType(t, ed25519.PrivKeyEd25519{}, nodeKey)
+
+	// deterministic, whatever the whitespace
+	again, err := PrivKey("  "+strings.Replace(mnemonic, " ", "\n ", -1)+"\n", KeyTypeEd25519, PurposeNodeKey)
+	require.NoError(t, err)
+	assert.True(t, nodeKey.Equals(again))
+
+	// separated by purpose
+	valKey, err := PrivKey(mnemonic, KeyTypeEd25519, PurposeValidatorKey)
+	require.NoError(t, err)
+	assert.False(t, nodeKey.Equals(valKey))
+
+	secpKey, err := PrivKey(mnemonic, KeyTypeSecp256k1, PurposeValidatorKey)
+	require.NoError(t, err)
+	assert.IsType(t, secp256k1.PrivKeySecp256k1{}, secpKey)
+
+	srKey, err := PrivKey(mnemonic, KeyTypeSr25519, PurposeValidatorKey)
+	require.NoError(t, err)
+	assert.IsType(t, sr25519.PrivKeySr25519{}, srKey)
+
+	blsKey, err := PrivKey(mnemonic, KeyTypeBLS, PurposeValidatorKey)
+	require.NoError(t, err)
+	assert.IsType(t, bls.PrivKeyBLS{}, blsKey)
+
+	// another mnemonic
+	other, err := New()
+	require.NoError(t, err)
+	otherKey, err := PrivKey(other, KeyTypeEd25519, PurposeNodeKey)
+	require.NoError(t, err)
+	assert.False(t, nodeKey.Equals(otherKey))
+}
+
+func TestPrivKeyErrors(t *testing.T) {
+	// a BIP39 test vector (zero entropy)
+	mnemonic := strings.Repeat("abandon ", 11) + "about"
+	_, err := PrivKey(mnemonic, KeyTypeEd25519, PurposeNodeKey)
+	require.NoError(t, err)
+
+	// a mistyped word breaks the checksum
+	_, err = PrivKey(strings.Repeat("abandon ", 12), KeyTypeEd25519, PurposeNodeKey)
+	assert.Error(t, err)
+	_, err = PrivKey("not a mnemonic", KeyTypeEd25519, PurposeNodeKey)
+	assert.Error(t, err)
+	_, err = PrivKey(mnemonic, "bls12-381", PurposeNodeKey)
+	assert.Error(t, err)
+	_, err = PrivKey(mnemonic, KeyTypeEd25519, "")
+	assert.Error(t, err)
+}
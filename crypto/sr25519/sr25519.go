@@ -0,0 +1,172 @@
+// Package sr25519 implements the Schnorr signatures over Ristretto25519 of
+// the schnorrkel library (used by Substrate/Polkadot), so validators can reuse
+// their sr25519 keys and hardware.
+package sr25519
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"io"
+
+	schnorrkel "github.com/ChainSafe/go-schnorrkel"
+	amino "github.com/tendermint/go-amino"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+//-------------------------------------
+
+var _ crypto.PrivKey = PrivKeySr25519{}
+
+const (
+	PrivKeyAminoName = "tendermint/PrivKeySr25519"
+	PubKeyAminoName  = "tendermint/PubKeySr25519"
+
+	// SignatureSize is the size of an sr25519 signature.
+	SignatureSize = 64
+)
+
+// signingContext is the schnorrkel signing context of the signatures.
+var signingContext = []byte{}
+
+var cdc = amino.NewCodec()
+
+func init() {
+	cdc.RegisterInterface((*crypto.PubKey)(nil), nil)
+	cdc.RegisterConcrete(PubKeySr25519{},
+		PubKeyAminoName, nil)
+
+	cdc.RegisterInterface((*crypto.PrivKey)(nil), nil)
+	cdc.RegisterConcrete(PrivKeySr25519{},
+		PrivKeyAminoName, nil)
+}
+
+// PrivKeySr25519Size is the number of bytes in an sr25519 private key: the
+// schnorrkel mini secret key, expanded Ed25519-style when used.
+const PrivKeySr25519Size = 32
+
+// PrivKeySr25519 implements crypto.PrivKey.
+type PrivKeySr25519 [PrivKeySr25519Size]byte
+
+// Bytes marshals the privkey using amino encoding.
+func (privKey PrivKeySr25519) Bytes() []byte {
+	return cdc.MustMarshalBinaryBare(privKey)
+}
+
+// Sign produces a signature on the provided message.
+func (privKey PrivKeySr25519) Sign(msg []byte) ([]byte, error) {
+	miniSecretKey, err := schnorrkel.NewMiniSecretKeyFromRaw(privKey)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := miniSecretKey.ExpandEd25519().Sign(schnorrkel.NewSigningContext(signingContext, msg))
+	if err != nil {
+		return nil, err
+	}
+	sigBytes := sig.Encode()
+	return sigBytes[:], nil
+}
+
+// PubKey gets the corresponding public key from the private key.
+func (privKey PrivKeySr25519) PubKey() crypto.PubKey {
+	miniSecretKey, err := schnorrkel.NewMiniSecretKeyFromRaw(privKey)
+	if err != nil {
+		panic(fmt.Sprintf("invalid sr25519 private key: %v", err))
+	}
+	pubKey, err := miniSecretKey.ExpandEd25519().Public()
+	if err != nil {
+		panic(fmt.Sprintf("failed to compute the sr25519 public key: %v", err))
+	}
+	return PubKeySr25519(pubKey.Encode())
+}
+
+// Equals - you probably don't need to use this.
+// Runs in constant time based on length of the keys.
+func (privKey PrivKeySr25519) Equals(other crypto.PrivKey) bool {
+	if otherSr, ok := other.(PrivKeySr25519); ok {
+		return subtle.ConstantTimeCompare(privKey[:], otherSr[:]) == 1
+	}
+	return false
+}
+
+// GenPrivKey generates a new sr25519 private key.
+// It uses OS randomness to generate the private key.
+func GenPrivKey() PrivKeySr25519 {
+	return genPrivKey(crypto.CReader())
+}
+
+// genPrivKey generates a new sr25519 private key using the provided reader.
+func genPrivKey(rand io.Reader) PrivKeySr25519 {
+	var privKey PrivKeySr25519
+	if _, err := io.ReadFull(rand, privKey[:]); err != nil {
+		panic(err)
+	}
+	return privKey
+}
+
+// GenPrivKeyFromSecret hashes the secret with SHA2, and uses
+// that 32 byte output to create the private key.
+// NOTE: secret should be the output of a KDF like bcrypt,
+// if it's derived from user input.
+func GenPrivKeyFromSecret(secret []byte) PrivKeySr25519 {
+	var privKey PrivKeySr25519
+	copy(privKey[:], crypto.Sha256(secret))
+	return privKey
+}
+
+//-------------------------------------
+
+var _ crypto.PubKey = PubKeySr25519{}
+
+// PubKeySr25519Size is the number of bytes in an sr25519 public key.
+const PubKeySr25519Size = 32
+
+// PubKeySr25519 implements crypto.PubKey for the sr25519 signature scheme.
+type PubKeySr25519 [PubKeySr25519Size]byte
+
+// Address is the SHA256-20 of the raw pubkey bytes.
+func (pubKey PubKeySr25519) Address() crypto.Address {
+	return crypto.Address(tmhash.SumTruncated(pubKey[:]))
+}
+
+// Bytes marshals the PubKey using amino encoding.
+func (pubKey PubKeySr25519) Bytes() []byte {
+	bz, err := cdc.MarshalBinaryBare(pubKey)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func (pubKey PubKeySr25519) VerifyBytes(msg []byte, sig []byte) bool {
+	// make sure we use the same algorithm to sign
+	if len(sig) != SignatureSize {
+		return false
+	}
+	var sig64 [SignatureSize]byte
+	copy(sig64[:], sig)
+
+	publicKey := new(schnorrkel.PublicKey)
+	if err := publicKey.Decode(pubKey); err != nil {
+		return false
+	}
+	signature := new(schnorrkel.Signature)
+	if err := signature.Decode(sig64); err != nil {
+		return false
+	}
+	return publicKey.Verify(signature, schnorrkel.NewSigningContext(signingContext, msg))
+}
+
+func (pubKey PubKeySr25519) String() string {
+	return fmt.Sprintf("PubKeySr25519{%X}", pubKey[:])
+}
+
+// nolint: golint
+func (pubKey PubKeySr25519) Equals(other crypto.PubKey) bool {
+	if otherSr, ok := other.(PubKeySr25519); ok {
+		return bytes.Equal(pubKey[:], otherSr[:])
+	}
+	return false
+}
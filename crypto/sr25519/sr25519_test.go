@@ -0,0 +1,39 @@
+package sr25519_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/sr25519"
+)
+
+func TestSignAndValidateSr25519(t *testing.T) {
+
+	privKey := sr25519.GenPrivKey()
+	pubKey := privKey.PubKey()
+
+	msg := crypto.CRandBytes(128)
+	sig, err := privKey.Sign(msg)
+	require.Nil(t, err)
+
+	// Test the signature
+	assert.True(t, pubKey.VerifyBytes(msg, sig))
+	assert.False(t, pubKey.VerifyBytes(crypto.CRandBytes(128), sig))
+	assert.False(t, sr25519.GenPrivKey().PubKey().VerifyBytes(msg, sig))
+
+	// Mutate the signature, just one bit.
+	sig[7] ^= byte(0x01)
+
+	assert.False(t, pubKey.VerifyBytes(msg, sig))
+	assert.False(t, pubKey.VerifyBytes(msg, sig[:32]))
+}
+
+func TestGenPrivKeyFromSecret(t *testing.T) {
+	privKey := sr25519.GenPrivKeyFromSecret([]byte("secret"))
+	assert.True(t, privKey.Equals(sr25519.GenPrivKeyFromSecret([]byte("secret"))))
+	assert.True(t, privKey.PubKey().Equals(sr25519.GenPrivKeyFromSecret([]byte("secret")).PubKey()))
+	assert.False(t, privKey.Equals(sr25519.GenPrivKeyFromSecret([]byte("other"))))
+}
@@ -57,7 +57,7 @@ func (evR *EvidenceReactor) GetChannels() []*p2p.ChannelDescriptor {
 
 // AddPeer implements Reactor.
 func (evR *EvidenceReactor) AddPeer(peer p2p.Peer) {
-	go evR.broadcastEvidenceRoutine(peer)
+	evR.Go("broadcastEvidenceRoutine", func() { evR.broadcastEvidenceRoutine(peer) })
 }
 
 // Receive implements Reactor.
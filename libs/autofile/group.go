@@ -63,6 +63,7 @@ type Group struct {
 	mtx                sync.Mutex
 	headSizeLimit      int64
 	totalSizeLimit     int64
+	retainFiles        int // rotated files kept, 0 - unlimited
 	groupCheckDuration time.Duration
 	minIndex           int // Includes head
 	maxIndex           int // Includes head, where Head will move to
@@ -131,6 +132,14 @@ func GroupTotalSizeLimit(limit int64) func(*Group) {
 	}
 }
 
+// GroupRetainFiles allows you to limit the number of rotated files kept by
+// the group - unlimited by default. The oldest files are removed first.
+func GroupRetainFiles(n int) func(*Group) {
+	return func(g *Group) {
+		g.retainFiles = n
+	}
+}
+
 // OnStart implements cmn.Service by starting the goroutine that checks file
 // and group limits.
 func (g *Group) OnStart() error {
@@ -176,6 +185,13 @@ func (g *Group) TotalSizeLimit() int64 {
 	return g.totalSizeLimit
 }
 
+// RetainFiles returns the number of rotated files kept by the group.
+func (g *Group) RetainFiles() int {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.retainFiles
+}
+
 // MaxIndex returns index of the last file in the group.
 func (g *Group) MaxIndex() int {
 	g.mtx.Lock()
@@ -237,6 +253,7 @@ func (g *Group) processTicks() {
 		case <-g.ticker.C:
 			g.checkHeadSizeLimit()
 			g.checkTotalSizeLimit()
+			g.checkRetainFiles()
 		case <-g.Quit():
 			return
 		}
@@ -292,6 +309,31 @@ func (g *Group) checkTotalSizeLimit() {
 	}
 }
 
+// NOTE: this function is called manually in tests.
+func (g *Group) checkRetainFiles() {
+	retain := g.RetainFiles()
+	if retain == 0 {
+		return
+	}
+
+	gInfo := g.readGroupInfo()
+	// the head (gInfo.MaxIndex) isn't a rotated file
+	minIndex := gInfo.MinIndex
+	for ; minIndex < gInfo.MaxIndex-retain; minIndex++ {
+		pathToRemove := filePathForIndex(g.Head.Path, minIndex, gInfo.MaxIndex)
+		if err := os.Remove(pathToRemove); err != nil && !os.IsNotExist(err) {
+			g.Logger.Error("Failed to remove path", "path", pathToRemove, "err", err)
+			break
+		}
+	}
+
+	g.mtx.Lock()
+	if g.minIndex < minIndex {
+		g.minIndex = minIndex
+	}
+	g.mtx.Unlock()
+}
+
 // RotateFile causes group to close the current head and assign it some index.
 // Note it does not create a new head.
 func (g *Group) RotateFile() {
@@ -213,6 +213,31 @@ func TestGroupReaderRead2(t *testing.T) {
 	destroyTestGroup(t, g)
 }
 
+func TestCheckRetainFiles(t *testing.T) {
+	g := createTestGroupWithHeadSizeLimit(t, 0)
+	GroupRetainFiles(2)(g)
+
+	for i := 0; i < 5; i++ {
+		err := g.WriteLine(cmn.RandStr(99))
+		require.NoError(t, err, "Error appending to head")
+		g.FlushAndSync()
+		g.RotateFile()
+	}
+	assertGroupInfo(t, g.ReadGroupInfo(), 0, 5, 500, 0)
+
+	// only the 2 latest rotated files are kept, along with the head
+	g.checkRetainFiles()
+	assertGroupInfo(t, g.ReadGroupInfo(), 3, 5, 200, 0)
+	assert.Equal(t, 3, g.MinIndex())
+
+	// nothing more to remove
+	g.checkRetainFiles()
+	assertGroupInfo(t, g.ReadGroupInfo(), 3, 5, 200, 0)
+
+	// Cleanup
+	destroyTestGroup(t, g)
+}
+
 func TestMinIndex(t *testing.T) {
 	g := createTestGroupWithHeadSizeLimit(t, 0)
 
@@ -0,0 +1,130 @@
+// Package cgroup detects the CPU and memory limits a container runtime puts on
+// the process through Linux control groups (v1 and v2).
+package cgroup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// DefaultRoot is where the cgroup filesystem is mounted. Container runtimes
+// mount the cgroup of the container there.
+const DefaultRoot = "/sys/fs/cgroup"
+
+// cgroup v1 reports "no memory limit" as a huge page aligned number.
+const v1UnlimitedMemory = 1 << 62
+
+// Limits are the resource limits of a cgroup. Zero means no limit.
+type Limits struct {
+	// CPUs is the CPU quota, in number of CPUs (possibly fractional).
+	CPUs float64
+	// MemoryBytes is the memory limit.
+	MemoryBytes uint64
+}
+
+// Detect returns the limits of the cgroup mounted at DefaultRoot. It returns
+// zero Limits on other systems than Linux or if no limit is found.
+func Detect() Limits {
+	if runtime.GOOS != "linux" {
+		return Limits{}
+	}
+	return DetectAt(DefaultRoot)
+}
+
+// DetectAt returns the limits of the cgroup mounted at root, trying cgroup
+// v2 before v1.
+func DetectAt(root string) Limits {
+	var l Limits
+
+	// cgroup v2: "<quota> <period>" or "max <period>"
+	if fields, ok := readFields(filepath.Join(root, "cpu.max")); ok && len(fields) == 2 {
+		l.CPUs = cpus(fields[0], fields[1])
+	} else if quota, ok := readFields(filepath.Join(root, "cpu", "cpu.cfs_quota_us")); ok && len(quota) == 1 {
+		if period, ok := readFields(filepath.Join(root, "cpu", "cpu.cfs_period_us")); ok && len(period) == 1 {
+			l.CPUs = cpus(quota[0], period[0])
+		}
+	}
+
+	// cgroup v2: "<bytes>" or "max"
+	if fields, ok := readFields(filepath.Join(root, "memory.max")); ok && len(fields) == 1 {
+		l.MemoryBytes = parseUint(fields[0])
+	} else if fields, ok := readFields(filepath.Join(root, "memory", "memory.limit_in_bytes")); ok && len(fields) == 1 {
+		if limit := parseUint(fields[0]); limit < v1UnlimitedMemory {
+			l.MemoryBytes = limit
+		}
+	}
+
+	return l
+}
+
+// MaxProcs returns the GOMAXPROCS value matching the CPU quota, rounded up,
+// or 0 if there is no quota.
+func (l Limits) MaxProcs() int {
+	if l.CPUs <= 0 {
+		return 0
+	}
+	return int(math.Ceil(l.CPUs))
+}
+
+// cpus returns quota/period, or 0 if there is no quota ("max" in cgroup v2,
+// -1 in cgroup v1).
+func cpus(quota, period string) float64 {
+	q, err := strconv.ParseFloat(quota, 64)
+	if err != nil || q <= 0 {
+		return 0
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0
+	}
+	return q / p
+}
+
+// parseUint returns the value of s, or 0 if it isn't a number ("max").
+func parseUint(s string) uint64 {
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func readFields(path string) ([]string, bool) {
+	bz, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return strings.Fields(string(bz)), true
+}
+
+// ParseCPUList parses a list of CPUs in the cpuset format (e.g. "0,2-4").
+// An empty list returns no CPUs.
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+	if strings.TrimSpace(list) == "" {
+		return cpus, nil
+	}
+	for _, part := range strings.Split(list, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(part), "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid CPU %q", part)
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU range %q", part)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
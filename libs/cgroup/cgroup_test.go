@@ -0,0 +1,93 @@
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	root, err := ioutil.TempDir("", "cgroup")
+	require.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+	return root
+}
+
+func TestDetectAt(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		expected Limits
+	}{
+		{"no cgroup", nil, Limits{}},
+		{"v2 limited", map[string]string{
+			"cpu.max":    "150000 100000\n",
+			"memory.max": "1073741824\n",
+		}, Limits{CPUs: 1.5, MemoryBytes: 1 << 30}},
+		{"v2 unlimited", map[string]string{
+			"cpu.max":    "max 100000\n",
+			"memory.max": "max\n",
+		}, Limits{}},
+		{"v1 limited", map[string]string{
+			"cpu/cpu.cfs_quota_us":         "200000\n",
+			"cpu/cpu.cfs_period_us":        "100000\n",
+			"memory/memory.limit_in_bytes": "536870912\n",
+		}, Limits{CPUs: 2, MemoryBytes: 1 << 29}},
+		{"v1 unlimited", map[string]string{
+			"cpu/cpu.cfs_quota_us":         "-1\n",
+			"cpu/cpu.cfs_period_us":        "100000\n",
+			"memory/memory.limit_in_bytes": "9223372036854771712\n",
+		}, Limits{}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			root := writeFiles(t, tc.files)
+			defer os.RemoveAll(root)
+			assert.Equal(t, tc.expected, DetectAt(root))
+		})
+	}
+}
+
+func TestLimitsMaxProcs(t *testing.T) {
+	assert.Equal(t, 0, Limits{}.MaxProcs())
+	assert.Equal(t, 1, Limits{CPUs: 0.5}.MaxProcs())
+	assert.Equal(t, 2, Limits{CPUs: 1.5}.MaxProcs())
+	assert.Equal(t, 4, Limits{CPUs: 4}.MaxProcs())
+}
+
+func TestParseCPUList(t *testing.T) {
+	testCases := []struct {
+		list     string
+		expected []int
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"3", []int{3}, false},
+		{"0,2-4", []int{0, 2, 3, 4}, false},
+		{" 1 , 5-6 ", []int{1, 5, 6}, false},
+		{"a", nil, true},
+		{"-1", nil, true},
+		{"4-2", nil, true},
+		{"1,", nil, true},
+	}
+
+	for _, tc := range testCases {
+		cpus, err := ParseCPUList(tc.list)
+		if tc.wantErr {
+			assert.Error(t, err, tc.list)
+			continue
+		}
+		assert.NoError(t, err, tc.list)
+		assert.Equal(t, tc.expected, cpus, tc.list)
+	}
+}
@@ -20,6 +20,16 @@ const (
 // Example:
 //		ParseLogLevel("consensus:debug,mempool:debug,*:error", log.NewTMLogger(os.Stdout), "info")
 func ParseLogLevel(lvl string, logger log.Logger, defaultLogLevelValue string) (log.Logger, error) {
+	options, err := ParseLogLevelOptions(lvl, defaultLogLevelValue)
+	if err != nil {
+		return nil, err
+	}
+	return log.NewFilter(logger, options...), nil
+}
+
+// ParseLogLevelOptions parses the log level like ParseLogLevel, and returns
+// the options of the filter.
+func ParseLogLevelOptions(lvl string, defaultLogLevelValue string) ([]log.Option, error) {
 	if lvl == "" {
 		return nil, errors.New("Empty log level")
 	}
@@ -86,5 +96,5 @@ func ParseLogLevel(lvl string, logger log.Logger, defaultLogLevelValue string) (
 		options = append(options, option)
 	}
 
-	return log.NewFilter(logger, options...), nil
+	return options, nil
 }
@@ -203,6 +203,10 @@ func (bs *BaseService) IsRunning() bool {
 	return atomic.LoadUint32(&bs.started) == 1 && atomic.LoadUint32(&bs.stopped) == 0
 }
 
+func (bs *BaseService) isStopped() bool {
+	return atomic.LoadUint32(&bs.stopped) == 1
+}
+
 // Wait blocks until the service is stopped.
 func (bs *BaseService) Wait() {
 	<-bs.quit
@@ -0,0 +1,128 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Goroutines spawned with BaseService#Go are accounted to their service, so
+// that a goroutine still running after its service was stopped can be
+// attributed (see ServiceGoroutines).
+
+var goroutines = struct {
+	sync.Mutex
+	// running goroutines by service, then by routine name
+	byService map[*BaseService]map[string]int
+	// closed when the last goroutine of the service exits
+	done map[*BaseService]chan struct{}
+}{
+	byService: make(map[*BaseService]map[string]int),
+	done:      make(map[*BaseService]chan struct{}),
+}
+
+// Go runs f in a new goroutine owned by the service. The goroutine is labeled
+// with the service and routine names in pprof goroutine profiles, and counted
+// until f returns. f must return once the service is stopped, or it is
+// reported as leaked.
+func (bs *BaseService) Go(routine string, f func()) {
+	goroutines.Lock()
+	routines, ok := goroutines.byService[bs]
+	if !ok {
+		routines = make(map[string]int)
+		goroutines.byService[bs] = routines
+		goroutines.done[bs] = make(chan struct{})
+	}
+	routines[routine]++
+	goroutines.Unlock()
+
+	go func() {
+		defer bs.goroutineExited(routine)
+		labels := pprof.Labels("service", bs.name, "routine", routine)
+		pprof.Do(context.Background(), labels, func(context.Context) { f() })
+	}()
+}
+
+func (bs *BaseService) goroutineExited(routine string) {
+	goroutines.Lock()
+	defer goroutines.Unlock()
+
+	routines := goroutines.byService[bs]
+	routines[routine]--
+	if routines[routine] == 0 {
+		delete(routines, routine)
+	}
+	if len(routines) == 0 {
+		delete(goroutines.byService, bs)
+		close(goroutines.done[bs])
+		delete(goroutines.done, bs)
+	}
+}
+
+// Goroutines returns the number of running goroutines spawned with Go, by
+// routine name.
+func (bs *BaseService) Goroutines() map[string]int {
+	goroutines.Lock()
+	defer goroutines.Unlock()
+
+	routines := make(map[string]int, len(goroutines.byService[bs]))
+	for routine, n := range goroutines.byService[bs] {
+		routines[routine] = n
+	}
+	return routines
+}
+
+// WaitGoroutines blocks until all the goroutines spawned with Go have
+// returned, or returns an error listing the ones still running after the
+// timeout. Call it after Stop to check the service doesn't leak goroutines.
+func (bs *BaseService) WaitGoroutines(timeout time.Duration) error {
+	goroutines.Lock()
+	done, ok := goroutines.done[bs]
+	goroutines.Unlock()
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("%v goroutines still running after %v: %v", bs.name, timeout, bs.Goroutines())
+	}
+}
+
+// ServiceGoroutinesInfo describes the goroutines owned by a service.
+type ServiceGoroutinesInfo struct {
+	Service string `json:"service"`
+	Running bool   `json:"running"`
+	// Leaked is true if the service was stopped, so that all its goroutines
+	// should have returned.
+	Leaked   bool           `json:"leaked"`
+	Routines map[string]int `json:"routines"`
+}
+
+// ServiceGoroutines returns the running goroutines spawned with
+// BaseService#Go, grouped by service and sorted by service name.
+func ServiceGoroutines() []ServiceGoroutinesInfo {
+	goroutines.Lock()
+	defer goroutines.Unlock()
+
+	infos := make([]ServiceGoroutinesInfo, 0, len(goroutines.byService))
+	for bs, routines := range goroutines.byService {
+		info := ServiceGoroutinesInfo{
+			Service:  bs.name,
+			Running:  bs.IsRunning(),
+			Leaked:   bs.isStopped(),
+			Routines: make(map[string]int, len(routines)),
+		}
+		for routine, n := range routines {
+			info.Routines[routine] = n
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Service < infos[j].Service })
+	return infos
+}
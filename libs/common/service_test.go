@@ -52,3 +52,36 @@ func TestBaseServiceReset(t *testing.T) {
 	err = ts.Start()
 	require.NoError(t, err)
 }
+
+func TestBaseServiceGoroutines(t *testing.T) {
+	ts := &testService{}
+	ts.BaseService = *NewBaseService(nil, "TestService", ts)
+	ts.Start()
+
+	release := make(chan struct{})
+	ts.Go("quitRoutine", func() { <-ts.Quit() })
+	ts.Go("leakyRoutine", func() { <-release })
+	ts.Go("leakyRoutine", func() { <-release })
+	require.Equal(t, map[string]int{"quitRoutine": 1, "leakyRoutine": 2}, ts.Goroutines())
+
+	ts.Stop()
+	err := ts.WaitGoroutines(100 * time.Millisecond)
+	require.Error(t, err, "expected leaked goroutines")
+	require.Equal(t, map[string]int{"leakyRoutine": 2}, ts.Goroutines())
+
+	var info *ServiceGoroutinesInfo
+	for _, i := range ServiceGoroutines() {
+		if i.Service == "TestService" {
+			i := i
+			info = &i
+		}
+	}
+	require.NotNil(t, info)
+	require.False(t, info.Running)
+	require.True(t, info.Leaked)
+	require.Equal(t, map[string]int{"leakyRoutine": 2}, info.Routines)
+
+	close(release)
+	require.NoError(t, ts.WaitGoroutines(time.Second))
+	require.Empty(t, ts.Goroutines())
+}
@@ -0,0 +1,283 @@
+// Package compress is a registry of compression codecs (none, snappy and
+// zstd, optionally with a dictionary trained on the data), shared by the
+// features compressing data on the wire (the compressed blocks of fast sync)
+// or on disk (the block store and the consensus WAL).
+//
+// The values compressed with Registry.Encode start with the ID of their
+// codec, so Registry.Decode reads the values written with any codec of the
+// registry: the codec can be changed without rewriting the existing data.
+package compress
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+)
+
+// Names of the built-in codecs.
+const (
+	CodecNone   = "none"
+	CodecSnappy = "snappy"
+	CodecZstd   = "zstd"
+)
+
+// IDs of the built-in codecs, in the first byte of the encoded values.
+const (
+	IDNone   byte = 0x00
+	IDSnappy byte = 0x01
+	IDZstd   byte = 0x02
+)
+
+// ErrEmptyValue is returned by Decode for a value without a codec ID.
+var ErrEmptyValue = errors.New("empty compressed value")
+
+// ErrTooLarge is returned by DecodeLimit for a value decompressing to more
+// than the limit.
+var ErrTooLarge = errors.New("decompressed value is too large")
+
+// Codec compresses and decompresses values. A Codec must be safe for
+// concurrent use.
+type Codec interface {
+	// Name is the name of the codec in the configs and the metrics.
+	Name() string
+	// ID identifies the codec in the values encoded by the Registry.
+	ID() byte
+	// Compress appends the compressed src to dst.
+	Compress(dst, src []byte) ([]byte, error)
+	// Decompress appends the decompressed src to dst.
+	Decompress(dst, src []byte) ([]byte, error)
+}
+
+// LimitedCodec is a Codec which can bound the size of the decompressed values
+// without decompressing them in full, to decode the values of untrusted
+// sources (e.g. peers). The built-in codecs are LimitedCodecs.
+type LimitedCodec interface {
+	Codec
+	// DecompressLimit appends the decompressed src to dst, or fails with
+	// ErrTooLarge if it's bigger than limit bytes.
+	DecompressLimit(dst, src []byte, limit int) ([]byte, error)
+}
+
+//-----------------------------------------------------------------------------
+
+type noneCodec struct{}
+
+func (noneCodec) Name() string { return CodecNone }
+func (noneCodec) ID() byte     { return IDNone }
+
+func (noneCodec) Compress(dst, src []byte) ([]byte, error) {
+	return append(dst, src...), nil
+}
+
+func (noneCodec) Decompress(dst, src []byte) ([]byte, error) {
+	return append(dst, src...), nil
+}
+
+// None returns the codec which doesn't compress.
+func None() Codec { return noneCodec{} }
+
+type snappyCodec struct{}
+
+func (snappyCodec) Name() string { return CodecSnappy }
+func (snappyCodec) ID() byte     { return IDSnappy }
+
+func (snappyCodec) Compress(dst, src []byte) ([]byte, error) {
+	return append(dst, snappy.Encode(nil, src)...), nil
+}
+
+func (snappyCodec) Decompress(dst, src []byte) ([]byte, error) {
+	bz, err := snappy.Decode(nil, src)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, bz...), nil
+}
+
+func (c snappyCodec) DecompressLimit(dst, src []byte, limit int) ([]byte, error) {
+	n, err := snappy.DecodedLen(src)
+	if err != nil {
+		return nil, err
+	}
+	if n > limit {
+		return nil, ErrTooLarge
+	}
+	return c.Decompress(dst, src)
+}
+
+// Snappy returns the snappy codec: fast, with a lower ratio than zstd.
+func Snappy() Codec { return snappyCodec{} }
+
+//-----------------------------------------------------------------------------
+
+// RegistryOption sets an optional parameter on the Registry.
+type RegistryOption func(*Registry)
+
+// WithMetrics sets the metrics.
+func WithMetrics(metrics *Metrics) RegistryOption {
+	return func(r *Registry) { r.metrics = metrics }
+}
+
+// Registry holds the codecs by name and ID. It's safe for concurrent use.
+type Registry struct {
+	metrics *Metrics
+
+	mtx    sync.RWMutex
+	byName map[string]Codec
+	byID   map[byte]Codec
+}
+
+// NewRegistry returns a Registry with the built-in codecs: none, snappy and,
+// if it's available in this build (see ZstdAvailable), zstd without
+// dictionary.
+func NewRegistry(options ...RegistryOption) *Registry {
+	r := &Registry{
+		metrics: NopMetrics(),
+		byName:  make(map[string]Codec),
+		byID:    make(map[byte]Codec),
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	r.mustRegister(None())
+	r.mustRegister(Snappy())
+	if ZstdAvailable {
+		codec, err := NewZstd(DefaultZstdLevel, nil)
+		if err != nil {
+			panic(err)
+		}
+		r.mustRegister(codec)
+	}
+	return r
+}
+
+// Register adds codec to the registry, replacing the codec of the same name
+// (e.g. the zstd codec without dictionary with one with a dictionary). It
+// fails if the ID of codec is taken by a codec of another name.
+func (r *Registry) Register(codec Codec) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if other, ok := r.byID[codec.ID()]; ok && other.Name() != codec.Name() {
+		return fmt.Errorf("codec ID %#x of %q is taken by %q", codec.ID(), codec.Name(), other.Name())
+	}
+	r.byName[codec.Name()] = codec
+	r.byID[codec.ID()] = codec
+	return nil
+}
+
+func (r *Registry) mustRegister(codec Codec) {
+	if err := r.Register(codec); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns the codec of the given name.
+func (r *Registry) Get(name string) (Codec, error) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	codec, ok := r.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown compression codec %q (want one of %v)", name, r.names())
+	}
+	return codec, nil
+}
+
+// Names returns the sorted names of the codecs.
+func (r *Registry) Names() []string {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.names()
+}
+
+func (r *Registry) names() []string {
+	names := make([]string, 0, len(r.byName))
+	for name := range r.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Encode compresses src with codec, and prepends the ID of the codec. The
+// values which don't shrink are stored uncompressed.
+func (r *Registry) Encode(codec Codec, src []byte) ([]byte, error) {
+	if codec.ID() != IDNone {
+		start := time.Now()
+		bz, err := codec.Compress([]byte{codec.ID()}, src)
+		r.observe(codec, "compress", start, len(src), len(bz)-1, err)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to compress with %s", codec.Name())
+		}
+		if len(bz)-1 < len(src) {
+			return bz, nil
+		}
+	}
+	return append([]byte{IDNone}, src...), nil
+}
+
+// Decode decompresses a value returned by Encode, with the codec of its ID.
+func (r *Registry) Decode(src []byte) ([]byte, error) {
+	return r.decode(src, -1)
+}
+
+// DecodeLimit is like Decode, but fails with ErrTooLarge if the value
+// decompresses to more than limit bytes, before decompressing it in full. It
+// fails for the codecs which aren't LimitedCodecs.
+func (r *Registry) DecodeLimit(src []byte, limit int) ([]byte, error) {
+	return r.decode(src, limit)
+}
+
+// decode decodes src, up to limit bytes if limit isn't negative.
+func (r *Registry) decode(src []byte, limit int) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, ErrEmptyValue
+	}
+	if src[0] == IDNone {
+		if limit >= 0 && len(src)-1 > limit {
+			return nil, ErrTooLarge
+		}
+		return src[1:], nil
+	}
+
+	r.mtx.RLock()
+	codec, ok := r.byID[src[0]]
+	r.mtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown compression codec ID %#x", src[0])
+	}
+	start := time.Now()
+	var (
+		bz  []byte
+		err error
+	)
+	if limit < 0 {
+		bz, err = codec.Decompress(nil, src[1:])
+	} else if limited, ok := codec.(LimitedCodec); ok {
+		bz, err = limited.DecompressLimit(nil, src[1:], limit)
+	} else {
+		return nil, fmt.Errorf("codec %s can't bound the decompressed size", codec.Name())
+	}
+	if err == ErrTooLarge {
+		return nil, err
+	}
+	r.observe(codec, "decompress", start, len(bz), len(src)-1, err)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decompress with %s", codec.Name())
+	}
+	return bz, nil
+}
+
+func (r *Registry) observe(codec Codec, op string, start time.Time, uncompressed, compressed int, err error) {
+	labels := []string{"codec", codec.Name(), "op", op}
+	if err != nil {
+		r.metrics.Errors.With(labels...).Add(1)
+		return
+	}
+	r.metrics.Duration.With(labels...).Observe(time.Since(start).Seconds())
+	r.metrics.UncompressedBytes.With(labels...).Add(float64(uncompressed))
+	r.metrics.CompressedBytes.With(labels...).Add(float64(compressed))
+}
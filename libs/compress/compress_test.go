@@ -0,0 +1,143 @@
+package compress
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+func TestRegistryEncodeDecode(t *testing.T) {
+	r := NewRegistry()
+	compressible := bytes.Repeat([]byte("tendermint block data "), 100)
+	random := cmn.RandBytes(1024)
+
+	for _, name := range r.Names() {
+		codec, err := r.Get(name)
+		require.NoError(t, err)
+
+		bz, err := r.Encode(codec, compressible)
+		require.NoError(t, err)
+		assert.Equal(t, codec.ID(), bz[0], name)
+		if name != CodecNone {
+			assert.True(t, len(bz) < len(compressible), name)
+		}
+		decoded, err := r.Decode(bz)
+		require.NoError(t, err)
+		assert.Equal(t, compressible, decoded, name)
+
+		// incompressible values are stored as is
+		bz, err = r.Encode(codec, random)
+		require.NoError(t, err)
+		assert.Equal(t, IDNone, bz[0], name)
+		decoded, err = r.Decode(bz)
+		require.NoError(t, err)
+		assert.Equal(t, random, decoded, name)
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	r := NewRegistry()
+
+	_, err := r.Get("lz4")
+	assert.Error(t, err)
+	_, err = r.Decode(nil)
+	assert.Equal(t, ErrEmptyValue, err)
+	_, err = r.Decode([]byte{0xff, 0x01})
+	assert.Error(t, err)
+	_, err = r.Decode([]byte{IDSnappy, 0xff, 0xff, 0xff})
+	assert.Error(t, err)
+
+	// the IDs are unique
+	assert.Error(t, r.Register(fakeCodec{name: "fake", id: IDSnappy}))
+	assert.NoError(t, r.Register(fakeCodec{name: "fake", id: 0x80}))
+	assert.Contains(t, r.Names(), "fake")
+}
+
+func TestRegistryDecodeLimit(t *testing.T) {
+	r := NewRegistry()
+	compressible := bytes.Repeat([]byte("tendermint block data "), 100)
+
+	for _, name := range r.Names() {
+		codec, err := r.Get(name)
+		require.NoError(t, err)
+		bz, err := r.Encode(codec, compressible)
+		require.NoError(t, err)
+
+		decoded, err := r.DecodeLimit(bz, len(compressible))
+		require.NoError(t, err, name)
+		assert.Equal(t, compressible, decoded, name)
+		_, err = r.DecodeLimit(bz, len(compressible)-1)
+		assert.Equal(t, ErrTooLarge, err, name)
+	}
+
+	// the size of the values of other codecs can't be bounded
+	require.NoError(t, r.Register(fakeCodec{name: "fake", id: 0x80}))
+	_, err := r.DecodeLimit([]byte{0x80, 0x01}, 10)
+	assert.Error(t, err)
+}
+
+func TestZstdDict(t *testing.T) {
+	if !ZstdAvailable {
+		_, err := NewZstd(DefaultZstdLevel, nil)
+		assert.Error(t, err)
+		t.Skip("zstd requires cgo")
+	}
+
+	samples := make([][]byte, 1000)
+	for i := range samples {
+		samples[i] = []byte(fmt.Sprintf(`{"chain_id":"test-chain","height":%d,"proposer":"%X"}`,
+			i, cmn.RandBytes(4)))
+	}
+	dict, err := TrainZstdDict(samples, 4096)
+	require.NoError(t, err)
+	_, err = TrainZstdDict(nil, 4096)
+	assert.Error(t, err)
+
+	_, err = NewZstd(0, dict)
+	assert.Error(t, err)
+	withDict, err := NewZstd(DefaultZstdLevel, dict)
+	require.NoError(t, err)
+	r := NewRegistry()
+	plain, err := r.Get(CodecZstd)
+	require.NoError(t, err)
+
+	value := []byte(`{"chain_id":"test-chain","height":1001,"proposer":"0A0B0C0D"}`)
+	plainBz, err := r.Encode(plain, value)
+	require.NoError(t, err)
+
+	// the dictionary improves the ratio of small values
+	require.NoError(t, r.Register(withDict))
+	dictBz, err := r.Encode(withDict, value)
+	require.NoError(t, err)
+	assert.Equal(t, IDZstd, dictBz[0])
+	assert.True(t, len(dictBz) < len(plainBz))
+
+	// values compressed with or without dictionary are decoded
+	long := bytes.Repeat(value, 10)
+	longBz, err := r.Encode(plain, long)
+	require.NoError(t, err)
+	assert.Equal(t, IDZstd, longBz[0])
+	decoded, err := r.Decode(longBz)
+	require.NoError(t, err)
+	assert.Equal(t, long, decoded)
+	for _, bz := range [][]byte{plainBz, dictBz} {
+		decoded, err = r.Decode(bz)
+		require.NoError(t, err)
+		assert.Equal(t, value, decoded)
+	}
+}
+
+type fakeCodec struct {
+	name string
+	id   byte
+}
+
+func (c fakeCodec) Name() string                               { return c.name }
+func (c fakeCodec) ID() byte                                   { return c.id }
+func (c fakeCodec) Compress(dst, src []byte) ([]byte, error)   { return append(dst, src...), nil }
+func (c fakeCodec) Decompress(dst, src []byte) ([]byte, error) { return append(dst, src...), nil }
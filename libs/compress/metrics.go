@@ -0,0 +1,75 @@
+package compress
+
+import (
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// MetricsSubsystem is a subsystem shared by all metrics exposed by this
+	// package.
+	MetricsSubsystem = "compress"
+)
+
+// Metrics contains metrics exposed by this package. They're labeled with the
+// codec and the operation (compress or decompress).
+type Metrics struct {
+	// Number of bytes before compression or after decompression.
+	UncompressedBytes metrics.Counter
+	// Number of compressed bytes.
+	CompressedBytes metrics.Counter
+	// Time to compress or decompress a value, in seconds.
+	Duration metrics.Histogram
+	// Number of values which failed to compress or decompress.
+	Errors metrics.Counter
+}
+
+// PrometheusMetrics returns Metrics build using Prometheus client library.
+// Optionally, labels can be provided along with their values ("foo",
+// "fooValue").
+func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
+	labels := []string{}
+	for i := 0; i < len(labelsAndValues); i += 2 {
+		labels = append(labels, labelsAndValues[i])
+	}
+	labels = append(labels, "codec", "op")
+	return &Metrics{
+		UncompressedBytes: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "uncompressed_bytes",
+			Help:      "Number of bytes before compression or after decompression.",
+		}, labels).With(labelsAndValues...),
+		CompressedBytes: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "compressed_bytes",
+			Help:      "Number of compressed bytes.",
+		}, labels).With(labelsAndValues...),
+		Duration: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "duration_seconds",
+			Help:      "Time to compress or decompress a value, in seconds.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, labels).With(labelsAndValues...),
+		Errors: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "errors",
+			Help:      "Number of values which failed to compress or decompress.",
+		}, labels).With(labelsAndValues...),
+	}
+}
+
+// NopMetrics returns no-op Metrics.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		UncompressedBytes: discard.NewCounter(),
+		CompressedBytes:   discard.NewCounter(),
+		Duration:          discard.NewHistogram(),
+		Errors:            discard.NewCounter(),
+	}
+}
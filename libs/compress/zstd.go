@@ -0,0 +1,99 @@
+// +build cgo
+
+package compress
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	"github.com/valyala/gozstd"
+)
+
+// ZstdAvailable is true if the build has the zstd codec, which requires cgo.
+const ZstdAvailable = true
+
+// DefaultZstdLevel is the compression level of the zstd codec of the
+// registries.
+const DefaultZstdLevel = 3
+
+type zstdCodec struct {
+	level int
+	cdict *gozstd.CDict // nil without dictionary
+	ddict *gozstd.DDict
+}
+
+// NewZstd returns the zstd codec with the given compression level (1 to 22)
+// and dictionary (see TrainZstdDict), which may be nil.
+//
+// The frames record the ID of the dictionary they're compressed with, so a
+// codec fails to decompress the frames of another dictionary. It still
+// decompresses the frames compressed without dictionary.
+func NewZstd(level int, dict []byte) (Codec, error) {
+	if level < 1 || level > 22 {
+		return nil, errors.Errorf("invalid zstd level %d (want 1 to 22)", level)
+	}
+	codec := &zstdCodec{level: level}
+	if len(dict) == 0 {
+		return codec, nil
+	}
+	var err error
+	if codec.cdict, err = gozstd.NewCDictLevel(dict, level); err != nil {
+		return nil, errors.Wrap(err, "invalid zstd dictionary")
+	}
+	if codec.ddict, err = gozstd.NewDDict(dict); err != nil {
+		return nil, errors.Wrap(err, "invalid zstd dictionary")
+	}
+	return codec, nil
+}
+
+func (c *zstdCodec) Name() string { return CodecZstd }
+func (c *zstdCodec) ID() byte     { return IDZstd }
+
+func (c *zstdCodec) Compress(dst, src []byte) ([]byte, error) {
+	if c.cdict != nil {
+		return gozstd.CompressDict(dst, src, c.cdict), nil
+	}
+	return gozstd.CompressLevel(dst, src, c.level), nil
+}
+
+func (c *zstdCodec) Decompress(dst, src []byte) ([]byte, error) {
+	if c.ddict != nil {
+		return gozstd.DecompressDict(dst, src, c.ddict)
+	}
+	return gozstd.Decompress(dst, src)
+}
+
+func (c *zstdCodec) DecompressLimit(dst, src []byte, limit int) ([]byte, error) {
+	var zr *gozstd.Reader
+	if c.ddict != nil {
+		zr = gozstd.NewReaderDict(bytes.NewReader(src), c.ddict)
+	} else {
+		zr = gozstd.NewReader(bytes.NewReader(src))
+	}
+	defer zr.Release()
+	bz, err := ioutil.ReadAll(io.LimitReader(zr, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(bz) > limit {
+		return nil, ErrTooLarge
+	}
+	return append(dst, bz...), nil
+}
+
+// TrainZstdDict trains a zstd dictionary of at most size bytes on the given
+// samples, which should be values like the ones to compress (e.g. the parts
+// and commits of the blocks of the chain). The dictionary pays off for small
+// values, which don't have enough context of their own.
+func TrainZstdDict(samples [][]byte, size int) ([]byte, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("no samples to train the dictionary")
+	}
+	dict := gozstd.BuildDict(samples, size)
+	if len(dict) == 0 {
+		return nil, errors.New("failed to train the dictionary: not enough samples")
+	}
+	return dict, nil
+}
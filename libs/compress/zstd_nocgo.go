@@ -0,0 +1,24 @@
+// +build !cgo
+
+package compress
+
+import "github.com/pkg/errors"
+
+// ZstdAvailable is true if the build has the zstd codec, which requires cgo.
+const ZstdAvailable = false
+
+// DefaultZstdLevel is the compression level of the zstd codec of the
+// registries.
+const DefaultZstdLevel = 3
+
+var errNoZstd = errors.New("zstd requires a build with cgo")
+
+// NewZstd is only supported in builds with cgo.
+func NewZstd(level int, dict []byte) (Codec, error) {
+	return nil, errNoZstd
+}
+
+// TrainZstdDict is only supported in builds with cgo.
+func TrainZstdDict(samples [][]byte, size int) ([]byte, error) {
+	return nil, errNoZstd
+}
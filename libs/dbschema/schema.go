@@ -0,0 +1,140 @@
+// Package dbschema versions the layout of the key-value databases of the node
+// (e.g. the state and the block store), and migrates the databases written by
+// older versions to the current layout.
+package dbschema
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// BaseVersion is the schema version of the databases written before their
+// layout was versioned.
+const BaseVersion = 1
+
+// versionKey holds the schema version of a database, in decimal.
+var versionKey = []byte("schemaVersion")
+
+// Migration upgrades a database to the schema Version from the previous one.
+//
+// Migrations must be idempotent: the version is only saved once the
+// migration completes, so a migration interrupted by a crash runs again from
+// the start on the next attempt.
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(db dbm.DB) error
+}
+
+// Schema is the history of the layout of a database.
+type Schema struct {
+	// Name of the database, e.g. "state".
+	Name string
+	// Migrations from BaseVersion, by increasing version.
+	Migrations []Migration
+}
+
+// Version returns the current version of the schema, the one the databases
+// are migrated to.
+func (s Schema) Version() int {
+	if len(s.Migrations) == 0 {
+		return BaseVersion
+	}
+	return s.Migrations[len(s.Migrations)-1].Version
+}
+
+// ValidateBasic checks that the migrations follow each other from
+// BaseVersion.
+func (s Schema) ValidateBasic() error {
+	for i, m := range s.Migrations {
+		if m.Version != BaseVersion+i+1 {
+			return fmt.Errorf("migration %d of the %s schema is to version %d, want %d",
+				i, s.Name, m.Version, BaseVersion+i+1)
+		}
+		if m.Migrate == nil {
+			return fmt.Errorf("migration to version %d of the %s schema has no Migrate function", m.Version, s.Name)
+		}
+	}
+	return nil
+}
+
+// LoadVersion returns the schema version of the database: 0 if it is empty,
+// BaseVersion if it was written before the layout was versioned.
+func LoadVersion(db dbm.DB) (int, error) {
+	bz := db.Get(versionKey)
+	if bz == nil {
+		if isEmpty(db) {
+			return 0, nil
+		}
+		return BaseVersion, nil
+	}
+	version, err := strconv.Atoi(string(bz))
+	if err != nil || version < BaseVersion {
+		return 0, fmt.Errorf("invalid schema version %q", bz)
+	}
+	return version, nil
+}
+
+func saveVersion(db dbm.DB, version int) {
+	db.SetSync(versionKey, []byte(strconv.Itoa(version)))
+}
+
+func isEmpty(db dbm.DB) bool {
+	it := db.Iterator(nil, nil)
+	defer it.Close()
+	return !it.Valid()
+}
+
+// Pending returns the migrations not applied to the database yet. A database
+// of a newer version than the schema can't be used, as downgrades aren't
+// supported.
+func (s Schema) Pending(db dbm.DB) ([]Migration, error) {
+	version, err := LoadVersion(db)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the version of the %s database", s.Name)
+	}
+	if version > s.Version() {
+		return nil, fmt.Errorf("the %s database has the schema version %d, newer than the version %d of this binary",
+			s.Name, version, s.Version())
+	}
+	if version == 0 {
+		// a new database is written with the current layout
+		return nil, nil
+	}
+	return s.Migrations[version-BaseVersion:], nil
+}
+
+// Migrate applies the pending migrations to the database, in order, and saves
+// its version. With dryRun, the pending migrations are only logged, and the
+// database is left untouched. It returns the pending migrations.
+func (s Schema) Migrate(db dbm.DB, dryRun bool, logger log.Logger) ([]Migration, error) {
+	if err := s.ValidateBasic(); err != nil {
+		return nil, err
+	}
+	pending, err := s.Pending(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range pending {
+		if dryRun {
+			logger.Info("Pending migration", "db", s.Name, "version", m.Version, "description", m.Description)
+			continue
+		}
+		logger.Info("Migrating database", "db", s.Name, "version", m.Version, "description", m.Description)
+		if err := m.Migrate(db); err != nil {
+			return pending, errors.Wrapf(err, "failed to migrate the %s database to version %d", s.Name, m.Version)
+		}
+		saveVersion(db, m.Version)
+	}
+
+	if !dryRun && db.Get(versionKey) == nil {
+		saveVersion(db, s.Version())
+	}
+	return pending, nil
+}
@@ -0,0 +1,90 @@
+package dbschema
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// testSchema renames the "k" key to "key", then fails to migrate to version 3
+// until fail is cleared.
+func testSchema(fail *bool) Schema {
+	return Schema{
+		Name: "test",
+		Migrations: []Migration{
+			{Version: 2, Description: "rename k to key", Migrate: func(db dbm.DB) error {
+				if v := db.Get([]byte("k")); v != nil {
+					db.Set([]byte("key"), v)
+					db.Delete([]byte("k"))
+				}
+				return nil
+			}},
+			{Version: 3, Description: "fail", Migrate: func(db dbm.DB) error {
+				if *fail {
+					return errors.New("failed")
+				}
+				return nil
+			}},
+		},
+	}
+}
+
+func TestSchemaMigrate(t *testing.T) {
+	fail := true
+	schema := testSchema(&fail)
+	require.NoError(t, schema.ValidateBasic())
+	assert.Equal(t, 3, schema.Version())
+
+	// new databases have the current version
+	db := dbm.NewMemDB()
+	pending, err := schema.Migrate(db, false, log.TestingLogger())
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	version, err := LoadVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+
+	// databases written before versioning are migrated from the base version
+	db = dbm.NewMemDB()
+	db.Set([]byte("k"), []byte("v"))
+	version, err = LoadVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, BaseVersion, version)
+
+	pending, err = schema.Migrate(db, true, log.TestingLogger())
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, []byte("v"), db.Get([]byte("k")), "dry run")
+	assert.Nil(t, db.Get(versionKey), "dry run")
+
+	_, err = schema.Migrate(db, false, log.TestingLogger())
+	assert.Error(t, err)
+	assert.Equal(t, []byte("v"), db.Get([]byte("key")))
+	version, err = LoadVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version, "the failed migration is run again")
+
+	fail = false
+	pending, err = schema.Migrate(db, false, log.TestingLogger())
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+	version, err = LoadVersion(db)
+	require.NoError(t, err)
+	assert.Equal(t, 3, version)
+
+	// downgrades aren't supported
+	_, err = Schema{Name: "test"}.Migrate(db, false, log.TestingLogger())
+	assert.Error(t, err)
+}
+
+func TestSchemaValidateBasic(t *testing.T) {
+	noop := func(dbm.DB) error { return nil }
+	assert.NoError(t, Schema{Name: "test"}.ValidateBasic())
+	assert.Error(t, Schema{Name: "test", Migrations: []Migration{{Version: 3, Migrate: noop}}}.ValidateBasic())
+	assert.Error(t, Schema{Name: "test", Migrations: []Migration{{Version: 2}}}.ValidateBasic())
+}
@@ -0,0 +1,214 @@
+// Package erasure implements a systematic Reed-Solomon erasure code over
+// GF(2^8): K data shards are extended with M parity shards, and any K of the
+// K+M shards are enough to reconstruct the others.
+package erasure
+
+import (
+	"github.com/pkg/errors"
+)
+
+// MaxShards is the maximum count of data and parity shards.
+const MaxShards = 256
+
+var (
+	ErrTooManyShards = errors.New("too many shards")
+	ErrTooFewShards  = errors.New("too few shards to reconstruct")
+	ErrShardSize     = errors.New("shards have different sizes")
+)
+
+// Encode returns the parityTotal parity shards of the given data shards, which
+// must all have the same size.
+func Encode(data [][]byte, parityTotal int) ([][]byte, error) {
+	if len(data)+parityTotal > MaxShards {
+		return nil, ErrTooManyShards
+	}
+	if len(data) == 0 || parityTotal <= 0 {
+		return nil, nil
+	}
+	size := len(data[0])
+	for _, shard := range data {
+		if len(shard) != size {
+			return nil, ErrShardSize
+		}
+	}
+
+	parity := make([][]byte, parityTotal)
+	for i := range parity {
+		parity[i] = make([]byte, size)
+		for j, shard := range data {
+			mulAddSlice(cauchy(len(data), i, j), shard, parity[i])
+		}
+	}
+	return parity, nil
+}
+
+// Reconstruct fills in the missing (nil) shards of shards, the dataTotal data
+// shards followed by the parity shards, from any dataTotal of them.
+func Reconstruct(shards [][]byte, dataTotal int) error {
+	if len(shards) > MaxShards {
+		return ErrTooManyShards
+	}
+
+	// Pick dataTotal shards to decode from.
+	present := make([]int, 0, dataTotal)
+	size := -1
+	for i, shard := range shards {
+		if shard == nil {
+			continue
+		}
+		if size == -1 {
+			size = len(shard)
+		} else if len(shard) != size {
+			return ErrShardSize
+		}
+		if len(present) < dataTotal {
+			present = append(present, i)
+		}
+	}
+	if len(present) < dataTotal {
+		return ErrTooFewShards
+	}
+
+	// Rows of the encoding matrix of the picked shards: the identity for data
+	// shards, the Cauchy matrix for parity shards.
+	matrix := make([][]byte, dataTotal)
+	for r, i := range present {
+		matrix[r] = make([]byte, dataTotal)
+		for j := 0; j < dataTotal; j++ {
+			if i < dataTotal {
+				if i == j {
+					matrix[r][j] = 1
+				}
+			} else {
+				matrix[r][j] = cauchy(dataTotal, i-dataTotal, j)
+			}
+		}
+	}
+	decode, err := invert(matrix)
+	if err != nil {
+		return err
+	}
+
+	// Decode the missing data shards, then re-encode the missing parity
+	// shards.
+	for j := 0; j < dataTotal; j++ {
+		if shards[j] != nil {
+			continue
+		}
+		shards[j] = make([]byte, size)
+		for r, i := range present {
+			mulAddSlice(decode[j][r], shards[i], shards[j])
+		}
+	}
+	for i := dataTotal; i < len(shards); i++ {
+		if shards[i] != nil {
+			continue
+		}
+		shards[i] = make([]byte, size)
+		for j := 0; j < dataTotal; j++ {
+			mulAddSlice(cauchy(dataTotal, i-dataTotal, j), shards[j], shards[i])
+		}
+	}
+	return nil
+}
+
+// cauchy returns the coefficient of the data shard j in the parity shard i:
+// 1 / (x_i + y_j) with x_i = dataTotal + i and y_j = j. Every square
+// submatrix of a Cauchy matrix is invertible, so any dataTotal shards can be
+// decoded.
+func cauchy(dataTotal, i, j int) byte {
+	return gfInv(byte(dataTotal+i) ^ byte(j))
+}
+
+// invert returns the inverse of the given square matrix, using Gauss-Jordan
+// elimination.
+func invert(matrix [][]byte) ([][]byte, error) {
+	n := len(matrix)
+	work := make([][]byte, n)
+	for r := range matrix {
+		work[r] = make([]byte, 2*n)
+		copy(work[r], matrix[r])
+		work[r][n+r] = 1
+	}
+
+	for c := 0; c < n; c++ {
+		pivot := c
+		for pivot < n && work[pivot][c] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, errors.New("singular matrix")
+		}
+		work[c], work[pivot] = work[pivot], work[c]
+
+		scale := gfInv(work[c][c])
+		for k := range work[c] {
+			work[c][k] = gfMul(work[c][k], scale)
+		}
+		for r := 0; r < n; r++ {
+			if r != c && work[r][c] != 0 {
+				mulAddSlice(work[r][c], work[c], work[r])
+			}
+		}
+	}
+
+	inverse := make([][]byte, n)
+	for r := range work {
+		inverse[r] = work[r][n:]
+	}
+	return inverse, nil
+}
+
+//-----------------------------------------------------------------------------
+// GF(2^8) arithmetic, with the 0x11d polynomial and 2 as generator.
+
+var (
+	gfExp [510]byte
+	gfLog [256]int
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfExp[i+255] = byte(x)
+		gfLog[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[gfLog[a]+gfLog[b]]
+}
+
+// gfInv returns the multiplicative inverse of a, which must not be 0.
+func gfInv(a byte) byte {
+	return gfExp[255-gfLog[a]]
+}
+
+// mulAddSlice adds c * in to out.
+func mulAddSlice(c byte, in, out []byte) {
+	switch c {
+	case 0:
+		return
+	case 1:
+		for i, b := range in {
+			out[i] ^= b
+		}
+		return
+	}
+
+	var table [256]byte
+	for b := 1; b < 256; b++ {
+		table[b] = gfMul(c, byte(b))
+	}
+	for i, b := range in {
+		out[i] ^= table[b]
+	}
+}
@@ -0,0 +1,62 @@
+package erasure
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGFInverse(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		assert.EqualValues(t, 1, gfMul(byte(a), gfInv(byte(a))), "a=%d", a)
+	}
+}
+
+func TestReconstructFromAnyShards(t *testing.T) {
+	testCases := []struct {
+		dataTotal, parityTotal int
+	}{
+		{1, 1},
+		{3, 1},
+		{10, 4},
+		{192, 64},
+	}
+	for _, tc := range testCases {
+		data := make([][]byte, tc.dataTotal)
+		for i := range data {
+			data[i] = make([]byte, 100)
+			rand.Read(data[i])
+		}
+		parity, err := Encode(data, tc.parityTotal)
+		require.NoError(t, err)
+		require.Len(t, parity, tc.parityTotal)
+		all := append(append([][]byte{}, data...), parity...)
+
+		// drop parityTotal random shards
+		for trial := 0; trial < 10; trial++ {
+			shards := append([][]byte{}, all...)
+			for _, i := range rand.Perm(len(shards))[:tc.parityTotal] {
+				shards[i] = nil
+			}
+			require.NoError(t, Reconstruct(shards, tc.dataTotal))
+			assert.Equal(t, all, shards, "data=%d, parity=%d", tc.dataTotal, tc.parityTotal)
+		}
+
+		// one more is too many
+		shards := append([][]byte{}, all...)
+		for _, i := range rand.Perm(len(shards))[:tc.parityTotal+1] {
+			shards[i] = nil
+		}
+		assert.Equal(t, ErrTooFewShards, Reconstruct(shards, tc.dataTotal))
+	}
+}
+
+func TestEncodeErrors(t *testing.T) {
+	_, err := Encode(make([][]byte, 200), 57)
+	assert.Equal(t, ErrTooManyShards, err)
+
+	_, err = Encode([][]byte{make([]byte, 2), make([]byte, 3)}, 1)
+	assert.Equal(t, ErrShardSize, err)
+}
@@ -0,0 +1,77 @@
+package log
+
+import "sync"
+
+// NewReloadableFilter wraps next like NewFilter, and returns a function to
+// replace the options of the filter at runtime, e.g. to change the log level
+// of a running node. The loggers derived from the returned logger with With
+// follow the changes.
+func NewReloadableFilter(next Logger, options ...Option) (Logger, func(options ...Option)) {
+	rules := &filterRules{root: NewFilter(next, options...)}
+	setOptions := func(options ...Option) {
+		root := NewFilter(next, options...)
+		rules.mtx.Lock()
+		rules.root = root
+		rules.version++
+		rules.mtx.Unlock()
+	}
+	return &reloadableFilter{rules: rules}, setOptions
+}
+
+// filterRules is the filter shared by a reloadable filter and the loggers
+// derived from it.
+type filterRules struct {
+	mtx     sync.RWMutex
+	root    Logger // filter of next with the current options
+	version uint64 // incremented when the options are replaced
+}
+
+type reloadableFilter struct {
+	rules *filterRules
+	withs [][]interface{} // keyvals of the successive calls to With
+
+	mtx     sync.Mutex
+	version uint64
+	current Logger // root with the keyvals, as of version
+}
+
+// logger returns the filter with the current options and the keyvals of l.
+func (l *reloadableFilter) logger() Logger {
+	l.rules.mtx.RLock()
+	root, version := l.rules.root, l.rules.version
+	l.rules.mtx.RUnlock()
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	if l.current == nil || l.version != version {
+		current := root
+		for _, keyvals := range l.withs {
+			current = current.With(keyvals...)
+		}
+		l.current, l.version = current, version
+	}
+	return l.current
+}
+
+func (l *reloadableFilter) Debug(msg string, keyvals ...interface{}) {
+	l.logger().Debug(msg, keyvals...)
+}
+
+func (l *reloadableFilter) Info(msg string, keyvals ...interface{}) {
+	l.logger().Info(msg, keyvals...)
+}
+
+func (l *reloadableFilter) Error(msg string, keyvals ...interface{}) {
+	l.logger().Error(msg, keyvals...)
+}
+
+// With implements Logger by keeping the keyvals, to be applied to the filter
+// with the current options.
+func (l *reloadableFilter) With(keyvals ...interface{}) Logger {
+	withs := make([][]interface{}, len(l.withs), len(l.withs)+1)
+	copy(withs, l.withs)
+	return &reloadableFilter{
+		rules: l.rules,
+		withs: append(withs, keyvals),
+	}
+}
@@ -0,0 +1,41 @@
+package log_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestReloadableFilter(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger, setOptions := log.NewReloadableFilter(log.NewTMJSONLogger(&buf), log.AllowError())
+	moduleLogger := logger.With("module", "consensus")
+
+	moduleLogger.Info("foo")
+	if want, have := ``, strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("\nwant '%s'\nhave '%s'", want, have)
+	}
+
+	// the loggers derived before the change follow it
+	setOptions(log.AllowError(), log.AllowInfoWith("module", "consensus"))
+	moduleLogger.Info("foo")
+	want := `{"_msg":"foo","level":"info","module":"consensus"}`
+	if have := strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("\nwant '%s'\nhave '%s'", want, have)
+	}
+
+	buf.Reset()
+	logger.With("module", "mempool").Info("foo")
+	if want, have := ``, strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("\nwant '%s'\nhave '%s'", want, have)
+	}
+
+	setOptions(log.AllowNone())
+	moduleLogger.Error("foo")
+	if want, have := ``, strings.TrimSpace(buf.String()); want != have {
+		t.Errorf("\nwant '%s'\nhave '%s'", want, have)
+	}
+}
@@ -127,6 +127,58 @@ func TestSlowClientIsRemovedWithErrOutOfCapacity(t *testing.T) {
 	assertCancelled(t, subscription, pubsub.ErrOutOfCapacity)
 }
 
+func TestSlowClientOverflowPolicies(t *testing.T) {
+	testCases := []struct {
+		policy   pubsub.OverflowPolicy
+		expected []string
+	}{
+		{pubsub.OverflowDropOldest, []string{"Viper", "Iceman"}},
+		{pubsub.OverflowDropNewest, []string{"Fat Cobra", "Viper"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			s := pubsub.NewServer()
+			s.SetLogger(log.TestingLogger())
+			s.Start()
+			defer s.Stop()
+
+			ctx := context.Background()
+			newBlock := map[string][]string{"tm.events.type": {"NewBlock"}}
+			subscription, err := s.SubscribeWithPolicy(ctx, clientID,
+				query.MustParse("tm.events.type='NewBlock'"), 2, tc.policy)
+			require.NoError(t, err)
+			for _, msg := range []string{"Fat Cobra", "Viper", "Iceman"} {
+				err = s.PublishWithEvents(ctx, msg, newBlock)
+				require.NoError(t, err)
+			}
+			// the server has processed the previous messages once it accepts
+			// another one (which doesn't match the query)
+			err = s.PublishWithEvents(ctx, "Ka-Zar", map[string][]string{"tm.events.type": {"NewRoundStep"}})
+			require.NoError(t, err)
+
+			for _, msg := range tc.expected {
+				assertReceive(t, msg, subscription.Out())
+			}
+			assert.EqualValues(t, 1, subscription.Dropped())
+			assert.Nil(t, subscription.Err(), "the subscription should not be cancelled")
+		})
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	for _, policy := range []pubsub.OverflowPolicy{
+		pubsub.OverflowDisconnect, pubsub.OverflowDropOldest, pubsub.OverflowDropNewest} {
+		parsed, err := pubsub.ParseOverflowPolicy(policy.String())
+		require.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+
+	_, err := pubsub.ParseOverflowPolicy("block")
+	assert.Error(t, err)
+}
+
 func TestDifferentClients(t *testing.T) {
 	s := pubsub.NewServer()
 	s.SetLogger(log.TestingLogger())
@@ -2,7 +2,9 @@ package pubsub
 
 import (
 	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
 )
 
 var (
@@ -14,23 +16,73 @@ var (
 	ErrOutOfCapacity = errors.New("client is not pulling messages fast enough")
 )
 
+// OverflowPolicy is what the server does when it publishes a message to a
+// subscription whose buffer is full, because the client is not pulling
+// messages fast enough. It doesn't apply to unbuffered subscriptions, on which
+// the server blocks.
+type OverflowPolicy int
+
+const (
+	// OverflowDisconnect terminates the subscription with ErrOutOfCapacity.
+	OverflowDisconnect OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest buffered message to make room for
+	// the new one.
+	OverflowDropOldest
+	// OverflowDropNewest drops the new message.
+	OverflowDropNewest
+)
+
+var overflowPolicyNames = map[OverflowPolicy]string{
+	OverflowDisconnect: "disconnect",
+	OverflowDropOldest: "drop_oldest",
+	OverflowDropNewest: "drop_newest",
+}
+
+// ParseOverflowPolicy returns the policy with the given name: "disconnect",
+// "drop_oldest" or "drop_newest".
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	for policy, policyName := range overflowPolicyNames {
+		if name == policyName {
+			return policy, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overflow policy %q (want disconnect, drop_oldest or drop_newest)", name)
+}
+
+func (p OverflowPolicy) String() string {
+	if name, ok := overflowPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
 // A Subscription represents a client subscription for a particular query and
 // consists of three things:
 // 1) channel onto which messages and tags are published
 // 2) channel which is closed if a client is too slow or choose to unsubscribe
 // 3) err indicating the reason for (2)
 type Subscription struct {
-	out chan Message
+	out     chan Message
+	policy  OverflowPolicy
+	dropped uint64 // accessed atomically
 
 	cancelled chan struct{}
 	mtx       sync.RWMutex
 	err       error
 }
 
-// NewSubscription returns a new subscription with the given outCapacity.
+// NewSubscription returns a new subscription with the given outCapacity,
+// which is terminated when its buffer overflows.
 func NewSubscription(outCapacity int) *Subscription {
+	return NewSubscriptionWithPolicy(outCapacity, OverflowDisconnect)
+}
+
+// NewSubscriptionWithPolicy returns a new subscription with the given
+// outCapacity and overflow policy.
+func NewSubscriptionWithPolicy(outCapacity int, policy OverflowPolicy) *Subscription {
 	return &Subscription{
 		out:       make(chan Message, outCapacity),
+		policy:    policy,
 		cancelled: make(chan struct{}),
 	}
 }
@@ -52,7 +104,7 @@ func (s *Subscription) Cancelled() <-chan struct{} {
 // If the channel is closed, Err returns a non-nil error explaining why:
 //   - ErrUnsubscribed if the subscriber choose to unsubscribe,
 //   - ErrOutOfCapacity if the subscriber is not pulling messages fast enough
-//   and the channel returned by Out became full,
+//   and the channel returned by Out became full (with OverflowDisconnect),
 // After Err returns a non-nil error, successive calls to Err return the same
 // error.
 func (s *Subscription) Err() error {
@@ -61,6 +113,46 @@ func (s *Subscription) Err() error {
 	return s.err
 }
 
+// Dropped returns the number of messages dropped because the buffer of the
+// subscription was full (see OverflowDropOldest and OverflowDropNewest).
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// deliver publishes the message to the subscription, applying its overflow
+// policy if the buffer is full. It returns false if the subscription must be
+// terminated. Only the server's loop may call it, so that it is the only
+// sender on s.out.
+func (s *Subscription) deliver(msg Message) bool {
+	if cap(s.out) == 0 {
+		// block on unbuffered channel
+		s.out <- msg
+		return true
+	}
+
+	// don't block on buffered channels
+	select {
+	case s.out <- msg:
+		return true
+	default:
+	}
+
+	switch s.policy {
+	case OverflowDropOldest:
+		select {
+		case <-s.out:
+		default: // the client pulled a message in the meantime
+		}
+		// we're the only sender, so there's room now
+		s.out <- msg
+	case OverflowDropNewest:
+	default:
+		return false
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	return true
+}
+
 func (s *Subscription) cancel(err error) {
 	s.mtx.Lock()
 	s.err = err
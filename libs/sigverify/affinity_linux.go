@@ -0,0 +1,15 @@
+// +build linux
+
+package sigverify
+
+import "golang.org/x/sys/unix"
+
+// setAffinity pins the calling thread to the given CPUs.
+func setAffinity(cpus []int) error {
+	var set unix.CPUSet
+	set.Zero()
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	return unix.SchedSetaffinity(0, &set)
+}
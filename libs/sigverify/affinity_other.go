@@ -0,0 +1,10 @@
+// +build !linux
+
+package sigverify
+
+import "errors"
+
+// setAffinity is only supported on Linux.
+func setAffinity(cpus []int) error {
+	return errors.New("CPU affinity is only supported on Linux")
+}
@@ -0,0 +1,140 @@
+// Package sigverify verifies signatures on a dedicated pool of workers, so
+// that verification bursts (e.g. commits during fast sync) don't starve the
+// p2p and consensus goroutines of CPU.
+package sigverify
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/tendermint/tendermint/crypto"
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// queueCapacityPerWorker is the number of signatures queued per worker before
+// VerifyBatch blocks.
+const queueCapacityPerWorker = 64
+
+type job struct {
+	pubKey crypto.PubKey
+	msg    []byte
+	sig    []byte
+	valid  *bool
+	wg     *sync.WaitGroup
+}
+
+// PoolOption sets an optional parameter on the Pool.
+type PoolOption func(*Pool)
+
+// WithCPUs pins the workers to the given CPUs (Linux only).
+func WithCPUs(cpus []int) PoolOption {
+	return func(p *Pool) { p.cpus = cpus }
+}
+
+// WithMetrics sets the metrics.
+func WithMetrics(metrics *Metrics) PoolOption {
+	return func(p *Pool) { p.metrics = metrics }
+}
+
+// Pool verifies signatures on a fixed number of worker goroutines. It
+// implements types.SigVerifier. When it isn't running, signatures are
+// verified inline.
+type Pool struct {
+	cmn.BaseService
+
+	workers int
+	cpus    []int
+	metrics *Metrics
+
+	mtx  sync.RWMutex // guards sending to jobs against closing it
+	jobs chan job
+}
+
+// NewPool returns a Pool with the given number of workers, or DefaultWorkers
+// if workers is not positive.
+func NewPool(workers int, options ...PoolOption) *Pool {
+	if workers <= 0 {
+		workers = DefaultWorkers()
+	}
+	p := &Pool{
+		workers: workers,
+		metrics: NopMetrics(),
+		jobs:    make(chan job, workers*queueCapacityPerWorker),
+	}
+	p.BaseService = *cmn.NewBaseService(nil, "SigVerifyPool", p)
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return p
+}
+
+// DefaultWorkers returns GOMAXPROCS - 1, leaving a core to the other
+// goroutines, or 1 on a single core.
+func DefaultWorkers() int {
+	if n := runtime.GOMAXPROCS(0) - 1; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// OnStart implements cmn.Service by starting the workers.
+func (p *Pool) OnStart() error {
+	for i := 0; i < p.workers; i++ {
+		p.Go("worker", p.worker)
+	}
+	return nil
+}
+
+// OnStop implements cmn.Service. The workers return once the queued
+// signatures are verified.
+func (p *Pool) OnStop() {
+	p.mtx.Lock()
+	close(p.jobs)
+	p.mtx.Unlock()
+}
+
+func (p *Pool) worker() {
+	if len(p.cpus) > 0 {
+		// The thread is never unlocked, so it exits along with the worker
+		// rather than running other goroutines with the affinity set.
+		runtime.LockOSThread()
+		if err := setAffinity(p.cpus); err != nil {
+			p.Logger.Error("Failed to set the CPU affinity of the worker", "cpus", p.cpus, "err", err)
+		}
+	}
+
+	for j := range p.jobs {
+		p.metrics.QueueDepth.Set(float64(len(p.jobs)))
+		*j.valid = j.pubKey.VerifyBytes(j.msg, j.sig)
+		j.wg.Done()
+	}
+}
+
+// VerifyBatch returns, for each i, whether sigs[i] is a valid signature of
+// msgs[i] by pubKeys[i]. It blocks until all the signatures are verified.
+func (p *Pool) VerifyBatch(pubKeys []crypto.PubKey, msgs, sigs [][]byte) []bool {
+	p.mtx.RLock()
+	if !p.IsRunning() {
+		p.mtx.RUnlock()
+		valid := make([]bool, len(pubKeys))
+		for i, pubKey := range pubKeys {
+			valid[i] = pubKey.VerifyBytes(msgs[i], sigs[i])
+		}
+		return valid
+	}
+
+	valid := make([]bool, len(pubKeys))
+	var wg sync.WaitGroup
+	wg.Add(len(pubKeys))
+	for i, pubKey := range pubKeys {
+		p.jobs <- job{pubKey: pubKey, msg: msgs[i], sig: sigs[i], valid: &valid[i], wg: &wg}
+	}
+	p.metrics.QueueDepth.Set(float64(len(p.jobs)))
+	p.mtx.RUnlock()
+
+	p.metrics.Signatures.Add(float64(len(pubKeys)))
+	wg.Wait()
+	return valid
+}
@@ -0,0 +1,64 @@
+package sigverify
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// signatures returns n signatures, the odd ones being invalid.
+func signatures(t *testing.T, n int) (pubKeys []crypto.PubKey, msgs, sigs [][]byte, expected []bool) {
+	for i := 0; i < n; i++ {
+		privKey := ed25519.GenPrivKey()
+		msg := []byte(fmt.Sprintf("msg %d", i))
+		sig, err := privKey.Sign(msg)
+		require.NoError(t, err)
+		if i%2 == 1 {
+			msg = []byte("tampered")
+		}
+		pubKeys = append(pubKeys, privKey.PubKey())
+		msgs = append(msgs, msg)
+		sigs = append(sigs, sig)
+		expected = append(expected, i%2 == 0)
+	}
+	return
+}
+
+func TestPoolVerifyBatch(t *testing.T) {
+	pubKeys, msgs, sigs, expected := signatures(t, 100)
+
+	pool := NewPool(3)
+	pool.SetLogger(log.TestingLogger())
+
+	// inline before the pool is started
+	assert.Equal(t, expected, pool.VerifyBatch(pubKeys, msgs, sigs))
+
+	require.NoError(t, pool.Start())
+	assert.Equal(t, map[string]int{"worker": 3}, pool.Goroutines())
+	assert.Equal(t, expected, pool.VerifyBatch(pubKeys, msgs, sigs))
+	assert.Empty(t, pool.VerifyBatch(nil, nil, nil))
+
+	require.NoError(t, pool.Stop())
+	require.NoError(t, pool.WaitGoroutines(time.Second))
+
+	// inline after the pool is stopped
+	assert.Equal(t, expected, pool.VerifyBatch(pubKeys, msgs, sigs))
+}
+
+func TestPoolVerifyBatchWithCPUs(t *testing.T) {
+	pubKeys, msgs, sigs, expected := signatures(t, 10)
+
+	pool := NewPool(2, WithCPUs([]int{0}))
+	pool.SetLogger(log.TestingLogger())
+	require.NoError(t, pool.Start())
+	defer pool.Stop() // nolint: errcheck
+
+	assert.Equal(t, expected, pool.VerifyBatch(pubKeys, msgs, sigs))
+}
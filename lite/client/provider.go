@@ -16,6 +16,10 @@ import (
 	"github.com/tendermint/tendermint/types"
 )
 
+// validatorsPerPage is the page size used to fetch validator sets (the
+// maximum the RPC server allows).
+const validatorsPerPage = 100
+
 // SignStatusClient combines a SignClient and StatusClient.
 type SignStatusClient interface {
 	rpcclient.SignClient
@@ -106,12 +110,20 @@ func (p *provider) getValidatorSet(chainID string, height int64) (valset *types.
 		err = fmt.Errorf("expected height >= 1, got height %v", height)
 		return
 	}
-	res, err := p.client.Validators(&height)
-	if err != nil {
-		// TODO pass through other types of errors.
-		return nil, lerr.ErrUnknownValidators(chainID, height)
+	// the validators are paginated, fetch all of them
+	var vals []*types.Validator
+	for page := 1; ; page++ {
+		res, err := p.client.Validators(&height, page, validatorsPerPage)
+		if err != nil {
+			// TODO pass through other types of errors.
+			return nil, lerr.ErrUnknownValidators(chainID, height)
+		}
+		vals = append(vals, res.Validators...)
+		if len(res.Validators) == 0 || len(vals) >= res.Total {
+			break
+		}
 	}
-	valset = types.NewValidatorSet(res.Validators)
+	valset = types.NewValidatorSet(vals)
 	return
 }
 
@@ -10,7 +10,6 @@ import (
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/rpc/client"
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
-	"github.com/tendermint/tendermint/rpc/core"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
 	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
@@ -46,7 +45,6 @@ func StartProxy(c rpcclient.Client, listenAddr string, logger log.Logger, maxOpe
 	}
 	wm := rpcserver.NewWebsocketManager(r, cdc, rpcserver.OnDisconnect(unsubscribeFromAllEvents))
 	wm.SetLogger(logger)
-	core.SetLogger(logger)
 	mux.HandleFunc(wsEndpoint, wm.WebsocketHandler)
 
 	config := rpcserver.DefaultConfig()
@@ -70,13 +68,14 @@ func RPCRoutes(c rpcclient.Client) map[string]*rpcserver.RPCFunc {
 		"unsubscribe_all": rpcserver.NewWSRPCFunc(c.(Wrapper).UnsubscribeAllWS, ""),
 
 		// info API
-		"status":     rpcserver.NewRPCFunc(makeStatusFunc(c), ""),
-		"blockchain": rpcserver.NewRPCFunc(makeBlockchainInfoFunc(c), "minHeight,maxHeight"),
-		"genesis":    rpcserver.NewRPCFunc(makeGenesisFunc(c), ""),
-		"block":      rpcserver.NewRPCFunc(makeBlockFunc(c), "height"),
-		"commit":     rpcserver.NewRPCFunc(makeCommitFunc(c), "height"),
-		"tx":         rpcserver.NewRPCFunc(makeTxFunc(c), "hash,prove"),
-		"validators": rpcserver.NewRPCFunc(makeValidatorsFunc(c), "height"),
+		"status":        rpcserver.NewRPCFunc(makeStatusFunc(c), ""),
+		"blockchain":    rpcserver.NewRPCFunc(makeBlockchainInfoFunc(c), "minHeight,maxHeight"),
+		"genesis":       rpcserver.NewRPCFunc(makeGenesisFunc(c), ""),
+		"block":         rpcserver.NewRPCFunc(makeBlockFunc(c), "height"),
+		"block_results": rpcserver.NewRPCFunc(makeBlockResultsFunc(c), "height"),
+		"commit":        rpcserver.NewRPCFunc(makeCommitFunc(c), "height"),
+		"tx":            rpcserver.NewRPCFunc(makeTxFunc(c), "hash,prove"),
+		"validators":    rpcserver.NewRPCFunc(makeValidatorsFunc(c), "height,page,per_page"),
 
 		// broadcast API
 		"broadcast_tx_commit": rpcserver.NewRPCFunc(makeBroadcastTxCommitFunc(c), "tx"),
@@ -117,6 +116,15 @@ func makeBlockFunc(c rpcclient.Client) func(ctx *rpctypes.Context, height *int64
 	}
 }
 
+func makeBlockResultsFunc(c rpcclient.Client) func(
+	ctx *rpctypes.Context,
+	height *int64,
+) (*ctypes.ResultBlockResults, error) {
+	return func(ctx *rpctypes.Context, height *int64) (*ctypes.ResultBlockResults, error) {
+		return c.BlockResults(height)
+	}
+}
+
 func makeCommitFunc(c rpcclient.Client) func(ctx *rpctypes.Context, height *int64) (*ctypes.ResultCommit, error) {
 	return func(ctx *rpctypes.Context, height *int64) (*ctypes.ResultCommit, error) {
 		return c.Commit(height)
@@ -132,9 +140,10 @@ func makeTxFunc(c rpcclient.Client) func(ctx *rpctypes.Context, hash []byte, pro
 func makeValidatorsFunc(c rpcclient.Client) func(
 	ctx *rpctypes.Context,
 	height *int64,
+	page, perPage int,
 ) (*ctypes.ResultValidators, error) {
-	return func(ctx *rpctypes.Context, height *int64) (*ctypes.ResultValidators, error) {
-		return c.Validators(height)
+	return func(ctx *rpctypes.Context, height *int64, page, perPage int) (*ctypes.ResultValidators, error) {
+		return c.Validators(height, page, perPage)
 	}
 }
 
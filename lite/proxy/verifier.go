@@ -1,6 +1,9 @@
 package proxy
 
 import (
+	"bytes"
+	"fmt"
+
 	"github.com/pkg/errors"
 
 	log "github.com/tendermint/tendermint/libs/log"
@@ -20,15 +23,7 @@ func NewVerifier(
 	logger = logger.With("module", "lite/proxy")
 	logger.Info("lite/proxy/NewVerifier()...", "chainID", chainID, "rootDir", rootDir, "client", client)
 
-	memProvider := lite.NewDBProvider("trusted.mem", dbm.NewMemDB()).SetLimit(cacheSize)
-	lvlProvider := lite.NewDBProvider("trusted.lvl", dbm.NewDB("trust-base", dbm.GoLevelDBBackend, rootDir))
-	trust := lite.NewMultiProvider(
-		memProvider,
-		lvlProvider,
-	)
-	source := lclient.NewProvider(chainID, client)
-	cert := lite.NewDynamicVerifier(chainID, trust, source)
-	cert.SetLogger(logger) // Sets logger recursively.
+	trust, source, cert := newVerifier(chainID, rootDir, client, logger, cacheSize)
 
 	// TODO: Make this more secure, e.g. make it interactive in the console?
 	_, err := trust.LatestFullCommit(chainID, 1, 1<<63-1)
@@ -46,3 +41,74 @@ func NewVerifier(
 
 	return cert, nil
 }
+
+// NewVerifierFromTrustedRoot returns a verifier of the headers of the source
+// which trusts the header of the given height and hash, obtained from a
+// trusted party (e.g. a block explorer, or the operator of another node),
+// rather than the first header of the source. The trusted root is only used
+// to initialize the trust store of rootDir: once the store holds a trusted
+// header, the verifier starts from the latest one, and the trusted height and
+// hash may be omitted (0 and nil).
+func NewVerifierFromTrustedRoot(
+	chainID,
+	rootDir string,
+	client lclient.SignStatusClient,
+	logger log.Logger,
+	cacheSize int,
+	trustedHeight int64,
+	trustedHash []byte,
+) (*lite.DynamicVerifier, error) {
+
+	logger = logger.With("module", "lite/proxy")
+	trust, source, cert := newVerifier(chainID, rootDir, client, logger, cacheSize)
+
+	if fc, err := trust.LatestFullCommit(chainID, 1, 1<<63-1); err == nil {
+		logger.Info("Starting from the latest trusted header", "height", fc.Height(), "hash", fc.SignedHeader.Hash())
+		return cert, nil
+	}
+	if trustedHeight <= 0 || len(trustedHash) == 0 {
+		return nil, errors.New("no trusted header in the trust store: a trusted height and hash are required")
+	}
+
+	fc, err := source.LatestFullCommit(chainID, trustedHeight, trustedHeight)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetching source full commit @ height %d", trustedHeight)
+	}
+	if fc.Height() != trustedHeight {
+		return nil, fmt.Errorf("the source returned the header of height %d, not %d", fc.Height(), trustedHeight)
+	}
+	if hash := fc.SignedHeader.Hash(); !bytes.Equal(hash, trustedHash) {
+		return nil, fmt.Errorf("the header of height %d from the source has hash %X, not the trusted %X",
+			trustedHeight, hash, trustedHash)
+	}
+	if err := fc.ValidateFull(chainID); err != nil {
+		return nil, errors.Wrap(err, "invalid trusted full commit")
+	}
+	if err := trust.SaveFullCommit(fc); err != nil {
+		return nil, errors.Wrap(err, "saving full commit to trusted")
+	}
+	logger.Info("Initialized the trust store", "height", trustedHeight, "hash", fc.SignedHeader.Hash())
+	return cert, nil
+}
+
+// newVerifier returns the trust store of rootDir, the provider of the headers
+// of the client, and a verifier of the latter against the former.
+func newVerifier(
+	chainID,
+	rootDir string,
+	client lclient.SignStatusClient,
+	logger log.Logger,
+	cacheSize int,
+) (lite.PersistentProvider, lite.Provider, *lite.DynamicVerifier) {
+
+	memProvider := lite.NewDBProvider("trusted.mem", dbm.NewMemDB()).SetLimit(cacheSize)
+	lvlProvider := lite.NewDBProvider("trusted.lvl", dbm.NewDB("trust-base", dbm.GoLevelDBBackend, rootDir))
+	trust := lite.NewMultiProvider(
+		memProvider,
+		lvlProvider,
+	)
+	source := lclient.NewProvider(chainID, client)
+	cert := lite.NewDynamicVerifier(chainID, trust, source)
+	cert.SetLogger(logger) // Sets logger recursively.
+	return trust, source, cert
+}
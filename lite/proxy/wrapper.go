@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
@@ -11,6 +12,7 @@ import (
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+	"github.com/tendermint/tendermint/types"
 )
 
 var _ rpcclient.Client = Wrapper{}
@@ -149,6 +151,89 @@ func (w Wrapper) Commit(height *int64) (*ctypes.ResultCommit, error) {
 	return res, err
 }
 
+// Status returns the status of the node, after checking that its latest block
+// is the one of the verified header of that height. The rest of the status
+// (node info, validator info, whether it's catching up) can't be verified.
+func (w Wrapper) Status() (*ctypes.ResultStatus, error) {
+	res, err := w.Client.Status()
+	if err != nil {
+		return nil, err
+	}
+	sh, err := GetCertifiedCommit(res.SyncInfo.LatestBlockHeight, w.Client, w.cert)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(sh.Hash(), res.SyncInfo.LatestBlockHash) {
+		return nil, fmt.Errorf("latest block hash %X doesn't match the verified header %X",
+			res.SyncInfo.LatestBlockHash, sh.Hash())
+	}
+	return res, nil
+}
+
+// Validators returns a page of the validator set of the given height, after
+// checking that the whole set hashes to the ValidatorsHash of the verified
+// header of that height. The other pages are fetched to hash the set.
+func (w Wrapper) Validators(height *int64, page, perPage int) (*ctypes.ResultValidators, error) {
+	res, err := w.Client.Validators(height, page, perPage)
+	if err != nil {
+		return nil, err
+	}
+	vals := res.Validators
+	if len(vals) < res.Total {
+		vals, err = w.allValidators(res.BlockHeight, res.Total)
+		if err != nil {
+			return nil, err
+		}
+	}
+	sh, err := GetCertifiedCommit(res.BlockHeight, w.Client, w.cert)
+	if err != nil {
+		return nil, err
+	}
+	bzs := make([][]byte, len(vals))
+	for i, val := range vals {
+		bzs[i] = val.Bytes()
+	}
+	if hash := merkle.SimpleHashFromByteSlices(bzs); !bytes.Equal(hash, sh.ValidatorsHash) {
+		return nil, fmt.Errorf("validators hash %X doesn't match the verified header %X", hash, sh.ValidatorsHash)
+	}
+	return res, nil
+}
+
+// allValidators returns the validator set of the height, in order.
+func (w Wrapper) allValidators(height int64, total int) ([]*types.Validator, error) {
+	const perPage = 100 // the max
+	vals := make([]*types.Validator, 0, total)
+	for page := 1; len(vals) < total; page++ {
+		res, err := w.Client.Validators(&height, page, perPage)
+		if err != nil {
+			return nil, err
+		}
+		if res.Total != total || len(res.Validators) == 0 {
+			return nil, fmt.Errorf("inconsistent pages of the validators of height %d", height)
+		}
+		vals = append(vals, res.Validators...)
+	}
+	return vals, nil
+}
+
+// BlockResults returns the results of the block at the given height, after
+// checking that they hash to the LastResultsHash of the verified header of the
+// next height: it waits for the next block.
+func (w Wrapper) BlockResults(height *int64) (*ctypes.ResultBlockResults, error) {
+	res, err := w.Client.BlockResults(height)
+	if err != nil {
+		return nil, err
+	}
+	sh, err := GetCertifiedCommit(res.Height+1, w.Client, w.cert)
+	if err != nil {
+		return nil, err
+	}
+	if hash := types.NewResults(res.Results.DeliverTx).Hash(); !bytes.Equal(hash, sh.LastResultsHash) {
+		return nil, fmt.Errorf("results hash %X doesn't match the verified header %X", hash, sh.LastResultsHash)
+	}
+	return res, nil
+}
+
 func (w Wrapper) RegisterOpDecoder(typ string, dec merkle.OpDecoder) {
 	w.prt.RegisterOpDecoder(typ, dec)
 }
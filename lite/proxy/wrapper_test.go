@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/rpc/client"
+)
+
+func TestVerifierFromTrustedRoot(t *testing.T) {
+	cl := client.NewLocal(node)
+	require.NoError(t, client.WaitForHeight(cl, 3, nil))
+	commit, err := cl.Commit(int64Ptr(2))
+	require.NoError(t, err)
+	trustedHash := commit.SignedHeader.Hash()
+
+	dir, err := ioutil.TempDir("", "lite_proxy_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logger := log.TestingLogger()
+
+	// a trusted root is required to start (NOTE: each verifier opens its own
+	// trust store, which stays open)
+	_, err = NewVerifierFromTrustedRoot(chainID, filepath.Join(dir, "none"), cl, logger, 10, 0, nil)
+	assert.Error(t, err)
+	// which must be the header of the node
+	_, err = NewVerifierFromTrustedRoot(chainID, filepath.Join(dir, "wrong"), cl, logger, 10, 2,
+		[]byte("not the hash of the header 2...."))
+	assert.Error(t, err)
+
+	cert, err := NewVerifierFromTrustedRoot(chainID, filepath.Join(dir, "trusted"), cl, logger, 10, 2, trustedHash)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, cert.LastTrustedHeight())
+
+	w := SecureClient(cl, cert)
+	_, err = w.Status()
+	require.NoError(t, err)
+	vals, err := w.Validators(int64Ptr(2), 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, vals.Validators, 1)
+	results, err := w.BlockResults(int64Ptr(2))
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, results.Height)
+}
+
+func int64Ptr(h int64) *int64 {
+	return &h
+}
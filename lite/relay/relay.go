@@ -0,0 +1,223 @@
+// Package relay tracks the headers of a remote chain with the lite client,
+// and delivers them in order, verified, with the changes of their validator
+// sets. It is a building block for IBC relayers and bridges, which submit the
+// headers of a chain to a light client of it running on another chain.
+package relay
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/pkg/errors"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/lite"
+	lerr "github.com/tendermint/tendermint/lite/errors"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBufferSize   = 100
+)
+
+// Header is a verified header of the remote chain, with the validators which
+// signed it and the validators of the next header.
+type Header struct {
+	lite.FullCommit
+
+	// ValidatorUpdates are the changes from Validators to NextValidators, as
+	// in the EndBlock of the app: the new validators and the ones whose
+	// voting power changed, and the removed ones with a voting power of 0.
+	// It is nil if the validator set doesn't change.
+	ValidatorUpdates []*types.Validator
+}
+
+// Option sets an optional parameter on the Relay.
+type Option func(*Relay)
+
+// PollInterval sets how often the source is polled for new headers. Defaults
+// to 1s.
+func PollInterval(d time.Duration) Option {
+	return func(r *Relay) { r.pollInterval = d }
+}
+
+// BufferSize sets the number of verified headers buffered in the channel
+// before the relay waits for them to be read. Defaults to 100.
+func BufferSize(n int) Option {
+	return func(r *Relay) { r.bufferSize = n }
+}
+
+// Relay polls a source (e.g. a client.HTTPProvider of a node of the remote
+// chain) for new headers, verifies each of them in order with a
+// lite.DynamicVerifier from the trusted provider, and sends them on the
+// Headers channel.
+//
+// The verified headers are saved in the trusted provider: a relay restarted
+// with the same (persistent) trusted provider resumes after the latest header
+// it verified.
+type Relay struct {
+	cmn.BaseService
+
+	chainID  string
+	trusted  lite.PersistentProvider
+	source   lite.Provider
+	verifier *lite.DynamicVerifier
+
+	pollInterval time.Duration
+	bufferSize   int
+
+	headers    chan Header
+	lastHeight int64
+	err        error
+}
+
+// NewRelay returns a Relay of the chain. The trusted provider must hold a
+// trusted header of the chain (the root of trust), after which the headers
+// are relayed.
+func NewRelay(chainID string, trusted lite.PersistentProvider, source lite.Provider, options ...Option) *Relay {
+	r := &Relay{
+		chainID:      chainID,
+		trusted:      trusted,
+		source:       source,
+		verifier:     lite.NewDynamicVerifier(chainID, trusted, source),
+		pollInterval: defaultPollInterval,
+		bufferSize:   defaultBufferSize,
+	}
+	r.BaseService = *cmn.NewBaseService(nil, "Relay", r)
+
+	for _, option := range options {
+		option(r)
+	}
+	r.headers = make(chan Header, r.bufferSize)
+
+	return r
+}
+
+// OnStart implements cmn.Service by starting to relay the headers after the
+// latest trusted one.
+func (r *Relay) OnStart() error {
+	r.verifier.SetLogger(r.Logger)
+
+	fc, err := r.trusted.LatestFullCommit(r.chainID, 1, 1<<63-1)
+	if err != nil {
+		return errors.Wrap(err, "loading the trusted header")
+	}
+	r.lastHeight = fc.Height()
+
+	r.Go("relayRoutine", r.relayRoutine)
+	return nil
+}
+
+// Headers returns the channel of the verified headers, in increasing order of
+// height. It is closed when the relay stops: see Err.
+func (r *Relay) Headers() <-chan Header {
+	return r.headers
+}
+
+// Err returns the verification error which stopped the relay, if any. It
+// must only be called once the Headers channel is closed.
+func (r *Relay) Err() error {
+	return r.err
+}
+
+func (r *Relay) relayRoutine() {
+	defer close(r.headers)
+
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.relayNewHeaders(); err != nil {
+			r.Logger.Error("Failed to verify a header; stopping the relay", "err", err)
+			r.err = err
+			r.Stop() // nolint: errcheck
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.Quit():
+			return
+		}
+	}
+}
+
+// relayNewHeaders sends the verified headers of the source after the last
+// one sent. It returns an error if a header fails the verification; the
+// errors of the source are logged, and the header retried on the next poll.
+func (r *Relay) relayNewHeaders() error {
+	latest, err := r.source.LatestFullCommit(r.chainID, r.lastHeight+1, 0)
+	if err != nil {
+		if !lerr.IsErrCommitNotFound(err) {
+			r.Logger.Info("Failed to get the latest header from the source", "err", err)
+		}
+		return nil
+	}
+
+	for h := r.lastHeight + 1; h <= latest.Height(); h++ {
+		fc := latest
+		if h < latest.Height() {
+			fc, err = r.source.LatestFullCommit(r.chainID, h, h)
+			if err != nil {
+				r.Logger.Info("Failed to get a header from the source", "height", h, "err", err)
+				return nil
+			}
+			if fc.Height() != h {
+				r.Logger.Info("Header missing from the source", "height", h)
+				return nil
+			}
+		}
+
+		if err := r.verify(fc); err != nil {
+			if lerr.IsErrCommitNotFound(err) {
+				r.Logger.Info("Failed to get a header from the source", "height", h, "err", err)
+				return nil
+			}
+			return errors.Wrapf(err, "header %v", h)
+		}
+
+		header := Header{
+			FullCommit:       fc,
+			ValidatorUpdates: validatorUpdates(fc.Validators, fc.NextValidators),
+		}
+		select {
+		case r.headers <- header:
+			r.lastHeight = h
+		case <-r.Quit():
+			return nil
+		}
+	}
+	return nil
+}
+
+// verify checks the full commit is consistent (its validators are the ones of
+// the header, and signed it), and that the header is trusted.
+func (r *Relay) verify(fc lite.FullCommit) error {
+	if err := fc.ValidateFull(r.chainID); err != nil {
+		return err
+	}
+	return r.verifier.Verify(fc.SignedHeader)
+}
+
+// validatorUpdates returns the updates turning vals into nextVals, or nil if
+// they are the same.
+func validatorUpdates(vals, nextVals *types.ValidatorSet) []*types.Validator {
+	if bytes.Equal(vals.Hash(), nextVals.Hash()) {
+		return nil
+	}
+
+	var updates []*types.Validator
+	for _, val := range nextVals.Validators {
+		_, prev := vals.GetByAddress(val.Address)
+		if prev == nil || prev.VotingPower != val.VotingPower {
+			updates = append(updates, types.NewValidator(val.PubKey, val.VotingPower))
+		}
+	}
+	for _, val := range vals.Validators {
+		if !nextVals.HasAddress(val.Address) {
+			updates = append(updates, types.NewValidator(val.PubKey, 0))
+		}
+	}
+	return updates
+}
@@ -0,0 +1,183 @@
+package relay
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/lite"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+const testChainID = "relay-test"
+
+// genFullCommits returns the full commits of the heights 1 to 9 of a chain
+// whose validator set changes after the heights 4 (a validator is added), 7
+// (the voting powers change) and 8 (a validator is removed).
+func genFullCommits() []lite.FullCommit {
+	keys := lite.GenSecpPrivKeys(4)
+	moreKeys := keys.ExtendSecp(1)
+	valsets := map[int64]*types.ValidatorSet{
+		5: moreKeys.ToValidators(10, 0),
+		6: moreKeys.ToValidators(10, 0),
+		7: moreKeys.ToValidators(10, 0),
+		8: moreKeys.ToValidators(10, 1),
+	}
+
+	fcz := make([]lite.FullCommit, 0, 9)
+	for h := int64(1); h <= 9; h++ {
+		signers, vals := keys, keys.ToValidators(10, 0)
+		if v, ok := valsets[h]; ok {
+			signers, vals = moreKeys, v
+		}
+		nextVals := keys.ToValidators(10, 0)
+		if v, ok := valsets[h+1]; ok {
+			nextVals = v
+		}
+		fcz = append(fcz, signers.GenFullCommit(testChainID, h, nil, vals, nextVals,
+			[]byte(fmt.Sprintf("h=%d", h)), []byte("params"), []byte("results"), 0, len(signers)))
+	}
+	return fcz
+}
+
+func newProviders(t *testing.T, fcz []lite.FullCommit) (trusted, source *lite.DBProvider) {
+	trusted = lite.NewDBProvider("trusted", dbm.NewMemDB())
+	source = lite.NewDBProvider("source", dbm.NewMemDB())
+	require.NoError(t, trusted.SaveFullCommit(fcz[0]))
+	for _, fc := range fcz {
+		require.NoError(t, source.SaveFullCommit(fc))
+	}
+	return trusted, source
+}
+
+func startRelay(t *testing.T, trusted lite.PersistentProvider, source lite.Provider) *Relay {
+	r := NewRelay(testChainID, trusted, source, PollInterval(10*time.Millisecond))
+	r.SetLogger(log.TestingLogger())
+	require.NoError(t, r.Start())
+	return r
+}
+
+func readHeader(t *testing.T, r *Relay) Header {
+	select {
+	case header, ok := <-r.Headers():
+		if !ok {
+			t.Fatalf("channel closed: %v", r.Err())
+		}
+		return header
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a header")
+	}
+	return Header{}
+}
+
+func TestRelayHeaders(t *testing.T) {
+	fcz := genFullCommits()
+	trusted, source := newProviders(t, fcz[:6])
+	r := startRelay(t, trusted, source)
+
+	// the headers after the trusted one, in order
+	for h := int64(2); h <= 6; h++ {
+		header := readHeader(t, r)
+		require.Equal(t, h, header.Height())
+		assert.Equal(t, fcz[h-1].SignedHeader.Hash(), header.SignedHeader.Hash())
+
+		switch h {
+		case 4:
+			// the added validator
+			require.Len(t, header.ValidatorUpdates, 1)
+			assert.False(t, header.Validators.HasAddress(header.ValidatorUpdates[0].Address))
+			assert.EqualValues(t, 10, header.ValidatorUpdates[0].VotingPower)
+		default:
+			assert.Nil(t, header.ValidatorUpdates, "height %v", h)
+		}
+	}
+
+	// the new headers of the source
+	require.NoError(t, source.SaveFullCommit(fcz[6]))
+	header := readHeader(t, r)
+	require.EqualValues(t, 7, header.Height())
+	// voting powers 10 to 14: all but the first change
+	assert.Len(t, header.ValidatorUpdates, 4)
+	for _, val := range header.ValidatorUpdates {
+		_, next := header.NextValidators.GetByAddress(val.Address)
+		require.NotNil(t, next)
+		assert.Equal(t, next.VotingPower, val.VotingPower)
+	}
+
+	require.NoError(t, r.Stop())
+	_, ok := <-r.Headers()
+	assert.False(t, ok)
+	assert.NoError(t, r.Err())
+
+	// a relay with the same trusted provider resumes after the last header
+	require.NoError(t, source.SaveFullCommit(fcz[7]))
+	require.NoError(t, source.SaveFullCommit(fcz[8]))
+	r = startRelay(t, trusted, source)
+	defer r.Stop() // nolint: errcheck
+	header = readHeader(t, r)
+	require.EqualValues(t, 8, header.Height())
+	// back to a voting power of 10, and the removed validator
+	require.Len(t, header.ValidatorUpdates, 4)
+	removed := 0
+	for _, val := range header.ValidatorUpdates {
+		if val.VotingPower == 0 {
+			removed++
+			assert.False(t, header.NextValidators.HasAddress(val.Address))
+		}
+	}
+	assert.Equal(t, 1, removed)
+	header = readHeader(t, r)
+	require.EqualValues(t, 9, header.Height())
+	assert.Nil(t, header.ValidatorUpdates)
+}
+
+func TestRelayStopsOnInvalidHeader(t *testing.T) {
+	fcz := genFullCommits()
+	trusted, source := newProviders(t, fcz[:2])
+
+	// signed by 1 validator out of 4
+	keys := lite.GenSecpPrivKeys(4)
+	vals := fcz[1].NextValidators
+	bad := keys.GenFullCommit(testChainID, 3, nil, vals, vals,
+		[]byte("bad"), []byte("params"), []byte("results"), 0, 1)
+	require.NoError(t, source.SaveFullCommit(bad))
+
+	r := startRelay(t, trusted, source)
+	header := readHeader(t, r)
+	require.EqualValues(t, 2, header.Height())
+
+	select {
+	case _, ok := <-r.Headers():
+		require.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the relay to stop")
+	}
+	assert.Error(t, r.Err())
+	require.NoError(t, r.WaitGoroutines(5*time.Second))
+	assert.False(t, r.IsRunning())
+}
+
+func TestRelayNeedsTrustedHeader(t *testing.T) {
+	r := NewRelay(testChainID,
+		lite.NewDBProvider("trusted", dbm.NewMemDB()),
+		lite.NewDBProvider("source", dbm.NewMemDB()))
+	assert.Error(t, r.Start())
+}
+
+func TestValidatorUpdates(t *testing.T) {
+	keys := lite.GenSecpPrivKeys(3)
+	vals := keys.ToValidators(10, 0)
+	assert.Nil(t, validatorUpdates(vals, keys.ToValidators(10, 0)))
+
+	nextKeys := append(lite.GenSecpPrivKeys(0), keys[:2]...).ExtendSecp(1)
+	updates := validatorUpdates(vals, nextKeys.ToValidators(10, 0))
+	require.Len(t, updates, 2)
+	assert.EqualValues(t, 10, updates[0].VotingPower)
+	assert.Equal(t, keys[2].PubKey().Address(), updates[1].Address)
+	assert.EqualValues(t, 0, updates[1].VotingPower)
+}
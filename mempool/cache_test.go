@@ -5,10 +5,12 @@ import (
 	"crypto/sha256"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/tendermint/tendermint/abci/example/kvstore"
 	abci "github.com/tendermint/tendermint/abci/types"
+	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/proxy"
 	"github.com/tendermint/tendermint/types"
 )
@@ -100,3 +102,57 @@ func TestCacheAfterUpdate(t *testing.T) {
 		mempool.Flush()
 	}
 }
+
+func TestCuckooCache(t *testing.T) {
+	cache := newCuckooTxCache(1000)
+	txs := make([]types.Tx, 3000)
+	for i := range txs {
+		txs[i] = make([]byte, 32)
+		rand.Read(txs[i]) // nolint: gosec
+		require.True(t, cache.Push(txs[i]), "#%d", i)
+		require.False(t, cache.Push(txs[i]), "#%d", i)
+	}
+
+	// the txs of the current and previous filters are seen, not the older ones
+	for i, tx := range txs {
+		assert.Equal(t, i >= 1000, cache.Has(txKey(tx)), "#%d", i)
+	}
+
+	cache.Remove(txs[2500])
+	assert.False(t, cache.Has(txKey(txs[2500])))
+	assert.True(t, cache.Push(txs[2500]))
+
+	cache.Reset()
+	assert.False(t, cache.Has(txKey(txs[2999])))
+}
+
+func TestCuckooCacheFile(t *testing.T) {
+	app := kvstore.NewKVStoreApplication()
+	cc := proxy.NewLocalClientCreator(app)
+	config := cfg.ResetTestRoot("mempool_test")
+	config.Mempool.CacheType = cfg.MempoolCacheCuckoo
+	mempool, cleanup := newMempoolWithAppAndConfig(cc, config)
+	defer cleanup()
+	mempool.InitCacheFile()
+
+	committed, rejected := types.Tx("committed"), types.Tx("rejected")
+	require.NoError(t, mempool.CheckTx(committed, nil))
+	require.NoError(t, mempool.CheckTx(rejected, nil))
+	err := mempool.Update(1, types.Txs{committed, rejected}, []*abci.ResponseDeliverTx{
+		{Code: abci.CodeTypeOK},
+		{Code: 1},
+	}, nil, nil)
+	require.NoError(t, err)
+
+	// resetting the cache doesn't forget the committed tx
+	mempool.ResetCache()
+	assert.Equal(t, ErrTxInCache, mempool.CheckTx(committed, nil))
+
+	// after a restart, the committed tx is still seen, and the rejected one
+	// can be resubmitted
+	restarted, _ := newMempoolWithAppAndConfig(cc, config)
+	restarted.InitCacheFile()
+	defer restarted.CloseCacheFile()
+	assert.Equal(t, ErrTxInCache, restarted.CheckTx(committed, nil))
+	assert.NoError(t, restarted.CheckTx(rejected, nil))
+}
@@ -5,6 +5,7 @@ import (
 	"container/list"
 	"crypto/sha256"
 	"fmt"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -60,10 +61,15 @@ type CListMempool struct {
 	// Keep a cache of already-seen txs.
 	// This reduces the pressure on the proxyApp.
 	cache txCache
+	// File the cache is saved to after each block, "" if it isn't
+	cacheFile string
 
 	// A log of mempool txs
 	wal *auto.AutoFile
 
+	// A log of the rejected txs, nil if disabled
+	rejections *rejectionLog
+
 	logger log.Logger
 
 	metrics *Metrics
@@ -93,7 +99,11 @@ func NewCListMempool(
 		metrics:       NopMetrics(),
 	}
 	if config.CacheSize > 0 {
-		mempool.cache = newMapTxCache(config.CacheSize)
+		if config.CacheType == cfg.MempoolCacheCuckoo {
+			mempool.cache = newCuckooTxCache(config.CacheSize)
+		} else {
+			mempool.cache = newMapTxCache(config.CacheSize)
+		}
 	} else {
 		mempool.cache = nopTxCache{}
 	}
@@ -114,6 +124,16 @@ func (mem *CListMempool) SetLogger(l log.Logger) {
 	mem.logger = l
 }
 
+// SetLimits changes the maximum number of txs in the mempool and their
+// maximum total size. The txs above the new limits aren't removed: the
+// mempool is full until it shrinks below them.
+func (mem *CListMempool) SetLimits(size int, maxTxsBytes int64) {
+	mem.proxyMtx.Lock()
+	defer mem.proxyMtx.Unlock()
+	mem.config.Size = size
+	mem.config.MaxTxsBytes = maxTxsBytes
+}
+
 // WithPreCheck sets a filter for the mempool to reject a tx if f(tx) returns
 // false. This is ran before CheckTx.
 func WithPreCheck(f PreCheckFunc) CListMempoolOption {
@@ -156,6 +176,93 @@ func (mem *CListMempool) CloseWAL() {
 	mem.wal = nil
 }
 
+// *panics* if can't create directory or open file.
+// *not thread safe*
+func (mem *CListMempool) InitRejectionsLog() {
+	rl, err := openRejectionLog(mem.config.RejectionsLogDir(), mem.config.RejectionsLogMaxSize)
+	if err != nil {
+		panic(errors.Wrap(err, "Error opening the rejections log"))
+	}
+	mem.rejections = rl
+}
+
+func (mem *CListMempool) CloseRejectionsLog() {
+	mem.proxyMtx.Lock()
+	defer mem.proxyMtx.Unlock()
+
+	mem.rejections.close()
+	mem.rejections = nil
+}
+
+// InitCacheFile loads the cache of the seen txs from its file, and saves it
+// there after each block from now on. It only applies to the "cuckoo" cache
+// (see MempoolConfig#CacheType).
+// *panics* if can't read the file.
+// *not thread safe*
+func (mem *CListMempool) InitCacheFile() {
+	cache, ok := mem.cache.(*cuckooTxCache)
+	if !ok {
+		return
+	}
+	path := mem.config.CacheFile()
+	if err := cmn.EnsureDir(filepath.Dir(path), 0700); err != nil {
+		panic(errors.Wrap(err, "Error ensuring the tx cache dir"))
+	}
+	if err := cache.load(path); err != nil {
+		panic(errors.Wrap(err, "Error loading the tx cache"))
+	}
+	mem.cacheFile = path
+}
+
+func (mem *CListMempool) CloseCacheFile() {
+	mem.proxyMtx.Lock()
+	defer mem.proxyMtx.Unlock()
+
+	mem.saveCache()
+	mem.cacheFile = ""
+}
+
+func (mem *CListMempool) saveCache() {
+	if mem.cacheFile == "" {
+		return
+	}
+	if err := mem.cache.(*cuckooTxCache).save(mem.cacheFile); err != nil {
+		mem.logger.Error("Error saving the tx cache", "err", err)
+	}
+}
+
+// RecentRejections implements Mempool.
+func (mem *CListMempool) RecentRejections(limit int) []TxRejection {
+	mem.proxyMtx.Lock()
+	rl := mem.rejections
+	mem.proxyMtx.Unlock()
+
+	if rl == nil {
+		return nil
+	}
+	return rl.recentRejections(limit)
+}
+
+// logRejection logs the rejection of tx, if the rejections log is enabled.
+func (mem *CListMempool) logRejection(tx types.Tx, reason string, res *abci.ResponseCheckTx, err error, peer p2p.ID) {
+	if mem.rejections == nil {
+		return
+	}
+	var (
+		code            uint32
+		codespace, info string
+	)
+	if res != nil {
+		code, codespace, info = res.Code, res.Codespace, res.Log
+	}
+	if err != nil {
+		info = err.Error()
+	}
+	if err := mem.rejections.add(tx, reason, code, codespace, info, peer); err != nil {
+		mem.logger.Error("Error writing to the rejections log", "err", err)
+	}
+}
+
 func (mem *CListMempool) Lock() {
 	mem.proxyMtx.Lock()
 }
@@ -191,6 +298,69 @@ func (mem *CListMempool) Flush() {
 	_ = atomic.SwapInt64(&mem.txsBytes, 0)
 }
 
+// Shrink removes the most recently added transactions until the mempool
+// holds at most maxBytes of transactions. The removed transactions are
+// removed from the cache, so they can be resubmitted. It returns the number
+// of removed transactions, and does nothing while transactions are rechecked.
+func (mem *CListMempool) Shrink(maxBytes int64) int {
+	mem.proxyMtx.Lock()
+	defer mem.proxyMtx.Unlock()
+
+	if atomic.LoadInt32(&mem.rechecking) > 0 {
+		return 0
+	}
+
+	removed := 0
+	for e := mem.txs.Back(); e != nil && mem.TxsBytes() > maxBytes; {
+		prev := e.Prev()
+		mem.removeTx(e.Value.(*mempoolTx).tx, e, true)
+		e = prev
+		removed++
+	}
+	mem.metrics.Size.Set(float64(mem.Size()))
+	return removed
+}
+
+// EvictTx removes the transaction with the given hash (see types.Tx#Hash)
+// from the mempool, and from the cache so that it can be resubmitted. It
+// returns false if there is no such transaction.
+func (mem *CListMempool) EvictTx(hash []byte) bool {
+	var key [sha256.Size]byte
+	if len(hash) != len(key) {
+		return false
+	}
+	copy(key[:], hash)
+
+	mem.proxyMtx.Lock()
+	defer mem.proxyMtx.Unlock()
+
+	e, ok := mem.txsMap.Load(key)
+	if !ok {
+		return false
+	}
+	elem := e.(*clist.CElement)
+	mem.removeTx(elem.Value.(*mempoolTx).tx, elem, true)
+	mem.metrics.Size.Set(float64(mem.Size()))
+	return true
+}
+
+// ResetCache evicts the transactions which are no longer in the mempool
+// (e.g. committed ones) from the cache of seen transactions. A cache saved to
+// its file (see InitCacheFile) is left untouched: it takes little memory, and
+// forgetting the committed transactions would admit them again.
+func (mem *CListMempool) ResetCache() {
+	mem.proxyMtx.Lock()
+	defer mem.proxyMtx.Unlock()
+
+	if mem.cacheFile != "" {
+		return
+	}
+	mem.cache.Reset()
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		mem.cache.Push(e.Value.(*mempoolTx).tx)
+	}
+}
+
 // TxsFront returns the first transaction in the ordered list for peer
 // goroutines to call .NextWait() on.
 // FIXME: leaking implementation details!
@@ -198,6 +368,22 @@ func (mem *CListMempool) TxsFront() *clist.CElement {
 	return mem.txs.Front()
 }
 
+// txByKey returns the tx with the given key, if it's in the mempool.
+func (mem *CListMempool) txByKey(key [sha256.Size]byte) (*mempoolTx, bool) {
+	e, ok := mem.txsMap.Load(key)
+	if !ok {
+		return nil, false
+	}
+	return e.(*clist.CElement).Value.(*mempoolTx), true
+}
+
+// seenTx returns true if the tx with the given key is in the mempool, or was
+// seen recently (see MempoolConfig#CacheSize).
+func (mem *CListMempool) seenTx(key [sha256.Size]byte) bool {
+	_, ok := mem.txsMap.Load(key)
+	return ok || mem.cache.Has(key)
+}
+
 // TxsWaitChan returns a channel to wait on transactions. It will be closed
 // once the mempool is not empty (ie. the internal `mem.txs` has at least one
 // element)
@@ -207,7 +393,9 @@ func (mem *CListMempool) TxsWaitChan() <-chan struct{} {
 
 // It blocks if we're waiting on Update() or Reap().
 // cb: A callback from the CheckTx command.
-//     It gets called from another goroutine.
+//
+//	It gets called from another goroutine.
+//
 // CONTRACT: Either cb will get called, or err returned.
 func (mem *CListMempool) CheckTx(tx types.Tx, cb func(*abci.Response)) (err error) {
 	return mem.CheckTxWithInfo(tx, cb, TxInfo{SenderID: UnknownPeerID})
@@ -225,20 +413,25 @@ func (mem *CListMempool) CheckTxWithInfo(tx types.Tx, cb func(*abci.Response), t
 	)
 	if memSize >= mem.config.Size ||
 		int64(txSize)+txsBytes > mem.config.MaxTxsBytes {
-		return ErrMempoolIsFull{
+		err := ErrMempoolIsFull{
 			memSize, mem.config.Size,
 			txsBytes, mem.config.MaxTxsBytes}
+		mem.logRejection(tx, RejectedFull, nil, err, txInfo.SenderP2PID)
+		return err
 	}
 
 	// The size of the corresponding amino-encoded TxMessage
 	// can't be larger than the maxMsgSize, otherwise we can't
 	// relay it to peers.
 	if txSize > mem.config.MaxTxBytes {
-		return ErrTxTooLarge{mem.config.MaxTxBytes, txSize}
+		err := ErrTxTooLarge{mem.config.MaxTxBytes, txSize}
+		mem.logRejection(tx, RejectedTooLarge, nil, err, txInfo.SenderP2PID)
+		return err
 	}
 
 	if mem.preCheck != nil {
 		if err := mem.preCheck(tx); err != nil {
+			mem.logRejection(tx, RejectedPreCheck, nil, err, txInfo.SenderP2PID)
 			return ErrPreCheck{err}
 		}
 	}
@@ -299,7 +492,6 @@ func (mem *CListMempool) globalCb(req *abci.Request, res *abci.Response) {
 		return
 	}
 
-	mem.metrics.RecheckTimes.Add(1)
 	mem.resCbRecheck(req, res)
 
 	// update metrics
@@ -340,7 +532,7 @@ func (mem *CListMempool) reqResCb(
 }
 
 // Called from:
-//  - resCbFirstTime (lock not held) if tx is valid
+//   - resCbFirstTime (lock not held) if tx is valid
 func (mem *CListMempool) addTx(memTx *mempoolTx) {
 	e := mem.txs.PushBack(memTx)
 	mem.txsMap.Store(txKey(memTx.tx), e)
@@ -349,8 +541,8 @@ func (mem *CListMempool) addTx(memTx *mempoolTx) {
 }
 
 // Called from:
-//  - Update (lock held) if tx was committed
-// 	- resCbRecheck (lock not held) if tx was invalidated
+//   - Update (lock held) if tx was committed
+//   - resCbRecheck (lock not held) if tx was invalidated
 func (mem *CListMempool) removeTx(tx types.Tx, elem *clist.CElement, removeFromCache bool) {
 	mem.txs.Remove(elem)
 	elem.DetachPrev()
@@ -383,6 +575,8 @@ func (mem *CListMempool) resCbFirstTime(
 				height:    mem.height,
 				gasWanted: r.CheckTx.GasWanted,
 				tx:        tx,
+				sender:    r.CheckTx.Sender,
+				sequence:  r.CheckTx.Sequence,
 			}
 			memTx.senders.Store(peerID, true)
 			mem.addTx(memTx)
@@ -398,6 +592,11 @@ func (mem *CListMempool) resCbFirstTime(
 			mem.logger.Info("Rejected bad transaction",
 				"tx", txID(tx), "peerID", peerP2PID, "res", r, "err", postCheckErr)
 			mem.metrics.FailedTxs.Add(1)
+			if postCheckErr != nil {
+				mem.logRejection(tx, RejectedPostCheck, r.CheckTx, postCheckErr, peerP2PID)
+			} else {
+				mem.logRejection(tx, RejectedCheckTx, r.CheckTx, nil, peerP2PID)
+			}
 			// remove from cache (it might be good later)
 			mem.cache.Remove(tx)
 		}
@@ -406,53 +605,73 @@ func (mem *CListMempool) resCbFirstTime(
 	}
 }
 
-// callback, which is called after the app rechecked the tx.
+// callback, which is called after the app rechecked the tx, or a batch of
+// txs.
 //
 // The case where the app checks the tx for the first time is handled by the
 // resCbFirstTime callback.
 func (mem *CListMempool) resCbRecheck(req *abci.Request, res *abci.Response) {
 	switch r := res.Value.(type) {
 	case *abci.Response_CheckTx:
-		tx := req.GetCheckTx().Tx
-		memTx := mem.recheckCursor.Value.(*mempoolTx)
-		if !bytes.Equal(tx, memTx.tx) {
+		mem.recheckedTx(req.GetCheckTx().Tx, r.CheckTx)
+	case *abci.Response_RecheckBatch:
+		txs := req.GetRecheckBatch().Txs
+		if len(r.RecheckBatch.Responses) != len(txs) {
 			panic(fmt.Sprintf(
-				"Unexpected tx response from proxy during recheck\nExpected %X, got %X",
-				memTx.tx,
-				tx))
-		}
-		var postCheckErr error
-		if mem.postCheck != nil {
-			postCheckErr = mem.postCheck(tx, r.CheckTx)
+				"Unexpected number of responses from proxy during recheck\nExpected %d, got %d",
+				len(txs),
+				len(r.RecheckBatch.Responses)))
 		}
-		if (r.CheckTx.Code == abci.CodeTypeOK) && postCheckErr == nil {
-			// Good, nothing to do.
-		} else {
-			// Tx became invalidated due to newly committed block.
-			mem.logger.Info("Tx is no longer valid", "tx", txID(tx), "res", r, "err", postCheckErr)
-			// NOTE: we remove tx from the cache because it might be good later
-			mem.removeTx(tx, mem.recheckCursor, true)
-		}
-		if mem.recheckCursor == mem.recheckEnd {
-			mem.recheckCursor = nil
-		} else {
-			mem.recheckCursor = mem.recheckCursor.Next()
-		}
-		if mem.recheckCursor == nil {
-			// Done!
-			atomic.StoreInt32(&mem.rechecking, 0)
-			mem.logger.Info("Done rechecking txs")
-
-			// incase the recheck removed all txs
-			if mem.Size() > 0 {
-				mem.notifyTxsAvailable()
-			}
+		for i, tx := range txs {
+			mem.recheckedTx(tx, &r.RecheckBatch.Responses[i])
 		}
 	default:
 		// ignore other messages
 	}
 }
 
+// recheckedTx handles the response of the app to the recheck of the tx at the
+// recheck cursor, and moves the cursor to the next tx.
+func (mem *CListMempool) recheckedTx(tx []byte, res *abci.ResponseCheckTx) {
+	memTx := mem.recheckCursor.Value.(*mempoolTx)
+	if !bytes.Equal(tx, memTx.tx) {
+		panic(fmt.Sprintf(
+			"Unexpected tx response from proxy during recheck\nExpected %X, got %X",
+			memTx.tx,
+			tx))
+	}
+	mem.metrics.RecheckTimes.Add(1)
+	var postCheckErr error
+	if mem.postCheck != nil {
+		postCheckErr = mem.postCheck(tx, res)
+	}
+	if (res.Code == abci.CodeTypeOK) && postCheckErr == nil {
+		// Good, nothing to do.
+	} else {
+		// Tx became invalidated due to newly committed block.
+		mem.logger.Info("Tx is no longer valid", "tx", txID(tx), "res", res, "err", postCheckErr)
+		mem.logRejection(tx, RejectedRecheck, res, postCheckErr, "")
+		// NOTE: we remove tx from the cache because it might be good later
+		mem.removeTx(tx, mem.recheckCursor, true)
+	}
+	atomic.StoreInt32(&memTx.rechecking, 0)
+	if mem.recheckCursor == mem.recheckEnd {
+		mem.recheckCursor = nil
+	} else {
+		mem.recheckCursor = mem.recheckCursor.Next()
+	}
+	if mem.recheckCursor == nil {
+		// Done!
+		atomic.StoreInt32(&mem.rechecking, 0)
+		mem.logger.Info("Done rechecking txs")
+
+		// incase the recheck removed all txs
+		if mem.Size() > 0 {
+			mem.notifyTxsAvailable()
+		}
+	}
+}
+
 func (mem *CListMempool) TxsAvailable() <-chan struct{} {
 	return mem.txsAvailable
 }
@@ -472,12 +691,17 @@ func (mem *CListMempool) notifyTxsAvailable() {
 }
 
 func (mem *CListMempool) ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs {
-	mem.proxyMtx.Lock()
+	return mem.ReapMaxBytesMaxGasUntil(maxBytes, maxGas, time.Time{})
+}
+
+func (mem *CListMempool) ReapMaxBytesMaxGasUntil(maxBytes, maxGas int64, deadline time.Time) types.Txs {
+	if !mem.lockUntil(deadline) {
+		return types.Txs{}
+	}
 	defer mem.proxyMtx.Unlock()
 
-	for atomic.LoadInt32(&mem.rechecking) > 0 {
-		// TODO: Something better?
-		time.Sleep(time.Millisecond * 10)
+	if !mem.waitForRecheck(deadline) {
+		return types.Txs{}
 	}
 
 	var totalBytes int64
@@ -486,12 +710,11 @@ func (mem *CListMempool) ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs {
 	// size per tx, and set the initial capacity based off of that.
 	// txs := make([]types.Tx, 0, cmn.MinInt(mem.txs.Len(), max/mem.avgTxSize))
 	txs := make([]types.Tx, 0, mem.txs.Len())
-	for e := mem.txs.Front(); e != nil; e = e.Next() {
-		memTx := e.Value.(*mempoolTx)
+	reap := func(memTx *mempoolTx) bool {
 		// Check total size requirement
 		aminoOverhead := types.ComputeAminoOverhead(memTx.tx, 1)
 		if maxBytes > -1 && totalBytes+int64(len(memTx.tx))+aminoOverhead > maxBytes {
-			return txs
+			return false
 		}
 		totalBytes += int64(len(memTx.tx)) + aminoOverhead
 		// Check total gas requirement.
@@ -500,10 +723,30 @@ func (mem *CListMempool) ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs {
 		// must be non-negative, it follows that this won't overflow.
 		newTotalGas := totalGas + memTx.gasWanted
 		if maxGas > -1 && newTotalGas > maxGas {
-			return txs
+			return false
 		}
 		totalGas = newTotalGas
 		txs = append(txs, memTx.tx)
+		return true
+	}
+	lane := newSenderLane(mem.txs)
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		if pastDeadline(deadline) {
+			return txs
+		}
+		memTx := e.Value.(*mempoolTx)
+		if atomic.LoadInt32(&memTx.rechecking) > 0 {
+			// the next txs may depend on this one
+			return txs
+		}
+		// Skip the txs which don't fit any block, since an EndBlock lowered
+		// the max gas: they'd block the next txs until rechecked.
+		if maxGas > -1 && memTx.gasWanted > maxGas {
+			continue
+		}
+		if !lane.push(memTx, reap) {
+			return txs
+		}
 	}
 	return txs
 }
@@ -516,17 +759,66 @@ func (mem *CListMempool) ReapMaxTxs(max int) types.Txs {
 		max = mem.txs.Len()
 	}
 
+	mem.waitForRecheck(time.Time{})
+
+	txs := make([]types.Tx, 0, cmn.MinInt(mem.txs.Len(), max))
+	reap := func(memTx *mempoolTx) bool {
+		if len(txs) >= max {
+			return false
+		}
+		txs = append(txs, memTx.tx)
+		return true
+	}
+	lane := newSenderLane(mem.txs)
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		memTx := e.Value.(*mempoolTx)
+		if atomic.LoadInt32(&memTx.rechecking) > 0 {
+			break
+		}
+		if !lane.push(memTx, reap) {
+			break
+		}
+	}
+	return txs
+}
+
+// waitForRecheck waits for the txs to be rechecked, unless the recheck is
+// asynchronous, in which case the txs not rechecked yet are skipped when
+// reaping. It returns false if they're still rechecking at the deadline (if
+// not zero).
+func (mem *CListMempool) waitForRecheck(deadline time.Time) bool {
+	if mem.config.RecheckAsync {
+		return true
+	}
 	for atomic.LoadInt32(&mem.rechecking) > 0 {
+		if pastDeadline(deadline) {
+			return false
+		}
 		// TODO: Something better?
 		time.Sleep(time.Millisecond * 10)
 	}
+	return true
+}
 
-	txs := make([]types.Tx, 0, cmn.MinInt(mem.txs.Len(), max))
-	for e := mem.txs.Front(); e != nil && len(txs) <= max; e = e.Next() {
-		memTx := e.Value.(*mempoolTx)
-		txs = append(txs, memTx.tx)
+// lockUntil locks the proxyMtx, unless it's still locked by someone else at
+// the deadline (if not zero), in which case it returns false.
+func (mem *CListMempool) lockUntil(deadline time.Time) bool {
+	if deadline.IsZero() {
+		mem.proxyMtx.Lock()
+		return true
 	}
-	return txs
+	for !mem.proxyMtx.TryLock() {
+		if pastDeadline(deadline) {
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return true
+}
+
+// pastDeadline returns true if the deadline isn't zero and has passed.
+func pastDeadline(deadline time.Time) bool {
+	return !deadline.IsZero() && !time.Now().Before(deadline)
 }
 
 func (mem *CListMempool) Update(
@@ -571,6 +863,9 @@ func (mem *CListMempool) Update(
 		}
 	}
 
+	// Save the committed txs, so they aren't admitted again after a restart.
+	mem.saveCache()
+
 	// Either recheck non-committed txs to see if they became invalid
 	// or just notify there're some txs left.
 	if mem.Size() > 0 {
@@ -600,14 +895,24 @@ func (mem *CListMempool) recheckTxs() {
 	mem.recheckCursor = mem.txs.Front()
 	mem.recheckEnd = mem.txs.Back()
 
-	// Push txs to proxyAppConn
+	// Push txs to proxyAppConn, one by one or in batches.
 	// NOTE: globalCb may be called concurrently.
+	var batch [][]byte
 	for e := mem.txs.Front(); e != nil; e = e.Next() {
 		memTx := e.Value.(*mempoolTx)
-		mem.proxyAppConn.CheckTxAsync(abci.RequestCheckTx{
-			Tx:   memTx.tx,
-			Type: abci.CheckTxType_Recheck,
-		})
+		atomic.StoreInt32(&memTx.rechecking, 1)
+		if mem.config.RecheckBatchSize == 0 {
+			mem.proxyAppConn.CheckTxAsync(abci.RequestCheckTx{
+				Tx:   memTx.tx,
+				Type: abci.CheckTxType_Recheck,
+			})
+			continue
+		}
+		batch = append(batch, memTx.tx)
+		if len(batch) == mem.config.RecheckBatchSize || e == mem.recheckEnd {
+			mem.proxyAppConn.RecheckBatchAsync(abci.RequestRecheckBatch{Txs: batch})
+			batch = nil
+		}
 	}
 
 	mem.proxyAppConn.FlushAsync()
@@ -617,9 +922,15 @@ func (mem *CListMempool) recheckTxs() {
 
 // mempoolTx is a transaction that successfully ran
 type mempoolTx struct {
-	height    int64    // height that this tx had been validated in
-	gasWanted int64    // amount of gas this tx states it will require
-	tx        types.Tx //
+	height     int64    // height that this tx had been validated in
+	gasWanted  int64    // amount of gas this tx states it will require
+	tx         types.Tx //
+	rechecking int32    // 1 while the tx is rechecked (atomic)
+
+	// sender and sequence of the tx returned by CheckTx, to reap the txs of
+	// the sender in order (see senderLane). No sender by default.
+	sender   string
+	sequence int64
 
 	// ids of peers who've sent us this tx (as a map for quick lookups).
 	// senders: PeerID -> bool
@@ -637,6 +948,7 @@ type txCache interface {
 	Reset()
 	Push(tx types.Tx) bool
 	Remove(tx types.Tx)
+	Has(key [sha256.Size]byte) bool
 }
 
 // mapTxCache maintains a LRU cache of transactions. This only stores the hash
@@ -706,13 +1018,22 @@ func (cache *mapTxCache) Remove(tx types.Tx) {
 	cache.mtx.Unlock()
 }
 
+// Has returns true if the tx with the given key is in the cache.
+func (cache *mapTxCache) Has(key [sha256.Size]byte) bool {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	_, ok := cache.map_[key]
+	return ok
+}
+
 type nopTxCache struct{}
 
 var _ txCache = (*nopTxCache)(nil)
 
-func (nopTxCache) Reset()             {}
-func (nopTxCache) Push(types.Tx) bool { return true }
-func (nopTxCache) Remove(types.Tx)    {}
+func (nopTxCache) Reset()                     {}
+func (nopTxCache) Push(types.Tx) bool         { return true }
+func (nopTxCache) Remove(types.Tx)            {}
+func (nopTxCache) Has([sha256.Size]byte) bool { return false }
 
 //--------------------------------------------------------------------------------
 
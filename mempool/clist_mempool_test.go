@@ -4,11 +4,15 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	mrand "math/rand"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,6 +21,7 @@ import (
 
 	amino "github.com/tendermint/go-amino"
 
+	"github.com/tendermint/tendermint/abci/example/code"
 	"github.com/tendermint/tendermint/abci/example/counter"
 	"github.com/tendermint/tendermint/abci/example/kvstore"
 	abciserver "github.com/tendermint/tendermint/abci/server"
@@ -136,6 +141,55 @@ func TestReapMaxBytesMaxGas(t *testing.T) {
 			len(got), tt.expectedNumTxs, tcIndex)
 		mempool.Flush()
 	}
+
+	// a tx wanting more than the max gas doesn't block the next ones
+	checkTxs(t, mempool, 20, UnknownPeerID)
+	mempool.TxsFront().Value.(*mempoolTx).gasWanted = 100
+	got := mempool.ReapMaxBytesMaxGas(-1, 10)
+	assert.Equal(t, 10, len(got))
+	assert.Equal(t, -1, got.Index(mempool.TxsFront().Value.(*mempoolTx).tx))
+	mempool.Flush()
+}
+
+func TestReapMaxBytesMaxGasUntil(t *testing.T) {
+	app := kvstore.NewKVStoreApplication()
+	cc := proxy.NewLocalClientCreator(app)
+	mempool, cleanup := newMempoolWithApp(cc)
+	defer cleanup()
+
+	checkTxs(t, mempool, 20, UnknownPeerID)
+	assert.Len(t, mempool.ReapMaxBytesMaxGasUntil(-1, -1, time.Now().Add(time.Minute)), 20)
+	assert.Empty(t, mempool.ReapMaxBytesMaxGasUntil(-1, -1, time.Now().Add(-time.Millisecond)))
+
+	// the mempool is locked: it gives up at the deadline
+	mempool.Lock()
+	start := time.Now()
+	assert.Empty(t, mempool.ReapMaxBytesMaxGasUntil(-1, -1, start.Add(50*time.Millisecond)))
+	assert.True(t, time.Since(start) < time.Second)
+	mempool.Unlock()
+
+	// and it doesn't wait for the txs to be rechecked past the deadline
+	atomic.StoreInt32(&mempool.rechecking, 1)
+	start = time.Now()
+	assert.Empty(t, mempool.ReapMaxBytesMaxGasUntil(-1, -1, start.Add(50*time.Millisecond)))
+	assert.True(t, time.Since(start) < time.Second)
+	atomic.StoreInt32(&mempool.rechecking, 0)
+
+	assert.Len(t, mempool.ReapMaxBytesMaxGasUntil(-1, -1, time.Time{}), 20)
+}
+
+func TestReapMaxTxs(t *testing.T) {
+	app := kvstore.NewKVStoreApplication()
+	cc := proxy.NewLocalClientCreator(app)
+	mempool, cleanup := newMempoolWithApp(cc)
+	defer cleanup()
+
+	txs := checkTxs(t, mempool, 20, UnknownPeerID)
+	assert.Empty(t, mempool.ReapMaxTxs(0))
+	assert.Equal(t, txs[:1], mempool.ReapMaxTxs(1))
+	assert.Equal(t, txs[:10], mempool.ReapMaxTxs(10))
+	assert.Equal(t, txs, mempool.ReapMaxTxs(30))
+	assert.Equal(t, txs, mempool.ReapMaxTxs(-1))
 }
 
 func TestMempoolFilters(t *testing.T) {
@@ -537,6 +591,193 @@ func TestMempoolTxsBytes(t *testing.T) {
 	assert.EqualValues(t, 0, mempool.TxsBytes())
 }
 
+func TestMempoolSetLimits(t *testing.T) {
+	app := kvstore.NewKVStoreApplication()
+	cc := proxy.NewLocalClientCreator(app)
+	config := cfg.ResetTestRoot("mempool_test")
+	config.Mempool.Size = 1
+	mempool, cleanup := newMempoolWithAppAndConfig(cc, config)
+	defer cleanup()
+
+	require.NoError(t, mempool.CheckTx([]byte{0x01}, nil))
+	err := mempool.CheckTx([]byte{0x02}, nil)
+	assert.IsType(t, ErrMempoolIsFull{}, err)
+
+	// the new limits apply to the next txs
+	mempool.SetLimits(2, 2)
+	require.NoError(t, mempool.CheckTx([]byte{0x02}, nil))
+	err = mempool.CheckTx([]byte{0x03}, nil)
+	assert.IsType(t, ErrMempoolIsFull{}, err)
+
+	// the txs above the limits are kept
+	mempool.SetLimits(1, 1)
+	assert.Equal(t, 2, mempool.Size())
+	err = mempool.CheckTx([]byte{0x03}, nil)
+	assert.IsType(t, ErrMempoolIsFull{}, err)
+}
+
+func TestMempoolShrink(t *testing.T) {
+	app := kvstore.NewKVStoreApplication()
+	cc := proxy.NewLocalClientCreator(app)
+	mempool, cleanup := newMempoolWithApp(cc)
+	defer cleanup()
+
+	for i := byte(0); i < 5; i++ {
+		require.NoError(t, mempool.CheckTx([]byte{i, i}, nil))
+	}
+	assert.EqualValues(t, 10, mempool.TxsBytes())
+
+	// the most recently added txs are removed first
+	assert.Equal(t, 3, mempool.Shrink(5))
+	assert.Equal(t, 2, mempool.Size())
+	assert.EqualValues(t, 4, mempool.TxsBytes())
+	assert.Equal(t, types.Txs{[]byte{0, 0}, []byte{1, 1}}, mempool.ReapMaxTxs(-1))
+
+	// removed txs may be resubmitted
+	require.NoError(t, mempool.CheckTx([]byte{4, 4}, nil))
+
+	// committed txs stay in the cache until it is reset, unlike the txs
+	// still in the mempool
+	mempool.Update(1, []types.Tx{[]byte{0, 0}}, abciResponses(1, abci.CodeTypeOK), nil, nil)
+	assert.Equal(t, ErrTxInCache, mempool.CheckTx([]byte{0, 0}, nil))
+	mempool.ResetCache()
+	assert.NoError(t, mempool.CheckTx([]byte{0, 0}, nil))
+	assert.Equal(t, ErrTxInCache, mempool.CheckTx([]byte{1, 1}, nil))
+
+	assert.Equal(t, 0, mempool.Shrink(100))
+}
+
+func TestMempoolEvictTx(t *testing.T) {
+	app := kvstore.NewKVStoreApplication()
+	cc := proxy.NewLocalClientCreator(app)
+	mempool, cleanup := newMempoolWithApp(cc)
+	defer cleanup()
+
+	for i := byte(0); i < 3; i++ {
+		require.NoError(t, mempool.CheckTx([]byte{i, i}, nil))
+	}
+
+	assert.True(t, mempool.EvictTx(types.Tx([]byte{1, 1}).Hash()))
+	assert.Equal(t, types.Txs{[]byte{0, 0}, []byte{2, 2}}, mempool.ReapMaxTxs(-1))
+	assert.False(t, mempool.EvictTx(types.Tx([]byte{1, 1}).Hash()), "already evicted")
+	assert.False(t, mempool.EvictTx([]byte{1, 1}), "not a hash")
+
+	// evicted txs may be resubmitted
+	assert.NoError(t, mempool.CheckTx([]byte{1, 1}, nil))
+}
+
+// recheckBatchApp is a kvstore rejecting the txs starting with an odd byte
+// when they are rechecked, and recording the size of the recheck batches.
+type recheckBatchApp struct {
+	*kvstore.KVStoreApplication
+	batches []int
+}
+
+func (app *recheckBatchApp) RecheckBatch(req abci.RequestRecheckBatch) abci.ResponseRecheckBatch {
+	app.batches = append(app.batches, len(req.Txs))
+	responses := make([]abci.ResponseCheckTx, len(req.Txs))
+	for i, tx := range req.Txs {
+		responses[i].Code = uint32(tx[0] % 2)
+	}
+	return abci.ResponseRecheckBatch{Responses: responses}
+}
+
+func TestMempoolRecheckBatch(t *testing.T) {
+	app := &recheckBatchApp{KVStoreApplication: kvstore.NewKVStoreApplication()}
+	cc := proxy.NewLocalClientCreator(app)
+	config := cfg.ResetTestRoot("mempool_test")
+	config.Mempool.RecheckBatchSize = 2
+	mempool, cleanup := newMempoolWithAppAndConfig(cc, config)
+	defer cleanup()
+
+	for i := byte(0); i < 6; i++ {
+		require.NoError(t, mempool.CheckTx([]byte{i}, nil))
+	}
+	mempool.Lock()
+	err := mempool.Update(1, []types.Tx{[]byte{0}}, abciResponses(1, abci.CodeTypeOK), nil, nil)
+	mempool.Unlock()
+	require.NoError(t, err)
+
+	assert.Equal(t, []int{2, 2, 1}, app.batches)
+	assert.Equal(t, types.Txs{[]byte{2}, []byte{4}}, mempool.ReapMaxTxs(-1))
+}
+
+// senderApp returns the sender and the sequence of the "sender/sequence" txs.
+type senderApp struct {
+	*kvstore.KVStoreApplication
+}
+
+func (app *senderApp) CheckTx(req abci.RequestCheckTx) abci.ResponseCheckTx {
+	res := abci.ResponseCheckTx{Code: abci.CodeTypeOK, GasWanted: 1}
+	parts := strings.Split(string(req.Tx), "/")
+	if len(parts) == 2 {
+		res.Sender = parts[0]
+		res.Sequence, _ = strconv.ParseInt(parts[1], 10, 64)
+	}
+	return res
+}
+
+func TestMempoolSenderLane(t *testing.T) {
+	app := &senderApp{KVStoreApplication: kvstore.NewKVStoreApplication()}
+	cc := proxy.NewLocalClientCreator(app)
+	mempool, cleanup := newMempoolWithApp(cc)
+	defer cleanup()
+
+	// a/2 is received before a/1, a/4 misses a/3, and a/02 reuses the sequence 2
+	for _, tx := range []string{"a/2", "b/1", "x", "a/1", "a/4", "y", "b/2", "a/02"} {
+		require.NoError(t, mempool.CheckTx(types.Tx(tx), nil))
+	}
+	expected := types.Txs{types.Tx("b/1"), types.Tx("x"), types.Tx("a/1"), types.Tx("a/2"),
+		types.Tx("y"), types.Tx("b/2")}
+	assert.Equal(t, expected, mempool.ReapMaxBytesMaxGas(-1, -1))
+	assert.Equal(t, expected, mempool.ReapMaxTxs(-1))
+	assert.Equal(t, expected[:3], mempool.ReapMaxBytesMaxGas(-1, 3))
+
+	// a/3 fills the gap
+	require.NoError(t, mempool.CheckTx(types.Tx("a/3"), nil))
+	txs := mempool.ReapMaxBytesMaxGas(-1, -1)
+	assert.Equal(t, append(expected, types.Tx("a/3"), types.Tx("a/4")), txs)
+}
+
+func TestMempoolRejectionsLog(t *testing.T) {
+	app := counter.NewCounterApplication(true)
+	cc := proxy.NewLocalClientCreator(app)
+	config := cfg.ResetTestRoot("mempool_test")
+	config.Mempool.RejectionsLogPath = "rejections"
+	config.Mempool.MaxTxBytes = 10
+	mempool, cleanup := newMempoolWithAppAndConfig(cc, config)
+	defer cleanup()
+
+	assert.Nil(t, mempool.RecentRejections(10), "rejections log disabled")
+	mempool.InitRejectionsLog()
+
+	// rejected by CheckTx, then too large
+	badTx, largeTx := types.Tx(make([]byte, 9)), types.Tx(make([]byte, 11))
+	require.NoError(t, mempool.CheckTxWithInfo(badTx, nil, TxInfo{SenderP2PID: "peer"}))
+	require.Error(t, mempool.CheckTx(largeTx, nil))
+	require.NoError(t, mempool.CheckTx(types.Tx{1}, nil))
+
+	rejections := mempool.RecentRejections(10)
+	require.Len(t, rejections, 2)
+	assert.Equal(t, RejectedTooLarge, rejections[0].Reason)
+	assert.EqualValues(t, largeTx.Hash(), rejections[0].Hash)
+	assert.Equal(t, 11, rejections[0].Size)
+	assert.Equal(t, RejectedCheckTx, rejections[1].Reason)
+	assert.Equal(t, code.CodeTypeEncodingError, rejections[1].Code)
+	assert.EqualValues(t, "peer", rejections[1].Peer)
+	assert.Len(t, mempool.RecentRejections(1), 1)
+
+	// the rejections are logged to disk, one per line
+	mempool.CloseRejectionsLog()
+	bz, err := ioutil.ReadFile(filepath.Join(config.Mempool.RejectionsLogDir(), "rejections"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(bz)), "\n")
+	require.Len(t, lines, 2)
+	var r TxRejection
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &r))
+	assert.Equal(t, rejections[1], r)
+}
+
 // This will non-deterministically catch some concurrency failures like
 // https://github.com/tendermint/tendermint/issues/3509
 // TODO: all of the tests should probably also run using the remote proxy app
@@ -0,0 +1,312 @@
+package mempool
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// cuckooBucketSize is the number of fingerprints per bucket of a filter.
+	cuckooBucketSize = 4
+	// cuckooMaxLoad is the share of the fingerprints of a filter in use once
+	// it holds its capacity, at most.
+	cuckooMaxLoad = 0.9
+	// cuckooMaxKicks is the number of fingerprints relocated to insert one in
+	// a filter before giving up.
+	cuckooMaxKicks = 500
+
+	cuckooCacheFileMagic   = "TMCC"
+	cuckooCacheFileVersion = 1
+)
+
+// cuckooTxCache is a txCache of the keys of the txs in cuckoo filters, which
+// can be saved to a file, so that the txs seen before a restart, the
+// committed ones in particular, aren't admitted into the mempool again after
+// it. See MempoolConfig#CacheType.
+//
+// It uses less than 10 bytes per tx, and takes a tx never seen for a seen one
+// with a probability under 4*10^-9. The txs are added to the current filter
+// until it holds size of them, and then to a new one: the txs of the previous
+// filter are still seen, and the ones before are forgotten.
+type cuckooTxCache struct {
+	mtx  sync.Mutex
+	size int
+
+	cur, prev *cuckooFilter
+	dirty     bool // changed since loaded or saved
+}
+
+var _ txCache = (*cuckooTxCache)(nil)
+
+// newCuckooTxCache returns an empty cuckooTxCache of the given size.
+func newCuckooTxCache(size int) *cuckooTxCache {
+	return &cuckooTxCache{
+		size: size,
+		cur:  newCuckooFilter(size),
+		prev: newCuckooFilter(0),
+	}
+}
+
+// Reset implements txCache.
+func (cache *cuckooTxCache) Reset() {
+	cache.mtx.Lock()
+	cache.cur = newCuckooFilter(cache.size)
+	cache.prev = newCuckooFilter(0)
+	cache.dirty = true
+	cache.mtx.Unlock()
+}
+
+// Push implements txCache.
+func (cache *cuckooTxCache) Push(tx types.Tx) bool {
+	key := txKey(tx)
+
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	if cache.cur.has(key) || cache.prev.has(key) {
+		return false
+	}
+	if cache.cur.count >= cache.cur.capacity || !cache.cur.insert(key) {
+		// NOTE: if the insertion failed, the fingerprint relocated last is
+		// lost: a tx of the filter is forgotten early.
+		cache.prev = cache.cur
+		cache.cur = newCuckooFilter(cache.size)
+		cache.cur.insert(key)
+	}
+	cache.dirty = true
+	return true
+}
+
+// Remove implements txCache.
+func (cache *cuckooTxCache) Remove(tx types.Tx) {
+	key := txKey(tx)
+
+	cache.mtx.Lock()
+	if cache.cur.delete(key) || cache.prev.delete(key) {
+		cache.dirty = true
+	}
+	cache.mtx.Unlock()
+}
+
+// Has implements txCache.
+func (cache *cuckooTxCache) Has(key [sha256.Size]byte) bool {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	return cache.cur.has(key) || cache.prev.has(key)
+}
+
+// load replaces the filters with the ones saved to the file, if it exists.
+// The filters keep the capacity they were saved with.
+func (cache *cuckooTxCache) load(path string) error {
+	bz, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	r := bytes.NewReader(bz)
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return errors.Wrap(err, "invalid tx cache file")
+	}
+	if string(header.Magic[:]) != cuckooCacheFileMagic {
+		return errors.New("invalid tx cache file: not a tx cache")
+	}
+	if header.Version != cuckooCacheFileVersion {
+		return fmt.Errorf("invalid tx cache file: unknown version %d", header.Version)
+	}
+	cur, err := readCuckooFilter(r)
+	if err != nil {
+		return errors.Wrap(err, "invalid tx cache file")
+	}
+	prev, err := readCuckooFilter(r)
+	if err != nil {
+		return errors.Wrap(err, "invalid tx cache file")
+	}
+
+	cache.mtx.Lock()
+	cache.cur, cache.prev = cur, prev
+	cache.dirty = false
+	cache.mtx.Unlock()
+	return nil
+}
+
+// save saves the filters to the file, if they changed.
+func (cache *cuckooTxCache) save(path string) error {
+	cache.mtx.Lock()
+	if !cache.dirty {
+		cache.mtx.Unlock()
+		return nil
+	}
+	buf := new(bytes.Buffer)
+	buf.WriteString(cuckooCacheFileMagic)
+	binary.Write(buf, binary.BigEndian, uint32(cuckooCacheFileVersion)) // nolint: errcheck
+	cache.cur.writeTo(buf)
+	cache.prev.writeTo(buf)
+	cache.dirty = false
+	cache.mtx.Unlock()
+
+	if err := cmn.WriteFileAtomic(path, buf.Bytes(), 0600); err != nil {
+		cache.mtx.Lock()
+		cache.dirty = true
+		cache.mtx.Unlock()
+		return err
+	}
+	return nil
+}
+
+//--------------------------------------------------------------------------------
+
+// cuckooFilter is a cuckoo filter of tx keys, with 4 bytes fingerprints. As
+// the keys are hashes already, the indexes and the fingerprint of a key are
+// taken from its bytes.
+type cuckooFilter struct {
+	buckets  []uint32 // cuckooBucketSize fingerprints per bucket, 0 if empty
+	mask     uint64   // number of buckets - 1
+	capacity int
+	count    int
+}
+
+// newCuckooFilter returns an empty filter for capacity keys.
+func newCuckooFilter(capacity int) *cuckooFilter {
+	numBuckets := uint64(1)
+	for float64(numBuckets*cuckooBucketSize)*cuckooMaxLoad < float64(capacity) {
+		numBuckets <<= 1
+	}
+	return &cuckooFilter{
+		buckets:  make([]uint32, numBuckets*cuckooBucketSize),
+		mask:     numBuckets - 1,
+		capacity: capacity,
+	}
+}
+
+func (f *cuckooFilter) indexes(key [sha256.Size]byte) (i1, i2 uint64, fp uint32) {
+	fp = binary.BigEndian.Uint32(key[8:12])
+	if fp == 0 {
+		fp = 1
+	}
+	i1 = binary.BigEndian.Uint64(key[:8]) & f.mask
+	return i1, f.altIndex(i1, fp), fp
+}
+
+// altIndex returns the other bucket of the fingerprint in bucket i.
+func (f *cuckooFilter) altIndex(i uint64, fp uint32) uint64 {
+	return (i ^ uint64(fp)*0x5bd1e995) & f.mask
+}
+
+func (f *cuckooFilter) bucket(i uint64) []uint32 {
+	return f.buckets[i*cuckooBucketSize : (i+1)*cuckooBucketSize]
+}
+
+func (f *cuckooFilter) has(key [sha256.Size]byte) bool {
+	i1, i2, fp := f.indexes(key)
+	for _, i := range []uint64{i1, i2} {
+		for _, x := range f.bucket(i) {
+			if x == fp {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// insert inserts the key, and returns false if the filter is too full: the
+// last fingerprint relocated is dropped then.
+func (f *cuckooFilter) insert(key [sha256.Size]byte) bool {
+	i1, i2, fp := f.indexes(key)
+	if f.add(i1, fp) || f.add(i2, fp) {
+		f.count++
+		return true
+	}
+	i := i1
+	for k := 0; k < cuckooMaxKicks; k++ {
+		b := f.bucket(i)
+		j := (fp + uint32(k)) % cuckooBucketSize
+		fp, b[j] = b[j], fp
+		i = f.altIndex(i, fp)
+		if f.add(i, fp) {
+			f.count++
+			return true
+		}
+	}
+	return false
+}
+
+func (f *cuckooFilter) add(i uint64, fp uint32) bool {
+	b := f.bucket(i)
+	for j, x := range b {
+		if x == 0 {
+			b[j] = fp
+			return true
+		}
+	}
+	return false
+}
+
+// delete deletes the key, and returns false if it isn't in the filter.
+func (f *cuckooFilter) delete(key [sha256.Size]byte) bool {
+	i1, i2, fp := f.indexes(key)
+	for _, i := range []uint64{i1, i2} {
+		b := f.bucket(i)
+		for j, x := range b {
+			if x == fp {
+				b[j] = 0
+				f.count--
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeTo writes the filter: its capacity, count and number of buckets,
+// followed by the buckets.
+func (f *cuckooFilter) writeTo(buf *bytes.Buffer) {
+	binary.Write(buf, binary.BigEndian, []uint64{ // nolint: errcheck
+		uint64(f.capacity),
+		uint64(f.count),
+		f.mask + 1,
+	})
+	binary.Write(buf, binary.BigEndian, f.buckets) // nolint: errcheck
+}
+
+func readCuckooFilter(r *bytes.Reader) (*cuckooFilter, error) {
+	header := make([]uint64, 3)
+	if err := binary.Read(r, binary.BigEndian, header); err != nil {
+		return nil, err
+	}
+	capacity, count, numBuckets := header[0], header[1], header[2]
+	if numBuckets == 0 || numBuckets&(numBuckets-1) != 0 {
+		return nil, fmt.Errorf("number of buckets %d not a power of 2", numBuckets)
+	}
+	if numBuckets*cuckooBucketSize*4 > uint64(r.Len()) {
+		return nil, fmt.Errorf("truncated filter of %d buckets", numBuckets)
+	}
+	if count > numBuckets*cuckooBucketSize {
+		return nil, fmt.Errorf("%d keys in a filter of %d buckets", count, numBuckets)
+	}
+	f := &cuckooFilter{
+		buckets:  make([]uint32, numBuckets*cuckooBucketSize),
+		mask:     numBuckets - 1,
+		capacity: int(capacity),
+		count:    int(count),
+	}
+	if err := binary.Read(r, binary.BigEndian, f.buckets); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
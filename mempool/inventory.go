@@ -0,0 +1,205 @@
+package mempool
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+const (
+	// maxInventoryHashes is the maximum number of tx hashes in a
+	// TxHashesMessage or a WantTxsMessage.
+	maxInventoryHashes = 1000
+
+	// maxInventoryMsgSize is the maximum size of a message on the
+	// MempoolInventoryChannel.
+	maxInventoryMsgSize = maxInventoryHashes*(sha256.Size+2) + 16
+
+	// wantTxTimeout is how long to wait for a requested tx before requesting
+	// it from another peer which announced it.
+	wantTxTimeout = 2 * time.Second
+
+	// maxTxAnnouncers bounds the peers remembered as having a wanted tx.
+	maxTxAnnouncers = 8
+
+	// maxWantedTxsPerPeer bounds the txs requested from a peer and not
+	// received yet, so that a peer can't fill the inventory.
+	maxWantedTxsPerPeer = maxInventoryHashes
+
+	// peerInventoryQueueKey is the key of the peerInventoryQueue of a peer (see
+	// p2p.Peer#Get).
+	peerInventoryQueueKey = "MempoolReactor.inventoryQueue"
+)
+
+// txInventory tracks the txs announced by peers which the node wants, so that
+// each tx is requested from a single peer at a time, and from another peer
+// which announced it if the first one doesn't send it in time.
+type txInventory struct {
+	mtx              sync.Mutex
+	wanted           map[[sha256.Size]byte]*wantedTx
+	requested        map[p2p.ID]int // number of wanted txs requested from the peer
+	maxWanted        int
+	maxWantedPerPeer int
+}
+
+type wantedTx struct {
+	peer        p2p.ID // the tx was requested from
+	requestedAt time.Time
+	announcers  []p2p.ID // other peers having the tx
+}
+
+func newTxInventory(maxWanted, maxWantedPerPeer int) *txInventory {
+	return &txInventory{
+		wanted:           make(map[[sha256.Size]byte]*wantedTx),
+		requested:        make(map[p2p.ID]int),
+		maxWanted:        maxWanted,
+		maxWantedPerPeer: maxWantedPerPeer,
+	}
+}
+
+// announced records that the peer has the tx with the given key, and returns
+// true if the tx must be requested from it, i.e. if it isn't requested from
+// another peer already, and not too many txs are requested from the peer.
+func (inv *txInventory) announced(key [sha256.Size]byte, peerID p2p.ID, now time.Time) bool {
+	inv.mtx.Lock()
+	defer inv.mtx.Unlock()
+
+	wtx, ok := inv.wanted[key]
+	if !ok {
+		if len(inv.wanted) >= inv.maxWanted || inv.requested[peerID] >= inv.maxWantedPerPeer {
+			return false
+		}
+		inv.wanted[key] = &wantedTx{peer: peerID, requestedAt: now}
+		inv.requested[peerID]++
+		return true
+	}
+	if wtx.peer == peerID || len(wtx.announcers) >= maxTxAnnouncers {
+		return false
+	}
+	for _, id := range wtx.announcers {
+		if id == peerID {
+			return false
+		}
+	}
+	wtx.announcers = append(wtx.announcers, peerID)
+	return false
+}
+
+// received forgets the tx with the given key.
+func (inv *txInventory) received(key [sha256.Size]byte) {
+	inv.mtx.Lock()
+	defer inv.mtx.Unlock()
+	if wtx, ok := inv.wanted[key]; ok {
+		inv.forgetRequest(wtx.peer)
+		delete(inv.wanted, key)
+	}
+}
+
+// expired returns the hashes of the txs to request again, by peer: the txs
+// requested more than wantTxTimeout ago are requested from the next peer which
+// announced them. The txs no other peer announced are forgotten.
+func (inv *txInventory) expired(now time.Time) map[p2p.ID][][]byte {
+	inv.mtx.Lock()
+	defer inv.mtx.Unlock()
+
+	requests := make(map[p2p.ID][][]byte)
+	for key, wtx := range inv.wanted {
+		if now.Sub(wtx.requestedAt) < wantTxTimeout {
+			continue
+		}
+		inv.forgetRequest(wtx.peer)
+		if len(wtx.announcers) == 0 {
+			delete(inv.wanted, key)
+			continue
+		}
+		wtx.peer, wtx.announcers = wtx.announcers[0], wtx.announcers[1:]
+		wtx.requestedAt = now
+		inv.requested[wtx.peer]++
+		hash := make([]byte, sha256.Size)
+		copy(hash, key[:])
+		requests[wtx.peer] = append(requests[wtx.peer], hash)
+	}
+	return requests
+}
+
+// removePeer forgets the peer: the txs requested from it are requested again
+// from other peers on the next call to expired.
+func (inv *txInventory) removePeer(peerID p2p.ID) {
+	inv.mtx.Lock()
+	defer inv.mtx.Unlock()
+
+	for _, wtx := range inv.wanted {
+		if wtx.peer == peerID {
+			wtx.requestedAt = time.Time{}
+		}
+		for i, id := range wtx.announcers {
+			if id == peerID {
+				wtx.announcers = append(wtx.announcers[:i], wtx.announcers[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// forgetRequest counts a tx less as requested from the peer.
+func (inv *txInventory) forgetRequest(peerID p2p.ID) {
+	if inv.requested[peerID] <= 1 {
+		delete(inv.requested, peerID)
+		return
+	}
+	inv.requested[peerID]--
+}
+
+// peerInventoryQueue holds the hashes of the txs to request from a peer and of
+// the txs the peer requested, until the inventory routine of the peer sends
+// them, so that receiving the messages of the peer never blocks on sending.
+type peerInventoryQueue struct {
+	mtx       sync.Mutex
+	wants     [][]byte // hashes of the txs to request from the peer
+	requested [][]byte // hashes of the txs the peer requested
+	ready     chan struct{}
+}
+
+func newPeerInventoryQueue() *peerInventoryQueue {
+	return &peerInventoryQueue{ready: make(chan struct{}, 1)}
+}
+
+// want queues the hashes of txs to request from the peer. Their number is
+// bounded by the txInventory (see maxWantedTxsPerPeer).
+func (q *peerInventoryQueue) want(hashes [][]byte) {
+	q.mtx.Lock()
+	q.wants = append(q.wants, hashes...)
+	q.mtx.Unlock()
+	q.signal()
+}
+
+// request queues the hashes of txs the peer requested. The hashes beyond
+// maxWantedTxsPerPeer are dropped: the peer doesn't request more txs at a time
+// unless it misbehaves, and requests the dropped ones again otherwise.
+func (q *peerInventoryQueue) request(hashes [][]byte) {
+	q.mtx.Lock()
+	if n := maxWantedTxsPerPeer - len(q.requested); len(hashes) > n {
+		hashes = hashes[:n]
+	}
+	q.requested = append(q.requested, hashes...)
+	q.mtx.Unlock()
+	q.signal()
+}
+
+// take returns and forgets the queued hashes.
+func (q *peerInventoryQueue) take() (wants, requested [][]byte) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	wants, requested = q.wants, q.requested
+	q.wants, q.requested = nil, nil
+	return wants, requested
+}
+
+func (q *peerInventoryQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,111 @@
+package mempool
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+func TestTxInventory(t *testing.T) {
+	inv := newTxInventory(2, 2)
+	now := time.Now()
+	key1, key2, key3 := sha256.Sum256([]byte{1}), sha256.Sum256([]byte{2}), sha256.Sum256([]byte{3})
+
+	// a tx is requested from the first peer announcing it
+	assert.True(t, inv.announced(key1, "a", now))
+	assert.False(t, inv.announced(key1, "a", now))
+	assert.False(t, inv.announced(key1, "b", now))
+	assert.False(t, inv.announced(key1, "c", now))
+	assert.True(t, inv.announced(key2, "a", now))
+	assert.False(t, inv.announced(key3, "a", now), "too many wanted txs")
+
+	// and from the next one once it times out
+	assert.Empty(t, inv.expired(now.Add(wantTxTimeout/2)))
+	now = now.Add(wantTxTimeout)
+	assert.Equal(t, map[p2p.ID][][]byte{"b": {key1[:]}}, inv.expired(now))
+	assert.Len(t, inv.wanted, 1, "key2 has no other announcer")
+
+	// or right away if the peer is removed
+	inv.removePeer("b")
+	assert.Equal(t, map[p2p.ID][][]byte{"c": {key1[:]}}, inv.expired(now))
+
+	inv.received(key1)
+	assert.Empty(t, inv.wanted)
+}
+
+func TestTxInventoryMaxWantedPerPeer(t *testing.T) {
+	inv := newTxInventory(10, 2)
+	now := time.Now()
+	key1, key2, key3 := sha256.Sum256([]byte{1}), sha256.Sum256([]byte{2}), sha256.Sum256([]byte{3})
+
+	// a peer can't have more than 2 txs requested
+	assert.True(t, inv.announced(key1, "a", now))
+	assert.True(t, inv.announced(key2, "a", now))
+	assert.False(t, inv.announced(key3, "a", now), "too many txs requested from a")
+	assert.True(t, inv.announced(key3, "b", now))
+
+	// until it sends one
+	inv.received(key1)
+	assert.True(t, inv.announced(sha256.Sum256([]byte{4}), "a", now))
+
+	// the expired requests are forgotten
+	inv.expired(now.Add(wantTxTimeout))
+	assert.Empty(t, inv.requested)
+}
+
+func TestPeerInventoryQueue(t *testing.T) {
+	q := newPeerInventoryQueue()
+	hashes := make([][]byte, maxWantedTxsPerPeer+1)
+	for i := range hashes {
+		hash := sha256.Sum256([]byte{byte(i), byte(i >> 8)})
+		hashes[i] = hash[:]
+	}
+
+	q.want(hashes[:1])
+	q.request(hashes[:2])
+	// the requests beyond the limit are dropped
+	q.request(hashes)
+	select {
+	case <-q.ready:
+	default:
+		t.Fatal("expected the queue to be ready")
+	}
+
+	wants, requested := q.take()
+	assert.Equal(t, hashes[:1], wants)
+	assert.Len(t, requested, maxWantedTxsPerPeer)
+	wants, requested = q.take()
+	assert.Empty(t, wants)
+	assert.Empty(t, requested)
+}
+
+func TestTxHashesMessageValidateBasic(t *testing.T) {
+	hash := make([]byte, sha256.Size)
+	tooMany := make([][]byte, maxInventoryHashes+1)
+	for i := range tooMany {
+		tooMany[i] = hash
+	}
+	testCases := []struct {
+		hashes  [][]byte
+		wantErr bool
+	}{
+		{[][]byte{hash}, false},
+		{nil, true},
+		{tooMany, true},
+		{[][]byte{hash[1:]}, true},
+	}
+	for i, tc := range testCases {
+		err := (&TxHashesMessage{Hashes: tc.hashes}).ValidateBasic()
+		assert.Equal(t, tc.wantErr, err != nil, "#%d", i)
+		err = (&WantTxsMessage{Hashes: tc.hashes}).ValidateBasic()
+		assert.Equal(t, tc.wantErr, err != nil, "#%d", i)
+	}
+
+	// the largest messages fit in the inventory channel
+	bz := cdc.MustMarshalBinaryBare(&TxHashesMessage{Hashes: tooMany[1:]})
+	assert.True(t, len(bz) <= maxInventoryMsgSize, "%d > %d", len(bz), maxInventoryMsgSize)
+}
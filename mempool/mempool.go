@@ -2,6 +2,7 @@ package mempool
 
 import (
 	"fmt"
+	"time"
 
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/p2p"
@@ -25,11 +26,20 @@ type Mempool interface {
 
 	// ReapMaxBytesMaxGas reaps transactions from the mempool up to maxBytes
 	// bytes total with the condition that the total gasWanted must be less than
-	// maxGas.
+	// maxGas. The transactions whose gasWanted exceeds maxGas on their own
+	// (e.g. after an EndBlock lowered the max gas) are skipped.
 	// If both maxes are negative, there is no cap on the size of all returned
 	// transactions (~ all available transactions).
+	// The transactions of a sender returned by CheckTx are reaped by
+	// increasing sequence, up to the first gap (see ResponseCheckTx#Sender).
 	ReapMaxBytesMaxGas(maxBytes, maxGas int64) types.Txs
 
+	// ReapMaxBytesMaxGasUntil reaps transactions like ReapMaxBytesMaxGas, but
+	// stops at the deadline and returns the transactions reaped so far, none
+	// if the mempool is still locked or rechecking then. A zero deadline means
+	// no deadline.
+	ReapMaxBytesMaxGasUntil(maxBytes, maxGas int64, deadline time.Time) types.Txs
+
 	// ReapMaxTxs reaps up to max transactions from the mempool.
 	// If max is negative, there is no cap on the size of all returned
 	// transactions (~ all available transactions).
@@ -80,6 +90,25 @@ type Mempool interface {
 	// CloseWAL closes and discards the underlying WAL file.
 	// Any further writes will not be relayed to disk.
 	CloseWAL()
+
+	// InitRejectionsLog opens the log of the rejected txs.
+	InitRejectionsLog()
+
+	// CloseRejectionsLog closes the log of the rejected txs.
+	CloseRejectionsLog()
+
+	// InitCacheFile loads the cache of the seen txs from its file, and saves
+	// it there after each block from now on, if the cache is persistent.
+	InitCacheFile()
+
+	// CloseCacheFile saves the cache of the seen txs to its file, and stops
+	// saving it.
+	CloseCacheFile()
+
+	// RecentRejections returns up to limit of the txs most recently rejected
+	// by the mempool, the most recent first. It returns nil if the log of the
+	// rejected txs isn't open.
+	RecentRejections(limit int) []TxRejection
 }
 
 //--------------------------------------------------------------------------------
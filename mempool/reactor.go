@@ -1,6 +1,7 @@
 package mempool
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"math"
 	"reflect"
@@ -11,6 +12,7 @@ import (
 
 	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/libs/clist"
+	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/types"
@@ -19,6 +21,12 @@ import (
 const (
 	MempoolChannel = byte(0x30)
 
+	// MempoolInventoryChannel carries the tx hashes announced to peers, and
+	// their requests for the txs they want (see TxHashesMessage). Txs are only
+	// announced to the peers having the channel, and sent in full to the
+	// others.
+	MempoolInventoryChannel = byte(0x31)
+
 	aminoOverheadForTxMessage = 8
 
 	peerCatchupSleepIntervalMS = 100 // If peer is behind, sleep this amount
@@ -33,11 +41,15 @@ const (
 // Reactor handles mempool tx broadcasting amongst peers.
 // It maintains a map from peer ID to counter, to prevent gossiping txs to the
 // peers you received it from.
+//
+// The hashes of the txs are announced to the peers supporting it, which only
+// request the txs they don't have, instead of sending them the txs.
 type Reactor struct {
 	p2p.BaseReactor
-	config  *cfg.MempoolConfig
-	mempool *CListMempool
-	ids     *mempoolIDs
+	config    *cfg.MempoolConfig
+	mempool   *CListMempool
+	ids       *mempoolIDs
+	inventory *txInventory
 }
 
 type mempoolIDs struct {
@@ -106,9 +118,10 @@ func newMempoolIDs() *mempoolIDs {
 // NewReactor returns a new Reactor with the given config and mempool.
 func NewReactor(config *cfg.MempoolConfig, mempool *CListMempool) *Reactor {
 	memR := &Reactor{
-		config:  config,
-		mempool: mempool,
-		ids:     newMempoolIDs(),
+		config:    config,
+		mempool:   mempool,
+		ids:       newMempoolIDs(),
+		inventory: newTxInventory(config.Size, maxWantedTxsPerPeer),
 	}
 	memR.BaseReactor = *p2p.NewBaseReactor("Reactor", memR)
 	return memR
@@ -125,6 +138,7 @@ func (memR *Reactor) OnStart() error {
 	if !memR.config.Broadcast {
 		memR.Logger.Info("Tx broadcasting is disabled")
 	}
+	memR.Go("wantTxsRoutine", memR.wantTxsRoutine)
 	return nil
 }
 
@@ -136,26 +150,47 @@ func (memR *Reactor) GetChannels() []*p2p.ChannelDescriptor {
 			ID:       MempoolChannel,
 			Priority: 5,
 		},
+		{
+			ID:       MempoolInventoryChannel,
+			Priority: 5,
+		},
+	}
+}
+
+// InitPeer implements Reactor by creating the inventory queue of the peer.
+func (memR *Reactor) InitPeer(peer p2p.Peer) p2p.Peer {
+	if hasChannel(peer, MempoolInventoryChannel) {
+		peer.Set(peerInventoryQueueKey, newPeerInventoryQueue())
 	}
+	return peer
 }
 
 // AddPeer implements Reactor.
-// It starts a broadcast routine ensuring all txs are forwarded to the given peer.
+// It starts a broadcast routine ensuring all txs are forwarded to the given peer,
+// and an inventory routine sending the tx requests to and from the peer.
 func (memR *Reactor) AddPeer(peer p2p.Peer) {
 	memR.ids.ReserveForPeer(peer)
-	go memR.broadcastTxRoutine(peer)
+	memR.Go("broadcastTxRoutine", func() { memR.broadcastTxRoutine(peer) })
+	if q, ok := peer.Get(peerInventoryQueueKey).(*peerInventoryQueue); ok {
+		memR.Go("inventoryRoutine", func() { memR.inventoryRoutine(peer, q) })
+	}
 }
 
 // RemovePeer implements Reactor.
 func (memR *Reactor) RemovePeer(peer p2p.Peer, reason interface{}) {
 	memR.ids.Reclaim(peer)
+	memR.inventory.removePeer(peer.ID())
 	// broadcast routine checks if peer is gone and returns
 }
 
 // Receive implements Reactor.
-// It adds any received transactions to the mempool.
+// It adds any received transactions to the mempool, requests the announced
+// ones it doesn't have and sends the requested ones.
 func (memR *Reactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
-	msg, err := memR.decodeMsg(msgBytes)
+	msg, err := memR.decodeMsg(chID, msgBytes)
+	if err == nil {
+		err = msg.ValidateBasic()
+	}
 	if err != nil {
 		memR.Logger.Error("Error decoding message", "src", src, "chId", chID, "msg", msg, "err", err, "bytes", msgBytes)
 		memR.Switch.StopPeerForError(src, err)
@@ -165,6 +200,7 @@ func (memR *Reactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
 
 	switch msg := msg.(type) {
 	case *TxMessage:
+		memR.inventory.received(txKey(msg.Tx))
 		txInfo := TxInfo{SenderID: memR.ids.GetForPeer(src)}
 		if src != nil {
 			txInfo.SenderP2PID = src.ID()
@@ -174,6 +210,29 @@ func (memR *Reactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
 			memR.Logger.Info("Could not check tx", "tx", txID(msg.Tx), "err", err)
 		}
 		// broadcasting happens from go routines per peer
+	case *TxHashesMessage:
+		peerID := memR.ids.GetForPeer(src)
+		var wanted [][]byte
+		for _, hash := range msg.Hashes {
+			var key [sha256.Size]byte
+			copy(key[:], hash)
+			if memTx, ok := memR.mempool.txByKey(key); ok {
+				// don't announce the tx back
+				memTx.senders.LoadOrStore(peerID, true)
+				continue
+			}
+			if !memR.mempool.seenTx(key) && memR.inventory.announced(key, src.ID(), time.Now()) {
+				wanted = append(wanted, hash)
+			}
+		}
+		if q, ok := src.Get(peerInventoryQueueKey).(*peerInventoryQueue); ok && len(wanted) > 0 {
+			q.want(wanted)
+		}
+	case *WantTxsMessage:
+		// the txs are sent by the inventory routine of the peer
+		if q, ok := src.Get(peerInventoryQueueKey).(*peerInventoryQueue); ok {
+			q.request(msg.Hashes)
+		}
 	default:
 		memR.Logger.Error(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
 	}
@@ -191,7 +250,11 @@ func (memR *Reactor) broadcastTxRoutine(peer p2p.Peer) {
 	}
 
 	peerID := memR.ids.GetForPeer(peer)
-	var next *clist.CElement
+	inventory := hasChannel(peer, MempoolInventoryChannel)
+	var (
+		next   *clist.CElement
+		hashes [][]byte // to announce once no more txs are available
+	)
 	for {
 		// In case of both next.NextWaitChan() and peer.Quit() are variable at the same time
 		if !memR.IsRunning() || !peer.IsRunning() {
@@ -233,13 +296,30 @@ func (memR *Reactor) broadcastTxRoutine(peer p2p.Peer) {
 
 		// ensure peer hasn't already sent us this tx
 		if _, ok := memTx.senders.Load(peerID); !ok {
-			// send memTx
-			msg := &TxMessage{Tx: memTx.tx}
-			success := peer.Send(MempoolChannel, cdc.MustMarshalBinaryBare(msg))
-			if !success {
+			if inventory {
+				key := txKey(memTx.tx)
+				hashes = append(hashes, key[:])
+			} else {
+				// send memTx
+				msg := &TxMessage{Tx: memTx.tx}
+				success := peer.Send(MempoolChannel, cdc.MustMarshalBinaryBare(msg))
+				if !success {
+					time.Sleep(peerCatchupSleepIntervalMS * time.Millisecond)
+					continue
+				}
+			}
+		}
+
+		// announce the hashes in batches
+		if len(hashes) > 0 && (len(hashes) >= maxInventoryHashes || next.Next() == nil) {
+			msg := cdc.MustMarshalBinaryBare(&TxHashesMessage{Hashes: hashes})
+			for !peer.Send(MempoolInventoryChannel, msg) {
+				if !memR.IsRunning() || !peer.IsRunning() {
+					return
+				}
 				time.Sleep(peerCatchupSleepIntervalMS * time.Millisecond)
-				continue
 			}
+			hashes = nil
 		}
 
 		select {
@@ -254,19 +334,96 @@ func (memR *Reactor) broadcastTxRoutine(peer p2p.Peer) {
 	}
 }
 
+// inventoryRoutine requests the wanted txs from the peer, and sends it the
+// txs it requested, as queued by Receive and wantTxsRoutine. The rate is
+// bounded by the send rate of the connection to the peer.
+func (memR *Reactor) inventoryRoutine(peer p2p.Peer, q *peerInventoryQueue) {
+	for {
+		select {
+		case <-q.ready:
+		case <-peer.Quit():
+			return
+		case <-memR.Quit():
+			return
+		}
+
+		// the txs which are not sent are requested again once expired
+		wants, requested := q.take()
+		for len(wants) > 0 {
+			n := cmn.MinInt(len(wants), maxInventoryHashes)
+			peer.Send(MempoolInventoryChannel, cdc.MustMarshalBinaryBare(&WantTxsMessage{Hashes: wants[:n]}))
+			wants = wants[n:]
+		}
+		for _, hash := range requested {
+			if !memR.IsRunning() || !peer.IsRunning() {
+				return
+			}
+			var key [sha256.Size]byte
+			copy(key[:], hash)
+			if memTx, ok := memR.mempool.txByKey(key); ok {
+				peer.Send(MempoolChannel, cdc.MustMarshalBinaryBare(&TxMessage{Tx: memTx.tx}))
+			}
+		}
+	}
+}
+
+// wantTxsRoutine requests the txs which weren't received in time from other
+// peers which announced them.
+func (memR *Reactor) wantTxsRoutine() {
+	ticker := time.NewTicker(wantTxTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			for peerID, hashes := range memR.inventory.expired(now) {
+				peer := memR.Switch.Peers().Get(peerID)
+				if peer == nil {
+					continue
+				}
+				if q, ok := peer.Get(peerInventoryQueueKey).(*peerInventoryQueue); ok {
+					q.want(hashes)
+				}
+			}
+		case <-memR.Quit():
+			return
+		}
+	}
+}
+
+// hasChannel returns true if the peer advertises the channel.
+func hasChannel(peer p2p.Peer, chID byte) bool {
+	nodeInfo, ok := peer.NodeInfo().(p2p.DefaultNodeInfo)
+	if !ok {
+		return false
+	}
+	for _, ch := range nodeInfo.Channels {
+		if ch == chID {
+			return true
+		}
+	}
+	return false
+}
+
 //-----------------------------------------------------------------------------
 // Messages
 
 // MempoolMessage is a message sent or received by the Reactor.
-type MempoolMessage interface{}
+type MempoolMessage interface {
+	ValidateBasic() error
+}
 
 func RegisterMempoolMessages(cdc *amino.Codec) {
 	cdc.RegisterInterface((*MempoolMessage)(nil), nil)
 	cdc.RegisterConcrete(&TxMessage{}, "tendermint/mempool/TxMessage", nil)
+	cdc.RegisterConcrete(&TxHashesMessage{}, "tendermint/mempool/TxHashesMessage", nil)
+	cdc.RegisterConcrete(&WantTxsMessage{}, "tendermint/mempool/WantTxsMessage", nil)
 }
 
-func (memR *Reactor) decodeMsg(bz []byte) (msg MempoolMessage, err error) {
+func (memR *Reactor) decodeMsg(chID byte, bz []byte) (msg MempoolMessage, err error) {
 	maxMsgSize := calcMaxMsgSize(memR.config.MaxTxBytes)
+	if chID == MempoolInventoryChannel {
+		maxMsgSize = maxInventoryMsgSize
+	}
 	if l := len(bz); l > maxMsgSize {
 		return msg, ErrTxTooLarge{maxMsgSize, l}
 	}
@@ -281,11 +438,63 @@ type TxMessage struct {
 	Tx types.Tx
 }
 
+// ValidateBasic implements MempoolMessage.
+func (m *TxMessage) ValidateBasic() error {
+	return nil
+}
+
 // String returns a string representation of the TxMessage.
 func (m *TxMessage) String() string {
 	return fmt.Sprintf("[TxMessage %v]", m.Tx)
 }
 
+//-------------------------------------
+
+// TxHashesMessage announces the hashes (SHA256) of txs in the mempool. The
+// peer answers with a WantTxsMessage for the txs it doesn't have, which are
+// then sent in TxMessages.
+type TxHashesMessage struct {
+	Hashes [][]byte
+}
+
+// ValidateBasic implements MempoolMessage.
+func (m *TxHashesMessage) ValidateBasic() error {
+	return validateTxHashes(m.Hashes)
+}
+
+// String returns a string representation of the TxHashesMessage.
+func (m *TxHashesMessage) String() string {
+	return fmt.Sprintf("[TxHashesMessage %d]", len(m.Hashes))
+}
+
+// WantTxsMessage requests the txs with the given hashes, announced in a
+// TxHashesMessage.
+type WantTxsMessage struct {
+	Hashes [][]byte
+}
+
+// ValidateBasic implements MempoolMessage.
+func (m *WantTxsMessage) ValidateBasic() error {
+	return validateTxHashes(m.Hashes)
+}
+
+// String returns a string representation of the WantTxsMessage.
+func (m *WantTxsMessage) String() string {
+	return fmt.Sprintf("[WantTxsMessage %d]", len(m.Hashes))
+}
+
+func validateTxHashes(hashes [][]byte) error {
+	if len(hashes) == 0 || len(hashes) > maxInventoryHashes {
+		return fmt.Errorf("number of hashes must be in [1, %d], got %d", maxInventoryHashes, len(hashes))
+	}
+	for _, hash := range hashes {
+		if len(hash) != sha256.Size {
+			return fmt.Errorf("wrong hash size: expected %d, got %d", sha256.Size, len(hash))
+		}
+	}
+	return nil
+}
+
 // calcMaxMsgSize returns the max size of TxMessage
 // account for amino overhead of TxMessage
 func calcMaxMsgSize(maxTxSize int) int {
@@ -0,0 +1,139 @@
+package mempool
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	auto "github.com/tendermint/tendermint/libs/autofile"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// maxRecentRejections is the number of rejections kept in memory, to be
+	// queried through the RPC.
+	maxRecentRejections = 1000
+
+	// rejectionsFlushInterval is how often the rejections log is flushed to
+	// disk.
+	rejectionsFlushInterval = time.Second
+)
+
+// Reasons of the rejection of a tx.
+const (
+	RejectedCheckTx   = "check_tx"   // rejected by CheckTx
+	RejectedPostCheck = "post_check" // rejected by the post-check filter
+	RejectedRecheck   = "recheck"    // removed by the recheck after a block
+	RejectedPreCheck  = "pre_check"  // rejected by the pre-check filter
+	RejectedTooLarge  = "too_large"  // larger than max_tx_bytes
+	RejectedFull      = "full"       // the mempool is full
+)
+
+// TxRejection is a tx rejected by the mempool, or removed from it by the
+// recheck after a block.
+type TxRejection struct {
+	Time      time.Time    `json:"time"`
+	Hash      cmn.HexBytes `json:"hash"`
+	Size      int          `json:"size"`
+	Reason    string       `json:"reason"`
+	Code      uint32       `json:"code"` // returned by CheckTx, if any
+	Codespace string       `json:"codespace"`
+	Log       string       `json:"log"`  // of CheckTx, or the error
+	Peer      p2p.ID       `json:"peer"` // which sent the tx, empty for the RPC and rechecks
+}
+
+// rejectionLog logs the rejected txs to a group of files bounded in size,
+// one JSON object per line, and keeps the most recent ones in memory.
+type rejectionLog struct {
+	group *auto.Group
+
+	mtx    sync.Mutex
+	recent []TxRejection // ring buffer
+	next   int           // index of the next rejection in recent
+}
+
+// openRejectionLog opens the log in dir, which holds at most maxSize bytes of
+// rejections.
+func openRejectionLog(dir string, maxSize int64) (*rejectionLog, error) {
+	if err := cmn.EnsureDir(dir, 0700); err != nil {
+		return nil, err
+	}
+	group, err := auto.OpenGroup(dir+"/rejections",
+		auto.GroupHeadSizeLimit(maxSize/10), auto.GroupTotalSizeLimit(maxSize))
+	if err != nil {
+		return nil, err
+	}
+	if err := group.Start(); err != nil {
+		return nil, err
+	}
+	rl := &rejectionLog{group: group}
+	go rl.flushRoutine()
+	return rl, nil
+}
+
+func (rl *rejectionLog) flushRoutine() {
+	ticker := time.NewTicker(rejectionsFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rl.group.FlushAndSync()
+		case <-rl.group.Quit():
+			return
+		}
+	}
+}
+
+// add logs the rejection of tx.
+func (rl *rejectionLog) add(tx types.Tx, reason string, code uint32, codespace, log string, peer p2p.ID) error {
+	r := TxRejection{
+		Time:      time.Now().UTC(),
+		Hash:      tx.Hash(),
+		Size:      len(tx),
+		Reason:    reason,
+		Code:      code,
+		Codespace: codespace,
+		Log:       log,
+		Peer:      peer,
+	}
+
+	rl.mtx.Lock()
+	if len(rl.recent) < maxRecentRejections {
+		rl.recent = append(rl.recent, r)
+	} else {
+		rl.recent[rl.next] = r
+	}
+	rl.next = (rl.next + 1) % maxRecentRejections
+	rl.mtx.Unlock()
+
+	bz, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return rl.group.WriteLine(string(bz))
+}
+
+// recentRejections returns up to limit of the most recent rejections, the
+// most recent first.
+func (rl *rejectionLog) recentRejections(limit int) []TxRejection {
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+
+	if limit > len(rl.recent) {
+		limit = len(rl.recent)
+	}
+	rejections := make([]TxRejection, limit)
+	for i := range rejections {
+		rejections[i] = rl.recent[(rl.next-1-i+2*maxRecentRejections)%maxRecentRejections]
+	}
+	return rejections
+}
+
+// close flushes the log and closes its files.
+func (rl *rejectionLog) close() {
+	rl.group.Stop()
+	rl.group.Wait()
+	rl.group.Close()
+}
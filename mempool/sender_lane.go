@@ -0,0 +1,80 @@
+package mempool
+
+import (
+	"github.com/tendermint/tendermint/libs/clist"
+)
+
+// senderLane is the per-sender ordering lane of the mempool, used while
+// reaping. The app returns the sender and the sequence of a tx from CheckTx
+// (see ResponseCheckTx#Sender), and the txs of each sender are reaped by
+// increasing sequence, from its lowest sequence in the mempool up to the
+// first gap, so that the txs submitted concurrently, and received out of
+// order, don't fail at DeliverTx. The txs without a sender are reaped in
+// mempool order, as usual.
+//
+// The mempool doesn't know the next sequence of a sender on chain: it's up to
+// the app to reject the txs whose sequence was used already.
+type senderLane struct {
+	next     map[string]int64                // next sequence to reap, per sender
+	deferred map[string]map[int64]*mempoolTx // txs met before their turn, per sender and sequence
+}
+
+// newSenderLane returns the lane of the txs, or nil if none has a sender.
+func newSenderLane(txs *clist.CList) *senderLane {
+	var lane *senderLane
+	for e := txs.Front(); e != nil; e = e.Next() {
+		memTx := e.Value.(*mempoolTx)
+		if memTx.sender == "" {
+			continue
+		}
+		if lane == nil {
+			lane = &senderLane{
+				next:     make(map[string]int64),
+				deferred: make(map[string]map[int64]*mempoolTx),
+			}
+		}
+		if next, ok := lane.next[memTx.sender]; !ok || memTx.sequence < next {
+			lane.next[memTx.sender] = memTx.sequence
+		}
+	}
+	return lane
+}
+
+// push is called with the txs in mempool order, and calls reap with the txs
+// to reap in lane order: memTx and the deferred txs of its sender following
+// it if it's its turn, none otherwise. It returns false as soon as reap does.
+// A nil lane reaps the txs in mempool order.
+func (lane *senderLane) push(memTx *mempoolTx, reap func(*mempoolTx) bool) bool {
+	if lane == nil || memTx.sender == "" {
+		return reap(memTx)
+	}
+
+	next := lane.next[memTx.sender]
+	switch {
+	case memTx.sequence < next:
+		// another tx of the sender has this sequence, and was reaped already
+		return true
+	case memTx.sequence > next:
+		deferred, ok := lane.deferred[memTx.sender]
+		if !ok {
+			deferred = make(map[int64]*mempoolTx)
+			lane.deferred[memTx.sender] = deferred
+		}
+		if _, ok := deferred[memTx.sequence]; !ok {
+			deferred[memTx.sequence] = memTx
+		}
+		return true
+	}
+
+	deferred := lane.deferred[memTx.sender]
+	for memTx != nil {
+		if !reap(memTx) {
+			return false
+		}
+		delete(deferred, next)
+		next++
+		lane.next[memTx.sender] = next
+		memTx = deferred[next]
+	}
+	return true
+}
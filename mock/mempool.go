@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"time"
+
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/libs/clist"
 	mempl "github.com/tendermint/tendermint/mempool"
@@ -23,7 +25,10 @@ func (Mempool) CheckTxWithInfo(_ types.Tx, _ func(*abci.Response),
 	return nil
 }
 func (Mempool) ReapMaxBytesMaxGas(_, _ int64) types.Txs { return types.Txs{} }
-func (Mempool) ReapMaxTxs(n int) types.Txs              { return types.Txs{} }
+func (Mempool) ReapMaxBytesMaxGasUntil(_, _ int64, _ time.Time) types.Txs {
+	return types.Txs{}
+}
+func (Mempool) ReapMaxTxs(n int) types.Txs { return types.Txs{} }
 func (Mempool) Update(
 	_ int64,
 	_ types.Txs,
@@ -44,3 +49,10 @@ func (Mempool) TxsWaitChan() <-chan struct{} { return nil }
 
 func (Mempool) InitWAL()  {}
 func (Mempool) CloseWAL() {}
+
+func (Mempool) InitCacheFile()  {}
+func (Mempool) CloseCacheFile() {}
+
+func (Mempool) InitRejectionsLog()                             {}
+func (Mempool) CloseRejectionsLog()                            {}
+func (Mempool) RecentRejections(limit int) []mempl.TxRejection { return nil }
@@ -0,0 +1,372 @@
+package node
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	amino "github.com/tendermint/go-amino"
+
+	"github.com/tendermint/tendermint/libs/log"
+	rpccore "github.com/tendermint/tendermint/rpc/core"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+	"github.com/tendermint/tendermint/version"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// SetLogLevelFunc sets the function applying the log levels set through the
+// admin API (e.g. by `tendermint console`), the logger being up to its
+// owner. It must be called before the node is started; without it, the log
+// level can't be changed.
+func (n *Node) SetLogLevelFunc(f func(logLevel string) error) {
+	n.setLogLevel = f
+}
+
+// startAdminServers serves the admin routes on the admin socket, which only
+// the user running the node can connect to, and on rpc.admin_laddr to the
+// clients authenticated with a token or a TLS certificate.
+func (n *Node) startAdminServers() ([]net.Listener, error) {
+	env := n.ConfigureRPC()
+	if fs, ok := n.bcReactor.(rpccore.FastSync); ok {
+		env.SetFastSync(fs)
+	}
+	env.SetDBCompactor(n.dbs)
+	if n.setLogLevel != nil {
+		env.SetLogLevelFunc(func(logLevel string) error {
+			if err := n.setLogLevel(logLevel); err != nil {
+				return err
+			}
+			n.config.LogLevel = logLevel
+			return nil
+		})
+	}
+
+	coreCodec := amino.NewCodec()
+	ctypes.RegisterAmino(coreCodec)
+
+	config := rpcserver.DefaultConfig()
+	config.MaxBodyBytes = n.config.RPC.MaxBodyBytes
+	config.MaxHeaderBytes = n.config.RPC.MaxHeaderBytes
+
+	mux := http.NewServeMux()
+	rpcLogger := n.Logger.With("module", "admin-server")
+	rpcserver.RegisterRPCFuncs(mux, env.AdminRoutes(), coreCodec, rpcLogger,
+		rpcserver.OpenAPIInfo("Tendermint admin RPC", version.TMCoreSemVer),
+	)
+	auditLogger := n.Logger.With("module", "admin-audit")
+
+	var listeners []net.Listener
+	closeAll := func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}
+
+	if n.config.AdminSocket != "" {
+		listener, err := listenAdminSocket(n.config.AdminSocketFile(), config)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+		handler := &adminHandler{next: mux, local: true, maxBodyBytes: config.MaxBodyBytes, logger: auditLogger}
+		go rpcserver.StartHTTPServer(listener, handler, rpcLogger, config)
+	}
+
+	if n.config.RPC.AdminListenAddress != "" {
+		handler := &adminHandler{next: mux, maxBodyBytes: config.MaxBodyBytes, logger: auditLogger}
+		if n.config.RPC.AdminTokenFile != "" {
+			token, err := loadAdminToken(n.config.RPC.AdminTokenFilePath())
+			if err != nil {
+				closeAll()
+				return nil, err
+			}
+			handler.token = token
+		}
+		listener, err := rpcserver.Listen(n.config.RPC.AdminListenAddress, config)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		if n.config.RPC.IsAdminTLSEnabled() {
+			clientCAFile := ""
+			if n.config.RPC.AdminTLSClientCAFile != "" {
+				clientCAFile = n.config.RPC.AdminClientCAFile()
+			}
+			tlsConfig, err := adminTLSConfig(n.config.RPC.AdminCertFile(), n.config.RPC.AdminKeyFile(), clientCAFile)
+			if err != nil {
+				listener.Close()
+				closeAll()
+				return nil, err
+			}
+			listener = tls.NewListener(listener, tlsConfig)
+		}
+		listeners = append(listeners, listener)
+		go rpcserver.StartHTTPServer(listener, handler, rpcLogger, config)
+	}
+
+	return listeners, nil
+}
+
+// listenAdminSocket listens on the admin socket, restricted to the user
+// running the node.
+func listenAdminSocket(socket string, config *rpcserver.Config) (net.Listener, error) {
+	// the socket of a previous run is left behind if it crashed
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to remove the previous admin socket")
+	}
+	listener, err := rpcserver.Listen("unix://"+socket, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return nil, errors.Wrap(err, "failed to restrict the access to the admin socket")
+	}
+	return listener, nil
+}
+
+func loadAdminToken(file string) ([]byte, error) {
+	token, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the admin token")
+	}
+	token = bytes.TrimSpace(token)
+	if len(token) == 0 {
+		return nil, fmt.Errorf("the admin token file %s is empty", file)
+	}
+	return token, nil
+}
+
+// adminTLSConfig returns the TLS config of the admin API server, verifying
+// the certificates of the clients against the CAs of clientCAFile, if set.
+func adminTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the admin TLS certificate")
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the admin client CAs")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %s", clientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		// clients without certificates may still authenticate with the token
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// adminHandler authenticates the clients of the admin API, and logs the
+// calls they make, with their outcome (audit log).
+type adminHandler struct {
+	next         http.Handler
+	token        []byte // nil if the clients can't authenticate with a token
+	local        bool   // admin socket, whose clients are authenticated by its permissions
+	maxBodyBytes int64
+	logger       log.Logger
+}
+
+// adminCall is a call of an admin route, from a JSON-RPC or URI request.
+type adminCall struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.authenticate(r)
+	if !ok {
+		h.logger.Error("Rejected unauthenticated admin request", "remote", r.RemoteAddr, "path", r.URL.Path)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="tendermint-admin"`)
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, h.maxBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	rec := &auditResponseWriter{ResponseWriter: w}
+	h.next.ServeHTTP(rec, r)
+
+	calls := adminCalls(r, body)
+	errs := adminErrors(rec.body.Bytes())
+	for i, call := range calls {
+		var callErr string
+		if len(errs) == len(calls) {
+			callErr = errs[i]
+		}
+		h.logger.Info("Admin action", "method", call.Method, "params", string(call.Params),
+			"client", client, "remote", r.RemoteAddr, "err", callErr)
+	}
+}
+
+// authenticate returns the identity of the client of the request: the common
+// name of its verified certificate, "token" or "local" (admin socket).
+func (h *adminHandler) authenticate(r *http.Request) (client string, ok bool) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return "cert:" + r.TLS.PeerCertificates[0].Subject.CommonName, true
+	}
+	if h.token != nil {
+		const prefix = "Bearer "
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, prefix) &&
+			subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), h.token) == 1 {
+			return "token", true
+		}
+	}
+	if h.local {
+		return "local", true
+	}
+	return "", false
+}
+
+// adminCalls returns the calls of the request: the route of a URI request,
+// or the method(s) of a JSON-RPC (batch) request.
+func adminCalls(r *http.Request, body []byte) []adminCall {
+	if r.Method == http.MethodGet {
+		params, _ := json.Marshal(r.URL.RawQuery)
+		return []adminCall{{Method: strings.TrimPrefix(r.URL.Path, "/"), Params: params}}
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var calls []adminCall
+		if err := json.Unmarshal(body, &calls); err == nil {
+			return calls
+		}
+	} else {
+		var call adminCall
+		if err := json.Unmarshal(body, &call); err == nil {
+			return []adminCall{call}
+		}
+	}
+	return []adminCall{{Method: "<invalid>"}}
+}
+
+// adminErrors returns the errors of the JSON-RPC (batch) response, "" for the
+// successful calls.
+func adminErrors(body []byte) []string {
+	type response struct {
+		Error *rpctypes.RPCError `json:"error"`
+	}
+	var responses []response
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &responses); err != nil {
+			return nil
+		}
+	} else {
+		var res response
+		if err := json.Unmarshal(body, &res); err != nil {
+			return []string{"invalid response"}
+		}
+		responses = []response{res}
+	}
+	errs := make([]string, len(responses))
+	for i, res := range responses {
+		if res.Error != nil {
+			errs[i] = res.Error.Error()
+		}
+	}
+	return errs
+}
+
+// auditResponseWriter keeps a copy of the response, for the audit log.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+//-----------------------------------------------------------------------------
+
+// dbRecorder records the databases opened by the node through its
+// DBProvider, to compact them from the admin API.
+type dbRecorder struct {
+	provider DBProvider
+
+	mtx sync.Mutex
+	ids []string // in the order they were opened
+	dbs map[string]dbm.DB
+}
+
+func newDBRecorder(provider DBProvider) *dbRecorder {
+	return &dbRecorder{provider: provider, dbs: make(map[string]dbm.DB)}
+}
+
+// Provide implements DBProvider.
+func (r *dbRecorder) Provide(ctx *DBContext) (dbm.DB, error) {
+	db, err := r.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if _, ok := r.dbs[ctx.ID]; !ok {
+		r.ids = append(r.ids, ctx.ID)
+	}
+	r.dbs[ctx.ID] = db
+	return db, nil
+}
+
+// CompactDB implements rpccore.DBCompactor. Only the goleveldb databases can
+// be compacted: the others are skipped when compacting all the databases.
+func (r *dbRecorder) CompactDB(id string) ([]string, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	ids := r.ids
+	if id != "" {
+		db, ok := r.dbs[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown database %q (want one of %v)", id, r.ids)
+		}
+		if _, ok := db.(*dbm.GoLevelDB); !ok {
+			return nil, fmt.Errorf("the %s database can't be compacted (only goleveldb ones can)", id)
+		}
+		ids = []string{id}
+	}
+
+	compacted := []string{}
+	for _, id := range ids {
+		db, ok := r.dbs[id].(*dbm.GoLevelDB)
+		if !ok {
+			continue
+		}
+		if err := db.DB().CompactRange(util.Range{}); err != nil {
+			return compacted, errors.Wrapf(err, "failed to compact the %s database", id)
+		}
+		compacted = append(compacted, id)
+	}
+	return compacted, nil
+}
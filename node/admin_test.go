@@ -0,0 +1,139 @@
+package node
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/log"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/lib/client"
+	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+)
+
+func TestNodeAdminSocket(t *testing.T) {
+	config := cfg.ResetTestRoot("node_admin_test")
+	defer os.RemoveAll(config.RootDir)
+	config.AdminSocket = "admin.sock"
+
+	n, err := DefaultNewNode(config, log.TestingLogger())
+	require.NoError(t, err)
+	logLevels := make(chan string, 1)
+	n.SetLogLevelFunc(func(logLevel string) error {
+		logLevels <- logLevel
+		return nil
+	})
+	require.NoError(t, n.Start())
+	defer n.Stop()
+
+	info, err := os.Stat(config.AdminSocketFile())
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	client := rpcclient.NewURIClient("unix://" + config.AdminSocketFile())
+	ctypes.RegisterAmino(client.Codec())
+
+	_, err = client.Call("status", map[string]interface{}{}, new(ctypes.ResultStatus))
+	require.NoError(t, err)
+
+	_, err = client.Call("set_log_level", map[string]interface{}{"level": "consensus:debug"}, new(ctypes.ResultSetLogLevel))
+	require.NoError(t, err)
+	assert.Equal(t, "consensus:debug", <-logLevels)
+
+	// only the blocks below the latest height can be pruned
+	_, err = client.Call("prune_blocks", map[string]interface{}{"height": 1000}, new(ctypes.ResultPruneBlocks))
+	assert.Error(t, err)
+}
+
+func TestNodeAdminAPI(t *testing.T) {
+	config := cfg.ResetTestRoot("node_admin_api_test")
+	defer os.RemoveAll(config.RootDir)
+	config.RPC.AdminListenAddress = "tcp://127.0.0.1:0"
+	config.RPC.AdminTokenFile = "admin_token"
+	err := ioutil.WriteFile(filepath.Join(config.RootDir, "config", "admin_token"), []byte("s3cret\n"), 0600)
+	require.NoError(t, err)
+
+	n, err := DefaultNewNode(config, log.TestingLogger())
+	require.NoError(t, err)
+	require.NoError(t, n.Start())
+	defer n.Stop()
+	require.Len(t, n.adminListeners, 1)
+	addr := "http://" + n.adminListeners[0].Addr().String()
+
+	call := func(token, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, addr, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return res
+	}
+	status := `{"jsonrpc":"2.0","id":1,"method":"status","params":{}}`
+
+	res := call("", status)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	res = call("wrong", status)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = call("s3cret", status)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the test databases are in memory, so there is nothing to compact
+	res = call("s3cret", `{"jsonrpc":"2.0","id":1,"method":"compact_db","params":{"db":""}}`)
+	defer res.Body.Close()
+	var rpcRes rpctypes.RPCResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&rpcRes))
+	require.Nil(t, rpcRes.Error)
+	var result ctypes.ResultCompactDB
+	require.NoError(t, json.Unmarshal(rpcRes.Result, &result))
+	assert.Equal(t, []string{}, result.Compacted)
+}
+
+func TestAdminHandlerAudit(t *testing.T) {
+	var logs bytes.Buffer
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"jsonrpc":"2.0","id":1,"result":{}},` +
+			`{"jsonrpc":"2.0","id":2,"error":{"code":-32603,"message":"Internal error","data":"tx not found"}}]`))
+	})
+	h := &adminHandler{
+		next:         next,
+		local:        true,
+		maxBodyBytes: rpcserver.DefaultConfig().MaxBodyBytes,
+		logger:       log.NewTMLogger(&logs),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(
+		`[{"jsonrpc":"2.0","id":1,"method":"dial_peers","params":{"peers":["a@1.2.3.4:26656"]}},`+
+			`{"jsonrpc":"2.0","id":2,"method":"evict_tx","params":{"hash":"0xAB"}}]`))
+	require.NoError(t, err)
+	h.ServeHTTP(&nopResponseWriter{}, req)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "method=dial_peers")
+	assert.Contains(t, lines[0], "client=local")
+	assert.Contains(t, lines[0], "err=")
+	assert.Contains(t, lines[1], "method=evict_tx")
+	assert.Contains(t, lines[1], "tx not found")
+}
+
+type nopResponseWriter struct{}
+
+func (nopResponseWriter) Header() http.Header         { return http.Header{} }
+func (nopResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (nopResponseWriter) WriteHeader(int)             {}
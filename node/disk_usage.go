@@ -0,0 +1,284 @@
+package node
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	cfg "github.com/tendermint/tendermint/config"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/store/archive"
+)
+
+const (
+	// diskUsageCheckInterval is how often the disk usage is measured and
+	// checked against the soft quotas.
+	diskUsageCheckInterval = time.Minute
+
+	// diskQuotaPruneRatio is the share of the blocks of the block store
+	// pruned at a time while the blocks exceed their soft quota.
+	diskQuotaPruneRatio = 0.1
+
+	// diskQuotaMinRetainBlocks is the number of latest blocks never pruned to
+	// honour the soft quota of the blocks.
+	diskQuotaMinRetainBlocks = 1000
+)
+
+// Categories of the node data whose disk usage is tracked.
+const (
+	diskBlocks   = "blocks"
+	diskState    = "state"
+	diskWAL      = "wal"
+	diskTxIndex  = "tx_index"
+	diskEvidence = "evidence"
+)
+
+var diskCategories = []string{diskBlocks, diskState, diskWAL, diskTxIndex, diskEvidence}
+
+// diskPaths returns the paths of the files and directories holding the data
+// of each category.
+func diskPaths(config *cfg.Config) map[string][]string {
+	db := func(name string) string { return filepath.Join(config.DBDir(), name+".db") }
+	paths := map[string][]string{
+		diskBlocks:   {db("blockstore"), config.BlockStoreFilesDir()},
+		diskState:    {db("state")},
+		diskWAL:      {filepath.Dir(config.Consensus.WalFile())},
+		diskTxIndex:  {db("tx_index")},
+		diskEvidence: {db("evidence")},
+	}
+	if config.Mempool.WalEnabled() {
+		paths[diskWAL] = append(paths[diskWAL], config.Mempool.WalDir())
+	}
+	return paths
+}
+
+// parseDiskSoftQuotas parses a comma separated list of <category>=<bytes>
+// pairs (see BaseConfig#DiskSoftQuotas).
+func parseDiskSoftQuotas(s string) (map[string]int64, error) {
+	quotas := make(map[string]int64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid disk quota %q (want <category>=<bytes>)", pair)
+		}
+		category := strings.TrimSpace(parts[0])
+		if !cmn.StringInSlice(category, diskCategories) {
+			return nil, fmt.Errorf("invalid disk quota %q: unknown category (want one of %v)", pair, diskCategories)
+		}
+		quota, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil || quota <= 0 {
+			return nil, fmt.Errorf("invalid disk quota %q: want a positive number of bytes", pair)
+		}
+		quotas[category] = quota
+	}
+	return quotas, nil
+}
+
+// diskUsagePath returns the size of the files under path, 0 if it doesn't
+// exist.
+func diskUsagePath(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+//-----------------------------------------------------------------------------
+
+// diskUsageMonitor measures the disk space used by each category of the node
+// data, for /status and the metrics, and checks it against the optional soft
+// quotas of the categories. When the blocks exceed their quota, the oldest
+// blocks are pruned from the block store. The other categories have no
+// pruning policy: exceeding their quota is only reported.
+//
+// NOTE the databases may only shrink once compacted, so the pruned space can
+// take a while to show up in the usage.
+type diskUsageMonitor struct {
+	cmn.BaseService
+
+	paths      map[string][]string
+	quotas     map[string]int64
+	blockStore archive.PrunableBlockStore // nil if the blocks have no quota
+	metrics    *diskUsageMetrics
+
+	mtx   sync.Mutex
+	usage []ctypes.DiskUsage // latest measure
+}
+
+func newDiskUsageMonitor(
+	paths map[string][]string,
+	quotas map[string]int64,
+	blockStore archive.PrunableBlockStore,
+	metrics *diskUsageMetrics,
+	logger log.Logger,
+) *diskUsageMonitor {
+	m := &diskUsageMonitor{
+		paths:      paths,
+		quotas:     quotas,
+		blockStore: blockStore,
+		metrics:    metrics,
+	}
+	m.BaseService = *cmn.NewBaseService(logger, "DiskUsageMonitor", m)
+	return m
+}
+
+// OnStart implements cmn.Service.
+func (m *diskUsageMonitor) OnStart() error {
+	go m.checkRoutine()
+	return nil
+}
+
+func (m *diskUsageMonitor) checkRoutine() {
+	m.check()
+
+	ticker := time.NewTicker(diskUsageCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.Quit():
+			return
+		}
+	}
+}
+
+// DiskUsage returns the latest measure of the disk usage, by category.
+func (m *diskUsageMonitor) DiskUsage() []ctypes.DiskUsage {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.usage
+}
+
+func (m *diskUsageMonitor) check() {
+	usage := make([]ctypes.DiskUsage, 0, len(diskCategories))
+	for _, category := range diskCategories {
+		u := ctypes.DiskUsage{Category: category, SoftQuota: m.quotas[category]}
+		for _, path := range m.paths[category] {
+			size, err := diskUsagePath(path)
+			if err != nil {
+				m.Logger.Error("Failed to measure the disk usage", "category", category, "path", path, "err", err)
+			}
+			u.Bytes += size
+		}
+		usage = append(usage, u)
+
+		m.metrics.UsageBytes.With("category", category).Set(float64(u.Bytes))
+		exceeded := u.SoftQuota > 0 && u.Bytes > u.SoftQuota
+		if exceeded {
+			m.metrics.QuotaExceeded.With("category", category).Set(1)
+			m.enforceQuota(u)
+		} else {
+			m.metrics.QuotaExceeded.With("category", category).Set(0)
+		}
+	}
+
+	m.mtx.Lock()
+	m.usage = usage
+	m.mtx.Unlock()
+}
+
+// enforceQuota applies the pruning policy of the category exceeding its
+// quota, if any.
+func (m *diskUsageMonitor) enforceQuota(u ctypes.DiskUsage) {
+	if u.Category != diskBlocks || m.blockStore == nil {
+		m.Logger.Error("Disk soft quota exceeded", "category", u.Category, "bytes", u.Bytes, "quota", u.SoftQuota)
+		return
+	}
+
+	base, height := m.blockStore.Base(), m.blockStore.Height()
+	pruneHeight := base + int64(float64(height-base+1)*diskQuotaPruneRatio)
+	if pruneHeight == base {
+		pruneHeight++
+	}
+	if retainHeight := height - diskQuotaMinRetainBlocks + 1; pruneHeight > retainHeight {
+		pruneHeight = retainHeight
+	}
+	if base == 0 || pruneHeight <= base {
+		m.Logger.Error("Disk soft quota of the blocks exceeded, but only the latest blocks are left",
+			"bytes", u.Bytes, "quota", u.SoftQuota, "base", base, "height", height)
+		return
+	}
+
+	pruned, err := m.blockStore.PruneBlocks(pruneHeight)
+	if err != nil {
+		m.Logger.Error("Failed to prune blocks", "height", pruneHeight, "err", err)
+		return
+	}
+	m.metrics.PrunedBlocks.Add(float64(pruned))
+	m.Logger.Info("Disk soft quota of the blocks exceeded, pruned the oldest blocks",
+		"bytes", u.Bytes, "quota", u.SoftQuota, "pruned", pruned, "base", pruneHeight)
+}
+
+//-----------------------------------------------------------------------------
+
+// diskUsageMetrics contains the metrics of the disk usage monitor.
+type diskUsageMetrics struct {
+	// Disk space used by the node data, by category.
+	UsageBytes metrics.Gauge
+	// Whether the disk usage of the category exceeds its soft quota.
+	QuotaExceeded metrics.Gauge
+	// Number of blocks pruned because the blocks exceeded their soft quota.
+	PrunedBlocks metrics.Counter
+}
+
+func prometheusDiskUsageMetrics(namespace string, labelsAndValues ...string) *diskUsageMetrics {
+	labels := []string{}
+	for i := 0; i < len(labelsAndValues); i += 2 {
+		labels = append(labels, labelsAndValues[i])
+	}
+	return &diskUsageMetrics{
+		UsageBytes: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "disk",
+			Name:      "usage_bytes",
+			Help:      "Disk space used by the node data, by category.",
+		}, append(labels, "category")).With(labelsAndValues...),
+		QuotaExceeded: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "disk",
+			Name:      "soft_quota_exceeded",
+			Help:      "Whether the disk usage of the category exceeds its soft quota.",
+		}, append(labels, "category")).With(labelsAndValues...),
+		PrunedBlocks: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "disk",
+			Name:      "soft_quota_pruned_blocks",
+			Help:      "Number of blocks pruned because the blocks exceeded their soft quota.",
+		}, labels).With(labelsAndValues...),
+	}
+}
+
+func nopDiskUsageMetrics() *diskUsageMetrics {
+	return &diskUsageMetrics{
+		UsageBytes:    discard.NewGauge(),
+		QuotaExceeded: discard.NewGauge(),
+		PrunedBlocks:  discard.NewCounter(),
+	}
+}
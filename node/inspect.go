@@ -0,0 +1,147 @@
+package node
+
+import (
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+	amino "github.com/tendermint/go-amino"
+
+	cfg "github.com/tendermint/tendermint/config"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	rpccore "github.com/tendermint/tendermint/rpc/core"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/indexer"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+)
+
+// Inspector serves the read-only RPC endpoints of a stopped node (blocks,
+// results, txs, validators, consensus params, ...) from its block store, state
+// DB and indexer, without starting p2p, consensus or the app. It lets
+// operators query the data of a crashed node without restarting consensus.
+type Inspector struct {
+	cmn.BaseService
+
+	config         *cfg.Config
+	env            *rpccore.Environment
+	blockStore     sm.BlockStore
+	eventBus       *types.EventBus
+	indexerService *indexer.IndexerService
+	rpcListeners   []net.Listener
+}
+
+// NewInspector opens the databases of the node with config. The node must be
+// stopped.
+func NewInspector(config *cfg.Config, dbProvider DBProvider, logger log.Logger) (*Inspector, error) {
+	blockStore, stateDB, err := initDBs(config, dbProvider, logger.With("module", "dbschema"))
+	if err != nil {
+		return nil, err
+	}
+
+	// the genesis doc is read from the state DB first, like the node does,
+	// but not saved to it
+	genDoc, err := loadGenesisDoc(stateDB)
+	if err != nil {
+		genDoc, err = DefaultGenesisDocProviderFunc(config)()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, _, err := setBlockStoreCompression(config, genDoc.ChainID, blockStore, logger); err != nil {
+		return nil, errors.Wrap(err, "failed to set the block store compression")
+	}
+
+	blockArchive, _, err := createArchiver(config, blockStore, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the block archive")
+	}
+
+	eventBus, err := createAndStartEventBus(logger)
+	if err != nil {
+		return nil, err
+	}
+	indexerService, eventSinks, err := createAndStartIndexerService(config, genDoc.ChainID, dbProvider, eventBus, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &rpccore.Environment{}
+	env.SetStateDB(stateDB)
+	env.SetBlockStore(blockStore)
+	if blockArchive != nil {
+		env.SetBlockArchive(blockArchive)
+	}
+	env.SetGenesisDoc(genDoc)
+	env.SetEventSinks(eventSinks)
+	env.SetLogger(logger.With("module", "rpc"))
+	env.SetConfig(*config.RPC)
+
+	ins := &Inspector{
+		config:         config,
+		env:            env,
+		blockStore:     blockStore,
+		eventBus:       eventBus,
+		indexerService: indexerService,
+	}
+	ins.BaseService = *cmn.NewBaseService(logger, "Inspector", ins)
+	return ins, nil
+}
+
+// OnStart starts the RPC server on the RPC listen addresses of the config.
+func (ins *Inspector) OnStart() error {
+	coreCodec := amino.NewCodec()
+	ctypes.RegisterAmino(coreCodec)
+
+	config := rpcserver.DefaultConfig()
+	config.MaxBodyBytes = ins.config.RPC.MaxBodyBytes
+	config.MaxHeaderBytes = ins.config.RPC.MaxHeaderBytes
+	config.MaxOpenConnections = ins.config.RPC.MaxOpenConnections
+	config.MaxBatchConcurrency = ins.config.RPC.MaxBatchConcurrency
+
+	rpcLogger := ins.Logger.With("module", "rpc-server")
+	for _, listenAddr := range splitAndTrimEmpty(ins.config.RPC.ListenAddress, ",", " ") {
+		mux := http.NewServeMux()
+		rpcserver.RegisterRPCFuncs(mux, ins.env.InspectRoutes(), coreCodec, rpcLogger,
+			rpcserver.MaxBatchConcurrency(config.MaxBatchConcurrency),
+			rpcserver.OpenAPIInfo("Tendermint inspect RPC", version.TMCoreSemVer),
+		)
+		listener, err := rpcserver.Listen(listenAddr, config)
+		if err != nil {
+			return err
+		}
+		go rpcserver.StartHTTPServer(listener, mux, rpcLogger, config)
+		ins.rpcListeners = append(ins.rpcListeners, listener)
+	}
+	return nil
+}
+
+// OnStop closes the RPC listeners and the block store.
+func (ins *Inspector) OnStop() {
+	for _, l := range ins.rpcListeners {
+		if err := l.Close(); err != nil {
+			ins.Logger.Error("Error closing listener", "listener", l, "err", err)
+		}
+	}
+	ins.indexerService.Stop()
+	ins.eventBus.Stop()
+	if c, ok := ins.blockStore.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			ins.Logger.Error("Error closing block store", "err", err)
+		}
+	}
+}
+
+// Listeners returns the addresses the RPC server listens on.
+func (ins *Inspector) Listeners() []string {
+	addrs := make([]string, len(ins.rpcListeners))
+	for i, l := range ins.rpcListeners {
+		addrs[i] = l.Addr().String()
+	}
+	return addrs
+}
@@ -0,0 +1,83 @@
+package node
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/privval"
+	"github.com/tendermint/tendermint/proxy"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/lib/client"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestInspector(t *testing.T) {
+	config := cfg.ResetTestRoot("node_inspect_test")
+	defer os.RemoveAll(config.RootDir)
+	config.RPC.ListenAddress = "tcp://127.0.0.1:0"
+
+	// the databases outlive the node
+	dbs := make(map[string]dbm.DB)
+	dbProvider := func(ctx *DBContext) (dbm.DB, error) {
+		if _, ok := dbs[ctx.ID]; !ok {
+			dbs[ctx.ID] = dbm.NewMemDB()
+		}
+		return dbs[ctx.ID], nil
+	}
+
+	// run a node until it commits a block
+	nodeKey, err := p2p.LoadOrGenNodeKey(config.NodeKeyFile())
+	require.NoError(t, err)
+	n, err := NewNode(config,
+		privval.LoadOrGenFilePV(config.PrivValidatorKeyFile(), config.PrivValidatorStateFile()),
+		nodeKey,
+		proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()),
+		DefaultGenesisDocProviderFunc(config),
+		dbProvider,
+		DefaultMetricsProvider(config.Instrumentation),
+		log.TestingLogger(),
+	)
+	require.NoError(t, err)
+	blocksSub, err := n.EventBus().Subscribe(context.Background(), "inspect_test", types.EventQueryNewBlock)
+	require.NoError(t, err)
+	require.NoError(t, n.Start())
+	select {
+	case <-blocksSub.Out():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the node to produce a block")
+	}
+	require.NoError(t, n.Stop())
+
+	// and inspect it once stopped
+	ins, err := NewInspector(config, dbProvider, log.TestingLogger())
+	require.NoError(t, err)
+	require.NoError(t, ins.Start())
+	defer ins.Stop()
+
+	client := rpcclient.NewURIClient("tcp://" + ins.Listeners()[0])
+	ctypes.RegisterAmino(client.Codec())
+
+	block := new(ctypes.ResultBlock)
+	_, err = client.Call("block", map[string]interface{}{"height": 1}, block)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, block.Block.Height)
+	assert.Equal(t, n.GenesisDoc().ChainID, block.Block.ChainID)
+
+	vals := new(ctypes.ResultValidators)
+	_, err = client.Call("validators", map[string]interface{}{"height": 1}, vals)
+	require.NoError(t, err)
+	assert.Len(t, vals.Validators, 1)
+
+	// consensus isn't running
+	_, err = client.Call("status", map[string]interface{}{}, new(ctypes.ResultStatus))
+	assert.Error(t, err)
+}
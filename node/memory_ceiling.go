@@ -0,0 +1,181 @@
+package node
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/cgroup"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	mempl "github.com/tendermint/tendermint/mempool"
+)
+
+const (
+	// memoryCeilingCheckInterval is how often the memory usage is checked
+	// against the soft limit.
+	memoryCeilingCheckInterval = 5 * time.Second
+
+	// defaultMemorySoftLimitRatio is the share of the container memory limit
+	// used as soft limit, if none is configured.
+	defaultMemorySoftLimitRatio = 0.9
+)
+
+// tuneGOMAXPROCS sets GOMAXPROCS to the CPU quota of the container, unless
+// it is set in the environment. The Go runtime only looks at the number of
+// CPUs of the host, which makes a throttled container run too many threads.
+func tuneGOMAXPROCS(limits cgroup.Limits, logger log.Logger) {
+	if os.Getenv("GOMAXPROCS") != "" {
+		return
+	}
+	if procs := limits.MaxProcs(); procs > 0 && procs < runtime.GOMAXPROCS(0) {
+		runtime.GOMAXPROCS(procs)
+		logger.Info("Set GOMAXPROCS to the container CPU quota", "GOMAXPROCS", procs, "cpus", limits.CPUs)
+	}
+}
+
+// memorySoftLimit returns the soft memory limit in bytes, or 0 if disabled.
+func memorySoftLimit(config *cfg.Config, limits cgroup.Limits) uint64 {
+	switch {
+	case config.MemorySoftLimit > 0:
+		return uint64(config.MemorySoftLimit)
+	case config.MemorySoftLimit == 0:
+		return uint64(float64(limits.MemoryBytes) * defaultMemorySoftLimitRatio)
+	default:
+		return 0
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// memoryCeiling checks the memory used by the node against a soft limit.
+// When it is exceeded, the mempool is halved, the caches are evicted and the
+// freed memory is returned to the OS, hopefully before the OOM killer
+// intervenes.
+type memoryCeiling struct {
+	cmn.BaseService
+
+	limit   uint64
+	mempool *mempl.CListMempool
+	metrics *memoryCeilingMetrics
+}
+
+func newMemoryCeiling(
+	limit uint64,
+	mempool *mempl.CListMempool,
+	metrics *memoryCeilingMetrics,
+	logger log.Logger,
+) *memoryCeiling {
+	mc := &memoryCeiling{
+		limit:   limit,
+		mempool: mempool,
+		metrics: metrics,
+	}
+	mc.BaseService = *cmn.NewBaseService(logger, "MemoryCeiling", mc)
+	return mc
+}
+
+// OnStart implements cmn.Service.
+func (mc *memoryCeiling) OnStart() error {
+	go mc.checkRoutine()
+	return nil
+}
+
+func (mc *memoryCeiling) checkRoutine() {
+	ticker := time.NewTicker(memoryCeilingCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mc.check()
+		case <-mc.Quit():
+			return
+		}
+	}
+}
+
+func (mc *memoryCeiling) check() {
+	used := memoryUsed()
+	mc.metrics.UsedBytes.Set(float64(used))
+	if used <= mc.limit {
+		return
+	}
+
+	mc.Logger.Error("Memory soft limit exceeded, shrinking the mempool and evicting caches",
+		"used", used, "limit", mc.limit)
+
+	if n := mc.mempool.Shrink(mc.mempool.TxsBytes() / 2); n > 0 {
+		mc.metrics.Enforcements.With("action", "mempool_shrink").Add(1)
+		mc.metrics.EvictedTxs.Add(float64(n))
+	}
+
+	// a cache saved to its file is kept, see ResetCache
+	mc.mempool.ResetCache()
+	mc.metrics.Enforcements.With("action", "cache_eviction").Add(1)
+
+	debug.FreeOSMemory()
+	mc.metrics.Enforcements.With("action", "free_os_memory").Add(1)
+}
+
+// memoryUsed returns the memory obtained from the OS by the Go runtime and
+// not released yet.
+func memoryUsed() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Sys - m.HeapReleased
+}
+
+//-----------------------------------------------------------------------------
+
+// memoryCeilingMetrics contains the metrics of the memory ceiling.
+type memoryCeilingMetrics struct {
+	// Memory used by the node.
+	UsedBytes metrics.Gauge
+	// Number of actions taken because the soft limit was exceeded, by action.
+	Enforcements metrics.Counter
+	// Number of transactions evicted from the mempool.
+	EvictedTxs metrics.Counter
+}
+
+func prometheusMemoryCeilingMetrics(namespace string, labelsAndValues ...string) *memoryCeilingMetrics {
+	labels := []string{}
+	for i := 0; i < len(labelsAndValues); i += 2 {
+		labels = append(labels, labelsAndValues[i])
+	}
+	return &memoryCeilingMetrics{
+		UsedBytes: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "memory",
+			Name:      "used_bytes",
+			Help:      "Memory obtained from the OS and not released yet.",
+		}, labels).With(labelsAndValues...),
+		Enforcements: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "memory",
+			Name:      "soft_limit_enforcements",
+			Help:      "Number of actions taken because the memory soft limit was exceeded.",
+		}, append(labels, "action")).With(labelsAndValues...),
+		EvictedTxs: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "memory",
+			Name:      "soft_limit_evicted_txs",
+			Help:      "Number of transactions evicted from the mempool because the memory soft limit was exceeded.",
+		}, labels).With(labelsAndValues...),
+	}
+}
+
+func nopMemoryCeilingMetrics() *memoryCeilingMetrics {
+	return &memoryCeilingMetrics{
+		UsedBytes:    discard.NewGauge(),
+		Enforcements: discard.NewCounter(),
+		EvictedTxs:   discard.NewCounter(),
+	}
+}
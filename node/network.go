@@ -0,0 +1,158 @@
+package node
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/privval"
+	"github.com/tendermint/tendermint/proxy"
+	"github.com/tendermint/tendermint/types"
+)
+
+// NewNetworkNode returns the node of another network (chain ID) run by the
+// primary node, in the same process: it has its own app, stores and reactors,
+// but shares the p2p connections of the primary node, multiplexing its
+// messages on the channels of the namespace of the network (see
+// p2p.Switch#AddNamespace). It exchanges them with the peers of the primary
+// node which run the network too.
+//
+// The node must be created before the primary node is started, which starts
+// and stops it. It has no listener of its own: its p2p config is ignored, and
+// its RPC is served by the RPC server of the primary node, under
+// NetworkRPCPath, with the rpc.unsafe, rpc.read_only and rpc.allowed_methods
+// of its config. Its gRPC, admin, Prometheus and profile servers are
+// disabled: the ones of the primary node only serve the primary node.
+func NewNetworkNode(primary *Node,
+	config *cfg.Config,
+	privValidator types.PrivValidator,
+	clientCreator proxy.ClientCreator,
+	genesisDocProvider GenesisDocProvider,
+	dbProvider DBProvider,
+	metricsProvider MetricsProvider,
+	logger log.Logger,
+	options ...Option) (*Node, error) {
+
+	if primary.primary != nil {
+		return nil, errors.New("the primary node is the node of another network")
+	}
+	if primary.IsRunning() {
+		return nil, errors.New("the primary node is running")
+	}
+	disableNetworkServers(config, logger)
+
+	node, err := newNode(primary, config, privValidator, primary.nodeKey, clientCreator, genesisDocProvider,
+		dbProvider, metricsProvider, logger, options...)
+	if err != nil {
+		return nil, err
+	}
+	primary.networks = append(primary.networks, node)
+	return node, nil
+}
+
+// NetworkRPCPath returns the path under which the RPC server of the primary
+// node serves the RPC of the network of the chain ID, e.g. its status at
+// /networks/<chain_id>/status and its websocket at /networks/<chain_id>/websocket.
+func NetworkRPCPath(chainID string) string {
+	return "/networks/" + chainID
+}
+
+// networkRPCTransport is the transport reported by the RPC of the node of
+// another network: the one of the primary node, with the chain ID of the
+// network in its node info.
+type networkRPCTransport struct {
+	*Node
+}
+
+func (t networkRPCTransport) NodeInfo() p2p.NodeInfo {
+	nodeInfo, ok := t.Node.NodeInfo().(p2p.DefaultNodeInfo)
+	if !ok {
+		return t.Node.NodeInfo()
+	}
+	nodeInfo.Network = t.genesisDoc.ChainID
+	return nodeInfo
+}
+
+// Networks returns the nodes of the other networks run by the node (see
+// NewNetworkNode).
+func (n *Node) Networks() []*Node {
+	return n.networks
+}
+
+// addNetwork adds the network to the node info, and returns the namespace of
+// the network on the switch.
+func (n *Node) addNetwork(network string) (*p2p.Switch, error) {
+	nodeInfo, ok := n.nodeInfo.(p2p.DefaultNodeInfo)
+	if !ok {
+		return nil, fmt.Errorf("expected DefaultNodeInfo, got %T", n.nodeInfo)
+	}
+	nodeInfo.Other.Networks = append(append([]string{}, nodeInfo.Other.Networks...), network)
+	if err := nodeInfo.Validate(); err != nil {
+		return nil, err
+	}
+
+	sw, err := n.sw.AddNamespace(network)
+	if err != nil {
+		return nil, err
+	}
+	n.nodeInfo = nodeInfo
+	n.sw.SetNodeInfo(nodeInfo)
+	n.transport.SetNodeInfo(nodeInfo)
+	return sw, nil
+}
+
+// disableNetworkServers disables the servers of the config of the node of
+// another network: its RPC is served by the RPC server of the primary node,
+// and its gRPC, admin, Prometheus and profile servers aren't served.
+func disableNetworkServers(config *cfg.Config, logger log.Logger) {
+	if config.RPC.GRPCListenAddress != "" || config.RPC.AdminListenAddress != "" || config.AdminSocket != "" ||
+		config.Instrumentation.Prometheus || config.ProfListenAddress != "" {
+		logger.Info("Disabling the gRPC, admin, Prometheus and profile servers of the network")
+	}
+	config.RPC.ListenAddress = ""
+	config.RPC.GRPCListenAddress = ""
+	config.RPC.AdminListenAddress = ""
+	config.AdminSocket = ""
+	config.Instrumentation.Prometheus = false
+	config.ProfListenAddress = ""
+}
+
+// defaultNewNetworkNode returns the node of the network of the home directory
+// (see P2PConfig.Networks), with the default settings of DefaultNewNode.
+func defaultNewNetworkNode(primary *Node, home string, logger log.Logger) (*Node, error) {
+	config, err := loadNetworkConfig(home)
+	if err != nil {
+		return nil, err
+	}
+	return NewNetworkNode(primary, config,
+		privval.LoadOrGenFilePV(config.PrivValidatorKeyFile(), config.PrivValidatorStateFile()),
+		proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()),
+		DefaultGenesisDocProviderFunc(config),
+		DefaultDBProvider,
+		DefaultMetricsProvider(config.Instrumentation),
+		logger,
+	)
+}
+
+// loadNetworkConfig loads the config file of the home directory of a network.
+func loadNetworkConfig(home string) (*cfg.Config, error) {
+	v := viper.New()
+	v.SetConfigFile(filepath.Join(home, "config", "config.toml"))
+	if err := v.ReadInConfig(); err != nil {
+		return nil, errors.Wrap(err, "could not read the config")
+	}
+	config := cfg.DefaultConfig()
+	if err := v.Unmarshal(config); err != nil {
+		return nil, errors.Wrap(err, "could not parse the config")
+	}
+	config.SetRoot(home)
+	if err := config.ValidateBasic(); err != nil {
+		return nil, errors.Wrap(err, "error in the config")
+	}
+	return config, nil
+}
@@ -0,0 +1,100 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestNodeNetworks(t *testing.T) {
+	config := cfg.ResetTestRoot("node_networks_test")
+	defer os.RemoveAll(config.RootDir)
+
+	// the config file of the network is loaded by DefaultNewNode
+	networkConfig := cfg.ResetTestRootWithChainID("node_networks_test", "other_chain")
+	defer os.RemoveAll(networkConfig.RootDir)
+	cfg.WriteConfigFile(filepath.Join(networkConfig.RootDir, "config", "config.toml"), networkConfig)
+	config.P2P.Networks = networkConfig.RootDir
+
+	n, err := DefaultNewNode(config, log.TestingLogger())
+	require.NoError(t, err)
+	require.Len(t, n.Networks(), 1)
+	network := n.Networks()[0]
+
+	// the network is advertised to the peers, on the connections of the node
+	assert.Equal(t, []string{"other_chain"}, n.NodeInfo().(p2p.DefaultNodeInfo).Other.Networks)
+	assert.Equal(t, n.NodeInfo(), network.NodeInfo())
+	assert.Equal(t, "other_chain", network.Switch().Network())
+	assert.Equal(t, "other_chain", network.GenesisDoc().ChainID)
+	assert.Empty(t, network.Config().RPC.ListenAddress)
+	assert.Equal(t, []string{"kv"}, network.Config().TxIndex.Indexer)
+
+	require.NoError(t, n.Start())
+	assert.True(t, network.IsRunning())
+
+	// both chains produce blocks
+	for _, node := range []*Node{n, network} {
+		blocksSub, err := node.EventBus().Subscribe(context.Background(), "node_test", types.EventQueryNewBlock)
+		require.NoError(t, err)
+		select {
+		case <-blocksSub.Out():
+		case <-blocksSub.Cancelled():
+			t.Fatal("blocksSub was cancelled")
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the node to produce a block")
+		}
+	}
+
+	// the network is served by the RPC server of the node, under its path
+	rpcAddr := "http://" + n.rpcListeners[0].Addr().String()
+	for _, path := range []string{"", NetworkRPCPath("other_chain")} {
+		status := struct {
+			Result struct {
+				NodeInfo struct {
+					Network string `json:"network"`
+				} `json:"node_info"`
+			} `json:"result"`
+		}{}
+		getRPC(t, rpcAddr+path+"/status", &status)
+		if path == "" {
+			assert.Equal(t, n.GenesisDoc().ChainID, status.Result.NodeInfo.Network)
+		} else {
+			assert.Equal(t, "other_chain", status.Result.NodeInfo.Network)
+		}
+	}
+	// with its kv indexer
+	search := struct {
+		Error  interface{} `json:"error"`
+		Result interface{} `json:"result"`
+	}{}
+	getRPC(t, rpcAddr+NetworkRPCPath("other_chain")+`/tx_search?query="tx.height=1"`, &search)
+	assert.Nil(t, search.Error)
+	assert.NotNil(t, search.Result)
+
+	// networks are added before the node is started
+	_, err = NewNetworkNode(n, networkConfig, nil, nil, nil, nil, nil, log.TestingLogger())
+	assert.Error(t, err)
+
+	require.NoError(t, n.Stop())
+	assert.False(t, network.IsRunning())
+}
+
+// getRPC decodes the JSON response of the RPC server to a GET on url.
+func getRPC(t *testing.T, url string, res interface{}) {
+	resp, err := http.Get(url) // nolint: gosec
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(res))
+}
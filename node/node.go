@@ -3,18 +3,22 @@ package node
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/rs/cors"
+	netproxy "golang.org/x/net/proxy"
 
 	amino "github.com/tendermint/go-amino"
 	abci "github.com/tendermint/tendermint/abci/types"
@@ -25,11 +29,15 @@ import (
 	cs "github.com/tendermint/tendermint/consensus"
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/evidence"
+	"github.com/tendermint/tendermint/libs/cgroup"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/compress"
 	"github.com/tendermint/tendermint/libs/log"
 	tmpubsub "github.com/tendermint/tendermint/libs/pubsub"
+	"github.com/tendermint/tendermint/libs/sigverify"
 	mempl "github.com/tendermint/tendermint/mempool"
 	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/admission"
 	"github.com/tendermint/tendermint/p2p/pex"
 	"github.com/tendermint/tendermint/privval"
 	"github.com/tendermint/tendermint/proxy"
@@ -38,10 +46,15 @@ import (
 	grpccore "github.com/tendermint/tendermint/rpc/grpc"
 	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
 	sm "github.com/tendermint/tendermint/state"
-	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/state/indexer"
+	blockidxkv "github.com/tendermint/tendermint/state/indexer/block/kv"
+	kvsink "github.com/tendermint/tendermint/state/indexer/sink/kv"
+	"github.com/tendermint/tendermint/state/indexer/sink/null"
+	"github.com/tendermint/tendermint/state/indexer/sink/psql"
+	"github.com/tendermint/tendermint/state/streaming"
 	"github.com/tendermint/tendermint/state/txindex/kv"
-	"github.com/tendermint/tendermint/state/txindex/null"
 	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/store/archive"
 	"github.com/tendermint/tendermint/types"
 	tmtime "github.com/tendermint/tendermint/types/time"
 	"github.com/tendermint/tendermint/version"
@@ -72,10 +85,13 @@ func DefaultDBProvider(ctx *DBContext) (dbm.DB, error) {
 type GenesisDocProvider func() (*types.GenesisDoc, error)
 
 // DefaultGenesisDocProviderFunc returns a GenesisDocProvider that loads
-// the GenesisDoc from the config.GenesisFile() on the filesystem.
+// the GenesisDoc from the config.GenesisFile() on the filesystem, checking its
+// hash against config.GenesisHash if set.
 func DefaultGenesisDocProviderFunc(config *cfg.Config) GenesisDocProvider {
 	return func() (*types.GenesisDoc, error) {
-		return types.GenesisDocFromFile(config.GenesisFile())
+		// validated by ValidateBasic
+		hash, _ := hex.DecodeString(config.GenesisHash)
+		return types.GenesisDocFromFileWithHash(config.GenesisFile(), hash)
 	}
 }
 
@@ -109,7 +125,7 @@ func DefaultNewNode(config *cfg.Config, logger log.Logger) (*Node, error) {
 		oldPV.Upgrade(newPrivValKey, newPrivValState)
 	}
 
-	return NewNode(config,
+	n, err := NewNode(config,
 		privval.LoadOrGenFilePV(newPrivValKey, newPrivValState),
 		nodeKey,
 		proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()),
@@ -118,6 +134,17 @@ func DefaultNewNode(config *cfg.Config, logger log.Logger) (*Node, error) {
 		DefaultMetricsProvider(config.Instrumentation),
 		logger,
 	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the other networks on the connections of the node.
+	for _, home := range config.P2P.NetworkHomes() {
+		if _, err := defaultNewNetworkNode(n, home, logger.With("network", home)); err != nil {
+			return nil, errors.Wrapf(err, "failed to create the node of %v", home)
+		}
+	}
+	return n, nil
 }
 
 // MetricsProvider returns a consensus, p2p and mempool Metrics.
@@ -177,43 +204,79 @@ type Node struct {
 	privValidator types.PrivValidator // local node's validator key
 
 	// network
-	transport   *p2p.MultiplexTransport
+	transport   p2p.Transport
 	sw          *p2p.Switch  // p2p connections
 	addrBook    pex.AddrBook // known peers
 	nodeInfo    p2p.NodeInfo
 	nodeKey     *p2p.NodeKey // our node privkey
 	isListening bool
+	primary     *Node   // whose connections are shared, for the node of another network
+	networks    []*Node // the nodes of the other networks (see NewNetworkNode)
 
 	// services
 	eventBus         *types.EventBus // pub/sub for services
 	stateDB          dbm.DB
-	blockStore       *store.BlockStore // store the blockchain to disk
-	bcReactor        p2p.Reactor       // for fast-syncing
-	mempoolReactor   *mempl.Reactor    // for gossipping transactions
+	blockStore       sm.BlockStore       // store the blockchain to disk
+	recompressor     *store.Recompressor // nil unless the existing blocks are recompressed
+	bcReactor        p2p.Reactor         // for fast-syncing
+	mempoolReactor   *mempl.Reactor      // for gossipping transactions
 	mempool          mempl.Mempool
 	consensusState   *cs.ConsensusState     // latest consensus state
 	consensusReactor *cs.ConsensusReactor   // for participating in the consensus
 	pexReactor       *pex.PEXReactor        // for exchanging peer addresses
 	evidencePool     *evidence.EvidencePool // tracking evidence
 	proxyApp         proxy.AppConns         // connection to the application
+	rpcEnv           *rpccore.Environment   // served by the rpc and admin servers
 	rpcListeners     []net.Listener         // rpc servers
-	txIndexer        txindex.TxIndexer
-	indexerService   *txindex.IndexerService
+	corsHandlers     []*corsHandler         // of the rpc servers
+	adminListeners   []net.Listener         // admin socket and rpc.admin_laddr
+	dbs              *dbRecorder            // for compacting them from the admin API
+	setLogLevel      func(string) error     // see SetLogLevelFunc
+	eventSinks       []indexer.EventSink
+	indexerService   *indexer.IndexerService
 	prometheusSrv    *http.Server
+	memoryCeiling    *memoryCeiling        // nil if there is no memory soft limit
+	sigVerifyPool    *sigverify.Pool       // nil if signatures are verified inline
+	blockArchive     *archive.Archive      // nil if blocks are not archived
+	archiver         *archive.Archiver     // nil if blocks are not pruned
+	diskUsage        *diskUsageMonitor     // of the node data, checked against the soft quotas
+	shadowExecutor   *sm.ShadowExecutor    // nil if there is no shadow app
+	backfiller       *sm.ResultsBackfiller // nil if there is no backfill app
+	blockListeners   []blockListener       // streaming the applied blocks
 }
 
-func initDBs(config *cfg.Config, dbProvider DBProvider) (blockStore *store.BlockStore, stateDB dbm.DB, err error) {
+// initDBs opens the block store and the state DB, and migrates them to the
+// current layout of their schema.
+func initDBs(
+	config *cfg.Config,
+	dbProvider DBProvider,
+	logger log.Logger,
+) (blockStore sm.BlockStore, stateDB dbm.DB, err error) {
 	var blockStoreDB dbm.DB
 	blockStoreDB, err = dbProvider(&DBContext{"blockstore", config})
 	if err != nil {
 		return
 	}
-	blockStore = store.NewBlockStore(blockStoreDB)
+	if _, err = store.Schema.Migrate(blockStoreDB, false, logger); err != nil {
+		return
+	}
+	switch config.BlockStoreBackend {
+	case cfg.BlockStoreBackendFlatFile:
+		blockStore, err = store.NewFileBlockStore(blockStoreDB, config.BlockStoreFilesDir(), store.DefaultSegmentSize)
+		if err != nil {
+			return
+		}
+	default:
+		blockStore = store.NewBlockStore(blockStoreDB)
+	}
 
 	stateDB, err = dbProvider(&DBContext{"state", config})
 	if err != nil {
 		return
 	}
+	if _, err = sm.Schema.Migrate(stateDB, false, logger); err != nil {
+		return
+	}
 
 	return
 }
@@ -227,6 +290,70 @@ func createAndStartProxyAppConns(clientCreator proxy.ClientCreator, logger log.L
 	return proxyApp, nil
 }
 
+// createShadowExecutor connects to the shadow app of the config, if any. The
+// builtin shadow apps keep their data apart from the ones of the app.
+func createShadowExecutor(config *cfg.Config, genDoc *types.GenesisDoc, state sm.State, stateDB dbm.DB,
+	blockStore sm.BlockStore, metrics *sm.Metrics, logger log.Logger) (*sm.ShadowExecutor, error) {
+	if config.ShadowProxyApp == "" {
+		return nil, nil
+	}
+	shadowLogger := logger.With("module", "shadow")
+	clientCreator := proxy.DefaultClientCreator(config.ShadowProxyApp, config.ABCI,
+		filepath.Join(config.DBDir(), "shadow"))
+	proxyApp, err := createAndStartProxyAppConns(clientCreator, shadowLogger)
+	if err != nil {
+		return nil, err
+	}
+	shadow := sm.NewShadowExecutor(proxyApp, blockStore, genDoc, stateDB, state, metrics)
+	shadow.SetLogger(shadowLogger)
+	return shadow, nil
+}
+
+func createResultsBackfiller(config *cfg.Config, genDoc *types.GenesisDoc, stateDB dbm.DB,
+	blockStore sm.BlockStore, logger log.Logger) (*sm.ResultsBackfiller, error) {
+	if config.BackfillProxyApp == "" {
+		return nil, nil
+	}
+	backfillLogger := logger.With("module", "backfill")
+	clientCreator := proxy.DefaultClientCreator(config.BackfillProxyApp, config.ABCI,
+		filepath.Join(config.DBDir(), "backfill"))
+	proxyApp, err := createAndStartProxyAppConns(clientCreator, backfillLogger)
+	if err != nil {
+		return nil, err
+	}
+	backfiller := sm.NewResultsBackfiller(proxyApp, blockStore, genDoc, stateDB)
+	backfiller.SetLogger(backfillLogger)
+	return backfiller, nil
+}
+
+// blockListener streams the blocks applied by the node to external consumers.
+type blockListener interface {
+	sm.BlockListener
+	cmn.Service
+}
+
+func createBlockListeners(config *cfg.Config, logger log.Logger) ([]blockListener, error) {
+	var listeners []blockListener
+	streamLogger := logger.With("module", "streaming")
+	if dir := config.TxIndex.StreamFileDir; dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(config.RootDir, dir)
+		}
+		fl, err := streaming.NewFileListener(dir, config.TxIndex.StreamFileMaxSize)
+		if err != nil {
+			return nil, err
+		}
+		fl.SetLogger(streamLogger)
+		listeners = append(listeners, fl)
+	}
+	if addr := config.TxIndex.StreamGRPCAddr; addr != "" {
+		gl := streaming.NewGRPCListener(addr, config.TxIndex.StreamBufferSize)
+		gl.SetLogger(streamLogger)
+		listeners = append(listeners, gl)
+	}
+	return listeners, nil
+}
+
 func createAndStartEventBus(logger log.Logger) (*types.EventBus, error) {
 	eventBus := types.NewEventBus()
 	eventBus.SetLogger(logger.With("module", "events"))
@@ -236,34 +363,63 @@ func createAndStartEventBus(logger log.Logger) (*types.EventBus, error) {
 	return eventBus, nil
 }
 
-func createAndStartIndexerService(config *cfg.Config, dbProvider DBProvider,
-	eventBus *types.EventBus, logger log.Logger) (*txindex.IndexerService, txindex.TxIndexer, error) {
-
-	var txIndexer txindex.TxIndexer
-	switch config.TxIndex.Indexer {
-	case "kv":
-		store, err := dbProvider(&DBContext{"tx_index", config})
-		if err != nil {
-			return nil, nil, err
-		}
-		switch {
-		case config.TxIndex.IndexTags != "":
-			txIndexer = kv.NewTxIndex(store, kv.IndexTags(splitAndTrimEmpty(config.TxIndex.IndexTags, ",", " ")))
-		case config.TxIndex.IndexAllTags:
-			txIndexer = kv.NewTxIndex(store, kv.IndexAllTags())
+func createAndStartIndexerService(config *cfg.Config, chainID string, dbProvider DBProvider,
+	eventBus *types.EventBus, logger log.Logger) (*indexer.IndexerService, []indexer.EventSink, error) {
+
+	eventSinks := []indexer.EventSink{}
+	for _, sinkType := range config.TxIndex.Indexer {
+		switch indexer.EventSinkType(sinkType) {
+		case indexer.NULL:
+			// when the null sink is set, no other sinks are used
+			eventSinks = []indexer.EventSink{null.NewEventSink()}
+		case indexer.KV:
+			store, err := dbProvider(&DBContext{"tx_index", config})
+			if err != nil {
+				return nil, nil, err
+			}
+			var (
+				txOptions    []func(*kv.TxIndex)
+				blockOptions []func(*blockidxkv.BlockerIndexer)
+			)
+			switch {
+			case config.TxIndex.IndexTags != "":
+				tags := splitAndTrimEmpty(config.TxIndex.IndexTags, ",", " ")
+				txOptions = append(txOptions, kv.IndexTags(tags))
+				blockOptions = append(blockOptions, blockidxkv.IndexTags(tags))
+			case config.TxIndex.IndexAllTags:
+				txOptions = append(txOptions, kv.IndexAllTags())
+				blockOptions = append(blockOptions, blockidxkv.IndexAllTags())
+			}
+			txIndexer := kv.NewTxIndex(store, txOptions...)
+			blockIndexer := blockidxkv.New(dbm.NewPrefixDB(store, []byte("block_events/")), blockOptions...)
+			eventSinks = append(eventSinks, kvsink.NewEventSink(txIndexer, blockIndexer))
+		case indexer.PSQL:
+			sink, err := psql.NewEventSink(config.TxIndex.PsqlConn, chainID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if config.TxIndex.PsqlReadReplicaConn != "" {
+				err := sink.SetReadReplica(
+					config.TxIndex.PsqlReadReplicaConn,
+					config.TxIndex.ReadReplicaMaxLag,
+					logger.With("module", "txindex"),
+				)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			eventSinks = append(eventSinks, sink)
 		default:
-			txIndexer = kv.NewTxIndex(store)
+			return nil, nil, fmt.Errorf("unsupported event sink type %q", sinkType)
 		}
-	default:
-		txIndexer = &null.TxIndex{}
 	}
 
-	indexerService := txindex.NewIndexerService(txIndexer, eventBus)
+	indexerService := indexer.NewIndexerService(eventSinks, eventBus)
 	indexerService.SetLogger(logger.With("module", "txindex"))
 	if err := indexerService.Start(); err != nil {
 		return nil, nil, err
 	}
-	return indexerService, txIndexer, nil
+	return indexerService, eventSinks, nil
 }
 
 func doHandshake(
@@ -338,6 +494,106 @@ func createMempoolAndMempoolReactor(config *cfg.Config, proxyApp proxy.AppConns,
 	return mempoolReactor, mempool
 }
 
+// createSigVerifyPool returns the pool verifying the commit and evidence
+// signatures, or nil if they are verified inline.
+func createSigVerifyPool(config *cfg.Config, genDoc *types.GenesisDoc, logger log.Logger) (*sigverify.Pool, error) {
+	if config.SigVerifyWorkers < 0 {
+		return nil, nil
+	}
+	cpus, err := cgroup.ParseCPUList(config.SigVerifyCPUs)
+	if err != nil {
+		return nil, err
+	}
+	metrics := sigverify.NopMetrics()
+	if config.Instrumentation.Prometheus {
+		metrics = sigverify.PrometheusMetrics(config.Instrumentation.Namespace, "chain_id", genDoc.ChainID)
+	}
+	pool := sigverify.NewPool(config.SigVerifyWorkers, sigverify.WithCPUs(cpus), sigverify.WithMetrics(metrics))
+	pool.SetLogger(logger.With("module", "sigverify"))
+	types.SetSigVerifier(pool)
+	return pool, nil
+}
+
+func createArchiver(config *cfg.Config, blockStore sm.BlockStore,
+	logger log.Logger) (*archive.Archive, *archive.Archiver, error) {
+	if config.ArchiveURL == "" {
+		return nil, nil, nil
+	}
+	objectStore, err := archive.NewObjectStore(config.ArchiveURL, config.ArchiveEndpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	blockArchive := archive.NewArchive(objectStore)
+	if config.RetainBlocks == 0 {
+		return blockArchive, nil, nil
+	}
+
+	prunable, ok := blockStore.(archive.PrunableBlockStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("block store %T can't be pruned", blockStore)
+	}
+	archiver := archive.NewArchiver(blockArchive, prunable, config.RetainBlocks)
+	archiver.SetLogger(logger.With("module", "archive"))
+	return blockArchive, archiver, nil
+}
+
+// setBlockStoreCompression sets the compression of the block store. It
+// returns its codecs, shared with the consensus WAL and the compressed blocks
+// of fast sync, and the Recompressor of its existing blocks if it's enabled.
+func setBlockStoreCompression(config *cfg.Config, chainID string, blockStore sm.BlockStore,
+	logger log.Logger) (*compress.Registry, *store.Recompressor, error) {
+	metrics := compress.NopMetrics()
+	if config.Instrumentation.Prometheus {
+		metrics = compress.PrometheusMetrics(config.Instrumentation.Namespace, "chain_id", chainID)
+	}
+	codecs, codec, err := store.LoadCompression(config.BlockStoreCompression,
+		config.BlockStoreCompressionDictFile(), metrics)
+	if err != nil {
+		return nil, nil, err
+	}
+	compressed, ok := blockStore.(interface {
+		SetCompression(codecs *compress.Registry, codec compress.Codec)
+	})
+	if !ok {
+		return codecs, nil, nil
+	}
+	compressed.SetCompression(codecs, codec)
+
+	if !config.BlockStoreRecompress {
+		return codecs, nil, nil
+	}
+	recompressible, ok := blockStore.(store.RecompressibleBlockStore)
+	if !ok {
+		return nil, nil, fmt.Errorf("block store %T can't be recompressed", blockStore)
+	}
+	recompressor := store.NewRecompressor(recompressible)
+	recompressor.SetLogger(logger.With("module", "blockstore"))
+	return codecs, recompressor, nil
+}
+
+func createDiskUsageMonitor(config *cfg.Config, chainID string, blockStore sm.BlockStore,
+	logger log.Logger) (*diskUsageMonitor, error) {
+	quotas, err := parseDiskSoftQuotas(config.DiskSoftQuotas)
+	if err != nil {
+		return nil, err
+	}
+	var prunable archive.PrunableBlockStore
+	if _, ok := quotas[diskBlocks]; ok {
+		if config.RetainBlocks > 0 {
+			return nil, errors.New("the disk soft quota of the blocks can't be combined with retain_blocks")
+		}
+		if prunable, ok = blockStore.(archive.PrunableBlockStore); !ok {
+			return nil, fmt.Errorf("block store %T can't be pruned", blockStore)
+		}
+	}
+
+	metrics := nopDiskUsageMetrics()
+	if config.Instrumentation.Prometheus {
+		metrics = prometheusDiskUsageMetrics(config.Instrumentation.Namespace, "chain_id", chainID)
+	}
+	return newDiskUsageMonitor(diskPaths(config), quotas, prunable, metrics, logger.With("module", "disk")), nil
+}
+
 func createEvidenceReactor(config *cfg.Config, dbProvider DBProvider,
 	stateDB dbm.DB, logger log.Logger) (*evidence.EvidenceReactor, *evidence.EvidencePool, error) {
 
@@ -356,15 +612,33 @@ func createEvidenceReactor(config *cfg.Config, dbProvider DBProvider,
 func createBlockchainReactor(config *cfg.Config,
 	state sm.State,
 	blockExec *sm.BlockExecutor,
-	blockStore *store.BlockStore,
+	blockStore sm.BlockStore,
 	fastSync bool,
+	expectedBlockHashes map[int64][]byte,
+	eventBus *types.EventBus,
+	codecs *compress.Registry,
 	logger log.Logger) (bcReactor p2p.Reactor, err error) {
 
 	switch config.FastSync.Version {
 	case "v0":
-		bcReactor = bcv0.NewBlockchainReactor(state.Copy(), blockExec, blockStore, fastSync)
+		bcReactor = bcv0.NewBlockchainReactor(state.Copy(), blockExec, blockStore, fastSync,
+			bcv0.ReactorNoProgressTimeout(config.FastSync.NoProgressTimeout),
+			bcv0.ReactorTipGracePeriod(config.FastSync.TipGracePeriod),
+			bcv0.ReactorMinPeers(config.FastSync.MinPeers, config.FastSync.PeerStabilizationDelay),
+			bcv0.ReactorMemoryBudget(config.FastSync.MemoryBudget),
+			bcv0.ReactorExpectedBlockHashes(expectedBlockHashes),
+			bcv0.ReactorBandwidthLimits(bcv0.BandwidthLimits{
+				UploadRate:          config.FastSync.UploadRate,
+				UploadRatePerPeer:   config.FastSync.UploadRatePerPeer,
+				DownloadRate:        config.FastSync.DownloadRate,
+				DownloadRatePerPeer: config.FastSync.DownloadRatePerPeer,
+			}),
+			bcv0.ReactorCodecs(codecs))
 	case "v1":
-		bcReactor = bcv1.NewBlockchainReactor(state.Copy(), blockExec, blockStore, fastSync)
+		bcReactor = bcv1.NewBlockchainReactor(state.Copy(), blockExec, blockStore, fastSync,
+			bcv1.ReactorMinPeers(config.FastSync.MinPeers, config.FastSync.PeerStabilizationDelay),
+			bcv1.ReactorMemoryBudget(config.FastSync.MemoryBudget),
+			bcv1.ReactorEventBus(eventBus))
 	default:
 		return nil, fmt.Errorf("unknown fastsync version %s", config.FastSync.Version)
 	}
@@ -383,6 +657,8 @@ func createConsensusReactor(config *cfg.Config,
 	csMetrics *cs.Metrics,
 	fastSync bool,
 	eventBus *types.EventBus,
+	clock *tmtime.MonotonicClock,
+	codecs *compress.Registry,
 	consensusLogger log.Logger) (*consensus.ConsensusReactor, *consensus.ConsensusState) {
 
 	consensusState := cs.NewConsensusState(
@@ -393,6 +669,8 @@ func createConsensusReactor(config *cfg.Config,
 		mempool,
 		evidencePool,
 		cs.StateMetrics(csMetrics),
+		cs.StateClock(clock),
+		cs.StateCodecs(codecs),
 	)
 	consensusState.SetLogger(consensusLogger)
 	if privValidator != nil {
@@ -406,18 +684,27 @@ func createConsensusReactor(config *cfg.Config,
 	return consensusReactor, consensusState
 }
 
+const (
+	// clockSkewPeers is the number of the last connected peers the clock
+	// skew is estimated from, and clockSkewMinPeers the number of peers
+	// required to warn about it.
+	clockSkewPeers    = 50
+	clockSkewMinPeers = 3
+)
+
 func createTransport(
 	config *cfg.Config,
 	nodeInfo p2p.NodeInfo,
 	nodeKey *p2p.NodeKey,
 	proxyApp proxy.AppConns,
+	clockSkew *tmtime.ClockSkew,
 ) (
-	*p2p.MultiplexTransport,
+	p2p.Transport,
 	[]p2p.PeerFilterFunc,
+	error,
 ) {
 	var (
 		mConnConfig = p2p.MConnConfig(config.P2P)
-		transport   = p2p.NewMultiplexTransport(nodeInfo, *nodeKey, mConnConfig)
 		connFilters = []p2p.ConnFilterFunc{}
 		peerFilters = []p2p.PeerFilterFunc{}
 	)
@@ -466,14 +753,58 @@ func createTransport(
 		)
 	}
 
+	// Ask an external system whether to admit peers.
+	if config.P2P.AdmissionCheckAddr != "" {
+		checker, err := admission.NewChecker(config.P2P.AdmissionCheckAddr)
+		if err != nil {
+			return nil, nil, err
+		}
+		if config.P2P.AdmissionCheckCacheTTL > 0 {
+			checker = admission.NewCachedChecker(checker, config.P2P.AdmissionCheckCacheTTL)
+		}
+		peerFilters = append(peerFilters, admission.PeerFilter(checker, config.P2P.AdmissionCheckTimeout))
+	}
+
+	if config.P2P.Transport == "quic" {
+		transport, err := p2p.NewQUICTransport(nodeInfo, *nodeKey, mConnConfig,
+			p2p.QUICTransportConnFilters(connFilters...),
+			p2p.QUICTransportClockSkew(clockSkew),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		return transport, peerFilters, nil
+	}
+
+	// Dial the peers through SOCKS5 proxies.
+	var dialer, persistentDialer netproxy.ContextDialer
+	if config.P2P.Proxy != "" {
+		d, err := p2p.NewProxyDialer(config.P2P.Proxy)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "p2p.proxy is incorrect")
+		}
+		dialer = d
+	}
+	if config.P2P.PersistentPeersProxy != "" {
+		d, err := p2p.NewProxyDialer(config.P2P.PersistentPeersProxy)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "p2p.persistent_peers_proxy is incorrect")
+		}
+		persistentDialer = d
+	}
+
+	transport := p2p.NewMultiplexTransport(nodeInfo, *nodeKey, mConnConfig)
 	p2p.MultiplexTransportConnFilters(connFilters...)(transport)
-	return transport, peerFilters
+	p2p.MultiplexTransportProxies(dialer, persistentDialer)(transport)
+	p2p.MultiplexTransportClockSkew(clockSkew)(transport)
+	return transport, peerFilters, nil
 }
 
 func createSwitch(config *cfg.Config,
 	transport p2p.Transport,
 	p2pMetrics *p2p.Metrics,
 	peerFilters []p2p.PeerFilterFunc,
+	peerLabels p2p.PeerLabels,
 	mempoolReactor *mempl.Reactor,
 	bcReactor p2p.Reactor,
 	consensusReactor *consensus.ConsensusReactor,
@@ -487,6 +818,7 @@ func createSwitch(config *cfg.Config,
 		transport,
 		p2p.WithMetrics(p2pMetrics),
 		p2p.SwitchPeerFilters(peerFilters...),
+		p2p.SwitchPeerLabels(peerLabels),
 	)
 	sw.SetLogger(p2pLogger)
 	sw.AddReactor("MEMPOOL", mempoolReactor)
@@ -508,19 +840,23 @@ func createAddrBookAndSetOnSwitch(config *cfg.Config, sw *p2p.Switch,
 	addrBook.SetLogger(p2pLogger.With("book", config.P2P.AddrBookFile()))
 
 	// Add ourselves to addrbook to prevent dialing ourselves
-	if config.P2P.ExternalAddress != "" {
-		addr, err := p2p.NewNetAddressString(p2p.IDAddressString(nodeKey.ID(), config.P2P.ExternalAddress))
+	for _, ext := range config.P2P.ExternalAddresses() {
+		if ext == "" || ext == cfg.ExternalAddressNone {
+			continue
+		}
+		addr, err := p2p.NewNetAddressString(p2p.IDAddressString(nodeKey.ID(), ext))
 		if err != nil {
 			return nil, errors.Wrap(err, "p2p.external_address is incorrect")
 		}
 		addrBook.AddOurAddress(addr)
 	}
-	if config.P2P.ListenAddress != "" {
-		addr, err := p2p.NewNetAddressString(p2p.IDAddressString(nodeKey.ID(), config.P2P.ListenAddress))
-		if err != nil {
-			return nil, errors.Wrap(err, "p2p.laddr is incorrect")
-		}
-		addrBook.AddOurAddress(addr)
+	listenAddrs, err := p2pListenAddrs(config.P2P, nodeKey.ID())
+	if err != nil {
+		return nil, err
+	}
+	for _, laddr := range listenAddrs {
+		addr := laddr.Addr
+		addrBook.AddOurAddress(&addr)
 	}
 
 	sw.SetAddrBook(addrBook)
@@ -559,7 +895,30 @@ func NewNode(config *cfg.Config,
 	logger log.Logger,
 	options ...Option) (*Node, error) {
 
-	blockStore, stateDB, err := initDBs(config, dbProvider)
+	return newNode(nil, config, privValidator, nodeKey, clientCreator, genesisDocProvider, dbProvider,
+		metricsProvider, logger, options...)
+}
+
+func newNode(primary *Node,
+	config *cfg.Config,
+	privValidator types.PrivValidator,
+	nodeKey *p2p.NodeKey,
+	clientCreator proxy.ClientCreator,
+	genesisDocProvider GenesisDocProvider,
+	dbProvider DBProvider,
+	metricsProvider MetricsProvider,
+	logger log.Logger,
+	options ...Option) (*Node, error) {
+
+	// Fit the node into the limits of its container, if any.
+	limits := cgroup.Detect()
+	tuneGOMAXPROCS(limits, logger)
+
+	// Record the databases, to compact them from the admin API.
+	dbs := newDBRecorder(dbProvider)
+	dbProvider = dbs.Provide
+
+	blockStore, stateDB, err := initDBs(config, dbProvider, logger.With("module", "dbschema"))
 	if err != nil {
 		return nil, err
 	}
@@ -569,12 +928,31 @@ func NewNode(config *cfg.Config,
 		return nil, err
 	}
 
+	// Compress the block parts before any block is saved.
+	codecs, recompressor, err := setBlockStoreCompression(config, genDoc.ChainID, blockStore, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not set the block store compression")
+	}
+
 	// Create the proxyApp and establish connections to the ABCI app (consensus, mempool, query).
 	proxyApp, err := createAndStartProxyAppConns(clientCreator, logger)
 	if err != nil {
 		return nil, err
 	}
 
+	// Start from the trust anchor instead of genesis, if there is one and the
+	// node has no blocks yet.
+	expectedBlockHashes := make(map[int64][]byte)
+	if config.FastSync.TrustHeight > 0 && blockStore.Height() == 0 {
+		var nextHash []byte
+		state, nextHash, err = bootstrapFromTrustAnchor(config.FastSync, genDoc.ChainID, stateDB, blockStore, proxyApp,
+			logger.With("module", "state"))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not bootstrap from the trust anchor")
+		}
+		expectedBlockHashes[state.LastBlockHeight+1] = nextHash
+	}
+
 	// EventBus and IndexerService must be started before the handshake because
 	// we might need to index the txs of the replayed block as this might not have happened
 	// when the node stopped last time (i.e. the node stopped after it saved the block
@@ -585,7 +963,7 @@ func NewNode(config *cfg.Config,
 	}
 
 	// Transaction indexing
-	indexerService, txIndexer, err := createAndStartIndexerService(config, dbProvider, eventBus, logger)
+	indexerService, eventSinks, err := createAndStartIndexerService(config, genDoc.ChainID, dbProvider, eventBus, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -612,6 +990,15 @@ func NewNode(config *cfg.Config,
 		}
 	}
 
+	// If an address is provided, connect to an external signing process
+	// serving gRPC.
+	if config.PrivValidatorGRPCAddr != "" {
+		privValidator, err = createPrivValidatorGRPCClient(config, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "error with private validator gRPC client")
+		}
+	}
+
 	pubKey := privValidator.GetPubKey()
 	if pubKey == nil {
 		// TODO: GetPubKey should return errors - https://github.com/tendermint/tendermint/issues/3602
@@ -629,79 +1016,188 @@ func NewNode(config *cfg.Config,
 	// Make MempoolReactor
 	mempoolReactor, mempool := createMempoolAndMempoolReactor(config, proxyApp, state, memplMetrics, logger)
 
+	var memCeiling *memoryCeiling
+	if limit := memorySoftLimit(config, limits); limit > 0 {
+		memCeilingMetrics := nopMemoryCeilingMetrics()
+		if config.Instrumentation.Prometheus {
+			memCeilingMetrics = prometheusMemoryCeilingMetrics(
+				config.Instrumentation.Namespace, "chain_id", genDoc.ChainID)
+		}
+		memCeiling = newMemoryCeiling(limit, mempool, memCeilingMetrics, logger.With("module", "memory"))
+	}
+
+	sigVerifyPool, err := createSigVerifyPool(config, genDoc, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	blockArchive, archiver, err := createArchiver(config, blockStore, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create block archive")
+	}
+
+	diskUsage, err := createDiskUsageMonitor(config, genDoc.ChainID, blockStore, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create disk usage monitor")
+	}
+
 	// Make Evidence Reactor
 	evidenceReactor, evidencePool, err := createEvidenceReactor(config, dbProvider, stateDB, logger)
 	if err != nil {
 		return nil, err
 	}
 
+	shadowExecutor, err := createShadowExecutor(config, genDoc, state, stateDB, blockStore, smMetrics, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to the shadow app")
+	}
+
+	backfiller, err := createResultsBackfiller(config, genDoc, stateDB, blockStore, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to the backfill app")
+	}
+
+	blockListeners, err := createBlockListeners(config, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create the block stream")
+	}
+
 	// make block executor for consensus and blockchain reactors to execute blocks
+	blockExecOptions := []sm.BlockExecutorOption{
+		sm.BlockExecutorWithMetrics(smMetrics),
+		sm.BlockExecutorWithCreateProposalDeadline(config.Consensus.CreateProposalDeadline),
+	}
+	if shadowExecutor != nil {
+		blockExecOptions = append(blockExecOptions, sm.BlockExecutorWithShadow(shadowExecutor))
+	}
+	for _, l := range blockListeners {
+		blockExecOptions = append(blockExecOptions, sm.BlockExecutorWithListeners(l))
+	}
+	if fastSync {
+		// the blockchain reactor stops batching before switching to consensus
+		blockExecOptions = append(blockExecOptions, sm.BlockExecutorWithBatchedWrites(config.FastSync.BatchedWrites))
+	}
 	blockExec := sm.NewBlockExecutor(
 		stateDB,
 		logger.With("module", "state"),
 		proxyApp.Consensus(),
 		mempool,
 		evidencePool,
-		sm.BlockExecutorWithMetrics(smMetrics),
+		blockExecOptions...,
 	)
 
 	// Make BlockchainReactor
-	bcReactor, err := createBlockchainReactor(config, state, blockExec, blockStore, fastSync, logger)
+	bcReactor, err := createBlockchainReactor(config, state, blockExec, blockStore, fastSync, expectedBlockHashes,
+		eventBus, codecs, logger)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not create blockchain reactor")
 	}
 
-	// Make ConsensusReactor
+	// Make ConsensusReactor, timestamping with a clock warning when the local
+	// clock steps.
+	clock := tmtime.NewMonotonicClock()
+	clock.SetStepHandler(tmtime.DefaultStepThreshold, func(step time.Duration) {
+		logger.Error("Local clock stepped", "step", step)
+	})
 	consensusReactor, consensusState := createConsensusReactor(
 		config, state, blockExec, blockStore, mempool, evidencePool,
-		privValidator, csMetrics, fastSync, eventBus, consensusLogger,
+		privValidator, csMetrics, fastSync, eventBus, clock, codecs, consensusLogger,
 	)
 
-	nodeInfo, err := makeNodeInfo(config, nodeKey, txIndexer, genDoc, state)
-	if err != nil {
-		return nil, err
-	}
+	var (
+		nodeInfo   p2p.NodeInfo
+		transport  p2p.Transport
+		sw         *p2p.Switch
+		addrBook   pex.AddrBook
+		pexReactor *pex.PEXReactor
+	)
+	if primary != nil {
+		// Share the connections of the primary node, on the channels of the
+		// namespace of the network.
+		sw, err = primary.addNetwork(genDoc.ChainID)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not add the network to the primary node")
+		}
+		sw.AddReactor("MEMPOOL", mempoolReactor)
+		sw.AddReactor("BLOCKCHAIN", bcReactor)
+		sw.AddReactor("CONSENSUS", consensusReactor)
+		sw.AddReactor("EVIDENCE", evidenceReactor)
+	} else {
+		nodeInfo, err = makeNodeInfo(config, nodeKey, eventSinks, genDoc, state)
+		if err != nil {
+			return nil, err
+		}
 
-	// Setup Transport.
-	transport, peerFilters := createTransport(config, nodeInfo, nodeKey, proxyApp)
+		p2pLogger := logger.With("module", "p2p")
 
-	// Setup Switch.
-	p2pLogger := logger.With("module", "p2p")
-	sw := createSwitch(
-		config, transport, p2pMetrics, peerFilters, mempoolReactor, bcReactor,
-		consensusReactor, evidenceReactor, nodeInfo, nodeKey, p2pLogger,
-	)
+		// Warn when the local clock deviates from the clocks of peers.
+		clockSkew := tmtime.NewClockSkew(clockSkewPeers)
+		clockSkew.SetSkewHandler(tmtime.DefaultMaxClockSkew, clockSkewMinPeers, func(median time.Duration, samples int) {
+			p2pLogger.Error("Local clock deviates from the median time of peers. Check NTP",
+				"offset", median, "peers", samples)
+		})
 
-	err = sw.AddPersistentPeers(splitAndTrimEmpty(config.P2P.PersistentPeers, ",", " "))
-	if err != nil {
-		return nil, errors.Wrap(err, "could not add peers from persistent_peers field")
-	}
+		// Setup Transport.
+		var peerFilters []p2p.PeerFilterFunc
+		transport, peerFilters, err = createTransport(config, nodeInfo, nodeKey, proxyApp, clockSkew)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create transport")
+		}
 
-	addrBook, err := createAddrBookAndSetOnSwitch(config, sw, p2pLogger, nodeKey)
-	if err != nil {
-		return nil, errors.Wrap(err, "could not create addrbook")
-	}
-
-	// Optionally, start the pex reactor
-	//
-	// TODO:
-	//
-	// We need to set Seeds and PersistentPeers on the switch,
-	// since it needs to be able to use these (and their DNS names)
-	// even if the PEX is off. We can include the DNS name in the NetAddress,
-	// but it would still be nice to have a clear list of the current "PersistentPeers"
-	// somewhere that we can return with net_info.
-	//
-	// If PEX is on, it should handle dialing the seeds. Otherwise the switch does it.
-	// Note we currently use the addrBook regardless at least for AddOurAddress
-	var pexReactor *pex.PEXReactor
-	if config.P2P.PexReactor {
-		pexReactor = createPEXReactorAndAddToSwitch(addrBook, config, sw, logger)
+		peerLabels, err := p2p.ParsePeerLabels(config.P2P.PeerLabels)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not parse peer_labels")
+		}
+
+		// Setup Switch.
+		sw = createSwitch(
+			config, transport, p2pMetrics, peerFilters, peerLabels, mempoolReactor, bcReactor,
+			consensusReactor, evidenceReactor, nodeInfo, nodeKey, p2pLogger,
+		)
+
+		err = sw.AddPersistentPeers(splitAndTrimEmpty(config.P2P.PersistentPeers, ",", " "))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not add peers from persistent_peers field")
+		}
+
+		err = sw.AddUnconditionalPeerIDs(splitAndTrimEmpty(config.P2P.UnconditionalPeerIDs, ",", " "))
+		if err != nil {
+			return nil, errors.Wrap(err, "could not add peer ids from unconditional_peer_ids field")
+		}
+
+		privatePeerIDs := splitAndTrimEmpty(config.P2P.PrivatePeerIDs, ",", " ")
+		err = sw.AddPrivatePeerIDs(privatePeerIDs)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not add peer ids from private_peer_ids field")
+		}
+
+		addrBook, err = createAddrBookAndSetOnSwitch(config, sw, p2pLogger, nodeKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create addrbook")
+		}
+		// Add private IDs to addrbook to block those peers being added
+		addrBook.AddPrivateIDs(privatePeerIDs)
+
+		// Optionally, start the pex reactor
+		//
+		// TODO:
+		//
+		// We need to set Seeds and PersistentPeers on the switch,
+		// since it needs to be able to use these (and their DNS names)
+		// even if the PEX is off. We can include the DNS name in the NetAddress,
+		// but it would still be nice to have a clear list of the current "PersistentPeers"
+		// somewhere that we can return with net_info.
+		//
+		// If PEX is on, it should handle dialing the seeds. Otherwise the switch does it.
+		// Note we currently use the addrBook regardless at least for AddOurAddress
+		if config.P2P.PexReactor {
+			pexReactor = createPEXReactorAndAddToSwitch(addrBook, config, sw, logger)
+		}
 	}
 
 	if config.ProfListenAddress != "" {
 		go func() {
-			logger.Error("Profile server", "err", http.ListenAndServe(config.ProfListenAddress, nil))
+			logger.Error("Profile server", "err", http.ListenAndServe(config.ProfListenAddress, profileServerMux()))
 		}()
 	}
 
@@ -709,6 +1205,7 @@ func NewNode(config *cfg.Config,
 		config:        config,
 		genesisDoc:    genDoc,
 		privValidator: privValidator,
+		primary:       primary,
 
 		transport: transport,
 		sw:        sw,
@@ -717,7 +1214,9 @@ func NewNode(config *cfg.Config,
 		nodeKey:   nodeKey,
 
 		stateDB:          stateDB,
+		dbs:              dbs,
 		blockStore:       blockStore,
+		recompressor:     recompressor,
 		bcReactor:        bcReactor,
 		mempoolReactor:   mempoolReactor,
 		mempool:          mempool,
@@ -726,9 +1225,18 @@ func NewNode(config *cfg.Config,
 		pexReactor:       pexReactor,
 		evidencePool:     evidencePool,
 		proxyApp:         proxyApp,
-		txIndexer:        txIndexer,
+		rpcEnv:           &rpccore.Environment{},
+		eventSinks:       eventSinks,
 		indexerService:   indexerService,
 		eventBus:         eventBus,
+		memoryCeiling:    memCeiling,
+		sigVerifyPool:    sigVerifyPool,
+		blockArchive:     blockArchive,
+		archiver:         archiver,
+		diskUsage:        diskUsage,
+		shadowExecutor:   shadowExecutor,
+		backfiller:       backfiller,
+		blockListeners:   blockListeners,
 	}
 	node.BaseService = *cmn.NewBaseService(logger, "Node", node)
 
@@ -748,9 +1256,6 @@ func (n *Node) OnStart() error {
 		time.Sleep(genTime.Sub(now))
 	}
 
-	// Add private IDs to addrbook to block those peers being added
-	n.addrBook.AddPrivateIDs(splitAndTrimEmpty(n.config.P2P.PrivatePeerIDs, ",", " "))
-
 	// Start the RPC server before the P2P server
 	// so we can eg. receive txs for the first block
 	if n.config.RPC.ListenAddress != "" {
@@ -761,34 +1266,105 @@ func (n *Node) OnStart() error {
 		n.rpcListeners = listeners
 	}
 
+	if n.config.AdminSocket != "" || n.config.RPC.AdminListenAddress != "" {
+		listeners, err := n.startAdminServers()
+		if err != nil {
+			return errors.Wrap(err, "failed to start the admin server")
+		}
+		n.adminListeners = listeners
+	}
+
 	if n.config.Instrumentation.Prometheus &&
 		n.config.Instrumentation.PrometheusListenAddr != "" {
 		n.prometheusSrv = n.startPrometheusServer(n.config.Instrumentation.PrometheusListenAddr)
 	}
 
-	// Start the transport.
-	addr, err := p2p.NewNetAddressString(p2p.IDAddressString(n.nodeKey.ID(), n.config.P2P.ListenAddress))
-	if err != nil {
-		return err
+	// Start the transport, unless the node shares the one of the primary node.
+	if n.primary == nil {
+		listenAddrs, err := p2pListenAddrs(n.config.P2P, n.nodeKey.ID())
+		if err != nil {
+			return err
+		}
+		if err := n.transport.ListenAll(listenAddrs); err != nil {
+			return err
+		}
+
+		n.isListening = true
+	}
+
+	if n.config.Mempool.WalEnabled() {
+		n.mempool.InitWAL() // no need to have the mempool wal during tests
+	}
+	if n.config.Mempool.RejectionsLogEnabled() {
+		n.mempool.InitRejectionsLog()
+	}
+	if n.config.Mempool.CacheFileEnabled() {
+		n.mempool.InitCacheFile()
 	}
-	if err := n.transport.Listen(*addr); err != nil {
+
+	if n.memoryCeiling != nil {
+		if err := n.memoryCeiling.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.sigVerifyPool != nil {
+		if err := n.sigVerifyPool.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.archiver != nil {
+		if err := n.archiver.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.recompressor != nil {
+		if err := n.recompressor.Start(); err != nil {
+			return err
+		}
+	}
+
+	if err := n.diskUsage.Start(); err != nil {
 		return err
 	}
 
-	n.isListening = true
+	if n.shadowExecutor != nil {
+		if err := n.shadowExecutor.Start(); err != nil {
+			return err
+		}
+	}
 
-	if n.config.Mempool.WalEnabled() {
-		n.mempool.InitWAL() // no need to have the mempool wal during tests
+	for _, l := range n.blockListeners {
+		if err := l.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.backfiller != nil {
+		if err := n.backfiller.Start(); err != nil {
+			return err
+		}
 	}
 
 	// Start the switch (the P2P server).
-	err = n.sw.Start()
-	if err != nil {
+	if err := n.sw.Start(); err != nil {
 		return err
 	}
+	if n.primary != nil {
+		return nil
+	}
+
+	// Start the nodes of the other networks, on the connections of the switch.
+	for _, network := range n.networks {
+		if err := network.Start(); err != nil {
+			return errors.Wrapf(err, "failed to start the node of %v", network.genesisDoc.ChainID)
+		}
+	}
 
 	// Always connect to persistent peers
-	err = n.sw.DialPeersAsync(splitAndTrimEmpty(n.config.P2P.PersistentPeers, ",", " "))
+	err := n.sw.DialPeersAsync(splitAndTrimEmpty(n.config.P2P.PersistentPeers, ",", " "))
 	if err != nil {
 		return errors.Wrap(err, "could not dial peers from persistent_peers field")
 	}
@@ -802,20 +1378,66 @@ func (n *Node) OnStop() {
 
 	n.Logger.Info("Stopping Node")
 
+	// the nodes of the other networks share the switch
+	for _, network := range n.networks {
+		network.Stop()
+	}
+
 	// first stop the non-reactor services
 	n.eventBus.Stop()
 	n.indexerService.Stop()
+	if n.memoryCeiling != nil {
+		n.memoryCeiling.Stop()
+	}
+	if n.archiver != nil {
+		n.archiver.Stop()
+	}
+	if n.recompressor != nil {
+		n.recompressor.Stop()
+	}
+	n.diskUsage.Stop()
+	if n.shadowExecutor != nil {
+		n.shadowExecutor.Stop()
+	}
+	if n.backfiller != nil {
+		n.backfiller.Stop()
+	}
 
 	// now stop the reactors
 	n.sw.Stop()
 
+	// signatures are verified inline once the pool is stopped
+	if n.sigVerifyPool != nil {
+		n.sigVerifyPool.Stop()
+	}
+
+	// no block is applied once the reactors are stopped
+	for _, l := range n.blockListeners {
+		l.Stop()
+	}
+
 	// stop mempool WAL
 	if n.config.Mempool.WalEnabled() {
 		n.mempool.CloseWAL()
 	}
+	if n.config.Mempool.RejectionsLogEnabled() {
+		n.mempool.CloseRejectionsLog()
+	}
+	if n.config.Mempool.CacheFileEnabled() {
+		n.mempool.CloseCacheFile()
+	}
 
-	if err := n.transport.Close(); err != nil {
-		n.Logger.Error("Error closing transport", "err", err)
+	if n.transport != nil {
+		if err := n.transport.Close(); err != nil {
+			n.Logger.Error("Error closing transport", "err", err)
+		}
+	}
+
+	// close the flatfile block store segments, if any
+	if c, ok := n.blockStore.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			n.Logger.Error("Error closing block store", "err", err)
+		}
 	}
 
 	n.isListening = false
@@ -828,6 +1450,12 @@ func (n *Node) OnStop() {
 		}
 	}
 
+	for _, l := range n.adminListeners {
+		if err := l.Close(); err != nil {
+			n.Logger.Error("Error closing admin listener", "listener", l, "err", err)
+		}
+	}
+
 	if pvsc, ok := n.privValidator.(cmn.Service); ok {
 		pvsc.Stop()
 	}
@@ -840,40 +1468,113 @@ func (n *Node) OnStop() {
 	}
 }
 
-// ConfigureRPC sets all variables in rpccore so they will serve
-// rpc calls from this node
-func (n *Node) ConfigureRPC() {
-	rpccore.SetStateDB(n.stateDB)
-	rpccore.SetBlockStore(n.blockStore)
-	rpccore.SetConsensusState(n.consensusState)
-	rpccore.SetMempool(n.mempool)
-	rpccore.SetEvidencePool(n.evidencePool)
-	rpccore.SetP2PPeers(n.sw)
-	rpccore.SetP2PTransport(n)
+// ConfigureRPC sets all variables of the RPC environment of the node so they
+// will serve rpc calls from this node, and returns it.
+func (n *Node) ConfigureRPC() *rpccore.Environment {
+	env := n.rpcEnv
+	env.SetStateDB(n.stateDB)
+	env.SetBlockStore(n.blockStore)
+	if n.blockArchive != nil {
+		env.SetBlockArchive(n.blockArchive)
+	}
+	if n.backfiller != nil {
+		env.SetResultsBackfiller(n.backfiller)
+	}
+	env.SetConsensusState(n.consensusState)
+	env.SetMempool(n.mempool)
+	env.SetEvidencePool(n.evidencePool)
+	env.SetP2PPeers(n.sw)
+	if n.primary != nil {
+		env.SetP2PTransport(networkRPCTransport{n})
+	} else {
+		env.SetP2PTransport(n)
+	}
+	env.SetDiskUsage(n.diskUsage)
+	if s, ok := n.bcReactor.(rpccore.FastSyncState); ok {
+		env.SetFastSyncState(s)
+	}
 	pubKey := n.privValidator.GetPubKey()
-	rpccore.SetPubKey(pubKey)
-	rpccore.SetGenesisDoc(n.genesisDoc)
-	rpccore.SetProxyAppQuery(n.proxyApp.Query())
-	rpccore.SetTxIndexer(n.txIndexer)
-	rpccore.SetConsensusReactor(n.consensusReactor)
-	rpccore.SetEventBus(n.eventBus)
-	rpccore.SetLogger(n.Logger.With("module", "rpc"))
-	rpccore.SetConfig(*n.config.RPC)
+	env.SetPubKey(pubKey)
+	if n.config.RPC.StatusAttestation {
+		env.SetStatusAttestationKey(n.nodeKey.PrivKey)
+	}
+	env.SetGenesisDoc(n.genesisDoc)
+	env.SetProxyAppQuery(n.proxyApp.Query())
+	env.SetEventSinks(n.eventSinks)
+	env.SetConsensusReactor(n.consensusReactor)
+	env.SetEventBus(n.eventBus)
+	env.SetLogger(n.Logger.With("module", "rpc"))
+	env.SetConfig(*n.config.RPC)
+	return env
+}
+
+// rpcRoutes returns the routes of the public RPC server of the node, according
+// to its RPC config (rpc.unsafe, rpc.read_only and rpc.allowed_methods).
+func (n *Node) rpcRoutes() (map[string]*rpcserver.RPCFunc, error) {
+	env := n.ConfigureRPC()
+	routes := env.Routes()
+	if n.config.RPC.Unsafe {
+		n.Logger.Info("rpc.unsafe is deprecated: the unsafe routes are served to authenticated clients by the admin API (rpc.admin_laddr)")
+		env.AddUnsafeRoutes(routes)
+	}
+	filtered, err := rpccore.FilterRoutes(routes, n.config.RPC)
+	if err != nil {
+		return nil, err
+	}
+	if len(filtered) < len(routes) {
+		n.Logger.Info("Serving a subset of the RPC methods",
+			"read_only", n.config.RPC.ReadOnly, "methods", len(filtered), "of", len(routes))
+	}
+	return filtered, nil
+}
+
+// rpcMux returns a mux serving the routes of the node over HTTP and on the
+// websocket.
+func (n *Node) rpcMux(routes map[string]*rpcserver.RPCFunc, coreCodec *amino.Codec,
+	config *rpcserver.Config) *http.ServeMux {
+	mux := http.NewServeMux()
+	rpcLogger := n.Logger.With("module", "rpc-server")
+	wmLogger := rpcLogger.With("protocol", "websocket")
+	wm := rpcserver.NewWebsocketManager(routes, coreCodec,
+		rpcserver.OnDisconnect(func(remoteAddr string) {
+			err := n.eventBus.UnsubscribeAll(context.Background(), remoteAddr)
+			if err != nil && err != tmpubsub.ErrSubscriptionNotFound {
+				wmLogger.Error("Failed to unsubscribe addr from events", "addr", remoteAddr, "err", err)
+			}
+		}),
+		rpcserver.ReadLimit(config.MaxBodyBytes),
+	)
+	wm.SetLogger(wmLogger)
+	mux.HandleFunc("/websocket", wm.WebsocketHandler)
+	rpcserver.RegisterRPCFuncs(mux, routes, coreCodec, rpcLogger,
+		rpcserver.MaxBatchConcurrency(config.MaxBatchConcurrency),
+		rpcserver.OpenAPIInfo("Tendermint RPC", version.TMCoreSemVer),
+	)
+	return mux
 }
 
 func (n *Node) startRPC() ([]net.Listener, error) {
-	n.ConfigureRPC()
 	listenAddrs := splitAndTrimEmpty(n.config.RPC.ListenAddress, ",", " ")
 	coreCodec := amino.NewCodec()
 	ctypes.RegisterAmino(coreCodec)
 
-	if n.config.RPC.Unsafe {
-		rpccore.AddUnsafeRoutes()
+	routes, err := n.rpcRoutes()
+	if err != nil {
+		return nil, err
+	}
+	// the other networks are served under their path (see NetworkRPCPath)
+	networkRoutes := make([]map[string]*rpcserver.RPCFunc, len(n.networks))
+	for i, network := range n.networks {
+		networkRoutes[i], err = network.rpcRoutes()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid RPC config of %v", network.genesisDoc.ChainID)
+		}
 	}
 
 	config := rpcserver.DefaultConfig()
 	config.MaxBodyBytes = n.config.RPC.MaxBodyBytes
 	config.MaxHeaderBytes = n.config.RPC.MaxHeaderBytes
+	config.MaxBatchConcurrency = n.config.RPC.MaxBatchConcurrency
 	config.MaxOpenConnections = n.config.RPC.MaxOpenConnections
 	// If necessary adjust global WriteTimeout to ensure it's greater than
 	// TimeoutBroadcastTxCommit.
@@ -881,25 +1582,21 @@ func (n *Node) startRPC() ([]net.Listener, error) {
 	if config.WriteTimeout <= n.config.RPC.TimeoutBroadcastTxCommit {
 		config.WriteTimeout = n.config.RPC.TimeoutBroadcastTxCommit + 1*time.Second
 	}
+	for _, network := range n.networks {
+		if config.WriteTimeout <= network.config.RPC.TimeoutBroadcastTxCommit {
+			config.WriteTimeout = network.config.RPC.TimeoutBroadcastTxCommit + 1*time.Second
+		}
+	}
 
 	// we may expose the rpc over both a unix and tcp socket
 	listeners := make([]net.Listener, len(listenAddrs))
 	for i, listenAddr := range listenAddrs {
-		mux := http.NewServeMux()
+		mux := n.rpcMux(routes, coreCodec, config)
+		for j, network := range n.networks {
+			path := NetworkRPCPath(network.genesisDoc.ChainID)
+			mux.Handle(path+"/", http.StripPrefix(path, network.rpcMux(networkRoutes[j], coreCodec, config)))
+		}
 		rpcLogger := n.Logger.With("module", "rpc-server")
-		wmLogger := rpcLogger.With("protocol", "websocket")
-		wm := rpcserver.NewWebsocketManager(rpccore.Routes, coreCodec,
-			rpcserver.OnDisconnect(func(remoteAddr string) {
-				err := n.eventBus.UnsubscribeAll(context.Background(), remoteAddr)
-				if err != nil && err != tmpubsub.ErrSubscriptionNotFound {
-					wmLogger.Error("Failed to unsubscribe addr from events", "addr", remoteAddr, "err", err)
-				}
-			}),
-			rpcserver.ReadLimit(config.MaxBodyBytes),
-		)
-		wm.SetLogger(wmLogger)
-		mux.HandleFunc("/websocket", wm.WebsocketHandler)
-		rpcserver.RegisterRPCFuncs(mux, rpccore.Routes, coreCodec, rpcLogger)
 		listener, err := rpcserver.Listen(
 			listenAddr,
 			config,
@@ -908,15 +1605,9 @@ func (n *Node) startRPC() ([]net.Listener, error) {
 			return nil, err
 		}
 
-		var rootHandler http.Handler = mux
-		if n.config.RPC.IsCorsEnabled() {
-			corsMiddleware := cors.New(cors.Options{
-				AllowedOrigins: n.config.RPC.CORSAllowedOrigins,
-				AllowedMethods: n.config.RPC.CORSAllowedMethods,
-				AllowedHeaders: n.config.RPC.CORSAllowedHeaders,
-			})
-			rootHandler = corsMiddleware.Handler(mux)
-		}
+		// the CORS settings can be reloaded
+		rootHandler := newCORSHandler(mux, n.config.RPC)
+		n.corsHandlers = append(n.corsHandlers, rootHandler)
 		if n.config.RPC.IsTLSEnabled() {
 			go rpcserver.StartHTTPAndTLSServer(
 				listener,
@@ -947,7 +1638,7 @@ func (n *Node) startRPC() ([]net.Listener, error) {
 		if err != nil {
 			return nil, err
 		}
-		go grpccore.StartGRPCServer(listener)
+		go grpccore.StartGRPCServer(n.rpcEnv, listener)
 		listeners = append(listeners, listener)
 	}
 
@@ -975,13 +1666,30 @@ func (n *Node) startPrometheusServer(addr string) *http.Server {
 	return srv
 }
 
+// profileServerMux serves the net/http/pprof handlers, along with the
+// goroutines spawned by each service at /debug/services/goroutines, to find
+// which service leaks goroutines.
+func profileServerMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.DefaultServeMux)
+	mux.HandleFunc("/debug/services/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(cmn.ServiceGoroutines()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+	return mux
+}
+
 // Switch returns the Node's Switch.
 func (n *Node) Switch() *p2p.Switch {
 	return n.sw
 }
 
 // BlockStore returns the Node's BlockStore.
-func (n *Node) BlockStore() *store.BlockStore {
+func (n *Node) BlockStore() sm.BlockStore {
 	return n.blockStore
 }
 
@@ -1044,30 +1752,42 @@ func (n *Node) Config() *cfg.Config {
 //------------------------------------------------------------------------------
 
 func (n *Node) Listeners() []string {
-	return []string{
-		fmt.Sprintf("Listener(@%v)", n.config.P2P.ExternalAddress),
+	if n.primary != nil {
+		return n.primary.Listeners()
 	}
+	var listeners []string
+	for _, laddr := range n.config.P2P.ListenAddresses() {
+		listeners = append(listeners, fmt.Sprintf("Listener(%v)", laddr))
+	}
+	return listeners
 }
 
 func (n *Node) IsListening() bool {
+	if n.primary != nil {
+		return n.primary.IsListening()
+	}
 	return n.isListening
 }
 
-// NodeInfo returns the Node's Info from the Switch.
+// NodeInfo returns the Node's Info from the Switch, which is the one of the
+// primary node for the node of another network.
 func (n *Node) NodeInfo() p2p.NodeInfo {
+	if n.primary != nil {
+		return n.primary.NodeInfo()
+	}
 	return n.nodeInfo
 }
 
 func makeNodeInfo(
 	config *cfg.Config,
 	nodeKey *p2p.NodeKey,
-	txIndexer txindex.TxIndexer,
+	eventSinks []indexer.EventSink,
 	genDoc *types.GenesisDoc,
 	state sm.State,
 ) (p2p.NodeInfo, error) {
-	txIndexerStatus := "on"
-	if _, ok := txIndexer.(*null.TxIndex); ok {
-		txIndexerStatus = "off"
+	txIndexerStatus := "off"
+	if indexer.TxSink(eventSinks) != nil {
+		txIndexerStatus = "on"
 	}
 
 	var bcChannel byte
@@ -1092,7 +1812,7 @@ func makeNodeInfo(
 		Channels: []byte{
 			bcChannel,
 			cs.StateChannel, cs.DataChannel, cs.VoteChannel, cs.VoteSetBitsChannel,
-			mempl.MempoolChannel,
+			mempl.MempoolChannel, mempl.MempoolInventoryChannel,
 			evidence.EvidenceChannel,
 		},
 		Moniker: config.Moniker,
@@ -1106,18 +1826,58 @@ func makeNodeInfo(
 		nodeInfo.Channels = append(nodeInfo.Channels, pex.PexChannel)
 	}
 
-	lAddr := config.P2P.ExternalAddress
-
-	if lAddr == "" {
-		lAddr = config.P2P.ListenAddress
+	// The first advertised address; the transport advertises the one of the
+	// IP version of each connection.
+	listenAddrs, err := p2pListenAddrs(config.P2P, nodeKey.ID())
+	if err != nil {
+		return nil, err
+	}
+	nodeInfo.ListenAddr = config.P2P.ExternalAddress
+	if len(listenAddrs) > 0 {
+		nodeInfo.ListenAddr = listenAddrs[0].Addr.DialString()
+		for _, laddr := range listenAddrs {
+			if laddr.Advertised != "" {
+				nodeInfo.ListenAddr = laddr.Advertised
+				break
+			}
+		}
 	}
 
-	nodeInfo.ListenAddr = lAddr
-
-	err := nodeInfo.Validate()
+	err = nodeInfo.Validate()
 	return nodeInfo, err
 }
 
+// p2pListenAddrs returns the listen addresses of the transport, with the
+// addresses they advertise.
+func p2pListenAddrs(config *cfg.P2PConfig, id p2p.ID) ([]p2p.ListenAddr, error) {
+	externals := config.ExternalAddresses()
+	var listenAddrs []p2p.ListenAddr
+	for i, laddr := range config.ListenAddresses() {
+		network := "tcp"
+		if j := strings.Index(laddr, "://"); j >= 0 {
+			network = laddr[:j]
+		}
+		addr, err := p2p.NewNetAddressString(p2p.IDAddressString(id, laddr))
+		if err != nil {
+			return nil, errors.Wrap(err, "p2p.laddr is incorrect")
+		}
+		listenAddr := p2p.ListenAddr{Network: network, Addr: *addr}
+		switch ext := externals[i]; ext {
+		case "":
+			listenAddr.Advertised = addr.DialString()
+		case cfg.ExternalAddressNone:
+		default:
+			// as is, hostnames included
+			if j := strings.Index(ext, "://"); j >= 0 {
+				ext = ext[j+len("://"):]
+			}
+			listenAddr.Advertised = ext
+		}
+		listenAddrs = append(listenAddrs, listenAddr)
+	}
+	return listenAddrs, nil
+}
+
 //------------------------------------------------------------------------------
 
 var (
@@ -1190,6 +1950,26 @@ func createAndStartPrivValidatorSocketClient(
 	return pvsc, nil
 }
 
+func createPrivValidatorGRPCClient(
+	config *cfg.Config,
+	logger log.Logger,
+) (types.PrivValidator, error) {
+	var opts []privval.SignerGRPCClientOption
+	if config.PrivValidatorGRPCCert != "" {
+		tlsConfig, err := privval.SignerGRPCClientTLSConfig(
+			config.PrivValidatorGRPCCertFile(),
+			config.PrivValidatorGRPCKeyFile(),
+			config.PrivValidatorGRPCRootCAFile(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, privval.SignerGRPCClientTLS(tlsConfig))
+	}
+
+	return privval.NewSignerGRPCClient(logger.With("module", "privval"), config.PrivValidatorGRPCAddr, opts...)
+}
+
 // splitAndTrimEmpty slices s into all subslices separated by sep and returns a
 // slice of the string s with all leading and trailing Unicode code points
 // contained in cutset removed. If sep is empty, SplitAndTrim splits after each
@@ -0,0 +1,104 @@
+package node
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rs/cors"
+
+	cfg "github.com/tendermint/tendermint/config"
+	mempl "github.com/tendermint/tendermint/mempool"
+)
+
+// ReloadConfig applies the hot reloadable fields of config (see
+// cfg.HotReloadableFields) to the running node, e.g. after the config file was
+// edited. The log level is only recorded: the logger is up to its owner. It
+// returns the keys of the other changed fields, which need a restart.
+func (n *Node) ReloadConfig(config *cfg.Config) (restartRequired []string, err error) {
+	if err := config.ValidateBasic(); err != nil {
+		return nil, err
+	}
+
+	var corsChanged, mempoolChanged, ratesChanged bool
+	for _, key := range n.config.Diff(config) {
+		if !cfg.IsHotReloadable(key) {
+			restartRequired = append(restartRequired, key)
+			continue
+		}
+		switch {
+		case strings.HasPrefix(key, "rpc.cors_"):
+			corsChanged = true
+		case strings.HasPrefix(key, "mempool."):
+			mempoolChanged = true
+		case strings.HasPrefix(key, "p2p."):
+			ratesChanged = true
+		}
+	}
+
+	n.config.LogLevel = config.LogLevel
+	if corsChanged {
+		n.config.RPC.CORSAllowedOrigins = config.RPC.CORSAllowedOrigins
+		n.config.RPC.CORSAllowedMethods = config.RPC.CORSAllowedMethods
+		n.config.RPC.CORSAllowedHeaders = config.RPC.CORSAllowedHeaders
+		for _, h := range n.corsHandlers {
+			h.setConfig(n.config.RPC)
+		}
+		n.Logger.Info("Reloaded the RPC CORS settings", "origins", config.RPC.CORSAllowedOrigins)
+	}
+	if mempoolChanged {
+		// the mempool shares the config of the node
+		if mem, ok := n.mempool.(*mempl.CListMempool); ok {
+			mem.SetLimits(config.Mempool.Size, config.Mempool.MaxTxsBytes)
+			n.Logger.Info("Reloaded the mempool limits",
+				"size", config.Mempool.Size, "max_txs_bytes", config.Mempool.MaxTxsBytes)
+		}
+	}
+	if ratesChanged {
+		n.config.P2P.SendRate = config.P2P.SendRate
+		n.config.P2P.RecvRate = config.P2P.RecvRate
+		if n.transport != nil { // nil for the node of another network
+			n.transport.SetRates(config.P2P.SendRate, config.P2P.RecvRate)
+		}
+		n.sw.SetRates(config.P2P.SendRate, config.P2P.RecvRate)
+		n.Logger.Info("Reloaded the peer rate limits",
+			"send_rate", config.P2P.SendRate, "recv_rate", config.P2P.RecvRate)
+	}
+	return restartRequired, nil
+}
+
+// corsHandler serves the requests with the CORS settings of the RPC config,
+// which can be changed while the server runs.
+type corsHandler struct {
+	next http.Handler
+
+	mtx     sync.RWMutex
+	handler http.Handler // next, behind the CORS middleware if enabled
+}
+
+func newCORSHandler(next http.Handler, config *cfg.RPCConfig) *corsHandler {
+	h := &corsHandler{next: next}
+	h.setConfig(config)
+	return h
+}
+
+func (h *corsHandler) setConfig(config *cfg.RPCConfig) {
+	handler := h.next
+	if config.IsCorsEnabled() {
+		handler = cors.New(cors.Options{
+			AllowedOrigins: config.CORSAllowedOrigins,
+			AllowedMethods: config.CORSAllowedMethods,
+			AllowedHeaders: config.CORSAllowedHeaders,
+		}).Handler(h.next)
+	}
+	h.mtx.Lock()
+	h.handler = handler
+	h.mtx.Unlock()
+}
+
+func (h *corsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mtx.RLock()
+	handler := h.handler
+	h.mtx.RUnlock()
+	handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,66 @@
+package node
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestNodeReloadConfig(t *testing.T) {
+	config := cfg.ResetTestRoot("node_reload_config_test")
+	defer os.RemoveAll(config.RootDir)
+	n, err := DefaultNewNode(config, log.TestingLogger())
+	require.NoError(t, err)
+
+	var (
+		newConfig = *config
+		rpc       = *config.RPC
+		mempool   = *config.Mempool
+		p2pConfig = *config.P2P
+		consensus = *config.Consensus
+	)
+	newConfig.RPC, newConfig.Mempool, newConfig.P2P, newConfig.Consensus = &rpc, &mempool, &p2pConfig, &consensus
+	newConfig.LogLevel = "debug"
+	newConfig.RPC.CORSAllowedOrigins = []string{"*"}
+	newConfig.Mempool.Size = 10
+	newConfig.P2P.SendRate = 1000
+	newConfig.Consensus.TimeoutCommit = time.Minute
+
+	restartRequired, err := n.ReloadConfig(&newConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"consensus.timeout_commit"}, restartRequired)
+	assert.Equal(t, "debug", n.config.LogLevel)
+	assert.Equal(t, []string{"*"}, n.config.RPC.CORSAllowedOrigins)
+	assert.Equal(t, 10, n.config.Mempool.Size)
+	assert.EqualValues(t, 1000, n.config.P2P.SendRate)
+	assert.NotEqual(t, time.Minute, n.config.Consensus.TimeoutCommit)
+
+	newConfig.Mempool.Size = -1
+	_, err = n.ReloadConfig(&newConfig)
+	assert.Error(t, err, "invalid config")
+}
+
+func TestCORSHandler(t *testing.T) {
+	config := cfg.TestRPCConfig()
+	h := newCORSHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), config)
+	allowedOrigin := func() string {
+		req := httptest.NewRequest("GET", "/status", nil)
+		req.Header.Set("Origin", "http://example.com")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	assert.Empty(t, allowedOrigin())
+	config.CORSAllowedOrigins = []string{"*"}
+	h.setConfig(config)
+	assert.Equal(t, "*", allowedOrigin())
+}
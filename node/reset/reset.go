@@ -0,0 +1,137 @@
+// Package reset implements the unsafe resets of the data of a node, one
+// component at a time: the block store, the state, the address book and the
+// sign state of the private validator. They are meant for testnets and
+// recovery by operators who know what they're doing: e.g. resetting the sign
+// state of a validator which already signed at the current height makes it
+// double sign.
+//
+// The node must be stopped. The functions take the paths of the data, as
+// found in the config, so other tooling (e.g. the CLI of an app embedding
+// Tendermint) can reuse them.
+package reset
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/privval"
+)
+
+// The databases in the db dir (see node.DefaultDBProvider), by component.
+var (
+	blockStoreDBs = []string{"blockstore"}
+	stateDBs      = []string{"state", "tx_index", "evidence"}
+)
+
+// BlockStore removes the block store from dbDir. The state must be reset too,
+// unless the blocks are synced again up to its height before the node starts:
+// the node refuses to start from a state ahead of its block store.
+func BlockStore(dbDir string, logger log.Logger) error {
+	return removeDBs(dbDir, blockStoreDBs, logger)
+}
+
+// State removes the state, the tx index and the evidence from dbDir, and the
+// consensus WAL at walFile (see config.ConsensusConfig.WalFile). The node
+// starts from genesis again, so the block store and the app must be reset too.
+func State(dbDir, walFile string, logger log.Logger) error {
+	if err := removeDBs(dbDir, stateDBs, logger); err != nil {
+		return err
+	}
+	return removeWAL(walFile, logger)
+}
+
+// AddrBook removes the address book, so the node dials its seeds and
+// persistent peers only. It's not an error if there is none.
+func AddrBook(addrBookFile string, logger log.Logger) error {
+	err := os.Remove(addrBookFile)
+	switch {
+	case os.IsNotExist(err):
+		return nil
+	case err != nil:
+		return errors.Wrap(err, "failed to remove the address book")
+	}
+	logger.Info("Removed the address book", "file", addrBookFile)
+	return nil
+}
+
+// PrivValidatorState resets the sign state of the file private validator to
+// genesis, so it signs at any height again. Its key must exist.
+func PrivValidatorState(privValKeyFile, privValStateFile string, logger log.Logger) error {
+	if _, err := os.Stat(privValKeyFile); err != nil {
+		return errors.Wrap(err, "failed to find the private validator key")
+	}
+	cmn.EnsureDir(filepath.Dir(privValStateFile), 0700)
+	pv := privval.LoadFilePVEmptyState(privValKeyFile, privValStateFile)
+	pv.Reset()
+	logger.Info("Reset the private validator state to genesis", "keyFile", privValKeyFile,
+		"stateFile", privValStateFile)
+	return nil
+}
+
+// All removes the whole db dir and the address book (unless keepAddrBook),
+// and resets the private validator state, generating its key if missing.
+func All(dbDir, addrBookFile, privValKeyFile, privValStateFile string, keepAddrBook bool,
+	logger log.Logger) error {
+	if keepAddrBook {
+		logger.Info("The address book remains intact")
+	} else if err := AddrBook(addrBookFile, logger); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dbDir); err != nil {
+		return errors.Wrap(err, "failed to remove all blockchain history")
+	}
+	logger.Info("Removed all blockchain history", "dir", dbDir)
+	// recreate the dbDir since the privVal state needs to live there
+	cmn.EnsureDir(dbDir, 0700)
+	return PrivValidator(privValKeyFile, privValStateFile, logger)
+}
+
+// PrivValidator resets the sign state of the file private validator to
+// genesis, generating its key if missing.
+func PrivValidator(privValKeyFile, privValStateFile string, logger log.Logger) error {
+	if _, err := os.Stat(privValKeyFile); os.IsNotExist(err) {
+		pv := privval.GenFilePV(privValKeyFile, privValStateFile)
+		pv.Save()
+		logger.Info("Generated private validator file", "keyFile", privValKeyFile,
+			"stateFile", privValStateFile)
+		return nil
+	}
+	return PrivValidatorState(privValKeyFile, privValStateFile, logger)
+}
+
+// removeDBs removes the databases of any backend: a directory (leveldb,
+// rocksdb) or a file (boltdb) named after the db.
+func removeDBs(dbDir string, names []string, logger log.Logger) error {
+	for _, name := range names {
+		path := filepath.Join(dbDir, name+".db")
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return errors.Wrapf(err, "failed to remove the %s db", name)
+		}
+		logger.Info("Removed the db", "db", name, "path", path)
+	}
+	return nil
+}
+
+// removeWAL removes the head of the WAL group and its rotated files
+// (walFile.000, walFile.001, ...), leaving the rest of its directory.
+func removeWAL(walFile string, logger log.Logger) error {
+	paths, err := filepath.Glob(walFile + ".[0-9][0-9][0-9]*")
+	if err != nil {
+		return err
+	}
+	paths = append(paths, walFile)
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to remove the consensus WAL")
+		}
+	}
+	logger.Info("Removed the consensus WAL", "file", walFile)
+	return nil
+}
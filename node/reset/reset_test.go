@@ -0,0 +1,86 @@
+package reset
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/privval"
+	"github.com/tendermint/tendermint/types"
+)
+
+func touch(t *testing.T, path string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+	require.NoError(t, ioutil.WriteFile(path, []byte("x"), 0600))
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestResetComponents(t *testing.T) {
+	root, err := ioutil.TempDir("", "reset")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	var (
+		dbDir        = filepath.Join(root, "data")
+		walFile      = filepath.Join(dbDir, "cs.wal", "wal")
+		addrBookFile = filepath.Join(root, "config", "addrbook.json")
+		logger       = log.TestingLogger()
+	)
+	for _, name := range []string{"blockstore", "state", "tx_index", "evidence"} {
+		touch(t, filepath.Join(dbDir, name+".db", "000001.log"))
+	}
+	touch(t, walFile)
+	touch(t, walFile+".000")
+	touch(t, filepath.Join(dbDir, "cs.wal", "other"))
+	touch(t, addrBookFile)
+
+	require.NoError(t, BlockStore(dbDir, logger))
+	assert.False(t, exists(filepath.Join(dbDir, "blockstore.db")))
+	assert.True(t, exists(filepath.Join(dbDir, "state.db")))
+
+	require.NoError(t, State(dbDir, walFile, logger))
+	for _, name := range []string{"state", "tx_index", "evidence"} {
+		assert.False(t, exists(filepath.Join(dbDir, name+".db")), name)
+	}
+	assert.False(t, exists(walFile))
+	assert.False(t, exists(walFile+".000"))
+	assert.True(t, exists(filepath.Join(dbDir, "cs.wal", "other")))
+
+	// idempotent
+	require.NoError(t, State(dbDir, walFile, logger))
+
+	require.NoError(t, AddrBook(addrBookFile, logger))
+	assert.False(t, exists(addrBookFile))
+	require.NoError(t, AddrBook(addrBookFile, logger))
+}
+
+func TestResetPrivValidatorState(t *testing.T) {
+	root, err := ioutil.TempDir("", "reset")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	keyFile := filepath.Join(root, "priv_validator_key.json")
+	stateFile := filepath.Join(root, "priv_validator_state.json")
+	logger := log.TestingLogger()
+
+	// the key must exist
+	assert.Error(t, PrivValidatorState(keyFile, stateFile, logger))
+
+	pv := privval.GenFilePV(keyFile, stateFile)
+	pv.Save()
+	vote := &types.Vote{Type: types.PrevoteType, Height: 10, ValidatorAddress: pv.GetAddress()}
+	require.NoError(t, pv.SignVote("test-chain", vote))
+
+	require.NoError(t, PrivValidatorState(keyFile, stateFile, logger))
+	pv = privval.LoadFilePV(keyFile, stateFile)
+	assert.EqualValues(t, 0, pv.LastSignState.Height)
+}
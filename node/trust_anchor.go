@@ -0,0 +1,260 @@
+package node
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/proxy"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/lib/client"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/version"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// trustAnchorSource provides the data needed to build the state at the trust
+// anchor. None of it is trusted: it is all verified against the anchor.
+type trustAnchorSource interface {
+	Block(height int64) (*types.Block, error)
+	Commit(height int64) (*types.SignedHeader, error)
+	Validators(height int64) (*types.ValidatorSet, error)
+	ConsensusParams(height int64) (*types.ConsensusParams, error)
+}
+
+// bootstrapFromTrustAnchor makes the node start from the trust anchor of the
+// config (see FastSyncConfig#TrustHeight) instead of genesis: it saves the
+// state after the block at the trust height, and the block itself, so that
+// fast sync goes on from the next block. The app must have committed the
+// block already. It also returns the hash of the next block, verified along
+// with the state, for fast sync to check the block against.
+//
+// The state is fetched from the RPC servers of the config, the first one
+// which gives a state matching the anchor is used.
+func bootstrapFromTrustAnchor(
+	config *cfg.FastSyncConfig,
+	chainID string,
+	stateDB dbm.DB,
+	blockStore sm.BlockStore,
+	proxyApp proxy.AppConns,
+	logger log.Logger,
+) (sm.State, []byte, error) {
+	height := config.TrustHeight
+	hash, err := hex.DecodeString(config.TrustHash)
+	if err != nil {
+		return sm.State{}, nil, errors.Wrap(err, "invalid trust_hash")
+	}
+
+	var (
+		state    sm.State
+		block    *types.Block
+		commit   *types.Commit
+		nextHash []byte
+	)
+	for _, server := range strings.Split(config.TrustRPCServers, ",") {
+		server = strings.TrimSpace(server)
+		state, block, commit, nextHash, err = makeTrustedState(newRPCTrustAnchorSource(server), chainID, height, hash)
+		if err == nil {
+			break
+		}
+		logger.Error("Failed to get the state at the trust anchor", "server", server, "err", err)
+	}
+	if err != nil {
+		return sm.State{}, nil, fmt.Errorf("no RPC server gave the state at the trust anchor (%d:%X)", height, hash)
+	}
+
+	res, err := proxyApp.Query().InfoSync(proxy.RequestInfo)
+	if err != nil {
+		return sm.State{}, nil, errors.Wrap(err, "error calling Info")
+	}
+	if res.LastBlockHeight != height || !bytes.Equal(res.LastBlockAppHash, state.AppHash) {
+		return sm.State{}, nil, fmt.Errorf("the app must have committed the block at the trust anchor (%d, app hash %X), "+
+			"it is at height %d with app hash %X", height, state.AppHash, res.LastBlockHeight, res.LastBlockAppHash)
+	}
+
+	// Save the state first: if the node crashes before the block is saved,
+	// the block store is still empty and the node bootstraps again.
+	sm.BootstrapState(stateDB, state)
+	blockStore.SaveBlock(block, block.MakePartSet(types.BlockPartSizeBytes), commit)
+	logger.Info("Bootstrapped the state from the trust anchor", "height", height, "hash", hash)
+	return state, nextHash, nil
+}
+
+// makeTrustedState returns the state after the block at height, whose hash
+// is hash, along with the block, its commit and the hash of the next block. The block, the headers and
+// the validator sets are verified against the hash, and with the signatures
+// of the commits.
+func makeTrustedState(src trustAnchorSource, chainID string, height int64,
+	hash []byte) (sm.State, *types.Block, *types.Commit, []byte, error) {
+
+	// the block at the anchor, and its commit by its validators
+	block, err := src.Block(height)
+	if err != nil {
+		return sm.State{}, nil, nil, nil, err
+	}
+	if !bytes.Equal(block.Hash(), hash) {
+		return sm.State{}, nil, nil, nil, fmt.Errorf("hash of block %d is %X, not %X", height, block.Hash(), hash)
+	}
+	sh, err := src.Commit(height)
+	if err != nil {
+		return sm.State{}, nil, nil, nil, err
+	}
+	blockID := sh.Commit.BlockID
+	if !bytes.Equal(blockID.Hash, hash) {
+		return sm.State{}, nil, nil, nil, fmt.Errorf("commit %d is for block %X, not %X", height, blockID.Hash, hash)
+	}
+	if !blockID.PartsHeader.Equals(block.MakePartSet(types.BlockPartSizeBytes).Header()) {
+		return sm.State{}, nil, nil, nil, fmt.Errorf("commit %d is for other block parts", height)
+	}
+	lastVals, err := trustedValidators(src, height, block.ValidatorsHash)
+	if err != nil {
+		return sm.State{}, nil, nil, nil, err
+	}
+	if err := lastVals.VerifyCommit(chainID, blockID, height, sh.Commit); err != nil {
+		return sm.State{}, nil, nil, nil, errors.Wrapf(err, "invalid commit %d", height)
+	}
+
+	// the next header, which has the results of the block, signed by the
+	// validators the block designated
+	vals, err := trustedValidators(src, height+1, block.NextValidatorsHash)
+	if err != nil {
+		return sm.State{}, nil, nil, nil, err
+	}
+	next, err := src.Commit(height + 1)
+	if err != nil {
+		return sm.State{}, nil, nil, nil, err
+	}
+	if !next.LastBlockID.Equals(blockID) {
+		return sm.State{}, nil, nil, nil, fmt.Errorf("header %d doesn't follow block %X", height+1, hash)
+	}
+	if !bytes.Equal(next.ValidatorsHash, block.NextValidatorsHash) {
+		return sm.State{}, nil, nil, nil, fmt.Errorf("header %d has unexpected validators", height+1)
+	}
+	if !bytes.Equal(next.Commit.BlockID.Hash, next.Hash()) {
+		return sm.State{}, nil, nil, nil, fmt.Errorf("commit %d is for another header", height+1)
+	}
+	if err := vals.VerifyCommit(chainID, next.Commit.BlockID, height+1, next.Commit); err != nil {
+		return sm.State{}, nil, nil, nil, errors.Wrapf(err, "invalid commit %d", height+1)
+	}
+
+	nextVals, err := trustedValidators(src, height+2, next.NextValidatorsHash)
+	if err != nil {
+		return sm.State{}, nil, nil, nil, err
+	}
+	params, err := src.ConsensusParams(height + 1)
+	if err != nil {
+		return sm.State{}, nil, nil, nil, err
+	}
+	if !bytes.Equal(params.Hash(), next.ConsensusHash) {
+		return sm.State{}, nil, nil, nil, fmt.Errorf("unexpected consensus params at height %d", height+1)
+	}
+
+	state := sm.State{
+		Version: sm.Version{Consensus: block.Version, Software: version.TMCoreSemVer},
+		ChainID: chainID,
+
+		LastBlockHeight:  height,
+		LastBlockTotalTx: block.TotalTxs,
+		LastBlockID:      blockID,
+		LastBlockTime:    block.Time,
+
+		NextValidators: nextVals,
+		Validators:     vals,
+		LastValidators: lastVals,
+		// the validators may have changed earlier, but their history before
+		// the anchor isn't known
+		LastHeightValidatorsChanged: height + 2,
+
+		ConsensusParams:                  *params,
+		LastHeightConsensusParamsChanged: height + 1,
+
+		LastResultsHash: next.LastResultsHash,
+		AppHash:         next.AppHash,
+	}
+	return state, block, sh.Commit, next.Commit.BlockID.Hash, nil
+}
+
+// trustedValidators returns the validators at height, checking they have the
+// given hash.
+func trustedValidators(src trustAnchorSource, height int64, hash []byte) (*types.ValidatorSet, error) {
+	vals, err := src.Validators(height)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(vals.Hash(), hash) {
+		return nil, fmt.Errorf("unexpected validators at height %d", height)
+	}
+	return vals, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// rpcTrustAnchorSource is a trustAnchorSource querying an RPC server.
+type rpcTrustAnchorSource struct {
+	client *rpcclient.JSONRPCClient
+}
+
+func newRPCTrustAnchorSource(remote string) *rpcTrustAnchorSource {
+	client := rpcclient.NewJSONRPCClient(remote)
+	ctypes.RegisterAmino(client.Codec())
+	return &rpcTrustAnchorSource{client: client}
+}
+
+func (s *rpcTrustAnchorSource) Block(height int64) (*types.Block, error) {
+	result := new(ctypes.ResultBlock)
+	if _, err := s.client.Call("block", map[string]interface{}{"height": height}, result); err != nil {
+		return nil, errors.Wrapf(err, "failed to get block %d", height)
+	}
+	return result.Block, nil
+}
+
+func (s *rpcTrustAnchorSource) Commit(height int64) (*types.SignedHeader, error) {
+	result := new(ctypes.ResultCommit)
+	if _, err := s.client.Call("commit", map[string]interface{}{"height": height}, result); err != nil {
+		return nil, errors.Wrapf(err, "failed to get commit %d", height)
+	}
+	if result.Header == nil || result.Commit == nil {
+		return nil, fmt.Errorf("no commit %d", height)
+	}
+	return &result.SignedHeader, nil
+}
+
+// Validators returns all the validators at height (see /validators), with
+// the proposer priorities of the height.
+func (s *rpcTrustAnchorSource) Validators(height int64) (*types.ValidatorSet, error) {
+	var vals []*types.Validator
+	for page := 1; ; page++ {
+		result := new(ctypes.ResultValidators)
+		_, err := s.client.Call("validators",
+			map[string]interface{}{"height": height, "page": page, "per_page": 100}, result)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get validators %d", height)
+		}
+		vals = append(vals, result.Validators...)
+		if len(result.Validators) == 0 || len(vals) >= result.Total {
+			break
+		}
+	}
+	// NOTE: not types.NewValidatorSet, which would change the priorities.
+	// The proposer isn't part of the response: it's found from the
+	// priorities (see ValidatorSet#GetProposer), which may pick another
+	// validator than the network for the first two heights after the anchor.
+	// The sets of the next heights are computed from the priorities while
+	// applying blocks, so fast sync gets them right.
+	return &types.ValidatorSet{Validators: vals}, nil
+}
+
+func (s *rpcTrustAnchorSource) ConsensusParams(height int64) (*types.ConsensusParams, error) {
+	result := new(ctypes.ResultConsensusParams)
+	_, err := s.client.Call("consensus_params", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get consensus params %d", height)
+	}
+	return &result.ConsensusParams, nil
+}
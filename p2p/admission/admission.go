@@ -0,0 +1,141 @@
+// Package admission asks external systems whether peers may connect, so that
+// allow-lists and deny-lists can be maintained outside of the node.
+//
+// A Checker is turned into a p2p.PeerFilterFunc with PeerFilter. The switch
+// runs peer filters concurrently and bounds them with its filter timeout.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// maxCacheSize is the number of decisions cached before the expired ones are
+// pruned.
+const maxCacheSize = 10000
+
+// Checker decides whether a peer is admitted.
+type Checker interface {
+	// CheckPeer returns nil if the peer is admitted, ErrNotAdmitted if it's
+	// rejected, or any other error if the check failed.
+	CheckPeer(ctx context.Context, id p2p.ID, addr string) error
+}
+
+// ErrNotAdmitted is returned when the external system rejects a peer.
+type ErrNotAdmitted struct {
+	Reason string
+}
+
+func (e ErrNotAdmitted) Error() string {
+	if e.Reason == "" {
+		return "peer not admitted"
+	}
+	return fmt.Sprintf("peer not admitted: %s", e.Reason)
+}
+
+// NewChecker returns the Checker for the given address: an HTTP(S) endpoint
+// (http://host:port/path) or a gRPC service (grpc://host:port).
+func NewChecker(addr string) (Checker, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid admission check address")
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return NewHTTPChecker(addr), nil
+	case "grpc":
+		return NewGRPCChecker(u.Host)
+	default:
+		return nil, fmt.Errorf("unsupported admission check scheme %q (want http, https or grpc)", u.Scheme)
+	}
+}
+
+// PeerFilter returns a peer filter rejecting the peers that are not
+// admitted by the checker. Checks time out after the given timeout, and any
+// failure rejects the peer.
+func PeerFilter(checker Checker, timeout time.Duration) p2p.PeerFilterFunc {
+	return func(_ p2p.IPeerSet, p p2p.Peer) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		addr := ""
+		if socketAddr := p.SocketAddr(); socketAddr != nil {
+			addr = socketAddr.String()
+		}
+		if err := checker.CheckPeer(ctx, p.ID(), addr); err != nil {
+			if _, ok := err.(ErrNotAdmitted); ok {
+				return err
+			}
+			return errors.Wrap(err, "admission check failed")
+		}
+		return nil
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+type cachedDecision struct {
+	err     error
+	expires time.Time
+}
+
+// CachedChecker caches the decisions of a Checker for some time, so that
+// reconnecting peers don't hit the external system each time. Failed checks
+// are not cached.
+type CachedChecker struct {
+	checker Checker
+	ttl     time.Duration
+
+	mtx       sync.Mutex
+	decisions map[p2p.ID]cachedDecision
+}
+
+var _ Checker = (*CachedChecker)(nil)
+
+// NewCachedChecker returns a CachedChecker keeping the decisions of the given
+// checker for ttl.
+func NewCachedChecker(checker Checker, ttl time.Duration) *CachedChecker {
+	return &CachedChecker{
+		checker:   checker,
+		ttl:       ttl,
+		decisions: make(map[p2p.ID]cachedDecision),
+	}
+}
+
+// CheckPeer implements Checker.
+func (cc *CachedChecker) CheckPeer(ctx context.Context, id p2p.ID, addr string) error {
+	now := time.Now()
+
+	cc.mtx.Lock()
+	decision, ok := cc.decisions[id]
+	cc.mtx.Unlock()
+	if ok && now.Before(decision.expires) {
+		return decision.err
+	}
+
+	err := cc.checker.CheckPeer(ctx, id, addr)
+	if _, rejected := err.(ErrNotAdmitted); err != nil && !rejected {
+		return err
+	}
+
+	cc.mtx.Lock()
+	defer cc.mtx.Unlock()
+	if len(cc.decisions) >= maxCacheSize {
+		for id, decision := range cc.decisions {
+			if !now.Before(decision.expires) {
+				delete(cc.decisions, id)
+			}
+		}
+	}
+	if len(cc.decisions) < maxCacheSize {
+		cc.decisions[id] = cachedDecision{err: err, expires: now.Add(cc.ttl)}
+	}
+	return err
+}
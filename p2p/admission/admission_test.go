@@ -0,0 +1,123 @@
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/p2p/mock"
+)
+
+// denyList admits all peers but the denied ones, and counts the checks.
+type denyList struct {
+	denied map[p2p.ID]bool
+	checks int
+}
+
+func (dl *denyList) CheckPeer(_ context.Context, id p2p.ID, _ string) error {
+	dl.checks++
+	if dl.denied[id] {
+		return ErrNotAdmitted{Reason: "denied"}
+	}
+	return nil
+}
+
+func (dl *denyList) Check(ctx context.Context, req *CheckRequest) (*CheckResponse, error) {
+	if err := dl.CheckPeer(ctx, p2p.ID(req.ID), req.Addr); err != nil {
+		return &CheckResponse{Admitted: false, Reason: err.(ErrNotAdmitted).Reason}, nil
+	}
+	return &CheckResponse{Admitted: true}, nil
+}
+
+func TestHTTPChecker(t *testing.T) {
+	dl := &denyList{denied: map[p2p.ID]bool{"bad": true}}
+	var lastReq Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&lastReq))
+		switch lastReq.ID {
+		case "slow":
+			time.Sleep(100 * time.Millisecond)
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if err := dl.CheckPeer(r.Context(), lastReq.ID, lastReq.Addr); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(err.(ErrNotAdmitted).Reason + "\n")) // nolint: errcheck
+		}
+	}))
+	defer srv.Close()
+
+	checker, err := NewChecker(srv.URL)
+	require.NoError(t, err)
+
+	assert.NoError(t, checker.CheckPeer(context.Background(), "good", "1.2.3.4:26656"))
+	assert.Equal(t, Request{ID: "good", Addr: "1.2.3.4:26656"}, lastReq)
+	assert.Equal(t, ErrNotAdmitted{Reason: "denied"}, checker.CheckPeer(context.Background(), "bad", ""))
+
+	err = checker.CheckPeer(context.Background(), "broken", "")
+	assert.Error(t, err)
+	_, rejected := err.(ErrNotAdmitted)
+	assert.False(t, rejected)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, checker.CheckPeer(ctx, "slow", ""))
+}
+
+func TestGRPCChecker(t *testing.T) {
+	dl := &denyList{denied: map[p2p.ID]bool{"bad": true}}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	RegisterGRPCServer(s, dl)
+	go s.Serve(ln) // nolint: errcheck
+	defer s.Stop()
+
+	checker, err := NewChecker("grpc://" + ln.Addr().String())
+	require.NoError(t, err)
+	defer checker.(*GRPCChecker).Close()
+
+	assert.NoError(t, checker.CheckPeer(context.Background(), "good", ""))
+	assert.Equal(t, ErrNotAdmitted{Reason: "denied"}, checker.CheckPeer(context.Background(), "bad", ""))
+}
+
+func TestNewCheckerUnsupportedScheme(t *testing.T) {
+	_, err := NewChecker("tcp://127.0.0.1:1234")
+	assert.Error(t, err)
+}
+
+func TestCachedChecker(t *testing.T) {
+	dl := &denyList{denied: map[p2p.ID]bool{"bad": true}}
+	checker := NewCachedChecker(dl, 50*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, checker.CheckPeer(context.Background(), "good", ""))
+		assert.Error(t, checker.CheckPeer(context.Background(), "bad", ""))
+	}
+	assert.Equal(t, 2, dl.checks)
+
+	// the decisions expire
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, checker.CheckPeer(context.Background(), "good", ""))
+	assert.Equal(t, 3, dl.checks)
+}
+
+func TestPeerFilter(t *testing.T) {
+	peer := mock.NewPeer(nil)
+	dl := &denyList{denied: map[p2p.ID]bool{}}
+	filter := PeerFilter(dl, time.Second)
+	assert.NoError(t, filter(nil, peer))
+
+	dl.denied[peer.ID()] = true
+	assert.Equal(t, ErrNotAdmitted{Reason: "denied"}, filter(nil, peer))
+}
@@ -0,0 +1,130 @@
+package admission
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// The gRPC admission service has a single unary method. Its messages and
+// service description are written by hand rather than generated:
+//
+//	service PeerAdmission {
+//	  rpc Check(CheckRequest) returns (CheckResponse);
+//	}
+//
+//	message CheckRequest  { string id = 1; string addr = 2; }
+//	message CheckResponse { bool admitted = 1; string reason = 2; }
+const (
+	grpcServiceName = "tendermint.p2p.PeerAdmission"
+	grpcCheckMethod = "/" + grpcServiceName + "/Check"
+)
+
+// CheckRequest asks whether the peer with the given ID and address is
+// admitted.
+type CheckRequest struct {
+	ID   string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addr string `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+}
+
+func (m *CheckRequest) Reset()         { *m = CheckRequest{} }
+func (m *CheckRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckRequest) ProtoMessage()    {}
+
+// CheckResponse tells whether the peer is admitted, and why not otherwise.
+type CheckResponse struct {
+	Admitted bool   `protobuf:"varint,1,opt,name=admitted,proto3" json:"admitted,omitempty"`
+	Reason   string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (m *CheckResponse) Reset()         { *m = CheckResponse{} }
+func (m *CheckResponse) String() string { return proto.CompactTextString(m) }
+func (*CheckResponse) ProtoMessage()    {}
+
+// GRPCServer is the server API of the gRPC admission service.
+type GRPCServer interface {
+	Check(context.Context, *CheckRequest) (*CheckResponse, error)
+}
+
+// RegisterGRPCServer registers the admission service on the given gRPC
+// server.
+func RegisterGRPCServer(s *grpc.Server, srv GRPCServer) {
+	s.RegisterService(&grpcServiceDesc, srv)
+}
+
+func grpcCheckHandler(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	in := new(CheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCServer).Check(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: grpcCheckMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCServer).Check(ctx, req.(*CheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: grpcServiceName,
+	HandlerType: (*GRPCServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Check",
+			Handler:    grpcCheckHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+//-----------------------------------------------------------------------------
+
+// GRPCChecker calls the gRPC admission service for each peer. The connection
+// is plaintext, so the service should run next to the node.
+type GRPCChecker struct {
+	conn *grpc.ClientConn
+}
+
+var _ Checker = (*GRPCChecker)(nil)
+
+// NewGRPCChecker returns a GRPCChecker for the service at the given address.
+// It connects in the background.
+func NewGRPCChecker(addr string) (*GRPCChecker, error) {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to dial the admission service")
+	}
+	return &GRPCChecker{conn: conn}, nil
+}
+
+// CheckPeer implements Checker.
+func (gc *GRPCChecker) CheckPeer(ctx context.Context, id p2p.ID, addr string) error {
+	res := new(CheckResponse)
+	err := gc.conn.Invoke(ctx, grpcCheckMethod, &CheckRequest{ID: string(id), Addr: addr}, res)
+	if err != nil {
+		return err
+	}
+	if !res.Admitted {
+		return ErrNotAdmitted{Reason: res.Reason}
+	}
+	return nil
+}
+
+// Close closes the connection to the service.
+func (gc *GRPCChecker) Close() error {
+	return gc.conn.Close()
+}
@@ -0,0 +1,68 @@
+package admission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// maxReasonSize is the maximum size of the rejection reason read from the
+// HTTP response.
+const maxReasonSize = 1024
+
+// Request is the body of the requests to the HTTP endpoint.
+type Request struct {
+	ID   p2p.ID `json:"id"`
+	Addr string `json:"addr"`
+}
+
+// HTTPChecker POSTs a JSON Request to an HTTP endpoint for each peer. The
+// endpoint admits the peer with 200 OK, or rejects it with 403 Forbidden and
+// the reason in the body.
+type HTTPChecker struct {
+	url    string
+	client *http.Client
+}
+
+var _ Checker = (*HTTPChecker)(nil)
+
+// NewHTTPChecker returns an HTTPChecker for the given endpoint.
+func NewHTTPChecker(url string) *HTTPChecker {
+	return &HTTPChecker{url: url, client: &http.Client{}}
+}
+
+// CheckPeer implements Checker.
+func (hc *HTTPChecker) CheckPeer(ctx context.Context, id p2p.ID, addr string) error {
+	body, err := json.Marshal(Request{ID: id, Addr: addr})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, hc.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := hc.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusForbidden:
+		reason, _ := ioutil.ReadAll(&io.LimitedReader{R: res.Body, N: maxReasonSize})
+		return ErrNotAdmitted{Reason: strings.TrimSpace(string(reason))}
+	default:
+		return fmt.Errorf("unexpected status %q", res.Status)
+	}
+}
@@ -47,6 +47,20 @@ type Reactor interface {
 	Receive(chID byte, peer Peer, msgBytes []byte)
 }
 
+// CongestionAwareReactor is implemented by the reactors which slow down when
+// their messages to a peer pile up in the send queue of a channel.
+type CongestionAwareReactor interface {
+	Reactor
+
+	// ChannelCongestion is called when the send queue of the channel chID to
+	// the peer becomes congested (filled to 3/4 of its capacity), and when
+	// it's relieved (drained to 1/4 of it).
+	//
+	// NOTE it's called from the goroutines sending the messages to the peer
+	// and from its send routine, so it must not block nor send to the peer.
+	ChannelCongestion(chID byte, peer Peer, congested bool)
+}
+
 //--------------------------------------
 
 type BaseReactor struct {
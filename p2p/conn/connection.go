@@ -25,6 +25,12 @@ const (
 	defaultMaxPacketMsgPayloadSize = 1024
 
 	numBatchPacketMsgs = 10
+
+	// maxPreemptivePackets is the maximum number of consecutive packets of
+	// preemptive channels sent while other channels have something to send,
+	// so that the other channels get at least 1/(maxPreemptivePackets+1) of
+	// the packets.
+	maxPreemptivePackets = 10
 	minReadBufferSize  = 1024
 	minWriteBufferSize = 65536
 	updateStats        = 2 * time.Second
@@ -46,6 +52,7 @@ const (
 
 type receiveCbFunc func(chID byte, msgBytes []byte)
 type errorCbFunc func(interface{})
+type congestionCbFunc func(chID byte, congested bool)
 
 /*
 Each peer has one `MConnection` (multiplex connection) instance.
@@ -70,6 +77,11 @@ request times out.  The message `msg` is serialized using Go-Amino.
 channel's queue is full.
 
 Inbound message bytes are handled with an onReceive callback function.
+
+A channel is congested once its send queue is filled to 3/4 of its capacity,
+until it drains to 1/4 of it. The changes are reported to the optional
+congestion callback (see SetCongestionCallback), so that the senders can slow
+down before their messages get dropped.
 */
 type MConnection struct {
 	cmn.BaseService
@@ -85,6 +97,7 @@ type MConnection struct {
 	channelsIdx   map[byte]*Channel
 	onReceive     receiveCbFunc
 	onError       errorCbFunc
+	onCongestion  congestionCbFunc
 	errored       uint32
 	config        MConnConfig
 
@@ -111,6 +124,10 @@ type MConnection struct {
 
 	created time.Time // time of creation
 
+	// consecutive packets of preemptive channels sent while other channels
+	// had something to send (see maxPreemptivePackets)
+	preemptivePackets int
+
 	_maxPacketMsgSize int
 }
 
@@ -312,6 +329,21 @@ func (c *MConnection) OnStop() {
 	// we close it @ recvRoutine.
 }
 
+// SetRates changes the send and receive rate limits of the connection, in
+// bytes per second.
+func (c *MConnection) SetRates(sendRate, recvRate int64) {
+	atomic.StoreInt64(&c.config.SendRate, sendRate)
+	atomic.StoreInt64(&c.config.RecvRate, recvRate)
+}
+
+// SetCongestionCallback sets the function called when a channel becomes
+// congested, and when it's relieved. It's called from the goroutines sending
+// the messages and from the send routine, so it must not block. Call it before
+// starting the connection.
+func (c *MConnection) SetCongestionCallback(cb func(chID byte, congested bool)) {
+	c.onCongestion = cb
+}
+
 func (c *MConnection) String() string {
 	return fmt.Sprintf("MConn{%v}", c.conn.RemoteAddr())
 }
@@ -509,21 +541,38 @@ func (c *MConnection) sendSomePacketMsgs() bool {
 // Returns true if messages from channels were exhausted.
 func (c *MConnection) sendPacketMsg() bool {
 	// Choose a channel to create a PacketMsg from.
-	// The chosen channel will be the one whose recentlySent/priority is the least.
-	var leastRatio float32 = math.MaxFloat32
-	var leastChannel *Channel
+	// Preemptive channels with something to send are served first, up to
+	// maxPreemptivePackets packets in a row while the others have something to
+	// send. Among them, or among the others, the chosen channel will be the
+	// one whose recentlySent/priority is the least.
+	var leastRatios = [2]float32{math.MaxFloat32, math.MaxFloat32}
+	var leastChannels [2]*Channel // the other and the preemptive channels
 	for _, channel := range c.channels {
 		// If nothing to send, skip this channel
 		if !channel.isSendPending() {
 			continue
 		}
+		i := 0
+		if channel.desc.Preemptive {
+			i = 1
+		}
 		// Get ratio, and keep track of lowest ratio.
 		ratio := float32(channel.recentlySent) / float32(channel.desc.Priority)
-		if ratio < leastRatio {
-			leastRatio = ratio
-			leastChannel = channel
+		if ratio < leastRatios[i] {
+			leastRatios[i] = ratio
+			leastChannels[i] = channel
 		}
 	}
+	leastChannel := leastChannels[1]
+	switch {
+	case leastChannels[0] == nil:
+		c.preemptivePackets = 0
+	case leastChannel == nil || c.preemptivePackets >= maxPreemptivePackets:
+		leastChannel = leastChannels[0]
+		c.preemptivePackets = 0
+	default:
+		c.preemptivePackets++
+	}
 
 	// Nothing to send?
 	if leastChannel == nil {
@@ -682,6 +731,7 @@ type ChannelStatus struct {
 	SendQueueSize     int
 	Priority          int
 	RecentlySent      int64
+	Congested         bool
 }
 
 func (c *MConnection) Status() ConnectionStatus {
@@ -697,6 +747,7 @@ func (c *MConnection) Status() ConnectionStatus {
 			SendQueueSize:     int(atomic.LoadInt32(&channel.sendQueueSize)),
 			Priority:          channel.desc.Priority,
 			RecentlySent:      atomic.LoadInt64(&channel.recentlySent),
+			Congested:         channel.isCongested(),
 		}
 	}
 	return status
@@ -710,6 +761,15 @@ type ChannelDescriptor struct {
 	SendQueueCapacity   int
 	RecvBufferCapacity  int
 	RecvMessageCapacity int
+
+	// Preemptive channels are sent before the other channels whenever they
+	// have something to send. Since messages are sent packet by packet, a
+	// message of a preemptive channel doesn't wait for a large message of
+	// another channel to be sent. Only use it for channels of small and
+	// latency sensitive messages: the other channels are slowed down, as they
+	// only get one packet every maxPreemptivePackets packets of the preemptive
+	// channels.
+	Preemptive bool
 }
 
 func (chDesc ChannelDescriptor) FillDefaults() (filled ChannelDescriptor) {
@@ -737,6 +797,9 @@ type Channel struct {
 	sending       []byte
 	recentlySent  int64 // exponential moving average
 
+	congestionMtx sync.Mutex
+	congested     bool // the send queue is filled above the high watermark
+
 	maxPacketMsgPayloadSize int
 
 	Logger log.Logger
@@ -764,6 +827,7 @@ func (ch *Channel) SetLogger(l log.Logger) {
 // Goroutine-safe
 // Times out (and returns false) after defaultSendTimeout
 func (ch *Channel) sendBytes(bytes []byte) bool {
+	defer ch.updateCongestion()
 	select {
 	case ch.sendQueue <- bytes:
 		atomic.AddInt32(&ch.sendQueueSize, 1)
@@ -777,6 +841,7 @@ func (ch *Channel) sendBytes(bytes []byte) bool {
 // Nonblocking, returns true if successful.
 // Goroutine-safe
 func (ch *Channel) trySendBytes(bytes []byte) bool {
+	defer ch.updateCongestion()
 	select {
 	case ch.sendQueue <- bytes:
 		atomic.AddInt32(&ch.sendQueueSize, 1)
@@ -797,6 +862,36 @@ func (ch *Channel) canSend() bool {
 	return ch.loadSendQueueSize() < defaultSendQueueCapacity
 }
 
+// Flags the channel as congested once its send queue is filled to 3/4 of its
+// capacity, and as relieved once it drains to 1/4 of it, and reports the
+// changes to the congestion callback of the connection.
+// Goroutine-safe
+func (ch *Channel) updateCongestion() {
+	ch.congestionMtx.Lock()
+	defer ch.congestionMtx.Unlock()
+
+	// read under the lock, so that the last update sees the last size
+	size, capacity := ch.loadSendQueueSize(), cap(ch.sendQueue)
+	switch {
+	case !ch.congested && size >= (3*capacity+3)/4:
+		ch.congested = true
+	case ch.congested && size <= capacity/4:
+		ch.congested = false
+	default:
+		return
+	}
+	if ch.conn.onCongestion != nil {
+		ch.conn.onCongestion(ch.desc.ID, ch.congested)
+	}
+}
+
+// Goroutine-safe
+func (ch *Channel) isCongested() bool {
+	ch.congestionMtx.Lock()
+	defer ch.congestionMtx.Unlock()
+	return ch.congested
+}
+
 // Returns true if any PacketMsgs are pending to be sent.
 // Call before calling nextPacketMsg()
 // Goroutine-safe
@@ -821,6 +916,7 @@ func (ch *Channel) nextPacketMsg() PacketMsg {
 		packet.EOF = byte(0x01)
 		ch.sending = nil
 		atomic.AddInt32(&ch.sendQueueSize, -1) // decrement sendQueueSize
+		ch.updateCongestion()
 	} else {
 		packet.EOF = byte(0x00)
 		ch.sending = ch.sending[cmn.MinInt(maxSize, len(ch.sending)):]
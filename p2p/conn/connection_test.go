@@ -145,6 +145,157 @@ func TestMConnectionReceive(t *testing.T) {
 	}
 }
 
+func TestMConnectionPreemptiveChannel(t *testing.T) {
+	server, client := NetPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	chDescs := []*ChannelDescriptor{
+		{ID: 0x01, Priority: 10, SendQueueCapacity: 1},
+		{ID: 0x02, Priority: 1, SendQueueCapacity: 1, Preemptive: true},
+	}
+	receivedCh := make(chan byte, 2)
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- chID
+	}
+	onError := func(r interface{}) {
+	}
+
+	mconnServer := NewMConnectionWithConfig(server, chDescs, onReceive, onError, DefaultMConnConfig())
+	mconnServer.SetLogger(log.TestingLogger())
+	err := mconnServer.Start()
+	require.Nil(t, err)
+	defer mconnServer.Stop()
+
+	mconnClient := NewMConnectionWithConfig(client, chDescs, func(byte, []byte) {}, onError, DefaultMConnConfig())
+	mconnClient.SetLogger(log.TestingLogger())
+
+	// Queue a message spanning several packets and a small message before
+	// starting, and make the preemptive channel look busy so that it would be
+	// served last by recentlySent/priority alone.
+	bigMsg := make([]byte, 10*mconnClient.config.MaxPacketMsgPayloadSize)
+	require.True(t, mconnClient.channelsIdx[0x01].trySendBytes(bigMsg))
+	require.True(t, mconnClient.channelsIdx[0x02].trySendBytes([]byte("Quicksilver")))
+	mconnClient.channelsIdx[0x02].recentlySent = 1 << 20
+
+	err = mconnClient.Start()
+	require.Nil(t, err)
+	defer mconnClient.Stop()
+	// wake up the send routine, as the messages were queued directly
+	mconnClient.send <- struct{}{}
+
+	for _, expected := range []byte{0x02, 0x01} {
+		select {
+		case chID := <-receivedCh:
+			assert.Equal(t, expected, chID)
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("Did not receive message on channel %X in 500ms", expected)
+		}
+	}
+}
+
+func TestMConnectionCongestion(t *testing.T) {
+	server, client := NetPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	chDescs := []*ChannelDescriptor{{ID: 0x01, Priority: 1, SendQueueCapacity: 4}}
+	onError := func(r interface{}) {}
+
+	mconnServer := NewMConnectionWithConfig(server, chDescs, func(byte, []byte) {}, onError, DefaultMConnConfig())
+	mconnServer.SetLogger(log.TestingLogger())
+
+	mconnClient := NewMConnectionWithConfig(client, chDescs, func(byte, []byte) {}, onError, DefaultMConnConfig())
+	mconnClient.SetLogger(log.TestingLogger())
+	congestionCh := make(chan bool, 2)
+	mconnClient.SetCongestionCallback(func(chID byte, congested bool) {
+		assert.EqualValues(t, 0x01, chID)
+		congestionCh <- congested
+	})
+
+	// fill the send queue to 3/4 of its capacity before starting
+	channel := mconnClient.channelsIdx[0x01]
+	for i := 0; i < 2; i++ {
+		require.True(t, channel.trySendBytes([]byte("Mockingbird")))
+		assert.False(t, mconnClient.Status().Channels[0].Congested)
+	}
+	require.True(t, channel.trySendBytes([]byte("Mockingbird")))
+	assert.True(t, mconnClient.Status().Channels[0].Congested)
+	assert.True(t, <-congestionCh)
+
+	// and drain it
+	err := mconnServer.Start()
+	require.Nil(t, err)
+	defer mconnServer.Stop()
+	err = mconnClient.Start()
+	require.Nil(t, err)
+	defer mconnClient.Stop()
+	mconnClient.send <- struct{}{}
+
+	select {
+	case congested := <-congestionCh:
+		assert.False(t, congested)
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Did not relieve the congestion in 500ms")
+	}
+	assert.False(t, mconnClient.Status().Channels[0].Congested)
+}
+
+func TestMConnectionPreemptiveChannelDoesNotStarveOthers(t *testing.T) {
+	server, client := NetPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	const numSmallMsgs = 50
+	chDescs := []*ChannelDescriptor{
+		{ID: 0x01, Priority: 10, SendQueueCapacity: 1},
+		{ID: 0x02, Priority: 1, SendQueueCapacity: numSmallMsgs, Preemptive: true},
+	}
+	receivedCh := make(chan byte, numSmallMsgs+1)
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- chID
+	}
+	onError := func(r interface{}) {
+	}
+
+	mconnServer := NewMConnectionWithConfig(server, chDescs, onReceive, onError, DefaultMConnConfig())
+	mconnServer.SetLogger(log.TestingLogger())
+	err := mconnServer.Start()
+	require.Nil(t, err)
+	defer mconnServer.Stop()
+
+	mconnClient := NewMConnectionWithConfig(client, chDescs, func(byte, []byte) {}, onError, DefaultMConnConfig())
+	mconnClient.SetLogger(log.TestingLogger())
+
+	// Queue a message of two packets, and more small messages on the
+	// preemptive channel than it can send in a row.
+	bigMsg := make([]byte, 2*mconnClient.config.MaxPacketMsgPayloadSize)
+	require.True(t, mconnClient.channelsIdx[0x01].trySendBytes(bigMsg))
+	for i := 0; i < numSmallMsgs; i++ {
+		require.True(t, mconnClient.channelsIdx[0x02].trySendBytes([]byte("Quicksilver")))
+	}
+
+	err = mconnClient.Start()
+	require.Nil(t, err)
+	defer mconnClient.Stop()
+	// wake up the send routine, as the messages were queued directly
+	mconnClient.send <- struct{}{}
+
+	// the big message is received after 2*maxPreemptivePackets small ones
+	for i := 0; i <= numSmallMsgs; i++ {
+		select {
+		case chID := <-receivedCh:
+			if chID == 0x01 {
+				assert.Equal(t, 2*maxPreemptivePackets, i)
+				return
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("Did not receive message %d in 500ms", i)
+		}
+	}
+	t.Fatal("The big message was not received before the small ones")
+}
+
 func TestMConnectionStatus(t *testing.T) {
 	server, client := NetPipe()
 	defer server.Close() // nolint: errcheck
@@ -0,0 +1,370 @@
+package conn
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+// The Noise upgrade replaces the keys of an established SecretConnection with
+// keys derived from a Noise_NN handshake, run over the SecretConnection itself.
+// Both peers sign the handshake hash, which binds the ephemeral keys, the
+// signatures and the prologue (e.g. the NodeInfos the peers exchanged) to
+// their identities. This closes the gap of the STS handshake, where the
+// key exchange itself is not authenticated.
+//
+// Handshake messages are protobuf encoded and prefixed with their uvarint
+// length:
+//
+//	-> e
+//	<- e, ee, auth
+//	-> auth
+//
+// where auth is the sender's public key and signature of the handshake hash,
+// encrypted under the keys derived so far.
+
+// noiseProtocolName is exactly 32 bytes long, so it is used as the initial
+// handshake hash without hashing, as per the Noise specification.
+const noiseProtocolName = "Noise_NN_25519_ChaChaPoly_SHA256"
+
+// noiseMaxMessageSize is the maximum size of an encoded handshake message.
+const noiseMaxMessageSize = 1024
+
+var (
+	ErrNoiseUnreadData       = errors.New("can't upgrade secret connection with unread data")
+	ErrNoiseRemoteKeyChanged = errors.New("noise handshake authenticated a different remote pubkey")
+)
+
+// noiseHandshakeMessage is a Noise handshake message.
+type noiseHandshakeMessage struct {
+	Ephemeral []byte `protobuf:"bytes,1,opt,name=ephemeral,proto3" json:"ephemeral,omitempty"`
+	Payload   []byte `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+}
+
+func (m *noiseHandshakeMessage) Reset()         { *m = noiseHandshakeMessage{} }
+func (m *noiseHandshakeMessage) String() string { return proto.CompactTextString(m) }
+func (*noiseHandshakeMessage) ProtoMessage()    {}
+
+// noiseAuthMessage is the encrypted payload authenticating a peer.
+type noiseAuthMessage struct {
+	PubKey []byte `protobuf:"bytes,1,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"`
+	Sig    []byte `protobuf:"bytes,2,opt,name=sig,proto3" json:"sig,omitempty"`
+}
+
+func (m *noiseAuthMessage) Reset()         { *m = noiseAuthMessage{} }
+func (m *noiseAuthMessage) String() string { return proto.CompactTextString(m) }
+func (*noiseAuthMessage) ProtoMessage()    {}
+
+// UpgradeNoise runs a Noise handshake over the secret connection and replaces
+// its keys with the ones derived from the handshake. The initiator must be the
+// peer which dialed the connection, and both peers must use the same prologue.
+// The connection must not be used concurrently during the upgrade.
+func (sc *SecretConnection) UpgradeNoise(locPrivKey crypto.PrivKey, initiator bool, prologue []byte) error {
+	ss := newNoiseSymmetricState(prologue)
+	locEphPub, locEphPriv := genEphKeys()
+	var remEphPub *[32]byte
+
+	// -> e
+	if initiator {
+		ss.mixHash(locEphPub[:])
+		msg := &noiseHandshakeMessage{Ephemeral: locEphPub[:], Payload: ss.encryptAndHash(nil)}
+		if err := writeNoiseMessage(sc, msg); err != nil {
+			return err
+		}
+	} else {
+		msg := new(noiseHandshakeMessage)
+		if err := readNoiseMessage(sc, msg); err != nil {
+			return err
+		}
+		var err error
+		if remEphPub, err = ss.readEphemeral(msg); err != nil {
+			return err
+		}
+		if _, err := ss.decryptAndHash(msg.Payload); err != nil {
+			return err
+		}
+	}
+
+	// <- e, ee, auth
+	if initiator {
+		msg := new(noiseHandshakeMessage)
+		if err := readNoiseMessage(sc, msg); err != nil {
+			return err
+		}
+		var err error
+		if remEphPub, err = ss.readEphemeral(msg); err != nil {
+			return err
+		}
+		if err := ss.mixDH(remEphPub, locEphPriv); err != nil {
+			return err
+		}
+		if err := sc.readNoiseAuth(ss, msg.Payload); err != nil {
+			return err
+		}
+	} else {
+		ss.mixHash(locEphPub[:])
+		if err := ss.mixDH(remEphPub, locEphPriv); err != nil {
+			return err
+		}
+		payload, err := ss.writeNoiseAuth(locPrivKey)
+		if err != nil {
+			return err
+		}
+		msg := &noiseHandshakeMessage{Ephemeral: locEphPub[:], Payload: payload}
+		if err := writeNoiseMessage(sc, msg); err != nil {
+			return err
+		}
+	}
+
+	// -> auth
+	if initiator {
+		payload, err := ss.writeNoiseAuth(locPrivKey)
+		if err != nil {
+			return err
+		}
+		if err := writeNoiseMessage(sc, &noiseHandshakeMessage{Payload: payload}); err != nil {
+			return err
+		}
+	} else {
+		msg := new(noiseHandshakeMessage)
+		if err := readNoiseMessage(sc, msg); err != nil {
+			return err
+		}
+		if err := sc.readNoiseAuth(ss, msg.Payload); err != nil {
+			return err
+		}
+	}
+
+	initKey, respKey := ss.split()
+	if initiator {
+		return sc.rekey(&initKey, &respKey)
+	}
+	return sc.rekey(&respKey, &initKey)
+}
+
+// writeNoiseAuth signs the current handshake hash and returns the encrypted auth payload.
+func (ss *noiseSymmetricState) writeNoiseAuth(locPrivKey crypto.PrivKey) ([]byte, error) {
+	sig, err := locPrivKey.Sign(ss.h[:])
+	if err != nil {
+		return nil, err
+	}
+	pubKey, ok := locPrivKey.PubKey().(ed25519.PubKeyEd25519)
+	if !ok {
+		return nil, errors.Errorf("expected ed25519 pubkey, got %T", locPrivKey.PubKey())
+	}
+	bz, err := proto.Marshal(&noiseAuthMessage{PubKey: pubKey[:], Sig: sig})
+	if err != nil {
+		return nil, err
+	}
+	return ss.encryptAndHash(bz), nil
+}
+
+// readNoiseAuth decrypts the remote auth payload and verifies that the remote
+// peer signed the handshake hash with the key authenticated by STS.
+func (sc *SecretConnection) readNoiseAuth(ss *noiseSymmetricState, payload []byte) error {
+	signed := ss.h
+	bz, err := ss.decryptAndHash(payload)
+	if err != nil {
+		return err
+	}
+	var auth noiseAuthMessage
+	if err := proto.Unmarshal(bz, &auth); err != nil {
+		return err
+	}
+	if len(auth.PubKey) != ed25519.PubKeyEd25519Size {
+		return errors.Errorf("invalid ed25519 pubkey size %d", len(auth.PubKey))
+	}
+	var remPubKey ed25519.PubKeyEd25519
+	copy(remPubKey[:], auth.PubKey)
+	if !remPubKey.VerifyBytes(signed[:], auth.Sig) {
+		return errors.New("noise handshake hash verification failed")
+	}
+	if !remPubKey.Equals(sc.remPubKey) {
+		return ErrNoiseRemoteKeyChanged
+	}
+	return nil
+}
+
+// rekey replaces the send and receive keys and resets the nonces.
+func (sc *SecretConnection) rekey(sendKey, recvKey *[aeadKeySize]byte) error {
+	sendAead, err := chacha20poly1305.New(sendKey[:])
+	if err != nil {
+		return errors.New("invalid send SecretConnection Key")
+	}
+	recvAead, err := chacha20poly1305.New(recvKey[:])
+	if err != nil {
+		return errors.New("invalid receive SecretConnection Key")
+	}
+
+	sc.recvMtx.Lock()
+	defer sc.recvMtx.Unlock()
+	if len(sc.recvBuffer) > 0 {
+		return ErrNoiseUnreadData
+	}
+	sc.recvAead = recvAead
+	sc.recvNonce = new([aeadNonceSize]byte)
+
+	sc.sendMtx.Lock()
+	defer sc.sendMtx.Unlock()
+	sc.sendAead = sendAead
+	sc.sendNonce = new([aeadNonceSize]byte)
+	return nil
+}
+
+//--------------------------------------------------------------------------------
+
+// noiseSymmetricState is the SymmetricState of the Noise specification.
+type noiseSymmetricState struct {
+	ck [32]byte    // chaining key
+	h  [32]byte    // handshake hash
+	k  cipher.AEAD // nil until the first DH
+	n  uint64
+}
+
+func newNoiseSymmetricState(prologue []byte) *noiseSymmetricState {
+	ss := new(noiseSymmetricState)
+	copy(ss.h[:], noiseProtocolName)
+	ss.ck = ss.h
+	ss.mixHash(prologue)
+	return ss
+}
+
+func (ss *noiseSymmetricState) mixHash(data []byte) {
+	hash := sha256.New()
+	hash.Write(ss.h[:])
+	hash.Write(data)
+	copy(ss.h[:], hash.Sum(nil))
+}
+
+func (ss *noiseSymmetricState) mixKey(ikm []byte) {
+	var k [32]byte
+	ss.ck, k = noiseHKDF(ss.ck[:], ikm)
+	aead, err := chacha20poly1305.New(k[:])
+	if err != nil {
+		panic(err) // k has the right size
+	}
+	ss.k = aead
+	ss.n = 0
+}
+
+func (ss *noiseSymmetricState) mixDH(remEphPub, locEphPriv *[32]byte) error {
+	dhSecret, err := computeDHSecret(remEphPub, locEphPriv)
+	if err != nil {
+		return err
+	}
+	ss.mixKey(dhSecret[:])
+	return nil
+}
+
+// readEphemeral validates the remote ephemeral key of msg and mixes it into the handshake hash.
+func (ss *noiseSymmetricState) readEphemeral(msg *noiseHandshakeMessage) (*[32]byte, error) {
+	if len(msg.Ephemeral) != 32 {
+		return nil, errors.Errorf("invalid ephemeral key size %d", len(msg.Ephemeral))
+	}
+	remEphPub := new([32]byte)
+	copy(remEphPub[:], msg.Ephemeral)
+	if hasSmallOrder(*remEphPub) {
+		return nil, ErrSmallOrderRemotePubKey
+	}
+	ss.mixHash(remEphPub[:])
+	return remEphPub, nil
+}
+
+func (ss *noiseSymmetricState) encryptAndHash(plaintext []byte) []byte {
+	ciphertext := plaintext
+	if ss.k != nil {
+		ciphertext = ss.k.Seal(nil, noiseNonce(ss.n), plaintext, ss.h[:])
+		ss.n++
+	}
+	ss.mixHash(ciphertext)
+	return ciphertext
+}
+
+func (ss *noiseSymmetricState) decryptAndHash(ciphertext []byte) ([]byte, error) {
+	plaintext := ciphertext
+	if ss.k != nil {
+		var err error
+		plaintext, err = ss.k.Open(nil, noiseNonce(ss.n), ciphertext, ss.h[:])
+		if err != nil {
+			return nil, errors.New("failed to decrypt noise handshake payload")
+		}
+		ss.n++
+	}
+	ss.mixHash(ciphertext)
+	return plaintext, nil
+}
+
+// split returns the initiator's and the responder's sending keys.
+func (ss *noiseSymmetricState) split() (initKey, respKey [32]byte) {
+	return noiseHKDF(ss.ck[:], nil)
+}
+
+// noiseHKDF is the HKDF function of the Noise specification with two outputs.
+func noiseHKDF(chainingKey, ikm []byte) (out1, out2 [32]byte) {
+	temp := hmacSHA256(chainingKey, ikm)
+	copy(out1[:], hmacSHA256(temp, []byte{0x01}))
+	copy(out2[:], hmacSHA256(temp, append(out1[:], 0x02)))
+	return
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// noiseNonce encodes n as a ChaChaPoly nonce: 32 bits of zeros followed by
+// the little-endian encoding of n.
+func noiseNonce(n uint64) []byte {
+	nonce := make([]byte, aeadNonceSize)
+	binary.LittleEndian.PutUint64(nonce[4:], n)
+	return nonce
+}
+
+func writeNoiseMessage(w io.Writer, msg proto.Message) error {
+	bz, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, binary.MaxVarintLen64+len(bz))
+	n := binary.PutUvarint(buf, uint64(len(bz)))
+	n += copy(buf[n:], bz)
+	_, err = w.Write(buf[:n])
+	return err
+}
+
+// readNoiseMessage reads exactly one length-prefixed message, so that no data
+// encrypted with the upgraded keys is read before the keys are replaced.
+func readNoiseMessage(r io.Reader, msg proto.Message) error {
+	size, err := binary.ReadUvarint(byteReader{r})
+	if err != nil {
+		return err
+	}
+	if size > noiseMaxMessageSize {
+		return errors.Errorf("noise handshake message too big (%d > %d)", size, noiseMaxMessageSize)
+	}
+	bz := make([]byte, size)
+	if _, err := io.ReadFull(r, bz); err != nil {
+		return err
+	}
+	return proto.Unmarshal(bz, msg)
+}
+
+type byteReader struct {
+	io.Reader
+}
+
+func (r byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(r.Reader, b[:])
+	return b[0], err
+}
@@ -0,0 +1,91 @@
+package conn
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+func makeSecretConnPairWithKeys(t *testing.T, fooPrvKey, barPrvKey ed25519.PrivKeyEd25519) (fooSecConn, barSecConn *SecretConnection) {
+	fooConn, barConn := makeKVStoreConnPair()
+	trs, ok := cmn.Parallel(
+		func(_ int) (val interface{}, err error, abort bool) {
+			fooSecConn, err = MakeSecretConnection(fooConn, fooPrvKey)
+			return nil, err, err != nil
+		},
+		func(_ int) (val interface{}, err error, abort bool) {
+			barSecConn, err = MakeSecretConnection(barConn, barPrvKey)
+			return nil, err, err != nil
+		},
+	)
+	require.Nil(t, trs.FirstError())
+	require.True(t, ok, "Unexpected task abortion")
+	return fooSecConn, barSecConn
+}
+
+// upgradeNoisePair runs the Noise upgrade on both sides, foo being the
+// initiator, and returns the errors of foo and bar. A failed side closes its
+// connection, so the other side doesn't block forever.
+func upgradeNoisePair(
+	fooSecConn, barSecConn *SecretConnection,
+	fooPrvKey, barPrvKey ed25519.PrivKeyEd25519,
+	fooPrologue, barPrologue []byte,
+) (fooErr, barErr error) {
+	errc := make(chan error, 1)
+	go func() {
+		err := barSecConn.UpgradeNoise(barPrvKey, false, barPrologue)
+		if err != nil {
+			barSecConn.Close()
+		}
+		errc <- err
+	}()
+	fooErr = fooSecConn.UpgradeNoise(fooPrvKey, true, fooPrologue)
+	if fooErr != nil {
+		fooSecConn.Close()
+	}
+	return fooErr, <-errc
+}
+
+func TestSecretConnectionUpgradeNoise(t *testing.T) {
+	fooPrvKey, barPrvKey := ed25519.GenPrivKey(), ed25519.GenPrivKey()
+	fooSecConn, barSecConn := makeSecretConnPairWithKeys(t, fooPrvKey, barPrvKey)
+	prologue := []byte("prologue")
+
+	fooErr, barErr := upgradeNoisePair(fooSecConn, barSecConn, fooPrvKey, barPrvKey, prologue, prologue)
+	require.NoError(t, fooErr)
+	require.NoError(t, barErr)
+
+	// Data must round-trip in both directions with the upgraded keys.
+	for _, pair := range [][2]*SecretConnection{{fooSecConn, barSecConn}, {barSecConn, fooSecConn}} {
+		w, r := pair[0], pair[1]
+		msg := cmn.RandBytes(2 * dataMaxSize)
+		go func() {
+			_, err := w.Write(msg)
+			assert.NoError(t, err)
+		}()
+		got := make([]byte, len(msg))
+		_, err := io.ReadFull(r, got)
+		require.NoError(t, err)
+		assert.Equal(t, msg, got)
+	}
+}
+
+func TestSecretConnectionUpgradeNoiseFailures(t *testing.T) {
+	fooPrvKey, barPrvKey := ed25519.GenPrivKey(), ed25519.GenPrivKey()
+
+	// Peers which disagree on the prologue must fail.
+	fooSecConn, barSecConn := makeSecretConnPairWithKeys(t, fooPrvKey, barPrvKey)
+	fooErr, barErr := upgradeNoisePair(fooSecConn, barSecConn, fooPrvKey, barPrvKey, []byte("foo"), []byte("bar"))
+	assert.Error(t, fooErr)
+	assert.Error(t, barErr)
+
+	// The Noise identity must match the one authenticated by STS.
+	fooSecConn, barSecConn = makeSecretConnPairWithKeys(t, fooPrvKey, barPrvKey)
+	fooErr, _ = upgradeNoisePair(fooSecConn, barSecConn, fooPrvKey, ed25519.GenPrivKey(), nil, nil)
+	assert.Equal(t, ErrNoiseRemoteKeyChanged, fooErr)
+}
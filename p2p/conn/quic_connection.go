@@ -0,0 +1,563 @@
+package conn
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/quic-go/quic-go"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	flow "github.com/tendermint/tendermint/libs/flowrate"
+)
+
+const (
+	// quicFlushTimeout is the time FlushStop waits for the peer to read the
+	// flushed messages and close the connection.
+	quicFlushTimeout = 5 * time.Second
+
+	// the error codes of the QUIC connections closed by the node
+	quicErrorCodeClosed = quic.ApplicationErrorCode(0)
+	quicErrorCodeFailed = quic.ApplicationErrorCode(1)
+)
+
+/*
+QUICConnection multiplexes the channels of a peer on the streams of a QUIC
+connection: each side opens a unidirectional stream per channel, starting with
+the channel ID, on which it writes the messages of the channel prefixed with
+their length (uvarint). A message lost on the network only delays the messages
+of its own channel.
+
+It has the same methods as the MConnection: Send blocks until the message is
+queued or times out, TrySend doesn't block, and the messages received are
+passed to the onReceive callback. The messages of different channels are
+received concurrently.
+
+The send and receive rates bound the whole connection. The keep-alives of QUIC
+replace the pings: the connection fails once it's idle for PingInterval +
+PongTimeout (see QUICConfig).
+*/
+type QUICConnection struct {
+	cmn.BaseService
+
+	conn         quic.Connection
+	sendMonitor  *flow.Monitor
+	recvMonitor  *flow.Monitor
+	channels     []*quicChannel
+	channelsIdx  map[byte]*quicChannel
+	onReceive    receiveCbFunc
+	onError      errorCbFunc
+	onCongestion congestionCbFunc
+	errored      uint32
+	config       MConnConfig
+
+	// Closing quit stops the routines; the send routines flush their queues
+	// first if flush is closed too.
+	quit      chan struct{}
+	flush     chan struct{}
+	doneSends sync.WaitGroup
+
+	// used to ensure FlushStop and OnStop
+	// are safe to call concurrently.
+	stopMtx sync.Mutex
+
+	created time.Time // time of creation
+}
+
+// QUICConfig returns the QUIC settings of the connections of the config: the
+// keep-alives are sent every PingInterval, and the connections idle for
+// PingInterval + PongTimeout time out. The peers open a stream per channel, at
+// most 256.
+func QUICConfig(config MConnConfig, handshakeTimeout time.Duration) *quic.Config {
+	return &quic.Config{
+		HandshakeIdleTimeout:  handshakeTimeout,
+		MaxIdleTimeout:        config.PingInterval + config.PongTimeout,
+		KeepAlivePeriod:       config.PingInterval,
+		MaxIncomingStreams:    1, // the handshake stream
+		MaxIncomingUniStreams: 256,
+	}
+}
+
+// NewQUICConnection multiplexes the channels on the established QUIC
+// connection.
+func NewQUICConnection(
+	conn quic.Connection,
+	chDescs []*ChannelDescriptor,
+	onReceive receiveCbFunc,
+	onError errorCbFunc,
+	config MConnConfig,
+) *QUICConnection {
+	qconn := &QUICConnection{
+		conn:        conn,
+		sendMonitor: flow.New(0, 0),
+		recvMonitor: flow.New(0, 0),
+		channelsIdx: make(map[byte]*quicChannel, len(chDescs)),
+		onReceive:   onReceive,
+		onError:     onError,
+		config:      config,
+		created:     time.Now(),
+	}
+
+	for _, desc := range chDescs {
+		channel := newQUICChannel(qconn, *desc)
+		qconn.channelsIdx[channel.desc.ID] = channel
+		qconn.channels = append(qconn.channels, channel)
+	}
+
+	qconn.BaseService = *cmn.NewBaseService(nil, "QUICConnection", qconn)
+	return qconn
+}
+
+// OnStart implements BaseService. It opens the streams of the channels.
+func (c *QUICConnection) OnStart() error {
+	if err := c.BaseService.OnStart(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.conn.Context(), defaultSendTimeout)
+	defer cancel()
+	for _, channel := range c.channels {
+		stream, err := c.conn.OpenUniStreamSync(ctx)
+		if err == nil {
+			_, err = stream.Write([]byte{channel.desc.ID})
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to open the stream of channel %X", channel.desc.ID)
+		}
+		channel.stream = stream
+	}
+
+	c.quit = make(chan struct{})
+	c.flush = make(chan struct{})
+	for _, channel := range c.channels {
+		c.doneSends.Add(1)
+		go c.sendRoutine(channel)
+	}
+	go c.acceptRoutine()
+	go c.statsRoutine()
+	return nil
+}
+
+// stopServices stops the BaseService and the routines. If they were already
+// stopped, it returns true, otherwise it returns false.
+func (c *QUICConnection) stopServices(flush bool) (alreadyStopped bool) {
+	c.stopMtx.Lock()
+	defer c.stopMtx.Unlock()
+
+	select {
+	case <-c.quit:
+		return true
+	default:
+	}
+
+	c.BaseService.OnStop()
+	if flush {
+		close(c.flush)
+	}
+	close(c.quit)
+	return false
+}
+
+// FlushStop replicates the logic of OnStop. It additionally ensures that all
+// the successful Send calls are written to the streams, and waits for the
+// peer to read them and close the connection, up to quicFlushTimeout.
+func (c *QUICConnection) FlushStop() {
+	if c.stopServices(true) {
+		return
+	}
+
+	// The send routines close their streams once their queues are flushed, and
+	// the peer closes the connection once it has read them.
+	done := make(chan struct{})
+	go func() {
+		c.doneSends.Wait()
+		close(done)
+	}()
+	timeout := time.NewTimer(quicFlushTimeout)
+	defer timeout.Stop()
+	select {
+	case <-done:
+		select {
+		case <-c.conn.Context().Done():
+		case <-timeout.C:
+		}
+	case <-timeout.C:
+	}
+
+	c.conn.CloseWithError(quicErrorCodeClosed, "") // nolint: errcheck
+}
+
+// OnStop implements BaseService.
+func (c *QUICConnection) OnStop() {
+	if c.stopServices(false) {
+		return
+	}
+
+	c.conn.CloseWithError(quicErrorCodeClosed, "") // nolint: errcheck
+}
+
+// SetRates changes the send and receive rate limits of the connection, in
+// bytes per second.
+func (c *QUICConnection) SetRates(sendRate, recvRate int64) {
+	atomic.StoreInt64(&c.config.SendRate, sendRate)
+	atomic.StoreInt64(&c.config.RecvRate, recvRate)
+}
+
+// SetCongestionCallback sets the function called when a channel becomes
+// congested, and when it's relieved (see MConnection). Call it before starting
+// the connection.
+func (c *QUICConnection) SetCongestionCallback(cb func(chID byte, congested bool)) {
+	c.onCongestion = cb
+}
+
+func (c *QUICConnection) String() string {
+	return fmt.Sprintf("QUICConn{%v}", c.conn.RemoteAddr())
+}
+
+// Catch the panics of the reactors, like the MConnection does.
+func (c *QUICConnection) _recover() {
+	if r := recover(); r != nil {
+		c.Logger.Error("QUICConnection panicked", "err", r, "stack", string(debug.Stack()))
+		c.stopForError(errors.Errorf("recovered from panic: %v", r))
+	}
+}
+
+// stopped returns true once the connection is stopped: the streams are
+// expected to fail as the connection is closed.
+func (c *QUICConnection) stopped() bool {
+	select {
+	case <-c.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *QUICConnection) stopForError(r interface{}) {
+	if !c.stopped() {
+		c.conn.CloseWithError(quicErrorCodeFailed, "") // nolint: errcheck
+	}
+	c.Stop()
+	if atomic.CompareAndSwapUint32(&c.errored, 0, 1) {
+		if c.onError != nil {
+			c.onError(r)
+		}
+	}
+}
+
+// Send queues a message to be sent to the channel. It times out (and returns
+// false) after defaultSendTimeout.
+func (c *QUICConnection) Send(chID byte, msgBytes []byte) bool {
+	if !c.IsRunning() {
+		return false
+	}
+
+	channel, ok := c.channelsIdx[chID]
+	if !ok {
+		c.Logger.Error(fmt.Sprintf("Cannot send bytes, unknown channel %X", chID))
+		return false
+	}
+
+	success := channel.sendBytes(msgBytes)
+	if !success {
+		c.Logger.Debug("Send failed", "channel", chID, "conn", c, "msgBytes", fmt.Sprintf("%X", msgBytes))
+	}
+	return success
+}
+
+// TrySend queues a message to be sent to the channel.
+// Nonblocking, returns true if successful.
+func (c *QUICConnection) TrySend(chID byte, msgBytes []byte) bool {
+	if !c.IsRunning() {
+		return false
+	}
+
+	channel, ok := c.channelsIdx[chID]
+	if !ok {
+		c.Logger.Error(fmt.Sprintf("Cannot send bytes, unknown channel %X", chID))
+		return false
+	}
+	return channel.trySendBytes(msgBytes)
+}
+
+// CanSend returns true if you can send more data onto the chID, false
+// otherwise. Use only as a heuristic.
+func (c *QUICConnection) CanSend(chID byte) bool {
+	if !c.IsRunning() {
+		return false
+	}
+
+	channel, ok := c.channelsIdx[chID]
+	if !ok {
+		c.Logger.Error(fmt.Sprintf("Unknown channel %X", chID))
+		return false
+	}
+	return channel.loadSendQueueSize() < cap(channel.sendQueue)
+}
+
+// Status returns the rates of the connection, and the queues of the streams
+// of the channels.
+func (c *QUICConnection) Status() ConnectionStatus {
+	var status ConnectionStatus
+	status.Duration = time.Since(c.created)
+	status.SendMonitor = c.sendMonitor.Status()
+	status.RecvMonitor = c.recvMonitor.Status()
+	status.Channels = make([]ChannelStatus, len(c.channels))
+	for i, channel := range c.channels {
+		status.Channels[i] = ChannelStatus{
+			ID:                channel.desc.ID,
+			SendQueueCapacity: cap(channel.sendQueue),
+			SendQueueSize:     channel.loadSendQueueSize(),
+			Priority:          channel.desc.Priority,
+			RecentlySent:      atomic.LoadInt64(&channel.recentlySent),
+			Congested:         channel.isCongested(),
+		}
+	}
+	return status
+}
+
+// sendRoutine writes the messages of the channel to its stream.
+func (c *QUICConnection) sendRoutine(channel *quicChannel) {
+	defer c.doneSends.Done()
+	defer c._recover()
+
+	for {
+		var msgBytes []byte
+		select {
+		case msgBytes = <-channel.sendQueue:
+		case <-c.quit:
+			select {
+			case <-c.flush:
+			default:
+				return
+			}
+			// flush the queue, then tell the peer we're done
+			for {
+				select {
+				case msgBytes = <-channel.sendQueue:
+					if err := c.writeMsg(channel, msgBytes); err != nil {
+						return
+					}
+					continue
+				default:
+				}
+				channel.stream.Close() // nolint: errcheck
+				return
+			}
+		}
+
+		if err := c.writeMsg(channel, msgBytes); err != nil {
+			if !c.stopped() {
+				c.Logger.Error("Connection failed @ sendRoutine", "conn", c, "channel", channel.desc.ID, "err", err)
+				c.stopForError(err)
+			}
+			return
+		}
+	}
+}
+
+// writeMsg writes the message to the stream of the channel, length-prefixed.
+// Blocks in accordance to .sendMonitor throttling.
+func (c *QUICConnection) writeMsg(channel *quicChannel, msgBytes []byte) error {
+	bz := make([]byte, binary.MaxVarintLen64+len(msgBytes))
+	n := binary.PutUvarint(bz, uint64(len(msgBytes)))
+	bz = append(bz[:n], msgBytes...)
+
+	c.sendMonitor.Limit(len(bz), atomic.LoadInt64(&c.config.SendRate), true)
+	n, err := channel.stream.Write(bz)
+	c.sendMonitor.Update(n)
+	atomic.AddInt64(&channel.recentlySent, int64(n))
+	atomic.AddInt32(&channel.sendQueueSize, -1)
+	channel.updateCongestion()
+	return err
+}
+
+// acceptRoutine accepts the streams of the channels opened by the peer.
+func (c *QUICConnection) acceptRoutine() {
+	defer c._recover()
+
+	for {
+		stream, err := c.conn.AcceptUniStream(c.conn.Context())
+		if err != nil {
+			if !c.stopped() {
+				c.Logger.Info("Connection is closed @ acceptRoutine (likely by the other side)", "conn", c, "err", err)
+				c.stopForError(err)
+			}
+			return
+		}
+		go c.recvRoutine(stream)
+	}
+}
+
+// recvRoutine reads the messages of the stream of a channel, and passes them
+// to onReceive. Blocks depending on how the connection is throttled.
+func (c *QUICConnection) recvRoutine(stream quic.ReceiveStream) {
+	defer c._recover()
+
+	r := bufio.NewReader(stream)
+	chID, err := r.ReadByte()
+	channel := c.channelsIdx[chID]
+	if err == nil && channel == nil {
+		// The peer opens the streams of all its channels, but only sends on
+		// the ones we have (see Peer#Send).
+		c.Logger.Debug("Ignoring the stream of an unknown channel", "conn", c, "channel", chID)
+		stream.CancelRead(quic.StreamErrorCode(0))
+		return
+	}
+
+	for err == nil {
+		var msgBytes []byte
+		msgBytes, err = c.readMsg(r, channel)
+		if err != nil {
+			break
+		}
+		c.Logger.Debug("Received bytes", "chID", chID, "msgBytes", fmt.Sprintf("%X", msgBytes))
+		c.onReceive(chID, msgBytes)
+	}
+
+	if !c.stopped() {
+		if err == io.EOF {
+			c.Logger.Info("Stream is closed @ recvRoutine (likely by the other side)", "conn", c, "channel", chID)
+		} else {
+			c.Logger.Error("Connection failed @ recvRoutine", "conn", c, "channel", chID, "err", err)
+		}
+		c.stopForError(err)
+	}
+}
+
+// readMsg reads a length-prefixed message of the channel, up to its
+// RecvMessageCapacity.
+func (c *QUICConnection) readMsg(r *bufio.Reader, channel *quicChannel) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if recvCap := channel.desc.RecvMessageCapacity; uint64(recvCap) < size {
+		return nil, fmt.Errorf("Received message exceeds available capacity: %v < %v", recvCap, size)
+	}
+
+	c.recvMonitor.Limit(int(size), atomic.LoadInt64(&c.config.RecvRate), true)
+	msgBytes := make([]byte, size)
+	n, err := io.ReadFull(r, msgBytes)
+	c.recvMonitor.Update(n + uvarintSize(size))
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return msgBytes, err
+}
+
+// statsRoutine updates the stats of the channels periodically.
+func (c *QUICConnection) statsRoutine() {
+	ticker := time.NewTicker(updateStats)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, channel := range c.channels {
+				atomic.StoreInt64(&channel.recentlySent, int64(float64(atomic.LoadInt64(&channel.recentlySent))*0.8))
+			}
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+func uvarintSize(x uint64) int {
+	var bz [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(bz[:], x)
+}
+
+//-----------------------------------------------------------------------------
+
+// quicChannel is a channel of a QUICConnection, sent on its own stream.
+type quicChannel struct {
+	conn          *QUICConnection
+	desc          ChannelDescriptor
+	stream        quic.SendStream
+	sendQueue     chan []byte
+	sendQueueSize int32 // atomic.
+	recentlySent  int64 // exponential moving average
+
+	congestionMtx sync.Mutex
+	congested     bool // the send queue is filled above the high watermark
+}
+
+func newQUICChannel(conn *QUICConnection, desc ChannelDescriptor) *quicChannel {
+	desc = desc.FillDefaults()
+	if desc.Priority <= 0 {
+		panic("Channel default priority must be a positive integer")
+	}
+	return &quicChannel{
+		conn:      conn,
+		desc:      desc,
+		sendQueue: make(chan []byte, desc.SendQueueCapacity),
+	}
+}
+
+// Queues message to send to this channel.
+// Goroutine-safe
+// Times out (and returns false) after defaultSendTimeout
+func (ch *quicChannel) sendBytes(bytes []byte) bool {
+	defer ch.updateCongestion()
+	select {
+	case ch.sendQueue <- bytes:
+		atomic.AddInt32(&ch.sendQueueSize, 1)
+		return true
+	case <-time.After(defaultSendTimeout):
+		return false
+	}
+}
+
+// Queues message to send to this channel.
+// Nonblocking, returns true if successful.
+// Goroutine-safe
+func (ch *quicChannel) trySendBytes(bytes []byte) bool {
+	defer ch.updateCongestion()
+	select {
+	case ch.sendQueue <- bytes:
+		atomic.AddInt32(&ch.sendQueueSize, 1)
+		return true
+	default:
+		return false
+	}
+}
+
+// Goroutine-safe
+func (ch *quicChannel) loadSendQueueSize() (size int) {
+	return int(atomic.LoadInt32(&ch.sendQueueSize))
+}
+
+// Flags the channel as congested once its send queue is filled to 3/4 of its
+// capacity, and as relieved once it drains to 1/4 of it (see Channel).
+// Goroutine-safe
+func (ch *quicChannel) updateCongestion() {
+	ch.congestionMtx.Lock()
+	defer ch.congestionMtx.Unlock()
+
+	size, capacity := ch.loadSendQueueSize(), cap(ch.sendQueue)
+	switch {
+	case !ch.congested && size >= (3*capacity+3)/4:
+		ch.congested = true
+	case ch.congested && size <= capacity/4:
+		ch.congested = false
+	default:
+		return
+	}
+	if ch.conn.onCongestion != nil {
+		ch.conn.onCongestion(ch.desc.ID, ch.congested)
+	}
+}
+
+// Goroutine-safe
+func (ch *quicChannel) isCongested() bool {
+	ch.congestionMtx.Lock()
+	defer ch.congestionMtx.Unlock()
+	return ch.congested
+}
@@ -0,0 +1,196 @@
+package conn
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+type quicTestMsg struct {
+	chID     byte
+	msgBytes []byte
+}
+
+// createQUICConnectionPair returns the client and the server sides of a QUIC
+// connection over the loopback interface, and the listener to close once done
+// (which closes the socket of the server).
+func createQUICConnectionPair(t *testing.T) (client, server quic.Connection, ln *quic.Listener) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotAfter: time.Now().Add(time.Hour)}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	tlsConfig := &tls.Config{
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}},
+		InsecureSkipVerify: true, // nolint: gosec
+		NextProtos:         []string{"test"},
+	}
+
+	quicConfig := QUICConfig(DefaultMConnConfig(), time.Second)
+	ln, err = quic.ListenAddr("127.0.0.1:0", tlsConfig, quicConfig)
+	require.NoError(t, err)
+
+	serverc := make(chan quic.Connection, 1)
+	go func() {
+		server, err := ln.Accept(context.Background())
+		if err != nil {
+			t.Errorf("accept failed: %v", err)
+		}
+		serverc <- server
+	}()
+
+	client, err = quic.DialAddr(context.Background(), ln.Addr().String(), tlsConfig, quicConfig)
+	require.NoError(t, err)
+	server = <-serverc
+	require.NotNil(t, server)
+	return client, server, ln
+}
+
+func createTestQUICConnection(
+	conn quic.Connection,
+	chDescs []*ChannelDescriptor,
+	receivedCh chan quicTestMsg,
+	errorsCh chan interface{},
+) *QUICConnection {
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- quicTestMsg{chID, msgBytes}
+	}
+	onError := func(r interface{}) {
+		errorsCh <- r
+	}
+	c := NewQUICConnection(conn, chDescs, onReceive, onError, DefaultMConnConfig())
+	c.SetLogger(log.TestingLogger())
+	return c
+}
+
+func TestQUICConnectionSendReceive(t *testing.T) {
+	client, server, ln := createQUICConnectionPair(t)
+	defer ln.Close()
+
+	var (
+		chDescs    = []*ChannelDescriptor{{ID: 0x01, Priority: 1}, {ID: 0x02, Priority: 1}}
+		receivedCh = make(chan quicTestMsg, 10)
+		errorsCh   = make(chan interface{}, 10)
+		clientConn = createTestQUICConnection(client, chDescs, receivedCh, errorsCh)
+		serverConn = createTestQUICConnection(server, chDescs, receivedCh, errorsCh)
+	)
+	require.NoError(t, clientConn.Start())
+	defer clientConn.Stop()
+	require.NoError(t, serverConn.Start())
+	defer serverConn.Stop()
+
+	assert.True(t, clientConn.Send(0x01, []byte("one")))
+	assert.True(t, serverConn.Send(0x02, []byte("two")))
+	assert.False(t, clientConn.Send(0x05, []byte("unknown channel")))
+
+	received := map[byte]string{}
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-receivedCh:
+			received[msg.chID] = string(msg.msgBytes)
+		case err := <-errorsCh:
+			t.Fatalf("Expected %v, got %v", "a message", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("Did not receive the messages in 5s")
+		}
+	}
+	assert.Equal(t, map[byte]string{0x01: "one", 0x02: "two"}, received)
+
+	status := clientConn.Status()
+	assert.Len(t, status.Channels, 2)
+}
+
+func TestQUICConnectionSendFlushStop(t *testing.T) {
+	client, server, ln := createQUICConnectionPair(t)
+	defer ln.Close()
+
+	var (
+		chDescs    = []*ChannelDescriptor{{ID: 0x01, Priority: 1}}
+		receivedCh = make(chan quicTestMsg, 10)
+		errorsCh   = make(chan interface{}, 10)
+		clientConn = createTestQUICConnection(client, chDescs, receivedCh, errorsCh)
+		serverConn = createTestQUICConnection(server, chDescs, receivedCh, errorsCh)
+	)
+	require.NoError(t, clientConn.Start())
+	require.NoError(t, serverConn.Start())
+	defer serverConn.Stop()
+
+	msg := []byte("abc")
+	assert.True(t, clientConn.Send(0x01, msg))
+	go clientConn.FlushStop()
+
+	// the queued message is written before the stream is closed
+	select {
+	case received := <-receivedCh:
+		assert.Equal(t, msg, received.msgBytes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Did not receive the message in 5s")
+	}
+	select {
+	case <-errorsCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the closed stream to stop the connection")
+	}
+}
+
+func TestQUICConnectionReadErrorLongMessage(t *testing.T) {
+	client, server, ln := createQUICConnectionPair(t)
+	defer ln.Close()
+
+	var (
+		chDescs    = []*ChannelDescriptor{{ID: 0x01, Priority: 1, RecvMessageCapacity: 10}}
+		receivedCh = make(chan quicTestMsg, 10)
+		errorsCh   = make(chan interface{}, 10)
+		serverConn = createTestQUICConnection(server, chDescs, receivedCh, errorsCh)
+	)
+	require.NoError(t, serverConn.Start())
+	defer serverConn.Stop()
+
+	stream, err := client.OpenUniStreamSync(context.Background())
+	require.NoError(t, err)
+
+	// the message of a stream of an unknown channel is ignored
+	unknown, err := client.OpenUniStreamSync(context.Background())
+	require.NoError(t, err)
+	_, err = unknown.Write([]byte{0x05, 1, 'x'})
+	require.NoError(t, err)
+
+	buf := []byte{0x01}
+	buf = append(buf, uvarintBytes(3)...)
+	buf = append(buf, "abc"...)
+	buf = append(buf, uvarintBytes(11)...)
+	_, err = stream.Write(buf)
+	require.NoError(t, err)
+
+	select {
+	case msg := <-receivedCh:
+		assert.Equal(t, quicTestMsg{0x01, []byte("abc")}, msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Did not receive the message in 5s")
+	}
+	select {
+	case <-errorsCh:
+	case msg := <-receivedCh:
+		t.Fatalf("Expected an error, got %v", msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the long message to stop the connection")
+	}
+}
+
+func uvarintBytes(x uint64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutUvarint(buf, x)]
+}
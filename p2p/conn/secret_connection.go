@@ -49,7 +49,7 @@ var (
 // (TODO(ismail): see also https://github.com/tendermint/tendermint/issues/3010)
 type SecretConnection struct {
 
-	// immutable
+	// immutable, unless replaced by UpgradeNoise before the connection is used
 	recvAead cipher.AEAD
 	sendAead cipher.AEAD
 
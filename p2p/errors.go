@@ -145,6 +145,16 @@ func (e ErrTransportClosed) Error() string {
 	return "transport has been closed"
 }
 
+// ErrResourceExhausted is raised when the Transport can't accept connections
+// because the node ran out of file descriptors or memory.
+type ErrResourceExhausted struct {
+	Err error
+}
+
+func (e ErrResourceExhausted) Error() string {
+	return fmt.Sprintf("resources exhausted: %v", e.Err)
+}
+
 //-------------------------------------------------------------------
 
 type ErrNetAddressNoID struct {
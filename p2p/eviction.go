@@ -0,0 +1,77 @@
+package p2p
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// isResourceExhausted returns true if the error of an accept or a dial is
+// due to the node running out of file descriptors or memory.
+func isResourceExhausted(err error) bool {
+	err = errors.Cause(err)
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	switch err {
+	case syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM:
+		return true
+	}
+	return false
+}
+
+// isPeerProtected returns true if the peer is never evicted: a persistent,
+// unconditional or private peer (e.g. the validator behind a sentry node).
+func (sw *Switch) isPeerProtected(p Peer) bool {
+	return p.IsPersistent() || sw.IsPeerUnconditional(p.ID()) || sw.IsPeerPrivate(p.ID())
+}
+
+// peerScore returns how useful the peer is: the number of times it was marked
+// as good (see MarkPeerAsGood).
+func (sw *Switch) peerScore(id ID) int {
+	sw.goodMarksMtx.Lock()
+	defer sw.goodMarksMtx.Unlock()
+	return sw.goodMarks[id]
+}
+
+// leastUsefulPeer returns the peer to evict first, or nil if all the peers
+// are protected: the one of lowest score, and among them the most recently
+// connected one, so that the new peers are evicted before the established
+// ones.
+func (sw *Switch) leastUsefulPeer() Peer {
+	var (
+		evicted Peer
+		score   int
+	)
+	for _, p := range sw.peers.List() {
+		if sw.isPeerProtected(p) {
+			continue
+		}
+		s := sw.peerScore(p.ID())
+		if evicted == nil || s < score ||
+			(s == score && p.Status().Duration < evicted.Status().Duration) {
+			evicted, score = p, s
+		}
+	}
+	return evicted
+}
+
+// evictPeer stops the least useful peer to free resources for a new
+// connection, when the node runs out of them. It returns false if all the
+// peers are protected.
+func (sw *Switch) evictPeer(reason error) bool {
+	p := sw.leastUsefulPeer()
+	if p == nil {
+		sw.Logger.Error("Can't evict a peer: all the peers are protected", "err", reason)
+		return false
+	}
+	sw.Logger.Info("Evicting peer to free resources", "peer", p, "score", sw.peerScore(p.ID()), "err", reason)
+	sw.metrics.PeerEvictions.Add(1)
+	sw.stopAndRemovePeer(p, reason)
+	return true
+}
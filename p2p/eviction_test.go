@@ -0,0 +1,89 @@
+package p2p
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+func TestIsResourceExhausted(t *testing.T) {
+	emfile := &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	assert.True(t, isResourceExhausted(emfile))
+	assert.True(t, isResourceExhausted(syscall.ENOBUFS))
+	assert.False(t, isResourceExhausted(&net.OpError{Op: "accept", Net: "tcp", Err: syscall.ECONNRESET}))
+	assert.False(t, isResourceExhausted(errors.New("use of closed network connection")))
+}
+
+// exhaustedListener fails the accepts for lack of file descriptors n times,
+// then for good.
+type exhaustedListener struct {
+	net.Listener
+	n int
+}
+
+func (ln *exhaustedListener) Accept() (net.Conn, error) {
+	if ln.n == 0 {
+		return nil, errors.New("closed")
+	}
+	ln.n--
+	return nil, &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+}
+
+func TestTransportAcceptResourceExhausted(t *testing.T) {
+	mt := testSetupMultiplexTransport(t)
+	defer mt.Close()
+	go mt.acceptPeers(&exhaustedListener{n: 2})
+
+	for i := 0; i < 2; i++ {
+		_, err := mt.Accept(peerConfig{})
+		require.IsType(t, ErrResourceExhausted{}, err, "#%d", i)
+	}
+	_, err := mt.Accept(peerConfig{})
+	require.Error(t, err)
+	assert.Equal(t, "closed", err.Error())
+}
+
+func TestSwitchEvictPeer(t *testing.T) {
+	sw := MakeSwitch(cfg, 1, "testing", "123.123.123", initSwitchFunc)
+	unconditional := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	require.NoError(t, sw.AddUnconditionalPeerIDs([]string{string(unconditional.ID())}))
+	private := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	require.NoError(t, sw.AddPrivatePeerIDs([]string{string(private.ID())}))
+	require.NoError(t, sw.Start())
+	defer sw.Stop()
+
+	// old and useful, old, new
+	remotePeers := []*remotePeer{unconditional, private}
+	for i := 0; i < 3; i++ {
+		remotePeers = append(remotePeers, &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg})
+	}
+	for _, rp := range remotePeers {
+		rp.Start()
+		defer rp.Stop()
+		_, err := rp.Dial(sw.NetAddress())
+		require.NoError(t, err)
+		time.Sleep(10 * time.Millisecond)
+	}
+	waitUntilSwitchHasAtLeastNPeers(sw, len(remotePeers))
+	require.Equal(t, len(remotePeers), sw.Peers().Size())
+	useful, old, newest := remotePeers[2], remotePeers[3], remotePeers[4]
+	sw.MarkPeerAsGood(sw.Peers().Get(useful.ID()))
+
+	reason := ErrResourceExhausted{syscall.EMFILE}
+	for _, evicted := range []*remotePeer{newest, old, useful} {
+		require.True(t, sw.evictPeer(reason))
+		assert.False(t, sw.Peers().Has(evicted.ID()))
+	}
+
+	// only the protected peers are left
+	assert.False(t, sw.evictPeer(reason))
+	assert.Equal(t, 2, sw.Peers().Size())
+}
@@ -23,8 +23,17 @@ type Metrics struct {
 	PeerSendBytesTotal metrics.Counter
 	// Pending bytes to be sent to a given peer.
 	PeerPendingSendBytes metrics.Gauge
+	// Number of messages in the send queue of a channel to a given peer.
+	PeerChannelSendQueueSize metrics.Gauge
+	// Number of messages to a given peer dropped because the send queue of the
+	// channel was full.
+	PeerChannelSendDropsTotal metrics.Counter
+	// Whether the send queue of a channel to a given peer is congested.
+	PeerChannelCongested metrics.Gauge
 	// Number of transactions submitted by each peer.
 	NumTxs metrics.Gauge
+	// Number of peers evicted to free resources for new connections.
+	PeerEvictions metrics.Counter
 }
 
 // PrometheusMetrics returns Metrics build using Prometheus client library.
@@ -47,35 +56,63 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Subsystem: MetricsSubsystem,
 			Name:      "peer_receive_bytes_total",
 			Help:      "Number of bytes received from a given peer.",
-		}, append(labels, "peer_id", "chID")).With(labelsAndValues...),
+		}, append(labels, "peer_id", "peer_label", "chID")).With(labelsAndValues...),
 		PeerSendBytesTotal: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
 			Name:      "peer_send_bytes_total",
 			Help:      "Number of bytes sent to a given peer.",
-		}, append(labels, "peer_id", "chID")).With(labelsAndValues...),
+		}, append(labels, "peer_id", "peer_label", "chID")).With(labelsAndValues...),
 		PeerPendingSendBytes: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
 			Name:      "peer_pending_send_bytes",
 			Help:      "Number of pending bytes to be sent to a given peer.",
-		}, append(labels, "peer_id")).With(labelsAndValues...),
+		}, append(labels, "peer_id", "peer_label")).With(labelsAndValues...),
+		PeerChannelSendQueueSize: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_channel_send_queue_size",
+			Help:      "Number of messages in the send queue of a channel to a given peer.",
+		}, append(labels, "peer_id", "peer_label", "chID")).With(labelsAndValues...),
+		PeerChannelSendDropsTotal: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_channel_send_drops_total",
+			Help:      "Number of messages to a given peer dropped because the send queue of the channel was full.",
+		}, append(labels, "peer_id", "peer_label", "chID")).With(labelsAndValues...),
+		PeerChannelCongested: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_channel_congested",
+			Help:      "Whether the send queue of a channel to a given peer is congested (1) or not (0).",
+		}, append(labels, "peer_id", "peer_label", "chID")).With(labelsAndValues...),
 		NumTxs: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
 			Namespace: namespace,
 			Subsystem: MetricsSubsystem,
 			Name:      "num_txs",
 			Help:      "Number of transactions submitted by each peer.",
 		}, append(labels, "peer_id")).With(labelsAndValues...),
+		PeerEvictions: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_evictions_total",
+			Help:      "Number of peers evicted to free resources for new connections.",
+		}, labels).With(labelsAndValues...),
 	}
 }
 
 // NopMetrics returns no-op Metrics.
 func NopMetrics() *Metrics {
 	return &Metrics{
-		Peers:                 discard.NewGauge(),
-		PeerReceiveBytesTotal: discard.NewCounter(),
-		PeerSendBytesTotal:    discard.NewCounter(),
-		PeerPendingSendBytes:  discard.NewGauge(),
-		NumTxs:                discard.NewGauge(),
+		Peers:                     discard.NewGauge(),
+		PeerReceiveBytesTotal:     discard.NewCounter(),
+		PeerSendBytesTotal:        discard.NewCounter(),
+		PeerPendingSendBytes:      discard.NewGauge(),
+		PeerChannelSendQueueSize:  discard.NewGauge(),
+		PeerChannelSendDropsTotal: discard.NewCounter(),
+		PeerChannelCongested:      discard.NewGauge(),
+		NumTxs:                    discard.NewGauge(),
+		PeerEvictions:             discard.NewCounter(),
 	}
 }
@@ -16,6 +16,8 @@ import (
 	"github.com/pkg/errors"
 )
 
+var errOnionWithoutProxy = errors.New("onion addresses can only be dialed through a proxy")
+
 // NetAddress defines information about a peer on the network
 // including its ID, IP address, and port.
 type NetAddress struct {
@@ -23,6 +25,10 @@ type NetAddress struct {
 	IP   net.IP `json:"ip"`
 	Port uint16 `json:"port"`
 
+	// Onion is the host of a Tor hidden service (<base32>.onion), which has
+	// no IP and is dialed through a SOCKS5 proxy.
+	Onion string `json:"onion,omitempty"`
+
 	// TODO:
 	// Name string `json:"name"` // optional DNS name
 
@@ -43,10 +49,18 @@ func IDAddressString(id ID, protocolHostPort string) string {
 // panic. Panics if ID is invalid.
 // TODO: socks proxies?
 func NewNetAddress(id ID, addr net.Addr) *NetAddress {
-	tcpAddr, ok := addr.(*net.TCPAddr)
-	if !ok {
+	var (
+		ip   net.IP
+		port int
+	)
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip, port = addr.IP, addr.Port
+	case *net.UDPAddr: // of the QUIC connections
+		ip, port = addr.IP, addr.Port
+	default:
 		if flag.Lookup("test.v") == nil { // normal run
-			panic(fmt.Sprintf("Only TCPAddrs are supported. Got: %v", addr))
+			panic(fmt.Sprintf("Only TCP and UDP addrs are supported. Got: %v", addr))
 		} else { // in testing
 			netAddr := NewNetAddressIPPort(net.IP("0.0.0.0"), 0)
 			netAddr.ID = id
@@ -58,9 +72,7 @@ func NewNetAddress(id ID, addr net.Addr) *NetAddress {
 		panic(fmt.Sprintf("Invalid ID %v: %v (addr: %v)", id, err, addr))
 	}
 
-	ip := tcpAddr.IP
-	port := uint16(tcpAddr.Port)
-	na := NewNetAddressIPPort(ip, port)
+	na := NewNetAddressIPPort(ip, uint16(port))
 	na.ID = id
 	return na
 }
@@ -94,6 +106,19 @@ func NewNetAddressString(addr string) (*NetAddress, error) {
 			errors.New("host is empty")}
 	}
 
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, ErrNetAddressInvalid{portStr, err}
+	}
+
+	// onion hosts are resolved by the proxy
+	if strings.HasSuffix(host, ".onion") {
+		if !isOnionHost(host) {
+			return nil, ErrNetAddressInvalid{addrWithoutProtocol, errors.New("invalid onion host")}
+		}
+		return &NetAddress{ID: id, Onion: host, Port: uint16(port)}, nil
+	}
+
 	ip := net.ParseIP(host)
 	if ip == nil {
 		ips, err := net.LookupIP(host)
@@ -103,11 +128,6 @@ func NewNetAddressString(addr string) (*NetAddress, error) {
 		ip = ips[0]
 	}
 
-	port, err := strconv.ParseUint(portStr, 10, 16)
-	if err != nil {
-		return nil, ErrNetAddressInvalid{portStr, err}
-	}
-
 	na := NewNetAddressIPPort(ip, uint16(port))
 	na.ID = id
 	return na, nil
@@ -179,14 +199,26 @@ func (na *NetAddress) DialString() string {
 	if na == nil {
 		return "<nil-NetAddress>"
 	}
+	host := na.IP.String()
+	if na.IsOnion() {
+		host = na.Onion
+	}
 	return net.JoinHostPort(
-		na.IP.String(),
+		host,
 		strconv.FormatUint(uint64(na.Port), 10),
 	)
 }
 
+// IsOnion returns true if the address is the one of a Tor hidden service.
+func (na *NetAddress) IsOnion() bool {
+	return na.Onion != ""
+}
+
 // Dial calls net.Dial on the address.
 func (na *NetAddress) Dial() (net.Conn, error) {
+	if na.IsOnion() {
+		return nil, errOnionWithoutProxy
+	}
 	conn, err := net.Dial("tcp", na.DialString())
 	if err != nil {
 		return nil, err
@@ -196,6 +228,9 @@ func (na *NetAddress) Dial() (net.Conn, error) {
 
 // DialTimeout calls net.DialTimeout on the address.
 func (na *NetAddress) DialTimeout(timeout time.Duration) (net.Conn, error) {
+	if na.IsOnion() {
+		return nil, errOnionWithoutProxy
+	}
 	conn, err := net.DialTimeout("tcp", na.DialString(), timeout)
 	if err != nil {
 		return nil, err
@@ -220,6 +255,12 @@ func (na *NetAddress) Valid() error {
 		return errors.Wrap(err, "invalid ID")
 	}
 
+	if na.IsOnion() {
+		if !isOnionHost(na.Onion) {
+			return errors.New("invalid onion host")
+		}
+		return nil
+	}
 	if na.IP == nil {
 		return errors.New("no IP")
 	}
@@ -337,6 +378,21 @@ func removeProtocolIfDefined(addr string) string {
 
 }
 
+// isOnionHost returns true if host is the host of a v2 or v3 Tor hidden
+// service: 16 or 56 base32 characters followed by .onion.
+func isOnionHost(host string) bool {
+	name := strings.TrimSuffix(host, ".onion")
+	if name == host || (len(name) != 16 && len(name) != 56) {
+		return false
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z') && !('2' <= c && c <= '7') {
+			return false
+		}
+	}
+	return true
+}
+
 func validateID(id ID) error {
 	if len(id) == 0 {
 		return errors.New("no ID")
@@ -19,9 +19,13 @@ func TestNewNetAddress(t *testing.T) {
 	addr := NewNetAddress("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", tcpAddr)
 	assert.Equal(t, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@127.0.0.1:8080", addr.String())
 
+	// the UDP addresses of the QUIC connections
+	addr = NewNetAddress("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8000})
+	assert.Equal(t, "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@127.0.0.1:8000", addr.String())
+
 	assert.NotPanics(t, func() {
-		NewNetAddress("", &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8000})
-	}, "Calling NewNetAddress with UDPAddr should not panic in testing")
+		NewNetAddress("", &net.UnixAddr{Name: "/tmp/p2p.sock", Net: "unix"})
+	}, "Calling NewNetAddress with UnixAddr should not panic in testing")
 }
 
 func TestNewNetAddressString(t *testing.T) {
@@ -84,6 +88,20 @@ func TestNewNetAddressString(t *testing.T) {
 		{"node id delimiter 1", "@", "", false},
 		{"node id delimiter 2", " @", "", false},
 		{"node id delimiter 3", " @ ", "", false},
+
+		{
+			"v2 onion",
+			"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@expyuzz4wqqyqhjn.onion:26656",
+			"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@expyuzz4wqqyqhjn.onion:26656",
+			true,
+		},
+		{
+			"v3 onion",
+			"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid.onion:26656",
+			"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@2gzyxa5ihm7nsggfxnu52rck2vv4rvmdlkiu3zzui5du4xyclen53wid.onion:26656",
+			true,
+		},
+		{"invalid onion", "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@notanonion.onion:26656", "", false},
 	}
 
 	for _, tc := range testCases {
@@ -125,6 +143,7 @@ func TestNetAddressProperties(t *testing.T) {
 	}{
 		{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@127.0.0.1:8080", true, true, false},
 		{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@ya.ru:80", true, false, true},
+		{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@expyuzz4wqqyqhjn.onion:26656", true, false, true},
 	}
 
 	for _, tc := range testCases {
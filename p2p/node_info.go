@@ -11,6 +11,7 @@ import (
 const (
 	maxNodeInfoSize = 10240 // 10KB
 	maxNumChannels  = 16    // plenty of room for upgrades, for now
+	maxNumNetworks  = 64    // see Switch#AddNamespace
 )
 
 // Max size of the NodeInfo struct
@@ -18,6 +19,25 @@ func MaxNodeInfoSize() int {
 	return maxNodeInfoSize
 }
 
+// Block version 11 only erasure codes the parts of the blocks of chains started
+// on it, and still processes the blocks of chains started on version 10: nodes
+// on either version connect, so that networks can upgrade a node at a time.
+const (
+	minUpgradableBlockProtocol version.Protocol = 10
+	maxUpgradableBlockProtocol version.Protocol = 11
+)
+
+// compatibleBlockVersions returns true if nodes on the Block versions a and b
+// can connect: the same version, or both between minUpgradableBlockProtocol
+// and maxUpgradableBlockProtocol.
+func compatibleBlockVersions(a, b version.Protocol) bool {
+	if a == b {
+		return true
+	}
+	return a >= minUpgradableBlockProtocol && a <= maxUpgradableBlockProtocol &&
+		b >= minUpgradableBlockProtocol && b <= maxUpgradableBlockProtocol
+}
+
 //-------------------------------------------------------------
 
 // NodeInfo exposes basic info of a node
@@ -89,12 +109,20 @@ type DefaultNodeInfo struct {
 	// ASCIIText fields
 	Moniker string               `json:"moniker"` // arbitrary moniker
 	Other   DefaultNodeInfoOther `json:"other"`   // other application specific data
+
+	// Time the NodeInfo is sent at in the handshake, in nanoseconds since the
+	// Unix epoch; 0 outside of the handshake.
+	HandshakeTime int64 `json:"handshake_time,omitempty"`
 }
 
 // DefaultNodeInfoOther is the misc. applcation specific data
 type DefaultNodeInfoOther struct {
 	TxIndex    string `json:"tx_index"`
 	RPCAddress string `json:"rpc_address"`
+
+	// Networks are the chain IDs of the other networks run by the node,
+	// multiplexed on its connections (see Switch#AddNamespace).
+	Networks []string `json:"networks,omitempty"`
 }
 
 // ID returns the node's peer ID.
@@ -165,20 +193,34 @@ func (info DefaultNodeInfo) Validate() error {
 	if len(rpcAddr) > 0 && (!cmn.IsASCIIText(rpcAddr) || cmn.ASCIITrim(rpcAddr) == "") {
 		return fmt.Errorf("info.Other.RPCAddress=%v must be valid ASCII text without tabs", rpcAddr)
 	}
+	if len(other.Networks) > maxNumNetworks {
+		return fmt.Errorf("info.Other.Networks is too long (%v). Max is %v", len(other.Networks), maxNumNetworks)
+	}
+	networks := make(map[string]struct{}, len(other.Networks))
+	for _, network := range other.Networks {
+		if !cmn.IsASCIIText(network) || cmn.ASCIITrim(network) == "" {
+			return fmt.Errorf("info.Other.Networks contains %q, must be valid ASCII text without tabs", network)
+		}
+		if _, ok := networks[network]; ok || network == info.Network {
+			return fmt.Errorf("info.Other.Networks contains duplicate network %v", network)
+		}
+		networks[network] = struct{}{}
+	}
 
 	return nil
 }
 
 // CompatibleWith checks if two DefaultNodeInfo are compatible with eachother.
-// CONTRACT: two nodes are compatible if the Block version and network match
-// and they have at least one channel in common.
+// CONTRACT: two nodes are compatible if the Block versions are compatible (see
+// compatibleBlockVersions), the network matches and they have at least one
+// channel in common.
 func (info DefaultNodeInfo) CompatibleWith(other_ NodeInfo) error {
 	other, ok := other_.(DefaultNodeInfo)
 	if !ok {
 		return fmt.Errorf("wrong NodeInfo type. Expected DefaultNodeInfo, got %v", reflect.TypeOf(other_))
 	}
 
-	if info.ProtocolVersion.Block != other.ProtocolVersion.Block {
+	if !compatibleBlockVersions(info.ProtocolVersion.Block, other.ProtocolVersion.Block) {
 		return fmt.Errorf("Peer is on a different Block version. Got %v, expected %v",
 			other.ProtocolVersion.Block, info.ProtocolVersion.Block)
 	}
@@ -64,6 +64,17 @@ func TestNodeInfoValidate(t *testing.T) {
 		{"Empty space RPCAddress", func(ni *DefaultNodeInfo) { ni.Other.RPCAddress = emptySpace }, true},
 		{"Empty RPCAddress", func(ni *DefaultNodeInfo) { ni.Other.RPCAddress = "" }, false},
 		{"Good RPCAddress", func(ni *DefaultNodeInfo) { ni.Other.RPCAddress = "0.0.0.0:26657" }, false},
+
+		{"Empty Network", func(ni *DefaultNodeInfo) { ni.Other.Networks = []string{""} }, true},
+		{"Non-ASCII Network", func(ni *DefaultNodeInfo) { ni.Other.Networks = []string{nonAscii} }, true},
+		{"Duplicate Network", func(ni *DefaultNodeInfo) { ni.Other.Networks = []string{"a", "a"} }, true},
+		{"Primary Network", func(ni *DefaultNodeInfo) { ni.Other.Networks = []string{ni.Network} }, true},
+		{"Too Many Networks", func(ni *DefaultNodeInfo) {
+			for i := 0; i <= maxNumNetworks; i++ {
+				ni.Other.Networks = append(ni.Other.Networks, fmt.Sprintf("chain-%d", i))
+			}
+		}, true},
+		{"Good Networks", func(ni *DefaultNodeInfo) { ni.Other.Networks = []string{"chain-a", "chain-b"} }, false},
 	}
 
 	nodeKey := NodeKey{PrivKey: ed25519.GenPrivKey()}
@@ -105,6 +116,12 @@ func TestNodeInfoCompatible(t *testing.T) {
 	ni2.Channels = []byte{newTestChannel, testCh}
 	assert.NoError(t, ni1.CompatibleWith(ni2))
 
+	// block versions 10 and 11 are compatible
+	ni2.ProtocolVersion.Block = 10
+	assert.NoError(t, ni1.CompatibleWith(ni2))
+	assert.NoError(t, ni2.CompatibleWith(ni1))
+	ni2.ProtocolVersion.Block = ni1.ProtocolVersion.Block
+
 	// wrong NodeInfo type is not compatible
 	_, netAddr := CreateRoutableAddr()
 	ni3 := mockNodeInfo{netAddr}
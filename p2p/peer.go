@@ -5,6 +5,8 @@ import (
 	"net"
 	"time"
 
+	"github.com/quic-go/quic-go"
+
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/libs/log"
 
@@ -93,6 +95,26 @@ func (pc peerConn) RemoteIP() net.IP {
 	return pc.ip
 }
 
+// peerConnection multiplexes the channels of a peer on its connection: the
+// MConnection of the MultiplexTransport, or the QUICConnection of the
+// QUICTransport.
+type peerConnection interface {
+	cmn.Service
+	FlushStop()
+
+	Send(byte, []byte) bool
+	TrySend(byte, []byte) bool
+	CanSend(byte) bool
+
+	Status() tmconn.ConnectionStatus
+	SetRates(sendRate, recvRate int64)
+}
+
+var (
+	_ peerConnection = (*tmconn.MConnection)(nil)
+	_ peerConnection = (*tmconn.QUICConnection)(nil)
+)
+
 // peer implements Peer.
 //
 // Before using a peer, you will need to perform a handshake on connection.
@@ -101,7 +123,7 @@ type peer struct {
 
 	// raw peerConn and the multiplex connection
 	peerConn
-	mconn *tmconn.MConnection
+	mconn peerConnection
 
 	// peer's node info and the channel it knows about
 	// channels = nodeInfo.Channels
@@ -112,6 +134,9 @@ type peer struct {
 	// User data
 	Data *cmn.CMap
 
+	// human readable name configured for the peer, if any
+	label string
+
 	metrics       *Metrics
 	metricsTicker *time.Ticker
 }
@@ -126,24 +151,37 @@ func newPeer(
 	chDescs []*tmconn.ChannelDescriptor,
 	onPeerError func(Peer, interface{}),
 	options ...PeerOption,
+) *peer {
+	return newPeerWithConnection(pc, nodeInfo, func(p *peer) peerConnection {
+		return createMConnection(
+			pc.conn,
+			p,
+			reactorsByCh,
+			chDescs,
+			onPeerError,
+			mConfig,
+		)
+	}, options...)
+}
+
+// newPeerWithConnection returns the peer of the connection created by
+// createConn.
+func newPeerWithConnection(
+	pc peerConn,
+	nodeInfo NodeInfo,
+	createConn func(*peer) peerConnection,
+	options ...PeerOption,
 ) *peer {
 	p := &peer{
 		peerConn:      pc,
 		nodeInfo:      nodeInfo,
-		channels:      nodeInfo.(DefaultNodeInfo).Channels, // TODO
+		channels:      peerChannels(nodeInfo.(DefaultNodeInfo)), // TODO
 		Data:          cmn.NewCMap(),
 		metricsTicker: time.NewTicker(metricsTickerDuration),
 		metrics:       NopMetrics(),
 	}
 
-	p.mconn = createMConnection(
-		pc.conn,
-		p,
-		reactorsByCh,
-		chDescs,
-		onPeerError,
-		mConfig,
-	)
+	p.mconn = createConn(p)
 	p.BaseService = *cmn.NewBaseService(nil, "Peer", p)
 	for _, option := range options {
 		option(p)
@@ -154,11 +192,15 @@ func newPeer(
 
 // String representation.
 func (p *peer) String() string {
+	dir := "in"
 	if p.outbound {
-		return fmt.Sprintf("Peer{%v %v out}", p.mconn, p.ID())
+		dir = "out"
+	}
+	if p.label != "" {
+		return fmt.Sprintf("Peer{%v %v %v %v}", p.mconn, p.ID(), p.label, dir)
 	}
 
-	return fmt.Sprintf("Peer{%v %v in}", p.mconn, p.ID())
+	return fmt.Sprintf("Peer{%v %v %v}", p.mconn, p.ID(), dir)
 }
 
 //---------------------------------------------------
@@ -247,12 +289,11 @@ func (p *peer) Send(chID byte, msgBytes []byte) bool {
 		return false
 	}
 	res := p.mconn.Send(chID, msgBytes)
+	labels := p.metricsLabels("chID", fmt.Sprintf("%#x", chID))
 	if res {
-		labels := []string{
-			"peer_id", string(p.ID()),
-			"chID", fmt.Sprintf("%#x", chID),
-		}
 		p.metrics.PeerSendBytesTotal.With(labels...).Add(float64(len(msgBytes)))
+	} else {
+		p.metrics.PeerChannelSendDropsTotal.With(labels...).Add(1)
 	}
 	return res
 }
@@ -266,12 +307,11 @@ func (p *peer) TrySend(chID byte, msgBytes []byte) bool {
 		return false
 	}
 	res := p.mconn.TrySend(chID, msgBytes)
+	labels := p.metricsLabels("chID", fmt.Sprintf("%#x", chID))
 	if res {
-		labels := []string{
-			"peer_id", string(p.ID()),
-			"chID", fmt.Sprintf("%#x", chID),
-		}
 		p.metrics.PeerSendBytesTotal.With(labels...).Add(float64(len(msgBytes)))
+	} else {
+		p.metrics.PeerChannelSendDropsTotal.With(labels...).Add(1)
 	}
 	return res
 }
@@ -341,6 +381,19 @@ func PeerMetrics(metrics *Metrics) PeerOption {
 	}
 }
 
+// PeerLabel sets the human readable name of the peer.
+func PeerLabel(label string) PeerOption {
+	return func(p *peer) {
+		p.label = label
+	}
+}
+
+// metricsLabels returns the labels identifying the peer in metrics, followed
+// by the given ones.
+func (p *peer) metricsLabels(labelsAndValues ...string) []string {
+	return append([]string{"peer_id", string(p.ID()), "peer_label", p.label}, labelsAndValues...)
+}
+
 func (p *peer) metricsReporter() {
 	for {
 		select {
@@ -349,9 +402,11 @@ func (p *peer) metricsReporter() {
 			var sendQueueSize float64
 			for _, chStatus := range status.Channels {
 				sendQueueSize += float64(chStatus.SendQueueSize)
+				labels := p.metricsLabels("chID", fmt.Sprintf("%#x", chStatus.ID))
+				p.metrics.PeerChannelSendQueueSize.With(labels...).Set(float64(chStatus.SendQueueSize))
 			}
 
-			p.metrics.PeerPendingSendBytes.With("peer_id", string(p.ID())).Set(sendQueueSize)
+			p.metrics.PeerPendingSendBytes.With(p.metricsLabels()...).Set(sendQueueSize)
 		case <-p.Quit():
 			return
 		}
@@ -369,31 +424,77 @@ func createMConnection(
 	onPeerError func(Peer, interface{}),
 	config tmconn.MConnConfig,
 ) *tmconn.MConnection {
+	onReceive, onError, onCongestion := connectionCallbacks(p, reactorsByCh, onPeerError)
+	mconn := tmconn.NewMConnectionWithConfig(
+		conn,
+		chDescs,
+		onReceive,
+		onError,
+		config,
+	)
+	mconn.SetCongestionCallback(onCongestion)
+	return mconn
+}
 
-	onReceive := func(chID byte, msgBytes []byte) {
+func createQUICConnection(
+	qc quic.Connection,
+	p *peer,
+	reactorsByCh map[byte]Reactor,
+	chDescs []*tmconn.ChannelDescriptor,
+	onPeerError func(Peer, interface{}),
+	config tmconn.MConnConfig,
+) *tmconn.QUICConnection {
+	onReceive, onError, onCongestion := connectionCallbacks(p, reactorsByCh, onPeerError)
+	qconn := tmconn.NewQUICConnection(
+		qc,
+		chDescs,
+		onReceive,
+		onError,
+		config,
+	)
+	qconn.SetCongestionCallback(onCongestion)
+	return qconn
+}
+
+// connectionCallbacks returns the callbacks of the connection of the peer,
+// passing the messages and the congestion of the channels to their reactors,
+// and the errors to onPeerError.
+func connectionCallbacks(
+	p *peer,
+	reactorsByCh map[byte]Reactor,
+	onPeerError func(Peer, interface{}),
+) (
+	onReceive func(chID byte, msgBytes []byte),
+	onError func(r interface{}),
+	onCongestion func(chID byte, congested bool),
+) {
+	onReceive = func(chID byte, msgBytes []byte) {
 		reactor := reactorsByCh[chID]
 		if reactor == nil {
 			// Note that its ok to panic here as it's caught in the conn._recover,
 			// which does onPeerError.
 			panic(fmt.Sprintf("Unknown channel %X", chID))
 		}
-		labels := []string{
-			"peer_id", string(p.ID()),
-			"chID", fmt.Sprintf("%#x", chID),
-		}
+		labels := p.metricsLabels("chID", fmt.Sprintf("%#x", chID))
 		p.metrics.PeerReceiveBytesTotal.With(labels...).Add(float64(len(msgBytes)))
 		reactor.Receive(chID, p, msgBytes)
 	}
 
-	onError := func(r interface{}) {
+	onError = func(r interface{}) {
 		onPeerError(p, r)
 	}
 
-	return tmconn.NewMConnectionWithConfig(
-		conn,
-		chDescs,
-		onReceive,
-		onError,
-		config,
-	)
+	onCongestion = func(chID byte, congested bool) {
+		labels := p.metricsLabels("chID", fmt.Sprintf("%#x", chID))
+		if congested {
+			p.metrics.PeerChannelCongested.With(labels...).Set(1)
+		} else {
+			p.metrics.PeerChannelCongested.With(labels...).Set(0)
+		}
+		if reactor, ok := reactorsByCh[chID].(CongestionAwareReactor); ok {
+			reactor.ChannelCongestion(chID, p, congested)
+		}
+	}
+
+	return onReceive, onError, onCongestion
 }
@@ -0,0 +1,36 @@
+package p2p
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PeerLabels maps node IDs to human readable names, which are attached to
+// the logs, metrics and RPC output concerning the peer.
+type PeerLabels map[ID]string
+
+// ParsePeerLabels parses a comma separated list of <ID>=<label> pairs.
+func ParsePeerLabels(s string) (PeerLabels, error) {
+	labels := make(PeerLabels)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid peer label %q (want <ID>=<label>)", pair)
+		}
+		id := ID(strings.ToLower(strings.TrimSpace(parts[0])))
+		if err := validateID(id); err != nil {
+			return nil, fmt.Errorf("invalid peer label %q: %v", pair, err)
+		}
+		labels[id] = strings.TrimSpace(parts[1])
+	}
+	return labels, nil
+}
+
+// Label returns the label of the peer with the given ID, or "" if it has none.
+func (pl PeerLabels) Label(id ID) string {
+	return pl[id]
+}
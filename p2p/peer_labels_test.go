@@ -0,0 +1,39 @@
+package p2p
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeerLabels(t *testing.T) {
+	const (
+		id1 = "deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"
+		id2 = "0123456789abcdef0123456789abcdef01234567"
+	)
+
+	labels, err := ParsePeerLabels("")
+	require.NoError(t, err)
+	assert.Empty(t, labels)
+
+	labels, err = ParsePeerLabels(" " + id1 + "=sentry-eu-1 , " + id2 + " = validator ,")
+	require.NoError(t, err)
+	assert.Equal(t, "sentry-eu-1", labels.Label(id1))
+	assert.Equal(t, "validator", labels.Label(id2))
+	assert.Equal(t, "", labels.Label("0000000000000000000000000000000000000000"))
+
+	for _, s := range []string{
+		id1,
+		id1 + "=",
+		"=sentry",
+		"nothex=sentry",
+		"deadbeef=sentry",
+	} {
+		_, err := ParsePeerLabels(s)
+		assert.Error(t, err, s)
+	}
+
+	var nilLabels PeerLabels
+	assert.Equal(t, "", nilLabels.Label(id1))
+}
@@ -86,6 +86,9 @@ func createOutboundPeerAndPerformHandshake(
 	if err != nil {
 		return nil, err
 	}
+	if err := testUpgradeNoise(pc, pk, true, ourNodeInfo, peerNodeInfo); err != nil {
+		return nil, err
+	}
 
 	p := newPeer(pc, mConfig, peerNodeInfo, reactorsByCh, chDescs, func(p Peer, r interface{}) {})
 	p.SetLogger(log.TestingLogger().With("peer", addr))
@@ -183,7 +186,11 @@ func (rp *remotePeer) Dial(addr *NetAddress) (net.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	_, err = handshake(pc.conn, time.Second, rp.nodeInfo())
+	peerNodeInfo, err := handshake(pc.conn, time.Second, rp.nodeInfo())
+	if err != nil {
+		return nil, err
+	}
+	err = testUpgradeNoise(pc, rp.PrivKey, true, rp.nodeInfo(), peerNodeInfo)
 	if err != nil {
 		return nil, err
 	}
@@ -208,11 +215,16 @@ func (rp *remotePeer) accept() {
 			golog.Fatalf("Failed to create a peer: %+v", err)
 		}
 
-		_, err = handshake(pc.conn, time.Second, rp.nodeInfo())
+		peerNodeInfo, err := handshake(pc.conn, time.Second, rp.nodeInfo())
 		if err != nil {
 			golog.Fatalf("Failed to perform handshake: %+v", err)
 		}
 
+		err = testUpgradeNoise(pc, rp.PrivKey, false, rp.nodeInfo(), peerNodeInfo)
+		if err != nil {
+			golog.Fatalf("Failed to perform noise upgrade: %+v", err)
+		}
+
 		conns = append(conns, conn)
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"math"
 	"math/rand"
 	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -65,6 +66,8 @@ type AddrBook interface {
 	GetSelection() []*p2p.NetAddress
 	// Send a selection of addresses with bias
 	GetSelectionWithBias(biasTowardsNewAddrs int) []*p2p.NetAddress
+	// Send a selection of addresses weighted by quality with bias
+	GetSelectionByQuality(biasTowardsNewAddrs int) []*p2p.NetAddress
 
 	Size() int
 
@@ -178,12 +181,17 @@ func (a *addrBook) OurAddress(addr *p2p.NetAddress) bool {
 	return ok
 }
 
+// AddPrivateIDs implements AddrBook. The addresses already in the book with
+// the given IDs (e.g. learned before they were made private) are removed.
 func (a *addrBook) AddPrivateIDs(ids []string) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
 	for _, id := range ids {
 		a.privateIDs[p2p.ID(id)] = struct{}{}
+		if ka := a.addrLookup[p2p.ID(id)]; ka != nil {
+			a.removeFromAllBuckets(ka)
+		}
 	}
 }
 
@@ -385,6 +393,28 @@ func (a *addrBook) GetSelectionWithBias(biasTowardsNewAddrs int) []*p2p.NetAddre
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 
+	return a.selectionWithBias(biasTowardsNewAddrs, a.randomPickAddresses)
+}
+
+// GetSelectionByQuality implements AddrBook.
+// It selects as many old and new addresses as GetSelectionWithBias, but picks
+// them randomly with probabilities weighted by their quality (see
+// knownAddress.quality) instead of uniformly. Used by seeds to advertise the
+// addresses most likely to be reachable, while still spreading the others.
+// Must never return a nil address.
+func (a *addrBook) GetSelectionByQuality(biasTowardsNewAddrs int) []*p2p.NetAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	return a.selectionWithBias(biasTowardsNewAddrs, a.qualityWeightedPickAddresses)
+}
+
+// selectionWithBias picks the addresses of a selection from the new and old
+// buckets with pick, according to biasTowardsNewAddrs.
+func (a *addrBook) selectionWithBias(
+	biasTowardsNewAddrs int,
+	pick func(bucketType byte, num int) []*p2p.NetAddress,
+) []*p2p.NetAddress {
 	bookSize := a.size()
 	if bookSize <= 0 {
 		if bookSize < 0 {
@@ -408,8 +438,8 @@ func (a *addrBook) GetSelectionWithBias(biasTowardsNewAddrs int) []*p2p.NetAddre
 	// number of new addresses that, if possible, should be in the beginning of the selection
 	// if there are no enough old addrs, will choose new addr instead.
 	numRequiredNewAdd := cmn.MaxInt(percentageOfNum(biasTowardsNewAddrs, numAddresses), numAddresses-a.nOld)
-	selection := a.randomPickAddresses(bucketTypeNew, numRequiredNewAdd)
-	selection = append(selection, a.randomPickAddresses(bucketTypeOld, numAddresses-len(selection))...)
+	selection := pick(bucketTypeNew, numRequiredNewAdd)
+	selection = append(selection, pick(bucketTypeOld, numAddresses-len(selection))...)
 	return selection
 }
 
@@ -609,6 +639,9 @@ func (a *addrBook) addAddress(addr, src *p2p.NetAddress) error {
 
 	ka := a.addrLookup[addr.ID]
 	if ka != nil {
+		if ka.Addr.Equals(addr) {
+			ka.LastSeen = time.Now()
+		}
 		// If its already old and the addr is the same, ignore it.
 		if ka.isOld() && ka.Addr.Equals(addr) {
 			return nil
@@ -631,6 +664,34 @@ func (a *addrBook) addAddress(addr, src *p2p.NetAddress) error {
 	return nil
 }
 
+// importAddress adds the exported address to the book with its history and
+// hints, in an old bucket if it's known to be good. Returns false if the book
+// already has the address or it isn't valid.
+func (a *addrBook) importAddress(exported *knownAddress) bool {
+	if exported.Addr == nil || a.addrLookup[exported.ID()] != nil {
+		return false
+	}
+	src := exported.Src
+	if src == nil {
+		src = exported.Addr
+	}
+	if err := a.addAddress(exported.Addr, src); err != nil {
+		a.Logger.Info("Skipping imported address", "addr", exported.Addr, "err", err)
+		return false
+	}
+
+	ka := a.addrLookup[exported.ID()]
+	ka.Attempts = exported.Attempts
+	ka.LastAttempt = exported.LastAttempt
+	ka.LastSuccess = exported.LastSuccess
+	ka.LastSeen = exported.LastSeen
+	ka.Hints = exported.Hints
+	if exported.isOld() {
+		a.moveToOld(ka)
+	}
+	return true
+}
+
 func (a *addrBook) randomPickAddresses(bucketType byte, num int) []*p2p.NetAddress {
 	var buckets []map[string]*knownAddress
 	switch bucketType {
@@ -669,6 +730,42 @@ func (a *addrBook) randomPickAddresses(bucketType byte, num int) []*p2p.NetAddre
 	return selection
 }
 
+// qualityWeightedPickAddresses returns up to num addresses of the given
+// bucket type, sampled without replacement with probabilities weighted by
+// their quality, so that the best addresses are picked more often without
+// always sharing the same ones.
+func (a *addrBook) qualityWeightedPickAddresses(bucketType byte, num int) []*p2p.NetAddress {
+	if bucketType != bucketTypeNew && bucketType != bucketTypeOld {
+		panic("unexpected bucketType")
+	}
+	if num <= 0 {
+		return nil
+	}
+
+	// Efraimidis-Spirakis sampling: the addresses with the largest keys
+	// u^(1/quality), u being uniform in [0, 1). The quality is always > 0.
+	now := time.Now()
+	addresses := make([]*knownAddress, 0)
+	keys := make(map[*knownAddress]float64)
+	for _, ka := range a.addrLookup {
+		if ka.BucketType != bucketType {
+			continue
+		}
+		addresses = append(addresses, ka)
+		keys[ka] = math.Pow(a.rand.Float64(), 1/ka.quality(now))
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return keys[addresses[i]] > keys[addresses[j]]
+	})
+
+	num = cmn.MinInt(num, len(addresses))
+	selection := make([]*p2p.NetAddress, num)
+	for i := range selection {
+		selection[i] = addresses[i].Addr
+	}
+	return selection
+}
+
 // Make space in the new buckets by expiring the really bad entries.
 // If no bad entries are available we remove the oldest.
 func (a *addrBook) expireNew(bucketIdx int) {
@@ -768,10 +865,14 @@ func (a *addrBook) calcOldBucket(addr *p2p.NetAddress) int {
 }
 
 // Return a string representing the network group of this address.
-// This is the /16 for IPv4, the /32 (/36 for he.net) for IPv6, the string
+// This is the /16 for IPv4, the /32 (/36 for he.net) for IPv6, "onion:" and
+// the first character of the host for a Tor hidden service, the string
 // "local" for a local address and the string "unroutable" for an unroutable
 // address.
 func (a *addrBook) groupKey(na *p2p.NetAddress) string {
+	if na.IsOnion() {
+		return "onion:" + na.Onion[:1]
+	}
 	if a.routabilityStrict && na.Local() {
 		return "local"
 	}
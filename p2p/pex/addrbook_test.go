@@ -2,11 +2,13 @@ package pex
 
 import (
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -368,6 +370,77 @@ func TestAddrBookGetSelectionWithBias(t *testing.T) {
 	}
 }
 
+func TestKnownAddressQuality(t *testing.T) {
+	now := time.Now()
+	addr := randIPv4Address(t)
+	ka := newKnownAddress(addr, addr)
+	assert.InDelta(t, 0.1, ka.quality(now), 1e-9)
+
+	ka.Attempts = 1
+	assert.InDelta(t, 0.05, ka.quality(now), 1e-9)
+
+	ka.Attempts = 0
+	ka.BucketType = bucketTypeOld
+	ka.LastSuccess = now
+	assert.InDelta(t, 1.0, ka.quality(now), 1e-9)
+
+	// the weight of the last success halves after qualityHalfLifeHours
+	ka.LastSuccess = now.Add(-qualityHalfLifeHours * time.Hour)
+	assert.InDelta(t, 0.75, ka.quality(now), 1e-9)
+}
+
+func TestAddrBookGetSelectionByQuality(t *testing.T) {
+	fname := createTempFileName("addrbook_test")
+	defer deleteTempFile(fname)
+
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+
+	// 1) empty book
+	assert.Empty(t, book.GetSelectionByQuality(100))
+
+	// 2) half of the addresses failed to connect: they are picked less often
+	// than the others, but still picked
+	randAddrs := randNetAddressPairs(t, 100)
+	failed := make(map[string]bool)
+	for i, addrSrc := range randAddrs {
+		book.AddAddress(addrSrc.addr, addrSrc.src)
+		if i%2 == 0 {
+			book.MarkAttempt(addrSrc.addr)
+			failed[addrSrc.addr.String()] = true
+		}
+	}
+
+	var selection []*p2p.NetAddress
+	nFailed, nOthers := 0, 0
+	for i := 0; i < 100; i++ {
+		selection = book.GetSelectionByQuality(100)
+		require.NotEmpty(t, selection)
+		require.True(t, len(selection) < len(randAddrs))
+		seen := make(map[string]bool)
+		for _, addr := range selection {
+			require.False(t, seen[addr.String()], "selection contains an address twice")
+			seen[addr.String()] = true
+			if failed[addr.String()] {
+				nFailed++
+			} else {
+				nOthers++
+			}
+		}
+	}
+	assert.True(t, nFailed > 0, "no address which failed to connect was selected")
+	assert.True(t, nOthers > nFailed, "the addresses which failed to connect were selected %d times, the others %d times",
+		nFailed, nOthers)
+
+	// 3) without bias towards new addresses, all the old (good) addresses are selected
+	for _, addrSrc := range randAddrs[:10] {
+		book.MarkGood(addrSrc.addr.ID)
+	}
+	nOld, nNew := countOldAndNewAddrsInSelection(book.GetSelectionByQuality(0), book)
+	assert.Equal(t, 10, nOld)
+	assert.Equal(t, len(selection)-10, nNew)
+}
+
 func TestAddrBookHasAddress(t *testing.T) {
 	fname := createTempFileName("addrbook_test")
 	defer deleteTempFile(fname)
@@ -443,6 +516,12 @@ func TestPrivatePeers(t *testing.T) {
 		_, ok := err.(ErrAddrBookPrivateSrc)
 		assert.True(t, ok)
 	}
+
+	// addrs already in the book are removed when made private
+	addr := randIPv4Address(t)
+	require.NoError(t, book.AddAddress(addr, addr))
+	book.AddPrivateIDs([]string{string(addr.ID)})
+	assert.False(t, book.HasAddress(addr))
 }
 
 func testAddrBookAddressSelection(t *testing.T, bookSize int) {
@@ -545,6 +624,71 @@ func TestMultipleAddrBookAddressSelection(t *testing.T) {
 	}
 }
 
+func TestAddrBookExportImport(t *testing.T) {
+	fname := createTempFileName("addrbook_test")
+	defer deleteTempFile(fname)
+
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+	randAddrs := randNetAddressPairs(t, 10)
+	for _, addrSrc := range randAddrs {
+		require.NoError(t, book.AddAddress(addrSrc.addr, addrSrc.src))
+	}
+	good := randAddrs[0].addr
+	book.MarkGood(good.ID)
+	book.Save()
+
+	importName := fname + ".import"
+	defer os.Remove(importName)
+
+	// only the good address
+	export, err := ExportAddrBook(fname, 0, true)
+	require.NoError(t, err)
+	n, err := ImportAddrBook(importName, true, export)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// all the addresses, the good one being already imported
+	export, err = ExportAddrBook(fname, 0, false)
+	require.NoError(t, err)
+	n, err = ImportAddrBook(importName, true, export)
+	require.NoError(t, err)
+	assert.Equal(t, 9, n)
+
+	imported := NewAddrBook(importName, true)
+	imported.SetLogger(log.TestingLogger())
+	require.True(t, imported.loadFromFile(importName))
+	assert.Equal(t, 10, imported.Size())
+	assert.True(t, imported.IsGood(good))
+	assert.False(t, imported.IsGood(randAddrs[1].addr))
+}
+
+func TestAddrBookLoadVersion1(t *testing.T) {
+	fname := createTempFileName("addrbook_test")
+	defer deleteTempFile(fname)
+
+	lastAttempt := time.Now().Add(-2 * time.Hour)
+	lastSuccess := lastAttempt.Add(time.Hour)
+	addr := randIPv4Address(t)
+	ka := newKnownAddress(addr, addr)
+	ka.Buckets = []int{0}
+	ka.LastAttempt = lastAttempt
+	ka.LastSuccess = lastSuccess
+	ka.LastSeen = time.Time{}
+	bz, err := json.Marshal(map[string]interface{}{
+		"key":   "000000000000000000000000",
+		"addrs": []*knownAddress{ka},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(fname, bz, 0644))
+
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+	require.True(t, book.loadFromFile(fname))
+	require.Equal(t, 1, book.Size())
+	assert.True(t, book.addrLookup[addr.ID].LastSeen.Equal(lastSuccess))
+}
+
 func assertMOldAndNNewAddrsInSelection(t *testing.T, m, n int, addrs []*p2p.NetAddress, book *addrBook) {
 	nOld, nNew := countOldAndNewAddrsInSelection(addrs, book)
 	assert.Equal(t, m, nOld, "old addresses")
@@ -4,15 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
+
+	"github.com/pkg/errors"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
 )
 
 /* Loading & Saving */
 
+// addrBookVersion is the version of the format of the address book files.
+// Version 2 adds the last seen time, the score and the hints of the addresses.
+const addrBookVersion = 2
+
 type addrBookJSON struct {
-	Key   string          `json:"key"`
-	Addrs []*knownAddress `json:"addrs"`
+	Version int             `json:"version"` // 0 for the version 1 files
+	Key     string          `json:"key,omitempty"`
+	Addrs   []*knownAddress `json:"addrs"`
 }
 
 func (a *addrBook) saveToFile(filePath string) {
@@ -21,13 +29,16 @@ func (a *addrBook) saveToFile(filePath string) {
 
 	a.Logger.Info("Saving AddrBook to file", "size", a.size())
 
+	now := time.Now()
 	addrs := make([]*knownAddress, 0, len(a.addrLookup))
 	for _, ka := range a.addrLookup {
+		ka.Score = ka.quality(now)
 		addrs = append(addrs, ka)
 	}
 	aJSON := &addrBookJSON{
-		Key:   a.key,
-		Addrs: addrs,
+		Version: addrBookVersion,
+		Key:     a.key,
+		Addrs:   addrs,
 	}
 
 	jsonBytes, err := json.MarshalIndent(aJSON, "", "\t")
@@ -50,24 +61,24 @@ func (a *addrBook) loadFromFile(filePath string) bool {
 		return false
 	}
 
-	// Load addrBookJSON{}
-	r, err := os.Open(filePath)
-	if err != nil {
-		panic(fmt.Sprintf("Error opening file %s: %v", filePath, err))
-	}
-	defer r.Close() // nolint: errcheck
-	aJSON := &addrBookJSON{}
-	dec := json.NewDecoder(r)
-	err = dec.Decode(aJSON)
+	aJSON, err := readAddrBookFile(filePath)
 	if err != nil {
-		panic(fmt.Sprintf("Error reading file %s: %v", filePath, err))
+		panic(err.Error())
 	}
+	a.restore(aJSON)
+	return true
+}
 
+// restore restores the addresses of the file into the book.
+func (a *addrBook) restore(aJSON *addrBookJSON) {
 	// Restore all the fields...
 	// Restore the key
 	a.key = aJSON.Key
 	// Restore .bucketsNew & .bucketsOld
 	for _, ka := range aJSON.Addrs {
+		if _, ok := a.privateIDs[ka.ID()]; ok {
+			continue
+		}
 		for _, bucketIndex := range ka.Buckets {
 			bucket := a.getBucket(ka.BucketType, bucketIndex)
 			bucket[ka.Addr.String()] = ka
@@ -79,5 +90,96 @@ func (a *addrBook) loadFromFile(filePath string) bool {
 			a.nOld++
 		}
 	}
-	return true
+}
+
+// readAddrBookFile reads the address book file at filePath, and migrates it
+// to the latest version.
+func readAddrBookFile(filePath string) (*addrBookJSON, error) {
+	r, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file %s: %v", filePath, err)
+	}
+	defer r.Close() // nolint: errcheck
+	aJSON := &addrBookJSON{}
+	dec := json.NewDecoder(r)
+	err = dec.Decode(aJSON)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %v", filePath, err)
+	}
+
+	switch {
+	case aJSON.Version > addrBookVersion:
+		return nil, fmt.Errorf("unsupported version %d of file %s", aJSON.Version, filePath)
+	case aJSON.Version < 2:
+		// version 1 files have no last seen times
+		for _, ka := range aJSON.Addrs {
+			ka.LastSeen = ka.LastAttempt
+			if ka.LastSuccess.After(ka.LastSeen) {
+				ka.LastSeen = ka.LastSuccess
+			}
+		}
+	}
+	aJSON.Version = addrBookVersion
+	return aJSON, nil
+}
+
+/* Exporting & Importing */
+
+// ExportAddrBook returns the addresses of the address book file at filePath
+// with a score of at least minScore (only the ones known to be good, i.e. in
+// the old buckets, if onlyGood), to be imported into the address book of
+// another node with ImportAddrBook. The export is in the format of the file,
+// without the key and the buckets, which are specific to the book.
+func ExportAddrBook(filePath string, minScore float64, onlyGood bool) ([]byte, error) {
+	aJSON, err := readAddrBookFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	exported := &addrBookJSON{Version: addrBookVersion, Addrs: []*knownAddress{}}
+	for _, ka := range aJSON.Addrs {
+		ka.Score = ka.quality(now)
+		if ka.Score < minScore || (onlyGood && !ka.isOld()) {
+			continue
+		}
+		ka.Buckets = nil
+		exported.Addrs = append(exported.Addrs, ka)
+	}
+	return json.MarshalIndent(exported, "", "\t")
+}
+
+// ImportAddrBook adds the addresses exported with ExportAddrBook to the
+// address book file at filePath, which is created if it doesn't exist. The
+// addresses are placed in the buckets of the book, with their history and
+// hints: in the old buckets if they are known to be good. The addresses
+// already in the book are skipped. Returns the number of addresses added.
+//
+// NOTE the node using the address book must be stopped.
+func ImportAddrBook(filePath string, routabilityStrict bool, export []byte) (int, error) {
+	imported := &addrBookJSON{}
+	if err := json.Unmarshal(export, imported); err != nil {
+		return 0, errors.Wrap(err, "failed to decode the export")
+	}
+	if imported.Version != addrBookVersion {
+		return 0, fmt.Errorf("unsupported export version %d", imported.Version)
+	}
+
+	book := NewAddrBook(filePath, routabilityStrict)
+	if _, err := os.Stat(filePath); err == nil {
+		aJSON, err := readAddrBookFile(filePath)
+		if err != nil {
+			return 0, err
+		}
+		book.restore(aJSON)
+	}
+
+	n := 0
+	for _, ka := range imported.Addrs {
+		if book.importAddress(ka) {
+			n++
+		}
+	}
+	book.saveToFile(filePath)
+	return n, nil
 }
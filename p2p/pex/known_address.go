@@ -1,6 +1,7 @@
 package pex
 
 import (
+	"math"
 	"time"
 
 	"github.com/tendermint/tendermint/p2p"
@@ -16,14 +17,27 @@ type knownAddress struct {
 	BucketType  byte            `json:"bucket_type"`
 	LastAttempt time.Time       `json:"last_attempt"`
 	LastSuccess time.Time       `json:"last_success"`
+	LastSeen    time.Time       `json:"last_seen"` // last time a peer sent it or we connected to it
+	Score       float64         `json:"score"`     // quality when the book was saved, informational
+	Hints       *AddrHints      `json:"hints,omitempty"`
+}
+
+// AddrHints are hints about an address, set by operators in the imported
+// address books (see ImportAddrBook), to debug connectivity issues. They
+// aren't used to select the peers.
+type AddrHints struct {
+	Region  string        `json:"region,omitempty"`  // geographic region, e.g. "eu-west"
+	Latency time.Duration `json:"latency,omitempty"` // measured round trip time
 }
 
 func newKnownAddress(addr *p2p.NetAddress, src *p2p.NetAddress) *knownAddress {
+	now := time.Now()
 	return &knownAddress{
 		Addr:        addr,
 		Src:         src,
 		Attempts:    0,
-		LastAttempt: time.Now(),
+		LastAttempt: now,
+		LastSeen:    now,
 		BucketType:  bucketTypeNew,
 		Buckets:     nil,
 	}
@@ -52,6 +66,27 @@ func (ka *knownAddress) markGood() {
 	ka.LastAttempt = now
 	ka.Attempts = 0
 	ka.LastSuccess = now
+	ka.LastSeen = now
+}
+
+// quality returns a score of how likely the address is to be reachable,
+// based on its connection history. The score starts at 0.1 for an address we
+// know nothing about, adds 0.4 for an address in the old bucket and up to
+// 0.5 for a recent success, halving every qualityHalfLifeHours. The result is
+// divided by the number of attempts since the last success plus one.
+func (ka *knownAddress) quality(now time.Time) float64 {
+	score := 0.1
+	if ka.isOld() {
+		score += 0.4
+	}
+	if !ka.LastSuccess.IsZero() {
+		hours := now.Sub(ka.LastSuccess).Hours()
+		if hours < 0 {
+			hours = 0
+		}
+		score += 0.5 * math.Exp2(-hours/qualityHalfLifeHours)
+	}
+	return score / float64(1+ka.Attempts)
 }
 
 func (ka *knownAddress) addBucketRef(bucketIdx int) int {
@@ -52,4 +52,7 @@ const (
 	// max addresses returned by GetSelection
 	// NOTE: this must match "maxMsgSize"
 	maxGetSelection = 250
+
+	// hours after which the weight of the last success in the address quality is halved.
+	qualityHalfLifeHours = 24
 )
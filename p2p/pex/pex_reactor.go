@@ -81,6 +81,7 @@ type PEXReactor struct {
 	book              AddrBook
 	config            *PEXReactorConfig
 	ensurePeersPeriod time.Duration // TODO: should go in the config
+	ensurePeersCh     chan struct{} // to ensure peers before the next period
 
 	// maps to prevent abuse
 	requestsSent         *cmn.CMap // ID->struct{}: unanswered send requests
@@ -126,6 +127,7 @@ func NewPEXReactor(b AddrBook, config *PEXReactorConfig) *PEXReactor {
 		book:                 b,
 		config:               config,
 		ensurePeersPeriod:    defaultEnsurePeersPeriod,
+		ensurePeersCh:        make(chan struct{}, 1),
 		requestsSent:         cmn.NewCMap(),
 		lastReceivedRequests: cmn.NewCMap(),
 		crawlPeerInfos:       make(map[p2p.ID]crawlPeerInfo),
@@ -153,9 +155,9 @@ func (r *PEXReactor) OnStart() error {
 	// Check if this node should run
 	// in seed/crawler mode
 	if r.config.SeedMode {
-		go r.crawlPeersRoutine()
+		r.Go("crawlPeersRoutine", func() { r.crawlPeersRoutine() })
 	} else {
-		go r.ensurePeersRoutine()
+		r.Go("ensurePeersRoutine", func() { r.ensurePeersRoutine() })
 	}
 	return nil
 }
@@ -197,6 +199,11 @@ func (r *PEXReactor) AddPeer(p Peer) {
 		// Make it explicit that addr and src are the same for an inbound peer.
 		src := addr
 
+		// private peers are never gossiped
+		if r.Switch != nil && r.Switch.IsPeerPrivate(addr.ID) {
+			return
+		}
+
 		// add to book. dont RequestAddrs right away because
 		// we don't trust inbound as much - let ensurePeersRoutine handle it.
 		err = r.book.AddAddress(addr, src)
@@ -253,8 +260,8 @@ func (r *PEXReactor) Receive(chID byte, src Peer, msgBytes []byte) {
 			}
 			r.lastReceivedRequests.Set(id, time.Now())
 
-			// Send addrs and disconnect
-			r.SendAddrs(src, r.book.GetSelectionWithBias(biasToSelectNewPeers))
+			// Send the best addrs we know of and disconnect
+			r.SendAddrs(src, r.book.GetSelectionByQuality(biasToSelectNewPeers))
 			go func() {
 				// In a go-routine so it doesn't block .Receive.
 				src.FlushStop()
@@ -381,8 +388,18 @@ func (r *PEXReactor) ReceiveAddrs(addrs []*p2p.NetAddress, src Peer) error {
 	return nil
 }
 
-// SendAddrs sends addrs to the peer.
+// SendAddrs sends addrs to the peer, except the ones of private peers (see
+// p2p.Switch#AddPrivatePeerIDs).
 func (r *PEXReactor) SendAddrs(p Peer, netAddrs []*p2p.NetAddress) {
+	if r.Switch != nil {
+		public := make([]*p2p.NetAddress, 0, len(netAddrs))
+		for _, addr := range netAddrs {
+			if !r.Switch.IsPeerPrivate(addr.ID) {
+				public = append(public, addr)
+			}
+		}
+		netAddrs = public
+	}
 	p.Send(PexChannel, cdc.MustMarshalBinaryBare(&pexAddrsMessage{Addrs: netAddrs}))
 }
 
@@ -391,6 +408,16 @@ func (r *PEXReactor) SetEnsurePeersPeriod(d time.Duration) {
 	r.ensurePeersPeriod = d
 }
 
+// EnsurePeers makes the reactor dial new peers (and ask for their addresses if
+// needed) right away, rather than at the next ensure peers period. It's meant
+// for other reactors after they disconnected unhelpful peers.
+func (r *PEXReactor) EnsurePeers() {
+	select {
+	case r.ensurePeersCh <- struct{}{}:
+	default:
+	}
+}
+
 // Ensures that sufficient peers are connected. (continuous)
 func (r *PEXReactor) ensurePeersRoutine() {
 	var (
@@ -415,6 +442,8 @@ func (r *PEXReactor) ensurePeersRoutine() {
 		select {
 		case <-ticker.C:
 			r.ensurePeers()
+		case <-r.ensurePeersCh:
+			r.ensurePeers()
 		case <-r.Quit():
 			ticker.Stop()
 			return
@@ -485,6 +485,33 @@ func TestPEXReactorDoesNotAddPrivatePeersToAddrBook(t *testing.T) {
 	assert.Equal(t, size, book.Size())
 }
 
+// recordingPeer records the messages sent to it.
+type recordingPeer struct {
+	*mock.Peer
+	sent [][]byte
+}
+
+func (rp *recordingPeer) Send(chID byte, msgBytes []byte) bool {
+	rp.sent = append(rp.sent, msgBytes)
+	return true
+}
+
+func TestPEXReactorDoesNotGossipPrivatePeers(t *testing.T) {
+	pexR, book := createReactor(&PEXReactorConfig{})
+	defer teardownReactor(book)
+	sw := createSwitchAndAddReactors(pexR)
+
+	private, public := mock.NewPeer(nil), mock.NewPeer(nil)
+	require.NoError(t, sw.AddPrivatePeerIDs([]string{string(private.ID())}))
+
+	peer := &recordingPeer{Peer: mock.NewPeer(nil)}
+	pexR.SendAddrs(peer, []*p2p.NetAddress{private.SocketAddr(), public.SocketAddr()})
+	require.Len(t, peer.sent, 1)
+	msg, err := decodeMsg(peer.sent[0])
+	require.NoError(t, err)
+	assert.Equal(t, []*p2p.NetAddress{public.SocketAddr()}, msg.(*pexAddrsMessage).Addrs)
+}
+
 func TestPEXReactorDialPeer(t *testing.T) {
 	pexR, book := createReactor(&PEXReactorConfig{})
 	defer teardownReactor(book)
@@ -0,0 +1,36 @@
+package p2p
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+// NewProxyDialer returns a dialer connecting through the SOCKS5 proxy at
+// proxyAddr: socks5://[user:password@]host:port. The proxy resolves the host
+// names, including the .onion ones of Tor hidden services.
+func NewProxyDialer(proxyAddr string) (proxy.ContextDialer, error) {
+	u, err := url.Parse(proxyAddr)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid proxy address")
+	}
+	if u.Scheme != "socks5" || u.Host == "" {
+		return nil, errors.Errorf("invalid proxy address %s: expected socks5://host:port", proxyAddr)
+	}
+
+	var auth *proxy.Auth
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = &proxy.Auth{User: u.User.Username(), Password: password}
+	}
+	dialer, err := proxy.SOCKS5("tcp", u.Host, auth, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, errors.New("the SOCKS5 dialer doesn't support contexts")
+	}
+	return contextDialer, nil
+}
@@ -79,19 +79,44 @@ type Switch struct {
 	addrBook     AddrBook
 	// peers addresses with whom we'll maintain constant connection
 	persistentPeersAddrs []*NetAddress
+	// see AddUnconditionalPeerIDs and AddPrivatePeerIDs
+	unconditionalPeerIDs map[ID]struct{}
+	privatePeerIDs       map[ID]struct{}
 
 	transport Transport
 
 	filterTimeout time.Duration
 	peerFilters   []PeerFilterFunc
 
+	peerLabels PeerLabels // human readable names of peers
+
+	// number of times each peer was marked as good, to pick the peers to
+	// evict when running out of resources (see evictPeer)
+	goodMarksMtx sync.Mutex
+	goodMarks    map[ID]int
+
 	rng *cmn.Rand // seed for randomizing dial times and orders
 
 	metrics *Metrics
+
+	// the namespaces of the other networks (see AddNamespace), and the
+	// reactor of their channels
+	namespaces []*Switch
+	nsRouter   *namespaceRouter
+
+	// set on a namespace: the switch whose connections it shares, the network,
+	// and its index in the Networks of our node info
+	parent  *Switch
+	network string
+	nsIndex int
+	nsMtx   sync.Mutex // for adding and removing the peers of the parent
 }
 
 // NetAddress returns the address the switch is listening on.
 func (sw *Switch) NetAddress() *NetAddress {
+	if sw.parent != nil {
+		return sw.parent.NetAddress()
+	}
 	addr := sw.transport.NetAddress()
 	return &addr
 }
@@ -117,6 +142,9 @@ func NewSwitch(
 		transport:            transport,
 		filterTimeout:        defaultFilterTimeout,
 		persistentPeersAddrs: make([]*NetAddress, 0),
+		unconditionalPeerIDs: make(map[ID]struct{}),
+		privatePeerIDs:       make(map[ID]struct{}),
+		goodMarks:            make(map[ID]int),
 	}
 
 	// Ensure we have a completely undeterministic PRNG.
@@ -146,6 +174,12 @@ func WithMetrics(metrics *Metrics) SwitchOption {
 	return func(sw *Switch) { sw.metrics = metrics }
 }
 
+// SwitchPeerLabels sets the human readable names of peers, attached to their
+// logs and metrics.
+func SwitchPeerLabels(labels PeerLabels) SwitchOption {
+	return func(sw *Switch) { sw.peerLabels = labels }
+}
+
 //---------------------------------------------------------------------
 // Switch setup
 
@@ -153,6 +187,9 @@ func WithMetrics(metrics *Metrics) SwitchOption {
 // NOTE: Not goroutine safe.
 func (sw *Switch) AddReactor(name string, reactor Reactor) Reactor {
 	for _, chDesc := range reactor.GetChannels() {
+		if sw.parent != nil {
+			sw.parent.addNamespacedChannel(chDesc)
+		}
 		chID := chDesc.ID
 		// No two reactors can share the same channel.
 		if sw.reactorsByCh[chID] != nil {
@@ -201,9 +238,13 @@ func (sw *Switch) SetNodeInfo(nodeInfo NodeInfo) {
 	sw.nodeInfo = nodeInfo
 }
 
-// NodeInfo returns the switch's NodeInfo.
+// NodeInfo returns the switch's NodeInfo, or the one of the parent for a
+// namespace.
 // NOTE: Not goroutine safe.
 func (sw *Switch) NodeInfo() NodeInfo {
+	if sw.parent != nil {
+		return sw.parent.NodeInfo()
+	}
 	return sw.nodeInfo
 }
 
@@ -218,6 +259,10 @@ func (sw *Switch) SetNodeKey(nodeKey *NodeKey) {
 
 // OnStart implements BaseService. It starts all the reactors and peers.
 func (sw *Switch) OnStart() error {
+	if sw.parent != nil {
+		return sw.startNamespace()
+	}
+
 	// Start reactors
 	for _, reactor := range sw.reactors {
 		err := reactor.Start()
@@ -234,6 +279,11 @@ func (sw *Switch) OnStart() error {
 
 // OnStop implements BaseService. It stops all peers and reactors.
 func (sw *Switch) OnStop() {
+	if sw.parent != nil {
+		sw.stopNamespace()
+		return
+	}
+
 	// Stop peers
 	for _, p := range sw.peers.List() {
 		sw.stopAndRemovePeer(p, nil)
@@ -279,6 +329,12 @@ func (sw *Switch) Broadcast(chID byte, msgBytes []byte) chan bool {
 	return successChan
 }
 
+// PeerLabel returns the human readable name of the peer with the given ID,
+// or "" if it has none.
+func (sw *Switch) PeerLabel(id ID) string {
+	return sw.peerLabels.Label(id)
+}
+
 // NumPeers returns the count of outbound/inbound and outbound-dialing peers.
 func (sw *Switch) NumPeers() (outbound, inbound, dialing int) {
 	peers := sw.peers.List()
@@ -303,10 +359,28 @@ func (sw *Switch) Peers() IPeerSet {
 	return sw.peers
 }
 
+// SetRates changes the send and receive rate limits of the connections of
+// the connected peers, in bytes per second. The limits of the next peers are
+// set by the transport.
+func (sw *Switch) SetRates(sendRate, recvRate int64) {
+	for _, p := range sw.peers.List() {
+		if p, ok := p.(*peer); ok {
+			p.mconn.SetRates(sendRate, recvRate)
+		}
+	}
+}
+
 // StopPeerForError disconnects from a peer due to external error.
 // If the peer is persistent, it will attempt to reconnect.
 // TODO: make record depending on reason.
 func (sw *Switch) StopPeerForError(peer Peer, reason interface{}) {
+	if sw.parent != nil {
+		if p := sw.parentPeer(peer); p != nil {
+			sw.parent.StopPeerForError(p, reason)
+		}
+		return
+	}
+
 	sw.Logger.Error("Stopping peer for error", "peer", peer, "err", reason)
 	sw.stopAndRemovePeer(peer, reason)
 
@@ -330,6 +404,13 @@ func (sw *Switch) StopPeerForError(peer Peer, reason interface{}) {
 // StopPeerGracefully disconnects from a peer gracefully.
 // TODO: handle graceful disconnects.
 func (sw *Switch) StopPeerGracefully(peer Peer) {
+	if sw.parent != nil {
+		if p := sw.parentPeer(peer); p != nil {
+			sw.parent.StopPeerGracefully(p)
+		}
+		return
+	}
+
 	sw.Logger.Info("Stopping peer gracefully")
 	sw.stopAndRemovePeer(peer, nil)
 }
@@ -341,6 +422,9 @@ func (sw *Switch) stopAndRemovePeer(peer Peer, reason interface{}) {
 	for _, reactor := range sw.reactors {
 		reactor.RemovePeer(peer, reason)
 	}
+	for _, ns := range sw.namespaces {
+		ns.removeNamespacedPeer(peer, reason)
+	}
 
 	// Removing a peer should go last to avoid a situation where a peer
 	// reconnect to our node and the switch calls InitPeer before
@@ -349,6 +433,10 @@ func (sw *Switch) stopAndRemovePeer(peer Peer, reason interface{}) {
 	if sw.peers.Remove(peer) {
 		sw.metrics.Peers.Add(float64(-1))
 	}
+
+	sw.goodMarksMtx.Lock()
+	delete(sw.goodMarks, peer.ID())
+	sw.goodMarksMtx.Unlock()
 }
 
 // reconnectToPeer tries to reconnect to the addr, first repeatedly
@@ -414,9 +502,22 @@ func (sw *Switch) SetAddrBook(addrBook AddrBook) {
 }
 
 // MarkPeerAsGood marks the given peer as good when it did something useful
-// like contributed to consensus.
+// like contributed to consensus. Private peers are kept out of the address
+// book. The peers marked the most are the last ones evicted when running out
+// of resources.
 func (sw *Switch) MarkPeerAsGood(peer Peer) {
-	if sw.addrBook != nil {
+	if sw.parent != nil {
+		sw.parent.MarkPeerAsGood(peer)
+		return
+	}
+
+	sw.goodMarksMtx.Lock()
+	if sw.peers.Has(peer.ID()) {
+		sw.goodMarks[peer.ID()]++
+	}
+	sw.goodMarksMtx.Unlock()
+
+	if sw.addrBook != nil && !sw.IsPeerPrivate(peer.ID()) {
 		sw.addrBook.MarkGood(peer.ID())
 	}
 }
@@ -558,6 +659,50 @@ func (sw *Switch) AddPersistentPeers(addrs []string) error {
 	return nil
 }
 
+// AddUnconditionalPeerIDs makes the switch accept the peers with the given
+// IDs even when it already has the maximum number of inbound peers, e.g. the
+// validator and the other sentries of a sentry node. It must be called
+// before the switch is started.
+func (sw *Switch) AddUnconditionalPeerIDs(ids []string) error {
+	sw.Logger.Info("Adding unconditional peer ids", "ids", ids)
+	for i, id := range ids {
+		if err := validateID(ID(id)); err != nil {
+			return fmt.Errorf("wrong unconditional peer ID #%d (%q): %v", i, id, err)
+		}
+		sw.unconditionalPeerIDs[ID(id)] = struct{}{}
+	}
+	return nil
+}
+
+// IsPeerUnconditional returns true if the peer with the given ID bypasses the
+// maximum number of peers (see AddUnconditionalPeerIDs).
+func (sw *Switch) IsPeerUnconditional(id ID) bool {
+	_, ok := sw.unconditionalPeerIDs[id]
+	return ok
+}
+
+// AddPrivatePeerIDs makes the peers with the given IDs private: their
+// addresses are never added to the address book nor gossiped by the PEX
+// reactor, e.g. the validator behind a sentry node. It must be called before
+// the switch is started.
+func (sw *Switch) AddPrivatePeerIDs(ids []string) error {
+	sw.Logger.Info("Adding private peer ids", "ids", ids)
+	for i, id := range ids {
+		if err := validateID(ID(id)); err != nil {
+			return fmt.Errorf("wrong private peer ID #%d (%q): %v", i, id, err)
+		}
+		sw.privatePeerIDs[ID(id)] = struct{}{}
+	}
+	return nil
+}
+
+// IsPeerPrivate returns true if the address of the peer with the given ID
+// must not be gossiped (see AddPrivatePeerIDs).
+func (sw *Switch) IsPeerPrivate(id ID) bool {
+	_, ok := sw.privatePeerIDs[id]
+	return ok
+}
+
 func (sw *Switch) isPeerPersistentFn() func(*NetAddress) bool {
 	return func(na *NetAddress) bool {
 		for _, pa := range sw.persistentPeersAddrs {
@@ -576,6 +721,7 @@ func (sw *Switch) acceptRoutine() {
 			onPeerError:  sw.StopPeerForError,
 			reactorsByCh: sw.reactorsByCh,
 			metrics:      sw.metrics,
+			labels:       sw.peerLabels,
 			isPersistent: sw.isPeerPersistentFn(),
 		})
 		if err != nil {
@@ -602,6 +748,18 @@ func (sw *Switch) acceptRoutine() {
 					"err", err,
 				)
 
+				continue
+			case ErrResourceExhausted:
+				// Rather than failing the next accepts until some peer
+				// disconnects, make room for them by evicting the least
+				// useful peer.
+				sw.Logger.Error(
+					"Can't accept connections",
+					"err", err,
+					"numPeers", sw.peers.Size(),
+				)
+				sw.evictPeer(err)
+
 				continue
 			case ErrTransportClosed:
 				sw.Logger.Error(
@@ -625,19 +783,22 @@ func (sw *Switch) acceptRoutine() {
 			break
 		}
 
-		// Ignore connection if we already have enough peers.
-		_, in, _ := sw.NumPeers()
-		if in >= sw.config.MaxNumInboundPeers {
-			sw.Logger.Info(
-				"Ignoring inbound connection: already have enough inbound peers",
-				"address", p.SocketAddr(),
-				"have", in,
-				"max", sw.config.MaxNumInboundPeers,
-			)
+		// Ignore connection if we already have enough peers, unless the
+		// peer is unconditional.
+		if !sw.IsPeerUnconditional(p.ID()) {
+			_, in, _ := sw.NumPeers()
+			if in >= sw.config.MaxNumInboundPeers {
+				sw.Logger.Info(
+					"Ignoring inbound connection: already have enough inbound peers",
+					"address", p.SocketAddr(),
+					"have", in,
+					"max", sw.config.MaxNumInboundPeers,
+				)
 
-			sw.transport.Cleanup(p)
+				sw.transport.Cleanup(p)
 
-			continue
+				continue
+			}
 		}
 
 		if err := sw.addPeer(p); err != nil {
@@ -677,6 +838,7 @@ func (sw *Switch) addOutboundPeerWithConfig(
 		isPersistent: sw.isPeerPersistentFn(),
 		reactorsByCh: sw.reactorsByCh,
 		metrics:      sw.metrics,
+		labels:       sw.peerLabels,
 	})
 	if err != nil {
 		if e, ok := err.(ErrRejected); ok {
@@ -693,6 +855,10 @@ func (sw *Switch) addOutboundPeerWithConfig(
 		// retry persistent peers after
 		// any dial error besides IsSelf()
 		if sw.isPeerPersistentFn()(addr) {
+			// make room for them if we ran out of resources
+			if isResourceExhausted(err) {
+				sw.evictPeer(ErrResourceExhausted{err})
+			}
 			go sw.reconnectToPeer(addr)
 		}
 
@@ -745,7 +911,11 @@ func (sw *Switch) addPeer(p Peer) error {
 		return err
 	}
 
-	p.SetLogger(sw.Logger.With("peer", p.SocketAddr()))
+	logger := sw.Logger.With("peer", p.SocketAddr())
+	if label := sw.PeerLabel(p.ID()); label != "" {
+		logger = logger.With("peer_label", label)
+	}
+	p.SetLogger(logger)
 
 	// Handle the shut down case where the switch has stopped but we're
 	// concurrently trying to add a peer.
@@ -782,6 +952,9 @@ func (sw *Switch) addPeer(p Peer) error {
 	for _, reactor := range sw.reactors {
 		reactor.AddPeer(p)
 	}
+	for _, ns := range sw.namespaces {
+		ns.addNamespacedPeer(p)
+	}
 
 	sw.Logger.Info("Added peer", "peer", p)
 
@@ -0,0 +1,330 @@
+package p2p
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+// namespacedChannelFlag is set on the IDs of the channels of the namespaces,
+// which multiplex the channels of the same ID below it. The channels of the
+// reactors of the namespaces must be below it.
+const namespacedChannelFlag = byte(0x80)
+
+// AddNamespace returns the switch of the reactors of another network (chain
+// ID), run by the same node, whose messages are multiplexed on the
+// connections of sw: they are sent on the namespaced channels of their
+// channels (the ID with the 0x80 bit set), prefixed with the index of the
+// network in the Networks of the node info of the sender.
+//
+// The reactors of the network only get the peers running it too, i.e. with
+// the network in the Networks of their node info: the network must be added
+// to the ones of sw (see DefaultNodeInfoOther), and the reactors added to the
+// namespace, before sw is started. The namespace is started and stopped
+// independently of sw, e.g. by the node of the network.
+//
+// The namespace shares the peers of sw: stopping a peer for an error in the
+// namespace disconnects it from all the networks.
+// NOTE: Not goroutine safe.
+func (sw *Switch) AddNamespace(network string) (*Switch, error) {
+	if sw.parent != nil {
+		return nil, errors.New("can't add a namespace to a namespace")
+	}
+	if sw.IsRunning() {
+		return nil, errors.New("can't add a namespace to a running switch")
+	}
+	if info, ok := sw.nodeInfo.(DefaultNodeInfo); ok && info.Network == network {
+		return nil, fmt.Errorf("namespace %v is the network of the switch", network)
+	}
+	if sw.namespace(network) != nil {
+		return nil, fmt.Errorf("duplicate namespace %v", network)
+	}
+	if len(sw.namespaces) >= maxNumNetworks {
+		return nil, fmt.Errorf("too many namespaces (max %v)", maxNumNetworks)
+	}
+
+	ns := NewSwitch(sw.config, nil)
+	ns.parent = sw
+	ns.network = network
+	ns.SetLogger(sw.Logger.With("network", network))
+
+	if sw.nsRouter == nil {
+		sw.nsRouter = &namespaceRouter{sw: sw}
+		sw.nsRouter.BaseReactor = *NewBaseReactor("NamespaceRouter", sw.nsRouter)
+	}
+	sw.namespaces = append(sw.namespaces, ns)
+	return ns, nil
+}
+
+// Network returns the network of the namespace, or "" if sw isn't a
+// namespace (see AddNamespace).
+func (sw *Switch) Network() string {
+	return sw.network
+}
+
+// addNamespacedChannel adds the namespaced channel of the channel of a
+// reactor of a namespace, if the channel of the same ID of another namespace
+// didn't already.
+func (sw *Switch) addNamespacedChannel(chDesc *conn.ChannelDescriptor) {
+	if chDesc.ID&namespacedChannelFlag != 0 {
+		panic(fmt.Sprintf("Channel %X of a namespace must be below %X", chDesc.ID, namespacedChannelFlag))
+	}
+	nsDesc := *chDesc
+	nsDesc.ID |= namespacedChannelFlag
+	if nsDesc.RecvMessageCapacity > 0 {
+		nsDesc.RecvMessageCapacity += binary.MaxVarintLen64
+	}
+
+	for _, desc := range sw.chDescs {
+		if desc.ID != nsDesc.ID {
+			continue
+		}
+		// shared with another namespace, fit the messages of both
+		if desc.RecvMessageCapacity > 0 &&
+			(nsDesc.RecvMessageCapacity == 0 || nsDesc.RecvMessageCapacity > desc.RecvMessageCapacity) {
+			desc.RecvMessageCapacity = nsDesc.RecvMessageCapacity
+		}
+		return
+	}
+	if sw.reactorsByCh[nsDesc.ID] != nil {
+		panic(fmt.Sprintf("Channel %X has multiple reactors %v & %v", nsDesc.ID, sw.reactorsByCh[nsDesc.ID], sw.nsRouter))
+	}
+	sw.chDescs = append(sw.chDescs, &nsDesc)
+	sw.reactorsByCh[nsDesc.ID] = sw.nsRouter
+}
+
+// startNamespace starts the reactors of the namespace, and adds the peers of
+// the parent running the network.
+func (sw *Switch) startNamespace() error {
+	sw.nsMtx.Lock()
+	defer sw.nsMtx.Unlock()
+
+	info, ok := sw.parent.NodeInfo().(DefaultNodeInfo)
+	if !ok {
+		return fmt.Errorf("expected DefaultNodeInfo, got %T", sw.parent.NodeInfo())
+	}
+	sw.nsIndex = indexOf(info.Other.Networks, sw.network)
+	if sw.nsIndex < 0 {
+		return fmt.Errorf("network %v is missing from the node info", sw.network)
+	}
+
+	for _, reactor := range sw.reactors {
+		if err := reactor.Start(); err != nil {
+			return errors.Wrapf(err, "failed to start %v", reactor)
+		}
+	}
+	for _, p := range sw.parent.peers.List() {
+		sw.addNamespacedPeerLocked(p)
+	}
+	return nil
+}
+
+// stopNamespace removes the peers of the namespace, without stopping them,
+// and stops its reactors.
+func (sw *Switch) stopNamespace() {
+	sw.nsMtx.Lock()
+	defer sw.nsMtx.Unlock()
+
+	for _, p := range sw.peers.List() {
+		sw.removeNamespacedPeerLocked(p, nil)
+	}
+	for _, reactor := range sw.reactors {
+		reactor.Stop() // nolint: errcheck
+	}
+}
+
+// addNamespacedPeer adds the peer of the parent to the namespace, if it runs
+// the network.
+func (sw *Switch) addNamespacedPeer(p Peer) {
+	sw.nsMtx.Lock()
+	defer sw.nsMtx.Unlock()
+	sw.addNamespacedPeerLocked(p)
+}
+
+func (sw *Switch) addNamespacedPeerLocked(p Peer) {
+	// the peer is stopped before it is removed from the namespaces
+	if !sw.IsRunning() || !p.IsRunning() || sw.peers.Has(p.ID()) ||
+		indexOf(peerNetworks(p), sw.network) < 0 {
+		return
+	}
+
+	var np Peer = &namespacedPeer{
+		Peer:  p,
+		index: sw.nsIndex,
+		data:  cmn.NewCMap(),
+	}
+	for _, reactor := range sw.reactors {
+		np = reactor.InitPeer(np)
+	}
+	if err := sw.peers.Add(np); err != nil {
+		sw.Logger.Error("Failed to add peer", "peer", p, "err", err)
+		return
+	}
+	for _, reactor := range sw.reactors {
+		reactor.AddPeer(np)
+	}
+	sw.Logger.Info("Added peer", "peer", p)
+}
+
+// removeNamespacedPeer removes the peer of the parent from the namespace.
+func (sw *Switch) removeNamespacedPeer(p Peer, reason interface{}) {
+	sw.nsMtx.Lock()
+	defer sw.nsMtx.Unlock()
+	sw.removeNamespacedPeerLocked(p, reason)
+}
+
+func (sw *Switch) removeNamespacedPeerLocked(p Peer, reason interface{}) {
+	np := sw.peers.Get(p.ID())
+	if np == nil {
+		return
+	}
+	for _, reactor := range sw.reactors {
+		reactor.RemovePeer(np, reason)
+	}
+	sw.peers.Remove(np)
+}
+
+// parentPeer returns the peer of the parent of the peer of the namespace, or
+// nil if it was removed.
+func (sw *Switch) parentPeer(p Peer) Peer {
+	return sw.parent.peers.Get(p.ID())
+}
+
+// namespace returns the namespace of the network, or nil if there is none.
+func (sw *Switch) namespace(network string) *Switch {
+	for _, ns := range sw.namespaces {
+		if ns.network == network {
+			return ns
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// namespaceRouter is the reactor of the namespaced channels of the parent
+// switch, passing the messages to the reactors of their namespaces.
+type namespaceRouter struct {
+	BaseReactor
+
+	sw *Switch
+}
+
+// Receive implements Reactor.
+func (r *namespaceRouter) Receive(chID byte, src Peer, msgBytes []byte) {
+	index, n := binary.Uvarint(msgBytes)
+	networks := peerNetworks(src)
+	if n <= 0 || index >= uint64(len(networks)) {
+		r.sw.StopPeerForError(src, fmt.Errorf("message of an unknown network on channel %X", chID))
+		return
+	}
+	ns, np := r.namespacedPeer(networks[index], src)
+	if np == nil {
+		// we don't run the network, or stopped
+		r.Logger.Debug("Ignoring message of another network", "network", networks[index], "peer", src)
+		return
+	}
+
+	nativeID := chID &^ namespacedChannelFlag
+	reactor := ns.reactorsByCh[nativeID]
+	if reactor == nil {
+		r.sw.StopPeerForError(src, fmt.Errorf("unknown channel %X of network %v", nativeID, ns.network))
+		return
+	}
+	reactor.Receive(nativeID, np, msgBytes[n:])
+}
+
+// ChannelCongestion implements CongestionAwareReactor, by passing the
+// congestion of the namespaced channel to the reactors of the channel in all
+// the namespaces the peer runs.
+func (r *namespaceRouter) ChannelCongestion(chID byte, p Peer, congested bool) {
+	nativeID := chID &^ namespacedChannelFlag
+	for _, network := range peerNetworks(p) {
+		ns, np := r.namespacedPeer(network, p)
+		if np == nil {
+			continue
+		}
+		if reactor, ok := ns.reactorsByCh[nativeID].(CongestionAwareReactor); ok {
+			reactor.ChannelCongestion(nativeID, np, congested)
+		}
+	}
+}
+
+func (r *namespaceRouter) namespacedPeer(network string, p Peer) (*Switch, Peer) {
+	ns := r.sw.namespace(network)
+	if ns == nil {
+		return nil, nil
+	}
+	return ns, ns.peers.Get(p.ID())
+}
+
+//-----------------------------------------------------------------------------
+
+// namespacedPeer is a peer of the parent switch in a namespace: it sends the
+// messages on the namespaced channels, and has its own data.
+type namespacedPeer struct {
+	Peer
+
+	index int // of the network in the Networks of our node info
+	data  *cmn.CMap
+}
+
+func (p *namespacedPeer) Send(chID byte, msgBytes []byte) bool {
+	return p.Peer.Send(chID|namespacedChannelFlag, p.namespaced(msgBytes))
+}
+
+func (p *namespacedPeer) TrySend(chID byte, msgBytes []byte) bool {
+	return p.Peer.TrySend(chID|namespacedChannelFlag, p.namespaced(msgBytes))
+}
+
+func (p *namespacedPeer) Get(key string) interface{} {
+	return p.data.Get(key)
+}
+
+func (p *namespacedPeer) Set(key string, data interface{}) {
+	p.data.Set(key, data)
+}
+
+func (p *namespacedPeer) namespaced(msgBytes []byte) []byte {
+	bz := make([]byte, binary.MaxVarintLen64+len(msgBytes))
+	n := binary.PutUvarint(bz, uint64(p.index))
+	return append(bz[:n], msgBytes...)
+}
+
+//-----------------------------------------------------------------------------
+
+// peerNetworks returns the other networks run by the peer.
+func peerNetworks(p Peer) []string {
+	if info, ok := p.NodeInfo().(DefaultNodeInfo); ok {
+		return info.Other.Networks
+	}
+	return nil
+}
+
+// peerChannels returns the channels the peer knows about: the channels of its
+// node info, and their namespaced channels if it runs other networks.
+func peerChannels(info DefaultNodeInfo) []byte {
+	if len(info.Other.Networks) == 0 {
+		return info.Channels
+	}
+	channels := append([]byte{}, info.Channels...)
+	for _, ch := range info.Channels {
+		if ch&namespacedChannelFlag == 0 {
+			channels = append(channels, ch|namespacedChannelFlag)
+		}
+	}
+	return channels
+}
+
+func indexOf(networks []string, network string) int {
+	for i, n := range networks {
+		if n == network {
+			return i
+		}
+	}
+	return -1
+}
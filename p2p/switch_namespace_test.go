@@ -0,0 +1,158 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+// makeNamespacedSwitch returns a switch of the reactors of initSwitchFunc,
+// with a namespace of each network with a TestReactor of the channels 0x00
+// and 0x01, the same as the ones of the "foo" reactor of the switch.
+func makeNamespacedSwitch(t *testing.T, i int, networks ...string) (*Switch, map[string]*Switch) {
+	namespaces := make(map[string]*Switch)
+	sw := MakeSwitch(cfg, i, "127.0.0.1", "123.123.123", func(i int, sw *Switch) *Switch {
+		sw = initSwitchFunc(i, sw)
+		for _, network := range networks {
+			ns, err := sw.AddNamespace(network)
+			require.NoError(t, err)
+			ns.AddReactor("foo", NewTestReactor([]*conn.ChannelDescriptor{
+				{ID: byte(0x00), Priority: 10},
+				{ID: byte(0x01), Priority: 10},
+			}, true))
+			namespaces[network] = ns
+		}
+		return sw
+	})
+
+	ni := sw.NodeInfo().(DefaultNodeInfo)
+	ni.Other.Networks = networks
+	sw.SetNodeInfo(ni)
+	sw.transport.(*MultiplexTransport).nodeInfo = ni
+	return sw, namespaces
+}
+
+func startNamespacedSwitch(t *testing.T, sw *Switch, namespaces map[string]*Switch) {
+	require.NoError(t, sw.Start())
+	for _, ns := range namespaces {
+		require.NoError(t, ns.Start())
+	}
+}
+
+func TestSwitchNamespaces(t *testing.T) {
+	// the shared network has another index on each side
+	s1, ns1 := makeNamespacedSwitch(t, 1, "chain-a", "chain-b")
+	s2, ns2 := makeNamespacedSwitch(t, 2, "chain-b", "chain-c")
+	startNamespacedSwitch(t, s1, ns1)
+	defer s1.Stop()
+	startNamespacedSwitch(t, s2, ns2)
+	defer s2.Stop()
+
+	Connect2Switches([]*Switch{s1, s2}, 0, 1)
+	assert.Equal(t, 1, s1.Peers().Size())
+	assert.Equal(t, 1, ns1["chain-b"].Peers().Size())
+	assert.Equal(t, 0, ns1["chain-a"].Peers().Size())
+	assert.Equal(t, 1, ns2["chain-b"].Peers().Size())
+	assert.Equal(t, 0, ns2["chain-c"].Peers().Size())
+
+	nsMsg := []byte("chain-b")
+	s1Msg := []byte("testing")
+	ns1["chain-b"].Broadcast(byte(0x00), nsMsg)
+	s1.Broadcast(byte(0x01), s1Msg)
+
+	nsReactor := ns2["chain-b"].Reactor("foo").(*TestReactor)
+	assertMsgReceivedWithTimeout(t, nsMsg, byte(0x00), nsReactor, 10*time.Millisecond, 5*time.Second)
+	reactor := s2.Reactor("foo").(*TestReactor)
+	assertMsgReceivedWithTimeout(t, s1Msg, byte(0x01), reactor, 10*time.Millisecond, 5*time.Second)
+
+	// each message only reached the reactor of its network
+	assert.Empty(t, reactor.getMsgs(byte(0x00)))
+	assert.Empty(t, nsReactor.getMsgs(byte(0x01)))
+	assert.Empty(t, ns2["chain-c"].Reactor("foo").(*TestReactor).getMsgs(byte(0x00)))
+
+	// the peers of the namespace have their own data
+	s1.Peers().List()[0].Set("key", "testing")
+	assert.Nil(t, ns1["chain-b"].Peers().List()[0].Get("key"))
+}
+
+func TestSwitchNamespacePeers(t *testing.T) {
+	s1, ns1 := makeNamespacedSwitch(t, 1, "chain-a")
+	s2, ns2 := makeNamespacedSwitch(t, 2, "chain-a")
+	require.NoError(t, s1.Start())
+	defer s1.Stop()
+	startNamespacedSwitch(t, s2, ns2)
+	defer s2.Stop()
+
+	// the peers connected before the namespace is started are added to it
+	Connect2Switches([]*Switch{s1, s2}, 0, 1)
+	ns := ns1["chain-a"]
+	assert.Equal(t, 0, ns.Peers().Size())
+	require.NoError(t, ns.Start())
+	assert.Equal(t, 1, ns.Peers().Size())
+
+	// stopping the namespace leaves the peers connected
+	require.NoError(t, ns.Stop())
+	assert.Equal(t, 0, ns.Peers().Size())
+	assert.Equal(t, 1, s1.Peers().Size())
+
+	// stopping a peer for an error in a namespace disconnects it
+	ns2["chain-a"].StopPeerForError(ns2["chain-a"].Peers().List()[0], "testing")
+	assert.Equal(t, 0, ns2["chain-a"].Peers().Size())
+	assert.Equal(t, 0, s2.Peers().Size())
+}
+
+func TestSwitchNamespaceUnknownNetwork(t *testing.T) {
+	s1, ns1 := makeNamespacedSwitch(t, 1, "chain-a")
+	s2, ns2 := makeNamespacedSwitch(t, 2, "chain-b")
+	startNamespacedSwitch(t, s1, ns1)
+	defer s1.Stop()
+	startNamespacedSwitch(t, s2, ns2)
+	defer s2.Stop()
+
+	Connect2Switches([]*Switch{s1, s2}, 0, 1)
+	assert.Equal(t, 0, ns1["chain-a"].Peers().Size())
+
+	// a message of a network s2 doesn't run is ignored
+	p := s1.Peers().List()[0]
+	require.True(t, p.Send(byte(0x80), []byte{0x00, 0x01}))
+	time.Sleep(500 * time.Millisecond)
+	assert.Equal(t, 1, s2.Peers().Size())
+
+	// a message of a network s1 doesn't advertise stops the peer
+	require.True(t, p.Send(byte(0x80), []byte{0x01, 0x01}))
+	for start := time.Now(); s2.Peers().Size() > 0; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("timed out waiting for the peer to be stopped")
+		}
+	}
+}
+
+func TestSwitchAddNamespace(t *testing.T) {
+	sw := MakeSwitch(cfg, 1, "127.0.0.1", "123.123.123", initSwitchFunc)
+
+	ns, err := sw.AddNamespace("chain-a")
+	require.NoError(t, err)
+	assert.Equal(t, "chain-a", ns.Network())
+
+	_, err = sw.AddNamespace("chain-a")
+	assert.Error(t, err, "duplicate")
+	_, err = sw.AddNamespace(sw.NodeInfo().(DefaultNodeInfo).Network)
+	assert.Error(t, err, "primary network")
+	_, err = ns.AddNamespace("chain-b")
+	assert.Error(t, err, "namespace of a namespace")
+
+	assert.Panics(t, func() {
+		ns.AddReactor("bar", NewTestReactor([]*conn.ChannelDescriptor{{ID: byte(0x80)}}, false))
+	})
+
+	// the network must be in the node info
+	require.NoError(t, sw.Start())
+	defer sw.Stop()
+	assert.Error(t, ns.Start())
+	_, err = sw.AddNamespace("chain-b")
+	assert.Error(t, err, "running")
+}
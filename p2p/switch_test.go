@@ -532,7 +532,10 @@ func TestSwitchAcceptRoutine(t *testing.T) {
 
 	// make switch
 	sw := MakeSwitch(cfg, 1, "testing", "123.123.123", initSwitchFunc)
-	err := sw.Start()
+	unconditional := &remotePeer{PrivKey: ed25519.GenPrivKey(), Config: cfg}
+	err := sw.AddUnconditionalPeerIDs([]string{string(unconditional.ID())})
+	require.NoError(t, err)
+	err = sw.Start()
 	require.NoError(t, err)
 	defer sw.Stop()
 
@@ -573,6 +576,24 @@ func TestSwitchAcceptRoutine(t *testing.T) {
 	assert.Equal(t, cfg.MaxNumInboundPeers, sw.Peers().Size())
 	rp.Stop()
 
+	// 3. check we accept unconditional peers even if we already have
+	// MaxNumInboundPeers peers
+	unconditional.Start()
+	remotePeers = append(remotePeers, unconditional)
+	conn, err = unconditional.Dial(sw.NetAddress())
+	require.NoError(t, err)
+	go func(c net.Conn) {
+		for {
+			one := make([]byte, 1)
+			_, err := c.Read(one)
+			if err != nil {
+				return
+			}
+		}
+	}(conn)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, cfg.MaxNumInboundPeers+1, sw.Peers().Size())
+
 	// stop remote peers
 	for _, rp := range remotePeers {
 		rp.Stop()
@@ -596,6 +617,21 @@ func (errorTransport) Dial(NetAddress, peerConfig) (Peer, error) {
 func (errorTransport) Cleanup(Peer) {
 	panic("not implemented")
 }
+func (errorTransport) Listen(NetAddress) error {
+	panic("not implemented")
+}
+func (errorTransport) ListenAll([]ListenAddr) error {
+	panic("not implemented")
+}
+func (errorTransport) Close() error {
+	panic("not implemented")
+}
+func (errorTransport) SetNodeInfo(NodeInfo) {
+	panic("not implemented")
+}
+func (errorTransport) SetRates(int64, int64) {
+	panic("not implemented")
+}
 
 func TestSwitchAcceptRoutineErrorCases(t *testing.T) {
 	sw := NewSwitch(cfg, errorTransport{ErrFilterTimeout{}})
@@ -251,6 +251,21 @@ func testPeerConn(
 	return newPeerConn(outbound, persistent, conn, socketAddr), nil
 }
 
+// testUpgradeNoise runs the Noise upgrade of the transport over the secret
+// connection of pc, if both peers support it.
+func testUpgradeNoise(
+	pc peerConn,
+	ourNodePrivKey crypto.PrivKey,
+	initiator bool,
+	ourNodeInfo, peerNodeInfo NodeInfo,
+) error {
+	if !supportsNoiseUpgrade(ourNodeInfo) || !supportsNoiseUpgrade(peerNodeInfo) {
+		return nil
+	}
+	sc := pc.conn.(*conn.SecretConnection)
+	return upgradeNoise(sc, time.Second, ourNodePrivKey, initiator, ourNodeInfo, peerNodeInfo)
+}
+
 //----------------------------------------------------------------
 // rand node info
 
@@ -4,18 +4,37 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
 
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/p2p/conn"
+	tmtime "github.com/tendermint/tendermint/types/time"
+	"github.com/tendermint/tendermint/version"
 )
 
 const (
 	defaultDialTimeout      = time.Second
 	defaultFilterTimeout    = 5 * time.Second
 	defaultHandshakeTimeout = 3 * time.Second
+
+	// proxyDialTimeout is the timeout of the dials through a proxy, which
+	// take longer (e.g. to set up a Tor circuit).
+	proxyDialTimeout = 30 * time.Second
+
+	// The accepts failing for lack of resources are retried after a delay
+	// doubling from minAcceptBackoff up to maxAcceptBackoff, leaving the
+	// switch time to free some.
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+
+	// noiseUpgradeP2PProtocol is the first P2P protocol version which upgrades
+	// the secret connection with a Noise handshake.
+	noiseUpgradeP2PProtocol version.Protocol = 8
+	noiseUpgradePrologue                     = "TENDERMINT_NOISE_UPGRADE"
 )
 
 // IPResolver is a behaviour subset of net.Resolver.
@@ -48,11 +67,14 @@ type peerConfig struct {
 	isPersistent func(*NetAddress) bool
 	reactorsByCh map[byte]Reactor
 	metrics      *Metrics
+	labels       PeerLabels
 }
 
 // Transport emits and connects to Peers. The implementation of Peer is left to
 // the transport. Each transport is also responsible to filter establishing
 // peers specific to its domain.
+//
+// The transports are the MultiplexTransport (TCP) and the QUICTransport.
 type Transport interface {
 	// Listening address.
 	NetAddress() NetAddress
@@ -65,13 +87,23 @@ type Transport interface {
 
 	// Cleanup any resources associated with Peer.
 	Cleanup(Peer)
-}
 
-// transportLifecycle bundles the methods for callers to control start and stop
-// behaviour.
-type transportLifecycle interface {
-	Close() error
+	// Listen listens on the address, and ListenAll on all the addresses
+	// (see ListenAddr). One of them must be called before the transport
+	// accepts or dials peers.
 	Listen(NetAddress) error
+	ListenAll([]ListenAddr) error
+
+	// Close stops listening, and makes Accept return ErrTransportClosed.
+	Close() error
+
+	// SetNodeInfo sets the node info sent in the handshakes, e.g. to
+	// advertise the networks added to the switch (see Switch#AddNamespace).
+	SetNodeInfo(NodeInfo)
+
+	// SetRates changes the send and receive rate limits of the connections
+	// of the next peers, in bytes per second.
+	SetRates(sendRate, recvRate int64)
 }
 
 // ConnFilterFunc to be implemented by filter hooks after a new connection has
@@ -122,11 +154,34 @@ func MultiplexTransportResolver(resolver IPResolver) MultiplexTransportOption {
 	return func(mt *MultiplexTransport) { mt.resolver = resolver }
 }
 
+// MultiplexTransportClockSkew sets the ClockSkew fed with the time peers
+// report in the handshake.
+func MultiplexTransportClockSkew(clockSkew *tmtime.ClockSkew) MultiplexTransportOption {
+	return func(mt *MultiplexTransport) { mt.clockSkew = clockSkew }
+}
+
+// MultiplexTransportNodeInfo sets the node info sent in the handshake, e.g.
+// to advertise the networks added to the switch (see Switch#AddNamespace).
+func MultiplexTransportNodeInfo(nodeInfo NodeInfo) MultiplexTransportOption {
+	return func(mt *MultiplexTransport) { mt.nodeInfo = nodeInfo }
+}
+
+// MultiplexTransportProxies sets the SOCKS5 proxies the peers are dialed
+// through (see NewProxyDialer): persistentDialer for the persistent peers, and
+// dialer for the others. Peers are dialed directly if their dialer is nil.
+func MultiplexTransportProxies(dialer, persistentDialer proxy.ContextDialer) MultiplexTransportOption {
+	return func(mt *MultiplexTransport) {
+		mt.proxyDialer = dialer
+		mt.persistentProxyDialer = persistentDialer
+	}
+}
+
 // MultiplexTransport accepts and dials tcp connections and upgrades them to
 // multiplexed peers.
 type MultiplexTransport struct {
-	netAddr  NetAddress
-	listener net.Listener
+	netAddr     NetAddress // the first listen address
+	listenAddrs []ListenAddr
+	listeners   []net.Listener
 
 	acceptc chan accept
 	closec  chan struct{}
@@ -141,16 +196,21 @@ type MultiplexTransport struct {
 	nodeInfo         NodeInfo
 	nodeKey          NodeKey
 	resolver         IPResolver
+	clockSkew        *tmtime.ClockSkew // nil if peer clocks are not tracked
+
+	// nil to dial directly
+	proxyDialer           proxy.ContextDialer
+	persistentProxyDialer proxy.ContextDialer
 
 	// TODO(xla): This config is still needed as we parameterise peerConn and
 	// peer currently. All relevant configuration should be refactored into options
 	// with sane defaults.
-	mConfig conn.MConnConfig
+	mConfigMtx sync.RWMutex
+	mConfig    conn.MConnConfig
 }
 
 // Test multiplexTransport for interface completeness.
 var _ Transport = (*MultiplexTransport)(nil)
-var _ transportLifecycle = (*MultiplexTransport)(nil)
 
 // NewMultiplexTransport returns a tcp connected multiplexed peer.
 func NewMultiplexTransport(
@@ -172,6 +232,19 @@ func NewMultiplexTransport(
 	}
 }
 
+// SetNodeInfo implements Transport.
+func (mt *MultiplexTransport) SetNodeInfo(nodeInfo NodeInfo) {
+	mt.nodeInfo = nodeInfo
+}
+
+// SetRates implements Transport.
+func (mt *MultiplexTransport) SetRates(sendRate, recvRate int64) {
+	mt.mConfigMtx.Lock()
+	mt.mConfig.SendRate = sendRate
+	mt.mConfig.RecvRate = recvRate
+	mt.mConfigMtx.Unlock()
+}
+
 // NetAddress implements Transport.
 func (mt *MultiplexTransport) NetAddress() NetAddress {
 	return mt.netAddr
@@ -200,14 +273,34 @@ func (mt *MultiplexTransport) Dial(
 	addr NetAddress,
 	cfg peerConfig,
 ) (Peer, error) {
-	c, err := addr.DialTimeout(mt.dialTimeout)
-	if err != nil {
-		return nil, err
+	dialer := mt.proxyDialer
+	if cfg.isPersistent != nil && cfg.isPersistent(&addr) {
+		dialer = mt.persistentProxyDialer
 	}
 
-	// TODO(xla): Evaluate if we should apply filters if we explicitly dial.
-	if err := mt.filterConn(c); err != nil {
-		return nil, err
+	var (
+		c   net.Conn
+		err error
+	)
+	if dialer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), proxyDialTimeout)
+		c, err = dialer.DialContext(ctx, "tcp", addr.DialString())
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		// The connection filters aren't applied: the remote address of the
+		// connection is the one of the proxy, shared by all the proxied peers.
+	} else {
+		c, err = addr.DialTimeout(mt.dialTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		// TODO(xla): Evaluate if we should apply filters if we explicitly dial.
+		if err := mt.filterConn(c); err != nil {
+			return nil, err
+		}
 	}
 
 	secretConn, nodeInfo, err := mt.upgrade(c, &addr)
@@ -222,35 +315,101 @@ func (mt *MultiplexTransport) Dial(
 	return p, nil
 }
 
-// Close implements transportLifecycle.
+// Close implements Transport.
 func (mt *MultiplexTransport) Close() error {
 	close(mt.closec)
 
-	if mt.listener != nil {
-		return mt.listener.Close()
+	var err error
+	for _, ln := range mt.listeners {
+		if lnErr := ln.Close(); lnErr != nil && err == nil {
+			err = lnErr
+		}
 	}
 
-	return nil
+	return err
 }
 
-// Listen implements transportLifecycle.
+// Listen implements Transport.
 func (mt *MultiplexTransport) Listen(addr NetAddress) error {
-	ln, err := net.Listen("tcp", addr.DialString())
-	if err != nil {
-		return err
+	return mt.ListenAll([]ListenAddr{{Network: "tcp", Addr: addr}})
+}
+
+// ListenAddr is an address the transport listens on.
+type ListenAddr struct {
+	// Network to listen on: "tcp" (both IPv4 and IPv6 for an unspecified IP),
+	// "tcp4" or "tcp6".
+	Network string
+	Addr    NetAddress
+
+	// Address ("host:port") advertised in the handshake to the peers
+	// connected over its IP version, or to all peers for a hostname, instead
+	// of the listen address of the NodeInfo. Empty - not advertised.
+	Advertised string
+}
+
+// ListenAll implements Transport. It listens on all the addrs, e.g. on both an
+// IPv4 and an IPv6 interface, and advertises their advertised addresses.
+func (mt *MultiplexTransport) ListenAll(addrs []ListenAddr) error {
+	if len(addrs) == 0 {
+		return errors.New("no listen address")
+	}
+
+	listeners := make([]net.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		ln, err := net.Listen(addr.Network, addr.Addr.DialString())
+		if err != nil {
+			for _, ln := range listeners {
+				_ = ln.Close()
+			}
+			return err
+		}
+		listeners = append(listeners, ln)
 	}
 
-	mt.netAddr = addr
-	mt.listener = ln
+	mt.netAddr = addrs[0].Addr
+	mt.listenAddrs = addrs
+	mt.listeners = listeners
 
-	go mt.acceptPeers()
+	for _, ln := range listeners {
+		go mt.acceptPeers(ln)
+	}
 
 	return nil
 }
 
-func (mt *MultiplexTransport) acceptPeers() {
+// advertisedAddr returns the first advertised address of the listen addresses
+// of the IP version of the local address of a connection, or empty if there is
+// none.
+func advertisedAddr(listenAddrs []ListenAddr, local net.Addr) string {
+	var ip net.IP
+	switch addr := local.(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+	case *net.UDPAddr:
+		ip = addr.IP
+	default:
+		return ""
+	}
+	isIPv4 := ip.To4() != nil
+	for _, addr := range listenAddrs {
+		if addr.Advertised == "" {
+			continue
+		}
+		host, _, err := net.SplitHostPort(addr.Advertised)
+		if err != nil {
+			continue
+		}
+		if ip := net.ParseIP(host); ip == nil || (ip.To4() != nil) == isIPv4 {
+			return addr.Advertised
+		}
+	}
+	return ""
+}
+
+func (mt *MultiplexTransport) acceptPeers(ln net.Listener) {
+	var backoff time.Duration
 	for {
-		c, err := mt.listener.Accept()
+		c, err := ln.Accept()
 		if err != nil {
 			// If Close() has been called, silently exit.
 			select {
@@ -262,9 +421,30 @@ func (mt *MultiplexTransport) acceptPeers() {
 				// Transport is not closed
 			}
 
+			if isResourceExhausted(err) {
+				// Let the switch evict a peer, and try again.
+				select {
+				case mt.acceptc <- accept{err: ErrResourceExhausted{err}}:
+				case <-mt.closec:
+					return
+				}
+				if backoff *= 2; backoff == 0 {
+					backoff = minAcceptBackoff
+				} else if backoff > maxAcceptBackoff {
+					backoff = maxAcceptBackoff
+				}
+				select {
+				case <-time.After(backoff):
+				case <-mt.closec:
+					return
+				}
+				continue
+			}
+
 			mt.acceptc <- accept{err: err}
 			return
 		}
+		backoff = 0
 
 		// Connection upgrade and filtering should be asynchronous to avoid
 		// Head-of-line blocking[0].
@@ -337,22 +517,34 @@ func (mt *MultiplexTransport) filterConn(c net.Conn) (err error) {
 		}
 	}()
 
+	return filterConn(c, mt.conns, mt.connFilters, mt.filterTimeout, mt.resolver)
+}
+
+// filterConn rejects the connection if it's already in conns, or if one of
+// the filters does, and adds it to conns otherwise.
+func filterConn(
+	c net.Conn,
+	conns ConnSet,
+	filters []ConnFilterFunc,
+	timeout time.Duration,
+	resolver IPResolver,
+) error {
 	// Reject if connection is already present.
-	if mt.conns.Has(c) {
+	if conns.Has(c) {
 		return ErrRejected{conn: c, isDuplicate: true}
 	}
 
 	// Resolve ips for incoming conn.
-	ips, err := resolveIPs(mt.resolver, c)
+	ips, err := resolveIPs(resolver, c)
 	if err != nil {
 		return err
 	}
 
-	errc := make(chan error, len(mt.connFilters))
+	errc := make(chan error, len(filters))
 
-	for _, f := range mt.connFilters {
+	for _, f := range filters {
 		go func(f ConnFilterFunc, c net.Conn, ips []net.IP, errc chan<- error) {
-			errc <- f(mt.conns, c, ips)
+			errc <- f(conns, c, ips)
 		}(f, c, ips, errc)
 	}
 
@@ -362,13 +554,13 @@ func (mt *MultiplexTransport) filterConn(c net.Conn) (err error) {
 			if err != nil {
 				return ErrRejected{conn: c, err: err, isFiltered: true}
 			}
-		case <-time.After(mt.filterTimeout):
+		case <-time.After(timeout):
 			return ErrFilterTimeout{}
 		}
 
 	}
 
-	mt.conns.Set(c, ips)
+	conns.Set(c, ips)
 
 	return nil
 }
@@ -409,7 +601,9 @@ func (mt *MultiplexTransport) upgrade(
 		}
 	}
 
-	nodeInfo, err = handshake(secretConn, mt.handshakeTimeout, mt.nodeInfo)
+	ourNodeInfo := handshakeNodeInfo(mt.nodeInfo, mt.listenAddrs, c.LocalAddr())
+	nodeInfo, err = handshake(secretConn, mt.handshakeTimeout, ourNodeInfo)
+	receivedAt := tmtime.Now()
 	if err != nil {
 		return nil, nil, ErrRejected{
 			conn:          c,
@@ -418,8 +612,56 @@ func (mt *MultiplexTransport) upgrade(
 		}
 	}
 
+	if supportsNoiseUpgrade(mt.nodeInfo) && supportsNoiseUpgrade(nodeInfo) {
+		// The dialing peer initiates the handshake. It runs before the NodeInfo
+		// is validated, so that both peers upgrade before either rejects the other.
+		err = upgradeNoise(secretConn, mt.handshakeTimeout, mt.nodeKey.PrivKey, dialedAddr != nil, ourNodeInfo, nodeInfo)
+		if err != nil {
+			return nil, nil, ErrRejected{
+				conn:          c,
+				id:            connID,
+				err:           fmt.Errorf("noise upgrade failed: %v", err),
+				isAuthFailure: true,
+			}
+		}
+	}
+
+	nodeInfo, err = checkNodeInfo(c, connID, mt.nodeInfo, nodeInfo, mt.clockSkew, receivedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return secretConn, nodeInfo, nil
+}
+
+// handshakeNodeInfo returns the node info sent in the handshake over a
+// connection: with our time, so that the peer can tell whether its clock is
+// skewed, and the address to dial us over the IP version of the connection.
+func handshakeNodeInfo(nodeInfo NodeInfo, listenAddrs []ListenAddr, local net.Addr) NodeInfo {
+	dni, ok := nodeInfo.(DefaultNodeInfo)
+	if !ok {
+		return nodeInfo
+	}
+	dni.HandshakeTime = tmtime.Now().UnixNano()
+	if addr := advertisedAddr(listenAddrs, local); addr != "" {
+		dni.ListenAddr = addr
+	}
+	return dni
+}
+
+// checkNodeInfo validates the node info received in the handshake over c,
+// authenticated as connID, against ours. It records the clock offset of the
+// peer in clockSkew, if not nil, and returns the node info without the
+// handshake time, so that it stays the same across connections.
+func checkNodeInfo(
+	c net.Conn,
+	connID ID,
+	ourNodeInfo, nodeInfo NodeInfo,
+	clockSkew *tmtime.ClockSkew,
+	receivedAt time.Time,
+) (NodeInfo, error) {
 	if err := nodeInfo.Validate(); err != nil {
-		return nil, nil, ErrRejected{
+		return nil, ErrRejected{
 			conn:              c,
 			err:               err,
 			isNodeInfoInvalid: true,
@@ -428,7 +670,7 @@ func (mt *MultiplexTransport) upgrade(
 
 	// Ensure connection key matches self reported key.
 	if connID != nodeInfo.ID() {
-		return nil, nil, ErrRejected{
+		return nil, ErrRejected{
 			conn: c,
 			id:   connID,
 			err: fmt.Errorf(
@@ -441,8 +683,8 @@ func (mt *MultiplexTransport) upgrade(
 	}
 
 	// Reject self.
-	if mt.nodeInfo.ID() == nodeInfo.ID() {
-		return nil, nil, ErrRejected{
+	if ourNodeInfo.ID() == nodeInfo.ID() {
+		return nil, ErrRejected{
 			addr:   *NewNetAddress(nodeInfo.ID(), c.RemoteAddr()),
 			conn:   c,
 			id:     nodeInfo.ID(),
@@ -450,8 +692,8 @@ func (mt *MultiplexTransport) upgrade(
 		}
 	}
 
-	if err := mt.nodeInfo.CompatibleWith(nodeInfo); err != nil {
-		return nil, nil, ErrRejected{
+	if err := ourNodeInfo.CompatibleWith(nodeInfo); err != nil {
+		return nil, ErrRejected{
 			conn:           c,
 			err:            err,
 			id:             nodeInfo.ID(),
@@ -459,7 +701,15 @@ func (mt *MultiplexTransport) upgrade(
 		}
 	}
 
-	return secretConn, nodeInfo, nil
+	if dni, ok := nodeInfo.(DefaultNodeInfo); ok && dni.HandshakeTime != 0 {
+		if clockSkew != nil {
+			clockSkew.AddSample(string(dni.ID()), time.Unix(0, dni.HandshakeTime), receivedAt)
+		}
+		dni.HandshakeTime = 0
+		nodeInfo = dni
+	}
+
+	return nodeInfo, nil
 }
 
 func (mt *MultiplexTransport) wrapPeer(
@@ -469,38 +719,44 @@ func (mt *MultiplexTransport) wrapPeer(
 	socketAddr *NetAddress,
 ) Peer {
 
-	persistent := false
-	if cfg.isPersistent != nil {
-		if cfg.outbound {
-			persistent = cfg.isPersistent(socketAddr)
-		} else {
-			selfReportedAddr, err := ni.NetAddress()
-			if err == nil {
-				persistent = cfg.isPersistent(selfReportedAddr)
-			}
-		}
-	}
-
 	peerConn := newPeerConn(
 		cfg.outbound,
-		persistent,
+		isPersistentPeer(cfg, ni, socketAddr),
 		c,
 		socketAddr,
 	)
 
+	mt.mConfigMtx.RLock()
+	mConfig := mt.mConfig
+	mt.mConfigMtx.RUnlock()
+
 	p := newPeer(
 		peerConn,
-		mt.mConfig,
+		mConfig,
 		ni,
 		cfg.reactorsByCh,
 		cfg.chDescs,
 		cfg.onPeerError,
 		PeerMetrics(cfg.metrics),
+		PeerLabel(cfg.labels.Label(ni.ID())),
 	)
 
 	return p
 }
 
+// isPersistentPeer returns true if the peer of the config, dialed at socketAddr
+// or reporting ni, is persistent.
+func isPersistentPeer(cfg peerConfig, ni NodeInfo, socketAddr *NetAddress) bool {
+	if cfg.isPersistent == nil {
+		return false
+	}
+	if cfg.outbound {
+		return cfg.isPersistent(socketAddr)
+	}
+	selfReportedAddr, err := ni.NetAddress()
+	return err == nil && cfg.isPersistent(selfReportedAddr)
+}
+
 func handshake(
 	c net.Conn,
 	timeout time.Duration,
@@ -557,6 +813,46 @@ func upgradeSecretConn(
 	return sc, sc.SetDeadline(time.Time{})
 }
 
+// supportsNoiseUpgrade returns true if the node advertises a P2P protocol
+// version which upgrades the secret connection with a Noise handshake.
+func supportsNoiseUpgrade(ni NodeInfo) bool {
+	dni, ok := ni.(DefaultNodeInfo)
+	return ok && dni.ProtocolVersion.P2P >= noiseUpgradeP2PProtocol
+}
+
+// upgradeNoise runs the Noise handshake over the secret connection. The
+// NodeInfos exchanged by the peers, initiator first, are bound to the
+// handshake transcript.
+func upgradeNoise(
+	sc *conn.SecretConnection,
+	timeout time.Duration,
+	privKey crypto.PrivKey,
+	initiator bool,
+	ourNodeInfo, peerNodeInfo NodeInfo,
+) error {
+	if err := sc.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+
+	initNodeInfo, respNodeInfo := ourNodeInfo, peerNodeInfo
+	if !initiator {
+		initNodeInfo, respNodeInfo = peerNodeInfo, ourNodeInfo
+	}
+	prologue := []byte(noiseUpgradePrologue)
+	for _, ni := range []NodeInfo{initNodeInfo, respNodeInfo} {
+		bz, err := cdc.MarshalBinaryLengthPrefixed(ni.(DefaultNodeInfo))
+		if err != nil {
+			return err
+		}
+		prologue = append(prologue, bz...)
+	}
+
+	if err := sc.UpgradeNoise(privKey, initiator, prologue); err != nil {
+		return err
+	}
+	return sc.SetDeadline(time.Time{})
+}
+
 func resolveIPs(resolver IPResolver, c net.Conn) ([]net.IP, error) {
 	host, _, err := net.SplitHostPort(c.RemoteAddr().String())
 	if err != nil {
@@ -0,0 +1,548 @@
+package p2p
+
+import (
+	"context"
+	stded25519 "crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/quic-go/quic-go"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/p2p/conn"
+	tmtime "github.com/tendermint/tendermint/types/time"
+)
+
+// quicALPN is the application protocol negotiated by the QUIC transports.
+const quicALPN = "tendermint-p2p"
+
+// QUICTransportOption sets an optional parameter on the QUICTransport.
+type QUICTransportOption func(*QUICTransport)
+
+// QUICTransportConnFilters sets the filters for rejection new connections.
+func QUICTransportConnFilters(filters ...ConnFilterFunc) QUICTransportOption {
+	return func(qt *QUICTransport) { qt.connFilters = filters }
+}
+
+// QUICTransportFilterTimeout sets the timeout waited for filter calls to
+// return.
+func QUICTransportFilterTimeout(timeout time.Duration) QUICTransportOption {
+	return func(qt *QUICTransport) { qt.filterTimeout = timeout }
+}
+
+// QUICTransportClockSkew sets the ClockSkew fed with the time peers report in
+// the handshake.
+func QUICTransportClockSkew(clockSkew *tmtime.ClockSkew) QUICTransportOption {
+	return func(qt *QUICTransport) { qt.clockSkew = clockSkew }
+}
+
+// QUICTransport accepts and dials QUIC connections, and multiplexes the
+// channels of the peers on their streams (see conn.QUICConnection), so that a
+// packet lost on the network only delays the messages of its channel.
+//
+// The TLS certificate of the node is self-signed with its (Ed25519) node key:
+// the ID of a peer is the one of the key of its certificate, checked against
+// the dialed ID. The NodeInfos are then exchanged on the first stream, opened
+// by the dialing peer, and checked like the MultiplexTransport does.
+//
+// The transport listens on the UDP ports of the listen addresses (a "tcp"
+// network of a ListenAddr is listened on over "udp"), and dials the peers from
+// the socket of the IP version of their address.
+type QUICTransport struct {
+	netAddr     NetAddress // the first listen address
+	listenAddrs []ListenAddr
+	transports  []*quic.Transport
+	listeners   []*quic.Listener
+
+	acceptc chan accept
+	closec  chan struct{}
+
+	// Lookup table for duplicate ip and id checks.
+	conns       ConnSet
+	connFilters []ConnFilterFunc
+
+	dialTimeout      time.Duration
+	filterTimeout    time.Duration
+	handshakeTimeout time.Duration
+	nodeInfo         NodeInfo
+	nodeKey          NodeKey
+	tlsConfig        *tls.Config
+	resolver         IPResolver
+	clockSkew        *tmtime.ClockSkew // nil if peer clocks are not tracked
+
+	mConfigMtx sync.RWMutex
+	mConfig    conn.MConnConfig
+}
+
+// Test QUICTransport for interface completeness.
+var _ Transport = (*QUICTransport)(nil)
+
+// NewQUICTransport returns a transport of peers connected over QUIC. The node
+// key must be an Ed25519 key.
+func NewQUICTransport(
+	nodeInfo NodeInfo,
+	nodeKey NodeKey,
+	mConfig conn.MConnConfig,
+	options ...QUICTransportOption,
+) (*QUICTransport, error) {
+	tlsConfig, err := quicTLSConfig(nodeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	qt := &QUICTransport{
+		acceptc:          make(chan accept),
+		closec:           make(chan struct{}),
+		dialTimeout:      defaultDialTimeout,
+		filterTimeout:    defaultFilterTimeout,
+		handshakeTimeout: defaultHandshakeTimeout,
+		mConfig:          mConfig,
+		nodeInfo:         nodeInfo,
+		nodeKey:          nodeKey,
+		tlsConfig:        tlsConfig,
+		conns:            NewConnSet(),
+		resolver:         net.DefaultResolver,
+	}
+	for _, option := range options {
+		option(qt)
+	}
+	return qt, nil
+}
+
+// NetAddress implements Transport.
+func (qt *QUICTransport) NetAddress() NetAddress {
+	return qt.netAddr
+}
+
+// SetNodeInfo implements Transport.
+func (qt *QUICTransport) SetNodeInfo(nodeInfo NodeInfo) {
+	qt.nodeInfo = nodeInfo
+}
+
+// SetRates implements Transport.
+func (qt *QUICTransport) SetRates(sendRate, recvRate int64) {
+	qt.mConfigMtx.Lock()
+	qt.mConfig.SendRate = sendRate
+	qt.mConfig.RecvRate = recvRate
+	qt.mConfigMtx.Unlock()
+}
+
+// Accept implements Transport.
+func (qt *QUICTransport) Accept(cfg peerConfig) (Peer, error) {
+	select {
+	case a := <-qt.acceptc:
+		if a.err != nil {
+			return nil, a.err
+		}
+
+		cfg.outbound = false
+
+		return qt.wrapPeer(a.conn.(*quicConn), a.nodeInfo, cfg, a.netAddr), nil
+	case <-qt.closec:
+		return nil, ErrTransportClosed{}
+	}
+}
+
+// Dial implements Transport.
+func (qt *QUICTransport) Dial(addr NetAddress, cfg peerConfig) (Peer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), qt.dialTimeout+qt.handshakeTimeout)
+	defer cancel()
+
+	qc, err := qt.dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ensure the key of the certificate matches the dialed key.
+	connID, err := quicRemoteID(qc)
+	if err == nil && connID != addr.ID {
+		err = fmt.Errorf("conn.ID (%v) dialed ID (%v) mismatch", connID, addr.ID)
+	}
+	if err != nil {
+		qc.CloseWithError(0, "") // nolint: errcheck
+		return nil, ErrRejected{id: connID, err: err, isAuthFailure: true}
+	}
+
+	stream, err := qc.OpenStreamSync(ctx)
+	if err != nil {
+		qc.CloseWithError(0, "") // nolint: errcheck
+		return nil, err
+	}
+	c := &quicConn{Stream: stream, conn: qc}
+
+	// TODO(xla): Evaluate if we should apply filters if we explicitly dial.
+	if err := qt.filterConn(c); err != nil {
+		return nil, err
+	}
+
+	nodeInfo, err := qt.upgrade(c, connID)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.outbound = true
+
+	return qt.wrapPeer(c, nodeInfo, cfg, &addr), nil
+}
+
+// dial connects to the address from the socket we listen on of its IP
+// version, so that the peer sees our listen port, or from a new socket.
+func (qt *QUICTransport) dial(ctx context.Context, addr NetAddress) (quic.Connection, error) {
+	udpAddr := &net.UDPAddr{IP: addr.IP, Port: int(addr.Port)}
+	quicConfig := qt.quicConfig()
+	for _, tr := range qt.transports {
+		local, ok := tr.Conn.LocalAddr().(*net.UDPAddr)
+		if ok && (local.IP.To4() != nil) == (addr.IP.To4() != nil) {
+			return tr.Dial(ctx, udpAddr, qt.tlsConfig, quicConfig)
+		}
+	}
+	return quic.DialAddr(ctx, udpAddr.String(), qt.tlsConfig, quicConfig)
+}
+
+// Close implements Transport.
+func (qt *QUICTransport) Close() error {
+	close(qt.closec)
+
+	var err error
+	for _, ln := range qt.listeners {
+		if lnErr := ln.Close(); lnErr != nil && err == nil {
+			err = lnErr
+		}
+	}
+	for _, tr := range qt.transports {
+		// the transport leaves the socket open
+		if trErr := tr.Close(); trErr != nil && err == nil {
+			err = trErr
+		}
+		if connErr := tr.Conn.Close(); connErr != nil && err == nil {
+			err = connErr
+		}
+	}
+
+	return err
+}
+
+// Listen implements Transport.
+func (qt *QUICTransport) Listen(addr NetAddress) error {
+	return qt.ListenAll([]ListenAddr{{Network: "udp", Addr: addr}})
+}
+
+// ListenAll implements Transport. The "tcp", "tcp4" and "tcp6" networks of the
+// addrs are listened on over "udp", "udp4" and "udp6".
+func (qt *QUICTransport) ListenAll(addrs []ListenAddr) error {
+	if len(addrs) == 0 {
+		return errors.New("no listen address")
+	}
+
+	var (
+		transports = make([]*quic.Transport, 0, len(addrs))
+		listeners  = make([]*quic.Listener, 0, len(addrs))
+		quicConfig = qt.quicConfig()
+	)
+	closeAll := func() {
+		for _, ln := range listeners {
+			_ = ln.Close()
+		}
+		for _, tr := range transports {
+			_ = tr.Close()
+			_ = tr.Conn.Close()
+		}
+	}
+	for _, addr := range addrs {
+		network := addr.Network
+		if strings.HasPrefix(network, "tcp") {
+			network = "udp" + strings.TrimPrefix(network, "tcp")
+		}
+		pc, err := net.ListenPacket(network, addr.Addr.DialString())
+		if err != nil {
+			closeAll()
+			return err
+		}
+		tr := &quic.Transport{Conn: pc}
+		transports = append(transports, tr)
+
+		ln, err := tr.Listen(qt.tlsConfig, quicConfig)
+		if err != nil {
+			closeAll()
+			return err
+		}
+		listeners = append(listeners, ln)
+	}
+
+	qt.netAddr = addrs[0].Addr
+	qt.listenAddrs = addrs
+	qt.transports = transports
+	qt.listeners = listeners
+
+	for _, ln := range listeners {
+		go qt.acceptPeers(ln)
+	}
+
+	return nil
+}
+
+func (qt *QUICTransport) acceptPeers(ln *quic.Listener) {
+	for {
+		qc, err := ln.Accept(context.Background())
+		if err != nil {
+			// If Close() has been called, silently exit.
+			select {
+			case _, ok := <-qt.closec:
+				if !ok {
+					return
+				}
+			default:
+				// Transport is not closed
+			}
+
+			select {
+			case qt.acceptc <- accept{err: err}:
+			case <-qt.closec:
+			}
+			return
+		}
+
+		// Connection upgrade and filtering should be asynchronous to avoid
+		// Head-of-line blocking (see MultiplexTransport#acceptPeers).
+		go func(qc quic.Connection) {
+			var (
+				c        *quicConn
+				nodeInfo NodeInfo
+				netAddr  *NetAddress
+			)
+
+			defer func() {
+				if r := recover(); r != nil {
+					err := ErrRejected{
+						err:           errors.Errorf("recovered from panic: %v", r),
+						isAuthFailure: true,
+					}
+					select {
+					case qt.acceptc <- accept{err: err}:
+					case <-qt.closec:
+						// Give up if the transport was closed.
+						qc.CloseWithError(0, "") // nolint: errcheck
+						return
+					}
+				}
+			}()
+
+			connID, err := quicRemoteID(qc)
+			if err == nil {
+				c, err = qt.acceptStream(qc)
+				if err == nil {
+					err = qt.filterConn(c)
+				}
+				if err == nil {
+					nodeInfo, err = qt.upgrade(c, connID)
+				}
+				if err == nil {
+					netAddr = NewNetAddress(connID, qc.RemoteAddr())
+				}
+			} else {
+				qc.CloseWithError(0, "") // nolint: errcheck
+				err = ErrRejected{err: err, isAuthFailure: true}
+			}
+
+			select {
+			case qt.acceptc <- accept{netAddr, c, nodeInfo, err}:
+				// Make the upgraded peer available.
+			case <-qt.closec:
+				// Give up if the transport was closed.
+				qc.CloseWithError(0, "") // nolint: errcheck
+				return
+			}
+		}(qc)
+	}
+}
+
+// acceptStream accepts the handshake stream of the connection.
+func (qt *QUICTransport) acceptStream(qc quic.Connection) (*quicConn, error) {
+	ctx, cancel := context.WithTimeout(qc.Context(), qt.handshakeTimeout)
+	defer cancel()
+
+	stream, err := qc.AcceptStream(ctx)
+	if err != nil {
+		qc.CloseWithError(0, "") // nolint: errcheck
+		return nil, ErrRejected{err: fmt.Errorf("handshake failed: %v", err), isAuthFailure: true}
+	}
+	return &quicConn{Stream: stream, conn: qc}, nil
+}
+
+// Cleanup removes the given address from the connections set and
+// closes the connection.
+func (qt *QUICTransport) Cleanup(p Peer) {
+	qt.conns.RemoveAddr(p.RemoteAddr())
+	_ = p.CloseConn()
+}
+
+func (qt *QUICTransport) cleanup(c net.Conn) error {
+	qt.conns.Remove(c)
+
+	return c.Close()
+}
+
+func (qt *QUICTransport) filterConn(c net.Conn) (err error) {
+	defer func() {
+		if err != nil {
+			_ = c.Close()
+		}
+	}()
+
+	return filterConn(c, qt.conns, qt.connFilters, qt.filterTimeout, qt.resolver)
+}
+
+// upgrade exchanges the NodeInfos on the handshake stream of the connection
+// of the peer authenticated as connID.
+func (qt *QUICTransport) upgrade(c *quicConn, connID ID) (nodeInfo NodeInfo, err error) {
+	defer func() {
+		if err != nil {
+			_ = qt.cleanup(c)
+		}
+	}()
+
+	ourNodeInfo := handshakeNodeInfo(qt.nodeInfo, qt.listenAddrs, c.LocalAddr())
+	nodeInfo, err = handshake(c, qt.handshakeTimeout, ourNodeInfo)
+	receivedAt := tmtime.Now()
+	if err != nil {
+		return nil, ErrRejected{
+			conn:          c,
+			err:           fmt.Errorf("handshake failed: %v", err),
+			isAuthFailure: true,
+		}
+	}
+
+	return checkNodeInfo(c, connID, qt.nodeInfo, nodeInfo, qt.clockSkew, receivedAt)
+}
+
+func (qt *QUICTransport) wrapPeer(
+	c *quicConn,
+	ni NodeInfo,
+	cfg peerConfig,
+	socketAddr *NetAddress,
+) Peer {
+	peerConn := newPeerConn(
+		cfg.outbound,
+		isPersistentPeer(cfg, ni, socketAddr),
+		c,
+		socketAddr,
+	)
+
+	qt.mConfigMtx.RLock()
+	mConfig := qt.mConfig
+	qt.mConfigMtx.RUnlock()
+
+	return newPeerWithConnection(peerConn, ni, func(p *peer) peerConnection {
+		return createQUICConnection(
+			c.conn,
+			p,
+			cfg.reactorsByCh,
+			cfg.chDescs,
+			cfg.onPeerError,
+			mConfig,
+		)
+	},
+		PeerMetrics(cfg.metrics),
+		PeerLabel(cfg.labels.Label(ni.ID())),
+	)
+}
+
+func (qt *QUICTransport) quicConfig() *quic.Config {
+	qt.mConfigMtx.RLock()
+	defer qt.mConfigMtx.RUnlock()
+	return conn.QUICConfig(qt.mConfig, qt.handshakeTimeout)
+}
+
+//-----------------------------------------------------------------------------
+
+// quicConn is the handshake stream of a QUIC connection, as a net.Conn with
+// the addresses of the connection. Closing it closes the connection.
+type quicConn struct {
+	quic.Stream
+	conn quic.Connection
+}
+
+var _ net.Conn = (*quicConn)(nil)
+
+func (c *quicConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *quicConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+func (c *quicConn) Close() error         { return c.conn.CloseWithError(0, "") }
+
+// quicTLSConfig returns the TLS config of the QUIC connections of the node:
+// its certificate is self-signed with the node key, and the peers present
+// theirs.
+func quicTLSConfig(nodeKey NodeKey) (*tls.Config, error) {
+	privKey, ok := nodeKey.PrivKey.(ed25519.PrivKeyEd25519)
+	if !ok {
+		return nil, fmt.Errorf("the QUIC transport needs an Ed25519 node key, got %T", nodeKey.PrivKey)
+	}
+	key := stded25519.PrivateKey(privKey[:])
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: string(nodeKey.ID())},
+		NotBefore:    time.Now().Add(-time.Hour),
+		// The peers don't check the validity period: the certificate only
+		// carries the node key.
+		NotAfter: time.Now().Add(100 * 365 * 24 * time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create the certificate")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{certDER}, PrivateKey: key}},
+		ClientAuth:   tls.RequireAnyClientCert,
+		// The certificates are self-signed: the IDs of the peers are checked
+		// by the transport instead.
+		InsecureSkipVerify:    true, // nolint: gosec
+		VerifyPeerCertificate: verifyQUICCertificate,
+		NextProtos:            []string{quicALPN},
+		MinVersion:            tls.VersionTLS13,
+	}, nil
+}
+
+// verifyQUICCertificate checks the peer presents a single certificate, of an
+// Ed25519 key, self-signed.
+func verifyQUICCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) != 1 {
+		return fmt.Errorf("expected 1 certificate, got %d", len(rawCerts))
+	}
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return err
+	}
+	if _, ok := cert.PublicKey.(stded25519.PublicKey); !ok {
+		return fmt.Errorf("expected an Ed25519 key, got %T", cert.PublicKey)
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature)
+}
+
+// quicRemoteID returns the ID of the key of the certificate of the peer.
+func quicRemoteID(qc quic.Connection) (ID, error) {
+	certs := qc.ConnectionState().TLS.PeerCertificates
+	if len(certs) == 0 {
+		return "", errors.New("no certificate")
+	}
+	key, ok := certs[0].PublicKey.(stded25519.PublicKey)
+	if !ok || len(key) != stded25519.PublicKeySize {
+		return "", fmt.Errorf("expected an Ed25519 key, got %T", certs[0].PublicKey)
+	}
+	var pubKey ed25519.PubKeyEd25519
+	copy(pubKey[:], key)
+	return PubKeyToID(pubKey), nil
+}
@@ -0,0 +1,212 @@
+package p2p
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/p2p/conn"
+)
+
+func TestTransportQUICDialAccept(t *testing.T) {
+	qt := testSetupQUICTransport(t)
+	defer qt.Close()
+	dialer := testSetupQUICTransport(t)
+	defer dialer.Close()
+
+	var (
+		id   = qt.nodeKey.ID()
+		addr = NewNetAddress(id, qt.listeners[0].Addr())
+	)
+
+	peerc := make(chan Peer)
+	go func() {
+		p, err := dialer.Dial(*addr, peerConfig{})
+		if err != nil {
+			t.Errorf("dial failed: %v", err)
+		}
+		peerc <- p
+	}()
+
+	p, err := qt.Accept(peerConfig{})
+	require.NoError(t, err)
+	dialed := <-peerc
+	require.NotNil(t, dialed)
+	defer dialed.CloseConn()
+	defer p.CloseConn()
+
+	assert.Equal(t, id, dialed.ID())
+	assert.True(t, dialed.IsOutbound())
+	assert.Equal(t, dialer.nodeKey.ID(), p.ID())
+	assert.False(t, p.IsOutbound())
+
+	// the dialer connected from the socket it listens on
+	assert.Equal(t, dialer.listeners[0].Addr().String(), p.SocketAddr().DialString())
+}
+
+func TestTransportQUICConnFilter(t *testing.T) {
+	qt := testSetupQUICTransport(t)
+	defer qt.Close()
+	QUICTransportConnFilters(
+		func(_ ConnSet, _ net.Conn, _ []net.IP) error { return nil },
+		func(_ ConnSet, _ net.Conn, _ []net.IP) error {
+			return fmt.Errorf("rejected")
+		},
+	)(qt)
+
+	dialer := testSetupQUICTransport(t)
+	defer dialer.Close()
+	go func() {
+		_, _ = dialer.Dial(*NewNetAddress(qt.nodeKey.ID(), qt.listeners[0].Addr()), peerConfig{})
+	}()
+
+	_, err := qt.Accept(peerConfig{})
+	if err, ok := err.(ErrRejected); ok {
+		if !err.IsFiltered() {
+			t.Errorf("expected peer to be filtered")
+		}
+	} else {
+		t.Errorf("expected ErrRejected, got %v", err)
+	}
+}
+
+func TestTransportQUICDialRejectWrongID(t *testing.T) {
+	qt := testSetupQUICTransport(t)
+	defer qt.Close()
+	dialer := testSetupQUICTransport(t)
+	defer dialer.Close()
+
+	wrongID := PubKeyToID(ed25519.GenPrivKey().PubKey())
+	addr := NewNetAddress(wrongID, qt.listeners[0].Addr())
+
+	_, err := dialer.Dial(*addr, peerConfig{})
+	if err, ok := err.(ErrRejected); ok {
+		if !err.IsAuthFailure() {
+			t.Errorf("expected auth failure")
+		}
+	} else {
+		t.Errorf("expected ErrRejected, got %v", err)
+	}
+}
+
+func TestTransportQUICRejectIncompatible(t *testing.T) {
+	qt := testSetupQUICTransport(t)
+	defer qt.Close()
+
+	var (
+		pv     = ed25519.GenPrivKey()
+		dialer = testNewQUICTransport(t,
+			testNodeInfoWithNetwork(PubKeyToID(pv.PubKey()), "dialer", "other"),
+			NodeKey{PrivKey: pv},
+		)
+	)
+	defer dialer.Close()
+	go func() {
+		_, _ = dialer.Dial(*NewNetAddress(qt.nodeKey.ID(), qt.listeners[0].Addr()), peerConfig{})
+	}()
+
+	_, err := qt.Accept(peerConfig{})
+	if err, ok := err.(ErrRejected); ok {
+		if !err.IsIncompatible() {
+			t.Errorf("expected to reject incompatible")
+		}
+	} else {
+		t.Errorf("expected ErrRejected, got %v", err)
+	}
+}
+
+func TestNewQUICTransportNodeKey(t *testing.T) {
+	pv := secp256k1.GenPrivKey()
+	_, err := NewQUICTransport(
+		testNodeInfo(PubKeyToID(pv.PubKey()), "transport"),
+		NodeKey{PrivKey: pv},
+		conn.DefaultMConnConfig(),
+	)
+	assert.Error(t, err)
+}
+
+func TestSwitchesQUIC(t *testing.T) {
+	s1 := makeQUICSwitch(t, 0)
+	s2 := makeQUICSwitch(t, 1)
+	require.NoError(t, StartSwitches([]*Switch{s1, s2}))
+	defer s1.Stop()
+	defer s2.Stop()
+
+	s2Addr := NewNetAddress(s2.NodeInfo().ID(), s2.transport.(*QUICTransport).listeners[0].Addr())
+	require.NoError(t, s1.DialPeerWithAddress(s2Addr))
+	require.Equal(t, 1, s1.Peers().Size())
+
+	// the messages of all the channels reach the peer, both ways
+	for i, ch := range []byte{0x00, 0x01, 0x02, 0x03} {
+		reactor := "foo"
+		if ch > 0x01 {
+			reactor = "bar"
+		}
+		msg := []byte(fmt.Sprintf("channel %d", i))
+		assert.True(t, s1.Peers().List()[0].Send(ch, msg))
+		assertMsgReceivedWithTimeout(t, msg, ch,
+			s2.Reactor(reactor).(*TestReactor), 10*time.Millisecond, 5*time.Second)
+
+		for s2.Peers().Size() == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.True(t, s2.Peers().List()[0].Send(ch, msg))
+		assertMsgReceivedWithTimeout(t, msg, ch,
+			s1.Reactor(reactor).(*TestReactor), 10*time.Millisecond, 5*time.Second)
+	}
+}
+
+// makeQUICSwitch returns a switch of the reactors of initSwitchFunc, listening
+// on a QUIC transport.
+func makeQUICSwitch(t *testing.T, i int) *Switch {
+	var (
+		pv       = ed25519.GenPrivKey()
+		nodeKey  = NodeKey{PrivKey: pv}
+		nodeInfo = testNodeInfo(nodeKey.ID(), fmt.Sprintf("node%d", i)).(DefaultNodeInfo)
+		qt       = testNewQUICTransport(t, nodeInfo, nodeKey)
+	)
+	addr, err := NewNetAddressString(IDAddressString(nodeKey.ID(), "127.0.0.1:0"))
+	require.NoError(t, err)
+	require.NoError(t, qt.Listen(*addr))
+
+	sw := initSwitchFunc(i, NewSwitch(cfg, qt))
+	sw.SetLogger(log.TestingLogger().With("switch", i))
+	sw.SetNodeKey(&nodeKey)
+
+	nodeInfo.ListenAddr = qt.listeners[0].Addr().String()
+	nodeInfo.Channels = nil
+	for ch := range sw.reactorsByCh {
+		nodeInfo.Channels = append(nodeInfo.Channels, ch)
+	}
+	qt.SetNodeInfo(nodeInfo)
+	sw.SetNodeInfo(nodeInfo)
+
+	return sw
+}
+
+func testNewQUICTransport(t *testing.T, nodeInfo NodeInfo, nodeKey NodeKey) *QUICTransport {
+	qt, err := NewQUICTransport(nodeInfo, nodeKey, conn.DefaultMConnConfig())
+	require.NoError(t, err)
+	return qt
+}
+
+func testSetupQUICTransport(t *testing.T) *QUICTransport {
+	var (
+		pv = ed25519.GenPrivKey()
+		id = PubKeyToID(pv.PubKey())
+		qt = testNewQUICTransport(t, testNodeInfo(id, "transport"), NodeKey{PrivKey: pv})
+	)
+
+	addr, err := NewNetAddressString(IDAddressString(id, "127.0.0.1:0"))
+	require.NoError(t, err)
+	require.NoError(t, qt.Listen(*addr))
+
+	return qt
+}
@@ -2,9 +2,11 @@ package p2p
 
 import (
 	"fmt"
+	"io"
 	"math/rand"
 	"net"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -59,7 +61,7 @@ func TestTransportMultiplexConnFilter(t *testing.T) {
 	errc := make(chan error)
 
 	go func() {
-		addr := NewNetAddress(id, mt.listener.Addr())
+		addr := NewNetAddress(id, mt.listeners[0].Addr())
 
 		_, err := addr.Dial()
 		if err != nil {
@@ -113,7 +115,7 @@ func TestTransportMultiplexConnFilterTimeout(t *testing.T) {
 	errc := make(chan error)
 
 	go func() {
-		addr := NewNetAddress(id, mt.listener.Addr())
+		addr := NewNetAddress(id, mt.listeners[0].Addr())
 
 		_, err := addr.Dial()
 		if err != nil {
@@ -136,7 +138,7 @@ func TestTransportMultiplexConnFilterTimeout(t *testing.T) {
 
 func TestTransportMultiplexAcceptMultiple(t *testing.T) {
 	mt := testSetupMultiplexTransport(t)
-	laddr := NewNetAddress(mt.nodeKey.ID(), mt.listener.Addr())
+	laddr := NewNetAddress(mt.nodeKey.ID(), mt.listeners[0].Addr())
 
 	var (
 		seed     = rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -222,7 +224,7 @@ func TestTransportMultiplexAcceptNonBlocking(t *testing.T) {
 
 	// Simulate slow Peer.
 	go func() {
-		addr := NewNetAddress(mt.nodeKey.ID(), mt.listener.Addr())
+		addr := NewNetAddress(mt.nodeKey.ID(), mt.listeners[0].Addr())
 
 		c, err := addr.Dial()
 		if err != nil {
@@ -269,7 +271,7 @@ func TestTransportMultiplexAcceptNonBlocking(t *testing.T) {
 				},
 			)
 		)
-		addr := NewNetAddress(mt.nodeKey.ID(), mt.listener.Addr())
+		addr := NewNetAddress(mt.nodeKey.ID(), mt.listeners[0].Addr())
 
 		_, err := dialer.Dial(*addr, peerConfig{})
 		if err != nil {
@@ -311,7 +313,7 @@ func TestTransportMultiplexValidateNodeInfo(t *testing.T) {
 			)
 		)
 
-		addr := NewNetAddress(mt.nodeKey.ID(), mt.listener.Addr())
+		addr := NewNetAddress(mt.nodeKey.ID(), mt.listeners[0].Addr())
 
 		_, err := dialer.Dial(*addr, peerConfig{})
 		if err != nil {
@@ -350,7 +352,7 @@ func TestTransportMultiplexRejectMissmatchID(t *testing.T) {
 				PrivKey: ed25519.GenPrivKey(),
 			},
 		)
-		addr := NewNetAddress(mt.nodeKey.ID(), mt.listener.Addr())
+		addr := NewNetAddress(mt.nodeKey.ID(), mt.listeners[0].Addr())
 
 		_, err := dialer.Dial(*addr, peerConfig{})
 		if err != nil {
@@ -389,7 +391,7 @@ func TestTransportMultiplexDialRejectWrongID(t *testing.T) {
 	)
 
 	wrongID := PubKeyToID(ed25519.GenPrivKey().PubKey())
-	addr := NewNetAddress(wrongID, mt.listener.Addr())
+	addr := NewNetAddress(wrongID, mt.listeners[0].Addr())
 
 	_, err := dialer.Dial(*addr, peerConfig{})
 	if err != nil {
@@ -419,7 +421,7 @@ func TestTransportMultiplexRejectIncompatible(t *testing.T) {
 				},
 			)
 		)
-		addr := NewNetAddress(mt.nodeKey.ID(), mt.listener.Addr())
+		addr := NewNetAddress(mt.nodeKey.ID(), mt.listeners[0].Addr())
 
 		_, err := dialer.Dial(*addr, peerConfig{})
 		if err != nil {
@@ -446,7 +448,7 @@ func TestTransportMultiplexRejectSelf(t *testing.T) {
 	errc := make(chan error)
 
 	go func() {
-		addr := NewNetAddress(mt.nodeKey.ID(), mt.listener.Addr())
+		addr := NewNetAddress(mt.nodeKey.ID(), mt.listeners[0].Addr())
 
 		_, err := mt.Dial(*addr, peerConfig{})
 		if err != nil {
@@ -558,6 +560,154 @@ func TestTransportHandshake(t *testing.T) {
 }
 
 // create listener
+func TestTransportMultiplexListenAll(t *testing.T) {
+	var (
+		pv = ed25519.GenPrivKey()
+		id = PubKeyToID(pv.PubKey())
+		mt = newMultiplexTransport(testNodeInfo(id, "transport"), NodeKey{PrivKey: pv})
+	)
+	addr4, err := NewNetAddressString(IDAddressString(id, "127.0.0.1:0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr6, err := NewNetAddressString(IDAddressString(id, "[::1]:0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mt.ListenAll([]ListenAddr{
+		{Network: "tcp4", Addr: *addr4, Advertised: "1.2.3.4:26656"},
+		{Network: "tcp6", Addr: *addr6, Advertised: "[2001:db8::1]:26656"},
+	})
+	if err != nil {
+		t.Skipf("no IPv6 loopback: %v", err)
+	}
+	defer mt.Close()
+
+	// the peers are told the address of the IP version they connected over
+	for i, advertised := range []string{"1.2.3.4:26656", "[2001:db8::1]:26656"} {
+		pv := ed25519.GenPrivKey()
+		dialer := newMultiplexTransport(testNodeInfo(PubKeyToID(pv.PubKey()), defaultNodeName), NodeKey{PrivKey: pv})
+		p, err := dialer.Dial(*NewNetAddress(id, mt.listeners[i].Addr()), peerConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := p.NodeInfo().(DefaultNodeInfo).ListenAddr, advertised; have != want {
+			t.Errorf("have %v, want %v", have, want)
+		}
+		_ = p.CloseConn()
+	}
+}
+
+func TestTransportMultiplexDialProxy(t *testing.T) {
+	mt := testSetupMultiplexTransport(t)
+	defer mt.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	reqc := make(chan string, 1)
+	go testSOCKS5Proxy(ln, reqc)
+
+	proxyDialer, err := NewProxyDialer("socks5://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pv := ed25519.GenPrivKey()
+	dialer := newMultiplexTransport(testNodeInfo(PubKeyToID(pv.PubKey()), defaultNodeName), NodeKey{PrivKey: pv})
+	MultiplexTransportProxies(proxyDialer, nil)(dialer)
+
+	addr := NewNetAddress(mt.nodeKey.ID(), mt.listeners[0].Addr())
+	p, err := dialer.Dial(*addr, peerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.CloseConn()
+	if have, want := <-reqc, addr.DialString(); have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+
+	// onion addresses can't be dialed directly
+	onion, err := NewNetAddressString(IDAddressString(mt.nodeKey.ID(), "expyuzz4wqqyqhjn.onion:26656"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct := newMultiplexTransport(testNodeInfo(PubKeyToID(pv.PubKey()), defaultNodeName), NodeKey{PrivKey: pv})
+	if _, err := direct.Dial(*onion, peerConfig{}); err != errOnionWithoutProxy {
+		t.Errorf("have %v, want %v", err, errOnionWithoutProxy)
+	}
+}
+
+// testSOCKS5Proxy serves the SOCKS5 CONNECT requests, without authentication,
+// on ln, and sends the requested addresses to reqc.
+func testSOCKS5Proxy(ln net.Listener, reqc chan<- string) {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(c net.Conn) {
+			defer c.Close()
+
+			// greeting: VER NMETHODS METHODS, answered with no authentication
+			buf := make([]byte, 256)
+			if _, err := io.ReadFull(c, buf[:2]); err != nil {
+				return
+			}
+			if _, err := io.ReadFull(c, buf[:int(buf[1])]); err != nil {
+				return
+			}
+			if _, err := c.Write([]byte{5, 0}); err != nil {
+				return
+			}
+
+			// request: VER CMD RSV ATYP DST.ADDR DST.PORT
+			if _, err := io.ReadFull(c, buf[:4]); err != nil {
+				return
+			}
+			var host string
+			switch buf[3] {
+			case 1, 4: // IPv4, IPv6
+				ip := make(net.IP, 4*buf[3])
+				if _, err := io.ReadFull(c, ip); err != nil {
+					return
+				}
+				host = ip.String()
+			case 3: // domain name
+				if _, err := io.ReadFull(c, buf[:1]); err != nil {
+					return
+				}
+				n := int(buf[0])
+				if _, err := io.ReadFull(c, buf[:n]); err != nil {
+					return
+				}
+				host = string(buf[:n])
+			default:
+				return
+			}
+			if _, err := io.ReadFull(c, buf[:2]); err != nil {
+				return
+			}
+			addr := net.JoinHostPort(host, strconv.Itoa(int(buf[0])<<8|int(buf[1])))
+			reqc <- addr
+
+			dst, err := net.Dial("tcp", addr)
+			if err != nil {
+				_, _ = c.Write([]byte{5, 1, 0, 1, 0, 0, 0, 0, 0, 0})
+				return
+			}
+			defer dst.Close()
+			if _, err := c.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+				return
+			}
+			go func() { _, _ = io.Copy(dst, c) }()
+			_, _ = io.Copy(c, dst)
+		}(c)
+	}
+}
+
 func testSetupMultiplexTransport(t *testing.T) *MultiplexTransport {
 	var (
 		pv = ed25519.GenPrivKey()
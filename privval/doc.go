@@ -15,6 +15,13 @@ SignerListenerEndpoint listens for the external KMS process to dial in.
 SignerListenerEndpoint takes a listener, which determines the type of connection
 (ie. encrypted over tcp, or unencrypted over unix).
 
+ThresholdPV
+
+ThresholdPV forwards sign requests to an external coordinator, via the
+ThresholdSigner interface, which runs the multi-party signing protocol. It
+shares the height/round/step watermark bookkeeping with FilePV so the
+coordinator is never asked to double sign.
+
 SignerDialerEndpoint
 
 SignerDialerEndpoint is a simple wrapper around a net.Conn. It's used by both IPCVal and TCPVal.
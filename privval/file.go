@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"sync"
 	"time"
 
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/tmhash"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	"github.com/tendermint/tendermint/types"
 	tmtime "github.com/tendermint/tendermint/types/time"
@@ -67,15 +69,36 @@ func (pvKey FilePVKey) Save() {
 
 // FilePVLastSignState stores the mutable part of PrivValidator.
 type FilePVLastSignState struct {
-	Height    int64        `json:"height"`
-	Round     int          `json:"round"`
-	Step      int8         `json:"step"`
-	Signature []byte       `json:"signature,omitempty"`
-	SignBytes cmn.HexBytes `json:"signbytes,omitempty"`
+	Height        int64        `json:"height"`
+	Round         int          `json:"round"`
+	Step          int8         `json:"step"`
+	Signature     []byte       `json:"signature,omitempty"`
+	SignBytes     cmn.HexBytes `json:"signbytes,omitempty"`
+	SignBytesHash cmn.HexBytes `json:"signbytes_hash,omitempty"`
 
 	filePath string
 }
 
+// ValidateBasic checks the consistency of the state. The SignBytesHash is
+// missing from states saved by older versions, and is the only trace of the
+// sign bytes in a FileSignStateStore, so it is only checked against set
+// SignBytes.
+func (lss *FilePVLastSignState) ValidateBasic() error {
+	if lss.Height < 0 || lss.Round < 0 {
+		return errors.New("negative height or round")
+	}
+	if lss.Step < stepNone || lss.Step > stepPrecommit {
+		return fmt.Errorf("unknown step %v", lss.Step)
+	}
+	if (lss.Signature == nil) != (lss.SignBytes == nil) {
+		return errors.New("signature and signbytes must be set together")
+	}
+	if lss.SignBytesHash != nil && lss.SignBytes != nil && !bytes.Equal(lss.SignBytesHash, tmhash.Sum(lss.SignBytes)) {
+		return errors.New("signbytes_hash doesn't match signbytes")
+	}
+	return nil
+}
+
 // CheckHRS checks the given height, round, step (HRS) against that of the
 // FilePVLastSignState. It returns an error if the arguments constitute a regression,
 // or if they match but the SignBytes are empty.
@@ -143,13 +166,20 @@ func (lss *FilePVLastSignState) Save() {
 type FilePV struct {
 	Key           FilePVKey
 	LastSignState FilePVLastSignState
+
+	mtx            sync.Mutex
+	signStateStore SignStateStore
 }
 
 // GenFilePV generates a new validator with randomly generated private key
 // and sets the filePaths, but does not call Save().
 func GenFilePV(keyFilePath, stateFilePath string) *FilePV {
-	privKey := ed25519.GenPrivKey()
+	return NewFilePV(ed25519.GenPrivKey(), keyFilePath, stateFilePath)
+}
 
+// NewFilePV generates a new validator from the given private key (e.g. derived
+// from a mnemonic) and sets the filePaths, but does not call Save().
+func NewFilePV(privKey crypto.PrivKey, keyFilePath, stateFilePath string) *FilePV {
 	return &FilePV{
 		Key: FilePVKey{
 			Address:  privKey.PubKey().Address(),
@@ -204,6 +234,9 @@ func loadFilePV(keyFilePath, stateFilePath string, loadState bool) *FilePV {
 		if err != nil {
 			cmn.Exit(fmt.Sprintf("Error reading PrivValidator state from %v: %v\n", stateFilePath, err))
 		}
+		if err := pvState.ValidateBasic(); err != nil {
+			cmn.Exit(fmt.Sprintf("Invalid PrivValidator state in %v: %v\n", stateFilePath, err))
+		}
 	}
 
 	pvState.filePath = stateFilePath
@@ -239,9 +272,21 @@ func (pv *FilePV) GetPubKey() crypto.PubKey {
 	return pv.Key.PubKey
 }
 
+// SetSignStateStore makes the FilePV check every signature against the
+// given store, shared with redundant signer instances, in addition to its
+// own last sign state.
+func (pv *FilePV) SetSignStateStore(store SignStateStore) {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+	pv.signStateStore = store
+}
+
 // SignVote signs a canonical representation of the vote, along with the
 // chainID. Implements PrivValidator.
 func (pv *FilePV) SignVote(chainID string, vote *types.Vote) error {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
 	if err := pv.signVote(chainID, vote); err != nil {
 		return fmt.Errorf("error signing vote: %v", err)
 	}
@@ -251,6 +296,9 @@ func (pv *FilePV) SignVote(chainID string, vote *types.Vote) error {
 // SignProposal signs a canonical representation of the proposal, along with
 // the chainID. Implements PrivValidator.
 func (pv *FilePV) SignProposal(chainID string, proposal *types.Proposal) error {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
 	if err := pv.signProposal(chainID, proposal); err != nil {
 		return fmt.Errorf("error signing proposal: %v", err)
 	}
@@ -272,6 +320,7 @@ func (pv *FilePV) Reset() {
 	pv.LastSignState.Step = 0
 	pv.LastSignState.Signature = sig
 	pv.LastSignState.SignBytes = nil
+	pv.LastSignState.SignBytesHash = nil
 	pv.Save()
 }
 
@@ -289,106 +338,24 @@ func (pv *FilePV) String() string {
 //------------------------------------------------------------------------------------
 
 // signVote checks if the vote is good to sign and sets the vote signature.
-// It may need to set the timestamp as well if the vote is otherwise the same as
-// a previously signed vote (ie. we crashed after signing but before the vote hit the WAL).
+// See signVoteWatermarked.
 func (pv *FilePV) signVote(chainID string, vote *types.Vote) error {
-	height, round, step := vote.Height, vote.Round, voteToStep(vote)
-
-	lss := pv.LastSignState
-
-	sameHRS, err := lss.CheckHRS(height, round, step)
-	if err != nil {
-		return err
-	}
-
-	signBytes := vote.SignBytes(chainID)
-
-	// We might crash before writing to the wal,
-	// causing us to try to re-sign for the same HRS.
-	// If signbytes are the same, use the last signature.
-	// If they only differ by timestamp, use last timestamp and signature
-	// Otherwise, return error
-	if sameHRS {
-		if bytes.Equal(signBytes, lss.SignBytes) {
-			vote.Signature = lss.Signature
-		} else if timestamp, ok := checkVotesOnlyDifferByTimestamp(lss.SignBytes, signBytes); ok {
-			vote.Timestamp = timestamp
-			vote.Signature = lss.Signature
-		} else {
-			err = fmt.Errorf("conflicting data")
-		}
-		return err
-	}
-
-	// It passed the checks. Sign the vote
-	sig, err := pv.Key.PrivKey.Sign(signBytes)
-	if err != nil {
-		return err
-	}
-	pv.saveSigned(height, round, step, signBytes, sig)
-	vote.Signature = sig
-	return nil
+	return signVoteWatermarked(&pv.LastSignState, pv.signStateStore, chainID, vote, pv.Key.PrivKey.Sign)
 }
 
 // signProposal checks if the proposal is good to sign and sets the proposal signature.
-// It may need to set the timestamp as well if the proposal is otherwise the same as
-// a previously signed proposal ie. we crashed after signing but before the proposal hit the WAL).
+// See signProposalWatermarked.
 func (pv *FilePV) signProposal(chainID string, proposal *types.Proposal) error {
-	height, round, step := proposal.Height, proposal.Round, stepPropose
-
-	lss := pv.LastSignState
-
-	sameHRS, err := lss.CheckHRS(height, round, step)
-	if err != nil {
-		return err
-	}
-
-	signBytes := proposal.SignBytes(chainID)
-
-	// We might crash before writing to the wal,
-	// causing us to try to re-sign for the same HRS.
-	// If signbytes are the same, use the last signature.
-	// If they only differ by timestamp, use last timestamp and signature
-	// Otherwise, return error
-	if sameHRS {
-		if bytes.Equal(signBytes, lss.SignBytes) {
-			proposal.Signature = lss.Signature
-		} else if timestamp, ok := checkProposalsOnlyDifferByTimestamp(lss.SignBytes, signBytes); ok {
-			proposal.Timestamp = timestamp
-			proposal.Signature = lss.Signature
-		} else {
-			err = fmt.Errorf("conflicting data")
-		}
-		return err
-	}
-
-	// It passed the checks. Sign the proposal
-	sig, err := pv.Key.PrivKey.Sign(signBytes)
-	if err != nil {
-		return err
-	}
-	pv.saveSigned(height, round, step, signBytes, sig)
-	proposal.Signature = sig
-	return nil
-}
-
-// Persist height/round/step and signature
-func (pv *FilePV) saveSigned(height int64, round int, step int8,
-	signBytes []byte, sig []byte) {
-
-	pv.LastSignState.Height = height
-	pv.LastSignState.Round = round
-	pv.LastSignState.Step = step
-	pv.LastSignState.Signature = sig
-	pv.LastSignState.SignBytes = signBytes
-	pv.LastSignState.Save()
+	return signProposalWatermarked(&pv.LastSignState, pv.signStateStore, chainID, proposal, pv.Key.PrivKey.Sign)
 }
 
 //-----------------------------------------------------------------------------------------
 
-// returns the timestamp from the lastSignBytes.
-// returns true if the only difference in the votes is their timestamp.
-func checkVotesOnlyDifferByTimestamp(lastSignBytes, newSignBytes []byte) (time.Time, bool) {
+// returns the timestamp and the extension from the lastSignBytes.
+// returns true if the only difference in the votes is their timestamp and
+// extension: the application may extend the precommit differently after a
+// restart.
+func checkVotesOnlyDifferByTimestampAndExtension(lastSignBytes, newSignBytes []byte) (time.Time, []byte, bool) {
 	var lastVote, newVote types.CanonicalVote
 	if err := cdc.UnmarshalBinaryLengthPrefixed(lastSignBytes, &lastVote); err != nil {
 		panic(fmt.Sprintf("LastSignBytes cannot be unmarshalled into vote: %v", err))
@@ -397,16 +364,16 @@ func checkVotesOnlyDifferByTimestamp(lastSignBytes, newSignBytes []byte) (time.T
 		panic(fmt.Sprintf("signBytes cannot be unmarshalled into vote: %v", err))
 	}
 
-	lastTime := lastVote.Timestamp
+	lastTime, lastExtension := lastVote.Timestamp, lastVote.Extension
 
-	// set the times to the same value and check equality
+	// set the times and the extensions to the same value and check equality
 	now := tmtime.Now()
-	lastVote.Timestamp = now
-	newVote.Timestamp = now
+	lastVote.Timestamp, lastVote.Extension = now, nil
+	newVote.Timestamp, newVote.Extension = now, nil
 	lastVoteBytes, _ := cdc.MarshalJSON(lastVote)
 	newVoteBytes, _ := cdc.MarshalJSON(newVote)
 
-	return lastTime, bytes.Equal(newVoteBytes, lastVoteBytes)
+	return lastTime, lastExtension, bytes.Equal(newVoteBytes, lastVoteBytes)
 }
 
 // returns the timestamp from the lastSignBytes.
@@ -10,7 +10,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/sr25519"
 	"github.com/tendermint/tendermint/types"
 	tmtime "github.com/tendermint/tendermint/types/time"
 )
@@ -35,6 +38,26 @@ func TestGenLoadValidator(t *testing.T) {
 	assert.Equal(height, privVal.LastSignState.Height, "expected privval.LastHeight to have been saved")
 }
 
+func TestFilePVKeyTypes(t *testing.T) {
+	for _, privKey := range []crypto.PrivKey{secp256k1.GenPrivKey(), sr25519.GenPrivKey()} {
+		tempKeyFile, err := ioutil.TempFile("", "priv_validator_key_")
+		require.Nil(t, err)
+		tempStateFile, err := ioutil.TempFile("", "priv_validator_state_")
+		require.Nil(t, err)
+
+		privVal := NewFilePV(privKey, tempKeyFile.Name(), tempStateFile.Name())
+		privVal.Save()
+		privVal = LoadFilePV(tempKeyFile.Name(), tempStateFile.Name())
+		assert.Equal(t, privKey, privVal.Key.PrivKey)
+		assert.Equal(t, privKey.PubKey().Address(), privVal.GetAddress())
+
+		block := types.BlockID{Hash: []byte{1, 2, 3}, PartsHeader: types.PartSetHeader{}}
+		vote := newVote(privVal.Key.Address, 0, 10, 1, byte(types.PrevoteType), block)
+		require.NoError(t, privVal.SignVote("mychainid", vote), "%T", privKey)
+		assert.NoError(t, vote.Verify("mychainid", privVal.GetPubKey()), "%T", privKey)
+	}
+}
+
 func TestResetValidator(t *testing.T) {
 	tempKeyFile, err := ioutil.TempFile("", "priv_validator_key_")
 	require.Nil(t, err)
@@ -298,6 +321,31 @@ func TestDifferByTimestamp(t *testing.T) {
 		assert.Equal(t, signBytes, vote.SignBytes(chainID))
 		assert.Equal(t, sig, vote.Signature)
 	}
+
+	// test precommit with extension
+	{
+		voteType := byte(types.PrecommitType)
+		blockID := types.BlockID{Hash: []byte{1, 2, 3}, PartsHeader: types.PartSetHeader{}}
+		vote := newVote(privVal.Key.Address, 0, height, round, voteType, blockID)
+		vote.Extension = []byte("extension")
+		err := privVal.SignVote("mychainid", vote)
+		assert.NoError(t, err, "expected no error signing vote")
+
+		signBytes := vote.SignBytes(chainID)
+		sig := vote.Signature
+
+		// the application extends the precommit differently after a restart.
+		// should get changed back
+		vote.Timestamp = vote.Timestamp.Add(time.Millisecond)
+		vote.Extension = []byte("other extension")
+		vote.Signature = nil
+		err = privVal.SignVote("mychainid", vote)
+		assert.NoError(t, err, "expected no error on signing same vote")
+
+		assert.Equal(t, []byte("extension"), vote.Extension)
+		assert.Equal(t, signBytes, vote.SignBytes(chainID))
+		assert.Equal(t, sig, vote.Signature)
+	}
 }
 
 func newVote(addr types.Address, idx int, height int64, round int, typ byte, blockID types.BlockID) *types.Vote {
@@ -320,3 +368,37 @@ func newProposal(height int64, round int, blockID types.BlockID) *types.Proposal
 		Timestamp: tmtime.Now(),
 	}
 }
+
+func TestLastSignStateValidateBasic(t *testing.T) {
+	tempKeyFile, err := ioutil.TempFile("", "priv_validator_key_")
+	require.Nil(t, err)
+	tempStateFile, err := ioutil.TempFile("", "priv_validator_state_")
+	require.Nil(t, err)
+
+	privVal := GenFilePV(tempKeyFile.Name(), tempStateFile.Name())
+	privVal.Save()
+	assert.NoError(t, privVal.LastSignState.ValidateBasic())
+
+	block := types.BlockID{Hash: []byte{1, 2, 3}, PartsHeader: types.PartSetHeader{}}
+	vote := newVote(privVal.Key.Address, 0, 10, 1, byte(types.PrevoteType), block)
+	require.NoError(t, privVal.SignVote("mychainid", vote))
+	assert.NotNil(t, privVal.LastSignState.SignBytesHash)
+	assert.NoError(t, privVal.LastSignState.ValidateBasic())
+
+	// the state must survive a reload
+	loaded := LoadFilePV(tempKeyFile.Name(), tempStateFile.Name())
+	assert.Equal(t, privVal.LastSignState, loaded.LastSignState)
+
+	tampered := privVal.LastSignState
+	tampered.SignBytes = append([]byte{}, tampered.SignBytes...)
+	tampered.SignBytes[0]++
+	assert.Error(t, tampered.ValidateBasic())
+
+	tampered = privVal.LastSignState
+	tampered.Signature = nil
+	assert.Error(t, tampered.ValidateBasic())
+
+	tampered = privVal.LastSignState
+	tampered.Step = 4
+	assert.Error(t, tampered.ValidateBasic())
+}
@@ -0,0 +1,301 @@
+package privval
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// ErrSignStateConflict is returned by a SignStateStore when the requested
+// height/round/step was already signed over different sign bytes, or a
+// higher one was signed.
+var ErrSignStateConflict = errors.New("sign state conflict")
+
+// SignStateStore keeps the last sign state shared by redundant signer
+// instances, so that at most one of them signs for a given height/round/step.
+type SignStateStore interface {
+	// CheckAndSet records that the sign bytes with the given hash are about
+	// to be signed at height/round/step. Recording the same sign bytes again
+	// succeeds, so that a signer can retry after a crash.
+	CheckAndSet(height int64, round int, step int8, signBytesHash []byte) error
+}
+
+//-------------------------------------------------------------------------------
+
+// FileSignStateStore is a SignStateStore persisted to a file.
+type FileSignStateStore struct {
+	mtx   sync.Mutex
+	state FilePVLastSignState
+}
+
+var _ SignStateStore = (*FileSignStateStore)(nil)
+
+// LoadOrGenFileSignStateStore loads the store from filePath if it exists or
+// else creates an empty one and saves it.
+func LoadOrGenFileSignStateStore(filePath string) (*FileSignStateStore, error) {
+	state := FilePVLastSignState{}
+	if cmn.FileExists(filePath) {
+		bz, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		if err := cdc.UnmarshalJSON(bz, &state); err != nil {
+			return nil, fmt.Errorf("error reading sign state from %v: %v", filePath, err)
+		}
+	}
+	state.filePath = filePath
+	if err := state.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("invalid sign state in %v: %v", filePath, err)
+	}
+	state.Save()
+
+	return &FileSignStateStore{state: state}, nil
+}
+
+// CheckAndSet implements SignStateStore.
+func (s *FileSignStateStore) CheckAndSet(height int64, round int, step int8, signBytesHash []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	last := &s.state
+	switch cmp := compareHRS(height, round, step, last.Height, last.Round, last.Step); {
+	case cmp < 0:
+		return errors.Wrapf(ErrSignStateConflict, "%v/%v/%v is below the last signed %v/%v/%v",
+			height, round, step, last.Height, last.Round, last.Step)
+	case cmp == 0:
+		if !bytes.Equal(signBytesHash, last.SignBytesHash) {
+			return errors.Wrapf(ErrSignStateConflict, "%v/%v/%v was signed over different sign bytes",
+				height, round, step)
+		}
+		return nil
+	}
+
+	last.Height = height
+	last.Round = round
+	last.Step = step
+	last.SignBytesHash = signBytesHash
+	last.Save()
+	return nil
+}
+
+// compareHRS returns -1, 0 or 1 if the first height/round/step is
+// respectively below, equal to or above the second one.
+func compareHRS(h1 int64, r1 int, s1 int8, h2 int64, r2 int, s2 int8) int {
+	switch {
+	case h1 != h2:
+		if h1 < h2 {
+			return -1
+		}
+		return 1
+	case r1 != r2:
+		if r1 < r2 {
+			return -1
+		}
+		return 1
+	case s1 != s2:
+		if s1 < s2 {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+//-------------------------------------------------------------------------------
+
+const grpcSignStateCheckAndSetMethod = "/tendermint.privval.SignStateAPI/CheckAndSet"
+
+// signStateRequest is the amino encoded request of the sign state service,
+// wrapped into a grpcSignerRequest.
+type signStateRequest struct {
+	Height        int64
+	Round         int
+	Step          int8
+	SignBytesHash []byte
+}
+
+// signStateResponse is the amino encoded response of the sign state service,
+// wrapped into a grpcSignerResponse.
+type signStateResponse struct {
+	Error *RemoteSignerError
+}
+
+func grpcSignStateCheckAndSetHandler(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	in := new(grpcSignerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(*SignStateServer).checkAndSet(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: grpcSignStateCheckAndSetMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*SignStateServer).checkAndSet(ctx, req.(*grpcSignerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var grpcSignStateServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tendermint.privval.SignStateAPI",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CheckAndSet",
+			Handler:    grpcSignStateCheckAndSetHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// SignStateServer serves a SignStateStore over gRPC to redundant signer
+// instances (see SignStateClient).
+type SignStateServer struct {
+	cmn.BaseService
+
+	listenAddr string
+	store      SignStateStore
+	tlsConfig  *tls.Config
+
+	server *grpc.Server
+}
+
+// NewSignStateServer returns a SignStateServer listening on listenAddr
+// (tcp://host:port or unix://path) once started. It serves over TLS unless
+// tlsConfig is nil (see SignerGRPCServerTLSConfig).
+func NewSignStateServer(
+	logger log.Logger,
+	listenAddr string,
+	store SignStateStore,
+	tlsConfig *tls.Config,
+) *SignStateServer {
+	ss := &SignStateServer{
+		listenAddr: listenAddr,
+		store:      store,
+		tlsConfig:  tlsConfig,
+	}
+	ss.BaseService = *cmn.NewBaseService(logger, "SignStateServer", ss)
+	return ss
+}
+
+// OnStart implements cmn.Service.
+func (ss *SignStateServer) OnStart() error {
+	ln, err := listenGRPC(ss.listenAddr)
+	if err != nil {
+		return err
+	}
+
+	if ss.tlsConfig == nil {
+		ss.Logger.Error("SignStateServer: serving without TLS")
+	}
+	ss.server = newGRPCServer(ss.tlsConfig)
+	ss.server.RegisterService(&grpcSignStateServiceDesc, ss)
+
+	go func() {
+		if err := ss.server.Serve(ln); err != nil {
+			ss.Logger.Error("SignStateServer: Serve", "err", err)
+		}
+	}()
+	return nil
+}
+
+// OnStop implements cmn.Service.
+func (ss *SignStateServer) OnStop() {
+	ss.server.GracefulStop()
+}
+
+func (ss *SignStateServer) checkAndSet(ctx context.Context, in *grpcSignerRequest) (*grpcSignerResponse, error) {
+	var req signStateRequest
+	if err := cdc.UnmarshalBinaryBare(in.Msg, &req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to decode request: %v", err)
+	}
+
+	var res signStateResponse
+	if err := ss.store.CheckAndSet(req.Height, req.Round, req.Step, req.SignBytesHash); err != nil {
+		ss.Logger.Error("SignStateServer: CheckAndSet", "err", err)
+		res.Error = &RemoteSignerError{0, err.Error()}
+	}
+
+	bz, err := cdc.MarshalBinaryBare(res)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	return &grpcSignerResponse{Msg: bz}, nil
+}
+
+//-------------------------------------------------------------------------------
+
+// SignStateClient is a SignStateStore backed by a remote SignStateServer. If
+// the server can't be reached within the timeout, CheckAndSet fails and
+// nothing gets signed.
+type SignStateClient struct {
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+var _ SignStateStore = (*SignStateClient)(nil)
+
+// NewSignStateClient returns a SignStateClient for the server at addr
+// (tcp://host:port or unix://path). It connects over TLS unless tlsConfig is
+// nil (see SignerGRPCClientTLSConfig).
+func NewSignStateClient(addr string, tlsConfig *tls.Config, timeout time.Duration) (*SignStateClient, error) {
+	conn, err := dialGRPC(addr, tlsConfig, defaultGRPCMaxBackoffSeconds*time.Second)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to dial sign state server")
+	}
+	return &SignStateClient{conn: conn, timeout: timeout}, nil
+}
+
+// Close closes the underlying connection
+func (sc *SignStateClient) Close() error {
+	return sc.conn.Close()
+}
+
+// CheckAndSet implements SignStateStore.
+func (sc *SignStateClient) CheckAndSet(height int64, round int, step int8, signBytesHash []byte) error {
+	bz, err := cdc.MarshalBinaryBare(signStateRequest{
+		Height:        height,
+		Round:         round,
+		Step:          step,
+		SignBytesHash: signBytesHash,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sc.timeout)
+	defer cancel()
+	out := new(grpcSignerResponse)
+	err = sc.conn.Invoke(ctx, grpcSignStateCheckAndSetMethod, &grpcSignerRequest{Msg: bz}, out, grpc.WaitForReady(true))
+	if err != nil {
+		return errors.Wrap(err, "failed to reach sign state server")
+	}
+
+	var res signStateResponse
+	if err := cdc.UnmarshalBinaryBare(out.Msg, &res); err != nil {
+		return err
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package privval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/types"
+)
+
+func newTestFileSignStateStore(t *testing.T) (*FileSignStateStore, string) {
+	dir, err := ioutil.TempDir("", "sign_state")
+	require.NoError(t, err)
+	store, err := LoadOrGenFileSignStateStore(filepath.Join(dir, "sign_state.json"))
+	require.NoError(t, err)
+	return store, dir
+}
+
+func TestFileSignStateStore(t *testing.T) {
+	store, dir := newTestFileSignStateStore(t)
+	defer os.RemoveAll(dir)
+
+	hash1, hash2 := tmhash.Sum([]byte{1}), tmhash.Sum([]byte{2})
+
+	require.NoError(t, store.CheckAndSet(10, 1, stepPrevote, hash1))
+	// the same sign bytes may be recorded again
+	assert.NoError(t, store.CheckAndSet(10, 1, stepPrevote, hash1))
+
+	testCases := []struct {
+		height int64
+		round  int
+		step   int8
+		hash   []byte
+	}{
+		{10, 1, stepPrevote, hash2},   // different sign bytes
+		{10, 1, stepPropose, hash1},   // step regression
+		{10, 0, stepPrecommit, hash1}, // round regression
+		{9, 2, stepPrecommit, hash1},  // height regression
+	}
+	for i, tc := range testCases {
+		err := store.CheckAndSet(tc.height, tc.round, tc.step, tc.hash)
+		assert.Equal(t, ErrSignStateConflict, errors.Cause(err), "#%d", i)
+	}
+
+	require.NoError(t, store.CheckAndSet(10, 1, stepPrecommit, hash2))
+
+	// the state must survive a restart
+	reloaded, err := LoadOrGenFileSignStateStore(filepath.Join(dir, "sign_state.json"))
+	require.NoError(t, err)
+	assert.Error(t, reloaded.CheckAndSet(10, 1, stepPrecommit, hash1))
+	assert.NoError(t, reloaded.CheckAndSet(10, 1, stepPrecommit, hash2))
+}
+
+func TestFilePVSharedSignState(t *testing.T) {
+	store, dir := newTestFileSignStateStore(t)
+	defer os.RemoveAll(dir)
+
+	// two redundant instances of the same validator
+	pv1 := GenFilePV(filepath.Join(dir, "key.json"), filepath.Join(dir, "state1.json"))
+	pv2 := GenFilePV(filepath.Join(dir, "key.json"), filepath.Join(dir, "state2.json"))
+	pv2.Key = pv1.Key
+	pv1.SetSignStateStore(store)
+	pv2.SetSignStateStore(store)
+
+	block1 := types.BlockID{Hash: []byte{1, 2, 3}, PartsHeader: types.PartSetHeader{}}
+	block2 := types.BlockID{Hash: []byte{3, 2, 1}, PartsHeader: types.PartSetHeader{}}
+	voteType := byte(types.PrevoteType)
+
+	require.NoError(t, pv1.SignVote("mychainid", newVote(pv1.Key.Address, 0, 10, 1, voteType, block1)))
+	// the other instance must not sign a conflicting vote
+	assert.Error(t, pv2.SignVote("mychainid", newVote(pv2.Key.Address, 0, 10, 1, voteType, block2)))
+	// nor a proposal of an earlier step
+	assert.Error(t, pv2.SignProposal("mychainid", newProposal(10, 1, block2)))
+	// but it may take over for the next height
+	assert.NoError(t, pv2.SignVote("mychainid", newVote(pv2.Key.Address, 0, 11, 0, voteType, block2)))
+}
+
+func TestSignStateServer(t *testing.T) {
+	store, dir := newTestFileSignStateStore(t)
+	defer os.RemoveAll(dir)
+
+	addr := GetFreeLocalhostAddrPort()
+	ss := NewSignStateServer(log.TestingLogger(), addr, store, nil)
+	require.NoError(t, ss.Start())
+	defer ss.Stop()
+
+	sc, err := NewSignStateClient(addr, nil, time.Second)
+	require.NoError(t, err)
+	defer sc.Close()
+
+	hash1, hash2 := tmhash.Sum([]byte{1}), tmhash.Sum([]byte{2})
+	require.NoError(t, sc.CheckAndSet(10, 1, stepPrevote, hash1))
+	assert.NoError(t, sc.CheckAndSet(10, 1, stepPrevote, hash1))
+	assert.Error(t, sc.CheckAndSet(10, 1, stepPrevote, hash2))
+	assert.Error(t, store.CheckAndSet(10, 1, stepPrevote, hash2))
+
+	// nothing gets signed if the server is down
+	require.NoError(t, ss.Stop())
+	assert.Error(t, sc.CheckAndSet(11, 0, stepPrevote, hash1))
+}
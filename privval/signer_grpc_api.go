@@ -0,0 +1,81 @@
+package privval
+
+import (
+	"context"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+// The gRPC signer service has a single unary method, which carries the
+// amino encoded SignerMessages of the socket signer protocol. The messages
+// and the service description are thus written by hand rather than generated:
+//
+//	service PrivValidatorAPI {
+//	  rpc Handle(SignerRequest) returns (SignerResponse);
+//	}
+//
+//	message SignerRequest  { bytes msg = 1; }
+//	message SignerResponse { bytes msg = 1; }
+const (
+	grpcSignerServiceName  = "tendermint.privval.PrivValidatorAPI"
+	grpcSignerHandleMethod = "/" + grpcSignerServiceName + "/Handle"
+)
+
+// grpcSignerRequest wraps an amino encoded SignerMessage request.
+type grpcSignerRequest struct {
+	Msg []byte `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`
+}
+
+func (m *grpcSignerRequest) Reset()         { *m = grpcSignerRequest{} }
+func (m *grpcSignerRequest) String() string { return proto.CompactTextString(m) }
+func (*grpcSignerRequest) ProtoMessage()    {}
+
+// grpcSignerResponse wraps an amino encoded SignerMessage response.
+type grpcSignerResponse struct {
+	Msg []byte `protobuf:"bytes,1,opt,name=msg,proto3" json:"msg,omitempty"`
+}
+
+func (m *grpcSignerResponse) Reset()         { *m = grpcSignerResponse{} }
+func (m *grpcSignerResponse) String() string { return proto.CompactTextString(m) }
+func (*grpcSignerResponse) ProtoMessage()    {}
+
+// grpcSignerAPI is the server API of the gRPC signer service.
+type grpcSignerAPI interface {
+	Handle(context.Context, *grpcSignerRequest) (*grpcSignerResponse, error)
+}
+
+func grpcSignerHandleHandler(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	in := new(grpcSignerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(grpcSignerAPI).Handle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: grpcSignerHandleMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(grpcSignerAPI).Handle(ctx, req.(*grpcSignerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var grpcSignerServiceDesc = grpc.ServiceDesc{
+	ServiceName: grpcSignerServiceName,
+	HandlerType: (*grpcSignerAPI)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Handle",
+			Handler:    grpcSignerHandleHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
@@ -0,0 +1,270 @@
+package privval
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/tendermint/tendermint/crypto"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	defaultGRPCRequestTimeoutSeconds = 3
+	defaultGRPCMaxBackoffSeconds     = 10
+)
+
+// SignerGRPCClientOption sets an optional parameter on the SignerGRPCClient.
+type SignerGRPCClientOption func(*SignerGRPCClient)
+
+// SignerGRPCClientTLS connects to the signer over TLS. Use a config with a
+// client certificate (see SignerGRPCClientTLSConfig) for mutual TLS.
+func SignerGRPCClientTLS(tlsConfig *tls.Config) SignerGRPCClientOption {
+	return func(sc *SignerGRPCClient) { sc.tlsConfig = tlsConfig }
+}
+
+// SignerGRPCClientTimeout sets the timeout of each request, including the
+// time spent waiting for the signer to (re)connect.
+func SignerGRPCClientTimeout(timeout time.Duration) SignerGRPCClientOption {
+	return func(sc *SignerGRPCClient) { sc.timeout = timeout }
+}
+
+// SignerGRPCClientMaxBackoff sets the maximum delay between reconnection
+// attempts.
+func SignerGRPCClientMaxBackoff(maxBackoff time.Duration) SignerGRPCClientOption {
+	return func(sc *SignerGRPCClient) { sc.maxBackoff = maxBackoff }
+}
+
+// SignerGRPCClient implements PrivValidator.
+// Handles a connection to a remote signer serving gRPC (see
+// SignerGRPCServer). The connection is reestablished with an exponential
+// backoff whenever it drops.
+type SignerGRPCClient struct {
+	logger log.Logger
+
+	addr       string
+	tlsConfig  *tls.Config
+	timeout    time.Duration
+	maxBackoff time.Duration
+
+	conn *grpc.ClientConn
+}
+
+var _ types.PrivValidator = (*SignerGRPCClient)(nil)
+
+// NewSignerGRPCClient returns a SignerGRPCClient for the signer at addr
+// (tcp://host:port or unix://path). It doesn't wait for the connection; see
+// WaitForConnection.
+func NewSignerGRPCClient(
+	logger log.Logger,
+	addr string,
+	options ...SignerGRPCClientOption,
+) (*SignerGRPCClient, error) {
+	sc := &SignerGRPCClient{
+		logger:     logger,
+		addr:       addr,
+		timeout:    defaultGRPCRequestTimeoutSeconds * time.Second,
+		maxBackoff: defaultGRPCMaxBackoffSeconds * time.Second,
+	}
+	for _, option := range options {
+		option(sc)
+	}
+
+	if sc.tlsConfig == nil {
+		logger.Error("SignerGRPCClient: connecting without TLS", "addr", addr)
+	}
+	conn, err := dialGRPC(addr, sc.tlsConfig, sc.maxBackoff)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to dial remote signer")
+	}
+	sc.conn = conn
+
+	return sc, nil
+}
+
+// dialGRPC returns a connection to addr (tcp://host:port or unix://path),
+// which is reestablished with an exponential backoff of up to maxBackoff
+// whenever it drops. The connection uses TLS unless tlsConfig is nil.
+func dialGRPC(addr string, tlsConfig *tls.Config, maxBackoff time.Duration) (*grpc.ClientConn, error) {
+	protocol, address := cmn.ProtocolAndAddress(addr)
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, protocol, address)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithContextDialer(dialer),
+		grpc.WithBackoffConfig(grpc.BackoffConfig{MaxDelay: maxBackoff}),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+
+	// The passthrough resolver hands the address over to the dialer as is.
+	return grpc.Dial("passthrough:///"+address, opts...)
+}
+
+// Close closes the underlying connection
+func (sc *SignerGRPCClient) Close() error {
+	return sc.conn.Close()
+}
+
+// IsConnected indicates with the signer is connected to a remote signing service
+func (sc *SignerGRPCClient) IsConnected() bool {
+	return sc.conn.GetState() == connectivity.Ready
+}
+
+// WaitForConnection waits maxWait for the remote signer to be connected and
+// healthy or returns a timeout error
+func (sc *SignerGRPCClient) WaitForConnection(maxWait time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), maxWait)
+	defer cancel()
+	if err := sc.checkHealth(ctx); err != nil {
+		if ctx.Err() != nil {
+			return ErrConnectionTimeout
+		}
+		return err
+	}
+	return nil
+}
+
+func (sc *SignerGRPCClient) checkHealth(ctx context.Context) error {
+	res, err := healthpb.NewHealthClient(sc.conn).Check(
+		ctx,
+		&healthpb.HealthCheckRequest{Service: grpcSignerServiceName},
+		grpc.WaitForReady(true),
+	)
+	if err != nil {
+		return err
+	}
+	if res.Status != healthpb.HealthCheckResponse_SERVING {
+		return errors.Errorf("remote signer is not serving (status: %v)", res.Status)
+	}
+	return nil
+}
+
+// sendRequest sends req to the remote signer, waiting up to the request
+// timeout for the signer to (re)connect.
+func (sc *SignerGRPCClient) sendRequest(req SignerMessage) (SignerMessage, error) {
+	bz, err := cdc.MarshalBinaryBare(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sc.timeout)
+	defer cancel()
+	out := new(grpcSignerResponse)
+	err = sc.conn.Invoke(ctx, grpcSignerHandleMethod, &grpcSignerRequest{Msg: bz}, out, grpc.WaitForReady(true))
+	if err != nil {
+		return nil, err
+	}
+
+	var res SignerMessage
+	if err := cdc.UnmarshalBinaryBare(out.Msg, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//--------------------------------------------------------
+// Implement PrivValidator
+
+// Ping checks the health of the remote signer
+func (sc *SignerGRPCClient) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), sc.timeout)
+	defer cancel()
+	return sc.checkHealth(ctx)
+}
+
+// GetPubKey retrieves a public key from a remote signer
+func (sc *SignerGRPCClient) GetPubKey() crypto.PubKey {
+	response, err := sc.sendRequest(&PubKeyRequest{})
+	if err != nil {
+		sc.logger.Error("SignerGRPCClient::GetPubKey", "err", err)
+		return nil
+	}
+
+	pubKeyResp, ok := response.(*PubKeyResponse)
+	if !ok {
+		sc.logger.Error("SignerGRPCClient::GetPubKey", "err", "response != PubKeyResponse")
+		return nil
+	}
+
+	if pubKeyResp.Error != nil {
+		sc.logger.Error("failed to get private validator's public key", "err", pubKeyResp.Error)
+		return nil
+	}
+
+	return pubKeyResp.PubKey
+}
+
+// SignVote requests a remote signer to sign a vote
+func (sc *SignerGRPCClient) SignVote(chainID string, vote *types.Vote) error {
+	response, err := sc.sendRequest(&SignVoteRequest{Vote: vote})
+	if err != nil {
+		sc.logger.Error("SignerGRPCClient::SignVote", "err", err)
+		return err
+	}
+
+	resp, ok := response.(*SignedVoteResponse)
+	if !ok {
+		sc.logger.Error("SignerGRPCClient::SignVote", "err", "response != SignedVoteResponse")
+		return ErrUnexpectedResponse
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+	*vote = *resp.Vote
+
+	return nil
+}
+
+// SignProposal requests a remote signer to sign a proposal
+func (sc *SignerGRPCClient) SignProposal(chainID string, proposal *types.Proposal) error {
+	response, err := sc.sendRequest(&SignProposalRequest{Proposal: proposal})
+	if err != nil {
+		sc.logger.Error("SignerGRPCClient::SignProposal", "err", err)
+		return err
+	}
+
+	resp, ok := response.(*SignedProposalResponse)
+	if !ok {
+		sc.logger.Error("SignerGRPCClient::SignProposal", "err", "response != SignedProposalResponse")
+		return ErrUnexpectedResponse
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	*proposal = *resp.Proposal
+
+	return nil
+}
+
+// SignerGRPCClientTLSConfig returns a TLS config presenting the given client
+// certificate and verifying the signer's certificate against rootCAFile.
+func SignerGRPCClientTLSConfig(certFile, keyFile, rootCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load client certificate")
+	}
+	rootCAs, err := loadCertPool(rootCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      rootCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
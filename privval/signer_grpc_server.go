@@ -0,0 +1,170 @@
+package privval
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/types"
+)
+
+// SignerGRPCServerOption sets an optional parameter on the SignerGRPCServer.
+type SignerGRPCServerOption func(*SignerGRPCServer)
+
+// SignerGRPCServerTLS serves the signer over TLS. Use a config requiring
+// client certificates (see SignerGRPCServerTLSConfig) for mutual TLS.
+func SignerGRPCServerTLS(tlsConfig *tls.Config) SignerGRPCServerOption {
+	return func(ss *SignerGRPCServer) { ss.tlsConfig = tlsConfig }
+}
+
+// SignerGRPCServer serves signature requests for its privVal over gRPC. It
+// also serves the standard gRPC health checking service, so the signer can
+// run behind load balancers and orchestrators.
+type SignerGRPCServer struct {
+	cmn.BaseService
+
+	listenAddr string
+	chainID    string
+	privVal    types.PrivValidator
+	tlsConfig  *tls.Config
+
+	server       *grpc.Server
+	healthServer *health.Server
+
+	handlerMtx               sync.Mutex
+	validationRequestHandler ValidationRequestHandlerFunc
+}
+
+var _ grpcSignerAPI = (*SignerGRPCServer)(nil)
+
+// NewSignerGRPCServer returns a SignerGRPCServer listening on listenAddr
+// (tcp://host:port or unix://path) once started.
+func NewSignerGRPCServer(
+	logger log.Logger,
+	listenAddr string,
+	chainID string,
+	privVal types.PrivValidator,
+	options ...SignerGRPCServerOption,
+) *SignerGRPCServer {
+	ss := &SignerGRPCServer{
+		listenAddr:               listenAddr,
+		chainID:                  chainID,
+		privVal:                  privVal,
+		validationRequestHandler: DefaultValidationRequestHandler,
+	}
+	ss.BaseService = *cmn.NewBaseService(logger, "SignerGRPCServer", ss)
+
+	for _, option := range options {
+		option(ss)
+	}
+
+	return ss
+}
+
+// OnStart implements cmn.Service.
+func (ss *SignerGRPCServer) OnStart() error {
+	ln, err := listenGRPC(ss.listenAddr)
+	if err != nil {
+		return err
+	}
+
+	if ss.tlsConfig == nil {
+		ss.Logger.Error("SignerGRPCServer: serving without TLS")
+	}
+	ss.server = newGRPCServer(ss.tlsConfig)
+	ss.server.RegisterService(&grpcSignerServiceDesc, ss)
+
+	ss.healthServer = health.NewServer()
+	ss.healthServer.SetServingStatus(grpcSignerServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(ss.server, ss.healthServer)
+
+	go func() {
+		if err := ss.server.Serve(ln); err != nil {
+			ss.Logger.Error("SignerGRPCServer: Serve", "err", err)
+		}
+	}()
+	return nil
+}
+
+// OnStop implements cmn.Service.
+func (ss *SignerGRPCServer) OnStop() {
+	ss.healthServer.Shutdown()
+	ss.server.GracefulStop()
+}
+
+// SetRequestHandler override the default function that is used to service requests
+func (ss *SignerGRPCServer) SetRequestHandler(validationRequestHandler ValidationRequestHandlerFunc) {
+	ss.handlerMtx.Lock()
+	defer ss.handlerMtx.Unlock()
+	ss.validationRequestHandler = validationRequestHandler
+}
+
+// Handle implements grpcSignerAPI.
+func (ss *SignerGRPCServer) Handle(ctx context.Context, req *grpcSignerRequest) (*grpcSignerResponse, error) {
+	var msg SignerMessage
+	if err := cdc.UnmarshalBinaryBare(req.Msg, &msg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to decode request: %v", err)
+	}
+
+	ss.handlerMtx.Lock()
+	res, err := ss.validationRequestHandler(ss.privVal, msg, ss.chainID)
+	ss.handlerMtx.Unlock()
+	if err != nil {
+		// only log the error; we'll reply with an error in res
+		ss.Logger.Error("SignerGRPCServer: handleMessage", "err", err)
+	}
+	if res == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "unhandled request: %v", err)
+	}
+
+	bz, err := cdc.MarshalBinaryBare(res)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	return &grpcSignerResponse{Msg: bz}, nil
+}
+
+func listenGRPC(listenAddr string) (net.Listener, error) {
+	protocol, address := cmn.ProtocolAndAddress(listenAddr)
+	return net.Listen(protocol, address)
+}
+
+// newGRPCServer returns a gRPC server using TLS unless tlsConfig is nil.
+func newGRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	var opts []grpc.ServerOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	return grpc.NewServer(opts...)
+}
+
+// SignerGRPCServerTLSConfig returns a TLS config for the signer, presenting
+// the given certificate and requiring clients to present a certificate
+// signed by a CA of clientCAFile.
+func SignerGRPCServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load signer certificate")
+	}
+	clientCAs, err := loadCertPool(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
@@ -0,0 +1,180 @@
+package privval
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/types"
+)
+
+func startGRPCSigner(t *testing.T, addr, chainID string, pv types.PrivValidator,
+	opts ...SignerGRPCServerOption) *SignerGRPCServer {
+	ss := NewSignerGRPCServer(log.TestingLogger(), addr, chainID, pv, opts...)
+	require.NoError(t, ss.Start())
+	return ss
+}
+
+func TestSignerGRPC(t *testing.T) {
+	unixFilePath, err := testUnixAddr()
+	require.NoError(t, err)
+
+	for _, addr := range []string{GetFreeLocalhostAddrPort(), "unix://" + unixFilePath} {
+		chainID := common.RandStr(12)
+		mockPV := types.NewMockPV()
+		ss := startGRPCSigner(t, addr, chainID, mockPV)
+		sc, err := NewSignerGRPCClient(log.TestingLogger(), addr)
+		require.NoError(t, err)
+
+		require.NoError(t, sc.WaitForConnection(time.Second))
+		assert.True(t, sc.IsConnected())
+		assert.NoError(t, sc.Ping())
+		assert.Equal(t, mockPV.GetPubKey(), sc.GetPubKey())
+
+		ts := time.Now()
+		wantVote := &types.Vote{Timestamp: ts, Type: types.PrecommitType}
+		haveVote := &types.Vote{Timestamp: ts, Type: types.PrecommitType}
+		require.NoError(t, mockPV.SignVote(chainID, wantVote))
+		require.NoError(t, sc.SignVote(chainID, haveVote))
+		assert.Equal(t, wantVote.Signature, haveVote.Signature)
+
+		wantProposal := &types.Proposal{Timestamp: ts}
+		haveProposal := &types.Proposal{Timestamp: ts}
+		require.NoError(t, mockPV.SignProposal(chainID, wantProposal))
+		require.NoError(t, sc.SignProposal(chainID, haveProposal))
+		assert.Equal(t, wantProposal.Signature, haveProposal.Signature)
+
+		// Errors of the signer are returned to the client.
+		ss.privVal = types.NewErroringMockPV()
+		err = sc.SignVote(chainID, &types.Vote{Timestamp: ts, Type: types.PrecommitType})
+		require.Error(t, err)
+		assert.Equal(t, types.ErroringMockPVErr.Error(), err.(*RemoteSignerError).Description)
+
+		assert.NoError(t, sc.Close())
+		assert.NoError(t, ss.Stop())
+	}
+}
+
+func TestSignerGRPCReconnect(t *testing.T) {
+	addr := GetFreeLocalhostAddrPort()
+	chainID := common.RandStr(12)
+	mockPV := types.NewMockPV()
+
+	ss := startGRPCSigner(t, addr, chainID, mockPV)
+	sc, err := NewSignerGRPCClient(log.TestingLogger(), addr,
+		SignerGRPCClientTimeout(5*time.Second), SignerGRPCClientMaxBackoff(100*time.Millisecond))
+	require.NoError(t, err)
+	defer sc.Close()
+	require.NoError(t, sc.WaitForConnection(time.Second))
+
+	// The signer restarts; the client reconnects on its own.
+	require.NoError(t, ss.Stop())
+	ss = startGRPCSigner(t, addr, chainID, mockPV)
+	defer ss.Stop()
+
+	vote := &types.Vote{Timestamp: time.Now(), Type: types.PrecommitType}
+	require.NoError(t, sc.SignVote(chainID, vote))
+	assert.NotEmpty(t, vote.Signature)
+}
+
+func TestSignerGRPCMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer_grpc_tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	caCert, caKey := writeTestCA(t, dir)
+	writeTestCert(t, dir, "server", caCert, caKey)
+	writeTestCert(t, dir, "client", caCert, caKey)
+	writeTestCert(t, dir, "other", nil, nil) // self-signed
+	file := func(name string) string { return filepath.Join(dir, name) }
+
+	serverTLS, err := SignerGRPCServerTLSConfig(file("server.crt"), file("server.key"), file("ca.crt"))
+	require.NoError(t, err)
+	addr := GetFreeLocalhostAddrPort()
+	chainID := common.RandStr(12)
+	ss := startGRPCSigner(t, addr, chainID, types.NewMockPV(), SignerGRPCServerTLS(serverTLS))
+	defer ss.Stop()
+
+	clientTLS, err := SignerGRPCClientTLSConfig(file("client.crt"), file("client.key"), file("ca.crt"))
+	require.NoError(t, err)
+	sc, err := NewSignerGRPCClient(log.TestingLogger(), addr, SignerGRPCClientTLS(clientTLS))
+	require.NoError(t, err)
+	defer sc.Close()
+	require.NoError(t, sc.WaitForConnection(time.Second))
+	assert.NotNil(t, sc.GetPubKey())
+
+	// A client certificate which isn't signed by the CA is rejected.
+	otherTLS, err := SignerGRPCClientTLSConfig(file("other.crt"), file("other.key"), file("ca.crt"))
+	require.NoError(t, err)
+	other, err := NewSignerGRPCClient(log.TestingLogger(), addr, SignerGRPCClientTLS(otherTLS))
+	require.NoError(t, err)
+	defer other.Close()
+	assert.Error(t, other.WaitForConnection(500*time.Millisecond))
+}
+
+func writeTestCA(t *testing.T, dir string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	return writeTestCert(t, dir, "ca", nil, nil)
+}
+
+// writeTestCert writes <name>.crt and <name>.key to dir, signed by the parent
+// certificate or self-signed if parent is nil.
+func writeTestCert(
+	t *testing.T,
+	dir, name string,
+	parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	writePEM := func(file, typ string, bz []byte) {
+		err := ioutil.WriteFile(filepath.Join(dir, file), pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: bz}), 0600)
+		require.NoError(t, err)
+	}
+	writePEM(fmt.Sprintf("%s.crt", name), "CERTIFICATE", der)
+	writePEM(fmt.Sprintf("%s.key", name), "EC PRIVATE KEY", keyDer)
+
+	// make sure the files form a valid key pair
+	_, err = tls.LoadX509KeyPair(filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key"))
+	require.NoError(t, err)
+
+	return cert, key
+}
@@ -0,0 +1,164 @@
+package privval
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/tendermint/tendermint/crypto"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ErrThresholdNotSigned is returned by a ThresholdSigner when the coordinator
+// explicitly refused the request, so that none of the key shares signed it.
+var ErrThresholdNotSigned = errors.New("threshold signer: request refused, not signed")
+
+// ThresholdSigner is the backend of a ThresholdPV. Implementations forward
+// sign requests to an external coordinator which runs the multi-party
+// signing protocol among the key share holders and returns the combined
+// signature. The coordinator is never asked to sign anything which would
+// violate the watermark kept by ThresholdPV, which is moved before the
+// request.
+type ThresholdSigner interface {
+	// GetPubKey returns the combined public key of the threshold group.
+	GetPubKey() crypto.PubKey
+
+	// Sign requests a signature over signBytes. It must return a signature
+	// which verifies against GetPubKey, or an error. ErrThresholdNotSigned
+	// must only be returned if the coordinator refused the request: any other
+	// error (e.g. a timeout) is taken to mean the shares may have signed.
+	Sign(signBytes []byte) ([]byte, error)
+}
+
+// ThresholdPV implements PrivValidator on top of a ThresholdSigner. It keeps
+// the same double signing protection as FilePV: the height/round/step is
+// persisted to disk as a pending intent before the signature is requested,
+// and with the signature before it is returned. If the node crashes while
+// the signature is requested, the height/round/step is found without a
+// signature on restart and isn't signed again, as the coordinator may have
+// signed it already.
+type ThresholdPV struct {
+	mtx sync.Mutex
+
+	signer        ThresholdSigner
+	LastSignState FilePVLastSignState
+}
+
+var _ types.PrivValidator = (*ThresholdPV)(nil)
+
+// NewThresholdPV returns a ThresholdPV with an empty sign state which will be
+// persisted to stateFilePath. It does not call Save().
+func NewThresholdPV(signer ThresholdSigner, stateFilePath string) *ThresholdPV {
+	return &ThresholdPV{
+		signer: signer,
+		LastSignState: FilePVLastSignState{
+			Step:     stepNone,
+			filePath: stateFilePath,
+		},
+	}
+}
+
+// LoadThresholdPV returns a ThresholdPV whose sign state is read from
+// stateFilePath.
+func LoadThresholdPV(signer ThresholdSigner, stateFilePath string) (*ThresholdPV, error) {
+	stateJSONBytes, err := ioutil.ReadFile(stateFilePath)
+	if err != nil {
+		return nil, err
+	}
+	pvState := FilePVLastSignState{}
+	if err := cdc.UnmarshalJSON(stateJSONBytes, &pvState); err != nil {
+		return nil, fmt.Errorf("error reading PrivValidator state from %v: %v", stateFilePath, err)
+	}
+	if err := pvState.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("invalid PrivValidator state in %v: %v", stateFilePath, err)
+	}
+	pvState.filePath = stateFilePath
+
+	return &ThresholdPV{
+		signer:        signer,
+		LastSignState: pvState,
+	}, nil
+}
+
+// LoadOrGenThresholdPV loads the sign state from stateFilePath if it exists or
+// else creates an empty one and saves it.
+func LoadOrGenThresholdPV(signer ThresholdSigner, stateFilePath string) (*ThresholdPV, error) {
+	if cmn.FileExists(stateFilePath) {
+		return LoadThresholdPV(signer, stateFilePath)
+	}
+	pv := NewThresholdPV(signer, stateFilePath)
+	pv.LastSignState.Save()
+	return pv, nil
+}
+
+// GetPubKey returns the combined public key of the threshold group.
+// Implements PrivValidator.
+func (pv *ThresholdPV) GetPubKey() crypto.PubKey {
+	return pv.signer.GetPubKey()
+}
+
+// SignVote checks the vote against the watermark and requests a signature
+// from the threshold signer. Implements PrivValidator.
+func (pv *ThresholdPV) SignVote(chainID string, vote *types.Vote) error {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	sign := pv.signWithIntent(vote.Height, vote.Round, voteToStep(vote))
+	if err := signVoteWatermarked(&pv.LastSignState, nil, chainID, vote, sign); err != nil {
+		return fmt.Errorf("error signing vote: %v", err)
+	}
+	return nil
+}
+
+// SignProposal checks the proposal against the watermark and requests a
+// signature from the threshold signer. Implements PrivValidator.
+func (pv *ThresholdPV) SignProposal(chainID string, proposal *types.Proposal) error {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	sign := pv.signWithIntent(proposal.Height, proposal.Round, stepPropose)
+	if err := signProposalWatermarked(&pv.LastSignState, nil, chainID, proposal, sign); err != nil {
+		return fmt.Errorf("error signing proposal: %v", err)
+	}
+	return nil
+}
+
+// signWithIntent returns a signFunc which persists height/round/step as a
+// pending intent, without sign bytes, before requesting the signature from
+// the threshold signer: CheckHRS then refuses to sign it again after a
+// crash. The intent is kept if the signer returns an error, as the shares may
+// have signed anyway, unless the coordinator refused the request
+// (ErrThresholdNotSigned).
+func (pv *ThresholdPV) signWithIntent(height int64, round int, step int8) signFunc {
+	return func(signBytes []byte) ([]byte, error) {
+		lss := &pv.LastSignState
+		last := *lss
+		lss.Height, lss.Round, lss.Step = height, round, step
+		lss.Signature, lss.SignBytes, lss.SignBytesHash = nil, nil, nil
+		lss.Save()
+
+		sig, err := pv.signer.Sign(signBytes)
+		if err == ErrThresholdNotSigned {
+			*lss = last
+			lss.Save()
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		return sig, nil
+	}
+}
+
+// String returns a string representation of the ThresholdPV.
+func (pv *ThresholdPV) String() string {
+	return fmt.Sprintf(
+		"ThresholdPV{%v LH:%v, LR:%v, LS:%v}",
+		pv.GetPubKey().Address(),
+		pv.LastSignState.Height,
+		pv.LastSignState.Round,
+		pv.LastSignState.Step,
+	)
+}
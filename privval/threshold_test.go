@@ -0,0 +1,139 @@
+package privval
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/types"
+)
+
+// mockThresholdSigner signs with a single local key and counts requests.
+type mockThresholdSigner struct {
+	privKey crypto.PrivKey
+	calls   int
+	err     error
+	onSign  func() // called before signing, e.g. to simulate a crash
+}
+
+func (m *mockThresholdSigner) GetPubKey() crypto.PubKey { return m.privKey.PubKey() }
+
+func (m *mockThresholdSigner) Sign(signBytes []byte) ([]byte, error) {
+	m.calls++
+	if m.onSign != nil {
+		m.onSign()
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.privKey.Sign(signBytes)
+}
+
+func TestThresholdPVSignVote(t *testing.T) {
+	tempStateFile, err := ioutil.TempFile("", "priv_validator_state_")
+	require.Nil(t, err)
+	defer os.Remove(tempStateFile.Name())
+
+	signer := &mockThresholdSigner{privKey: ed25519.GenPrivKey()}
+	privVal := NewThresholdPV(signer, tempStateFile.Name())
+
+	block1 := types.BlockID{Hash: []byte{1, 2, 3}, PartsHeader: types.PartSetHeader{}}
+	block2 := types.BlockID{Hash: []byte{3, 2, 1}, PartsHeader: types.PartSetHeader{}}
+	height, round := int64(10), 1
+	addr := signer.GetPubKey().Address()
+
+	vote := newVote(addr, 0, height, round, byte(types.PrevoteType), block1)
+	require.NoError(t, privVal.SignVote("mychainid", vote))
+	assert.True(t, signer.GetPubKey().VerifyBytes(vote.SignBytes("mychainid"), vote.Signature))
+	assert.Equal(t, 1, signer.calls)
+
+	// signing the same vote again reuses the signature without asking the coordinator
+	vote = newVote(addr, 0, height, round, byte(types.PrevoteType), block1)
+	require.NoError(t, privVal.SignVote("mychainid", vote))
+	assert.Equal(t, 1, signer.calls)
+
+	// conflicting vote is rejected before reaching the coordinator
+	vote = newVote(addr, 0, height, round, byte(types.PrevoteType), block2)
+	assert.Error(t, privVal.SignVote("mychainid", vote))
+	assert.Equal(t, 1, signer.calls)
+
+	// regression is rejected
+	vote = newVote(addr, 0, height-1, round, byte(types.PrevoteType), block1)
+	assert.Error(t, privVal.SignVote("mychainid", vote))
+
+	// state survives a reload
+	loaded, err := LoadThresholdPV(signer, tempStateFile.Name())
+	require.NoError(t, err)
+	assert.Equal(t, height, loaded.LastSignState.Height)
+	assert.Equal(t, stepPrevote, loaded.LastSignState.Step)
+}
+
+func TestThresholdPVSignerError(t *testing.T) {
+	tempStateFile, err := ioutil.TempFile("", "priv_validator_state_")
+	require.Nil(t, err)
+	defer os.Remove(tempStateFile.Name())
+
+	signer := &mockThresholdSigner{privKey: ed25519.GenPrivKey(), err: errors.New("coordinator unavailable")}
+	privVal := NewThresholdPV(signer, tempStateFile.Name())
+
+	block := types.BlockID{Hash: []byte{1, 2, 3}, PartsHeader: types.PartSetHeader{}}
+	proposal := newProposal(10, 1, block)
+	assert.Error(t, privVal.SignProposal("mychainid", proposal))
+
+	// the shares may have signed before the error: the intent is kept and
+	// the proposal isn't signed again
+	assert.EqualValues(t, 10, privVal.LastSignState.Height)
+	assert.Equal(t, stepPropose, privVal.LastSignState.Step)
+	signer.err = nil
+	assert.Error(t, privVal.SignProposal("mychainid", newProposal(10, 1, block)))
+	assert.Equal(t, 1, signer.calls)
+
+	// a refused request doesn't move the watermark
+	signer.err = ErrThresholdNotSigned
+	proposal = newProposal(11, 1, block)
+	assert.Error(t, privVal.SignProposal("mychainid", proposal))
+	assert.EqualValues(t, 10, privVal.LastSignState.Height)
+}
+
+func TestThresholdPVCrashWhileSigning(t *testing.T) {
+	tempStateFile, err := ioutil.TempFile("", "priv_validator_state_")
+	require.Nil(t, err)
+	defer os.Remove(tempStateFile.Name())
+
+	signer := &mockThresholdSigner{privKey: ed25519.GenPrivKey()}
+	privVal := NewThresholdPV(signer, tempStateFile.Name())
+
+	block1 := types.BlockID{Hash: []byte{1, 2, 3}, PartsHeader: types.PartSetHeader{}}
+	block2 := types.BlockID{Hash: []byte{3, 2, 1}, PartsHeader: types.PartSetHeader{}}
+	height, round := int64(10), 1
+	addr := signer.GetPubKey().Address()
+
+	// the node crashes once the coordinator is asked to sign: the state on
+	// disk is the one it restarts with
+	var restarted *ThresholdPV
+	signer.onSign = func() {
+		restarted, err = LoadThresholdPV(signer, tempStateFile.Name())
+		require.NoError(t, err)
+	}
+	vote := newVote(addr, 0, height, round, byte(types.PrevoteType), block1)
+	require.NoError(t, privVal.SignVote("mychainid", vote))
+	signer.onSign = nil
+
+	// the pending intent keeps the restarted node from signing a conflicting
+	// vote, which the coordinator would sign too
+	assert.Equal(t, height, restarted.LastSignState.Height)
+	assert.Equal(t, stepPrevote, restarted.LastSignState.Step)
+	vote = newVote(addr, 0, height, round, byte(types.PrevoteType), block2)
+	assert.Error(t, restarted.SignVote("mychainid", vote))
+	assert.Equal(t, 1, signer.calls)
+
+	// the next steps are signed
+	vote = newVote(addr, 0, height, round, byte(types.PrecommitType), block2)
+	assert.NoError(t, restarted.SignVote("mychainid", vote))
+}
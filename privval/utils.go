@@ -1,7 +1,9 @@
 package privval
 
 import (
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net"
 
 	"github.com/pkg/errors"
@@ -60,3 +62,16 @@ func GetFreeLocalhostAddrPort() string {
 	}
 	return fmt.Sprintf("127.0.0.1:%d", port)
 }
+
+// loadCertPool returns a pool of the PEM encoded certificates of caFile.
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read CA certificates")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no CA certificates found in %s", caFile)
+	}
+	return pool, nil
+}
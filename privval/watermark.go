@@ -0,0 +1,140 @@
+package privval
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"github.com/tendermint/tendermint/types"
+)
+
+// signFunc produces a signature over the given sign bytes. It is backed by a
+// local private key for FilePV and by an external coordinator for ThresholdPV.
+type signFunc func(signBytes []byte) ([]byte, error)
+
+// signVoteWatermarked checks the vote against the last sign state (the
+// "watermark") and, if set, the shared sign state store, signs it with sign
+// and persists the new state before setting the vote signature.
+// It may need to set the timestamp and the extension as well if the vote is
+// otherwise the same as a previously signed vote (ie. we crashed after signing
+// but before the vote hit the WAL).
+func signVoteWatermarked(
+	lss *FilePVLastSignState,
+	store SignStateStore,
+	chainID string,
+	vote *types.Vote,
+	sign signFunc,
+) error {
+	height, round, step := vote.Height, vote.Round, voteToStep(vote)
+
+	sameHRS, err := lss.CheckHRS(height, round, step)
+	if err != nil {
+		return err
+	}
+
+	signBytes := vote.SignBytes(chainID)
+
+	// We might crash before writing to the wal,
+	// causing us to try to re-sign for the same HRS.
+	// If signbytes are the same, use the last signature.
+	// If they only differ by timestamp and extension, use last timestamp,
+	// extension and signature
+	// Otherwise, return error
+	if sameHRS {
+		if bytes.Equal(signBytes, lss.SignBytes) {
+			vote.Signature = lss.Signature
+		} else if timestamp, extension, ok := checkVotesOnlyDifferByTimestampAndExtension(
+			lss.SignBytes, signBytes); ok {
+			vote.Timestamp = timestamp
+			vote.Extension = extension
+			vote.Signature = lss.Signature
+		} else {
+			err = fmt.Errorf("conflicting data")
+		}
+		return err
+	}
+
+	// Redundant signer instances must not sign for the same HRS.
+	if store != nil {
+		if err := store.CheckAndSet(height, round, step, tmhash.Sum(signBytes)); err != nil {
+			return err
+		}
+	}
+
+	// It passed the checks. Sign the vote
+	sig, err := sign(signBytes)
+	if err != nil {
+		return err
+	}
+	lss.saveSigned(height, round, step, signBytes, sig)
+	vote.Signature = sig
+	return nil
+}
+
+// signProposalWatermarked checks the proposal against the last sign state
+// and, if set, the shared sign state store, signs it with sign and persists
+// the new state before setting the proposal signature.
+// It may need to set the timestamp as well if the proposal is otherwise the same as
+// a previously signed proposal ie. we crashed after signing but before the proposal hit the WAL).
+func signProposalWatermarked(
+	lss *FilePVLastSignState,
+	store SignStateStore,
+	chainID string,
+	proposal *types.Proposal,
+	sign signFunc,
+) error {
+	height, round, step := proposal.Height, proposal.Round, stepPropose
+
+	sameHRS, err := lss.CheckHRS(height, round, step)
+	if err != nil {
+		return err
+	}
+
+	signBytes := proposal.SignBytes(chainID)
+
+	// We might crash before writing to the wal,
+	// causing us to try to re-sign for the same HRS.
+	// If signbytes are the same, use the last signature.
+	// If they only differ by timestamp, use last timestamp and signature
+	// Otherwise, return error
+	if sameHRS {
+		if bytes.Equal(signBytes, lss.SignBytes) {
+			proposal.Signature = lss.Signature
+		} else if timestamp, ok := checkProposalsOnlyDifferByTimestamp(lss.SignBytes, signBytes); ok {
+			proposal.Timestamp = timestamp
+			proposal.Signature = lss.Signature
+		} else {
+			err = fmt.Errorf("conflicting data")
+		}
+		return err
+	}
+
+	// Redundant signer instances must not sign for the same HRS.
+	if store != nil {
+		if err := store.CheckAndSet(height, round, step, tmhash.Sum(signBytes)); err != nil {
+			return err
+		}
+	}
+
+	// It passed the checks. Sign the proposal
+	sig, err := sign(signBytes)
+	if err != nil {
+		return err
+	}
+	lss.saveSigned(height, round, step, signBytes, sig)
+	proposal.Signature = sig
+	return nil
+}
+
+// Persist height/round/step and signature
+func (lss *FilePVLastSignState) saveSigned(height int64, round int, step int8,
+	signBytes []byte, sig []byte) {
+
+	lss.Height = height
+	lss.Round = round
+	lss.Step = step
+	lss.Signature = sig
+	lss.SignBytes = signBytes
+	lss.SignBytesHash = tmhash.Sum(signBytes)
+	lss.Save()
+}
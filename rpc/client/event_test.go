@@ -1,6 +1,7 @@
 package client_test
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -59,13 +60,24 @@ func TestBlockEvents(t *testing.T) {
 				defer c.Stop()
 			}
 
-			// listen for a new block; ensure height increases by 1
+			// listen for new blocks on a single subscription, so that none is
+			// missed; ensure height increases by 1
+			ctx, cancel := context.WithTimeout(context.Background(), waitForEventTimeout)
+			defer cancel()
+			query := types.EventQueryNewBlock.String()
+			eventCh, err := c.Subscribe(ctx, "TestBlockEvents", query, 3)
+			require.NoError(t, err)
+			defer c.Unsubscribe(context.Background(), "TestBlockEvents", query) // nolint: errcheck
+
 			var firstBlockHeight int64
 			for j := 0; j < 3; j++ {
-				evtTyp := types.EventNewBlock
-				evt, err := client.WaitForOneEvent(c, evtTyp, waitForEventTimeout)
-				require.Nil(t, err, "%d: %+v", j, err)
-				blockEvent, ok := evt.(types.EventDataNewBlock)
+				var evt ctypes.ResultEvent
+				select {
+				case evt = <-eventCh:
+				case <-ctx.Done():
+					t.Fatalf("%d: timed out waiting for event", j)
+				}
+				blockEvent, ok := evt.Data.(types.EventDataNewBlock)
 				require.True(t, ok, "%d: %#v", j, evt)
 
 				block := blockEvent.Block
@@ -98,13 +110,17 @@ func testTxEventsSent(t *testing.T, broadcastMethod string) {
 
 			// make the tx
 			_, _, tx := MakeTxKV()
-			evtTyp := types.EventTx
+
+			// subscribe before sending, so the tx can't be included in between
+			ctx, cancel := context.WithTimeout(context.Background(), waitForEventTimeout)
+			defer cancel()
+			query := types.EventQueryTxFor(tx).String()
+			eventCh, err := c.Subscribe(ctx, "testTxEventsSent", query)
+			require.NoError(t, err)
+			defer c.Unsubscribe(context.Background(), "testTxEventsSent", query) // nolint: errcheck
 
 			// send
-			var (
-				txres *ctypes.ResultBroadcastTx
-				err   error
-			)
+			var txres *ctypes.ResultBroadcastTx
 			switch broadcastMethod {
 			case "async":
 				txres, err = c.BroadcastTxAsync(tx)
@@ -118,10 +134,14 @@ func testTxEventsSent(t *testing.T, broadcastMethod string) {
 			require.Equal(t, txres.Code, abci.CodeTypeOK)
 
 			// and wait for confirmation
-			evt, err := client.WaitForOneEvent(c, evtTyp, waitForEventTimeout)
-			require.Nil(t, err, "%d: %+v", i, err)
+			var evt ctypes.ResultEvent
+			select {
+			case evt = <-eventCh:
+			case <-ctx.Done():
+				t.Fatalf("%d: timed out waiting for event", i)
+			}
 			// and make sure it has the proper info
-			txe, ok := evt.(types.EventDataTx)
+			txe, ok := evt.Data.(types.EventDataTx)
 			require.True(t, ok, "%d: %#v", i, evt)
 			// make sure this is the proper tx
 			require.EqualValues(t, tx, txe.Tx)
@@ -130,6 +150,32 @@ func testTxEventsSent(t *testing.T, broadcastMethod string) {
 	}
 }
 
+func TestBroadcastTxAndWait(t *testing.T) {
+	for i, c := range GetClients() {
+		i, c := i, c // capture params
+		t.Run(reflect.TypeOf(c).String(), func(t *testing.T) {
+			if !c.IsRunning() {
+				err := c.Start()
+				require.Nil(t, err, "%d: %+v", i, err)
+				defer c.Stop()
+			}
+
+			_, _, tx := MakeTxKV()
+			res, err := client.BroadcastTxAndWait(c, tx, waitForEventTimeout)
+			require.NoError(t, err, "%d", i)
+			require.True(t, res.CheckTx.IsOK())
+			require.True(t, res.DeliverTx.IsOK())
+			require.EqualValues(t, types.Tx(tx).Hash(), res.Hash)
+			require.True(t, res.Height > 0)
+
+			// the tx is in the block of the height returned
+			txRes, err := c.Tx(res.Hash, false)
+			require.NoError(t, err)
+			require.Equal(t, res.Height, txRes.Height)
+		})
+	}
+}
+
 // Test HTTPClient resubscribes upon disconnect && subscription error.
 // Test Local client resubscribes upon subscription error.
 func TestClientsResubscribe(t *testing.T) {
@@ -2,9 +2,13 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/pkg/errors"
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -75,3 +79,62 @@ func WaitForOneEvent(c EventsClient, evtTyp string, timeout time.Duration) (type
 		return nil, errors.New("timed out waiting for event")
 	}
 }
+
+// TxBroadcaster broadcasts txs and subscribes to their inclusion in a block.
+type TxBroadcaster interface {
+	ABCIClient
+	EventsClient
+}
+
+// BroadcastTxAndWait broadcasts the tx with BroadcastTxSync, and waits for it
+// to be included in a block for up to timeout, subscribed to the Tx event of
+// the tx. It returns the result of the tx and the height of its block, like
+// BroadcastTxCommit, with the CheckTx result only if the tx is rejected, and
+// a ctypes.ErrTimeout error if the tx isn't included in time.
+//
+// Unlike BroadcastTxCommit, the node doesn't hold a request and a subscription
+// open for each tx until it's committed: the client waits on its own event
+// subscription, which is safe under load. For the HTTP client, the
+// subscription requires the websocket client to be started.
+func BroadcastTxAndWait(c TxBroadcaster, tx types.Tx, timeout time.Duration) (*ctypes.ResultBroadcastTxCommit, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Subscribe before broadcasting, so the tx can't be included in between.
+	subscriber := fmt.Sprintf("broadcast-%X-%s", tx.Hash(), cmn.RandStr(8))
+	query := types.EventQueryTxFor(tx).String()
+	eventCh, err := c.Subscribe(ctx, subscriber, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to subscribe to tx")
+	}
+	defer c.Unsubscribe(context.Background(), subscriber, query) // nolint: errcheck
+
+	res, err := c.BroadcastTxSync(tx)
+	if err != nil {
+		return nil, err
+	}
+	result := &ctypes.ResultBroadcastTxCommit{
+		CheckTx: abci.ResponseCheckTx{
+			Code: res.Code,
+			Data: res.Data,
+			Log:  res.Log,
+		},
+		Hash: res.Hash,
+	}
+	if res.Code != abci.CodeTypeOK {
+		return result, nil
+	}
+
+	select {
+	case event := <-eventCh:
+		txEvent, ok := event.Data.(types.EventDataTx)
+		if !ok {
+			return result, errors.Errorf("unexpected event data %T", event.Data)
+		}
+		result.DeliverTx = txEvent.Result
+		result.Height = txEvent.Height
+		return result, nil
+	case <-ctx.Done():
+		return result, ctypes.ErrTimeout{Reason: "Timed out waiting for tx to be included in a block"}
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"strings"
 	"sync"
@@ -15,6 +16,7 @@ import (
 	tmpubsub "github.com/tendermint/tendermint/libs/pubsub"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpcclient "github.com/tendermint/tendermint/rpc/lib/client"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -228,6 +230,15 @@ func (c *baseRPCClient) NumUnconfirmedTxs() (*ctypes.ResultUnconfirmedTxs, error
 	return result, nil
 }
 
+func (c *baseRPCClient) RejectedTxs(limit int) (*ctypes.ResultRejectedTxs, error) {
+	result := new(ctypes.ResultRejectedTxs)
+	_, err := c.caller.Call("rejected_txs", map[string]interface{}{"limit": limit}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "rejected_txs")
+	}
+	return result, nil
+}
+
 func (c *baseRPCClient) NetInfo() (*ctypes.ResultNetInfo, error) {
 	result := new(ctypes.ResultNetInfo)
 	_, err := c.caller.Call("net_info", map[string]interface{}{}, result)
@@ -255,6 +266,15 @@ func (c *baseRPCClient) ConsensusState() (*ctypes.ResultConsensusState, error) {
 	return result, nil
 }
 
+func (c *baseRPCClient) ConsensusRounds(height *int64) (*ctypes.ResultConsensusRounds, error) {
+	result := new(ctypes.ResultConsensusRounds)
+	_, err := c.caller.Call("consensus_rounds", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "ConsensusRounds")
+	}
+	return result, nil
+}
+
 func (c *baseRPCClient) Health() (*ctypes.ResultHealth, error) {
 	result := new(ctypes.ResultHealth)
 	_, err := c.caller.Call("health", map[string]interface{}{}, result)
@@ -324,13 +344,14 @@ func (c *baseRPCClient) Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	return result, nil
 }
 
-func (c *baseRPCClient) TxSearch(query string, prove bool, page, perPage int) (*ctypes.ResultTxSearch, error) {
+func (c *baseRPCClient) TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error) {
 	result := new(ctypes.ResultTxSearch)
 	params := map[string]interface{}{
 		"query":    query,
 		"prove":    prove,
 		"page":     page,
 		"per_page": perPage,
+		"order_by": orderBy,
 	}
 	_, err := c.caller.Call("tx_search", params, result)
 	if err != nil {
@@ -339,15 +360,44 @@ func (c *baseRPCClient) TxSearch(query string, prove bool, page, perPage int) (*
 	return result, nil
 }
 
-func (c *baseRPCClient) Validators(height *int64) (*ctypes.ResultValidators, error) {
+func (c *baseRPCClient) BlockSearch(query string, page, perPage int, orderBy string) (*ctypes.ResultBlockSearch, error) {
+	result := new(ctypes.ResultBlockSearch)
+	params := map[string]interface{}{
+		"query":    query,
+		"page":     page,
+		"per_page": perPage,
+		"order_by": orderBy,
+	}
+	_, err := c.caller.Call("block_search", params, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "BlockSearch")
+	}
+	return result, nil
+}
+
+func (c *baseRPCClient) Validators(height *int64, page, perPage int) (*ctypes.ResultValidators, error) {
 	result := new(ctypes.ResultValidators)
-	_, err := c.caller.Call("validators", map[string]interface{}{"height": height}, result)
+	params := map[string]interface{}{
+		"height":   height,
+		"page":     page,
+		"per_page": perPage,
+	}
+	_, err := c.caller.Call("validators", params, result)
 	if err != nil {
 		return nil, errors.Wrap(err, "Validators")
 	}
 	return result, nil
 }
 
+func (c *baseRPCClient) ValidatorChanges(height *int64) (*ctypes.ResultValidatorChanges, error) {
+	result := new(ctypes.ResultValidatorChanges)
+	_, err := c.caller.Call("validator_changes", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "ValidatorChanges")
+	}
+	return result, nil
+}
+
 func (c *baseRPCClient) BroadcastEvidence(ev types.Evidence) (*ctypes.ResultBroadcastEvidence, error) {
 	result := new(ctypes.ResultBroadcastEvidence)
 	_, err := c.caller.Call("broadcast_evidence", map[string]interface{}{"evidence": ev}, result)
@@ -370,14 +420,19 @@ type WSEvents struct {
 	mtx sync.RWMutex
 	// query -> chan
 	subscriptions map[string]chan ctypes.ResultEvent
+	// request ID -> chan of the response error, for the Subscribe calls
+	// waiting for the response of the node
+	pendingSubscribes map[rpctypes.JSONRPCStringID]chan *rpctypes.RPCError
+	lastSubscribeID   uint64
 }
 
 func newWSEvents(cdc *amino.Codec, remote, endpoint string) *WSEvents {
 	wsEvents := &WSEvents{
-		cdc:           cdc,
-		endpoint:      endpoint,
-		remote:        remote,
-		subscriptions: make(map[string]chan ctypes.ResultEvent),
+		cdc:               cdc,
+		endpoint:          endpoint,
+		remote:            remote,
+		subscriptions:     make(map[string]chan ctypes.ResultEvent),
+		pendingSubscribes: make(map[rpctypes.JSONRPCStringID]chan *rpctypes.RPCError),
 	}
 
 	wsEvents.BaseService = *cmn.NewBaseService(nil, "WSEvents", wsEvents)
@@ -408,30 +463,68 @@ func (w *WSEvents) OnStop() {
 
 // Subscribe implements EventsClient by using WSClient to subscribe given
 // subscriber to query. By default, returns a channel with cap=1. Error is
-// returned if it fails to subscribe.
+// returned if it fails to subscribe. It returns once the node has subscribed,
+// so that the events published after it returns aren't missed.
 // Channel is never closed to prevent clients from seeing an erroneus event.
 func (w *WSEvents) Subscribe(ctx context.Context, subscriber, query string,
 	outCapacity ...int) (out <-chan ctypes.ResultEvent, err error) {
 
-	if err := w.ws.Subscribe(ctx, query); err != nil {
-		return nil, err
-	}
-
 	outCap := 1
 	if len(outCapacity) > 0 {
 		outCap = outCapacity[0]
 	}
 
 	outc := make(chan ctypes.ResultEvent, outCap)
+	respc := make(chan *rpctypes.RPCError, 1)
 	w.mtx.Lock()
+	w.lastSubscribeID++
+	id := rpctypes.JSONRPCStringID(fmt.Sprintf("ws-client-subscribe-%d", w.lastSubscribeID))
 	// subscriber param is ignored because Tendermint will override it with
-	// remote IP anyway.
+	// remote IP anyway. The events may be received before the response.
 	w.subscriptions[query] = outc
+	w.pendingSubscribes[id] = respc
+	w.mtx.Unlock()
+
+	err = w.subscribe(ctx, id, query, respc)
+
+	w.mtx.Lock()
+	delete(w.pendingSubscribes, id)
+	if err != nil && w.subscriptions[query] == outc {
+		delete(w.subscriptions, query)
+	}
 	w.mtx.Unlock()
 
+	if err != nil {
+		return nil, err
+	}
 	return outc, nil
 }
 
+// subscribe sends the subscribe request with the given ID, and waits for the
+// response of the node, received by eventListener on respc.
+func (w *WSEvents) subscribe(ctx context.Context, id rpctypes.JSONRPCStringID, query string,
+	respc <-chan *rpctypes.RPCError) error {
+
+	params := map[string]interface{}{"query": query}
+	request, err := rpctypes.MapToRequest(w.cdc, id, "subscribe", params)
+	if err != nil {
+		return err
+	}
+	if err := w.ws.Send(ctx, request); err != nil {
+		return err
+	}
+
+	select {
+	case rpcErr := <-respc:
+		if rpcErr != nil && !isErrAlreadySubscribed(rpcErr) {
+			return rpcErr
+		}
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "waiting for the node to subscribe")
+	}
+}
+
 // Unsubscribe implements EventsClient by using WSClient to unsubscribe given
 // subscriber from query.
 func (w *WSEvents) Unsubscribe(ctx context.Context, subscriber, query string) error {
@@ -490,6 +583,17 @@ func (w *WSEvents) eventListener() {
 				return
 			}
 
+			// the response to a Subscribe call is passed on to it
+			if id, ok := resp.ID.(rpctypes.JSONRPCStringID); ok {
+				w.mtx.RLock()
+				respc, ok := w.pendingSubscribes[id]
+				w.mtx.RUnlock()
+				if ok {
+					respc <- resp.Error
+					continue
+				}
+			}
+
 			if resp.Error != nil {
 				w.Logger.Error("WS error", "err", resp.Error.Error())
 				// Error can be ErrAlreadySubscribed or max client (subscriptions per
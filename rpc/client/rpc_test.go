@@ -9,6 +9,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -21,6 +22,7 @@ import (
 	"github.com/tendermint/tendermint/rpc/client"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpcclient "github.com/tendermint/tendermint/rpc/lib/client"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
 	rpctest "github.com/tendermint/tendermint/rpc/test"
 	"github.com/tendermint/tendermint/types"
 )
@@ -131,6 +133,34 @@ func TestConsensusState(t *testing.T) {
 	}
 }
 
+func TestConsensusRounds(t *testing.T) {
+	for i, c := range GetClients() {
+		nc, ok := c.(client.NetworkClient)
+		require.True(t, ok, "%d", i)
+
+		// the rounds of the last block are all there
+		require.NoError(t, client.WaitForHeight(c, 1, nil))
+		s, err := c.Status()
+		require.Nil(t, err, "%d: %+v", i, err)
+		height := s.SyncInfo.LatestBlockHeight
+		rounds, err := nc.ConsensusRounds(&height)
+		require.Nil(t, err, "%d: %+v", i, err)
+		assert.Equal(t, height, rounds.Height)
+		require.Len(t, rounds.Validators, 1)
+		require.NotEmpty(t, rounds.Rounds)
+		last := rounds.Rounds[len(rounds.Rounds)-1]
+		assert.Equal(t, rounds.Validators[0], last.Proposer)
+		assert.False(t, last.ProposalTime.IsZero())
+		assert.False(t, last.Prevotes[0].IsZero())
+		assert.False(t, last.Precommits[0].IsZero())
+
+		// future heights aren't available
+		future := height + 100
+		_, err = nc.ConsensusRounds(&future)
+		assert.Error(t, err)
+	}
+}
+
 func TestHealth(t *testing.T) {
 	for i, c := range GetClients() {
 		nc, ok := c.(client.NetworkClient)
@@ -151,9 +181,11 @@ func TestGenesisAndValidators(t *testing.T) {
 		gval := gen.Genesis.Validators[0]
 
 		// get the current validators
-		vals, err := c.Validators(nil)
+		vals, err := c.Validators(nil, 0, 0)
 		require.Nil(t, err, "%d: %+v", i, err)
 		require.Equal(t, 1, len(vals.Validators))
+		require.Equal(t, 1, vals.Count)
+		require.Equal(t, 1, vals.Total)
 		val := vals.Validators[0]
 
 		// make sure the current set is also the genesis set
@@ -162,6 +194,25 @@ func TestGenesisAndValidators(t *testing.T) {
 	}
 }
 
+func TestValidatorChanges(t *testing.T) {
+	for i, c := range GetClients() {
+		require.NoError(t, client.WaitForHeight(c, 1, nil))
+
+		// the validator set doesn't change
+		changes, err := c.ValidatorChanges(nil)
+		require.Nil(t, err, "%d: %+v", i, err)
+		assert.Equal(t, changes.Height+2, changes.EffectiveHeight)
+		assert.Empty(t, changes.Added)
+		assert.Empty(t, changes.Removed)
+		assert.Empty(t, changes.PowerChanges)
+
+		// future heights aren't available
+		future := changes.Height + 100
+		_, err = c.ValidatorChanges(&future)
+		assert.Error(t, err)
+	}
+}
+
 func TestABCIQuery(t *testing.T) {
 	for i, c := range GetClients() {
 		// write something
@@ -404,6 +455,40 @@ func TestTx(t *testing.T) {
 	}
 }
 
+func TestErrorCodes(t *testing.T) {
+	c := getHTTPClient()
+	status, err := c.Status()
+	require.Nil(t, err, "%+v", err)
+	future := status.SyncInfo.LatestBlockHeight + 1000
+	missingTxHash := types.Tx("a tx never sent").Hash()
+
+	for i, c := range GetClients() {
+		t.Logf("client %d", i)
+
+		_, err := c.Block(&future)
+		assert.Equal(t, ctypes.CodeHeightNotAvailable, errorCode(t, err))
+
+		_, err = c.Tx(missingTxHash, false)
+		assert.Equal(t, ctypes.CodeTxNotFound, errorCode(t, err))
+
+		// the tx is committed, so that the txs of the other tests are
+		// alone in their blocks, and stays in the cache of the mempool
+		_, _, tx := MakeTxKV()
+		_, err = c.BroadcastTxCommit(tx)
+		require.Nil(t, err, "%+v", err)
+		_, err = c.BroadcastTxSync(tx)
+		assert.Equal(t, ctypes.CodeTxInCache, errorCode(t, err))
+	}
+}
+
+// errorCode returns the JSON-RPC code of the error.
+func errorCode(t *testing.T, err error) int {
+	require.Error(t, err)
+	cerr, ok := errors.Cause(err).(rpctypes.CodedError)
+	require.True(t, ok, "error %v has no code", err)
+	return cerr.RPCCode()
+}
+
 func TestTxSearch(t *testing.T) {
 	// first we broadcast a tx
 	c := getHTTPClient()
@@ -421,7 +506,7 @@ func TestTxSearch(t *testing.T) {
 
 		// now we query for the tx.
 		// since there's only one tx, we know index=0.
-		result, err := c.TxSearch(fmt.Sprintf("tx.hash='%v'", txHash), true, 1, 30)
+		result, err := c.TxSearch(fmt.Sprintf("tx.hash='%v'", txHash), true, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		require.Len(t, result.Txs, 1)
 
@@ -439,33 +524,77 @@ func TestTxSearch(t *testing.T) {
 		}
 
 		// query by height
-		result, err = c.TxSearch(fmt.Sprintf("tx.height=%d", txHeight), true, 1, 30)
+		result, err = c.TxSearch(fmt.Sprintf("tx.height=%d", txHeight), true, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		require.Len(t, result.Txs, 1)
 
 		// query for non existing tx
-		result, err = c.TxSearch(fmt.Sprintf("tx.hash='%X'", anotherTxHash), false, 1, 30)
+		result, err = c.TxSearch(fmt.Sprintf("tx.hash='%X'", anotherTxHash), false, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		require.Len(t, result.Txs, 0)
 
 		// query using a tag (see kvstore application)
-		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko'", false, 1, 30)
+		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko'", false, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		if len(result.Txs) == 0 {
 			t.Fatal("expected a lot of transactions")
 		}
 
 		// query using a tag (see kvstore application) and height
-		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko' AND tx.height<10000", true, 1, 30)
+		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko' AND tx.height<10000", true, 1, 30, "asc")
 		require.Nil(t, err, "%+v", err)
 		if len(result.Txs) == 0 {
 			t.Fatal("expected a lot of transactions")
 		}
 
 		// query a non existing tx with page 1 and txsPerPage 1
-		result, err = c.TxSearch("app.creator='Cosmoshi Neetowoko'", true, 1, 1)
+		result, err = c.TxSearch("app.creator='Cosmoshi Neetowoko'", true, 1, 1, "asc")
 		require.Nil(t, err, "%+v", err)
 		require.Len(t, result.Txs, 0)
+
+		// check the ordering of the results
+		result, err = c.TxSearch("app.creator='Cosmoshi Netowoko'", false, 1, 30, "desc")
+		require.Nil(t, err, "%+v", err)
+		for k := 1; k < len(result.Txs); k++ {
+			assert.True(t, result.Txs[k-1].Height >= result.Txs[k].Height)
+		}
+
+		// an invalid order is rejected
+		_, err = c.TxSearch("app.creator='Cosmoshi Netowoko'", false, 1, 30, "sideways")
+		require.Error(t, err)
+	}
+}
+
+func TestBlockSearch(t *testing.T) {
+	// make sure there is at least one committed block
+	c := getHTTPClient()
+	_, _, tx := MakeTxKV()
+	bres, err := c.BroadcastTxCommit(tx)
+	require.Nil(t, err, "%+v", err)
+
+	for i, c := range GetClients() {
+		t.Logf("client %d", i)
+
+		result, err := c.BlockSearch(fmt.Sprintf("block.height = %d", bres.Height), 1, 30, "asc")
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, result.Blocks, 1)
+		assert.EqualValues(t, bres.Height, result.Blocks[0].Block.Height)
+
+		result, err = c.BlockSearch(fmt.Sprintf("block.height <= %d", bres.Height), 1, 1, "asc")
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, result.Blocks, 1)
+		assert.True(t, result.TotalCount >= 1)
+		assert.EqualValues(t, 1, result.Blocks[0].Block.Height)
+
+		result, err = c.BlockSearch(fmt.Sprintf("block.height <= %d", bres.Height), 1, 1, "desc")
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, result.Blocks, 1)
+		assert.EqualValues(t, bres.Height, result.Blocks[0].Block.Height)
+
+		// query for a block which does not exist yet
+		result, err = c.BlockSearch(fmt.Sprintf("block.height = %d", bres.Height+1000), 1, 30, "asc")
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, result.Blocks, 0)
 	}
 }
 
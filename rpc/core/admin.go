@@ -0,0 +1,135 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+	"github.com/tendermint/tendermint/store/archive"
+)
+
+// SyncStatus returns the state of the fast sync: the height of the pool, its
+// pending requests and its peers, for fast sync v0. Admin only.
+func (env *Environment) SyncStatus(ctx *rpctypes.Context) (*ctypes.ResultSyncStatus, error) {
+	if env.fastSync == nil {
+		return nil, errors.New("the fast sync version can't be inspected")
+	}
+	status, err := env.fastSync.SyncStatusJSON()
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultSyncStatus{SyncStatus: status}, nil
+}
+
+// PauseSync stops requesting and applying blocks until ResumeSync, and
+// returns the state of the fast sync. Admin only.
+func (env *Environment) PauseSync(ctx *rpctypes.Context) (*ctypes.ResultSyncStatus, error) {
+	if env.fastSync == nil {
+		return nil, errors.New("the fast sync version can't be paused")
+	}
+	if err := env.fastSync.PauseSync(); err != nil {
+		return nil, err
+	}
+	return env.SyncStatus(ctx)
+}
+
+// ResumeSync resumes the fast sync paused by PauseSync, and returns its
+// state. Admin only.
+func (env *Environment) ResumeSync(ctx *rpctypes.Context) (*ctypes.ResultSyncStatus, error) {
+	if env.fastSync == nil {
+		return nil, errors.New("the fast sync version can't be paused")
+	}
+	if err := env.fastSync.ResumeSync(); err != nil {
+		return nil, err
+	}
+	return env.SyncStatus(ctx)
+}
+
+// SetLogLevel changes the log level of the node, in the log_level config
+// format (e.g. "consensus:debug,*:info"), until it is restarted or its config
+// reloaded. Admin only.
+func (env *Environment) SetLogLevel(ctx *rpctypes.Context, level string) (*ctypes.ResultSetLogLevel, error) {
+	if env.setLogLevel == nil {
+		return nil, errors.New("the log level can't be changed")
+	}
+	if err := env.setLogLevel(level); err != nil {
+		return nil, err
+	}
+	env.logger.Info("Changed the log level", "level", level)
+	return &ctypes.ResultSetLogLevel{}, nil
+}
+
+// PruneBlocks removes the blocks below height from the block store, and
+// returns the number of blocks pruned. The blocks of a node archiving them
+// are only pruned by the archiver. Admin only.
+func (env *Environment) PruneBlocks(ctx *rpctypes.Context, height int64) (*ctypes.ResultPruneBlocks, error) {
+	store, ok := env.blockStore.(archive.PrunableBlockStore)
+	if !ok {
+		return nil, errors.New("the block store can't be pruned")
+	}
+	if env.blockArchive != nil {
+		return nil, errors.New("the blocks are archived, and pruned by the archiver (see retain_blocks)")
+	}
+	if height <= 0 || height > store.Height() {
+		return nil, fmt.Errorf("height must be within 1 and the latest height %d", store.Height())
+	}
+	pruned, err := store.PruneBlocks(height)
+	if err != nil {
+		return nil, err
+	}
+	env.logger.Info("Pruned blocks", "pruned", pruned, "base", store.Base())
+	return &ctypes.ResultPruneBlocks{Pruned: pruned, Base: store.Base()}, nil
+}
+
+// EvictTx removes the transaction of the given hash from the mempool, without
+// keeping it in the cache, so that it can be resubmitted. Admin only.
+func (env *Environment) EvictTx(ctx *rpctypes.Context, hash []byte) (*ctypes.ResultEvictTx, error) {
+	mem, ok := env.mempool.(interface{ EvictTx(hash []byte) bool })
+	if !ok {
+		return nil, errors.New("the mempool can't evict transactions")
+	}
+	if !mem.EvictTx(hash) {
+		return nil, fmt.Errorf("tx %X not found in the mempool", hash)
+	}
+	env.logger.Info("Evicted tx from the mempool", "hash", fmt.Sprintf("%X", hash))
+	return &ctypes.ResultEvictTx{}, nil
+}
+
+// BackfillResults starts recomputing the block results missing up to height
+// in the background, by re-executing the blocks against the backfill app,
+// and returns the progress of the backfill. Admin only.
+func (env *Environment) BackfillResults(ctx *rpctypes.Context, height int64) (*ctypes.ResultBackfillStatus, error) {
+	if env.backfiller == nil {
+		return nil, errors.New("the block results can't be backfilled (see backfill_proxy_app)")
+	}
+	if err := env.backfiller.StartBackfill(height); err != nil {
+		return nil, err
+	}
+	env.logger.Info("Started backfilling the block results", "to", height)
+	return env.BackfillStatus(ctx)
+}
+
+// BackfillStatus returns the progress of the last backfill of the block
+// results. Admin only.
+func (env *Environment) BackfillStatus(ctx *rpctypes.Context) (*ctypes.ResultBackfillStatus, error) {
+	if env.backfiller == nil {
+		return nil, errors.New("the block results can't be backfilled (see backfill_proxy_app)")
+	}
+	return &ctypes.ResultBackfillStatus{Backfill: env.backfiller.BackfillStatus()}, nil
+}
+
+// CompactDB compacts the database of the given ID (e.g. "blockstore",
+// "state"), or all the databases of the node if db is empty, to reclaim the
+// space of the deleted data, e.g. pruned blocks. Admin only.
+func (env *Environment) CompactDB(ctx *rpctypes.Context, db string) (*ctypes.ResultCompactDB, error) {
+	if env.dbCompactor == nil {
+		return nil, errors.New("the databases can't be compacted")
+	}
+	compacted, err := env.dbCompactor.CompactDB(db)
+	if err != nil {
+		return nil, err
+	}
+	env.logger.Info("Compacted databases", "dbs", compacted)
+	return &ctypes.ResultCompactDB{Compacted: compacted}, nil
+}
@@ -2,11 +2,14 @@ package core
 
 import (
 	"fmt"
+	"sort"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
+	tmquery "github.com/tendermint/tendermint/libs/pubsub/query"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
 	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store/archive"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -69,25 +72,28 @@ import (
 // ```
 //
 // <aside class="notice">Returns at most 20 items.</aside>
-func BlockchainInfo(ctx *rpctypes.Context, minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error) {
+func (env *Environment) BlockchainInfo(
+	ctx *rpctypes.Context,
+	minHeight, maxHeight int64,
+) (*ctypes.ResultBlockchainInfo, error) {
 
 	// maximum 20 block metas
 	const limit int64 = 20
 	var err error
-	minHeight, maxHeight, err = filterMinMax(blockStore.Height(), minHeight, maxHeight, limit)
+	minHeight, maxHeight, err = filterMinMax(env.blockStore.Height(), minHeight, maxHeight, limit)
 	if err != nil {
 		return nil, err
 	}
-	logger.Debug("BlockchainInfoHandler", "maxHeight", maxHeight, "minHeight", minHeight)
+	env.logger.Debug("BlockchainInfoHandler", "maxHeight", maxHeight, "minHeight", minHeight)
 
 	blockMetas := []*types.BlockMeta{}
 	for height := maxHeight; height >= minHeight; height-- {
-		blockMeta := blockStore.LoadBlockMeta(height)
+		blockMeta := env.blockStore.LoadBlockMeta(height)
 		blockMetas = append(blockMetas, blockMeta)
 	}
 
 	return &ctypes.ResultBlockchainInfo{
-		LastHeight: blockStore.Height(),
+		LastHeight: env.blockStore.Height(),
 		BlockMetas: blockMetas}, nil
 }
 
@@ -229,15 +235,30 @@ func filterMinMax(height, min, max, limit int64) (int64, int64, error) {
 //   "jsonrpc": "2.0"
 // }
 // ```
-func Block(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultBlock, error) {
-	storeHeight := blockStore.Height()
+func (env *Environment) Block(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultBlock, error) {
+	storeHeight := env.blockStore.Height()
 	height, err := getHeight(storeHeight, heightPtr)
 	if err != nil {
 		return nil, err
 	}
 
-	blockMeta := blockStore.LoadBlockMeta(height)
-	block := blockStore.LoadBlock(height)
+	// Blocks below the base were pruned, after being archived if enabled.
+	if base := env.blockStore.Base(); height < base {
+		if env.blockArchive == nil {
+			return nil, ctypes.ErrHeightPruned{Height: height, Base: base}
+		}
+		blockMeta, block, err := env.blockArchive.LoadBlock(ctx.Context(), height)
+		if err == archive.ErrNotFound {
+			return nil, ctypes.ErrHeightPruned{Height: height, Base: base}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load archived block %v: %v", height, err)
+		}
+		return &ctypes.ResultBlock{BlockMeta: blockMeta, Block: block}, nil
+	}
+
+	blockMeta := env.blockStore.LoadBlockMeta(height)
+	block := env.blockStore.LoadBlock(height)
 	return &ctypes.ResultBlock{BlockMeta: blockMeta, Block: block}, nil
 }
 
@@ -318,24 +339,27 @@ func Block(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultBlock, error)
 //   "jsonrpc": "2.0"
 // }
 // ```
-func Commit(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultCommit, error) {
-	storeHeight := blockStore.Height()
+func (env *Environment) Commit(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultCommit, error) {
+	storeHeight := env.blockStore.Height()
 	height, err := getHeight(storeHeight, heightPtr)
 	if err != nil {
 		return nil, err
 	}
+	if base := env.blockStore.Base(); height < base {
+		return nil, ctypes.ErrHeightPruned{Height: height, Base: base}
+	}
 
-	header := blockStore.LoadBlockMeta(height).Header
+	header := env.blockStore.LoadBlockMeta(height).Header
 
 	// If the next block has not been committed yet,
 	// use a non-canonical commit
 	if height == storeHeight {
-		commit := blockStore.LoadSeenCommit(height)
+		commit := env.blockStore.LoadSeenCommit(height)
 		return ctypes.NewResultCommit(&header, commit, false), nil
 	}
 
 	// Return the canonical commit (comes from the block at height+1)
-	commit := blockStore.LoadBlockCommit(height)
+	commit := env.blockStore.LoadBlockCommit(height)
 	return ctypes.NewResultCommit(&header, commit, true), nil
 }
 
@@ -346,6 +370,10 @@ func Commit(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultCommit, erro
 // Thus response.results.deliver_tx[5] is the results of executing
 // getBlock(h).Txs[5]
 //
+// The results missing from the node (e.g. removed by a pruning tool) can be
+// recomputed by an operator with the admin backfill_results, if the node has
+// a backfill app (backfill_proxy_app).
+//
 // ```shell
 // curl 'localhost:26657/block_results?height=10'
 // ```
@@ -388,14 +416,14 @@ func Commit(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultCommit, erro
 //   }
 // }
 // ```
-func BlockResults(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultBlockResults, error) {
-	storeHeight := blockStore.Height()
+func (env *Environment) BlockResults(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultBlockResults, error) {
+	storeHeight := env.blockStore.Height()
 	height, err := getHeight(storeHeight, heightPtr)
 	if err != nil {
 		return nil, err
 	}
 
-	results, err := sm.LoadABCIResponses(stateDB, height)
+	results, err := sm.LoadABCIResponses(env.stateDB, height)
 	if err != nil {
 		return nil, err
 	}
@@ -407,14 +435,121 @@ func BlockResults(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultBlockR
 	return res, nil
 }
 
+// BlockSearch searches for a paginated set of blocks matching BeginBlock and
+// EndBlock event search criteria. It returns a list of blocks (maximum
+// ?per_page entries) and the total count.
+//
+// ```shell
+// curl "localhost:26657/block_search?query=\"slash.reason='double_sign'\"&page=1&per_page=30"
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:26657", "/websocket")
+// err := client.Start()
+// if err != nil {
+//   // handle error
+// }
+// defer client.Stop()
+// result, err := client.BlockSearch("slash.reason='double_sign'", 1, 30)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+//   "jsonrpc": "2.0",
+//   "id": "",
+//   "result": {
+//     "blocks": [
+//       {
+//         "block_meta": {...},
+//         "block": {...}
+//       }
+//     ],
+//     "total_count": "1"
+//   }
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type   | Default | Required | Description                                 |
+// |-----------+--------+---------+----------+---------------------------------------------|
+// | query     | string | ""      | true     | Query (e.g. "block.height > 5 AND a.b='c'") |
+// | page      | int    | 1       | false    | Page number (1-based)                       |
+// | per_page  | int    | 30      | false    | Number of entries per page (max: 100)       |
+// | order_by  | string | "asc"   | false    | Order by height ("asc" or "desc")           |
+//
+// ### Returns
+//
+// - `blocks`: the matching blocks, ordered by height
+// - `total_count`: `int` - total number of matching blocks
+func (env *Environment) BlockSearch(
+	ctx *rpctypes.Context,
+	query string,
+	page, perPage int,
+	orderBy string,
+) (*ctypes.ResultBlockSearch, error) {
+	sink, err := env.getKVEventSink()
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := validateOrderBy(orderBy)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := tmquery.New(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := sink.SearchBlockEvents(ctx.Context(), q)
+	if err != nil {
+		return nil, err
+	}
+
+	// sort the results, so that pages are stable across requests
+	if desc {
+		sort.Slice(results, func(i, j int) bool { return results[i] > results[j] })
+	} else {
+		sort.Slice(results, func(i, j int) bool { return results[i] < results[j] })
+	}
+
+	totalCount := len(results)
+	perPage = validatePerPage(perPage)
+	page, err = validatePage(page, perPage, totalCount)
+	if err != nil {
+		return nil, err
+	}
+	skipCount := validateSkipCount(page, perPage)
+
+	apiResults := make([]*ctypes.ResultBlock, 0, cmn.MinInt(perPage, totalCount-skipCount))
+	for i := skipCount; i < skipCount+cap(apiResults); i++ {
+		height := results[i]
+		blockMeta := env.blockStore.LoadBlockMeta(height)
+		if blockMeta == nil {
+			// the block may have been indexed but not stored (yet)
+			continue
+		}
+		apiResults = append(apiResults, &ctypes.ResultBlock{
+			BlockMeta: blockMeta,
+			Block:     env.blockStore.LoadBlock(height),
+		})
+	}
+
+	return &ctypes.ResultBlockSearch{Blocks: apiResults, TotalCount: totalCount}, nil
+}
+
 func getHeight(currentHeight int64, heightPtr *int64) (int64, error) {
 	if heightPtr != nil {
 		height := *heightPtr
 		if height <= 0 {
-			return 0, fmt.Errorf("Height must be greater than 0")
+			return 0, ctypes.ErrHeightNotAvailable{Height: height, Latest: currentHeight}
 		}
 		if height > currentHeight {
-			return 0, fmt.Errorf("Height must be less than or equal to the current blockchain height")
+			return 0, ctypes.ErrHeightNotAvailable{Height: height, Latest: currentHeight}
 		}
 		return height, nil
 	}
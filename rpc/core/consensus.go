@@ -1,7 +1,10 @@
 package core
 
 import (
+	"fmt"
+
 	cm "github.com/tendermint/tendermint/consensus"
+	cmn "github.com/tendermint/tendermint/libs/common"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
 	sm "github.com/tendermint/tendermint/state"
@@ -44,33 +47,171 @@ import (
 // 				"address": "E89A51D60F68385E09E716D353373B11F8FACD62"
 // 			}
 // 		],
-// 		"block_height": "5241"
+// 		"block_height": "5241",
+// 		"count": "1",
+// 		"total": "1"
 // 	},
 // 	"id": "",
 // 	"jsonrpc": "2.0"
 // }
 // ```
-func Validators(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultValidators, error) {
+//
+// ### Query Parameters
+//
+// | Parameter | Type   | Default | Required | Description                                   |
+// |-----------+--------+---------+----------+-----------------------------------------------|
+// | height    | int64  | 0       | false    | Height to return. If no height, return latest |
+// | page      | int    | 1       | false    | Page number (1-based)                         |
+// | per_page  | int    | 30      | false    | Number of entries per page (max: 100)         |
+//
+// ### Returns
+//
+// - `validators`: the validators of the page
+// - `count`: `int` - number of validators in the page
+// - `total`: `int` - total number of validators
+func (env *Environment) Validators(
+	ctx *rpctypes.Context,
+	heightPtr *int64,
+	page, perPage int,
+) (*ctypes.ResultValidators, error) {
 	// The latest validator that we know is the
 	// NextValidator of the last block.
-	height := consensusState.GetState().LastBlockHeight + 1
+	height := env.latestStateHeight() + 1
 	height, err := getHeight(height, heightPtr)
 	if err != nil {
 		return nil, err
 	}
 
-	validators, err := sm.LoadValidators(stateDB, height)
+	validators, err := sm.LoadValidators(env.stateDB, height)
 	if err != nil {
 		return nil, err
 	}
+
+	totalCount := len(validators.Validators)
+	perPage = validatePerPage(perPage)
+	page, err = validatePage(page, perPage, totalCount)
+	if err != nil {
+		return nil, err
+	}
+	skipCount := validateSkipCount(page, perPage)
+
+	v := validators.Validators[skipCount : skipCount+cmn.MinInt(perPage, totalCount-skipCount)]
+
 	return &ctypes.ResultValidators{
 		BlockHeight: height,
-		Validators:  validators.Validators}, nil
+		Validators:  v,
+		Count:       len(v),
+		Total:       totalCount}, nil
+}
+
+// ValidatorChanges returns the changes of the validator set made by the
+// validator updates returned by EndBlock at the given height: the validators
+// which enter and leave the set, and the ones whose voting power changes. If
+// no height is provided, it returns the changes of the latest height.
+//
+// The changes take effect at height + 2. If the updates were rejected for
+// changing the voting power too much, there are no changes.
+//
+// ```shell
+// curl 'localhost:26657/validator_changes?height=10'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:26657", "/websocket")
+// err := client.Start()
+// if err != nil {
+//   // handle error
+// }
+// defer client.Stop()
+// changes, err := client.ValidatorChanges(&height)
+// ```
+//
+// The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"height": "10",
+// 		"effective_height": "12",
+// 		"added": [
+// 			{
+// 				"proposer_priority": "0",
+// 				"voting_power": "10",
+// 				"pub_key": {
+// 					"type": "tendermint/PubKeyEd25519",
+// 					"value": "9tK9IT+FPdf2qm+5c2qaxi10sWP+3erWTKgftn2PaQM="
+// 				},
+// 				"address": "E89A51D60F68385E09E716D353373B11F8FACD62"
+// 			}
+// 		],
+// 		"removed": null,
+// 		"power_changes": [
+// 			{
+// 				"address": "000001E443FD237E4B616E2FA69DF4EE3D49A94F",
+// 				"old_power": "250353",
+// 				"new_power": "250000"
+// 			}
+// 		]
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type  | Default | Required | Description                                   |
+// |-----------+-------+---------+----------+-----------------------------------------------|
+// | height    | int64 | 0       | false    | Height to return. If no height, return latest |
+//
+// ### Returns
+//
+// - `added`: the validators entering the set
+// - `removed`: the validators leaving the set, with the voting power they had
+// - `power_changes`: the old and new voting power of the other updated validators
+func (env *Environment) ValidatorChanges(
+	ctx *rpctypes.Context,
+	heightPtr *int64,
+) (*ctypes.ResultValidatorChanges, error) {
+	height, err := getHeight(env.latestStateHeight(), heightPtr)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := sm.LoadABCIResponses(env.stateDB, height)
+	if err != nil {
+		return nil, err
+	}
+	// the validator updates breaking the power change limit were not applied
+	var updates []*types.Validator
+	if results.EndBlock != nil && results.RejectedValidatorUpdates == nil {
+		updates, err = types.PB2TM.ValidatorUpdates(results.EndBlock.ValidatorUpdates)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the updates apply to the validators of height + 1
+	validators, err := sm.LoadValidators(env.stateDB, height+1)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := types.NewEventDataValidatorSetUpdates(height, validators, updates)
+	return &ctypes.ResultValidatorChanges{
+		Height:          height,
+		EffectiveHeight: height + 2,
+		Added:           changes.Added,
+		Removed:         changes.Removed,
+		PowerChanges:    changes.PowerChanges,
+	}, nil
 }
 
 // DumpConsensusState dumps consensus state.
 // UNSTABLE
 //
+// Returns ErrNodeSyncing while the node is fast syncing or replaying its WAL.
+//
 // ```shell
 // curl 'localhost:26657/dump_consensus_state'
 // ```
@@ -203,9 +344,13 @@ func Validators(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultValidato
 //   }
 // }
 // ```
-func DumpConsensusState(ctx *rpctypes.Context) (*ctypes.ResultDumpConsensusState, error) {
+func (env *Environment) DumpConsensusState(ctx *rpctypes.Context) (*ctypes.ResultDumpConsensusState, error) {
+	if err := env.ensureNotSyncing(); err != nil {
+		return nil, err
+	}
+
 	// Get Peer consensus states.
-	peers := p2pPeers.Peers().List()
+	peers := env.p2pPeers.Peers().List()
 	peerStates := make([]ctypes.PeerStateInfo, len(peers))
 	for i, peer := range peers {
 		peerState, ok := peer.Get(types.PeerStateKey).(*cm.PeerState)
@@ -224,7 +369,7 @@ func DumpConsensusState(ctx *rpctypes.Context) (*ctypes.ResultDumpConsensusState
 		}
 	}
 	// Get self round state.
-	roundState, err := consensusState.GetRoundStateJSON()
+	roundState, err := env.consensusState.GetRoundStateJSON()
 	if err != nil {
 		return nil, err
 	}
@@ -236,6 +381,8 @@ func DumpConsensusState(ctx *rpctypes.Context) (*ctypes.ResultDumpConsensusState
 // ConsensusState returns a concise summary of the consensus state.
 // UNSTABLE
 //
+// Returns ErrNodeSyncing while the node is fast syncing or replaying its WAL.
+//
 // ```shell
 // curl 'localhost:26657/consensus_state'
 // ```
@@ -280,12 +427,106 @@ func DumpConsensusState(ctx *rpctypes.Context) (*ctypes.ResultDumpConsensusState
 //  }
 //}
 //```
-func ConsensusState(ctx *rpctypes.Context) (*ctypes.ResultConsensusState, error) {
+func (env *Environment) ConsensusState(ctx *rpctypes.Context) (*ctypes.ResultConsensusState, error) {
+	if err := env.ensureNotSyncing(); err != nil {
+		return nil, err
+	}
+
 	// Get self round state.
-	bz, err := consensusState.GetRoundStateSimpleJSON()
+	bz, err := env.consensusState.GetRoundStateSimpleJSON()
 	return &ctypes.ResultConsensusState{RoundState: bz}, err
 }
 
+// ConsensusRounds returns what the node saw of the rounds of a height: for
+// each round, when it started, its proposer, when the proposal was received,
+// and when the prevote and precommit of each validator were received. Only
+// the latest heights are kept. It is meant to drive visualizations of the
+// consensus.
+// UNSTABLE
+//
+// If no height is provided, it returns the rounds of the current height.
+// Returns ErrNodeSyncing while the node is fast syncing or replaying its WAL.
+//
+// ```shell
+// curl 'localhost:26657/consensus_rounds?height=7185'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:26657", "/websocket")
+// err := client.Start()
+// if err != nil {
+//   // handle error
+// }
+// defer client.Stop()
+// rounds, err := client.ConsensusRounds(&height)
+// ```
+//
+// The above command returns JSON structured like this:
+//
+// ```json
+// {
+//   "jsonrpc": "2.0",
+//   "id": "",
+//   "result": {
+//     "height": "7185",
+//     "validators": [
+//       "B5B3D40BE53982AD294EF99FF5A34C0C3E5A3244",
+//       "E89A51D60F68385E09E716D353373B11F8FACD62"
+//     ],
+//     "rounds": [
+//       {
+//         "round": "0",
+//         "start_time": "2018-05-12T13:57:28.440293621-07:00",
+//         "proposer": "B5B3D40BE53982AD294EF99FF5A34C0C3E5A3244",
+//         "proposal_time": "2018-05-12T13:57:28.512344719-07:00",
+//         "prevotes": [
+//           "2018-05-12T13:57:28.601234118-07:00",
+//           "0001-01-01T00:00:00Z"
+//         ],
+//         "precommits": [
+//           "2018-05-12T13:57:28.701133542-07:00",
+//           "0001-01-01T00:00:00Z"
+//         ]
+//       }
+//     ]
+//   }
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type  | Default | Required | Description                                     |
+// |-----------+-------+---------+----------+-------------------------------------------------|
+// | height    | int64 | 0       | false    | Height to return. If no height, return current |
+//
+// ### Returns
+//
+// - `validators`: addresses of the validators of the height
+// - `rounds`: the rounds, with the times the votes were received by
+// validator, in the order of `validators` (zero if not received)
+func (env *Environment) ConsensusRounds(
+	ctx *rpctypes.Context,
+	heightPtr *int64,
+) (*ctypes.ResultConsensusRounds, error) {
+	if err := env.ensureNotSyncing(); err != nil {
+		return nil, err
+	}
+
+	height, err := getHeight(env.consensusState.GetLastHeight()+1, heightPtr)
+	if err != nil {
+		return nil, err
+	}
+
+	trace, ok := env.consensusState.GetHeightTrace(height)
+	if !ok {
+		return nil, fmt.Errorf("rounds of height %d are not recorded, only the latest heights are", height)
+	}
+	return &ctypes.ResultConsensusRounds{
+		Height:     trace.Height,
+		Validators: trace.Validators,
+		Rounds:     trace.Rounds}, nil
+}
+
 // Get the consensus parameters  at the given block height.
 // If no height is provided, it will fetch the current consensus params.
 //
@@ -323,14 +564,17 @@ func ConsensusState(ctx *rpctypes.Context) (*ctypes.ResultConsensusState, error)
 //   }
 // }
 // ```
-func ConsensusParams(ctx *rpctypes.Context, heightPtr *int64) (*ctypes.ResultConsensusParams, error) {
-	height := consensusState.GetState().LastBlockHeight + 1
+func (env *Environment) ConsensusParams(
+	ctx *rpctypes.Context,
+	heightPtr *int64,
+) (*ctypes.ResultConsensusParams, error) {
+	height := env.latestStateHeight() + 1
 	height, err := getHeight(height, heightPtr)
 	if err != nil {
 		return nil, err
 	}
 
-	consensusparams, err := sm.LoadConsensusParams(stateDB, height)
+	consensusparams, err := sm.LoadConsensusParams(env.stateDB, height)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// validatorUpdatesApp returns its validator updates in EndBlock.
+type validatorUpdatesApp struct {
+	abci.BaseApplication
+
+	ValidatorUpdates []abci.ValidatorUpdate
+}
+
+func (app *validatorUpdatesApp) EndBlock(req abci.RequestEndBlock) abci.ResponseEndBlock {
+	return abci.ResponseEndBlock{ValidatorUpdates: app.ValidatorUpdates}
+}
+
+func TestValidatorChangesRejected(t *testing.T) {
+	app := &validatorUpdatesApp{}
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(app))
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	pk := ed25519.GenPrivKey()
+	state, err := sm.MakeGenesisState(&types.GenesisDoc{
+		ChainID:    "validator-changes",
+		Validators: []types.GenesisValidator{{PubKey: pk.PubKey(), Power: 1000}},
+	})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, state.ConsensusParams.Validator.MaxPowerChangeNum)
+	require.EqualValues(t, 3, state.ConsensusParams.Validator.MaxPowerChangeDenom)
+
+	db := dbm.NewMemDB()
+	sm.SaveState(db, state)
+	blockExec := sm.NewBlockExecutor(db, log.TestingLogger(), proxyApp.Consensus(), mock.Mempool{},
+		sm.MockEvidencePool{})
+
+	// doubling the voting power breaks the default limit of 1/3
+	app.ValidatorUpdates = []abci.ValidatorUpdate{
+		{PubKey: types.TM2PB.PubKey(ed25519.GenPrivKey().PubKey()), Power: 1000},
+	}
+	block, _ := state.MakeBlock(1, nil, new(types.Commit), nil, state.Validators.GetProposer().Address)
+	blockID := types.BlockID{Hash: block.Hash(), PartsHeader: block.MakePartSet(types.BlockPartSizeBytes).Header()}
+	_, err = blockExec.ApplyBlock(state, blockID, block)
+	require.NoError(t, err)
+
+	env := &Environment{}
+	env.SetStateDB(db)
+
+	height := int64(1)
+	changes, err := env.ValidatorChanges(nil, &height)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Added)
+	assert.Empty(t, changes.Removed)
+	assert.Empty(t, changes.PowerChanges)
+}
@@ -66,6 +66,36 @@ JSONRPC requests can be made via websocket.
 The websocket endpoint is at `/websocket`, e.g. `localhost:26657/websocket`.
 Asynchronous RPC functions like event `subscribe` and `unsubscribe` are only available via websockets.
 
+## Errors
+
+Errors are returned as JSONRPC errors. The errors clients may want to handle
+have their own code, within the range reserved for server errors:
+
+| Code   | Error                                                                  |
+|--------+------------------------------------------------------------------------|
+| -32001 | The node is syncing (fast sync or WAL replay)                          |
+| -32002 | The height was pruned                                                  |
+| -32003 | The height was not produced yet (or is not positive)                   |
+| -32004 | The transaction was not found                                          |
+| -32005 | The mempool is full                                                    |
+| -32006 | The transaction is already in the mempool cache                        |
+| -32007 | The index can not be queried (indexing disabled or not "kv")           |
+| -32008 | Timed out waiting for an event (e.g. the commit of a transaction)      |
+
+Other errors are internal errors (-32603). The Go client returns errors
+implementing `rpctypes.CodedError` for both kinds of errors.
+
+```json
+{
+	"jsonrpc": "2.0",
+	"id": "",
+	"error": {
+		"code": -32003,
+		"message": "Server error",
+		"data": "Height must be less than or equal to the current blockchain height (12)"
+	}
+}
+```
 
 ## More Examples
 
@@ -143,31 +143,56 @@ import (
 //
 // ### Query Parameters
 //
-// | Parameter | Type   | Default | Required | Description |
-// |-----------+--------+---------+----------+-------------|
-// | query     | string | ""      | true     | Query       |
+// | Parameter     | Type   | Default      | Required | Description                                                   |
+// |---------------+--------+--------------+----------+---------------------------------------------------------------|
+// | query         | string | ""           | true     | Query                                                         |
+// | buffer_size   | int    | 100          | false    | Number of events buffered for a slow client (max: 1000)       |
+// | buffer_policy | string | "disconnect" | false    | When the buffer is full: disconnect, drop_oldest, drop_newest |
+//
+// The defaults and the maximum buffer size are set by the
+// `subscription_buffer_size`, `subscription_buffer_policy` and
+// `max_subscription_buffer_size` configs of the node.
 //
 // <aside class="notice">WebSocket only</aside>
-func Subscribe(ctx *rpctypes.Context, query string) (*ctypes.ResultSubscribe, error) {
+func (env *Environment) Subscribe(
+	ctx *rpctypes.Context,
+	query string,
+	bufferSize int,
+	bufferPolicy string,
+) (*ctypes.ResultSubscribe, error) {
 	addr := ctx.RemoteAddr()
 
-	if eventBus.NumClients() >= config.MaxSubscriptionClients {
-		return nil, fmt.Errorf("max_subscription_clients %d reached", config.MaxSubscriptionClients)
-	} else if eventBus.NumClientSubscriptions(addr) >= config.MaxSubscriptionsPerClient {
-		return nil, fmt.Errorf("max_subscriptions_per_client %d reached", config.MaxSubscriptionsPerClient)
+	if env.eventBus.NumClients() >= env.config.MaxSubscriptionClients {
+		return nil, fmt.Errorf("max_subscription_clients %d reached", env.config.MaxSubscriptionClients)
+	} else if env.eventBus.NumClientSubscriptions(addr) >= env.config.MaxSubscriptionsPerClient {
+		return nil, fmt.Errorf("max_subscriptions_per_client %d reached", env.config.MaxSubscriptionsPerClient)
 	}
 
-	logger.Info("Subscribe to query", "remote", addr, "query", query)
+	env.logger.Info("Subscribe to query", "remote", addr, "query", query)
 
 	q, err := tmquery.New(query)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse query")
 	}
 
+	if bufferSize <= 0 {
+		bufferSize = env.config.SubscriptionBufferSize
+	} else if bufferSize > env.config.MaxSubscriptionBufferSize {
+		return nil, fmt.Errorf("buffer_size should be at most %d, given %d",
+			env.config.MaxSubscriptionBufferSize, bufferSize)
+	}
+	if bufferPolicy == "" {
+		bufferPolicy = env.config.SubscriptionBufferPolicy
+	}
+	policy, err := tmpubsub.ParseOverflowPolicy(bufferPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	subCtx, cancel := context.WithTimeout(ctx.Context(), SubscribeTimeout)
 	defer cancel()
 
-	sub, err := eventBus.Subscribe(subCtx, addr, q)
+	sub, err := env.eventBus.SubscribeWithPolicy(subCtx, addr, q, bufferSize, policy)
 	if err != nil {
 		return nil, err
 	}
@@ -177,7 +202,9 @@ func Subscribe(ctx *rpctypes.Context, query string) (*ctypes.ResultSubscribe, er
 			select {
 			case msg := <-sub.Out():
 				resultEvent := &ctypes.ResultEvent{Query: query, Data: msg.Data(), Events: msg.Events()}
-				ctx.WSConn.TryWriteRPCResponse(
+				// block on a slow client, so the events pile up in the
+				// subscription's buffer and its overflow policy applies
+				ctx.WSConn.WriteRPCResponse(
 					rpctypes.NewRPCSuccessResponse(
 						ctx.WSConn.Codec(),
 						rpctypes.JSONRPCStringID(fmt.Sprintf("%v#event", ctx.JSONReq.ID)),
@@ -239,14 +266,14 @@ func Subscribe(ctx *rpctypes.Context, query string) (*ctypes.ResultSubscribe, er
 // | query     | string | ""      | true     | Query       |
 //
 // <aside class="notice">WebSocket only</aside>
-func Unsubscribe(ctx *rpctypes.Context, query string) (*ctypes.ResultUnsubscribe, error) {
+func (env *Environment) Unsubscribe(ctx *rpctypes.Context, query string) (*ctypes.ResultUnsubscribe, error) {
 	addr := ctx.RemoteAddr()
-	logger.Info("Unsubscribe from query", "remote", addr, "query", query)
+	env.logger.Info("Unsubscribe from query", "remote", addr, "query", query)
 	q, err := tmquery.New(query)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse query")
 	}
-	err = eventBus.Unsubscribe(context.Background(), addr, q)
+	err = env.eventBus.Unsubscribe(context.Background(), addr, q)
 	if err != nil {
 		return nil, err
 	}
@@ -280,10 +307,10 @@ func Unsubscribe(ctx *rpctypes.Context, query string) (*ctypes.ResultUnsubscribe
 // ```
 //
 // <aside class="notice">WebSocket only</aside>
-func UnsubscribeAll(ctx *rpctypes.Context) (*ctypes.ResultUnsubscribe, error) {
+func (env *Environment) UnsubscribeAll(ctx *rpctypes.Context) (*ctypes.ResultUnsubscribe, error) {
 	addr := ctx.RemoteAddr()
-	logger.Info("Unsubscribe from all", "remote", addr)
-	err := eventBus.UnsubscribeAll(context.Background(), addr)
+	env.logger.Info("Unsubscribe from all", "remote", addr)
+	err := env.eventBus.UnsubscribeAll(context.Background(), addr)
 	if err != nil {
 		return nil, err
 	}
@@ -32,8 +32,11 @@ import (
 // | Parameter | Type           | Default | Required | Description                 |
 // |-----------+----------------+---------+----------+-----------------------------|
 // | evidence  | types.Evidence | nil     | true     | Amino-encoded JSON evidence |
-func BroadcastEvidence(ctx *rpctypes.Context, ev types.Evidence) (*ctypes.ResultBroadcastEvidence, error) {
-	err := evidencePool.AddEvidence(ev)
+func (env *Environment) BroadcastEvidence(
+	ctx *rpctypes.Context,
+	ev types.Evidence,
+) (*ctypes.ResultBroadcastEvidence, error) {
+	err := env.evidencePool.AddEvidence(ev)
 	if err != nil {
 		return nil, err
 	}
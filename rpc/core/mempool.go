@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 
 	abci "github.com/tendermint/tendermint/abci/types"
+	mempl "github.com/tendermint/tendermint/mempool"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
 	"github.com/tendermint/tendermint/types"
@@ -72,10 +73,10 @@ import (
 // | Parameter | Type | Default | Required | Description     |
 // |-----------+------+---------+----------+-----------------|
 // | tx        | Tx   | nil     | true     | The transaction |
-func BroadcastTxAsync(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
-	err := mempool.CheckTx(tx, nil)
+func (env *Environment) BroadcastTxAsync(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
+	err := env.mempool.CheckTx(tx, nil)
 	if err != nil {
-		return nil, err
+		return nil, checkTxError(err)
 	}
 	return &ctypes.ResultBroadcastTx{Hash: tx.Hash()}, nil
 }
@@ -133,13 +134,13 @@ func BroadcastTxAsync(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadca
 // | Parameter | Type | Default | Required | Description     |
 // |-----------+------+---------+----------+-----------------|
 // | tx        | Tx   | nil     | true     | The transaction |
-func BroadcastTxSync(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
+func (env *Environment) BroadcastTxSync(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
 	resCh := make(chan *abci.Response, 1)
-	err := mempool.CheckTx(tx, func(res *abci.Response) {
+	err := env.mempool.CheckTx(tx, func(res *abci.Response) {
 		resCh <- res
 	})
 	if err != nil {
-		return nil, err
+		return nil, checkTxError(err)
 	}
 	res := <-resCh
 	r := res.GetCheckTx()
@@ -157,9 +158,12 @@ func BroadcastTxSync(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadcas
 // BroadcastTxSync or BroadcastTxAsync. You can subscribe for the transaction
 // result using JSONRPC via a websocket. See
 // https://tendermint.com/docs/app-dev/subscribing-to-events-via-websocket.html
+// Go clients can use client.BroadcastTxAndWait, which does both and returns
+// the same result, without a request blocking on the node until the tx is
+// committed.
 //
-// CONTRACT: only returns error if mempool.CheckTx() errs or if we timeout
-// waiting for tx to commit.
+// CONTRACT: only returns error if the node is syncing (ErrNodeSyncing), if
+// mempool.CheckTx() errs or if we timeout waiting for tx to commit.
 //
 // If CheckTx or DeliverTx fail, no error will be returned, but the returned result
 // will contain a non-OK ABCI code.
@@ -212,35 +216,40 @@ func BroadcastTxSync(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadcas
 // | Parameter | Type | Default | Required | Description     |
 // |-----------+------+---------+----------+-----------------|
 // | tx        | Tx   | nil     | true     | The transaction |
-func BroadcastTxCommit(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
+func (env *Environment) BroadcastTxCommit(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
+	// The tx can't be committed by this node before it catches up.
+	if err := env.ensureNotSyncing(); err != nil {
+		return nil, err
+	}
+
 	subscriber := ctx.RemoteAddr()
 
-	if eventBus.NumClients() >= config.MaxSubscriptionClients {
-		return nil, fmt.Errorf("max_subscription_clients %d reached", config.MaxSubscriptionClients)
-	} else if eventBus.NumClientSubscriptions(subscriber) >= config.MaxSubscriptionsPerClient {
-		return nil, fmt.Errorf("max_subscriptions_per_client %d reached", config.MaxSubscriptionsPerClient)
+	if env.eventBus.NumClients() >= env.config.MaxSubscriptionClients {
+		return nil, fmt.Errorf("max_subscription_clients %d reached", env.config.MaxSubscriptionClients)
+	} else if env.eventBus.NumClientSubscriptions(subscriber) >= env.config.MaxSubscriptionsPerClient {
+		return nil, fmt.Errorf("max_subscriptions_per_client %d reached", env.config.MaxSubscriptionsPerClient)
 	}
 
 	// Subscribe to tx being committed in block.
 	subCtx, cancel := context.WithTimeout(ctx.Context(), SubscribeTimeout)
 	defer cancel()
 	q := types.EventQueryTxFor(tx)
-	deliverTxSub, err := eventBus.Subscribe(subCtx, subscriber, q)
+	deliverTxSub, err := env.eventBus.Subscribe(subCtx, subscriber, q)
 	if err != nil {
 		err = errors.Wrap(err, "failed to subscribe to tx")
-		logger.Error("Error on broadcast_tx_commit", "err", err)
+		env.logger.Error("Error on broadcast_tx_commit", "err", err)
 		return nil, err
 	}
-	defer eventBus.Unsubscribe(context.Background(), subscriber, q)
+	defer env.eventBus.Unsubscribe(context.Background(), subscriber, q)
 
 	// Broadcast tx and wait for CheckTx result
 	checkTxResCh := make(chan *abci.Response, 1)
-	err = mempool.CheckTx(tx, func(res *abci.Response) {
+	err = env.mempool.CheckTx(tx, func(res *abci.Response) {
 		checkTxResCh <- res
 	})
 	if err != nil {
-		logger.Error("Error on broadcastTxCommit", "err", err)
-		return nil, fmt.Errorf("Error on broadcastTxCommit: %v", err)
+		env.logger.Error("Error on broadcastTxCommit", "err", err)
+		return nil, errors.Wrap(checkTxError(err), "Error on broadcastTxCommit")
 	}
 	checkTxResMsg := <-checkTxResCh
 	checkTxRes := checkTxResMsg.GetCheckTx()
@@ -270,15 +279,15 @@ func BroadcastTxCommit(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadc
 			reason = deliverTxSub.Err().Error()
 		}
 		err = fmt.Errorf("deliverTxSub was cancelled (reason: %s)", reason)
-		logger.Error("Error on broadcastTxCommit", "err", err)
+		env.logger.Error("Error on broadcastTxCommit", "err", err)
 		return &ctypes.ResultBroadcastTxCommit{
 			CheckTx:   *checkTxRes,
 			DeliverTx: abci.ResponseDeliverTx{},
 			Hash:      tx.Hash(),
 		}, err
-	case <-time.After(config.TimeoutBroadcastTxCommit):
-		err = errors.New("Timed out waiting for tx to be included in a block")
-		logger.Error("Error on broadcastTxCommit", "err", err)
+	case <-time.After(env.config.TimeoutBroadcastTxCommit):
+		err = ctypes.ErrTimeout{Reason: "Timed out waiting for tx to be included in a block"}
+		env.logger.Error("Error on broadcastTxCommit", "err", err)
 		return &ctypes.ResultBroadcastTxCommit{
 			CheckTx:   *checkTxRes,
 			DeliverTx: abci.ResponseDeliverTx{},
@@ -324,15 +333,15 @@ func BroadcastTxCommit(ctx *rpctypes.Context, tx types.Tx) (*ctypes.ResultBroadc
 // |-----------+------+---------+----------+--------------------------------------|
 // | limit     | int  | 30      | false    | Maximum number of entries (max: 100) |
 // ```
-func UnconfirmedTxs(ctx *rpctypes.Context, limit int) (*ctypes.ResultUnconfirmedTxs, error) {
+func (env *Environment) UnconfirmedTxs(ctx *rpctypes.Context, limit int) (*ctypes.ResultUnconfirmedTxs, error) {
 	// reuse per_page validator
 	limit = validatePerPage(limit)
 
-	txs := mempool.ReapMaxTxs(limit)
+	txs := env.mempool.ReapMaxTxs(limit)
 	return &ctypes.ResultUnconfirmedTxs{
 		Count:      len(txs),
-		Total:      mempool.Size(),
-		TotalBytes: mempool.TxsBytes(),
+		Total:      env.mempool.Size(),
+		TotalBytes: env.mempool.TxsBytes(),
 		Txs:        txs}, nil
 }
 
@@ -366,9 +375,84 @@ func UnconfirmedTxs(ctx *rpctypes.Context, limit int) (*ctypes.ResultUnconfirmed
 //   }
 // }
 // ```
-func NumUnconfirmedTxs(ctx *rpctypes.Context) (*ctypes.ResultUnconfirmedTxs, error) {
+func (env *Environment) NumUnconfirmedTxs(ctx *rpctypes.Context) (*ctypes.ResultUnconfirmedTxs, error) {
 	return &ctypes.ResultUnconfirmedTxs{
-		Count:      mempool.Size(),
-		Total:      mempool.Size(),
-		TotalBytes: mempool.TxsBytes()}, nil
+		Count:      env.mempool.Size(),
+		Total:      env.mempool.Size(),
+		TotalBytes: env.mempool.TxsBytes()}, nil
+}
+
+// Get the txs most recently rejected by the mempool (maximum ?limit entries),
+// the most recent first, with the reason of their rejection: "check_tx",
+// "post_check", "pre_check", "too_large", "full" or "recheck" (removed by the
+// recheck after a block). The mempool must log the rejected txs
+// (rejections_log_dir).
+//
+// ```shell
+// curl 'localhost:26657/rejected_txs?limit=1'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:26657", "/websocket")
+// err := client.Start()
+// if err != nil {
+//   // handle error
+// }
+// defer client.Stop()
+// result, err := client.RejectedTxs(1)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+//   "jsonrpc": "2.0",
+//   "id": "",
+//   "result": {
+//     "n_txs": "1",
+//     "rejections": [
+//       {
+//         "time": "2019-11-04T10:21:17.104436Z",
+//         "hash": "C9F5A6D9C1E4A4CF8E7FB6B3C4A0F9D2A4BB1A5E6C1D0E7F8A9B0C1D2E3F4A5B",
+//         "size": "42",
+//         "reason": "check_tx",
+//         "code": 2,
+//         "codespace": "",
+//         "log": "Invalid nonce. Expected 3, got 2",
+//         "peer": "9c8e4f2fb1c6a6de6f5c8dd6bd4a5f4e3c2b1a09"
+//       }
+//     ]
+//   }
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type | Default | Required | Description                          |
+// |-----------+------+---------+----------+--------------------------------------|
+// | limit     | int  | 30      | false    | Maximum number of entries (max: 100) |
+func (env *Environment) RejectedTxs(ctx *rpctypes.Context, limit int) (*ctypes.ResultRejectedTxs, error) {
+	// reuse per_page validator
+	limit = validatePerPage(limit)
+
+	rejections := env.mempool.RecentRejections(limit)
+	if rejections == nil {
+		return nil, errors.New("the rejected txs aren't logged (empty rejections_log_dir)")
+	}
+	return &ctypes.ResultRejectedTxs{
+		Count:      len(rejections),
+		Rejections: rejections}, nil
+}
+
+// checkTxError returns the error of the RPC for an error of the mempool
+// rejecting a tx, so that clients can tell a full mempool from a tx seen
+// earlier.
+func checkTxError(err error) error {
+	if _, ok := err.(mempl.ErrMempoolIsFull); ok {
+		return ctypes.ErrMempoolFull{Err: err}
+	}
+	if err == mempl.ErrTxInCache {
+		return ctypes.ErrTxInCache{Err: err}
+	}
+	return err
 }
@@ -19,8 +19,9 @@ func TestUnsafeDialSeeds(t *testing.T) {
 	require.NoError(t, err)
 	defer sw.Stop()
 
-	logger = log.TestingLogger()
-	p2pPeers = sw
+	env := &Environment{}
+	env.SetLogger(log.TestingLogger())
+	env.SetP2PPeers(sw)
 
 	testCases := []struct {
 		seeds []string
@@ -32,7 +33,7 @@ func TestUnsafeDialSeeds(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		res, err := UnsafeDialSeeds(&rpctypes.Context{}, tc.seeds)
+		res, err := env.UnsafeDialSeeds(&rpctypes.Context{}, tc.seeds)
 		if tc.isErr {
 			assert.Error(t, err)
 		} else {
@@ -49,8 +50,9 @@ func TestUnsafeDialPeers(t *testing.T) {
 	require.NoError(t, err)
 	defer sw.Stop()
 
-	logger = log.TestingLogger()
-	p2pPeers = sw
+	env := &Environment{}
+	env.SetLogger(log.TestingLogger())
+	env.SetP2PPeers(sw)
 
 	testCases := []struct {
 		peers []string
@@ -62,7 +64,7 @@ func TestUnsafeDialPeers(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		res, err := UnsafeDialPeers(&rpctypes.Context{}, tc.peers, false)
+		res, err := env.UnsafeDialPeers(&rpctypes.Context{}, tc.peers, false)
 		if tc.isErr {
 			assert.Error(t, err)
 		} else {
@@ -6,13 +6,16 @@ import (
 
 	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/consensus"
+	cstypes "github.com/tendermint/tendermint/consensus/types"
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/libs/log"
 	mempl "github.com/tendermint/tendermint/mempool"
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/proxy"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	sm "github.com/tendermint/tendermint/state"
-	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/state/indexer"
+	"github.com/tendermint/tendermint/store/archive"
 	"github.com/tendermint/tendermint/types"
 	dbm "github.com/tendermint/tm-db"
 )
@@ -36,6 +39,36 @@ type Consensus interface {
 	GetLastHeight() int64
 	GetRoundStateJSON() ([]byte, error)
 	GetRoundStateSimpleJSON() ([]byte, error)
+	GetHeightTrace(height int64) (*cstypes.HeightTrace, bool)
+}
+
+// FastSync is implemented by the fast sync reactors which can be inspected and
+// paused from the admin API.
+type FastSync interface {
+	SyncStatusJSON() ([]byte, error)
+	PauseSync() error
+	ResumeSync() error
+}
+
+// FastSyncState is implemented by the fast sync reactors which report in
+// /status why they don't make progress, e.g. backing off after losing all
+// their peers, or aborted after a terminal error.
+type FastSyncState interface {
+	SyncState() (state string, retries int, err error)
+}
+
+// DBCompactor compacts the databases of the node, from the admin API.
+type DBCompactor interface {
+	// CompactDB compacts the database of the given ID (e.g. "state"), or all
+	// of them if id is empty, and returns the IDs of the compacted databases.
+	CompactDB(id string) ([]string, error)
+}
+
+// ResultsBackfiller recomputes the block results missing from the state DB in
+// the background, from the admin API (see sm.ResultsBackfiller).
+type ResultsBackfiller interface {
+	StartBackfill(height int64) error
+	BackfillStatus() sm.BackfillStatus
 }
 
 type transport interface {
@@ -44,101 +77,183 @@ type transport interface {
 	NodeInfo() p2p.NodeInfo
 }
 
+type diskUsage interface {
+	DiskUsage() []ctypes.DiskUsage
+}
+
 type peers interface {
 	AddPersistentPeers([]string) error
 	DialPeersAsync([]string) error
 	NumPeers() (outbound, inbound, dialig int)
 	Peers() p2p.IPeerSet
+	PeerLabel(p2p.ID) string
 }
 
 //----------------------------------------------
-// These package level globals come with setters
-// that are expected to be called only once, on startup
 
-var (
+// Environment contains the objects of a node served by the RPC functions,
+// which are its methods. It comes with setters that are expected to be called
+// only once, on startup. A process running several nodes (see
+// node.NewNetworkNode) has an Environment for each of them.
+type Environment struct {
 	// external, thread safe interfaces
 	proxyAppQuery proxy.AppConnQuery
 
 	// interfaces defined in types and above
 	stateDB        dbm.DB
 	blockStore     sm.BlockStore
+	blockArchive   *archive.Archive // nil if blocks are not archived
 	evidencePool   sm.EvidencePool
 	consensusState Consensus
 	p2pPeers       peers
 	p2pTransport   transport
+	diskUsageStats diskUsage         // nil if the disk usage isn't measured
+	fastSync       FastSync          // nil if the fast sync reactor doesn't implement it
+	fastSyncState  FastSyncState     // nil if the fast sync reactor doesn't implement it
+	dbCompactor    DBCompactor       // nil if the databases can't be compacted
+	backfiller     ResultsBackfiller // nil if the missing results aren't recomputed
 
 	// objects
 	pubKey           crypto.PubKey
+	attestationKey   crypto.PrivKey    // nil if /status isn't attested
 	genDoc           *types.GenesisDoc // cache the genesis structure
-	txIndexer        txindex.TxIndexer
+	eventSinks       []indexer.EventSink
 	consensusReactor *consensus.ConsensusReactor
 	eventBus         *types.EventBus // thread safe
 	mempool          mempl.Mempool
 
-	logger log.Logger
+	logger      log.Logger
+	setLogLevel func(string) error // nil if the log level can't be changed
 
 	config cfg.RPCConfig
-)
+}
+
+func (env *Environment) SetStateDB(db dbm.DB) {
+	env.stateDB = db
+}
+
+func (env *Environment) SetBlockStore(bs sm.BlockStore) {
+	env.blockStore = bs
+}
+
+func (env *Environment) SetBlockArchive(a *archive.Archive) {
+	env.blockArchive = a
+}
+
+func (env *Environment) SetMempool(mem mempl.Mempool) {
+	env.mempool = mem
+}
 
-func SetStateDB(db dbm.DB) {
-	stateDB = db
+func (env *Environment) SetEvidencePool(evpool sm.EvidencePool) {
+	env.evidencePool = evpool
 }
 
-func SetBlockStore(bs sm.BlockStore) {
-	blockStore = bs
+func (env *Environment) SetConsensusState(cs Consensus) {
+	env.consensusState = cs
 }
 
-func SetMempool(mem mempl.Mempool) {
-	mempool = mem
+func (env *Environment) SetP2PPeers(p peers) {
+	env.p2pPeers = p
 }
 
-func SetEvidencePool(evpool sm.EvidencePool) {
-	evidencePool = evpool
+func (env *Environment) SetP2PTransport(t transport) {
+	env.p2pTransport = t
 }
 
-func SetConsensusState(cs Consensus) {
-	consensusState = cs
+func (env *Environment) SetDiskUsage(d diskUsage) {
+	env.diskUsageStats = d
 }
 
-func SetP2PPeers(p peers) {
-	p2pPeers = p
+func (env *Environment) SetFastSync(fs FastSync) {
+	env.fastSync = fs
 }
 
-func SetP2PTransport(t transport) {
-	p2pTransport = t
+func (env *Environment) SetFastSyncState(s FastSyncState) {
+	env.fastSyncState = s
 }
 
-func SetPubKey(pk crypto.PubKey) {
-	pubKey = pk
+func (env *Environment) SetDBCompactor(c DBCompactor) {
+	env.dbCompactor = c
 }
 
-func SetGenesisDoc(doc *types.GenesisDoc) {
-	genDoc = doc
+func (env *Environment) SetResultsBackfiller(b ResultsBackfiller) {
+	env.backfiller = b
 }
 
-func SetProxyAppQuery(appConn proxy.AppConnQuery) {
-	proxyAppQuery = appConn
+func (env *Environment) SetPubKey(pk crypto.PubKey) {
+	env.pubKey = pk
 }
 
-func SetTxIndexer(indexer txindex.TxIndexer) {
-	txIndexer = indexer
+func (env *Environment) SetStatusAttestationKey(key crypto.PrivKey) {
+	env.attestationKey = key
 }
 
-func SetConsensusReactor(conR *consensus.ConsensusReactor) {
-	consensusReactor = conR
+func (env *Environment) SetGenesisDoc(doc *types.GenesisDoc) {
+	env.genDoc = doc
 }
 
-func SetLogger(l log.Logger) {
-	logger = l
+func (env *Environment) SetProxyAppQuery(appConn proxy.AppConnQuery) {
+	env.proxyAppQuery = appConn
 }
 
-func SetEventBus(b *types.EventBus) {
-	eventBus = b
+func (env *Environment) SetEventSinks(sinks []indexer.EventSink) {
+	env.eventSinks = sinks
+}
+
+func (env *Environment) SetConsensusReactor(conR *consensus.ConsensusReactor) {
+	env.consensusReactor = conR
+}
+
+func (env *Environment) SetLogger(l log.Logger) {
+	env.logger = l
+}
+
+func (env *Environment) SetLogLevelFunc(f func(string) error) {
+	env.setLogLevel = f
+}
+
+func (env *Environment) SetEventBus(b *types.EventBus) {
+	env.eventBus = b
 }
 
 // SetConfig sets an RPCConfig.
-func SetConfig(c cfg.RPCConfig) {
-	config = c
+func (env *Environment) SetConfig(c cfg.RPCConfig) {
+	env.config = c
+}
+
+// syncPhase returns the phase the node is in while catching up with the
+// network. ok is false once the node participates in consensus, or if it runs
+// no consensus reactor.
+func (env *Environment) syncPhase() (phase ctypes.SyncPhase, ok bool) {
+	switch {
+	case env.consensusReactor == nil:
+		return "", false
+	case env.consensusReactor.FastSync():
+		return ctypes.SyncPhaseFastSync, true
+	case env.consensusReactor.ReplayingWAL():
+		return ctypes.SyncPhaseWALReplay, true
+	default:
+		return "", false
+	}
+}
+
+// ensureNotSyncing returns ErrNodeSyncing while the node is catching up.
+// Used by the endpoints backed by the consensus state or the mempool.
+func (env *Environment) ensureNotSyncing() error {
+	if phase, ok := env.syncPhase(); ok {
+		return ctypes.ErrNodeSyncing{Phase: phase, Height: env.blockStore.Height()}
+	}
+	return nil
+}
+
+// latestStateHeight returns the height of the last block applied to the state.
+// The consensus state is not updated during fast sync, nor running in inspect
+// mode, so the state DB is used instead.
+func (env *Environment) latestStateHeight() int64 {
+	if env.consensusReactor == nil || env.consensusReactor.FastSync() {
+		return sm.LoadState(env.stateDB).LastBlockHeight
+	}
+	return env.consensusState.GetState().LastBlockHeight
 }
 
 func validatePage(page, perPage, totalCount int) (int, error) {
@@ -170,6 +285,19 @@ func validatePerPage(perPage int) int {
 	return perPage
 }
 
+// validateOrderBy returns true if results must be sorted in descending order,
+// for the given value of the order_by parameter ("asc", "desc" or empty).
+func validateOrderBy(orderBy string) (bool, error) {
+	switch orderBy {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, fmt.Errorf("expected order_by to be either `asc` or `desc` or empty, given %q", orderBy)
+	}
+}
+
 func validateSkipCount(page, perPage int) int {
 	skipCount := (page - 1) * perPage
 	if skipCount < 0 {
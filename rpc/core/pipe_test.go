@@ -72,3 +72,35 @@ func TestPaginationPerPage(t *testing.T) {
 		assert.Equal(t, c.newPerPage, p, fmt.Sprintf("%v", c))
 	}
 }
+
+func TestOrderBy(t *testing.T) {
+	cases := []struct {
+		orderBy string
+		desc    bool
+		expErr  bool
+	}{
+		{"", false, false},
+		{"asc", false, false},
+		{"desc", true, false},
+		{"DESC", false, true},
+		{"height", false, true},
+	}
+
+	for _, c := range cases {
+		desc, err := validateOrderBy(c.orderBy)
+		if c.expErr {
+			assert.Error(t, err)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, c.desc, desc, fmt.Sprintf("%v", c))
+	}
+}
+
+func TestEnsureNotSyncingWithoutConsensusReactor(t *testing.T) {
+	env := &Environment{}
+	_, ok := env.syncPhase()
+	assert.False(t, ok)
+	assert.NoError(t, env.ensureNotSyncing())
+}
@@ -1,56 +1,187 @@
 package core
 
 import (
+	"fmt"
+
+	cfg "github.com/tendermint/tendermint/config"
 	rpc "github.com/tendermint/tendermint/rpc/lib/server"
 )
 
 // TODO: better system than "unsafe" prefix
 // NOTE: Amino is registered in rpc/core/types/codec.go.
-var Routes = map[string]*rpc.RPCFunc{
-	// subscribe/unsubscribe are reserved for websocket events.
-	"subscribe":       rpc.NewWSRPCFunc(Subscribe, "query"),
-	"unsubscribe":     rpc.NewWSRPCFunc(Unsubscribe, "query"),
-	"unsubscribe_all": rpc.NewWSRPCFunc(UnsubscribeAll, ""),
-
-	// info API
-	"health":               rpc.NewRPCFunc(Health, ""),
-	"status":               rpc.NewRPCFunc(Status, ""),
-	"net_info":             rpc.NewRPCFunc(NetInfo, ""),
-	"blockchain":           rpc.NewRPCFunc(BlockchainInfo, "minHeight,maxHeight"),
-	"genesis":              rpc.NewRPCFunc(Genesis, ""),
-	"block":                rpc.NewRPCFunc(Block, "height"),
-	"block_results":        rpc.NewRPCFunc(BlockResults, "height"),
-	"commit":               rpc.NewRPCFunc(Commit, "height"),
-	"tx":                   rpc.NewRPCFunc(Tx, "hash,prove"),
-	"tx_search":            rpc.NewRPCFunc(TxSearch, "query,prove,page,per_page"),
-	"validators":           rpc.NewRPCFunc(Validators, "height"),
-	"dump_consensus_state": rpc.NewRPCFunc(DumpConsensusState, ""),
-	"consensus_state":      rpc.NewRPCFunc(ConsensusState, ""),
-	"consensus_params":     rpc.NewRPCFunc(ConsensusParams, "height"),
-	"unconfirmed_txs":      rpc.NewRPCFunc(UnconfirmedTxs, "limit"),
-	"num_unconfirmed_txs":  rpc.NewRPCFunc(NumUnconfirmedTxs, ""),
-
-	// tx broadcast API
-	"broadcast_tx_commit": rpc.NewRPCFunc(BroadcastTxCommit, "tx"),
-	"broadcast_tx_sync":   rpc.NewRPCFunc(BroadcastTxSync, "tx"),
-	"broadcast_tx_async":  rpc.NewRPCFunc(BroadcastTxAsync, "tx"),
-
-	// abci API
-	"abci_query": rpc.NewRPCFunc(ABCIQuery, "path,data,height,prove"),
-	"abci_info":  rpc.NewRPCFunc(ABCIInfo, ""),
-
-	// evidence API
-	"broadcast_evidence": rpc.NewRPCFunc(BroadcastEvidence, "evidence"),
-}
-
-func AddUnsafeRoutes() {
-	// control API
-	Routes["dial_seeds"] = rpc.NewRPCFunc(UnsafeDialSeeds, "seeds")
-	Routes["dial_peers"] = rpc.NewRPCFunc(UnsafeDialPeers, "peers,persistent")
-	Routes["unsafe_flush_mempool"] = rpc.NewRPCFunc(UnsafeFlushMempool, "")
-
-	// profiler API
-	Routes["unsafe_start_cpu_profiler"] = rpc.NewRPCFunc(UnsafeStartCPUProfiler, "filename")
-	Routes["unsafe_stop_cpu_profiler"] = rpc.NewRPCFunc(UnsafeStopCPUProfiler, "")
-	Routes["unsafe_write_heap_profile"] = rpc.NewRPCFunc(UnsafeWriteHeapProfile, "filename")
+
+// Routes returns the routes of the public RPC server, served from env.
+func (env *Environment) Routes() map[string]*rpc.RPCFunc {
+	return map[string]*rpc.RPCFunc{
+		// subscribe/unsubscribe are reserved for websocket events.
+		"subscribe":       rpc.NewWSRPCFunc(env.Subscribe, "query,buffer_size,buffer_policy"),
+		"unsubscribe":     rpc.NewWSRPCFunc(env.Unsubscribe, "query"),
+		"unsubscribe_all": rpc.NewWSRPCFunc(env.UnsubscribeAll, ""),
+
+		// info API
+		"health":               rpc.NewRPCFunc(env.Health, ""),
+		"status":               rpc.NewRPCFunc(env.Status, ""),
+		"net_info":             rpc.NewRPCFunc(env.NetInfo, ""),
+		"blockchain":           rpc.NewRPCFunc(env.BlockchainInfo, "minHeight,maxHeight"),
+		"genesis":              rpc.NewRPCFunc(env.Genesis, ""),
+		"block":                rpc.NewRPCFunc(env.Block, "height"),
+		"block_results":        rpc.NewRPCFunc(env.BlockResults, "height"),
+		"commit":               rpc.NewRPCFunc(env.Commit, "height"),
+		"tx":                   rpc.NewRPCFunc(env.Tx, "hash,prove"),
+		"tx_search":            rpc.NewRPCFunc(env.TxSearch, "query,prove,page,per_page,order_by"),
+		"block_search":         rpc.NewRPCFunc(env.BlockSearch, "query,page,per_page,order_by"),
+		"validators":           rpc.NewRPCFunc(env.Validators, "height,page,per_page"),
+		"validator_changes":    rpc.NewRPCFunc(env.ValidatorChanges, "height"),
+		"dump_consensus_state": rpc.NewRPCFunc(env.DumpConsensusState, ""),
+		"consensus_state":      rpc.NewRPCFunc(env.ConsensusState, ""),
+		"consensus_params":     rpc.NewRPCFunc(env.ConsensusParams, "height"),
+		"consensus_rounds":     rpc.NewRPCFunc(env.ConsensusRounds, "height"),
+		"unconfirmed_txs":      rpc.NewRPCFunc(env.UnconfirmedTxs, "limit"),
+		"num_unconfirmed_txs":  rpc.NewRPCFunc(env.NumUnconfirmedTxs, ""),
+		"rejected_txs":         rpc.NewRPCFunc(env.RejectedTxs, "limit"),
+
+		// tx broadcast API
+		"broadcast_tx_commit": rpc.NewRPCFunc(env.BroadcastTxCommit, "tx"),
+		"broadcast_tx_sync":   rpc.NewRPCFunc(env.BroadcastTxSync, "tx"),
+		"broadcast_tx_async":  rpc.NewRPCFunc(env.BroadcastTxAsync, "tx"),
+
+		// abci API
+		"abci_query": rpc.NewRPCFunc(env.ABCIQuery, "path,data,height,prove"),
+		"abci_info":  rpc.NewRPCFunc(env.ABCIInfo, ""),
+
+		// evidence API
+		"broadcast_evidence": rpc.NewRPCFunc(env.BroadcastEvidence, "evidence"),
+	}
+}
+
+// InspectRoutes returns the routes served by the inspect mode, which only have
+// the block store, the state DB and the indexer of a stopped node.
+func (env *Environment) InspectRoutes() map[string]*rpc.RPCFunc {
+	return map[string]*rpc.RPCFunc{
+		"health":            rpc.NewRPCFunc(env.Health, ""),
+		"blockchain":        rpc.NewRPCFunc(env.BlockchainInfo, "minHeight,maxHeight"),
+		"genesis":           rpc.NewRPCFunc(env.Genesis, ""),
+		"block":             rpc.NewRPCFunc(env.Block, "height"),
+		"block_results":     rpc.NewRPCFunc(env.BlockResults, "height"),
+		"commit":            rpc.NewRPCFunc(env.Commit, "height"),
+		"tx":                rpc.NewRPCFunc(env.Tx, "hash,prove"),
+		"tx_search":         rpc.NewRPCFunc(env.TxSearch, "query,prove,page,per_page,order_by"),
+		"block_search":      rpc.NewRPCFunc(env.BlockSearch, "query,page,per_page,order_by"),
+		"validators":        rpc.NewRPCFunc(env.Validators, "height,page,per_page"),
+		"validator_changes": rpc.NewRPCFunc(env.ValidatorChanges, "height"),
+		"consensus_params":  rpc.NewRPCFunc(env.ConsensusParams, "height"),
+	}
+}
+
+// AdminRoutes returns the routes of the admin API, served on the admin socket
+// (used by the console) and on rpc.admin_laddr to authenticated clients, to
+// inspect and operate the node. They supersede the unsafe routes.
+func (env *Environment) AdminRoutes() map[string]*rpc.RPCFunc {
+	return map[string]*rpc.RPCFunc{
+		// inspection
+		"status":               rpc.NewRPCFunc(env.Status, ""),
+		"net_info":             rpc.NewRPCFunc(env.NetInfo, ""),
+		"dump_consensus_state": rpc.NewRPCFunc(env.DumpConsensusState, ""),
+		"sync_status":          rpc.NewRPCFunc(env.SyncStatus, ""),
+		"backfill_status":      rpc.NewRPCFunc(env.BackfillStatus, ""),
+
+		// control
+		"dial_seeds":       rpc.NewRPCFunc(env.UnsafeDialSeeds, "seeds"),
+		"dial_peers":       rpc.NewRPCFunc(env.UnsafeDialPeers, "peers,persistent"),
+		"pause_sync":       rpc.NewRPCFunc(env.PauseSync, ""),
+		"resume_sync":      rpc.NewRPCFunc(env.ResumeSync, ""),
+		"evict_tx":         rpc.NewRPCFunc(env.EvictTx, "hash"),
+		"flush_mempool":    rpc.NewRPCFunc(env.UnsafeFlushMempool, ""),
+		"set_log_level":    rpc.NewRPCFunc(env.SetLogLevel, "level"),
+		"prune_blocks":     rpc.NewRPCFunc(env.PruneBlocks, "height"),
+		"compact_db":       rpc.NewRPCFunc(env.CompactDB, "db"),
+		"backfill_results": rpc.NewRPCFunc(env.BackfillResults, "height"),
+
+		// profiler
+		"start_cpu_profiler": rpc.NewRPCFunc(env.UnsafeStartCPUProfiler, "filename"),
+		"stop_cpu_profiler":  rpc.NewRPCFunc(env.UnsafeStopCPUProfiler, ""),
+		"write_heap_profile": rpc.NewRPCFunc(env.UnsafeWriteHeapProfile, "filename"),
+	}
+}
+
+// AddUnsafeRoutes adds the control and profiler routes to the routes of the
+// public RPC server (rpc.unsafe).
+//
+// Deprecated: they are served to authenticated clients by the admin API.
+func (env *Environment) AddUnsafeRoutes(routes map[string]*rpc.RPCFunc) {
+	for name, rpcFunc := range env.unsafeRoutes() {
+		routes[name] = rpcFunc
+	}
+}
+
+func (env *Environment) unsafeRoutes() map[string]*rpc.RPCFunc {
+	return map[string]*rpc.RPCFunc{
+		// control API
+		"dial_seeds":           rpc.NewRPCFunc(env.UnsafeDialSeeds, "seeds"),
+		"dial_peers":           rpc.NewRPCFunc(env.UnsafeDialPeers, "peers,persistent"),
+		"unsafe_flush_mempool": rpc.NewRPCFunc(env.UnsafeFlushMempool, ""),
+
+		// profiler API
+		"unsafe_start_cpu_profiler": rpc.NewRPCFunc(env.UnsafeStartCPUProfiler, "filename"),
+		"unsafe_stop_cpu_profiler":  rpc.NewRPCFunc(env.UnsafeStopCPUProfiler, ""),
+		"unsafe_write_heap_profile": rpc.NewRPCFunc(env.UnsafeWriteHeapProfile, "filename"),
+	}
+}
+
+// unsafeRouteNames are the names of the unsafe routes.
+var unsafeRouteNames = func() map[string]bool {
+	names := make(map[string]bool)
+	for name := range new(Environment).unsafeRoutes() {
+		names[name] = true
+	}
+	return names
+}()
+
+// writeRoutes are the routes of Routes which change the state of the node or
+// of the network, disabled by rpc.read_only along with the unsafe routes.
+var writeRoutes = map[string]bool{
+	"broadcast_tx_commit": true,
+	"broadcast_tx_sync":   true,
+	"broadcast_tx_async":  true,
+	"broadcast_evidence":  true,
+}
+
+// FilterRoutes returns the routes served by the public RPC server according
+// to rpc.read_only and rpc.allowed_methods. It returns an error if an allowed
+// method is not one of the routes.
+func FilterRoutes(routes map[string]*rpc.RPCFunc, config *cfg.RPCConfig) (map[string]*rpc.RPCFunc, error) {
+	for _, name := range config.AllowedMethods {
+		if _, ok := routes[name]; !ok {
+			return nil, fmt.Errorf("rpc.allowed_methods: unknown method %q", name)
+		}
+	}
+	filtered := make(map[string]*rpc.RPCFunc, len(routes))
+	for name, rpcFunc := range routes {
+		if methodAllowed(config, name) {
+			filtered[name] = rpcFunc
+		}
+	}
+	return filtered, nil
+}
+
+// MethodAllowed returns true if the method is served according to
+// rpc.read_only and rpc.allowed_methods (see FilterRoutes). The gRPC server
+// checks its methods with the names of the matching routes.
+func (env *Environment) MethodAllowed(name string) bool {
+	return methodAllowed(&env.config, name)
+}
+
+func methodAllowed(config *cfg.RPCConfig, name string) bool {
+	if config.ReadOnly && (writeRoutes[name] || unsafeRouteNames[name]) {
+		return false
+	}
+	if len(config.AllowedMethods) == 0 {
+		return true
+	}
+	for _, allowed := range config.AllowedMethods {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cfg "github.com/tendermint/tendermint/config"
+	rpc "github.com/tendermint/tendermint/rpc/lib/server"
+)
+
+func TestFilterRoutes(t *testing.T) {
+	env := &Environment{}
+	routes := map[string]*rpc.RPCFunc{
+		"status":             env.Routes()["status"],
+		"block":              env.Routes()["block"],
+		"broadcast_tx_sync":  env.Routes()["broadcast_tx_sync"],
+		"broadcast_evidence": env.Routes()["broadcast_evidence"],
+		"dial_seeds":         env.unsafeRoutes()["dial_seeds"],
+	}
+	names := func(routes map[string]*rpc.RPCFunc) []string {
+		var names []string
+		for name := range routes {
+			names = append(names, name)
+		}
+		return names
+	}
+
+	config := cfg.DefaultRPCConfig()
+	filtered, err := FilterRoutes(routes, config)
+	require.NoError(t, err)
+	assert.Len(t, filtered, len(routes))
+
+	config.ReadOnly = true
+	filtered, err = FilterRoutes(routes, config)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"status", "block"}, names(filtered))
+
+	config.AllowedMethods = []string{"status", "broadcast_tx_sync"}
+	filtered, err = FilterRoutes(routes, config)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"status"}, names(filtered))
+
+	config.ReadOnly = false
+	filtered, err = FilterRoutes(routes, config)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"status", "broadcast_tx_sync"}, names(filtered))
+
+	config.AllowedMethods = []string{"status", "stauts"}
+	_, err = FilterRoutes(routes, config)
+	assert.Error(t, err)
+}
+
+func TestMethodAllowed(t *testing.T) {
+	env := &Environment{}
+
+	env.SetConfig(cfg.RPCConfig{ReadOnly: true})
+	assert.True(t, env.MethodAllowed("block"))
+	assert.False(t, env.MethodAllowed("broadcast_tx_commit"))
+	assert.False(t, env.MethodAllowed("unsafe_flush_mempool"))
+
+	env.SetConfig(cfg.RPCConfig{AllowedMethods: []string{"block"}})
+	assert.True(t, env.MethodAllowed("block"))
+	assert.False(t, env.MethodAllowed("status"))
+}
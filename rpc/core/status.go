@@ -10,10 +10,17 @@ import (
 	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
 	sm "github.com/tendermint/tendermint/state"
 	"github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
 )
 
 // Get Tendermint status including node info, pubkey, latest block
-// hash, app hash, block height and time.
+// hash, app hash, block height and time, and the disk space used by the
+// node data (blocks, state, WALs, tx index and evidence).
+//
+// With `rpc.status_attestation` on, the status includes an attestation of
+// the node ID, chain ID, latest height and current time signed by the node
+// key, which clients check with `ResultStatus#VerifyAttestation` to make sure
+// they talk to the node itself rather than to a stale cache or an imposter.
 //
 // ```shell
 // curl 'localhost:26657/status'
@@ -67,16 +74,35 @@ import (
 //   			"value": "wVxKNtEsJmR4vvh651LrVoRguPs+6yJJ9Bz174gw9DM="
 //   		},
 //   		"voting_power": "10"
+//   	},
+//   	"disk_usage": [
+//   		{
+//   			"category": "blocks",
+//   			"bytes": "1073741824",
+//   			"soft_quota": "0"
+//   		},
+//   		...
+//   	],
+//   	"attestation": {
+//   		"node_id": "53729852020041b956e86685e24394e0bee4373f",
+//   		"chain_id": "test-chain-Y1OHx6",
+//   		"height": "18",
+//   		"time": "2018-09-17T11:42:20.03625116Z",
+//   		"pub_key": {
+//   			"type": "tendermint/PubKeyEd25519",
+//   			"value": "Gm1qbKzTRnhNm8cOpNKbK7eD6VKMk+DOuMdqCMRpYCo="
+//   		},
+//   		"signature": "hkqUBnUUgjbfnLoBwhYVu6pbMoEKjC0hWz7D5GAqaO1UMNu9GPr2nYB7TgtdEQPvBFbOGtPF7FGnnDGBDqKgCQ=="
 //   	}
 //   }
 // }
 // ```
-func Status(ctx *rpctypes.Context) (*ctypes.ResultStatus, error) {
+func (env *Environment) Status(ctx *rpctypes.Context) (*ctypes.ResultStatus, error) {
 	var latestHeight int64
-	if consensusReactor.FastSync() {
-		latestHeight = blockStore.Height()
+	if env.consensusReactor.FastSync() {
+		latestHeight = env.blockStore.Height()
 	} else {
-		latestHeight = consensusState.GetLastHeight()
+		latestHeight = env.consensusState.GetLastHeight()
 	}
 	var (
 		latestBlockMeta     *types.BlockMeta
@@ -85,7 +111,7 @@ func Status(ctx *rpctypes.Context) (*ctypes.ResultStatus, error) {
 		latestBlockTimeNano int64
 	)
 	if latestHeight != 0 {
-		latestBlockMeta = blockStore.LoadBlockMeta(latestHeight)
+		latestBlockMeta = env.blockStore.LoadBlockMeta(latestHeight)
 		latestBlockHash = latestBlockMeta.BlockID.Hash
 		latestAppHash = latestBlockMeta.Header.AppHash
 		latestBlockTimeNano = latestBlockMeta.Header.Time.UnixNano()
@@ -94,34 +120,53 @@ func Status(ctx *rpctypes.Context) (*ctypes.ResultStatus, error) {
 	latestBlockTime := time.Unix(0, latestBlockTimeNano)
 
 	var votingPower int64
-	if val := validatorAtHeight(latestHeight); val != nil {
+	if val := env.validatorAtHeight(latestHeight); val != nil {
 		votingPower = val.VotingPower
 	}
 
 	result := &ctypes.ResultStatus{
-		NodeInfo: p2pTransport.NodeInfo().(p2p.DefaultNodeInfo),
+		NodeInfo: env.p2pTransport.NodeInfo().(p2p.DefaultNodeInfo),
 		SyncInfo: ctypes.SyncInfo{
 			LatestBlockHash:   latestBlockHash,
 			LatestAppHash:     latestAppHash,
 			LatestBlockHeight: latestHeight,
 			LatestBlockTime:   latestBlockTime,
-			CatchingUp:        consensusReactor.FastSync(),
+			CatchingUp:        env.consensusReactor.FastSync(),
 		},
 		ValidatorInfo: ctypes.ValidatorInfo{
-			Address:     pubKey.Address(),
-			PubKey:      pubKey,
+			Address:     env.pubKey.Address(),
+			PubKey:      env.pubKey,
 			VotingPower: votingPower,
 		},
 	}
+	if env.fastSyncState != nil && result.SyncInfo.CatchingUp {
+		state, retries, err := env.fastSyncState.SyncState()
+		result.SyncInfo.FastSyncState = state
+		result.SyncInfo.FastSyncRetries = retries
+		if err != nil {
+			result.SyncInfo.FastSyncError = err.Error()
+		}
+	}
+	if env.diskUsageStats != nil {
+		result.DiskUsage = env.diskUsageStats.DiskUsage()
+	}
+	if env.attestationKey != nil {
+		attestation, err := ctypes.NewStatusAttestation(
+			env.attestationKey, result.NodeInfo.Network, latestHeight, tmtime.Now())
+		if err != nil {
+			return nil, err
+		}
+		result.Attestation = attestation
+	}
 
 	return result, nil
 }
 
-func validatorAtHeight(h int64) *types.Validator {
-	privValAddress := pubKey.Address()
+func (env *Environment) validatorAtHeight(h int64) *types.Validator {
+	privValAddress := env.pubKey.Address()
 
 	// If we're still at height h, search in the current validator set.
-	lastBlockHeight, vals := consensusState.GetValidators()
+	lastBlockHeight, vals := env.consensusState.GetValidators()
 	if lastBlockHeight == h {
 		for _, val := range vals {
 			if bytes.Equal(val.Address, privValAddress) {
@@ -130,9 +175,10 @@ func validatorAtHeight(h int64) *types.Validator {
 		}
 	}
 
-	// If we've moved to the next height, retrieve the validator set from DB.
-	if lastBlockHeight > h {
-		vals, err := sm.LoadValidators(stateDB, h)
+	// If we've moved to the next height, or the consensus state is not updated
+	// because we are fast syncing, retrieve the validator set from DB.
+	if lastBlockHeight > h || env.consensusReactor.FastSync() {
+		vals, err := sm.LoadValidators(env.stateDB, h)
 		if err != nil {
 			return nil // should not happen
 		}
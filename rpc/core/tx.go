@@ -1,14 +1,14 @@
 package core
 
 import (
-	"fmt"
+	"sort"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
 
 	tmquery "github.com/tendermint/tendermint/libs/pubsub/query"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
-	"github.com/tendermint/tendermint/state/txindex/null"
+	"github.com/tendermint/tendermint/state/indexer"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -78,20 +78,19 @@ import (
 // - `index`: `int` - index of the transaction
 // - `height`: `int` - height of the block where this transaction was in
 // - `hash`: `[]byte` - hash of the transaction
-func Tx(ctx *rpctypes.Context, hash []byte, prove bool) (*ctypes.ResultTx, error) {
-
-	// if index is disabled, return error
-	if _, ok := txIndexer.(*null.TxIndex); ok {
-		return nil, fmt.Errorf("Transaction indexing is disabled")
+func (env *Environment) Tx(ctx *rpctypes.Context, hash []byte, prove bool) (*ctypes.ResultTx, error) {
+	sink, err := env.getTxEventSink()
+	if err != nil {
+		return nil, err
 	}
 
-	r, err := txIndexer.Get(hash)
+	r, err := sink.GetTxByHash(hash)
 	if err != nil {
 		return nil, err
 	}
 
 	if r == nil {
-		return nil, fmt.Errorf("Tx (%X) not found", hash)
+		return nil, ctypes.ErrTxNotFound{Hash: hash}
 	}
 
 	height := r.Height
@@ -99,7 +98,7 @@ func Tx(ctx *rpctypes.Context, hash []byte, prove bool) (*ctypes.ResultTx, error
 
 	var proof types.TxProof
 	if prove {
-		block := blockStore.LoadBlock(height)
+		block := env.blockStore.LoadBlock(height)
 		proof = block.Data.Txs.Proof(int(index)) // XXX: overflow on 32-bit machines
 	}
 
@@ -175,6 +174,7 @@ func Tx(ctx *rpctypes.Context, hash []byte, prove bool) (*ctypes.ResultTx, error
 // | prove     | bool   | false   | false    | Include proofs of the transactions inclusion in the block |
 // | page      | int    | 1       | false    | Page number (1-based)                                     |
 // | per_page  | int    | 30      | false    | Number of entries per page (max: 100)                     |
+// | order_by  | string | "asc"   | false    | Order by height and index ("asc" or "desc")               |
 //
 // ### Returns
 //
@@ -184,10 +184,21 @@ func Tx(ctx *rpctypes.Context, hash []byte, prove bool) (*ctypes.ResultTx, error
 // - `index`: `int` - index of the transaction
 // - `height`: `int` - height of the block where this transaction was in
 // - `hash`: `[]byte` - hash of the transaction
-func TxSearch(ctx *rpctypes.Context, query string, prove bool, page, perPage int) (*ctypes.ResultTxSearch, error) {
-	// if index is disabled, return error
-	if _, ok := txIndexer.(*null.TxIndex); ok {
-		return nil, fmt.Errorf("Transaction indexing is disabled")
+func (env *Environment) TxSearch(
+	ctx *rpctypes.Context,
+	query string,
+	prove bool,
+	page, perPage int,
+	orderBy string,
+) (*ctypes.ResultTxSearch, error) {
+	sink, err := env.getTxEventSink()
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := validateOrderBy(orderBy)
+	if err != nil {
+		return nil, err
 	}
 
 	q, err := tmquery.New(query)
@@ -195,11 +206,23 @@ func TxSearch(ctx *rpctypes.Context, query string, prove bool, page, perPage int
 		return nil, err
 	}
 
-	results, err := txIndexer.Search(q)
+	results, err := sink.SearchTxEvents(ctx.Context(), q)
 	if err != nil {
 		return nil, err
 	}
 
+	// sort the results, so that pages are stable across requests
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if desc {
+			a, b = b, a
+		}
+		if a.Height == b.Height {
+			return a.Index < b.Index
+		}
+		return a.Height < b.Height
+	})
+
 	totalCount := len(results)
 	perPage = validatePerPage(perPage)
 	page, err = validatePage(page, perPage, totalCount)
@@ -217,7 +240,7 @@ func TxSearch(ctx *rpctypes.Context, query string, prove bool, page, perPage int
 		index := r.Index
 
 		if prove {
-			block := blockStore.LoadBlock(height)
+			block := env.blockStore.LoadBlock(height)
 			proof = block.Data.Txs.Proof(int(index)) // XXX: overflow on 32-bit machines
 		}
 
@@ -233,3 +256,26 @@ func TxSearch(ctx *rpctypes.Context, query string, prove bool, page, perPage int
 
 	return &ctypes.ResultTxSearch{Txs: apiResults, TotalCount: totalCount}, nil
 }
+
+// getKVEventSink returns the key-value event sink used to serve queries. It
+// returns an error if indexing is disabled or no sink supports queries.
+func (env *Environment) getKVEventSink() (indexer.EventSink, error) {
+	if sink := indexer.KVSink(env.eventSinks); sink != nil {
+		return sink, nil
+	}
+	if indexer.IndexingEnabled(env.eventSinks) {
+		return nil, ctypes.ErrIndexingDisabled{
+			Reason: "Querying the index requires the \"kv\" indexer (query the database of other indexers directly)",
+		}
+	}
+	return nil, ctypes.ErrIndexingDisabled{Reason: "Indexing is disabled"}
+}
+
+// getTxEventSink returns the event sink used to serve the tx queries (see
+// indexer.TxSink). It returns an error if indexing is disabled.
+func (env *Environment) getTxEventSink() (indexer.EventSink, error) {
+	if sink := indexer.TxSink(env.eventSinks); sink != nil {
+		return sink, nil
+	}
+	return nil, ctypes.ErrIndexingDisabled{Reason: "Indexing is disabled"}
+}
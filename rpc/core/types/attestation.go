@@ -0,0 +1,111 @@
+package core_types
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	amino "github.com/tendermint/go-amino"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+var attestationCdc = amino.NewCodec()
+
+// StatusAttestation is a statement signed by the node key that the node
+// served a /status for the chain at the height and time, so that monitoring
+// systems can check they talk to the node itself rather than to a stale
+// cache or an imposter behind a load balancer.
+//
+// It's signed by the node key rather than by the validator key, as the
+// signers of the validator key only sign votes and proposals.
+type StatusAttestation struct {
+	NodeID    p2p.ID        `json:"node_id"`
+	ChainID   string        `json:"chain_id"`
+	Height    int64         `json:"height"`
+	Time      time.Time     `json:"time"`
+	PubKey    crypto.PubKey `json:"pub_key"`
+	Signature []byte        `json:"signature"`
+}
+
+// canonicalStatusAttestation is the signed part of a StatusAttestation.
+type canonicalStatusAttestation struct {
+	Type    string // "status_attestation", so it can't be mistaken for other signed messages
+	NodeID  string
+	ChainID string
+	Height  int64
+	Time    time.Time
+}
+
+// NewStatusAttestation returns an attestation of the status of the node of
+// the key, signed with it.
+func NewStatusAttestation(
+	key crypto.PrivKey,
+	chainID string,
+	height int64,
+	now time.Time,
+) (*StatusAttestation, error) {
+	pubKey := key.PubKey()
+	a := &StatusAttestation{
+		NodeID:  p2p.PubKeyToID(pubKey),
+		ChainID: chainID,
+		Height:  height,
+		Time:    now,
+		PubKey:  pubKey,
+	}
+	sig, err := key.Sign(a.SignBytes())
+	if err != nil {
+		return nil, err
+	}
+	a.Signature = sig
+	return a, nil
+}
+
+// SignBytes returns the bytes signed by the attestation.
+func (a *StatusAttestation) SignBytes() []byte {
+	return attestationCdc.MustMarshalBinaryBare(canonicalStatusAttestation{
+		Type:    "status_attestation",
+		NodeID:  string(a.NodeID),
+		ChainID: a.ChainID,
+		Height:  a.Height,
+		Time:    a.Time,
+	})
+}
+
+// Verify checks the attestation is signed by the key of its node ID, for the
+// chain, and that its time is at most maxAge away from now.
+func (a *StatusAttestation) Verify(chainID string, now time.Time, maxAge time.Duration) error {
+	if a.PubKey == nil {
+		return errors.New("no public key")
+	}
+	if id := p2p.PubKeyToID(a.PubKey); id != a.NodeID {
+		return fmt.Errorf("public key of node %v, not %v", id, a.NodeID)
+	}
+	if a.ChainID != chainID {
+		return fmt.Errorf("attestation for chain %q, not %q", a.ChainID, chainID)
+	}
+	if age := now.Sub(a.Time); age > maxAge || age < -maxAge {
+		return fmt.Errorf("attestation time %v too far from %v (max %v)", a.Time, now, maxAge)
+	}
+	if !a.PubKey.VerifyBytes(a.SignBytes(), a.Signature) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// VerifyAttestation checks the status is attested by the node it describes,
+// at its latest height: see StatusAttestation#Verify.
+func (s *ResultStatus) VerifyAttestation(chainID string, now time.Time, maxAge time.Duration) error {
+	a := s.Attestation
+	if a == nil {
+		return errors.New("status not attested")
+	}
+	if a.NodeID != s.NodeInfo.ID() {
+		return fmt.Errorf("attestation of node %v, not %v", a.NodeID, s.NodeInfo.ID())
+	}
+	if a.Height != s.SyncInfo.LatestBlockHeight {
+		return fmt.Errorf("attestation at height %d, not %d", a.Height, s.SyncInfo.LatestBlockHeight)
+	}
+	return a.Verify(chainID, now, maxAge)
+}
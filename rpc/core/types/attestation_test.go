@@ -0,0 +1,51 @@
+package core_types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+func TestStatusAttestation(t *testing.T) {
+	key := ed25519.GenPrivKey()
+	now := time.Now()
+
+	a, err := NewStatusAttestation(key, "test-chain", 18, now)
+	require.NoError(t, err)
+	assert.Equal(t, p2p.PubKeyToID(key.PubKey()), a.NodeID)
+	assert.NoError(t, a.Verify("test-chain", now.Add(time.Second), 5*time.Second))
+
+	assert.Error(t, a.Verify("other-chain", now, 5*time.Second))
+	assert.Error(t, a.Verify("test-chain", now.Add(10*time.Second), 5*time.Second), "stale")
+	assert.Error(t, a.Verify("test-chain", now.Add(-10*time.Second), 5*time.Second), "from the future")
+
+	tampered := *a
+	tampered.Height++
+	assert.Error(t, tampered.Verify("test-chain", now, 5*time.Second))
+
+	// an imposter signing with its own key for the node ID
+	imposter, err := NewStatusAttestation(ed25519.GenPrivKey(), "test-chain", 18, now)
+	require.NoError(t, err)
+	imposter.NodeID = a.NodeID
+	assert.Error(t, imposter.Verify("test-chain", now, 5*time.Second))
+
+	status := &ResultStatus{
+		NodeInfo:    p2p.DefaultNodeInfo{ID_: a.NodeID},
+		SyncInfo:    SyncInfo{LatestBlockHeight: 18},
+		Attestation: a,
+	}
+	assert.NoError(t, status.VerifyAttestation("test-chain", now, 5*time.Second))
+
+	status.SyncInfo.LatestBlockHeight = 19
+	assert.Error(t, status.VerifyAttestation("test-chain", now, 5*time.Second))
+	status.SyncInfo.LatestBlockHeight = 18
+	status.NodeInfo.ID_ = p2p.PubKeyToID(ed25519.GenPrivKey().PubKey())
+	assert.Error(t, status.VerifyAttestation("test-chain", now, 5*time.Second))
+	status.Attestation = nil
+	assert.Error(t, status.VerifyAttestation("test-chain", now, 5*time.Second))
+}
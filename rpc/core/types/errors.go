@@ -0,0 +1,123 @@
+package core_types
+
+import (
+	"fmt"
+)
+
+// JSON-RPC error codes of the errors returned by the RPC endpoints, within the
+// range reserved for server errors. Errors without a code of their own are
+// returned as internal errors (-32603).
+//
+// NOTE: append new codes; never reuse or change them, clients depend on them.
+const (
+	CodeNodeSyncing        = -32001
+	CodeHeightPruned       = -32002
+	CodeHeightNotAvailable = -32003
+	CodeTxNotFound         = -32004
+	CodeMempoolFull        = -32005
+	CodeTxInCache          = -32006
+	CodeIndexingDisabled   = -32007
+	CodeTimeout            = -32008
+)
+
+// ErrNodeSyncing is returned by the endpoints which can not give a
+// meaningful response while the node is catching up.
+type ErrNodeSyncing struct {
+	Phase  SyncPhase
+	Height int64 // height of the latest block in the store
+}
+
+func (e ErrNodeSyncing) Error() string {
+	return fmt.Sprintf("node is syncing (phase: %s, height: %d)", e.Phase, e.Height)
+}
+
+// RPCCode implements rpctypes.CodedError.
+func (e ErrNodeSyncing) RPCCode() int { return CodeNodeSyncing }
+
+// ErrHeightPruned is returned when the requested height was pruned from the
+// node, which only keeps the heights from Base.
+type ErrHeightPruned struct {
+	Height int64
+	Base   int64
+}
+
+func (e ErrHeightPruned) Error() string {
+	return fmt.Sprintf("height %d is not available, lowest height is %d", e.Height, e.Base)
+}
+
+// RPCCode implements rpctypes.CodedError.
+func (e ErrHeightPruned) RPCCode() int { return CodeHeightPruned }
+
+// ErrHeightNotAvailable is returned when the requested height was not
+// produced yet (or is invalid): the latest height is Latest.
+type ErrHeightNotAvailable struct {
+	Height int64
+	Latest int64
+}
+
+func (e ErrHeightNotAvailable) Error() string {
+	if e.Height <= 0 {
+		return "Height must be greater than 0"
+	}
+	return fmt.Sprintf("Height must be less than or equal to the current blockchain height (%d)", e.Latest)
+}
+
+// RPCCode implements rpctypes.CodedError.
+func (e ErrHeightNotAvailable) RPCCode() int { return CodeHeightNotAvailable }
+
+// ErrTxNotFound is returned when no indexed transaction has the given hash.
+type ErrTxNotFound struct {
+	Hash []byte
+}
+
+func (e ErrTxNotFound) Error() string {
+	return fmt.Sprintf("Tx (%X) not found", e.Hash)
+}
+
+// RPCCode implements rpctypes.CodedError.
+func (e ErrTxNotFound) RPCCode() int { return CodeTxNotFound }
+
+// ErrMempoolFull is returned when a transaction is rejected because the
+// mempool is full. Clients may retry later.
+type ErrMempoolFull struct {
+	Err error // error of the mempool
+}
+
+func (e ErrMempoolFull) Error() string { return e.Err.Error() }
+
+// RPCCode implements rpctypes.CodedError.
+func (e ErrMempoolFull) RPCCode() int { return CodeMempoolFull }
+
+// ErrTxInCache is returned when a transaction was already seen by the
+// mempool.
+type ErrTxInCache struct {
+	Err error // error of the mempool
+}
+
+func (e ErrTxInCache) Error() string { return e.Err.Error() }
+
+// RPCCode implements rpctypes.CodedError.
+func (e ErrTxInCache) RPCCode() int { return CodeTxInCache }
+
+// ErrIndexingDisabled is returned by the endpoints querying the transaction
+// or block index when it can not be queried: indexing is disabled, or no
+// enabled indexer supports queries.
+type ErrIndexingDisabled struct {
+	Reason string
+}
+
+func (e ErrIndexingDisabled) Error() string { return e.Reason }
+
+// RPCCode implements rpctypes.CodedError.
+func (e ErrIndexingDisabled) RPCCode() int { return CodeIndexingDisabled }
+
+// ErrTimeout is returned when the node timed out waiting for an event, such
+// as the commit of a transaction.
+type ErrTimeout struct {
+	Reason string
+}
+
+func (e ErrTimeout) Error() string { return e.Reason }
+
+// RPCCode implements rpctypes.CodedError.
+func (e ErrTimeout) RPCCode() int { return CodeTimeout }
@@ -5,8 +5,10 @@ import (
 	"time"
 
 	abci "github.com/tendermint/tendermint/abci/types"
+	cstypes "github.com/tendermint/tendermint/consensus/types"
 	"github.com/tendermint/tendermint/crypto"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	mempl "github.com/tendermint/tendermint/mempool"
 
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/state"
@@ -63,8 +65,27 @@ type SyncInfo struct {
 	LatestBlockHeight int64        `json:"latest_block_height"`
 	LatestBlockTime   time.Time    `json:"latest_block_time"`
 	CatchingUp        bool         `json:"catching_up"`
+
+	// state of the fast sync, while catching up, e.g. waitForRetry after
+	// losing all the peers, or aborted after an error the node can't recover
+	// from (see types.EventDataFastSyncStatus)
+	FastSyncState   string `json:"fast_sync_state,omitempty"`
+	FastSyncRetries int    `json:"fast_sync_retries,omitempty"`
+	FastSyncError   string `json:"fast_sync_error,omitempty"`
 }
 
+// SyncPhase describes how the node is catching up with the network.
+type SyncPhase string
+
+const (
+	// SyncPhaseFastSync means blocks are downloaded from peers and executed
+	// by the blockchain reactor.
+	SyncPhaseFastSync SyncPhase = "fast_sync"
+	// SyncPhaseWALReplay means the consensus state is replaying its WAL
+	// before participating in consensus.
+	SyncPhaseWALReplay SyncPhase = "wal_replay"
+)
+
 // Info about the node's validator
 type ValidatorInfo struct {
 	Address     cmn.HexBytes  `json:"address"`
@@ -77,6 +98,16 @@ type ResultStatus struct {
 	NodeInfo      p2p.DefaultNodeInfo `json:"node_info"`
 	SyncInfo      SyncInfo            `json:"sync_info"`
 	ValidatorInfo ValidatorInfo       `json:"validator_info"`
+	DiskUsage     []DiskUsage         `json:"disk_usage"`
+	Attestation   *StatusAttestation  `json:"attestation,omitempty"` // nil unless rpc.status_attestation is on
+}
+
+// DiskUsage is the disk space used by a category of the node data: blocks,
+// state, wal, tx_index or evidence.
+type DiskUsage struct {
+	Category  string `json:"category"`
+	Bytes     int64  `json:"bytes"`
+	SoftQuota int64  `json:"soft_quota"` // 0 if none
 }
 
 // Is TxIndexing enabled
@@ -111,12 +142,25 @@ type Peer struct {
 	IsOutbound       bool                 `json:"is_outbound"`
 	ConnectionStatus p2p.ConnectionStatus `json:"connection_status"`
 	RemoteIP         string               `json:"remote_ip"`
+	Label            string               `json:"label,omitempty"`
 }
 
 // Validators for a height
 type ResultValidators struct {
 	BlockHeight int64              `json:"block_height"`
 	Validators  []*types.Validator `json:"validators"`
+	// Count of the validators in this page, and Total count of validators
+	Count int `json:"count"`
+	Total int `json:"total"`
+}
+
+// Changes of the validator set made by the updates of a height
+type ResultValidatorChanges struct {
+	Height          int64                        `json:"height"`
+	EffectiveHeight int64                        `json:"effective_height"`
+	Added           []*types.Validator           `json:"added"`
+	Removed         []*types.Validator           `json:"removed"`
+	PowerChanges    []types.ValidatorPowerChange `json:"power_changes"`
 }
 
 // ConsensusParams for given height
@@ -143,6 +187,15 @@ type ResultConsensusState struct {
 	RoundState json.RawMessage `json:"round_state"`
 }
 
+// Rounds of a height, as seen by the node. Vote times are indexed like
+// Validators; zero times are for votes which weren't received.
+// UNSTABLE
+type ResultConsensusRounds struct {
+	Height     int64                 `json:"height"`
+	Validators []types.Address       `json:"validators"`
+	Rounds     []*cstypes.RoundTrace `json:"rounds"`
+}
+
 // CheckTx result
 type ResultBroadcastTx struct {
 	Code uint32       `json:"code"`
@@ -176,6 +229,12 @@ type ResultTxSearch struct {
 	TotalCount int         `json:"total_count"`
 }
 
+// Result of searching for blocks
+type ResultBlockSearch struct {
+	Blocks     []*ResultBlock `json:"blocks"`
+	TotalCount int            `json:"total_count"`
+}
+
 // List of mempool txs
 type ResultUnconfirmedTxs struct {
 	Count      int        `json:"n_txs"`
@@ -184,6 +243,12 @@ type ResultUnconfirmedTxs struct {
 	Txs        []types.Tx `json:"txs"`
 }
 
+// List of txs recently rejected by the mempool
+type ResultRejectedTxs struct {
+	Count      int                 `json:"n_txs"`
+	Rejections []mempl.TxRejection `json:"rejections"`
+}
+
 // Info abci msg
 type ResultABCIInfo struct {
 	Response abci.ResponseInfo `json:"response"`
@@ -199,8 +264,32 @@ type ResultBroadcastEvidence struct {
 	Hash []byte `json:"hash"`
 }
 
+// Result of the admin sync_status, pause_sync and resume_sync
+type ResultSyncStatus struct {
+	// Depends on the fast sync version
+	SyncStatus json.RawMessage `json:"sync_status"`
+}
+
+// Result of the admin prune_blocks
+type ResultPruneBlocks struct {
+	Pruned uint64 `json:"pruned"`
+	Base   int64  `json:"base"` // lowest height left in the block store
+}
+
+// Result of the admin backfill_results and backfill_status
+type ResultBackfillStatus struct {
+	Backfill state.BackfillStatus `json:"backfill"`
+}
+
+// Result of the admin compact_db
+type ResultCompactDB struct {
+	Compacted []string `json:"compacted"` // IDs of the compacted databases
+}
+
 // empty results
 type (
+	ResultSetLogLevel        struct{}
+	ResultEvictTx            struct{}
 	ResultUnsafeFlushMempool struct{}
 	ResultUnsafeProfile      struct{}
 	ResultSubscribe          struct{}
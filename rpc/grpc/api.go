@@ -9,6 +9,7 @@ import (
 )
 
 type broadcastAPI struct {
+	env *core.Environment
 }
 
 func (bapi *broadcastAPI) Ping(ctx context.Context, req *RequestPing) (*ResponsePing, error) {
@@ -17,9 +18,12 @@ func (bapi *broadcastAPI) Ping(ctx context.Context, req *RequestPing) (*Response
 }
 
 func (bapi *broadcastAPI) BroadcastTx(ctx context.Context, req *RequestBroadcastTx) (*ResponseBroadcastTx, error) {
+	if err := checkMethodAllowed(bapi.env, "broadcast_tx_commit"); err != nil {
+		return nil, err
+	}
 	// NOTE: there's no way to get client's remote address
 	// see https://stackoverflow.com/questions/33684570/session-and-remote-ip-address-in-grpc-go
-	res, err := core.BroadcastTxCommit(&rpctypes.Context{}, req.Tx)
+	res, err := bapi.env.BroadcastTxCommit(&rpctypes.Context{}, req.Tx)
 	if err != nil {
 		return nil, err
 	}
@@ -37,3 +41,130 @@ func (bapi *broadcastAPI) BroadcastTx(ctx context.Context, req *RequestBroadcast
 		},
 	}, nil
 }
+
+//-----------------------------------------------------------------------------
+
+type coreAPI struct {
+	env *core.Environment
+}
+
+var _ CoreAPIServer = (*coreAPI)(nil)
+
+func (capi *coreAPI) Status(ctx context.Context, req *StatusRequest) (*StatusResponse, error) {
+	if err := checkMethodAllowed(capi.env, "status"); err != nil {
+		return nil, err
+	}
+	res, err := capi.env.Status(&rpctypes.Context{})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &StatusResponse{
+		NodeInfo: &NodeInfo{
+			Id:         string(res.NodeInfo.ID_),
+			ListenAddr: res.NodeInfo.ListenAddr,
+			Network:    res.NodeInfo.Network,
+			Version:    res.NodeInfo.Version,
+			Moniker:    res.NodeInfo.Moniker,
+		},
+		SyncInfo: &SyncInfo{
+			LatestBlockHash:   res.SyncInfo.LatestBlockHash,
+			LatestAppHash:     res.SyncInfo.LatestAppHash,
+			LatestBlockHeight: res.SyncInfo.LatestBlockHeight,
+			LatestBlockTime:   timestampProto(res.SyncInfo.LatestBlockTime),
+			CatchingUp:        res.SyncInfo.CatchingUp,
+		},
+		ValidatorInfo: &Validator{
+			Address:     res.ValidatorInfo.Address,
+			PubKey:      pubKeyBytes(res.ValidatorInfo.PubKey),
+			VotingPower: res.ValidatorInfo.VotingPower,
+		},
+	}, nil
+}
+
+func (capi *coreAPI) Block(ctx context.Context, req *BlockRequest) (*BlockResponse, error) {
+	if err := checkMethodAllowed(capi.env, "block"); err != nil {
+		return nil, err
+	}
+	res, err := capi.env.Block(&rpctypes.Context{}, heightPtr(req.Height))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	block := res.Block
+	txs := make([][]byte, len(block.Txs))
+	for i, tx := range block.Txs {
+		txs[i] = tx
+	}
+	evidence := make([][]byte, len(block.Evidence.Evidence))
+	for i, ev := range block.Evidence.Evidence {
+		evidence[i] = cdc.MustMarshalBinaryBare(ev)
+	}
+	return &BlockResponse{
+		BlockId:    blockIDProto(res.BlockMeta.BlockID),
+		Header:     headerProto(&block.Header),
+		Txs:        txs,
+		Evidence:   evidence,
+		LastCommit: commitProto(block.LastCommit),
+	}, nil
+}
+
+func (capi *coreAPI) BlockResults(ctx context.Context, req *BlockResultsRequest) (*BlockResultsResponse, error) {
+	if err := checkMethodAllowed(capi.env, "block_results"); err != nil {
+		return nil, err
+	}
+	res, err := capi.env.BlockResults(&rpctypes.Context{}, heightPtr(req.Height))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &BlockResultsResponse{
+		Height:     res.Height,
+		BeginBlock: res.Results.BeginBlock,
+		DeliverTx:  res.Results.DeliverTx,
+		EndBlock:   res.Results.EndBlock,
+	}, nil
+}
+
+func (capi *coreAPI) Validators(ctx context.Context, req *ValidatorsRequest) (*ValidatorsResponse, error) {
+	if err := checkMethodAllowed(capi.env, "validators"); err != nil {
+		return nil, err
+	}
+	res, err := capi.env.Validators(&rpctypes.Context{}, heightPtr(req.Height), int(req.Page), int(req.PerPage))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	validators := make([]*Validator, len(res.Validators))
+	for i, val := range res.Validators {
+		validators[i] = &Validator{
+			Address:          val.Address,
+			PubKey:           pubKeyBytes(val.PubKey),
+			VotingPower:      val.VotingPower,
+			ProposerPriority: val.ProposerPriority,
+		}
+	}
+	return &ValidatorsResponse{
+		BlockHeight: res.BlockHeight,
+		Validators:  validators,
+		Count:       int32(res.Count),
+		Total:       int32(res.Total),
+	}, nil
+}
+
+// BroadcastTx has the semantics of broadcast_tx_sync: it returns once the tx
+// passed CheckTx, or not. Clients can wait for it to be committed with a
+// subscription to its events over the websocket, or poll for it.
+func (capi *coreAPI) BroadcastTx(ctx context.Context, req *BroadcastTxRequest) (*BroadcastTxResponse, error) {
+	if err := checkMethodAllowed(capi.env, "broadcast_tx_sync"); err != nil {
+		return nil, err
+	}
+	res, err := capi.env.BroadcastTxSync(&rpctypes.Context{}, req.Tx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &BroadcastTxResponse{
+		CheckTx: &abci.ResponseCheckTx{
+			Code: res.Code,
+			Data: res.Data,
+			Log:  res.Log,
+		},
+		Hash: res.Hash,
+	}, nil
+}
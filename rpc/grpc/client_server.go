@@ -7,6 +7,7 @@ import (
 	"google.golang.org/grpc"
 
 	cmn "github.com/tendermint/tendermint/libs/common"
+	core "github.com/tendermint/tendermint/rpc/core"
 )
 
 // Config is an gRPC server configuration.
@@ -14,25 +15,45 @@ type Config struct {
 	MaxOpenConnections int
 }
 
-// StartGRPCServer starts a new gRPC BroadcastAPIServer using the given
-// net.Listener.
+// StartGRPCServer starts a new gRPC server with the BroadcastAPI and the
+// CoreAPI of the RPC environment using the given net.Listener.
 // NOTE: This function blocks - you may want to call it in a go-routine.
-func StartGRPCServer(ln net.Listener) error {
-	grpcServer := grpc.NewServer()
-	RegisterBroadcastAPIServer(grpcServer, &broadcastAPI{})
+func StartGRPCServer(env *core.Environment, ln net.Listener) error {
+	grpcServer := grpc.NewServer(grpc.CustomCodec(codec{}))
+	RegisterBroadcastAPIServer(grpcServer, &broadcastAPI{env: env})
+	RegisterCoreAPIServer(grpcServer, &coreAPI{env: env})
 	return grpcServer.Serve(ln)
 }
 
 // StartGRPCClient dials the gRPC server using protoAddr and returns a new
 // BroadcastAPIClient.
 func StartGRPCClient(protoAddr string) BroadcastAPIClient {
-	conn, err := grpc.Dial(protoAddr, grpc.WithInsecure(), grpc.WithContextDialer(dialerFunc))
+	conn, err := dial(protoAddr)
 	if err != nil {
 		panic(err)
 	}
 	return NewBroadcastAPIClient(conn)
 }
 
+// StartGRPCCoreClient dials the gRPC server using protoAddr and returns a new
+// CoreAPIClient.
+func StartGRPCCoreClient(protoAddr string) CoreAPIClient {
+	conn, err := dial(protoAddr)
+	if err != nil {
+		panic(err)
+	}
+	return NewCoreAPIClient(conn)
+}
+
+func dial(protoAddr string) (*grpc.ClientConn, error) {
+	return grpc.Dial(
+		protoAddr,
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(dialerFunc),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})),
+	)
+}
+
 func dialerFunc(ctx context.Context, addr string) (net.Conn, error) {
 	return cmn.Connect(addr)
 }
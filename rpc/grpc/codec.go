@@ -0,0 +1,31 @@
+package core_grpc
+
+import (
+	"github.com/gogo/protobuf/proto"
+)
+
+// codec encodes the messages of the gRPC services with their code generated by
+// protoc-gen-gogo, rather than with golang/protobuf, whose reflection doesn't
+// know the gogoproto options of the ABCI messages (e.g. the non-nullable
+// events).
+type codec struct{}
+
+// Marshal implements grpc/encoding.Codec.
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return proto.Marshal(v.(proto.Message))
+}
+
+// Unmarshal implements grpc/encoding.Codec.
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return proto.Unmarshal(data, v.(proto.Message))
+}
+
+// Name implements grpc/encoding.Codec.
+func (codec) Name() string {
+	return "proto"
+}
+
+// String implements grpc.Codec, for grpc.CustomCodec.
+func (codec) String() string {
+	return "proto"
+}
@@ -0,0 +1,147 @@
+package core_grpc
+
+import (
+	"time"
+
+	gogotypes "github.com/gogo/protobuf/types"
+	"github.com/pkg/errors"
+	amino "github.com/tendermint/go-amino"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tendermint/tendermint/crypto"
+	core "github.com/tendermint/tendermint/rpc/core"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
+)
+
+// cdc encodes the evidence and the aggregate commits, which have no messages
+// of their own in the core service.
+var cdc = amino.NewCodec()
+
+func init() {
+	ctypes.RegisterAmino(cdc)
+}
+
+// grpcError returns the gRPC status of an error of the RPC core, with the
+// code closest to its JSON-RPC one (see ctypes.CodeNodeSyncing etc.).
+func grpcError(err error) error {
+	var code codes.Code
+	switch errors.Cause(err).(type) {
+	case ctypes.ErrNodeSyncing:
+		code = codes.Unavailable
+	case ctypes.ErrHeightPruned, ctypes.ErrTxNotFound:
+		code = codes.NotFound
+	case ctypes.ErrHeightNotAvailable:
+		code = codes.OutOfRange
+	case ctypes.ErrMempoolFull:
+		code = codes.ResourceExhausted
+	case ctypes.ErrTxInCache:
+		code = codes.AlreadyExists
+	case ctypes.ErrIndexingDisabled:
+		code = codes.FailedPrecondition
+	case ctypes.ErrTimeout:
+		code = codes.DeadlineExceeded
+	default:
+		code = codes.Unknown
+	}
+	return status.Error(code, err.Error())
+}
+
+// checkMethodAllowed returns a PermissionDenied status if the RPC method
+// matching a gRPC one isn't served (rpc.read_only and rpc.allowed_methods).
+func checkMethodAllowed(env *core.Environment, method string) error {
+	if !env.MethodAllowed(method) {
+		return status.Errorf(codes.PermissionDenied, "method %s is disabled (rpc.read_only or rpc.allowed_methods)", method)
+	}
+	return nil
+}
+
+// heightPtr returns the height argument of the RPC core for a requested
+// height: nil, the latest one, for 0.
+func heightPtr(height int64) *int64 {
+	if height == 0 {
+		return nil
+	}
+	return &height
+}
+
+// timestampProto returns the time of a Timestamp field, nil for the zero time.
+func timestampProto(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	if _, err := gogotypes.TimestampProto(t); err != nil {
+		// out of the range of the timestamps (years 1 to 9999)
+		return nil
+	}
+	return &t
+}
+
+func pubKeyBytes(pubKey crypto.PubKey) []byte {
+	if pubKey == nil {
+		return nil
+	}
+	return pubKey.Bytes()
+}
+
+func blockIDProto(blockID types.BlockID) *BlockID {
+	return &BlockID{
+		Hash: blockID.Hash,
+		PartsHeader: &PartSetHeader{
+			Total: int32(blockID.PartsHeader.Total),
+			Hash:  blockID.PartsHeader.Hash,
+		},
+	}
+}
+
+func headerProto(h *types.Header) *Header {
+	return &Header{
+		VersionBlock:       uint64(h.Version.Block),
+		VersionApp:         uint64(h.Version.App),
+		ChainId:            h.ChainID,
+		Height:             h.Height,
+		Time:               timestampProto(h.Time),
+		NumTxs:             h.NumTxs,
+		TotalTxs:           h.TotalTxs,
+		LastBlockId:        blockIDProto(h.LastBlockID),
+		LastCommitHash:     h.LastCommitHash,
+		DataHash:           h.DataHash,
+		ValidatorsHash:     h.ValidatorsHash,
+		NextValidatorsHash: h.NextValidatorsHash,
+		ConsensusHash:      h.ConsensusHash,
+		AppHash:            h.AppHash,
+		LastResultsHash:    h.LastResultsHash,
+		EvidenceHash:       h.EvidenceHash,
+		ProposerAddress:    h.ProposerAddress,
+	}
+}
+
+func commitProto(commit *types.Commit) *Commit {
+	if commit == nil {
+		return nil
+	}
+	sigs := make([]*CommitSig, len(commit.Precommits))
+	for i, precommit := range commit.Precommits {
+		if precommit == nil {
+			sigs[i] = &CommitSig{}
+			continue
+		}
+		sigs[i] = &CommitSig{
+			ValidatorAddress: precommit.ValidatorAddress,
+			ValidatorIndex:   int32(precommit.ValidatorIndex),
+			Round:            int32(precommit.Round),
+			Timestamp:        timestampProto(precommit.Timestamp),
+			Signature:        precommit.Signature,
+		}
+	}
+	var aggregate []byte
+	if commit.Aggregate != nil {
+		aggregate = cdc.MustMarshalBinaryBare(commit.Aggregate)
+	}
+	return &Commit{
+		BlockId:    blockIDProto(commit.BlockID),
+		Signatures: sigs,
+		Aggregate:  aggregate,
+	}
+}
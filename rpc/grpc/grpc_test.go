@@ -4,12 +4,16 @@ import (
 	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
 
 	"github.com/tendermint/tendermint/abci/example/kvstore"
 	core_grpc "github.com/tendermint/tendermint/rpc/grpc"
 	rpctest "github.com/tendermint/tendermint/rpc/test"
+	"github.com/tendermint/tendermint/types"
 )
 
 func TestMain(m *testing.M) {
@@ -33,3 +37,59 @@ func TestBroadcastTx(t *testing.T) {
 	require.EqualValues(t, 0, res.CheckTx.Code)
 	require.EqualValues(t, 0, res.DeliverTx.Code)
 }
+
+func TestCoreAPI(t *testing.T) {
+	client := rpctest.GetGRPCCoreClient()
+	ctx := context.Background()
+
+	status, err := client.Status(ctx, &core_grpc.StatusRequest{})
+	require.NoError(t, err)
+	require.NotEmpty(t, status.NodeInfo.Id)
+	require.NotEmpty(t, status.ValidatorInfo.PubKey)
+
+	tx := []byte("grpc=core")
+	res, err := client.BroadcastTx(ctx, &core_grpc.BroadcastTxRequest{Tx: tx})
+	require.NoError(t, err)
+	require.EqualValues(t, 0, res.CheckTx.Code)
+	require.Equal(t, types.Tx(tx).Hash(), res.Hash)
+
+	// wait for a block with the tx, and one more with its results
+	var block *core_grpc.BlockResponse
+	for height := status.SyncInfo.LatestBlockHeight + 1; block == nil; height++ {
+		waitForHeight(t, client, height+1)
+		b, err := client.Block(ctx, &core_grpc.BlockRequest{Height: height})
+		require.NoError(t, err)
+		require.EqualValues(t, height, b.Header.Height)
+		if len(b.Txs) > 0 {
+			block = b
+		}
+	}
+	require.Equal(t, [][]byte{tx}, block.Txs)
+	require.NotNil(t, block.Header.Time)
+	require.Len(t, block.LastCommit.Signatures, 1)
+
+	results, err := client.BlockResults(ctx, &core_grpc.BlockResultsRequest{Height: block.Header.Height})
+	require.NoError(t, err)
+	require.Len(t, results.DeliverTx, 1)
+	require.EqualValues(t, 0, results.DeliverTx[0].Code)
+
+	vals, err := client.Validators(ctx, &core_grpc.ValidatorsRequest{Height: block.Header.Height})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, vals.Total)
+	require.Equal(t, status.ValidatorInfo.PubKey, vals.Validators[0].PubKey)
+
+	_, err = client.Block(ctx, &core_grpc.BlockRequest{Height: 1 << 40})
+	require.Equal(t, codes.OutOfRange, grpcstatus.Code(err))
+}
+
+func waitForHeight(t *testing.T, client core_grpc.CoreAPIClient, height int64) {
+	for i := 0; i < 100; i++ {
+		status, err := client.Status(context.Background(), &core_grpc.StatusRequest{})
+		require.NoError(t, err)
+		if status.SyncInfo.LatestBlockHeight >= height {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for height %d", height)
+}
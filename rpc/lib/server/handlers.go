@@ -12,6 +12,7 @@ import (
 	"runtime/debug"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -24,17 +25,59 @@ import (
 )
 
 // RegisterRPCFuncs adds a route for each function in the funcMap,
-// as well as general jsonrpc and websocket handlers for all functions.
+// as well as general jsonrpc and websocket handlers for all functions, and
+// /openapi.json, their OpenAPI document (see OpenAPI).
 // "result" is the interface on which the result objects are registered,
 // and is popualted with every RPCResponse
-func RegisterRPCFuncs(mux *http.ServeMux, funcMap map[string]*RPCFunc, cdc *amino.Codec, logger log.Logger) {
+func RegisterRPCFuncs(
+	mux *http.ServeMux,
+	funcMap map[string]*RPCFunc,
+	cdc *amino.Codec,
+	logger log.Logger,
+	options ...func(*jsonRPCHandlerConfig),
+) {
+	cfg := defaultJSONRPCHandlerConfig()
+	for _, option := range options {
+		option(cfg)
+	}
+
 	// HTTP endpoints
 	for funcName, rpcFunc := range funcMap {
 		mux.HandleFunc("/"+funcName, makeHTTPHandler(rpcFunc, cdc, logger))
 	}
 
+	mux.HandleFunc("/openapi.json", makeOpenAPIHandler(funcMap, cfg))
+
 	// JSONRPC endpoints
-	mux.HandleFunc("/", handleInvalidJSONRPCPaths(makeJSONRPCHandler(funcMap, cdc, logger)))
+	mux.HandleFunc("/", handleInvalidJSONRPCPaths(makeJSONRPCHandler(funcMap, cdc, logger, cfg)))
+}
+
+const defaultMaxBatchConcurrency = 8
+
+// jsonRPCHandlerConfig holds the settings of the JSON-RPC over HTTP handler.
+type jsonRPCHandlerConfig struct {
+	// maximum number of requests from a single batch executed concurrently
+	maxBatchConcurrency int
+	// info of the OpenAPI document
+	openAPITitle   string
+	openAPIVersion string
+}
+
+func defaultJSONRPCHandlerConfig() *jsonRPCHandlerConfig {
+	return &jsonRPCHandlerConfig{
+		maxBatchConcurrency: defaultMaxBatchConcurrency,
+		openAPITitle:        "RPC",
+	}
+}
+
+// MaxBatchConcurrency sets the maximum number of requests from a single
+// JSON-RPC batch which are executed concurrently. Values below 1 make the
+// handler process batches sequentially.
+// It should only be used in RegisterRPCFuncs.
+func MaxBatchConcurrency(n int) func(*jsonRPCHandlerConfig) {
+	return func(cfg *jsonRPCHandlerConfig) {
+		cfg.maxBatchConcurrency = n
+	}
 }
 
 //-------------------------------------
@@ -101,7 +144,12 @@ func funcReturnTypes(f interface{}) []reflect.Type {
 // rpc.json
 
 // jsonrpc calls grab the given method's function info and runs reflect.Call
-func makeJSONRPCHandler(funcMap map[string]*RPCFunc, cdc *amino.Codec, logger log.Logger) http.HandlerFunc {
+func makeJSONRPCHandler(
+	funcMap map[string]*RPCFunc,
+	cdc *amino.Codec,
+	logger log.Logger,
+	cfg *jsonRPCHandlerConfig,
+) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		b, err := ioutil.ReadAll(r.Body)
 		if err != nil {
@@ -122,10 +170,7 @@ func makeJSONRPCHandler(funcMap map[string]*RPCFunc, cdc *amino.Codec, logger lo
 		}
 
 		// first try to unmarshal the incoming request as an array of RPC requests
-		var (
-			requests  []types.RPCRequest
-			responses []types.RPCResponse
-		)
+		var requests []types.RPCRequest
 		if err := json.Unmarshal(b, &requests); err != nil {
 			// next, try to unmarshal as a single request
 			var request types.RPCRequest
@@ -142,54 +187,85 @@ func makeJSONRPCHandler(funcMap map[string]*RPCFunc, cdc *amino.Codec, logger lo
 			requests = []types.RPCRequest{request}
 		}
 
+		// A Notification is a Request object without an "id" member.
+		// The Server MUST NOT reply to a Notification, including those that are within a batch request.
+		calls := make([]types.RPCRequest, 0, len(requests))
 		for _, request := range requests {
-			request := request
-			// A Notification is a Request object without an "id" member.
-			// The Server MUST NOT reply to a Notification, including those that are within a batch request.
 			if request.ID == types.JSONRPCStringID("") {
 				logger.Debug(
 					"HTTPJSONRPC received a notification, skipping... (please send a non-empty ID if you want to call a method)",
 				)
 				continue
 			}
-			if len(r.URL.Path) > 1 {
-				responses = append(
-					responses,
-					types.RPCInvalidRequestError(request.ID, errors.Errorf("path %s is invalid", r.URL.Path)),
-				)
-				continue
-			}
-			rpcFunc, ok := funcMap[request.Method]
-			if !ok || rpcFunc.ws {
-				responses = append(responses, types.RPCMethodNotFoundError(request.ID))
-				continue
-			}
-			ctx := &types.Context{JSONReq: &request, HTTPReq: r}
-			args := []reflect.Value{reflect.ValueOf(ctx)}
-			if len(request.Params) > 0 {
-				fnArgs, err := jsonParamsToArgs(rpcFunc, cdc, request.Params)
-				if err != nil {
-					responses = append(
-						responses,
-						types.RPCInvalidParamsError(request.ID, errors.Wrap(err, "error converting json params to arguments")),
-					)
-					continue
-				}
-				args = append(args, fnArgs...)
-			}
-			returns := rpcFunc.f.Call(args)
-			logger.Info("HTTPJSONRPC", "method", request.Method, "args", args, "returns", returns)
-			result, err := unreflectResult(returns)
-			if err != nil {
-				responses = append(responses, types.RPCInternalError(request.ID, err))
-				continue
-			}
-			responses = append(responses, types.NewRPCSuccessResponse(cdc, request.ID, result))
+			calls = append(calls, request)
+		}
+		if len(calls) == 0 {
+			return
+		}
+
+		// Requests of a batch are independent of each other, so run them
+		// concurrently, but keep the responses in request order.
+		responses := make([]types.RPCResponse, len(calls))
+		sem := make(chan struct{}, cmn.MaxInt(cfg.maxBatchConcurrency, 1))
+		var wg sync.WaitGroup
+		for i := range calls {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(i int) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				responses[i] = handleJSONRPCRequest(funcMap, cdc, logger, r, &calls[i])
+			}(i)
+		}
+		wg.Wait()
+
+		WriteRPCResponseArrayHTTP(w, responses)
+	}
+}
+
+// handleJSONRPCRequest executes a single (non-notification) JSON-RPC request
+// and returns its response. Panics in the underlying RPC function are turned
+// into internal errors, since they can not be recovered by the HTTP handler
+// once the request runs in its own goroutine.
+func handleJSONRPCRequest(
+	funcMap map[string]*RPCFunc,
+	cdc *amino.Codec,
+	logger log.Logger,
+	r *http.Request,
+	request *types.RPCRequest,
+) (res types.RPCResponse) {
+	defer func() {
+		if e := recover(); e != nil {
+			logger.Error("Panic in RPC HTTP handler", "err", e, "stack", string(debug.Stack()))
+			res = types.RPCInternalError(request.ID, fmt.Errorf("%v", e))
 		}
-		if len(responses) > 0 {
-			WriteRPCResponseArrayHTTP(w, responses)
+	}()
+
+	if len(r.URL.Path) > 1 {
+		return types.RPCInvalidRequestError(request.ID, errors.Errorf("path %s is invalid", r.URL.Path))
+	}
+	rpcFunc, ok := funcMap[request.Method]
+	if !ok || rpcFunc.ws {
+		return types.RPCMethodNotFoundError(request.ID)
+	}
+	ctx := &types.Context{JSONReq: request, HTTPReq: r}
+	args := []reflect.Value{reflect.ValueOf(ctx)}
+	if len(request.Params) > 0 {
+		fnArgs, err := jsonParamsToArgs(rpcFunc, cdc, request.Params)
+		if err != nil {
+			return types.RPCInvalidParamsError(request.ID, errors.Wrap(err, "error converting json params to arguments"))
 		}
+		args = append(args, fnArgs...)
+	}
+	returns := rpcFunc.f.Call(args)
+	logger.Info("HTTPJSONRPC", "method", request.Method, "args", args, "returns", returns)
+	result, err := unreflectResult(returns)
+	if err != nil {
+		return types.RPCMethodError(request.ID, err)
 	}
+	return types.NewRPCSuccessResponse(cdc, request.ID, result)
 }
 
 func handleInvalidJSONRPCPaths(next http.HandlerFunc) http.HandlerFunc {
@@ -320,7 +396,7 @@ func makeHTTPHandler(rpcFunc *RPCFunc, cdc *amino.Codec, logger log.Logger) func
 		logger.Info("HTTPRestRPC", "method", r.URL.Path, "args", args, "returns", returns)
 		result, err := unreflectResult(returns)
 		if err != nil {
-			WriteRPCResponseHTTP(w, types.RPCInternalError(types.JSONRPCStringID(""), err))
+			WriteRPCResponseHTTP(w, types.RPCMethodError(types.JSONRPCStringID(""), err))
 			return
 		}
 		WriteRPCResponseHTTP(w, types.NewRPCSuccessResponse(cdc, types.JSONRPCStringID(""), result))
@@ -741,7 +817,7 @@ func (wsc *wsConnection) readRoutine() {
 
 			result, err := unreflectResult(returns)
 			if err != nil {
-				wsc.WriteRPCResponse(types.RPCInternalError(request.ID, err))
+				wsc.WriteRPCResponse(types.RPCMethodError(request.ID, err))
 				continue
 			}
 
@@ -875,7 +951,7 @@ func (wm *WebsocketManager) WebsocketHandler(w http.ResponseWriter, r *http.Requ
 func unreflectResult(returns []reflect.Value) (interface{}, error) {
 	errV := returns[1]
 	if errV.Interface() != nil {
-		return nil, errors.Errorf("%v", errV.Interface())
+		return nil, errV.Interface().(error)
 	}
 	rv := returns[0]
 	// the result is a registered interface,
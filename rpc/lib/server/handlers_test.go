@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
@@ -225,6 +226,58 @@ func TestRPCNotificationInBatch(t *testing.T) {
 	}
 }
 
+func TestRPCBatchResponseOrder(t *testing.T) {
+	funcMap := map[string]*rs.RPCFunc{
+		"echo": rs.NewRPCFunc(func(ctx *types.Context, s string, d int) (string, error) {
+			// make earlier requests finish later
+			time.Sleep(time.Duration(d) * time.Millisecond)
+			return s, nil
+		}, "s,d"),
+	}
+	cdc := amino.NewCodec()
+	mux := http.NewServeMux()
+	rs.RegisterRPCFuncs(mux, funcMap, cdc, log.NewNopLogger(), rs.MaxBatchConcurrency(2))
+
+	payload := `[
+		{"jsonrpc": "2.0","method":"echo","id":"0","params":["a","30"]},
+		{"jsonrpc": "2.0","method":"echo","id":"1","params":["b","20"]},
+		{"jsonrpc": "2.0","id": ""},
+		{"jsonrpc": "2.0","method":"unknown","id":"2"},
+		{"jsonrpc": "2.0","method":"echo","id":"3","params":["c","0"]}
+	]`
+	req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader(payload))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	res := rec.Result()
+	require.True(t, statusOK(res.StatusCode))
+	blob, err := ioutil.ReadAll(res.Body)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	var responses []types.RPCResponse
+	require.NoError(t, json.Unmarshal(blob, &responses), "blob: %s", blob)
+	require.Len(t, responses, 4)
+
+	expected := []struct {
+		id     types.JSONRPCStringID
+		result string
+	}{
+		{"0", `"a"`},
+		{"1", `"b"`},
+		{"2", ""},
+		{"3", `"c"`},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.id, responses[i].ID, "#%d", i)
+		if e.result == "" {
+			assert.NotNil(t, responses[i].Error, "#%d", i)
+			continue
+		}
+		assert.Nil(t, responses[i].Error, "#%d", i)
+		assert.Equal(t, e.result, string(responses[i].Result), "#%d", i)
+	}
+}
+
 func TestUnknownRPCPath(t *testing.T) {
 	mux := testMux()
 	req, _ := http.NewRequest("GET", "http://localhost/unknownrpcpath", nil)
@@ -0,0 +1,271 @@
+package rpcserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OpenAPI generates the OpenAPI 3.0 document of the URI endpoints of the
+// funcMap from the signatures of their functions, so that it can't drift from
+// the handlers: the parameters are typed after the arguments, and the results
+// are described by JSON schemas of the result types, in their amino JSON
+// encoding (e.g. the 64 bits integers are strings). The websocket only
+// functions aren't described.
+//
+// NOTE: the schemas of the interfaces (keys, evidence, events) are the generic
+// amino wrapper, {"type": ..., "value": ...}.
+func OpenAPI(funcMap map[string]*RPCFunc, title, version string) ([]byte, error) {
+	gen := &openAPIGenerator{schemas: make(map[string]interface{})}
+
+	names := make([]string, 0, len(funcMap))
+	for name, rpcFunc := range funcMap {
+		if !rpcFunc.ws {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	paths := make(map[string]interface{}, len(names))
+	for _, name := range names {
+		paths["/"+name] = map[string]interface{}{
+			"get": gen.operation(name, funcMap[name]),
+		}
+	}
+
+	return json.MarshalIndent(map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":       title,
+			"version":     version,
+			"description": "The URI endpoints of the RPC server. They can also be called with JSON-RPC 2.0 on /.",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": gen.schemas,
+		},
+	}, "", "  ")
+}
+
+// OpenAPIInfo sets the title and the version of the OpenAPI document served
+// on /openapi.json (see OpenAPI).
+// It should only be used in RegisterRPCFuncs.
+func OpenAPIInfo(title, version string) func(*jsonRPCHandlerConfig) {
+	return func(cfg *jsonRPCHandlerConfig) {
+		cfg.openAPITitle = title
+		cfg.openAPIVersion = version
+	}
+}
+
+// makeOpenAPIHandler returns the handler of /openapi.json. The document is
+// generated once, on the first request.
+func makeOpenAPIHandler(funcMap map[string]*RPCFunc, cfg *jsonRPCHandlerConfig) http.HandlerFunc {
+	var (
+		once sync.Once
+		doc  []byte
+		err  error
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			doc, err = OpenAPI(funcMap, cfg.openAPITitle, cfg.openAPIVersion)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(doc) // nolint: errcheck
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// openAPIGenerator collects the schemas of the named types met while
+// describing the operations, referenced by their Go name.
+type openAPIGenerator struct {
+	schemas map[string]interface{}
+}
+
+func (gen *openAPIGenerator) operation(name string, rpcFunc *RPCFunc) map[string]interface{} {
+	// skip types.Context
+	const argsOffset = 1
+
+	params := make([]interface{}, 0, len(rpcFunc.argNames))
+	for i, argName := range rpcFunc.argNames {
+		params = append(params, uriParameter(argName, rpcFunc.args[i+argsOffset]))
+	}
+
+	// the functions return (result, error)
+	result := map[string]interface{}{}
+	if len(rpcFunc.returns) > 0 {
+		result = gen.schema(rpcFunc.returns[0])
+	}
+
+	return map[string]interface{}{
+		"operationId": name,
+		"parameters":  params,
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "The JSON-RPC response, with the result or the error of the call.",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"jsonrpc": map[string]interface{}{"type": "string", "example": "2.0"},
+								"id":      map[string]interface{}{"type": "string"},
+								"result":  result,
+								"error": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"code":    map[string]interface{}{"type": "integer"},
+										"message": map[string]interface{}{"type": "string"},
+										"data":    map[string]interface{}{"type": "string"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// uriParameter describes an argument as a query parameter, in the formats
+// httpParamsToArgs accepts: plain integers, quoted strings, and byte slices in
+// hex with the 0x prefix or quoted. The other arguments are in amino JSON. All
+// of them are optional: a missing one is the zero value of its type.
+func uriParameter(name string, rt reflect.Type) map[string]interface{} {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	var (
+		schema      map[string]interface{}
+		description string
+	)
+	switch {
+	case isInteger(rt.Kind()):
+		schema = map[string]interface{}{"type": "integer", "example": 1}
+	case rt.Kind() == reflect.Bool:
+		schema = map[string]interface{}{"type": "boolean", "example": true}
+	case rt.Kind() == reflect.String:
+		schema = map[string]interface{}{"type": "string", "example": `"abc"`}
+		description = "A quoted string."
+	case rt.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.Uint8:
+		schema = map[string]interface{}{"type": "string", "example": "0xABCD"}
+		description = "Hex with the 0x prefix, or a quoted string."
+	default:
+		schema = map[string]interface{}{"type": "string"}
+		description = "Amino JSON of " + rt.String() + "."
+	}
+	param := map[string]interface{}{
+		"name":     name,
+		"in":       "query",
+		"required": false,
+		"schema":   schema,
+	}
+	if description != "" {
+		param["description"] = description
+	}
+	return param
+}
+
+// schema returns the JSON schema of the amino JSON encoding of the type: a
+// reference to the components for the named structs.
+func (gen *openAPIGenerator) schema(rt reflect.Type) map[string]interface{} {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	switch {
+	case rt == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rt.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.Uint8:
+		if rt.Implements(jsonMarshalerType) {
+			return map[string]interface{}{"type": "string", "format": "hex"} // e.g. cmn.HexBytes
+		}
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case rt.Implements(jsonMarshalerType) || reflect.PtrTo(rt).Implements(jsonMarshalerType):
+		// custom encoding, e.g. bit arrays
+		return map[string]interface{}{}
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int64, reflect.Uint64, reflect.Int, reflect.Uint:
+		// amino encodes the 64 bits integers as strings
+		return map[string]interface{}{"type": "string", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": gen.schema(rt.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": gen.schema(rt.Elem())}
+	case reflect.Interface:
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"type":  map[string]interface{}{"type": "string"},
+				"value": map[string]interface{}{},
+			},
+		}
+	case reflect.Struct:
+		if rt.Name() == "" {
+			return gen.structSchema(rt)
+		}
+		name := rt.String()
+		if _, ok := gen.schemas[name]; !ok {
+			// placeholder, for the recursive types
+			gen.schemas[name] = nil
+			gen.schemas[name] = gen.structSchema(rt)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (gen *openAPIGenerator) structSchema(rt reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{}, rt.NumField())
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		props[name] = gen.schema(field.Type)
+	}
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+func isInteger(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
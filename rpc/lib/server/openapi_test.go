@@ -0,0 +1,104 @@
+package rpcserver_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	rs "github.com/tendermint/tendermint/rpc/lib/server"
+	types "github.com/tendermint/tendermint/rpc/lib/types"
+)
+
+type openAPIResult struct {
+	Height  int64        `json:"height"`
+	Hash    cmn.HexBytes `json:"hash"`
+	Time    time.Time    `json:"time"`
+	Round   int32        `json:"round"`
+	Next    *openAPIResult
+	Skipped string `json:"-"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	funcMap := map[string]*rs.RPCFunc{
+		"get": rs.NewRPCFunc(func(ctx *types.Context, height *int64, query string, data []byte) (*openAPIResult, error) {
+			return nil, nil
+		}, "height,query,data"),
+		"subscribe": rs.NewWSRPCFunc(func(ctx *types.Context, query string) (*openAPIResult, error) {
+			return nil, nil
+		}, "query"),
+	}
+	bz, err := rs.OpenAPI(funcMap, "Test RPC", "1.0.0")
+	require.NoError(t, err)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Title   string `json:"title"`
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]struct {
+			Get struct {
+				Parameters []struct {
+					Name   string                 `json:"name"`
+					Schema map[string]interface{} `json:"schema"`
+				} `json:"parameters"`
+				Responses map[string]struct {
+					Content map[string]struct {
+						Schema struct {
+							Properties map[string]map[string]interface{} `json:"properties"`
+						} `json:"schema"`
+					} `json:"content"`
+				} `json:"responses"`
+			} `json:"get"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(bz, &doc))
+	assert.Equal(t, "3.0.0", doc.OpenAPI)
+	assert.Equal(t, "Test RPC", doc.Info.Title)
+	assert.Equal(t, "1.0.0", doc.Info.Version)
+
+	// the websocket only functions aren't described
+	require.Len(t, doc.Paths, 1)
+	get := doc.Paths["/get"].Get
+	require.Len(t, get.Parameters, 3)
+	assert.Equal(t, "height", get.Parameters[0].Name)
+	assert.Equal(t, "integer", get.Parameters[0].Schema["type"])
+	assert.Equal(t, `"abc"`, get.Parameters[1].Schema["example"])
+	assert.Equal(t, "0xABCD", get.Parameters[2].Schema["example"])
+	response := get.Responses["200"].Content["application/json"].Schema.Properties
+	assert.Equal(t, "#/components/schemas/rpcserver_test.openAPIResult", response["result"]["$ref"])
+
+	result := doc.Components.Schemas["rpcserver_test.openAPIResult"]
+	require.NotNil(t, result.Properties)
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "int64"}, result.Properties["height"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "hex"}, result.Properties["hash"])
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"}, result.Properties["time"])
+	assert.Equal(t, map[string]interface{}{"type": "integer"}, result.Properties["round"])
+	assert.Equal(t, "#/components/schemas/rpcserver_test.openAPIResult", result.Properties["Next"]["$ref"])
+	assert.NotContains(t, result.Properties, "Skipped")
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	mux := testMux()
+	req, _ := http.NewRequest("GET", "http://localhost/openapi.json", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	res := rec.Result()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	var doc map[string]interface{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&doc))
+	assert.Contains(t, doc["paths"], "/c")
+}
@@ -145,6 +145,12 @@ func (err RPCError) Error() string {
 	return fmt.Sprintf(baseFormat, err.Code, err.Message)
 }
 
+// RPCCode implements CodedError, so that clients get the code of the errors
+// returned by the server as from a local call.
+func (err RPCError) RPCCode() int {
+	return err.Code
+}
+
 type RPCResponse struct {
 	JSONRPC string          `json:"jsonrpc"`
 	ID      jsonrpcid       `json:"id"`
@@ -232,6 +238,30 @@ func RPCServerError(id jsonrpcid, err error) RPCResponse {
 	return NewRPCErrorResponse(id, -32000, "Server error", err.Error())
 }
 
+// CodedError is an error returned by an RPC method which has its own error
+// code. Codes of the errors defined by the server should be within the
+// [-32099, -32000] range reserved by the JSON-RPC specification.
+//
+// Clients can get the code of an error with:
+//
+//	if cerr, ok := errors.Cause(err).(rpctypes.CodedError); ok {
+//		code := cerr.RPCCode()
+//	}
+type CodedError interface {
+	error
+	RPCCode() int
+}
+
+// RPCMethodError returns the response for an error returned by an RPC method:
+// a server error with the code of the error if it (or its cause) is a
+// CodedError, an internal error otherwise.
+func RPCMethodError(id jsonrpcid, err error) RPCResponse {
+	if cerr, ok := errors.Cause(err).(CodedError); ok {
+		return NewRPCErrorResponse(id, cerr.RPCCode(), "Server error", err.Error())
+	}
+	return RPCInternalError(id, err)
+}
+
 //----------------------------------------
 
 // WSRPCConnection represents a websocket connection.
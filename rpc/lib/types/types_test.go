@@ -88,3 +88,27 @@ func TestRPCError(t *testing.T) {
 			Message: "Badness",
 		}))
 }
+
+type codedError struct{}
+
+func (codedError) Error() string { return "coded error" }
+func (codedError) RPCCode() int  { return -32042 }
+
+func TestRPCMethodError(t *testing.T) {
+	id := JSONRPCIntID(1)
+
+	resp := RPCMethodError(id, errors.New("plain error"))
+	assert.Equal(t, -32603, resp.Error.Code)
+	assert.Equal(t, "plain error", resp.Error.Data)
+
+	resp = RPCMethodError(id, errors.Wrap(codedError{}, "wrapped"))
+	assert.Equal(t, -32042, resp.Error.Code)
+	assert.Equal(t, "Server error", resp.Error.Message)
+	assert.Equal(t, "wrapped: coded error", resp.Error.Data)
+
+	// the code of an RPCError is available to clients
+	cerr, ok := errors.Cause(errors.Wrap(resp.Error, "response error")).(CodedError)
+	if assert.True(t, ok) {
+		assert.Equal(t, -32042, cerr.RPCCode())
+	}
+}
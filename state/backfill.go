@@ -0,0 +1,237 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/proxy"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// ResultsBackfiller recomputes the ABCI responses missing from the state DB
+// (e.g. removed by an external pruning tool), by re-executing the blocks
+// against a backfill app: an instance of the app restored to a past state
+// (e.g. from a snapshot of its data), with its own data directory.
+//
+// The backfill app only moves forward: the blocks are executed from its
+// height up to the requested one, and the responses missing on the way are
+// saved, once verified against the results hash and app hash of the next
+// header. To backfill heights below the backfill app, restore it from an
+// earlier snapshot. As it can take a long time, the backfill is started by an
+// operator (see StartBackfill), not by the requests for the results.
+type ResultsBackfiller struct {
+	cmn.BaseService
+
+	proxyApp   proxy.AppConns
+	blockStore BlockStore
+	genDoc     *types.GenesisDoc
+	db         dbm.DB
+
+	mtx sync.Mutex // serializes the backfills
+
+	statusMtx sync.Mutex
+	status    BackfillStatus
+}
+
+// NewResultsBackfiller returns a ResultsBackfiller saving the responses to the
+// state DB db. The connections to the backfill app must be started, they are
+// stopped with the backfiller.
+func NewResultsBackfiller(
+	proxyApp proxy.AppConns,
+	blockStore BlockStore,
+	genDoc *types.GenesisDoc,
+	db dbm.DB,
+) *ResultsBackfiller {
+	rb := &ResultsBackfiller{
+		proxyApp:   proxyApp,
+		blockStore: blockStore,
+		genDoc:     genDoc,
+		db:         db,
+	}
+	rb.BaseService = *cmn.NewBaseService(nil, "ResultsBackfiller", rb)
+	return rb
+}
+
+// OnStop implements cmn.Service.
+func (rb *ResultsBackfiller) OnStop() {
+	rb.proxyApp.Stop()
+}
+
+// BackfillStatus is the progress of the last backfill.
+type BackfillStatus struct {
+	Running bool   `json:"running"`
+	From    int64  `json:"from"`   // first height to execute
+	To      int64  `json:"to"`     // height to backfill up to
+	Height  int64  `json:"height"` // last height executed
+	Error   string `json:"error"`  // why the backfill failed, if it did
+}
+
+// Backfill returns the ABCI responses to the block at height, executing the
+// blocks from the height of the backfill app if they are missing.
+func (rb *ResultsBackfiller) Backfill(height int64) (*ABCIResponses, error) {
+	rb.mtx.Lock()
+	defer rb.mtx.Unlock()
+
+	if responses, err := LoadABCIResponses(rb.db, height); err == nil {
+		return responses, nil // backfilled concurrently
+	}
+	from, err := rb.prepare(height)
+	if err != nil {
+		return nil, err
+	}
+	return rb.run(from, height)
+}
+
+// StartBackfill starts backfilling the responses up to height in the
+// background, as re-executing the blocks can take a long time. It returns an
+// error if a backfill is running already, or can't run up to height. The
+// progress is reported by BackfillStatus.
+func (rb *ResultsBackfiller) StartBackfill(height int64) error {
+	rb.statusMtx.Lock()
+	if rb.status.Running {
+		defer rb.statusMtx.Unlock()
+		return fmt.Errorf("the backfill up to height %d is still running", rb.status.To)
+	}
+	rb.status = BackfillStatus{Running: true, To: height}
+	rb.statusMtx.Unlock()
+
+	rb.mtx.Lock()
+	var from int64
+	_, err := LoadABCIResponses(rb.db, height)
+	if err == nil {
+		err = fmt.Errorf("the results of height %d aren't missing", height)
+	} else {
+		from, err = rb.prepare(height)
+	}
+	if err != nil {
+		rb.mtx.Unlock()
+		rb.finish(err)
+		return err
+	}
+
+	rb.statusMtx.Lock()
+	rb.status.From = from
+	rb.status.Height = from - 1
+	rb.statusMtx.Unlock()
+	go func() {
+		defer rb.mtx.Unlock()
+		_, err := rb.run(from, height)
+		if err != nil {
+			rb.Logger.Error("Failed to backfill the block results", "to", height, "err", err)
+		}
+		rb.finish(err)
+	}()
+	return nil
+}
+
+// BackfillStatus returns the progress of the last backfill.
+func (rb *ResultsBackfiller) BackfillStatus() BackfillStatus {
+	rb.statusMtx.Lock()
+	defer rb.statusMtx.Unlock()
+	return rb.status
+}
+
+func (rb *ResultsBackfiller) finish(err error) {
+	rb.statusMtx.Lock()
+	defer rb.statusMtx.Unlock()
+	rb.status.Running = false
+	if err != nil {
+		rb.status.Error = err.Error()
+	}
+}
+
+// prepare checks that the responses up to height can be backfilled, and
+// returns the first height to execute. The caller must hold mtx.
+func (rb *ResultsBackfiller) prepare(height int64) (int64, error) {
+	res, err := rb.proxyApp.Query().InfoSync(proxy.RequestInfo)
+	if err != nil {
+		return 0, fmt.Errorf("error calling Info: %v", err)
+	}
+	appHeight := res.LastBlockHeight
+	switch {
+	case appHeight >= height:
+		return 0, fmt.Errorf("the backfill app is at height %d, past height %d: restore it from an earlier snapshot",
+			appHeight, height)
+	case appHeight+1 < rb.blockStore.Base():
+		return 0, fmt.Errorf("the backfill app is at height %d, but the block store starts at height %d",
+			appHeight, rb.blockStore.Base())
+	case height >= rb.blockStore.Height():
+		return 0, fmt.Errorf("the results of height %d can't be verified before the next block is committed",
+			height)
+	}
+
+	if appHeight == 0 {
+		req, err := InitChainRequest(rb.genDoc)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := rb.proxyApp.Consensus().InitChainSync(req); err != nil {
+			return 0, fmt.Errorf("error calling InitChain: %v", err)
+		}
+	}
+	return appHeight + 1, nil
+}
+
+// run executes the blocks from height from up to height to, and returns the
+// responses to the last one. It stops early if the backfiller is stopped. The
+// caller must hold mtx.
+func (rb *ResultsBackfiller) run(from, to int64) (*ABCIResponses, error) {
+	rb.Logger.Info("Backfilling the block results", "from", from, "to", to)
+	var (
+		responses *ABCIResponses
+		err       error
+	)
+	for h := from; h <= to; h++ {
+		select {
+		case <-rb.Quit():
+			return nil, errors.New("the backfiller was stopped")
+		default:
+		}
+		if responses, err = rb.execute(h); err != nil {
+			return nil, err
+		}
+		rb.statusMtx.Lock()
+		rb.status.Height = h
+		rb.statusMtx.Unlock()
+	}
+	return responses, nil
+}
+
+// execute executes and commits the block at height against the backfill app,
+// and saves its responses if they are missing, once verified.
+func (rb *ResultsBackfiller) execute(height int64) (*ABCIResponses, error) {
+	block := rb.blockStore.LoadBlock(height)
+	if block == nil {
+		return nil, ErrUnknownBlock{Height: height}
+	}
+	next := rb.blockStore.LoadBlockMeta(height + 1)
+	if next == nil {
+		return nil, ErrUnknownBlock{Height: height + 1}
+	}
+
+	lastBlockTime, err := LastBlockTime(rb.blockStore, height)
+	if err != nil {
+		return nil, err
+	}
+	responses, appHash, err := execCommitBlock(rb.proxyApp.Consensus(), block, lastBlockTime, rb.Logger, rb.db)
+	if err != nil {
+		return nil, ErrProxyAppConn(err)
+	}
+	if !bytes.Equal(appHash, next.Header.AppHash) {
+		return nil, fmt.Errorf("the backfill app hash after height %d is %X, want %X: the app wasn't restored "+
+			"from a snapshot of this chain, or isn't deterministic", height, appHash, next.Header.AppHash)
+	}
+	if !bytes.Equal(responses.ResultsHash(), next.Header.LastResultsHash) {
+		return nil, fmt.Errorf("the results hash of height %d is %X, want %X",
+			height, responses.ResultsHash(), next.Header.LastResultsHash)
+	}
+
+	if _, err := LoadABCIResponses(rb.db, height); err != nil {
+		saveABCIResponses(rb.db, height, responses)
+	}
+	return responses, nil
+}
@@ -0,0 +1,135 @@
+package state_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// newPersistentKVStore returns a kvstore reporting its height in Info, in a
+// temporary directory removed by the returned function.
+func newPersistentKVStore(t *testing.T) (*kvstore.PersistentKVStoreApplication, func()) {
+	dir, err := ioutil.TempDir("", "backfill_test")
+	require.NoError(t, err)
+	return kvstore.NewPersistentKVStoreApplication(dir), func() { os.RemoveAll(dir) }
+}
+
+// makeBackfillChain commits 4 blocks, and removes their results from the
+// state DB. It returns the removed results, by height.
+func makeBackfillChain(t *testing.T) (sm.BlockStore, dbm.DB, []*sm.ABCIResponses) {
+	app, cleanup := newPersistentKVStore(t)
+	defer cleanup()
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(app))
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	state, stateDB, privVals := makeState(1, 1)
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{})
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+	for height := int64(1); height <= 4; height++ {
+		block, parts := state.MakeBlock(height, makeTxs(height), lastCommit, nil,
+			state.Validators.GetProposer().Address)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		commit, err := makeValidCommit(height, blockID, state.Validators, privVals)
+		require.NoError(t, err)
+		blockStore.SaveBlock(block, parts, commit)
+		state, err = blockExec.ApplyBlock(state, blockID, block)
+		require.NoError(t, err)
+		lastCommit = commit
+	}
+
+	// the results are missing
+	removed := make([]*sm.ABCIResponses, 5)
+	for height := int64(1); height <= 4; height++ {
+		responses, err := sm.LoadABCIResponses(stateDB, height)
+		require.NoError(t, err)
+		removed[height] = responses
+		stateDB.Delete(sm.CalcABCIResponsesKey(height))
+	}
+	return blockStore, stateDB, removed
+}
+
+func TestResultsBackfiller(t *testing.T) {
+	blockStore, stateDB, want := makeBackfillChain(t)
+
+	app, cleanup := newPersistentKVStore(t)
+	defer cleanup()
+	backfillApp := proxy.NewAppConns(proxy.NewLocalClientCreator(app))
+	require.NoError(t, backfillApp.Start())
+	backfiller := sm.NewResultsBackfiller(backfillApp, blockStore, randomGenesisDoc(), stateDB)
+	backfiller.SetLogger(log.TestingLogger())
+	require.NoError(t, backfiller.Start())
+	defer backfiller.Stop()
+
+	responses, err := backfiller.Backfill(2)
+	require.NoError(t, err)
+	assert.Equal(t, want[2].ResultsHash(), responses.ResultsHash())
+	for height := int64(1); height <= 2; height++ {
+		responses, err := sm.LoadABCIResponses(stateDB, height)
+		require.NoError(t, err, "height %d", height)
+		assert.Equal(t, want[height].ResultsHash(), responses.ResultsHash())
+	}
+	_, err = sm.LoadABCIResponses(stateDB, 3)
+	assert.Error(t, err)
+
+	responses, err = backfiller.Backfill(3)
+	require.NoError(t, err)
+	assert.Equal(t, want[3].ResultsHash(), responses.ResultsHash())
+
+	// the results of the last block can't be verified yet
+	_, err = backfiller.Backfill(4)
+	assert.Error(t, err)
+
+	// the backfill app only moves forward
+	stateDB.Delete(sm.CalcABCIResponsesKey(1))
+	_, err = backfiller.Backfill(1)
+	assert.Error(t, err)
+}
+
+func TestResultsBackfillerStartBackfill(t *testing.T) {
+	blockStore, stateDB, want := makeBackfillChain(t)
+
+	app, cleanup := newPersistentKVStore(t)
+	defer cleanup()
+	backfillApp := proxy.NewAppConns(proxy.NewLocalClientCreator(app))
+	require.NoError(t, backfillApp.Start())
+	backfiller := sm.NewResultsBackfiller(backfillApp, blockStore, randomGenesisDoc(), stateDB)
+	backfiller.SetLogger(log.TestingLogger())
+	require.NoError(t, backfiller.Start())
+	defer backfiller.Stop()
+
+	// the results of the last block can't be verified yet
+	err := backfiller.StartBackfill(4)
+	require.Error(t, err)
+	status := backfiller.BackfillStatus()
+	assert.False(t, status.Running)
+	assert.Equal(t, err.Error(), status.Error)
+
+	require.NoError(t, backfiller.StartBackfill(3))
+	assert.Eventually(t, func() bool { return !backfiller.BackfillStatus().Running },
+		5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, sm.BackfillStatus{From: 1, To: 3, Height: 3}, backfiller.BackfillStatus())
+	for height := int64(1); height <= 3; height++ {
+		responses, err := sm.LoadABCIResponses(stateDB, height)
+		require.NoError(t, err, "height %d", height)
+		assert.Equal(t, want[height].ResultsHash(), responses.ResultsHash())
+	}
+
+	// the results are there already
+	assert.Error(t, backfiller.StartBackfill(3))
+}
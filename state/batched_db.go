@@ -0,0 +1,91 @@
+package state
+
+import (
+	"sync"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+// batchedDB buffers the writes to a DB in memory, and writes them in a single
+// synced batch on Flush. Get and Has see the buffered writes, iterators don't.
+//
+// It is safe for concurrent use.
+type batchedDB struct {
+	dbm.DB
+
+	mtx     sync.Mutex
+	pending map[string][]byte // nil for deleted keys
+}
+
+var _ dbm.DB = (*batchedDB)(nil)
+
+func newBatchedDB(db dbm.DB) *batchedDB {
+	return &batchedDB{DB: db, pending: make(map[string][]byte)}
+}
+
+// Get implements dbm.DB.
+func (bdb *batchedDB) Get(key []byte) []byte {
+	bdb.mtx.Lock()
+	value, ok := bdb.pending[string(key)]
+	bdb.mtx.Unlock()
+	if ok {
+		return value
+	}
+	return bdb.DB.Get(key)
+}
+
+// Has implements dbm.DB.
+func (bdb *batchedDB) Has(key []byte) bool {
+	bdb.mtx.Lock()
+	value, ok := bdb.pending[string(key)]
+	bdb.mtx.Unlock()
+	if ok {
+		return value != nil
+	}
+	return bdb.DB.Has(key)
+}
+
+// Set implements dbm.DB.
+func (bdb *batchedDB) Set(key []byte, value []byte) {
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	bdb.pending[string(key)] = value
+}
+
+// SetSync implements dbm.DB. The write is buffered like with Set.
+func (bdb *batchedDB) SetSync(key []byte, value []byte) {
+	bdb.Set(key, value)
+}
+
+// Delete implements dbm.DB.
+func (bdb *batchedDB) Delete(key []byte) {
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	bdb.pending[string(key)] = nil
+}
+
+// DeleteSync implements dbm.DB. The delete is buffered like with Delete.
+func (bdb *batchedDB) DeleteSync(key []byte) {
+	bdb.Delete(key)
+}
+
+// Flush writes the buffered writes to the DB, atomically.
+func (bdb *batchedDB) Flush() {
+	bdb.mtx.Lock()
+	defer bdb.mtx.Unlock()
+	if len(bdb.pending) == 0 {
+		return
+	}
+
+	batch := bdb.DB.NewBatch()
+	defer batch.Close()
+	for key, value := range bdb.pending {
+		if value == nil {
+			batch.Delete([]byte(key))
+		} else {
+			batch.Set([]byte(key), value)
+		}
+	}
+	batch.WriteSync()
+	bdb.pending = make(map[string][]byte)
+}
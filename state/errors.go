@@ -1,6 +1,10 @@
 package state
 
-import "fmt"
+import (
+	"fmt"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+)
 
 type (
 	ErrInvalidBlock error
@@ -43,6 +47,30 @@ type (
 	ErrNoABCIResponsesForHeight struct {
 		Height int64
 	}
+
+	ErrShadowAppHashMismatch struct {
+		Height        int64
+		AppHash       []byte
+		ShadowAppHash []byte
+	}
+
+	// ErrInvalidValidatorUpdate is an invalid validator update of the app, at
+	// Index in ResponseEndBlock.ValidatorUpdates.
+	ErrInvalidValidatorUpdate struct {
+		Index  int
+		Update abci.ValidatorUpdate
+		Reason string
+	}
+
+	// ErrValidatorPowerChangeTooBig is why the validator updates of a block
+	// are rejected when they change the total voting power by more than
+	// ValidatorParams.MaxPowerChangeNum/MaxPowerChangeDenom of it.
+	ErrValidatorPowerChangeTooBig struct {
+		Change     int64
+		TotalPower int64
+		MaxNum     int64
+		MaxDenom   int64
+	}
 )
 
 func (e ErrUnknownBlock) Error() string {
@@ -89,3 +117,17 @@ func (e ErrNoConsensusParamsForHeight) Error() string {
 func (e ErrNoABCIResponsesForHeight) Error() string {
 	return fmt.Sprintf("Could not find results for height #%d", e.Height)
 }
+
+func (e ErrInvalidValidatorUpdate) Error() string {
+	return fmt.Sprintf("Invalid validator update #%d (%v): %s", e.Index, e.Update, e.Reason)
+}
+
+func (e ErrValidatorPowerChangeTooBig) Error() string {
+	return fmt.Sprintf("Validator updates change the voting power by %d, more than %d/%d of the total voting power (%d)",
+		e.Change, e.MaxNum, e.MaxDenom, e.TotalPower)
+}
+
+func (e ErrShadowAppHashMismatch) Error() string {
+	return fmt.Sprintf("Shadow app hash (%X) does not match app hash (%X) for height %d",
+		e.ShadowAppHash, e.AppHash, e.Height)
+}
@@ -0,0 +1,95 @@
+package state
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// selectEvidence returns the pending evidence to include in the proposal
+// block, up to maxNum evidence of maxBytes in total. The evidence is
+// prioritized by severity, the voting power of the faulty validator at the
+// height of the evidence, then by age, the oldest first as it's the closest
+// to expiry. Expired evidence, which would make the block invalid, is
+// skipped, and evidence which doesn't fit waits for the next blocks.
+func (blockExec *BlockExecutor) selectEvidence(state State, maxNum, maxBytes int64) []types.Evidence {
+	pending := blockExec.evpool.PendingEvidence(-1)
+	if len(pending) == 0 {
+		return nil
+	}
+
+	type candidate struct {
+		ev    types.Evidence
+		power int64
+		size  int64
+	}
+	var (
+		candidates = make([]candidate, 0, len(pending))
+		valSets    = make(map[int64]*types.ValidatorSet)
+		expired    int
+	)
+	for _, ev := range pending {
+		if state.LastBlockHeight-ev.Height() > state.ConsensusParams.Evidence.MaxAge {
+			expired++
+			continue
+		}
+		valSet, ok := valSets[ev.Height()]
+		if !ok {
+			valSet, _ = LoadValidators(blockExec.db, ev.Height())
+			valSets[ev.Height()] = valSet
+		}
+		var power int64
+		if valSet != nil {
+			if _, val := valSet.GetByAddress(ev.Address()); val != nil {
+				power = val.VotingPower
+			}
+		}
+		candidates = append(candidates, candidate{ev: ev, power: power, size: int64(len(ev.Bytes()))})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.power != b.power {
+			return a.power > b.power
+		}
+		if a.ev.Height() != b.ev.Height() {
+			return a.ev.Height() < b.ev.Height()
+		}
+		return bytes.Compare(a.ev.Hash(), b.ev.Hash()) < 0
+	})
+
+	var (
+		selected      []types.Evidence
+		selectedBytes int64
+	)
+	for _, c := range candidates {
+		if int64(len(selected)) >= maxNum {
+			break
+		}
+		if selectedBytes+c.size > maxBytes {
+			continue // a smaller one may fit
+		}
+		selected = append(selected, c.ev)
+		selectedBytes += c.size
+	}
+
+	blockExec.logger.Debug("Selected the evidence of the proposal",
+		"pending", len(pending),
+		"expired", expired,
+		"selected", len(selected),
+		"bytes", selectedBytes,
+		"maxNum", maxNum,
+		"maxBytes", maxBytes,
+		"deferred", len(candidates)-len(selected))
+	return selected
+}
+
+// evidenceBytes returns the total size of the evidence, as accounted by
+// selectEvidence.
+func evidenceBytes(evidence []types.Evidence) int64 {
+	var size int64
+	for _, ev := range evidence {
+		size += int64(len(ev.Bytes()))
+	}
+	return size
+}
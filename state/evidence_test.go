@@ -0,0 +1,74 @@
+package state_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// pendingEvidencePool is an evidence pool with the given pending evidence.
+type pendingEvidencePool struct {
+	sm.MockEvidencePool
+	pending []types.Evidence
+}
+
+func (p pendingEvidencePool) PendingEvidence(maxNum int64) []types.Evidence {
+	if maxNum >= 0 && int64(len(p.pending)) > maxNum {
+		return p.pending[:maxNum]
+	}
+	return p.pending
+}
+
+func TestCreateProposalBlockEvidence(t *testing.T) {
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(kvstore.NewKVStoreApplication()))
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	state, stateDB, _ := makeState(2, 1)
+	state.LastBlockHeight = 3
+	val0, val1 := state.Validators.Validators[0].Address, state.Validators.Validators[1].Address
+	var (
+		// by priority
+		evOld     = types.NewMockGoodEvidence(1, 1, val1)
+		evRecent  = types.NewMockGoodEvidence(2, 0, val0)
+		evUnknown = types.NewMockGoodEvidence(2, 0, ed25519.GenPrivKey().PubKey().Address())
+	)
+	evpool := pendingEvidencePool{pending: []types.Evidence{evUnknown, evRecent, evOld}}
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, evpool)
+	commit := types.NewCommit(types.BlockID{}, nil)
+	proposer := state.Validators.GetProposer().Address
+
+	testCases := []struct {
+		name     string
+		params   types.EvidenceParams
+		evidence []types.Evidence
+	}{
+		{"by severity then age", types.EvidenceParams{MaxAge: 10},
+			[]types.Evidence{evOld, evRecent, evUnknown}},
+		{"max num", types.EvidenceParams{MaxAge: 10, MaxNum: 2},
+			[]types.Evidence{evOld, evRecent}},
+		{"max bytes", types.EvidenceParams{MaxAge: 10, MaxNum: 10, MaxBytes: int64(len(evOld.Bytes())) + 1},
+			[]types.Evidence{evOld}},
+		{"expired", types.EvidenceParams{MaxAge: 1},
+			[]types.Evidence{evRecent, evUnknown}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := state.Copy()
+			state.ConsensusParams.Evidence = tc.params
+			require.NoError(t, state.ConsensusParams.Validate())
+			block, _ := blockExec.CreateProposalBlock(4, state, commit, proposer)
+			assert.Equal(t, types.EvidenceList(tc.evidence), block.Evidence.Evidence)
+		})
+	}
+}
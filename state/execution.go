@@ -1,7 +1,11 @@
 package state
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"sync"
 	"time"
 
 	abci "github.com/tendermint/tendermint/abci/types"
@@ -37,6 +41,25 @@ type BlockExecutor struct {
 	logger log.Logger
 
 	metrics *Metrics
+
+	// bounds the time spent reaping the mempool in CreateProposalBlock
+	createProposalDeadline time.Duration
+
+	// guards db and the batched writes: ApplyBlock holds it, so that
+	// StopBatchedWrites doesn't swap the DB under a block being applied
+	dbMtx sync.Mutex
+
+	// buffers the state writes of batchBlocks blocks, if not nil (see
+	// BlockExecutorWithBatchedWrites)
+	batch         *batchedDB
+	batchBlocks   int
+	pendingBlocks int
+
+	// executes the committed blocks against a shadow app, if not nil
+	shadow *ShadowExecutor
+
+	// notified of the applied blocks (see BlockExecutorWithListeners)
+	listeners []BlockListener
 }
 
 type BlockExecutorOption func(executor *BlockExecutor)
@@ -47,6 +70,45 @@ func BlockExecutorWithMetrics(metrics *Metrics) BlockExecutorOption {
 	}
 }
 
+// BlockExecutorWithCreateProposalDeadline bounds the time CreateProposalBlock
+// spends reaping the mempool and waiting for PrepareProposal. Past the
+// deadline, the block is proposed with the txs reaped so far rather than
+// missing the proposal. 0 means no deadline.
+func BlockExecutorWithCreateProposalDeadline(deadline time.Duration) BlockExecutorOption {
+	return func(blockExec *BlockExecutor) {
+		blockExec.createProposalDeadline = deadline
+	}
+}
+
+// BlockExecutorWithBatchedWrites makes ApplyBlock write the state, validators
+// and consensus params in a single synced batch every blocks blocks, instead
+// of at every block. It's meant for fast sync, and must be stopped with
+// StopBatchedWrites before switching to consensus.
+//
+// The ABCI responses are still written at every block, before the app commits
+// it: after a crash, the Handshaker catches the state up with the app from
+// them. Reads through the executor see the buffered writes, but other users of
+// the state DB only see them once flushed. blocks <= 1 disables batching.
+func BlockExecutorWithBatchedWrites(blocks int) BlockExecutorOption {
+	return func(blockExec *BlockExecutor) {
+		if blocks <= 1 {
+			return
+		}
+		blockExec.batch = newBatchedDB(blockExec.db)
+		blockExec.batchBlocks = blocks
+		blockExec.db = blockExec.batch
+	}
+}
+
+// BlockExecutorWithShadow makes ApplyBlock hand the committed blocks to the
+// shadow executor, which executes them against the shadow app in the
+// background.
+func BlockExecutorWithShadow(shadow *ShadowExecutor) BlockExecutorOption {
+	return func(blockExec *BlockExecutor) {
+		blockExec.shadow = shadow
+	}
+}
+
 // NewBlockExecutor returns a new BlockExecutor with a NopEventBus.
 // Call SetEventBus to provide one.
 func NewBlockExecutor(
@@ -75,9 +137,25 @@ func NewBlockExecutor(
 }
 
 func (blockExec *BlockExecutor) DB() dbm.DB {
+	blockExec.dbMtx.Lock()
+	defer blockExec.dbMtx.Unlock()
 	return blockExec.db
 }
 
+// StopBatchedWrites flushes the batched writes, if any, and makes ApplyBlock
+// write the state at every block from now on. It waits for the block being
+// applied, if any.
+func (blockExec *BlockExecutor) StopBatchedWrites() {
+	blockExec.dbMtx.Lock()
+	defer blockExec.dbMtx.Unlock()
+	if blockExec.batch == nil {
+		return
+	}
+	blockExec.batch.Flush()
+	blockExec.db = blockExec.batch.DB
+	blockExec.batch = nil
+}
+
 // SetEventBus - sets the event bus for publishing block related events.
 // If not called, it defaults to types.NopEventBus.
 func (blockExec *BlockExecutor) SetEventBus(eventBus types.BlockEventPublisher) {
@@ -86,33 +164,236 @@ func (blockExec *BlockExecutor) SetEventBus(eventBus types.BlockEventPublisher)
 
 // CreateProposalBlock calls state.MakeBlock with evidence from the evpool
 // and txs from the mempool. The max bytes must be big enough to fit the commit.
-// Up to 1/10th of the block space is allcoated for maximum sized evidence.
-// The rest is given to txs, up to the max gas.
+// The evidence is limited by the evidence params (see
+// ConsensusParams.MaxEvidence), and prioritized by severity and age.
+// The rest is given to txs, up to the max gas, as many as are reaped by the
+// create proposal deadline (see BlockExecutorWithCreateProposalDeadline). The
+// limits are the ones of the given state, so they follow the consensus params
+// updated by EndBlock. The app may then reorder or replace the reaped txs in
+// PrepareProposal, by the same deadline.
 func (blockExec *BlockExecutor) CreateProposalBlock(
 	height int64,
 	state State, commit *types.Commit,
 	proposerAddr []byte,
 ) (*types.Block, *types.PartSet) {
 
+	var deadline time.Time
+	if blockExec.createProposalDeadline > 0 {
+		deadline = time.Now().Add(blockExec.createProposalDeadline)
+	}
 	maxBytes := state.ConsensusParams.Block.MaxBytes
 	maxGas := state.ConsensusParams.Block.MaxGas
 
-	// Fetch a limited amount of valid evidence
-	maxNumEvidence, _ := types.MaxEvidencePerBlock(maxBytes)
-	evidence := blockExec.evpool.PendingEvidence(maxNumEvidence)
+	// Fetch the evidence with the highest priority, up to the limits of the
+	// evidence params
+	maxNumEvidence, maxEvidenceBytes := state.ConsensusParams.MaxEvidence()
+	evidence := blockExec.selectEvidence(state, maxNumEvidence, maxEvidenceBytes)
+
+	// Deliver the vote extensions of the commit to the app, if the
+	// precommits of the last height carry them. They are part of the commit,
+	// so they take block space away from txs.
+	if state.ConsensusParams.Block.VoteExtensionsEnabled(height - 1) {
+		blockExec.deliverVoteExtensions(height, state, commit)
+	}
+
+	// Aggregate the precommits of the commit if the consensus params enable
+	// it. The validators may not all have BLS keys yet after the params
+	// changed: the commit is proposed in full then.
+	if state.ConsensusParams.Block.AggregateCommits && commit.Size() > 0 {
+		aggCommit, err := types.NewAggregateCommit(commit, state.LastValidators)
+		if err != nil {
+			blockExec.logger.Error("Can't aggregate the commit, proposing it in full", "err", err)
+		} else {
+			commit = aggCommit
+		}
+	}
 
 	// Fetch a limited amount of valid txs
 	maxDataBytes := types.MaxDataBytes(maxBytes, state.Validators.Size(), len(evidence))
-	txs := blockExec.mempool.ReapMaxBytesMaxGas(maxDataBytes, maxGas)
+	maxDataBytes -= commit.VoteExtensionsSize()
+	// The evidence of the validators with BLS keys is longer than
+	// MaxEvidenceBytes, counted by MaxDataBytes.
+	for _, ev := range evidence {
+		maxDataBytes -= types.MaxEvidenceBytesOf(ev) - types.MaxEvidenceBytes
+	}
+	if maxDataBytes < 0 {
+		maxDataBytes = 0
+	}
+	txs := blockExec.reapTxs(maxDataBytes, maxGas, deadline)
+	txs = blockExec.prepareProposal(height, txs, maxDataBytes, maxGas, proposerAddr, deadline)
 
 	return state.MakeBlock(height, txs, commit, evidence, proposerAddr)
 }
 
+// reapTxs reaps the txs of the proposal block from the mempool, until the
+// deadline (if not zero): reaping can be slow while the mempool is locked or
+// rechecking txs, and stops at the deadline with the txs reaped so far.
+func (blockExec *BlockExecutor) reapTxs(maxDataBytes, maxGas int64, deadline time.Time) types.Txs {
+	txs := blockExec.mempool.ReapMaxBytesMaxGasUntil(maxDataBytes, maxGas, deadline)
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		blockExec.logger.Error("Reaping the mempool reached the create proposal deadline, proposing the txs reaped so far",
+			"deadline", blockExec.createProposalDeadline, "txs", len(txs))
+		blockExec.metrics.ProposalDeadlineExceeded.Add(1)
+	}
+	return txs
+}
+
+// prepareProposal lets the app reorder, add or remove txs of the proposal
+// block. It returns the reaped txs if the app fails, its txs don't fit in
+// maxDataBytes, or it doesn't respond by the deadline (if not zero). The max
+// gas can't be checked for the txs added by the app, whose gas isn't known:
+// it's up to the app to respect it.
+func (blockExec *BlockExecutor) prepareProposal(
+	height int64,
+	txs types.Txs,
+	maxDataBytes, maxGas int64,
+	proposerAddr []byte,
+	deadline time.Time,
+) types.Txs {
+	res, err := blockExec.prepareProposalSync(abci.RequestPrepareProposal{
+		Height:          height,
+		Txs:             txs.ToSliceOfBytes(),
+		MaxTxBytes:      maxDataBytes,
+		MaxGas:          maxGas,
+		ProposerAddress: proposerAddr,
+	}, deadline)
+	if err == errPrepareProposalDeadline {
+		blockExec.logger.Error("PrepareProposal exceeded the create proposal deadline, proposing the reaped txs",
+			"deadline", blockExec.createProposalDeadline)
+		blockExec.metrics.ProposalDeadlineExceeded.Add(1)
+		return txs
+	}
+	if err != nil {
+		blockExec.logger.Error("Client error during proxyAppConn.PrepareProposalSync, proposing the reaped txs",
+			"err", err)
+		return txs
+	}
+
+	prepared := types.ToTxs(res.Txs)
+	if size := prepared.TotalAminoSize(); size > maxDataBytes {
+		blockExec.logger.Error("The txs prepared by the app exceed the max bytes, proposing the reaped txs",
+			"size", size, "max", maxDataBytes)
+		return txs
+	}
+	return prepared
+}
+
+var errPrepareProposalDeadline = errors.New("PrepareProposal exceeded the deadline")
+
+// prepareProposalSync calls PrepareProposal, and waits for its response until
+// the deadline (if not zero). The call can't be cancelled: past the deadline,
+// the next calls of the consensus connection wait for it to return.
+func (blockExec *BlockExecutor) prepareProposalSync(
+	req abci.RequestPrepareProposal,
+	deadline time.Time,
+) (*abci.ResponsePrepareProposal, error) {
+	if deadline.IsZero() {
+		return blockExec.proxyApp.PrepareProposalSync(req)
+	}
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return nil, errPrepareProposalDeadline
+	}
+
+	type result struct {
+		res *abci.ResponsePrepareProposal
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		res, err := blockExec.proxyApp.PrepareProposalSync(req)
+		resCh <- result{res, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-resCh:
+		return r.res, r.err
+	case <-timer.C:
+		return nil, errPrepareProposalDeadline
+	}
+}
+
+// deliverVoteExtensions sends the extensions of the commit's precommits to the
+// app before it's included in the proposal block. Nothing is sent if the
+// precommits carry no extensions.
+func (blockExec *BlockExecutor) deliverVoteExtensions(height int64, state State, commit *types.Commit) {
+	voteExtensions := make([]abci.VoteExtension, 0, commit.Size())
+	for i, precommit := range commit.Precommits {
+		if precommit == nil || len(precommit.Extension) == 0 {
+			continue
+		}
+		_, val := state.LastValidators.GetByIndex(i)
+		if val == nil {
+			continue
+		}
+		voteExtensions = append(voteExtensions, abci.VoteExtension{
+			Validator:     types.TM2PB.Validator(val),
+			VoteExtension: precommit.Extension,
+		})
+	}
+	if len(voteExtensions) == 0 {
+		return
+	}
+
+	_, err := blockExec.proxyApp.DeliverVoteExtensionsSync(abci.RequestDeliverVoteExtensions{
+		Height:         height,
+		VoteExtensions: voteExtensions,
+	})
+	if err != nil {
+		blockExec.logger.Error("Client error during proxyAppConn.DeliverVoteExtensionsSync", "err", err)
+	}
+}
+
+// ExtendVote asks the app for the extension of our precommit for the block
+// with the given hash. It returns nil for nil precommits.
+func (blockExec *BlockExecutor) ExtendVote(height int64, round int, hash []byte) ([]byte, error) {
+	if len(hash) == 0 {
+		return nil, nil
+	}
+	res, err := blockExec.proxyApp.ExtendVoteSync(abci.RequestExtendVote{
+		Height: height,
+		Round:  int32(round),
+		Hash:   hash,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(res.VoteExtension) > types.MaxVoteExtensionSize {
+		return nil, fmt.Errorf("vote extension is too big (%d > %d)",
+			len(res.VoteExtension), types.MaxVoteExtensionSize)
+	}
+	return res.VoteExtension, nil
+}
+
+// ProcessProposal asks the app whether to accept the txs of a valid proposal
+// block before prevoting for it. It returns an error if the app rejects them,
+// so that app-level rules are enforced before the block is committed rather
+// than only at DeliverTx.
+func (blockExec *BlockExecutor) ProcessProposal(block *types.Block) error {
+	res, err := blockExec.proxyApp.ProcessProposalSync(abci.RequestProcessProposal{
+		Height:          block.Height,
+		Hash:            block.Hash(),
+		Txs:             block.Txs.ToSliceOfBytes(),
+		ProposerAddress: block.ProposerAddress,
+	})
+	if err != nil {
+		return err
+	}
+	if !res.Accept {
+		return fmt.Errorf("app rejected the proposal: %s", res.Log)
+	}
+	return nil
+}
+
 // ValidateBlock validates the given block against the given state.
 // If the block is invalid, it returns an error.
 // Validation does not mutate state, but does require historical information from the stateDB,
 // ie. to verify evidence from a validator at an old height.
 func (blockExec *BlockExecutor) ValidateBlock(state State, block *types.Block) error {
+	blockExec.dbMtx.Lock()
+	defer blockExec.dbMtx.Unlock()
 	return validateBlock(blockExec.evpool, blockExec.db, state, block)
 }
 
@@ -122,13 +403,16 @@ func (blockExec *BlockExecutor) ValidateBlock(state State, block *types.Block) e
 // from outside this package to process and commit an entire block.
 // It takes a blockID to avoid recomputing the parts hash.
 func (blockExec *BlockExecutor) ApplyBlock(state State, blockID types.BlockID, block *types.Block) (State, error) {
+	blockExec.dbMtx.Lock()
+	defer blockExec.dbMtx.Unlock()
 
-	if err := blockExec.ValidateBlock(state, block); err != nil {
+	if err := validateBlock(blockExec.evpool, blockExec.db, state, block); err != nil {
 		return state, ErrInvalidBlock(err)
 	}
 
 	startTime := time.Now().UnixNano()
-	abciResponses, err := execBlockOnProxyApp(blockExec.logger, blockExec.proxyApp, block, blockExec.db)
+	abciResponses, err := execBlockOnProxyApp(blockExec.logger, blockExec.proxyApp, block, state.LastBlockTime,
+		blockExec.db)
 	endTime := time.Now().UnixNano()
 	blockExec.metrics.BlockProcessingTime.Observe(float64(endTime-startTime) / 1000000)
 	if err != nil {
@@ -137,16 +421,32 @@ func (blockExec *BlockExecutor) ApplyBlock(state State, blockID types.BlockID, b
 
 	fail.Fail() // XXX
 
-	// Save the results before we commit.
-	saveABCIResponses(blockExec.db, block.Height, abciResponses)
+	// Validate the validator updates. The ones changing the voting power too
+	// much are rejected, and the app learns it in the next BeginBlock.
+	abciValUpdates := abciResponses.EndBlock.ValidatorUpdates
+	valUpdatesErr := validateValidatorUpdates(abciValUpdates, state.ConsensusParams.Validator, state.NextValidators)
+	if tooBig, ok := valUpdatesErr.(ErrValidatorPowerChangeTooBig); ok {
+		blockExec.logger.Error("Rejecting the validator updates", "height", block.Height, "err", tooBig)
+		abciResponses.RejectedValidatorUpdates = &abci.RejectedValidatorUpdates{
+			Height:              block.Height,
+			PowerChange:         tooBig.Change,
+			TotalPower:          tooBig.TotalPower,
+			MaxPowerChangeNum:   tooBig.MaxNum,
+			MaxPowerChangeDenom: tooBig.MaxDenom,
+		}
+		abciValUpdates = nil
+		valUpdatesErr = nil
+	}
+
+	// Save the results before we commit. They bypass the batched writes, as
+	// they are needed to recover the state after a crash.
+	saveABCIResponses(blockExec.unbatchedDB(), block.Height, abciResponses)
 
 	fail.Fail() // XXX
 
-	// validate the validator updates and convert to tendermint types
-	abciValUpdates := abciResponses.EndBlock.ValidatorUpdates
-	err = validateValidatorUpdates(abciValUpdates, state.ConsensusParams.Validator)
-	if err != nil {
-		return state, fmt.Errorf("Error in validator updates: %v", err)
+	// convert the validator updates to tendermint types
+	if valUpdatesErr != nil {
+		return state, valUpdatesErr
 	}
 	validatorUpdates, err := types.PB2TM.ValidatorUpdates(abciValUpdates)
 	if err != nil {
@@ -156,6 +456,9 @@ func (blockExec *BlockExecutor) ApplyBlock(state State, blockID types.BlockID, b
 		blockExec.logger.Info("Updates to validators", "updates", types.ValidatorListString(validatorUpdates))
 	}
 
+	// The updates apply to the next validators.
+	nextValidators := state.NextValidators
+
 	// Update the state with the block and responses.
 	state, err = updateState(state, blockID, &block.Header, abciResponses, validatorUpdates)
 	if err != nil {
@@ -176,16 +479,42 @@ func (blockExec *BlockExecutor) ApplyBlock(state State, blockID types.BlockID, b
 	// Update the app hash and save the state.
 	state.AppHash = appHash
 	SaveState(blockExec.db, state)
+	if blockExec.batch != nil {
+		blockExec.pendingBlocks++
+		if blockExec.pendingBlocks >= blockExec.batchBlocks {
+			blockExec.batch.Flush()
+			blockExec.pendingBlocks = 0
+		}
+	}
+	if blockExec.shadow != nil {
+		blockExec.shadow.enqueue(block.Height, appHash, blockExec.db)
+	}
 
 	fail.Fail() // XXX
 
+	blockExec.notifyListeners(BlockUpdate{
+		Block:            block,
+		BlockID:          blockID,
+		ABCIResponses:    abciResponses,
+		ValidatorUpdates: validatorUpdates,
+		AppHash:          appHash,
+	})
+
 	// Events are fired after everything else.
 	// NOTE: if we crash between Commit and Save, events wont be fired during replay
-	fireEvents(blockExec.logger, blockExec.eventBus, block, abciResponses, validatorUpdates)
+	fireEvents(blockExec.logger, blockExec.eventBus, block, abciResponses, nextValidators, validatorUpdates)
 
 	return state, nil
 }
 
+// unbatchedDB returns the state DB, bypassing the batched writes.
+func (blockExec *BlockExecutor) unbatchedDB() dbm.DB {
+	if blockExec.batch != nil {
+		return blockExec.batch.DB
+	}
+	return blockExec.db
+}
+
 // Commit locks the mempool, runs the ABCI Commit message, and updates the
 // mempool.
 // It returns the result of calling abci.Commit (the AppHash), and an error.
@@ -247,6 +576,7 @@ func execBlockOnProxyApp(
 	logger log.Logger,
 	proxyAppConn proxy.AppConnConsensus,
 	block *types.Block,
+	lastBlockTime time.Time,
 	stateDB dbm.DB,
 ) (*ABCIResponses, error) {
 	var validTxs, invalidTxs = 0, 0
@@ -278,10 +608,12 @@ func execBlockOnProxyApp(
 	// Begin block
 	var err error
 	abciResponses.BeginBlock, err = proxyAppConn.BeginBlockSync(abci.RequestBeginBlock{
-		Hash:                block.Hash(),
-		Header:              types.TM2PB.Header(&block.Header),
-		LastCommitInfo:      commitInfo,
-		ByzantineValidators: byzVals,
+		Hash:                     block.Hash(),
+		Header:                   types.TM2PB.Header(&block.Header),
+		LastCommitInfo:           commitInfo,
+		ByzantineValidators:      byzVals,
+		TimeInfo:                 getBeginBlockTimeInfo(block, lastBlockTime),
+		RejectedValidatorUpdates: getBeginBlockRejectedValidatorUpdates(block, stateDB),
 	})
 	if err != nil {
 		logger.Error("Error in proxyAppConn.BeginBlock", "err", err)
@@ -308,6 +640,35 @@ func execBlockOnProxyApp(
 	return abciResponses, nil
 }
 
+// getBeginBlockTimeInfo returns the timing data of the block for the app. It
+// only depends on the block and the previous block time, so it's the same on
+// all the nodes, whether the block is applied or replayed.
+func getBeginBlockTimeInfo(block *types.Block, lastBlockTime time.Time) *abci.BlockTimeInfo {
+	info := &abci.BlockTimeInfo{
+		ProposerAddress:    block.ProposerAddress,
+		ProposerAddressHex: block.ProposerAddress.String(),
+	}
+	if block.Height > 1 {
+		info.TimeSinceLastBlock = int64(block.Time.Sub(lastBlockTime))
+		info.LastCommitRound = int64(block.LastCommit.Round())
+	}
+	return info
+}
+
+// getBeginBlockRejectedValidatorUpdates returns why the validator updates of
+// the previous block were rejected, if they were, from its saved ABCI
+// responses.
+func getBeginBlockRejectedValidatorUpdates(block *types.Block, stateDB dbm.DB) *abci.RejectedValidatorUpdates {
+	if block.Height <= 1 {
+		return nil
+	}
+	abciResponses, err := LoadABCIResponses(stateDB, block.Height-1)
+	if err != nil {
+		return nil
+	}
+	return abciResponses.RejectedValidatorUpdates
+}
+
 func getBeginBlockValidatorInfo(block *types.Block, stateDB dbm.DB) (abci.LastCommitInfo, []abci.Evidence) {
 	voteInfos := make([]abci.VoteInfo, block.LastCommit.Size())
 	byzVals := make([]abci.Evidence, len(block.Evidence.Evidence))
@@ -333,14 +694,11 @@ func getBeginBlockValidatorInfo(block *types.Block, stateDB dbm.DB) (abci.LastCo
 		lastValSet = types.NewValidatorSet(nil)
 	}
 
+	signers := block.LastCommit.BitArray()
 	for i, val := range lastValSet.Validators {
-		var vote *types.CommitSig
-		if i < len(block.LastCommit.Precommits) {
-			vote = block.LastCommit.Precommits[i]
-		}
 		voteInfo := abci.VoteInfo{
 			Validator:       types.TM2PB.Validator(val),
-			SignedLastBlock: vote != nil,
+			SignedLastBlock: signers.GetIndex(i),
 		}
 		voteInfos[i] = voteInfo
 	}
@@ -364,27 +722,90 @@ func getBeginBlockValidatorInfo(block *types.Block, stateDB dbm.DB) (abci.LastCo
 
 }
 
+// validateValidatorUpdates checks the validator updates of the app against the
+// validator params and the validators they apply to. The errors are
+// ErrInvalidValidatorUpdate and ErrValidatorPowerChangeTooBig.
 func validateValidatorUpdates(abciUpdates []abci.ValidatorUpdate,
-	params types.ValidatorParams) error {
-	for _, valUpdate := range abciUpdates {
+	params types.ValidatorParams, vals *types.ValidatorSet) error {
+	var (
+		seen   = make(map[string]bool, len(abciUpdates))
+		change int64 // saturated at math.MaxInt64
+	)
+	for i, valUpdate := range abciUpdates {
+		invalid := func(reason string) error {
+			return ErrInvalidValidatorUpdate{Index: i, Update: valUpdate, Reason: reason}
+		}
+
+		if isZeroPubKey(valUpdate.PubKey) {
+			return invalid("the pubkey is empty")
+		}
+		key := valUpdate.PubKey.Type + "/" + string(valUpdate.PubKey.Data)
+		if seen[key] {
+			return invalid("the pubkey is updated twice")
+		}
+		seen[key] = true
+
 		if valUpdate.GetPower() < 0 {
-			return fmt.Errorf("Voting power can't be negative %v", valUpdate)
-		} else if valUpdate.GetPower() == 0 {
-			// continue, since this is deleting the validator, and thus there is no
-			// pubkey to check
+			return invalid("the voting power can't be negative")
+		} else if valUpdate.GetPower() > 0 {
+			// Check if validator's pubkey matches an ABCI type in the consensus
+			// params. A removed validator has no pubkey to check.
+			if !params.IsValidPubkeyType(valUpdate.PubKey.Type) {
+				return invalid(fmt.Sprintf("the pubkey type %s is unsupported for consensus",
+					valUpdate.PubKey.Type))
+			}
+		}
+
+		if params.MaxPowerChangeDenom == 0 || vals == nil {
 			continue
 		}
+		pubKey, err := types.PB2TM.PubKey(valUpdate.PubKey)
+		if err != nil {
+			return invalid(err.Error())
+		}
+		var power int64
+		if _, val := vals.GetByAddress(pubKey.Address()); val != nil {
+			power = val.VotingPower
+		}
+		delta := valUpdate.Power - power
+		if delta < 0 {
+			delta = -delta
+		}
+		if delta > math.MaxInt64-change {
+			change = math.MaxInt64
+		} else {
+			change += delta
+		}
+	}
 
-		// Check if validator's pubkey matches an ABCI type in the consensus params
-		thisKeyType := valUpdate.PubKey.Type
-		if !params.IsValidPubkeyType(thisKeyType) {
-			return fmt.Errorf("Validator %v is using pubkey %s, which is unsupported for consensus",
-				valUpdate, thisKeyType)
+	if params.MaxPowerChangeDenom == 0 || vals == nil || vals.Size() == 0 {
+		return nil
+	}
+	// change <= total * num / denom
+	total := vals.TotalVotingPower()
+	maxChange := new(big.Int).Mul(big.NewInt(total), big.NewInt(params.MaxPowerChangeNum))
+	maxChange.Quo(maxChange, big.NewInt(params.MaxPowerChangeDenom))
+	if big.NewInt(change).Cmp(maxChange) > 0 {
+		return ErrValidatorPowerChangeTooBig{
+			Change:     change,
+			TotalPower: total,
+			MaxNum:     params.MaxPowerChangeNum,
+			MaxDenom:   params.MaxPowerChangeDenom,
 		}
 	}
 	return nil
 }
 
+// isZeroPubKey returns true if the pubkey has no data, or only zeros.
+func isZeroPubKey(pubKey abci.PubKey) bool {
+	for _, b := range pubKey.Data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // updateState returns a new State updated according to the header and responses.
 func updateState(
 	state State,
@@ -457,6 +878,7 @@ func fireEvents(
 	eventBus types.BlockEventPublisher,
 	block *types.Block,
 	abciResponses *ABCIResponses,
+	nextValidators *types.ValidatorSet,
 	validatorUpdates []*types.Validator,
 ) {
 	eventBus.PublishEventNewBlock(types.EventDataNewBlock{
@@ -481,7 +903,7 @@ func fireEvents(
 
 	if len(validatorUpdates) > 0 {
 		eventBus.PublishEventValidatorSetUpdates(
-			types.EventDataValidatorSetUpdates{ValidatorUpdates: validatorUpdates})
+			types.NewEventDataValidatorSetUpdates(block.Height, nextValidators, validatorUpdates))
 	}
 }
 
@@ -489,24 +911,51 @@ func fireEvents(
 // Execute block without state. TODO: eliminate
 
 // ExecCommitBlock executes and commits a block on the proxyApp without validating or mutating the state.
+// lastBlockTime is the time of the previous block (see LastBlockTime).
 // It returns the application root hash (result of abci.Commit).
 func ExecCommitBlock(
 	appConnConsensus proxy.AppConnConsensus,
 	block *types.Block,
+	lastBlockTime time.Time,
 	logger log.Logger,
 	stateDB dbm.DB,
 ) ([]byte, error) {
-	_, err := execBlockOnProxyApp(logger, appConnConsensus, block, stateDB)
+	_, appHash, err := execCommitBlock(appConnConsensus, block, lastBlockTime, logger, stateDB)
+	return appHash, err
+}
+
+// LastBlockTime returns the time of the block before height in blockStore, to
+// be passed to ExecCommitBlock. It's the zero time for the first block.
+func LastBlockTime(blockStore BlockStoreRPC, height int64) (time.Time, error) {
+	if height <= 1 {
+		return time.Time{}, nil
+	}
+	meta := blockStore.LoadBlockMeta(height - 1)
+	if meta == nil {
+		return time.Time{}, ErrUnknownBlock{Height: height - 1}
+	}
+	return meta.Header.Time, nil
+}
+
+// execCommitBlock is ExecCommitBlock, also returning the ABCI responses.
+func execCommitBlock(
+	appConnConsensus proxy.AppConnConsensus,
+	block *types.Block,
+	lastBlockTime time.Time,
+	logger log.Logger,
+	stateDB dbm.DB,
+) (*ABCIResponses, []byte, error) {
+	abciResponses, err := execBlockOnProxyApp(logger, appConnConsensus, block, lastBlockTime, stateDB)
 	if err != nil {
 		logger.Error("Error executing block on proxy app", "height", block.Height, "err", err)
-		return nil, err
+		return nil, nil, err
 	}
 	// Commit block, get hash back
 	res, err := appConnConsensus.CommitSync()
 	if err != nil {
 		logger.Error("Client error during proxyAppConn.CommitSync", "err", res)
-		return nil, err
+		return nil, nil, err
 	}
 	// ResponseCommit has no error or log, just data
-	return res.Data, nil
+	return abciResponses, res.Data, nil
 }
@@ -1,6 +1,7 @@
 package state_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 	"time"
@@ -47,6 +48,101 @@ func TestApplyBlock(t *testing.T) {
 	// TODO check state and mempool
 }
 
+type blockRecorder struct {
+	updates []sm.BlockUpdate
+}
+
+func (br *blockRecorder) ListenBlock(update sm.BlockUpdate) error {
+	br.updates = append(br.updates, update)
+	return nil
+}
+
+func TestApplyBlockListeners(t *testing.T) {
+	cc := proxy.NewLocalClientCreator(kvstore.NewKVStoreApplication())
+	proxyApp := proxy.NewAppConns(cc)
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	state, stateDB, _ := makeState(1, 1)
+	listener := new(blockRecorder)
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{}, sm.BlockExecutorWithListeners(listener))
+
+	block := makeBlock(state, 1)
+	blockID := types.BlockID{Hash: block.Hash(), PartsHeader: block.MakePartSet(testPartSize).Header()}
+	state, err := blockExec.ApplyBlock(state, blockID, block)
+	require.NoError(t, err)
+
+	require.Len(t, listener.updates, 1)
+	update := listener.updates[0]
+	assert.Equal(t, block, update.Block)
+	assert.Equal(t, blockID, update.BlockID)
+	assert.Len(t, update.ABCIResponses.DeliverTx, len(block.Data.Txs))
+	assert.EqualValues(t, state.AppHash, update.AppHash)
+}
+
+func TestApplyBlockBatchedWrites(t *testing.T) {
+	proxyApp := newTestApp()
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	state, stateDB, privVals := makeState(3, 1)
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{}, sm.BlockExecutorWithBatchedWrites(3))
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+
+	// the state is written every 3 blocks, the ABCI responses at every block
+	expected := []int64{0, 0, 3, 3}
+	for i, stateHeight := range expected {
+		height := int64(i + 1)
+		var err error
+		state, _, lastCommit, err = makeAndCommitGoodBlock(
+			state, height, lastCommit, state.Validators.GetProposer().Address, blockExec, privVals, nil)
+		require.NoError(t, err, "height %d", height)
+
+		assert.EqualValues(t, stateHeight, sm.LoadState(stateDB).LastBlockHeight, "height %d", height)
+		_, err = sm.LoadABCIResponses(stateDB, height)
+		assert.NoError(t, err, "height %d", height)
+	}
+
+	blockExec.StopBatchedWrites()
+	assert.EqualValues(t, 4, sm.LoadState(stateDB).LastBlockHeight)
+
+	// writes aren't batched anymore
+	_, _, _, err := makeAndCommitGoodBlock(
+		state, 5, lastCommit, state.Validators.GetProposer().Address, blockExec, privVals, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, sm.LoadState(stateDB).LastBlockHeight)
+}
+
+func TestStopBatchedWritesWhileApplyingBlocks(t *testing.T) {
+	proxyApp := newTestApp()
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	state, stateDB, privVals := makeState(3, 1)
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{}, sm.BlockExecutorWithBatchedWrites(3))
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+
+	stopped := make(chan struct{})
+	go func() {
+		blockExec.StopBatchedWrites()
+		close(stopped)
+	}()
+	for height := int64(1); height <= 4; height++ {
+		var err error
+		state, _, lastCommit, err = makeAndCommitGoodBlock(
+			state, height, lastCommit, state.Validators.GetProposer().Address, blockExec, privVals, nil)
+		require.NoError(t, err, "height %d", height)
+	}
+	<-stopped
+
+	// the blocks applied after stopping are written right away, the ones
+	// before are flushed
+	assert.EqualValues(t, 4, sm.LoadState(stateDB).LastBlockHeight)
+}
+
 // TestBeginBlockValidators ensures we send absent validators list.
 func TestBeginBlockValidators(t *testing.T) {
 	app := &testApp{}
@@ -82,7 +178,7 @@ func TestBeginBlockValidators(t *testing.T) {
 		// block for height 2
 		block, _ := state.MakeBlock(2, makeTxs(2), lastCommit, nil, state.Validators.GetProposer().Address)
 
-		_, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, log.TestingLogger(), stateDB)
+		_, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, state.LastBlockTime, log.TestingLogger(), stateDB)
 		require.Nil(t, err, tc.desc)
 
 		// -> app receives a list of validators with a bool indicating if they signed
@@ -143,7 +239,7 @@ func TestBeginBlockByzantineValidators(t *testing.T) {
 		block, _ := state.MakeBlock(10, makeTxs(2), lastCommit, nil, state.Validators.GetProposer().Address)
 		block.Time = now
 		block.Evidence.Evidence = tc.evidence
-		_, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, log.TestingLogger(), stateDB)
+		_, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, state.LastBlockTime, log.TestingLogger(), stateDB)
 		require.Nil(t, err, tc.desc)
 
 		// -> app must receive an index of the byzantine validator
@@ -151,13 +247,62 @@ func TestBeginBlockByzantineValidators(t *testing.T) {
 	}
 }
 
+// TestBeginBlockTimeInfo ensures we send the timing data of the block.
+func TestBeginBlockTimeInfo(t *testing.T) {
+	app := &testApp{}
+	cc := proxy.NewLocalClientCreator(app)
+	proxyApp := proxy.NewAppConns(cc)
+	err := proxyApp.Start()
+	require.Nil(t, err)
+	defer proxyApp.Stop()
+
+	state, stateDB, _ := makeState(2, 2)
+	proposer := state.Validators.GetProposer().Address
+
+	now := tmtime.Now()
+	lastBlockTime := now.Add(-5 * time.Second)
+
+	// first block
+	block, _ := state.MakeBlock(1, makeTxs(2), new(types.Commit), nil, proposer)
+	_, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, time.Time{}, log.TestingLogger(), stateDB)
+	require.Nil(t, err)
+	assert.Equal(t, &abci.BlockTimeInfo{ProposerAddress: proposer, ProposerAddressHex: proposer.String()},
+		app.TimeInfo)
+
+	// block for height 2, committed at round 3
+	prevBlockID := types.BlockID{Hash: state.LastBlockID.Hash}
+	commitSig0 := (&types.Vote{ValidatorIndex: 0, Round: 3, Timestamp: now, Type: types.PrecommitType}).CommitSig()
+	commitSig1 := (&types.Vote{ValidatorIndex: 1, Round: 3, Timestamp: now, Type: types.PrecommitType}).CommitSig()
+	lastCommit := types.NewCommit(prevBlockID, []*types.CommitSig{commitSig0, commitSig1})
+	block, _ = state.MakeBlock(2, makeTxs(2), lastCommit, nil, proposer)
+	block.Time = now
+	_, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, lastBlockTime, log.TestingLogger(), stateDB)
+	require.Nil(t, err)
+	assert.Equal(t, &abci.BlockTimeInfo{
+		TimeSinceLastBlock: int64(5 * time.Second),
+		LastCommitRound:    3,
+		ProposerAddress:    proposer,
+		ProposerAddressHex: proposer.String(),
+	}, app.TimeInfo)
+}
+
 func TestValidateValidatorUpdates(t *testing.T) {
 	pubkey1 := ed25519.GenPrivKey().PubKey()
 	pubkey2 := ed25519.GenPrivKey().PubKey()
 
+	pubkey3 := ed25519.GenPrivKey().PubKey()
+
 	secpKey := secp256k1.GenPrivKey().PubKey()
+	zeroKey := abci.PubKey{Type: types.ABCIPubKeyTypeEd25519, Data: make([]byte, ed25519.PubKeyEd25519Size)}
 
 	defaultValidatorParams := types.ValidatorParams{PubKeyTypes: []string{types.ABCIPubKeyTypeEd25519}}
+	limitedValidatorParams := types.DefaultValidatorParams() // 1/3
+
+	// total voting power of 30
+	vals := types.NewValidatorSet([]*types.Validator{
+		types.NewValidator(pubkey1, 20),
+		types.NewValidator(pubkey3, 10),
+	})
 
 	testCases := []struct {
 		name string
@@ -205,6 +350,52 @@ func TestValidateValidatorUpdates(t *testing.T) {
 			[]abci.ValidatorUpdate{{PubKey: types.TM2PB.PubKey(secpKey), Power: -100}},
 			defaultValidatorParams,
 
+			true,
+		},
+		{
+			"updating a validator with an empty pubkey results in error",
+
+			[]abci.ValidatorUpdate{{PubKey: zeroKey, Power: 10}},
+			defaultValidatorParams,
+
+			true,
+		},
+		{
+			"updating a validator twice results in error",
+
+			[]abci.ValidatorUpdate{
+				{PubKey: types.TM2PB.PubKey(pubkey2), Power: 10},
+				{PubKey: types.TM2PB.PubKey(pubkey2), Power: 20},
+			},
+			defaultValidatorParams,
+
+			true,
+		},
+		{
+			"changing 1/3 of the voting power is OK",
+
+			[]abci.ValidatorUpdate{{PubKey: types.TM2PB.PubKey(pubkey1), Power: 30}},
+			limitedValidatorParams,
+
+			false,
+		},
+		{
+			"changing more than 1/3 of the voting power results in error",
+
+			[]abci.ValidatorUpdate{
+				{PubKey: types.TM2PB.PubKey(pubkey1), Power: 10},
+				{PubKey: types.TM2PB.PubKey(pubkey2), Power: 1},
+			},
+			limitedValidatorParams,
+
+			true,
+		},
+		{
+			"removing 2/3 of the voting power results in error",
+
+			[]abci.ValidatorUpdate{{PubKey: types.TM2PB.PubKey(pubkey1), Power: 0}},
+			limitedValidatorParams,
+
 			true,
 		},
 	}
@@ -212,7 +403,7 @@ func TestValidateValidatorUpdates(t *testing.T) {
 	for _, tc := range testCases {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
-			err := sm.ValidateValidatorUpdates(tc.abciUpdates, tc.validatorParams)
+			err := sm.ValidateValidatorUpdates(tc.abciUpdates, tc.validatorParams, vals)
 			if tc.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -220,6 +411,18 @@ func TestValidateValidatorUpdates(t *testing.T) {
 			}
 		})
 	}
+
+	// the errors are structured
+	err := sm.ValidateValidatorUpdates([]abci.ValidatorUpdate{
+		{PubKey: types.TM2PB.PubKey(pubkey2), Power: 10},
+		{PubKey: zeroKey, Power: 10},
+	}, defaultValidatorParams, vals)
+	if assert.IsType(t, sm.ErrInvalidValidatorUpdate{}, err) {
+		assert.Equal(t, 1, err.(sm.ErrInvalidValidatorUpdate).Index)
+	}
+	err = sm.ValidateValidatorUpdates([]abci.ValidatorUpdate{{PubKey: types.TM2PB.PubKey(pubkey1), Power: 0}},
+		limitedValidatorParams, vals)
+	assert.Equal(t, sm.ErrValidatorPowerChangeTooBig{Change: 20, TotalPower: 30, MaxNum: 1, MaxDenom: 3}, err)
 }
 
 func TestUpdateValidators(t *testing.T) {
@@ -358,6 +561,12 @@ func TestEndBlockValidatorUpdates(t *testing.T) {
 			assert.Equal(t, pubkey, event.ValidatorUpdates[0].PubKey)
 			assert.EqualValues(t, 10, event.ValidatorUpdates[0].VotingPower)
 		}
+		assert.EqualValues(t, 1, event.Height)
+		if assert.Len(t, event.Added, 1) {
+			assert.Equal(t, pubkey, event.Added[0].PubKey)
+		}
+		assert.Empty(t, event.Removed)
+		assert.Empty(t, event.PowerChanges)
 	case <-updatesSub.Cancelled():
 		t.Fatalf("updatesSub was cancelled (reason: %v)", updatesSub.Err())
 	case <-time.After(1 * time.Second):
@@ -376,6 +585,8 @@ func TestEndBlockValidatorUpdatesResultingInEmptySet(t *testing.T) {
 	defer proxyApp.Stop()
 
 	state, stateDB, _ := makeState(1, 1)
+	// no limit on the power change, to reach the empty set
+	state.ConsensusParams.Validator.MaxPowerChangeDenom = 0
 	blockExec := sm.NewBlockExecutor(
 		stateDB,
 		log.TestingLogger(),
@@ -397,3 +608,256 @@ func TestEndBlockValidatorUpdatesResultingInEmptySet(t *testing.T) {
 	assert.NotEmpty(t, state.NextValidators.Validators)
 
 }
+
+// TestEndBlockValidatorUpdatesRejected checks that the validator updates
+// changing the voting power by more than the default 1/3 are rejected without
+// an error, and that the app learns it in the next BeginBlock.
+func TestEndBlockValidatorUpdatesRejected(t *testing.T) {
+	app := &testApp{}
+	cc := proxy.NewLocalClientCreator(app)
+	proxyApp := proxy.NewAppConns(cc)
+	err := proxyApp.Start()
+	require.Nil(t, err)
+	defer proxyApp.Stop()
+
+	state, stateDB, _ := makeState(1, 1)
+	blockExec := sm.NewBlockExecutor(
+		stateDB,
+		log.TestingLogger(),
+		proxyApp.Consensus(),
+		mock.Mempool{},
+		sm.MockEvidencePool{},
+	)
+
+	block := makeBlock(state, 1)
+	blockID := types.BlockID{Hash: block.Hash(), PartsHeader: block.MakePartSet(testPartSize).Header()}
+
+	// Double the voting power
+	app.ValidatorUpdates = []abci.ValidatorUpdate{
+		{PubKey: types.TM2PB.PubKey(ed25519.GenPrivKey().PubKey()), Power: 1000},
+	}
+
+	nextValidators := state.NextValidators
+	state, err = blockExec.ApplyBlock(state, blockID, block)
+	require.Nil(t, err)
+	assert.Equal(t, nextValidators.Hash(), state.NextValidators.Hash())
+
+	rejected := &abci.RejectedValidatorUpdates{
+		Height:              1,
+		PowerChange:         1000,
+		TotalPower:          1000,
+		MaxPowerChangeNum:   1,
+		MaxPowerChangeDenom: 3,
+	}
+	abciResponses, err := sm.LoadABCIResponses(stateDB, 1)
+	require.Nil(t, err)
+	assert.Equal(t, rejected, abciResponses.RejectedValidatorUpdates)
+
+	// the app learns it in the next BeginBlock
+	commitSig := (&types.Vote{ValidatorIndex: 0, Timestamp: block.Time, Type: types.PrecommitType}).CommitSig()
+	lastCommit := types.NewCommit(blockID, []*types.CommitSig{commitSig})
+	block, _ = state.MakeBlock(2, makeTxs(2), lastCommit, nil, state.Validators.GetProposer().Address)
+	_, err = sm.ExecCommitBlock(proxyApp.Consensus(), block, state.LastBlockTime, log.TestingLogger(), stateDB)
+	require.Nil(t, err)
+	assert.Equal(t, rejected, app.RejectedUpdates)
+}
+
+// slowMempool reaps its first tx right away, and the next ones once released.
+type slowMempool struct {
+	mock.Mempool
+	txs     types.Txs
+	release chan struct{}
+}
+
+func (mem slowMempool) ReapMaxBytesMaxGasUntil(_, _ int64, deadline time.Time) types.Txs {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timeout = time.After(time.Until(deadline))
+	}
+	select {
+	case <-mem.release:
+		return mem.txs
+	case <-timeout:
+		return mem.txs[:1]
+	}
+}
+
+func TestCreateProposalBlockDeadline(t *testing.T) {
+	app := &prepareProposalApp{prepare: func(txs types.Txs) types.Txs { return txs }}
+	cc := proxy.NewLocalClientCreator(app)
+	proxyApp := proxy.NewAppConns(cc)
+	err := proxyApp.Start()
+	require.Nil(t, err)
+	defer proxyApp.Stop()
+
+	state, stateDB, _ := makeState(1, 1)
+	commit := types.NewCommit(types.BlockID{}, nil)
+	proposerAddr := state.Validators.Validators[0].Address
+	mempool := slowMempool{txs: makeTxs(2), release: make(chan struct{})}
+
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(), mempool, sm.MockEvidencePool{},
+		sm.BlockExecutorWithCreateProposalDeadline(50*time.Millisecond))
+
+	// the mempool is too slow: the txs reaped by the deadline
+	start := time.Now()
+	block, _ := blockExec.CreateProposalBlock(1, state, commit, proposerAddr)
+	assert.Equal(t, mempool.txs[:1], block.Txs)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// the mempool is fast enough
+	close(mempool.release)
+	block, _ = blockExec.CreateProposalBlock(1, state, commit, proposerAddr)
+	assert.Equal(t, mempool.txs, block.Txs)
+
+	// the app is too slow: the reaped txs
+	prepared := make(chan struct{})
+	app.prepare = func(txs types.Txs) types.Txs {
+		<-prepared
+		return nil
+	}
+	start = time.Now()
+	block, _ = blockExec.CreateProposalBlock(1, state, commit, proposerAddr)
+	assert.Equal(t, mempool.txs, block.Txs)
+	assert.True(t, time.Since(start) < time.Second)
+	close(prepared)
+}
+
+// TestVoteExtensions ensures the app extends our precommits and receives the
+// extensions of the last commit when we propose.
+func TestVoteExtensions(t *testing.T) {
+	app := &testApp{VoteExtension: []byte("price=42")}
+	cc := proxy.NewLocalClientCreator(app)
+	proxyApp := proxy.NewAppConns(cc)
+	err := proxyApp.Start()
+	require.Nil(t, err)
+	defer proxyApp.Stop()
+
+	state, stateDB, _ := makeState(2, 2)
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{})
+
+	// nil precommits have no extension
+	extension, err := blockExec.ExtendVote(1, 0, nil)
+	require.NoError(t, err)
+	assert.Nil(t, extension)
+
+	extension, err = blockExec.ExtendVote(1, 0, []byte("block_hash"))
+	require.NoError(t, err)
+	assert.Equal(t, app.VoteExtension, extension)
+
+	app.VoteExtension = make([]byte, types.MaxVoteExtensionSize+1)
+	_, err = blockExec.ExtendVote(1, 0, []byte("block_hash"))
+	assert.Error(t, err)
+
+	// only the second validator extended its precommit
+	prevBlockID := types.BlockID{Hash: state.LastBlockID.Hash}
+	now := tmtime.Now()
+	commitSig0 := (&types.Vote{ValidatorIndex: 0, Timestamp: now, Type: types.PrecommitType}).CommitSig()
+	commitSig1 := (&types.Vote{ValidatorIndex: 1, Timestamp: now, Type: types.PrecommitType,
+		Extension: []byte("price=42")}).CommitSig()
+	commit := types.NewCommit(prevBlockID, []*types.CommitSig{commitSig0, commitSig1})
+
+	// they aren't delivered while vote extensions are disabled
+	proposerAddr := state.Validators.GetProposer().Address
+	_, _ = blockExec.CreateProposalBlock(2, state, commit, proposerAddr)
+	assert.Empty(t, app.VoteExtensions)
+
+	state.ConsensusParams.Block.VoteExtensionsEnableHeight = 1
+	_, _ = blockExec.CreateProposalBlock(2, state, commit, proposerAddr)
+	require.Len(t, app.VoteExtensions, 1)
+	assert.Equal(t, state.LastValidators.Validators[1].Address.Bytes(), app.VoteExtensions[0].Validator.Address)
+	assert.Equal(t, []byte("price=42"), app.VoteExtensions[0].VoteExtension)
+}
+
+// prepareProposalApp replaces the reaped txs with the ones of prepare.
+type prepareProposalApp struct {
+	testApp
+	prepare func(txs types.Txs) types.Txs
+	req     abci.RequestPrepareProposal
+}
+
+func (app *prepareProposalApp) PrepareProposal(req abci.RequestPrepareProposal) abci.ResponsePrepareProposal {
+	app.req = req
+	return abci.ResponsePrepareProposal{Txs: app.prepare(types.ToTxs(req.Txs)).ToSliceOfBytes()}
+}
+
+// TestPrepareProposal ensures the app prepares the txs of our proposals within
+// the limits of the consensus params of the state.
+func TestPrepareProposal(t *testing.T) {
+	app := &prepareProposalApp{}
+	cc := proxy.NewLocalClientCreator(app)
+	proxyApp := proxy.NewAppConns(cc)
+	err := proxyApp.Start()
+	require.Nil(t, err)
+	defer proxyApp.Stop()
+
+	state, stateDB, _ := makeState(1, 1)
+	commit := types.NewCommit(types.BlockID{}, nil)
+	proposerAddr := state.Validators.Validators[0].Address
+	mempool := slowMempool{txs: makeTxs(1), release: make(chan struct{})}
+	close(mempool.release)
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(), mempool, sm.MockEvidencePool{})
+
+	// the app reverses the txs, with the max gas updated by EndBlock
+	state.ConsensusParams.Block.MaxGas = 100
+	app.prepare = func(txs types.Txs) types.Txs {
+		reversed := make(types.Txs, 0, len(txs))
+		for i := len(txs) - 1; i >= 0; i-- {
+			reversed = append(reversed, txs[i])
+		}
+		return reversed
+	}
+	block, _ := blockExec.CreateProposalBlock(1, state, commit, proposerAddr)
+	require.Len(t, block.Txs, len(mempool.txs))
+	assert.Equal(t, mempool.txs[0], block.Txs[len(block.Txs)-1])
+	assert.EqualValues(t, 100, app.req.MaxGas)
+	assert.EqualValues(t, 1, app.req.Height)
+	assert.Equal(t, proposerAddr.Bytes(), app.req.ProposerAddress)
+
+	// the txs of the app must fit in the block
+	app.prepare = func(txs types.Txs) types.Txs {
+		return types.Txs{make([]byte, app.req.MaxTxBytes)}
+	}
+	block, _ = blockExec.CreateProposalBlock(1, state, commit, proposerAddr)
+	assert.Equal(t, mempool.txs, block.Txs)
+}
+
+// processProposalApp rejects the proposals with a tx of reject.
+type processProposalApp struct {
+	testApp
+	reject types.Tx
+	req    abci.RequestProcessProposal
+}
+
+func (app *processProposalApp) ProcessProposal(req abci.RequestProcessProposal) abci.ResponseProcessProposal {
+	app.req = req
+	for _, tx := range req.Txs {
+		if bytes.Equal(tx, app.reject) {
+			return abci.ResponseProcessProposal{Accept: false, Log: "rejected tx"}
+		}
+	}
+	return abci.ResponseProcessProposal{Accept: true}
+}
+
+// TestProcessProposal ensures the app accepts or rejects the txs of proposals.
+func TestProcessProposal(t *testing.T) {
+	app := &processProposalApp{reject: types.Tx("reject")}
+	cc := proxy.NewLocalClientCreator(app)
+	proxyApp := proxy.NewAppConns(cc)
+	err := proxyApp.Start()
+	require.Nil(t, err)
+	defer proxyApp.Stop()
+
+	state, stateDB, _ := makeState(1, 1)
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{})
+
+	block := makeBlock(state, 1)
+	require.NoError(t, blockExec.ProcessProposal(block))
+	assert.EqualValues(t, 1, app.req.Height)
+	assert.Equal(t, block.Hash().Bytes(), app.req.Hash)
+	assert.Equal(t, block.Txs.ToSliceOfBytes(), app.req.Txs)
+
+	block.Txs = append(block.Txs, app.reject)
+	assert.Error(t, blockExec.ProcessProposal(block))
+}
@@ -33,8 +33,9 @@ func UpdateState(
 
 // ValidateValidatorUpdates is an alias for validateValidatorUpdates exported
 // from execution.go, exclusively and explicitly for testing.
-func ValidateValidatorUpdates(abciUpdates []abci.ValidatorUpdate, params types.ValidatorParams) error {
-	return validateValidatorUpdates(abciUpdates, params)
+func ValidateValidatorUpdates(abciUpdates []abci.ValidatorUpdate, params types.ValidatorParams,
+	vals *types.ValidatorSet) error {
+	return validateValidatorUpdates(abciUpdates, params, vals)
 }
 
 // CalcValidatorsKey is an alias for the private calcValidatorsKey method in
@@ -43,6 +44,12 @@ func CalcValidatorsKey(height int64) []byte {
 	return calcValidatorsKey(height)
 }
 
+// CalcABCIResponsesKey is an alias for the private calcABCIResponsesKey
+// method in store.go, exported exclusively and explicitly for testing.
+func CalcABCIResponsesKey(height int64) []byte {
+	return calcABCIResponsesKey(height)
+}
+
 // SaveABCIResponses is an alias for the private saveABCIResponses method in
 // store.go, exported exclusively and explicitly for testing.
 func SaveABCIResponses(db dbm.DB, height int64, abciResponses *ABCIResponses) {
@@ -0,0 +1,39 @@
+package state
+
+import (
+	"errors"
+
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// Fork replaces the validators of the state in db with validators from the
+// next height on, so that a sandbox network signing with their keys continues
+// the chain from its last height, e.g. to replay mainnet txs against a copy of
+// the mainnet data. The validators of the last height are kept, for the commit
+// of the last block to stay valid. The chain ID, the app hash and the
+// consensus params are left as they are.
+//
+// It returns the forked state.
+func Fork(db dbm.DB, validators *types.ValidatorSet) (State, error) {
+	state := LoadState(db)
+	if state.IsEmpty() {
+		return State{}, errors.New("no state found")
+	}
+	if state.LastBlockHeight == 0 {
+		return State{}, errors.New("no block committed yet, change the genesis validators instead")
+	}
+	if validators.IsNilOrEmpty() {
+		return State{}, errors.New("no validators")
+	}
+
+	height := state.LastBlockHeight
+	state.Validators = validators.Copy()
+	state.NextValidators = validators.CopyIncrementProposerPriority(1)
+	state.LastHeightValidatorsChanged = height + 1
+
+	// SaveState only saves the validators of the height after next.
+	saveValidatorsInfo(db, height+1, height+1, state.Validators)
+	SaveState(db, state)
+	return state, nil
+}
@@ -0,0 +1,70 @@
+package state_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestFork(t *testing.T) {
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(kvstore.NewKVStoreApplication()))
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	_, err := sm.Fork(dbm.NewMemDB(), types.NewValidatorSet(nil))
+	assert.Error(t, err, "no state")
+
+	state, stateDB, privVals := makeState(2, 1)
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{})
+
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+	for height := int64(1); height <= 2; height++ {
+		block, parts := state.MakeBlock(height, makeTxs(height), lastCommit, nil,
+			state.Validators.GetProposer().Address)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		lastCommit, err = makeValidCommit(height, blockID, state.Validators, privVals)
+		require.NoError(t, err)
+		state, err = blockExec.ApplyBlock(state, blockID, block)
+		require.NoError(t, err)
+	}
+	liveVals := state.Validators
+
+	// a single validator takes over from height 3
+	pk := ed25519.GenPrivKeyFromSecret([]byte("sandbox"))
+	sandboxPV := types.NewMockPVWithParams(pk, false, false)
+	sandboxVals := types.NewValidatorSet([]*types.Validator{types.NewValidator(pk.PubKey(), 10)})
+	forked, err := sm.Fork(stateDB, sandboxVals)
+	require.NoError(t, err)
+	assert.True(t, forked.Equals(sm.LoadState(stateDB)))
+	assert.Equal(t, state.AppHash, forked.AppHash)
+	assert.EqualValues(t, 3, forked.LastHeightValidatorsChanged)
+
+	vals, err := sm.LoadValidators(stateDB, 2)
+	require.NoError(t, err)
+	assert.Equal(t, liveVals.Hash(), vals.Hash())
+	for _, height := range []int64{3, 4} {
+		vals, err = sm.LoadValidators(stateDB, height)
+		require.NoError(t, err)
+		assert.Equal(t, sandboxVals.Hash(), vals.Hash(), "height %d", height)
+	}
+
+	// the chain continues with the last commit of the live validators
+	block, parts := forked.MakeBlock(3, makeTxs(3), lastCommit, nil, pk.PubKey().Address())
+	blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+	state, err = blockExec.ApplyBlock(forked, blockID, block)
+	require.NoError(t, err)
+	privVals[pk.PubKey().Address().String()] = sandboxPV
+	_, err = makeValidCommit(3, blockID, state.LastValidators, privVals)
+	require.NoError(t, err)
+}
@@ -246,7 +246,11 @@ type testApp struct {
 
 	CommitVotes         []abci.VoteInfo
 	ByzantineValidators []abci.Evidence
+	TimeInfo            *abci.BlockTimeInfo
+	RejectedUpdates     *abci.RejectedValidatorUpdates
 	ValidatorUpdates    []abci.ValidatorUpdate
+	VoteExtension       []byte
+	VoteExtensions      []abci.VoteExtension
 }
 
 var _ abci.Application = (*testApp)(nil)
@@ -258,6 +262,8 @@ func (app *testApp) Info(req abci.RequestInfo) (resInfo abci.ResponseInfo) {
 func (app *testApp) BeginBlock(req abci.RequestBeginBlock) abci.ResponseBeginBlock {
 	app.CommitVotes = req.LastCommitInfo.Votes
 	app.ByzantineValidators = req.ByzantineValidators
+	app.TimeInfo = req.TimeInfo
+	app.RejectedUpdates = req.RejectedValidatorUpdates
 	return abci.ResponseBeginBlock{}
 }
 
@@ -265,6 +271,15 @@ func (app *testApp) EndBlock(req abci.RequestEndBlock) abci.ResponseEndBlock {
 	return abci.ResponseEndBlock{ValidatorUpdates: app.ValidatorUpdates}
 }
 
+func (app *testApp) ExtendVote(req abci.RequestExtendVote) abci.ResponseExtendVote {
+	return abci.ResponseExtendVote{VoteExtension: app.VoteExtension}
+}
+
+func (app *testApp) DeliverVoteExtensions(req abci.RequestDeliverVoteExtensions) abci.ResponseDeliverVoteExtensions {
+	app.VoteExtensions = req.VoteExtensions
+	return abci.ResponseDeliverVoteExtensions{}
+}
+
 func (app *testApp) DeliverTx(req abci.RequestDeliverTx) abci.ResponseDeliverTx {
 	return abci.ResponseDeliverTx{Events: []abci.Event{}}
 }
@@ -0,0 +1,296 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	dbm "github.com/tendermint/tm-db"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	heightPrefix    = "height/"
+	heightPrefixEnd = "height0" // heightPrefix with its last byte incremented
+	eventPrefix     = "event/"
+)
+
+// BlockerIndexer implements a block indexer, indexing BeginBlock and EndBlock
+// events with an underlying KV store. Block events are stored by height, and
+// events with string values are additionally indexed by their composite key
+// and value to speed up equality searches.
+type BlockerIndexer struct {
+	store        dbm.DB
+	tagsToIndex  []string
+	indexAllTags bool
+}
+
+// New returns a new BlockerIndexer. By default only the block height is
+// indexed, which makes blocks searchable by "block.height" only.
+func New(store dbm.DB, options ...func(*BlockerIndexer)) *BlockerIndexer {
+	idx := &BlockerIndexer{store: store, tagsToIndex: make([]string, 0)}
+	for _, o := range options {
+		o(idx)
+	}
+	return idx
+}
+
+// IndexTags is an option for setting which events to index.
+func IndexTags(tags []string) func(*BlockerIndexer) {
+	return func(idx *BlockerIndexer) {
+		idx.tagsToIndex = tags
+	}
+}
+
+// IndexAllTags is an option for indexing all events.
+func IndexAllTags() func(*BlockerIndexer) {
+	return func(idx *BlockerIndexer) {
+		idx.indexAllTags = true
+	}
+}
+
+// Has returns true if the given height has been indexed. An error is returned
+// upon database query failure.
+func (idx *BlockerIndexer) Has(height int64) (bool, error) {
+	return idx.store.Has(heightKey(height)), nil
+}
+
+// Index indexes BeginBlock and EndBlock events for a given block by its height.
+// The following is indexed:
+//
+// primary key: encode(block.height | height) => encode(events)
+// BeginBlock/EndBlock events: encode(eventType.eventAttr|eventValue|height) => encode(height)
+func (idx *BlockerIndexer) Index(bh types.EventDataNewBlockHeader) error {
+	batch := idx.store.NewBatch()
+	defer batch.Close()
+
+	height := bh.Header.Height
+	events := map[string][]string{
+		types.BlockHeightKey: {strconv.FormatInt(height, 10)},
+	}
+
+	for _, event := range append(bh.ResultBeginBlock.Events, bh.ResultEndBlock.Events...) {
+		// only index events with a non-empty type
+		if len(event.Type) == 0 {
+			continue
+		}
+
+		for _, attr := range event.Attributes {
+			if len(attr.Key) == 0 {
+				continue
+			}
+
+			compositeTag := fmt.Sprintf("%s.%s", event.Type, string(attr.Key))
+			if compositeTag == types.BlockHeightKey {
+				continue
+			}
+			if idx.indexAllTags || cmn.StringInSlice(compositeTag, idx.tagsToIndex) {
+				events[compositeTag] = append(events[compositeTag], string(attr.Value))
+				batch.Set(eventKey(compositeTag, string(attr.Value), height), heightKey(height))
+			}
+		}
+	}
+
+	rawBytes, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	batch.Set(heightKey(height), rawBytes)
+
+	batch.Write()
+	return nil
+}
+
+// Search performs a query for block heights that match a given BeginBlock
+// and Endblock event search criteria. The given query can match against zero,
+// one or more block heights. In the case of height queries, i.e. block.height=H,
+// if the height is indexed, that height alone will be returned. An error and
+// nil slice is returned. Otherwise, a non-nil slice and nil error is returned.
+//
+// Candidate heights are narrowed down using a height or string equality
+// condition if there is one, or a range of heights otherwise; the full query
+// is then matched against the events stored for each candidate.
+func (idx *BlockerIndexer) Search(ctx context.Context, q *query.Query) ([]int64, error) {
+	conditions, err := q.Conditions()
+	if err != nil {
+		return nil, errors.Wrap(err, "error during parsing conditions from query")
+	}
+
+	var candidates []int64
+	if height, ok := lookForHeight(conditions); ok {
+		candidates = []int64{height}
+	} else if c, ok := lookForStringEquality(conditions); ok {
+		candidates, err = idx.heightsForEvent(ctx, c.Tag, c.Operand.(string))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		lower, upper, hasUpper := lookForHeightRange(conditions)
+		candidates, err = idx.heightsInRange(ctx, lower, upper, hasUpper)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]int64, 0, len(candidates))
+	for _, height := range candidates {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		rawBytes := idx.store.Get(heightKey(height))
+		if rawBytes == nil {
+			continue
+		}
+		events := make(map[string][]string)
+		if err := json.Unmarshal(rawBytes, &events); err != nil {
+			return nil, errors.Wrapf(err, "error reading events of block %d", height)
+		}
+		match, err := q.Matches(events)
+		if err != nil {
+			return nil, errors.Wrap(err, "error matching block events")
+		}
+		if match {
+			results = append(results, height)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i] < results[j] })
+	return results, nil
+}
+
+func (idx *BlockerIndexer) heightsForEvent(ctx context.Context, tag, value string) ([]int64, error) {
+	it := dbm.IteratePrefix(idx.store, []byte(eventPrefix+tag+"/"+value+"/"))
+	defer it.Close()
+
+	seen := make(map[int64]bool)
+	heights := make([]int64, 0)
+	for ; it.Valid(); it.Next() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		height, err := parseHeightKey(it.Value())
+		if err != nil {
+			return nil, err
+		}
+		if !seen[height] {
+			seen[height] = true
+			heights = append(heights, height)
+		}
+	}
+	return heights, nil
+}
+
+func (idx *BlockerIndexer) heightsInRange(ctx context.Context, lower, upper int64, hasUpper bool) ([]int64, error) {
+	if hasUpper && upper < lower {
+		return []int64{}, nil
+	}
+
+	var end []byte
+	if hasUpper {
+		end = heightKey(upper + 1)
+	} else {
+		end = []byte(heightPrefixEnd)
+	}
+	it := idx.store.Iterator(heightKey(lower), end)
+	defer it.Close()
+
+	heights := make([]int64, 0)
+	for ; it.Valid(); it.Next() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		height, err := parseHeightKey(it.Key())
+		if err != nil {
+			return nil, err
+		}
+		heights = append(heights, height)
+	}
+	return heights, nil
+}
+
+// lookForHeight returns the height of a "block.height = H" condition.
+func lookForHeight(conditions []query.Condition) (int64, bool) {
+	for _, c := range conditions {
+		if c.Tag == types.BlockHeightKey && c.Op == query.OpEqual {
+			if height, ok := c.Operand.(int64); ok {
+				return height, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// lookForStringEquality returns the first equality condition on an event with
+// a string operand.
+func lookForStringEquality(conditions []query.Condition) (query.Condition, bool) {
+	for _, c := range conditions {
+		if c.Tag == types.BlockHeightKey || c.Op != query.OpEqual {
+			continue
+		}
+		if _, ok := c.Operand.(string); ok {
+			return c, true
+		}
+	}
+	return query.Condition{}, false
+}
+
+// lookForHeightRange returns the inclusive bounds set on block.height by the
+// conditions.
+func lookForHeightRange(conditions []query.Condition) (lower, upper int64, hasUpper bool) {
+	lower = 1
+	for _, c := range conditions {
+		if c.Tag != types.BlockHeightKey {
+			continue
+		}
+		v, ok := c.Operand.(int64)
+		if !ok {
+			continue
+		}
+		switch c.Op {
+		case query.OpGreater:
+			lower = cmn.MaxInt64(lower, v+1)
+		case query.OpGreaterEqual:
+			lower = cmn.MaxInt64(lower, v)
+		case query.OpLess:
+			if !hasUpper || v-1 < upper {
+				upper, hasUpper = v-1, true
+			}
+		case query.OpLessEqual:
+			if !hasUpper || v < upper {
+				upper, hasUpper = v, true
+			}
+		}
+	}
+	return lower, upper, hasUpper
+}
+
+func heightKey(height int64) []byte {
+	return []byte(fmt.Sprintf("%s%020d", heightPrefix, height))
+}
+
+func eventKey(compositeTag, value string, height int64) []byte {
+	return []byte(fmt.Sprintf("%s%s/%s/%020d", eventPrefix, compositeTag, value, height))
+}
+
+func parseHeightKey(key []byte) (int64, error) {
+	if len(key) <= len(heightPrefix) {
+		return 0, fmt.Errorf("invalid height key %q", key)
+	}
+	return strconv.ParseInt(string(key[len(heightPrefix):]), 10, 64)
+}
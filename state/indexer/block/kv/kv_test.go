@@ -0,0 +1,135 @@
+package kv_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	db "github.com/tendermint/tm-db"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	blockidxkv "github.com/tendermint/tendermint/state/indexer/block/kv"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestBlockIndexer(t *testing.T) {
+	indexer := blockidxkv.New(db.NewMemDB(), blockidxkv.IndexAllTags())
+
+	require.NoError(t, indexer.Index(types.EventDataNewBlockHeader{
+		Header: types.Header{Height: 1},
+		ResultBeginBlock: abci.ResponseBeginBlock{
+			Events: []abci.Event{
+				{
+					Type: "begin_event",
+					Attributes: []cmn.KVPair{
+						{Key: []byte("proposer"), Value: []byte("FCAA001")},
+					},
+				},
+			},
+		},
+		ResultEndBlock: abci.ResponseEndBlock{
+			Events: []abci.Event{
+				{
+					Type: "end_event",
+					Attributes: []cmn.KVPair{
+						{Key: []byte("foo"), Value: []byte("100")},
+					},
+				},
+			},
+		},
+	}))
+
+	for i := 2; i < 12; i++ {
+		var events []abci.Event
+		if i%2 == 0 {
+			events = []abci.Event{
+				{
+					Type: "end_event",
+					Attributes: []cmn.KVPair{
+						{Key: []byte("foo"), Value: []byte(fmt.Sprintf("%d", i))},
+					},
+				},
+			}
+		}
+		require.NoError(t, indexer.Index(types.EventDataNewBlockHeader{
+			Header: types.Header{Height: int64(i)},
+			ResultBeginBlock: abci.ResponseBeginBlock{
+				Events: []abci.Event{
+					{
+						Type: "begin_event",
+						Attributes: []cmn.KVPair{
+							{Key: []byte("proposer"), Value: []byte("FCAA001")},
+						},
+					},
+				},
+			},
+			ResultEndBlock: abci.ResponseEndBlock{Events: events},
+		}))
+	}
+
+	ok, err := indexer.Has(5)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = indexer.Has(12)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	testCases := map[string]struct {
+		q       *query.Query
+		results []int64
+	}{
+		"block.height = 100": {
+			q:       query.MustParse("block.height = 100"),
+			results: []int64{},
+		},
+		"block.height = 5": {
+			q:       query.MustParse("block.height = 5"),
+			results: []int64{5},
+		},
+		"begin_event.key1 = 'value1'": {
+			q:       query.MustParse("begin_event.key1 = 'value1'"),
+			results: []int64{},
+		},
+		"begin_event.proposer = 'FCAA001'": {
+			q:       query.MustParse("begin_event.proposer = 'FCAA001'"),
+			results: []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+		},
+		"end_event.foo <= 5": {
+			q:       query.MustParse("end_event.foo <= 5"),
+			results: []int64{2, 4},
+		},
+		"end_event.foo >= 100": {
+			q:       query.MustParse("end_event.foo >= 100"),
+			results: []int64{1},
+		},
+		"block.height > 2 AND end_event.foo <= 8": {
+			q:       query.MustParse("block.height > 2 AND end_event.foo <= 8"),
+			results: []int64{4, 6, 8},
+		},
+		"block.height <= 3 AND begin_event.proposer = 'FCAA001'": {
+			q:       query.MustParse("block.height <= 3 AND begin_event.proposer = 'FCAA001'"),
+			results: []int64{1, 2, 3},
+		},
+		"begin_event.proposer CONTAINS 'FFFFFFF'": {
+			q:       query.MustParse("begin_event.proposer CONTAINS 'FFFFFFF'"),
+			results: []int64{},
+		},
+		"begin_event.proposer CONTAINS 'FCAA'": {
+			q:       query.MustParse("block.height < 4 AND begin_event.proposer CONTAINS 'FCAA'"),
+			results: []int64{1, 2, 3},
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			results, err := indexer.Search(context.Background(), tc.q)
+			require.NoError(t, err)
+			require.Equal(t, tc.results, results)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/types"
+)
+
+// EventSinkType is the type of an EventSink, as used in the tx_index.indexer
+// config option.
+type EventSinkType string
+
+const (
+	NULL EventSinkType = "null"
+	KV   EventSinkType = "kv"
+	PSQL EventSinkType = "psql"
+)
+
+// EventSink interface is defined the APIs for the IndexerService to interact
+// with the data store, including the block/transaction indexing and the
+// search functions.
+//
+// The IndexerService will accept a list of one or more EventSink types. During
+// the OnStart method it will call the appropriate APIs on each EventSink to
+// index both block and transaction events.
+type EventSink interface {
+
+	// IndexBlockEvents indexes the BeginBlock and EndBlock events of a block.
+	IndexBlockEvents(types.EventDataNewBlockHeader) error
+
+	// IndexTxEvents indexes the results (and events) of the transactions of
+	// a block.
+	IndexTxEvents([]*types.TxResult) error
+
+	// SearchBlockEvents returns the heights of the blocks whose BeginBlock or
+	// EndBlock events match the query.
+	SearchBlockEvents(context.Context, *query.Query) ([]int64, error)
+
+	// SearchTxEvents returns the transactions matching the query.
+	SearchTxEvents(context.Context, *query.Query) ([]*types.TxResult, error)
+
+	// GetTxByHash returns the transaction specified by hash or nil if the
+	// transaction is not indexed.
+	GetTxByHash([]byte) (*types.TxResult, error)
+
+	// HasBlock checks if the events of the block at the given height have
+	// been indexed.
+	HasBlock(int64) (bool, error)
+
+	// Type returns the type of the event sink.
+	Type() EventSinkType
+
+	// Stop releases the resources held by the event sink.
+	Stop() error
+}
+
+// ErrSearchNotSupported is returned by event sinks for the searches they don't
+// support, which must be run against their database directly (e.g. the block
+// searches of the PostgreSQL sink).
+var ErrSearchNotSupported = errors.New("search is not supported by this event sink")
+
+// IndexingEnabled returns true if at least one sink other than the null sink
+// is configured.
+func IndexingEnabled(sinks []EventSink) bool {
+	for _, sink := range sinks {
+		if sink.Type() != NULL {
+			return true
+		}
+	}
+	return false
+}
+
+// KVSink returns the first key-value sink among sinks, which is the one used
+// to serve RPC queries, or nil if there is none.
+func KVSink(sinks []EventSink) EventSink {
+	return sinkOfType(sinks, KV)
+}
+
+// TxSink returns the sink used to serve the tx RPC queries (/tx, /tx_search):
+// the first key-value sink among sinks, else the first PostgreSQL sink, or nil
+// if there is none.
+func TxSink(sinks []EventSink) EventSink {
+	if sink := sinkOfType(sinks, KV); sink != nil {
+		return sink
+	}
+	return sinkOfType(sinks, PSQL)
+}
+
+func sinkOfType(sinks []EventSink, sinkType EventSinkType) EventSink {
+	for _, sink := range sinks {
+		if sink.Type() == sinkType {
+			return sink
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package indexer
+
+import (
+	"context"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	tmpubsub "github.com/tendermint/tendermint/libs/pubsub"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	subscriber = "IndexerService"
+)
+
+// IndexerService connects event bus and event sinks together in order
+// to index blocks and transactions coming from event bus.
+type IndexerService struct {
+	cmn.BaseService
+
+	eventSinks []EventSink
+	eventBus   *types.EventBus
+
+	// closed when indexRoutine returns
+	done chan struct{}
+}
+
+// NewIndexerService returns a new service instance.
+func NewIndexerService(es []EventSink, eventBus *types.EventBus) *IndexerService {
+	is := &IndexerService{eventSinks: es, eventBus: eventBus}
+	is.BaseService = *cmn.NewBaseService(nil, "IndexerService", is)
+	return is
+}
+
+// OnStart implements cmn.Service by subscribing for all blocks and
+// transactions and indexing them by events.
+func (is *IndexerService) OnStart() error {
+	// Use SubscribeUnbuffered here to ensure both subscriptions does not get
+	// cancelled due to not pulling messages fast enough. Cause this might
+	// sometimes happen when there are no other subscribers.
+
+	blockHeadersSub, err := is.eventBus.SubscribeUnbuffered(
+		context.Background(),
+		subscriber,
+		types.EventQueryNewBlockHeader)
+	if err != nil {
+		return err
+	}
+
+	txsSub, err := is.eventBus.SubscribeUnbuffered(context.Background(), subscriber, types.EventQueryTx)
+	if err != nil {
+		return err
+	}
+
+	is.done = make(chan struct{})
+	go is.indexRoutine(blockHeadersSub, txsSub)
+	return nil
+}
+
+// indexRoutine indexes the blocks and their txs in the event sinks, until the
+// subscriptions are cancelled or the service is stopped.
+func (is *IndexerService) indexRoutine(blockHeadersSub, txsSub types.Subscription) {
+	defer close(is.done)
+
+	for {
+		var msg tmpubsub.Message
+		select {
+		case msg = <-blockHeadersSub.Out():
+		case <-blockHeadersSub.Cancelled():
+			return
+		case <-is.Quit():
+			return
+		}
+		eventDataHeader := msg.Data().(types.EventDataNewBlockHeader)
+		height := eventDataHeader.Header.Height
+		numTxs := eventDataHeader.Header.NumTxs
+
+		txResults := make([]*types.TxResult, numTxs)
+		for i := int64(0); i < numTxs; i++ {
+			select {
+			case msg2 := <-txsSub.Out():
+				txResult := msg2.Data().(types.EventDataTx).TxResult
+				txResults[i] = &txResult
+			case <-txsSub.Cancelled():
+				return
+			case <-is.Quit():
+				return
+			}
+		}
+
+		for _, sink := range is.eventSinks {
+			if err := sink.IndexBlockEvents(eventDataHeader); err != nil {
+				is.Logger.Error("Failed to index block events", "sink", sink.Type(), "height", height, "err", err)
+			} else {
+				is.Logger.Debug("Indexed block events", "sink", sink.Type(), "height", height)
+			}
+
+			if len(txResults) > 0 {
+				if err := sink.IndexTxEvents(txResults); err != nil {
+					is.Logger.Error("Failed to index block txs", "sink", sink.Type(), "height", height, "err", err)
+				} else {
+					is.Logger.Debug("Indexed block txs", "sink", sink.Type(), "height", height, "num_txs", numTxs)
+				}
+			}
+		}
+		is.Logger.Info("Indexed block", "height", height)
+	}
+}
+
+// OnStop implements cmn.Service by unsubscribing from all transactions,
+// waiting for the block being indexed, and then stopping the event sinks.
+func (is *IndexerService) OnStop() {
+	if is.eventBus.IsRunning() {
+		_ = is.eventBus.UnsubscribeAll(context.Background(), subscriber)
+	}
+	// the subscriptions are cancelled by now, either unsubscribed or by the
+	// event bus stopping
+	if is.done != nil {
+		<-is.done
+	}
+
+	for _, sink := range is.eventSinks {
+		if err := sink.Stop(); err != nil {
+			is.Logger.Error("Failed to stop event sink", "sink", sink.Type(), "err", err)
+		}
+	}
+}
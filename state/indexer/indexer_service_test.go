@@ -0,0 +1,148 @@
+package indexer_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/indexer"
+	blockidxkv "github.com/tendermint/tendermint/state/indexer/block/kv"
+	kvsink "github.com/tendermint/tendermint/state/indexer/sink/kv"
+	nullsink "github.com/tendermint/tendermint/state/indexer/sink/null"
+	"github.com/tendermint/tendermint/state/txindex/kv"
+	"github.com/tendermint/tendermint/types"
+	db "github.com/tendermint/tm-db"
+)
+
+func TestIndexerServiceIndexesBlocks(t *testing.T) {
+	// event bus
+	eventBus := types.NewEventBus()
+	eventBus.SetLogger(log.TestingLogger())
+	err := eventBus.Start()
+	require.NoError(t, err)
+	defer eventBus.Stop()
+
+	// event sink
+	store := db.NewMemDB()
+	sink := kvsink.NewEventSink(kv.NewTxIndex(store, kv.IndexAllTags()), blockidxkv.New(db.NewMemDB()))
+
+	service := indexer.NewIndexerService([]indexer.EventSink{sink}, eventBus)
+	service.SetLogger(log.TestingLogger())
+	err = service.Start()
+	require.NoError(t, err)
+	defer service.Stop()
+
+	// publish block with txs
+	eventBus.PublishEventNewBlockHeader(types.EventDataNewBlockHeader{
+		Header: types.Header{Height: 1, NumTxs: 2},
+	})
+	txResult1 := &types.TxResult{
+		Height: 1,
+		Index:  uint32(0),
+		Tx:     types.Tx("foo"),
+		Result: abci.ResponseDeliverTx{Code: 0},
+	}
+	eventBus.PublishEventTx(types.EventDataTx{TxResult: *txResult1})
+	txResult2 := &types.TxResult{
+		Height: 1,
+		Index:  uint32(1),
+		Tx:     types.Tx("bar"),
+		Result: abci.ResponseDeliverTx{Code: 0},
+	}
+	eventBus.PublishEventTx(types.EventDataTx{TxResult: *txResult2})
+
+	time.Sleep(100 * time.Millisecond)
+
+	// check the result
+	res, err := sink.GetTxByHash(types.Tx("foo").Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, txResult1, res)
+	res, err = sink.GetTxByHash(types.Tx("bar").Hash())
+	assert.NoError(t, err)
+	assert.Equal(t, txResult2, res)
+
+	ok, err := sink.HasBlock(1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	heights, err := sink.SearchBlockEvents(context.Background(), query.MustParse("block.height = 1"))
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{1}, heights)
+}
+
+// slowSink blocks in IndexBlockEvents until released, and records whether it
+// was stopped while indexing.
+type slowSink struct {
+	indexer.EventSink
+	indexing chan struct{}
+	release  chan struct{}
+
+	mtx             sync.Mutex
+	busy            bool
+	stoppedWhenBusy bool
+}
+
+func (s *slowSink) IndexBlockEvents(types.EventDataNewBlockHeader) error {
+	s.mtx.Lock()
+	s.busy = true
+	s.mtx.Unlock()
+	close(s.indexing)
+	<-s.release
+	s.mtx.Lock()
+	s.busy = false
+	s.mtx.Unlock()
+	return nil
+}
+
+func (s *slowSink) Stop() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.stoppedWhenBusy = s.busy
+	return nil
+}
+
+func TestIndexerServiceStopWaitsForIndexing(t *testing.T) {
+	eventBus := types.NewEventBus()
+	eventBus.SetLogger(log.TestingLogger())
+	require.NoError(t, eventBus.Start())
+	defer eventBus.Stop()
+
+	sink := &slowSink{
+		EventSink: nullsink.NewEventSink(),
+		indexing:  make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+	service := indexer.NewIndexerService([]indexer.EventSink{sink}, eventBus)
+	service.SetLogger(log.TestingLogger())
+	require.NoError(t, service.Start())
+
+	go eventBus.PublishEventNewBlockHeader(types.EventDataNewBlockHeader{
+		Header: types.Header{Height: 1},
+	})
+	<-sink.indexing
+
+	// the sink is only stopped once it's done indexing the block
+	stopped := make(chan struct{})
+	go func() {
+		service.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		require.Fail(t, "Stopped while indexing")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(sink.release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		require.Fail(t, "Not stopped after indexing")
+	}
+	assert.False(t, sink.stoppedWhenBusy)
+}
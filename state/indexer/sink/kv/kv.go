@@ -0,0 +1,77 @@
+package kv
+
+import (
+	"context"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/indexer"
+	kvb "github.com/tendermint/tendermint/state/indexer/block/kv"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/types"
+)
+
+var _ indexer.EventSink = (*EventSink)(nil)
+
+// EventSink is the key-value implementation of indexer.EventSink. It indexes
+// transactions with a txindex.TxIndexer and block events with a
+// kvb.BlockerIndexer, and is the sink used to serve RPC queries.
+type EventSink struct {
+	txi txindex.TxIndexer
+	bi  *kvb.BlockerIndexer
+}
+
+// NewEventSink returns a new EventSink. txi is usually a kv.TxIndex, possibly
+// wrapped in a txindex.ReplicatedTxIndex.
+func NewEventSink(txi txindex.TxIndexer, bi *kvb.BlockerIndexer) *EventSink {
+	return &EventSink{
+		txi: txi,
+		bi:  bi,
+	}
+}
+
+// Type implements indexer.EventSink.
+func (kves *EventSink) Type() indexer.EventSinkType {
+	return indexer.KV
+}
+
+// IndexBlockEvents implements indexer.EventSink.
+func (kves *EventSink) IndexBlockEvents(bh types.EventDataNewBlockHeader) error {
+	return kves.bi.Index(bh)
+}
+
+// IndexTxEvents implements indexer.EventSink.
+func (kves *EventSink) IndexTxEvents(results []*types.TxResult) error {
+	b := txindex.NewBatch(int64(len(results)))
+	for _, r := range results {
+		if err := b.Add(r); err != nil {
+			return err
+		}
+	}
+	return kves.txi.AddBatch(b)
+}
+
+// SearchBlockEvents implements indexer.EventSink.
+func (kves *EventSink) SearchBlockEvents(ctx context.Context, q *query.Query) ([]int64, error) {
+	return kves.bi.Search(ctx, q)
+}
+
+// SearchTxEvents implements indexer.EventSink.
+func (kves *EventSink) SearchTxEvents(ctx context.Context, q *query.Query) ([]*types.TxResult, error) {
+	return kves.txi.Search(q)
+}
+
+// GetTxByHash implements indexer.EventSink.
+func (kves *EventSink) GetTxByHash(hash []byte) (*types.TxResult, error) {
+	return kves.txi.Get(hash)
+}
+
+// HasBlock implements indexer.EventSink.
+func (kves *EventSink) HasBlock(h int64) (bool, error) {
+	return kves.bi.Has(h)
+}
+
+// Stop implements indexer.EventSink. The underlying stores are owned and
+// closed by the node.
+func (kves *EventSink) Stop() error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package null
+
+import (
+	"context"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/indexer"
+	"github.com/tendermint/tendermint/types"
+)
+
+var _ indexer.EventSink = (*EventSink)(nil)
+
+// EventSink acts as a /dev/null.
+type EventSink struct{}
+
+// NewEventSink returns a new null EventSink.
+func NewEventSink() indexer.EventSink {
+	return &EventSink{}
+}
+
+// Type implements indexer.EventSink.
+func (nes *EventSink) Type() indexer.EventSinkType {
+	return indexer.NULL
+}
+
+// IndexBlockEvents is a noop and always returns nil.
+func (nes *EventSink) IndexBlockEvents(bh types.EventDataNewBlockHeader) error {
+	return nil
+}
+
+// IndexTxEvents is a noop and always returns nil.
+func (nes *EventSink) IndexTxEvents(results []*types.TxResult) error {
+	return nil
+}
+
+// SearchBlockEvents always returns no results.
+func (nes *EventSink) SearchBlockEvents(ctx context.Context, q *query.Query) ([]int64, error) {
+	return nil, nil
+}
+
+// SearchTxEvents always returns no results.
+func (nes *EventSink) SearchTxEvents(ctx context.Context, q *query.Query) ([]*types.TxResult, error) {
+	return nil, nil
+}
+
+// GetTxByHash always returns nil.
+func (nes *EventSink) GetTxByHash(hash []byte) (*types.TxResult, error) {
+	return nil, nil
+}
+
+// HasBlock always returns false.
+func (nes *EventSink) HasBlock(height int64) (bool, error) {
+	return false, nil
+}
+
+// Stop is a noop and always returns nil.
+func (nes *EventSink) Stop() error {
+	return nil
+}
@@ -0,0 +1,297 @@
+// Package psql implements an event sink backed by a PostgreSQL database.
+//
+// The sink serves the /tx and /tx_search RPC queries, from a read replica of
+// the database if one is set (see SetReadReplica), but not /block_search,
+// which should be run against the database directly. See schema.sql for the
+// table layout. The PostgreSQL driver is github.com/lib/pq.
+package psql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/lib/pq" // registers the "postgres" driver
+	"github.com/pkg/errors"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/indexer"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/types"
+)
+
+var _ indexer.EventSink = (*EventSink)(nil)
+
+const (
+	driverName = "postgres"
+
+	tableBlocks     = "blocks"
+	tableTxResults  = "tx_results"
+	tableEvents     = "events"
+	tableAttributes = "attributes"
+)
+
+// EventSink is an indexer backend providing the tx/block index services. This
+// implementation stores records in a PostgreSQL database using the schema
+// defined in schema.sql.
+type EventSink struct {
+	store   *sql.DB
+	chainID string
+
+	replica *sql.DB
+	reader  txindex.TxIndexer
+}
+
+// NewEventSink constructs an event sink associated with the PostgreSQL
+// database specified by connStr. Events written to the sink are attributed
+// to the specified chainID.
+func NewEventSink(connStr, chainID string) (*EventSink, error) {
+	db, err := sql.Open(driverName, connStr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open psql connection")
+	}
+	return &EventSink{
+		store:   db,
+		chainID: chainID,
+		reader:  &txReader{db: db, chainID: chainID},
+	}, nil
+}
+
+// SetReadReplica makes the sink read the transactions from the read replica
+// of its database specified by connStr (e.g. a streaming replica), unless the
+// replica lags more than maxLag blocks behind (0 - no limit).
+func (es *EventSink) SetReadReplica(connStr string, maxLag int64, logger log.Logger) error {
+	db, err := sql.Open(driverName, connStr)
+	if err != nil {
+		return errors.Wrap(err, "failed to open psql read replica connection")
+	}
+	replicated := txindex.NewReplicatedTxIndex(
+		&txReader{db: es.store, chainID: es.chainID},
+		&txReader{db: db, chainID: es.chainID},
+		maxLag,
+	)
+	replicated.SetLogger(logger)
+	es.replica, es.reader = db, replicated
+	return nil
+}
+
+// DB returns the underlying database handle.
+func (es *EventSink) DB() *sql.DB { return es.store }
+
+// Type returns the structure type for this sink, which is PSQL.
+func (es *EventSink) Type() indexer.EventSinkType { return indexer.PSQL }
+
+// runInTransaction executes query in a fresh database transaction.
+// If query reports an error, the transaction is rolled back and the
+// error from query is reported to the caller.
+// Otherwise, the result of committing the transaction is returned.
+func runInTransaction(db *sql.DB, query func(*sql.Tx) error) error {
+	dbtx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := query(dbtx); err != nil {
+		_ = dbtx.Rollback() // report the initial error, not the rollback
+		return err
+	}
+	return dbtx.Commit()
+}
+
+// queryWithID executes the specified SQL query with the given arguments,
+// expecting a single-row, single-column result containing an ID. If the query
+// succeeds, the ID from the result is returned.
+func queryWithID(tx *sql.Tx, query string, args ...interface{}) (uint32, error) {
+	var id uint32
+	if err := tx.QueryRow(query, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// insertEvents inserts a slice of events and any indexed attributes of those
+// events into the database associated with dbtx.
+//
+// If txID > 0, the event is attributed to the transaction with that
+// ID; otherwise it is recorded as a block event.
+func insertEvents(dbtx *sql.Tx, blockID, txID uint32, evts []abci.Event) error {
+	// Populate the transaction ID field iff one is defined (> 0).
+	var txIDArg interface{}
+	if txID > 0 {
+		txIDArg = txID
+	}
+
+	for _, evt := range evts {
+		// Skip events with an empty type.
+		if evt.Type == "" {
+			continue
+		}
+
+		eid, err := queryWithID(dbtx, fmt.Sprintf(`
+INSERT INTO %s (block_id, tx_id, type) VALUES ($1, $2, $3)
+  RETURNING rowid;
+`, tableEvents), blockID, txIDArg, evt.Type)
+		if err != nil {
+			return err
+		}
+
+		// Add any attributes flagged for indexing.
+		for _, attr := range evt.Attributes {
+			if len(attr.Key) == 0 {
+				continue
+			}
+			compositeKey := evt.Type + "." + string(attr.Key)
+			if _, err := dbtx.Exec(fmt.Sprintf(`
+INSERT INTO %s (event_id, key, composite_key, value)
+  VALUES ($1, $2, $3, $4);
+`, tableAttributes), eid, string(attr.Key), compositeKey, string(attr.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// makeIndexedEvent constructs an event from the specified composite key and
+// value. If the key has the form "type.name", the event will have a single
+// attribute with that name and the value; otherwise the event will have only
+// a type and no attributes.
+func makeIndexedEvent(compositeKey, value string) abci.Event {
+	for i := 0; i < len(compositeKey); i++ {
+		if compositeKey[i] == '.' {
+			return abci.Event{Type: compositeKey[:i], Attributes: []cmn.KVPair{
+				{Key: []byte(compositeKey[i+1:]), Value: []byte(value)},
+			}}
+		}
+	}
+	return abci.Event{Type: compositeKey}
+}
+
+// IndexBlockEvents indexes the specified block header, part of the
+// indexer.EventSink interface.
+func (es *EventSink) IndexBlockEvents(h types.EventDataNewBlockHeader) error {
+	ts := time.Now().UTC()
+
+	return runInTransaction(es.store, func(dbtx *sql.Tx) error {
+		// Add the block to the blocks table and report back its row ID for use
+		// in indexing the events for the block.
+		blockID, err := queryWithID(dbtx, fmt.Sprintf(`
+INSERT INTO %s (height, chain_id, created_at)
+  VALUES ($1, $2, $3)
+  ON CONFLICT DO NOTHING
+  RETURNING rowid;
+`, tableBlocks), h.Header.Height, es.chainID, ts)
+		if err == sql.ErrNoRows {
+			return nil // we already saw this block; quietly succeed
+		} else if err != nil {
+			return errors.Wrap(err, "indexing block header")
+		}
+
+		// Insert the special block meta-event for height.
+		if err := insertEvents(dbtx, blockID, 0, []abci.Event{
+			makeIndexedEvent(types.BlockHeightKey, fmt.Sprint(h.Header.Height)),
+		}); err != nil {
+			return errors.Wrap(err, "block meta-events")
+		}
+		// Insert all the block events.
+		if err := insertEvents(dbtx, blockID, 0, h.ResultBeginBlock.Events); err != nil {
+			return errors.Wrap(err, "begin-block events")
+		}
+		if err := insertEvents(dbtx, blockID, 0, h.ResultEndBlock.Events); err != nil {
+			return errors.Wrap(err, "end-block events")
+		}
+		return nil
+	})
+}
+
+// IndexTxEvents indexes the specified transaction results, part of the
+// indexer.EventSink interface.
+func (es *EventSink) IndexTxEvents(txrs []*types.TxResult) error {
+	ts := time.Now().UTC()
+
+	for _, txr := range txrs {
+		// Encode the result message in amino binary format for storage.
+		resultData, err := types.GetCodec().MarshalBinaryBare(txr)
+		if err != nil {
+			return errors.Wrap(err, "marshaling tx_result")
+		}
+
+		// Index the hash of the underlying transaction as a hex string.
+		txHash := fmt.Sprintf("%X", txr.Tx.Hash())
+
+		if err := runInTransaction(es.store, func(dbtx *sql.Tx) error {
+			// Find the block associated with this transaction. The block header
+			// must have been indexed prior to the transactions belonging to it.
+			blockID, err := queryWithID(dbtx, fmt.Sprintf(`
+SELECT rowid FROM %s WHERE height = $1 AND chain_id = $2;
+`, tableBlocks), txr.Height, es.chainID)
+			if err != nil {
+				return errors.Wrap(err, "finding block ID")
+			}
+
+			// Insert a record for this tx_result and capture its ID for indexing events.
+			txID, err := queryWithID(dbtx, fmt.Sprintf(`
+INSERT INTO %s (block_id, index, created_at, tx_hash, tx_result)
+  VALUES ($1, $2, $3, $4, $5)
+  ON CONFLICT DO NOTHING
+  RETURNING rowid;
+`, tableTxResults), blockID, txr.Index, ts, txHash, resultData)
+			if err == sql.ErrNoRows {
+				return nil // we already saw this transaction; quietly succeed
+			} else if err != nil {
+				return errors.Wrap(err, "indexing tx_result")
+			}
+
+			// Insert the special transaction meta-events for hash and height.
+			if err := insertEvents(dbtx, blockID, txID, []abci.Event{
+				makeIndexedEvent(types.TxHashKey, txHash),
+				makeIndexedEvent(types.TxHeightKey, fmt.Sprint(txr.Height)),
+			}); err != nil {
+				return errors.Wrap(err, "indexing transaction meta-events")
+			}
+			// Index any events packaged with the transaction.
+			if err := insertEvents(dbtx, blockID, txID, txr.Result.Events); err != nil {
+				return errors.Wrap(err, "indexing transaction events")
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchBlockEvents is not implemented by this sink, and reports an error for all queries.
+func (es *EventSink) SearchBlockEvents(ctx context.Context, q *query.Query) ([]int64, error) {
+	return nil, indexer.ErrSearchNotSupported
+}
+
+// SearchTxEvents returns the transactions matching the query, part of the
+// indexer.EventSink interface. Conditions on dates and times aren't supported.
+func (es *EventSink) SearchTxEvents(ctx context.Context, q *query.Query) ([]*types.TxResult, error) {
+	return es.reader.Search(q)
+}
+
+// GetTxByHash returns the transaction with the hash, or nil if it isn't
+// indexed, part of the indexer.EventSink interface.
+func (es *EventSink) GetTxByHash(hash []byte) (*types.TxResult, error) {
+	return es.reader.Get(hash)
+}
+
+// HasBlock is not implemented by this sink, and reports an error for all queries.
+func (es *EventSink) HasBlock(h int64) (bool, error) {
+	return false, indexer.ErrSearchNotSupported
+}
+
+// Stop closes the underlying PostgreSQL database, and its read replica.
+func (es *EventSink) Stop() error {
+	if es.replica != nil {
+		if err := es.replica.Close(); err != nil {
+			return err
+		}
+	}
+	return es.store.Close()
+}
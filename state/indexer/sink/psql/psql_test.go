@@ -0,0 +1,308 @@
+package psql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/types"
+)
+
+const testChainID = "test-chain"
+
+func TestMakeTxSearchQuery(t *testing.T) {
+	const (
+		selectTxs = "SELECT tx_result FROM tx_results JOIN blocks ON blocks.rowid = tx_results.block_id WHERE "
+		orderBy   = " ORDER BY height, index;"
+		condition = "tx_results.rowid IN (SELECT tx_id FROM event_attributes WHERE composite_key = "
+	)
+
+	testCases := []struct {
+		query string
+		where string
+		args  []interface{}
+	}{
+		{
+			"tx.height = 5",
+			"chain_id = $1 AND " + condition + "$2 AND " + numericValue + " = $3)",
+			[]interface{}{testChainID, "tx.height", int64(5)},
+		},
+		{
+			"account.owner = 'Ivan'",
+			"chain_id = $1 AND " + condition + "$2 AND value = $3)",
+			[]interface{}{testChainID, "account.owner", "Ivan"},
+		},
+		{
+			"account.owner CONTAINS 'Iv'",
+			"chain_id = $1 AND " + condition + "$2 AND strpos(value, $3) > 0)",
+			[]interface{}{testChainID, "account.owner", "Iv"},
+		},
+		{
+			"tx.height > 3 AND transfer.amount <= 1.5",
+			"chain_id = $1 AND " +
+				condition + "$2 AND " + numericValue + " > $3) AND " +
+				condition + "$4 AND " + numericValue + " <= $5)",
+			[]interface{}{testChainID, "tx.height", int64(3), "transfer.amount", 1.5},
+		},
+	}
+
+	for _, tc := range testCases {
+		sqlQuery, args, err := makeTxSearchQuery(testChainID, query.MustParse(tc.query))
+		require.NoError(t, err, tc.query)
+		assert.Equal(t, selectTxs+tc.where+orderBy, normalizeSQL(sqlQuery), tc.query)
+		assert.Equal(t, tc.args, args, tc.query)
+	}
+}
+
+func TestMakeTxSearchQueryUnsupported(t *testing.T) {
+	for _, q := range []string{
+		"tx.date = DATE 2013-05-03",
+		"tx.date > DATE 2013-05-03",
+		"tx.time >= TIME 2013-05-03T14:45:00Z",
+	} {
+		_, _, err := makeTxSearchQuery(testChainID, query.MustParse(q))
+		assert.Error(t, err, q)
+	}
+}
+
+func TestMakeIndexedEvent(t *testing.T) {
+	assert.Equal(t,
+		abci.Event{Type: "tx", Attributes: []cmn.KVPair{{Key: []byte("hash"), Value: []byte("AB")}}},
+		makeIndexedEvent("tx.hash", "AB"))
+	assert.Equal(t,
+		abci.Event{Type: "transfer", Attributes: []cmn.KVPair{{Key: []byte("to.id"), Value: []byte("1")}}},
+		makeIndexedEvent("transfer.to.id", "1"))
+	assert.Equal(t, abci.Event{Type: "height"}, makeIndexedEvent("height", "1"))
+}
+
+func TestIndexBlockEvents(t *testing.T) {
+	sink, rec := newRecordingEventSink(t)
+
+	err := sink.IndexBlockEvents(types.EventDataNewBlockHeader{
+		Header: types.Header{Height: 5},
+		ResultBeginBlock: abci.ResponseBeginBlock{Events: []abci.Event{
+			{Type: "begin", Attributes: []cmn.KVPair{{Key: []byte("proposer"), Value: []byte("FCAA")}}},
+		}},
+		ResultEndBlock: abci.ResponseEndBlock{Events: []abci.Event{
+			{Type: ""}, // skipped
+			{Type: "end", Attributes: []cmn.KVPair{
+				{Key: []byte(""), Value: []byte("skipped")},
+				{Key: []byte("size"), Value: []byte("10")},
+			}},
+		}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []statement{
+		{"BEGIN", nil},
+		{insertBlock, []driver.Value{int64(5), testChainID, anyTime}},
+		{insertEvent, []driver.Value{int64(1), nil, "block"}},
+		{insertAttribute, []driver.Value{int64(2), "height", "block.height", "5"}},
+		{insertEvent, []driver.Value{int64(1), nil, "begin"}},
+		{insertAttribute, []driver.Value{int64(3), "proposer", "begin.proposer", "FCAA"}},
+		{insertEvent, []driver.Value{int64(1), nil, "end"}},
+		{insertAttribute, []driver.Value{int64(4), "size", "end.size", "10"}},
+		{"COMMIT", nil},
+	}, rec.statements())
+}
+
+func TestIndexTxEvents(t *testing.T) {
+	sink, rec := newRecordingEventSink(t)
+
+	txr := &types.TxResult{
+		Height: 5,
+		Index:  2,
+		Tx:     types.Tx("HELLO WORLD"),
+		Result: abci.ResponseDeliverTx{Events: []abci.Event{
+			{Type: "account", Attributes: []cmn.KVPair{{Key: []byte("owner"), Value: []byte("Ivan")}}},
+		}},
+	}
+	require.NoError(t, sink.IndexTxEvents([]*types.TxResult{txr}))
+
+	data, err := types.GetCodec().MarshalBinaryBare(txr)
+	require.NoError(t, err)
+	hash := fmt.Sprintf("%X", txr.Tx.Hash())
+	assert.Equal(t, []statement{
+		{"BEGIN", nil},
+		{selectBlock, []driver.Value{int64(5), testChainID}},
+		{insertTxResult, []driver.Value{int64(1), int64(2), anyTime, hash, data}},
+		{insertEvent, []driver.Value{int64(1), int64(2), "tx"}},
+		{insertAttribute, []driver.Value{int64(3), "hash", "tx.hash", hash}},
+		{insertEvent, []driver.Value{int64(1), int64(2), "tx"}},
+		{insertAttribute, []driver.Value{int64(4), "height", "tx.height", "5"}},
+		{insertEvent, []driver.Value{int64(1), int64(2), "account"}},
+		{insertAttribute, []driver.Value{int64(5), "owner", "account.owner", "Ivan"}},
+		{"COMMIT", nil},
+	}, rec.statements())
+}
+
+func TestIndexBlockEventsRollback(t *testing.T) {
+	sink, rec := newRecordingEventSink(t)
+	rec.failOn = insertAttribute
+
+	err := sink.IndexBlockEvents(types.EventDataNewBlockHeader{Header: types.Header{Height: 5}})
+	require.Error(t, err)
+	stmts := rec.statements()
+	assert.Equal(t, "ROLLBACK", stmts[len(stmts)-1].query)
+}
+
+//-----------------------------------------------------------------------------
+// recording driver
+
+const (
+	insertBlock = "INSERT INTO blocks (height, chain_id, created_at) VALUES ($1, $2, $3) " +
+		"ON CONFLICT DO NOTHING RETURNING rowid;"
+	selectBlock    = "SELECT rowid FROM blocks WHERE height = $1 AND chain_id = $2;"
+	insertTxResult = "INSERT INTO tx_results (block_id, index, created_at, tx_hash, tx_result) " +
+		"VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING RETURNING rowid;"
+	insertEvent     = "INSERT INTO events (block_id, tx_id, type) VALUES ($1, $2, $3) RETURNING rowid;"
+	insertAttribute = "INSERT INTO attributes (event_id, key, composite_key, value) VALUES ($1, $2, $3, $4);"
+)
+
+// anyTime stands for the time arguments, which are replaced by it when
+// recorded.
+var anyTime = time.Time{}
+
+type statement struct {
+	query string
+	args  []driver.Value
+}
+
+// recorder is a database/sql driver recording the statements executed
+// against it. The queries return a single row, with a new ID.
+type recorder struct {
+	mtx    sync.Mutex
+	stmts  []statement
+	lastID int64
+	failOn string // query to fail
+}
+
+var recorders = struct {
+	sync.Mutex
+	byName map[string]*recorder
+}{byName: make(map[string]*recorder)}
+
+func init() {
+	sql.Register("psql-recorder", recordingDriver{})
+}
+
+// newRecordingEventSink returns an event sink writing to a new recorder.
+func newRecordingEventSink(t *testing.T) (*EventSink, *recorder) {
+	rec := &recorder{}
+	recorders.Lock()
+	recorders.byName[t.Name()] = rec
+	recorders.Unlock()
+
+	db, err := sql.Open("psql-recorder", t.Name())
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return &EventSink{store: db, chainID: testChainID, reader: &txReader{db: db, chainID: testChainID}}, rec
+}
+
+// record records the statement, and returns the ID of its row if it's a
+// query.
+func (r *recorder) record(query string, args []driver.Value, isQuery bool) (int64, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	query = normalizeSQL(query)
+	for i, arg := range args {
+		if _, ok := arg.(time.Time); ok {
+			args[i] = anyTime
+		}
+	}
+	r.stmts = append(r.stmts, statement{query, args})
+	if query == r.failOn {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if !isQuery {
+		return 0, nil
+	}
+	r.lastID++
+	return r.lastID, nil
+}
+
+func (r *recorder) statements() []statement {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.stmts
+}
+
+type recordingDriver struct{}
+
+func (recordingDriver) Open(name string) (driver.Conn, error) {
+	recorders.Lock()
+	defer recorders.Unlock()
+	return recordingConn{recorders.byName[name]}, nil
+}
+
+type recordingConn struct{ rec *recorder }
+
+func (c recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return recordingStmt{c.rec, query}, nil
+}
+func (c recordingConn) Close() error { return nil }
+func (c recordingConn) Begin() (driver.Tx, error) {
+	_, err := c.rec.record("BEGIN", nil, false)
+	return c, err
+}
+func (c recordingConn) Commit() error {
+	_, err := c.rec.record("COMMIT", nil, false)
+	return err
+}
+func (c recordingConn) Rollback() error {
+	_, err := c.rec.record("ROLLBACK", nil, false)
+	return err
+}
+
+type recordingStmt struct {
+	rec   *recorder
+	query string
+}
+
+func (s recordingStmt) Close() error  { return nil }
+func (s recordingStmt) NumInput() int { return -1 }
+func (s recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	_, err := s.rec.record(s.query, args, false)
+	return driver.RowsAffected(1), err
+}
+func (s recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	id, err := s.rec.record(s.query, args, true)
+	if err != nil {
+		return nil, err
+	}
+	return &idRows{id: id}, nil
+}
+
+// idRows is a single row with a single ID column.
+type idRows struct {
+	id   int64
+	done bool
+}
+
+func (r *idRows) Columns() []string { return []string{"rowid"} }
+func (r *idRows) Close() error      { return nil }
+func (r *idRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.id
+	return nil
+}
+
+// normalizeSQL collapses the whitespace of query.
+func normalizeSQL(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
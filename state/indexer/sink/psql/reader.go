@@ -0,0 +1,158 @@
+package psql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/types"
+)
+
+var (
+	_ txindex.TxIndexer      = (*txReader)(nil)
+	_ txindex.HeightReporter = (*txReader)(nil)
+)
+
+// errReadOnly is returned by the write methods of txReader: the sink writes
+// the transactions itself (see IndexTxEvents).
+var errReadOnly = errors.New("psql: the tx reader is read-only")
+
+// numericValue is the value of an attribute as a number, or NULL if it isn't
+// one, so that comparing it never fails.
+const numericValue = `(CASE WHEN value ~ '^-?[0-9]+(\.[0-9]+)?$' THEN value::numeric END)`
+
+// txReader reads the transactions indexed by the sink from db, which is the
+// database of the sink or a read replica of it. It implements the read
+// methods of txindex.TxIndexer, so that a txindex.ReplicatedTxIndex can pick
+// the database to read from.
+type txReader struct {
+	db      *sql.DB
+	chainID string
+}
+
+// AddBatch implements txindex.TxIndexer, and returns errReadOnly.
+func (r *txReader) AddBatch(b *txindex.Batch) error { return errReadOnly }
+
+// Index implements txindex.TxIndexer, and returns errReadOnly.
+func (r *txReader) Index(result *types.TxResult) error { return errReadOnly }
+
+// Get returns the transaction with the hash, or nil if it isn't indexed.
+func (r *txReader) Get(hash []byte) (*types.TxResult, error) {
+	var data []byte
+	err := r.db.QueryRow(fmt.Sprintf(`
+SELECT tx_result FROM %[1]s JOIN %[2]s ON %[2]s.rowid = %[1]s.block_id
+  WHERE chain_id = $1 AND tx_hash = $2;
+`, tableTxResults, tableBlocks), r.chainID, fmt.Sprintf("%X", hash)).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "getting tx_result")
+	}
+	return decodeTxResult(data)
+}
+
+// Search returns the transactions matching the query, ordered by height and
+// index. Conditions on dates and times aren't supported.
+func (r *txReader) Search(q *query.Query) ([]*types.TxResult, error) {
+	sqlQuery, args, err := makeTxSearchQuery(r.chainID, q)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "searching tx_results")
+	}
+	defer rows.Close()
+
+	results := make([]*types.TxResult, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, errors.Wrap(err, "searching tx_results")
+		}
+		txr, err := decodeTxResult(data)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, txr)
+	}
+	return results, errors.Wrap(rows.Err(), "searching tx_results")
+}
+
+// IndexedHeight returns the height of the last indexed block, or 0 if no
+// block is indexed. Implements txindex.HeightReporter.
+func (r *txReader) IndexedHeight() (int64, error) {
+	var height int64
+	err := r.db.QueryRow(fmt.Sprintf(`
+SELECT COALESCE(MAX(height), 0) FROM %s WHERE chain_id = $1;
+`, tableBlocks), r.chainID).Scan(&height)
+	return height, errors.Wrap(err, "getting the indexed height")
+}
+
+// makeTxSearchQuery returns the SQL query, and its arguments, selecting the
+// results of the transactions of chainID matching all the conditions of q.
+func makeTxSearchQuery(chainID string, q *query.Query) (string, []interface{}, error) {
+	conditions, err := q.Conditions()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "error during parsing conditions from query")
+	}
+
+	args := []interface{}{chainID}
+	where := []string{"chain_id = $1"}
+	for _, c := range conditions {
+		args = append(args, c.Tag)
+		keyArg := len(args)
+
+		var match string
+		switch operand := c.Operand.(type) {
+		case string:
+			args = append(args, operand)
+			switch c.Op {
+			case query.OpEqual:
+				match = fmt.Sprintf("value = $%d", len(args))
+			case query.OpContains:
+				match = fmt.Sprintf("strpos(value, $%d) > 0", len(args))
+			default:
+				return "", nil, fmt.Errorf("psql: unsupported operator for a string in the condition on %s", c.Tag)
+			}
+		case int64, float64:
+			args = append(args, operand)
+			op, ok := sqlOperators[c.Op]
+			if !ok {
+				return "", nil, fmt.Errorf("psql: unsupported operator for a number in the condition on %s", c.Tag)
+			}
+			match = fmt.Sprintf("%s %s $%d", numericValue, op, len(args))
+		default:
+			return "", nil, fmt.Errorf("psql: search on %T operands is not supported", operand)
+		}
+		where = append(where, fmt.Sprintf(
+			"%s.rowid IN (SELECT tx_id FROM event_attributes WHERE composite_key = $%d AND %s)",
+			tableTxResults, keyArg, match))
+	}
+
+	return fmt.Sprintf(`
+SELECT tx_result FROM %[1]s JOIN %[2]s ON %[2]s.rowid = %[1]s.block_id
+  WHERE %[3]s
+  ORDER BY height, index;
+`, tableTxResults, tableBlocks, strings.Join(where, " AND ")), args, nil
+}
+
+var sqlOperators = map[query.Operator]string{
+	query.OpLessEqual:    "<=",
+	query.OpGreaterEqual: ">=",
+	query.OpLess:         "<",
+	query.OpGreater:      ">",
+	query.OpEqual:        "=",
+}
+
+func decodeTxResult(data []byte) (*types.TxResult, error) {
+	txr := new(types.TxResult)
+	if err := types.GetCodec().UnmarshalBinaryBare(data, txr); err != nil {
+		return nil, errors.Wrap(err, "decoding tx_result")
+	}
+	return txr, nil
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"github.com/tendermint/tendermint/types"
+)
+
+// BlockListener is notified of every block applied by the BlockExecutor, with
+// its results, e.g. to stream the state changes to an external indexer (see
+// the state/streaming package) instead of having it poll the RPC.
+//
+// ListenBlock is called by ApplyBlock, once the state is saved and before the
+// events are fired: it must not block, and should hand the update to a slow
+// consumer in the background. An error is only logged. The update must not
+// be modified.
+type BlockListener interface {
+	ListenBlock(update BlockUpdate) error
+}
+
+// BlockUpdate is a block applied by the BlockExecutor, with its results.
+type BlockUpdate struct {
+	Block   *types.Block
+	BlockID types.BlockID
+
+	// ABCIResponses of the app to the block.
+	ABCIResponses *ABCIResponses
+	// ValidatorUpdates returned by EndBlock, which take effect at
+	// Block.Height + 2.
+	ValidatorUpdates []*types.Validator
+	// AppHash of the app after the block.
+	AppHash []byte
+}
+
+// BlockExecutorWithListeners makes ApplyBlock notify the listeners of every
+// block it applies, in order.
+func BlockExecutorWithListeners(listeners ...BlockListener) BlockExecutorOption {
+	return func(blockExec *BlockExecutor) {
+		blockExec.listeners = append(blockExec.listeners, listeners...)
+	}
+}
+
+// notifyListeners hands the block applied with its results to the listeners.
+func (blockExec *BlockExecutor) notifyListeners(update BlockUpdate) {
+	for _, l := range blockExec.listeners {
+		if err := l.ListenBlock(update); err != nil {
+			blockExec.logger.Error("Block listener failed", "height", update.Block.Height, "err", err)
+		}
+	}
+}
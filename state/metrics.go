@@ -17,6 +17,14 @@ const (
 type Metrics struct {
 	// Time between BeginBlock and EndBlock.
 	BlockProcessingTime metrics.Histogram
+	// Number of proposal blocks created with the txs reaped so far because
+	// reaping the mempool or PrepareProposal reached the create proposal
+	// deadline.
+	ProposalDeadlineExceeded metrics.Counter
+	// Height of the last block executed by the shadow app.
+	ShadowAppHeight metrics.Gauge
+	// Height of the block whose app hash the shadow app diverged at, if any.
+	ShadowAppDivergenceHeight metrics.Gauge
 }
 
 // PrometheusMetrics returns Metrics build using Prometheus client library.
@@ -35,12 +43,34 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Help:      "Time between BeginBlock and EndBlock in ms.",
 			Buckets:   stdprometheus.LinearBuckets(1, 10, 10),
 		}, labels).With(labelsAndValues...),
+		ProposalDeadlineExceeded: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "proposal_deadline_exceeded",
+			Help:      "Number of proposal blocks created with the txs reaped so far because reaping the mempool " +
+				"or PrepareProposal reached the deadline.",
+		}, labels).With(labelsAndValues...),
+		ShadowAppHeight: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "shadow_app_height",
+			Help:      "Height of the last block executed by the shadow app.",
+		}, labels).With(labelsAndValues...),
+		ShadowAppDivergenceHeight: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "shadow_app_divergence_height",
+			Help:      "Height of the block whose app hash the shadow app diverged at, if any.",
+		}, labels).With(labelsAndValues...),
 	}
 }
 
 // NopMetrics returns no-op Metrics.
 func NopMetrics() *Metrics {
 	return &Metrics{
-		BlockProcessingTime: discard.NewHistogram(),
+		BlockProcessingTime:       discard.NewHistogram(),
+		ProposalDeadlineExceeded:  discard.NewCounter(),
+		ShadowAppHeight:           discard.NewGauge(),
+		ShadowAppDivergenceHeight: discard.NewGauge(),
 	}
 }
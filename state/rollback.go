@@ -0,0 +1,85 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+
+	dbm "github.com/tendermint/tm-db"
+)
+
+// Rollback overwrites the state in db with the state of the previous height,
+// rebuilt from the block store and the validators and consensus params saved
+// in db. The block store and the app are left as they are: on restart, the
+// node executes the last block again against the app, e.g. after replacing a
+// non-deterministic version of the app which computed a wrong app hash.
+//
+// It returns the height and app hash of the rolled back state. If the state
+// is already one height behind the block store, it is left as it is.
+func Rollback(blockStore BlockStore, db dbm.DB) (int64, []byte, error) {
+	invalidState := LoadState(db)
+	if invalidState.IsEmpty() {
+		return -1, nil, errors.New("no state found")
+	}
+
+	height := blockStore.Height()
+	// The state and the block store aren't saved atomically: if the node
+	// stopped after saving the block but before saving the state, the state
+	// is already at the previous height.
+	if height == invalidState.LastBlockHeight+1 {
+		return invalidState.LastBlockHeight, invalidState.AppHash, nil
+	}
+	if height != invalidState.LastBlockHeight {
+		return -1, nil, fmt.Errorf("the state is at height %d, not one below or equal to the block store (%d)",
+			invalidState.LastBlockHeight, height)
+	}
+
+	rollbackHeight := invalidState.LastBlockHeight - 1
+	rollbackBlock := blockStore.LoadBlockMeta(rollbackHeight)
+	if rollbackBlock == nil {
+		return -1, nil, fmt.Errorf("block at height %d not found", rollbackHeight)
+	}
+	// The app hash and the results hash of a height are in the next header.
+	latestBlock := blockStore.LoadBlockMeta(invalidState.LastBlockHeight)
+	if latestBlock == nil {
+		return -1, nil, fmt.Errorf("block at height %d not found", invalidState.LastBlockHeight)
+	}
+
+	previousLastValidators, err := LoadValidators(db, rollbackHeight)
+	if err != nil {
+		return -1, nil, err
+	}
+	previousParams, err := LoadConsensusParams(db, rollbackHeight+1)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	// The validators or params may have changed with the rolled back height.
+	valsChangeHeight := invalidState.LastHeightValidatorsChanged
+	if valsChangeHeight > rollbackHeight {
+		valsChangeHeight = rollbackHeight + 1
+	}
+	paramsChangeHeight := invalidState.LastHeightConsensusParamsChanged
+	if paramsChangeHeight > rollbackHeight {
+		paramsChangeHeight = rollbackHeight + 1
+	}
+
+	rolledBackState := invalidState.Copy()
+	rolledBackState.LastBlockHeight = rollbackBlock.Header.Height
+	rolledBackState.LastBlockTotalTx = rollbackBlock.Header.TotalTxs
+	rolledBackState.LastBlockID = rollbackBlock.BlockID
+	rolledBackState.LastBlockTime = rollbackBlock.Header.Time
+
+	rolledBackState.NextValidators = invalidState.Validators
+	rolledBackState.Validators = invalidState.LastValidators
+	rolledBackState.LastValidators = previousLastValidators
+	rolledBackState.LastHeightValidatorsChanged = valsChangeHeight
+
+	rolledBackState.ConsensusParams = previousParams
+	rolledBackState.LastHeightConsensusParamsChanged = paramsChangeHeight
+
+	rolledBackState.LastResultsHash = latestBlock.Header.LastResultsHash
+	rolledBackState.AppHash = latestBlock.Header.AppHash
+
+	SaveState(db, rolledBackState)
+	return rolledBackState.LastBlockHeight, rolledBackState.AppHash, nil
+}
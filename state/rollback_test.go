@@ -0,0 +1,62 @@
+package state_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+func TestRollback(t *testing.T) {
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(kvstore.NewKVStoreApplication()))
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	state, stateDB, privVals := makeState(1, 1)
+	blockStore := store.NewBlockStore(dbm.NewMemDB())
+	blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+		mock.Mempool{}, sm.MockEvidencePool{})
+
+	height, appHash, err := sm.Rollback(blockStore, stateDB)
+	assert.Error(t, err, "nothing to roll back")
+	assert.EqualValues(t, -1, height)
+	assert.Nil(t, appHash)
+
+	lastCommit := types.NewCommit(types.BlockID{}, nil)
+	states := []sm.State{state}
+	for height := int64(1); height <= 3; height++ {
+		block, parts := state.MakeBlock(height, makeTxs(height), lastCommit, nil,
+			state.Validators.GetProposer().Address)
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+		commit, err := makeValidCommit(height, blockID, state.Validators, privVals)
+		require.NoError(t, err)
+		blockStore.SaveBlock(block, parts, commit)
+		state, err = blockExec.ApplyBlock(state, blockID, block)
+		require.NoError(t, err)
+		states = append(states, state)
+		lastCommit = commit
+	}
+
+	// the state of height 2 is rebuilt
+	height, appHash, err = sm.Rollback(blockStore, stateDB)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, height)
+	assert.Equal(t, states[2].AppHash, appHash)
+	assert.True(t, states[2].Equals(sm.LoadState(stateDB)))
+
+	// and left as it is, one height behind the block store
+	height, appHash, err = sm.Rollback(blockStore, stateDB)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, height)
+	assert.Equal(t, states[2].AppHash, appHash)
+	assert.True(t, states[2].Equals(sm.LoadState(stateDB)))
+}
@@ -0,0 +1,204 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/proxy"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// ShadowExecutor executes the committed blocks against a shadow app (e.g. the
+// next version of the app) and compares its app hashes with the ones of the
+// app, to validate an upgrade of the app before making it. It runs in the
+// background: the shadow app never affects consensus, divergences are only
+// reported, in the logs and metrics.
+//
+// The shadow app first catches up with the state, from genesis if it's empty,
+// then executes the blocks applied by the BlockExecutor (see
+// BlockExecutorWithShadow). The executor stops at the first divergence, as
+// the shadow app can't execute the next blocks from a different state.
+type ShadowExecutor struct {
+	cmn.BaseService
+
+	proxyApp   proxy.AppConns
+	blockStore BlockStore
+	genDoc     *types.GenesisDoc
+	db         dbm.DB
+	state      State // to catch up with
+	metrics    *Metrics
+
+	mtx      sync.Mutex
+	queue    []shadowBlock
+	height   int64 // of the last block executed by the shadow app
+	err      error // which stopped the executor, if any
+	newBlock chan struct{}
+}
+
+// shadowBlock is a block to execute against the shadow app.
+type shadowBlock struct {
+	height  int64
+	appHash []byte // of the app after the block
+	db      dbm.DB // holding the validators of the block
+}
+
+// NewShadowExecutor returns a ShadowExecutor catching the shadow app up with
+// the state, whose validators are in db. The connections to the shadow app
+// must be started, they are stopped with the executor.
+func NewShadowExecutor(
+	proxyApp proxy.AppConns,
+	blockStore BlockStore,
+	genDoc *types.GenesisDoc,
+	db dbm.DB,
+	state State,
+	metrics *Metrics,
+) *ShadowExecutor {
+	se := &ShadowExecutor{
+		proxyApp:   proxyApp,
+		blockStore: blockStore,
+		genDoc:     genDoc,
+		db:         db,
+		state:      state,
+		metrics:    metrics,
+		newBlock:   make(chan struct{}, 1),
+	}
+	se.BaseService = *cmn.NewBaseService(nil, "ShadowExecutor", se)
+	return se
+}
+
+// OnStart implements cmn.Service.
+func (se *ShadowExecutor) OnStart() error {
+	se.Go("executeRoutine", se.executeRoutine)
+	return nil
+}
+
+// OnStop implements cmn.Service.
+func (se *ShadowExecutor) OnStop() {
+	se.proxyApp.Stop()
+}
+
+// Status returns the height of the last block executed by the shadow app, and
+// the error which stopped the executor, if any (e.g. an
+// ErrShadowAppHashMismatch).
+func (se *ShadowExecutor) Status() (int64, error) {
+	se.mtx.Lock()
+	defer se.mtx.Unlock()
+	return se.height, se.err
+}
+
+// enqueue queues the block at height, after which the app hash of the app is
+// appHash, for execution against the shadow app. db must hold the validators
+// of the block.
+func (se *ShadowExecutor) enqueue(height int64, appHash []byte, db dbm.DB) {
+	se.mtx.Lock()
+	defer se.mtx.Unlock()
+	if se.err != nil {
+		return
+	}
+	se.queue = append(se.queue, shadowBlock{height: height, appHash: appHash, db: db})
+	select {
+	case se.newBlock <- struct{}{}:
+	default:
+	}
+}
+
+func (se *ShadowExecutor) executeRoutine() {
+	err := se.catchUp()
+	for err == nil {
+		select {
+		case <-se.newBlock:
+		case <-se.Quit():
+			return
+		}
+
+		se.mtx.Lock()
+		blocks := se.queue
+		se.queue = nil
+		se.mtx.Unlock()
+
+		for _, b := range blocks {
+			if err = se.execute(b); err != nil || !se.IsRunning() {
+				break
+			}
+		}
+	}
+	if !se.IsRunning() {
+		return
+	}
+
+	se.Logger.Error("Stopped executing blocks against the shadow app", "err", err)
+	se.mtx.Lock()
+	se.err = err
+	se.queue = nil
+	se.mtx.Unlock()
+}
+
+// catchUp executes the blocks the shadow app lacks up to the state.
+func (se *ShadowExecutor) catchUp() error {
+	res, err := se.proxyApp.Query().InfoSync(proxy.RequestInfo)
+	if err != nil {
+		return fmt.Errorf("error calling Info: %v", err)
+	}
+	appHeight := res.LastBlockHeight
+	stateHeight := se.state.LastBlockHeight
+	switch {
+	case appHeight > stateHeight:
+		return ErrAppBlockHeightTooHigh{CoreHeight: stateHeight, AppHeight: appHeight}
+	case appHeight+1 < se.blockStore.Base():
+		return fmt.Errorf("the shadow app is at height %d, but the block store starts at height %d",
+			appHeight, se.blockStore.Base())
+	case appHeight > 0 && appHeight == stateHeight && !bytes.Equal(res.LastBlockAppHash, se.state.AppHash):
+		return ErrShadowAppHashMismatch{Height: appHeight, AppHash: se.state.AppHash, ShadowAppHash: res.LastBlockAppHash}
+	}
+
+	if appHeight == 0 {
+		req, err := InitChainRequest(se.genDoc)
+		if err != nil {
+			return err
+		}
+		if _, err := se.proxyApp.Consensus().InitChainSync(req); err != nil {
+			return fmt.Errorf("error calling InitChain: %v", err)
+		}
+	}
+	se.Logger.Info("Catching the shadow app up with the state", "appHeight", appHeight, "stateHeight", stateHeight)
+	for height := appHeight + 1; height <= stateHeight && se.IsRunning(); height++ {
+		appHash := se.state.AppHash
+		if height < stateHeight {
+			appHash = se.blockStore.LoadBlockMeta(height + 1).Header.AppHash
+		}
+		if err := se.execute(shadowBlock{height: height, appHash: appHash, db: se.db}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execute executes the block against the shadow app, and compares the app
+// hashes.
+func (se *ShadowExecutor) execute(b shadowBlock) error {
+	block := se.blockStore.LoadBlock(b.height)
+	if block == nil {
+		return ErrUnknownBlock{Height: b.height}
+	}
+	lastBlockTime, err := LastBlockTime(se.blockStore, b.height)
+	if err != nil {
+		return err
+	}
+	appHash, err := ExecCommitBlock(se.proxyApp.Consensus(), block, lastBlockTime, se.Logger, b.db)
+	if err != nil {
+		return ErrProxyAppConn(err)
+	}
+
+	se.mtx.Lock()
+	se.height = b.height
+	se.mtx.Unlock()
+	se.metrics.ShadowAppHeight.Set(float64(b.height))
+	if !bytes.Equal(appHash, b.appHash) {
+		se.metrics.ShadowAppDivergenceHeight.Set(float64(b.height))
+		return ErrShadowAppHashMismatch{Height: b.height, AppHash: b.appHash, ShadowAppHash: appHash}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package state_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/abci/example/kvstore"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/mock"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/types"
+	dbm "github.com/tendermint/tm-db"
+)
+
+// divergentApp is a kvstore whose app hash diverges from the height
+// divergeAt, if not 0.
+type divergentApp struct {
+	*kvstore.KVStoreApplication
+	height    int64
+	divergeAt int64
+}
+
+func (app *divergentApp) Commit() abci.ResponseCommit {
+	res := app.KVStoreApplication.Commit()
+	app.height++
+	if app.divergeAt > 0 && app.height >= app.divergeAt {
+		res.Data = []byte("divergent")
+	}
+	return res
+}
+
+// waitShadowHeight waits for the shadow executor to reach height, or to stop.
+func waitShadowHeight(shadow *sm.ShadowExecutor, height int64) (int64, error) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		h, err := shadow.Status()
+		if h >= height || err != nil || time.Now().After(deadline) {
+			return h, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShadowExecutor(t *testing.T) {
+	testCases := []struct {
+		name       string
+		divergeAt  int64
+		wantHeight int64
+		wantErr    bool
+	}{
+		{"deterministic", 0, 4, false},
+		{"diverges while catching up", 2, 2, true},
+		{"diverges after catching up", 3, 3, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(kvstore.NewKVStoreApplication()))
+			require.NoError(t, proxyApp.Start())
+			defer proxyApp.Stop()
+			shadowApp := proxy.NewAppConns(proxy.NewLocalClientCreator(
+				&divergentApp{KVStoreApplication: kvstore.NewKVStoreApplication(), divergeAt: tc.divergeAt}))
+			require.NoError(t, shadowApp.Start())
+
+			state, stateDB, privVals := makeState(1, 1)
+			blockStore := store.NewBlockStore(dbm.NewMemDB())
+			lastCommit := types.NewCommit(types.BlockID{}, nil)
+			applyBlock := func(blockExec *sm.BlockExecutor, height int64) {
+				block, parts := state.MakeBlock(height, makeTxs(height), lastCommit, nil,
+					state.Validators.GetProposer().Address)
+				blockID := types.BlockID{Hash: block.Hash(), PartsHeader: parts.Header()}
+				commit, err := makeValidCommit(height, blockID, state.Validators, privVals)
+				require.NoError(t, err)
+				blockStore.SaveBlock(block, parts, commit)
+				state, err = blockExec.ApplyBlock(state, blockID, block)
+				require.NoError(t, err)
+				lastCommit = commit
+			}
+
+			// the shadow app catches up with the first 2 blocks, then executes
+			// the next ones as they are applied
+			blockExec := sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+				mock.Mempool{}, sm.MockEvidencePool{})
+			applyBlock(blockExec, 1)
+			applyBlock(blockExec, 2)
+
+			shadow := sm.NewShadowExecutor(shadowApp, blockStore, randomGenesisDoc(), stateDB, state, sm.NopMetrics())
+			shadow.SetLogger(log.TestingLogger())
+			require.NoError(t, shadow.Start())
+			defer shadow.Stop()
+			blockExec = sm.NewBlockExecutor(stateDB, log.TestingLogger(), proxyApp.Consensus(),
+				mock.Mempool{}, sm.MockEvidencePool{}, sm.BlockExecutorWithShadow(shadow))
+			applyBlock(blockExec, 3)
+			applyBlock(blockExec, 4)
+
+			height, err := waitShadowHeight(shadow, 4)
+			assert.Equal(t, tc.wantHeight, height)
+			if tc.wantErr {
+				assert.IsType(t, sm.ErrShadowAppHashMismatch{}, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -3,9 +3,9 @@ package state
 import (
 	"bytes"
 	"fmt"
-	"io/ioutil"
 	"time"
 
+	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/types"
 	tmtime "github.com/tendermint/tendermint/types/time"
 	"github.com/tendermint/tendermint/version"
@@ -143,9 +143,12 @@ func (state State) MakeBlock(
 
 	// Set time.
 	var timestamp time.Time
-	if height == 1 {
+	switch {
+	case state.ConsensusParams.Synchrony.ProposerBasedTimestamps():
+		timestamp = ProposerTime(height, state)
+	case height == 1:
 		timestamp = state.LastBlockTime // genesis time
-	} else {
+	default:
 		timestamp = MedianTime(commit, state.LastValidators)
 	}
 
@@ -161,15 +164,51 @@ func (state State) MakeBlock(
 	return block, block.MakePartSet(types.BlockPartSizeBytes)
 }
 
+// ProposerTime returns the time of the block at height proposed by the local
+// node with proposer-based timestamps: the local time. The proposer waits for
+// its clock to pass ProposerMinTime before it proposes, so the time is only
+// bumped to it if the clock went back in the meantime. The validators check the
+// time against their own clock, see types.SynchronyParams.IsTimely.
+func ProposerTime(height int64, state State) time.Time {
+	minTime := ProposerMinTime(height, state)
+	if now := tmtime.Now(); now.After(minTime) {
+		return now
+	}
+	return minTime
+}
+
+// ProposerMinTime returns the earliest time of the block at height with
+// proposer-based timestamps: the genesis time for the first block, TimeIotaMs
+// after the last block time for the others.
+func ProposerMinTime(height int64, state State) time.Time {
+	minTime := state.LastBlockTime // genesis time
+	if height > 1 {
+		minTime = minTime.Add(time.Duration(state.ConsensusParams.Block.TimeIotaMs) * time.Millisecond)
+	}
+	return minTime
+}
+
 // MedianTime computes a median time for a given Commit (based on Timestamp field of votes messages) and the
 // corresponding validator set. The computed time is always between timestamps of
 // the votes sent by honest processes, i.e., a faulty processes can not arbitrarily increase or decrease the
 // computed value.
 func MedianTime(commit *types.Commit, validators *types.ValidatorSet) time.Time {
 
-	weightedTimes := make([]*tmtime.WeightedTime, len(commit.Precommits))
+	weightedTimes := make([]*tmtime.WeightedTime, commit.Size())
 	totalVotingPower := int64(0)
 
+	if agg := commit.Aggregate; agg != nil {
+		for i, j := 0, 0; i < agg.Signers.Size(); i++ {
+			if !agg.Signers.GetIndex(i) {
+				continue
+			}
+			_, validator := validators.GetByIndex(i)
+			totalVotingPower += validator.VotingPower
+			weightedTimes[i] = tmtime.NewWeightedTime(agg.Precommits[j].Timestamp, validator.VotingPower)
+			j++
+		}
+	}
+
 	for i, vote := range commit.Precommits {
 		if vote != nil {
 			_, validator := validators.GetByIndex(vote.ValidatorIndex)
@@ -198,15 +237,7 @@ func MakeGenesisStateFromFile(genDocFile string) (State, error) {
 
 // MakeGenesisDocFromFile reads and unmarshals genesis doc from the given file.
 func MakeGenesisDocFromFile(genDocFile string) (*types.GenesisDoc, error) {
-	genDocJSON, err := ioutil.ReadFile(genDocFile)
-	if err != nil {
-		return nil, fmt.Errorf("Couldn't read GenesisDoc file: %v", err)
-	}
-	genDoc, err := types.GenesisDocFromJSON(genDocJSON)
-	if err != nil {
-		return nil, fmt.Errorf("Error reading GenesisDoc: %v", err)
-	}
-	return genDoc, nil
+	return types.GenesisDocFromFile(genDocFile)
 }
 
 // MakeGenesisState creates state from types.GenesisDoc.
@@ -248,3 +279,24 @@ func MakeGenesisState(genDoc *types.GenesisDoc) (State, error) {
 		AppHash: genDoc.AppHash,
 	}, nil
 }
+
+// InitChainRequest returns the InitChain request initializing the app with
+// the genesis. It reads the app state from the genesis file if it's not in
+// memory.
+func InitChainRequest(genDoc *types.GenesisDoc) (abci.RequestInitChain, error) {
+	appState, err := genDoc.AppStateBytes()
+	if err != nil {
+		return abci.RequestInitChain{}, err
+	}
+	validators := make([]*types.Validator, len(genDoc.Validators))
+	for i, val := range genDoc.Validators {
+		validators[i] = types.NewValidator(val.PubKey, val.Power)
+	}
+	return abci.RequestInitChain{
+		Time:            genDoc.GenesisTime,
+		ChainId:         genDoc.ChainID,
+		ConsensusParams: types.TM2PB.ConsensusParams(genDoc.ConsensusParams),
+		Validators:      types.TM2PB.ValidatorUpdates(types.NewValidatorSet(validators)),
+		AppStateBytes:   appState,
+	}, nil
+}
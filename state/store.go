@@ -5,6 +5,7 @@ import (
 
 	abci "github.com/tendermint/tendermint/abci/types"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/dbschema"
 	"github.com/tendermint/tendermint/types"
 	dbm "github.com/tendermint/tm-db"
 )
@@ -17,6 +18,11 @@ const (
 	valSetCheckpointInterval = 100000
 )
 
+// Schema is the layout history of the state DB. A change of the keys or of
+// the encoding of the values must come with a migration, so that the state
+// DBs written by older versions are migrated when the node starts.
+var Schema = dbschema.Schema{Name: "state"}
+
 //------------------------------------------------------------------------
 
 func calcValidatorsKey(height int64) []byte {
@@ -93,6 +99,16 @@ func SaveState(db dbm.DB, state State) {
 	saveState(db, state, stateKey)
 }
 
+// BootstrapState persists a State which doesn't derive from the genesis
+// state (e.g. built from a trust anchor), along with its last, current and
+// next validator sets, as no validators were saved for the previous heights.
+func BootstrapState(db dbm.DB, state State) {
+	height := state.LastBlockHeight
+	saveValidatorsInfo(db, height, height, state.LastValidators)
+	saveValidatorsInfo(db, height+1, height+1, state.Validators)
+	saveState(db, state, stateKey)
+}
+
 func saveState(db dbm.DB, state State, key []byte) {
 	nextHeight := state.LastBlockHeight + 1
 	// If first block, save validators for block 1.
@@ -118,6 +134,10 @@ type ABCIResponses struct {
 	DeliverTx  []*abci.ResponseDeliverTx `json:"deliver_tx"`
 	EndBlock   *abci.ResponseEndBlock    `json:"end_block"`
 	BeginBlock *abci.ResponseBeginBlock  `json:"begin_block"`
+
+	// RejectedValidatorUpdates is set if the validator updates of EndBlock
+	// were rejected. The app learns it in the next BeginBlock.
+	RejectedValidatorUpdates *abci.RejectedValidatorUpdates `json:"rejected_validator_updates,omitempty"`
 }
 
 // NewABCIResponses returns a new ABCIResponses
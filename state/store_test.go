@@ -54,6 +54,36 @@ func TestStoreLoadValidators(t *testing.T) {
 	assert.NotZero(t, loadedVals.Size())
 }
 
+func TestBootstrapState(t *testing.T) {
+	stateDB := dbm.NewMemDB()
+	lastVals, _ := types.RandValidatorSet(3, 10)
+	vals, _ := types.RandValidatorSet(3, 10)
+	nextVals, _ := types.RandValidatorSet(3, 10)
+	state := sm.State{
+		ChainID:                          "test",
+		LastBlockHeight:                  1000,
+		LastValidators:                   lastVals,
+		Validators:                       vals,
+		NextValidators:                   nextVals,
+		LastHeightValidatorsChanged:      1002,
+		ConsensusParams:                  *types.DefaultConsensusParams(),
+		LastHeightConsensusParamsChanged: 1001,
+	}
+	sm.BootstrapState(stateDB, state)
+
+	for height, want := range map[int64]*types.ValidatorSet{1000: lastVals, 1001: vals, 1002: nextVals} {
+		loaded, err := sm.LoadValidators(stateDB, height)
+		require.NoError(t, err, "height %d", height)
+		assert.Equal(t, want.Hash(), loaded.Hash(), "height %d", height)
+	}
+	_, err := sm.LoadValidators(stateDB, 999)
+	assert.Error(t, err)
+	params, err := sm.LoadConsensusParams(stateDB, 1001)
+	require.NoError(t, err)
+	assert.Equal(t, state.ConsensusParams, params)
+	assert.EqualValues(t, 1000, sm.LoadState(stateDB).LastBlockHeight)
+}
+
 func BenchmarkLoadValidators(b *testing.B) {
 	const valSetSize = 100
 
@@ -0,0 +1,67 @@
+package streaming
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	auto "github.com/tendermint/tendermint/libs/autofile"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+// FileListener writes the applied blocks to a group of files in a directory,
+// one BlockRecord per line. The head file is "blocks", rotated to
+// "blocks.000", "blocks.001"... as it grows; the oldest files are removed
+// past the maximum size of the group, so a consumer must keep up with them.
+//
+// Each record is synced to disk before ApplyBlock returns. The blocks replayed
+// by the Handshaker after a crash aren't streamed: a consumer should fetch the
+// heights missing from the files from the RPC.
+type FileListener struct {
+	cmn.BaseService
+
+	group *auto.Group
+}
+
+var _ sm.BlockListener = (*FileListener)(nil)
+
+// NewFileListener returns a FileListener writing to dir at most maxSize
+// bytes of records.
+func NewFileListener(dir string, maxSize int64) (*FileListener, error) {
+	if err := cmn.EnsureDir(dir, 0700); err != nil {
+		return nil, err
+	}
+	group, err := auto.OpenGroup(filepath.Join(dir, "blocks"),
+		auto.GroupHeadSizeLimit(maxSize/10), auto.GroupTotalSizeLimit(maxSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the block stream files")
+	}
+	fl := &FileListener{group: group}
+	fl.BaseService = *cmn.NewBaseService(nil, "FileListener", fl)
+	return fl, nil
+}
+
+// OnStart implements cmn.Service.
+func (fl *FileListener) OnStart() error {
+	return fl.group.Start()
+}
+
+// OnStop implements cmn.Service.
+func (fl *FileListener) OnStop() {
+	fl.group.Stop()
+	fl.group.Wait()
+	fl.group.Close()
+}
+
+// ListenBlock implements sm.BlockListener.
+func (fl *FileListener) ListenBlock(update sm.BlockUpdate) error {
+	bz, err := MarshalBlockRecord(NewBlockRecord(update))
+	if err != nil {
+		return err
+	}
+	if err := fl.group.WriteLine(string(bz)); err != nil {
+		return err
+	}
+	return fl.group.FlushAndSync()
+}
@@ -0,0 +1,201 @@
+package streaming
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+// The gRPC block stream service has a single unary method, called by the node
+// for each block in order. Its messages and service description are written
+// by hand rather than generated:
+//
+//	service BlockStream {
+//	  rpc ListenBlock(ListenBlockRequest) returns (ListenBlockResponse);
+//	}
+//
+//	message ListenBlockRequest  { int64 height = 1; bytes record = 2; }
+//	message ListenBlockResponse {}
+const (
+	grpcServiceName       = "tendermint.state.BlockStream"
+	grpcListenBlockMethod = "/" + grpcServiceName + "/ListenBlock"
+)
+
+const (
+	// timeout of a call to the service
+	grpcPushTimeout = 10 * time.Second
+	// bounds of the delay between the attempts to push a block
+	grpcMinRetryDelay = 100 * time.Millisecond
+	grpcMaxRetryDelay = 30 * time.Second
+)
+
+// ListenBlockRequest pushes the block at the given height. Record is the
+// BlockRecord, in amino JSON (see UnmarshalBlockRecord).
+type ListenBlockRequest struct {
+	Height int64  `protobuf:"varint,1,opt,name=height,proto3" json:"height,omitempty"`
+	Record []byte `protobuf:"bytes,2,opt,name=record,proto3" json:"record,omitempty"`
+}
+
+func (m *ListenBlockRequest) Reset()         { *m = ListenBlockRequest{} }
+func (m *ListenBlockRequest) String() string { return proto.CompactTextString(m) }
+func (*ListenBlockRequest) ProtoMessage()    {}
+
+// ListenBlockResponse acknowledges a block. An error makes the node push the
+// block again.
+type ListenBlockResponse struct{}
+
+func (m *ListenBlockResponse) Reset()         { *m = ListenBlockResponse{} }
+func (m *ListenBlockResponse) String() string { return proto.CompactTextString(m) }
+func (*ListenBlockResponse) ProtoMessage()    {}
+
+// GRPCServer is the server API of the gRPC block stream service, implemented
+// by the consumers of the stream.
+type GRPCServer interface {
+	ListenBlock(context.Context, *ListenBlockRequest) (*ListenBlockResponse, error)
+}
+
+// RegisterGRPCServer registers the block stream service on the given gRPC
+// server.
+func RegisterGRPCServer(s *grpc.Server, srv GRPCServer) {
+	s.RegisterService(&grpcServiceDesc, srv)
+}
+
+func grpcListenBlockHandler(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	in := new(ListenBlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GRPCServer).ListenBlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: grpcListenBlockMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GRPCServer).ListenBlock(ctx, req.(*ListenBlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: grpcServiceName,
+	HandlerType: (*GRPCServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListenBlock",
+			Handler:    grpcListenBlockHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+//-----------------------------------------------------------------------------
+
+// GRPCListener pushes the applied blocks to the gRPC block stream service, in
+// order, from a buffer. A block is pushed again until the service
+// acknowledges it, with an increasing delay. When the buffer is full (the
+// service is down or too slow), the blocks are dropped, with an error: the
+// consumer should fetch the heights it missed from the RPC. The connection
+// is plaintext, so the service should run next to the node.
+type GRPCListener struct {
+	cmn.BaseService
+
+	addr  string
+	conn  *grpc.ClientConn
+	queue chan *ListenBlockRequest
+}
+
+var _ sm.BlockListener = (*GRPCListener)(nil)
+
+// NewGRPCListener returns a GRPCListener for the service at the given
+// address, buffering up to bufferSize blocks.
+func NewGRPCListener(addr string, bufferSize int) *GRPCListener {
+	gl := &GRPCListener{
+		addr:  addr,
+		queue: make(chan *ListenBlockRequest, bufferSize),
+	}
+	gl.BaseService = *cmn.NewBaseService(nil, "GRPCListener", gl)
+	return gl
+}
+
+// OnStart implements cmn.Service. It connects in the background.
+func (gl *GRPCListener) OnStart() error {
+	conn, err := grpc.Dial(gl.addr, grpc.WithInsecure())
+	if err != nil {
+		return errors.Wrap(err, "failed to dial the block stream service")
+	}
+	gl.conn = conn
+	gl.Go("pushRoutine", gl.pushRoutine)
+	return nil
+}
+
+// OnStop implements cmn.Service. The buffered blocks are dropped.
+func (gl *GRPCListener) OnStop() {
+	gl.conn.Close()
+}
+
+// ListenBlock implements sm.BlockListener.
+func (gl *GRPCListener) ListenBlock(update sm.BlockUpdate) error {
+	bz, err := MarshalBlockRecord(NewBlockRecord(update))
+	if err != nil {
+		return err
+	}
+	select {
+	case gl.queue <- &ListenBlockRequest{Height: update.Block.Height, Record: bz}:
+		return nil
+	default:
+		return fmt.Errorf("the buffer of %d blocks is full, dropped the block", cap(gl.queue))
+	}
+}
+
+func (gl *GRPCListener) pushRoutine() {
+	for {
+		select {
+		case req := <-gl.queue:
+			if !gl.push(req) {
+				return
+			}
+		case <-gl.Quit():
+			return
+		}
+	}
+}
+
+// push pushes the block until the service acknowledges it. It returns false
+// if the listener was stopped first.
+func (gl *GRPCListener) push(req *ListenBlockRequest) bool {
+	delay := grpcMinRetryDelay
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), grpcPushTimeout)
+		err := gl.conn.Invoke(ctx, grpcListenBlockMethod, req, new(ListenBlockResponse))
+		cancel()
+		if err == nil {
+			return true
+		}
+		gl.Logger.Error("Failed to push the block to the stream service",
+			"height", req.Height, "retry_in", delay, "err", err)
+
+		select {
+		case <-time.After(delay):
+		case <-gl.Quit():
+			return false
+		}
+		delay *= 2
+		if delay > grpcMaxRetryDelay {
+			delay = grpcMaxRetryDelay
+		}
+	}
+}
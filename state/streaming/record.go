@@ -0,0 +1,61 @@
+// Package streaming implements the state.BlockListener streaming the blocks
+// applied by the node, with their results, to external consumers (e.g.
+// indexers): the FileListener writes them to a group of files, the
+// GRPCListener pushes them to a gRPC service.
+package streaming
+
+import (
+	amino "github.com/tendermint/go-amino"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+var cdc = amino.NewCodec()
+
+func init() {
+	types.RegisterBlockAmino(cdc)
+}
+
+// BlockRecord is a block applied by the node with its results, as streamed by
+// the listeners, in amino JSON.
+type BlockRecord struct {
+	Height           int64                     `json:"height"`
+	Block            *types.Block              `json:"block"`
+	BlockID          types.BlockID             `json:"block_id"`
+	ResultBeginBlock *abci.ResponseBeginBlock  `json:"result_begin_block"`
+	TxResults        []*abci.ResponseDeliverTx `json:"tx_results"`
+	ResultEndBlock   *abci.ResponseEndBlock    `json:"result_end_block"`
+	ValidatorUpdates []*types.Validator        `json:"validator_updates"`
+	AppHash          cmn.HexBytes              `json:"app_hash"` // after the block
+}
+
+// NewBlockRecord returns the record of the update.
+func NewBlockRecord(update sm.BlockUpdate) *BlockRecord {
+	return &BlockRecord{
+		Height:           update.Block.Height,
+		Block:            update.Block,
+		BlockID:          update.BlockID,
+		ResultBeginBlock: update.ABCIResponses.BeginBlock,
+		TxResults:        update.ABCIResponses.DeliverTx,
+		ResultEndBlock:   update.ABCIResponses.EndBlock,
+		ValidatorUpdates: update.ValidatorUpdates,
+		AppHash:          update.AppHash,
+	}
+}
+
+// MarshalBlockRecord encodes the record in amino JSON.
+func MarshalBlockRecord(r *BlockRecord) ([]byte, error) {
+	return cdc.MarshalJSON(r)
+}
+
+// UnmarshalBlockRecord decodes a record encoded by MarshalBlockRecord.
+func UnmarshalBlockRecord(bz []byte) (*BlockRecord, error) {
+	r := new(BlockRecord)
+	if err := cdc.UnmarshalJSON(bz, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
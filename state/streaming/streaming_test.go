@@ -0,0 +1,126 @@
+package streaming
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/log"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+func makeUpdate(height int64) sm.BlockUpdate {
+	block := types.MakeBlock(height, []types.Tx{types.Tx("a=1"), types.Tx("b=2")}, nil, nil)
+	return sm.BlockUpdate{
+		Block:   block,
+		BlockID: types.BlockID{Hash: block.Hash()},
+		ABCIResponses: &sm.ABCIResponses{
+			BeginBlock: &abci.ResponseBeginBlock{},
+			DeliverTx:  []*abci.ResponseDeliverTx{{Code: 0}, {Code: 1, Log: "invalid"}},
+			EndBlock:   &abci.ResponseEndBlock{},
+		},
+		ValidatorUpdates: []*types.Validator{types.NewValidator(ed25519.GenPrivKey().PubKey(), 10)},
+		AppHash:          []byte{1, 2, 3},
+	}
+}
+
+func TestFileListener(t *testing.T) {
+	dir, err := ioutil.TempDir("", "streaming")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fl, err := NewFileListener(dir, 1024*1024)
+	require.NoError(t, err)
+	require.NoError(t, fl.Start())
+	for h := int64(1); h <= 3; h++ {
+		require.NoError(t, fl.ListenBlock(makeUpdate(h)))
+	}
+	require.NoError(t, fl.Stop())
+
+	f, err := os.Open(filepath.Join(dir, "blocks"))
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	height := int64(0)
+	for scanner.Scan() {
+		height++
+		r, err := UnmarshalBlockRecord(scanner.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, height, r.Height)
+		assert.Equal(t, height, r.Block.Height)
+		assert.Len(t, r.TxResults, 2)
+		assert.Equal(t, "invalid", r.TxResults[1].Log)
+		assert.Len(t, r.ValidatorUpdates, 1)
+		assert.EqualValues(t, []byte{1, 2, 3}, r.AppHash)
+	}
+	require.NoError(t, scanner.Err())
+	assert.EqualValues(t, 3, height)
+}
+
+// blockConsumer fails the first push of each block.
+type blockConsumer struct {
+	mtx     sync.Mutex
+	failed  map[int64]bool
+	heights []int64
+}
+
+func (bc *blockConsumer) ListenBlock(_ context.Context, req *ListenBlockRequest) (*ListenBlockResponse, error) {
+	bc.mtx.Lock()
+	defer bc.mtx.Unlock()
+	if !bc.failed[req.Height] {
+		bc.failed[req.Height] = true
+		return nil, errors.New("try again")
+	}
+	r, err := UnmarshalBlockRecord(req.Record)
+	if err != nil {
+		return nil, err
+	}
+	bc.heights = append(bc.heights, r.Height)
+	return &ListenBlockResponse{}, nil
+}
+
+func (bc *blockConsumer) received() []int64 {
+	bc.mtx.Lock()
+	defer bc.mtx.Unlock()
+	return append([]int64(nil), bc.heights...)
+}
+
+func TestGRPCListener(t *testing.T) {
+	consumer := &blockConsumer{failed: make(map[int64]bool)}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	RegisterGRPCServer(s, consumer)
+	go s.Serve(ln) // nolint: errcheck
+	defer s.Stop()
+
+	gl := NewGRPCListener(ln.Addr().String(), 2)
+	gl.SetLogger(log.TestingLogger())
+	for h := int64(1); h <= 2; h++ {
+		require.NoError(t, gl.ListenBlock(makeUpdate(h)))
+	}
+	// the buffer is full until the listener is started
+	assert.Error(t, gl.ListenBlock(makeUpdate(3)))
+
+	require.NoError(t, gl.Start())
+	defer gl.Stop() // nolint: errcheck
+	require.Eventually(t, func() bool { return len(consumer.received()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, gl.ListenBlock(makeUpdate(3)))
+	require.Eventually(t, func() bool { return len(consumer.received()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []int64{1, 2, 3}, consumer.received())
+}
@@ -0,0 +1,5 @@
+package txindex
+
+// LagCheckInterval is exported for the replica tests, which wait for the
+// cached lag of the replica to expire.
+const LagCheckInterval = lagCheckInterval
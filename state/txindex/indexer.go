@@ -24,6 +24,15 @@ type TxIndexer interface {
 	Search(q *query.Query) ([]*types.TxResult, error)
 }
 
+// HeightReporter is implemented by indexers which keep track of the last
+// block height they have indexed. It is used to measure how far a read
+// replica lags behind the indexer it replicates.
+type HeightReporter interface {
+	// IndexedHeight returns the height of the last indexed block or 0 if
+	// nothing has been indexed yet.
+	IndexedHeight() (int64, error)
+}
+
 //----------------------------------------------------
 // Txs are written as a batch
 
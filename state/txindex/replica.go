@@ -0,0 +1,150 @@
+package txindex
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/types"
+)
+
+var _ TxIndexer = (*ReplicatedTxIndex)(nil)
+
+// lagCheckInterval is how long the lag of the replica is cached for.
+const lagCheckInterval = time.Second
+
+// ReplicatedTxIndex sends all writes to a primary indexer and serves reads
+// from a read replica of it (e.g. a database replica maintained outside of
+// Tendermint), so that search-heavy RPC load does not hit the write path.
+//
+// Reads fall back to the primary when the replica fails, when a transaction
+// can not be found on the replica yet or when the replica lags more than
+// maxLag blocks behind the primary. Lag is only measured if both implement
+// HeightReporter, at most every lagCheckInterval.
+type ReplicatedTxIndex struct {
+	primary TxIndexer
+	replica TxIndexer
+	maxLag  int64
+
+	lagMtx       sync.Mutex
+	lagCheckedAt time.Time
+	usable       bool
+
+	logger log.Logger
+}
+
+// NewReplicatedTxIndex returns a ReplicatedTxIndex writing to primary and
+// reading from replica.
+func NewReplicatedTxIndex(primary, replica TxIndexer, maxLag int64) *ReplicatedTxIndex {
+	return &ReplicatedTxIndex{
+		primary: primary,
+		replica: replica,
+		maxLag:  maxLag,
+		usable:  true,
+		logger:  log.NewNopLogger(),
+	}
+}
+
+// SetLogger sets the logger used to report replica failures.
+func (txi *ReplicatedTxIndex) SetLogger(l log.Logger) {
+	txi.logger = l
+}
+
+// AddBatch indexes the batch on the primary.
+func (txi *ReplicatedTxIndex) AddBatch(b *Batch) error {
+	return txi.primary.AddBatch(b)
+}
+
+// Index indexes a single transaction on the primary.
+func (txi *ReplicatedTxIndex) Index(result *types.TxResult) error {
+	return txi.primary.Index(result)
+}
+
+// Get returns the transaction from the replica, or from the primary if the
+// replica is unusable or does not have it (yet).
+func (txi *ReplicatedTxIndex) Get(hash []byte) (*types.TxResult, error) {
+	if txi.replicaUsable() {
+		res, err := txi.replica.Get(hash)
+		if err == nil && res != nil {
+			return res, nil
+		}
+		if err != nil {
+			txi.logger.Error("Failed to get tx from read replica, using primary", "err", err)
+		}
+	}
+	return txi.primary.Get(hash)
+}
+
+// Search runs the query on the replica, or on the primary if the replica is
+// unusable.
+func (txi *ReplicatedTxIndex) Search(q *query.Query) ([]*types.TxResult, error) {
+	if txi.replicaUsable() {
+		res, err := txi.replica.Search(q)
+		if err == nil {
+			return res, nil
+		}
+		txi.logger.Error("Failed to search read replica, using primary", "err", err)
+	}
+	return txi.primary.Search(q)
+}
+
+// Lag returns the number of blocks the replica is behind the primary. It
+// returns 0 if either of them does not report its height.
+func (txi *ReplicatedTxIndex) Lag() (int64, error) {
+	primaryHR, ok := txi.primary.(HeightReporter)
+	if !ok {
+		return 0, nil
+	}
+	replicaHR, ok := txi.replica.(HeightReporter)
+	if !ok {
+		return 0, nil
+	}
+	replicaHeight, err := replicaHR.IndexedHeight()
+	if err != nil {
+		return 0, err
+	}
+
+	primaryHeight, err := primaryHR.IndexedHeight()
+	if err != nil {
+		return 0, err
+	}
+
+	if replicaHeight >= primaryHeight {
+		return 0, nil
+	}
+	return primaryHeight - replicaHeight, nil
+}
+
+// replicaUsable returns true if the replica is within maxLag blocks of the
+// primary. The result is cached for lagCheckInterval, and only the changes
+// are logged.
+func (txi *ReplicatedTxIndex) replicaUsable() bool {
+	txi.lagMtx.Lock()
+	defer txi.lagMtx.Unlock()
+	if !txi.lagCheckedAt.IsZero() && time.Since(txi.lagCheckedAt) < lagCheckInterval {
+		return txi.usable
+	}
+	txi.lagCheckedAt = time.Now()
+
+	lag, err := txi.Lag()
+	switch {
+	case err != nil:
+		if txi.usable {
+			txi.logger.Error("Failed to get read replica height, using primary", "err", err)
+		}
+		txi.usable = false
+	case txi.maxLag > 0 && lag > txi.maxLag:
+		if txi.usable {
+			txi.logger.Info("Read replica is lagging, using primary", "lag", lag, "max", txi.maxLag)
+		}
+		txi.usable = false
+	default:
+		if !txi.usable {
+			txi.logger.Info("Read replica caught up, using it", "lag", lag)
+		}
+		txi.usable = true
+	}
+	txi.logger.Debug("Checked read replica lag", "lag", lag, "usable", txi.usable)
+	return txi.usable
+}
@@ -0,0 +1,94 @@
+package txindex_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/state/txindex/kv"
+	"github.com/tendermint/tendermint/types"
+	db "github.com/tendermint/tm-db"
+)
+
+// heightIndex is a kv indexer reporting the height of the last tx it indexed.
+type heightIndex struct {
+	*kv.TxIndex
+	height int64
+}
+
+func newHeightIndex() *heightIndex {
+	return &heightIndex{TxIndex: kv.NewTxIndex(db.NewMemDB(), kv.IndexAllTags())}
+}
+
+func (txi *heightIndex) AddBatch(b *txindex.Batch) error {
+	for _, r := range b.Ops {
+		if r.Height > txi.height {
+			txi.height = r.Height
+		}
+	}
+	return txi.TxIndex.AddBatch(b)
+}
+
+func (txi *heightIndex) IndexedHeight() (int64, error) {
+	return txi.height, nil
+}
+
+func indexBlock(t *testing.T, idx txindex.TxIndexer, height int64, tx types.Tx) {
+	batch := txindex.NewBatch(1)
+	require.NoError(t, batch.Add(&types.TxResult{
+		Height: height,
+		Index:  0,
+		Tx:     tx,
+		Result: abci.ResponseDeliverTx{Code: abci.CodeTypeOK},
+	}))
+	require.NoError(t, idx.AddBatch(batch))
+}
+
+func TestReplicatedTxIndex(t *testing.T) {
+	primary, replica := newHeightIndex(), newHeightIndex()
+	txi := txindex.NewReplicatedTxIndex(primary, replica, 2)
+
+	// tx only known to the replica is served from the replica
+	indexBlock(t, replica, 1, types.Tx("replica-only"))
+	indexBlock(t, txi, 1, types.Tx("foo"))
+	res, err := txi.Get(types.Tx("replica-only").Hash())
+	require.NoError(t, err)
+	assert.NotNil(t, res)
+
+	// tx not replicated yet is served from the primary
+	res, err = txi.Get(types.Tx("foo").Hash())
+	require.NoError(t, err)
+	require.NotNil(t, res)
+	assert.EqualValues(t, 1, res.Height)
+
+	lag, err := txi.Lag()
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, lag)
+
+	// searches go to the replica as long as it is within the max lag
+	indexBlock(t, txi, 2, types.Tx("bar"))
+	indexBlock(t, txi, 3, types.Tx("baz"))
+	time.Sleep(txindex.LagCheckInterval)
+	lag, err = txi.Lag()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, lag)
+	results, err := txi.Search(query.MustParse("tx.height >= 1"))
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	// beyond the max lag searches go to the primary, once the cached lag
+	// expires
+	indexBlock(t, txi, 4, types.Tx("qux"))
+	results, err = txi.Search(query.MustParse("tx.height >= 1"))
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+	time.Sleep(txindex.LagCheckInterval)
+	results, err = txi.Search(query.MustParse("tx.height >= 1"))
+	require.NoError(t, err)
+	assert.Len(t, results, 4)
+}
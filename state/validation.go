@@ -89,12 +89,19 @@ func validateBlock(evidencePool EvidencePool, stateDB dbm.DB, state State, block
 
 	// Validate block LastCommit.
 	if block.Height == 1 {
-		if len(block.LastCommit.Precommits) != 0 {
+		if block.LastCommit.Size() != 0 {
 			return errors.New("Block at height 1 can't have LastCommit precommits")
 		}
 	} else {
-		if len(block.LastCommit.Precommits) != state.LastValidators.Size() {
-			return types.NewErrInvalidCommitPrecommits(state.LastValidators.Size(), len(block.LastCommit.Precommits))
+		if block.LastCommit.Size() != state.LastValidators.Size() {
+			return types.NewErrInvalidCommitPrecommits(state.LastValidators.Size(), block.LastCommit.Size())
+		}
+		if block.LastCommit.Aggregate != nil && !state.ConsensusParams.Block.AggregateCommits {
+			return errors.New("Block LastCommit can't be aggregated: the consensus params don't enable it")
+		}
+		if block.LastCommit.VoteExtensionsSize() > 0 &&
+			!state.ConsensusParams.Block.VoteExtensionsEnabled(block.Height-1) {
+			return errors.New("Block LastCommit can't have vote extensions: the consensus params don't enable them")
 		}
 		err := state.LastValidators.VerifyCommit(
 			state.ChainID, state.LastBlockID, block.Height-1, block.LastCommit)
@@ -112,16 +119,35 @@ func validateBlock(evidencePool EvidencePool, stateDB dbm.DB, state State, block
 			)
 		}
 
-		medianTime := MedianTime(block.LastCommit, state.LastValidators)
-		if !block.Time.Equal(medianTime) {
-			return fmt.Errorf("Invalid block time. Expected %v, got %v",
-				medianTime,
-				block.Time,
-			)
+		// with proposer-based timestamps, the validators check the time against
+		// their own clock before they prevote the proposal, but it must still be
+		// TimeIotaMs after the last block time
+		if state.ConsensusParams.Synchrony.ProposerBasedTimestamps() {
+			if minTime := ProposerMinTime(block.Height, state); block.Time.Before(minTime) {
+				return fmt.Errorf("Block time %v is less than TimeIotaMs after last block time %v",
+					block.Time,
+					state.LastBlockTime,
+				)
+			}
+		} else {
+			medianTime := MedianTime(block.LastCommit, state.LastValidators)
+			if !block.Time.Equal(medianTime) {
+				return fmt.Errorf("Invalid block time. Expected %v, got %v",
+					medianTime,
+					block.Time,
+				)
+			}
 		}
 	} else if block.Height == 1 {
 		genesisTime := state.LastBlockTime
-		if !block.Time.Equal(genesisTime) {
+		if state.ConsensusParams.Synchrony.ProposerBasedTimestamps() {
+			if block.Time.Before(genesisTime) {
+				return fmt.Errorf("Block time %v is before genesis time %v",
+					block.Time,
+					genesisTime,
+				)
+			}
+		} else if !block.Time.Equal(genesisTime) {
 			return fmt.Errorf("Block time %v is not equal to genesis time %v",
 				block.Time,
 				genesisTime,
@@ -130,11 +156,14 @@ func validateBlock(evidencePool EvidencePool, stateDB dbm.DB, state State, block
 	}
 
 	// Limit the amount of evidence
-	maxNumEvidence, _ := types.MaxEvidencePerBlock(state.ConsensusParams.Block.MaxBytes)
+	maxNumEvidence, maxEvidenceBytes := state.ConsensusParams.MaxEvidence()
 	numEvidence := int64(len(block.Evidence.Evidence))
 	if numEvidence > maxNumEvidence {
 		return types.NewErrEvidenceOverflow(maxNumEvidence, numEvidence)
-
+	}
+	if size := evidenceBytes(block.Evidence.Evidence); size > maxEvidenceBytes {
+		return fmt.Errorf("the evidence takes %d bytes, more than the maximum of %d bytes",
+			size, maxEvidenceBytes)
 	}
 
 	// Validate all evidence.
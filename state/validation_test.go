@@ -89,6 +89,57 @@ func TestValidateBlockHeader(t *testing.T) {
 	}
 }
 
+func TestValidateBlockTime(t *testing.T) {
+	proxyApp := newTestApp()
+	require.NoError(t, proxyApp.Start())
+	defer proxyApp.Stop()
+
+	pbtsParams := types.SynchronyParams{Precision: 500 * time.Millisecond, MessageDelay: 2 * time.Second}
+	for _, synchrony := range []types.SynchronyParams{{}, pbtsParams} {
+		state, stateDB, privVals := makeState(3, 1)
+		state.ConsensusParams.Synchrony = synchrony
+		pbts := synchrony.ProposerBasedTimestamps()
+		blockExec := sm.NewBlockExecutor(
+			stateDB,
+			log.TestingLogger(),
+			proxyApp.Consensus(),
+			mock.Mempool{},
+			sm.MockEvidencePool{},
+		)
+		lastCommit := types.NewCommit(types.BlockID{}, nil)
+
+		for height := int64(1); height < 4; height++ {
+			proposerAddr := state.Validators.GetProposer().Address
+
+			// with proposer-based timestamps, any time after the last block is
+			// valid, it's up to the validators to check that it's timely
+			block, _ := state.MakeBlock(height, makeTxs(height), lastCommit, nil, proposerAddr)
+			block.Time = block.Time.Add(time.Second)
+			err := blockExec.ValidateBlock(state, block)
+			if pbts {
+				require.NoError(t, err, "height %d", height)
+			} else {
+				require.Error(t, err, "height %d", height)
+			}
+
+			block.Time = state.LastBlockTime.Add(-time.Millisecond)
+			require.Error(t, blockExec.ValidateBlock(state, block), "height %d", height)
+
+			// the time must be TimeIotaMs after the last block time
+			if pbts && height > 1 {
+				timeIota := time.Duration(state.ConsensusParams.Block.TimeIotaMs) * time.Millisecond
+				block.Time = state.LastBlockTime.Add(timeIota - time.Millisecond)
+				require.Error(t, blockExec.ValidateBlock(state, block), "height %d", height)
+				block.Time = state.LastBlockTime.Add(timeIota)
+				require.NoError(t, blockExec.ValidateBlock(state, block), "height %d", height)
+			}
+
+			state, _, lastCommit, err = makeAndCommitGoodBlock(state, height, lastCommit, proposerAddr, blockExec, privVals, nil)
+			require.NoError(t, err, "height %d", height)
+		}
+	}
+}
+
 func TestValidateBlockCommit(t *testing.T) {
 	proxyApp := newTestApp()
 	require.NoError(t, proxyApp.Start())
@@ -140,6 +191,17 @@ func TestValidateBlockCommit(t *testing.T) {
 				height,
 				err,
 			)
+
+			/*
+				the precommits can't have extensions until the consensus params enable them
+			*/
+			extendedCommitSig := *lastCommit.Precommits[0]
+			extendedCommitSig.Extension = []byte("extension")
+			extendedCommit := types.NewCommit(lastCommit.BlockID, []*types.CommitSig{&extendedCommitSig})
+			block, _ = state.MakeBlock(height, makeTxs(height), extendedCommit, nil, proposerAddr)
+			err = blockExec.ValidateBlock(state, block)
+			require.Error(t, err, "height %d", height)
+			require.Contains(t, err.Error(), "vote extensions")
 		}
 
 		/*
@@ -0,0 +1,187 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	amino "github.com/tendermint/go-amino"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// archiveInterval is how often the Archiver checks for blocks to archive.
+	archiveInterval = 10 * time.Second
+
+	// maxBlocksPerRound bounds the blocks archived before pruning them, so
+	// pruning keeps up with a big backlog.
+	maxBlocksPerRound = 1000
+
+	// archiveTimeout bounds the time to put a block in the object store.
+	archiveTimeout = 30 * time.Second
+)
+
+var cdc = amino.NewCodec()
+
+func init() {
+	types.RegisterBlockAmino(cdc)
+}
+
+// archivedBlock is the object stored for each block.
+type archivedBlock struct {
+	Meta   *types.BlockMeta `json:"meta"`
+	Block  *types.Block     `json:"block"`
+	Commit *types.Commit    `json:"commit"`
+}
+
+// Archive keeps blocks in an ObjectStore, one object per block.
+type Archive struct {
+	store ObjectStore
+}
+
+// NewArchive returns an Archive storing blocks in the given ObjectStore.
+func NewArchive(store ObjectStore) *Archive {
+	return &Archive{store: store}
+}
+
+// SaveBlock stores the block, its meta and the commit for it.
+func (a *Archive) SaveBlock(ctx context.Context, meta *types.BlockMeta, block *types.Block, commit *types.Commit) error {
+	bz, err := cdc.MarshalBinaryBare(archivedBlock{Meta: meta, Block: block, Commit: commit})
+	if err != nil {
+		return err
+	}
+	return a.store.Put(ctx, blockKey(block.Height), bz)
+}
+
+// LoadBlock returns the block at the given height and its meta. It returns
+// ErrNotFound if the block was not archived.
+func (a *Archive) LoadBlock(ctx context.Context, height int64) (*types.BlockMeta, *types.Block, error) {
+	ab, err := a.load(ctx, height)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ab.Meta, ab.Block, nil
+}
+
+// LoadBlockCommit returns the commit for the block at the given height. It
+// returns ErrNotFound if the block was not archived.
+func (a *Archive) LoadBlockCommit(ctx context.Context, height int64) (*types.Commit, error) {
+	ab, err := a.load(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	return ab.Commit, nil
+}
+
+func (a *Archive) load(ctx context.Context, height int64) (archivedBlock, error) {
+	var ab archivedBlock
+	bz, err := a.store.Get(ctx, blockKey(height))
+	if err != nil {
+		return ab, err
+	}
+	if err := cdc.UnmarshalBinaryBare(bz, &ab); err != nil {
+		return ab, errors.Wrapf(err, "failed to decode archived block %d", height)
+	}
+	return ab, nil
+}
+
+// blockKey is zero padded, so that keys sort by height.
+func blockKey(height int64) string {
+	return fmt.Sprintf("blocks/%020d", height)
+}
+
+//-----------------------------------------------------------------------------
+
+// PrunableBlockStore is a block store whose oldest blocks can be removed.
+type PrunableBlockStore interface {
+	sm.BlockStoreRPC
+	PruneBlocks(height int64) (uint64, error)
+}
+
+// Archiver is a service moving the blocks of a block store to an Archive,
+// keeping only the latest retainBlocks blocks in the block store.
+type Archiver struct {
+	cmn.BaseService
+
+	archive      *Archive
+	blockStore   PrunableBlockStore
+	retainBlocks int64
+}
+
+// NewArchiver returns a new Archiver. retainBlocks must be positive.
+func NewArchiver(archive *Archive, blockStore PrunableBlockStore, retainBlocks int64) *Archiver {
+	a := &Archiver{
+		archive:      archive,
+		blockStore:   blockStore,
+		retainBlocks: retainBlocks,
+	}
+	a.BaseService = *cmn.NewBaseService(nil, "Archiver", a)
+	return a
+}
+
+// OnStart implements cmn.Service.
+func (a *Archiver) OnStart() error {
+	a.Go("archiveRoutine", a.archiveRoutine)
+	return nil
+}
+
+func (a *Archiver) archiveRoutine() {
+	ticker := time.NewTicker(archiveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := a.archiveBlocks(); err != nil {
+				a.Logger.Error("Failed to archive blocks", "err", err)
+			}
+		case <-a.Quit():
+			return
+		}
+	}
+}
+
+// archiveBlocks archives the blocks beyond the retained ones, and prunes them
+// from the block store once they are all archived.
+func (a *Archiver) archiveBlocks() error {
+	base, retainHeight := a.blockStore.Base(), a.blockStore.Height()-a.retainBlocks+1
+	if base == 0 || retainHeight <= base {
+		return nil
+	}
+	if retainHeight-base > maxBlocksPerRound {
+		retainHeight = base + maxBlocksPerRound
+	}
+
+	for height := base; height < retainHeight; height++ {
+		select {
+		case <-a.Quit():
+			return nil
+		default:
+		}
+
+		meta := a.blockStore.LoadBlockMeta(height)
+		block := a.blockStore.LoadBlock(height)
+		commit := a.blockStore.LoadBlockCommit(height)
+		if meta == nil || block == nil {
+			return fmt.Errorf("block %d is missing from the block store", height)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), archiveTimeout)
+		err := a.archive.SaveBlock(ctx, meta, block, commit)
+		cancel()
+		if err != nil {
+			return errors.Wrapf(err, "failed to archive block %d", height)
+		}
+	}
+
+	pruned, err := a.blockStore.PruneBlocks(retainHeight)
+	if err != nil {
+		return errors.Wrap(err, "failed to prune archived blocks")
+	}
+	a.Logger.Info("Archived blocks", "from", base, "to", retainHeight-1, "pruned", pruned)
+	return nil
+}
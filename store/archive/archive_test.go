@@ -0,0 +1,78 @@
+package archive
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/log"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/store"
+	"github.com/tendermint/tendermint/types"
+)
+
+func makeBlockStore(t *testing.T, n int64) (*store.BlockStore, func()) {
+	config := cfg.ResetTestRoot("archive_test")
+	state, err := sm.LoadStateFromDBOrGenesisFile(dbm.NewMemDB(), config.GenesisFile())
+	require.NoError(t, err)
+
+	bs := store.NewBlockStore(dbm.NewMemDB())
+	for h := int64(1); h <= n; h++ {
+		block, _ := state.MakeBlock(h, []types.Tx{types.Tx{byte(h)}}, new(types.Commit), nil,
+			state.Validators.GetProposer().Address)
+		seenCommit := types.NewCommit(types.BlockID{Hash: block.Hash()},
+			[]*types.CommitSig{{Height: h, Timestamp: time.Now()}})
+		bs.SaveBlock(block, block.MakePartSet(types.BlockPartSizeBytes), seenCommit)
+	}
+	return bs, func() { os.RemoveAll(config.RootDir) }
+}
+
+func TestArchiverArchivesAndPrunes(t *testing.T) {
+	bs, cleanup := makeBlockStore(t, 30)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "archive_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	objectStore, err := NewObjectStore("file://"+dir, "")
+	require.NoError(t, err)
+
+	blocks := make(map[int64]*types.Block)
+	for h := int64(1); h <= 30; h++ {
+		blocks[h] = bs.LoadBlock(h)
+	}
+
+	archive := NewArchive(objectStore)
+	archiver := NewArchiver(archive, bs, 10)
+	archiver.SetLogger(log.TestingLogger())
+	require.NoError(t, archiver.archiveBlocks())
+
+	assert.EqualValues(t, 21, bs.Base())
+	assert.Nil(t, bs.LoadBlock(20))
+
+	ctx := context.Background()
+	for h := int64(1); h <= 20; h++ {
+		meta, block, err := archive.LoadBlock(ctx, h)
+		require.NoError(t, err, "height %d", h)
+		assert.Equal(t, blocks[h].Hash(), block.Hash())
+		assert.Equal(t, blocks[h].Hash(), meta.BlockID.Hash)
+
+		commit, err := archive.LoadBlockCommit(ctx, h)
+		require.NoError(t, err)
+		assert.Equal(t, blocks[h+1].LastCommit.Hash(), commit.Hash())
+	}
+	_, _, err = archive.LoadBlock(ctx, 21)
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+
+	// nothing left to archive
+	require.NoError(t, archiver.archiveBlocks())
+	assert.EqualValues(t, 21, bs.Base())
+}
@@ -0,0 +1,97 @@
+package archive
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by ObjectStore#Get when there is no object with
+// the given key.
+var ErrNotFound = errors.New("object not found")
+
+// ObjectStore is a flat store of immutable objects, such as an S3 or GCS
+// bucket.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// NewObjectStore returns the ObjectStore at the given URL:
+//   - file:///path/to/dir: a local (or mounted) directory
+//   - s3://bucket/prefix: an S3 bucket
+//   - gs://bucket/prefix: a GCS bucket, through its S3 compatible API
+//
+// S3 and GCS are authenticated with the AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY environment variables (HMAC keys for GCS). endpoint
+// overrides the default endpoint of the service (e.g. for MinIO), and may be
+// empty.
+func NewObjectStore(rawURL, endpoint string) (ObjectStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid object store URL")
+	}
+	prefix := strings.Trim(u.Path, "/")
+
+	switch u.Scheme {
+	case "file":
+		return NewDirStore(u.Path)
+	case "s3":
+		if endpoint == "" {
+			endpoint = "https://s3.amazonaws.com"
+		}
+		return newS3Store(endpoint, u.Host, prefix, defaultS3Region())
+	case "gs":
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		return newS3Store(endpoint, u.Host, prefix, "auto")
+	default:
+		return nil, errors.Errorf("unknown object store scheme %q (want file, s3 or gs)", u.Scheme)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// DirStore is an ObjectStore keeping objects as files in a directory.
+type DirStore struct {
+	dir string
+}
+
+var _ ObjectStore = (*DirStore)(nil)
+
+// NewDirStore returns a DirStore keeping objects under dir, which is created
+// if needed.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to create object store directory")
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// Put writes the object atomically, by renaming a temporary file.
+func (ds *DirStore) Put(_ context.Context, key string, data []byte) error {
+	path := filepath.Join(ds.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get implements ObjectStore.
+func (ds *DirStore) Get(_ context.Context, key string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filepath.Join(ds.dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
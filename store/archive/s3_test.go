@@ -0,0 +1,71 @@
+package archive
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3StoreRoundTrip(t *testing.T) {
+	var (
+		mtx     sync.Mutex
+		objects = make(map[string][]byte)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(auth, "/us-east-1/s3/aws4_request") ||
+			r.Header.Get("x-amz-content-sha256") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := ioutil.ReadAll(r.Body)
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(body)
+		}
+	}))
+	defer srv.Close()
+
+	os.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	defer os.Unsetenv("AWS_ACCESS_KEY_ID")
+	defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+	s, err := NewObjectStore("s3://bucket/chain-1", srv.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s.Put(ctx, "blocks/1", []byte("block")))
+	assert.Contains(t, objects, "/bucket/chain-1/blocks/1")
+
+	data, err := s.Get(ctx, "blocks/1")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("block"), data)
+
+	_, err = s.Get(ctx, "blocks/2")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestNewObjectStoreRejectsUnknownSchemes(t *testing.T) {
+	_, err := NewObjectStore("ftp://host/dir", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,298 @@
+// Package chainfile reads and writes chain archives: the headers, data,
+// commits and validator sets of a range of heights, in a flat-file format
+// which doesn't require the Go types to be produced or consumed. The format
+// is specified in docs/spec/blockchain/chain-archive.md.
+//
+// An archive is a directory holding:
+//   - MANIFEST.json: the format, its version, the chain ID and the heights
+//   - chain.jsonl: one JSON record per height, in height order
+//   - chain.idx: the offset and length of each record in chain.jsonl
+//
+// The manifest is written last, so that an archive without a manifest is
+// incomplete.
+package chainfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	amino "github.com/tendermint/go-amino"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// Format identifies chain archives in their manifest.
+	Format = "tendermint-chain-archive"
+
+	// Version is the version of the format written by this package. Readers
+	// reject the archives of a later version.
+	Version = 1
+
+	// ManifestFile, RecordsFile and IndexFile are the files of an archive.
+	ManifestFile = "MANIFEST.json"
+	RecordsFile  = "chain.jsonl"
+	IndexFile    = "chain.idx"
+
+	// indexEntrySize is the size of an entry of the index: the big endian
+	// offset and length of a record.
+	indexEntrySize = 16
+)
+
+var cdc = amino.NewCodec()
+
+func init() {
+	types.RegisterBlockAmino(cdc)
+}
+
+// Manifest describes an archive.
+type Manifest struct {
+	Format      string `json:"format"`
+	Version     int    `json:"version"`
+	ChainID     string `json:"chain_id"`
+	FirstHeight int64  `json:"first_height"`
+	LastHeight  int64  `json:"last_height"`
+}
+
+// Record holds a block, split into its header, data, evidence and last
+// commit, the commit for it and the validators which signed that commit.
+type Record struct {
+	Height     int64               `json:"height"`
+	Header     types.Header        `json:"header"`
+	Data       types.Data          `json:"data"`
+	Evidence   types.EvidenceData  `json:"evidence"`
+	LastCommit *types.Commit       `json:"last_commit"`
+	Commit     *types.Commit       `json:"commit"`
+	Validators *types.ValidatorSet `json:"validators"`
+}
+
+// NewRecord returns the record of the block, the commit for it and the
+// validators of its height.
+func NewRecord(block *types.Block, commit *types.Commit, vals *types.ValidatorSet) *Record {
+	return &Record{
+		Height:     block.Height,
+		Header:     block.Header,
+		Data:       block.Data,
+		Evidence:   block.Evidence,
+		LastCommit: block.LastCommit,
+		Commit:     commit,
+		Validators: vals,
+	}
+}
+
+// Block returns the block of the record.
+func (r *Record) Block() *types.Block {
+	return &types.Block{
+		Header:     r.Header,
+		Data:       r.Data,
+		Evidence:   r.Evidence,
+		LastCommit: r.LastCommit,
+	}
+}
+
+// Verify checks that the record is consistent: the validators are the ones
+// of the header, and +2/3 of them signed the commit for the block.
+func (r *Record) Verify(chainID string) error {
+	if r.Height != r.Header.Height {
+		return fmt.Errorf("record of height %d holds the header of height %d", r.Height, r.Header.Height)
+	}
+	if r.Header.ChainID != chainID {
+		return fmt.Errorf("wrong chain ID %q (want %q)", r.Header.ChainID, chainID)
+	}
+	if r.Commit == nil || r.Validators == nil {
+		return errors.New("missing commit or validators")
+	}
+	block := r.Block()
+	if err := block.ValidateBasic(); err != nil {
+		return errors.Wrap(err, "invalid block")
+	}
+	if hash := r.Validators.Hash(); !bytes.Equal(hash, block.ValidatorsHash) {
+		return fmt.Errorf("validators hash %X doesn't match the header (%X)", hash, block.ValidatorsHash)
+	}
+	if !block.HashesTo(r.Commit.BlockID.Hash) {
+		return fmt.Errorf("block hash %X doesn't match the commit (%X)", block.Hash(), r.Commit.BlockID.Hash)
+	}
+	return r.Validators.VerifyCommit(chainID, r.Commit.BlockID, r.Height, r.Commit)
+}
+
+//-----------------------------------------------------------------------------
+
+// Writer writes an archive, one height at a time.
+type Writer struct {
+	dir         string
+	manifest    Manifest
+	recordsFile *os.File
+	records     *bufio.Writer
+	indexFile   *os.File
+	index       *bufio.Writer
+	offset      uint64 // of the next record
+}
+
+// NewWriter creates an archive of the given chain in dir, which must not hold
+// an archive already.
+func NewWriter(dir, chainID string) (*Writer, error) {
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+		return nil, fmt.Errorf("%s already holds an archive", dir)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to create the archive directory")
+	}
+	records, err := os.Create(filepath.Join(dir, RecordsFile))
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.Create(filepath.Join(dir, IndexFile))
+	if err != nil {
+		records.Close()
+		return nil, err
+	}
+	return &Writer{
+		dir:         dir,
+		manifest:    Manifest{Format: Format, Version: Version, ChainID: chainID},
+		recordsFile: records,
+		records:     bufio.NewWriter(records),
+		indexFile:   index,
+		index:       bufio.NewWriter(index),
+	}, nil
+}
+
+// Append writes the record, whose height must follow the one of the
+// previous record.
+func (w *Writer) Append(r *Record) error {
+	if w.manifest.LastHeight > 0 && r.Height != w.manifest.LastHeight+1 {
+		return fmt.Errorf("can only append contiguous heights: wanted %d, got %d",
+			w.manifest.LastHeight+1, r.Height)
+	}
+	bz, err := cdc.MarshalJSON(r)
+	if err != nil {
+		return err
+	}
+	if _, err := w.records.Write(append(bz, '\n')); err != nil {
+		return err
+	}
+	var entry [indexEntrySize]byte
+	binary.BigEndian.PutUint64(entry[:8], w.offset)
+	binary.BigEndian.PutUint64(entry[8:], uint64(len(bz)))
+	if _, err := w.index.Write(entry[:]); err != nil {
+		return err
+	}
+	w.offset += uint64(len(bz)) + 1
+
+	if w.manifest.FirstHeight == 0 {
+		w.manifest.FirstHeight = r.Height
+	}
+	w.manifest.LastHeight = r.Height
+	return nil
+}
+
+// Close flushes the records and the index, and writes the manifest.
+func (w *Writer) Close() error {
+	defer w.recordsFile.Close()
+	defer w.indexFile.Close()
+
+	if w.manifest.LastHeight == 0 {
+		return errors.New("the archive is empty")
+	}
+	for _, f := range []struct {
+		buf  *bufio.Writer
+		file *os.File
+	}{{w.records, w.recordsFile}, {w.index, w.indexFile}} {
+		if err := f.buf.Flush(); err != nil {
+			return err
+		}
+		if err := f.file.Sync(); err != nil {
+			return err
+		}
+	}
+	bz, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(w.dir, ManifestFile), bz, 0600)
+}
+
+//-----------------------------------------------------------------------------
+
+// Reader reads the records of an archive.
+type Reader struct {
+	manifest Manifest
+	records  *os.File
+	index    *os.File
+}
+
+// Open opens the archive in dir. It fails if the archive is incomplete, or of
+// an unsupported version.
+func Open(dir string) (*Reader, error) {
+	bz, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the manifest (incomplete archive?)")
+	}
+	var m Manifest
+	if err := json.Unmarshal(bz, &m); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the manifest")
+	}
+	switch {
+	case m.Format != Format:
+		return nil, fmt.Errorf("unknown archive format %q", m.Format)
+	case m.Version < 1 || m.Version > Version:
+		return nil, fmt.Errorf("unsupported archive version %d (this node supports up to %d)", m.Version, Version)
+	case m.FirstHeight < 1 || m.LastHeight < m.FirstHeight:
+		return nil, fmt.Errorf("invalid heights [%d, %d]", m.FirstHeight, m.LastHeight)
+	}
+
+	records, err := os.Open(filepath.Join(dir, RecordsFile))
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.Open(filepath.Join(dir, IndexFile))
+	if err != nil {
+		records.Close()
+		return nil, err
+	}
+	return &Reader{manifest: m, records: records, index: index}, nil
+}
+
+// Manifest returns the manifest of the archive.
+func (r *Reader) Manifest() Manifest {
+	return r.manifest
+}
+
+// Read returns the record at the given height.
+func (r *Reader) Read(height int64) (*Record, error) {
+	if height < r.manifest.FirstHeight || height > r.manifest.LastHeight {
+		return nil, fmt.Errorf("height %d is not in the archive [%d, %d]",
+			height, r.manifest.FirstHeight, r.manifest.LastHeight)
+	}
+	var entry [indexEntrySize]byte
+	if _, err := r.index.ReadAt(entry[:], (height-r.manifest.FirstHeight)*indexEntrySize); err != nil {
+		return nil, errors.Wrapf(err, "failed to read the index entry of height %d", height)
+	}
+	offset, length := binary.BigEndian.Uint64(entry[:8]), binary.BigEndian.Uint64(entry[8:])
+	bz := make([]byte, length)
+	if _, err := r.records.ReadAt(bz, int64(offset)); err != nil && err != io.EOF {
+		return nil, errors.Wrapf(err, "failed to read the record of height %d", height)
+	}
+	rec := new(Record)
+	if err := cdc.UnmarshalJSON(bz, rec); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode the record of height %d", height)
+	}
+	if rec.Height != height {
+		return nil, fmt.Errorf("the index points to the record of height %d instead of %d", rec.Height, height)
+	}
+	return rec, nil
+}
+
+// Close closes the files of the archive.
+func (r *Reader) Close() error {
+	r.index.Close()
+	return r.records.Close()
+}
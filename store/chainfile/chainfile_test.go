@@ -0,0 +1,118 @@
+package chainfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const testChainID = "chainfile-test"
+
+// makeRecords returns the records of a chain of n blocks signed by 2
+// validators.
+func makeRecords(t *testing.T, n int64) []*Record {
+	vals, privVals := types.RandValidatorSet(2, 10)
+	records := make([]*Record, 0, n)
+	var lastCommit *types.Commit
+	for h := int64(1); h <= n; h++ {
+		block := types.MakeBlock(h, []types.Tx{types.Tx{byte(h)}}, lastCommit, nil)
+		block.ChainID = testChainID
+		block.ValidatorsHash = vals.Hash()
+		block.ProposerAddress = vals.GetProposer().Address
+		blockID := types.BlockID{Hash: block.Hash(), PartsHeader: block.MakePartSet(types.BlockPartSizeBytes).Header()}
+		voteSet := types.NewVoteSet(testChainID, h, 0, types.PrecommitType, vals)
+		commit, err := types.MakeCommit(blockID, h, 0, voteSet, privVals)
+		require.NoError(t, err)
+		records = append(records, NewRecord(block, commit, vals))
+		lastCommit = commit
+	}
+	return records
+}
+
+func TestWriteAndRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chainfile_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	records := makeRecords(t, 5)
+	w, err := NewWriter(dir, testChainID)
+	require.NoError(t, err)
+	for _, rec := range records[1:] {
+		require.NoError(t, w.Append(rec))
+	}
+	assert.Error(t, w.Append(records[1]), "heights must be contiguous")
+	require.NoError(t, w.Close())
+
+	_, err = NewWriter(dir, testChainID)
+	assert.Error(t, err, "the directory holds an archive")
+
+	r, err := Open(dir)
+	require.NoError(t, err)
+	defer r.Close()
+	assert.Equal(t, Manifest{Format: Format, Version: Version, ChainID: testChainID, FirstHeight: 2, LastHeight: 5},
+		r.Manifest())
+	for _, h := range []int64{4, 2, 5, 3} {
+		rec, err := r.Read(h)
+		require.NoError(t, err)
+		require.NoError(t, rec.Verify(testChainID))
+		assert.Equal(t, records[h-1].Block().Hash(), rec.Block().Hash())
+	}
+	_, err = r.Read(1)
+	assert.Error(t, err)
+	_, err = r.Read(6)
+	assert.Error(t, err)
+}
+
+func TestRecordVerify(t *testing.T) {
+	records := makeRecords(t, 2)
+	other := makeRecords(t, 2)
+
+	testCases := []struct {
+		name     string
+		malleate func(rec *Record)
+		chainID  string
+	}{
+		{"wrong chain ID", func(rec *Record) {}, "other-chain"},
+		{"wrong height", func(rec *Record) { rec.Height = 3 }, testChainID},
+		{"tampered data", func(rec *Record) { rec.Data = types.Data{Txs: []types.Tx{{0xff}}} }, testChainID},
+		{"other validators", func(rec *Record) { rec.Validators = other[1].Validators }, testChainID},
+		{"commit for another block", func(rec *Record) { rec.Commit = records[0].Commit }, testChainID},
+		{"missing commit", func(rec *Record) { rec.Commit = nil }, testChainID},
+	}
+	require.NoError(t, records[1].Verify(testChainID))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := *records[1]
+			tc.malleate(&rec)
+			assert.Error(t, rec.Verify(tc.chainID))
+		})
+	}
+}
+
+func TestOpenRejectsUnsupportedArchives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chainfile_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = Open(dir)
+	assert.Error(t, err, "no manifest")
+
+	for _, m := range []Manifest{
+		{Format: "other", Version: 1, FirstHeight: 1, LastHeight: 1},
+		{Format: Format, Version: Version + 1, FirstHeight: 1, LastHeight: 1},
+		{Format: Format, Version: Version, FirstHeight: 2, LastHeight: 1},
+	} {
+		bz, err := json.Marshal(m)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ManifestFile), bz, 0600))
+		_, err = Open(dir)
+		assert.Error(t, err, "%+v", m)
+	}
+}
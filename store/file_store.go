@@ -0,0 +1,389 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// DefaultSegmentSize is the size after which FileBlockStore starts a new
+// segment file.
+const DefaultSegmentSize = 512 * 1024 * 1024 // 512MB
+
+/*
+FileBlockStore is a BlockStore keeping block parts in flat append-only
+segment files under a directory, rather than in the key-value DB.
+
+The block meta, commits and an index locating each block's parts within the
+segments are kept in the DB, exactly like BlockStore does. Since the parts of
+consecutive blocks are laid out contiguously on disk, loading a range of
+blocks (e.g. when serving fast sync) turns into sequential reads.
+
+A block is appended and synced to its segment before its index entry and meta
+are written, so a crash may leave a torn tail, which is truncated on the next
+start.
+*/
+type FileBlockStore struct {
+	*BlockStore
+
+	dir         string
+	segmentSize int64
+
+	wmtx    sync.Mutex
+	segment int64    // index of the segment being appended to
+	wfile   *os.File // segment being appended to
+	woffset int64    // size of the segment being appended to
+
+	rmtx   sync.Mutex
+	rfiles map[int64]*os.File // read handles, by segment index
+}
+
+// fileBlockIndex locates the parts of a block within the segment files.
+type fileBlockIndex struct {
+	Segment   int64 `json:"segment"`
+	Offset    int64 `json:"offset"`
+	PartSizes []int `json:"part_sizes"`
+	// The parts start with the ID of their codec (see compress.Registry).
+	Compressed bool `json:"compressed"`
+}
+
+func (idx fileBlockIndex) end() int64 {
+	end := idx.Offset
+	for _, size := range idx.PartSizes {
+		end += int64(size)
+	}
+	return end
+}
+
+// NewFileBlockStore returns a new FileBlockStore with the given DB, keeping
+// segment files under dir. It truncates any data appended after the last
+// block that was committed to the DB.
+func NewFileBlockStore(db dbm.DB, dir string, segmentSize int64) (*FileBlockStore, error) {
+	if segmentSize <= 0 {
+		return nil, fmt.Errorf("segment size must be positive, got %d", segmentSize)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "failed to create block store directory")
+	}
+
+	fbs := &FileBlockStore{
+		BlockStore:  NewBlockStore(db),
+		dir:         dir,
+		segmentSize: segmentSize,
+		rfiles:      make(map[int64]*os.File),
+	}
+
+	var tail fileBlockIndex
+	if height := fbs.Height(); height > 0 {
+		idx := fbs.loadBlockIndex(height)
+		if idx == nil {
+			return nil, fmt.Errorf("no segment index for the last block %d", height)
+		}
+		tail = fileBlockIndex{Segment: idx.Segment, Offset: idx.end()}
+	}
+	if err := fbs.removeSegmentsAfter(tail.Segment); err != nil {
+		return nil, err
+	}
+	if err := fbs.openSegment(tail.Segment, tail.Offset); err != nil {
+		return nil, err
+	}
+	return fbs, nil
+}
+
+// LoadBlock returns the block with the given height, reading all its data
+// parts at once. If no block is found for that height, it returns nil.
+func (fbs *FileBlockStore) LoadBlock(height int64) *types.Block {
+	var blockMeta = fbs.LoadBlockMeta(height)
+	if blockMeta == nil {
+		return nil
+	}
+	idx := fbs.loadBlockIndex(height)
+	if idx == nil {
+		panic(fmt.Sprintf("no segment index for block %d", height))
+	}
+
+	// The parity parts come after the data parts.
+	dataTotal := blockMeta.BlockID.PartsHeader.DataTotal(blockMeta.Header.Version.Block)
+	sizes := idx.PartSizes[:dataTotal]
+	bz, err := fbs.readSegment(idx.Segment, idx.Offset,
+		fileBlockIndex{PartSizes: sizes}.end())
+	if err != nil {
+		panic(errors.Wrap(err, "Error reading block"))
+	}
+
+	buf := []byte{}
+	for _, size := range sizes {
+		part, err := fbs.decodePart(idx, bz[:size])
+		if err != nil {
+			panic(errors.Wrap(err, "Error reading block part"))
+		}
+		buf = append(buf, part.Bytes...)
+		bz = bz[size:]
+	}
+
+	var block = new(types.Block)
+	err = cdc.UnmarshalBinaryLengthPrefixed(buf, block)
+	if err != nil {
+		panic(errors.Wrap(err, "Error reading block"))
+	}
+	return block
+}
+
+// LoadBlockPart returns the Part at the given index
+// from the block at the given height.
+// If no part is found for the given height and index, it returns nil.
+func (fbs *FileBlockStore) LoadBlockPart(height int64, index int) *types.Part {
+	idx := fbs.loadBlockIndex(height)
+	if idx == nil || index < 0 || index >= len(idx.PartSizes) {
+		return nil
+	}
+	offset := fileBlockIndex{Offset: idx.Offset, PartSizes: idx.PartSizes[:index]}.end()
+	bz, err := fbs.readSegment(idx.Segment, offset, int64(idx.PartSizes[index]))
+	if err != nil {
+		panic(errors.Wrap(err, "Error reading block part"))
+	}
+	part, err := fbs.decodePart(idx, bz)
+	if err != nil {
+		panic(errors.Wrap(err, "Error reading block part"))
+	}
+	return part
+}
+
+// decodePart decodes a part read from the segment of idx.
+func (fbs *FileBlockStore) decodePart(idx *fileBlockIndex, bz []byte) (*types.Part, error) {
+	if idx.Compressed {
+		var err error
+		if bz, err = fbs.codecs.Decode(bz); err != nil {
+			return nil, err
+		}
+	}
+	var part = new(types.Part)
+	if err := cdc.UnmarshalBinaryBare(bz, part); err != nil {
+		return nil, err
+	}
+	return part, nil
+}
+
+// RecompressBlock isn't supported: the segments are append-only, so the
+// existing parts keep the codec they were saved with.
+func (fbs *FileBlockStore) RecompressBlock(height int64) (bool, error) {
+	return false, errors.New("the flatfile block store can't recompress the existing blocks")
+}
+
+// SaveBlock appends the given blockParts to the current segment and persists
+// the block meta, index and seenCommit to the underlying db.
+// See BlockStore.SaveBlock.
+func (fbs *FileBlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
+	if block == nil {
+		panic("BlockStore can only save a non-nil block")
+	}
+	height := block.Height
+	if g, w := height, fbs.Height()+1; fbs.Base() > 0 && g != w {
+		panic(fmt.Sprintf("BlockStore can only save contiguous blocks. Wanted %v, got %v", w, g))
+	}
+	if !blockParts.IsComplete() {
+		panic(fmt.Sprintf("BlockStore can only save complete block part sets"))
+	}
+
+	// Append block parts, and sync them before anything points at them
+	idx, err := fbs.appendParts(blockParts)
+	if err != nil {
+		panic(errors.Wrap(err, "Error appending block parts"))
+	}
+	fbs.db.Set(calcBlockIndexKey(height), cdc.MustMarshalBinaryBare(idx))
+
+	// Save block meta
+	blockMeta := types.NewBlockMeta(block, blockParts)
+	metaBytes := cdc.MustMarshalBinaryBare(blockMeta)
+	fbs.db.Set(calcBlockMetaKey(height), metaBytes)
+
+	// Save block commit (duplicate and separate from the Block)
+	blockCommitBytes := cdc.MustMarshalBinaryBare(block.LastCommit)
+	fbs.db.Set(calcBlockCommitKey(height-1), blockCommitBytes)
+
+	// Save seen commit (seen +2/3 precommits for block)
+	seenCommitBytes := cdc.MustMarshalBinaryBare(seenCommit)
+	fbs.db.Set(calcSeenCommitKey(height), seenCommitBytes)
+
+	// Save new BlockStoreStateJSON descriptor, and we're done!
+	fbs.saveHeight(height)
+
+	// Flush
+	fbs.db.SetSync(nil, nil)
+}
+
+// PruneBlocks removes the blocks below the given height from the DB, and the
+// segments holding pruned blocks only. See BlockStore.PruneBlocks.
+func (fbs *FileBlockStore) PruneBlocks(height int64) (uint64, error) {
+	base, err := fbs.setBase(height)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := uint64(0)
+	for h := base; h < height; h++ {
+		if fbs.LoadBlockMeta(h) == nil {
+			continue
+		}
+		fbs.db.Delete(calcBlockIndexKey(h))
+		fbs.deleteBlockMeta(h)
+		pruned++
+	}
+
+	// Segments before the one of the new base hold pruned blocks only.
+	idx := fbs.loadBlockIndex(height)
+	if idx == nil {
+		return pruned, nil
+	}
+	for segment := idx.Segment - 1; segment >= 0; segment-- {
+		fbs.rmtx.Lock()
+		if f, ok := fbs.rfiles[segment]; ok {
+			f.Close()
+			delete(fbs.rfiles, segment)
+		}
+		fbs.rmtx.Unlock()
+
+		err := os.Remove(fbs.segmentPath(segment))
+		if os.IsNotExist(err) {
+			break // removed by an earlier pruning
+		}
+		if err != nil {
+			return pruned, errors.Wrap(err, "failed to remove segment")
+		}
+	}
+	return pruned, nil
+}
+
+// Close closes the segment files. It does not close the DB.
+func (fbs *FileBlockStore) Close() error {
+	fbs.wmtx.Lock()
+	err := fbs.wfile.Close()
+	fbs.wmtx.Unlock()
+
+	fbs.rmtx.Lock()
+	defer fbs.rmtx.Unlock()
+	for segment, f := range fbs.rfiles {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(fbs.rfiles, segment)
+	}
+	return err
+}
+
+func (fbs *FileBlockStore) appendParts(blockParts *types.PartSet) (fileBlockIndex, error) {
+	fbs.wmtx.Lock()
+	defer fbs.wmtx.Unlock()
+
+	if fbs.woffset >= fbs.segmentSize {
+		if err := fbs.wfile.Close(); err != nil {
+			return fileBlockIndex{}, err
+		}
+		if err := fbs.openSegment(fbs.segment+1, 0); err != nil {
+			return fileBlockIndex{}, err
+		}
+	}
+
+	idx := fileBlockIndex{
+		Segment:    fbs.segment,
+		Offset:     fbs.woffset,
+		PartSizes:  make([]int, blockParts.Total()),
+		Compressed: fbs.codec != nil,
+	}
+	buf := []byte{}
+	for i := 0; i < blockParts.Total(); i++ {
+		partBytes := cdc.MustMarshalBinaryBare(blockParts.GetPart(i))
+		if fbs.codec != nil {
+			var err error
+			if partBytes, err = fbs.codecs.Encode(fbs.codec, partBytes); err != nil {
+				return fileBlockIndex{}, err
+			}
+		}
+		idx.PartSizes[i] = len(partBytes)
+		buf = append(buf, partBytes...)
+	}
+	if _, err := fbs.wfile.WriteAt(buf, fbs.woffset); err != nil {
+		return fileBlockIndex{}, err
+	}
+	if err := fbs.wfile.Sync(); err != nil {
+		return fileBlockIndex{}, err
+	}
+	fbs.woffset += int64(len(buf))
+	return idx, nil
+}
+
+// openSegment opens the given segment for appending, truncating it to size.
+func (fbs *FileBlockStore) openSegment(segment, size int64) error {
+	f, err := os.OpenFile(fbs.segmentPath(segment), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to open segment")
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to truncate segment")
+	}
+	fbs.segment, fbs.wfile, fbs.woffset = segment, f, size
+	return nil
+}
+
+// removeSegmentsAfter deletes segments that were created after the given one,
+// but never got a block committed.
+func (fbs *FileBlockStore) removeSegmentsAfter(segment int64) error {
+	for s := segment + 1; ; s++ {
+		err := os.Remove(fbs.segmentPath(s))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to remove segment")
+		}
+	}
+}
+
+func (fbs *FileBlockStore) readSegment(segment, offset, size int64) ([]byte, error) {
+	fbs.rmtx.Lock()
+	f, ok := fbs.rfiles[segment]
+	if !ok {
+		var err error
+		f, err = os.Open(fbs.segmentPath(segment))
+		if err != nil {
+			fbs.rmtx.Unlock()
+			return nil, err
+		}
+		fbs.rfiles[segment] = f
+	}
+	fbs.rmtx.Unlock()
+
+	bz := make([]byte, size)
+	if _, err := f.ReadAt(bz, offset); err != nil {
+		return nil, err
+	}
+	return bz, nil
+}
+
+func (fbs *FileBlockStore) loadBlockIndex(height int64) *fileBlockIndex {
+	bz := fbs.db.Get(calcBlockIndexKey(height))
+	if len(bz) == 0 {
+		return nil
+	}
+	var idx = new(fileBlockIndex)
+	if err := cdc.UnmarshalBinaryBare(bz, idx); err != nil {
+		panic(errors.Wrap(err, "Error reading block index"))
+	}
+	return idx
+}
+
+func (fbs *FileBlockStore) segmentPath(segment int64) string {
+	return filepath.Join(fbs.dir, fmt.Sprintf("%08d.blocks", segment))
+}
+
+func calcBlockIndexKey(height int64) []byte {
+	return []byte(fmt.Sprintf("F:%v", height))
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	dbm "github.com/tendermint/tm-db"
+
+	"github.com/tendermint/tendermint/libs/compress"
+	"github.com/tendermint/tendermint/types"
+	tmtime "github.com/tendermint/tendermint/types/time"
+)
+
+func saveTestBlocks(t *testing.T, bs *FileBlockStore, n int64) []*types.Block {
+	blocks := make([]*types.Block, 0, n)
+	for h := bs.Height() + 1; h <= n; h++ {
+		block := makeBlock(h, state, new(types.Commit))
+		bs.SaveBlock(block, block.MakePartSet(64), makeTestCommit(h, tmtime.Now()))
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+func TestFileBlockStoreSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_block_store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := dbm.NewMemDB()
+	// a tiny segment size to start a new segment after every block
+	bs, err := NewFileBlockStore(db, dir, 1)
+	require.NoError(t, err)
+
+	blocks := saveTestBlocks(t, bs, 5)
+	require.EqualValues(t, 5, bs.Height())
+	require.NoError(t, bs.Close())
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*.blocks"))
+	require.NoError(t, err)
+	assert.Len(t, segments, 5)
+
+	// reopen and read everything back
+	bs, err = NewFileBlockStore(db, dir, 1)
+	require.NoError(t, err)
+	defer bs.Close()
+	require.EqualValues(t, 5, bs.Height())
+
+	for _, block := range blocks {
+		h := block.Height
+		got := bs.LoadBlock(h)
+		require.NotNil(t, got, "height %d", h)
+		assert.Equal(t, block.Hash(), got.Hash(), "height %d", h)
+
+		meta := bs.LoadBlockMeta(h)
+		require.NotNil(t, meta)
+		partSet := block.MakePartSet(64)
+		for i := 0; i < partSet.Total(); i++ {
+			part := bs.LoadBlockPart(h, i)
+			require.NotNil(t, part, "height %d part %d", h, i)
+			assert.Equal(t, partSet.GetPart(i).Bytes, part.Bytes)
+		}
+		assert.Nil(t, bs.LoadBlockPart(h, partSet.Total()))
+		assert.NotNil(t, bs.LoadSeenCommit(h))
+	}
+	assert.Nil(t, bs.LoadBlock(6))
+	assert.Nil(t, bs.LoadBlockPart(6, 0))
+}
+
+func TestFileBlockStoreTruncatesTornTail(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_block_store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := dbm.NewMemDB()
+	bs, err := NewFileBlockStore(db, dir, DefaultSegmentSize)
+	require.NoError(t, err)
+	saveTestBlocks(t, bs, 2)
+	end := bs.loadBlockIndex(2).end()
+	require.NoError(t, bs.Close())
+
+	// simulate a crash after appending the parts of block 3, and after
+	// starting a new segment
+	segment := filepath.Join(dir, "00000000.blocks")
+	f, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("torn block"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000001.blocks"), []byte("x"), 0600))
+
+	bs, err = NewFileBlockStore(db, dir, DefaultSegmentSize)
+	require.NoError(t, err)
+	defer bs.Close()
+
+	fi, err := os.Stat(segment)
+	require.NoError(t, err)
+	assert.Equal(t, end, fi.Size())
+	_, err = os.Stat(filepath.Join(dir, "00000001.blocks"))
+	assert.True(t, os.IsNotExist(err))
+
+	// appending resumes right after block 2
+	saveTestBlocks(t, bs, 3)
+	assert.NotNil(t, bs.LoadBlock(3))
+	assert.NotNil(t, bs.LoadBlock(2))
+}
+
+func TestFileBlockStoreCompression(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_block_store")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db := dbm.NewMemDB()
+	bs, err := NewFileBlockStore(db, dir, DefaultSegmentSize)
+	require.NoError(t, err)
+	blocks := saveTestBlocks(t, bs, 3)
+	codecs, snappy, err := LoadCompression(compress.CodecSnappy, "", compress.NopMetrics())
+	require.NoError(t, err)
+	bs.SetCompression(codecs, snappy)
+	blocks = append(blocks, saveTestBlocks(t, bs, 6)...)
+	require.NoError(t, bs.Close())
+
+	// the blocks saved before and after the compression is enabled are read
+	bs, err = NewFileBlockStore(db, dir, DefaultSegmentSize)
+	require.NoError(t, err)
+	defer bs.Close()
+	bs.SetCompression(codecs, snappy)
+	for _, block := range blocks {
+		h := block.Height
+		got := bs.LoadBlock(h)
+		require.NotNil(t, got, "height %d", h)
+		assert.Equal(t, block.Hash(), got.Hash(), "height %d", h)
+		assert.Equal(t, h > 3, bs.loadBlockIndex(h).Compressed, "height %d", h)
+		part := bs.LoadBlockPart(h, 0)
+		require.NotNil(t, part, "height %d", h)
+		assert.Equal(t, block.MakePartSet(64).GetPart(0).Bytes, part.Bytes)
+	}
+
+	// the segments are append-only
+	_, err = bs.RecompressBlock(1)
+	assert.Error(t, err)
+}
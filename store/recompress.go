@@ -0,0 +1,79 @@
+package store
+
+import (
+	"time"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// recompressPause is the pause of the Recompressor between blocks, so the
+// rewrite of an archive doesn't starve the node of disk IO.
+const recompressPause = 10 * time.Millisecond
+
+// RecompressibleBlockStore is a block store whose existing blocks can be
+// saved again with its current codec.
+type RecompressibleBlockStore interface {
+	Base() int64
+	Height() int64
+	RecompressBlock(height int64) (bool, error)
+}
+
+// Recompressor is a service saving the blocks of a block store written before
+// the compression was enabled or changed again with the current codec, from
+// the base up to the height at start. The blocks saved afterwards already use
+// the current codec. It stops once all the blocks are done, and starts over
+// on the next start of the node, skipping the blocks already done.
+type Recompressor struct {
+	cmn.BaseService
+
+	blockStore RecompressibleBlockStore
+}
+
+// NewRecompressor returns a new Recompressor.
+func NewRecompressor(blockStore RecompressibleBlockStore) *Recompressor {
+	r := &Recompressor{blockStore: blockStore}
+	r.BaseService = *cmn.NewBaseService(nil, "Recompressor", r)
+	return r
+}
+
+// OnStart implements cmn.Service.
+func (r *Recompressor) OnStart() error {
+	r.Go("recompressRoutine", r.recompressRoutine)
+	return nil
+}
+
+func (r *Recompressor) recompressRoutine() {
+	from, to := r.blockStore.Base(), r.blockStore.Height()
+	if from == 0 {
+		return
+	}
+	r.Logger.Info("Recompressing the blocks", "from", from, "to", to)
+
+	rewritten := 0
+	for height := from; height <= to; height++ {
+		if base := r.blockStore.Base(); height < base {
+			height = base // pruned meanwhile
+		}
+		done, err := r.blockStore.RecompressBlock(height)
+		if err != nil {
+			r.Logger.Error("Failed to recompress the blocks", "height", height, "err", err)
+			return
+		}
+
+		// the blocks already done are skipped without pause
+		pause := time.Duration(0)
+		if done {
+			rewritten++
+			pause = recompressPause
+			if rewritten%1000 == 0 {
+				r.Logger.Info("Recompressing the blocks", "height", height, "to", to)
+			}
+		}
+		select {
+		case <-time.After(pause):
+		case <-r.Quit():
+			return
+		}
+	}
+	r.Logger.Info("Recompressed the blocks", "from", from, "to", to, "rewritten", rewritten)
+}
@@ -2,15 +2,31 @@ package store
 
 import (
 	"fmt"
+	"io/ioutil"
 	"sync"
 
 	"github.com/pkg/errors"
 
 	dbm "github.com/tendermint/tm-db"
 
+	"github.com/tendermint/tendermint/libs/compress"
+	"github.com/tendermint/tendermint/libs/dbschema"
 	"github.com/tendermint/tendermint/types"
 )
 
+// Schema is the layout history of the block store DB, of both the BlockStore
+// and the FileBlockStore. A change of the keys or of the encoding of the
+// values must come with a migration, so that the block stores written by
+// older versions are migrated when the node starts.
+var Schema = dbschema.Schema{
+	Name: "blockstore",
+	Migrations: []dbschema.Migration{
+		// The older versions can't read the compressed block parts, so the
+		// version is bumped, but the existing parts are left as they are.
+		{Version: 2, Description: "compressed block parts", Migrate: func(dbm.DB) error { return nil }},
+	},
+}
+
 /*
 BlockStore is a simple low level store for blocks.
 
@@ -29,18 +45,74 @@ the Commit data outside the Block. (TODO)
 type BlockStore struct {
 	db dbm.DB
 
+	codecs *compress.Registry
+	codec  compress.Codec // nil if the parts aren't compressed
+
 	mtx    sync.RWMutex
+	base   int64
 	height int64
+
+	partsMtx sync.Mutex // serializes RecompressBlock and PruneBlocks
 }
 
 // NewBlockStore returns a new BlockStore with the given DB,
 // initialized to the last height that was committed to the DB.
 func NewBlockStore(db dbm.DB) *BlockStore {
 	bsjson := LoadBlockStoreStateJSON(db)
+	// Stores written before pruning was supported start at height 1.
+	if bsjson.Height > 0 && bsjson.Base == 0 {
+		bsjson.Base = 1
+	}
 	return &BlockStore{
+		base:   bsjson.Base,
 		height: bsjson.Height,
 		db:     db,
+		codecs: compress.NewRegistry(),
+	}
+}
+
+// LoadCompression returns the codecs to read a block store with, and the
+// codec named codecName to save the block parts with, with the zstd
+// dictionary of dictFile if it's not empty.
+func LoadCompression(codecName, dictFile string, metrics *compress.Metrics) (*compress.Registry, compress.Codec, error) {
+	codecs := compress.NewRegistry(compress.WithMetrics(metrics))
+	if dictFile != "" {
+		dict, err := ioutil.ReadFile(dictFile)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read the compression dictionary")
+		}
+		codec, err := compress.NewZstd(compress.DefaultZstdLevel, dict)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := codecs.Register(codec); err != nil {
+			return nil, nil, err
+		}
 	}
+	codec, err := codecs.Get(codecName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return codecs, codec, nil
+}
+
+// SetCompression compresses the block parts saved from now on with codec of
+// codecs, or not at all if codec is nil or none. The parts are decompressed
+// with the codec they were saved with, which must be in codecs. It must be
+// called before the block store is used.
+func (bs *BlockStore) SetCompression(codecs *compress.Registry, codec compress.Codec) {
+	if codec != nil && codec.ID() == compress.IDNone {
+		codec = nil
+	}
+	bs.codecs, bs.codec = codecs, codec
+}
+
+// Base returns the first known contiguous block height, or 0 for empty block
+// stores.
+func (bs *BlockStore) Base() int64 {
+	bs.mtx.RLock()
+	defer bs.mtx.RUnlock()
+	return bs.base
 }
 
 // Height returns the last known contiguous block height.
@@ -60,7 +132,8 @@ func (bs *BlockStore) LoadBlock(height int64) *types.Block {
 
 	var block = new(types.Block)
 	buf := []byte{}
-	for i := 0; i < blockMeta.BlockID.PartsHeader.Total; i++ {
+	// The parity parts come after the data parts.
+	for i := 0; i < blockMeta.BlockID.PartsHeader.DataTotal(blockMeta.Header.Version.Block); i++ {
 		part := bs.LoadBlockPart(height, i)
 		buf = append(buf, part.Bytes...)
 	}
@@ -78,11 +151,14 @@ func (bs *BlockStore) LoadBlock(height int64) *types.Block {
 // If no part is found for the given height and index, it returns nil.
 func (bs *BlockStore) LoadBlockPart(height int64, index int) *types.Part {
 	var part = new(types.Part)
-	bz := bs.db.Get(calcBlockPartKey(height, index))
+	bz, err := bs.loadBlockPartBytes(height, index)
+	if err != nil {
+		panic(errors.Wrap(err, "Error reading block part"))
+	}
 	if len(bz) == 0 {
 		return nil
 	}
-	err := cdc.UnmarshalBinaryBare(bz, part)
+	err = cdc.UnmarshalBinaryBare(bz, part)
 	if err != nil {
 		panic(errors.Wrap(err, "Error reading block part"))
 	}
@@ -137,18 +213,76 @@ func (bs *BlockStore) LoadSeenCommit(height int64) *types.Commit {
 	return commit
 }
 
+// PruneBlocks removes the blocks below the given height, and returns the
+// number of blocks pruned. The new base is persisted before any block is
+// deleted, so a crash leaves at worst unreachable blocks behind.
+func (bs *BlockStore) PruneBlocks(height int64) (uint64, error) {
+	base, err := bs.setBase(height)
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := uint64(0)
+	for h := base; h < height; h++ {
+		meta := bs.LoadBlockMeta(h)
+		if meta == nil {
+			continue
+		}
+		bs.partsMtx.Lock()
+		for i := 0; i < meta.BlockID.PartsHeader.Total; i++ {
+			bs.db.Delete(calcBlockPartKey(h, i))
+			bs.db.Delete(calcCompressedBlockPartKey(h, i))
+		}
+		bs.partsMtx.Unlock()
+		bs.deleteBlockMeta(h)
+		pruned++
+	}
+	return pruned, nil
+}
+
+// deleteBlockMeta deletes the meta and commits of the block at height.
+func (bs *BlockStore) deleteBlockMeta(height int64) {
+	bs.db.Delete(calcBlockCommitKey(height))
+	bs.db.Delete(calcSeenCommitKey(height))
+	bs.db.Delete(calcBlockMetaKey(height))
+}
+
+// setBase persists height as the new base, and returns the previous one.
+func (bs *BlockStore) setBase(height int64) (int64, error) {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+
+	if height <= 0 {
+		return 0, fmt.Errorf("height must be greater than 0")
+	}
+	if height > bs.height {
+		return 0, fmt.Errorf("cannot prune beyond the latest height %v", bs.height)
+	}
+	if height < bs.base {
+		return 0, fmt.Errorf("cannot prune to height %v, it is lower than base height %v",
+			height, bs.base)
+	}
+
+	base := bs.base
+	bs.base = height
+	BlockStoreStateJSON{Base: bs.base, Height: bs.height}.Save(bs.db)
+	return base, nil
+}
+
 // SaveBlock persists the given block, blockParts, and seenCommit to the underlying db.
 // blockParts: Must be parts of the block
 // seenCommit: The +2/3 precommits that were seen which committed at height.
 //             If all the nodes restart after committing a block,
 //             we need this to reload the precommits to catch-up nodes to the
 //             most recent height.  Otherwise they'd stall at H-1.
+// The first block of an empty store may be at any height (e.g. a trust
+// anchor); the next ones must be contiguous.
 func (bs *BlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
 	if block == nil {
 		panic("BlockStore can only save a non-nil block")
 	}
 	height := block.Height
-	if g, w := height, bs.Height()+1; g != w {
+	if g, w := height, bs.Height()+1; bs.Base() > 0 && g != w {
 		panic(fmt.Sprintf("BlockStore can only save contiguous blocks. Wanted %v, got %v", w, g))
 	}
 	if !blockParts.IsComplete() {
@@ -175,24 +309,100 @@ func (bs *BlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, s
 	seenCommitBytes := cdc.MustMarshalBinaryBare(seenCommit)
 	bs.db.Set(calcSeenCommitKey(height), seenCommitBytes)
 
-	// Save new BlockStoreStateJSON descriptor
-	BlockStoreStateJSON{Height: height}.Save(bs.db)
-
-	// Done!
-	bs.mtx.Lock()
-	bs.height = height
-	bs.mtx.Unlock()
+	// Save new BlockStoreStateJSON descriptor, and we're done!
+	bs.saveHeight(height)
 
 	// Flush
 	bs.db.SetSync(nil, nil)
 }
 
+// saveHeight persists height as the new height, and sets the base if the
+// store was empty.
+func (bs *BlockStore) saveHeight(height int64) {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	bs.height = height
+	if bs.base == 0 {
+		bs.base = height
+	}
+	BlockStoreStateJSON{Base: bs.base, Height: bs.height}.Save(bs.db)
+}
+
 func (bs *BlockStore) saveBlockPart(height int64, index int, part *types.Part) {
-	if height != bs.Height()+1 {
+	if bs.Base() > 0 && height != bs.Height()+1 {
 		panic(fmt.Sprintf("BlockStore can only save contiguous blocks. Wanted %v, got %v", bs.Height()+1, height))
 	}
 	partBytes := cdc.MustMarshalBinaryBare(part)
-	bs.db.Set(calcBlockPartKey(height, index), partBytes)
+	if bs.codec == nil {
+		bs.db.Set(calcBlockPartKey(height, index), partBytes)
+		return
+	}
+	bz, err := bs.codecs.Encode(bs.codec, partBytes)
+	if err != nil {
+		panic(errors.Wrap(err, "Error compressing block part"))
+	}
+	bs.db.Set(calcCompressedBlockPartKey(height, index), bz)
+}
+
+// loadBlockPartBytes returns the amino encoded part, decompressed if it was
+// saved compressed, or nil if there is no such part.
+func (bs *BlockStore) loadBlockPartBytes(height int64, index int) ([]byte, error) {
+	if bz := bs.db.Get(calcCompressedBlockPartKey(height, index)); len(bz) > 0 {
+		return bs.codecs.Decode(bz)
+	}
+	return bs.db.Get(calcBlockPartKey(height, index)), nil
+}
+
+// RecompressBlock saves the parts of the block at height again with the codec
+// of the block store (see SetCompression), unless they already are. It
+// returns true if the parts were rewritten.
+func (bs *BlockStore) RecompressBlock(height int64) (bool, error) {
+	bs.partsMtx.Lock()
+	defer bs.partsMtx.Unlock()
+
+	meta := bs.LoadBlockMeta(height)
+	if meta == nil || height < bs.Base() {
+		return false, nil // pruned
+	}
+
+	batch := bs.db.NewBatch()
+	defer batch.Close()
+	rewritten := false
+	for i := 0; i < meta.BlockID.PartsHeader.Total; i++ {
+		compressed := bs.db.Get(calcCompressedBlockPartKey(height, i))
+		switch {
+		case bs.codec == nil && len(compressed) == 0:
+			continue
+		case bs.codec != nil && len(compressed) > 0 &&
+			(compressed[0] == bs.codec.ID() || compressed[0] == compress.IDNone):
+			// the parts which don't shrink are stored uncompressed
+			continue
+		}
+
+		partBytes, err := bs.loadBlockPartBytes(height, i)
+		if err != nil {
+			return false, errors.Wrapf(err, "failed to load part %d of block %d", i, height)
+		}
+		if len(partBytes) == 0 {
+			return false, fmt.Errorf("part %d of block %d is missing", i, height)
+		}
+		if bs.codec == nil {
+			batch.Set(calcBlockPartKey(height, i), partBytes)
+			batch.Delete(calcCompressedBlockPartKey(height, i))
+		} else {
+			bz, err := bs.codecs.Encode(bs.codec, partBytes)
+			if err != nil {
+				return false, err
+			}
+			batch.Set(calcCompressedBlockPartKey(height, i), bz)
+			batch.Delete(calcBlockPartKey(height, i))
+		}
+		rewritten = true
+	}
+	if rewritten {
+		batch.WriteSync()
+	}
+	return rewritten, nil
 }
 
 //-----------------------------------------------------------------------------
@@ -205,6 +415,10 @@ func calcBlockPartKey(height int64, partIndex int) []byte {
 	return []byte(fmt.Sprintf("P:%v:%v", height, partIndex))
 }
 
+func calcCompressedBlockPartKey(height int64, partIndex int) []byte {
+	return []byte(fmt.Sprintf("PZ:%v:%v", height, partIndex))
+}
+
 func calcBlockCommitKey(height int64) []byte {
 	return []byte(fmt.Sprintf("C:%v", height))
 }
@@ -219,6 +433,7 @@ var blockStoreKey = []byte("blockStore")
 
 // BlockStoreStateJSON is the block store state JSON structure.
 type BlockStoreStateJSON struct {
+	Base   int64 `json:"base"`
 	Height int64 `json:"height"`
 }
 
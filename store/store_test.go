@@ -16,6 +16,7 @@ import (
 	dbm "github.com/tendermint/tm-db"
 
 	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/libs/compress"
 	"github.com/tendermint/tendermint/libs/log"
 	sm "github.com/tendermint/tendermint/state"
 
@@ -110,7 +111,6 @@ var (
 	block       *types.Block
 	partSet     *types.PartSet
 	part1       *types.Part
-	part2       *types.Part
 	seenCommit1 *types.Commit
 )
 
@@ -120,7 +120,6 @@ func TestMain(m *testing.M) {
 	block = makeBlock(1, state, new(types.Commit))
 	partSet = block.MakePartSet(2)
 	part1 = partSet.GetPart(0)
-	part2 = partSet.GetPart(1)
 	seenCommit1 = makeTestCommit(10, tmtime.Now())
 	code := m.Run()
 	cleanup()
@@ -150,17 +149,15 @@ func TestBlockStoreSaveLoadBlock(t *testing.T) {
 	require.Equal(t, bs.Height(), block.Header.Height, "expecting the new height to be changed")
 
 	incompletePartSet := types.NewPartSetFromHeader(types.PartSetHeader{Total: 2})
-	uncontiguousPartSet := types.NewPartSetFromHeader(types.PartSetHeader{Total: 0})
-	uncontiguousPartSet.AddPart(part2)
 
+	// The version decides how the block is split into the parts.
 	header1 := types.Header{
+		Version: block.Version,
 		Height:  1,
 		NumTxs:  100,
 		ChainID: "block_test",
 		Time:    tmtime.Now(),
 	}
-	header2 := header1
-	header2.Height = 4
 
 	// End of setup, test data
 
@@ -189,12 +186,6 @@ func TestBlockStoreSaveLoadBlock(t *testing.T) {
 			wantPanic: "only save a non-nil block",
 		},
 
-		{
-			block:     newBlock(header2, commitAtH10),
-			parts:     uncontiguousPartSet,
-			wantPanic: "only save contiguous blocks", // and incomplete and uncontiguous parts
-		},
-
 		{
 			block:     newBlock(header1, commitAtH10),
 			parts:     incompletePartSet,
@@ -434,3 +425,120 @@ func newBlock(hdr types.Header, lastCommit *types.Commit) *types.Block {
 		LastCommit: lastCommit,
 	}
 }
+
+func TestBlockStoreSaveFirstBlockAtAnyHeight(t *testing.T) {
+	bs, _ := freshBlockStore()
+
+	// e.g. the block of a trust anchor
+	block := makeBlock(100, state, new(types.Commit))
+	bs.SaveBlock(block, block.MakePartSet(2), makeTestCommit(100, tmtime.Now()))
+	assert.EqualValues(t, 100, bs.Base())
+	assert.EqualValues(t, 100, bs.Height())
+	assert.NotNil(t, bs.LoadBlock(100))
+
+	block = makeBlock(102, state, new(types.Commit))
+	assert.PanicsWithValue(t, "BlockStore can only save contiguous blocks. Wanted 101, got 102", func() {
+		bs.SaveBlock(block, block.MakePartSet(2), makeTestCommit(102, tmtime.Now()))
+	})
+}
+
+func TestBlockStorePruneBlocks(t *testing.T) {
+	bs, _ := freshBlockStore()
+	assert.EqualValues(t, 0, bs.Base())
+	assert.EqualValues(t, 0, bs.Height())
+
+	_, err := bs.PruneBlocks(1)
+	require.Error(t, err)
+
+	for h := int64(1); h <= 20; h++ {
+		block := makeBlock(h, state, new(types.Commit))
+		bs.SaveBlock(block, block.MakePartSet(2), makeTestCommit(h, tmtime.Now()))
+	}
+	assert.EqualValues(t, 1, bs.Base())
+	assert.EqualValues(t, 20, bs.Height())
+
+	pruned, err := bs.PruneBlocks(11)
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, pruned)
+	assert.EqualValues(t, 11, bs.Base())
+	assert.EqualValues(t, 20, bs.Height())
+	assert.Nil(t, bs.LoadBlock(10))
+	assert.Nil(t, bs.LoadBlockMeta(10))
+	assert.Nil(t, bs.LoadBlockPart(10, 0))
+	assert.Nil(t, bs.LoadSeenCommit(10))
+	assert.NotNil(t, bs.LoadBlock(11))
+
+	// the base survives reloading
+	bs = NewBlockStore(bs.db)
+	assert.EqualValues(t, 11, bs.Base())
+
+	_, err = bs.PruneBlocks(10)
+	require.Error(t, err, "below base")
+	_, err = bs.PruneBlocks(21)
+	require.Error(t, err, "beyond height")
+
+	pruned, err = bs.PruneBlocks(11)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, pruned)
+}
+
+func TestBlockStoreCompression(t *testing.T) {
+	bs, db := freshBlockStore()
+	hashes := make(map[int64][]byte)
+	saveBlocks := func(from, to int64) {
+		for h := from; h <= to; h++ {
+			block := makeBlock(h, state, new(types.Commit))
+			bs.SaveBlock(block, block.MakePartSet(2), makeTestCommit(h, tmtime.Now()))
+			hashes[h] = block.Hash()
+		}
+	}
+	loadBlocks := func() {
+		for h := bs.Base(); h <= bs.Height(); h++ {
+			block := bs.LoadBlock(h)
+			require.NotNil(t, block, "height %d", h)
+			assert.EqualValues(t, hashes[h], block.Hash(), "height %d", h)
+		}
+	}
+
+	// the blocks saved before and after the compression is enabled are read
+	saveBlocks(1, 5)
+	codecs, snappy, err := LoadCompression(compress.CodecSnappy, "", compress.NopMetrics())
+	require.NoError(t, err)
+	bs.SetCompression(codecs, snappy)
+	saveBlocks(6, 10)
+	loadBlocks()
+	assert.NotNil(t, db.Get(calcBlockPartKey(5, 0)))
+	assert.Nil(t, db.Get(calcCompressedBlockPartKey(5, 0)))
+	assert.Nil(t, db.Get(calcBlockPartKey(6, 0)))
+	assert.NotNil(t, db.Get(calcCompressedBlockPartKey(6, 0)))
+
+	// the existing blocks are recompressed once
+	for h := int64(1); h <= 10; h++ {
+		done, err := bs.RecompressBlock(h)
+		require.NoError(t, err)
+		assert.Equal(t, h <= 5, done, "height %d", h)
+	}
+	assert.Nil(t, db.Get(calcBlockPartKey(5, 0)))
+	assert.NotNil(t, db.Get(calcCompressedBlockPartKey(5, 0)))
+	loadBlocks()
+
+	// and decompressed without codec
+	bs.SetCompression(codecs, nil)
+	done, err := bs.RecompressBlock(6)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.NotNil(t, db.Get(calcBlockPartKey(6, 0)))
+	assert.Nil(t, db.Get(calcCompressedBlockPartKey(6, 0)))
+	loadBlocks()
+
+	// the pruned blocks are skipped, and their compressed parts deleted
+	_, err = bs.PruneBlocks(8)
+	require.NoError(t, err)
+	assert.Nil(t, db.Get(calcCompressedBlockPartKey(7, 0)))
+	done, err = bs.RecompressBlock(7)
+	require.NoError(t, err)
+	assert.False(t, done)
+
+	_, _, err = LoadCompression("lz4", "", compress.NopMetrics())
+	assert.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	amino "github.com/tendermint/go-amino"
 
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/merkle"
@@ -187,7 +188,9 @@ func (b *Block) Hash() cmn.HexBytes {
 }
 
 // MakePartSet returns a PartSet containing parts of a serialized block.
-// This is the form in which the block is gossipped to peers.
+// This is the form in which the block is gossipped to peers. The parts are
+// erasure coded if the block protocol version of the block says so (see
+// ErasureCodedBlockParts).
 // CONTRACT: partSize is greater than zero.
 func (b *Block) MakePartSet(partSize int) *PartSet {
 	if b == nil {
@@ -202,6 +205,9 @@ func (b *Block) MakePartSet(partSize int) *PartSet {
 	if err != nil {
 		panic(err)
 	}
+	if ErasureCodedBlockParts(b.Version.Block) {
+		return NewErasureCodedPartSetFromData(bz, partSize)
+	}
 	return NewPartSetFromData(bz, partSize)
 }
 
@@ -492,6 +498,8 @@ type Commit struct {
 	// active ValidatorSet.
 	BlockID    BlockID      `json:"block_id"`
 	Precommits []*CommitSig `json:"precommits"`
+	// Set instead of the Precommits when the commit is aggregated.
+	Aggregate *AggregateCommit `json:"aggregate,omitempty"`
 
 	// memoized in first call to corresponding method
 	// NOTE: can't memoize in constructor because constructor
@@ -531,9 +539,13 @@ func CommitToVoteSet(chainID string, commit *Commit, vals *ValidatorSet) *VoteSe
 }
 
 // GetVote converts the CommitSig for the given valIdx to a Vote.
-// Returns nil if the precommit at valIdx is nil.
+// Returns nil if the precommit at valIdx is nil, or if the commit is
+// aggregated: its votes can't be reconstructed.
 // Panics if valIdx >= commit.Size().
 func (commit *Commit) GetVote(valIdx int) *Vote {
+	if commit.Aggregate != nil {
+		return nil
+	}
 	commitSig := commit.Precommits[valIdx]
 	if commitSig == nil {
 		return nil
@@ -553,7 +565,28 @@ func (commit *Commit) GetVote(valIdx int) *Vote {
 		ValidatorAddress: commitSig.ValidatorAddress,
 		ValidatorIndex:   valIdx,
 		Signature:        commitSig.Signature,
+		Extension:        commitSig.Extension,
+	}
+}
+
+// VoteExtensionsSize returns the size of the extensions of the precommits,
+// including amino overhead.
+func (commit *Commit) VoteExtensionsSize() int64 {
+	var size int64
+	for _, precommit := range commit.Precommits {
+		if precommit == nil || len(precommit.Extension) == 0 {
+			continue
+		}
+		size += int64(amino.ByteSliceSize(precommit.Extension)) + 1 // field key
+	}
+	if commit.Aggregate != nil {
+		for _, precommit := range commit.Aggregate.Precommits {
+			if len(precommit.Extension) > 0 {
+				size += int64(amino.ByteSliceSize(precommit.Extension)) + 1 // field key
+			}
+		}
 	}
+	return size
 }
 
 // VoteSignBytes constructs the SignBytes for the given CommitSig.
@@ -568,10 +601,12 @@ func (commit *Commit) VoteSignBytes(chainID string, valIdx int) []byte {
 // the first non-nil vote.
 // Should be called before any attempt to access `commit.height` or `commit.round`.
 func (commit *Commit) memoizeHeightRound() {
-	if len(commit.Precommits) == 0 {
+	if commit.height > 0 {
 		return
 	}
-	if commit.height > 0 {
+	if commit.Aggregate != nil {
+		commit.height = commit.Aggregate.Height
+		commit.round = commit.Aggregate.Round
 		return
 	}
 	for _, precommit := range commit.Precommits {
@@ -605,12 +640,19 @@ func (commit *Commit) Size() int {
 	if commit == nil {
 		return 0
 	}
+	if commit.Aggregate != nil {
+		return commit.Aggregate.Signers.Size()
+	}
 	return len(commit.Precommits)
 }
 
 // BitArray returns a BitArray of which validators voted in this commit
 func (commit *Commit) BitArray() *cmn.BitArray {
 	if commit.bitArray == nil {
+		if commit.Aggregate != nil {
+			commit.bitArray = commit.Aggregate.Signers.Copy()
+			return commit.bitArray
+		}
 		commit.bitArray = cmn.NewBitArray(len(commit.Precommits))
 		for i, precommit := range commit.Precommits {
 			// TODO: need to check the BlockID otherwise we could be counting conflicts,
@@ -630,7 +672,7 @@ func (commit *Commit) GetByIndex(valIdx int) *Vote {
 
 // IsCommit returns true if there is at least one vote.
 func (commit *Commit) IsCommit() bool {
-	return len(commit.Precommits) != 0
+	return len(commit.Precommits) != 0 || commit.Aggregate != nil
 }
 
 // ValidateBasic performs basic validation that doesn't involve state data.
@@ -639,6 +681,12 @@ func (commit *Commit) ValidateBasic() error {
 	if commit.BlockID.IsZero() {
 		return errors.New("Commit cannot be for nil block")
 	}
+	if commit.Aggregate != nil {
+		if len(commit.Precommits) != 0 {
+			return errors.New("Aggregate commit cannot have precommits")
+		}
+		return commit.Aggregate.ValidateBasic()
+	}
 	if len(commit.Precommits) == 0 {
 		return errors.New("No precommits in commit")
 	}
@@ -674,6 +722,9 @@ func (commit *Commit) Hash() cmn.HexBytes {
 	if commit == nil {
 		return nil
 	}
+	if commit.hash == nil && commit.Aggregate != nil {
+		commit.hash = merkle.SimpleHashFromByteSlices([][]byte{cdcEncode(commit.Aggregate)})
+	}
 	if commit.hash == nil {
 		bs := make([][]byte, len(commit.Precommits))
 		for i, precommit := range commit.Precommits {
@@ -689,6 +740,15 @@ func (commit *Commit) StringIndented(indent string) string {
 	if commit == nil {
 		return "nil-Commit"
 	}
+	if commit.Aggregate != nil {
+		return fmt.Sprintf(`Commit{
+%s  BlockID:    %v
+%s  Aggregate:  %v
+%s}#%v`,
+			indent, commit.BlockID,
+			indent, commit.Aggregate,
+			indent, commit.hash)
+	}
 	precommitStrings := make([]string, len(commit.Precommits))
 	for i, precommit := range commit.Precommits {
 		precommitStrings[i] = precommit.String()
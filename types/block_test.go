@@ -110,6 +110,15 @@ func TestBlockMakePartSet(t *testing.T) {
 	partSet := MakeBlock(int64(3), []Tx{Tx("Hello World")}, nil, nil).MakePartSet(1024)
 	assert.NotNil(t, partSet)
 	assert.Equal(t, 1, partSet.Total())
+
+	// the parts of the blocks of the current block protocol are erasure coded
+	block := MakeBlock(int64(3), []Tx{Tx("Hello World")}, nil, nil)
+	block.Version.Block = version.BlockProtocol
+	partSet = block.MakePartSet(1024)
+	assert.Equal(t, 1, partSet.DataTotal())
+	assert.Equal(t, 2, partSet.Total())
+	assert.Equal(t, 1, partSet.Header().DataTotal(version.BlockProtocol))
+	assert.Equal(t, 2, partSet.Header().DataTotal(version.BlockProtocol-1))
 }
 
 func TestBlockMakePartSetWithEvidence(t *testing.T) {
@@ -39,6 +39,7 @@ type CanonicalVote struct {
 	BlockID   CanonicalBlockID
 	Timestamp time.Time
 	ChainID   string
+	Extension []byte // omitted when empty, leaving the sign bytes of votes without extension unchanged
 }
 
 //-----------------------------------
@@ -78,6 +79,7 @@ func CanonicalizeVote(chainID string, vote *Vote) CanonicalVote {
 		BlockID:   CanonicalizeBlockID(vote.BlockID),
 		Timestamp: vote.Timestamp,
 		ChainID:   chainID,
+		Extension: vote.Extension,
 	}
 }
 
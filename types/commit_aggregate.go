@@ -0,0 +1,196 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tendermint/tendermint/crypto/bls"
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// AggregateCommit is the compact form of a Commit, when the consensus params
+// enable it (BlockParams.AggregateCommits) and the validators have BLS keys:
+// the precommits for the block are aggregated into a single signature, and
+// only the parts of the precommits which differ between the validators are
+// kept, in the order of the validators. The precommits for nil or for another
+// block are dropped.
+// EXPERIMENTAL
+type AggregateCommit struct {
+	Height     int64                `json:"height"`
+	Round      int                  `json:"round"`
+	Signers    *cmn.BitArray        `json:"signers"`
+	Precommits []AggregatePrecommit `json:"precommits"` // of the signers
+	Signature  []byte               `json:"signature"`
+}
+
+// AggregatePrecommit is the part of a precommit of an AggregateCommit which
+// differs between the validators.
+type AggregatePrecommit struct {
+	Timestamp time.Time `json:"timestamp"`
+	Extension []byte    `json:"extension"`
+}
+
+// NewAggregateCommit returns the aggregate form of commit, for the validators
+// vals. It fails unless the validators which precommitted the block all have
+// BLS keys.
+func NewAggregateCommit(commit *Commit, vals *ValidatorSet) (*Commit, error) {
+	if commit.Aggregate != nil {
+		return commit, nil
+	}
+	if vals.Size() != len(commit.Precommits) {
+		return nil, NewErrInvalidCommitPrecommits(vals.Size(), len(commit.Precommits))
+	}
+
+	var (
+		signers    = cmn.NewBitArray(len(commit.Precommits))
+		precommits = make([]AggregatePrecommit, 0, len(commit.Precommits))
+		pubKeys    = make([]bls.PubKeyBLS, 0, len(commit.Precommits))
+		sigs       = make([][]byte, 0, len(commit.Precommits))
+	)
+	for idx, precommit := range commit.Precommits {
+		if precommit == nil || !commit.BlockID.Equals(precommit.BlockID) {
+			continue
+		}
+		_, val := vals.GetByIndex(idx)
+		pubKey, ok := val.PubKey.(bls.PubKeyBLS)
+		if !ok {
+			return nil, fmt.Errorf("validator %v doesn't have a BLS key", val.Address)
+		}
+		signers.SetIndex(idx, true)
+		precommits = append(precommits, AggregatePrecommit{
+			Timestamp: precommit.Timestamp,
+			Extension: precommit.Extension,
+		})
+		pubKeys = append(pubKeys, pubKey)
+		sigs = append(sigs, precommit.Signature)
+	}
+	sig, err := bls.AggregateSignatures(pubKeys, sigs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Commit{
+		BlockID: commit.BlockID,
+		Aggregate: &AggregateCommit{
+			Height:     commit.Height(),
+			Round:      commit.Round(),
+			Signers:    signers,
+			Precommits: precommits,
+			Signature:  sig,
+		},
+	}, nil
+}
+
+// ValidateBasic performs basic validation that doesn't involve state data.
+// Does not actually check the aggregate signature.
+func (agg *AggregateCommit) ValidateBasic() error {
+	if agg.Height < 0 {
+		return errors.New("Negative Height")
+	}
+	if agg.Round < 0 {
+		return errors.New("Negative Round")
+	}
+	if agg.Signers == nil || agg.Signers.Size() == 0 {
+		return errors.New("No signers in aggregate commit")
+	}
+	if len(agg.Signers.Elems) != (agg.Signers.Size()+63)/64 {
+		return fmt.Errorf("Invalid signers bit array: %d elems for %d bits",
+			len(agg.Signers.Elems), agg.Signers.Size())
+	}
+	signers := 0
+	for i := 0; i < agg.Signers.Size(); i++ {
+		if agg.Signers.GetIndex(i) {
+			signers++
+		}
+	}
+	if signers == 0 {
+		return errors.New("No precommits in aggregate commit")
+	}
+	if len(agg.Precommits) != signers {
+		return fmt.Errorf("Aggregate commit has %d precommits for %d signers", len(agg.Precommits), signers)
+	}
+	for _, precommit := range agg.Precommits {
+		if len(precommit.Extension) > MaxVoteExtensionSize {
+			return fmt.Errorf("Extension is too big (max: %d)", MaxVoteExtensionSize)
+		}
+	}
+	if len(agg.Signature) != bls.SignatureSize {
+		return fmt.Errorf("Invalid aggregate signature size %d, want %d", len(agg.Signature), bls.SignatureSize)
+	}
+	return nil
+}
+
+// vote returns the precommit of the validator valIdx for blockID, the i-th
+// signer, without its signature.
+func (agg *AggregateCommit) vote(blockID BlockID, valIdx, i int, address Address) *Vote {
+	return &Vote{
+		Type:             PrecommitType,
+		Height:           agg.Height,
+		Round:            agg.Round,
+		BlockID:          blockID,
+		Timestamp:        agg.Precommits[i].Timestamp,
+		ValidatorAddress: address,
+		ValidatorIndex:   valIdx,
+		Extension:        agg.Precommits[i].Extension,
+	}
+}
+
+// String returns a string representation of the aggregate commit.
+func (agg *AggregateCommit) String() string {
+	if agg == nil {
+		return "nil-AggregateCommit"
+	}
+	return fmt.Sprintf("AggregateCommit{%v/%v %v %X}",
+		agg.Height, agg.Round, agg.Signers, cmn.Fingerprint(agg.Signature))
+}
+
+// verifyAggregateCommit verifies the aggregate signature of commit, and that
+// +2/3 of vals signed it.
+func (vals *ValidatorSet) verifyAggregateCommit(chainID string, commit *Commit) error {
+	agg := commit.Aggregate
+	var (
+		pubKeys            = make([]bls.PubKeyBLS, 0, len(agg.Precommits))
+		msgs               = make([][]byte, 0, len(agg.Precommits))
+		talliedVotingPower int64
+	)
+	for idx := 0; idx < agg.Signers.Size(); idx++ {
+		if !agg.Signers.GetIndex(idx) {
+			continue
+		}
+		_, val := vals.GetByIndex(idx)
+		pubKey, ok := val.PubKey.(bls.PubKeyBLS)
+		if !ok {
+			return fmt.Errorf("Invalid commit -- validator %v doesn't have a BLS key", val.Address)
+		}
+		vote := agg.vote(commit.BlockID, idx, len(pubKeys), val.Address)
+		pubKeys = append(pubKeys, pubKey)
+		msgs = append(msgs, vote.SignBytes(chainID))
+		talliedVotingPower += val.VotingPower
+	}
+	if !bls.VerifyAggregateSignature(pubKeys, msgs, agg.Signature) {
+		return fmt.Errorf("Invalid commit -- invalid aggregate signature: %v", agg)
+	}
+
+	if talliedVotingPower > vals.TotalVotingPower()*2/3 {
+		return nil
+	}
+	return errTooMuchChange{talliedVotingPower, vals.TotalVotingPower()*2/3 + 1}
+}
+
+// signersVotingPower returns the voting power of vals of the signers of
+// newSet, by address.
+func (vals *ValidatorSet) signersVotingPower(newSet *ValidatorSet, signers *cmn.BitArray) int64 {
+	var power int64
+	for idx := 0; idx < signers.Size(); idx++ {
+		if !signers.GetIndex(idx) {
+			continue
+		}
+		_, newVal := newSet.GetByIndex(idx)
+		if _, val := vals.GetByAddress(newVal.Address); val != nil {
+			power += val.VotingPower
+		}
+	}
+	return power
+}
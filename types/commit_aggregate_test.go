@@ -0,0 +1,92 @@
+package types
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/crypto/bls"
+)
+
+func randBLSValidatorSet(numValidators int) (*ValidatorSet, []PrivValidator) {
+	var (
+		vals     = make([]*Validator, numValidators)
+		privVals = make([]PrivValidator, numValidators)
+	)
+	for i := 0; i < numValidators; i++ {
+		privVals[i] = NewMockPVWithParams(bls.GenPrivKey(), false, false)
+		vals[i] = NewValidator(privVals[i].GetPubKey(), 10)
+	}
+	sort.Sort(PrivValidatorsByAddress(privVals))
+	return NewValidatorSet(vals), privVals
+}
+
+func TestAggregateCommit(t *testing.T) {
+	const (
+		chainID = "test_chain_id"
+		height  = int64(3)
+		round   = 1
+	)
+	blockID := makeBlockIDRandom()
+	valSet, privVals := randBLSValidatorSet(4)
+	voteSet := NewVoteSet(chainID, height, round, PrecommitType, valSet)
+	commit, err := MakeCommit(blockID, height, round, voteSet, privVals)
+	require.NoError(t, err)
+	commit.Precommits[1] = nil
+
+	aggCommit, err := NewAggregateCommit(commit, valSet)
+	require.NoError(t, err)
+	require.NoError(t, aggCommit.ValidateBasic())
+	assert.Empty(t, aggCommit.Precommits)
+	assert.Equal(t, height, aggCommit.Height())
+	assert.Equal(t, round, aggCommit.Round())
+	assert.Equal(t, 4, aggCommit.Size())
+	assert.True(t, aggCommit.IsCommit())
+	assert.Nil(t, aggCommit.GetVote(0))
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, i != 1, aggCommit.BitArray().GetIndex(i), "#%d", i)
+	}
+	assert.NotEqual(t, commit.Hash(), aggCommit.Hash())
+	assert.NoError(t, valSet.VerifyCommit(chainID, blockID, height, aggCommit))
+	assert.NoError(t, valSet.VerifyFutureCommit(valSet, chainID, blockID, height, aggCommit))
+
+	// smaller than the full commit
+	assert.True(t, len(cdc.MustMarshalBinaryBare(aggCommit)) < len(cdc.MustMarshalBinaryBare(commit)))
+
+	// survives the encoding
+	var decoded *Commit
+	require.NoError(t, cdc.UnmarshalBinaryBare(cdc.MustMarshalBinaryBare(aggCommit), &decoded))
+	assert.NoError(t, valSet.VerifyCommit(chainID, blockID, height, decoded))
+	assert.Equal(t, aggCommit.Hash(), decoded.Hash())
+
+	// another chain
+	assert.Error(t, valSet.VerifyCommit("other_chain_id", blockID, height, aggCommit))
+
+	// a tampered timestamp
+	decoded = &Commit{BlockID: aggCommit.BlockID, Aggregate: &AggregateCommit{}}
+	*decoded.Aggregate = *aggCommit.Aggregate
+	decoded.Aggregate.Precommits = append([]AggregatePrecommit(nil), aggCommit.Aggregate.Precommits...)
+	decoded.Aggregate.Precommits[0].Timestamp = decoded.Aggregate.Precommits[0].Timestamp.Add(time.Second)
+	assert.Error(t, valSet.VerifyCommit(chainID, blockID, height, decoded))
+
+	// a signer claimed without its precommit
+	decoded.Aggregate.Signers = aggCommit.Aggregate.Signers.Copy()
+	decoded.Aggregate.Signers.SetIndex(1, true)
+	assert.Error(t, decoded.ValidateBasic())
+
+	// not enough voting power
+	commit.Precommits[2] = nil
+	aggCommit, err = NewAggregateCommit(commit, valSet)
+	require.NoError(t, err)
+	assert.Error(t, valSet.VerifyCommit(chainID, blockID, height, aggCommit))
+
+	// validators without BLS keys
+	voteSet, valSet, privVals = randVoteSet(height, round, PrecommitType, 4, 10)
+	commit, err = MakeCommit(blockID, height, round, voteSet, privVals)
+	require.NoError(t, err)
+	_, err = NewAggregateCommit(commit, valSet)
+	assert.Error(t, err)
+}
@@ -0,0 +1,199 @@
+package types
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// The amino field numbers of the repeated fields of a block.
+const (
+	blockDataField       = 2
+	blockEvidenceField   = 3
+	blockLastCommitField = 4
+
+	dataTxsField              = 1
+	evidenceDataEvidenceField = 1
+	commitPrecommitsField     = 2
+	commitAggregateField      = 3
+	aggregatePrecommitsField  = 4
+)
+
+const (
+	// minEncodedTxBytes is the size of the encoding of an empty tx: its field
+	// key and length.
+	minEncodedTxBytes = 2
+
+	// minEvidenceBytes is a lower bound of the size of an evidence, which
+	// holds two signatures.
+	minEvidenceBytes = 128
+)
+
+// DecodeLimits are the maximum counts of the elements of the repeated fields
+// of a block. Blocks from untrusted peers are checked against them before
+// they're decoded, so that a crafted block of many small or empty elements
+// can't make the node allocate much more memory than the size of the block.
+type DecodeLimits struct {
+	MaxTxs        int
+	MaxEvidence   int
+	MaxCommitSigs int
+}
+
+// NewDecodeLimits returns the decode limits of the blocks valid under the
+// consensus params, whose last commit is signed by numLastValidators
+// validators.
+func NewDecodeLimits(params ConsensusParams, numLastValidators int) DecodeLimits {
+	maxEvidence, _ := params.MaxEvidence()
+	return DecodeLimits{
+		MaxTxs:        int(params.Block.MaxBytes / minEncodedTxBytes),
+		MaxEvidence:   int(maxEvidence),
+		MaxCommitSigs: numLastValidators,
+	}
+}
+
+// MaxDecodeLimits returns the decode limits of the blocks valid under the
+// largest valid consensus params. They're used to decode the blocks of the
+// heights whose consensus params aren't known yet, like during fast sync.
+func MaxDecodeLimits() DecodeLimits {
+	return DecodeLimits{
+		MaxTxs:        MaxBlockSizeBytes / minEncodedTxBytes,
+		MaxEvidence:   MaxBlockSizeBytes / 2 / minEvidenceBytes,
+		MaxCommitSigs: MaxVotesCount,
+	}
+}
+
+// CheckBlock checks the counts of the repeated fields of the amino encoding
+// of a block against the limits, without decoding it.
+func (limits DecodeLimits) CheckBlock(bz []byte) error {
+	return forEachField(bz, func(num uint64, value []byte) error {
+		switch num {
+		case blockDataField:
+			return checkFieldCount(value, dataTxsField, limits.MaxTxs, "txs")
+		case blockEvidenceField:
+			return checkFieldCount(value, evidenceDataEvidenceField, limits.MaxEvidence, "evidence")
+		case blockLastCommitField:
+			return limits.checkCommit(value)
+		}
+		return nil
+	})
+}
+
+// CheckBlocks checks the blocks encoded in the field of the amino encoding
+// of a struct, like a message embedding blocks, against the limits. There
+// must be at most maxBlocks of them.
+func (limits DecodeLimits) CheckBlocks(bz []byte, field uint64, maxBlocks int) error {
+	if err := checkFieldCount(bz, field, maxBlocks, "blocks"); err != nil {
+		return err
+	}
+	return forEachField(bz, func(num uint64, value []byte) error {
+		if num != field {
+			return nil
+		}
+		return limits.CheckBlock(value)
+	})
+}
+
+// DecodeBlock decodes the amino encoding of a block from an untrusted peer,
+// after checking it against the limits.
+func DecodeBlock(bz []byte, limits DecodeLimits) (*Block, error) {
+	if err := limits.CheckBlock(bz); err != nil {
+		return nil, err
+	}
+	block := new(Block)
+	if err := cdc.UnmarshalBinaryBare(bz, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// DecodeBlockLengthPrefixed is DecodeBlock for the length prefixed encoding
+// of a block of at most maxBytes.
+func DecodeBlockLengthPrefixed(bz []byte, maxBytes int64, limits DecodeLimits) (*Block, error) {
+	size, n := binary.Uvarint(bz)
+	if n <= 0 {
+		return nil, errors.New("invalid block length prefix")
+	}
+	if size > uint64(maxBytes) {
+		return nil, fmt.Errorf("block too big (%d > %d)", size, maxBytes)
+	}
+	if size != uint64(len(bz)-n) {
+		return nil, fmt.Errorf("block length prefix %d doesn't match its size %d", size, len(bz)-n)
+	}
+	return DecodeBlock(bz[n:], limits)
+}
+
+func (limits DecodeLimits) checkCommit(bz []byte) error {
+	numPrecommits := 0
+	return forEachField(bz, func(num uint64, value []byte) error {
+		switch num {
+		case commitPrecommitsField:
+			numPrecommits++
+			if numPrecommits > limits.MaxCommitSigs {
+				return fmt.Errorf("too many precommits (> %d)", limits.MaxCommitSigs)
+			}
+		case commitAggregateField:
+			return checkFieldCount(value, aggregatePrecommitsField, limits.MaxCommitSigs, "aggregate precommits")
+		}
+		return nil
+	})
+}
+
+// checkFieldCount checks there are at most max occurrences of the field in
+// the amino encoding of a struct.
+func checkFieldCount(bz []byte, field uint64, max int, name string) error {
+	count := 0
+	return forEachField(bz, func(num uint64, _ []byte) error {
+		if num == field {
+			count++
+			if count > max {
+				return fmt.Errorf("too many %s (> %d)", name, max)
+			}
+		}
+		return nil
+	})
+}
+
+// forEachField calls fn with the number and the value of each length
+// delimited field of the amino encoding of a struct. Other fields are
+// skipped.
+func forEachField(bz []byte, fn func(num uint64, value []byte) error) error {
+	for len(bz) > 0 {
+		key, n := binary.Uvarint(bz)
+		if n <= 0 {
+			return errors.New("invalid field key")
+		}
+		bz = bz[n:]
+		num, typ := key>>3, key&7
+		switch typ {
+		case 0: // varint
+			if _, n = binary.Uvarint(bz); n <= 0 {
+				return fmt.Errorf("invalid varint of field %d", num)
+			}
+			bz = bz[n:]
+		case 1: // 8 bytes
+			if len(bz) < 8 {
+				return fmt.Errorf("truncated field %d", num)
+			}
+			bz = bz[8:]
+		case 5: // 4 bytes
+			if len(bz) < 4 {
+				return fmt.Errorf("truncated field %d", num)
+			}
+			bz = bz[4:]
+		case 2: // length delimited
+			size, n := binary.Uvarint(bz)
+			if n <= 0 || size > uint64(len(bz)-n) {
+				return fmt.Errorf("invalid length of field %d", num)
+			}
+			value := bz[n : n+int(size)]
+			bz = bz[n+int(size):]
+			if err := fn(num, value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("invalid wire type %d of field %d", typ, num)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeLimitsCheckBlock(t *testing.T) {
+	lastID := makeBlockIDRandom()
+	h := int64(3)
+
+	voteSet, valSet, vals := randVoteSet(h-1, 1, PrecommitType, 10, 1)
+	commit, err := MakeCommit(lastID, h-1, 1, voteSet, vals)
+	require.NoError(t, err)
+
+	ev := NewMockGoodEvidence(h, 0, valSet.Validators[0].Address)
+	block := MakeBlock(h, []Tx{Tx("foo"), Tx("")}, commit, []Evidence{ev})
+	bz, err := cdc.MarshalBinaryBare(block)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		limits DecodeLimits
+		expErr bool
+	}{
+		{DecodeLimits{MaxTxs: 2, MaxEvidence: 1, MaxCommitSigs: 10}, false},
+		{MaxDecodeLimits(), false},
+		{DecodeLimits{MaxTxs: 1, MaxEvidence: 1, MaxCommitSigs: 10}, true},
+		{DecodeLimits{MaxTxs: 2, MaxEvidence: 0, MaxCommitSigs: 10}, true},
+		{DecodeLimits{MaxTxs: 2, MaxEvidence: 1, MaxCommitSigs: 9}, true},
+	}
+	for i, tc := range testCases {
+		decoded, err := DecodeBlock(bz, tc.limits)
+		if tc.expErr {
+			assert.Error(t, err, "#%d", i)
+			continue
+		}
+		if assert.NoError(t, err, "#%d", i) {
+			assert.Equal(t, block.Hash(), decoded.Hash(), "#%d", i)
+		}
+	}
+
+	// the length prefixed encoding, as in the block parts
+	lbz, err := cdc.MarshalBinaryLengthPrefixed(block)
+	require.NoError(t, err)
+	limits := NewDecodeLimits(*DefaultConsensusParams(), valSet.Size())
+	decoded, err := DecodeBlockLengthPrefixed(lbz, int64(len(bz)), limits)
+	require.NoError(t, err)
+	assert.Equal(t, block.Hash(), decoded.Hash())
+	_, err = DecodeBlockLengthPrefixed(lbz, int64(len(bz)-1), limits)
+	assert.Error(t, err)
+	_, err = DecodeBlockLengthPrefixed(lbz[:len(lbz)-1], int64(len(bz)), limits)
+	assert.Error(t, err)
+}
+
+func TestDecodeLimitsCraftedBlock(t *testing.T) {
+	// a last commit of 20000 empty precommits, of 40kB but decoded into as
+	// many allocated commit sigs
+	precommits := bytes.Repeat([]byte{commitPrecommitsField<<3 | 2, 0}, 2*MaxVotesCount)
+	bz := appendField(nil, blockLastCommitField, precommits)
+
+	_, err := DecodeBlock(bz, MaxDecodeLimits())
+	assert.Error(t, err)
+
+	// the same in an aggregate commit
+	aggregate := bytes.Repeat([]byte{aggregatePrecommitsField<<3 | 2, 0}, 2*MaxVotesCount)
+	bz = appendField(nil, blockLastCommitField, appendField(nil, commitAggregateField, aggregate))
+	_, err = DecodeBlock(bz, MaxDecodeLimits())
+	assert.Error(t, err)
+
+	// blocks embedded in a message
+	msg := appendField(nil, 1, bz)
+	assert.Error(t, MaxDecodeLimits().CheckBlocks(msg, 1, 1))
+	msg = appendField(appendField(nil, 1, nil), 1, nil)
+	assert.Error(t, MaxDecodeLimits().CheckBlocks(msg, 1, 1))
+	assert.NoError(t, MaxDecodeLimits().CheckBlocks(msg, 1, 2))
+
+	// malformed encodings
+	for i, bz := range [][]byte{
+		{blockDataField<<3 | 2},
+		{blockDataField<<3 | 2, 5, 0},
+		{blockDataField<<3 | 3},
+		{0x80},
+	} {
+		assert.Error(t, MaxDecodeLimits().CheckBlock(bz), "#%d", i)
+	}
+}
+
+func appendField(bz []byte, num uint64, value []byte) []byte {
+	buf := make([]byte, 2*binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, num<<3|2)
+	n += binary.PutUvarint(buf[n:], uint64(len(value)))
+	return append(append(bz, buf[:n]...), value...)
+}
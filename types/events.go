@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"time"
 
 	amino "github.com/tendermint/go-amino"
 	abci "github.com/tendermint/tendermint/abci/types"
@@ -21,6 +22,10 @@ const (
 	EventTx                  = "Tx"
 	EventValidatorSetUpdates = "ValidatorSetUpdates"
 
+	// Fast sync events, published when the fast sync state machine changes
+	// state, e.g. to back off after losing all its peers, or to abort.
+	EventFastSyncStatus = "FastSyncStatus"
+
 	// Internal consensus events.
 	// These are used for testing the consensus state machine.
 	// They can also be used to build real-time consensus visualizers.
@@ -56,6 +61,7 @@ func RegisterEventDatas(cdc *amino.Codec) {
 	cdc.RegisterConcrete(EventDataCompleteProposal{}, "tendermint/event/CompleteProposal", nil)
 	cdc.RegisterConcrete(EventDataVote{}, "tendermint/event/Vote", nil)
 	cdc.RegisterConcrete(EventDataValidatorSetUpdates{}, "tendermint/event/ValidatorSetUpdates", nil)
+	cdc.RegisterConcrete(EventDataFastSyncStatus{}, "tendermint/event/FastSyncStatus", nil)
 	cdc.RegisterConcrete(EventDataString(""), "tendermint/event/ProposalString", nil)
 }
 
@@ -116,8 +122,64 @@ type EventDataVote struct {
 
 type EventDataString string
 
+// EventDataValidatorSetUpdates is published when EndBlock updates the
+// validator set. Besides the updates returned by the app, it includes their
+// diff with the validator set they apply to: the updates take effect at
+// Height + 2.
 type EventDataValidatorSetUpdates struct {
 	ValidatorUpdates []*Validator `json:"validator_updates"`
+
+	Height       int64                  `json:"height"`
+	Added        []*Validator           `json:"added"`
+	Removed      []*Validator           `json:"removed"` // with the voting power they had
+	PowerChanges []ValidatorPowerChange `json:"power_changes"`
+}
+
+// ValidatorPowerChange is the change of the voting power of a validator which
+// stays in the validator set.
+type ValidatorPowerChange struct {
+	Address  Address `json:"address"`
+	OldPower int64   `json:"old_power"`
+	NewPower int64   `json:"new_power"`
+}
+
+// NewEventDataValidatorSetUpdates returns the event for the updates returned
+// by EndBlock at the given height, which apply to vals.
+func NewEventDataValidatorSetUpdates(height int64, vals *ValidatorSet,
+	updates []*Validator) EventDataValidatorSetUpdates {
+	data := EventDataValidatorSetUpdates{
+		ValidatorUpdates: updates,
+		Height:           height,
+	}
+	for _, update := range updates {
+		_, val := vals.GetByAddress(update.Address)
+		switch {
+		case val == nil && update.VotingPower > 0:
+			data.Added = append(data.Added, update)
+		case val != nil && update.VotingPower == 0:
+			data.Removed = append(data.Removed, val)
+		case val != nil && val.VotingPower != update.VotingPower:
+			data.PowerChanges = append(data.PowerChanges, ValidatorPowerChange{
+				Address:  update.Address,
+				OldPower: val.VotingPower,
+				NewPower: update.VotingPower,
+			})
+		}
+	}
+	return data
+}
+
+// EventDataFastSyncStatus is published when the fast sync state machine
+// changes state. In waitForRetry, it lost all its peers and restarts at
+// RetryTime; in aborted, it stopped for good on Error, without switching to
+// consensus.
+type EventDataFastSyncStatus struct {
+	State         string    `json:"state"`
+	Height        int64     `json:"height"`
+	MaxPeerHeight int64     `json:"max_peer_height"`
+	Retries       int       `json:"retries"`
+	RetryTime     time.Time `json:"retry_time,omitempty"`
+	Error         string    `json:"error,omitempty"`
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -133,10 +195,14 @@ const (
 	// TxHeightKey is a reserved key, used to specify transaction block's height.
 	// see EventBus#PublishEventTx
 	TxHeightKey = "tx.height"
+	// BlockHeightKey is a reserved key used for indexing BeginBlock and
+	// EndBlock events.
+	BlockHeightKey = "block.height"
 )
 
 var (
 	EventQueryCompleteProposal    = QueryForEvent(EventCompleteProposal)
+	EventQueryFastSyncStatus      = QueryForEvent(EventFastSyncStatus)
 	EventQueryLock                = QueryForEvent(EventLock)
 	EventQueryNewBlock            = QueryForEvent(EventNewBlock)
 	EventQueryNewBlockHeader      = QueryForEvent(EventNewBlockHeader)
@@ -21,3 +21,25 @@ func TestQueryForEvent(t *testing.T) {
 		QueryForEvent(EventNewBlock).String(),
 	)
 }
+
+func TestNewEventDataValidatorSetUpdates(t *testing.T) {
+	vals, _ := RandValidatorSet(3, 10)
+	_, kept := vals.GetByIndex(0)
+	_, changed := vals.GetByIndex(1)
+	_, removed := vals.GetByIndex(2)
+	added, _ := RandValidator(false, 5)
+
+	updates := []*Validator{
+		NewValidator(kept.PubKey, kept.VotingPower),
+		NewValidator(changed.PubKey, 20),
+		NewValidator(removed.PubKey, 0),
+		added,
+	}
+	data := NewEventDataValidatorSetUpdates(7, vals, updates)
+
+	assert.EqualValues(t, 7, data.Height)
+	assert.Equal(t, updates, data.ValidatorUpdates)
+	assert.Equal(t, []*Validator{added}, data.Added)
+	assert.Equal(t, []*Validator{removed}, data.Removed)
+	assert.Equal(t, []ValidatorPowerChange{{Address: changed.Address, OldPower: 10, NewPower: 20}}, data.PowerChanges)
+}
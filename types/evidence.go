@@ -10,12 +10,17 @@ import (
 	amino "github.com/tendermint/go-amino"
 
 	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/bls"
 	"github.com/tendermint/tendermint/crypto/merkle"
 )
 
 const (
-	// MaxEvidenceBytes is a maximum size of any evidence (including amino overhead).
+	// MaxEvidenceBytes is a maximum size of any evidence (including amino overhead)
+	// of a validator without a BLS key.
 	MaxEvidenceBytes int64 = 484
+	// MaxBLSEvidenceBytes is a maximum size of the evidence of a validator
+	// with a BLS key, whose pubkey is longer.
+	MaxBLSEvidenceBytes int64 = 581
 )
 
 // ErrEvidenceInvalid wraps a piece of evidence and the error denoting how or why it is invalid.
@@ -91,6 +96,18 @@ func MaxEvidencePerBlock(blockMaxBytes int64) (int64, int64) {
 	return maxNum, maxBytes
 }
 
+// MaxEvidenceBytesOf returns the maximum size of the evidence, depending on
+// the key type of the validator: MaxBLSEvidenceBytes for BLS keys,
+// MaxEvidenceBytes otherwise.
+func MaxEvidenceBytesOf(ev Evidence) int64 {
+	if dve, ok := ev.(*DuplicateVoteEvidence); ok {
+		if _, ok := dve.PubKey.(bls.PubKeyBLS); ok {
+			return MaxBLSEvidenceBytes
+		}
+	}
+	return MaxEvidenceBytes
+}
+
 //-------------------------------------------
 
 // DuplicateVoteEvidence contains evidence a validator signed two conflicting
@@ -173,10 +190,15 @@ func (dve *DuplicateVoteEvidence) Verify(chainID string, pubKey crypto.PubKey) e
 	}
 
 	// Signatures must be valid
-	if !pubKey.VerifyBytes(dve.VoteA.SignBytes(chainID), dve.VoteA.Signature) {
+	valid := verifySignatures(
+		[]crypto.PubKey{pubKey, pubKey},
+		[][]byte{dve.VoteA.SignBytes(chainID), dve.VoteB.SignBytes(chainID)},
+		[][]byte{dve.VoteA.Signature, dve.VoteB.Signature},
+	)
+	if !valid[0] {
 		return fmt.Errorf("DuplicateVoteEvidence Error verifying VoteA: %v", ErrVoteInvalidSignature)
 	}
-	if !pubKey.VerifyBytes(dve.VoteB.SignBytes(chainID), dve.VoteB.Signature) {
+	if !valid[1] {
 		return fmt.Errorf("DuplicateVoteEvidence Error verifying VoteB: %v", ErrVoteInvalidSignature)
 	}
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tendermint/crypto/bls"
 	"github.com/tendermint/tendermint/crypto/secp256k1"
 	"github.com/tendermint/tendermint/crypto/tmhash"
 )
@@ -110,6 +111,15 @@ func TestMaxEvidenceBytes(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.EqualValues(t, MaxEvidenceBytes, len(bz))
+	assert.EqualValues(t, MaxEvidenceBytes, MaxEvidenceBytesOf(ev))
+
+	// the pubkeys of BLS validators are longer still
+	ev.PubKey = bls.GenPrivKey().PubKey()
+	bz, err = cdc.MarshalBinaryLengthPrefixed(ev)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, MaxBLSEvidenceBytes, len(bz))
+	assert.EqualValues(t, MaxBLSEvidenceBytes, MaxEvidenceBytesOf(ev))
 }
 
 func randomDuplicatedVoteEvidence() *DuplicateVoteEvidence {
@@ -3,8 +3,6 @@ package types
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
 	"time"
 
 	"github.com/pkg/errors"
@@ -41,6 +39,11 @@ type GenesisDoc struct {
 	Validators      []GenesisValidator `json:"validators,omitempty"`
 	AppHash         cmn.HexBytes       `json:"app_hash"`
 	AppState        json.RawMessage    `json:"app_state,omitempty"`
+
+	// AppStateRef locates the app state in the genesis file instead of
+	// AppState, when it's too big to be kept in memory (see
+	// GenesisDocFromFile). Use AppStateBytes to read the app state.
+	AppStateRef *GenesisAppStateRef `json:"app_state_ref,omitempty"`
 }
 
 // SaveAs is a utility method for saving GenensisDoc as a JSON file.
@@ -82,6 +85,9 @@ func (genDoc *GenesisDoc) ValidateAndComplete() error {
 		if v.Power == 0 {
 			return errors.Errorf("The genesis file cannot contain validators with no voting power: %v", v)
 		}
+		if err := genDoc.ConsensusParams.Validator.ValidatePubKey(v.PubKey); err != nil {
+			return errors.Errorf("The genesis file cannot contain validator %v: %v", v, err)
+		}
 		if len(v.Address) > 0 && !bytes.Equal(v.PubKey.Address(), v.Address) {
 			return errors.Errorf("Incorrect address for validator %v in the genesis file, should be %v", v, v.PubKey.Address())
 		}
@@ -114,16 +120,3 @@ func GenesisDocFromJSON(jsonBlob []byte) (*GenesisDoc, error) {
 
 	return &genDoc, err
 }
-
-// GenesisDocFromFile reads JSON data from a file and unmarshalls it into a GenesisDoc.
-func GenesisDocFromFile(genDocFile string) (*GenesisDoc, error) {
-	jsonBlob, err := ioutil.ReadFile(genDocFile)
-	if err != nil {
-		return nil, errors.Wrap(err, "Couldn't read GenesisDoc file")
-	}
-	genDoc, err := GenesisDocFromJSON(jsonBlob)
-	if err != nil {
-		return nil, errors.Wrap(err, fmt.Sprintf("Error reading GenesisDoc at %v", genDocFile))
-	}
-	return genDoc, nil
-}
@@ -0,0 +1,351 @@
+package types
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// MaxGenesisAppStateInMemory is the size of the biggest app state kept in
+// memory by GenesisDocFromFile. A bigger app state is left in the genesis file
+// (see GenesisAppStateRef), so that chains with genesis exports of several GB
+// can start.
+const MaxGenesisAppStateInMemory = 64 << 20 // 64MB
+
+// GenesisAppStateRef locates the app state of a genesis doc in its file, when
+// it's too big to be kept in memory. It's set by GenesisDocFromFile, and saved
+// with the genesis doc by the node: the file must not be moved nor edited
+// until the app is initialized with InitChain.
+type GenesisAppStateRef struct {
+	File   string       `json:"file"`
+	Offset int64        `json:"offset"`
+	Size   int64        `json:"size"`
+	Hash   cmn.HexBytes `json:"hash"` // SHA-256 of the app state
+}
+
+// AppStateBytes returns the app state of the genesis doc, reading it from the
+// genesis file if it's not in memory.
+func (genDoc *GenesisDoc) AppStateBytes() ([]byte, error) {
+	ref := genDoc.AppStateRef
+	if ref == nil {
+		return genDoc.AppState, nil
+	}
+	f, err := os.Open(ref.File)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the genesis file")
+	}
+	defer f.Close()
+	bz := make([]byte, ref.Size)
+	if _, err := f.ReadAt(bz, ref.Offset); err != nil {
+		return nil, errors.Wrap(err, "failed to read the app state from the genesis file")
+	}
+	if hash := sha256.Sum256(bz); !bytes.Equal(hash[:], ref.Hash) {
+		return nil, fmt.Errorf("the app state in %s has hash %X, want %X: the genesis file changed",
+			ref.File, hash, ref.Hash)
+	}
+	return bz, nil
+}
+
+// GenesisDocFromFile reads JSON data from a file and unmarshalls it into a GenesisDoc.
+// The file is streamed, and an app state bigger than MaxGenesisAppStateInMemory
+// is left in the file.
+func GenesisDocFromFile(genDocFile string) (*GenesisDoc, error) {
+	return GenesisDocFromFileWithHash(genDocFile, nil)
+}
+
+// GenesisDocFromFileWithHash is like GenesisDocFromFile, but fails unless the
+// SHA-256 hash of the file is hash (see GenesisFileHash). The hash isn't
+// checked if empty.
+func GenesisDocFromFileWithHash(genDocFile string, hash []byte) (*GenesisDoc, error) {
+	genDoc, fileHash, err := loadGenesisDocFile(genDocFile, MaxGenesisAppStateInMemory)
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) > 0 && !bytes.Equal(fileHash, hash) {
+		return nil, fmt.Errorf("the genesis file %s has hash %X, want %X", genDocFile, fileHash, hash)
+	}
+	return genDoc, nil
+}
+
+// GenesisFileHash validates the genesis doc in genDocFile, and returns the
+// SHA-256 hash of the file.
+func GenesisFileHash(genDocFile string) ([]byte, error) {
+	_, fileHash, err := loadGenesisDocFile(genDocFile, MaxGenesisAppStateInMemory)
+	return fileHash, err
+}
+
+// loadGenesisDocFile streams the genesis doc in genDocFile, and returns it
+// with the SHA-256 hash of the file. The fields but the app state are small:
+// they are buffered and unmarshalled at the end. The app state is hashed on
+// the fly, and only kept in memory up to maxAppState bytes.
+func loadGenesisDocFile(genDocFile string, maxAppState int64) (*GenesisDoc, []byte, error) {
+	f, err := os.Open(genDocFile)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Couldn't read GenesisDoc file")
+	}
+	defer f.Close()
+
+	fileHasher := sha256.New()
+	s := &genesisScanner{r: bufio.NewReaderSize(io.TeeReader(f, fileHasher), 1<<20)}
+	fields, appState, err := s.scanDoc(maxAppState)
+	if err == nil {
+		// hash the rest of the file
+		_, err = io.Copy(ioutil.Discard, s.r)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, fmt.Sprintf("Error reading GenesisDoc at %v", genDocFile))
+	}
+
+	genDoc := GenesisDoc{}
+	if err := cdc.UnmarshalJSON(fields, &genDoc); err != nil {
+		return nil, nil, errors.Wrap(err, fmt.Sprintf("Error reading GenesisDoc at %v", genDocFile))
+	}
+	if appState != nil {
+		if appState.buf != nil {
+			genDoc.AppState = appState.buf
+		} else {
+			genDoc.AppStateRef = &GenesisAppStateRef{
+				File:   genDocFile,
+				Offset: appState.offset,
+				Size:   appState.size,
+				Hash:   appState.hasher.Sum(nil),
+			}
+		}
+	}
+	if err := genDoc.ValidateAndComplete(); err != nil {
+		return nil, nil, errors.Wrap(err, fmt.Sprintf("Error reading GenesisDoc at %v", genDocFile))
+	}
+	return &genDoc, fileHasher.Sum(nil), nil
+}
+
+//------------------------------------------------------------
+
+// genesisScanner scans the JSON of a genesis doc, without unmarshalling it.
+type genesisScanner struct {
+	r   *bufio.Reader
+	off int64 // of the next byte
+}
+
+// appStateSink receives the app state, as scanned.
+type appStateSink struct {
+	offset int64 // in the file
+	size   int64
+	hasher hash.Hash
+	buf    []byte // nil once over the limit
+	limit  int64
+}
+
+func (as *appStateSink) Write(p []byte) (int, error) {
+	as.hasher.Write(p) // nolint: errcheck
+	as.size += int64(len(p))
+	if as.buf != nil {
+		if as.size > as.limit {
+			as.buf = nil
+		} else {
+			as.buf = append(as.buf, p...)
+		}
+	}
+	return len(p), nil
+}
+
+func (s *genesisScanner) readByte() (byte, error) {
+	c, err := s.r.ReadByte()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil {
+		s.off++
+	}
+	return c, err
+}
+
+// nextByte returns the next byte which isn't whitespace.
+func (s *genesisScanner) nextByte() (byte, error) {
+	for {
+		c, err := s.readByte()
+		if err != nil || !isJSONSpace(c) {
+			return c, err
+		}
+	}
+}
+
+// scanDoc scans the top-level object: it returns its fields but the app state
+// as a JSON object, and sends the app state to a sink, nil if missing.
+func (s *genesisScanner) scanDoc(maxAppState int64) (fields []byte, appState *appStateSink, err error) {
+	var buf bytes.Buffer
+	expect := func(c byte, want byte) error {
+		if c != want {
+			return fmt.Errorf("invalid character %q at offset %d, want %q", c, s.off-1, want)
+		}
+		return nil
+	}
+
+	c, err := s.nextByte()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := expect(c, '{'); err != nil {
+		return nil, nil, err
+	}
+	buf.WriteByte('{')
+	for n := 0; ; n++ {
+		if c, err = s.nextByte(); err != nil {
+			return nil, nil, err
+		}
+		if c == '}' {
+			break
+		}
+		if n > 0 {
+			if err := expect(c, ','); err != nil {
+				return nil, nil, err
+			}
+			if c, err = s.nextByte(); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := expect(c, '"'); err != nil {
+			return nil, nil, err
+		}
+		var rawKey bytes.Buffer
+		if err := s.copyValue(c, &rawKey); err != nil {
+			return nil, nil, err
+		}
+		var key string
+		if err := json.Unmarshal(rawKey.Bytes(), &key); err != nil {
+			return nil, nil, err
+		}
+		if c, err = s.nextByte(); err != nil {
+			return nil, nil, err
+		}
+		if err := expect(c, ':'); err != nil {
+			return nil, nil, err
+		}
+		if c, err = s.nextByte(); err != nil {
+			return nil, nil, err
+		}
+
+		switch key {
+		case "app_state":
+			appState = &appStateSink{
+				offset: s.off - 1,
+				hasher: sha256.New(),
+				buf:    make([]byte, 0),
+				limit:  maxAppState,
+			}
+			if err := s.copyValue(c, appState); err != nil {
+				return nil, nil, err
+			}
+		case "app_state_ref":
+			return nil, nil, errors.New("app_state_ref can't be set in the genesis file")
+		default:
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			buf.Write(rawKey.Bytes())
+			buf.WriteByte(':')
+			if err := s.copyValue(c, &buf); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	buf.WriteByte('}')
+
+	// only whitespace may follow
+	for {
+		c, err := s.r.ReadByte()
+		if err == io.EOF {
+			return buf.Bytes(), appState, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if !isJSONSpace(c) {
+			return nil, nil, fmt.Errorf("invalid character %q after the genesis doc", c)
+		}
+	}
+}
+
+// copyValue copies the JSON value starting with c to w, in chunks. The value is
+// only checked to be balanced: it's validated when unmarshalled.
+func (s *genesisScanner) copyValue(c byte, w io.Writer) error {
+	const chunkSize = 32 * 1024
+	chunk := make([]byte, 0, chunkSize)
+	flush := func() error {
+		_, err := w.Write(chunk)
+		chunk = chunk[:0]
+		return err
+	}
+
+	switch c {
+	case '{', '[', '"':
+	default:
+		// a number, true, false or null: up to the next delimiter
+		chunk = append(chunk, c)
+		for {
+			c, err := s.r.ReadByte()
+			if err != nil {
+				return io.ErrUnexpectedEOF
+			}
+			if isJSONSpace(c) || c == ',' || c == '}' || c == ']' {
+				if err := s.r.UnreadByte(); err != nil {
+					return err
+				}
+				return flush()
+			}
+			s.off++
+			chunk = append(chunk, c)
+		}
+	}
+
+	var (
+		depth    int
+		inString bool
+		escaped  bool
+	)
+	for {
+		chunk = append(chunk, c)
+		if len(chunk) == chunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+		}
+		if depth == 0 && !inString {
+			return flush()
+		}
+		var err error
+		if c, err = s.readByte(); err != nil {
+			return err
+		}
+	}
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,96 @@
+package types
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testGenesisAppState = `{"owner": "Bob", "tricky": "x}\"]{", "list": [1, 2.5e3, true, null, {}]}`
+	testGenesisDoc      = `{
+  "genesis_time": "2019-01-01T00:00:00Z",
+  "chain_id": "test-chain",
+  "validators": [{"pub_key": {"type": "tendermint/PubKeyEd25519",` +
+		`"value": "AT/+aaL1eB0477Mud9JMm8Sh8BIvOYlPGC9KkIUmFaE="}, "power": "10", "name": ""}],
+  "app_state": ` + testGenesisAppState + `,
+  "app_hash": ""
+}
+`
+)
+
+func writeGenesisFile(t *testing.T, dir, content string) string {
+	file := filepath.Join(dir, "genesis.json")
+	require.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestGenesisDocFromFileStreaming(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesis")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := writeGenesisFile(t, dir, testGenesisDoc)
+
+	want, err := GenesisDocFromJSON([]byte(testGenesisDoc))
+	require.NoError(t, err)
+
+	// in memory
+	genDoc, err := GenesisDocFromFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, want, genDoc)
+
+	// left in the file
+	genDoc, fileHash, err := loadGenesisDocFile(file, 10)
+	require.NoError(t, err)
+	assert.Nil(t, genDoc.AppState)
+	require.NotNil(t, genDoc.AppStateRef)
+	assert.EqualValues(t, len(testGenesisAppState), genDoc.AppStateRef.Size)
+	appState, err := genDoc.AppStateBytes()
+	require.NoError(t, err)
+	assert.Equal(t, testGenesisAppState, string(appState))
+	genDoc.AppState, genDoc.AppStateRef = want.AppState, nil
+	assert.Equal(t, want, genDoc)
+
+	sum := sha256.Sum256([]byte(testGenesisDoc))
+	assert.Equal(t, sum[:], fileHash)
+	hash, err := GenesisFileHash(file)
+	require.NoError(t, err)
+	assert.Equal(t, sum[:], hash)
+	_, err = GenesisDocFromFileWithHash(file, sum[:])
+	assert.NoError(t, err)
+	_, err = GenesisDocFromFileWithHash(file, make([]byte, sha256.Size))
+	assert.Error(t, err)
+
+	// the genesis file changed
+	genDoc, _, err = loadGenesisDocFile(file, 10)
+	require.NoError(t, err)
+	writeGenesisFile(t, dir, strings.Replace(testGenesisDoc, "Bob", "Eve", 1))
+	_, err = genDoc.AppStateBytes()
+	assert.Error(t, err)
+}
+
+func TestGenesisDocFromFileBad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "genesis")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	testCases := []string{
+		"",
+		testGenesisDoc[:len(testGenesisDoc)/2],
+		testGenesisDoc + "{}",
+		`{"chain_id": "test-chain", "app_state_ref": {"file": "/etc/passwd"}}`,
+		`{"chain_id": "test-chain" "app_state": {}}`,
+		`{"chain_id": "test-chain", "app_state": {"a": [}`,
+	}
+	for i, tc := range testCases {
+		file := writeGenesisFile(t, dir, tc)
+		_, err := GenesisDocFromFile(file)
+		assert.Error(t, err, "#%d", i)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/sr25519"
 	tmtime "github.com/tendermint/tendermint/types/time"
 )
 
@@ -111,6 +112,28 @@ func TestGenesisGood(t *testing.T) {
 	}
 }
 
+func TestGenesisValidatorKeyTypes(t *testing.T) {
+	pubKey := sr25519.GenPrivKey().PubKey()
+	genDoc := &GenesisDoc{
+		ChainID:    "abc",
+		Validators: []GenesisValidator{{PubKey: pubKey, Power: 10}},
+	}
+	// ed25519 only by default
+	assert.Error(t, genDoc.ValidateAndComplete())
+
+	genDoc.ConsensusParams = DefaultConsensusParams()
+	genDoc.ConsensusParams.Validator.PubKeyTypes = []string{ABCIPubKeyTypeEd25519, ABCIPubKeyTypeSr25519}
+	require.NoError(t, genDoc.ValidateAndComplete())
+	assert.EqualValues(t, pubKey.Address(), genDoc.Validators[0].Address)
+
+	// the key survives the JSON encoding
+	genDocBytes, err := cdc.MarshalJSON(genDoc)
+	require.NoError(t, err)
+	genDoc2, err := GenesisDocFromJSON(genDocBytes)
+	require.NoError(t, err)
+	assert.Equal(t, pubKey, genDoc2.Validators[0].PubKey)
+}
+
 func TestGenesisSaveAs(t *testing.T) {
 	tmpfile, err := ioutil.TempFile("", "genesis")
 	require.NoError(t, err)
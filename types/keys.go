@@ -3,4 +3,8 @@ package types
 // UNSTABLE
 var (
 	PeerStateKey = "ConsensusReactor.peerState"
+
+	// PeerSyncPhaseKey is the key of the SyncPhase of a peer (see
+	// p2p.Peer#Get), set by the blockchain reactor.
+	PeerSyncPhaseKey = "BlockchainReactor.syncPhase"
 )
@@ -1,9 +1,13 @@
 package types
 
 import (
+	"math"
+	"time"
+
 	"github.com/pkg/errors"
 
 	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/tmhash"
 	cmn "github.com/tendermint/tendermint/libs/common"
 )
@@ -17,6 +21,22 @@ const (
 
 	// MaxBlockPartsCount is the maximum count of block parts.
 	MaxBlockPartsCount = (MaxBlockSizeBytes / BlockPartSizeBytes) + 1
+
+	// MaxBlockDataPartsCount is the maximum count of data parts of a block
+	// whose parts are erasure coded. Parts of bigger blocks grow past
+	// BlockPartSizeBytes, so that the data and parity parts fit in the erasure
+	// code.
+	MaxBlockDataPartsCount = 192
+
+	// MaxBlockPartSizeBytes is the maximum size of one block part: of the
+	// erasure coded parts, including the data size prefix of parity parts.
+	MaxBlockPartSizeBytes = MaxBlockSizeBytes/MaxBlockDataPartsCount + 1 + 8
+
+	// MaxTimeoutParam is the maximum value of each of the timeout params.
+	MaxTimeoutParam = 10 * time.Minute
+
+	// MaxSynchronyParam is the maximum value of each of the synchrony params.
+	MaxSynchronyParam = 10 * time.Minute
 )
 
 // ConsensusParams contains consensus critical parameters that determine the
@@ -25,14 +45,22 @@ type ConsensusParams struct {
 	Block     BlockParams     `json:"block"`
 	Evidence  EvidenceParams  `json:"evidence"`
 	Validator ValidatorParams `json:"validator"`
+	Timeout   TimeoutParams   `json:"timeout"`
+	Synchrony SynchronyParams `json:"synchrony"`
 }
 
 // HashedParams is a subset of ConsensusParams.
 // It is amino encoded and hashed into
 // the Header.ConsensusHash.
 type HashedParams struct {
-	BlockMaxBytes int64
-	BlockMaxGas   int64
+	BlockMaxBytes         int64
+	BlockMaxGas           int64
+	BlockAggregateCommits bool // omitted when false, leaving the hash unchanged
+	// omitted when zero (BFT time), leaving the hash unchanged
+	SynchronyPrecision    time.Duration
+	SynchronyMessageDelay time.Duration
+	// omitted when zero (no vote extensions), leaving the hash unchanged
+	BlockVoteExtensionsEnableHeight int64
 }
 
 // BlockParams define limits on the block size and gas plus minimum time
@@ -43,17 +71,73 @@ type BlockParams struct {
 	// Minimum time increment between consecutive blocks (in milliseconds)
 	// Not exposed to the application.
 	TimeIotaMs int64 `json:"time_iota_ms"`
+	// Aggregate the precommits of the commits in the blocks into a single
+	// signature (see AggregateCommit). The validators must have BLS keys.
+	// EXPERIMENTAL
+	AggregateCommits bool `json:"aggregate_commits"`
+	// The non-nil precommits of the heights from VoteExtensionsEnableHeight
+	// on are extended by the application (see ExtendVote), and the
+	// extensions are signed with them. Zero, the default, disables vote
+	// extensions, so that the validators agree on the sign bytes of the
+	// precommits until they all run a version supporting them.
+	VoteExtensionsEnableHeight int64 `json:"vote_extensions_enable_height"`
 }
 
 // EvidenceParams determine how we handle evidence of malfeasance.
 type EvidenceParams struct {
 	MaxAge int64 `json:"max_age"` // only accept new evidence more recent than this
+
+	// Maximum number of evidence in a block. 0 - as many evidence of the
+	// maximum size (MaxEvidenceBytes) as fit in MaxBytes.
+	MaxNum int64 `json:"max_num"`
+	// Maximum total size of the evidence in a block, at most half of the
+	// maximum block size, so that evidence never takes all the space of the
+	// txs. 0 - a tenth of the maximum block size.
+	MaxBytes int64 `json:"max_bytes"`
 }
 
-// ValidatorParams restrict the public key types validators can use.
+// ValidatorParams restrict the public key types validators can use, and how
+// much the validator updates of a block can change the voting power.
 // NOTE: uses ABCI pubkey naming, not Amino names.
 type ValidatorParams struct {
 	PubKeyTypes []string `json:"pub_key_types"`
+
+	// The validator updates of a block are rejected if they change the total
+	// voting power of the validators by more than
+	// MaxPowerChangeNum/MaxPowerChangeDenom of it, so that light clients
+	// trusting a validator set keep trusting the next ones. A zero
+	// MaxPowerChangeDenom removes the limit.
+	MaxPowerChangeNum   int64 `json:"max_power_change_num"`
+	MaxPowerChangeDenom int64 `json:"max_power_change_denom"`
+}
+
+// TimeoutParams are the consensus timeouts, which the network can adjust
+// without every node editing its config. A zero timeout leaves it to the
+// config of each node.
+type TimeoutParams struct {
+	Propose        time.Duration `json:"propose"`
+	ProposeDelta   time.Duration `json:"propose_delta"`
+	Prevote        time.Duration `json:"prevote"`
+	PrevoteDelta   time.Duration `json:"prevote_delta"`
+	Precommit      time.Duration `json:"precommit"`
+	PrecommitDelta time.Duration `json:"precommit_delta"`
+	Commit         time.Duration `json:"commit"`
+}
+
+// SynchronyParams enable proposer-based timestamps, and bound the clock drift
+// between the validators and the delay of the proposals.
+//
+// With proposer-based timestamps, the block time is the time of the clock of
+// the proposer, and the validators prevote nil for a new proposal unless it's
+// timely (see IsTimely). With zero params, the block time is BFT time: the
+// median of the timestamps of the precommits of the last commit.
+type SynchronyParams struct {
+	// Bound on the difference between the clocks of the correct validators.
+	Precision time.Duration `json:"precision"`
+	// Bound on the time for a proposal to reach the validators, in round 0.
+	// It grows by 10% per round, so that the network decides a block even if
+	// MessageDelay is too small.
+	MessageDelay time.Duration `json:"message_delay"`
 }
 
 // DefaultConsensusParams returns a default ConsensusParams.
@@ -62,6 +146,8 @@ func DefaultConsensusParams() *ConsensusParams {
 		DefaultBlockParams(),
 		DefaultEvidenceParams(),
 		DefaultValidatorParams(),
+		DefaultTimeoutParams(),
+		DefaultSynchronyParams(),
 	}
 }
 
@@ -82,9 +168,64 @@ func DefaultEvidenceParams() EvidenceParams {
 }
 
 // DefaultValidatorParams returns a default ValidatorParams, which allows
-// only ed25519 pubkeys.
+// only ed25519 pubkeys, and changes of at most 1/3 of the voting power per
+// block.
 func DefaultValidatorParams() ValidatorParams {
-	return ValidatorParams{[]string{ABCIPubKeyTypeEd25519}}
+	return ValidatorParams{
+		PubKeyTypes:         []string{ABCIPubKeyTypeEd25519},
+		MaxPowerChangeNum:   1,
+		MaxPowerChangeDenom: 3,
+	}
+}
+
+// DefaultTimeoutParams returns a default TimeoutParams, which leaves the
+// timeouts to the config of each node.
+func DefaultTimeoutParams() TimeoutParams {
+	return TimeoutParams{}
+}
+
+// DefaultSynchronyParams returns a default SynchronyParams, which leaves
+// proposer-based timestamps disabled: the block time is BFT time.
+func DefaultSynchronyParams() SynchronyParams {
+	return SynchronyParams{}
+}
+
+// VoteExtensionsEnabled returns true if the precommits of the height carry
+// vote extensions.
+func (params BlockParams) VoteExtensionsEnabled(height int64) bool {
+	return params.VoteExtensionsEnableHeight > 0 && height >= params.VoteExtensionsEnableHeight
+}
+
+// ProposerBasedTimestamps returns true if the params enable proposer-based
+// timestamps, rather than BFT time.
+func (params SynchronyParams) ProposerBasedTimestamps() bool {
+	return params.Precision > 0 && params.MessageDelay > 0
+}
+
+// InRound returns the params in round: the MessageDelay grows by 10% per
+// round, up to MaxSynchronyParam.
+func (params SynchronyParams) InRound(round int) SynchronyParams {
+	delay := float64(params.MessageDelay) * math.Pow(1.1, float64(round))
+	if delay > float64(MaxSynchronyParam) {
+		delay = float64(MaxSynchronyParam)
+	}
+	return SynchronyParams{
+		Precision:    params.Precision,
+		MessageDelay: time.Duration(delay),
+	}
+}
+
+// IsTimely returns true if a proposal of a block with the time blockTime,
+// received at recvTime (by the local clock) in round, is timely:
+//
+//	blockTime - Precision <= recvTime <= blockTime + MessageDelay + Precision
+//
+// with the MessageDelay of the round.
+func (params SynchronyParams) IsTimely(blockTime, recvTime time.Time, round int) bool {
+	sp := params.InRound(round)
+	lower := blockTime.Add(-sp.Precision)
+	upper := blockTime.Add(sp.MessageDelay + sp.Precision)
+	return !recvTime.Before(lower) && !recvTime.After(upper)
 }
 
 func (params *ValidatorParams) IsValidPubkeyType(pubkeyType string) bool {
@@ -96,6 +237,20 @@ func (params *ValidatorParams) IsValidPubkeyType(pubkeyType string) bool {
 	return false
 }
 
+// ValidatePubKey returns an error unless pubKey is of a type allowed for the
+// validators.
+func (params *ValidatorParams) ValidatePubKey(pubKey crypto.PubKey) error {
+	keyType, ok := ABCIPubKeyType(pubKey)
+	if !ok {
+		return errors.Errorf("unsupported validator key %v", pubKey)
+	}
+	if !params.IsValidPubkeyType(keyType) {
+		return errors.Errorf("validator key type %s is not allowed, want one of %v",
+			keyType, params.PubKeyTypes)
+	}
+	return nil
+}
+
 // Validate validates the ConsensusParams to ensure all values are within their
 // allowed limits, and returns an error if they are not.
 func (params *ConsensusParams) Validate() error {
@@ -118,11 +273,39 @@ func (params *ConsensusParams) Validate() error {
 			params.Block.TimeIotaMs)
 	}
 
+	if params.Block.VoteExtensionsEnableHeight < 0 {
+		return errors.Errorf("Block.VoteExtensionsEnableHeight can't be negative. Got %d",
+			params.Block.VoteExtensionsEnableHeight)
+	}
+
+	if params.Block.AggregateCommits {
+		for _, keyType := range params.Validator.PubKeyTypes {
+			if keyType != ABCIPubKeyTypeBLS {
+				return errors.Errorf("Block.AggregateCommits needs %s validator keys only. Got %v",
+					ABCIPubKeyTypeBLS, params.Validator.PubKeyTypes)
+			}
+		}
+	}
+
 	if params.Evidence.MaxAge <= 0 {
 		return errors.Errorf("EvidenceParams.MaxAge must be greater than 0. Got %d",
 			params.Evidence.MaxAge)
 	}
 
+	if params.Evidence.MaxNum < 0 {
+		return errors.Errorf("EvidenceParams.MaxNum can't be negative. Got %d",
+			params.Evidence.MaxNum)
+	}
+
+	if params.Evidence.MaxBytes < 0 {
+		return errors.Errorf("EvidenceParams.MaxBytes can't be negative. Got %d",
+			params.Evidence.MaxBytes)
+	}
+	if params.Evidence.MaxBytes > params.Block.MaxBytes/2 {
+		return errors.Errorf("EvidenceParams.MaxBytes is more than half of Block.MaxBytes. %d > %d",
+			params.Evidence.MaxBytes, params.Block.MaxBytes/2)
+	}
+
 	if len(params.Validator.PubKeyTypes) == 0 {
 		return errors.New("len(Validator.PubKeyTypes) must be greater than 0")
 	}
@@ -136,11 +319,70 @@ func (params *ConsensusParams) Validate() error {
 		}
 	}
 
+	if params.Validator.MaxPowerChangeDenom < 0 {
+		return errors.Errorf("Validator.MaxPowerChangeDenom can't be negative. Got %d",
+			params.Validator.MaxPowerChangeDenom)
+	}
+	if params.Validator.MaxPowerChangeDenom > 0 && params.Validator.MaxPowerChangeNum <= 0 {
+		return errors.Errorf("Validator.MaxPowerChangeNum must be greater than 0. Got %d",
+			params.Validator.MaxPowerChangeNum)
+	}
+
+	timeouts := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"Propose", params.Timeout.Propose},
+		{"ProposeDelta", params.Timeout.ProposeDelta},
+		{"Prevote", params.Timeout.Prevote},
+		{"PrevoteDelta", params.Timeout.PrevoteDelta},
+		{"Precommit", params.Timeout.Precommit},
+		{"PrecommitDelta", params.Timeout.PrecommitDelta},
+		{"Commit", params.Timeout.Commit},
+	}
+	for _, timeout := range timeouts {
+		if timeout.value < 0 {
+			return errors.Errorf("Timeout.%s can't be negative. Got %v",
+				timeout.name, timeout.value)
+		}
+		if timeout.value > MaxTimeoutParam {
+			return errors.Errorf("Timeout.%s is too big. %v > %v",
+				timeout.name, timeout.value, MaxTimeoutParam)
+		}
+	}
+
+	if params.Synchrony.Precision < 0 || params.Synchrony.MessageDelay < 0 {
+		return errors.Errorf("Synchrony params can't be negative. Got %v", params.Synchrony)
+	}
+	if params.Synchrony.Precision > MaxSynchronyParam || params.Synchrony.MessageDelay > MaxSynchronyParam {
+		return errors.Errorf("Synchrony params are too big. %v > %v", params.Synchrony, MaxSynchronyParam)
+	}
+	if (params.Synchrony.Precision == 0) != (params.Synchrony.MessageDelay == 0) {
+		return errors.Errorf("Synchrony.Precision and Synchrony.MessageDelay must be both zero or both positive. Got %v",
+			params.Synchrony)
+	}
+
 	return nil
 }
 
+// MaxEvidence returns the maximum number of evidence in a block and their
+// maximum total size, following the defaults of the evidence params.
+func (params *ConsensusParams) MaxEvidence() (maxNum, maxBytes int64) {
+	maxNum, maxBytes = MaxEvidencePerBlock(params.Block.MaxBytes)
+	if params.Evidence.MaxBytes > 0 {
+		maxBytes = params.Evidence.MaxBytes
+		maxNum = maxBytes / MaxEvidenceBytes
+	}
+	if params.Evidence.MaxNum > 0 {
+		maxNum = params.Evidence.MaxNum
+	}
+	return maxNum, maxBytes
+}
+
 // Hash returns a hash of a subset of the parameters to store in the block header.
-// Only the Block.MaxBytes and Block.MaxGas are included in the hash.
+// Only the Block.MaxBytes, Block.MaxGas, Block.AggregateCommits,
+// Block.VoteExtensionsEnableHeight and the Synchrony params are included in
+// the hash.
 // This allows the ConsensusParams to evolve more without breaking the block
 // protocol. No need for a Merkle tree here, just a small struct to hash.
 func (params *ConsensusParams) Hash() []byte {
@@ -148,6 +390,10 @@ func (params *ConsensusParams) Hash() []byte {
 	bz := cdcEncode(HashedParams{
 		params.Block.MaxBytes,
 		params.Block.MaxGas,
+		params.Block.AggregateCommits,
+		params.Synchrony.Precision,
+		params.Synchrony.MessageDelay,
+		params.Block.VoteExtensionsEnableHeight,
 	})
 	if bz == nil {
 		panic("cannot fail to encode ConsensusParams")
@@ -159,7 +405,11 @@ func (params *ConsensusParams) Hash() []byte {
 func (params *ConsensusParams) Equals(params2 *ConsensusParams) bool {
 	return params.Block == params2.Block &&
 		params.Evidence == params2.Evidence &&
-		cmn.StringSliceEqual(params.Validator.PubKeyTypes, params2.Validator.PubKeyTypes)
+		params.Timeout == params2.Timeout &&
+		params.Synchrony == params2.Synchrony &&
+		cmn.StringSliceEqual(params.Validator.PubKeyTypes, params2.Validator.PubKeyTypes) &&
+		params.Validator.MaxPowerChangeNum == params2.Validator.MaxPowerChangeNum &&
+		params.Validator.MaxPowerChangeDenom == params2.Validator.MaxPowerChangeDenom
 }
 
 // Update returns a copy of the params with updates from the non-zero fields of p2.
@@ -175,14 +425,43 @@ func (params ConsensusParams) Update(params2 *abci.ConsensusParams) ConsensusPar
 	if params2.Block != nil {
 		res.Block.MaxBytes = params2.Block.MaxBytes
 		res.Block.MaxGas = params2.Block.MaxGas
+		res.Block.AggregateCommits = params2.Block.AggregateCommits
+		// 0 leaves it unchanged, for the apps unaware of it
+		if params2.Block.VoteExtensionsEnableHeight != 0 {
+			res.Block.VoteExtensionsEnableHeight = params2.Block.VoteExtensionsEnableHeight
+		}
 	}
 	if params2.Evidence != nil {
 		res.Evidence.MaxAge = params2.Evidence.MaxAge
+		res.Evidence.MaxNum = params2.Evidence.MaxNum
+		res.Evidence.MaxBytes = params2.Evidence.MaxBytes
 	}
 	if params2.Validator != nil {
 		// Copy params2.Validator.PubkeyTypes, and set result's value to the copy.
 		// This avoids having to initialize the slice to 0 values, and then write to it again.
 		res.Validator.PubKeyTypes = append([]string{}, params2.Validator.PubKeyTypes...)
+		// 0/0 leaves the limit unchanged, for the apps unaware of it
+		if params2.Validator.MaxPowerChangeDenom != 0 || params2.Validator.MaxPowerChangeNum != 0 {
+			res.Validator.MaxPowerChangeNum = params2.Validator.MaxPowerChangeNum
+			res.Validator.MaxPowerChangeDenom = params2.Validator.MaxPowerChangeDenom
+		}
+	}
+	if params2.Timeout != nil {
+		res.Timeout = TimeoutParams{
+			Propose:        time.Duration(params2.Timeout.ProposeMs) * time.Millisecond,
+			ProposeDelta:   time.Duration(params2.Timeout.ProposeDeltaMs) * time.Millisecond,
+			Prevote:        time.Duration(params2.Timeout.PrevoteMs) * time.Millisecond,
+			PrevoteDelta:   time.Duration(params2.Timeout.PrevoteDeltaMs) * time.Millisecond,
+			Precommit:      time.Duration(params2.Timeout.PrecommitMs) * time.Millisecond,
+			PrecommitDelta: time.Duration(params2.Timeout.PrecommitDeltaMs) * time.Millisecond,
+			Commit:         time.Duration(params2.Timeout.CommitMs) * time.Millisecond,
+		}
+	}
+	if params2.Synchrony != nil {
+		res.Synchrony = SynchronyParams{
+			Precision:    time.Duration(params2.Synchrony.PrecisionMs) * time.Millisecond,
+			MessageDelay: time.Duration(params2.Synchrony.MessageDelayMs) * time.Millisecond,
+		}
 	}
 	return res
 }
@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto/tmhash"
 )
 
 var (
 	valEd25519   = []string{ABCIPubKeyTypeEd25519}
 	valSecp256k1 = []string{ABCIPubKeyTypeSecp256k1}
+
+	pbtsParams = SynchronyParams{Precision: 500 * time.Millisecond, MessageDelay: 2 * time.Second}
 )
 
 func TestConsensusParamsValidation(t *testing.T) {
@@ -36,6 +40,32 @@ func TestConsensusParamsValidation(t *testing.T) {
 		11: {makeParams(1, 0, 10, 1, []string{}), false},
 		// test invalid pubkey type provided
 		12: {makeParams(1, 0, 10, 1, []string{"potatoes make good pubkeys"}), false},
+		// test timeout params
+		13: {makeParamsWithTimeout(TimeoutParams{Propose: time.Second, Commit: MaxTimeoutParam}), true},
+		14: {makeParamsWithTimeout(TimeoutParams{PrevoteDelta: -time.Millisecond}), false},
+		15: {makeParamsWithTimeout(TimeoutParams{Commit: MaxTimeoutParam + 1}), false},
+		// test evidence limits
+		16: {makeParamsWithEvidence(EvidenceParams{MaxAge: 1, MaxNum: 10, MaxBytes: 5000}), true},
+		17: {makeParamsWithEvidence(EvidenceParams{MaxAge: 1, MaxNum: -1}), false},
+		18: {makeParamsWithEvidence(EvidenceParams{MaxAge: 1, MaxBytes: -1}), false},
+		19: {makeParamsWithEvidence(EvidenceParams{MaxAge: 1, MaxBytes: 5001}), false},
+		// test validator power change limits
+		20: {makeParamsWithPowerChange(1, 3), true},
+		21: {makeParamsWithPowerChange(0, 0), true},
+		22: {makeParamsWithPowerChange(0, 3), false},
+		23: {makeParamsWithPowerChange(1, -3), false},
+		// test aggregate commits
+		24: {makeParamsWithAggregateCommits([]string{ABCIPubKeyTypeBLS}), true},
+		25: {makeParamsWithAggregateCommits([]string{ABCIPubKeyTypeBLS, ABCIPubKeyTypeEd25519}), false},
+		// test synchrony params
+		26: {makeParamsWithSynchrony(pbtsParams), true},
+		27: {makeParamsWithSynchrony(SynchronyParams{}), true},
+		28: {makeParamsWithSynchrony(SynchronyParams{Precision: time.Second}), false},
+		29: {makeParamsWithSynchrony(SynchronyParams{Precision: -1, MessageDelay: time.Second}), false},
+		30: {makeParamsWithSynchrony(SynchronyParams{Precision: 1, MessageDelay: MaxSynchronyParam + 1}), false},
+		// test vote extensions
+		31: {makeParamsWithVoteExtensions(10), true},
+		32: {makeParamsWithVoteExtensions(-1), false},
 	}
 	for i, tc := range testCases {
 		if tc.valid {
@@ -67,6 +97,61 @@ func makeParams(
 	}
 }
 
+func makeParamsWithTimeout(timeout TimeoutParams) ConsensusParams {
+	params := makeParams(1, 0, 10, 1, valEd25519)
+	params.Timeout = timeout
+	return params
+}
+
+func makeParamsWithPowerChange(num, denom int64) ConsensusParams {
+	params := makeParams(1, 0, 10, 1, valEd25519)
+	params.Validator.MaxPowerChangeNum = num
+	params.Validator.MaxPowerChangeDenom = denom
+	return params
+}
+
+func makeParamsWithAggregateCommits(pubkeyTypes []string) ConsensusParams {
+	params := makeParams(1, 0, 10, 1, pubkeyTypes)
+	params.Block.AggregateCommits = true
+	return params
+}
+
+func makeParamsWithVoteExtensions(enableHeight int64) ConsensusParams {
+	params := makeParams(1, 0, 10, 1, valEd25519)
+	params.Block.VoteExtensionsEnableHeight = enableHeight
+	return params
+}
+
+func makeParamsWithSynchrony(synchrony SynchronyParams) ConsensusParams {
+	params := makeParams(1, 0, 10, 1, valEd25519)
+	params.Synchrony = synchrony
+	return params
+}
+
+func makeParamsWithEvidence(evidence EvidenceParams) ConsensusParams {
+	params := makeParams(10000, 0, 10, 1, valEd25519)
+	params.Evidence = evidence
+	return params
+}
+
+func TestConsensusParamsMaxEvidence(t *testing.T) {
+	testCases := []struct {
+		evidence         EvidenceParams
+		maxNum, maxBytes int64
+	}{
+		{EvidenceParams{MaxAge: 1}, 2, 1000},
+		{EvidenceParams{MaxAge: 1, MaxNum: 1}, 1, 1000},
+		{EvidenceParams{MaxAge: 1, MaxBytes: 5000}, 10, 5000},
+		{EvidenceParams{MaxAge: 1, MaxNum: 20, MaxBytes: 5000}, 20, 5000},
+	}
+	for i, tc := range testCases {
+		params := makeParamsWithEvidence(tc.evidence)
+		maxNum, maxBytes := params.MaxEvidence()
+		assert.Equal(t, tc.maxNum, maxNum, "#%d", i)
+		assert.Equal(t, tc.maxBytes, maxBytes, "#%d", i)
+	}
+}
+
 func TestConsensusParamsHash(t *testing.T) {
 	params := []ConsensusParams{
 		makeParams(4, 2, 10, 3, valEd25519),
@@ -77,6 +162,8 @@ func TestConsensusParamsHash(t *testing.T) {
 		makeParams(9, 5, 10, 4, valEd25519),
 		makeParams(7, 8, 10, 9, valEd25519),
 		makeParams(4, 6, 10, 5, valEd25519),
+		makeParamsWithSynchrony(pbtsParams),
+		makeParamsWithVoteExtensions(10),
 	}
 
 	hashes := make([][]byte, len(params))
@@ -92,6 +179,17 @@ func TestConsensusParamsHash(t *testing.T) {
 	for i := 0; i < len(hashes)-1; i++ {
 		assert.NotEqual(t, hashes[i], hashes[i+1])
 	}
+
+	// the default params, with BFT time, are hashed as before the synchrony
+	// params
+	type blockHashedParams struct {
+		BlockMaxBytes int64
+		BlockMaxGas   int64
+	}
+	defaultParams := DefaultConsensusParams()
+	assert.Equal(t,
+		tmhash.Sum(cdcEncode(blockHashedParams{defaultParams.Block.MaxBytes, defaultParams.Block.MaxGas})),
+		defaultParams.Hash())
 }
 
 func TestConsensusParamsUpdate(t *testing.T) {
@@ -123,8 +221,106 @@ func TestConsensusParamsUpdate(t *testing.T) {
 			},
 			makeParams(100, 200, 10, 300, valSecp256k1),
 		},
+		// timeout updates
+		{
+			makeParamsWithTimeout(TimeoutParams{}),
+			&abci.ConsensusParams{
+				Timeout: &abci.TimeoutParams{
+					ProposeMs:      5000,
+					ProposeDeltaMs: 100,
+					CommitMs:       2000,
+				},
+			},
+			makeParamsWithTimeout(TimeoutParams{
+				Propose:      5 * time.Second,
+				ProposeDelta: 100 * time.Millisecond,
+				Commit:       2 * time.Second,
+			}),
+		},
+		// validator power change updates, 0/0 leaves them unchanged
+		{
+			makeParamsWithPowerChange(1, 3),
+			&abci.ConsensusParams{
+				Validator: &abci.ValidatorParams{PubKeyTypes: valEd25519},
+			},
+			makeParamsWithPowerChange(1, 3),
+		},
+		{
+			makeParamsWithPowerChange(1, 3),
+			&abci.ConsensusParams{
+				Validator: &abci.ValidatorParams{
+					PubKeyTypes:         valEd25519,
+					MaxPowerChangeNum:   1,
+					MaxPowerChangeDenom: 2,
+				},
+			},
+			makeParamsWithPowerChange(1, 2),
+		},
+		// vote extensions updates, 0 leaves them unchanged
+		{
+			makeParamsWithVoteExtensions(10),
+			&abci.ConsensusParams{
+				Block: &abci.BlockParams{MaxBytes: 1},
+			},
+			makeParamsWithVoteExtensions(10),
+		},
+		{
+			makeParamsWithVoteExtensions(0),
+			&abci.ConsensusParams{
+				Block: &abci.BlockParams{MaxBytes: 1, VoteExtensionsEnableHeight: 20},
+			},
+			makeParamsWithVoteExtensions(20),
+		},
+		// synchrony updates
+		{
+			makeParamsWithSynchrony(SynchronyParams{}),
+			&abci.ConsensusParams{
+				Synchrony: &abci.SynchronyParams{
+					PrecisionMs:    500,
+					MessageDelayMs: 3000,
+				},
+			},
+			makeParamsWithSynchrony(SynchronyParams{
+				Precision:    500 * time.Millisecond,
+				MessageDelay: 3 * time.Second,
+			}),
+		},
 	}
 	for _, tc := range testCases {
 		assert.Equal(t, tc.updatedParams, tc.params.Update(tc.updates))
 	}
 }
+
+func TestBlockParamsVoteExtensionsEnabled(t *testing.T) {
+	assert.False(t, BlockParams{}.VoteExtensionsEnabled(1))
+	assert.False(t, BlockParams{VoteExtensionsEnableHeight: 10}.VoteExtensionsEnabled(9))
+	assert.True(t, BlockParams{VoteExtensionsEnableHeight: 10}.VoteExtensionsEnabled(10))
+	assert.True(t, BlockParams{VoteExtensionsEnableHeight: 10}.VoteExtensionsEnabled(11))
+}
+
+func TestSynchronyParamsIsTimely(t *testing.T) {
+	sp := pbtsParams
+	blockTime := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		recvTime time.Time
+		round    int
+		timely   bool
+	}{
+		{blockTime, 0, true},
+		{blockTime.Add(-500 * time.Millisecond), 0, true},
+		{blockTime.Add(-501 * time.Millisecond), 0, false},
+		{blockTime.Add(2500 * time.Millisecond), 0, true},
+		{blockTime.Add(2501 * time.Millisecond), 0, false},
+		// the message delay grows with the rounds
+		{blockTime.Add(2501 * time.Millisecond), 1, true},
+		{blockTime.Add(-501 * time.Millisecond), 1, false},
+		{blockTime.Add(MaxSynchronyParam), 1000, true},
+		{blockTime.Add(MaxSynchronyParam + time.Second), 1000, false},
+	}
+	for i, tc := range testCases {
+		assert.Equal(t, tc.timely, sp.IsTimely(blockTime, tc.recvTime, tc.round), "#%d", i)
+	}
+	assert.True(t, sp.ProposerBasedTimestamps())
+	assert.False(t, DefaultSynchronyParams().ProposerBasedTimestamps())
+}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"sync"
@@ -10,26 +11,57 @@ import (
 
 	"github.com/tendermint/tendermint/crypto/merkle"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/libs/erasure"
+	"github.com/tendermint/tendermint/version"
 )
 
 var (
 	ErrPartSetUnexpectedIndex = errors.New("Error part set unexpected index")
 	ErrPartSetInvalidProof    = errors.New("Error part set invalid proof")
+	ErrPartSetInvalidEncoding = errors.New("Error part set invalid erasure encoding")
+	ErrPartSetPartTooBig      = errors.New("Error part set part too big")
 )
 
+const (
+	// parityPrefixSize is the size of the data size prefix of parity parts.
+	parityPrefixSize = 8
+
+	// erasureCodingBlockProtocol is the first block protocol version whose
+	// block parts are erasure coded.
+	erasureCodingBlockProtocol version.Protocol = 11
+)
+
+// ErasureCodedBlockParts returns true if the parts of the blocks of the block
+// protocol version are erasure coded.
+func ErasureCodedBlockParts(blockProtocol version.Protocol) bool {
+	return blockProtocol >= erasureCodingBlockProtocol
+}
+
+// MaxBlockPartSize returns the maximum size of one part of the blocks of the
+// block protocol version: BlockPartSizeBytes, unless the parts are erasure
+// coded.
+func MaxBlockPartSize(blockProtocol version.Protocol) int {
+	if !ErasureCodedBlockParts(blockProtocol) {
+		return BlockPartSizeBytes
+	}
+	return MaxBlockPartSizeBytes
+}
+
 type Part struct {
 	Index int                `json:"index"`
 	Bytes cmn.HexBytes       `json:"bytes"`
 	Proof merkle.SimpleProof `json:"proof"`
 }
 
-// ValidateBasic performs basic validation.
+// ValidateBasic performs basic validation. The size is checked against the
+// bound of any block protocol version: PartSet.AddPart checks it against the
+// bound of the version of the block.
 func (part *Part) ValidateBasic() error {
 	if part.Index < 0 {
 		return errors.New("negative Index")
 	}
-	if len(part.Bytes) > BlockPartSizeBytes {
-		return errors.Errorf("too big: %d bytes, max: %d", len(part.Bytes), BlockPartSizeBytes)
+	if len(part.Bytes) > MaxBlockPartSizeBytes {
+		return errors.Errorf("too big: %d bytes, max: %d", len(part.Bytes), MaxBlockPartSizeBytes)
 	}
 	if err := part.Proof.ValidateBasic(); err != nil {
 		return errors.Wrap(err, "wrong Proof")
@@ -71,6 +103,16 @@ func (psh PartSetHeader) Equals(other PartSetHeader) bool {
 	return psh.Total == other.Total && bytes.Equal(psh.Hash, other.Hash)
 }
 
+// DataTotal returns the count of data parts, the first ones of the part set
+// of a block of the block protocol version: all of them, unless they're erasure
+// coded. Any DataTotal parts are enough to reconstruct the others.
+func (psh PartSetHeader) DataTotal(blockProtocol version.Protocol) int {
+	if !ErasureCodedBlockParts(blockProtocol) {
+		return psh.Total
+	}
+	return dataPartsTotal(psh.Total)
+}
+
 // ValidateBasic performs basic validation.
 func (psh PartSetHeader) ValidateBasic() error {
 	if psh.Total < 0 {
@@ -85,14 +127,20 @@ func (psh PartSetHeader) ValidateBasic() error {
 
 //-------------------------------------
 
+// PartSet holds the parts of some data. If they're erasure coded, the data
+// parts are followed by parity parts, and any dataTotal parts are enough to
+// reconstruct the others.
 type PartSet struct {
-	total int
-	hash  []byte
-
-	mtx           sync.Mutex
-	parts         []*Part
-	partsBitArray *cmn.BitArray
-	count         int
+	total       int
+	dataTotal   int
+	maxPartSize int
+	hash        []byte
+
+	mtx             sync.Mutex
+	parts           []*Part
+	partsBitArray   *cmn.BitArray
+	count           int
+	invalidEncoding bool
 }
 
 // Returns an immutable, full PartSet from the data bytes.
@@ -119,6 +167,64 @@ func NewPartSetFromData(data []byte, partSize int) *PartSet {
 	}
 	return &PartSet{
 		total:         total,
+		dataTotal:     total,
+		hash:          root,
+		parts:         parts,
+		partsBitArray: partsBitArray,
+		count:         total,
+	}
+}
+
+// Returns an immutable, full and erasure coded PartSet from the data bytes.
+// The data bytes are split into "partSize" chunks (bigger ones if there would
+// be more than MaxBlockDataPartsCount), extended with a third as many parity
+// chunks, and merkle tree computed.
+func NewErasureCodedPartSetFromData(data []byte, partSize int) *PartSet {
+	dataTotal := (len(data) + partSize - 1) / partSize
+	if dataTotal > MaxBlockDataPartsCount {
+		partSize = (len(data) + MaxBlockDataPartsCount - 1) / MaxBlockDataPartsCount
+		dataTotal = (len(data) + partSize - 1) / partSize
+	}
+	total := dataTotal + parityPartsTotal(dataTotal)
+	parts := make([]*Part, total)
+	partsBitArray := cmn.NewBitArray(total)
+	for i := 0; i < dataTotal; i++ {
+		parts[i] = &Part{
+			Index: i,
+			Bytes: data[i*partSize : cmn.MinInt(len(data), (i+1)*partSize)],
+		}
+	}
+	// Compute parity parts
+	if total > dataTotal {
+		shardSize := len(parts[0].Bytes)
+		shards := make([][]byte, dataTotal)
+		for i := 0; i < dataTotal; i++ {
+			shards[i] = padShard(parts[i].Bytes, shardSize)
+		}
+		parity, err := erasure.Encode(shards, total-dataTotal)
+		if err != nil {
+			panic(err)
+		}
+		for i, shard := range parity {
+			parts[dataTotal+i] = &Part{
+				Index: dataTotal + i,
+				Bytes: parityPartBytes(len(data), shard),
+			}
+		}
+	}
+	// Compute merkle proofs
+	partsBytes := make([][]byte, total)
+	for i := 0; i < total; i++ {
+		partsBytes[i] = parts[i].Bytes
+		partsBitArray.SetIndex(i, true)
+	}
+	root, proofs := merkle.SimpleProofsFromByteSlices(partsBytes)
+	for i := 0; i < total; i++ {
+		parts[i].Proof = *proofs[i]
+	}
+	return &PartSet{
+		total:         total,
+		dataTotal:     dataTotal,
 		hash:          root,
 		parts:         parts,
 		partsBitArray: partsBitArray,
@@ -130,6 +236,8 @@ func NewPartSetFromData(data []byte, partSize int) *PartSet {
 func NewPartSetFromHeader(header PartSetHeader) *PartSet {
 	return &PartSet{
 		total:         header.Total,
+		dataTotal:     header.Total,
+		maxPartSize:   BlockPartSizeBytes,
 		hash:          header.Hash,
 		parts:         make([]*Part, header.Total),
 		partsBitArray: cmn.NewBitArray(header.Total),
@@ -137,6 +245,15 @@ func NewPartSetFromHeader(header PartSetHeader) *PartSet {
 	}
 }
 
+// Returns an empty PartSet ready to be populated with the parts of a block of
+// the block protocol version, erasure coded or not.
+func NewBlockPartSetFromHeader(header PartSetHeader, blockProtocol version.Protocol) *PartSet {
+	ps := NewPartSetFromHeader(header)
+	ps.dataTotal = header.DataTotal(blockProtocol)
+	ps.maxPartSize = MaxBlockPartSize(blockProtocol)
+	return ps
+}
+
 func (ps *PartSet) Header() PartSetHeader {
 	if ps == nil {
 		return PartSetHeader{}
@@ -188,6 +305,14 @@ func (ps *PartSet) Total() int {
 	return ps.total
 }
 
+// DataTotal returns the count of data parts, enough to reconstruct the others.
+func (ps *PartSet) DataTotal() int {
+	if ps == nil {
+		return 0
+	}
+	return ps.dataTotal
+}
+
 func (ps *PartSet) AddPart(part *Part) (bool, error) {
 	if ps == nil {
 		return false, nil
@@ -205,6 +330,11 @@ func (ps *PartSet) AddPart(part *Part) (bool, error) {
 		return false, nil
 	}
 
+	// Part too big for the block protocol version
+	if len(part.Bytes) > ps.maxPartSize {
+		return false, ErrPartSetPartTooBig
+	}
+
 	// Check hash proof
 	if part.Proof.Verify(ps.Hash(), part.Bytes) != nil {
 		return false, ErrPartSetInvalidProof
@@ -214,9 +344,89 @@ func (ps *PartSet) AddPart(part *Part) (bool, error) {
 	ps.parts[part.Index] = part
 	ps.partsBitArray.SetIndex(part.Index, true)
 	ps.count++
+
+	// Reconstruct the missing parts as soon as we have enough of them
+	if ps.count >= ps.dataTotal && ps.count < ps.total && !ps.invalidEncoding {
+		if err := ps.reconstruct(); err != nil {
+			// Wait for all the parts.
+			ps.invalidEncoding = true
+			return true, err
+		}
+	}
 	return true, nil
 }
 
+// reconstruct decodes the missing parts from the ones we have, and checks
+// they hash to the part set hash.
+func (ps *PartSet) reconstruct() error {
+	// The size of the data is in the parity parts. If we have none, we have
+	// all the data parts.
+	dataSize, shardSize := 0, 0
+	for i := ps.dataTotal; i < ps.total; i++ {
+		if ps.parts[i] != nil {
+			if len(ps.parts[i].Bytes) < parityPrefixSize {
+				return ErrPartSetInvalidEncoding
+			}
+			dataSize = int(binary.BigEndian.Uint64(ps.parts[i].Bytes))
+			shardSize = len(ps.parts[i].Bytes) - parityPrefixSize
+			break
+		}
+	}
+	if dataSize == 0 {
+		shardSize = len(ps.parts[0].Bytes)
+		for i := 0; i < ps.dataTotal; i++ {
+			dataSize += len(ps.parts[i].Bytes)
+		}
+	}
+	if shardSize <= 0 || dataSize <= 0 || (dataSize+shardSize-1)/shardSize != ps.dataTotal {
+		return ErrPartSetInvalidEncoding
+	}
+
+	shards := make([][]byte, ps.total)
+	for i, part := range ps.parts {
+		switch {
+		case part == nil:
+		case i < ps.dataTotal:
+			if len(part.Bytes) != dataPartSize(i, dataSize, shardSize) {
+				return ErrPartSetInvalidEncoding
+			}
+			shards[i] = padShard(part.Bytes, shardSize)
+		default:
+			if len(part.Bytes) != parityPrefixSize+shardSize {
+				return ErrPartSetInvalidEncoding
+			}
+			shards[i] = part.Bytes[parityPrefixSize:]
+		}
+	}
+	if err := erasure.Reconstruct(shards, ps.dataTotal); err != nil {
+		return ErrPartSetInvalidEncoding
+	}
+
+	partsBytes := make([][]byte, ps.total)
+	for i, shard := range shards {
+		switch {
+		case ps.parts[i] != nil:
+			partsBytes[i] = ps.parts[i].Bytes
+		case i < ps.dataTotal:
+			partsBytes[i] = shard[:dataPartSize(i, dataSize, shardSize)]
+		default:
+			partsBytes[i] = parityPartBytes(dataSize, shard)
+		}
+	}
+	root, proofs := merkle.SimpleProofsFromByteSlices(partsBytes)
+	if !bytes.Equal(root, ps.hash) {
+		return ErrPartSetInvalidEncoding
+	}
+	for i := range ps.parts {
+		if ps.parts[i] == nil {
+			ps.parts[i] = &Part{Index: i, Bytes: partsBytes[i], Proof: *proofs[i]}
+			ps.partsBitArray.SetIndex(i, true)
+		}
+	}
+	ps.count = ps.total
+	return nil
+}
+
 func (ps *PartSet) GetPart(index int) *Part {
 	ps.mtx.Lock()
 	defer ps.mtx.Unlock()
@@ -231,7 +441,7 @@ func (ps *PartSet) GetReader() io.Reader {
 	if !ps.IsComplete() {
 		panic("Cannot GetReader() on incomplete PartSet")
 	}
-	return NewPartSetReader(ps.parts)
+	return NewPartSetReader(ps.parts[:ps.dataTotal])
 }
 
 type PartSetReader struct {
@@ -269,6 +479,47 @@ func (psr *PartSetReader) Read(p []byte) (n int, err error) {
 	return psr.Read(p)
 }
 
+// dataPartsTotal returns the count of data parts of a part set of the given
+// total. Totals that don't match any count of data parts have no parity parts.
+func dataPartsTotal(total int) int {
+	dataTotal := total * 3 / 4
+	if dataTotal+parityPartsTotal(dataTotal) != total {
+		return total
+	}
+	return dataTotal
+}
+
+// parityPartsTotal returns the count of parity parts for the given count of
+// data parts: a third, rounded up.
+func parityPartsTotal(dataTotal int) int {
+	return (dataTotal + 2) / 3
+}
+
+// dataPartSize returns the size of the i-th data part. All but the last one
+// are shardSize long.
+func dataPartSize(i, dataSize, shardSize int) int {
+	return cmn.MinInt(shardSize, dataSize-i*shardSize)
+}
+
+// padShard pads the given data part with zeros to the shard size.
+func padShard(bz []byte, shardSize int) []byte {
+	if len(bz) == shardSize {
+		return bz
+	}
+	shard := make([]byte, shardSize)
+	copy(shard, bz)
+	return shard
+}
+
+// parityPartBytes prefixes the parity shard with the data size, so that the
+// last data part can be trimmed when it's reconstructed.
+func parityPartBytes(dataSize int, shard []byte) []byte {
+	bz := make([]byte, parityPrefixSize+len(shard))
+	binary.BigEndian.PutUint64(bz, uint64(dataSize))
+	copy(bz[parityPrefixSize:], shard)
+	return bz
+}
+
 func (ps *PartSet) StringShort() string {
 	if ps == nil {
 		return "nil-PartSet"
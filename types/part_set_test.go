@@ -9,6 +9,7 @@ import (
 
 	"github.com/tendermint/tendermint/crypto/merkle"
 	cmn "github.com/tendermint/tendermint/libs/common"
+	"github.com/tendermint/tendermint/version"
 )
 
 const (
@@ -60,6 +61,151 @@ func TestBasicPartSet(t *testing.T) {
 	assert.Equal(t, data, data2)
 }
 
+func TestErasureCodedPartSet(t *testing.T) {
+	// Construct random data of size partSize * 100
+	data := cmn.RandBytes(testPartSize * 100)
+	partSet := NewErasureCodedPartSetFromData(data, testPartSize)
+
+	// 100 data parts, 34 parity parts
+	assert.NotEmpty(t, partSet.Hash())
+	assert.Equal(t, 134, partSet.Total())
+	assert.Equal(t, 100, partSet.DataTotal())
+	assert.Equal(t, 134, partSet.BitArray().Size())
+	assert.True(t, partSet.HashesTo(partSet.Hash()))
+	assert.True(t, partSet.IsComplete())
+	assert.Equal(t, 134, partSet.Count())
+
+	// Test adding parts to a new partSet.
+	partSet2 := NewBlockPartSetFromHeader(partSet.Header(), version.BlockProtocol)
+
+	assert.True(t, partSet2.HasHeader(partSet.Header()))
+	for i := 0; i < 100; i++ {
+		part := partSet.GetPart(i)
+		//t.Logf("\n%v", part)
+		added, err := partSet2.AddPart(part)
+		if !added || err != nil {
+			t.Errorf("Failed to add part %v, error: %v", i, err)
+		}
+	}
+	// adding part with invalid index
+	added, err := partSet2.AddPart(&Part{Index: 10000})
+	assert.False(t, added)
+	assert.Error(t, err)
+	// adding existing part
+	added, err = partSet2.AddPart(partSet2.GetPart(0))
+	assert.False(t, added)
+	assert.Nil(t, err)
+	// adding a parity part, which was reconstructed
+	added, err = partSet2.AddPart(partSet.GetPart(133))
+	assert.False(t, added)
+	assert.Nil(t, err)
+
+	assert.Equal(t, partSet.Hash(), partSet2.Hash())
+	assert.Equal(t, 134, partSet2.Total())
+	assert.True(t, partSet2.IsComplete())
+
+	// Reconstruct data, assert that they are equal.
+	data2Reader := partSet2.GetReader()
+	data2, err := ioutil.ReadAll(data2Reader)
+	require.NoError(t, err)
+
+	assert.Equal(t, data, data2)
+}
+
+func TestPartSetReconstruct(t *testing.T) {
+	testCases := []struct {
+		testName string
+		dataSize int
+		partSize int
+	}{
+		{"One part", 100, testPartSize},
+		{"Uneven last part", 10*testPartSize + 123, testPartSize},
+		{"Bigger parts", (MaxBlockDataPartsCount + 10) * 100, 100},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.testName, func(t *testing.T) {
+			data := cmn.RandBytes(tc.dataSize)
+			partSet := NewErasureCodedPartSetFromData(data, tc.partSize)
+			dataTotal := partSet.DataTotal()
+			require.True(t, dataTotal <= MaxBlockDataPartsCount)
+			require.Equal(t, dataTotal+(dataTotal+2)/3, partSet.Total())
+
+			// any dataTotal parts are enough
+			partSet2 := NewBlockPartSetFromHeader(partSet.Header(), version.BlockProtocol)
+			for i, index := range cmn.RandPerm(partSet.Total())[:dataTotal] {
+				assert.False(t, partSet2.IsComplete())
+				added, err := partSet2.AddPart(partSet.GetPart(index))
+				require.NoError(t, err, "part %d", i)
+				require.True(t, added)
+			}
+			require.True(t, partSet2.IsComplete())
+			assert.Equal(t, partSet.BitArray(), partSet2.BitArray())
+			for i := 0; i < partSet.Total(); i++ {
+				assert.Equal(t, partSet.GetPart(i).Bytes, partSet2.GetPart(i).Bytes, "part %d", i)
+			}
+
+			data2, err := ioutil.ReadAll(partSet2.GetReader())
+			require.NoError(t, err)
+			assert.Equal(t, data, data2)
+		})
+	}
+}
+
+func TestPartSetInvalidEncoding(t *testing.T) {
+	// The parity part doesn't encode the data parts, but is part of the hash.
+	data := cmn.RandBytes(3 * testPartSize)
+	partSet := NewErasureCodedPartSetFromData(data, testPartSize)
+	parity := partSet.GetPart(3)
+	parity.Bytes[parityPrefixSize] ^= 0x01
+	partsBytes := make([][]byte, partSet.Total())
+	for i := range partsBytes {
+		partsBytes[i] = partSet.GetPart(i).Bytes
+	}
+	root, proofs := merkle.SimpleProofsFromByteSlices(partsBytes)
+	for i := range proofs {
+		partSet.GetPart(i).Proof = *proofs[i]
+	}
+
+	partSet2 := NewBlockPartSetFromHeader(PartSetHeader{Total: partSet.Total(), Hash: root}, version.BlockProtocol)
+	for _, index := range []int{0, 1} {
+		_, err := partSet2.AddPart(partSet.GetPart(index))
+		require.NoError(t, err)
+	}
+	added, err := partSet2.AddPart(parity)
+	assert.True(t, added)
+	assert.Equal(t, ErrPartSetInvalidEncoding, err)
+	assert.False(t, partSet2.IsComplete())
+
+	// the block is still complete with all the parts
+	added, err = partSet2.AddPart(partSet.GetPart(2))
+	assert.True(t, added)
+	assert.NoError(t, err)
+	assert.True(t, partSet2.IsComplete())
+	data2, err := ioutil.ReadAll(partSet2.GetReader())
+	require.NoError(t, err)
+	assert.Equal(t, data, data2)
+}
+
+func TestPartSetPartTooBig(t *testing.T) {
+	// parts bigger than BlockPartSizeBytes, as the erasure coded parts of big
+	// blocks are
+	partSize := 2 * BlockPartSizeBytes
+	partSet := NewErasureCodedPartSetFromData(cmn.RandBytes(partSize*4), partSize)
+
+	// rejected for the blocks of the former versions
+	legacy := NewBlockPartSetFromHeader(partSet.Header(), erasureCodingBlockProtocol-1)
+	added, err := legacy.AddPart(partSet.GetPart(0))
+	assert.False(t, added)
+	assert.Equal(t, ErrPartSetPartTooBig, err)
+
+	// accepted for the erasure coded blocks
+	erasureCoded := NewBlockPartSetFromHeader(partSet.Header(), erasureCodingBlockProtocol)
+	added, err = erasureCoded.AddPart(partSet.GetPart(0))
+	assert.True(t, added)
+	assert.NoError(t, err)
+}
+
 func TestWrongProof(t *testing.T) {
 	// Construct random data of size partSize * 100
 	data := cmn.RandBytes(testPartSize * 100)
@@ -115,7 +261,7 @@ func TestPartValidateBasic(t *testing.T) {
 	}{
 		{"Good Part", func(pt *Part) {}, false},
 		{"Negative index", func(pt *Part) { pt.Index = -1 }, true},
-		{"Too big part", func(pt *Part) { pt.Bytes = make([]byte, BlockPartSizeBytes+1) }, true},
+		{"Too big part", func(pt *Part) { pt.Bytes = make([]byte, MaxBlockPartSizeBytes+1) }, true},
 		{"Too big proof", func(pt *Part) {
 			pt.Proof = merkle.SimpleProof{
 				Total:    1,
@@ -7,8 +7,10 @@ import (
 
 	abci "github.com/tendermint/tendermint/abci/types"
 	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/bls"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/sr25519"
 )
 
 //-------------------------------------------------------
@@ -22,12 +24,33 @@ const (
 const (
 	ABCIPubKeyTypeEd25519   = "ed25519"
 	ABCIPubKeyTypeSecp256k1 = "secp256k1"
+	ABCIPubKeyTypeSr25519   = "sr25519"
+	ABCIPubKeyTypeBLS       = "bls"
 )
 
 // TODO: Make non-global by allowing for registration of more pubkey types
 var ABCIPubKeyTypesToAminoNames = map[string]string{
 	ABCIPubKeyTypeEd25519:   ed25519.PubKeyAminoName,
 	ABCIPubKeyTypeSecp256k1: secp256k1.PubKeyAminoName,
+	ABCIPubKeyTypeSr25519:   sr25519.PubKeyAminoName,
+	ABCIPubKeyTypeBLS:       bls.PubKeyAminoName,
+}
+
+// ABCIPubKeyType returns the ABCI type of pubKey (see ValidatorParams), or
+// false if it can't be a validator key.
+func ABCIPubKeyType(pubKey crypto.PubKey) (string, bool) {
+	switch pubKey.(type) {
+	case ed25519.PubKeyEd25519:
+		return ABCIPubKeyTypeEd25519, true
+	case secp256k1.PubKeySecp256k1:
+		return ABCIPubKeyTypeSecp256k1, true
+	case sr25519.PubKeySr25519:
+		return ABCIPubKeyTypeSr25519, true
+	case bls.PubKeyBLS:
+		return ABCIPubKeyTypeBLS, true
+	default:
+		return "", false
+	}
 }
 
 //-------------------------------------------------------
@@ -109,6 +132,16 @@ func (tm2pb) PubKey(pubKey crypto.PubKey) abci.PubKey {
 			Type: ABCIPubKeyTypeSecp256k1,
 			Data: pk[:],
 		}
+	case sr25519.PubKeySr25519:
+		return abci.PubKey{
+			Type: ABCIPubKeyTypeSr25519,
+			Data: pk[:],
+		}
+	case bls.PubKeyBLS:
+		return abci.PubKey{
+			Type: ABCIPubKeyTypeBLS,
+			Data: pk[:],
+		}
 	default:
 		panic(fmt.Sprintf("unknown pubkey type: %v %v", pubKey, reflect.TypeOf(pubKey)))
 	}
@@ -126,14 +159,34 @@ func (tm2pb) ValidatorUpdates(vals *ValidatorSet) []abci.ValidatorUpdate {
 func (tm2pb) ConsensusParams(params *ConsensusParams) *abci.ConsensusParams {
 	return &abci.ConsensusParams{
 		Block: &abci.BlockParams{
-			MaxBytes: params.Block.MaxBytes,
-			MaxGas:   params.Block.MaxGas,
+			MaxBytes:         params.Block.MaxBytes,
+			MaxGas:           params.Block.MaxGas,
+			AggregateCommits: params.Block.AggregateCommits,
+
+			VoteExtensionsEnableHeight: params.Block.VoteExtensionsEnableHeight,
 		},
 		Evidence: &abci.EvidenceParams{
-			MaxAge: params.Evidence.MaxAge,
+			MaxAge:   params.Evidence.MaxAge,
+			MaxNum:   params.Evidence.MaxNum,
+			MaxBytes: params.Evidence.MaxBytes,
 		},
 		Validator: &abci.ValidatorParams{
-			PubKeyTypes: params.Validator.PubKeyTypes,
+			PubKeyTypes:         params.Validator.PubKeyTypes,
+			MaxPowerChangeNum:   params.Validator.MaxPowerChangeNum,
+			MaxPowerChangeDenom: params.Validator.MaxPowerChangeDenom,
+		},
+		Timeout: &abci.TimeoutParams{
+			ProposeMs:        int64(params.Timeout.Propose / time.Millisecond),
+			ProposeDeltaMs:   int64(params.Timeout.ProposeDelta / time.Millisecond),
+			PrevoteMs:        int64(params.Timeout.Prevote / time.Millisecond),
+			PrevoteDeltaMs:   int64(params.Timeout.PrevoteDelta / time.Millisecond),
+			PrecommitMs:      int64(params.Timeout.Precommit / time.Millisecond),
+			PrecommitDeltaMs: int64(params.Timeout.PrecommitDelta / time.Millisecond),
+			CommitMs:         int64(params.Timeout.Commit / time.Millisecond),
+		},
+		Synchrony: &abci.SynchronyParams{
+			PrecisionMs:    int64(params.Synchrony.Precision / time.Millisecond),
+			MessageDelayMs: int64(params.Synchrony.MessageDelay / time.Millisecond),
 		},
 	}
 }
@@ -204,6 +257,22 @@ func (pb2tm) PubKey(pubKey abci.PubKey) (crypto.PubKey, error) {
 		var pk secp256k1.PubKeySecp256k1
 		copy(pk[:], pubKey.Data)
 		return pk, nil
+	case ABCIPubKeyTypeSr25519:
+		if len(pubKey.Data) != sr25519.PubKeySr25519Size {
+			return nil, fmt.Errorf("Invalid size for PubKeySr25519. Got %d, expected %d",
+				len(pubKey.Data), sr25519.PubKeySr25519Size)
+		}
+		var pk sr25519.PubKeySr25519
+		copy(pk[:], pubKey.Data)
+		return pk, nil
+	case ABCIPubKeyTypeBLS:
+		if len(pubKey.Data) != bls.PubKeyBLSSize {
+			return nil, fmt.Errorf("Invalid size for PubKeyBLS. Got %d, expected %d",
+				len(pubKey.Data), bls.PubKeyBLSSize)
+		}
+		var pk bls.PubKeyBLS
+		copy(pk[:], pubKey.Data)
+		return pk, nil
 	default:
 		return nil, fmt.Errorf("Unknown pubkey type %v", pubKey.Type)
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/sr25519"
 	"github.com/tendermint/tendermint/version"
 )
 
@@ -20,13 +21,24 @@ func TestABCIPubKey(t *testing.T) {
 	pkSecp := secp256k1.GenPrivKey().PubKey()
 	testABCIPubKey(t, pkEd, ABCIPubKeyTypeEd25519)
 	testABCIPubKey(t, pkSecp, ABCIPubKeyTypeSecp256k1)
+	pkSr := sr25519.GenPrivKey().PubKey()
+	testABCIPubKey(t, pkSr, ABCIPubKeyTypeSr25519)
 }
 
 func testABCIPubKey(t *testing.T, pk crypto.PubKey, typeStr string) {
 	abciPubKey := TM2PB.PubKey(pk)
+	assert.Equal(t, typeStr, abciPubKey.Type)
 	pk2, err := PB2TM.PubKey(abciPubKey)
 	assert.Nil(t, err)
 	assert.Equal(t, pk, pk2)
+
+	abciType, ok := ABCIPubKeyType(pk)
+	assert.True(t, ok)
+	assert.Equal(t, typeStr, abciType)
+
+	abciPubKey.Data = abciPubKey.Data[1:]
+	_, err = PB2TM.PubKey(abciPubKey)
+	assert.Error(t, err)
 }
 
 func TestABCIValidators(t *testing.T) {
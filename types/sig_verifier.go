@@ -0,0 +1,47 @@
+package types
+
+import (
+	"sync/atomic"
+
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// SigVerifier verifies batches of signatures, possibly concurrently.
+type SigVerifier interface {
+	// VerifyBatch returns, for each i, whether sigs[i] is a valid signature
+	// of msgs[i] by pubKeys[i].
+	VerifyBatch(pubKeys []crypto.PubKey, msgs, sigs [][]byte) []bool
+}
+
+// InlineSigVerifier verifies the signatures one after the other, in the
+// calling goroutine.
+type InlineSigVerifier struct{}
+
+var _ SigVerifier = InlineSigVerifier{}
+
+// VerifyBatch implements SigVerifier.
+func (InlineSigVerifier) VerifyBatch(pubKeys []crypto.PubKey, msgs, sigs [][]byte) []bool {
+	valid := make([]bool, len(pubKeys))
+	for i, pubKey := range pubKeys {
+		valid[i] = pubKey.VerifyBytes(msgs[i], sigs[i])
+	}
+	return valid
+}
+
+// sigVerifier verifies the commit and evidence signatures.
+var sigVerifier atomic.Value
+
+func init() {
+	SetSigVerifier(InlineSigVerifier{})
+}
+
+// SetSigVerifier sets the SigVerifier used to verify the signatures of
+// commits (ValidatorSet#VerifyCommit) and evidence (Evidence#Verify). By
+// default, they are verified inline.
+func SetSigVerifier(verifier SigVerifier) {
+	sigVerifier.Store(&verifier)
+}
+
+func verifySignatures(pubKeys []crypto.PubKey, msgs, sigs [][]byte) []bool {
+	return (*sigVerifier.Load().(*SigVerifier)).VerifyBatch(pubKeys, msgs, sigs)
+}
@@ -0,0 +1,41 @@
+package types
+
+// SyncPhase is the phase of a node catching up with the network, advertised to
+// its peers in the status messages of the blockchain reactor (see
+// PeerSyncPhaseKey).
+type SyncPhase uint8
+
+const (
+	// SyncPhaseUnknown is the phase of the peers which don't advertise it
+	// (e.g. running an older version), or advertise one this version
+	// doesn't know.
+	SyncPhaseUnknown SyncPhase = iota
+	// SyncPhaseFastSync means the node downloads and executes the blocks
+	// from its peers.
+	SyncPhaseFastSync
+	// SyncPhaseCaughtUp means the node takes part in consensus.
+	SyncPhaseCaughtUp
+	// SyncPhaseStateSync means the node restores a snapshot of the app
+	// state. It's reserved for state sync, which this version doesn't
+	// implement.
+	SyncPhaseStateSync
+)
+
+// IsSyncing returns true if the node is still catching up: it can't serve
+// recent blocks reliably, nor use consensus messages.
+func (p SyncPhase) IsSyncing() bool {
+	return p == SyncPhaseFastSync || p == SyncPhaseStateSync
+}
+
+func (p SyncPhase) String() string {
+	switch p {
+	case SyncPhaseFastSync:
+		return "fast_sync"
+	case SyncPhaseCaughtUp:
+		return "caught_up"
+	case SyncPhaseStateSync:
+		return "state_sync"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,71 @@
+package time
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultStepThreshold is the minimum jump of the wall clock, relative to the
+// monotonic clock, reported as a clock step.
+const DefaultStepThreshold = 500 * time.Millisecond
+
+// MonotonicClock hands out canonical timestamps (see Canonical) that strictly
+// increase, even when the wall clock is stepped backwards, e.g. by NTP. It
+// also detects steps of the wall clock by comparing it with the monotonic
+// clock of the process. Use it to timestamp what this node produces (e.g.
+// proposals and votes).
+//
+// It is safe for concurrent use.
+type MonotonicClock struct {
+	start time.Time // reference reading of the monotonic clock
+
+	mtx       sync.Mutex
+	last      time.Time     // last timestamp handed out
+	lastWall  time.Time     // last wall clock reading
+	lastMono  time.Duration // last monotonic clock reading, since start
+	threshold time.Duration
+	onStep    func(step time.Duration)
+}
+
+// NewMonotonicClock returns a MonotonicClock reporting steps bigger than
+// DefaultStepThreshold.
+func NewMonotonicClock() *MonotonicClock {
+	return &MonotonicClock{start: time.Now(), threshold: DefaultStepThreshold}
+}
+
+// SetStepHandler sets a function called, with the lock held, whenever the
+// wall clock moved by more than threshold from what the monotonic clock
+// measured. A negative step means the wall clock went backwards.
+func (mc *MonotonicClock) SetStepHandler(threshold time.Duration, onStep func(step time.Duration)) {
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+	mc.threshold = threshold
+	mc.onStep = onStep
+}
+
+// Now returns the current time, or just after the last timestamp handed out
+// if the wall clock is behind it.
+func (mc *MonotonicClock) Now() time.Time {
+	raw := time.Now()
+	// Sub uses the monotonic components of both readings.
+	return mc.now(Canonical(raw), raw.Sub(mc.start))
+}
+
+func (mc *MonotonicClock) now(wall time.Time, mono time.Duration) time.Time {
+	mc.mtx.Lock()
+	defer mc.mtx.Unlock()
+
+	if !mc.lastWall.IsZero() && mc.onStep != nil {
+		step := wall.Sub(mc.lastWall) - (mono - mc.lastMono)
+		if step >= mc.threshold || step <= -mc.threshold {
+			mc.onStep(step)
+		}
+	}
+	mc.lastWall, mc.lastMono = wall, mono
+
+	if !wall.After(mc.last) {
+		wall = mc.last.Add(time.Nanosecond)
+	}
+	mc.last = wall
+	return wall
+}
@@ -0,0 +1,49 @@
+package time
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonotonicClockNeverGoesBack(t *testing.T) {
+	mc := NewMonotonicClock()
+	wall := Now()
+
+	t1 := mc.now(wall, 0)
+	assert.Equal(t, wall, t1)
+
+	// the wall clock is stepped back by a minute
+	t2 := mc.now(wall.Add(-time.Minute), time.Second)
+	assert.True(t, t2.After(t1))
+
+	// the same reading twice still yields increasing timestamps
+	t3 := mc.now(wall.Add(-time.Minute), time.Second)
+	assert.True(t, t3.After(t2))
+
+	// the wall clock catches up
+	t4 := mc.now(wall.Add(time.Minute), 2*time.Minute)
+	assert.Equal(t, wall.Add(time.Minute), t4)
+
+	for i, prev := 0, mc.Now(); i < 100; i++ {
+		now := mc.Now()
+		assert.True(t, now.After(prev))
+		prev = now
+	}
+}
+
+func TestMonotonicClockDetectsSteps(t *testing.T) {
+	mc := NewMonotonicClock()
+	var steps []time.Duration
+	mc.SetStepHandler(time.Second, func(step time.Duration) { steps = append(steps, step) })
+
+	wall := Now()
+	mc.now(wall, 0)
+	mc.now(wall.Add(10*time.Second), 10*time.Second)     // no step
+	mc.now(wall.Add(11*time.Second), 20*time.Second)     // stepped back by 9s
+	mc.now(wall.Add(31*time.Second+500), 40*time.Second) // jitter
+	mc.now(wall.Add(time.Hour), 41*time.Second)          // stepped forward
+
+	assert.Equal(t, []time.Duration{-9 * time.Second, time.Hour - 31*time.Second - 500 - time.Second}, steps)
+}
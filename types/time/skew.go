@@ -0,0 +1,112 @@
+package time
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultMaxClockSkew is the deviation from the median peer time above which
+// the local clock is considered skewed.
+const DefaultMaxClockSkew = 5 * time.Second
+
+// ClockSkew estimates the deviation of the local clock from the median time
+// of peers. Each peer contributes the offset of the time it reported when
+// connecting; only the last offset of a peer is kept, so that reconnecting
+// peers don't outweigh the others.
+//
+// Offsets include the network latency, so small skews are meaningless.
+//
+// It is safe for concurrent use.
+type ClockSkew struct {
+	mtx     sync.Mutex
+	offsets map[string]time.Duration
+	order   []string // peers, oldest offset first
+	size    int
+
+	maxSkew    time.Duration
+	minSamples int
+	onSkew     func(median time.Duration, samples int)
+	skewed     bool
+}
+
+// NewClockSkew returns a ClockSkew keeping the offsets of the last size peers.
+func NewClockSkew(size int) *ClockSkew {
+	return &ClockSkew{
+		offsets: make(map[string]time.Duration),
+		size:    size,
+	}
+}
+
+// SetSkewHandler sets a function called, with the lock held, when the median
+// offset of at least minSamples peers starts exceeding maxSkew. It is called
+// again only after the skew went back below maxSkew.
+func (cs *ClockSkew) SetSkewHandler(maxSkew time.Duration, minSamples int,
+	onSkew func(median time.Duration, samples int)) {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	cs.maxSkew = maxSkew
+	cs.minSamples = minSamples
+	cs.onSkew = onSkew
+}
+
+// AddSample records that the peer reported peerTime, when the local clock
+// read localTime.
+func (cs *ClockSkew) AddSample(peer string, peerTime, localTime time.Time) {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	if _, ok := cs.offsets[peer]; ok {
+		for i, p := range cs.order {
+			if p == peer {
+				cs.order = append(cs.order[:i], cs.order[i+1:]...)
+				break
+			}
+		}
+	}
+	cs.offsets[peer] = peerTime.Sub(localTime)
+	cs.order = append(cs.order, peer)
+
+	for len(cs.order) > cs.size {
+		delete(cs.offsets, cs.order[0])
+		cs.order = cs.order[1:]
+	}
+
+	if cs.onSkew == nil {
+		return
+	}
+	median, samples := cs.median()
+	if samples < cs.minSamples {
+		return
+	}
+	skewed := median > cs.maxSkew || median < -cs.maxSkew
+	if skewed && !cs.skewed {
+		cs.onSkew(median, samples)
+	}
+	cs.skewed = skewed
+}
+
+// Median returns the median offset of the peer clocks from the local one
+// (positive if peers are ahead), and the number of peers it is computed from.
+func (cs *ClockSkew) Median() (offset time.Duration, samples int) {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	return cs.median()
+}
+
+func (cs *ClockSkew) median() (time.Duration, int) {
+	if len(cs.offsets) == 0 {
+		return 0, 0
+	}
+	offsets := make([]time.Duration, 0, len(cs.offsets))
+	for _, o := range cs.offsets {
+		offsets = append(offsets, o)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	n := len(offsets)
+	if n%2 == 1 {
+		return offsets[n/2], n
+	}
+	return (offsets[n/2-1] + offsets[n/2]) / 2, n
+}
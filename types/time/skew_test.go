@@ -0,0 +1,66 @@
+package time
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockSkewMedian(t *testing.T) {
+	cs := NewClockSkew(3)
+	now := Now()
+
+	median, samples := cs.Median()
+	assert.Equal(t, time.Duration(0), median)
+	assert.Equal(t, 0, samples)
+
+	cs.AddSample("a", now.Add(time.Second), now)
+	cs.AddSample("b", now.Add(3*time.Second), now)
+	median, samples = cs.Median()
+	assert.Equal(t, 2*time.Second, median)
+	assert.Equal(t, 2, samples)
+
+	// a peer only counts once
+	cs.AddSample("a", now.Add(-time.Hour), now)
+	cs.AddSample("c", now.Add(-time.Second), now)
+	median, samples = cs.Median()
+	assert.Equal(t, -time.Second, median)
+	assert.Equal(t, 3, samples)
+
+	// the oldest peer (b) is evicted
+	cs.AddSample("d", now.Add(-2*time.Second), now)
+	median, samples = cs.Median()
+	assert.Equal(t, -2*time.Second, median)
+	assert.Equal(t, 3, samples)
+}
+
+func TestClockSkewHandler(t *testing.T) {
+	cs := NewClockSkew(10)
+	var calls int
+	cs.SetSkewHandler(5*time.Second, 3, func(median time.Duration, samples int) { calls++ })
+	now := Now()
+
+	// not enough peers
+	cs.AddSample("a", now.Add(time.Minute), now)
+	cs.AddSample("b", now.Add(time.Minute), now)
+	assert.Equal(t, 0, calls)
+
+	// skewed, reported once
+	for i := 0; i < 3; i++ {
+		cs.AddSample(fmt.Sprintf("c%d", i), now.Add(time.Minute), now)
+	}
+	assert.Equal(t, 1, calls)
+
+	// back to normal, then skewed again
+	for i := 0; i < 6; i++ {
+		cs.AddSample(fmt.Sprintf("d%d", i), now, now)
+	}
+	cs.AddSample("a", now.Add(-time.Minute), now)
+	assert.Equal(t, 1, calls)
+	for i := 0; i < 10; i++ {
+		cs.AddSample(fmt.Sprintf("e%d", i), now.Add(-time.Minute), now)
+	}
+	assert.Equal(t, 2, calls)
+}
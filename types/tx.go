@@ -63,6 +63,34 @@ func (txs Txs) IndexByHash(hash []byte) int {
 	return -1
 }
 
+// TotalAminoSize returns the size of the txs in the block data, with their
+// amino overhead (see ComputeAminoOverhead).
+func (txs Txs) TotalAminoSize() int64 {
+	size := int64(0)
+	for _, tx := range txs {
+		size += int64(len(tx)) + ComputeAminoOverhead(tx, 1)
+	}
+	return size
+}
+
+// ToSliceOfBytes returns the txs as a slice of byte slices, as in ABCI.
+func (txs Txs) ToSliceOfBytes() [][]byte {
+	bzs := make([][]byte, len(txs))
+	for i, tx := range txs {
+		bzs[i] = tx
+	}
+	return bzs
+}
+
+// ToTxs returns the byte slices of ABCI as txs.
+func ToTxs(bzs [][]byte) Txs {
+	txs := make(Txs, len(bzs))
+	for i, bz := range bzs {
+		txs[i] = bz
+	}
+	return txs
+}
+
 // Proof returns a simple merkle proof for this node.
 // Panics if i < 0 or i >= len(txs)
 // TODO: optimize this!
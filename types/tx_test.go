@@ -121,6 +121,8 @@ func TestComputeTxsOverhead(t *testing.T) {
 		assert.EqualValues(t, tc.wantOverhead, totalOverhead)
 		assert.NoError(t, err)
 		assert.EqualValues(t, len(bz), totalBytes)
+		assert.EqualValues(t, len(bz), tc.txs.TotalAminoSize())
+		assert.Equal(t, tc.txs, ToTxs(tc.txs.ToSliceOfBytes()))
 	}
 }
 
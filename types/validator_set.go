@@ -10,6 +10,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/merkle"
 )
 
@@ -596,8 +597,8 @@ func (vals *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height i
 	if err := commit.ValidateBasic(); err != nil {
 		return err
 	}
-	if vals.Size() != len(commit.Precommits) {
-		return NewErrInvalidCommitPrecommits(vals.Size(), len(commit.Precommits))
+	if vals.Size() != commit.Size() {
+		return NewErrInvalidCommitPrecommits(vals.Size(), commit.Size())
 	}
 	if height != commit.Height() {
 		return NewErrInvalidCommitHeight(height, commit.Height())
@@ -606,21 +607,39 @@ func (vals *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height i
 		return fmt.Errorf("Invalid commit -- wrong block id: want %v got %v",
 			blockID, commit.BlockID)
 	}
+	if commit.Aggregate != nil {
+		return vals.verifyAggregateCommit(chainID, commit)
+	}
 
 	talliedVotingPower := int64(0)
 
+	// Validate the signatures in a batch, which may be verified concurrently.
+	var (
+		indexes = make([]int, 0, len(commit.Precommits))
+		pubKeys = make([]crypto.PubKey, 0, len(commit.Precommits))
+		msgs    = make([][]byte, 0, len(commit.Precommits))
+		sigs    = make([][]byte, 0, len(commit.Precommits))
+	)
 	for idx, precommit := range commit.Precommits {
 		if precommit == nil {
 			continue // OK, some precommits can be missing.
 		}
 		_, val := vals.GetByIndex(idx)
-		// Validate signature.
-		precommitSignBytes := commit.VoteSignBytes(chainID, idx)
-		if !val.PubKey.VerifyBytes(precommitSignBytes, precommit.Signature) {
+		indexes = append(indexes, idx)
+		pubKeys = append(pubKeys, val.PubKey)
+		msgs = append(msgs, commit.VoteSignBytes(chainID, idx))
+		sigs = append(sigs, precommit.Signature)
+	}
+	valid := verifySignatures(pubKeys, msgs, sigs)
+
+	for i, idx := range indexes {
+		precommit := commit.Precommits[idx]
+		if !valid[i] {
 			return fmt.Errorf("Invalid commit -- invalid signature: %v", precommit)
 		}
 		// Good precommit!
 		if blockID.Equals(precommit.BlockID) {
+			_, val := vals.GetByIndex(idx)
 			talliedVotingPower += val.VotingPower
 		}
 		// else {
@@ -676,6 +695,11 @@ func (vals *ValidatorSet) VerifyFutureCommit(newSet *ValidatorSet, chainID strin
 
 	// Check old voting power.
 	oldVotingPower := int64(0)
+	if commit.Aggregate != nil {
+		// The aggregate signature was verified with the keys of newSet, and
+		// the commit has no precommits to go through.
+		oldVotingPower = oldVals.signersVotingPower(newSet, commit.Aggregate.Signers)
+	}
 	seen := map[int]bool{}
 	round := commit.Round()
 
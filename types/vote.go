@@ -11,9 +11,13 @@ import (
 )
 
 const (
-	// MaxVoteBytes is a maximum vote size (including amino overhead).
-	MaxVoteBytes int64  = 223
-	nilVoteStr   string = "nil-Vote"
+	// MaxVoteBytes is a maximum vote size (including amino overhead), without
+	// the extension.
+	MaxVoteBytes int64 = 223
+	// MaxVoteExtensionSize is a maximum size of the application-provided
+	// extension of a precommit.
+	MaxVoteExtensionSize        = 1024
+	nilVoteStr           string = "nil-Vote"
 )
 
 var (
@@ -58,6 +62,7 @@ type Vote struct {
 	ValidatorAddress Address       `json:"validator_address"`
 	ValidatorIndex   int           `json:"validator_index"`
 	Signature        []byte        `json:"signature"`
+	Extension        []byte        `json:"extension"` // only for non-nil precommits
 }
 
 // CommitSig converts the Vote to a CommitSig.
@@ -158,5 +163,13 @@ func (vote *Vote) ValidateBasic() error {
 	if len(vote.Signature) > MaxSignatureSize {
 		return fmt.Errorf("Signature is too big (max: %d)", MaxSignatureSize)
 	}
+	if len(vote.Extension) > 0 {
+		if vote.Type != PrecommitType || vote.BlockID.IsZero() {
+			return errors.New("Only non-nil precommits can have an Extension")
+		}
+		if len(vote.Extension) > MaxVoteExtensionSize {
+			return fmt.Errorf("Extension is too big (max: %d)", MaxVoteExtensionSize)
+		}
+	}
 	return nil
 }
@@ -11,6 +11,8 @@ import (
 	amino "github.com/tendermint/go-amino"
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	"github.com/tendermint/tendermint/crypto/sr25519"
 	"github.com/tendermint/tendermint/crypto/tmhash"
 )
 
@@ -68,6 +70,19 @@ func TestVoteSignable(t *testing.T) {
 	require.Equal(t, expected, signBytes, "Got unexpected sign bytes for Vote.")
 }
 
+func TestVoteSignBytesExtension(t *testing.T) {
+	vote := examplePrecommit()
+	signBytes := vote.SignBytes("test_chain_id")
+
+	// the extension is signed over
+	vote.Extension = []byte("extension")
+	require.NotEqual(t, signBytes, vote.SignBytes("test_chain_id"))
+
+	// an empty extension leaves the sign bytes unchanged
+	vote.Extension = []byte{}
+	require.Equal(t, signBytes, vote.SignBytes("test_chain_id"))
+}
+
 func TestVoteSignBytesTestVectors(t *testing.T) {
 
 	tests := []struct {
@@ -223,6 +238,23 @@ func TestVoteVerify(t *testing.T) {
 	}
 }
 
+func TestVoteSignKeyTypes(t *testing.T) {
+	privKeys := []crypto.PrivKey{ed25519.GenPrivKey(), secp256k1.GenPrivKey(), sr25519.GenPrivKey()}
+	for _, privKey := range privKeys {
+		privVal := NewMockPVWithParams(privKey, false, false)
+		pubKey := privVal.GetPubKey()
+
+		vote := examplePrevote()
+		vote.ValidatorAddress = pubKey.Address()
+		require.NoError(t, privVal.SignVote("test_chain_id", vote))
+		assert.True(t, len(vote.Signature) <= MaxSignatureSize, "%T", privKey)
+		assert.NoError(t, vote.Verify("test_chain_id", pubKey), "%T", privKey)
+
+		vote.Round++
+		assert.Equal(t, ErrVoteInvalidSignature, vote.Verify("test_chain_id", pubKey), "%T", privKey)
+	}
+}
+
 func TestMaxVoteBytes(t *testing.T) {
 	// time is varint encoded so need to pick the max.
 	// year int, month Month, day, hour, min, sec, nsec int, loc *Location
@@ -286,6 +318,16 @@ func TestVoteValidateBasic(t *testing.T) {
 		{"Invalid ValidatorIndex", func(v *Vote) { v.ValidatorIndex = -1 }, true},
 		{"Invalid Signature", func(v *Vote) { v.Signature = nil }, true},
 		{"Too big Signature", func(v *Vote) { v.Signature = make([]byte, MaxSignatureSize+1) }, true},
+		{"Extension", func(v *Vote) { v.Extension = []byte("extension") }, false},
+		{"Too big Extension", func(v *Vote) { v.Extension = make([]byte, MaxVoteExtensionSize+1) }, true},
+		{"Extension on Prevote", func(v *Vote) {
+			v.Type = PrevoteType
+			v.Extension = []byte("extension")
+		}, true},
+		{"Extension on nil Precommit", func(v *Vote) {
+			v.BlockID = BlockID{}
+			v.Extension = []byte("extension")
+		}, true},
 	}
 	for _, tc := range testCases {
 		tc := tc
@@ -39,11 +39,13 @@ func (p Protocol) Uint64() uint64 {
 var (
 	// P2PProtocol versions all p2p behaviour and msgs.
 	// This includes proposer selection.
-	P2PProtocol Protocol = 7
+	// Since version 8, peers upgrade the secret connection with a Noise handshake.
+	P2PProtocol Protocol = 8
 
 	// BlockProtocol versions all block data structures and processing.
 	// This includes validity of blocks and state updates.
-	BlockProtocol Protocol = 10
+	// Since version 11, the block parts are erasure coded.
+	BlockProtocol Protocol = 11
 )
 
 //------------------------------------------------------------------------